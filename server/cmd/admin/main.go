@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"gongdan-system/internal/services"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, `Usage: go run cmd/admin/main.go <command> [flags]
+
+Commands:
+  verify       检查冗余计数字段及孤立记录（-repair 修复，-json 输出完整报告）
+  rotate-keys  用密钥文件中的 primary 主密钥重新包装附件的数据密钥（-keyfile 指定密钥文件）
+
+Flags:
+`)
+	flag.PrintDefaults()
+}
+
+func main() {
+	os.Exit(run())
+}
+
+// run 解析命令并执行，返回进程退出码
+func run() int {
+	var (
+		dsn     string
+		repair  bool
+		asJSON  bool
+		keyFile string
+		timeout time.Duration
+	)
+	flag.StringVar(&dsn, "dsn", "", "Database connection string (defaults to DATABASE_URL)")
+	flag.BoolVar(&repair, "repair", false, "Repair discrepancies found by verify")
+	flag.BoolVar(&asJSON, "json", false, "Print the full report as JSON")
+	flag.StringVar(&keyFile, "keyfile", "", "Attachment key file for rotate-keys (defaults to ATTACHMENT_KEYFILE)")
+	flag.DurationVar(&timeout, "timeout", 10*time.Minute, "Command timeout")
+	flag.Usage = usage
+
+	if len(os.Args) < 2 {
+		usage()
+		return 2
+	}
+	command := os.Args[1]
+	if command != "verify" && command != "rotate-keys" {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", command)
+		usage()
+		return 2
+	}
+	flag.CommandLine.Parse(os.Args[2:])
+
+	if dsn == "" {
+		dsn = os.Getenv("DATABASE_URL")
+		if dsn == "" {
+			log.Print("DATABASE_URL environment variable is required")
+			return 1
+		}
+	}
+
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Error)})
+	if err != nil {
+		log.Printf("Failed to connect to database: %v", err)
+		return 1
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if command == "rotate-keys" {
+		if keyFile == "" {
+			keyFile = os.Getenv("ATTACHMENT_KEYFILE")
+		}
+		return runRotateKeys(ctx, db, keyFile)
+	}
+	return runVerify(ctx, db, repair, asJSON)
+}
+
+// runRotateKeys 重新包装附件的数据密钥，存在失败时返回非零退出码，此时不应移除旧主密钥
+func runRotateKeys(ctx context.Context, db *gorm.DB, keyFile string) int {
+	if keyFile == "" {
+		log.Print("-keyfile or ATTACHMENT_KEYFILE is required")
+		return 1
+	}
+	keys, err := services.LoadLocalKeyProvider(keyFile)
+	if err != nil {
+		log.Printf("Failed to load key file: %v", err)
+		return 1
+	}
+
+	// 重新包装只读写附件记录，不访问文件存储
+	attachmentService := services.NewTicketAttachmentService(db, nil)
+	attachmentService.SetCipher(services.NewAttachmentCipher(keys))
+	result, err := attachmentService.RewrapKeys(ctx, time.Now())
+	if err != nil {
+		log.Printf("Rewrap failed: %v", err)
+		return 1
+	}
+
+	fmt.Printf("primary key: %s\nrewrapped: %d\nfailed: %d\n", result.PrimaryKeyID, result.Rewrapped, result.Failed)
+	for _, message := range result.Errors {
+		fmt.Printf("    %s\n", message)
+	}
+	if result.Failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// runVerify 执行数据一致性检查，存在未修复的不一致时返回非零退出码
+func runVerify(ctx context.Context, db *gorm.DB, repair, asJSON bool) int {
+	report, err := services.NewConsistencyService(db).Verify(ctx, repair)
+	if err != nil {
+		log.Printf("Consistency check failed: %v", err)
+		return 1
+	}
+
+	if asJSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			log.Printf("Failed to encode report: %v", err)
+			return 1
+		}
+	} else {
+		for _, result := range report.Results {
+			status := "ok"
+			switch {
+			case result.Error != "":
+				status = "error: " + result.Error
+			case result.Discrepancies > 0:
+				status = fmt.Sprintf("%d discrepancies, %d repaired", result.Discrepancies, result.Repaired)
+			}
+			fmt.Printf("%-30s %s\n", result.Check, status)
+			for _, issue := range result.Issues {
+				if issue.Detail != "" {
+					fmt.Printf("    #%d %s\n", issue.EntityID, issue.Detail)
+				} else {
+					fmt.Printf("    #%d stored=%d actual=%d\n", issue.EntityID, issue.Stored, issue.Actual)
+				}
+			}
+		}
+		fmt.Printf("total: %d discrepancies, %d repaired\n", report.TotalDiscrepancies, report.TotalRepaired)
+	}
+
+	for _, result := range report.Results {
+		if result.Error != "" || result.Discrepancies > result.Repaired {
+			return 1
+		}
+	}
+	return 0
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
@@ -8,29 +9,47 @@ import (
 	"time"
 
 	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 
 	"gongdan-system/internal/auth"
+	"gongdan-system/internal/database"
 	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
 )
 
 var (
+	driver   string
 	dsn      string
 	verbose  bool
 	dropAll  bool
 	seedData bool
+	// 按全文搜索语言配置重建索引
+	searchIndexes bool
+	// JSON 列存储方式（text 或 jsonb）
+	jsonStorage string
 )
 
 func init() {
 	// 从环境变量或命令行参数获取数据库连接
-	flag.StringVar(&dsn, "dsn", "", "Database connection string")
+	flag.StringVar(&driver, "driver", getEnv("DB_DRIVER", "postgres"), "Database driver: postgres or sqlite")
+	flag.StringVar(&dsn, "dsn", "", "Database connection string (SQLite: database file path)")
 	flag.BoolVar(&verbose, "v", false, "Verbose output")
 	flag.BoolVar(&dropAll, "drop", false, "Drop all tables before migration")
 	flag.BoolVar(&seedData, "seed", false, "Seed initial data")
+	flag.BoolVar(&searchIndexes, "search-indexes", false, "Rebuild full-text search indexes using the configured search language")
+	flag.StringVar(&jsonStorage, "json-storage", os.Getenv("DB_JSON_STORAGE"), "Storage for JSON columns: text or jsonb (converts columns and manages GIN indexes)")
 	flag.Parse()
 
+	if driver != "postgres" && driver != "sqlite" {
+		log.Fatalf("Unsupported database driver %q, expected postgres or sqlite", driver)
+	}
+
 	// 如果没有提供DSN，从环境变量读取
+	if dsn == "" && driver == "sqlite" {
+		dsn = getEnv("DB_SQLITE_PATH", "data/gongdan.db")
+	}
 	if dsn == "" {
 		dsn = os.Getenv("DATABASE_URL")
 		if dsn == "" {
@@ -39,9 +58,20 @@ func init() {
 	}
 }
 
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
 func main() {
 	log.Println("🚀 Starting database migration...")
 
+	if err := models.SetJSONStorage(jsonStorage); err != nil {
+		log.Fatalf("Invalid JSON storage: %v", err)
+	}
+
 	// 配置GORM
 	config := &gorm.Config{
 		DisableForeignKeyConstraintWhenMigrating: false, // 启用外键约束
@@ -56,7 +86,11 @@ func main() {
 	}
 
 	// 连接数据库
-	db, err := gorm.Open(postgres.Open(dsn), config)
+	dialector := postgres.Open(dsn)
+	if driver == "sqlite" {
+		dialector = sqlite.Open("file:" + dsn + "?_busy_timeout=5000&_foreign_keys=on")
+	}
+	db, err := gorm.Open(dialector, config)
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
@@ -78,18 +112,45 @@ func main() {
 		dropAllTables(db)
 	}
 
+	// 切换 JSON 列存储方式，需在模型迁移前执行
+	log.Println("🧾 Migrating JSON column storage...")
+	if executed, err := services.NewJSONStorageService(db).Migrate(context.Background()); err != nil {
+		log.Fatalf("JSON column storage migration failed: %v", err)
+	} else {
+		log.Printf("✅ Executed %d JSON storage statements", len(executed))
+	}
+
 	// 执行迁移
 	log.Println("📦 Running auto migration...")
 	if err := runMigration(db); err != nil {
 		log.Fatalf("Migration failed: %v", err)
 	}
 
+	// 补全历史工单的影响/紧急程度
+	if err := database.BackfillTicketImpactUrgency(db); err != nil {
+		log.Printf("Warning: impact/urgency backfill failed: %v", err)
+	}
+	if err := database.BackfillCommentVisibility(db); err != nil {
+		log.Printf("Warning: comment visibility backfill failed: %v", err)
+	}
+
 	// 创建索引
 	log.Println("🔍 Creating indexes...")
 	if err := createIndexes(db); err != nil {
 		log.Printf("Warning: Some indexes may have failed: %v", err)
 	}
 
+	// 按语言配置迁移全文索引
+	if searchIndexes {
+		log.Println("🔤 Migrating full-text search indexes...")
+		executed, err := services.NewSearchConfigService(db).MigrateIndexes(context.Background())
+		if err != nil {
+			log.Printf("Warning: search index migration failed: %v", err)
+		} else {
+			log.Printf("✅ Executed %d search index statements", len(executed))
+		}
+	}
+
 	// 如果需要种子数据
 	if seedData {
 		log.Println("🌱 Seeding initial data...")
@@ -109,6 +170,7 @@ func runMigration(db *gorm.DB) error {
 		&models.User{},
 		&auth.RefreshToken{},
 		&auth.LoginAttempt{},
+		&auth.MagicLinkToken{},
 		&models.Category{},
 		&models.EmailConfig{},
 		&models.SystemConfig{},
@@ -123,6 +185,7 @@ func runMigration(db *gorm.DB) error {
 		&models.LoginHistory{},
 		&models.OTPTrustedDevice{},
 		&models.NotificationPreference{},
+		&models.Team{},
 		&models.Ticket{},
 	}
 
@@ -139,7 +202,63 @@ func runMigration(db *gorm.DB) error {
 		&models.EmailLog{},
 		&models.CleanupLog{},
 		&models.Notification{},
+		&models.NotificationArchive{},
 		&models.WebhookConfig{},
+		&models.InboundEmail{},
+		&models.EmailThreadMessage{},
+		&models.InboxBlocklistEntry{},
+		&models.TicketChangeProposal{},
+		&models.KBArticle{},
+		&models.KBArticleTicketLink{},
+		&models.AutomationRuleRevision{},
+		&models.AssignmentDelegation{},
+		&models.DelegatedTicket{},
+		&models.TicketCommentDraft{},
+		&models.TicketReplyLock{},
+		&models.IntakeQuarantineItem{},
+		&models.UserBulkJob{},
+		&models.ChatUserMapping{},
+		&models.ChatTicketThread{},
+		&models.TicketAccessLog{},
+		&models.AnalyticsExportJob{},
+		&models.AccountDeletionRequest{},
+		&models.SLAContract{},
+		&models.TicketChecklistItem{},
+		&models.PushSubscription{},
+		&models.TicketBulkCommentJob{},
+		&models.QuotaAlert{},
+		&models.SyncTombstone{},
+		&models.CommentTranslation{},
+		&models.TranslationPreference{},
+		&models.TicketWarRoom{},
+		&models.TicketCallLog{},
+		&models.SMSMessage{},
+		&models.EmailAddressStatus{},
+		&models.EmailDeliveryEvent{},
+		&models.ScriptHook{},
+		&models.ScriptHookLog{},
+		&models.PrefillLink{},
+		&models.TicketClassification{},
+		&models.UserInvitation{},
+		&models.ExternalReference{},
+		&models.PendingUpload{},
+		&models.NotificationDelivery{},
+		&models.TicketHandoffSchedule{},
+		&models.TicketHandoff{},
+		&models.TicketHandoffItem{},
+		&models.SLAWarning{},
+		&models.TicketReminder{},
+		&models.UserFieldDefinition{},
+		&models.UserFieldValue{},
+		&models.UserSkill{},
+		&models.AutomationSimulationJob{},
+		&models.TicketArchiveJob{},
+		&models.LoginIPBan{},
+		&models.TicketSummary{},
+		&models.TicketBillingReference{},
+		&models.BillingPeriodLock{},
+		&models.CategoryMembership{},
+		&models.DownloadArtifact{},
 	}
 
 	// 5. FE008 自动化相关表
@@ -274,11 +393,17 @@ func dropAllTables(db *gorm.DB) {
 		"users",
 	}
 
-	// 禁用外键约束
-	db.Exec("SET session_replication_role = 'replica';")
+	// 禁用外键约束，SQLite 不支持 CASCADE
+	dropSQL := "DROP TABLE IF EXISTS %s CASCADE"
+	if driver == "sqlite" {
+		db.Exec("PRAGMA foreign_keys = OFF;")
+		dropSQL = "DROP TABLE IF EXISTS %s"
+	} else {
+		db.Exec("SET session_replication_role = 'replica';")
+	}
 
 	for _, table := range tables {
-		if err := db.Exec(fmt.Sprintf("DROP TABLE IF EXISTS %s CASCADE", table)).Error; err != nil {
+		if err := db.Exec(fmt.Sprintf(dropSQL, table)).Error; err != nil {
 			log.Printf("  ⚠️  Failed to drop table %s: %v", table, err)
 		} else if verbose {
 			log.Printf("  ✓ Dropped table %s", table)
@@ -286,7 +411,11 @@ func dropAllTables(db *gorm.DB) {
 	}
 
 	// 重新启用外键约束
-	db.Exec("SET session_replication_role = 'origin';")
+	if driver == "sqlite" {
+		db.Exec("PRAGMA foreign_keys = ON;")
+	} else {
+		db.Exec("SET session_replication_role = 'origin';")
+	}
 }
 
 // seedInitialData 种子数据
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/rand"
 	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/base64"
 	"encoding/hex"
 	"errors"
@@ -28,11 +29,29 @@ var (
 	ErrEmailNotVerified   = errors.New("email not verified")
 	ErrAccountLocked      = errors.New("account locked")
 	ErrPasswordTooWeak    = errors.New("password too weak")
+	ErrOTPRequired        = errors.New("OTP code required")
+	ErrMagicLinkDisabled  = errors.New("magic link login is disabled")
+	ErrPhoneNotVerified   = errors.New("phone number not verified")
+	ErrSMSOTPNotEnabled   = errors.New("sms OTP not enabled")
+	ErrSessionIdleTimeout = errors.New("session expired due to inactivity")
+	ErrOTPSetupRequired   = errors.New("second factor required by role policy")
+	ErrOTPPolicyRequired  = errors.New("second factor cannot be disabled for this role")
 )
 
+// PendingDeletionError 账户处于注销宽限期，登录被拦截，可通过恢复接口撤销注销
+type PendingDeletionError struct {
+	ScheduledAt time.Time
+}
+
+func (e *PendingDeletionError) Error() string {
+	return "account is pending deletion"
+}
+
 var (
 	defaultTrustedDeviceTTL        = 30 * 24 * time.Hour
 	defaultTrustedDeviceMaxPerUser = 5
+	defaultMagicLinkTTL            = 15 * time.Minute
+	defaultMagicLinkMaxPerHour     = 5
 )
 
 // UserRole 用户角色枚举
@@ -70,12 +89,21 @@ type User struct {
 	LockedUntil       *time.Time `json:"locked_until"`
 	OTPEnabled        bool       `json:"otp_enabled" gorm:"default:false"`
 	OTPSecret         string     `json:"-"`
+	SMSOTPEnabled     bool       `json:"sms_otp_enabled"`
+	Phone             string     `json:"phone"`
+	PhoneVerified     bool       `json:"phone_verified"`
+	PhoneVerifiedAt   *time.Time `json:"phone_verified_at"`
 	BackupCodes       string     `json:"-"`
 	PasswordChangedAt *time.Time `json:"password_changed_at"`
 	CreatedAt         time.Time  `json:"created_at"`
 	UpdatedAt         time.Time  `json:"updated_at"`
 }
 
+// SecondFactorEnabled 是否开启了登录第二因子（TOTP 或短信验证码）
+func (u *User) SecondFactorEnabled() bool {
+	return u.OTPEnabled || u.SMSOTPEnabled
+}
+
 // UserProfile 用户资料
 type UserProfile struct {
 	ID          uint      `json:"id" gorm:"primaryKey"`
@@ -107,6 +135,282 @@ type LoginAttempt struct {
 	User       *User     `json:"user,omitempty" gorm:"foreignKey:UserID"`
 }
 
+// loginSession 一次已通过身份验证的登录上下文，供密码登录与免密链接登录共用
+type loginSession struct {
+	email          string
+	ipAddress      string
+	userAgent      string
+	method         string
+	trustedDevice  *models.OTPTrustedDevice // 本次登录使用的可信设备，未使用时为 nil
+	otpValidated   bool
+	rememberDevice bool
+	deviceName     string
+}
+
+// resolveTrustedDevice 校验客户端提交的可信设备令牌，顺带吊销已过期的设备。
+// 角色的可信设备有效期缩短后，已有设备的到期时间同步收紧
+func (s *AuthService) resolveTrustedDevice(ctx context.Context, user *User, deviceToken string) (*models.OTPTrustedDevice, bool) {
+	if deviceToken == "" || s.trustedDeviceRepo == nil {
+		return nil, false
+	}
+	tokenHash := hashTrustedDeviceToken(deviceToken)
+	if tokenHash == "" {
+		return nil, false
+	}
+	device, err := s.trustedDeviceRepo.GetByTokenHash(ctx, tokenHash)
+	if err != nil || device == nil || device.UserID != user.ID {
+		return nil, false
+	}
+	if !device.LastUsedAt.IsZero() {
+		if maxExpiry := device.LastUsedAt.Add(trustedDeviceTTL(s.EffectiveSessionPolicy(ctx, user.Role))); device.ExpiresAt.After(maxExpiry) {
+			device.ExpiresAt = maxExpiry
+		}
+	}
+	if !device.Revoked && device.ExpiresAt.After(time.Now()) {
+		return device, true
+	}
+	if device.ExpiresAt.Before(time.Now()) && !device.Revoked {
+		device.Revoked = true
+		if updateErr := s.trustedDeviceRepo.Update(ctx, device); updateErr != nil {
+			fmt.Printf("Warning: failed to revoke expired trusted device: %v\n", updateErr)
+		}
+	}
+	return nil, false
+}
+
+// completeLogin 身份验证通过后签发令牌、记录登录并维护可信设备
+func (s *AuthService) completeLogin(ctx context.Context, user *User, session *loginSession) (*AuthResponse, error) {
+	// 注销宽限期内不签发令牌，由客户端引导用户恢复账户
+	if s.accountDeletion != nil {
+		scheduledAt, err := s.accountDeletion.PendingDeletion(ctx, user.ID)
+		if err != nil {
+			return nil, err
+		}
+		if scheduledAt != nil {
+			s.recordLoginHistoryFailure(ctx, user, session.ipAddress, session.userAgent, session.method, "pending deletion", models.LoginStatusBlocked)
+			return nil, &PendingDeletionError{ScheduledAt: *scheduledAt}
+		}
+	}
+
+	// 获取用户资料
+	profile, _ := s.profileRepo.GetByUserID(ctx, user.ID)
+
+	// 重置失败登录计数
+	s.userRepo.ResetFailedLogin(ctx, user.ID)
+
+	// 更新最后登录时间
+	now := time.Now()
+	policy := s.EffectiveSessionPolicy(ctx, user.Role)
+	deviceTTL := trustedDeviceTTL(policy)
+	maxTrustedDevices := policy.MaxTrustedDevices
+	otpSetupRequired := policy.RequireOTP && !user.SecondFactorEnabled()
+	s.userRepo.UpdateLastLogin(ctx, user.ID, now)
+
+	// 记录成功登录
+	s.recordLoginAttempt(ctx, &user.ID, session.email, session.ipAddress, session.userAgent, true, "")
+
+	// 生成令牌
+	sessionID, err := GenerateSecureToken(16)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate session id: %w", err)
+	}
+
+	accessToken, refreshToken, err := s.jwtManager.GenerateTokenPair(user.ID, user.Role, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate tokens: %w", err)
+	}
+
+	// 保存刷新令牌
+	if err := s.saveRefreshToken(ctx, user.ID, refreshToken, sessionID, session.ipAddress, session.userAgent, refreshTokenTTL(policy)); err != nil {
+		return nil, fmt.Errorf("failed to save refresh token: %w", err)
+	}
+
+	s.recordLoginHistorySuccess(ctx, user, session.ipAddress, session.userAgent, sessionID, now, session.method)
+
+	var trustedDeviceToken string
+	if s.trustedDeviceRepo != nil {
+		if trustedDevice := session.trustedDevice; trustedDevice != nil {
+			trustedDevice.LastUsedAt = now
+			trustedDevice.LastIP = session.ipAddress
+			trustedDevice.UserAgent = session.userAgent
+			if session.rememberDevice {
+				trustedDevice.ExpiresAt = now.Add(deviceTTL)
+				if session.deviceName != "" {
+					trustedDevice.DeviceName = session.deviceName
+				}
+			}
+			if err := s.trustedDeviceRepo.Update(ctx, trustedDevice); err != nil {
+				fmt.Printf("Warning: failed to update trusted device: %v\n", err)
+			}
+		} else if session.rememberDevice && !otpSetupRequired && (session.otpValidated || !user.SecondFactorEnabled()) {
+			deviceToken, tokenErr := GenerateSecureToken(32)
+			if tokenErr != nil {
+				fmt.Printf("Warning: failed to generate trusted device token: %v\n", tokenErr)
+			} else {
+				hash := hashTrustedDeviceToken(deviceToken)
+				device := &models.OTPTrustedDevice{
+					UserID:          user.ID,
+					DeviceTokenHash: hash,
+					DeviceName:      resolveTrustedDeviceName(session.deviceName, session.userAgent),
+					LastUsedAt:      now,
+					LastIP:          session.ipAddress,
+					UserAgent:       session.userAgent,
+					ExpiresAt:       now.Add(deviceTTL),
+				}
+				if err := s.trustedDeviceRepo.Create(ctx, device); err != nil {
+					fmt.Printf("Warning: failed to persist trusted device: %v\n", err)
+				} else {
+					trustedDeviceToken = deviceToken
+				}
+			}
+		}
+
+		if maxTrustedDevices > 0 {
+			s.enforceTrustedDeviceQuota(ctx, user.ID, maxTrustedDevices, now)
+		}
+	}
+
+	return &AuthResponse{
+		User:               s.buildUserInfo(user, profile),
+		AccessToken:        accessToken,
+		RefreshToken:       refreshToken,
+		ExpiresIn:          int64(s.config.AccessTokenExpire.Seconds()),
+		TokenType:          "Bearer",
+		TrustedDeviceToken: trustedDeviceToken,
+		OTPSetupRequired:   otpSetupRequired,
+		SessionIdle:        sessionIdleStatus(policy, now, now),
+	}, nil
+}
+
+// RequestMagicLink 申请免密登录链接。账户不存在或不可登录时同样返回成功，避免泄露账户信息
+func (s *AuthService) RequestMagicLink(ctx context.Context, email, ipAddress, userAgent string) error {
+	if !s.isMagicLinkEnabled() {
+		return ErrMagicLinkDisabled
+	}
+
+	// 登录失败次数过多时不再发放链接
+	if err := s.checkLoginAttempts(ctx, email, ipAddress); err != nil {
+		return err
+	}
+
+	// 按邮箱限流且在查询账户之前判断，超限时静默不发送，响应与账户是否存在无关
+	since := time.Now().Add(-time.Hour)
+	count, err := s.tokenRepo.CountMagicLinksSince(ctx, email, since)
+	if err != nil {
+		return fmt.Errorf("failed to count magic links: %w", err)
+	}
+	if count >= int64(s.getMagicLinkMaxPerHour()) {
+		s.emitAuthEvent("magic_link_request", "throttled", nil, email, ipAddress, userAgent, "")
+		return nil
+	}
+
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		return nil
+	}
+	// 被锁定、停用或未验证邮箱的账户不发送链接
+	if err := s.checkUserStatus(ctx, user); err != nil {
+		return nil
+	}
+
+	token, err := generateSecureToken(32)
+	if err != nil {
+		return fmt.Errorf("failed to generate magic link token: %w", err)
+	}
+	ttl := s.getMagicLinkTTL()
+	link := &MagicLinkToken{
+		UserID:    user.ID,
+		Email:     user.Email,
+		Token:     hashOneTimeToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+		IPAddress: ipAddress,
+		UserAgent: truncateUserAgent(userAgent),
+	}
+	if err := s.tokenRepo.CreateMagicLink(ctx, link); err != nil {
+		return fmt.Errorf("failed to create magic link: %w", err)
+	}
+
+	if err := s.emailService.SendMagicLinkEmail(ctx, user.Email, token, ttl); err != nil {
+		return fmt.Errorf("failed to send magic link email: %w", err)
+	}
+	return nil
+}
+
+// VerifyMagicLink 使用免密登录链接换取令牌。
+// 账户锁定与失败次数限制同密码登录；开启OTP且设备不可信时需附带验证码，验证码缺失或错误时链接不会被消耗
+func (s *AuthService) VerifyMagicLink(ctx context.Context, req *MagicLinkVerifyRequest, ipAddress, userAgent string) (*AuthResponse, error) {
+	if !s.isMagicLinkEnabled() {
+		return nil, ErrMagicLinkDisabled
+	}
+	if req.Token == "" {
+		return nil, ErrInvalidToken
+	}
+
+	tokenHash := hashOneTimeToken(req.Token)
+	link, err := s.tokenRepo.GetMagicLink(ctx, tokenHash)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	if subtle.ConstantTimeCompare([]byte(link.Token), []byte(tokenHash)) != 1 ||
+		link.Used || time.Now().After(link.ExpiresAt) {
+		return nil, ErrInvalidToken
+	}
+
+	user, err := s.userRepo.GetByID(ctx, link.UserID)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if err := s.checkLoginAttempts(ctx, user.Email, ipAddress); err != nil {
+		s.recordLoginAttempt(ctx, &user.ID, user.Email, ipAddress, userAgent, false, err.Error())
+		return nil, err
+	}
+
+	trustedDevice, deviceTrusted := s.resolveTrustedDevice(ctx, user, req.DeviceToken)
+	method := "magic_link"
+	if deviceTrusted {
+		method = "magic_link+trusted"
+	} else if user.SecondFactorEnabled() {
+		method = "magic_link+otp"
+	}
+
+	if statusErr := s.checkUserStatus(ctx, user); statusErr != nil {
+		s.recordLoginAttempt(ctx, &user.ID, user.Email, ipAddress, userAgent, false, statusErr.Error())
+		s.recordLoginHistoryFailure(ctx, user, ipAddress, userAgent, method, statusErr.Error(), loginStatusFromError(statusErr))
+		return nil, statusErr
+	}
+
+	otpValidated := deviceTrusted
+	if user.SecondFactorEnabled() && !deviceTrusted {
+		if req.OTPCode == "" {
+			return nil, ErrOTPRequired
+		}
+		if err := s.verifySecondFactor(ctx, user, req.OTPCode, req.OTPMethod); err != nil {
+			s.userRepo.IncrementFailedLogin(ctx, user.ID)
+			s.recordLoginAttempt(ctx, &user.ID, user.Email, ipAddress, userAgent, false, "invalid OTP")
+			s.recordLoginHistoryFailure(ctx, user, ipAddress, userAgent, method, "invalid OTP", models.LoginStatusFailed)
+			return nil, err
+		}
+		otpValidated = true
+	}
+
+	// 原子地消耗链接，并发请求只有一个能成功
+	if err := s.tokenRepo.UseMagicLink(ctx, link.ID, ipAddress, userAgent); err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	return s.completeLogin(ctx, user, &loginSession{
+		email:          user.Email,
+		ipAddress:      ipAddress,
+		userAgent:      userAgent,
+		method:         method,
+		trustedDevice:  trustedDevice,
+		otpValidated:   otpValidated,
+		rememberDevice: req.RememberDevice,
+		deviceName:     req.DeviceName,
+	})
+}
+
 // RefreshToken 刷新令牌
 type RefreshToken struct {
 	ID        uint       `json:"id" gorm:"primaryKey"`
@@ -124,30 +428,58 @@ type RefreshToken struct {
 
 // EmailVerification 邮箱验证
 type EmailVerification struct {
-	ID        uint       `json:"id" gorm:"primaryKey"`
-	UserID    uint       `json:"user_id" gorm:"not null;index"`
-	Email     string     `json:"email" gorm:"size:255;not null"`
-	Token     string     `json:"token" gorm:"size:255;not null;uniqueIndex"`
-	Used      bool       `json:"used" gorm:"default:false"`
-	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
-	UsedAt    *time.Time `json:"used_at"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt time.Time  `json:"updated_at"`
-	User      User       `json:"user" gorm:"foreignKey:UserID"`
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	UserID        uint       `json:"user_id" gorm:"not null;index"`
+	Email         string     `json:"email" gorm:"size:255;not null"`
+	Token         string     `json:"-" gorm:"size:255;not null;uniqueIndex"` // 令牌的SHA-256哈希，原始令牌只出现在邮件中
+	Used          bool       `json:"used" gorm:"default:false"`
+	ExpiresAt     time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt        *time.Time `json:"used_at"`
+	UsedIP        string     `json:"used_ip" gorm:"size:45"`
+	UsedUserAgent string     `json:"used_user_agent" gorm:"size:500"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	User          User       `json:"user" gorm:"foreignKey:UserID"`
 }
 
 // PasswordReset 密码重置
 type PasswordReset struct {
-	ID        uint       `json:"id" gorm:"primaryKey"`
-	UserID    uint       `json:"user_id" gorm:"not null;index"`
-	Email     string     `json:"email" gorm:"size:255;not null"`
-	Token     string     `json:"token" gorm:"size:255;not null;uniqueIndex"`
-	Used      bool       `json:"used" gorm:"default:false"`
-	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
-	UsedAt    *time.Time `json:"used_at"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt time.Time  `json:"updated_at"`
-	User      User       `json:"user" gorm:"foreignKey:UserID"`
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	UserID        uint       `json:"user_id" gorm:"not null;index"`
+	Email         string     `json:"email" gorm:"size:255;not null"`
+	Token         string     `json:"-" gorm:"size:255;not null;uniqueIndex"` // 令牌的SHA-256哈希，原始令牌只出现在邮件中
+	Used          bool       `json:"used" gorm:"default:false"`
+	ExpiresAt     time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt        *time.Time `json:"used_at"`
+	IPAddress     string     `json:"ip_address" gorm:"size:45"`
+	UserAgent     string     `json:"user_agent" gorm:"size:500"`
+	UsedIP        string     `json:"used_ip" gorm:"size:45"`
+	UsedUserAgent string     `json:"used_user_agent" gorm:"size:500"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	User          User       `json:"user" gorm:"foreignKey:UserID"`
+}
+
+// MagicLinkToken 免密登录链接
+type MagicLinkToken struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	UserID        uint       `json:"user_id" gorm:"not null;index"`
+	Email         string     `json:"email" gorm:"size:255;not null"`
+	Token         string     `json:"-" gorm:"size:255;not null;uniqueIndex"` // 令牌的SHA-256哈希，原始令牌只出现在邮件中
+	Used          bool       `json:"used" gorm:"default:false"`
+	ExpiresAt     time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt        *time.Time `json:"used_at"`
+	IPAddress     string     `json:"ip_address" gorm:"size:45"`
+	UserAgent     string     `json:"user_agent" gorm:"size:500"`
+	UsedIP        string     `json:"used_ip" gorm:"size:45"`
+	UsedUserAgent string     `json:"used_user_agent" gorm:"size:500"`
+	CreatedAt     time.Time  `json:"created_at" gorm:"index"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (MagicLinkToken) TableName() string {
+	return "magic_link_tokens"
 }
 
 // OTPCode OTP验证码
@@ -183,6 +515,7 @@ type LoginRequest struct {
 	DeviceToken    string `json:"device_token,omitempty"`
 	RememberDevice bool   `json:"remember_device,omitempty"`
 	DeviceName     string `json:"device_name,omitempty"`
+	OTPMethod      string `json:"otp_method,omitempty"` // totp 或 sms，为空时开启了TOTP则按TOTP校验
 }
 
 // RefreshTokenRequest 刷新令牌请求
@@ -207,6 +540,38 @@ type ResetPasswordRequest struct {
 	NewPassword string `json:"new_password" validate:"required,min=8"`
 }
 
+// MagicLinkRequest 申请免密登录链接请求
+type MagicLinkRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// MagicLinkVerifyRequest 使用免密登录链接换取令牌，开启OTP且设备不可信时需提供 otp_code
+type MagicLinkVerifyRequest struct {
+	Token          string `json:"token"`
+	OTPCode        string `json:"otp_code,omitempty"`
+	OTPMethod      string `json:"otp_method,omitempty"`
+	DeviceToken    string `json:"device_token,omitempty"`
+	RememberDevice bool   `json:"remember_device,omitempty"`
+	DeviceName     string `json:"device_name,omitempty"`
+}
+
+// DeleteAccountRequest 申请注销账户请求
+type DeleteAccountRequest struct {
+	Password string `json:"password" validate:"required"`
+	Reason   string `json:"reason,omitempty" validate:"max=500"`
+}
+
+// ConfirmAccountDeletionRequest 确认注销请求
+type ConfirmAccountDeletionRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// RestoreAccountRequest 宽限期内恢复账户请求
+type RestoreAccountRequest struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
 // ResendVerificationRequest 重发验证邮件请求
 type ResendVerificationRequest struct {
 	Email string `json:"email" validate:"required,email"`
@@ -245,6 +610,10 @@ type AuthResponse struct {
 	ExpiresIn          int64     `json:"expires_in"`
 	TokenType          string    `json:"token_type"`
 	TrustedDeviceToken string    `json:"trusted_device_token,omitempty"`
+	// 角色要求第二因子但用户尚未启用：本次登录的刷新令牌不可用，需在访问令牌过期前完成设置
+	OTPSetupRequired bool `json:"otp_setup_required,omitempty"`
+	// 角色设置了空闲超时时返回：超过空闲时间没有任何请求后不能再刷新令牌
+	SessionIdle *models.SessionIdleStatus `json:"session_idle,omitempty"`
 }
 
 // UserInfo 用户信息
@@ -256,6 +625,7 @@ type UserInfo struct {
 	Status        UserStatus   `json:"status"`
 	EmailVerified bool         `json:"email_verified"`
 	OTPEnabled    bool         `json:"otp_enabled"`
+	SMSOTPEnabled bool         `json:"sms_otp_enabled"`
 	LastLoginAt   *time.Time   `json:"last_login_at"`
 	Profile       *UserProfile `json:"profile,omitempty"`
 }
@@ -305,16 +675,24 @@ type TokenRepository interface {
 	CleanupExpiredTokens(ctx context.Context) error
 	// 创建邮箱验证
 	CreateEmailVerification(ctx context.Context, verification *EmailVerification) error
-	// 获取邮箱验证
-	GetEmailVerification(ctx context.Context, token string) (*EmailVerification, error)
-	// 使用邮箱验证
-	UseEmailVerification(ctx context.Context, token string) error
+	// 根据令牌哈希获取未使用且未过期的邮箱验证
+	GetEmailVerification(ctx context.Context, tokenHash string) (*EmailVerification, error)
+	// 原子地标记邮箱验证为已使用，已被使用时返回 ErrInvalidToken
+	UseEmailVerification(ctx context.Context, id uint, ipAddress, userAgent string) error
 	// 创建密码重置
 	CreatePasswordReset(ctx context.Context, reset *PasswordReset) error
-	// 获取密码重置
-	GetPasswordReset(ctx context.Context, token string) (*PasswordReset, error)
-	// 使用密码重置
-	UsePasswordReset(ctx context.Context, token string) error
+	// 根据令牌哈希获取未使用且未过期的密码重置
+	GetPasswordReset(ctx context.Context, tokenHash string) (*PasswordReset, error)
+	// 原子地标记密码重置为已使用，已被使用时返回 ErrInvalidToken
+	UsePasswordReset(ctx context.Context, id uint, ipAddress, userAgent string) error
+	// 创建免密登录链接
+	CreateMagicLink(ctx context.Context, link *MagicLinkToken) error
+	// 根据令牌哈希获取未使用且未过期的免密登录链接
+	GetMagicLink(ctx context.Context, tokenHash string) (*MagicLinkToken, error)
+	// 原子地标记免密登录链接为已使用，已被使用时返回 ErrInvalidToken
+	UseMagicLink(ctx context.Context, id uint, ipAddress, userAgent string) error
+	// 统计某邮箱在某时间之后申请的免密登录链接数量
+	CountMagicLinksSince(ctx context.Context, email string, since time.Time) (int64, error)
 
 	CreateOTPCode(ctx context.Context, otp *OTPCode) error
 	GetOTPCode(ctx context.Context, userID uint, code string) (*OTPCode, error)
@@ -336,6 +714,9 @@ type LoginHistoryRepository interface {
 	RefreshSession(ctx context.Context, userID uint, sessionID, ipAddress, userAgent string, at time.Time) error
 	EndSession(ctx context.Context, userID uint, sessionID string, status models.LoginStatus, reason string, at time.Time) error
 	EndAllSessions(ctx context.Context, userID uint, status models.LoginStatus, reason string, at time.Time) error
+	HasSuccessfulLoginFromDevice(ctx context.Context, userID uint, userAgent string) (bool, error)
+	GetSession(ctx context.Context, userID uint, sessionID string) (*models.LoginHistory, error)
+	TouchSession(ctx context.Context, userID uint, sessionID string, at time.Time) error
 }
 
 // TrustedDeviceRepository 可信设备仓库接口
@@ -352,6 +733,10 @@ type EmailService interface {
 	SendPasswordResetEmail(ctx context.Context, email, token string) error
 	SendWelcomeEmail(ctx context.Context, email, username string) error
 	SendOTPEmail(ctx context.Context, email, code string) error
+	SendPasswordResetAlertEmail(ctx context.Context, email, ipAddress, userAgent string, at time.Time) error
+	SendMagicLinkEmail(ctx context.Context, email, token string, ttl time.Duration) error
+	SendAccountDeletionEmail(ctx context.Context, email, token string, graceDays int) error
+	SendInvitationEmail(ctx context.Context, email, token, role string, ttl time.Duration) error
 }
 
 // OTPService OTP服务接口
@@ -426,6 +811,14 @@ type AuthService struct {
 	passwordService    PasswordService
 	jwtManager         JWTManager
 	config             *AuthConfig
+	auditForwarder     *services.AuditForwarder
+	securityAudit      *services.AdminAuditService
+	accountDeletion    *services.AccountDeletionService
+	smsOTP             *services.SMSOTPService
+	sessionPolicy      *services.SessionPolicyService
+	invitations        *services.UserInvitationService
+	loginIPGuard       *services.LoginIPGuardService
+	activity           sessionActivityTracker
 }
 
 // AuthConfig 认证配置
@@ -447,7 +840,7 @@ type AuthConfig struct {
 
 // JWTManager JWT管理器接口
 type JWTManager interface {
-	GenerateTokenPair(userID uint, role UserRole) (accessToken, refreshToken string, err error)
+	GenerateTokenPair(userID uint, role UserRole, sessionID string) (accessToken, refreshToken string, err error)
 	VerifyAccessToken(token string) (*Claims, error)
 	VerifyRefreshToken(token string) (*Claims, error)
 	RevokeToken(token string) error
@@ -462,6 +855,8 @@ type Claims struct {
 	Exp    int64    `json:"exp"`
 	Iat    int64    `json:"iat"`
 	Jti    string   `json:"jti"`
+	// SessionID 登录会话ID
+	SessionID string `json:"sid,omitempty"`
 }
 
 // NewAuthService 创建认证服务
@@ -605,19 +1000,19 @@ func (s *AuthService) Register(ctx context.Context, req *RegisterRequest, ipAddr
 	}
 
 	// 生成令牌
-	accessToken, refreshToken, err := s.jwtManager.GenerateTokenPair(user.ID, user.Role)
+	sessionID, err := GenerateSecureToken(16)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate tokens: %w", err)
+		return nil, fmt.Errorf("failed to generate session id: %w", err)
 	}
 
-	sessionID, err := GenerateSecureToken(16)
+	accessToken, refreshToken, err := s.jwtManager.GenerateTokenPair(user.ID, user.Role, sessionID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate session id: %w", err)
+		return nil, fmt.Errorf("failed to generate tokens: %w", err)
 	}
 	loginTime := time.Now()
 
 	// 保存刷新令牌
-	if err := s.saveRefreshToken(ctx, user.ID, refreshToken, sessionID, ipAddress, userAgent); err != nil {
+	if err := s.saveRefreshToken(ctx, user.ID, refreshToken, sessionID, ipAddress, userAgent, refreshTokenTTL(s.EffectiveSessionPolicy(ctx, user.Role))); err != nil {
 		return nil, fmt.Errorf("failed to save refresh token: %w", err)
 	}
 
@@ -635,7 +1030,7 @@ func (s *AuthService) Register(ctx context.Context, req *RegisterRequest, ipAddr
 // Login 用户登录
 func (s *AuthService) Login(ctx context.Context, req *LoginRequest, ipAddress, userAgent string) (*AuthResponse, error) {
 	// 检查最近的失败登录次数
-	if err := s.checkLoginAttempts(ctx, req.Email); err != nil {
+	if err := s.checkLoginAttempts(ctx, req.Email, ipAddress); err != nil {
 		s.recordLoginAttempt(ctx, nil, req.Email, ipAddress, userAgent, false, err.Error())
 		return nil, err
 	}
@@ -647,27 +1042,7 @@ func (s *AuthService) Login(ctx context.Context, req *LoginRequest, ipAddress, u
 		return nil, ErrInvalidCredentials
 	}
 
-	var (
-		trustedDevice *models.OTPTrustedDevice
-		deviceTrusted bool
-	)
-
-	if req.DeviceToken != "" && s.trustedDeviceRepo != nil {
-		tokenHash := hashTrustedDeviceToken(req.DeviceToken)
-		if tokenHash != "" {
-			if device, deviceErr := s.trustedDeviceRepo.GetByTokenHash(ctx, tokenHash); deviceErr == nil && device != nil {
-				if device.UserID == user.ID && !device.Revoked && device.ExpiresAt.After(time.Now()) {
-					trustedDevice = device
-					deviceTrusted = true
-				} else if device.UserID == user.ID && device.ExpiresAt.Before(time.Now()) && !device.Revoked {
-					device.Revoked = true
-					if updateErr := s.trustedDeviceRepo.Update(ctx, device); updateErr != nil {
-						fmt.Printf("Warning: failed to revoke expired trusted device: %v\n", updateErr)
-					}
-				}
-			}
-		}
-	}
+	trustedDevice, deviceTrusted := s.resolveTrustedDevice(ctx, user, req.DeviceToken)
 
 	otpValidated := deviceTrusted
 
@@ -688,117 +1063,100 @@ func (s *AuthService) Login(ctx context.Context, req *LoginRequest, ipAddress, u
 		return nil, ErrInvalidCredentials
 	}
 
-	// 检查是否需要OTP验证
-	if user.OTPEnabled && !deviceTrusted {
+	// 检查是否需要OTP验证（TOTP 或短信验证码）
+	if user.SecondFactorEnabled() && !deviceTrusted {
 		if req.OTPCode == "" {
 			method := determineLoginMethod(user, req, deviceTrusted, otpValidated)
 			s.recordLoginAttempt(ctx, &user.ID, req.Email, ipAddress, userAgent, false, "otp required")
 			s.recordLoginHistoryFailure(ctx, user, ipAddress, userAgent, method, "otp required", models.LoginStatusFailed)
-			return nil, errors.New("OTP code required")
+			return nil, ErrOTPRequired
 		}
 
-		if !s.otpService.VerifyCode(user.OTPSecret, req.OTPCode) {
-			// 检查是否是备用码
-			if !s.verifyBackupCode(user, req.OTPCode) {
-				method := determineLoginMethod(user, req, deviceTrusted, false)
-				s.recordLoginAttempt(ctx, &user.ID, req.Email, ipAddress, userAgent, false, "invalid OTP")
-				s.recordLoginHistoryFailure(ctx, user, ipAddress, userAgent, method, "invalid OTP", models.LoginStatusFailed)
-				return nil, ErrInvalidOTP
-			}
-			// 使用备用码后持久化剩余的备用码集合
-			if err := s.userRepo.Update(ctx, user); err != nil {
-				fmt.Printf("Warning: failed to persist backup code usage for user %d: %v\n", user.ID, err)
-			}
-			otpValidated = true
+		if err := s.verifySecondFactor(ctx, user, req.OTPCode, req.OTPMethod); err != nil {
+			method := determineLoginMethod(user, req, deviceTrusted, false)
+			s.recordLoginAttempt(ctx, &user.ID, req.Email, ipAddress, userAgent, false, "invalid OTP")
+			s.recordLoginHistoryFailure(ctx, user, ipAddress, userAgent, method, "invalid OTP", models.LoginStatusFailed)
+			return nil, err
 		}
 		otpValidated = true
 	}
 
-	// 获取用户资料
-	profile, _ := s.profileRepo.GetByUserID(ctx, user.ID)
-
-	// 重置失败登录计数
-	s.userRepo.ResetFailedLogin(ctx, user.ID)
-
-	// 更新最后登录时间
-	now := time.Now()
-	trustedDeviceTTL := s.getTrustedDeviceTTL()
-	maxTrustedDevices := s.getTrustedDeviceLimit()
-	s.userRepo.UpdateLastLogin(ctx, user.ID, now)
+	return s.completeLogin(ctx, user, &loginSession{
+		email:          req.Email,
+		ipAddress:      ipAddress,
+		userAgent:      userAgent,
+		method:         determineLoginMethod(user, req, deviceTrusted, otpValidated),
+		trustedDevice:  trustedDevice,
+		otpValidated:   otpValidated,
+		rememberDevice: req.RememberDevice,
+		deviceName:     req.DeviceName,
+	})
+}
 
-	// 记录成功登录
-	s.recordLoginAttempt(ctx, &user.ID, req.Email, ipAddress, userAgent, true, "")
+// RequestAccountDeletion 校验密码后发起注销，确认链接发送到账户邮箱
+func (s *AuthService) RequestAccountDeletion(ctx context.Context, userID uint, req *DeleteAccountRequest, ipAddress, userAgent string) error {
+	if s.accountDeletion == nil {
+		return services.ErrAccountDeletionNotAllowed
+	}
 
-	// 生成令牌
-	accessToken, refreshToken, err := s.jwtManager.GenerateTokenPair(user.ID, user.Role)
+	user, err := s.userRepo.GetByID(ctx, userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate tokens: %w", err)
+		return err
+	}
+	if err := s.passwordService.VerifyPassword(user.PasswordHash, req.Password); err != nil {
+		return ErrInvalidCredentials
 	}
 
-	sessionID, err := GenerateSecureToken(16)
+	request, token, err := s.accountDeletion.CreateRequest(ctx, userID, req.Reason, ipAddress)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate session id: %w", err)
+		return err
 	}
-
-	// 保存刷新令牌
-	if err := s.saveRefreshToken(ctx, user.ID, refreshToken, sessionID, ipAddress, userAgent); err != nil {
-		return nil, fmt.Errorf("failed to save refresh token: %w", err)
+	if err := s.emailService.SendAccountDeletionEmail(ctx, user.Email, token, s.accountDeletion.GraceDays()); err != nil {
+		return fmt.Errorf("failed to send account deletion email: %w", err)
 	}
+	s.emitAuthEvent("account_deletion_request", "success", &user.ID, user.Email, ipAddress, userAgent, fmt.Sprintf("request_id=%d", request.ID))
+	return nil
+}
 
-	loginMethod := determineLoginMethod(user, req, deviceTrusted, otpValidated)
-	s.recordLoginHistorySuccess(ctx, user, ipAddress, userAgent, sessionID, now, loginMethod)
+// ConfirmAccountDeletion 确认注销，账户进入宽限期，所有会话与可信设备立即失效
+func (s *AuthService) ConfirmAccountDeletion(ctx context.Context, req *ConfirmAccountDeletionRequest, ipAddress, userAgent string) (*time.Time, error) {
+	if s.accountDeletion == nil {
+		return nil, services.ErrAccountDeletionNotFound
+	}
+	request, err := s.accountDeletion.Confirm(ctx, req.Token)
+	if err != nil {
+		return nil, err
+	}
+	s.emitAuthEvent("account_deletion_confirm", "success", &request.UserID, request.Email, ipAddress, userAgent, "")
+	return request.ScheduledAt, nil
+}
 
-	var trustedDeviceToken string
-	if s.trustedDeviceRepo != nil {
-		if deviceTrusted && trustedDevice != nil {
-			trustedDevice.LastUsedAt = now
-			trustedDevice.LastIP = ipAddress
-			trustedDevice.UserAgent = userAgent
-			if req.RememberDevice {
-				trustedDevice.ExpiresAt = now.Add(trustedDeviceTTL)
-				if req.DeviceName != "" {
-					trustedDevice.DeviceName = req.DeviceName
-				}
-			}
-			if err := s.trustedDeviceRepo.Update(ctx, trustedDevice); err != nil {
-				fmt.Printf("Warning: failed to update trusted device: %v\n", err)
-			}
-		} else if req.RememberDevice && (otpValidated || !user.OTPEnabled) {
-			deviceToken, tokenErr := GenerateSecureToken(32)
-			if tokenErr != nil {
-				fmt.Printf("Warning: failed to generate trusted device token: %v\n", tokenErr)
-			} else {
-				hash := hashTrustedDeviceToken(deviceToken)
-				device := &models.OTPTrustedDevice{
-					UserID:          user.ID,
-					DeviceTokenHash: hash,
-					DeviceName:      resolveTrustedDeviceName(req.DeviceName, userAgent),
-					LastUsedAt:      now,
-					LastIP:          ipAddress,
-					UserAgent:       userAgent,
-					ExpiresAt:       now.Add(trustedDeviceTTL),
-				}
-				if err := s.trustedDeviceRepo.Create(ctx, device); err != nil {
-					fmt.Printf("Warning: failed to persist trusted device: %v\n", err)
-				} else {
-					trustedDeviceToken = deviceToken
-				}
-			}
-		}
+// RestoreAccount 宽限期内凭邮箱和密码撤销注销，之后可正常登录
+func (s *AuthService) RestoreAccount(ctx context.Context, req *RestoreAccountRequest, ipAddress, userAgent string) error {
+	if s.accountDeletion == nil {
+		return services.ErrAccountDeletionNotFound
+	}
+	if err := s.checkLoginAttempts(ctx, req.Email, ipAddress); err != nil {
+		s.recordLoginAttempt(ctx, nil, req.Email, ipAddress, userAgent, false, err.Error())
+		return err
+	}
 
-		if maxTrustedDevices > 0 {
-			s.enforceTrustedDeviceQuota(ctx, user.ID, maxTrustedDevices, now)
-		}
+	user, err := s.userRepo.GetByEmail(ctx, req.Email)
+	if err != nil {
+		s.recordLoginAttempt(ctx, nil, req.Email, ipAddress, userAgent, false, "user not found")
+		return ErrInvalidCredentials
+	}
+	if err := s.passwordService.VerifyPassword(user.PasswordHash, req.Password); err != nil {
+		s.userRepo.IncrementFailedLogin(ctx, user.ID)
+		s.recordLoginAttempt(ctx, &user.ID, req.Email, ipAddress, userAgent, false, "invalid password")
+		return ErrInvalidCredentials
 	}
 
-	return &AuthResponse{
-		User:               s.buildUserInfo(user, profile),
-		AccessToken:        accessToken,
-		RefreshToken:       refreshToken,
-		ExpiresIn:          int64(s.config.AccessTokenExpire.Seconds()),
-		TokenType:          "Bearer",
-		TrustedDeviceToken: trustedDeviceToken,
-	}, nil
+	if err := s.accountDeletion.CancelDeletion(ctx, user.ID); err != nil {
+		return err
+	}
+	s.emitAuthEvent("account_restore", "success", &user.ID, user.Email, ipAddress, userAgent, "")
+	return nil
 }
 
 // RefreshToken 刷新令牌
@@ -814,6 +1172,11 @@ func (s *AuthService) RefreshToken(ctx context.Context, req *RefreshTokenRequest
 	if err != nil || tokenRecord.Revoked {
 		return nil, ErrInvalidToken
 	}
+	// 角色策略可能缩短了刷新令牌有效期，以数据库记录为准
+	now := time.Now()
+	if tokenRecord.ExpiresAt.Before(now) {
+		return nil, ErrTokenExpired
+	}
 	sessionID := tokenRecord.SessionID
 	if sessionID == "" {
 		if generatedSessionID, genErr := GenerateSecureToken(16); genErr == nil {
@@ -834,17 +1197,29 @@ func (s *AuthService) RefreshToken(ctx context.Context, req *RefreshTokenRequest
 		return nil, err
 	}
 
+	// 角色会话策略：空闲超时及第二因子要求
+	policy := s.EffectiveSessionPolicy(ctx, user.Role)
+	lastActivityAt := s.lastSessionActivity(ctx, user.ID, tokenRecord.SessionID, tokenRecord.CreatedAt)
+	if idle := sessionIdleStatus(policy, lastActivityAt, now); idle != nil && idle.Expired {
+		s.endPolicySession(ctx, user.ID, tokenRecord, "idle_timeout", now)
+		return nil, ErrSessionIdleTimeout
+	}
+	if policy.RequireOTP && !user.SecondFactorEnabled() {
+		s.endPolicySession(ctx, user.ID, tokenRecord, "otp_setup_required", now)
+		return nil, ErrOTPSetupRequired
+	}
+
 	// 撤销旧的刷新令牌
 	s.tokenRepo.RevokeRefreshToken(ctx, req.RefreshToken)
 
 	// 生成新的令牌对
-	accessToken, refreshToken, err := s.jwtManager.GenerateTokenPair(user.ID, user.Role)
+	accessToken, refreshToken, err := s.jwtManager.GenerateTokenPair(user.ID, user.Role, sessionID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate tokens: %w", err)
 	}
 
 	// 保存新的刷新令牌
-	if err := s.saveRefreshToken(ctx, user.ID, refreshToken, sessionID, ipAddress, userAgent); err != nil {
+	if err := s.saveRefreshToken(ctx, user.ID, refreshToken, sessionID, ipAddress, userAgent, refreshTokenTTL(policy)); err != nil {
 		return nil, fmt.Errorf("failed to save refresh token: %w", err)
 	}
 
@@ -863,6 +1238,7 @@ func (s *AuthService) RefreshToken(ctx context.Context, req *RefreshTokenRequest
 		RefreshToken: refreshToken,
 		ExpiresIn:    int64(s.config.AccessTokenExpire.Seconds()),
 		TokenType:    "Bearer",
+		SessionIdle:  sessionIdleStatus(policy, now, now),
 	}, nil
 }
 
@@ -889,6 +1265,9 @@ func (s *AuthService) Logout(ctx context.Context, refreshToken string) error {
 			fmt.Printf("Warning: failed to mark session logout: %v\n", err)
 		}
 	}
+	if userID != 0 {
+		s.emitAuthEvent("logout", "success", &userID, "", "", "", "")
+	}
 
 	return s.tokenRepo.RevokeRefreshToken(ctx, refreshToken)
 }
@@ -904,7 +1283,7 @@ func (s *AuthService) LogoutAll(ctx context.Context, userID uint) error {
 }
 
 // ForgotPassword 忘记密码
-func (s *AuthService) ForgotPassword(ctx context.Context, email string) error {
+func (s *AuthService) ForgotPassword(ctx context.Context, email, ipAddress, userAgent string) error {
 	// 查找用户
 	user, err := s.userRepo.GetByEmail(ctx, email)
 	if err != nil {
@@ -918,12 +1297,14 @@ func (s *AuthService) ForgotPassword(ctx context.Context, email string) error {
 		return fmt.Errorf("failed to generate reset token: %w", err)
 	}
 
-	// 创建密码重置记录
+	// 创建密码重置记录，仅保存令牌哈希
 	reset := &PasswordReset{
 		UserID:    user.ID,
 		Email:     email,
-		Token:     token,
-		ExpiresAt: time.Now().Add(s.config.PasswordResetExpire),
+		Token:     hashOneTimeToken(token),
+		ExpiresAt: time.Now().Add(s.getPasswordResetTTL()),
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
 	}
 
 	err = s.tokenRepo.CreatePasswordReset(ctx, reset)
@@ -941,18 +1322,20 @@ func (s *AuthService) ForgotPassword(ctx context.Context, email string) error {
 }
 
 // ResetPassword 重置密码
-func (s *AuthService) ResetPassword(ctx context.Context, token, newPassword string) error {
+func (s *AuthService) ResetPassword(ctx context.Context, token, newPassword, ipAddress, userAgent string) error {
 	// 验证令牌
-	reset, err := s.tokenRepo.GetPasswordReset(ctx, token)
+	tokenHash := hashOneTimeToken(token)
+	reset, err := s.tokenRepo.GetPasswordReset(ctx, tokenHash)
 	if err != nil {
 		return ErrInvalidToken
 	}
 
-	if reset.Used || time.Now().After(reset.ExpiresAt) {
+	if subtle.ConstantTimeCompare([]byte(reset.Token), []byte(tokenHash)) != 1 ||
+		reset.Used || time.Now().After(reset.ExpiresAt) {
 		return ErrInvalidToken
 	}
 
-	// 验证新密码
+	// 验证新密码（不合法时不消耗令牌）
 	err = s.passwordService.ValidatePassword(newPassword)
 	if err != nil {
 		return err
@@ -970,6 +1353,11 @@ func (s *AuthService) ResetPassword(ctx context.Context, token, newPassword stri
 		return fmt.Errorf("failed to hash password: %w", err)
 	}
 
+	// 先原子地消耗令牌，保证并发请求中只有一个能重置密码
+	if err := s.tokenRepo.UsePasswordReset(ctx, reset.ID, ipAddress, userAgent); err != nil {
+		return err
+	}
+
 	// 更新用户密码
 	user.PasswordHash = hashedPassword
 	user.PasswordChangedAt = timePtr(time.Now())
@@ -981,30 +1369,55 @@ func (s *AuthService) ResetPassword(ctx context.Context, token, newPassword stri
 		return fmt.Errorf("failed to update user: %w", err)
 	}
 
-	// 标记令牌为已使用
-	err = s.tokenRepo.UsePasswordReset(ctx, token)
-	if err != nil {
-		return fmt.Errorf("failed to mark token as used: %w", err)
-	}
-
 	// 撤销所有刷新令牌
 	_ = s.tokenRepo.RevokeAllUserTokens(ctx, user.ID)
 
+	// 从未登录过的设备重置密码时通知用户
+	s.notifyPasswordResetFromNewDevice(ctx, user, ipAddress, userAgent)
+	s.recordSecurityEvent(services.WithAuditScope(ctx, &user.ID, ipAddress, userAgent), "password_reset", user, "")
+
 	return nil
 }
 
+// notifyPasswordResetFromNewDevice 重置请求来自无成功登录记录的设备时发送安全提醒邮件
+func (s *AuthService) notifyPasswordResetFromNewDevice(ctx context.Context, user *User, ipAddress, userAgent string) {
+	if s.loginHistoryRepo == nil {
+		return
+	}
+
+	known, err := s.loginHistoryRepo.HasSuccessfulLoginFromDevice(ctx, user.ID, userAgent)
+	if err != nil {
+		fmt.Printf("Warning: failed to check known devices for user %d: %v\n", user.ID, err)
+		return
+	}
+	if known {
+		return
+	}
+
+	if err := s.emailService.SendPasswordResetAlertEmail(ctx, user.Email, ipAddress, userAgent, time.Now()); err != nil {
+		fmt.Printf("Warning: failed to send password reset alert to user %d: %v\n", user.ID, err)
+	}
+}
+
 // VerifyEmail 验证邮箱
-func (s *AuthService) VerifyEmail(ctx context.Context, token string) error {
+func (s *AuthService) VerifyEmail(ctx context.Context, token, ipAddress, userAgent string) error {
 	// 验证令牌
-	verification, err := s.tokenRepo.GetEmailVerification(ctx, token)
+	tokenHash := hashOneTimeToken(token)
+	verification, err := s.tokenRepo.GetEmailVerification(ctx, tokenHash)
 	if err != nil {
 		return ErrInvalidToken
 	}
 
-	if verification.Used || time.Now().After(verification.ExpiresAt) {
+	if subtle.ConstantTimeCompare([]byte(verification.Token), []byte(tokenHash)) != 1 ||
+		verification.Used || time.Now().After(verification.ExpiresAt) {
 		return ErrInvalidToken
 	}
 
+	// 原子地消耗令牌，防止重复使用
+	if err := s.tokenRepo.UseEmailVerification(ctx, verification.ID, ipAddress, userAgent); err != nil {
+		return err
+	}
+
 	// 获取用户
 	user, err := s.userRepo.GetByID(ctx, verification.UserID)
 	if err != nil {
@@ -1020,12 +1433,6 @@ func (s *AuthService) VerifyEmail(ctx context.Context, token string) error {
 		return fmt.Errorf("failed to update user: %w", err)
 	}
 
-	// 标记验证为已使用
-	err = s.tokenRepo.UseEmailVerification(ctx, token)
-	if err != nil {
-		return fmt.Errorf("failed to mark verification as used: %w", err)
-	}
-
 	// 发送欢迎邮件
 	_ = s.emailService.SendWelcomeEmail(ctx, user.Email, user.Username)
 
@@ -1132,6 +1539,7 @@ func (s *AuthService) ChangePassword(ctx context.Context, userID uint, currentPa
 
 	// 撤销所有刷新令牌（强制重新登录）
 	_ = s.tokenRepo.RevokeAllUserTokens(ctx, user.ID)
+	s.recordSecurityEvent(ctx, "password_change", user, "")
 
 	return nil
 }
@@ -1185,6 +1593,7 @@ func (s *AuthService) EnableOTP(ctx context.Context, userID uint, password strin
 	if err != nil {
 		return nil, fmt.Errorf("failed to update user: %w", err)
 	}
+	s.recordSecurityEvent(ctx, "otp_enabled", user, "")
 
 	return &OTPSetupResponse{
 		Secret:      secret,
@@ -1206,6 +1615,9 @@ func (s *AuthService) DisableOTP(ctx context.Context, userID uint, password stri
 	if err != nil {
 		return ErrInvalidCredentials
 	}
+	if err := s.checkSecondFactorRemoval(ctx, user, user.SMSOTPEnabled); err != nil {
+		return err
+	}
 
 	// 禁用OTP
 	user.OTPEnabled = false
@@ -1216,6 +1628,7 @@ func (s *AuthService) DisableOTP(ctx context.Context, userID uint, password stri
 	if err != nil {
 		return fmt.Errorf("failed to update user: %w", err)
 	}
+	s.recordSecurityEvent(ctx, "otp_disabled", user, "")
 
 	return nil
 }
@@ -1254,8 +1667,8 @@ func (s *AuthService) GenerateBackupCodes(ctx context.Context, userID uint) ([]s
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
-	// 检查是否启用OTP
-	if !user.OTPEnabled {
+	// 检查是否启用OTP（TOTP 或短信验证码）
+	if !user.SecondFactorEnabled() {
 		return nil, errors.New("OTP not enabled")
 	}
 
@@ -1278,7 +1691,14 @@ func (s *AuthService) GenerateBackupCodes(ctx context.Context, userID uint) ([]s
 
 // 辅助方法
 
-func (s *AuthService) checkLoginAttempts(ctx context.Context, email string) error {
+func (s *AuthService) checkLoginAttempts(ctx context.Context, email, ipAddress string) error {
+	// 同一IP失败次数过多时先于按邮箱的限制拦截，防止轮换邮箱绕过
+	if s.loginIPGuard != nil {
+		if err := s.loginIPGuard.Check(ctx, ipAddress); err != nil {
+			return err
+		}
+	}
+
 	since := time.Now().Add(-time.Hour) // 检查最近1小时的尝试
 	failedCount, err := s.loginAttemptRepo.GetRecentFailedAttempts(ctx, email, since)
 	if err != nil {
@@ -1331,14 +1751,81 @@ func (s *AuthService) recordLoginAttempt(ctx context.Context, userID *uint, emai
 		FailReason: failReason,
 	}
 	s.loginAttemptRepo.Create(ctx, attempt)
+
+	// 被IP封禁拦截的请求不再累计，避免封禁到期后立即再次封禁
+	if !success && s.loginIPGuard != nil && failReason != services.ErrLoginIPBanned.Error() {
+		s.loginIPGuard.RecordFailure(ctx, ipAddress)
+	}
+
+	result := "success"
+	if !success {
+		result = "failure"
+	}
+	s.emitAuthEvent("login", result, userID, email, ipAddress, userAgent, failReason)
 }
 
-func (s *AuthService) saveRefreshToken(ctx context.Context, userID uint, token, sessionID, ipAddress, userAgent string) error {
+// SetAccountDeletionService 设置账户注销服务，启用自助注销及宽限期内的登录拦截
+func (s *AuthService) SetAccountDeletionService(accountDeletion *services.AccountDeletionService) {
+	s.accountDeletion = accountDeletion
+}
+
+// SetSMSOTPService 设置短信验证码服务，启用手机验证及短信登录验证
+func (s *AuthService) SetSMSOTPService(smsOTP *services.SMSOTPService) {
+	s.smsOTP = smsOTP
+}
+
+// SetLoginIPGuard 设置按IP的登录防护，同一IP失败次数过多时临时封禁
+func (s *AuthService) SetLoginIPGuard(guard *services.LoginIPGuardService) {
+	s.loginIPGuard = guard
+}
+
+// SetAuditForwarder 设置审计事件转发器，登录、登出、密码重置等认证事件会转发到 SIEM
+func (s *AuthService) SetAuditForwarder(forwarder *services.AuditForwarder) {
+	s.auditForwarder = forwarder
+}
+
+// SetSecurityAuditLog 设置审计日志服务，修改密码、两步验证开关等账户安全事件写入审计日志（哈希链）并转发
+func (s *AuthService) SetSecurityAuditLog(auditLog *services.AdminAuditService) {
+	s.securityAudit = auditLog
+}
+
+// recordSecurityEvent 记录账户安全事件，客户端信息取自请求上下文；未设置审计日志时只转发到 SIEM
+func (s *AuthService) recordSecurityEvent(ctx context.Context, action string, user *User, detail string) {
+	_, ipAddress, userAgent := services.AuditActorFrom(ctx)
+	if s.securityAudit == nil {
+		s.emitAuthEvent(action, "success", &user.ID, user.Username, ipAddress, userAgent, detail)
+		return
+	}
+	s.securityAudit.RecordSecurityEvent(ctx, &services.AdminAuditRecord{
+		UserID:    &user.ID,
+		Username:  user.Username,
+		Role:      string(user.Role),
+		Action:    action,
+		ClientIP:  ipAddress,
+		UserAgent: userAgent,
+		Notes:     detail,
+	})
+}
+
+func (s *AuthService) emitAuthEvent(action, result string, userID *uint, username, ipAddress, userAgent, detail string) {
+	s.auditForwarder.Emit(&services.AuditEvent{
+		Type:      models.AuditEventAuth,
+		Action:    action,
+		Result:    result,
+		UserID:    userID,
+		Username:  username,
+		ClientIP:  ipAddress,
+		UserAgent: userAgent,
+		Detail:    detail,
+	})
+}
+
+func (s *AuthService) saveRefreshToken(ctx context.Context, userID uint, token, sessionID, ipAddress, userAgent string, ttl time.Duration) error {
 	refreshToken := &RefreshToken{
 		UserID:    userID,
 		Token:     token,
 		SessionID: sessionID,
-		ExpiresAt: time.Now().Add(s.config.RefreshTokenExpire),
+		ExpiresAt: time.Now().Add(ttl),
 		IPAddress: ipAddress,
 		UserAgent: userAgent,
 	}
@@ -1354,8 +1841,8 @@ func (s *AuthService) sendEmailVerification(ctx context.Context, user *User) err
 	verification := &EmailVerification{
 		UserID:    user.ID,
 		Email:     user.Email,
-		Token:     token,
-		ExpiresAt: time.Now().Add(s.config.EmailVerificationExpire),
+		Token:     hashOneTimeToken(token),
+		ExpiresAt: time.Now().Add(s.getEmailVerificationTTL()),
 	}
 
 	if err := s.tokenRepo.CreateEmailVerification(ctx, verification); err != nil {
@@ -1457,12 +1944,16 @@ func determineLoginMethod(user *User, req *LoginRequest, deviceTrusted bool, otp
 		return "password+trusted"
 	}
 
-	if user != nil && user.OTPEnabled {
+	if user != nil && user.SecondFactorEnabled() {
+		factor := "otp"
+		if req != nil && usesSMSCode(user, req.OTPMethod) {
+			factor = "sms"
+		}
 		if otpValidated {
-			return "password+otp"
+			return "password+" + factor
 		}
 		if req != nil && req.OTPCode != "" {
-			return "password+otp"
+			return "password+" + factor
 		}
 		return "password+otp_required"
 	}
@@ -1532,6 +2023,50 @@ func (s *AuthService) getTrustedDeviceTTL() time.Duration {
 	return defaultTrustedDeviceTTL
 }
 
+func (s *AuthService) getPasswordResetTTL() time.Duration {
+	if s.configService != nil {
+		if minutes, err := s.configService.GetConfigInt(services.KeyPasswordResetTTLMinutes); err == nil && minutes > 0 {
+			return time.Duration(minutes) * time.Minute
+		}
+	}
+	return s.config.PasswordResetExpire
+}
+
+func (s *AuthService) isMagicLinkEnabled() bool {
+	if s.configService == nil {
+		return false
+	}
+	enabled, err := s.configService.GetConfigBool(services.KeyMagicLinkEnabled)
+	return err == nil && enabled
+}
+
+func (s *AuthService) getMagicLinkTTL() time.Duration {
+	if s.configService != nil {
+		if minutes, err := s.configService.GetConfigInt(services.KeyMagicLinkTTLMinutes); err == nil && minutes > 0 {
+			return time.Duration(minutes) * time.Minute
+		}
+	}
+	return defaultMagicLinkTTL
+}
+
+func (s *AuthService) getMagicLinkMaxPerHour() int {
+	if s.configService != nil {
+		if limit, err := s.configService.GetConfigInt(services.KeyMagicLinkMaxPerHour); err == nil && limit > 0 {
+			return limit
+		}
+	}
+	return defaultMagicLinkMaxPerHour
+}
+
+func (s *AuthService) getEmailVerificationTTL() time.Duration {
+	if s.configService != nil {
+		if hours, err := s.configService.GetConfigInt(services.KeyEmailVerificationTTLHours); err == nil && hours > 0 {
+			return time.Duration(hours) * time.Hour
+		}
+	}
+	return s.config.EmailVerificationExpire
+}
+
 func (s *AuthService) getTrustedDeviceLimit() int {
 	if s.configService != nil {
 		if limit, err := s.configService.GetConfigInt(services.KeyTrustedDeviceMaxPerUser); err == nil {
@@ -1576,6 +2111,12 @@ func hashTrustedDeviceToken(token string) string {
 	return hex.EncodeToString(sum[:])
 }
 
+// hashOneTimeToken 计算一次性令牌（密码重置、邮箱验证）的存储哈希
+func hashOneTimeToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 func resolveTrustedDeviceName(providedName, userAgent string) string {
 	name := strings.TrimSpace(providedName)
 	if name != "" {
@@ -1619,6 +2160,7 @@ func (s *AuthService) buildUserInfo(user *User, profile *UserProfile) *UserInfo
 		Status:        user.Status,
 		EmailVerified: user.EmailVerified,
 		OTPEnabled:    user.OTPEnabled,
+		SMSOTPEnabled: user.SMSOTPEnabled,
 		LastLoginAt:   user.LastLoginAt,
 	}
 
@@ -0,0 +1,219 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// 会话模式：bearer 由客户端保存令牌并通过 Authorization 头发送；
+// cookie 将令牌写入 httpOnly Cookie，写操作需携带双提交的CSRF令牌
+const (
+	SessionModeBearer = "bearer"
+	SessionModeCookie = "cookie"
+)
+
+// Cookie 会话使用的 Cookie 与请求头名称
+const (
+	AccessTokenCookie  = "gd_access_token"
+	RefreshTokenCookie = "gd_refresh_token"
+	CSRFTokenCookie    = "gd_csrf_token"
+	CSRFTokenHeader    = "X-CSRF-Token"
+
+	accessTokenCookiePath  = "/api"
+	refreshTokenCookiePath = "/api/auth" // 刷新令牌只发送给刷新及登出接口
+)
+
+// CookieSessionConfig Cookie 会话配置，按部署通过环境变量设置
+type CookieSessionConfig struct {
+	Domain     string
+	Secure     bool
+	SameSite   http.SameSite
+	CSRFSecret []byte
+}
+
+// ParseSameSite 解析 SameSite 配置：lax、strict 或 none
+func ParseSameSite(value string) (http.SameSite, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "lax":
+		return http.SameSiteLaxMode, nil
+	case "strict":
+		return http.SameSiteStrictMode, nil
+	case "none":
+		return http.SameSiteNoneMode, nil
+	default:
+		return http.SameSiteDefaultMode, fmt.Errorf("invalid SameSite value %q", value)
+	}
+}
+
+// SetCookieSession 启用 Cookie 会话，为 nil 时只使用 Bearer 令牌
+func (h *AuthHandler) SetCookieSession(config *CookieSessionConfig) {
+	h.cookieSession = config
+}
+
+// SessionMode 获取当前部署的会话模式，供前端决定令牌的保存方式
+func (h *AuthHandler) SessionMode(c HTTPContext) {
+	data := map[string]interface{}{"mode": SessionModeBearer}
+	if h.cookieSession != nil {
+		data = map[string]interface{}{
+			"mode":        SessionModeCookie,
+			"csrf_cookie": CSRFTokenCookie,
+			"csrf_header": CSRFTokenHeader,
+		}
+	}
+	c.JSON(http.StatusOK, SuccessResponse{Success: true, Data: data})
+}
+
+// CSRFProtect 校验通过 Cookie 认证的写请求携带的CSRF令牌。
+// 请求头中的CSRF令牌须与 Cookie 中的一致，且由服务端为会话 Cookie 所属的登录会话签发；
+// 使用 Authorization 头或未携带会话 Cookie 的请求不受跨站伪造影响，直接放行
+func (h *AuthHandler) CSRFProtect(c HTTPContext) {
+	if h.cookieSession == nil || !isUnsafeMethod(c.Request().Method) || c.GetHeader("Authorization") != "" {
+		c.Next()
+		return
+	}
+	accessToken, refreshToken := cookieValue(c, AccessTokenCookie), cookieValue(c, RefreshTokenCookie)
+	if accessToken == "" && refreshToken == "" {
+		c.Next()
+		return
+	}
+	sessionID := tokenSessionID(accessToken)
+	if sessionID == "" {
+		sessionID = tokenSessionID(refreshToken)
+	}
+
+	token := c.GetHeader(CSRFTokenHeader)
+	cookie := cookieValue(c, CSRFTokenCookie)
+	if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(cookie)) != 1 || !h.validCSRFToken(token, sessionID) {
+		c.JSON(http.StatusForbidden, ErrorResponse{
+			Error:   "csrf_token_invalid",
+			Message: "Missing or invalid CSRF token",
+			Code:    "csrf_token_invalid",
+		})
+		c.Abort()
+		return
+	}
+	c.Next()
+}
+
+// issueSessionCookies 在 Cookie 模式下将令牌写入 Cookie，并从响应体中移除令牌
+func (h *AuthHandler) issueSessionCookies(c HTTPContext, resp *AuthResponse) error {
+	if h.cookieSession == nil || resp == nil {
+		return nil
+	}
+	sessionID := tokenSessionID(resp.AccessToken)
+	if sessionID == "" {
+		return errors.New("session token has no session id")
+	}
+	csrfToken, err := h.newCSRFToken(sessionID)
+	if err != nil {
+		return err
+	}
+
+	refreshMaxAge := 0
+	if h.authService != nil && h.authService.config != nil {
+		config := h.authService.config
+		refreshMaxAge = int(config.RefreshTokenExpire.Seconds())
+	}
+	c.SetCookie(h.sessionCookie(AccessTokenCookie, resp.AccessToken, accessTokenCookiePath, int(resp.ExpiresIn), true))
+	if resp.RefreshToken != "" {
+		c.SetCookie(h.sessionCookie(RefreshTokenCookie, resp.RefreshToken, refreshTokenCookiePath, refreshMaxAge, true))
+	}
+	// CSRF令牌须能被前端脚本读取，以便放入请求头
+	c.SetCookie(h.sessionCookie(CSRFTokenCookie, csrfToken, "/", refreshMaxAge, false))
+	c.SetHeader("Cache-Control", "no-store")
+
+	resp.AccessToken = ""
+	resp.RefreshToken = ""
+	resp.TokenType = "Cookie"
+	return nil
+}
+
+// clearSessionCookies 登出时清除会话 Cookie
+func (h *AuthHandler) clearSessionCookies(c HTTPContext) {
+	if h.cookieSession == nil {
+		return
+	}
+	c.SetCookie(h.sessionCookie(AccessTokenCookie, "", accessTokenCookiePath, -1, true))
+	c.SetCookie(h.sessionCookie(RefreshTokenCookie, "", refreshTokenCookiePath, -1, true))
+	c.SetCookie(h.sessionCookie(CSRFTokenCookie, "", "/", -1, false))
+}
+
+// sessionTokenFromCookie Cookie 模式下读取会话 Cookie 中的令牌
+func (h *AuthHandler) sessionTokenFromCookie(c HTTPContext, name string) string {
+	if h.cookieSession == nil {
+		return ""
+	}
+	return cookieValue(c, name)
+}
+
+func (h *AuthHandler) sessionCookie(name, value, path string, maxAge int, httpOnly bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   h.cookieSession.Domain,
+		MaxAge:   maxAge,
+		Secure:   h.cookieSession.Secure,
+		HttpOnly: httpOnly,
+		SameSite: h.cookieSession.SameSite,
+	}
+}
+
+// newCSRFToken 生成绑定登录会话的签名CSRF令牌：随机值.HMAC(会话ID, 随机值)
+func (h *AuthHandler) newCSRFToken(sessionID string) (string, error) {
+	nonce, err := GenerateSecureToken(16)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate csrf token: %w", err)
+	}
+	return nonce + "." + h.signCSRF(sessionID, nonce), nil
+}
+
+// validCSRFToken 校验CSRF令牌由服务端为该登录会话签发，其他会话的令牌无效
+func (h *AuthHandler) validCSRFToken(token, sessionID string) bool {
+	nonce, signature, ok := strings.Cut(token, ".")
+	if !ok || nonce == "" || sessionID == "" {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(h.signCSRF(sessionID, nonce)))
+}
+
+func (h *AuthHandler) signCSRF(sessionID, nonce string) string {
+	mac := hmac.New(sha256.New, h.cookieSession.CSRFSecret)
+	mac.Write([]byte("csrf:" + sessionID + ":" + nonce))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// tokenSessionID 读取会话令牌中的会话ID。这里只用于绑定CSRF令牌，令牌本身由认证中间件验证
+func tokenSessionID(token string) string {
+	if token == "" {
+		return ""
+	}
+	payload, err := decodeTokenPayload(token)
+	if err != nil {
+		return ""
+	}
+	return payload.Sid
+}
+
+func cookieValue(c HTTPContext, name string) string {
+	cookie, err := c.Request().Cookie(name)
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
+
+func isUnsafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	default:
+		return true
+	}
+}
@@ -0,0 +1,118 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCookieSession_IssueCookiesAndCSRF(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := NewAuthHandler(nil, nil)
+	h.SetCookieSession(&CookieSessionConfig{Secure: true, SameSite: http.SameSiteStrictMode, CSRFSecret: []byte("test-csrf-secret")})
+	jwt := NewSimpleJWTManager("access-secret", "refresh-secret", 15*time.Minute, time.Hour)
+	accessToken, refreshToken, err := jwt.GenerateTokenPair(1, RoleAgent, "session-a")
+	if err != nil {
+		t.Fatalf("generate tokens failed: %v", err)
+	}
+	otherAccess, _, err := jwt.GenerateTokenPair(1, RoleAgent, "session-b")
+	if err != nil {
+		t.Fatalf("generate tokens failed: %v", err)
+	}
+
+	// 登录响应：令牌写入 httpOnly Cookie，响应体不再包含令牌
+	rec := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(rec)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/auth/login", nil)
+	resp := &AuthResponse{AccessToken: accessToken, RefreshToken: refreshToken, ExpiresIn: 900, TokenType: "Bearer"}
+	if err := h.issueSessionCookies(NewGinHTTPContext(c), resp); err != nil {
+		t.Fatalf("issue session cookies failed: %v", err)
+	}
+	if resp.AccessToken != "" || resp.RefreshToken != "" || resp.TokenType != "Cookie" {
+		t.Fatalf("expected tokens to be removed from response body, got %+v", resp)
+	}
+	cookies := map[string]*http.Cookie{}
+	for _, cookie := range rec.Result().Cookies() {
+		cookies[cookie.Name] = cookie
+	}
+	access, refresh, csrf := cookies[AccessTokenCookie], cookies[RefreshTokenCookie], cookies[CSRFTokenCookie]
+	if access == nil || !access.HttpOnly || !access.Secure || access.SameSite != http.SameSiteStrictMode || access.Path != "/api" || access.MaxAge != 900 {
+		t.Fatalf("unexpected access cookie %+v", access)
+	}
+	if refresh == nil || !refresh.HttpOnly || refresh.Path != "/api/auth" || refresh.Value != refreshToken {
+		t.Fatalf("unexpected refresh cookie %+v", refresh)
+	}
+	if csrf == nil || csrf.HttpOnly || !h.validCSRFToken(csrf.Value, "session-a") || h.validCSRFToken(csrf.Value, "session-b") {
+		t.Fatalf("unexpected csrf cookie %+v", csrf)
+	}
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) { h.CSRFProtect(NewGinHTTPContext(c)) })
+	router.POST("/api/tickets", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	router.GET("/api/tickets", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	send := func(method string, headers map[string]string, cookies ...*http.Cookie) int {
+		req := httptest.NewRequest(method, "/api/tickets", strings.NewReader("{}"))
+		for key, value := range headers {
+			req.Header.Set(key, value)
+		}
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	session := &http.Cookie{Name: AccessTokenCookie, Value: accessToken}
+	refreshOnly := &http.Cookie{Name: RefreshTokenCookie, Value: refreshToken}
+	otherSession := &http.Cookie{Name: AccessTokenCookie, Value: otherAccess}
+	csrfCookie := &http.Cookie{Name: CSRFTokenCookie, Value: csrf.Value}
+	forged := "0123456789abcdef.forged"
+
+	cases := []struct {
+		name    string
+		method  string
+		headers map[string]string
+		cookies []*http.Cookie
+		want    int
+	}{
+		{"no session cookie", http.MethodPost, nil, nil, http.StatusNoContent},
+		{"safe method", http.MethodGet, nil, []*http.Cookie{session}, http.StatusNoContent},
+		{"bearer request", http.MethodPost, map[string]string{"Authorization": "Bearer access"}, []*http.Cookie{session}, http.StatusNoContent},
+		{"missing header", http.MethodPost, nil, []*http.Cookie{session, csrfCookie}, http.StatusForbidden},
+		{"mismatched header", http.MethodPost, map[string]string{CSRFTokenHeader: forged}, []*http.Cookie{session, csrfCookie}, http.StatusForbidden},
+		{"unsigned token", http.MethodPost, map[string]string{CSRFTokenHeader: forged}, []*http.Cookie{session, {Name: CSRFTokenCookie, Value: forged}}, http.StatusForbidden},
+		{"valid token", http.MethodPost, map[string]string{CSRFTokenHeader: csrf.Value}, []*http.Cookie{session, csrfCookie}, http.StatusNoContent},
+		{"valid token with refresh cookie", http.MethodPost, map[string]string{CSRFTokenHeader: csrf.Value}, []*http.Cookie{refreshOnly, csrfCookie}, http.StatusNoContent},
+		{"token from another session", http.MethodPost, map[string]string{CSRFTokenHeader: csrf.Value}, []*http.Cookie{otherSession, csrfCookie}, http.StatusForbidden},
+		{"session cookie without session id", http.MethodPost, map[string]string{CSRFTokenHeader: csrf.Value}, []*http.Cookie{{Name: AccessTokenCookie, Value: "access"}, csrfCookie}, http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		if got := send(tc.method, tc.headers, tc.cookies...); got != tc.want {
+			t.Errorf("%s: expected %d, got %d", tc.name, tc.want, got)
+		}
+	}
+
+	// Bearer 模式下不签发 Cookie，也不校验CSRF
+	bearer := NewAuthHandler(nil, nil)
+	rec = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(rec)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/auth/login", nil)
+	resp = &AuthResponse{AccessToken: "access"}
+	bearer.issueSessionCookies(NewGinHTTPContext(c), resp)
+	if resp.AccessToken != "access" || len(rec.Result().Cookies()) != 0 {
+		t.Fatalf("bearer mode must not issue cookies")
+	}
+
+	// 令牌不含会话ID时无法绑定CSRF令牌，签发失败
+	rec = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(rec)
+	c.Request = httptest.NewRequest(http.MethodPost, "/api/auth/register", nil)
+	if err := h.issueSessionCookies(NewGinHTTPContext(c), &AuthResponse{AccessToken: "access"}); err == nil {
+		t.Fatalf("expected session cookies without session id to fail")
+	}
+}
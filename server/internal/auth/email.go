@@ -3,9 +3,12 @@ package auth
 import (
 	"context"
 	"fmt"
+	"html"
 	"net/smtp"
 	"strings"
 	"time"
+
+	"gongdan-system/internal/services"
 )
 
 // SMTPEmailService SMTP邮件服务实现
@@ -16,6 +19,9 @@ type SMTPEmailService struct {
 	password string
 	from     string
 	auth     smtp.Auth
+
+	suppression *services.EmailSuppressionService
+	branding    *services.BrandingService
 }
 
 // EmailConfig 邮件配置
@@ -40,6 +46,16 @@ func NewSMTPEmailService(config *EmailConfig) *SMTPEmailService {
 	}
 }
 
+// SetEmailSuppression 设置邮件退信与投诉服务，永久退信或投诉的地址停止发送；未设置时不检查
+func (s *SMTPEmailService) SetEmailSuppression(suppression *services.EmailSuppressionService) {
+	s.suppression = suppression
+}
+
+// SetBranding 设置品牌服务，邮件中的品牌变量和发件人按品牌设置渲染；未设置时使用默认品牌
+func (s *SMTPEmailService) SetBranding(branding *services.BrandingService) {
+	s.branding = branding
+}
+
 // SendVerificationEmail 发送邮箱验证邮件
 func (s *SMTPEmailService) SendVerificationEmail(ctx context.Context, email, token string) error {
 	subject := "Verify Your Email Address"
@@ -52,28 +68,28 @@ func (s *SMTPEmailService) SendVerificationEmail(ctx context.Context, email, tok
     <style>
         body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
         .container { max-width: 600px; margin: 0 auto; padding: 20px; }
-        .header { background-color: #007bff; color: white; padding: 20px; text-align: center; }
+        .header { background-color: {{.BrandPrimaryColor}}; color: white; padding: 20px; text-align: center; }
         .content { padding: 20px; background-color: #f9f9f9; }
-        .button { display: inline-block; padding: 12px 24px; background-color: #007bff; color: white; text-decoration: none; border-radius: 4px; margin: 20px 0; }
+        .button { display: inline-block; padding: 12px 24px; background-color: {{.BrandPrimaryColor}}; color: white; text-decoration: none; border-radius: 4px; margin: 20px 0; }
         .footer { padding: 20px; text-align: center; color: #666; font-size: 12px; }
     </style>
 </head>
 <body>
     <div class="container">
         <div class="header">
-            <h1>Email Verification</h1>
+            {{.BrandLogoImg}}<h1>Email Verification</h1>
         </div>
         <div class="content">
-            <h2>Welcome to our Ticketing System!</h2>
+            <h2>Welcome to {{.BrandProductName}}!</h2>
             <p>Thank you for registering with us. To complete your registration, please verify your email address by clicking the button below:</p>
-            <a href="http://localhost:3000/verify-email?token=%s" class="button">Verify Email Address</a>
+            <a href="{{.BrandPortalURL}}/verify-email?token=%s" class="button">Verify Email Address</a>
             <p>If the button doesn't work, you can copy and paste this link into your browser:</p>
-            <p>http://localhost:3000/verify-email?token=%s</p>
+            <p>{{.BrandPortalURL}}/verify-email?token=%s</p>
             <p>This verification link will expire in 24 hours.</p>
             <p>If you didn't create an account with us, please ignore this email.</p>
         </div>
         <div class="footer">
-            <p>© 2024 Ticketing System. All rights reserved.</p>
+            <p>{{.BrandFooter}}</p>
         </div>
     </div>
 </body>
@@ -105,14 +121,14 @@ func (s *SMTPEmailService) SendPasswordResetEmail(ctx context.Context, email, to
 <body>
     <div class="container">
         <div class="header">
-            <h1>Password Reset Request</h1>
+            {{.BrandLogoImg}}<h1>Password Reset Request</h1>
         </div>
         <div class="content">
             <h2>Reset Your Password</h2>
             <p>We received a request to reset your password. If you made this request, click the button below to reset your password:</p>
-            <a href="http://localhost:3000/reset-password?token=%s" class="button">Reset Password</a>
+            <a href="{{.BrandPortalURL}}/reset-password?token=%s" class="button">Reset Password</a>
             <p>If the button doesn't work, you can copy and paste this link into your browser:</p>
-            <p>http://localhost:3000/reset-password?token=%s</p>
+            <p>{{.BrandPortalURL}}/reset-password?token=%s</p>
             <div class="warning">
                 <strong>Security Notice:</strong>
                 <ul>
@@ -123,7 +139,7 @@ func (s *SMTPEmailService) SendPasswordResetEmail(ctx context.Context, email, to
             </div>
         </div>
         <div class="footer">
-            <p>© 2024 Ticketing System. All rights reserved.</p>
+            <p>{{.BrandFooter}}</p>
         </div>
     </div>
 </body>
@@ -135,7 +151,7 @@ func (s *SMTPEmailService) SendPasswordResetEmail(ctx context.Context, email, to
 
 // SendWelcomeEmail 发送欢迎邮件
 func (s *SMTPEmailService) SendWelcomeEmail(ctx context.Context, email, username string) error {
-	subject := "Welcome to Ticketing System!"
+	subject := "Welcome to {{.BrandProductName}}!"
 	body := fmt.Sprintf(`
 <!DOCTYPE html>
 <html>
@@ -155,7 +171,7 @@ func (s *SMTPEmailService) SendWelcomeEmail(ctx context.Context, email, username
 <body>
     <div class="container">
         <div class="header">
-            <h1>Welcome to Ticketing System!</h1>
+            {{.BrandLogoImg}}<h1>Welcome to {{.BrandProductName}}!</h1>
         </div>
         <div class="content">
             <h2>Hello %s!</h2>
@@ -175,12 +191,12 @@ func (s *SMTPEmailService) SendWelcomeEmail(ctx context.Context, email, username
                 <strong>Secure Access:</strong> Enable two-factor authentication for enhanced security
             </div>
             
-            <a href="http://localhost:3000/dashboard" class="button">Go to Dashboard</a>
+            <a href="{{.BrandPortalURL}}/dashboard" class="button">Go to Dashboard</a>
             
             <p>If you have any questions or need assistance, feel free to contact our support team.</p>
         </div>
         <div class="footer">
-            <p>© 2024 Ticketing System. All rights reserved.</p>
+            <p>{{.BrandFooter}}</p>
         </div>
     </div>
 </body>
@@ -212,7 +228,7 @@ func (s *SMTPEmailService) SendOTPEmail(ctx context.Context, email, code string)
 <body>
     <div class="container">
         <div class="header">
-            <h1>Verification Code</h1>
+            {{.BrandLogoImg}}<h1>Verification Code</h1>
         </div>
         <div class="content">
             <h2>Your One-Time Password</h2>
@@ -232,7 +248,7 @@ func (s *SMTPEmailService) SendOTPEmail(ctx context.Context, email, code string)
             <p>If you're having trouble, please contact our support team.</p>
         </div>
         <div class="footer">
-            <p>© 2024 Ticketing System. All rights reserved.</p>
+            <p>{{.BrandFooter}}</p>
         </div>
     </div>
 </body>
@@ -242,11 +258,221 @@ func (s *SMTPEmailService) SendOTPEmail(ctx context.Context, email, code string)
 	return s.sendEmail(email, subject, body)
 }
 
+// SendPasswordResetAlertEmail 发送密码已从新设备重置的安全提醒邮件
+func (s *SMTPEmailService) SendPasswordResetAlertEmail(ctx context.Context, email, ipAddress, userAgent string, at time.Time) error {
+	subject := "Your Password Was Reset"
+	body := fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>Password Reset Alert</title>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background-color: #dc3545; color: white; padding: 20px; text-align: center; }
+        .content { padding: 20px; background-color: #f9f9f9; }
+        .footer { padding: 20px; text-align: center; color: #666; font-size: 12px; }
+        .warning { background-color: #fff3cd; border: 1px solid #ffeaa7; padding: 10px; border-radius: 4px; margin: 10px 0; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            {{.BrandLogoImg}}<h1>Password Reset Alert</h1>
+        </div>
+        <div class="content">
+            <h2>Your password was reset from a new device</h2>
+            <p>The password for your account was reset from a device that has not signed in before.</p>
+            <ul>
+                <li><strong>Time:</strong> %s</li>
+                <li><strong>IP address:</strong> %s</li>
+                <li><strong>Device:</strong> %s</li>
+            </ul>
+            
+            <div class="warning">
+                <strong>Wasn't you?</strong> Contact our support team immediately and secure your account.
+            </div>
+        </div>
+        <div class="footer">
+            <p>{{.BrandFooter}}</p>
+        </div>
+    </div>
+</body>
+</html>
+	`, at.Format(time.RFC1123), html.EscapeString(ipAddress), html.EscapeString(userAgent))
+
+	return s.sendEmail(email, subject, body)
+}
+
+// SendMagicLinkEmail 发送免密登录链接邮件
+func (s *SMTPEmailService) SendMagicLinkEmail(ctx context.Context, email, token string, ttl time.Duration) error {
+	subject := "Your Sign-in Link"
+	body := fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>Sign-in Link</title>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background-color: {{.BrandPrimaryColor}}; color: white; padding: 20px; text-align: center; }
+        .content { padding: 20px; background-color: #f9f9f9; }
+        .button { display: inline-block; padding: 12px 24px; background-color: {{.BrandPrimaryColor}}; color: white; text-decoration: none; border-radius: 4px; margin: 20px 0; }
+        .footer { padding: 20px; text-align: center; color: #666; font-size: 12px; }
+        .warning { background-color: #fff3cd; border: 1px solid #ffeaa7; padding: 10px; border-radius: 4px; margin: 10px 0; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            {{.BrandLogoImg}}<h1>Sign in to {{.BrandProductName}}</h1>
+        </div>
+        <div class="content">
+            <h2>Your one-time sign-in link</h2>
+            <p>Click the button below to sign in without a password:</p>
+            <a href="{{.BrandPortalURL}}/magic-link?token=%s" class="button">Sign In</a>
+            <p>If the button doesn't work, you can copy and paste this link into your browser:</p>
+            <p>{{.BrandPortalURL}}/magic-link?token=%s</p>
+            <div class="warning">
+                <strong>Security Notice:</strong>
+                <ul>
+                    <li>This link can be used once and expires in %d minutes</li>
+                    <li>If you didn't request this link, please ignore this email</li>
+                </ul>
+            </div>
+        </div>
+        <div class="footer">
+            <p>{{.BrandFooter}}</p>
+        </div>
+    </div>
+</body>
+</html>
+	`, token, token, int(ttl.Minutes()))
+
+	return s.sendEmail(email, subject, body)
+}
+
+// SendAccountDeletionEmail 发送账户注销确认邮件
+func (s *SMTPEmailService) SendAccountDeletionEmail(ctx context.Context, email, token string, graceDays int) error {
+	subject := "Confirm Account Deletion"
+	body := fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>Confirm Account Deletion</title>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background-color: #dc3545; color: white; padding: 20px; text-align: center; }
+        .content { padding: 20px; background-color: #f9f9f9; }
+        .button { display: inline-block; padding: 12px 24px; background-color: #dc3545; color: white; text-decoration: none; border-radius: 4px; margin: 20px 0; }
+        .footer { padding: 20px; text-align: center; color: #666; font-size: 12px; }
+        .warning { background-color: #fff3cd; border: 1px solid #ffeaa7; padding: 10px; border-radius: 4px; margin: 10px 0; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            {{.BrandLogoImg}}<h1>Account Deletion Request</h1>
+        </div>
+        <div class="content">
+            <h2>Confirm that you want to delete your account</h2>
+            <p>We received a request to delete your account. Click the button below to confirm:</p>
+            <a href="{{.BrandPortalURL}}/delete-account/confirm?token=%s" class="button">Delete My Account</a>
+            <p>If the button doesn't work, you can copy and paste this link into your browser:</p>
+            <p>{{.BrandPortalURL}}/delete-account/confirm?token=%s</p>
+            <div class="warning">
+                <strong>What happens next:</strong>
+                <ul>
+                    <li>You will be signed out on all devices once you confirm</li>
+                    <li>You can restore your account by signing in within %d days</li>
+                    <li>After that, your personal data is permanently anonymized</li>
+                    <li>This link expires in 24 hours; if you didn't request this, please ignore this email</li>
+                </ul>
+            </div>
+        </div>
+        <div class="footer">
+            <p>{{.BrandFooter}}</p>
+        </div>
+    </div>
+</body>
+</html>
+	`, token, token, graceDays)
+
+	return s.sendEmail(email, subject, body)
+}
+
+// SendInvitationEmail 发送账户邀请邮件
+func (s *SMTPEmailService) SendInvitationEmail(ctx context.Context, email, token, role string, ttl time.Duration) error {
+	subject := "You're Invited to {{.BrandProductName}}"
+	body := fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>Invitation</title>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background-color: {{.BrandPrimaryColor}}; color: white; padding: 20px; text-align: center; }
+        .content { padding: 20px; background-color: #f9f9f9; }
+        .button { display: inline-block; padding: 12px 24px; background-color: {{.BrandPrimaryColor}}; color: white; text-decoration: none; border-radius: 4px; margin: 20px 0; }
+        .footer { padding: 20px; text-align: center; color: #666; font-size: 12px; }
+        .warning { background-color: #fff3cd; border: 1px solid #ffeaa7; padding: 10px; border-radius: 4px; margin: 10px 0; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            {{.BrandLogoImg}}<h1>Welcome to {{.BrandProductName}}</h1>
+        </div>
+        <div class="content">
+            <h2>You have been invited as %s</h2>
+            <p>An administrator created an invitation for this email address. Click the button below to set your password and activate your account:</p>
+            <a href="{{.BrandPortalURL}}/invitations/%s" class="button">Accept Invitation</a>
+            <p>If the button doesn't work, you can copy and paste this link into your browser:</p>
+            <p>{{.BrandPortalURL}}/invitations/%s</p>
+            <div class="warning">
+                <strong>Security Notice:</strong>
+                <ul>
+                    <li>This invitation can be used once and expires in %d hours</li>
+                    <li>If you weren't expecting this invitation, please ignore this email</li>
+                </ul>
+            </div>
+        </div>
+        <div class="footer">
+            <p>{{.BrandFooter}}</p>
+        </div>
+    </div>
+</body>
+</html>
+	`, html.EscapeString(role), token, token, int(ttl.Hours()))
+
+	return s.sendEmail(email, subject, body)
+}
+
 // sendEmail 发送邮件的通用方法
 func (s *SMTPEmailService) sendEmail(to, subject, body string) error {
+	// 永久退信或投诉的地址停止发送
+	if s.suppression != nil {
+		if err := s.suppression.CheckSendable(context.Background(), to); err != nil {
+			return err
+		}
+	}
+
+	// 模板中的产品名称、配色、门户地址等按品牌设置渲染
+	subject, body = services.RenderBrandedEmail(context.Background(), s.branding, subject, body)
+	fromEmail, fromName := services.BrandedSender(context.Background(), s.branding, s.from, "")
+
 	// 构建邮件头
 	headers := make(map[string]string)
-	headers["From"] = s.from
+	headers["From"] = fromEmail
+	if fromName != "" {
+		headers["From"] = fmt.Sprintf("%s <%s>", fromName, fromEmail)
+	}
 	headers["To"] = to
 	headers["Subject"] = subject
 	headers["MIME-Version"] = "1.0"
@@ -334,6 +560,50 @@ func (m *MockEmailService) SendOTPEmail(ctx context.Context, email, code string)
 	return nil
 }
 
+// SendPasswordResetAlertEmail 模拟发送密码重置安全提醒邮件
+func (m *MockEmailService) SendPasswordResetAlertEmail(ctx context.Context, email, ipAddress, userAgent string, at time.Time) error {
+	m.sentEmails = append(m.sentEmails, SentEmail{
+		To:      email,
+		Subject: "Your Password Was Reset",
+		Body:    fmt.Sprintf("Password reset from IP %s, device %s", ipAddress, userAgent),
+		SentAt:  time.Now(),
+	})
+	return nil
+}
+
+// SendMagicLinkEmail 模拟发送免密登录链接邮件
+func (m *MockEmailService) SendMagicLinkEmail(ctx context.Context, email, token string, ttl time.Duration) error {
+	m.sentEmails = append(m.sentEmails, SentEmail{
+		To:      email,
+		Subject: "Your Sign-in Link",
+		Body:    fmt.Sprintf("Magic link token: %s (expires in %s)", token, ttl),
+		SentAt:  time.Now(),
+	})
+	return nil
+}
+
+// SendAccountDeletionEmail 模拟发送账户注销确认邮件
+func (m *MockEmailService) SendAccountDeletionEmail(ctx context.Context, email, token string, graceDays int) error {
+	m.sentEmails = append(m.sentEmails, SentEmail{
+		To:      email,
+		Subject: "Confirm Account Deletion",
+		Body:    fmt.Sprintf("Account deletion token: %s (grace period %d days)", token, graceDays),
+		SentAt:  time.Now(),
+	})
+	return nil
+}
+
+// SendInvitationEmail 模拟发送账户邀请邮件
+func (m *MockEmailService) SendInvitationEmail(ctx context.Context, email, token, role string, ttl time.Duration) error {
+	m.sentEmails = append(m.sentEmails, SentEmail{
+		To:      email,
+		Subject: "You're Invited to Ticketing System",
+		Body:    fmt.Sprintf("Invitation token: %s (role %s, expires in %s)", token, role, ttl),
+		SentAt:  time.Now(),
+	})
+	return nil
+}
+
 // GetSentEmails 获取已发送邮件列表
 func (m *MockEmailService) GetSentEmails() []SentEmail {
 	return m.sentEmails
@@ -27,6 +27,11 @@ func (g *GinHTTPContext) SetHeader(key, value string) {
 	g.ginCtx.Header(key, value)
 }
 
+// SetCookie 设置响应Cookie
+func (g *GinHTTPContext) SetCookie(cookie *http.Cookie) {
+	http.SetCookie(g.ginCtx.Writer, cookie)
+}
+
 // GetQuery 获取查询参数
 func (g *GinHTTPContext) GetQuery(key string) string {
 	return g.ginCtx.Query(key)
@@ -35,6 +35,10 @@ func (r *GormUserRepository) Create(ctx context.Context, user *User) error {
 		LockedUntil:      user.LockedUntil,
 		TwoFactorEnabled: user.OTPEnabled,
 		TwoFactorSecret:  user.OTPSecret,
+		SMSOTPEnabled:    user.SMSOTPEnabled,
+		Phone:            user.Phone,
+		PhoneVerified:    user.PhoneVerified,
+		PhoneVerifiedAt:  user.PhoneVerifiedAt,
 		BackupCodes:      user.BackupCodes,
 		PasswordResetAt:  user.PasswordChangedAt,
 	}
@@ -107,6 +111,10 @@ func (r *GormUserRepository) Update(ctx context.Context, user *User) error {
 		LockedUntil:      user.LockedUntil,
 		TwoFactorEnabled: user.OTPEnabled,
 		TwoFactorSecret:  user.OTPSecret,
+		SMSOTPEnabled:    user.SMSOTPEnabled,
+		Phone:            user.Phone,
+		PhoneVerified:    user.PhoneVerified,
+		PhoneVerifiedAt:  user.PhoneVerifiedAt,
 		BackupCodes:      user.BackupCodes,
 		PasswordResetAt:  user.PasswordChangedAt,
 	}
@@ -208,6 +216,10 @@ func convertToAuthUser(modelUser *models.User) *User {
 		LockedUntil:       modelUser.LockedUntil,
 		OTPEnabled:        modelUser.TwoFactorEnabled,
 		OTPSecret:         modelUser.TwoFactorSecret,
+		SMSOTPEnabled:     modelUser.SMSOTPEnabled,
+		Phone:             modelUser.Phone,
+		PhoneVerified:     modelUser.PhoneVerified,
+		PhoneVerifiedAt:   modelUser.PhoneVerifiedAt,
 		BackupCodes:       modelUser.BackupCodes,
 		PasswordChangedAt: modelUser.PasswordResetAt,
 		CreatedAt:         modelUser.CreatedAt,
@@ -286,10 +298,10 @@ func (r *GormTokenRepository) CreateEmailVerification(ctx context.Context, verif
 	return r.db.WithContext(ctx).Create(verification).Error
 }
 
-// GetEmailVerification 获取邮箱验证
-func (r *GormTokenRepository) GetEmailVerification(ctx context.Context, token string) (*EmailVerification, error) {
+// GetEmailVerification 根据令牌哈希获取邮箱验证
+func (r *GormTokenRepository) GetEmailVerification(ctx context.Context, tokenHash string) (*EmailVerification, error) {
 	var verification EmailVerification
-	if err := r.db.WithContext(ctx).Where("token = ? AND used = false AND expires_at > ?", token, time.Now()).First(&verification).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("token = ? AND used = false AND expires_at > ?", tokenHash, time.Now()).First(&verification).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, ErrInvalidToken
 		}
@@ -298,13 +310,22 @@ func (r *GormTokenRepository) GetEmailVerification(ctx context.Context, token st
 	return &verification, nil
 }
 
-// UseEmailVerification 使用邮箱验证
-func (r *GormTokenRepository) UseEmailVerification(ctx context.Context, token string) error {
+// UseEmailVerification 使用邮箱验证（仅未使用的记录会被更新）
+func (r *GormTokenRepository) UseEmailVerification(ctx context.Context, id uint, ipAddress, userAgent string) error {
 	now := time.Now()
-	return r.db.WithContext(ctx).Model(&EmailVerification{}).Where("token = ?", token).Updates(map[string]interface{}{
-		"used":    true,
-		"used_at": &now,
-	}).Error
+	result := r.db.WithContext(ctx).Model(&EmailVerification{}).Where("id = ? AND used = ?", id, false).Updates(map[string]interface{}{
+		"used":            true,
+		"used_at":         &now,
+		"used_ip":         ipAddress,
+		"used_user_agent": truncateUserAgent(userAgent),
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidToken
+	}
+	return nil
 }
 
 // CreatePasswordReset 创建密码重置
@@ -312,10 +333,10 @@ func (r *GormTokenRepository) CreatePasswordReset(ctx context.Context, reset *Pa
 	return r.db.WithContext(ctx).Create(reset).Error
 }
 
-// GetPasswordReset 获取密码重置
-func (r *GormTokenRepository) GetPasswordReset(ctx context.Context, token string) (*PasswordReset, error) {
+// GetPasswordReset 根据令牌哈希获取密码重置
+func (r *GormTokenRepository) GetPasswordReset(ctx context.Context, tokenHash string) (*PasswordReset, error) {
 	var reset PasswordReset
-	if err := r.db.WithContext(ctx).Where("token = ? AND used = false AND expires_at > ?", token, time.Now()).First(&reset).Error; err != nil {
+	if err := r.db.WithContext(ctx).Where("token = ? AND used = false AND expires_at > ?", tokenHash, time.Now()).First(&reset).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, ErrInvalidToken
 		}
@@ -324,13 +345,64 @@ func (r *GormTokenRepository) GetPasswordReset(ctx context.Context, token string
 	return &reset, nil
 }
 
-// UsePasswordReset 使用密码重置
-func (r *GormTokenRepository) UsePasswordReset(ctx context.Context, token string) error {
+// UsePasswordReset 使用密码重置（仅未使用的记录会被更新）
+func (r *GormTokenRepository) UsePasswordReset(ctx context.Context, id uint, ipAddress, userAgent string) error {
 	now := time.Now()
-	return r.db.WithContext(ctx).Model(&PasswordReset{}).Where("token = ?", token).Updates(map[string]interface{}{
-		"used":    true,
-		"used_at": &now,
-	}).Error
+	result := r.db.WithContext(ctx).Model(&PasswordReset{}).Where("id = ? AND used = ?", id, false).Updates(map[string]interface{}{
+		"used":            true,
+		"used_at":         &now,
+		"used_ip":         ipAddress,
+		"used_user_agent": truncateUserAgent(userAgent),
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+// CreateMagicLink 创建免密登录链接
+func (r *GormTokenRepository) CreateMagicLink(ctx context.Context, link *MagicLinkToken) error {
+	return r.db.WithContext(ctx).Create(link).Error
+}
+
+// GetMagicLink 根据令牌哈希获取免密登录链接
+func (r *GormTokenRepository) GetMagicLink(ctx context.Context, tokenHash string) (*MagicLinkToken, error) {
+	var link MagicLinkToken
+	if err := r.db.WithContext(ctx).Where("token = ? AND used = ? AND expires_at > ?", tokenHash, false, time.Now()).First(&link).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidToken
+		}
+		return nil, err
+	}
+	return &link, nil
+}
+
+// UseMagicLink 使用免密登录链接（仅未使用的记录会被更新）
+func (r *GormTokenRepository) UseMagicLink(ctx context.Context, id uint, ipAddress, userAgent string) error {
+	now := time.Now()
+	result := r.db.WithContext(ctx).Model(&MagicLinkToken{}).Where("id = ? AND used = ?", id, false).Updates(map[string]interface{}{
+		"used":            true,
+		"used_at":         &now,
+		"used_ip":         ipAddress,
+		"used_user_agent": truncateUserAgent(userAgent),
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidToken
+	}
+	return nil
+}
+
+// CountMagicLinksSince 统计某邮箱在某时间之后申请的免密登录链接数量
+func (r *GormTokenRepository) CountMagicLinksSince(ctx context.Context, email string, since time.Time) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&MagicLinkToken{}).Where("email = ? AND created_at >= ?", email, since).Count(&count).Error
+	return count, err
 }
 
 // CreateOTPCode 创建OTP验证码
@@ -501,6 +573,28 @@ func (r *GormLoginHistoryRepository) RefreshSession(ctx context.Context, userID
 	return r.db.WithContext(ctx).Model(&history).Updates(updates).Error
 }
 
+// GetSession 获取会话最近的登录记录，不存在时返回 gorm.ErrRecordNotFound
+func (r *GormLoginHistoryRepository) GetSession(ctx context.Context, userID uint, sessionID string) (*models.LoginHistory, error) {
+	var history models.LoginHistory
+	if err := r.db.WithContext(ctx).
+		Where("user_id = ? AND session_id = ?", userID, sessionID).
+		Order("login_time DESC").
+		First(&history).Error; err != nil {
+		return nil, err
+	}
+	return &history, nil
+}
+
+// TouchSession 记录活跃会话的最近活动时间
+func (r *GormLoginHistoryRepository) TouchSession(ctx context.Context, userID uint, sessionID string, at time.Time) error {
+	if sessionID == "" {
+		return nil
+	}
+	return r.db.WithContext(ctx).Model(&models.LoginHistory{}).
+		Where("user_id = ? AND session_id = ? AND is_active = ?", userID, sessionID, true).
+		UpdateColumn("last_activity_at", at).Error
+}
+
 // EndSession 结束指定会话
 func (r *GormLoginHistoryRepository) EndSession(ctx context.Context, userID uint, sessionID string, status models.LoginStatus, reason string, at time.Time) error {
 	if sessionID == "" {
@@ -612,3 +706,26 @@ func (r *GormTrustedDeviceRepository) ListActiveDevices(ctx context.Context, use
 		Find(&devices).Error
 	return devices, err
 }
+
+// HasSuccessfulLoginFromDevice 判断用户是否曾从该设备成功登录
+func (r *GormLoginHistoryRepository) HasSuccessfulLoginFromDevice(ctx context.Context, userID uint, userAgent string) (bool, error) {
+	if userAgent == "" {
+		return false, nil
+	}
+
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&models.LoginHistory{}).
+		Where("user_id = ? AND login_status = ? AND user_agent = ?", userID, models.LoginStatusSuccess, userAgent).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// truncateUserAgent 截断过长的User-Agent以适配列宽
+func truncateUserAgent(userAgent string) string {
+	if len(userAgent) > 500 {
+		return userAgent[:500]
+	}
+	return userAgent
+}
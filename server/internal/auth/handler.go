@@ -8,12 +8,16 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
 )
 
 // AuthHandler 认证处理器
 type AuthHandler struct {
-	authService *AuthService
-	logger      Logger
+	authService   *AuthService
+	logger        Logger
+	cookieSession *CookieSessionConfig // 为 nil 时使用 Bearer 模式
 }
 
 // Logger 日志接口
@@ -58,6 +62,7 @@ func NewAuthHandler(authService *AuthService, logger Logger) *AuthHandler {
 type HTTPContext interface {
 	GetHeader(key string) string
 	SetHeader(key, value string)
+	SetCookie(cookie *http.Cookie)
 	GetQuery(key string) string
 	GetParam(key string) string
 	Bind(obj interface{}) error
@@ -145,6 +150,11 @@ func (h *AuthHandler) Register(c HTTPContext) {
 	}
 
 	h.logger.Info("User registered successfully", "user_id", resp.User.ID, "email", req.Email)
+	if err := h.issueSessionCookies(c, resp); err != nil {
+		h.logger.Error("Failed to issue session cookies", "error", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "session_error", Message: "Failed to create session"})
+		return
+	}
 
 	// 返回成功响应
 	c.JSON(http.StatusCreated, map[string]interface{}{
@@ -185,6 +195,9 @@ func (h *AuthHandler) Login(c HTTPContext) {
 	resp, err := h.authService.Login(ctx, &req, ipAddress, userAgent)
 	if err != nil {
 		h.logger.Error("Login failed", "error", err, "email", req.Email)
+		if h.respondPendingDeletion(c, err) {
+			return
+		}
 
 		message := "Login failed"
 		status := http.StatusUnauthorized
@@ -202,6 +215,9 @@ func (h *AuthHandler) Login(c HTTPContext) {
 			status = http.StatusForbidden
 		case ErrInvalidOTP:
 			message = "Invalid OTP code"
+		case ErrOTPExpired:
+			message = "OTP code expired"
+			status = http.StatusBadRequest
 		default:
 			if strings.Contains(err.Error(), "OTP") {
 				message = "OTP code required"
@@ -222,8 +238,14 @@ func (h *AuthHandler) Login(c HTTPContext) {
 
 	h.logger.Info("User logged in successfully", "user_id", resp.User.ID, "email", req.Email)
 
-	// 设置安全头
-	c.SetHeader("X-Auth-Token", resp.AccessToken)
+	// 设置安全头；Cookie 模式下令牌只写入 httpOnly Cookie
+	if h.cookieSession == nil {
+		c.SetHeader("X-Auth-Token", resp.AccessToken)
+	} else if err := h.issueSessionCookies(c, resp); err != nil {
+		h.logger.Error("Failed to issue session cookies", "error", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "session_error", Message: "Failed to create session"})
+		return
+	}
 
 	// 返回成功响应 - 使用ApiResponse格式与前端保持一致
 	c.JSON(http.StatusOK, map[string]interface{}{
@@ -236,13 +258,16 @@ func (h *AuthHandler) Login(c HTTPContext) {
 // RefreshToken 刷新令牌
 func (h *AuthHandler) RefreshToken(c HTTPContext) {
 	var req RefreshTokenRequest
-	if err := c.Bind(&req); err != nil {
-		h.logger.Error("Failed to bind refresh token request", "error", err)
-		c.JSON(http.StatusBadRequest, ErrorResponse{
-			Error:   "invalid_request",
-			Message: "Invalid request format",
-		})
-		return
+	// Cookie 模式下刷新令牌来自 Cookie，请求体可以为空
+	if req.RefreshToken = h.sessionTokenFromCookie(c, RefreshTokenCookie); req.RefreshToken == "" {
+		if err := c.Bind(&req); err != nil {
+			h.logger.Error("Failed to bind refresh token request", "error", err)
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "invalid_request",
+				Message: "Invalid request format",
+			})
+			return
+		}
 	}
 
 	if req.RefreshToken == "" {
@@ -277,6 +302,12 @@ func (h *AuthHandler) RefreshToken(c HTTPContext) {
 		case ErrUserNotFound:
 			code = "user_not_found"
 			message = "User not found"
+		case ErrSessionIdleTimeout:
+			code = "session_idle_timeout"
+			message = "Session expired due to inactivity, please log in again"
+		case ErrOTPSetupRequired:
+			code = "otp_setup_required"
+			message = "Your role requires two-factor authentication, please enable it and log in again"
 		}
 
 		c.JSON(status, ErrorResponse{
@@ -288,6 +319,11 @@ func (h *AuthHandler) RefreshToken(c HTTPContext) {
 	}
 
 	h.logger.Info("Token refreshed successfully", "user_id", resp.User.ID)
+	if err := h.issueSessionCookies(c, resp); err != nil {
+		h.logger.Error("Failed to issue session cookies", "error", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "session_error", Message: "Failed to refresh session"})
+		return
+	}
 
 	// 返回成功响应
 	c.JSON(http.StatusOK, SuccessResponse{
@@ -301,6 +337,9 @@ func (h *AuthHandler) RefreshToken(c HTTPContext) {
 func (h *AuthHandler) Logout(c HTTPContext) {
 	// 从头部获取刷新令牌
 	refreshToken := c.GetHeader("X-Refresh-Token")
+	if refreshToken == "" {
+		refreshToken = h.sessionTokenFromCookie(c, RefreshTokenCookie)
+	}
 	if refreshToken == "" {
 		// 尝试从请求体获取
 		var req struct {
@@ -316,6 +355,7 @@ func (h *AuthHandler) Logout(c HTTPContext) {
 		h.logger.Error("Logout failed", "error", err)
 	}
 
+	h.clearSessionCookies(c)
 	h.logger.Info("User logged out successfully")
 
 	// 返回成功响应
@@ -407,6 +447,80 @@ func (h *AuthHandler) GetProfile(c HTTPContext) {
 	})
 }
 
+// GetSessionPolicy 获取当前用户角色生效的会话策略（刷新令牌有效期、可信设备、第二因子要求、空闲超时）
+func (h *AuthHandler) GetSessionPolicy(c HTTPContext) {
+	userInfo, err := GetUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	policy, err := h.authService.GetSessionPolicy(context.Background(), userInfo.ID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error:   "user_not_found",
+			Message: "User not found",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Data:    policy,
+	})
+}
+
+// GetSessionIdle 获取当前会话的空闲状态，供前端在到期前提示用户；查询本身不计入会话活动
+func (h *AuthHandler) GetSessionIdle(c HTTPContext) {
+	h.respondSessionIdle(c)
+}
+
+// KeepSessionAlive 用户确认继续使用时调用，立即记录会话活动并返回新的空闲状态
+func (h *AuthHandler) KeepSessionAlive(c HTTPContext) {
+	userInfo, err := GetUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+	sessionID, _ := c.Get("session_id")
+	sid, _ := sessionID.(string)
+	h.authService.RecordSessionActivity(context.Background(), userInfo.ID, sid, true)
+	h.respondSessionIdle(c)
+}
+
+func (h *AuthHandler) respondSessionIdle(c HTTPContext) {
+	userInfo, err := GetUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+	sessionID, _ := c.Get("session_id")
+	sid, _ := sessionID.(string)
+
+	status, err := h.authService.GetSessionIdleStatus(context.Background(), userInfo.ID, sid)
+	if err != nil {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error:   "user_not_found",
+			Message: "User not found",
+		})
+		return
+	}
+	// 角色未设置空闲超时时 data 为 null
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Data:    status,
+	})
+}
+
 // Health 健康检查
 func (h *AuthHandler) Health(c HTTPContext) {
 	c.JSON(http.StatusOK, SuccessResponse{
@@ -428,7 +542,7 @@ func (h *AuthHandler) ForgotPassword(c HTTPContext) {
 	}
 
 	ctx := context.Background()
-	err := h.authService.ForgotPassword(ctx, req.Email)
+	err := h.authService.ForgotPassword(ctx, req.Email, c.ClientIP(), c.UserAgent())
 	if err != nil {
 		h.logger.Error("Failed to process forgot password", "error", err, "email", req.Email)
 		c.JSON(http.StatusInternalServerError, ErrorResponse{
@@ -444,6 +558,131 @@ func (h *AuthHandler) ForgotPassword(c HTTPContext) {
 	})
 }
 
+// RequestMagicLink 申请免密登录链接
+func (h *AuthHandler) RequestMagicLink(c HTTPContext) {
+	var req MagicLinkRequest
+	if err := c.Bind(&req); err != nil || req.Email == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Email is required",
+		})
+		return
+	}
+
+	ctx := context.Background()
+	err := h.authService.RequestMagicLink(ctx, req.Email, c.ClientIP(), c.UserAgent())
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrMagicLinkDisabled):
+			c.JSON(http.StatusForbidden, ErrorResponse{
+				Error:   "magic_link_disabled",
+				Message: "Magic link login is disabled",
+			})
+		case strings.Contains(err.Error(), "too many"):
+			c.JSON(http.StatusTooManyRequests, ErrorResponse{
+				Error:   "too_many_requests",
+				Message: "Too many sign-in link requests, please try again later",
+			})
+		default:
+			h.logger.Error("Failed to process magic link request", "error", err, "email", req.Email)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{
+				Error:   "magic_link_failed",
+				Message: "Failed to process sign-in link request",
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: "If the account exists, a sign-in link has been sent",
+	})
+}
+
+// VerifyMagicLink 使用免密登录链接登录
+func (h *AuthHandler) VerifyMagicLink(c HTTPContext) {
+	req := MagicLinkVerifyRequest{
+		Token:          c.GetQuery("token"),
+		OTPCode:        c.GetQuery("otp_code"),
+		OTPMethod:      c.GetQuery("otp_method"),
+		DeviceToken:    c.GetQuery("device_token"),
+		RememberDevice: c.GetQuery("remember_device") == "true",
+		DeviceName:     c.GetQuery("device_name"),
+	}
+	if req.DeviceToken == "" {
+		req.DeviceToken = c.GetHeader("X-Device-Token")
+	}
+	if req.Token == "" {
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"code": 1,
+			"msg":  "Token is required",
+			"data": nil,
+		})
+		return
+	}
+
+	ctx := context.Background()
+	resp, err := h.authService.VerifyMagicLink(ctx, &req, c.ClientIP(), c.UserAgent())
+	if err != nil {
+		h.logger.Error("Magic link login failed", "error", err)
+		if h.respondPendingDeletion(c, err) {
+			return
+		}
+
+		message := "Login failed"
+		status := http.StatusUnauthorized
+
+		switch err {
+		case ErrInvalidToken:
+			message = "Invalid or expired sign-in link"
+		case ErrMagicLinkDisabled:
+			message = "Magic link login is disabled"
+			status = http.StatusForbidden
+		case ErrAccountLocked:
+			message = "Account is locked"
+			status = http.StatusForbidden
+		case ErrEmailNotVerified:
+			message = "Email not verified"
+			status = http.StatusForbidden
+		case ErrInvalidOTP:
+			message = "Invalid OTP code"
+		case ErrOTPExpired:
+			message = "OTP code expired"
+			status = http.StatusBadRequest
+		case ErrOTPRequired:
+			message = "OTP code required"
+			status = http.StatusBadRequest
+		default:
+			if strings.Contains(err.Error(), "too many") {
+				message = "Too many failed login attempts"
+				status = http.StatusTooManyRequests
+			}
+		}
+
+		c.JSON(status, map[string]interface{}{
+			"code": 1,
+			"msg":  message,
+			"data": nil,
+		})
+		return
+	}
+
+	h.logger.Info("User logged in with magic link", "user_id", resp.User.ID)
+
+	if h.cookieSession == nil {
+		c.SetHeader("X-Auth-Token", resp.AccessToken)
+	} else if err := h.issueSessionCookies(c, resp); err != nil {
+		h.logger.Error("Failed to issue session cookies", "error", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "session_error", Message: "Failed to create session"})
+		return
+	}
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"code": 0,
+		"msg":  "Login successful",
+		"data": resp,
+	})
+}
+
 // ResetPassword 重置密码
 func (h *AuthHandler) ResetPassword(c HTTPContext) {
 	var req ResetPasswordRequest
@@ -457,7 +696,7 @@ func (h *AuthHandler) ResetPassword(c HTTPContext) {
 	}
 
 	ctx := context.Background()
-	err := h.authService.ResetPassword(ctx, req.Token, req.NewPassword)
+	err := h.authService.ResetPassword(ctx, req.Token, req.NewPassword, c.ClientIP(), c.UserAgent())
 	if err != nil {
 		h.logger.Error("Failed to reset password", "error", err)
 		if err == ErrInvalidToken {
@@ -492,7 +731,7 @@ func (h *AuthHandler) VerifyEmail(c HTTPContext) {
 	}
 
 	ctx := context.Background()
-	err := h.authService.VerifyEmail(ctx, token)
+	err := h.authService.VerifyEmail(ctx, token, c.ClientIP(), c.UserAgent())
 	if err != nil {
 		h.logger.Error("Failed to verify email", "error", err)
 		if err == ErrInvalidToken {
@@ -605,7 +844,7 @@ func (h *AuthHandler) ChangePassword(c HTTPContext) {
 		return
 	}
 
-	ctx := context.Background()
+	ctx := withAuditScope(context.Background(), c, userInfo.ID)
 	err = h.authService.ChangePassword(ctx, userInfo.ID, req.CurrentPassword, req.NewPassword)
 	if err != nil {
 		h.logger.Error("Failed to change password", "error", err, "userID", userInfo.ID)
@@ -629,91 +868,447 @@ func (h *AuthHandler) ChangePassword(c HTTPContext) {
 	})
 }
 
-// EnableOTP 启用OTP
-func (h *AuthHandler) EnableOTP(c HTTPContext) {
+// withAuditScope 附加操作人和客户端信息，账户安全事件写入审计日志时使用
+func withAuditScope(ctx context.Context, c HTTPContext, userID uint) context.Context {
+	return services.WithAuditScope(ctx, &userID, c.ClientIP(), c.UserAgent())
+}
+
+// respondPendingDeletion 账户处于注销宽限期时返回恢复入口，而不是普通的登录失败
+func (h *AuthHandler) respondPendingDeletion(c HTTPContext, err error) bool {
+	var pendingErr *PendingDeletionError
+	if !errors.As(err, &pendingErr) {
+		return false
+	}
+	c.JSON(http.StatusForbidden, map[string]interface{}{
+		"code": 1,
+		"msg":  "Account is scheduled for deletion",
+		"data": map[string]interface{}{
+			"pending_deletion":      true,
+			"scheduled_deletion_at": pendingErr.ScheduledAt,
+			"restore_url":           "/api/auth/restore-account",
+		},
+	})
+	return true
+}
+
+// RequestAccountDeletion 申请注销当前账户
+func (h *AuthHandler) RequestAccountDeletion(c HTTPContext) {
 	userInfo, err := GetUserFromContext(c)
 	if err != nil {
+		h.logger.Error("Failed to get user from context", "error", err)
 		c.JSON(http.StatusUnauthorized, ErrorResponse{
 			Error:   "unauthorized",
-			Message: "User not authenticated",
+			Message: "Authentication required",
 		})
 		return
 	}
 
-	var req EnableOTPRequest
-	if err := c.Bind(&req); err != nil {
-		h.logger.Error("Failed to bind enable OTP request", "error", err)
+	var req DeleteAccountRequest
+	if err := c.Bind(&req); err != nil || req.Password == "" {
 		c.JSON(http.StatusBadRequest, ErrorResponse{
 			Error:   "invalid_request",
-			Message: "Invalid request body",
+			Message: "Password is required",
 		})
 		return
 	}
 
-	ctx := c.Request().Context()
-	if ctx == nil {
-		ctx = context.Background()
+	ctx := context.Background()
+	if err := h.authService.RequestAccountDeletion(ctx, userInfo.ID, &req, c.ClientIP(), c.UserAgent()); err != nil {
+		h.logger.Error("Failed to request account deletion", "error", err, "userID", userInfo.ID)
+		switch {
+		case errors.Is(err, ErrInvalidCredentials):
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "invalid_password",
+				Message: "Password is incorrect",
+			})
+		case errors.Is(err, services.ErrAccountDeletionPending):
+			c.JSON(http.StatusConflict, ErrorResponse{
+				Error:   "deletion_pending",
+				Message: "Account deletion is already scheduled",
+			})
+		case errors.Is(err, services.ErrAccountDeletionNotAllowed):
+			c.JSON(http.StatusForbidden, ErrorResponse{
+				Error:   "deletion_not_allowed",
+				Message: "This account cannot be deleted",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, ErrorResponse{
+				Error:   "deletion_request_failed",
+				Message: "Failed to request account deletion",
+			})
+		}
+		return
 	}
 
-	otpSetup, err := h.authService.EnableOTP(ctx, userInfo.ID, req.Password)
-	if err != nil {
-		h.logger.Error("Failed to enable OTP", "error", err, "user_id", userInfo.ID)
-		status := http.StatusInternalServerError
-		message := "Failed to enable OTP"
+	c.JSON(http.StatusAccepted, SuccessResponse{
+		Success: true,
+		Message: "A confirmation link has been sent to your email",
+	})
+}
 
-		if errors.Is(err, ErrInvalidCredentials) {
-			status = http.StatusUnauthorized
-			message = "Invalid password"
-		} else if errors.Is(err, ErrUserNotFound) {
-			status = http.StatusNotFound
-			message = "User not found"
-		} else if err.Error() == "OTP already enabled" {
-			status = http.StatusBadRequest
-			message = "OTP already enabled"
+// ConfirmAccountDeletion 通过邮件链接确认注销，账户进入宽限期
+func (h *AuthHandler) ConfirmAccountDeletion(c HTTPContext) {
+	var req ConfirmAccountDeletionRequest
+	if err := c.Bind(&req); err != nil || req.Token == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Token is required",
+		})
+		return
+	}
+
+	ctx := context.Background()
+	scheduledAt, err := h.authService.ConfirmAccountDeletion(ctx, &req, c.ClientIP(), c.UserAgent())
+	if err != nil {
+		h.logger.Error("Failed to confirm account deletion", "error", err)
+		if errors.Is(err, services.ErrAccountDeletionNotFound) {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Error:   "invalid_token",
+				Message: "Invalid or expired confirmation link",
+			})
+			return
 		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "deletion_confirm_failed",
+			Message: "Failed to confirm account deletion",
+		})
+		return
+	}
 
-		c.JSON(status, ErrorResponse{
-			Error:   "enable_otp_failed",
-			Message: message,
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: "Account deletion confirmed",
+		Data: map[string]interface{}{
+			"scheduled_deletion_at": scheduledAt,
+		},
+	})
+}
+
+// RestoreAccount 宽限期内恢复账户
+func (h *AuthHandler) RestoreAccount(c HTTPContext) {
+	var req RestoreAccountRequest
+	if err := c.Bind(&req); err != nil || req.Email == "" || req.Password == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Email and password are required",
 		})
 		return
 	}
 
+	ctx := context.Background()
+	if err := h.authService.RestoreAccount(ctx, &req, c.ClientIP(), c.UserAgent()); err != nil {
+		h.logger.Error("Failed to restore account", "error", err, "email", req.Email)
+		switch {
+		case errors.Is(err, ErrInvalidCredentials):
+			c.JSON(http.StatusUnauthorized, ErrorResponse{
+				Error:   "invalid_credentials",
+				Message: "Invalid email or password",
+			})
+		case errors.Is(err, services.ErrAccountDeletionNotFound):
+			c.JSON(http.StatusNotFound, ErrorResponse{
+				Error:   "no_pending_deletion",
+				Message: "Account is not scheduled for deletion",
+			})
+		case strings.Contains(err.Error(), "too many"):
+			c.JSON(http.StatusTooManyRequests, ErrorResponse{
+				Error:   "too_many_attempts",
+				Message: "Too many failed login attempts",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, ErrorResponse{
+				Error:   "restore_failed",
+				Message: "Failed to restore account",
+			})
+		}
+		return
+	}
+
 	c.JSON(http.StatusOK, SuccessResponse{
 		Success: true,
-		Message: "OTP enabled successfully",
-		Data:    otpSetup,
+		Message: "Account restored, you can sign in again",
 	})
 }
 
-// DisableOTP 禁用OTP
-func (h *AuthHandler) DisableOTP(c HTTPContext) {
+// InviteUsers 管理员批量邀请用户，单个条目失败不影响其他条目
+func (h *AuthHandler) InviteUsers(c HTTPContext) {
 	userInfo, err := GetUserFromContext(c)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, ErrorResponse{
 			Error:   "unauthorized",
-			Message: "User not authenticated",
+			Message: "Authentication required",
 		})
 		return
 	}
 
-	var req struct {
-		Password string `json:"password" binding:"required"`
-	}
+	var req models.UserInviteRequest
 	if err := c.Bind(&req); err != nil {
-		h.logger.Error("Failed to bind disable OTP request", "error", err)
 		c.JSON(http.StatusBadRequest, ErrorResponse{
 			Error:   "invalid_request",
-			Message: "Invalid request body",
+			Message: "Invalid request format: " + err.Error(),
 		})
 		return
 	}
 
-	ctx := c.Request().Context()
-	if ctx == nil {
-		ctx = context.Background()
+	results, err := h.authService.InviteUsers(context.Background(), &req, userInfo.ID)
+	if err != nil {
+		h.logger.Error("Failed to invite users", "error", err, "adminID", userInfo.ID)
+		h.respondInvitationError(c, err)
+		return
 	}
 
+	succeeded := 0
+	for _, result := range results {
+		if result.Success {
+			succeeded++
+		}
+	}
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: "Invitations processed",
+		Data: map[string]interface{}{
+			"results":   results,
+			"succeeded": succeeded,
+			"failed":    len(results) - succeeded,
+		},
+	})
+}
+
+// ListInvitations 分页获取邀请及各状态数量
+func (h *AuthHandler) ListInvitations(c HTTPContext) {
+	page, _ := strconv.Atoi(c.GetQuery("page"))
+	pageSize, _ := strconv.Atoi(c.GetQuery("page_size"))
+
+	items, total, summary, err := h.authService.ListInvitations(context.Background(), c.GetQuery("status"), page, pageSize)
+	if err != nil {
+		h.logger.Error("Failed to list invitations", "error", err)
+		h.respondInvitationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"items":   items,
+			"total":   total,
+			"summary": summary,
+		},
+	})
+}
+
+// ResendInvitation 重发邀请邮件，旧链接随之失效
+func (h *AuthHandler) ResendInvitation(c HTTPContext) {
+	id, err := strconv.ParseUint(c.GetParam("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid invitation ID",
+		})
+		return
+	}
+
+	invitation, err := h.authService.ResendInvitation(context.Background(), uint(id))
+	if err != nil {
+		h.logger.Error("Failed to resend invitation", "error", err, "invitationID", id)
+		h.respondInvitationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: "Invitation resent",
+		Data:    invitation,
+	})
+}
+
+// RevokeInvitation 撤销待接受的邀请
+func (h *AuthHandler) RevokeInvitation(c HTTPContext) {
+	id, err := strconv.ParseUint(c.GetParam("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid invitation ID",
+		})
+		return
+	}
+	if err := h.authService.RevokeInvitation(context.Background(), uint(id)); err != nil {
+		h.logger.Error("Failed to revoke invitation", "error", err, "invitationID", id)
+		h.respondInvitationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: "Invitation revoked",
+	})
+}
+
+// GetInvitation 校验邀请链接，返回被邀请邮箱、角色及是否需要设置OTP
+func (h *AuthHandler) GetInvitation(c HTTPContext) {
+	info, err := h.authService.GetInvitation(context.Background(), c.GetParam("token"))
+	if err != nil {
+		h.respondInvitationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Data:    info,
+	})
+}
+
+// AcceptInvitation 设置密码（可选同时启用OTP）接受邀请并直接登录
+func (h *AuthHandler) AcceptInvitation(c HTTPContext) {
+	var req AcceptInvitationRequest
+	if err := c.Bind(&req); err != nil || req.Password == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Password is required",
+		})
+		return
+	}
+
+	resp, err := h.authService.AcceptInvitation(context.Background(), c.GetParam("token"), &req, c.ClientIP(), c.UserAgent())
+	if err != nil {
+		h.logger.Error("Failed to accept invitation", "error", err)
+		h.respondInvitationError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: "Invitation accepted",
+		Data:    resp,
+	})
+}
+
+// respondInvitationError 将邀请相关错误映射为响应
+func (h *AuthHandler) respondInvitationError(c HTTPContext, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvitationNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error:   "invalid_invitation",
+			Message: "Invitation not found or expired",
+		})
+	case errors.Is(err, services.ErrInvitationNotPending):
+		c.JSON(http.StatusConflict, ErrorResponse{
+			Error:   "invitation_not_pending",
+			Message: "Invitation has already been accepted or revoked",
+		})
+	case errors.Is(err, services.ErrInvitationConflict):
+		c.JSON(http.StatusConflict, ErrorResponse{
+			Error:   "invitation_conflict",
+			Message: err.Error(),
+		})
+	case errors.Is(err, services.ErrQuotaExceeded):
+		c.JSON(http.StatusForbidden, ErrorResponse{
+			Error:   "quota_exceeded",
+			Message: "Agent seat quota exceeded",
+		})
+	case errors.Is(err, services.ErrInvalidInvitationStatus):
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: err.Error(),
+		})
+	case errors.Is(err, ErrPasswordTooWeak):
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_password",
+			Message: err.Error(),
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "invitation_failed",
+			Message: "Failed to process invitation",
+		})
+	}
+}
+
+// EnableOTP 启用OTP
+func (h *AuthHandler) EnableOTP(c HTTPContext) {
+	userInfo, err := GetUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	var req EnableOTPRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger.Error("Failed to bind enable OTP request", "error", err)
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx = withAuditScope(ctx, c, userInfo.ID)
+
+	otpSetup, err := h.authService.EnableOTP(ctx, userInfo.ID, req.Password)
+	if err != nil {
+		h.logger.Error("Failed to enable OTP", "error", err, "user_id", userInfo.ID)
+		status := http.StatusInternalServerError
+		message := "Failed to enable OTP"
+
+		if errors.Is(err, ErrInvalidCredentials) {
+			status = http.StatusUnauthorized
+			message = "Invalid password"
+		} else if errors.Is(err, ErrUserNotFound) {
+			status = http.StatusNotFound
+			message = "User not found"
+		} else if err.Error() == "OTP already enabled" {
+			status = http.StatusBadRequest
+			message = "OTP already enabled"
+		}
+
+		c.JSON(status, ErrorResponse{
+			Error:   "enable_otp_failed",
+			Message: message,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: "OTP enabled successfully",
+		Data:    otpSetup,
+	})
+}
+
+// DisableOTP 禁用OTP
+func (h *AuthHandler) DisableOTP(c HTTPContext) {
+	userInfo, err := GetUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	var req struct {
+		Password string `json:"password" binding:"required"`
+	}
+	if err := c.Bind(&req); err != nil {
+		h.logger.Error("Failed to bind disable OTP request", "error", err)
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx = withAuditScope(ctx, c, userInfo.ID)
+
 	if err := h.authService.DisableOTP(ctx, userInfo.ID, req.Password); err != nil {
 		h.logger.Error("Failed to disable OTP", "error", err, "user_id", userInfo.ID)
 		status := http.StatusInternalServerError
@@ -722,6 +1317,9 @@ func (h *AuthHandler) DisableOTP(c HTTPContext) {
 		if errors.Is(err, ErrInvalidCredentials) {
 			status = http.StatusUnauthorized
 			message = "Invalid password"
+		} else if errors.Is(err, ErrOTPPolicyRequired) {
+			status = http.StatusForbidden
+			message = "Your role requires two-factor authentication"
 		}
 
 		c.JSON(status, ErrorResponse{
@@ -824,6 +1422,227 @@ func (h *AuthHandler) GenerateBackupCodes(c HTTPContext) {
 	})
 }
 
+// RequestLoginSMSCode 登录第二因子：校验密码后向已验证的手机号发送验证码
+func (h *AuthHandler) RequestLoginSMSCode(c HTTPContext) {
+	var req LoginSMSCodeRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger.Error("Failed to bind login sms code request", "error", err)
+		c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"code": 1,
+			"msg":  "Invalid request format",
+			"data": nil,
+		})
+		return
+	}
+
+	ctx := context.Background()
+	resp, err := h.authService.RequestLoginSMSCode(ctx, &req, c.ClientIP(), c.UserAgent())
+	if err != nil {
+		h.logger.Error("Failed to send login sms code", "error", err, "email", req.Email)
+		if h.respondPendingDeletion(c, err) {
+			return
+		}
+		status, message := smsErrorStatus(err)
+		switch {
+		case errors.Is(err, ErrInvalidCredentials):
+			status, message = http.StatusUnauthorized, "Invalid email or password"
+		case errors.Is(err, ErrAccountLocked):
+			status, message = http.StatusForbidden, "Account is locked"
+		case errors.Is(err, ErrEmailNotVerified):
+			status, message = http.StatusForbidden, "Email not verified"
+		case errors.Is(err, ErrSMSOTPNotEnabled):
+			status, message = http.StatusBadRequest, "SMS verification is not enabled for this account"
+		case strings.Contains(err.Error(), "too many failed"):
+			status, message = http.StatusTooManyRequests, "Too many failed login attempts"
+		}
+		c.JSON(status, map[string]interface{}{
+			"code": 1,
+			"msg":  message,
+			"data": nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, map[string]interface{}{
+		"code": 0,
+		"msg":  "Verification code sent",
+		"data": resp,
+	})
+}
+
+// SendPhoneCode 向待绑定的手机号发送验证码
+func (h *AuthHandler) SendPhoneCode(c HTTPContext) {
+	userInfo, err := GetUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	var req PhoneCodeRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger.Error("Failed to bind phone code request", "error", err)
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	resp, err := h.authService.SendPhoneVerificationCode(ctx, userInfo.ID, req.Phone, c.ClientIP(), c.UserAgent())
+	if err != nil {
+		h.logger.Error("Failed to send phone verification code", "error", err, "user_id", userInfo.ID)
+		status, message := smsErrorStatus(err)
+		c.JSON(status, ErrorResponse{
+			Error:   "send_phone_code_failed",
+			Message: message,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: "Verification code sent",
+		Data:    resp,
+	})
+}
+
+// VerifyPhone 校验手机验证码并绑定手机号
+func (h *AuthHandler) VerifyPhone(c HTTPContext) {
+	userInfo, err := GetUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	var req PhoneVerifyRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger.Error("Failed to bind verify phone request", "error", err)
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx = withAuditScope(ctx, c, userInfo.ID)
+
+	if err := h.authService.VerifyPhone(ctx, userInfo.ID, req.Phone, req.Code); err != nil {
+		h.logger.Error("Failed to verify phone", "error", err, "user_id", userInfo.ID)
+		status, message := smsErrorStatus(err)
+		c.JSON(status, ErrorResponse{
+			Error:   "verify_phone_failed",
+			Message: message,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: "Phone number verified successfully",
+	})
+}
+
+// EnableSMSOTP 启用短信验证码登录
+func (h *AuthHandler) EnableSMSOTP(c HTTPContext) {
+	h.toggleSMSOTP(c, true)
+}
+
+// DisableSMSOTP 关闭短信验证码登录
+func (h *AuthHandler) DisableSMSOTP(c HTTPContext) {
+	h.toggleSMSOTP(c, false)
+}
+
+func (h *AuthHandler) toggleSMSOTP(c HTTPContext, enable bool) {
+	userInfo, err := GetUserFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Error:   "unauthorized",
+			Message: "User not authenticated",
+		})
+		return
+	}
+
+	var req EnableOTPRequest
+	if err := c.Bind(&req); err != nil {
+		h.logger.Error("Failed to bind sms OTP request", "error", err)
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid request body",
+		})
+		return
+	}
+
+	ctx := c.Request().Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx = withAuditScope(ctx, c, userInfo.ID)
+
+	errorCode, message := "enable_sms_otp_failed", "SMS OTP enabled successfully"
+	if enable {
+		err = h.authService.EnableSMSOTP(ctx, userInfo.ID, req.Password)
+	} else {
+		errorCode, message = "disable_sms_otp_failed", "SMS OTP disabled successfully"
+		err = h.authService.DisableSMSOTP(ctx, userInfo.ID, req.Password)
+	}
+	if err != nil {
+		h.logger.Error("Failed to update sms OTP", "error", err, "user_id", userInfo.ID, "enable", enable)
+		status, msg := smsErrorStatus(err)
+		switch {
+		case errors.Is(err, ErrInvalidCredentials):
+			status, msg = http.StatusUnauthorized, "Invalid password"
+		case errors.Is(err, ErrPhoneNotVerified):
+			status, msg = http.StatusBadRequest, "Verify a phone number before enabling SMS OTP"
+		case errors.Is(err, ErrOTPPolicyRequired):
+			status, msg = http.StatusForbidden, "Your role requires two-factor authentication"
+		}
+		c.JSON(status, ErrorResponse{
+			Error:   errorCode,
+			Message: msg,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: message,
+	})
+}
+
+// smsErrorStatus 短信相关错误对应的状态码与提示
+func smsErrorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidPhoneNumber):
+		return http.StatusBadRequest, "Phone number must be in E.164 format, e.g. +8613800000000"
+	case errors.Is(err, services.ErrSMSRateLimited):
+		return http.StatusTooManyRequests, "Too many verification codes requested, please try again later"
+	case errors.Is(err, services.ErrSMSNotConfigured):
+		return http.StatusServiceUnavailable, "SMS service is not configured"
+	case errors.Is(err, services.ErrSMSSendFailed):
+		return http.StatusBadGateway, "Failed to send verification code"
+	case errors.Is(err, services.ErrSMSCodeExpired):
+		return http.StatusBadRequest, "Verification code expired"
+	case errors.Is(err, services.ErrSMSCodeInvalid):
+		return http.StatusBadRequest, "Invalid verification code"
+	}
+	return http.StatusInternalServerError, "Failed to process SMS request"
+}
+
 // 验证方法
 
 func (h *AuthHandler) validateRegisterRequest(req *RegisterRequest) error {
@@ -909,30 +1728,33 @@ func GetUserFromContext(c HTTPContext) (*UserInfo, error) {
 
 // RequireAuth 认证中间件
 func (h *AuthHandler) RequireAuth(c HTTPContext) {
-	// 获取Authorization头
+	// 获取Authorization头，Cookie 模式下没有该头时读取访问令牌 Cookie
 	authHeader := c.GetHeader("Authorization")
+	token := ""
 	if authHeader == "" {
-		c.JSON(http.StatusUnauthorized, ErrorResponse{
-			Error:   "missing_token",
-			Message: "Authorization token is required",
-		})
-		c.Abort()
-		return
-	}
-
-	// 解析Bearer令牌
-	parts := strings.SplitN(authHeader, " ", 2)
-	if len(parts) != 2 || parts[0] != "Bearer" {
-		c.JSON(http.StatusUnauthorized, ErrorResponse{
-			Error:   "invalid_token_format",
-			Message: "Invalid authorization header format",
-		})
-		c.Abort()
-		return
+		token = h.sessionTokenFromCookie(c, AccessTokenCookie)
+		if token == "" {
+			c.JSON(http.StatusUnauthorized, ErrorResponse{
+				Error:   "missing_token",
+				Message: "Authorization token is required",
+			})
+			c.Abort()
+			return
+		}
+	} else {
+		// 解析Bearer令牌
+		parts := strings.SplitN(authHeader, " ", 2)
+		if len(parts) != 2 || parts[0] != "Bearer" {
+			c.JSON(http.StatusUnauthorized, ErrorResponse{
+				Error:   "invalid_token_format",
+				Message: "Invalid authorization header format",
+			})
+			c.Abort()
+			return
+		}
+		token = parts[1]
 	}
 
-	token := parts[1]
-
 	// 验证令牌
 	claims, err := h.authService.jwtManager.VerifyAccessToken(token)
 	if err != nil {
@@ -950,11 +1772,26 @@ func (h *AuthHandler) RequireAuth(c HTTPContext) {
 	c.Set("user_role", string(claims.Role))
 	c.Set("user_role_enum", claims.Role)
 	c.Set("token_jti", claims.Jti)
+	c.Set("session_id", claims.SessionID)
+
+	// 记录会话活动时间（用于空闲超时），客户端的后台轮询可带 X-Session-Passive 头不计入
+	if _, passive := c.Get(passiveSessionKey); !passive && c.GetHeader("X-Session-Passive") == "" {
+		h.authService.RecordSessionActivity(context.Background(), claims.UserID, claims.SessionID, false)
+	}
 
 	// 继续处理
 	c.Next()
 }
 
+// passiveSessionKey 请求不计入会话活动时间的上下文标记
+const passiveSessionKey = "session_passive"
+
+// PassiveSession 标记请求不计入会话活动时间，需放在 RequireAuth 之前
+func (h *AuthHandler) PassiveSession(c HTTPContext) {
+	c.Set(passiveSessionKey, true)
+	c.Next()
+}
+
 // RequireRole 角色权限中间件
 func (h *AuthHandler) RequireRole(requiredRole UserRole) func(HTTPContext) {
 	return func(c HTTPContext) {
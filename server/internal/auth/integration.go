@@ -9,9 +9,10 @@ import (
 
 // AuthModule 认证模块
 type AuthModule struct {
-	AuthService *AuthService
-	Handler     *AuthHandler
-	Config      *AuthConfig
+	AuthService  *AuthService
+	Handler      *AuthHandler
+	EmailService *SMTPEmailService
+	Config       *AuthConfig
 }
 
 // NewAuthModule 创建认证模块
@@ -87,9 +88,10 @@ func NewAuthModule(db *gorm.DB) (*AuthModule, error) {
 	authHandler := NewAuthHandler(authService, logger)
 
 	return &AuthModule{
-		AuthService: authService,
-		Handler:     authHandler,
-		Config:      config,
+		AuthService:  authService,
+		Handler:      authHandler,
+		EmailService: emailService,
+		Config:       config,
 	}, nil
 }
 
@@ -0,0 +1,207 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// InvitationInfo 邀请链接对应的邀请信息，供接受页面展示
+type InvitationInfo struct {
+	Email       string    `json:"email"`
+	Role        string    `json:"role"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	OTPRequired bool      `json:"otp_required"` // 角色策略要求第二因子，接受时会同时完成OTP设置
+}
+
+// AcceptInvitationRequest 接受邀请请求
+type AcceptInvitationRequest struct {
+	Username        string `json:"username"` // 为空时使用邮箱前缀
+	Password        string `json:"password" validate:"required"`
+	ConfirmPassword string `json:"confirm_password" validate:"required"`
+	FirstName       string `json:"first_name"`
+	LastName        string `json:"last_name"`
+	EnableOTP       bool   `json:"enable_otp"` // 同时启用TOTP；角色策略要求第二因子时总是启用
+}
+
+// AcceptInvitationResponse 接受邀请后直接登录，启用了OTP时附带密钥与备用码
+type AcceptInvitationResponse struct {
+	*AuthResponse
+	OTPSetup *OTPSetupResponse `json:"otp_setup,omitempty"`
+}
+
+// SetUserInvitationService 设置用户邀请服务，未设置时邀请相关接口不可用
+func (s *AuthService) SetUserInvitationService(invitations *services.UserInvitationService) {
+	s.invitations = invitations
+}
+
+// InviteUsers 批量创建邀请并发送邀请邮件，邮件发送失败的邀请保留，可稍后重发
+func (s *AuthService) InviteUsers(ctx context.Context, req *models.UserInviteRequest, adminID uint) ([]*models.UserInviteResult, error) {
+	if s.invitations == nil {
+		return nil, services.ErrInvitationNotFound
+	}
+	results, err := s.invitations.Invite(ctx, req, adminID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, result := range results {
+		if !result.Success {
+			continue
+		}
+		ttl := time.Until(*result.ExpiresAt)
+		if err := s.emailService.SendInvitationEmail(ctx, result.Email, result.Token, string(result.Role), ttl); err != nil {
+			fmt.Printf("Failed to send invitation email to %s: %v\n", result.Email, err)
+			continue
+		}
+		result.EmailSent = true
+	}
+	return results, nil
+}
+
+// ResendInvitation 轮换邀请令牌并重新发送邀请邮件
+func (s *AuthService) ResendInvitation(ctx context.Context, id uint) (*models.UserInvitation, error) {
+	if s.invitations == nil {
+		return nil, services.ErrInvitationNotFound
+	}
+	invitation, token, err := s.invitations.Resend(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.emailService.SendInvitationEmail(ctx, invitation.Email, token, string(invitation.Role), time.Until(invitation.ExpiresAt)); err != nil {
+		return nil, fmt.Errorf("failed to send invitation email: %w", err)
+	}
+	return invitation, nil
+}
+
+// RevokeInvitation 撤销待接受的邀请
+func (s *AuthService) RevokeInvitation(ctx context.Context, id uint) error {
+	if s.invitations == nil {
+		return services.ErrInvitationNotFound
+	}
+	return s.invitations.Revoke(ctx, id)
+}
+
+// ListInvitations 分页获取邀请及各状态数量
+func (s *AuthService) ListInvitations(ctx context.Context, status string, page, pageSize int) ([]*models.UserInvitation, int64, *models.UserInvitationSummary, error) {
+	if s.invitations == nil {
+		return nil, 0, nil, services.ErrInvitationNotFound
+	}
+	items, total, err := s.invitations.List(ctx, status, page, pageSize)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	summary, err := s.invitations.Summary(ctx)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	return items, total, summary, nil
+}
+
+// GetInvitation 校验邀请链接并返回邀请信息
+func (s *AuthService) GetInvitation(ctx context.Context, token string) (*InvitationInfo, error) {
+	if s.invitations == nil {
+		return nil, services.ErrInvitationNotFound
+	}
+	invitation, err := s.invitations.Lookup(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	return &InvitationInfo{
+		Email:       invitation.Email,
+		Role:        string(invitation.Role),
+		ExpiresAt:   invitation.ExpiresAt,
+		OTPRequired: s.EffectiveSessionPolicy(ctx, UserRole(invitation.Role)).RequireOTP,
+	}, nil
+}
+
+// AcceptInvitation 设置密码接受邀请，创建账户后直接登录。
+// 密码不合法时不消耗邀请；请求启用OTP或角色策略要求第二因子时一并生成TOTP密钥
+func (s *AuthService) AcceptInvitation(ctx context.Context, token string, req *AcceptInvitationRequest, ipAddress, userAgent string) (*AcceptInvitationResponse, error) {
+	if s.invitations == nil {
+		return nil, services.ErrInvitationNotFound
+	}
+	if req.Password != req.ConfirmPassword {
+		return nil, fmt.Errorf("%w: passwords do not match", ErrPasswordTooWeak)
+	}
+	if err := s.passwordService.ValidatePassword(req.Password); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPasswordTooWeak, err)
+	}
+	hashedPassword, err := s.passwordService.HashPassword(req.Password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	created, err := s.invitations.Accept(ctx, token, &services.InvitationAcceptance{
+		Username:     req.Username,
+		PasswordHash: hashedPassword,
+		FirstName:    req.FirstName,
+		LastName:     req.LastName,
+	})
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.userRepo.GetByID(ctx, created.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	var otpSetup *OTPSetupResponse
+	if req.EnableOTP || s.EffectiveSessionPolicy(ctx, user.Role).RequireOTP {
+		otpSetup, err = s.setupInvitedUserOTP(ctx, user)
+		if err != nil {
+			return nil, err
+		}
+	}
+	s.recordSecurityEvent(services.WithAuditScope(ctx, &user.ID, ipAddress, userAgent), "invitation_accepted", user, "")
+
+	method := "invitation"
+	if otpSetup != nil {
+		method = "invitation+otp_setup"
+	}
+	authResponse, err := s.completeLogin(ctx, user, &loginSession{
+		email:        user.Email,
+		ipAddress:    ipAddress,
+		userAgent:    userAgent,
+		method:       method,
+		otpValidated: otpSetup != nil,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &AcceptInvitationResponse{AuthResponse: authResponse, OTPSetup: otpSetup}, nil
+}
+
+// setupInvitedUserOTP 为刚接受邀请的用户生成TOTP密钥与备用码
+func (s *AuthService) setupInvitedUserOTP(ctx context.Context, user *User) (*OTPSetupResponse, error) {
+	secret, err := s.otpService.GenerateSecret()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate OTP secret: %w", err)
+	}
+	qrCode, err := s.otpService.GenerateQRCode(secret, user.Email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate QR code: %w", err)
+	}
+	backupCodes, err := s.otpService.GenerateBackupCodes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate backup codes: %w", err)
+	}
+
+	user.OTPSecret = secret
+	user.OTPEnabled = true
+	user.BackupCodes = strings.Join(backupCodes, ",")
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+	s.recordSecurityEvent(ctx, "otp_enabled", user, "")
+
+	return &OTPSetupResponse{
+		Secret:      secret,
+		QRCode:      qrCode,
+		BackupCodes: backupCodes,
+	}, nil
+}
@@ -55,18 +55,19 @@ type JWTHeader struct {
 type JWTPayload struct {
 	UserID uint     `json:"user_id"`
 	Role   UserRole `json:"role"`
-	Type   string   `json:"type"` // access, refresh
-	Iss    string   `json:"iss"`  // issuer
-	Sub    string   `json:"sub"`  // subject
-	Aud    string   `json:"aud"`  // audience
-	Exp    int64    `json:"exp"`  // expiration time
-	Nbf    int64    `json:"nbf"`  // not before
-	Iat    int64    `json:"iat"`  // issued at
-	Jti    string   `json:"jti"`  // JWT ID
+	Type   string   `json:"type"`          // access, refresh
+	Iss    string   `json:"iss"`           // issuer
+	Sub    string   `json:"sub"`           // subject
+	Aud    string   `json:"aud"`           // audience
+	Exp    int64    `json:"exp"`           // expiration time
+	Nbf    int64    `json:"nbf"`           // not before
+	Iat    int64    `json:"iat"`           // issued at
+	Jti    string   `json:"jti"`           // JWT ID
+	Sid    string   `json:"sid,omitempty"` // session ID
 }
 
-// GenerateTokenPair 生成令牌对
-func (j *SimpleJWTManager) GenerateTokenPair(userID uint, role UserRole) (accessToken, refreshToken string, err error) {
+// GenerateTokenPair 生成令牌对，sessionID 写入两个令牌以便记录会话活跃时间并绑定CSRF令牌
+func (j *SimpleJWTManager) GenerateTokenPair(userID uint, role UserRole, sessionID string) (accessToken, refreshToken string, err error) {
 	now := time.Now()
 	userIDStr := strconv.FormatUint(uint64(userID), 10)
 
@@ -82,6 +83,7 @@ func (j *SimpleJWTManager) GenerateTokenPair(userID uint, role UserRole) (access
 		Nbf:    now.Unix(),
 		Iat:    now.Unix(),
 		Jti:    generateJTI(),
+		Sid:    sessionID,
 	}
 
 	accessToken, err = j.generateToken(accessPayload, j.accessSecret)
@@ -101,6 +103,7 @@ func (j *SimpleJWTManager) GenerateTokenPair(userID uint, role UserRole) (access
 		Nbf:    now.Unix(),
 		Iat:    now.Unix(),
 		Jti:    generateJTI(),
+		Sid:    sessionID,
 	}
 
 	refreshToken, err = j.generateToken(refreshPayload, j.refreshSecret)
@@ -123,12 +126,13 @@ func (j *SimpleJWTManager) VerifyAccessToken(token string) (*Claims, error) {
 	}
 
 	return &Claims{
-		UserID: payload.UserID,
-		Role:   payload.Role,
-		Type:   payload.Type,
-		Exp:    payload.Exp,
-		Iat:    payload.Iat,
-		Jti:    payload.Jti,
+		UserID:    payload.UserID,
+		Role:      payload.Role,
+		Type:      payload.Type,
+		Exp:       payload.Exp,
+		Iat:       payload.Iat,
+		Jti:       payload.Jti,
+		SessionID: payload.Sid,
 	}, nil
 }
 
@@ -144,12 +148,13 @@ func (j *SimpleJWTManager) VerifyRefreshToken(token string) (*Claims, error) {
 	}
 
 	return &Claims{
-		UserID: payload.UserID,
-		Role:   payload.Role,
-		Type:   payload.Type,
-		Exp:    payload.Exp,
-		Iat:    payload.Iat,
-		Jti:    payload.Jti,
+		UserID:    payload.UserID,
+		Role:      payload.Role,
+		Type:      payload.Type,
+		Exp:       payload.Exp,
+		Iat:       payload.Iat,
+		Jti:       payload.Jti,
+		SessionID: payload.Sid,
 	}, nil
 }
 
@@ -261,13 +266,30 @@ func generateJTI() string {
 
 // ParseTokenClaims 解析令牌声明（不验证签名，用于获取过期令牌信息）
 func (j *SimpleJWTManager) ParseTokenClaims(token string) (*Claims, error) {
+	payload, err := decodeTokenPayload(token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Claims{
+		UserID:    payload.UserID,
+		Role:      payload.Role,
+		Type:      payload.Type,
+		Exp:       payload.Exp,
+		Iat:       payload.Iat,
+		Jti:       payload.Jti,
+		SessionID: payload.Sid,
+	}, nil
+}
+
+// decodeTokenPayload 解码令牌载荷，不验证签名
+func decodeTokenPayload(token string) (*JWTPayload, error) {
 	parts := strings.Split(token, ".")
 	if len(parts) != 3 {
 		return nil, errors.New("invalid token format")
 	}
 
-	payloadEncoded := parts[1]
-	payloadBytes, err := base64.RawURLEncoding.DecodeString(payloadEncoded)
+	payloadBytes, err := base64.RawURLEncoding.DecodeString(parts[1])
 	if err != nil {
 		return nil, fmt.Errorf("failed to decode payload: %w", err)
 	}
@@ -276,15 +298,7 @@ func (j *SimpleJWTManager) ParseTokenClaims(token string) (*Claims, error) {
 	if err := json.Unmarshal(payloadBytes, &payload); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal payload: %w", err)
 	}
-
-	return &Claims{
-		UserID: payload.UserID,
-		Role:   payload.Role,
-		Type:   payload.Type,
-		Exp:    payload.Exp,
-		Iat:    payload.Iat,
-		Jti:    payload.Jti,
-	}, nil
+	return &payload, nil
 }
 
 // GetTokenExpiration 获取令牌过期时间
@@ -337,7 +351,7 @@ func (j *SimpleJWTManager) RefreshTokenIfNeeded(accessToken string, threshold ti
 	}
 
 	// 生成新的访问令牌
-	newAccessToken, _, err := j.GenerateTokenPair(claims.UserID, claims.Role)
+	newAccessToken, _, err := j.GenerateTokenPair(claims.UserID, claims.Role, claims.SessionID)
 	if err != nil {
 		return "", false, err
 	}
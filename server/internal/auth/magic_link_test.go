@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestMagicLink_RequestVerifyAndThrottle(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:magic_link_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &UserProfile{}, &RefreshToken{}, &LoginAttempt{}, &MagicLinkToken{},
+		&models.LoginHistory{}, &models.OTPTrustedDevice{}, &models.SystemConfig{}, &models.EmailConfig{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+
+	ctx := context.Background()
+	config := &AuthConfig{
+		JWTSecret:          "test-secret",
+		JWTRefreshSecret:   "test-refresh-secret",
+		AccessTokenExpire:  time.Hour,
+		RefreshTokenExpire: 24 * time.Hour,
+		MaxFailedLogins:    5,
+	}
+	configService := services.NewConfigService(db)
+	mailer := NewMockEmailService()
+	svc := NewAuthService(
+		NewGormUserRepository(db),
+		NewGormProfileRepository(db),
+		NewGormTokenRepository(db),
+		NewGormLoginAttemptRepository(db),
+		NewGormLoginHistoryRepository(db),
+		NewGormTrustedDeviceRepository(db),
+		configService,
+		mailer,
+		services.NewEmailConfigService(db),
+		NewSimpleOTPService("Test"),
+		NewSimplePasswordService(8, "salt"),
+		NewSimpleJWTManager(config.JWTSecret, config.JWTRefreshSecret, config.AccessTokenExpire, config.RefreshTokenExpire),
+		config,
+	)
+
+	user := models.User{Username: "ml-user", Email: "ml@example.com", PasswordHash: "x", Role: models.RoleAgent,
+		Status: models.UserStatusActive, EmailVerified: true}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("failed to seed user: %v", err)
+	}
+
+	if err := svc.RequestMagicLink(ctx, user.Email, "10.0.0.1", "test"); err != ErrMagicLinkDisabled {
+		t.Fatalf("expected magic link to be disabled by default, got %v", err)
+	}
+
+	if err := configService.SetConfig(services.KeyMagicLinkEnabled, "true", "bool", "", services.CategorySecurity, "magic_link"); err != nil {
+		t.Fatalf("failed to enable magic link: %v", err)
+	}
+	if err := configService.SetConfig(services.KeyMagicLinkMaxPerHour, "2", "int", "", services.CategorySecurity, "magic_link"); err != nil {
+		t.Fatalf("failed to set magic link limit: %v", err)
+	}
+
+	if err := svc.RequestMagicLink(ctx, "nobody@example.com", "10.0.0.1", "test"); err != nil {
+		t.Fatalf("expected unknown account to look like success, got %v", err)
+	}
+	if len(mailer.GetSentEmails()) != 0 {
+		t.Fatalf("expected no email for unknown account")
+	}
+
+	if err := svc.RequestMagicLink(ctx, user.Email, "10.0.0.1", "test"); err != nil {
+		t.Fatalf("request magic link failed: %v", err)
+	}
+	sent := mailer.GetLastSentEmail()
+	if sent == nil || sent.To != user.Email {
+		t.Fatalf("expected magic link email to %s, got %+v", user.Email, sent)
+	}
+	token := strings.Fields(strings.TrimPrefix(sent.Body, "Magic link token: "))[0]
+
+	resp, err := svc.VerifyMagicLink(ctx, &MagicLinkVerifyRequest{Token: token}, "10.0.0.2", "test")
+	if err != nil {
+		t.Fatalf("verify magic link failed: %v", err)
+	}
+	if resp.AccessToken == "" || resp.User.ID != user.ID {
+		t.Fatalf("unexpected auth response: %+v", resp)
+	}
+	if _, err := svc.VerifyMagicLink(ctx, &MagicLinkVerifyRequest{Token: token}, "10.0.0.2", "test"); err != ErrInvalidToken {
+		t.Fatalf("expected used link to be rejected, got %v", err)
+	}
+
+	if err := svc.RequestMagicLink(ctx, user.Email, "10.0.0.1", "test"); err != nil {
+		t.Fatalf("second request should be within limit: %v", err)
+	}
+	sentBefore := len(mailer.GetSentEmails())
+	if err := svc.RequestMagicLink(ctx, user.Email, "10.0.0.1", "test"); err != nil {
+		t.Fatalf("expected throttled request to look like success, got %v", err)
+	}
+	if len(mailer.GetSentEmails()) != sentBefore {
+		t.Fatalf("expected no email for the third request within the hour")
+	}
+}
@@ -0,0 +1,249 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// newOneTimeTokenTestService 创建使用内存数据库和模拟邮件服务的认证服务
+func newOneTimeTokenTestService(t *testing.T, name string) (*gorm.DB, *AuthService, *MockEmailService) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file:"+name+"?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	// 并发兑换时串行访问内存数据库，避免 table is locked
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&models.User{}, &UserProfile{}, &RefreshToken{}, &LoginAttempt{}, &PasswordReset{}, &EmailVerification{},
+		&models.LoginHistory{}, &models.OTPTrustedDevice{}, &models.SystemConfig{}, &models.EmailConfig{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+
+	config := &AuthConfig{
+		JWTSecret:               "test-secret",
+		JWTRefreshSecret:        "test-refresh-secret",
+		AccessTokenExpire:       time.Hour,
+		RefreshTokenExpire:      24 * time.Hour,
+		PasswordResetExpire:     time.Hour,
+		EmailVerificationExpire: 24 * time.Hour,
+		MaxFailedLogins:         5,
+	}
+	mailer := NewMockEmailService()
+	svc := NewAuthService(
+		NewGormUserRepository(db),
+		NewGormProfileRepository(db),
+		NewGormTokenRepository(db),
+		NewGormLoginAttemptRepository(db),
+		NewGormLoginHistoryRepository(db),
+		NewGormTrustedDeviceRepository(db),
+		services.NewConfigService(db),
+		mailer,
+		services.NewEmailConfigService(db),
+		NewSimpleOTPService("Test"),
+		NewSimplePasswordService(8, "salt"),
+		NewSimpleJWTManager(config.JWTSecret, config.JWTRefreshSecret, config.AccessTokenExpire, config.RefreshTokenExpire),
+		config,
+	)
+	return db, svc, mailer
+}
+
+// sentToken 从模拟邮件正文中取出令牌
+func sentToken(t *testing.T, mailer *MockEmailService, prefix string) string {
+	t.Helper()
+	sent := mailer.GetLastSentEmail()
+	if sent == nil || !strings.HasPrefix(sent.Body, prefix) {
+		t.Fatalf("expected an email starting with %q, got %+v", prefix, sent)
+	}
+	return strings.TrimSpace(strings.TrimPrefix(sent.Body, prefix))
+}
+
+// countAlerts 统计发送的密码重置安全提醒邮件
+func countAlerts(mailer *MockEmailService) int {
+	count := 0
+	for _, sent := range mailer.GetSentEmails() {
+		if sent.Subject == "Your Password Was Reset" {
+			count++
+		}
+	}
+	return count
+}
+
+func TestPasswordReset_HashedSingleUseAndExpiry(t *testing.T) {
+	db, svc, mailer := newOneTimeTokenTestService(t, "auth_password_reset_test")
+	ctx := context.Background()
+
+	user := models.User{Username: "pr-user", Email: "pr@example.com", PasswordHash: "x", Role: models.RoleAgent,
+		Status: models.UserStatusActive, EmailVerified: true}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("failed to seed user: %v", err)
+	}
+	if err := db.Create(&models.LoginHistory{UserID: user.ID, Username: user.Username, Email: user.Email, IPAddress: "10.0.0.1",
+		UserAgent: "known-browser", LoginTime: time.Now(), LoginStatus: models.LoginStatusSuccess}).Error; err != nil {
+		t.Fatalf("failed to seed login history: %v", err)
+	}
+
+	// 只保存令牌哈希
+	if err := svc.ForgotPassword(ctx, user.Email, "10.0.0.1", "known-browser"); err != nil {
+		t.Fatalf("forgot password failed: %v", err)
+	}
+	token := sentToken(t, mailer, "Reset token: ")
+	var reset PasswordReset
+	if err := db.Where("user_id = ?", user.ID).First(&reset).Error; err != nil {
+		t.Fatalf("failed to load password reset: %v", err)
+	}
+	if reset.Token == token || reset.Token != hashOneTimeToken(token) {
+		t.Fatalf("expected only the token hash to be stored, got %q", reset.Token)
+	}
+	var plain int64
+	db.Model(&PasswordReset{}).Where("token = ?", token).Count(&plain)
+	if plain != 0 {
+		t.Fatalf("expected the raw token not to be stored")
+	}
+	if err := svc.ResetPassword(ctx, reset.Token, "NewPassw0rd!", "10.0.0.1", "known-browser"); err != ErrInvalidToken {
+		t.Fatalf("expected the stored hash not to work as a token, got %v", err)
+	}
+
+	// 已登录过的设备重置不发送提醒，令牌只能使用一次
+	if err := svc.ResetPassword(ctx, token, "NewPassw0rd!", "10.0.0.1", "known-browser"); err != nil {
+		t.Fatalf("reset password failed: %v", err)
+	}
+	if got := countAlerts(mailer); got != 0 {
+		t.Fatalf("expected no alert for a known device, got %d", got)
+	}
+	if err := svc.ResetPassword(ctx, token, "OtherPassw0rd!", "10.0.0.1", "known-browser"); err != ErrInvalidToken {
+		t.Fatalf("expected used token to be rejected, got %v", err)
+	}
+	db.First(&reset, reset.ID)
+	if !reset.Used || reset.UsedAt == nil || reset.UsedIP != "10.0.0.1" || reset.UsedUserAgent != "known-browser" {
+		t.Fatalf("expected redemption to be recorded, got %+v", reset)
+	}
+
+	// 过期令牌被拒绝
+	if err := svc.ForgotPassword(ctx, user.Email, "10.0.0.1", "known-browser"); err != nil {
+		t.Fatalf("forgot password failed: %v", err)
+	}
+	expired := sentToken(t, mailer, "Reset token: ")
+	db.Model(&PasswordReset{}).Where("token = ?", hashOneTimeToken(expired)).Update("expires_at", time.Now().Add(-time.Minute))
+	if err := svc.ResetPassword(ctx, expired, "NewPassw0rd!", "10.0.0.1", "known-browser"); err != ErrInvalidToken {
+		t.Fatalf("expected expired token to be rejected, got %v", err)
+	}
+
+	// 两个请求同时兑换同一令牌，只有一个成功，新设备提醒只发送一次
+	if err := svc.ForgotPassword(ctx, user.Email, "10.0.0.2", "new-browser"); err != nil {
+		t.Fatalf("forgot password failed: %v", err)
+	}
+	raced := sentToken(t, mailer, "Reset token: ")
+	var wg sync.WaitGroup
+	results := make([]error, 2)
+	start := make(chan struct{})
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			results[i] = svc.ResetPassword(ctx, raced, "RacedPassw0rd!", "10.0.0.2", "new-browser")
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+	succeeded := 0
+	for _, err := range results {
+		switch err {
+		case nil:
+			succeeded++
+		case ErrInvalidToken:
+		default:
+			t.Fatalf("unexpected redemption error: %v", err)
+		}
+	}
+	if succeeded != 1 {
+		t.Fatalf("expected exactly one concurrent redemption to succeed, got %d", succeeded)
+	}
+	if got := countAlerts(mailer); got != 1 {
+		t.Fatalf("expected one alert for the new device, got %d", got)
+	}
+	if sent := mailer.GetLastSentEmail(); sent.To != user.Email || !strings.Contains(sent.Body, "new-browser") {
+		t.Fatalf("expected the alert to name the new device, got %+v", sent)
+	}
+}
+
+func TestEmailVerification_HashedSingleUseAndExpiry(t *testing.T) {
+	db, svc, mailer := newOneTimeTokenTestService(t, "auth_email_verification_test")
+	ctx := context.Background()
+
+	user := models.User{Username: "ev-user", Email: "ev@example.com", PasswordHash: "x", Role: models.RoleCustomer,
+		Status: models.UserStatusActive}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("failed to seed user: %v", err)
+	}
+
+	// 只保存令牌哈希
+	if err := svc.ResendVerification(ctx, user.Email); err != nil {
+		t.Fatalf("resend verification failed: %v", err)
+	}
+	token := sentToken(t, mailer, "Verification token: ")
+	var verification EmailVerification
+	if err := db.Where("user_id = ?", user.ID).First(&verification).Error; err != nil {
+		t.Fatalf("failed to load email verification: %v", err)
+	}
+	if verification.Token == token || verification.Token != hashOneTimeToken(token) {
+		t.Fatalf("expected only the token hash to be stored, got %q", verification.Token)
+	}
+
+	// 过期令牌被拒绝
+	db.Model(&EmailVerification{}).Where("id = ?", verification.ID).Update("expires_at", time.Now().Add(-time.Minute))
+	if err := svc.VerifyEmail(ctx, token, "10.0.0.1", "browser"); err != ErrInvalidToken {
+		t.Fatalf("expected expired token to be rejected, got %v", err)
+	}
+
+	// 两个请求同时兑换同一令牌，只有一个成功
+	if err := svc.ResendVerification(ctx, user.Email); err != nil {
+		t.Fatalf("resend verification failed: %v", err)
+	}
+	token = sentToken(t, mailer, "Verification token: ")
+	var wg sync.WaitGroup
+	results := make([]error, 2)
+	start := make(chan struct{})
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			results[i] = svc.VerifyEmail(ctx, token, "10.0.0.1", "browser")
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+	succeeded := 0
+	for _, err := range results {
+		switch err {
+		case nil:
+			succeeded++
+		case ErrInvalidToken:
+		default:
+			t.Fatalf("unexpected redemption error: %v", err)
+		}
+	}
+	if succeeded != 1 {
+		t.Fatalf("expected exactly one concurrent redemption to succeed, got %d", succeeded)
+	}
+	if err := svc.VerifyEmail(ctx, token, "10.0.0.1", "browser"); err != ErrInvalidToken {
+		t.Fatalf("expected used token to be rejected, got %v", err)
+	}
+
+	var verified models.User
+	db.First(&verified, user.ID)
+	if !verified.EmailVerified || verified.EmailVerifiedAt == nil {
+		t.Fatalf("expected the email to be verified, got %+v", verified)
+	}
+}
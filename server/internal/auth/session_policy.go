@@ -0,0 +1,207 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// SetSessionPolicyService 设置按角色的会话策略，未设置时所有角色沿用全局设置
+func (s *AuthService) SetSessionPolicyService(sessionPolicy *services.SessionPolicyService) {
+	s.sessionPolicy = sessionPolicy
+}
+
+// EffectiveSessionPolicy 合并全局设置与角色策略，得到该角色实际生效的会话策略
+func (s *AuthService) EffectiveSessionPolicy(ctx context.Context, role UserRole) *models.EffectiveSessionPolicy {
+	effective := &models.EffectiveSessionPolicy{
+		Role:                   models.UserRole(role),
+		AccessTokenTTLSeconds:  int64(s.config.AccessTokenExpire.Seconds()),
+		RefreshTokenTTLSeconds: int64(s.config.RefreshTokenExpire.Seconds()),
+		TrustedDeviceTTLHours:  int(s.getTrustedDeviceTTL().Hours()),
+		MaxTrustedDevices:      s.getTrustedDeviceLimit(),
+	}
+	if s.sessionPolicy == nil {
+		return effective
+	}
+
+	config, err := s.sessionPolicy.GetPolicy(ctx)
+	if err != nil {
+		fmt.Printf("Warning: failed to load session policy, using global settings: %v\n", err)
+		return effective
+	}
+	policy := config.ForRole(models.UserRole(role))
+	if policy == nil {
+		return effective
+	}
+
+	// 角色策略只收紧全局设置
+	if ttl := int64(policy.RefreshTokenTTLHours) * 3600; ttl > 0 && ttl < effective.RefreshTokenTTLSeconds {
+		effective.RefreshTokenTTLSeconds = ttl
+	}
+	if policy.TrustedDeviceTTLHours > 0 && policy.TrustedDeviceTTLHours < effective.TrustedDeviceTTLHours {
+		effective.TrustedDeviceTTLHours = policy.TrustedDeviceTTLHours
+	}
+	if policy.MaxTrustedDevices > 0 && (effective.MaxTrustedDevices <= 0 || policy.MaxTrustedDevices < effective.MaxTrustedDevices) {
+		effective.MaxTrustedDevices = policy.MaxTrustedDevices
+	}
+	effective.RequireOTP = policy.RequireOTP
+	effective.IdleTimeoutMinutes = policy.IdleTimeoutMinutes
+	if policy.IdleTimeoutMinutes > 0 {
+		effective.IdleWarningMinutes = policy.IdleWarningMinutes
+		if effective.IdleWarningMinutes <= 0 {
+			effective.IdleWarningMinutes = defaultIdleWarningMinutes
+			if half := policy.IdleTimeoutMinutes / 2; half < effective.IdleWarningMinutes {
+				effective.IdleWarningMinutes = half
+			}
+		}
+	}
+	return effective
+}
+
+// refreshTokenTTL 生效的刷新令牌有效期
+func refreshTokenTTL(policy *models.EffectiveSessionPolicy) time.Duration {
+	return time.Duration(policy.RefreshTokenTTLSeconds) * time.Second
+}
+
+// trustedDeviceTTL 生效的可信设备有效期
+func trustedDeviceTTL(policy *models.EffectiveSessionPolicy) time.Duration {
+	return time.Duration(policy.TrustedDeviceTTLHours) * time.Hour
+}
+
+// sessionIdleStatus 按最近活动时间计算会话空闲状态，角色未设置空闲超时时返回 nil
+func sessionIdleStatus(policy *models.EffectiveSessionPolicy, lastActivityAt, now time.Time) *models.SessionIdleStatus {
+	if policy.IdleTimeoutMinutes <= 0 {
+		return nil
+	}
+	expiresAt := lastActivityAt.Add(time.Duration(policy.IdleTimeoutMinutes) * time.Minute)
+	warningAt := expiresAt.Add(-time.Duration(policy.IdleWarningMinutes) * time.Minute)
+	remaining := int64(expiresAt.Sub(now).Seconds())
+	if remaining < 0 {
+		remaining = 0
+	}
+	return &models.SessionIdleStatus{
+		IdleTimeoutMinutes: policy.IdleTimeoutMinutes,
+		LastActivityAt:     lastActivityAt,
+		WarningAt:          warningAt,
+		ExpiresAt:          expiresAt,
+		RemainingSeconds:   remaining,
+		Warning:            !now.Before(warningAt),
+		Expired:            now.After(expiresAt),
+	}
+}
+
+// lastSessionActivity 会话最近活动时间：登录记录中的活动时间与上次刷新令牌时间取较晚者
+func (s *AuthService) lastSessionActivity(ctx context.Context, userID uint, sessionID string, lastRefreshAt time.Time) time.Time {
+	last := lastRefreshAt
+	if s.loginHistoryRepo == nil || sessionID == "" {
+		return last
+	}
+	history, err := s.loginHistoryRepo.GetSession(ctx, userID, sessionID)
+	if err == nil && history.LastActivityAt != nil && history.LastActivityAt.After(last) {
+		last = *history.LastActivityAt
+	}
+	return last
+}
+
+const (
+	// defaultIdleWarningMinutes 未设置提示时间时，空闲到期前 5 分钟开始提示
+	defaultIdleWarningMinutes = 5
+	// sessionActivityWriteInterval 同一会话两次写入活动时间的最小间隔，避免每个请求都写库
+	sessionActivityWriteInterval = time.Minute
+	// sessionActivityTrackerLimit 进程内记录的会话数上限，超过时清理已过写入间隔的记录
+	sessionActivityTrackerLimit = 10000
+)
+
+// sessionActivityTracker 记录本进程内各会话上次写入活动时间的时刻
+type sessionActivityTracker struct {
+	mu      sync.Mutex
+	written map[string]time.Time
+}
+
+// due 距上次写入已超过间隔（或 force）时返回 true 并记下本次写入
+func (t *sessionActivityTracker) due(sessionID string, now time.Time, force bool) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.written == nil {
+		t.written = make(map[string]time.Time)
+	}
+	if last, ok := t.written[sessionID]; ok && !force && now.Sub(last) < sessionActivityWriteInterval {
+		return false
+	}
+	if len(t.written) >= sessionActivityTrackerLimit {
+		for id, last := range t.written {
+			if now.Sub(last) >= sessionActivityWriteInterval {
+				delete(t.written, id)
+			}
+		}
+	}
+	t.written[sessionID] = now
+	return true
+}
+
+// RecordSessionActivity 记录访问令牌所属会话的活动时间，同一会话每分钟最多写入一次，force 时立即写入
+func (s *AuthService) RecordSessionActivity(ctx context.Context, userID uint, sessionID string, force bool) {
+	if s.loginHistoryRepo == nil || sessionID == "" {
+		return
+	}
+	now := time.Now()
+	if !s.activity.due(sessionID, now, force) {
+		return
+	}
+	if err := s.loginHistoryRepo.TouchSession(ctx, userID, sessionID, now); err != nil {
+		fmt.Printf("Warning: failed to record session activity: %v\n", err)
+	}
+}
+
+// GetSessionIdleStatus 获取会话的空闲状态，角色未设置空闲超时或会话不存在时返回 nil
+func (s *AuthService) GetSessionIdleStatus(ctx context.Context, userID uint, sessionID string) (*models.SessionIdleStatus, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	policy := s.EffectiveSessionPolicy(ctx, user.Role)
+	if policy.IdleTimeoutMinutes <= 0 || s.loginHistoryRepo == nil || sessionID == "" {
+		return nil, nil
+	}
+	history, err := s.loginHistoryRepo.GetSession(ctx, userID, sessionID)
+	if err != nil || history.LastActivityAt == nil {
+		return nil, nil
+	}
+	return sessionIdleStatus(policy, *history.LastActivityAt, time.Now()), nil
+}
+
+// endPolicySession 因会话策略拒绝刷新时撤销刷新令牌并结束登录会话
+func (s *AuthService) endPolicySession(ctx context.Context, userID uint, token *RefreshToken, reason string, now time.Time) {
+	s.tokenRepo.RevokeRefreshToken(ctx, token.Token)
+	if s.loginHistoryRepo != nil && token.SessionID != "" {
+		if err := s.loginHistoryRepo.EndSession(ctx, userID, token.SessionID, models.LoginStatusExpired, reason, now); err != nil {
+			fmt.Printf("Warning: failed to end session %s: %v\n", token.SessionID, err)
+		}
+	}
+	s.emitAuthEvent("token_refresh", "denied", &userID, "", "", "", reason)
+}
+
+// checkSecondFactorRemoval 角色要求第二因子时，禁止关闭最后一个第二因子
+func (s *AuthService) checkSecondFactorRemoval(ctx context.Context, user *User, remaining bool) error {
+	if remaining {
+		return nil
+	}
+	if s.EffectiveSessionPolicy(ctx, user.Role).RequireOTP {
+		return ErrOTPPolicyRequired
+	}
+	return nil
+}
+
+// GetSessionPolicy 获取用户当前角色生效的会话策略，供客户端调整刷新与登录流程
+func (s *AuthService) GetSessionPolicy(ctx context.Context, userID uint) (*models.EffectiveSessionPolicy, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+	return s.EffectiveSessionPolicy(ctx, user.Role), nil
+}
@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestSessionPolicy_PerRoleEnforcement(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:auth_session_policy_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &UserProfile{}, &RefreshToken{}, &LoginAttempt{}, &models.LoginHistory{},
+		&models.OTPTrustedDevice{}, &models.SystemConfig{}, &models.EmailConfig{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+
+	ctx := context.Background()
+	config := &AuthConfig{
+		JWTSecret:          "test-secret",
+		JWTRefreshSecret:   "test-refresh-secret",
+		AccessTokenExpire:  15 * time.Minute,
+		RefreshTokenExpire: 24 * time.Hour,
+		MaxFailedLogins:    5,
+	}
+	passwords := NewSimplePasswordService(8, "salt")
+	svc := NewAuthService(
+		NewGormUserRepository(db),
+		NewGormProfileRepository(db),
+		NewGormTokenRepository(db),
+		NewGormLoginAttemptRepository(db),
+		NewGormLoginHistoryRepository(db),
+		NewGormTrustedDeviceRepository(db),
+		services.NewConfigService(db),
+		NewMockEmailService(),
+		services.NewEmailConfigService(db),
+		NewSimpleOTPService("Test"),
+		passwords,
+		NewSimpleJWTManager(config.JWTSecret, config.JWTRefreshSecret, config.AccessTokenExpire, config.RefreshTokenExpire),
+		config,
+	)
+	policyService := services.NewSessionPolicyService(db)
+	svc.SetSessionPolicyService(policyService)
+
+	// 角色策略只能收紧全局设置
+	if err := policyService.SetPolicy(ctx, &models.SessionPolicyConfig{Roles: map[models.UserRole]*models.RoleSessionPolicy{
+		"owner": {RequireOTP: true},
+	}}, 1); err == nil {
+		t.Fatalf("expected unknown role to be rejected")
+	}
+	if err := policyService.SetPolicy(ctx, &models.SessionPolicyConfig{Roles: map[models.UserRole]*models.RoleSessionPolicy{
+		models.RoleAdmin: {RefreshTokenTTLHours: 48, RequireOTP: true, MaxTrustedDevices: 2},
+		models.RoleAgent: {RefreshTokenTTLHours: 2, IdleTimeoutMinutes: 30},
+	}}, 1); err != nil {
+		t.Fatalf("set policy: %v", err)
+	}
+	adminPolicy := svc.EffectiveSessionPolicy(ctx, RoleAdmin)
+	if adminPolicy.RefreshTokenTTLSeconds != int64((24*time.Hour).Seconds()) || !adminPolicy.RequireOTP || adminPolicy.MaxTrustedDevices != 2 {
+		t.Fatalf("unexpected admin policy %+v", adminPolicy)
+	}
+	if customer := svc.EffectiveSessionPolicy(ctx, UserRole(models.RoleCustomer)); customer.RequireOTP || customer.IdleTimeoutMinutes != 0 {
+		t.Fatalf("expected customer to use global settings, got %+v", customer)
+	}
+
+	hash, _ := passwords.HashPassword("Password123!")
+	agent := models.User{Username: "policy-agent", Email: "agent@example.com", PasswordHash: hash, Role: models.RoleAgent,
+		Status: models.UserStatusActive, EmailVerified: true}
+	admin := models.User{Username: "policy-admin", Email: "admin@example.com", PasswordHash: hash, Role: models.RoleAdmin,
+		Status: models.UserStatusActive, EmailVerified: true}
+	for _, user := range []*models.User{&agent, &admin} {
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("failed to seed user: %v", err)
+		}
+	}
+
+	// 刷新令牌按角色有效期签发
+	resp, err := svc.Login(ctx, &LoginRequest{Email: agent.Email, Password: "Password123!"}, "10.0.0.1", "test")
+	if err != nil {
+		t.Fatalf("agent login: %v", err)
+	}
+	var record RefreshToken
+	db.Where("token = ?", resp.RefreshToken).First(&record)
+	if ttl := time.Until(record.ExpiresAt); ttl > 2*time.Hour || ttl < 119*time.Minute {
+		t.Fatalf("expected 2h refresh token for agent, got %v", ttl)
+	}
+	resp, err = svc.RefreshToken(ctx, &RefreshTokenRequest{RefreshToken: resp.RefreshToken}, "10.0.0.1", "test")
+	if err != nil {
+		t.Fatalf("refresh within idle timeout: %v", err)
+	}
+
+	if resp.SessionIdle == nil || resp.SessionIdle.IdleTimeoutMinutes != 30 || resp.SessionIdle.Warning {
+		t.Fatalf("expected idle status in refresh response, got %+v", resp.SessionIdle)
+	}
+	claims, err := svc.jwtManager.VerifyAccessToken(resp.AccessToken)
+	if err != nil || claims.SessionID == "" {
+		t.Fatalf("expected access token to carry session id, got %+v, %v", claims, err)
+	}
+
+	// 使用访问令牌的请求计入会话活动：上次刷新已久，但最近有请求时仍可刷新
+	age := func(minutes int) {
+		db.Model(&RefreshToken{}).Where("token = ?", resp.RefreshToken).
+			Update("created_at", time.Now().Add(-time.Duration(minutes)*time.Minute))
+		db.Model(&models.LoginHistory{}).Where("session_id = ?", claims.SessionID).
+			Update("last_activity_at", time.Now().Add(-time.Duration(minutes)*time.Minute))
+	}
+	age(40)
+	svc.RecordSessionActivity(ctx, agent.ID, claims.SessionID, false)
+	if resp, err = svc.RefreshToken(ctx, &RefreshTokenRequest{RefreshToken: resp.RefreshToken}, "10.0.0.1", "test"); err != nil {
+		t.Fatalf("expected recent activity to extend the session, got %v", err)
+	}
+
+	// 进入提示期后返回 warning，超过空闲时间没有请求后不能再刷新
+	age(27)
+	status, err := svc.GetSessionIdleStatus(ctx, agent.ID, claims.SessionID)
+	if err != nil || status == nil || !status.Warning || status.Expired || status.RemainingSeconds > 3*60 {
+		t.Fatalf("expected idle warning, got %+v, %v", status, err)
+	}
+	age(31)
+	if _, err := svc.RefreshToken(ctx, &RefreshTokenRequest{RefreshToken: resp.RefreshToken}, "10.0.0.1", "test"); !errors.Is(err, ErrSessionIdleTimeout) {
+		t.Fatalf("expected idle timeout, got %v", err)
+	}
+	if _, err := svc.RefreshToken(ctx, &RefreshTokenRequest{RefreshToken: resp.RefreshToken}, "10.0.0.1", "test"); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected idle session token to be revoked, got %v", err)
+	}
+
+	// 要求第二因子的角色未启用时只能使用本次访问令牌
+	resp, err = svc.Login(ctx, &LoginRequest{Email: admin.Email, Password: "Password123!", RememberDevice: true}, "10.0.0.1", "test")
+	if err != nil {
+		t.Fatalf("admin login: %v", err)
+	}
+	if !resp.OTPSetupRequired || resp.TrustedDeviceToken != "" {
+		t.Fatalf("expected otp setup required without trusted device, got %+v", resp)
+	}
+	if _, err := svc.RefreshToken(ctx, &RefreshTokenRequest{RefreshToken: resp.RefreshToken}, "10.0.0.1", "test"); !errors.Is(err, ErrOTPSetupRequired) {
+		t.Fatalf("expected refresh to require otp setup, got %v", err)
+	}
+
+	// 不能关闭最后一个第二因子
+	db.Model(&models.User{}).Where("id = ?", admin.ID).Update("otp_enabled", true)
+	if err := svc.DisableOTP(ctx, admin.ID, "Password123!"); !errors.Is(err, ErrOTPPolicyRequired) {
+		t.Fatalf("expected policy to block disabling OTP, got %v", err)
+	}
+	if err := svc.DisableOTP(ctx, agent.ID, "Password123!"); err != nil {
+		t.Fatalf("agent may disable OTP: %v", err)
+	}
+
+	policy, err := svc.GetSessionPolicy(ctx, agent.ID)
+	if err != nil || policy.IdleTimeoutMinutes != 30 || policy.RefreshTokenTTLSeconds != 7200 {
+		t.Fatalf("unexpected agent policy %+v, %v", policy, err)
+	}
+}
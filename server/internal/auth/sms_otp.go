@@ -0,0 +1,222 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// PhoneCodeRequest 向待验证手机号发送验证码
+type PhoneCodeRequest struct {
+	Phone string `json:"phone" binding:"required"`
+}
+
+// PhoneVerifyRequest 提交手机验证码
+type PhoneVerifyRequest struct {
+	Phone string `json:"phone" binding:"required"`
+	Code  string `json:"code" binding:"required"`
+}
+
+// LoginSMSCodeRequest 登录时请求短信验证码，需先通过密码校验
+type LoginSMSCodeRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
+// SMSCodeSentResponse 验证码发送结果
+type SMSCodeSentResponse struct {
+	Phone     string `json:"phone"`      // 脱敏后的手机号
+	ExpiresIn int64  `json:"expires_in"` // 有效期（秒）
+}
+
+// SendPhoneVerificationCode 向用户填写的手机号发送验证码，验证通过后才会写入账户
+func (s *AuthService) SendPhoneVerificationCode(ctx context.Context, userID uint, phone, ipAddress, userAgent string) (*SMSCodeSentResponse, error) {
+	if s.smsOTP == nil {
+		return nil, services.ErrSMSNotConfigured
+	}
+	phone = strings.TrimSpace(phone)
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return s.sendSMSCode(ctx, &services.SMSCodeRequest{
+		UserID:    userID,
+		Purpose:   models.OTPTypePhoneVerification,
+		Phone:     phone,
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+	})
+}
+
+// VerifyPhone 校验手机验证码，通过后更新账户手机号并标记为已验证
+func (s *AuthService) VerifyPhone(ctx context.Context, userID uint, phone, code string) error {
+	if s.smsOTP == nil {
+		return services.ErrSMSNotConfigured
+	}
+	phone = strings.TrimSpace(phone)
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if err := s.smsOTP.VerifyCode(ctx, userID, models.OTPTypePhoneVerification, phone, code); err != nil {
+		return err
+	}
+
+	// 更换手机号后需重新启用短信验证码登录
+	if user.Phone != phone {
+		user.SMSOTPEnabled = false
+	}
+	user.Phone = phone
+	user.PhoneVerified = true
+	user.PhoneVerifiedAt = timePtr(time.Now())
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	s.recordSecurityEvent(ctx, "phone_verified", user, services.MaskPhoneNumber(phone))
+	return nil
+}
+
+// EnableSMSOTP 启用短信验证码作为登录第二因子，需验证密码且手机号已验证
+func (s *AuthService) EnableSMSOTP(ctx context.Context, userID uint, password string) error {
+	if s.smsOTP == nil || !s.smsOTP.Enabled() {
+		return services.ErrSMSNotConfigured
+	}
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if password == "" {
+		return ErrInvalidCredentials
+	}
+	if err := s.passwordService.VerifyPassword(user.PasswordHash, password); err != nil {
+		return ErrInvalidCredentials
+	}
+	if !user.PhoneVerified || user.Phone == "" {
+		return ErrPhoneNotVerified
+	}
+	if user.SMSOTPEnabled {
+		return nil
+	}
+
+	user.SMSOTPEnabled = true
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	s.recordSecurityEvent(ctx, "sms_otp_enabled", user, "")
+	return nil
+}
+
+// DisableSMSOTP 关闭短信验证码登录，未开启TOTP时同时清空备用码
+func (s *AuthService) DisableSMSOTP(ctx context.Context, userID uint, password string) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if err := s.passwordService.VerifyPassword(user.PasswordHash, password); err != nil {
+		return ErrInvalidCredentials
+	}
+	if err := s.checkSecondFactorRemoval(ctx, user, user.OTPEnabled); err != nil {
+		return err
+	}
+
+	user.SMSOTPEnabled = false
+	if !user.OTPEnabled {
+		user.BackupCodes = ""
+	}
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	s.recordSecurityEvent(ctx, "sms_otp_disabled", user, "")
+	return nil
+}
+
+// RequestLoginSMSCode 校验密码后向账户已验证的手机号发送登录验证码。
+// 密码错误按登录失败计数，与 Login 共用失败次数限制
+func (s *AuthService) RequestLoginSMSCode(ctx context.Context, req *LoginSMSCodeRequest, ipAddress, userAgent string) (*SMSCodeSentResponse, error) {
+	if s.smsOTP == nil {
+		return nil, services.ErrSMSNotConfigured
+	}
+	if err := s.checkLoginAttempts(ctx, req.Email, ipAddress); err != nil {
+		s.recordLoginAttempt(ctx, nil, req.Email, ipAddress, userAgent, false, err.Error())
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByEmail(ctx, req.Email)
+	if err != nil {
+		s.recordLoginAttempt(ctx, nil, req.Email, ipAddress, userAgent, false, "user not found")
+		return nil, ErrInvalidCredentials
+	}
+	if err := s.checkUserStatus(ctx, user); err != nil {
+		s.recordLoginAttempt(ctx, &user.ID, req.Email, ipAddress, userAgent, false, err.Error())
+		return nil, err
+	}
+	if err := s.passwordService.VerifyPassword(user.PasswordHash, req.Password); err != nil {
+		s.userRepo.IncrementFailedLogin(ctx, user.ID)
+		s.recordLoginAttempt(ctx, &user.ID, req.Email, ipAddress, userAgent, false, "invalid password")
+		s.recordLoginHistoryFailure(ctx, user, ipAddress, userAgent, "password+sms", "invalid password", models.LoginStatusFailed)
+		return nil, ErrInvalidCredentials
+	}
+	if !user.SMSOTPEnabled || !user.PhoneVerified || user.Phone == "" {
+		return nil, ErrSMSOTPNotEnabled
+	}
+
+	return s.sendSMSCode(ctx, &services.SMSCodeRequest{
+		UserID:    user.ID,
+		Purpose:   models.OTPTypeTwoFactor,
+		Phone:     user.Phone,
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+	})
+}
+
+func (s *AuthService) sendSMSCode(ctx context.Context, req *services.SMSCodeRequest) (*SMSCodeSentResponse, error) {
+	otp, err := s.smsOTP.SendCode(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &SMSCodeSentResponse{
+		Phone:     services.MaskPhoneNumber(req.Phone),
+		ExpiresIn: int64(otp.ExpiresAt.Sub(time.Now()).Seconds()),
+	}, nil
+}
+
+// usesSMSCode 同时开启TOTP与短信验证码时需显式指定 otp_method=sms
+func usesSMSCode(user *User, method string) bool {
+	if !user.SMSOTPEnabled {
+		return false
+	}
+	return method == "sms" || !user.OTPEnabled
+}
+
+// verifySecondFactor 校验登录第二因子：短信验证码或TOTP，均不匹配时尝试备用码
+func (s *AuthService) verifySecondFactor(ctx context.Context, user *User, code, method string) error {
+	if usesSMSCode(user, method) {
+		if s.smsOTP != nil {
+			err := s.smsOTP.VerifyCode(ctx, user.ID, models.OTPTypeTwoFactor, user.Phone, code)
+			if err == nil {
+				return nil
+			}
+			if errors.Is(err, services.ErrSMSCodeExpired) {
+				return ErrOTPExpired
+			}
+			if !errors.Is(err, services.ErrSMSCodeInvalid) {
+				return err
+			}
+		}
+	} else if user.OTPEnabled && s.otpService.VerifyCode(user.OTPSecret, code) {
+		return nil
+	}
+
+	// 检查是否是备用码，使用后持久化剩余的备用码集合
+	if !s.verifyBackupCode(user, code) {
+		return ErrInvalidOTP
+	}
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		fmt.Printf("Warning: failed to persist backup code usage for user %d: %v\n", user.ID, err)
+	}
+	return nil
+}
@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestSMSOTP_PhoneVerificationAndLogin(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:auth_sms_otp_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &UserProfile{}, &RefreshToken{}, &LoginAttempt{}, &models.LoginHistory{},
+		&models.OTPTrustedDevice{}, &models.SystemConfig{}, &models.EmailConfig{}, &models.OTPCode{}, &models.SMSMessage{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+
+	// 模拟 Twilio，记录最近一次发送的验证码
+	var mu sync.Mutex
+	lastCode := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		mu.Lock()
+		lastCode = regexp.MustCompile(`\d{6}`).FindString(r.Form.Get("Body"))
+		mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"sid":"SM1","num_segments":"1"}`))
+	}))
+	defer server.Close()
+	sentCode := func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		return lastCode
+	}
+
+	ctx := context.Background()
+	config := &AuthConfig{
+		JWTSecret:          "test-secret",
+		JWTRefreshSecret:   "test-refresh-secret",
+		AccessTokenExpire:  time.Hour,
+		RefreshTokenExpire: 24 * time.Hour,
+		MaxFailedLogins:    5,
+	}
+	configService := services.NewConfigService(db)
+	passwords := NewSimplePasswordService(8, "salt")
+	svc := NewAuthService(
+		NewGormUserRepository(db),
+		NewGormProfileRepository(db),
+		NewGormTokenRepository(db),
+		NewGormLoginAttemptRepository(db),
+		NewGormLoginHistoryRepository(db),
+		NewGormTrustedDeviceRepository(db),
+		configService,
+		NewMockEmailService(),
+		services.NewEmailConfigService(db),
+		NewSimpleOTPService("Test"),
+		passwords,
+		NewSimpleJWTManager(config.JWTSecret, config.JWTRefreshSecret, config.AccessTokenExpire, config.RefreshTokenExpire),
+		config,
+	)
+	svc.SetSMSOTPService(services.NewSMSOTPService(db))
+
+	hash, _ := passwords.HashPassword("Password123!")
+	user := models.User{Username: "sms-login", Email: "sms-login@example.com", PasswordHash: hash, Role: models.RoleAgent,
+		Status: models.UserStatusActive, EmailVerified: true}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("failed to seed user: %v", err)
+	}
+
+	phone := "+8613800000000"
+	if _, err := svc.SendPhoneVerificationCode(ctx, user.ID, phone, "10.0.0.1", "test"); !errors.Is(err, services.ErrSMSNotConfigured) {
+		t.Fatalf("expected sms to be disabled by default, got %v", err)
+	}
+	for key, value := range map[string]string{
+		services.KeySMSEnabled:       "true",
+		services.KeySMSAccessKeyID:   "AC123",
+		services.KeySMSAccessSecret:  "token",
+		services.KeySMSSender:        "+15550000000",
+		services.KeySMSEndpoint:      server.URL,
+		services.KeySMSResendSeconds: "0",
+	} {
+		if err := configService.SetConfig(key, value, "string", "", services.CategorySecurity, "sms"); err != nil {
+			t.Fatalf("failed to set %s: %v", key, err)
+		}
+	}
+
+	// 未验证手机号不能启用
+	if err := svc.EnableSMSOTP(ctx, user.ID, "Password123!"); !errors.Is(err, ErrPhoneNotVerified) {
+		t.Fatalf("expected unverified phone to block sms OTP, got %v", err)
+	}
+	sent, err := svc.SendPhoneVerificationCode(ctx, user.ID, phone, "10.0.0.1", "test")
+	if err != nil || sent.Phone != "+86138****0000" {
+		t.Fatalf("send phone code failed: %+v %v", sent, err)
+	}
+	if err := svc.VerifyPhone(ctx, user.ID, phone, "000000x"); !errors.Is(err, services.ErrSMSCodeInvalid) {
+		t.Fatalf("expected wrong phone code to be rejected, got %v", err)
+	}
+	if err := svc.VerifyPhone(ctx, user.ID, phone, sentCode()); err != nil {
+		t.Fatalf("verify phone failed: %v", err)
+	}
+	if err := svc.EnableSMSOTP(ctx, user.ID, "wrong"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected wrong password to be rejected, got %v", err)
+	}
+	if err := svc.EnableSMSOTP(ctx, user.ID, "Password123!"); err != nil {
+		t.Fatalf("enable sms OTP failed: %v", err)
+	}
+
+	login := &LoginRequest{Email: user.Email, Password: "Password123!"}
+	if _, err := svc.Login(ctx, login, "10.0.0.1", "test"); err != ErrOTPRequired {
+		t.Fatalf("expected login to require second factor, got %v", err)
+	}
+	if _, err := svc.RequestLoginSMSCode(ctx, &LoginSMSCodeRequest{Email: user.Email, Password: "wrong"}, "10.0.0.1", "test"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected login code request to check password, got %v", err)
+	}
+	if _, err := svc.RequestLoginSMSCode(ctx, &LoginSMSCodeRequest{Email: user.Email, Password: "Password123!"}, "10.0.0.1", "test"); err != nil {
+		t.Fatalf("request login sms code failed: %v", err)
+	}
+	login.OTPCode = sentCode()
+	resp, err := svc.Login(ctx, login, "10.0.0.1", "test")
+	if err != nil {
+		t.Fatalf("login with sms code failed: %v", err)
+	}
+	if !resp.User.SMSOTPEnabled || resp.AccessToken == "" {
+		t.Fatalf("unexpected auth response: %+v", resp.User)
+	}
+	if _, err := svc.Login(ctx, login, "10.0.0.1", "test"); err != ErrInvalidOTP {
+		t.Fatalf("expected used sms code to be rejected, got %v", err)
+	}
+
+	// 手机验证状态已持久化到账户
+	var stored models.User
+	db.First(&stored, user.ID)
+	if !stored.PhoneVerified || stored.Phone != phone || !stored.SMSOTPEnabled || stored.PhoneVerifiedAt == nil {
+		t.Fatalf("unexpected stored user: phone=%s verified=%v sms=%v", stored.Phone, stored.PhoneVerified, stored.SMSOTPEnabled)
+	}
+
+	if err := svc.DisableSMSOTP(ctx, user.ID, "Password123!"); err != nil {
+		t.Fatalf("disable sms OTP failed: %v", err)
+	}
+	login.OTPCode = ""
+	if _, err := svc.Login(ctx, login, "10.0.0.1", "test"); err != nil {
+		t.Fatalf("expected password-only login after disabling sms OTP, got %v", err)
+	}
+}
@@ -33,10 +33,21 @@ type ServerConfig struct {
 	Debug         bool   `json:"debug"`
 	EnableSwagger bool   `json:"enable_swagger"`
 	EnablePprof   bool   `json:"enable_pprof"`
+
+	// 响应压缩：响应体达到最小大小且客户端支持时使用 gzip/deflate 压缩
+	CompressionEnabled bool `json:"compression_enabled"`
+	CompressionMinSize int  `json:"compression_min_size"`
+	CompressionLevel   int  `json:"compression_level"`
+
+	// 受信任的反向代理（IP 或 CIDR），只有来自这些地址的 X-Forwarded-For 才用于确定客户端IP；
+	// 为空时不信任任何代理，直接使用连接的对端地址
+	TrustedProxies []string `json:"trusted_proxies"`
 }
 
 // DatabaseConfig 数据库配置
 type DatabaseConfig struct {
+	Driver          string        `json:"driver"`      // postgres 或 sqlite（仅用于本地开发及测试）
+	SQLitePath      string        `json:"sqlite_path"` // SQLite 数据库文件，:memory: 为内存库
 	Host            string        `json:"host"`
 	Port            int           `json:"port"`
 	User            string        `json:"user"`
@@ -47,6 +58,7 @@ type DatabaseConfig struct {
 	MaxOpenConns    int           `json:"max_open_conns"`
 	MaxIdleConns    int           `json:"max_idle_conns"`
 	ConnMaxLifetime time.Duration `json:"conn_max_lifetime"`
+	JSONStorage     string        `json:"json_storage"` // JSON 列存储方式：text 或 jsonb（仅 PostgreSQL）
 }
 
 // RedisConfig Redis配置
@@ -59,6 +71,13 @@ type RedisConfig struct {
 	MinIdleConns int           `json:"min_idle_conns"`
 	PoolTimeout  time.Duration `json:"pool_timeout"`
 	IdleTimeout  time.Duration `json:"idle_timeout"`
+
+	// 熔断配置：连续失败达到阈值后熔断，熔断期间请求直接失败并走降级路径，
+	// 到期后放行一次探测，探测失败则按指数退避延长熔断时间
+	BreakerThreshold   int           `json:"breaker_threshold"`
+	BreakerOpenTimeout time.Duration `json:"breaker_open_timeout"`
+	BreakerMaxBackoff  time.Duration `json:"breaker_max_backoff"`
+	OperationTimeout   time.Duration `json:"operation_timeout"` // 单次命令超时
 }
 
 // JWTConfig JWT配置
@@ -88,6 +107,13 @@ type OTPConfig struct {
 type SecurityConfig struct {
 	BcryptCost int    `json:"bcrypt_cost"`
 	CSRFSecret string `json:"csrf_secret"`
+
+	// 会话模式：bearer（默认）由客户端通过 Authorization 头发送令牌；
+	// cookie 将令牌写入 httpOnly Cookie，写请求需携带 X-CSRF-Token
+	SessionMode    string `json:"session_mode"`
+	CookieDomain   string `json:"cookie_domain"`
+	CookieSecure   bool   `json:"cookie_secure"`
+	CookieSameSite string `json:"cookie_same_site"` // lax、strict 或 none（none 需启用 Secure）
 }
 
 // AppConfig 应用配置
@@ -116,6 +142,9 @@ type LogConfig struct {
 type UploadConfig struct {
 	MaxSize      string   `json:"max_size"`
 	AllowedTypes []string `json:"allowed_types"`
+	Dir          string   `json:"dir"`        // 本地存储目录
+	URLPrefix    string   `json:"url_prefix"` // 本地存储文件的访问路径前缀
+	KeyFile      string   `json:"-"`          // 附件加密主密钥文件，为空时附件不加密存储
 }
 
 // RateLimitConfig 限流配置
@@ -140,8 +169,16 @@ func Load() (*Config, error) {
 			Debug:         getEnvAsBool("DEBUG", true),
 			EnableSwagger: getEnvAsBool("ENABLE_SWAGGER", true),
 			EnablePprof:   getEnvAsBool("ENABLE_PPROF", false),
+
+			CompressionEnabled: getEnvAsBool("COMPRESSION_ENABLED", true),
+			CompressionMinSize: getEnvAsInt("COMPRESSION_MIN_SIZE", 1024),
+			CompressionLevel:   getEnvAsInt("COMPRESSION_LEVEL", 5),
+
+			TrustedProxies: getEnvAsSlice("TRUSTED_PROXIES", nil),
 		},
 		Database: DatabaseConfig{
+			Driver:          getEnv("DB_DRIVER", "postgres"),
+			SQLitePath:      getEnv("DB_SQLITE_PATH", "data/gongdan.db"),
 			Host:            getEnv("DB_HOST", "localhost"),
 			Port:            getEnvAsInt("DB_PORT", 5432),
 			User:            getEnv("DB_USER", "ticket_user"),
@@ -152,6 +189,7 @@ func Load() (*Config, error) {
 			MaxOpenConns:    getEnvAsInt("DB_MAX_OPEN_CONNS", 25),
 			MaxIdleConns:    getEnvAsInt("DB_MAX_IDLE_CONNS", 5),
 			ConnMaxLifetime: getEnvAsDuration("DB_CONN_MAX_LIFETIME", 5*time.Minute),
+			JSONStorage:     getEnv("DB_JSON_STORAGE", "text"),
 		},
 		Redis: RedisConfig{
 			Host:         getEnv("REDIS_HOST", "localhost"),
@@ -162,6 +200,11 @@ func Load() (*Config, error) {
 			MinIdleConns: getEnvAsInt("REDIS_MIN_IDLE_CONNS", 5),
 			PoolTimeout:  getEnvAsDuration("REDIS_POOL_TIMEOUT", 4*time.Second),
 			IdleTimeout:  getEnvAsDuration("REDIS_IDLE_TIMEOUT", 5*time.Minute),
+
+			BreakerThreshold:   getEnvAsInt("REDIS_BREAKER_THRESHOLD", 5),
+			BreakerOpenTimeout: getEnvAsDuration("REDIS_BREAKER_OPEN_TIMEOUT", 5*time.Second),
+			BreakerMaxBackoff:  getEnvAsDuration("REDIS_BREAKER_MAX_BACKOFF", 2*time.Minute),
+			OperationTimeout:   getEnvAsDuration("REDIS_OPERATION_TIMEOUT", 2*time.Second),
 		},
 		JWT: JWTConfig{
 			Secret:           getEnv("JWT_SECRET", "your-super-secret-jwt-key-change-in-production"),
@@ -183,6 +226,11 @@ func Load() (*Config, error) {
 		Security: SecurityConfig{
 			BcryptCost: getEnvAsInt("BCRYPT_COST", 12),
 			CSRFSecret: getEnv("CSRF_SECRET", "your-csrf-secret-key"),
+
+			SessionMode:    getEnv("AUTH_SESSION_MODE", "bearer"),
+			CookieDomain:   getEnv("AUTH_COOKIE_DOMAIN", ""),
+			CookieSecure:   getEnvAsBool("AUTH_COOKIE_SECURE", getEnv("ENVIRONMENT", "development") == "production"),
+			CookieSameSite: getEnv("AUTH_COOKIE_SAMESITE", "lax"),
 		},
 		App: AppConfig{
 			Name:    getEnv("APP_NAME", "Ticket System"),
@@ -203,6 +251,9 @@ func Load() (*Config, error) {
 		Upload: UploadConfig{
 			MaxSize:      getEnv("UPLOAD_MAX_SIZE", "10MB"),
 			AllowedTypes: getEnvAsSlice("UPLOAD_ALLOWED_TYPES", []string{"jpg", "jpeg", "png", "gif", "pdf", "doc", "docx"}),
+			Dir:          getEnv("UPLOAD_DIR", "./uploads"),
+			URLPrefix:    getEnv("UPLOAD_URL_PREFIX", "/uploads"),
+			KeyFile:      getEnv("ATTACHMENT_KEYFILE", ""),
 		},
 		RateLimit: RateLimitConfig{
 			Requests: getEnvAsInt("RATE_LIMIT_REQUESTS", 100),
@@ -225,22 +276,57 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("JWT secret must be changed in production environment")
 	}
 
-	if c.Database.Host == "" {
-		return fmt.Errorf("database host is required")
-	}
+	switch c.Database.Driver {
+	case "postgres":
+		if c.Database.Host == "" {
+			return fmt.Errorf("database host is required")
+		}
 
-	if c.Database.User == "" {
-		return fmt.Errorf("database user is required")
+		if c.Database.User == "" {
+			return fmt.Errorf("database user is required")
+		}
+
+		if c.Database.Name == "" {
+			return fmt.Errorf("database name is required")
+		}
+	case "sqlite":
+		if c.Database.SQLitePath == "" {
+			return fmt.Errorf("sqlite database path is required")
+		}
+		if c.Server.Environment == "production" {
+			return fmt.Errorf("sqlite driver is not supported in production environment")
+		}
+	default:
+		return fmt.Errorf("database driver must be postgres or sqlite")
 	}
 
-	if c.Database.Name == "" {
-		return fmt.Errorf("database name is required")
+	if c.Database.JSONStorage != "text" && c.Database.JSONStorage != "jsonb" {
+		return fmt.Errorf("database json storage must be text or jsonb")
 	}
 
 	if c.Redis.Host == "" {
 		return fmt.Errorf("redis host is required")
 	}
 
+	switch c.Security.SessionMode {
+	case "bearer":
+	case "cookie":
+		switch strings.ToLower(c.Security.CookieSameSite) {
+		case "lax", "strict":
+		case "none":
+			if !c.Security.CookieSecure {
+				return fmt.Errorf("SameSite=None cookies require AUTH_COOKIE_SECURE=true")
+			}
+		default:
+			return fmt.Errorf("auth cookie SameSite must be lax, strict or none")
+		}
+		if c.Security.CSRFSecret == "your-csrf-secret-key" && c.Server.Environment == "production" {
+			return fmt.Errorf("CSRF secret must be changed in production environment when cookie sessions are enabled")
+		}
+	default:
+		return fmt.Errorf("auth session mode must be bearer or cookie")
+	}
+
 	return nil
 }
 
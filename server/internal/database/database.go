@@ -3,23 +3,33 @@ package database
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/go-redis/redis/v8"
 	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 	"gongdan-system/internal/config"
 )
 
+// ErrRedisKeyNotFound 键不存在，各实现统一返回该错误，不视为连接故障
+var ErrRedisKeyNotFound = errors.New("redis: key not found")
+
+// ErrRedisPubSubUnsupported 当前客户端不支持发布订阅（如 HTTP REST 客户端）
+var ErrRedisPubSubUnsupported = errors.New("redis: pub/sub not supported")
+
 // RedisInterface 定义Redis接口，支持不同的实现
 type RedisInterface interface {
 	Ping(ctx context.Context) error
 	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error
 	Get(ctx context.Context, key string) (string, error)
+	Incr(ctx context.Context, key string) (int64, error)
 	Del(ctx context.Context, keys ...string) error
 	Exists(ctx context.Context, keys ...string) (int64, error)
 	Expire(ctx context.Context, key string, expiration time.Duration) error
@@ -27,6 +37,13 @@ type RedisInterface interface {
 	Close() error
 }
 
+// RedisPubSub 发布订阅，TCP 客户端实现，HTTP REST 客户端不支持
+type RedisPubSub interface {
+	Publish(ctx context.Context, channel, message string) error
+	// Subscribe 订阅频道，ctx 取消或连接关闭时返回的 channel 被关闭
+	Subscribe(ctx context.Context, channel string) (<-chan string, error)
+}
+
 // TCPRedisClient TCP Redis客户端包装器
 type TCPRedisClient struct {
 	client *redis.Client
@@ -47,7 +64,15 @@ func (c *TCPRedisClient) Set(ctx context.Context, key string, value interface{},
 }
 
 func (c *TCPRedisClient) Get(ctx context.Context, key string) (string, error) {
-	return c.client.Get(ctx, key).Result()
+	value, err := c.client.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrRedisKeyNotFound
+	}
+	return value, err
+}
+
+func (c *TCPRedisClient) Incr(ctx context.Context, key string) (int64, error) {
+	return c.client.Incr(ctx, key).Result()
 }
 
 func (c *TCPRedisClient) Del(ctx context.Context, keys ...string) error {
@@ -70,6 +95,42 @@ func (c *TCPRedisClient) Close() error {
 	return c.client.Close()
 }
 
+// 实现RedisPubSub接口
+func (c *TCPRedisClient) Publish(ctx context.Context, channel, message string) error {
+	return c.client.Publish(ctx, channel, message).Err()
+}
+
+func (c *TCPRedisClient) Subscribe(ctx context.Context, channel string) (<-chan string, error) {
+	pubsub := c.client.Subscribe(ctx, channel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		pubsub.Close()
+		return nil, err
+	}
+
+	messages := make(chan string, 16)
+	go func() {
+		defer close(messages)
+		defer pubsub.Close()
+		incoming := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-incoming:
+				if !ok {
+					return
+				}
+				select {
+				case messages <- msg.Payload:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return messages, nil
+}
+
 // DatabaseInterface 定义数据库接口
 type DatabaseInterface interface {
 	Close() error
@@ -79,28 +140,40 @@ type DatabaseInterface interface {
 // Database 数据库结构体
 type Database struct {
 	DB    *gorm.DB
-	Redis RedisInterface
+	Redis *ResilientRedis
 }
 
 // New 创建新的数据库连接
 func New(cfg *config.Config) (*Database, error) {
-	// 连接 PostgreSQL
-	db, err := connectPostgreSQL(cfg)
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+	var db *gorm.DB
+	var err error
+	if cfg.Database.Driver == "sqlite" {
+		// 本地开发及测试使用 SQLite，无需启动 PostgreSQL
+		db, err = connectSQLite(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open SQLite database: %w", err)
+		}
+	} else {
+		// 连接 PostgreSQL
+		db, err = connectPostgreSQL(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to connect to PostgreSQL: %w", err)
+		}
 	}
 
 	// 尝试连接 Redis（可选）
 	rdb, err := connectRedis(cfg)
 	if err != nil {
-		// Redis 连接失败时只记录警告，不阻止应用启动
+		// Redis 连接失败时只记录警告，不阻止应用启动，由熔断器的健康检查后台重连
 		fmt.Printf("Warning: Failed to connect to Redis: %v\n", err)
 		rdb = nil
 	}
 
 	return &Database{
-		DB:    db,
-		Redis: rdb,
+		DB: db,
+		Redis: NewResilientRedis(rdb, func() (RedisInterface, error) {
+			return connectRedis(cfg)
+		}, RedisBreakerOptionsFromConfig(cfg)),
 	}, nil
 }
 
@@ -153,6 +226,33 @@ func connectPostgreSQL(cfg *config.Config) (*gorm.DB, error) {
 	return db, nil
 }
 
+// connectSQLite 打开 SQLite 数据库，:memory: 为进程内共享的内存库
+func connectSQLite(cfg *config.Config) (*gorm.DB, error) {
+	path := cfg.Database.SQLitePath
+	if path == ":memory:" {
+		path = "file::memory:?cache=shared"
+	} else {
+		if dir := filepath.Dir(path); dir != "." {
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				return nil, err
+			}
+		}
+		// 开启 WAL 并设置忙等待，避免后台任务与请求并发写入时报 database is locked
+		path = "file:" + path + "?_journal_mode=WAL&_busy_timeout=5000&_foreign_keys=on"
+	}
+
+	var logLevel logger.LogLevel
+	if cfg.Server.Environment == "production" {
+		logLevel = logger.Error
+	} else {
+		logLevel = logger.Info
+	}
+
+	return gorm.Open(sqlite.Open(path), &gorm.Config{
+		Logger: logger.Default.LogMode(logLevel),
+	})
+}
+
 // connectRedis 连接 Redis
 func connectRedis(cfg *config.Config) (RedisInterface, error) {
 	// 首先尝试HTTP REST API连接（推荐用于Upstash）
@@ -271,8 +371,8 @@ func (d *Database) HealthCheck() error {
 		}
 	}
 
-	// 检查 Redis 连接（如果可用）
-	if d.Redis != nil {
+	// 检查 Redis 连接（如果可用），熔断期间由熔断器负责探测
+	if d.Redis != nil && d.Redis.Available() {
 		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 		defer cancel()
 		if err := d.Redis.Ping(ctx); err != nil {
@@ -387,7 +387,9 @@ func (m *FastMigrator) GetIndexSQL() []string {
 		`CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_notifications_scheduled ON notifications(scheduled_at) WHERE scheduled_at IS NOT NULL;`,
 
 		// 全文搜索索引
-		`CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_tickets_search ON tickets USING gin(to_tsvector('english', title || ' ' || description));`,
+		// 默认使用 simple 配置，安装中文分词扩展后可通过 migrate -search-indexes 按语言配置重建
+		`CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_tickets_fts_simple ON tickets USING gin(to_tsvector('simple', coalesce(title, '') || ' ' || coalesce(description, '')));`,
+		`CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_ticket_comments_fts_simple ON ticket_comments USING gin(to_tsvector('simple', coalesce(content, '')));`,
 	}
 }
 
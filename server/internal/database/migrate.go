@@ -20,7 +20,11 @@ func AutoMigrate(db *gorm.DB) error {
 		&auth.UserProfile{},
 		&auth.RefreshToken{},
 		&auth.LoginAttempt{},
+		&auth.EmailVerification{},
+		&auth.PasswordReset{},
+		&auth.MagicLinkToken{},
 		&models.Category{},
+		&models.Team{},
 		&models.Ticket{},
 		&models.TicketComment{},
 		&models.TicketHistory{},
@@ -31,6 +35,7 @@ func AutoMigrate(db *gorm.DB) error {
 		&models.LoginHistory{},
 		&models.SystemConfig{},
 		&models.CleanupLog{},
+		&models.NotificationArchive{},
 		// FE008 自动化相关模型
 		&models.AutomationRule{},
 		&models.SLAConfig{},
@@ -38,6 +43,62 @@ func AutoMigrate(db *gorm.DB) error {
 		&models.AutomationLog{},
 		&models.QuickReply{},
 		&models.AdminAuditLog{},
+		// 邮件渠道共享收件箱
+		&models.InboundEmail{},
+		&models.EmailThreadMessage{},
+		&models.InboxBlocklistEntry{},
+		&models.TicketChangeProposal{},
+		&models.KBArticle{},
+		&models.KBArticleTicketLink{},
+		&models.AutomationRuleRevision{},
+		&models.AssignmentDelegation{},
+		&models.DelegatedTicket{},
+		&models.TicketCommentDraft{},
+		&models.TicketReplyLock{},
+		&models.IntakeQuarantineItem{},
+		&models.UserBulkJob{},
+		&models.ChatUserMapping{},
+		&models.ChatTicketThread{},
+		&models.TicketAccessLog{},
+		&models.AnalyticsExportJob{},
+		&models.AccountDeletionRequest{},
+		&models.SLAContract{},
+		&models.TicketChecklistItem{},
+		&models.PushSubscription{},
+		&models.TicketBulkCommentJob{},
+		&models.QuotaAlert{},
+		&models.SyncTombstone{},
+		&models.CommentTranslation{},
+		&models.TranslationPreference{},
+		&models.TicketWarRoom{},
+		&models.TicketCallLog{},
+		&models.SMSMessage{},
+		&models.EmailAddressStatus{},
+		&models.EmailDeliveryEvent{},
+		&models.ScriptHook{},
+		&models.ScriptHookLog{},
+		&models.PrefillLink{},
+		&models.TicketClassification{},
+		&models.UserInvitation{},
+		&models.ExternalReference{},
+		&models.PendingUpload{},
+		&models.NotificationDelivery{},
+		&models.TicketHandoffSchedule{},
+		&models.TicketHandoff{},
+		&models.TicketHandoffItem{},
+		&models.SLAWarning{},
+		&models.TicketReminder{},
+		&models.UserFieldDefinition{},
+		&models.UserFieldValue{},
+		&models.UserSkill{},
+		&models.AutomationSimulationJob{},
+		&models.TicketArchiveJob{},
+		&models.LoginIPBan{},
+		&models.TicketSummary{},
+		&models.TicketBillingReference{},
+		&models.BillingPeriodLock{},
+		&models.CategoryMembership{},
+		&models.DownloadArtifact{},
 	)
 
 	if err != nil {
@@ -74,9 +135,9 @@ func CreateIndexes(db *gorm.DB) error {
 		"CREATE INDEX IF NOT EXISTS idx_tickets_type ON tickets(type);",
 		"CREATE INDEX IF NOT EXISTS idx_tickets_source ON tickets(source);",
 		"CREATE INDEX IF NOT EXISTS idx_tickets_category_id ON tickets(category_id);",
-		"CREATE INDEX IF NOT EXISTS idx_tickets_created_by ON tickets(created_by);",
-		"CREATE INDEX IF NOT EXISTS idx_tickets_assigned_to ON tickets(assigned_to);",
-		"CREATE INDEX IF NOT EXISTS idx_tickets_due_at ON tickets(due_at);",
+		"CREATE INDEX IF NOT EXISTS idx_tickets_created_by ON tickets(created_by_id);",
+		"CREATE INDEX IF NOT EXISTS idx_tickets_assigned_to ON tickets(assigned_to_id);",
+		"CREATE INDEX IF NOT EXISTS idx_tickets_due_at ON tickets(due_date);",
 		"CREATE INDEX IF NOT EXISTS idx_tickets_resolved_at ON tickets(resolved_at);",
 		"CREATE INDEX IF NOT EXISTS idx_tickets_closed_at ON tickets(closed_at);",
 		"CREATE INDEX IF NOT EXISTS idx_tickets_status_priority ON tickets(status, priority);",
@@ -299,6 +360,49 @@ func generateSampleDataIfNeeded(db *gorm.DB) error {
 	return nil
 }
 
+// BackfillTicketImpactUrgency 按现有优先级为历史工单补全影响和紧急程度
+func BackfillTicketImpactUrgency(db *gorm.DB) error {
+	priorities := []models.TicketPriority{
+		models.TicketPriorityLow,
+		models.TicketPriorityNormal,
+		models.TicketPriorityHigh,
+		models.TicketPriorityUrgent,
+		models.TicketPriorityCritical,
+	}
+
+	var total int64
+	for _, priority := range priorities {
+		impact, urgency := models.DefaultImpactUrgencyForPriority(priority)
+		result := db.Model(&models.Ticket{}).
+			Where("priority = ? AND (impact IS NULL OR impact = '')", priority).
+			Updates(map[string]interface{}{"impact": impact, "urgency": urgency})
+		if result.Error != nil {
+			return fmt.Errorf("failed to backfill impact/urgency for %s: %w", priority, result.Error)
+		}
+		total += result.RowsAffected
+	}
+
+	if total > 0 {
+		log.Printf("Backfilled impact/urgency for %d tickets", total)
+	}
+	return nil
+}
+
+// BackfillCommentVisibility 为历史内部评论补全可见范围，避免新增列默认值将其暴露为公开
+func BackfillCommentVisibility(db *gorm.DB) error {
+	result := db.Model(&models.TicketComment{}).
+		Where("type = ? AND (visibility IS NULL OR visibility = '' OR visibility = ?)", models.CommentTypeInternal, models.CommentVisibilityPublic).
+		UpdateColumn("visibility", models.CommentVisibilityInternal)
+	if result.Error != nil {
+		return fmt.Errorf("failed to backfill comment visibility: %w", result.Error)
+	}
+
+	if result.RowsAffected > 0 {
+		log.Printf("Backfilled visibility for %d internal comments", result.RowsAffected)
+	}
+	return nil
+}
+
 // RunMigrations 运行完整的数据库迁移流程
 func RunMigrations(db *gorm.DB) error {
 	log.Println("Running database migrations...")
@@ -308,12 +412,20 @@ func RunMigrations(db *gorm.DB) error {
 		return fmt.Errorf("auto migration failed: %w", err)
 	}
 
-	// 2. 创建额外索引
+	// 2. 为历史工单补全影响/紧急程度
+	if err := BackfillTicketImpactUrgency(db); err != nil {
+		return fmt.Errorf("impact/urgency backfill failed: %w", err)
+	}
+	if err := BackfillCommentVisibility(db); err != nil {
+		return fmt.Errorf("comment visibility backfill failed: %w", err)
+	}
+
+	// 3. 创建额外索引
 	if err := CreateIndexes(db); err != nil {
 		return fmt.Errorf("index creation failed: %w", err)
 	}
 
-	// 3. 初始化种子数据
+	// 4. 初始化种子数据
 	if err := SeedData(db); err != nil {
 		return fmt.Errorf("seed data creation failed: %w", err)
 	}
@@ -0,0 +1,39 @@
+package database
+
+import (
+	"path/filepath"
+	"testing"
+
+	"gongdan-system/internal/config"
+	"gongdan-system/internal/models"
+)
+
+func TestRunMigrations_SQLite(t *testing.T) {
+	cfg := &config.Config{Database: config.DatabaseConfig{Driver: "sqlite", SQLitePath: filepath.Join(t.TempDir(), "data", "test.db")}}
+	db, err := connectSQLite(cfg)
+	if err != nil {
+		t.Fatalf("failed to open sqlite database: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	defer sqlDB.Close()
+
+	// 迁移可重复执行
+	for i := 0; i < 2; i++ {
+		if err := RunMigrations(db); err != nil {
+			t.Fatalf("migration run %d failed: %v", i+1, err)
+		}
+	}
+
+	for _, index := range []string{"idx_tickets_created_by", "idx_tickets_assigned_to", "idx_tickets_due_at"} {
+		var count int64
+		db.Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND name = ?", index).Scan(&count)
+		if count != 1 {
+			t.Fatalf("expected index %s to be created", index)
+		}
+	}
+	var admins int64
+	db.Model(&models.User{}).Where("role = ?", models.RoleAdmin).Count(&admins)
+	if admins == 0 {
+		t.Fatalf("expected seed data to create an admin user")
+	}
+}
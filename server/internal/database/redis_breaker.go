@@ -0,0 +1,399 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"gongdan-system/internal/config"
+)
+
+// RedisBreakerState 熔断器状态
+type RedisBreakerState string
+
+const (
+	RedisBreakerClosed   RedisBreakerState = "closed"    // 正常
+	RedisBreakerOpen     RedisBreakerState = "open"      // 熔断，请求直接失败
+	RedisBreakerHalfOpen RedisBreakerState = "half_open" // 放行一次探测
+)
+
+// ErrRedisUnavailable Redis 不可用（熔断中或未连接），调用方应走降级路径
+var ErrRedisUnavailable = errors.New("redis unavailable")
+
+// RedisBreakerOptions 熔断器参数
+type RedisBreakerOptions struct {
+	FailureThreshold int           // 连续失败多少次后熔断
+	OpenTimeout      time.Duration // 首次熔断时长
+	MaxBackoff       time.Duration // 探测失败后熔断时长翻倍，不超过该值
+	OperationTimeout time.Duration // 单次命令超时
+}
+
+// RedisBreakerOptionsFromConfig 从配置读取熔断参数，未配置的项使用默认值
+func RedisBreakerOptionsFromConfig(cfg *config.Config) RedisBreakerOptions {
+	opts := RedisBreakerOptions{
+		FailureThreshold: cfg.Redis.BreakerThreshold,
+		OpenTimeout:      cfg.Redis.BreakerOpenTimeout,
+		MaxBackoff:       cfg.Redis.BreakerMaxBackoff,
+		OperationTimeout: cfg.Redis.OperationTimeout,
+	}
+	if opts.FailureThreshold <= 0 {
+		opts.FailureThreshold = 5
+	}
+	if opts.OpenTimeout <= 0 {
+		opts.OpenTimeout = 5 * time.Second
+	}
+	if opts.MaxBackoff < opts.OpenTimeout {
+		opts.MaxBackoff = opts.OpenTimeout
+	}
+	return opts
+}
+
+// RedisBreakerStats 熔断器状态及计数，用于健康检查和监控
+type RedisBreakerStats struct {
+	State               RedisBreakerState `json:"state"`
+	Connected           bool              `json:"connected"`
+	ConsecutiveFailures int               `json:"consecutive_failures"`
+	TotalRequests       int64             `json:"total_requests"`
+	TotalFailures       int64             `json:"total_failures"`
+	RejectedRequests    int64             `json:"rejected_requests"` // 熔断期间被直接拒绝的请求
+	Reconnects          int64             `json:"reconnects"`
+	StateChanges        int64             `json:"state_changes"`
+	LastError           string            `json:"last_error,omitempty"`
+	LastFailureAt       *time.Time        `json:"last_failure_at,omitempty"`
+	StateChangedAt      time.Time         `json:"state_changed_at"`
+	NextRetryAt         *time.Time        `json:"next_retry_at,omitempty"`
+	BackoffSeconds      float64           `json:"backoff_seconds,omitempty"`
+}
+
+// ResilientRedis 带熔断的 Redis 客户端，实现 RedisInterface。
+// 连续失败后熔断，熔断期间所有命令立即返回 ErrRedisUnavailable，避免请求堆积在超时上；
+// 到期后放行一次探测，成功则恢复，失败则按指数退避继续熔断。
+// 启动时未能连接的情况下由健康检查协程定期重连。
+type ResilientRedis struct {
+	dial func() (RedisInterface, error)
+	opts RedisBreakerOptions
+	now  func() time.Time
+
+	mu          sync.Mutex
+	client      RedisInterface
+	state       RedisBreakerState
+	consecutive int
+	backoff     time.Duration
+	nextAttempt time.Time
+	probing     bool
+	stats       RedisBreakerStats
+}
+
+// NewResilientRedis 包装 Redis 客户端；client 为 nil 时以熔断状态启动，由 dial 重连
+func NewResilientRedis(client RedisInterface, dial func() (RedisInterface, error), opts RedisBreakerOptions) *ResilientRedis {
+	r := &ResilientRedis{
+		dial:   dial,
+		opts:   opts,
+		now:    time.Now,
+		client: client,
+		state:  RedisBreakerClosed,
+	}
+	r.stats.StateChangedAt = r.now()
+	if client == nil {
+		r.state = RedisBreakerOpen
+		r.backoff = opts.OpenTimeout
+		r.nextAttempt = r.now().Add(opts.OpenTimeout)
+	}
+	return r
+}
+
+// Available Redis 当前是否可用（未熔断），供调用方提前选择降级路径
+func (r *ResilientRedis) Available() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.client != nil && r.state != RedisBreakerOpen
+}
+
+// Stats 返回熔断器状态快照
+func (r *ResilientRedis) Stats() RedisBreakerStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := r.stats
+	stats.State = r.state
+	stats.Connected = r.client != nil && r.state == RedisBreakerClosed
+	stats.ConsecutiveFailures = r.consecutive
+	if r.state == RedisBreakerOpen {
+		next := r.nextAttempt
+		stats.NextRetryAt = &next
+		stats.BackoffSeconds = r.backoff.Seconds()
+	}
+	return stats
+}
+
+// StartHealthCheck 定期探测 Redis：正常时检测故障，熔断到期时探测恢复，未连接时重连
+func (r *ResilientRedis) StartHealthCheck(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.checkHealth(ctx)
+			}
+		}
+	}()
+}
+
+func (r *ResilientRedis) checkHealth(ctx context.Context) {
+	r.mu.Lock()
+	due := r.state != RedisBreakerOpen || !r.now().Before(r.nextAttempt)
+	disconnected := r.client == nil
+	r.mu.Unlock()
+	if !due {
+		return
+	}
+
+	if disconnected {
+		r.reconnect()
+		return
+	}
+	_ = r.Ping(ctx)
+}
+
+// reconnect 重新建立连接，失败时延长熔断时间
+func (r *ResilientRedis) reconnect() {
+	if r.dial == nil {
+		return
+	}
+	client, err := r.dial()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		r.recordFailureLocked(err, true)
+		return
+	}
+	if r.client != nil {
+		r.client.Close()
+	}
+	r.client = client
+	r.stats.Reconnects++
+	r.recordSuccessLocked()
+}
+
+// acquire 判断本次命令是否放行，熔断到期后仅放行一次探测
+func (r *ResilientRedis) acquire() (RedisInterface, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.stats.TotalRequests++
+	probe := false
+	switch r.state {
+	case RedisBreakerOpen:
+		if r.client == nil || r.probing || r.now().Before(r.nextAttempt) {
+			r.stats.RejectedRequests++
+			return nil, false, ErrRedisUnavailable
+		}
+		r.setStateLocked(RedisBreakerHalfOpen)
+		r.probing = true
+		probe = true
+	case RedisBreakerHalfOpen:
+		if r.probing {
+			r.stats.RejectedRequests++
+			return nil, false, ErrRedisUnavailable
+		}
+		r.probing = true
+		probe = true
+	}
+	return r.client, probe, nil
+}
+
+// do 经过熔断器执行一次命令
+func (r *ResilientRedis) do(ctx context.Context, op func(ctx context.Context, client RedisInterface) error) error {
+	client, probe, err := r.acquire()
+	if err != nil {
+		return err
+	}
+
+	opCtx := ctx
+	if r.opts.OperationTimeout > 0 {
+		var cancel context.CancelFunc
+		opCtx, cancel = context.WithTimeout(ctx, r.opts.OperationTimeout)
+		defer cancel()
+	}
+	err = op(opCtx, client)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	switch {
+	case err == nil || errors.Is(err, ErrRedisKeyNotFound):
+		r.recordSuccessLocked()
+	case ctx.Err() != nil:
+		// 调用方取消的请求不反映 Redis 健康状况
+		if probe {
+			r.probing = false
+		}
+	default:
+		r.recordFailureLocked(err, probe)
+	}
+	return err
+}
+
+func (r *ResilientRedis) recordSuccessLocked() {
+	r.consecutive = 0
+	r.probing = false
+	if r.state != RedisBreakerClosed {
+		log.Printf("Redis recovered, circuit breaker closed")
+		r.backoff = 0
+		r.setStateLocked(RedisBreakerClosed)
+	}
+}
+
+func (r *ResilientRedis) recordFailureLocked(err error, probe bool) {
+	now := r.now()
+	r.consecutive++
+	r.stats.TotalFailures++
+	r.stats.LastError = err.Error()
+	r.stats.LastFailureAt = &now
+
+	// 熔断前已放行的请求随后失败时只计数，不重复延长熔断时间
+	switch {
+	case probe:
+		r.probing = false
+		r.backoff *= 2
+		if r.backoff < r.opts.OpenTimeout {
+			r.backoff = r.opts.OpenTimeout
+		}
+		if r.backoff > r.opts.MaxBackoff {
+			r.backoff = r.opts.MaxBackoff
+		}
+		log.Printf("Redis still unavailable, next retry in %s: %v", r.backoff, err)
+		r.openLocked(now)
+	case r.state == RedisBreakerClosed && r.consecutive >= r.opts.FailureThreshold:
+		r.backoff = r.opts.OpenTimeout
+		log.Printf("Redis failed %d times in a row, circuit breaker open for %s: %v", r.consecutive, r.backoff, err)
+		r.openLocked(now)
+	}
+}
+
+func (r *ResilientRedis) openLocked(now time.Time) {
+	r.nextAttempt = now.Add(r.backoff)
+	r.setStateLocked(RedisBreakerOpen)
+}
+
+func (r *ResilientRedis) setStateLocked(state RedisBreakerState) {
+	if r.state == state {
+		return
+	}
+	r.state = state
+	r.stats.StateChanges++
+	r.stats.StateChangedAt = r.now()
+}
+
+// 实现RedisInterface接口
+func (r *ResilientRedis) Ping(ctx context.Context) error {
+	return r.do(ctx, func(ctx context.Context, client RedisInterface) error {
+		return client.Ping(ctx)
+	})
+}
+
+func (r *ResilientRedis) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	return r.do(ctx, func(ctx context.Context, client RedisInterface) error {
+		return client.Set(ctx, key, value, expiration)
+	})
+}
+
+func (r *ResilientRedis) Get(ctx context.Context, key string) (string, error) {
+	var value string
+	err := r.do(ctx, func(ctx context.Context, client RedisInterface) (err error) {
+		value, err = client.Get(ctx, key)
+		return err
+	})
+	return value, err
+}
+
+func (r *ResilientRedis) Incr(ctx context.Context, key string) (int64, error) {
+	var value int64
+	err := r.do(ctx, func(ctx context.Context, client RedisInterface) (err error) {
+		value, err = client.Incr(ctx, key)
+		return err
+	})
+	return value, err
+}
+
+func (r *ResilientRedis) Del(ctx context.Context, keys ...string) error {
+	return r.do(ctx, func(ctx context.Context, client RedisInterface) error {
+		return client.Del(ctx, keys...)
+	})
+}
+
+func (r *ResilientRedis) Exists(ctx context.Context, keys ...string) (int64, error) {
+	var count int64
+	err := r.do(ctx, func(ctx context.Context, client RedisInterface) (err error) {
+		count, err = client.Exists(ctx, keys...)
+		return err
+	})
+	return count, err
+}
+
+func (r *ResilientRedis) Expire(ctx context.Context, key string, expiration time.Duration) error {
+	return r.do(ctx, func(ctx context.Context, client RedisInterface) error {
+		return client.Expire(ctx, key, expiration)
+	})
+}
+
+func (r *ResilientRedis) TTL(ctx context.Context, key string) (time.Duration, error) {
+	var ttl time.Duration
+	err := r.do(ctx, func(ctx context.Context, client RedisInterface) (err error) {
+		ttl, err = client.TTL(ctx, key)
+		return err
+	})
+	return ttl, err
+}
+
+// pubSubClient 返回支持发布订阅的当前客户端；熔断中或客户端不支持时返回错误，不计入熔断统计
+func (r *ResilientRedis) pubSubClient() (RedisPubSub, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.client == nil || r.state == RedisBreakerOpen {
+		return nil, ErrRedisUnavailable
+	}
+	pubsub, ok := r.client.(RedisPubSub)
+	if !ok {
+		return nil, ErrRedisPubSubUnsupported
+	}
+	return pubsub, nil
+}
+
+// Publish 发布消息，经过熔断器
+func (r *ResilientRedis) Publish(ctx context.Context, channel, message string) error {
+	if _, err := r.pubSubClient(); err != nil {
+		return err
+	}
+	return r.do(ctx, func(ctx context.Context, client RedisInterface) error {
+		pubsub, ok := client.(RedisPubSub)
+		if !ok {
+			return ErrRedisPubSubUnsupported
+		}
+		return pubsub.Publish(ctx, channel, message)
+	})
+}
+
+// Subscribe 在当前客户端上订阅频道。订阅是长连接，不经过熔断器；
+// 重连后旧客户端关闭，返回的 channel 随之关闭，调用方应重新订阅
+func (r *ResilientRedis) Subscribe(ctx context.Context, channel string) (<-chan string, error) {
+	pubsub, err := r.pubSubClient()
+	if err != nil {
+		return nil, err
+	}
+	return pubsub.Subscribe(ctx, channel)
+}
+
+func (r *ResilientRedis) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.client == nil {
+		return nil
+	}
+	return r.client.Close()
+}
@@ -0,0 +1,139 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeRedis 可切换故障状态的内存 Redis
+type fakeRedis struct {
+	down  bool
+	calls int
+	data  map[string]string
+}
+
+var errFakeDown = errors.New("connection refused")
+
+func (f *fakeRedis) op() error {
+	f.calls++
+	if f.down {
+		return errFakeDown
+	}
+	return nil
+}
+
+func (f *fakeRedis) Ping(ctx context.Context) error { return f.op() }
+func (f *fakeRedis) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	if err := f.op(); err != nil {
+		return err
+	}
+	f.data[key] = value.(string)
+	return nil
+}
+func (f *fakeRedis) Get(ctx context.Context, key string) (string, error) {
+	if err := f.op(); err != nil {
+		return "", err
+	}
+	value, ok := f.data[key]
+	if !ok {
+		return "", ErrRedisKeyNotFound
+	}
+	return value, nil
+}
+func (f *fakeRedis) Incr(ctx context.Context, key string) (int64, error)           { return 0, f.op() }
+func (f *fakeRedis) Del(ctx context.Context, keys ...string) error                 { return f.op() }
+func (f *fakeRedis) Exists(ctx context.Context, keys ...string) (int64, error)     { return 0, f.op() }
+func (f *fakeRedis) Expire(ctx context.Context, key string, d time.Duration) error { return f.op() }
+func (f *fakeRedis) TTL(ctx context.Context, key string) (time.Duration, error)    { return 0, f.op() }
+func (f *fakeRedis) Close() error                                                  { return nil }
+
+func TestResilientRedis_OpenProbeAndBackoff(t *testing.T) {
+	ctx := context.Background()
+	backend := &fakeRedis{data: map[string]string{}}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := NewResilientRedis(backend, nil, RedisBreakerOptions{FailureThreshold: 3, OpenTimeout: 5 * time.Second, MaxBackoff: 15 * time.Second})
+	r.now = func() time.Time { return now }
+
+	// 键不存在不算故障
+	if _, err := r.Get(ctx, "missing"); !errors.Is(err, ErrRedisKeyNotFound) {
+		t.Fatalf("expected key not found, got %v", err)
+	}
+
+	backend.down = true
+	for i := 0; i < 3; i++ {
+		r.Set(ctx, "k", "v", 0)
+	}
+	if stats := r.Stats(); stats.State != RedisBreakerOpen || r.Available() {
+		t.Fatalf("expected breaker to open after 3 failures, got %+v", stats)
+	}
+
+	// 熔断期间不访问后端
+	calls := backend.calls
+	if err := r.Set(ctx, "k", "v", 0); !errors.Is(err, ErrRedisUnavailable) || backend.calls != calls {
+		t.Fatalf("expected request to be rejected without hitting redis: %v", err)
+	}
+
+	// 到期后放行一次探测，失败则退避翻倍
+	now = now.Add(5 * time.Second)
+	r.Ping(ctx)
+	stats := r.Stats()
+	if stats.State != RedisBreakerOpen || stats.BackoffSeconds != 10 || backend.calls != calls+1 {
+		t.Fatalf("expected failed probe to double backoff, got %+v", stats)
+	}
+	now = now.Add(10 * time.Second)
+	r.Ping(ctx)
+	now = now.Add(15 * time.Second)
+	r.Ping(ctx)
+	if stats := r.Stats(); stats.BackoffSeconds != 15 {
+		t.Fatalf("expected backoff to be capped at 15s, got %v", stats.BackoffSeconds)
+	}
+
+	// 恢复后探测成功即关闭熔断
+	backend.down = false
+	now = now.Add(15 * time.Second)
+	r.checkHealth(ctx)
+	stats = r.Stats()
+	if stats.State != RedisBreakerClosed || !stats.Connected || stats.RejectedRequests != 1 || stats.StateChanges != 9 {
+		t.Fatalf("expected breaker to close after successful probe, got %+v", stats)
+	}
+	if err := r.Set(ctx, "k", "v", 0); err != nil {
+		t.Fatalf("expected set to succeed after recovery: %v", err)
+	}
+}
+
+func TestResilientRedis_ReconnectWhenStartedDisconnected(t *testing.T) {
+	ctx := context.Background()
+	backend := &fakeRedis{data: map[string]string{}}
+	dialErr := errFakeDown
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	r := NewResilientRedis(nil, func() (RedisInterface, error) {
+		if dialErr != nil {
+			return nil, dialErr
+		}
+		return backend, nil
+	}, RedisBreakerOptions{FailureThreshold: 3, OpenTimeout: time.Second, MaxBackoff: time.Minute})
+	r.now = func() time.Time { return now }
+	r.nextAttempt = now.Add(time.Second)
+
+	if _, err := r.Get(ctx, "k"); !errors.Is(err, ErrRedisUnavailable) {
+		t.Fatalf("expected disconnected client to reject requests, got %v", err)
+	}
+
+	now = now.Add(time.Second)
+	r.checkHealth(ctx)
+	if stats := r.Stats(); stats.Connected || stats.BackoffSeconds != 2 {
+		t.Fatalf("expected failed reconnect to back off, got %+v", stats)
+	}
+
+	dialErr = nil
+	now = now.Add(2 * time.Second)
+	r.checkHealth(ctx)
+	if stats := r.Stats(); !stats.Connected || stats.Reconnects != 1 {
+		t.Fatalf("expected reconnect to succeed, got %+v", stats)
+	}
+	if err := r.Set(ctx, "k", "v", 0); err != nil {
+		t.Fatalf("expected set to succeed after reconnect: %v", err)
+	}
+}
@@ -70,7 +70,7 @@ func (c *HTTPRedisClient) Get(ctx context.Context, key string) (string, error) {
 	}
 
 	if resp.Result == nil {
-		return "", fmt.Errorf("key not found")
+		return "", ErrRedisKeyNotFound
 	}
 
 	if str, ok := resp.Result.(string); ok {
@@ -80,6 +80,21 @@ func (c *HTTPRedisClient) Get(ctx context.Context, key string) (string, error) {
 	return fmt.Sprintf("%v", resp.Result), nil
 }
 
+// Incr 计数器自增
+func (c *HTTPRedisClient) Incr(ctx context.Context, key string) (int64, error) {
+	url := fmt.Sprintf("/incr/%s", key)
+	resp, err := c.makeRequest(ctx, "GET", url, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	if result, ok := resp.Result.(float64); ok {
+		return int64(result), nil
+	}
+
+	return 0, fmt.Errorf("invalid INCR response")
+}
+
 // Del 删除键
 func (c *HTTPRedisClient) Del(ctx context.Context, keys ...string) error {
 	for _, key := range keys {
@@ -407,12 +407,18 @@ func (g *SampleDataGenerator) createSampleComments() error {
 				commenterID = g.users[randomEmail].ID
 			}
 
+			// 工单创建不足1小时时评论时间与工单相同
+			commentedAt := ticket.CreatedAt
+			if hours := int(time.Since(ticket.CreatedAt).Hours()); hours > 0 {
+				commentedAt = commentedAt.Add(time.Duration(rand.Intn(hours)) * time.Hour)
+			}
+
 			comment := models.TicketComment{
 				TicketID:  ticket.ID,
 				UserID:    commenterID,
 				Content:   template,
 				Type:      models.CommentTypePublic,
-				CreatedAt: ticket.CreatedAt.Add(time.Duration(rand.Intn(24*int(time.Since(ticket.CreatedAt).Hours()))) * time.Hour),
+				CreatedAt: commentedAt,
 			}
 
 			if err := g.db.Create(&comment).Error; err != nil {
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// AccountDeletionHandler 账户注销申请管理处理器
+type AccountDeletionHandler struct {
+	deletionService *services.AccountDeletionService
+	response        *middleware.ResponseHelper
+}
+
+// NewAccountDeletionHandler 创建账户注销申请管理处理器
+func NewAccountDeletionHandler(deletionService *services.AccountDeletionService) *AccountDeletionHandler {
+	return &AccountDeletionHandler{
+		deletionService: deletionService,
+		response:        middleware.NewResponseHelper(),
+	}
+}
+
+// RegisterAdminRoutes 注册管理员路由
+func (h *AccountDeletionHandler) RegisterAdminRoutes(router *gin.RouterGroup) {
+	deletions := router.Group("/account-deletions")
+	{
+		deletions.GET("", h.ListRequests)
+		deletions.POST("/:id/cancel", h.CancelRequest)
+	}
+}
+
+// ListRequests 查询注销申请，默认只看处于宽限期的账户
+func (h *AccountDeletionHandler) ListRequests(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	filter := &services.AccountDeletionFilter{
+		Status:   models.AccountDeletionStatus(c.DefaultQuery("status", string(models.AccountDeletionPending))),
+		Page:     page,
+		PageSize: pageSize,
+	}
+	switch filter.Status {
+	case models.AccountDeletionAwaitingConfirmation, models.AccountDeletionPending,
+		models.AccountDeletionCancelled, models.AccountDeletionCompleted:
+	case "all":
+		filter.Status = ""
+	default:
+		h.response.BadRequest(c, "status 只能为 awaiting_confirmation、pending、cancelled、completed 或 all")
+		return
+	}
+
+	requests, total, err := h.deletionService.List(context.Background(), filter)
+	if err != nil {
+		h.response.InternalServerError(c, "获取注销申请失败", err.Error())
+		return
+	}
+	h.response.List(c, requests, total, filter.Page, filter.PageSize, "获取注销申请成功")
+}
+
+// CancelRequest 管理员取消注销申请，账户恢复正常
+func (h *AccountDeletionHandler) CancelRequest(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, "无效的注销申请ID")
+		return
+	}
+
+	request, err := h.deletionService.AdminCancel(context.Background(), uint(id), c.GetUint("user_id"))
+	if err != nil {
+		if errors.Is(err, services.ErrAccountDeletionNotFound) {
+			h.response.NotFound(c, "注销申请不存在或已结束")
+			return
+		}
+		h.response.InternalServerError(c, "取消注销申请失败", err.Error())
+		return
+	}
+	h.response.Success(c, request, "注销申请已取消")
+}
@@ -37,6 +37,7 @@ func (h *AdminAuditHandler) GetAuditLogs(c *gin.Context) {
 		Path      string `form:"path"`
 		Status    string `form:"status"`
 		Keyword   string `form:"keyword"`
+		EventType string `form:"event_type"`
 		StartTime string `form:"start_time"`
 		EndTime   string `form:"end_time"`
 		Page      int    `form:"page"`
@@ -53,12 +54,13 @@ func (h *AdminAuditHandler) GetAuditLogs(c *gin.Context) {
 	}
 
 	filter := &services.AdminAuditFilter{
-		Role:    query.Role,
-		Method:  query.Method,
-		Path:    query.Path,
-		Keyword: query.Keyword,
-		Page:    query.Page,
-		Limit:   query.Limit,
+		Role:      query.Role,
+		Method:    query.Method,
+		Path:      query.Path,
+		Keyword:   query.Keyword,
+		EventType: query.EventType,
+		Page:      query.Page,
+		Limit:     query.Limit,
 	}
 
 	if query.UserID != "" {
@@ -124,3 +126,35 @@ func (h *AdminAuditHandler) GetAuditLogs(c *gin.Context) {
 		Data: response,
 	})
 }
+
+// VerifyAuditLogs 按写入顺序校验审计日志哈希链，返回第一条被篡改或链接断开的记录
+func (h *AdminAuditHandler) VerifyAuditLogs(c *gin.Context) {
+	if h.auditService == nil {
+		c.JSON(http.StatusServiceUnavailable, ApiResponse{
+			Code: 1,
+			Msg:  "审计日志服务未初始化",
+			Data: nil,
+		})
+		return
+	}
+
+	result, err := h.auditService.Verify(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ApiResponse{
+			Code: 1,
+			Msg:  "校验审计日志失败: " + err.Error(),
+			Data: nil,
+		})
+		return
+	}
+
+	msg := "审计日志完整"
+	if !result.Valid {
+		msg = "审计日志校验失败"
+	}
+	c.JSON(http.StatusOK, ApiResponse{
+		Code: 0,
+		Msg:  msg,
+		Data: result,
+	})
+}
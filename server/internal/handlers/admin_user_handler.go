@@ -1,9 +1,12 @@
 package handlers
 
 import (
+	"bytes"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gongdan-system/internal/models"
@@ -33,8 +36,13 @@ func NewAdminUserHandler(adminUserService *services.AdminUserService) *AdminUser
 // @Param page_size query int false "每页数量" default(20)
 // @Param role query string false "用户角色过滤" Enums(admin, agent, customer, supervisor)
 // @Param status query string false "用户状态过滤" Enums(active, inactive, suspended, deleted)
-// @Param search query string false "搜索关键词（用户名、邮箱、姓名）"
-// @Param order_by query string false "排序字段" Enums(id, username, email, created_at, updated_at, last_login_at) default(created_at)
+// @Param department query string false "部门"
+// @Param email_verified query bool false "邮箱是否已验证"
+// @Param otp_enabled query bool false "是否启用双因素认证"
+// @Param last_login_from query string false "最后登录时间起（RFC3339 或 YYYY-MM-DD）"
+// @Param last_login_to query string false "最后登录时间止（RFC3339 或 YYYY-MM-DD，包含当天）"
+// @Param search query string false "搜索关键词（用户名、邮箱、姓名），多个关键词需全部命中"
+// @Param order_by query string false "排序字段" Enums(id, username, email, display_name, role, status, department, created_at, updated_at, last_login_at) default(created_at)
 // @Param order query string false "排序方向" Enums(asc, desc) default(desc)
 // @Success 200 {object} ApiResponse{data=services.UserListResponse}
 // @Failure 400 {object} ApiResponse
@@ -52,9 +60,18 @@ func (h *AdminUserHandler) GetUserList(c *gin.Context) {
 		})
 		return
 	}
+	req.Fields = userFieldFilters(c)
 
 	response, err := h.adminUserService.GetUserList(c.Request.Context(), &req)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidUserListFilter) {
+			c.JSON(http.StatusBadRequest, ApiResponse{
+				Code: 1,
+				Msg:  "查询参数错误: " + err.Error(),
+				Data: nil,
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, ApiResponse{
 			Code: 1,
 			Msg:  "获取用户列表失败: " + err.Error(),
@@ -70,6 +87,170 @@ func (h *AdminUserHandler) GetUserList(c *gin.Context) {
 	})
 }
 
+// ExportUsers 导出用户列表
+// @Summary 导出用户列表
+// @Description 按用户列表的过滤和排序条件导出 CSV，最多 50000 行
+// @Tags 管理员-用户管理
+// @Produce text/csv
+// @Security ApiKeyAuth
+// @Success 200 {file} file
+// @Failure 400 {object} ApiResponse
+// @Failure 500 {object} ApiResponse
+// @Router /api/admin/users/export [get]
+func (h *AdminUserHandler) ExportUsers(c *gin.Context) {
+	var req services.UserListRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ApiResponse{
+			Code: 1,
+			Msg:  "查询参数错误: " + err.Error(),
+			Data: nil,
+		})
+		return
+	}
+	req.Fields = userFieldFilters(c)
+
+	// 先生成到内存，出错时仍可返回 JSON 错误
+	var buf bytes.Buffer
+	if _, err := h.adminUserService.ExportUsersCSV(c.Request.Context(), &req, &buf); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrInvalidUserListFilter) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, ApiResponse{
+			Code: 1,
+			Msg:  "导出用户失败: " + err.Error(),
+			Data: nil,
+		})
+		return
+	}
+
+	filename := "users_" + time.Now().Format("20060102_150405") + ".csv"
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+}
+
+// CreateBulkJob 创建用户批量操作任务
+// @Summary 创建用户批量操作任务
+// @Description 在后台批量修改角色或强制重置密码，返回任务，通过任务详情查看逐个用户的结果
+// @Tags 管理员-用户管理
+// @Accept json
+// @Produce json
+// @Security ApiKeyAuth
+// @Param request body models.UserBulkJobRequest true "批量操作请求"
+// @Success 202 {object} ApiResponse{data=models.UserBulkJob}
+// @Failure 400 {object} ApiResponse
+// @Failure 500 {object} ApiResponse
+// @Router /api/admin/users/bulk-jobs [post]
+func (h *AdminUserHandler) CreateBulkJob(c *gin.Context) {
+	var req models.UserBulkJobRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ApiResponse{
+			Code: 1,
+			Msg:  "请求参数错误: " + err.Error(),
+			Data: nil,
+		})
+		return
+	}
+
+	job, err := h.adminUserService.CreateBulkJob(c.Request.Context(), &req, c.GetUint("user_id"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrInvalidUserBulkJob) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, ApiResponse{
+			Code: 1,
+			Msg:  "创建批量操作失败: " + err.Error(),
+			Data: nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, ApiResponse{
+		Code: 0,
+		Msg:  "批量操作已提交",
+		Data: job,
+	})
+}
+
+// ListBulkJobs 获取用户批量操作任务列表
+// @Summary 获取用户批量操作任务列表
+// @Tags 管理员-用户管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Param page query int false "页码" default(1)
+// @Param page_size query int false "每页数量" default(20)
+// @Success 200 {object} ApiResponse
+// @Failure 500 {object} ApiResponse
+// @Router /api/admin/users/bulk-jobs [get]
+func (h *AdminUserHandler) ListBulkJobs(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	jobs, total, err := h.adminUserService.ListBulkJobs(c.Request.Context(), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ApiResponse{
+			Code: 1,
+			Msg:  "获取批量操作列表失败: " + err.Error(),
+			Data: nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, ApiResponse{
+		Code: 0,
+		Msg:  "获取批量操作列表成功",
+		Data: gin.H{"items": jobs, "total": total},
+	})
+}
+
+// GetBulkJob 获取用户批量操作任务详情
+// @Summary 获取用户批量操作任务详情
+// @Tags 管理员-用户管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Param id path int true "任务ID"
+// @Success 200 {object} ApiResponse{data=models.UserBulkJob}
+// @Failure 400 {object} ApiResponse
+// @Failure 404 {object} ApiResponse
+// @Failure 500 {object} ApiResponse
+// @Router /api/admin/users/bulk-jobs/{id} [get]
+func (h *AdminUserHandler) GetBulkJob(c *gin.Context) {
+	jobID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ApiResponse{
+			Code: 1,
+			Msg:  "无效的任务ID",
+			Data: nil,
+		})
+		return
+	}
+
+	job, err := h.adminUserService.GetBulkJob(c.Request.Context(), uint(jobID))
+	if err != nil {
+		if errors.Is(err, services.ErrUserBulkJobNotFound) {
+			c.JSON(http.StatusNotFound, ApiResponse{
+				Code: 1,
+				Msg:  "批量操作任务不存在",
+				Data: nil,
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ApiResponse{
+			Code: 1,
+			Msg:  "获取批量操作任务失败: " + err.Error(),
+			Data: nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, ApiResponse{
+		Code: 0,
+		Msg:  "获取批量操作任务成功",
+		Data: job,
+	})
+}
+
 // GetUser 获取用户详细信息
 // @Summary 获取用户详细信息
 // @Description 管理员获取指定用户的详细信息
@@ -169,6 +350,15 @@ func (h *AdminUserHandler) CreateUser(c *gin.Context) {
 
 	user, err := h.adminUserService.CreateUser(c.Request.Context(), &req)
 	if err != nil {
+		var quotaErr *services.QuotaExceededError
+		if errors.As(err, &quotaErr) {
+			c.JSON(http.StatusForbidden, ApiResponse{
+				Code: 1,
+				Msg:  quotaErr.Message(),
+				Data: quotaErr.Usage,
+			})
+			return
+		}
 		if strings.Contains(err.Error(), "already exists") {
 			c.JSON(http.StatusConflict, ApiResponse{
 				Code: 1,
@@ -233,6 +423,15 @@ func (h *AdminUserHandler) UpdateUser(c *gin.Context) {
 
 	user, err := h.adminUserService.UpdateUser(c.Request.Context(), uint(userID), &req)
 	if err != nil {
+		var quotaErr *services.QuotaExceededError
+		if errors.As(err, &quotaErr) {
+			c.JSON(http.StatusForbidden, ApiResponse{
+				Code: 1,
+				Msg:  quotaErr.Message(),
+				Data: quotaErr.Usage,
+			})
+			return
+		}
 		if strings.Contains(err.Error(), "not found") {
 			c.JSON(http.StatusNotFound, ApiResponse{
 				Code: 1,
@@ -558,4 +757,20 @@ type ResetPasswordRequest struct {
 // BatchDeleteUsersRequest 批量删除用户请求
 type BatchDeleteUsersRequest struct {
 	UserIDs []uint `json:"user_ids" binding:"required" example:"[1,2,3]"`
-}
\ No newline at end of file
+}
+
+// userFieldFilters 收集 field.<key>=<value> 形式的自定义字段过滤参数
+func userFieldFilters(c *gin.Context) map[string]string {
+	var fields map[string]string
+	for name, values := range c.Request.URL.Query() {
+		key, ok := strings.CutPrefix(name, "field.")
+		if !ok || key == "" || len(values) == 0 {
+			continue
+		}
+		if fields == nil {
+			fields = make(map[string]string)
+		}
+		fields[key] = values[0]
+	}
+	return fields
+}
@@ -1,27 +1,39 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 
+	"gongdan-system/internal/models"
 	"gongdan-system/internal/services"
 )
 
 // AnalyticsHandler 分析统计处理器
 type AnalyticsHandler struct {
 	analyticsService *services.AnalyticsService
+	agingService     *services.BacklogAgingService
+	exportService    *services.AnalyticsExportService
 }
 
 // NewAnalyticsHandler 创建分析处理器
 func NewAnalyticsHandler(db *gorm.DB) *AnalyticsHandler {
 	return &AnalyticsHandler{
 		analyticsService: services.NewAnalyticsService(db),
+		agingService:     services.NewBacklogAgingService(db),
 	}
 }
 
+// SetExportService 设置 XLSX 报表导出服务
+func (h *AnalyticsHandler) SetExportService(exportService *services.AnalyticsExportService) {
+	h.exportService = exportService
+}
+
 // GetSystemStats 获取系统运行状态
 // @Summary 获取系统运行状态
 // @Description 获取系统运行状态，包括内存、CPU、GC等信息
@@ -279,13 +291,17 @@ func (h *AnalyticsHandler) GetHealthCheck(c *gin.Context) {
 
 // ExportStats 导出统计数据
 // @Summary 导出统计数据
-// @Description 导出系统和业务统计数据
+// @Description 导出系统和业务统计数据；xlsx 格式包含按状态统计、SLA达成率、坐席绩效三个工作表，超过31天的范围转为后台生成
 // @Tags 系统监控
 // @Security ApiKeyAuth
-// @Param format query string false "导出格式" Enums(json) default(json)
+// @Param format query string false "导出格式" Enums(json, xlsx) default(json)
+// @Param range query string false "xlsx 时间范围" Enums(7d, 30d, 90d, custom) default(30d)
 // @Param start_date query string false "开始日期 (YYYY-MM-DD)"
 // @Param end_date query string false "结束日期 (YYYY-MM-DD)"
+// @Param locale query string false "xlsx 表头语言" Enums(zh-CN, en-US) default(zh-CN)
+// @Param async query bool false "xlsx 是否后台生成"
 // @Success 200 {object} map[string]interface{} "成功"
+// @Success 202 {object} map[string]interface{} "已创建后台导出任务"
 // @Failure 400 {object} map[string]interface{} "请求参数错误"
 // @Failure 401 {object} map[string]interface{} "未授权"
 // @Failure 500 {object} map[string]interface{} "服务器错误"
@@ -326,6 +342,11 @@ func (h *AnalyticsHandler) ExportStats(c *gin.Context) {
 		endDate = &end
 	}
 
+	if format == "xlsx" {
+		h.exportWorkbook(c, startDate, endDate)
+		return
+	}
+
 	data, err := h.analyticsService.ExportStats(c.Request.Context(), format, startDate, endDate)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -344,6 +365,147 @@ func (h *AnalyticsHandler) ExportStats(c *gin.Context) {
 	c.Data(http.StatusOK, "application/json", data)
 }
 
+// exportWorkbook 导出 XLSX 报表，范围较大或指定 async 时创建后台任务并返回下载地址
+func (h *AnalyticsHandler) exportWorkbook(c *gin.Context, startDate, endDate *time.Time) {
+	if h.exportService == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"success": false,
+			"message": "报表导出未启用",
+		})
+		return
+	}
+
+	preset := c.Query("range")
+	if preset == "" && startDate == nil {
+		preset = "30d"
+	}
+	exportRange, err := services.ResolveAnalyticsExportRange(preset, startDate, endDate, time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "导出时间范围无效",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	locale := c.Query("locale")
+	if locale == "" {
+		locale = c.GetHeader("Accept-Language")
+	}
+	locale = services.NormalizeAnalyticsExportLocale(locale)
+
+	if c.Query("async") == "true" || exportRange.Days() > services.AnalyticsExportSyncMaxDays {
+		job, err := h.exportService.CreateJob(c.Request.Context(), exportRange, locale, c.GetUint("user_id"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "创建导出任务失败",
+				"error":   err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusAccepted, gin.H{
+			"success": true,
+			"message": "报表正在后台生成",
+			"data":    analyticsExportJobResponse(job),
+		})
+		return
+	}
+
+	buf, err := h.exportService.BuildWorkbook(c.Request.Context(), exportRange, locale)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "导出统计数据失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.Header("Content-Disposition", "attachment; filename="+services.AnalyticsExportFileName(exportRange.Start, exportRange.End))
+	c.Data(http.StatusOK, xlsxContentType, buf.Bytes())
+}
+
+// GetExportJob 获取后台导出任务状态
+// @Summary 获取报表导出任务
+// @Tags 系统监控
+// @Security ApiKeyAuth
+// @Param id path int true "任务ID"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 404 {object} map[string]interface{} "任务不存在"
+// @Router /api/admin/analytics/export/jobs/{id} [get]
+func (h *AnalyticsHandler) GetExportJob(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil || h.exportService == nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "导出任务不存在"})
+		return
+	}
+
+	job, err := h.exportService.GetJob(c.Request.Context(), uint(id))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrAnalyticsExportNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"success": false, "message": "获取导出任务失败", "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "获取导出任务成功",
+		"data":    analyticsExportJobResponse(job),
+	})
+}
+
+// DownloadExportJob 下载后台生成的报表
+// @Summary 下载报表导出文件
+// @Tags 系统监控
+// @Security ApiKeyAuth
+// @Param id path int true "任务ID"
+// @Success 200 {file} file "XLSX文件"
+// @Failure 404 {object} map[string]interface{} "任务不存在"
+// @Failure 409 {object} map[string]interface{} "报表尚未生成"
+// @Failure 410 {object} map[string]interface{} "文件已过期"
+// @Router /api/admin/analytics/export/jobs/{id}/download [get]
+func (h *AnalyticsHandler) DownloadExportJob(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil || h.exportService == nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "导出任务不存在"})
+		return
+	}
+
+	job, path, err := h.exportService.JobFile(c.Request.Context(), uint(id))
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, services.ErrAnalyticsExportNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, services.ErrAnalyticsExportNotReady):
+			status = http.StatusConflict
+		case errors.Is(err, services.ErrAnalyticsExportExpired):
+			status = http.StatusGone
+		}
+		c.JSON(status, gin.H{"success": false, "message": "无法下载导出文件", "error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", xlsxContentType)
+	c.FileAttachment(path, job.FileName)
+}
+
+const xlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// analyticsExportJobResponse 导出任务附带下载地址
+func analyticsExportJobResponse(job *models.AnalyticsExportJob) gin.H {
+	resp := gin.H{"job": job}
+	if job.Status == models.AnalyticsExportCompleted {
+		resp["download_url"] = fmt.Sprintf("/api/admin/analytics/export/jobs/%d/download", job.ID)
+	}
+	return resp
+}
+
 // GetRealtimeMetrics 获取实时指标
 // @Summary 获取实时指标
 // @Description 获取实时系统指标用于监控面板
@@ -397,3 +559,43 @@ func (h *AnalyticsHandler) GetRealtimeMetrics(c *gin.Context) {
 	})
 }
 
+// GetAgingReport 获取工单积压账龄报表
+// @Summary 获取工单积压账龄报表
+// @Description 按账龄和状态统计未完结工单，并按处理人列出超过 stale_days 天未更新的工单
+// @Tags 系统监控
+// @Security ApiKeyAuth
+// @Param stale_days query int false "停滞天数，默认使用停滞提醒策略配置"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 401 {object} map[string]interface{} "未授权"
+// @Failure 500 {object} map[string]interface{} "服务器错误"
+// @Router /api/admin/analytics/aging [get]
+func (h *AnalyticsHandler) GetAgingReport(c *gin.Context) {
+	staleDays := 0
+	if daysStr := c.Query("stale_days"); daysStr != "" {
+		d, err := strconv.Atoi(daysStr)
+		if err != nil || d < 1 || d > 365 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "stale_days 必须在 1 到 365 之间",
+			})
+			return
+		}
+		staleDays = d
+	}
+
+	report, err := h.agingService.GetAgingReport(c.Request.Context(), staleDays)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "获取积压账龄报表失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "获取积压账龄报表成功",
+		"data":    report,
+	})
+}
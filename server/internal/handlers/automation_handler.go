@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
@@ -304,6 +305,301 @@ func (h *AutomationHandler) GetRuleStats(c *gin.Context) {
 	})
 }
 
+// GetRuleRevisions 获取规则修订历史
+// @Summary 获取规则修订历史
+// @Description 获取自动化规则的全部修订版本，新版本在前
+// @Tags 自动化
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "规则ID"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 404 {object} map[string]interface{} "规则不存在"
+// @Failure 500 {object} map[string]interface{} "服务器错误"
+// @Router /api/admin/automation/rules/{id}/revisions [get]
+func (h *AutomationHandler) GetRuleRevisions(c *gin.Context) {
+	ruleID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的规则ID",
+		})
+		return
+	}
+
+	revisions, err := h.automationService.GetRuleRevisions(c.Request.Context(), uint(ruleID))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "not found") {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"message": "获取修订历史失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "获取修订历史成功",
+		"data":    revisions,
+	})
+}
+
+// RollbackRule 回滚规则到指定版本
+// @Summary 回滚自动化规则
+// @Description 将规则恢复为指定修订版本的内容，回滚会生成新的修订版本
+// @Tags 自动化
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "规则ID"
+// @Param version path int true "修订版本号"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 404 {object} map[string]interface{} "规则或版本不存在"
+// @Failure 500 {object} map[string]interface{} "服务器错误"
+// @Router /api/admin/automation/rules/{id}/revisions/{version}/rollback [post]
+func (h *AutomationHandler) RollbackRule(c *gin.Context) {
+	ruleID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的规则ID",
+		})
+		return
+	}
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的版本号",
+		})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	rule, err := h.automationService.RollbackRule(c.Request.Context(), uint(ruleID), version, userID.(uint))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if strings.Contains(err.Error(), "not found") {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"message": "回滚规则失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "回滚规则成功",
+		"data":    rule,
+	})
+}
+
+// SimulateRule 模拟规则在历史工单上的命中情况
+// @Summary 模拟自动化规则
+// @Description 用指定时间范围内创建的历史工单评估规则条件，只读取数据，不执行动作；工单较多时在后台执行并返回任务
+// @Tags 自动化
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param request body models.AutomationSimulationRequest true "模拟请求"
+// @Success 200 {object} map[string]interface{} "模拟结果"
+// @Success 202 {object} map[string]interface{} "已创建后台任务"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 404 {object} map[string]interface{} "规则不存在"
+// @Router /api/admin/automation/rules/simulate [post]
+func (h *AutomationHandler) SimulateRule(c *gin.Context) {
+	var req models.AutomationSimulationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	result, job, err := h.automationService.SimulateRule(c.Request.Context(), &req, c.GetUint("user_id"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, services.ErrInvalidAutomationSimulation):
+			status = http.StatusBadRequest
+		case strings.Contains(err.Error(), "not found"):
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"message": "模拟规则失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	if job != nil {
+		c.JSON(http.StatusAccepted, gin.H{
+			"success": true,
+			"message": "规则模拟正在后台执行",
+			"data":    job,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "模拟规则成功",
+		"data":    result,
+	})
+}
+
+// GetSimulationJob 获取后台规则模拟任务
+// @Summary 获取规则模拟任务
+// @Tags 自动化
+// @Security ApiKeyAuth
+// @Produce json
+// @Param id path int true "任务ID"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 404 {object} map[string]interface{} "任务不存在"
+// @Router /api/admin/automation/rules/simulations/{id} [get]
+func (h *AutomationHandler) GetSimulationJob(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "模拟任务不存在"})
+		return
+	}
+
+	job, err := h.automationService.GetSimulationJob(c.Request.Context(), uint(id))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrAutomationSimulationNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"success": false, "message": "获取模拟任务失败", "error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "获取模拟任务成功",
+		"data":    job,
+	})
+}
+
+// ExportBundle 导出自动化配置包
+// @Summary 导出自动化配置包
+// @Description 以JSON配置包导出全部自动化规则、SLA配置和工单模板，用于复制到其他环境
+// @Tags 自动化
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 500 {object} map[string]interface{} "服务器错误"
+// @Router /api/admin/automation/bundle/export [get]
+func (h *AutomationHandler) ExportBundle(c *gin.Context) {
+	bundle, err := h.automationService.ExportBundle(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "导出配置包失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "导出配置包成功",
+		"data":    bundle,
+	})
+}
+
+// ImportBundle 导入自动化配置包
+// @Summary 导入自动化配置包
+// @Description 校验并导入配置包，同名配置按 on_conflict（skip/overwrite/rename）处理，dry_run 时只返回导入计划
+// @Tags 自动化
+// @Security ApiKeyAuth
+// @Accept json
+// @Produce json
+// @Param request body models.AutomationImportRequest true "导入请求"
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 400 {object} map[string]interface{} "配置包校验失败"
+// @Failure 500 {object} map[string]interface{} "服务器错误"
+// @Router /api/admin/automation/bundle/import [post]
+func (h *AutomationHandler) ImportBundle(c *gin.Context) {
+	var req models.AutomationImportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	result, err := h.automationService.ImportBundle(c.Request.Context(), &req, userID.(uint))
+	if err != nil {
+		var bundleErr *services.AutomationBundleError
+		switch {
+		case errors.As(err, &bundleErr):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success":  false,
+				"message":  "配置包校验失败",
+				"error":    "invalid_bundle",
+				"problems": bundleErr.Problems,
+			})
+		case errors.Is(err, services.ErrAutomationChecksumMismatch):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"message": "配置包校验和不匹配，内容可能在导出后被修改",
+				"error":   "checksum_mismatch",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "导入配置包失败",
+				"error":   err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "导入配置包成功",
+		"data":    result,
+	})
+}
+
+// GetChecksum 获取当前环境配置校验和
+// @Summary 获取自动化配置校验和
+// @Description 返回整体、分类及逐项校验和，用于比对不同环境的配置差异
+// @Tags 自动化
+// @Security ApiKeyAuth
+// @Produce json
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 500 {object} map[string]interface{} "服务器错误"
+// @Router /api/admin/automation/bundle/checksum [get]
+func (h *AutomationHandler) GetChecksum(c *gin.Context) {
+	checksum, err := h.automationService.Checksum(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "计算配置校验和失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "获取配置校验和成功",
+		"data":    checksum,
+	})
+}
+
 // GetExecutionLogs 获取执行日志
 // @Summary 获取自动化执行日志
 // @Description 获取自动化规则的执行日志
@@ -395,7 +691,11 @@ func (h *AutomationHandler) CreateSLAConfig(c *gin.Context) {
 
 	config, err := h.automationService.CreateSLAConfig(c.Request.Context(), &req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrInvalidSLAWarningThresholds) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{
 			"success": false,
 			"message": "创建SLA配置失败",
 			"error":   err.Error(),
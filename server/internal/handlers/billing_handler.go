@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// BillingHandler 工时计费处理器
+type BillingHandler struct {
+	billingService *services.BillingService
+	response       *middleware.ResponseHelper
+}
+
+// NewBillingHandler 创建工时计费处理器
+func NewBillingHandler(billingService *services.BillingService) *BillingHandler {
+	return &BillingHandler{
+		billingService: billingService,
+		response:       middleware.NewResponseHelper(),
+	}
+}
+
+// RegisterAdminRoutes 注册管理员路由
+func (h *BillingHandler) RegisterAdminRoutes(router *gin.RouterGroup) {
+	billing := router.Group("/billing")
+	{
+		billing.GET("/rates", h.GetRates)
+		billing.PUT("/rates", h.UpdateRates)
+		billing.GET("/report", h.GetReport)
+		billing.GET("/periods", h.ListLocks)
+		billing.POST("/periods/:period/lock", h.LockPeriod)
+		billing.DELETE("/periods/:period/lock", h.UnlockPeriod)
+	}
+}
+
+// ListWorklogs 获取工单的工时记录
+func (h *BillingHandler) ListWorklogs(c *gin.Context) {
+	ticketID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, "无效的工单ID")
+		return
+	}
+
+	worklogs, err := h.billingService.ListWorklogs(c.Request.Context(), uint(ticketID))
+	if err != nil {
+		h.handleError(c, err, "获取工时记录失败")
+		return
+	}
+	h.response.Success(c, worklogs)
+}
+
+// SetWorklogBillable 设置评论工时是否计费
+func (h *BillingHandler) SetWorklogBillable(c *gin.Context) {
+	ticketID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, "无效的工单ID")
+		return
+	}
+	commentID, err := strconv.ParseUint(c.Param("comment_id"), 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, "无效的评论ID")
+		return
+	}
+	var req models.WorklogBillableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	worklog, err := h.billingService.SetWorklogBillable(c.Request.Context(), uint(ticketID), uint(commentID), &req)
+	if err != nil {
+		h.handleError(c, err, "更新计费工时失败")
+		return
+	}
+	h.response.Success(c, worklog, "计费工时已更新")
+}
+
+// GetReference 获取工单的计费参考
+func (h *BillingHandler) GetReference(c *gin.Context) {
+	ticketID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, "无效的工单ID")
+		return
+	}
+
+	reference, err := h.billingService.GetReference(c.Request.Context(), uint(ticketID))
+	if err != nil {
+		h.handleError(c, err, "获取计费参考失败")
+		return
+	}
+	h.response.Success(c, reference)
+}
+
+// SetReference 设置工单的计费参考
+func (h *BillingHandler) SetReference(c *gin.Context) {
+	ticketID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, "无效的工单ID")
+		return
+	}
+	var req models.TicketBillingReferenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	reference, err := h.billingService.SetReference(c.Request.Context(), uint(ticketID), &req, c.GetUint("user_id"))
+	if err != nil {
+		h.handleError(c, err, "设置计费参考失败")
+		return
+	}
+	h.response.Success(c, reference, "计费参考已保存")
+}
+
+// DeleteReference 删除工单的计费参考
+func (h *BillingHandler) DeleteReference(c *gin.Context) {
+	ticketID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, "无效的工单ID")
+		return
+	}
+
+	if err := h.billingService.DeleteReference(c.Request.Context(), uint(ticketID)); err != nil {
+		h.handleError(c, err, "删除计费参考失败")
+		return
+	}
+	h.response.Success(c, nil, "计费参考已删除")
+}
+
+// GetRates 获取小时费率配置
+func (h *BillingHandler) GetRates(c *gin.Context) {
+	rates, err := h.billingService.GetRates(c.Request.Context())
+	if err != nil {
+		h.response.InternalServerError(c, "获取计费费率失败", err.Error())
+		return
+	}
+	h.response.Success(c, rates)
+}
+
+// UpdateRates 更新小时费率配置
+func (h *BillingHandler) UpdateRates(c *gin.Context) {
+	var req models.BillingRateConfig
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	if err := h.billingService.SetRates(c.Request.Context(), &req, c.GetUint("user_id")); err != nil {
+		h.response.BadRequest(c, err.Error())
+		return
+	}
+	h.response.Success(c, req, "计费费率已更新")
+}
+
+// GetReport 月度计费工时报表，默认上个月、按客户汇总，format=csv 时导出 CSV
+func (h *BillingHandler) GetReport(c *gin.Context) {
+	period := c.DefaultQuery("period", time.Now().AddDate(0, -1, 0).Format(models.BillingPeriodFormat))
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		h.response.BadRequest(c, "format 只能为 json 或 csv")
+		return
+	}
+
+	report, err := h.billingService.Report(c.Request.Context(), period, c.Query("group_by"))
+	if err != nil {
+		h.handleError(c, err, "获取计费工时报表失败")
+		return
+	}
+	if format == "json" {
+		h.response.Success(c, report)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := h.billingService.WriteReportCSV(report, &buf); err != nil {
+		h.response.InternalServerError(c, "导出计费工时报表失败", err.Error())
+		return
+	}
+	filename := fmt.Sprintf("billable_hours_%s_%s.csv", report.Period, report.GroupBy)
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+}
+
+// ListLocks 获取已锁定的计费周期
+func (h *BillingHandler) ListLocks(c *gin.Context) {
+	locks, err := h.billingService.ListLocks(c.Request.Context())
+	if err != nil {
+		h.response.InternalServerError(c, "获取计费周期失败", err.Error())
+		return
+	}
+	h.response.Success(c, gin.H{"items": locks, "total": len(locks)})
+}
+
+// LockPeriod 开票后锁定计费周期
+func (h *BillingHandler) LockPeriod(c *gin.Context) {
+	// 请求体可省略
+	var req models.BillingPeriodLockRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			h.response.BadRequest(c, "请求参数无效", err.Error())
+			return
+		}
+	}
+
+	lock, err := h.billingService.LockPeriod(c.Request.Context(), c.Param("period"), &req, c.GetUint("user_id"))
+	if err != nil {
+		h.handleError(c, err, "锁定计费周期失败")
+		return
+	}
+	h.response.Success(c, lock, "计费周期已锁定")
+}
+
+// UnlockPeriod 解除计费周期锁定
+func (h *BillingHandler) UnlockPeriod(c *gin.Context) {
+	if err := h.billingService.UnlockPeriod(c.Request.Context(), c.Param("period")); err != nil {
+		h.handleError(c, err, "解除计费周期锁定失败")
+		return
+	}
+	h.response.Success(c, nil, "计费周期已解除锁定")
+}
+
+func (h *BillingHandler) handleError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrBillingTicketNotFound):
+		h.response.NotFound(c, "工单不存在")
+	case errors.Is(err, services.ErrWorklogNotFound):
+		h.response.NotFound(c, "评论不存在")
+	case errors.Is(err, services.ErrBillingReferenceNotFound):
+		h.response.NotFound(c, "工单未设置计费参考")
+	case errors.Is(err, services.ErrBillingPeriodNotLocked):
+		h.response.NotFound(c, "计费周期未锁定")
+	case errors.Is(err, services.ErrBillingPeriodLocked):
+		h.response.Error(c, http.StatusConflict, "计费周期已锁定", err.Error())
+	case errors.Is(err, services.ErrInvalidWorklogBilling),
+		errors.Is(err, services.ErrInvalidBillingPeriod),
+		errors.Is(err, services.ErrInvalidBillingReport):
+		h.response.BadRequest(c, err.Error())
+	default:
+		h.response.Error(c, http.StatusInternalServerError, message, err.Error())
+	}
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// BrandingHandler 品牌与白标设置处理器
+type BrandingHandler struct {
+	brandingService *services.BrandingService
+	response        *middleware.ResponseHelper
+}
+
+// NewBrandingHandler 创建品牌与白标设置处理器
+func NewBrandingHandler(brandingService *services.BrandingService) *BrandingHandler {
+	return &BrandingHandler{
+		brandingService: brandingService,
+		response:        middleware.NewResponseHelper(),
+	}
+}
+
+// RegisterAdminRoutes 注册管理员路由
+func (h *BrandingHandler) RegisterAdminRoutes(router *gin.RouterGroup) {
+	branding := router.Group("/branding")
+	branding.GET("", h.GetBranding)
+	branding.PUT("", h.UpdateBranding)
+	branding.POST("/assets/:kind", h.UploadAsset)
+	branding.GET("/preview", h.PreviewBranding)
+}
+
+// RegisterPublicRoutes 注册门户公开路由（无需登录）
+func (h *BrandingHandler) RegisterPublicRoutes(router *gin.RouterGroup) {
+	router.GET("", h.GetPublicBranding)
+}
+
+// GetBranding 获取品牌配置（含租户覆盖）
+func (h *BrandingHandler) GetBranding(c *gin.Context) {
+	branding, err := h.brandingService.GetConfig(c.Request.Context())
+	if err != nil {
+		h.response.InternalServerError(c, "获取品牌配置失败", err.Error())
+		return
+	}
+	h.response.Success(c, branding)
+}
+
+// UpdateBranding 更新品牌配置
+func (h *BrandingHandler) UpdateBranding(c *gin.Context) {
+	var req models.BrandingConfig
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	if err := h.brandingService.SetConfig(c.Request.Context(), &req, c.GetUint("user_id")); err != nil {
+		h.response.BadRequest(c, err.Error())
+		return
+	}
+	h.response.Success(c, req, "品牌配置已更新")
+}
+
+// UploadAsset 上传 Logo 或 favicon，tenant 参数指定写入的租户覆盖
+func (h *BrandingHandler) UploadAsset(c *gin.Context) {
+	file, _, err := c.Request.FormFile("file")
+	if err != nil {
+		h.response.BadRequest(c, "文件上传错误")
+		return
+	}
+	defer file.Close()
+
+	branding, err := h.brandingService.UploadAsset(c.Request.Context(), c.Param("kind"), c.Query("tenant"), file, c.GetUint("user_id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrBrandingTenantNotFound):
+			h.response.NotFound(c, "租户不存在")
+		case errors.Is(err, services.ErrBrandingAssetTooLarge):
+			h.response.Error(c, http.StatusRequestEntityTooLarge, err.Error(), nil)
+		case errors.Is(err, services.ErrInvalidBrandingAsset):
+			h.response.Error(c, http.StatusUnsupportedMediaType, err.Error(), nil)
+		default:
+			h.response.InternalServerError(c, "上传品牌资源失败", err.Error())
+		}
+		return
+	}
+	h.response.Success(c, branding, "品牌资源已上传")
+}
+
+// PreviewBranding 预览指定门户域名生效的品牌设置（含继承的默认值）
+func (h *BrandingHandler) PreviewBranding(c *gin.Context) {
+	settings, tenant := h.brandingService.Resolve(c.Request.Context(), c.Query("host"))
+	h.response.Success(c, gin.H{"tenant": tenant, "branding": settings})
+}
+
+// GetPublicBranding 获取当前门户域名的品牌信息，供门户页面与登录页渲染
+func (h *BrandingHandler) GetPublicBranding(c *gin.Context) {
+	settings, tenant := h.brandingService.Resolve(c.Request.Context(), c.Request.Host)
+	public := settings.Public()
+	public.Tenant = tenant
+	h.response.Success(c, public)
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/services"
+)
+
+// CalendarHandler 营业日历处理器
+type CalendarHandler struct {
+	calendarService *services.BusinessCalendarService
+	response        *middleware.ResponseHelper
+}
+
+// NewCalendarHandler 创建营业日历处理器
+func NewCalendarHandler(calendarService *services.BusinessCalendarService) *CalendarHandler {
+	return &CalendarHandler{
+		calendarService: calendarService,
+		response:        middleware.NewResponseHelper(),
+	}
+}
+
+// GetCalendar 返回工作时间、近期节假日、当前是否营业及截止时间建议
+func (h *CalendarHandler) GetCalendar(c *gin.Context) {
+	status, err := h.calendarService.Status(c.Request.Context(), time.Now())
+	if err != nil {
+		h.response.InternalServerError(c, "获取营业日历失败", err.Error())
+		return
+	}
+	h.response.Success(c, status, "获取营业日历成功")
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// CategoryMembershipHandler 坐席分类成员处理器
+type CategoryMembershipHandler struct {
+	membershipService *services.CategoryMembershipService
+	response          *middleware.ResponseHelper
+}
+
+// NewCategoryMembershipHandler 创建坐席分类成员处理器
+func NewCategoryMembershipHandler(membershipService *services.CategoryMembershipService) *CategoryMembershipHandler {
+	return &CategoryMembershipHandler{
+		membershipService: membershipService,
+		response:          middleware.NewResponseHelper(),
+	}
+}
+
+// ListMemberships 获取分类成员列表，可按 user_id、category_id 过滤
+func (h *CategoryMembershipHandler) ListMemberships(c *gin.Context) {
+	userID, _ := strconv.ParseUint(c.Query("user_id"), 10, 32)
+	categoryID, _ := strconv.ParseUint(c.Query("category_id"), 10, 32)
+
+	memberships, err := h.membershipService.List(c.Request.Context(), uint(userID), uint(categoryID))
+	if err != nil {
+		h.handleError(c, err, "获取分类成员失败")
+		return
+	}
+	h.response.Success(c, gin.H{
+		"items":   memberships,
+		"enabled": h.membershipService.Enabled(),
+	}, "获取分类成员成功")
+}
+
+// BulkUpdateMemberships 批量添加或移除坐席与分类的所属关系
+func (h *CategoryMembershipHandler) BulkUpdateMemberships(c *gin.Context) {
+	var req models.CategoryMembershipBulkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	result, err := h.membershipService.Bulk(c.Request.Context(), &req, c.GetUint("user_id"))
+	if err != nil {
+		h.handleError(c, err, "更新分类成员失败")
+		return
+	}
+	h.response.Success(c, result, "分类成员已更新")
+}
+
+// ReplaceUserMemberships 替换坐席所属的全部分类
+func (h *CategoryMembershipHandler) ReplaceUserMemberships(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, "无效的用户ID")
+		return
+	}
+
+	var req models.CategoryMembershipReplaceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	result, err := h.membershipService.Replace(c.Request.Context(), uint(userID), req.CategoryIDs, c.GetUint("user_id"))
+	if err != nil {
+		h.handleError(c, err, "更新分类成员失败")
+		return
+	}
+	h.response.Success(c, result, "分类成员已更新")
+}
+
+// ListAssignees 获取可接手工单的处理人，开启分类权限时只返回工单所在分类的坐席及管理员、主管
+func (h *CategoryMembershipHandler) ListAssignees(c *gin.Context) {
+	ticketID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, "无效的工单ID")
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.membershipService.AuthorizeTicket(ctx, uint(ticketID), c.GetUint("user_id"), c.GetString("user_role"), 0); err != nil {
+		h.handleError(c, err, "获取处理人失败")
+		return
+	}
+	assignees, err := h.membershipService.Assignees(ctx, uint(ticketID))
+	if err != nil {
+		h.handleError(c, err, "获取处理人失败")
+		return
+	}
+	h.response.Success(c, assignees, "获取处理人成功")
+}
+
+// RequireTicketAccess 工单子资源（评论、附件、历史）的分类权限检查：开启分类权限后坐席只能访问所属分类的工单
+func (h *CategoryMembershipHandler) RequireTicketAccess(c *gin.Context) {
+	ticketID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, "无效的工单ID")
+		c.Abort()
+		return
+	}
+	if err := h.membershipService.AuthorizeTicket(c.Request.Context(), uint(ticketID), c.GetUint("user_id"), c.GetString("user_role"), 0); err != nil {
+		h.handleError(c, err, "检查分类权限失败")
+		c.Abort()
+		return
+	}
+	c.Next()
+}
+
+func (h *CategoryMembershipHandler) handleError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrCategoryAccessDenied):
+		h.response.Forbidden(c, "无权访问该分类的工单")
+	case errors.Is(err, services.ErrCategoryMembershipTicketNotFound):
+		h.response.NotFound(c, "工单不存在")
+	case errors.Is(err, services.ErrCategoryMembershipUserInvalid):
+		h.response.BadRequest(c, "用户不存在或不是坐席、主管、管理员")
+	case errors.Is(err, services.ErrCategoryMembershipCategoryNotFound):
+		h.response.BadRequest(c, "分类不存在")
+	default:
+		h.response.Error(c, http.StatusInternalServerError, message, err.Error())
+	}
+}
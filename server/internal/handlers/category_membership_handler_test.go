@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestCategoryMembership_TicketSubResourcesAndEscalation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open("file:category_access_handler?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&models.User{}, &models.Team{}, &models.Category{}, &models.Ticket{}, &models.TicketHistory{},
+		&models.SystemConfig{}, &models.CategoryMembership{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+	ctx := context.Background()
+
+	admin := models.User{Username: "ca-admin", Email: "ca-admin@example.com", PasswordHash: "x", Role: models.RoleAdmin, Status: models.UserStatusActive}
+	network := models.User{Username: "ca-network", Email: "ca-network@example.com", PasswordHash: "x", Role: models.RoleAgent, Status: models.UserStatusActive}
+	billing := models.User{Username: "ca-billing", Email: "ca-billing@example.com", PasswordHash: "x", Role: models.RoleAgent, Status: models.UserStatusActive}
+	for _, user := range []*models.User{&admin, &network, &billing} {
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("failed to seed user: %v", err)
+		}
+	}
+	networking := models.Category{Name: "网络", Slug: "ca-network", Type: models.CategoryTypeTechnical, Status: models.CategoryStatusActive, CreatedBy: admin.ID}
+	accounts := models.Category{Name: "账务", Slug: "ca-billing", Type: models.CategoryTypeGeneral, Status: models.CategoryStatusActive, CreatedBy: admin.ID}
+	db.Create(&networking)
+	db.Create(&accounts)
+	vpn := models.Ticket{TicketNumber: "CA-VPN", Title: "vpn", Status: models.TicketStatusOpen, Priority: models.TicketPriorityNormal,
+		Type: models.TicketTypeIncident, Source: models.TicketSourceWeb, CreatedByID: admin.ID, CategoryID: &networking.ID}
+	invoice := models.Ticket{TicketNumber: "CA-INVOICE", Title: "invoice", Status: models.TicketStatusOpen, Priority: models.TicketPriorityNormal,
+		Type: models.TicketTypeIncident, Source: models.TicketSourceWeb, CreatedByID: admin.ID, CategoryID: &accounts.ID}
+	db.Create(&vpn)
+	db.Create(&invoice)
+
+	membershipService := services.NewCategoryMembershipService(db)
+	if _, err := membershipService.Bulk(ctx, &models.CategoryMembershipBulkRequest{Action: models.CategoryMembershipAdd,
+		UserIDs: []uint{network.ID}, CategoryIDs: []uint{networking.ID}}, admin.ID); err != nil {
+		t.Fatalf("failed to add membership: %v", err)
+	}
+	if _, err := membershipService.Bulk(ctx, &models.CategoryMembershipBulkRequest{Action: models.CategoryMembershipAdd,
+		UserIDs: []uint{billing.ID}, CategoryIDs: []uint{accounts.ID}}, admin.ID); err != nil {
+		t.Fatalf("failed to add membership: %v", err)
+	}
+	if err := services.NewConfigService(db).SetConfig(services.KeyTicketCategoryScopeEnabled, "true", "bool", "", services.CategoryTicket, "workflow"); err != nil {
+		t.Fatalf("failed to enable category scope: %v", err)
+	}
+
+	membershipHandler := NewCategoryMembershipHandler(membershipService)
+	workflowHandler := NewTicketWorkflowHandler(services.NewTicketService(db))
+	workflowHandler.SetCategoryMembershipService(membershipService)
+
+	ok := func(c *gin.Context) { c.Status(http.StatusNoContent) }
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", network.ID)
+		c.Set("user_role", string(models.RoleAgent))
+	})
+	tickets := router.Group("/api/tickets")
+	tickets.GET("/:id/comments", membershipHandler.RequireTicketAccess, ok)
+	tickets.GET("/:id/attachments", membershipHandler.RequireTicketAccess, ok)
+	tickets.GET("/:id/history", membershipHandler.RequireTicketAccess, ok)
+	tickets.POST("/:id/archive", membershipHandler.RequireTicketAccess, ok)
+	tickets.GET("/:id/worklogs", membershipHandler.RequireTicketAccess, ok)
+	tickets.GET("/:id/checklist", membershipHandler.RequireTicketAccess, ok)
+	tickets.GET("/:id/email-thread", membershipHandler.RequireTicketAccess, ok)
+	tickets.POST("/:id/escalate", workflowHandler.EscalateTicket)
+	tickets.POST("/:id/transfer-category", workflowHandler.TransferCategory)
+
+	send := func(method, path, body string) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// 评论、附件、历史、归档等子资源：只能访问所属分类的工单
+	for _, resource := range []struct{ method, path string }{
+		{http.MethodGet, "comments"}, {http.MethodGet, "attachments"}, {http.MethodGet, "history"}, {http.MethodPost, "archive"},
+		{http.MethodGet, "worklogs"}, {http.MethodGet, "checklist"}, {http.MethodGet, "email-thread"},
+	} {
+		if got := send(resource.method, fmt.Sprintf("/api/tickets/%d/%s", vpn.ID, resource.path), ""); got != http.StatusNoContent {
+			t.Errorf("%s of own category: expected 204, got %d", resource.path, got)
+		}
+		if got := send(resource.method, fmt.Sprintf("/api/tickets/%d/%s", invoice.ID, resource.path), ""); got != http.StatusForbidden {
+			t.Errorf("%s of other category: expected 403, got %d", resource.path, got)
+		}
+	}
+	if got := send(http.MethodGet, "/api/tickets/abc/comments", ""); got != http.StatusBadRequest {
+		t.Errorf("invalid ticket id: expected 400, got %d", got)
+	}
+
+	// 升级：不能升级其他分类的工单，也不能升级给不属于工单分类的坐席
+	if got := send(http.MethodPost, fmt.Sprintf("/api/tickets/%d/escalate", invoice.ID), fmt.Sprintf(`{"escalate_to_id":%d,"reason":"超出处理范围"}`, network.ID)); got != http.StatusForbidden {
+		t.Errorf("escalate ticket of other category: expected 403, got %d", got)
+	}
+	if got := send(http.MethodPost, fmt.Sprintf("/api/tickets/%d/escalate", vpn.ID), fmt.Sprintf(`{"escalate_to_id":%d,"reason":"超出处理范围"}`, billing.ID)); got != http.StatusBadRequest {
+		t.Errorf("escalate to non-member: expected 400, got %d", got)
+	}
+
+	// 转移分类：不能转出其他分类的工单，也不能转入自己不属于的分类
+	if got := send(http.MethodPost, fmt.Sprintf("/api/tickets/%d/transfer-category", invoice.ID), fmt.Sprintf(`{"category_id":%d}`, networking.ID)); got != http.StatusForbidden {
+		t.Errorf("transfer ticket of other category: expected 403, got %d", got)
+	}
+	if got := send(http.MethodPost, fmt.Sprintf("/api/tickets/%d/transfer-category", vpn.ID), fmt.Sprintf(`{"category_id":%d}`, accounts.ID)); got != http.StatusForbidden {
+		t.Errorf("transfer into non-member category: expected 403, got %d", got)
+	}
+	var unchanged models.Ticket
+	if err := db.First(&unchanged, vpn.ID).Error; err != nil || unchanged.AssignedToID != nil {
+		t.Fatalf("expected rejected escalation to leave the ticket unassigned, got %+v (%v)", unchanged.AssignedToID, err)
+	}
+	if unchanged.CategoryID == nil || *unchanged.CategoryID != networking.ID {
+		t.Fatalf("expected rejected transfer to keep the category, got %v", unchanged.CategoryID)
+	}
+}
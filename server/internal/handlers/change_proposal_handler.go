@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// ChangeProposalHandler 工单变更提案审核处理器
+type ChangeProposalHandler struct {
+	proposalService *services.TicketChangeProposalService
+	response        *middleware.ResponseHelper
+}
+
+// NewChangeProposalHandler 创建变更提案处理器
+func NewChangeProposalHandler(proposalService *services.TicketChangeProposalService) *ChangeProposalHandler {
+	return &ChangeProposalHandler{
+		proposalService: proposalService,
+		response:        middleware.NewResponseHelper(),
+	}
+}
+
+// RegisterRoutes 注册变更提案路由
+func (h *ChangeProposalHandler) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("", h.ListProposals)
+	router.GET("/:id", h.GetProposal)
+	router.POST("/:id/approve", h.ApproveProposal)
+	router.POST("/:id/reject", h.RejectProposal)
+}
+
+// ListProposals 获取变更提案列表；审核角色可查看全部，其他角色只能查看自己提交的提案
+func (h *ChangeProposalHandler) ListProposals(c *gin.Context) {
+	ctx := context.Background()
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	ticketID, _ := strconv.ParseUint(c.Query("ticket_id"), 10, 32)
+
+	filters := services.ChangeProposalFilters{
+		Status:   models.ChangeProposalStatus(c.Query("status")),
+		TicketID: uint(ticketID),
+		Page:     page,
+		PageSize: pageSize,
+	}
+
+	policy, err := h.proposalService.GetPolicy(ctx)
+	if err != nil {
+		h.response.InternalServerError(c, "获取变更提案策略失败", err.Error())
+		return
+	}
+	if !policy.CanReview(c.GetString("user_role")) {
+		filters.ProposerID = c.GetUint("user_id")
+	}
+
+	proposals, total, err := h.proposalService.List(ctx, filters)
+	if err != nil {
+		h.response.InternalServerError(c, "获取变更提案失败", err.Error())
+		return
+	}
+	h.response.List(c, proposals, total, page, pageSize, "获取变更提案成功")
+}
+
+// GetProposal 获取提案详情，待审核提案附带与工单当前值的差异
+func (h *ChangeProposalHandler) GetProposal(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	proposal, err := h.proposalService.Get(context.Background(), id, c.GetUint("user_id"), c.GetString("user_role"))
+	if err != nil {
+		h.handleError(c, err, "获取变更提案失败")
+		return
+	}
+	h.response.Success(c, proposal, "获取变更提案成功")
+}
+
+// ApproveProposal 批准提案并应用到工单
+func (h *ChangeProposalHandler) ApproveProposal(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	req, ok := h.bindReview(c)
+	if !ok {
+		return
+	}
+
+	ticket, err := h.proposalService.Approve(context.Background(), id, c.GetUint("user_id"), c.GetString("user_role"), req.Comment)
+	if err != nil {
+		h.handleError(c, err, "批准变更提案失败")
+		return
+	}
+	h.response.Success(c, ticket.ToResponse(), "变更提案已批准并应用")
+}
+
+// RejectProposal 驳回提案
+func (h *ChangeProposalHandler) RejectProposal(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	req, ok := h.bindReview(c)
+	if !ok {
+		return
+	}
+
+	proposal, err := h.proposalService.Reject(context.Background(), id, c.GetUint("user_id"), c.GetString("user_role"), req.Comment)
+	if err != nil {
+		h.handleError(c, err, "驳回变更提案失败")
+		return
+	}
+	h.response.Success(c, proposal, "变更提案已驳回")
+}
+
+// bindReview 解析审核意见，请求体可省略
+func (h *ChangeProposalHandler) bindReview(c *gin.Context) (*models.ChangeProposalReviewRequest, bool) {
+	var req models.ChangeProposalReviewRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			h.response.BadRequest(c, "请求参数无效", err.Error())
+			return nil, false
+		}
+	}
+	return &req, true
+}
+
+func (h *ChangeProposalHandler) parseID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, "无效的提案ID")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func (h *ChangeProposalHandler) handleError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrChangeProposalNotFound):
+		h.response.NotFound(c, "变更提案不存在")
+	case err.Error() == "ticket not found":
+		h.response.NotFound(c, "工单不存在")
+	case errors.Is(err, services.ErrChangeProposalForbidden), errors.Is(err, services.ErrChangeProposalSelfReview):
+		h.response.Forbidden(c, err.Error())
+	case errors.Is(err, services.ErrChangeProposalReviewed):
+		h.response.Error(c, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrInvalidImpactUrgency), errors.Is(err, services.ErrTeamNotFound):
+		h.response.BadRequest(c, err.Error())
+	default:
+		h.response.Error(c, http.StatusInternalServerError, message, err.Error())
+	}
+}
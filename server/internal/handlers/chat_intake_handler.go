@@ -0,0 +1,275 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// chatRequestMaxBytes 聊天平台回调请求体上限
+const chatRequestMaxBytes = 1 << 20
+
+// ChatIntakeHandler Slack 斜杠命令 / Telegram 机器人建单处理器
+type ChatIntakeHandler struct {
+	chatService *services.ChatIntakeService
+	response    *middleware.ResponseHelper
+}
+
+// NewChatIntakeHandler 创建聊天建单处理器
+func NewChatIntakeHandler(chatService *services.ChatIntakeService) *ChatIntakeHandler {
+	return &ChatIntakeHandler{
+		chatService: chatService,
+		response:    middleware.NewResponseHelper(),
+	}
+}
+
+// RegisterPublicRoutes 注册聊天平台回调路由（不使用登录认证，按平台签名/密钥校验）
+func (h *ChatIntakeHandler) RegisterPublicRoutes(router *gin.RouterGroup) {
+	router.POST("/slack/command", h.SlackCommand)
+	router.POST("/telegram/webhook", h.TelegramWebhook)
+}
+
+// RegisterAdminRoutes 注册管理员路由
+func (h *ChatIntakeHandler) RegisterAdminRoutes(router *gin.RouterGroup) {
+	chat := router.Group("/integrations/chat")
+	{
+		chat.GET("/config", h.GetConfig)
+		chat.PUT("/config", h.UpdateConfig)
+
+		chat.GET("/mappings", h.ListMappings)
+		chat.POST("/mappings", h.SaveMapping)
+		chat.DELETE("/mappings/:id", h.DeleteMapping)
+	}
+}
+
+// slackReply Slack 斜杠命令响应，ephemeral 仅提交人可见
+func slackReply(c *gin.Context, responseType, text string) {
+	c.JSON(http.StatusOK, gin.H{
+		"response_type": responseType,
+		"text":          text,
+	})
+}
+
+// SlackCommand 处理 /ticket 斜杠命令
+func (h *ChatIntakeHandler) SlackCommand(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, chatRequestMaxBytes))
+	if err != nil {
+		h.response.BadRequest(c, "读取请求失败")
+		return
+	}
+
+	ctx := c.Request.Context()
+	if err := h.chatService.VerifySlackRequest(ctx, c.GetHeader("X-Slack-Request-Timestamp"), c.GetHeader("X-Slack-Signature"), body); err != nil {
+		if errors.Is(err, services.ErrChatPlatformDisabled) {
+			h.response.NotFound(c, "Slack 建单未启用")
+			return
+		}
+		h.response.Unauthorized(c, "签名校验失败")
+		return
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		h.response.BadRequest(c, "请求参数无效")
+		return
+	}
+
+	result, err := h.chatService.HandleMessage(ctx, &models.ChatIntakeMessage{
+		Platform:         models.ChatPlatformSlack,
+		ExternalUserID:   form.Get("user_id"),
+		ExternalUsername: form.Get("user_name"),
+		ChannelID:        form.Get("channel_id"),
+		Text:             form.Get("text"),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrChatTicketTextEmpty):
+			slackReply(c, "ephemeral", "请在命令后描述问题，例如：/ticket \"打印机无法打印\"")
+		case errors.Is(err, services.ErrChatUserNotMapped):
+			slackReply(c, "ephemeral", "你的 Slack 账号尚未关联系统用户，请联系管理员添加账号映射")
+		default:
+			log.Printf("Failed to create ticket from Slack command: %v", err)
+			slackReply(c, "ephemeral", "创建工单失败，请稍后重试")
+		}
+		return
+	}
+
+	if result.Threaded {
+		slackReply(c, "ephemeral", "工单已创建，后续状态更新会回复在频道消息的线程中")
+		return
+	}
+	// 确认消息未能发送到频道时，直接以命令响应告知提交人
+	slackReply(c, "in_channel", result.Reply)
+}
+
+// telegramUpdate Telegram Webhook 推送的更新（只处理文本消息）
+type telegramUpdate struct {
+	UpdateID int64 `json:"update_id"`
+	Message  *struct {
+		MessageID int64 `json:"message_id"`
+		From      *struct {
+			ID       int64  `json:"id"`
+			Username string `json:"username"`
+		} `json:"from"`
+		Chat struct {
+			ID int64 `json:"id"`
+		} `json:"chat"`
+		Text string `json:"text"`
+	} `json:"message"`
+}
+
+// TelegramWebhook 处理 Telegram 机器人消息，/ticket 开头的消息创建工单。
+// 除校验失败外始终返回 200，避免 Telegram 反复重试
+func (h *ChatIntakeHandler) TelegramWebhook(c *gin.Context) {
+	ctx := c.Request.Context()
+	if err := h.chatService.VerifyTelegramRequest(ctx, c.GetHeader("X-Telegram-Bot-Api-Secret-Token")); err != nil {
+		if errors.Is(err, services.ErrChatPlatformDisabled) {
+			h.response.NotFound(c, "Telegram 建单未启用")
+			return
+		}
+		h.response.Unauthorized(c, "密钥校验失败")
+		return
+	}
+
+	var update telegramUpdate
+	if err := json.NewDecoder(io.LimitReader(c.Request.Body, chatRequestMaxBytes)).Decode(&update); err != nil {
+		h.response.BadRequest(c, "请求参数无效")
+		return
+	}
+	msg := update.Message
+	if msg == nil || msg.From == nil || !strings.HasPrefix(strings.TrimSpace(msg.Text), "/ticket") {
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+		return
+	}
+
+	intake := &models.ChatIntakeMessage{
+		Platform:         models.ChatPlatformTelegram,
+		ExternalUserID:   strconv.FormatInt(msg.From.ID, 10),
+		ExternalUsername: msg.From.Username,
+		ChannelID:        strconv.FormatInt(msg.Chat.ID, 10),
+		MessageID:        strconv.FormatInt(msg.MessageID, 10),
+		Text:             msg.Text,
+	}
+	if _, err := h.chatService.HandleMessage(ctx, intake); err != nil {
+		reply := "创建工单失败，请稍后重试"
+		switch {
+		case errors.Is(err, services.ErrChatTicketTextEmpty):
+			reply = "请在命令后描述问题，例如：/ticket 打印机无法打印"
+		case errors.Is(err, services.ErrChatUserNotMapped):
+			reply = "你的 Telegram 账号尚未关联系统用户，请联系管理员添加账号映射"
+		default:
+			log.Printf("Failed to create ticket from Telegram message: %v", err)
+		}
+		if err := h.chatService.Reply(ctx, intake, reply); err != nil {
+			log.Printf("Failed to reply Telegram message: %v", err)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"ok": true})
+}
+
+// GetConfig 获取聊天建单配置，密钥不返回明文
+func (h *ChatIntakeHandler) GetConfig(c *gin.Context) {
+	config, err := h.chatService.GetConfig(context.Background())
+	if err != nil {
+		h.response.InternalServerError(c, "获取聊天建单配置失败", err.Error())
+		return
+	}
+
+	secretsSet := gin.H{
+		"slack_signing_secret":  config.SlackSigningSecret != "",
+		"slack_bot_token":       config.SlackBotToken != "",
+		"telegram_bot_token":    config.TelegramBotToken != "",
+		"telegram_secret_token": config.TelegramSecretToken != "",
+		"teams_client_secret":   config.TeamsClientSecret != "",
+	}
+	config.SlackSigningSecret = ""
+	config.SlackBotToken = ""
+	config.TelegramBotToken = ""
+	config.TelegramSecretToken = ""
+	config.TeamsClientSecret = ""
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"data":        config,
+		"secrets_set": secretsSet,
+	})
+}
+
+// UpdateConfig 更新聊天建单配置，密钥留空时保留原值
+func (h *ChatIntakeHandler) UpdateConfig(c *gin.Context) {
+	req := models.GetDefaultChatIntegrationConfig()
+	if err := c.ShouldBindJSON(req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	if err := h.chatService.SetConfig(context.Background(), req, c.GetUint("user_id")); err != nil {
+		h.response.BadRequest(c, err.Error())
+		return
+	}
+	h.response.Success(c, nil, "聊天建单配置已更新")
+}
+
+// ListMappings 获取聊天账号映射，可按 platform 过滤
+func (h *ChatIntakeHandler) ListMappings(c *gin.Context) {
+	platform := models.ChatPlatform(c.Query("platform"))
+	switch platform {
+	case "", models.ChatPlatformSlack, models.ChatPlatformTelegram:
+	default:
+		h.response.BadRequest(c, "platform 只能为 slack 或 telegram")
+		return
+	}
+
+	mappings, err := h.chatService.ListMappings(context.Background(), platform)
+	if err != nil {
+		h.response.InternalServerError(c, "获取聊天账号映射失败", err.Error())
+		return
+	}
+	h.response.Success(c, mappings)
+}
+
+// SaveMapping 创建或更新聊天账号映射
+func (h *ChatIntakeHandler) SaveMapping(c *gin.Context) {
+	var req models.ChatUserMappingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	mapping, err := h.chatService.SaveMapping(context.Background(), &req, c.GetUint("user_id"))
+	if err != nil {
+		h.response.InternalServerError(c, "保存聊天账号映射失败", err.Error())
+		return
+	}
+	h.response.Success(c, mapping, "聊天账号映射已保存")
+}
+
+// DeleteMapping 删除聊天账号映射
+func (h *ChatIntakeHandler) DeleteMapping(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, "无效的ID")
+		return
+	}
+
+	if err := h.chatService.DeleteMapping(context.Background(), uint(id)); err != nil {
+		if errors.Is(err, services.ErrChatUserMappingNotFound) {
+			h.response.NotFound(c, "聊天账号映射不存在")
+			return
+		}
+		h.response.InternalServerError(c, "删除聊天账号映射失败", err.Error())
+		return
+	}
+	h.response.Success(c, nil, "聊天账号映射已删除")
+}
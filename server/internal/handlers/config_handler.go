@@ -115,7 +115,8 @@ func (h *ConfigHandler) CreateConfig(c *gin.Context) {
 		return
 	}
 
-	// 验证配置值类型
+	// 按配置定义补全并校验
+	services.ApplyConfigSchema(&req)
 	if err := h.configService.ValidateConfig(req.Key, req.Value, req.ValueType); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
@@ -125,6 +126,7 @@ func (h *ConfigHandler) CreateConfig(c *gin.Context) {
 		return
 	}
 
+	before := h.configSnapshot(req.Key)
 	if err := h.configService.SetConfig(req.Key, req.Value, req.ValueType, req.Description, req.Category, req.Group); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -134,10 +136,14 @@ func (h *ConfigHandler) CreateConfig(c *gin.Context) {
 		return
 	}
 
+	services.AddAuditChanges(c.Request.Context(), services.DiffAuditFields(before, map[string]interface{}{req.Key: req.Value})...)
+
+	entry, _ := services.LookupConfigSchema(req.Key)
 	c.JSON(http.StatusCreated, gin.H{
-		"success": true,
-		"message": "配置创建成功",
-		"data":    req,
+		"success":          true,
+		"message":          "配置创建成功",
+		"data":             req,
+		"restart_required": entry.RestartRequired,
 	})
 }
 
@@ -169,7 +175,8 @@ func (h *ConfigHandler) UpdateConfig(c *gin.Context) {
 	// 确保 key 一致
 	req.Key = key
 
-	// 验证配置值类型
+	// 按配置定义补全并校验
+	services.ApplyConfigSchema(&req)
 	if err := h.configService.ValidateConfig(req.Key, req.Value, req.ValueType); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"success": false,
@@ -179,6 +186,7 @@ func (h *ConfigHandler) UpdateConfig(c *gin.Context) {
 		return
 	}
 
+	before := h.configSnapshot(req.Key)
 	if err := h.configService.SetConfig(req.Key, req.Value, req.ValueType, req.Description, req.Category, req.Group); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -188,10 +196,14 @@ func (h *ConfigHandler) UpdateConfig(c *gin.Context) {
 		return
 	}
 
+	services.AddAuditChanges(c.Request.Context(), services.DiffAuditFields(before, map[string]interface{}{req.Key: req.Value})...)
+
+	entry, _ := services.LookupConfigSchema(req.Key)
 	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"message": "配置更新成功",
-		"data":    req,
+		"success":          true,
+		"message":          "配置更新成功",
+		"data":             req,
+		"restart_required": entry.RestartRequired,
 	})
 }
 
@@ -208,6 +220,7 @@ func (h *ConfigHandler) UpdateConfig(c *gin.Context) {
 func (h *ConfigHandler) DeleteConfig(c *gin.Context) {
 	key := c.Param("key")
 
+	before := h.configSnapshot(key)
 	if err := h.configService.DeleteConfig(key); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -217,6 +230,8 @@ func (h *ConfigHandler) DeleteConfig(c *gin.Context) {
 		return
 	}
 
+	services.AddAuditChanges(c.Request.Context(), services.DiffAuditFields(before, map[string]interface{}{key: nil})...)
+
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "配置删除成功",
@@ -245,7 +260,10 @@ func (h *ConfigHandler) BatchUpdateConfigs(c *gin.Context) {
 	}
 
 	// 验证所有配置
-	for _, config := range configs {
+	restartRequired := false
+	for i := range configs {
+		config := &configs[i]
+		services.ApplyConfigSchema(config)
 		if err := h.configService.ValidateConfig(config.Key, config.Value, config.ValueType); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{
 				"success": false,
@@ -254,8 +272,18 @@ func (h *ConfigHandler) BatchUpdateConfigs(c *gin.Context) {
 			})
 			return
 		}
+		if entry, ok := services.LookupConfigSchema(config.Key); ok && entry.RestartRequired {
+			restartRequired = true
+		}
 	}
 
+	keys := make([]string, len(configs))
+	after := make(map[string]interface{}, len(configs))
+	for i, config := range configs {
+		keys[i] = config.Key
+		after[config.Key] = config.Value
+	}
+	before := h.configSnapshot(keys...)
 	if err := h.configService.BatchUpdateConfigs(configs); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -265,11 +293,43 @@ func (h *ConfigHandler) BatchUpdateConfigs(c *gin.Context) {
 		return
 	}
 
+	services.AddAuditChanges(c.Request.Context(), services.DiffAuditFields(before, after)...)
+
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "批量更新成功",
 		"data": gin.H{
-			"updated_count": len(configs),
+			"updated_count":    len(configs),
+			"restart_required": restartRequired,
+		},
+	})
+}
+
+// GetSchema 获取配置定义
+// @Summary 获取配置定义
+// @Description 获取全部配置项的类型、默认值、校验规则及界面提示，并比对数据库找出孤立配置、无效取值和缺失配置
+// @Tags 系统配置
+// @Security ApiKeyAuth
+// @Success 200 {object} map[string]interface{} "成功"
+// @Failure 500 {object} map[string]interface{} "服务器错误"
+// @Router /api/admin/configs/schema [get]
+func (h *ConfigHandler) GetSchema(c *gin.Context) {
+	report, err := h.configService.CheckConfigSchema()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "配置比对失败",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "获取配置定义成功",
+		"data": gin.H{
+			"schema": services.ConfigSchema(),
+			"report": report,
 		},
 	})
 }
@@ -458,4 +518,15 @@ func (h *ConfigHandler) InitDefaultConfigs(c *gin.Context) {
 		"success": true,
 		"message": "默认配置初始化成功",
 	})
-}
\ No newline at end of file
+}
+
+// configSnapshot 读取配置的当前值，用于审计日志中的变更对比；不存在的配置为 nil
+func (h *ConfigHandler) configSnapshot(keys ...string) map[string]interface{} {
+	snapshot := make(map[string]interface{}, len(keys))
+	for _, key := range keys {
+		if value, err := h.configService.GetConfig(key); err == nil {
+			snapshot[key] = value
+		}
+	}
+	return snapshot
+}
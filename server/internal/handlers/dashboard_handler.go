@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/services"
+)
+
+// DashboardHandler 首页仪表板处理器
+type DashboardHandler struct {
+	dashboardService *services.DashboardService
+	response         *middleware.ResponseHelper
+}
+
+// NewDashboardHandler 创建仪表板处理器
+func NewDashboardHandler(dashboardService *services.DashboardService) *DashboardHandler {
+	return &DashboardHandler{
+		dashboardService: dashboardService,
+		response:         middleware.NewResponseHelper(),
+	}
+}
+
+// GetDashboard 一次返回统计、我的工单、SLA风险、最近动态及未读通知数
+// 单个区块查询失败不影响整体响应，错误信息在对应区块的 error 字段中返回
+func (h *DashboardHandler) GetDashboard(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	if userID == 0 {
+		h.response.Unauthorized(c, "用户未认证")
+		return
+	}
+
+	dashboard := h.dashboardService.GetDashboard(c.Request.Context(), userID, c.GetString("user_role"))
+	h.response.Success(c, dashboard, "获取仪表板成功")
+}
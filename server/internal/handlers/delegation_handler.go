@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// DelegationHandler 休假委托处理器
+type DelegationHandler struct {
+	delegationService *services.DelegationService
+	response          *middleware.ResponseHelper
+}
+
+// NewDelegationHandler 创建休假委托处理器
+func NewDelegationHandler(delegationService *services.DelegationService) *DelegationHandler {
+	return &DelegationHandler{
+		delegationService: delegationService,
+		response:          middleware.NewResponseHelper(),
+	}
+}
+
+// ListDelegations 获取委托列表；普通坐席只能看到与自己相关的委托，管理员/主管可按 user_id 过滤
+func (h *DelegationHandler) ListDelegations(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	filters := services.DelegationFilters{
+		UserID:   c.GetUint("user_id"),
+		Status:   models.DelegationStatus(c.Query("status")),
+		Page:     page,
+		PageSize: pageSize,
+	}
+	if services.CanManageDelegations(c.GetString("user_role")) {
+		filters.UserID = 0
+		if userID, err := strconv.ParseUint(c.Query("user_id"), 10, 32); err == nil {
+			filters.UserID = uint(userID)
+		}
+	}
+
+	delegations, total, err := h.delegationService.List(context.Background(), filters)
+	if err != nil {
+		h.response.InternalServerError(c, "获取委托列表失败", err.Error())
+		return
+	}
+	h.response.List(c, delegations, total, page, pageSize, "获取委托列表成功")
+}
+
+// GetDelegation 获取委托详情
+func (h *DelegationHandler) GetDelegation(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	delegation, err := h.delegationService.Get(context.Background(), id)
+	if err != nil {
+		h.handleError(c, err, "获取委托失败")
+		return
+	}
+	userID := c.GetUint("user_id")
+	if delegation.FromUserID != userID && (delegation.ToUserID == nil || *delegation.ToUserID != userID) &&
+		!services.CanManageDelegations(c.GetString("user_role")) {
+		h.response.Forbidden(c, "无权查看该委托")
+		return
+	}
+	h.response.Success(c, delegation, "获取委托成功")
+}
+
+// CreateDelegation 创建委托
+func (h *DelegationHandler) CreateDelegation(c *gin.Context) {
+	var req models.DelegationCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	delegation, err := h.delegationService.Create(context.Background(), &req, c.GetUint("user_id"), c.GetString("user_role"))
+	if err != nil {
+		h.handleError(c, err, "创建委托失败")
+		return
+	}
+	h.response.Created(c, delegation, "创建委托成功")
+}
+
+// CancelDelegation 取消委托，生效中的委托会立即结束并转回已转移的工单
+func (h *DelegationHandler) CancelDelegation(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	delegation, err := h.delegationService.Cancel(context.Background(), id, c.GetUint("user_id"), c.GetString("user_role"))
+	if err != nil {
+		h.handleError(c, err, "取消委托失败")
+		return
+	}
+	h.response.Success(c, delegation, "取消委托成功")
+}
+
+// RegisterRoutes 注册休假委托路由
+func (h *DelegationHandler) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("", h.ListDelegations)
+	router.POST("", h.CreateDelegation)
+	router.GET("/:id", h.GetDelegation)
+	router.POST("/:id/cancel", h.CancelDelegation)
+}
+
+func (h *DelegationHandler) parseID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, "无效的委托ID")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func (h *DelegationHandler) handleError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrDelegationNotFound):
+		h.response.NotFound(c, "委托不存在")
+	case errors.Is(err, services.ErrDelegationForbidden):
+		h.response.Forbidden(c, "无权管理该委托")
+	case errors.Is(err, services.ErrDelegationInvalid):
+		h.response.BadRequest(c, err.Error())
+	case errors.Is(err, services.ErrDelegationOverlap), errors.Is(err, services.ErrDelegationFinished):
+		h.response.Error(c, http.StatusConflict, err.Error())
+	default:
+		h.response.Error(c, http.StatusInternalServerError, message, err.Error())
+	}
+}
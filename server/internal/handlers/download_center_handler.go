@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/services"
+)
+
+// DownloadCenterHandler 下载中心处理器
+type DownloadCenterHandler struct {
+	downloadService *services.DownloadCenterService
+	response        *middleware.ResponseHelper
+}
+
+// NewDownloadCenterHandler 创建下载中心处理器
+func NewDownloadCenterHandler(downloadService *services.DownloadCenterService) *DownloadCenterHandler {
+	return &DownloadCenterHandler{
+		downloadService: downloadService,
+		response:        middleware.NewResponseHelper(),
+	}
+}
+
+// ListDownloads 当前用户的导出文件列表，未过期的附带签名下载链接
+func (h *DownloadCenterHandler) ListDownloads(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	artifacts, total, err := h.downloadService.List(c.Request.Context(), c.GetUint("user_id"), page, pageSize)
+	if err != nil {
+		h.handleError(c, err, "获取下载列表失败")
+		return
+	}
+	h.response.List(c, artifacts, total, page, pageSize, "获取下载列表成功")
+}
+
+// RegenerateDownload 按原参数重新生成文件，完成后另行通知
+func (h *DownloadCenterHandler) RegenerateDownload(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, "无效的下载ID")
+		return
+	}
+
+	result, err := h.downloadService.Regenerate(c.Request.Context(), uint(id), c.GetUint("user_id"))
+	if err != nil {
+		h.handleError(c, err, "重新生成失败")
+		return
+	}
+	c.JSON(http.StatusAccepted, middleware.StandardResponse{
+		Code: 0,
+		Msg:  "已开始重新生成，完成后将通知您",
+		Data: result,
+	})
+}
+
+// DownloadFile 通过签名链接下载文件（无需登录）
+func (h *DownloadCenterHandler) DownloadFile(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.response.NotFound(c, "文件不存在")
+		return
+	}
+
+	artifact, path, err := h.downloadService.SignedFile(c.Request.Context(), uint(id), c.Query("expires"), c.Query("signature"))
+	if err != nil {
+		h.handleError(c, err, "无法下载文件")
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.FileAttachment(path, artifact.FileName)
+}
+
+func (h *DownloadCenterHandler) handleError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrDownloadNotFound):
+		h.response.NotFound(c, "文件不存在")
+	case errors.Is(err, services.ErrInvalidDownloadLink):
+		h.response.Forbidden(c, "下载链接无效或已过期")
+	case errors.Is(err, services.ErrDownloadExpired):
+		h.response.Error(c, http.StatusGone, "文件已过期，请重新生成")
+	case errors.Is(err, services.ErrDownloadNotRegenerable):
+		h.response.Error(c, http.StatusConflict, "该文件不支持重新生成")
+	case errors.Is(err, services.ErrTicketArchiveTicketNotFound):
+		h.response.NotFound(c, "工单不存在")
+	case errors.Is(err, services.ErrInvalidExportRange):
+		h.response.BadRequest(c, "导出时间范围无效")
+	default:
+		h.response.Error(c, http.StatusInternalServerError, message, err.Error())
+	}
+}
@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/services"
+)
+
+// EmailChannelHandler 邮件通道健康监控处理器
+type EmailChannelHandler struct {
+	emailService services.EmailNotificationServiceInterface
+	response     *middleware.ResponseHelper
+}
+
+// NewEmailChannelHandler 创建邮件通道健康监控处理器
+func NewEmailChannelHandler(emailService services.EmailNotificationServiceInterface) *EmailChannelHandler {
+	return &EmailChannelHandler{
+		emailService: emailService,
+		response:     middleware.NewResponseHelper(),
+	}
+}
+
+// RegisterAdminRoutes 注册管理员路由
+func (h *EmailChannelHandler) RegisterAdminRoutes(router *gin.RouterGroup) {
+	router.GET("/email-channel/health", h.GetHealth)
+}
+
+// GetHealth 邮件通道是否可用、连续失败次数、最近错误及排队等待重发的邮件数
+func (h *EmailChannelHandler) GetHealth(c *gin.Context) {
+	status, err := h.emailService.ChannelStatus(c.Request.Context())
+	if err != nil {
+		h.response.InternalServerError(c, "获取邮件通道状态失败", err.Error())
+		return
+	}
+	h.response.Success(c, status)
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// emailEventMaxBytes 退信回调请求体上限
+const emailEventMaxBytes = 1 << 20
+
+// EmailSuppressionHandler 邮件退信/投诉回调及地址状态管理处理器
+type EmailSuppressionHandler struct {
+	suppressionService *services.EmailSuppressionService
+	response           *middleware.ResponseHelper
+}
+
+// NewEmailSuppressionHandler 创建邮件退信处理器
+func NewEmailSuppressionHandler(suppressionService *services.EmailSuppressionService) *EmailSuppressionHandler {
+	return &EmailSuppressionHandler{
+		suppressionService: suppressionService,
+		response:           middleware.NewResponseHelper(),
+	}
+}
+
+// RegisterPublicRoutes 注册服务商回调路由（不使用登录认证，按回调令牌校验）
+func (h *EmailSuppressionHandler) RegisterPublicRoutes(router *gin.RouterGroup) {
+	router.POST("/:provider", h.HandleEvents)
+}
+
+// RegisterAdminRoutes 注册管理员路由
+func (h *EmailSuppressionHandler) RegisterAdminRoutes(router *gin.RouterGroup) {
+	addresses := router.Group("/email-addresses")
+	{
+		addresses.GET("", h.ListAddresses)
+		addresses.GET("/:id", h.GetAddress)
+		addresses.POST("/:id/reenable", h.Reenable)
+	}
+}
+
+// HandleEvents 处理 SES / SendGrid / Mailgun / 通用格式的退信、投诉及送达回调。
+// 令牌通过 token 查询参数或 X-Webhook-Token 请求头传递
+func (h *EmailSuppressionHandler) HandleEvents(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		token = c.GetHeader("X-Webhook-Token")
+	}
+	if err := h.suppressionService.VerifyWebhookToken(token); err != nil {
+		if errors.Is(err, services.ErrEmailEventWebhookDisabled) {
+			h.response.NotFound(c, "退信回调未启用")
+			return
+		}
+		h.response.Unauthorized(c, "回调令牌无效")
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, emailEventMaxBytes))
+	if err != nil {
+		h.response.BadRequest(c, "读取请求失败")
+		return
+	}
+	processed, err := h.suppressionService.HandleWebhook(c.Request.Context(), c.Param("provider"), body)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUnknownEmailEventProvider):
+			h.response.NotFound(c, "不支持的回调来源")
+		case errors.Is(err, services.ErrInvalidEmailEventPayload):
+			h.response.BadRequest(c, "回调内容无效", err.Error())
+		default:
+			h.response.InternalServerError(c, "处理退信回调失败", err.Error())
+		}
+		return
+	}
+	h.response.Success(c, gin.H{"processed": processed})
+}
+
+// ListAddresses 获取退信/投诉地址，status 默认为全部非正常状态，all 返回全部，可按 search 模糊匹配
+func (h *EmailSuppressionHandler) ListAddresses(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	status := c.Query("status")
+	switch models.EmailAddressState(status) {
+	case "", "all", models.EmailAddressOK, models.EmailAddressSoftBounce, models.EmailAddressHardBounce, models.EmailAddressComplained:
+	default:
+		h.response.BadRequest(c, "status 只能为 all、ok、soft_bounce、hard_bounce 或 complained")
+		return
+	}
+
+	items, total, err := h.suppressionService.ListAddresses(context.Background(), status, c.Query("search"), page, pageSize)
+	if err != nil {
+		h.response.InternalServerError(c, "获取邮箱地址状态失败", err.Error())
+		return
+	}
+	h.response.List(c, items, total, page, pageSize, "获取邮箱地址状态成功")
+}
+
+// GetAddress 获取地址状态及最近的退信、投诉与恢复记录
+func (h *EmailSuppressionHandler) GetAddress(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	detail, err := h.suppressionService.GetAddress(context.Background(), id)
+	if err != nil {
+		h.handleError(c, err, "获取邮箱地址状态失败")
+		return
+	}
+	h.response.Success(c, detail)
+}
+
+// Reenable 填写理由后恢复向地址发送邮件
+func (h *EmailSuppressionHandler) Reenable(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	var req models.EmailReenableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请填写恢复理由（10-500 字）", err.Error())
+		return
+	}
+
+	status, err := h.suppressionService.Reenable(context.Background(), id, req.Reason, c.GetUint("user_id"))
+	if err != nil {
+		h.handleError(c, err, "恢复邮箱地址失败")
+		return
+	}
+	h.response.Success(c, status, "已恢复向该地址发送邮件")
+}
+
+func (h *EmailSuppressionHandler) parseID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, "无效的ID")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func (h *EmailSuppressionHandler) handleError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrEmailAddressNotFound):
+		h.response.NotFound(c, "邮箱地址状态不存在")
+	case errors.Is(err, services.ErrEmailAddressNotSuppressed):
+		h.response.Error(c, http.StatusConflict, "该地址状态正常，无需恢复")
+	default:
+		h.response.Error(c, http.StatusInternalServerError, message, err.Error())
+	}
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// ExternalReferenceHandler 工单外部关联处理器
+type ExternalReferenceHandler struct {
+	referenceService *services.ExternalReferenceService
+	response         *middleware.ResponseHelper
+}
+
+// NewExternalReferenceHandler 创建工单外部关联处理器
+func NewExternalReferenceHandler(referenceService *services.ExternalReferenceService) *ExternalReferenceHandler {
+	return &ExternalReferenceHandler{
+		referenceService: referenceService,
+		response:         middleware.NewResponseHelper(),
+	}
+}
+
+// ListReferences 获取工单的外部关联
+func (h *ExternalReferenceHandler) ListReferences(c *gin.Context) {
+	ticketID, ok := h.parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	refs, err := h.referenceService.List(context.Background(), ticketID)
+	if err != nil {
+		h.handleError(c, err, "获取外部关联失败")
+		return
+	}
+	h.response.Success(c, refs, "获取外部关联成功")
+}
+
+// AttachReference 为工单关联外部记录
+func (h *ExternalReferenceHandler) AttachReference(c *gin.Context) {
+	ticketID, ok := h.parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req models.ExternalReferenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	ref, err := h.referenceService.Attach(context.Background(), ticketID, &req, c.GetUint("user_id"))
+	if err != nil {
+		h.handleError(c, err, "关联外部记录失败")
+		return
+	}
+	h.response.Created(c, ref, "外部记录已关联")
+}
+
+// DetachReference 解除工单的外部关联
+func (h *ExternalReferenceHandler) DetachReference(c *gin.Context) {
+	ticketID, ok := h.parseID(c, "id")
+	if !ok {
+		return
+	}
+	refID, ok := h.parseID(c, "ref_id")
+	if !ok {
+		return
+	}
+
+	if err := h.referenceService.Detach(context.Background(), ticketID, refID); err != nil {
+		h.handleError(c, err, "解除外部关联失败")
+		return
+	}
+	h.response.Success(c, nil, "外部关联已解除")
+}
+
+func (h *ExternalReferenceHandler) parseID(c *gin.Context, param string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(param), 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, "无效的ID")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func (h *ExternalReferenceHandler) handleError(c *gin.Context, err error, message string) {
+	var conflictErr *services.ExternalReferenceConflictError
+	switch {
+	case errors.As(err, &conflictErr):
+		h.response.Error(c, http.StatusConflict, "该外部编号已关联到其他工单", gin.H{"ticket_id": conflictErr.TicketID})
+	case errors.Is(err, services.ErrExternalReferenceTicketNotFound):
+		h.response.NotFound(c, "工单不存在")
+	case errors.Is(err, services.ErrExternalReferenceNotFound):
+		h.response.NotFound(c, "外部关联不存在")
+	case errors.Is(err, services.ErrInvalidExternalReference):
+		h.response.BadRequest(c, err.Error())
+	default:
+		h.response.Error(c, http.StatusInternalServerError, message, err.Error())
+	}
+}
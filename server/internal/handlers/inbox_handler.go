@@ -0,0 +1,398 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// InboxHandler 邮件渠道共享收件箱处理器
+type InboxHandler struct {
+	inboxService *services.InboxService
+	response     *middleware.ResponseHelper
+}
+
+// NewInboxHandler 创建收件箱处理器
+func NewInboxHandler(inboxService *services.InboxService) *InboxHandler {
+	return &InboxHandler{
+		inboxService: inboxService,
+		response:     middleware.NewResponseHelper(),
+	}
+}
+
+// RegisterRoutes 注册收件箱路由（客服及以上）
+func (h *InboxHandler) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("", h.ListInbox)
+	router.POST("/emails", h.IngestEmail)
+
+	router.POST("/emails/:id/convert", h.ConvertEmail)
+	router.POST("/emails/:id/merge", h.MergeEmail)
+	router.POST("/emails/:id/snooze", h.SnoozeEmail)
+	router.POST("/emails/:id/spam", h.MarkEmailSpam)
+	router.POST("/emails/:id/not-spam", h.MarkEmailNotSpam)
+
+	router.POST("/tickets/:id/assign", h.AssignTicket)
+	router.POST("/tickets/:id/snooze", h.SnoozeTicket)
+	router.POST("/tickets/:id/spam", h.MarkTicketSpam)
+
+	router.GET("/blocklist", h.ListBlocklist)
+	router.POST("/blocklist", h.AddBlocklistEntry)
+	router.DELETE("/blocklist/:id", h.DeleteBlocklistEntry)
+
+	router.GET("/senders/trust", h.GetSenderTrust)
+}
+
+// RegisterAdminRoutes 注册管理员路由（收件认证策略）
+func (h *InboxHandler) RegisterAdminRoutes(router *gin.RouterGroup) {
+	intake := router.Group("/intake")
+	{
+		intake.GET("/email-auth-policy", h.GetAuthPolicy)
+		intake.PUT("/email-auth-policy", h.UpdateAuthPolicy)
+	}
+}
+
+type inboxSnoozeRequest struct {
+	Until time.Time `json:"until" binding:"required"`
+}
+
+type inboxSpamRequest struct {
+	BlockDomain bool `json:"block_domain"` // 同时拉黑发件人所在域名
+}
+
+// ListInbox 获取收件箱，kind=email|ticket，include_snoozed=true 时包含暂缓条目
+func (h *InboxHandler) ListInbox(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	kind := c.Query("kind")
+	if kind != "" && kind != "email" && kind != "ticket" {
+		h.response.BadRequest(c, "kind 只能为 email 或 ticket")
+		return
+	}
+
+	items, total, err := h.inboxService.List(context.Background(), services.InboxListOptions{
+		Kind:           kind,
+		Search:         c.Query("q"),
+		IncludeSnoozed: c.Query("include_snoozed") == "true",
+		Page:           page,
+		PageSize:       pageSize,
+	})
+	if err != nil {
+		h.response.InternalServerError(c, "获取收件箱失败", err.Error())
+		return
+	}
+	h.response.List(c, items, total, page, pageSize, "获取收件箱成功")
+}
+
+// IngestEmail 收件入库，供邮件网关或收信任务调用
+func (h *InboxHandler) IngestEmail(c *gin.Context) {
+	var req models.InboundEmailCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	email, err := h.inboxService.Ingest(context.Background(), &req)
+	if err != nil {
+		h.response.InternalServerError(c, "收件入库失败", err.Error())
+		return
+	}
+	h.response.Created(c, email, "收件入库成功")
+}
+
+// ConvertEmail 将邮件转为工单，可同时分配处理人或团队
+func (h *InboxHandler) ConvertEmail(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	var req models.InboxConvertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	ticket, err := h.inboxService.ConvertEmail(context.Background(), id, &req, c.GetUint("user_id"))
+	if err != nil {
+		h.handleError(c, err, "转为工单失败")
+		return
+	}
+	h.response.Created(c, ticket.ToResponse(), "已转为工单")
+}
+
+// MergeEmail 将邮件合并到已有工单
+func (h *InboxHandler) MergeEmail(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		TicketID uint `json:"ticket_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	ticket, err := h.inboxService.MergeEmail(context.Background(), id, req.TicketID, c.GetUint("user_id"))
+	if err != nil {
+		h.handleError(c, err, "合并邮件失败")
+		return
+	}
+	h.response.Success(c, ticket.ToResponse(), "已合并到工单")
+}
+
+// SnoozeEmail 暂缓处理邮件
+func (h *InboxHandler) SnoozeEmail(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	var req inboxSnoozeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	if err := h.inboxService.SnoozeEmail(context.Background(), id, req.Until); err != nil {
+		h.handleError(c, err, "暂缓失败")
+		return
+	}
+	h.response.Success(c, gin.H{"snoozed_until": req.Until}, "已暂缓")
+}
+
+// MarkEmailSpam 标记邮件为垃圾邮件
+func (h *InboxHandler) MarkEmailSpam(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	// 请求体可省略
+	var req inboxSpamRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			h.response.BadRequest(c, "请求参数无效", err.Error())
+			return
+		}
+	}
+
+	if err := h.inboxService.MarkEmailSpam(context.Background(), id, req.BlockDomain, c.GetUint("user_id")); err != nil {
+		h.handleError(c, err, "标记垃圾邮件失败")
+		return
+	}
+	h.response.Success(c, nil, "已标记为垃圾邮件")
+}
+
+// MarkEmailNotSpam 将误判的垃圾邮件放回收件箱
+func (h *InboxHandler) MarkEmailNotSpam(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.inboxService.MarkEmailNotSpam(context.Background(), id); err != nil {
+		h.handleError(c, err, "恢复邮件失败")
+		return
+	}
+	h.response.Success(c, nil, "已恢复到收件箱")
+}
+
+// AssignTicket 为待分拣的邮件工单分配处理人或团队
+func (h *InboxHandler) AssignTicket(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	var req models.InboxAssignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	ticket, err := h.inboxService.AssignTicket(context.Background(), id, &req, c.GetUint("user_id"))
+	if err != nil {
+		h.handleError(c, err, "分配工单失败")
+		return
+	}
+	h.response.Success(c, ticket.ToResponse(), "工单分配成功")
+}
+
+// SnoozeTicket 暂缓分拣邮件工单
+func (h *InboxHandler) SnoozeTicket(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	var req inboxSnoozeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	if err := h.inboxService.SnoozeTicket(context.Background(), id, req.Until); err != nil {
+		h.handleError(c, err, "暂缓失败")
+		return
+	}
+	h.response.Success(c, gin.H{"snoozed_until": req.Until}, "已暂缓")
+}
+
+// MarkTicketSpam 将邮件工单标记为垃圾邮件并取消
+func (h *InboxHandler) MarkTicketSpam(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	// 请求体可省略
+	var req inboxSpamRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			h.response.BadRequest(c, "请求参数无效", err.Error())
+			return
+		}
+	}
+
+	if err := h.inboxService.MarkTicketSpam(context.Background(), id, req.BlockDomain, c.GetUint("user_id")); err != nil {
+		h.handleError(c, err, "标记垃圾邮件失败")
+		return
+	}
+	h.response.Success(c, nil, "已标记为垃圾邮件")
+}
+
+// ListBlocklist 获取发件人/域名黑名单
+func (h *InboxHandler) ListBlocklist(c *gin.Context) {
+	entries, err := h.inboxService.ListBlocklist(context.Background())
+	if err != nil {
+		h.response.InternalServerError(c, "获取黑名单失败", err.Error())
+		return
+	}
+	h.response.Success(c, entries, "获取黑名单成功")
+}
+
+// AddBlocklistEntry 手动拉黑发件人地址或域名
+func (h *InboxHandler) AddBlocklistEntry(c *gin.Context) {
+	var req struct {
+		Pattern string `json:"pattern" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	entry, err := h.inboxService.AddBlocklistEntry(context.Background(), req.Pattern, c.GetUint("user_id"))
+	if err != nil {
+		h.handleError(c, err, "添加黑名单失败")
+		return
+	}
+	h.response.Success(c, entry, "已加入黑名单")
+}
+
+// DeleteBlocklistEntry 删除黑名单条目
+func (h *InboxHandler) DeleteBlocklistEntry(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.inboxService.DeleteBlocklistEntry(context.Background(), id); err != nil {
+		h.response.NotFound(c, "黑名单条目不存在")
+		return
+	}
+	h.response.Success(c, nil, "已移出黑名单")
+}
+
+// GetSenderTrust 获取发件人可信度，address 可重复传入多个
+func (h *InboxHandler) GetSenderTrust(c *gin.Context) {
+	addresses := c.QueryArray("address")
+	if len(addresses) == 0 {
+		h.response.BadRequest(c, "address 不能为空")
+		return
+	}
+
+	trust, err := h.inboxService.AuthService().SenderTrust(context.Background(), addresses)
+	if err != nil {
+		h.response.InternalServerError(c, "获取发件人可信度失败", err.Error())
+		return
+	}
+	h.response.Success(c, trust, "获取发件人可信度成功")
+}
+
+// GetAuthPolicy 获取收件认证策略
+func (h *InboxHandler) GetAuthPolicy(c *gin.Context) {
+	policy, err := h.inboxService.AuthService().GetPolicy(context.Background())
+	if err != nil {
+		h.response.InternalServerError(c, "获取收件认证策略失败", err.Error())
+		return
+	}
+	h.response.Success(c, policy)
+}
+
+// UpdateAuthPolicy 更新收件认证策略
+func (h *InboxHandler) UpdateAuthPolicy(c *gin.Context) {
+	var req models.EmailAuthPolicy
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	if err := h.inboxService.AuthService().SetPolicy(context.Background(), &req, c.GetUint("user_id")); err != nil {
+		h.response.BadRequest(c, err.Error())
+		return
+	}
+	h.response.Success(c, req, "收件认证策略已更新")
+}
+
+// GetTicketEmailThread 获取工单关联的原始邮件往来（含 Message-ID、In-Reply-To 等会话信息）
+func (h *InboxHandler) GetTicketEmailThread(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	thread, err := h.inboxService.GetTicketEmailThread(context.Background(), id)
+	if err != nil {
+		h.handleError(c, err, "获取邮件会话失败")
+		return
+	}
+	h.response.Success(c, thread)
+}
+
+func (h *InboxHandler) parseID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, "无效的ID")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func (h *InboxHandler) handleError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInboundEmailNotFound):
+		h.response.NotFound(c, "邮件不存在")
+	case errors.Is(err, services.ErrInboxTicketNotFound), err.Error() == "ticket not found":
+		h.response.NotFound(c, "工单不存在")
+	case errors.Is(err, services.ErrInboxItemProcessed), errors.Is(err, services.ErrInboxTicketNotTriage):
+		h.response.Error(c, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrInvalidSnoozeTime),
+		errors.Is(err, services.ErrInboxAssigneeRequired),
+		errors.Is(err, services.ErrInvalidBlocklistPattern),
+		errors.Is(err, services.ErrTeamNotFound):
+		h.response.BadRequest(c, err.Error())
+	default:
+		h.response.Error(c, http.StatusInternalServerError, message, err.Error())
+	}
+}
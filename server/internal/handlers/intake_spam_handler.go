@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// IntakeSpamHandler 公开渠道垃圾检测策略及隔离区审核处理器
+type IntakeSpamHandler struct {
+	spamService *services.IntakeSpamService
+	response    *middleware.ResponseHelper
+}
+
+// NewIntakeSpamHandler 创建受理垃圾检测处理器
+func NewIntakeSpamHandler(spamService *services.IntakeSpamService) *IntakeSpamHandler {
+	return &IntakeSpamHandler{
+		spamService: spamService,
+		response:    middleware.NewResponseHelper(),
+	}
+}
+
+// RegisterAdminRoutes 注册管理员路由
+func (h *IntakeSpamHandler) RegisterAdminRoutes(router *gin.RouterGroup) {
+	intake := router.Group("/intake")
+	{
+		intake.GET("/spam-policy", h.GetPolicy)
+		intake.PUT("/spam-policy", h.UpdatePolicy)
+
+		intake.GET("/quarantine", h.ListQuarantine)
+		intake.GET("/quarantine/:id", h.GetQuarantineItem)
+		intake.POST("/quarantine/:id/release", h.ReleaseQuarantineItem)
+		intake.POST("/quarantine/:id/reject", h.RejectQuarantineItem)
+	}
+}
+
+// GetPolicy 获取垃圾检测策略
+func (h *IntakeSpamHandler) GetPolicy(c *gin.Context) {
+	policy, err := h.spamService.GetPolicy(context.Background())
+	if err != nil {
+		h.response.InternalServerError(c, "获取垃圾检测策略失败", err.Error())
+		return
+	}
+	h.response.Success(c, policy)
+}
+
+// UpdatePolicy 更新垃圾检测策略
+func (h *IntakeSpamHandler) UpdatePolicy(c *gin.Context) {
+	var req models.IntakeSpamPolicy
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	if err := h.spamService.SetPolicy(context.Background(), &req, c.GetUint("user_id")); err != nil {
+		h.response.BadRequest(c, err.Error())
+		return
+	}
+	h.response.Success(c, req, "垃圾检测策略已更新")
+}
+
+// ListQuarantine 获取隔离区条目，status 默认为 pending，可按 channel 过滤
+func (h *IntakeSpamHandler) ListQuarantine(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	status := models.IntakeQuarantineStatus(c.Query("status"))
+	switch status {
+	case "", models.IntakeQuarantinePending, models.IntakeQuarantineReleased, models.IntakeQuarantineRejected:
+	default:
+		h.response.BadRequest(c, "status 只能为 pending、released 或 rejected")
+		return
+	}
+
+	items, total, err := h.spamService.ListQuarantine(context.Background(), status, c.Query("channel"), page, pageSize)
+	if err != nil {
+		h.response.InternalServerError(c, "获取隔离区失败", err.Error())
+		return
+	}
+	h.response.List(c, items, total, page, pageSize, "获取隔离区成功")
+}
+
+// GetQuarantineItem 获取隔离区条目详情
+func (h *IntakeSpamHandler) GetQuarantineItem(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	item, err := h.spamService.GetQuarantineItem(context.Background(), id)
+	if err != nil {
+		h.handleError(c, err, "获取隔离区条目失败")
+		return
+	}
+	h.response.Success(c, item)
+}
+
+// ReleaseQuarantineItem 放行隔离区条目
+func (h *IntakeSpamHandler) ReleaseQuarantineItem(c *gin.Context) {
+	h.review(c, h.spamService.Release, "已放行")
+}
+
+// RejectQuarantineItem 确认隔离区条目为垃圾
+func (h *IntakeSpamHandler) RejectQuarantineItem(c *gin.Context) {
+	h.review(c, h.spamService.Reject, "已确认为垃圾")
+}
+
+type quarantineReviewFunc func(ctx context.Context, id uint, req *models.IntakeQuarantineReviewRequest, reviewerID uint) (*models.IntakeQuarantineItem, error)
+
+func (h *IntakeSpamHandler) review(c *gin.Context, action quarantineReviewFunc, message string) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	// 请求体可省略
+	var req models.IntakeQuarantineReviewRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			h.response.BadRequest(c, "请求参数无效", err.Error())
+			return
+		}
+	}
+
+	item, err := action(context.Background(), id, &req, c.GetUint("user_id"))
+	if err != nil {
+		h.handleError(c, err, "审核隔离区条目失败")
+		return
+	}
+	h.response.Success(c, item, message)
+}
+
+func (h *IntakeSpamHandler) parseID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, "无效的ID")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func (h *IntakeSpamHandler) handleError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrQuarantineItemNotFound):
+		h.response.NotFound(c, "隔离区条目不存在")
+	case errors.Is(err, services.ErrQuarantineItemReviewed):
+		h.response.Error(c, http.StatusConflict, err.Error())
+	default:
+		h.response.Error(c, http.StatusInternalServerError, message, err.Error())
+	}
+}
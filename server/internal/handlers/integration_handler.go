@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// IntegrationHandler Zapier/Make 等无代码平台集成处理器
+type IntegrationHandler struct {
+	integrationService *services.IntegrationService
+	response           *middleware.ResponseHelper
+}
+
+// NewIntegrationHandler 创建集成处理器
+func NewIntegrationHandler(integrationService *services.IntegrationService) *IntegrationHandler {
+	return &IntegrationHandler{
+		integrationService: integrationService,
+		response:           middleware.NewResponseHelper(),
+	}
+}
+
+// RegisterRoutes 注册集成路由
+func (h *IntegrationHandler) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("/auth/test", h.AuthTest)
+	triggers := router.Group("/triggers")
+	{
+		triggers.GET("/new-tickets", h.NewTickets)
+		triggers.GET("/updated-tickets", h.UpdatedTickets)
+	}
+}
+
+// AuthTest 连接测试，返回当前令牌对应的账户信息
+func (h *IntegrationHandler) AuthTest(c *gin.Context) {
+	info, err := h.integrationService.GetAuthInfo(context.Background(), c.GetUint("user_id"))
+	if err != nil {
+		h.response.Unauthorized(c, "认证信息无效")
+		return
+	}
+	h.response.Success(c, info, "认证成功")
+}
+
+// NewTickets 新建工单轮询触发器，支持 since（RFC3339）、cursor、limit 参数
+func (h *IntegrationHandler) NewTickets(c *gin.Context) {
+	h.listTickets(c, h.integrationService.ListNewTickets)
+}
+
+// UpdatedTickets 更新工单轮询触发器，支持 since（RFC3339）、cursor、limit 参数
+func (h *IntegrationHandler) UpdatedTickets(c *gin.Context) {
+	h.listTickets(c, h.integrationService.ListUpdatedTickets)
+}
+
+type integrationListFunc func(ctx context.Context, since *time.Time, cursor string, limit int) (*models.IntegrationTriggerPage, error)
+
+func (h *IntegrationHandler) listTickets(c *gin.Context, list integrationListFunc) {
+	var since *time.Time
+	if raw := c.Query("since"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			h.response.BadRequest(c, "since 参数必须为 RFC3339 时间格式")
+			return
+		}
+		since = &parsed
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "0"))
+
+	page, err := list(context.Background(), since, c.Query("cursor"), limit)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidIntegrationCursor) {
+			h.response.BadRequest(c, "无效的游标")
+			return
+		}
+		h.response.InternalServerError(c, "获取工单失败")
+		return
+	}
+	h.response.Success(c, page, "获取成功")
+}
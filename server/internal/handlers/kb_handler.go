@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// KBHandler 知识库处理器
+type KBHandler struct {
+	kbService *services.KBArticleService
+	response  *middleware.ResponseHelper
+}
+
+// NewKBHandler 创建知识库处理器
+func NewKBHandler(kbService *services.KBArticleService) *KBHandler {
+	return &KBHandler{
+		kbService: kbService,
+		response:  middleware.NewResponseHelper(),
+	}
+}
+
+// ListArticles 获取文章列表，支持 q 全文检索及分类、状态、可见范围过滤
+func (h *KBHandler) ListArticles(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	filters := &services.KBArticleFilters{
+		Query:      c.Query("q"),
+		Status:     models.KBArticleStatus(c.Query("status")),
+		Visibility: models.KBArticleVisibility(c.Query("visibility")),
+		Page:       page,
+		PageSize:   pageSize,
+	}
+	if categoryID, err := strconv.ParseUint(c.Query("category_id"), 10, 32); err == nil {
+		id := uint(categoryID)
+		filters.CategoryID = &id
+	}
+
+	articles, total, err := h.kbService.List(context.Background(), filters, c.GetString("user_role"))
+	if err != nil {
+		h.response.InternalServerError(c, "获取知识库文章失败", err.Error())
+		return
+	}
+	h.response.List(c, articles, total, page, pageSize, "获取知识库文章成功")
+}
+
+// GetArticle 获取文章详情
+func (h *KBHandler) GetArticle(c *gin.Context) {
+	id, ok := h.parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	article, err := h.kbService.Get(context.Background(), id, c.GetString("user_role"))
+	if err != nil {
+		h.handleError(c, err, "获取知识库文章失败")
+		return
+	}
+	h.response.Success(c, article, "获取知识库文章成功")
+}
+
+// CreateArticle 创建文章
+func (h *KBHandler) CreateArticle(c *gin.Context) {
+	var req models.KBArticleCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	article, err := h.kbService.Create(context.Background(), &req, c.GetUint("user_id"))
+	if err != nil {
+		h.handleError(c, err, "创建知识库文章失败")
+		return
+	}
+	h.response.Created(c, article, "创建知识库文章成功")
+}
+
+// UpdateArticle 更新文章
+func (h *KBHandler) UpdateArticle(c *gin.Context) {
+	id, ok := h.parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req models.KBArticleUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	article, err := h.kbService.Update(context.Background(), id, &req, c.GetUint("user_id"))
+	if err != nil {
+		h.handleError(c, err, "更新知识库文章失败")
+		return
+	}
+	h.response.Success(c, article, "更新知识库文章成功")
+}
+
+// DeleteArticle 删除文章
+func (h *KBHandler) DeleteArticle(c *gin.Context) {
+	id, ok := h.parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.kbService.Delete(context.Background(), id); err != nil {
+		h.handleError(c, err, "删除知识库文章失败")
+		return
+	}
+	h.response.Success(c, nil, "删除知识库文章成功")
+}
+
+// RecordDeflection 记录客户阅读文章后放弃提交工单
+func (h *KBHandler) RecordDeflection(c *gin.Context) {
+	id, ok := h.parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.kbService.RecordDeflection(context.Background(), id, c.GetString("user_role")); err != nil {
+		h.handleError(c, err, "记录拦截失败")
+		return
+	}
+	h.response.Success(c, nil, "已记录")
+}
+
+// SuggestArticles 根据工单（ticket_id）或任意文本（text）推荐相关文章
+func (h *KBHandler) SuggestArticles(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "5"))
+	role := c.GetString("user_role")
+
+	var (
+		suggestions []models.KBArticleSuggestion
+		err         error
+	)
+	if ticketParam := c.Query("ticket_id"); ticketParam != "" {
+		ticketID, parseErr := strconv.ParseUint(ticketParam, 10, 32)
+		if parseErr != nil {
+			h.response.BadRequest(c, "无效的工单ID")
+			return
+		}
+		if !models.IsKBStaffRole(role) {
+			h.response.Forbidden(c, "无权按工单推荐文章")
+			return
+		}
+		suggestions, err = h.kbService.SuggestForTicket(context.Background(), uint(ticketID), role, limit)
+	} else if text := c.Query("text"); text != "" {
+		suggestions, err = h.kbService.Suggest(context.Background(), text, role, limit)
+	} else {
+		h.response.BadRequest(c, "需要提供 ticket_id 或 text 参数")
+		return
+	}
+	if err != nil {
+		h.handleError(c, err, "获取推荐文章失败")
+		return
+	}
+	h.response.Success(c, suggestions, "获取推荐文章成功")
+}
+
+// ListTicketArticles 获取工单关联的文章
+func (h *KBHandler) ListTicketArticles(c *gin.Context) {
+	ticketID, ok := h.parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	links, err := h.kbService.ListTicketArticles(context.Background(), ticketID)
+	if err != nil {
+		h.handleError(c, err, "获取工单关联文章失败")
+		return
+	}
+	h.response.Success(c, links, "获取工单关联文章成功")
+}
+
+// LinkTicketArticle 将文章关联到工单，as_resolution 为 true 时作为解决方案
+func (h *KBHandler) LinkTicketArticle(c *gin.Context) {
+	ticketID, ok := h.parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req models.KBLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	link, err := h.kbService.LinkToTicket(context.Background(), ticketID, &req, c.GetUint("user_id"))
+	if err != nil {
+		h.handleError(c, err, "关联知识库文章失败")
+		return
+	}
+	h.response.Created(c, link, "关联知识库文章成功")
+}
+
+// UnlinkTicketArticle 取消文章与工单的关联
+func (h *KBHandler) UnlinkTicketArticle(c *gin.Context) {
+	ticketID, ok := h.parseID(c, "id")
+	if !ok {
+		return
+	}
+	articleID, ok := h.parseID(c, "article_id")
+	if !ok {
+		return
+	}
+
+	if err := h.kbService.UnlinkFromTicket(context.Background(), ticketID, articleID); err != nil {
+		h.handleError(c, err, "取消关联失败")
+		return
+	}
+	h.response.Success(c, nil, "已取消关联")
+}
+
+func (h *KBHandler) parseID(c *gin.Context, param string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(param), 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, "无效的ID")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func (h *KBHandler) handleError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrKBArticleNotFound):
+		h.response.NotFound(c, "知识库文章不存在")
+	case errors.Is(err, services.ErrKBTicketNotFound):
+		h.response.NotFound(c, "工单不存在")
+	case errors.Is(err, services.ErrKBLinkNotFound):
+		h.response.NotFound(c, err.Error())
+	case errors.Is(err, services.ErrKBAlreadyLinked):
+		h.response.Error(c, http.StatusConflict, err.Error())
+	default:
+		h.response.Error(c, http.StatusInternalServerError, message, err.Error())
+	}
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// LiveQueueHandler 工单列表实时更新的轮询接口（WebSocket 不可用时使用）
+type LiveQueueHandler struct {
+	liveQueue *services.LiveQueueService
+	response  *middleware.ResponseHelper
+}
+
+// NewLiveQueueHandler 创建工单列表变更处理器
+func NewLiveQueueHandler(liveQueue *services.LiveQueueService) *LiveQueueHandler {
+	return &LiveQueueHandler{
+		liveQueue: liveQueue,
+		response:  middleware.NewResponseHelper(),
+	}
+}
+
+// GetChanges 返回游标之后符合筛选条件的工单变更，筛选参数与 WebSocket 订阅的 filter 一致
+func (h *LiveQueueHandler) GetChanges(c *gin.Context) {
+	filter := &models.LiveQueueFilter{
+		Status:     extractFilterStrings(c.Query("status")),
+		Priority:   extractFilterStrings(c.Query("priority")),
+		Type:       extractFilterStrings(c.Query("type")),
+		Tags:       extractFilterStrings(c.Query("tags")),
+		Mine:       c.Query("mine") == "true",
+		Unassigned: c.Query("unassigned") == "true",
+	}
+	for param, target := range map[string]**uint{
+		"assignee_id": &filter.AssigneeID,
+		"creator_id":  &filter.CreatorID,
+		"team_id":     &filter.TeamID,
+		"category_id": &filter.CategoryID,
+	} {
+		value := c.Query(param)
+		if value == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			h.response.BadRequest(c, "无效的"+param)
+			return
+		}
+		parsed := uint(id)
+		*target = &parsed
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	changes, err := h.liveQueue.Changes(c.Request.Context(), c.GetUint("user_id"), filter, c.Query("cursor"), limit)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidLiveQueueCursor):
+			h.response.BadRequest(c, "无效的游标")
+		case errors.Is(err, services.ErrLiveQueueCursorExpired):
+			h.response.Error(c, http.StatusGone, "游标已过期，请重新加载工单列表")
+		default:
+			h.response.InternalServerError(c, "获取工单变更失败", err.Error())
+		}
+		return
+	}
+	h.response.Success(c, changes)
+}
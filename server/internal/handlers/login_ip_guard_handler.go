@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// LoginIPGuardHandler 按IP的登录防护管理处理器
+type LoginIPGuardHandler struct {
+	guardService *services.LoginIPGuardService
+	response     *middleware.ResponseHelper
+}
+
+// NewLoginIPGuardHandler 创建IP登录防护处理器
+func NewLoginIPGuardHandler(guardService *services.LoginIPGuardService) *LoginIPGuardHandler {
+	return &LoginIPGuardHandler{
+		guardService: guardService,
+		response:     middleware.NewResponseHelper(),
+	}
+}
+
+// RegisterAdminRoutes 注册管理员路由
+func (h *LoginIPGuardHandler) RegisterAdminRoutes(router *gin.RouterGroup) {
+	router.GET("/security/login-ip-protection", h.GetConfig)
+	router.PUT("/security/login-ip-protection", h.UpdateConfig)
+	router.GET("/security/login-ip-bans", h.ListBans)
+	router.DELETE("/security/login-ip-bans/:id", h.Unban)
+}
+
+// GetConfig 获取IP登录防护配置
+func (h *LoginIPGuardHandler) GetConfig(c *gin.Context) {
+	cfg, err := h.guardService.GetConfig(c.Request.Context())
+	if err != nil {
+		h.response.InternalServerError(c, "获取IP登录防护配置失败", err.Error())
+		return
+	}
+	h.response.Success(c, cfg)
+}
+
+// UpdateConfig 更新IP登录防护配置
+func (h *LoginIPGuardHandler) UpdateConfig(c *gin.Context) {
+	var req models.LoginIPProtectionConfig
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	if err := h.guardService.SetConfig(c.Request.Context(), &req, c.GetUint("user_id")); err != nil {
+		h.response.BadRequest(c, err.Error())
+		return
+	}
+	h.response.Success(c, req, "IP登录防护配置已更新")
+}
+
+// ListBans 获取当前被封禁的IP
+func (h *LoginIPGuardHandler) ListBans(c *gin.Context) {
+	bans, err := h.guardService.ListActiveBans(c.Request.Context())
+	if err != nil {
+		h.response.InternalServerError(c, "获取IP封禁列表失败", err.Error())
+		return
+	}
+	h.response.Success(c, gin.H{"items": bans, "total": len(bans)})
+}
+
+// Unban 手动解封IP
+func (h *LoginIPGuardHandler) Unban(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, "无效的封禁ID")
+		return
+	}
+
+	ban, err := h.guardService.Unban(c.Request.Context(), uint(id), c.GetUint("user_id"))
+	if err != nil {
+		if errors.Is(err, services.ErrLoginIPBanNotFound) {
+			h.response.NotFound(c, "封禁记录不存在或已失效")
+			return
+		}
+		h.response.InternalServerError(c, "解封IP失败", err.Error())
+		return
+	}
+	h.response.Success(c, ban, "IP已解封")
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
     "encoding/json"
+    "errors"
     "net/http"
     "strconv"
     "strings"
@@ -69,22 +70,17 @@ func (h *NotificationHandler) GetNotifications(c *gin.Context) {
     filter.Limit = pageSize
     filter.Offset = (page - 1) * pageSize
 
-    // 解析排序参数
-    if sortParam := c.Query("sort"); sortParam != "" {
-        var sortFields []string
-        if err := json.Unmarshal([]byte(sortParam), &sortFields); err == nil && len(sortFields) == 2 {
-            field := sortFields[0]
-            if !isValidNotificationSortField(field) {
-                field = "created_at"
-            }
-            direction := strings.ToLower(sortFields[1])
-            if direction != "asc" && direction != "desc" {
-                direction = "desc"
-            }
-            filter.OrderBy = field
-            filter.OrderDir = direction
-        }
+    // 解析排序参数：sort=priority:desc,created_at:asc，兼容 ["created_at","DESC"]
+    sort, err := services.ParseSort(c.Query("sort"))
+    if err != nil {
+        c.JSON(http.StatusBadRequest, gin.H{
+            "code": 1,
+            "msg":  "排序参数无效: " + err.Error(),
+            "data": nil,
+        })
+        return
     }
+    filter.Sort = sort
 
     // 解析过滤参数(filter=...)
     if filterParam := c.Query("filter"); filterParam != "" {
@@ -119,14 +115,45 @@ func (h *NotificationHandler) GetNotifications(c *gin.Context) {
         }
     }
 
-    if filter.OrderBy == "" {
-        filter.OrderBy = "created_at"
-    }
-    if filter.OrderDir == "" {
-        filter.OrderDir = "desc"
+    totalPages := int64(0)
+
+    // grouped=true 时按工单和类型返回分组摘要
+    if grouped, _ := strconv.ParseBool(c.Query("grouped")); grouped {
+        groups, total, err := h.notificationService.GetNotificationGroups(c.Request.Context(), &filter)
+        if err != nil {
+            c.JSON(http.StatusInternalServerError, gin.H{
+                "code": 1,
+                "msg":  "获取通知失败: " + err.Error(),
+                "data": nil,
+            })
+            return
+        }
+        if pageSize > 0 {
+            totalPages = (total + int64(pageSize) - 1) / int64(pageSize)
+        }
+        c.JSON(http.StatusOK, gin.H{
+            "code": 0,
+            "msg":  "获取通知分组成功",
+            "data": gin.H{
+                "items":       groups,
+                "page":        page,
+                "page_size":   pageSize,
+                "total":       total,
+                "total_pages": totalPages,
+            },
+        })
+        return
     }
 
     notifications, total, err := h.notificationService.GetNotifications(c.Request.Context(), &filter)
+    if errors.Is(err, services.ErrInvalidSort) {
+        c.JSON(http.StatusBadRequest, gin.H{
+            "code": 1,
+            "msg":  "排序参数无效: " + err.Error(),
+            "data": nil,
+        })
+        return
+    }
     if err != nil {
         c.JSON(http.StatusInternalServerError, gin.H{
             "code": 1,
@@ -141,7 +168,6 @@ func (h *NotificationHandler) GetNotifications(c *gin.Context) {
         responses = append(responses, notification.ToResponse())
     }
 
-    totalPages := int64(0)
     if pageSize > 0 {
         totalPages = (total + int64(pageSize) - 1) / int64(pageSize)
     }
@@ -393,15 +419,6 @@ func parseDateValue(value interface{}) (*time.Time, bool) {
     return nil, false
 }
 
-func isValidNotificationSortField(field string) bool {
-    switch field {
-    case "created_at", "priority", "type", "channel", "recipient_id", "sender_id", "is_read", "is_sent":
-        return true
-    default:
-        return false
-    }
-}
-
 // MarkAsRead 标记通知为已读
 func (h *NotificationHandler) MarkAsRead(c *gin.Context) {
 	userID, exists := c.Get("user_id")
@@ -438,6 +455,115 @@ func (h *NotificationHandler) MarkAsRead(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "标记成功"})
 }
 
+// GetNotificationDeliveries 获取通知在各渠道（应用内、邮件、浏览器推送、Webhook）上的投递记录，
+// 用于排查用户反馈收不到通知的问题。管理员与主管可查看任意通知
+func (h *NotificationHandler) GetNotificationDeliveries(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的通知ID"})
+		return
+	}
+
+	role := c.GetString("user_role")
+	isAdmin := role == string(models.RoleAdmin) || role == string(models.RoleSupervisor)
+	deliveries, err := h.notificationService.GetNotificationDeliveries(c.Request.Context(), uint(id), userID.(uint), isAdmin)
+	if err != nil {
+		if err.Error() == "通知不存在" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "通知不存在"})
+			return
+		}
+		if err.Error() == "无权限操作此通知" {
+			c.JSON(http.StatusForbidden, gin.H{"error": "无权限操作此通知"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取投递记录失败"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "获取投递记录成功",
+		"data": gin.H{
+			"notification_id": id,
+			"items":           deliveries,
+			"total":           len(deliveries),
+		},
+	})
+}
+
+// GetGroupNotifications 展开通知分组，返回组内全部通知
+func (h *NotificationHandler) GetGroupNotifications(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	groupID, err := strconv.ParseUint(c.Param("group_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的分组ID"})
+		return
+	}
+
+	notifications, err := h.notificationService.GetGroupNotifications(c.Request.Context(), uint(groupID), userID.(uint))
+	if err != nil {
+		if err.Error() == "通知不存在" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "通知分组不存在"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "获取分组通知失败"})
+		return
+	}
+
+	responses := make([]*models.NotificationResponse, 0, len(notifications))
+	for _, notification := range notifications {
+		responses = append(responses, notification.ToResponse())
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "获取分组通知成功",
+		"data": gin.H{
+			"group_id": groupID,
+			"items":    responses,
+			"total":    len(responses),
+		},
+	})
+}
+
+// MarkGroupAsRead 将分组内的通知全部标记为已读
+func (h *NotificationHandler) MarkGroupAsRead(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	groupID, err := strconv.ParseUint(c.Param("group_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "无效的分组ID"})
+		return
+	}
+
+	updated, err := h.notificationService.MarkGroupAsRead(c.Request.Context(), uint(groupID), userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "分组标记已读失败"})
+		return
+	}
+
+	// 触发WebSocket实时更新未读数量
+	if unread, err := h.notificationService.GetUnreadCount(c.Request.Context(), userID.(uint)); err == nil {
+		websocketPkg.NotificationsClearedHook(c.Request.Context(), userID.(uint), unread)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "标记成功", "updated": updated})
+}
+
 // MarkAllAsRead 标记所有通知为已读
 func (h *NotificationHandler) MarkAllAsRead(c *gin.Context) {
 	userID, exists := c.Get("user_id")
@@ -458,6 +584,50 @@ func (h *NotificationHandler) MarkAllAsRead(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "标记成功"})
 }
 
+// ClearNotifications 批量清理当前用户的通知
+// 查询参数: read_only (默认 true) 仅清理已读通知, older_than_days 仅清理早于指定天数的通知
+func (h *NotificationHandler) ClearNotifications(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "未授权"})
+		return
+	}
+
+	opts := &models.NotificationClearOptions{ReadOnly: true}
+	if readOnlyStr := c.Query("read_only"); readOnlyStr != "" {
+		readOnly, err := strconv.ParseBool(readOnlyStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的read_only参数"})
+			return
+		}
+		opts.ReadOnly = readOnly
+	}
+	if daysStr := c.Query("older_than_days"); daysStr != "" {
+		days, err := strconv.Atoi(daysStr)
+		if err != nil || days < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "无效的older_than_days参数"})
+			return
+		}
+		olderThan := time.Now().AddDate(0, 0, -days)
+		opts.OlderThan = &olderThan
+	}
+
+	deleted, err := h.notificationService.ClearNotifications(c.Request.Context(), userID.(uint), opts)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "清理通知失败"})
+		return
+	}
+
+	// 清理未读通知时同步推送最新未读数量
+	if !opts.ReadOnly && deleted > 0 {
+		if count, err := h.notificationService.GetUnreadCount(c.Request.Context(), userID.(uint)); err == nil {
+			websocketPkg.NotificationsClearedHook(c.Request.Context(), userID.(uint), count)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "清理成功", "deleted": deleted})
+}
+
 // GetUnreadCount 获取未读通知数量
 func (h *NotificationHandler) GetUnreadCount(c *gin.Context) {
 	userID, exists := c.Get("user_id")
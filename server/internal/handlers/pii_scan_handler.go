@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// PIIScanHandler 敏感信息检测配置与脱敏记录处理器
+type PIIScanHandler struct {
+	piiScanService *services.PIIScanService
+	response       *middleware.ResponseHelper
+}
+
+// NewPIIScanHandler 创建敏感信息检测处理器
+func NewPIIScanHandler(piiScanService *services.PIIScanService) *PIIScanHandler {
+	return &PIIScanHandler{
+		piiScanService: piiScanService,
+		response:       middleware.NewResponseHelper(),
+	}
+}
+
+// RegisterAdminRoutes 注册管理员路由
+func (h *PIIScanHandler) RegisterAdminRoutes(router *gin.RouterGroup) {
+	pii := router.Group("/pii-scan")
+	{
+		pii.GET("/config", h.GetConfig)
+		pii.PUT("/config", h.UpdateConfig)
+		pii.POST("/test", h.TestScan)
+		pii.GET("/redactions", h.ListRedactions)
+	}
+}
+
+// writePIIBlockedError 内容因包含敏感信息被拒绝保存时返回 422 及命中类型，返回是否已处理
+func writePIIBlockedError(c *gin.Context, response *middleware.ResponseHelper, err error) bool {
+	var blockedErr *services.PIIBlockedError
+	if !errors.As(err, &blockedErr) {
+		return false
+	}
+	response.Error(c, http.StatusUnprocessableEntity,
+		"内容包含敏感信息（"+services.DescribePIIFindings(blockedErr.Findings)+"），请删除后重新提交",
+		gin.H{"findings": blockedErr.Findings})
+	return true
+}
+
+// GetConfig 获取检测配置
+func (h *PIIScanHandler) GetConfig(c *gin.Context) {
+	config, err := h.piiScanService.GetConfig(c.Request.Context())
+	if err != nil {
+		h.response.InternalServerError(c, "获取敏感信息检测配置失败", err.Error())
+		return
+	}
+	h.response.Success(c, config)
+}
+
+// UpdateConfig 更新检测配置
+func (h *PIIScanHandler) UpdateConfig(c *gin.Context) {
+	var req models.PIIScanConfig
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	if err := h.piiScanService.SetConfig(c.Request.Context(), &req, c.GetUint("user_id")); err != nil {
+		h.response.BadRequest(c, err.Error())
+		return
+	}
+	h.response.Success(c, req, "敏感信息检测配置已更新")
+}
+
+// TestScan 用已保存或请求中的配置试运行检测，返回命中类型和脱敏结果，不保存任何内容
+func (h *PIIScanHandler) TestScan(c *gin.Context) {
+	var req models.PIIScanTestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	config := req.Config
+	if config != nil {
+		if err := config.Validate(); err != nil {
+			h.response.BadRequest(c, err.Error())
+			return
+		}
+	} else {
+		saved, err := h.piiScanService.GetConfig(c.Request.Context())
+		if err != nil {
+			h.response.InternalServerError(c, "获取敏感信息检测配置失败", err.Error())
+			return
+		}
+		config = saved
+	}
+	config.Enabled = true
+
+	h.response.Success(c, services.ScanPII(config, req.CategoryID, req.Text))
+}
+
+// ListRedactions 获取各工单的敏感信息检测记录，since 为 YYYY-MM-DD
+func (h *PIIScanHandler) ListRedactions(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	var since *time.Time
+	if value := c.Query("since"); value != "" {
+		t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			h.response.BadRequest(c, "since 需为 YYYY-MM-DD 格式")
+			return
+		}
+		since = &t
+	}
+
+	histories, total, err := h.piiScanService.ListRedactions(c.Request.Context(), since, page, pageSize)
+	if err != nil {
+		h.response.InternalServerError(c, "获取脱敏记录失败", err.Error())
+		return
+	}
+	h.response.List(c, histories, total, page, pageSize)
+}
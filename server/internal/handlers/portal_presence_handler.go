@@ -0,0 +1,47 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/services"
+)
+
+// PortalPresenceHandler 客户门户实时状态处理器
+type PortalPresenceHandler struct {
+	presenceService *services.PortalPresenceService
+	response        *middleware.ResponseHelper
+}
+
+// NewPortalPresenceHandler 创建客户门户实时状态处理器
+func NewPortalPresenceHandler(presenceService *services.PortalPresenceService) *PortalPresenceHandler {
+	return &PortalPresenceHandler{
+		presenceService: presenceService,
+		response:        middleware.NewResponseHelper(),
+	}
+}
+
+// IssueToken 为工单发起人签发门户实时连接令牌，令牌仅在短时间内可用于建立连接
+func (h *PortalPresenceHandler) IssueToken(c *gin.Context) {
+	ticketID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, "无效的工单ID")
+		return
+	}
+
+	token, err := h.presenceService.IssueToken(c.Request.Context(), uint(ticketID), c.GetUint("user_id"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrPresenceTicketNotFound), errors.Is(err, services.ErrPresenceForbidden):
+			// 非本人工单同样返回不存在，避免泄露工单编号
+			h.response.NotFound(c, "工单不存在")
+		default:
+			h.response.Error(c, http.StatusInternalServerError, "签发实时连接令牌失败", err.Error())
+		}
+		return
+	}
+	h.response.Success(c, token, "签发实时连接令牌成功")
+}
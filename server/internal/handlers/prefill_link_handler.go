@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// PrefillLinkHandler 建单预填链接处理器
+type PrefillLinkHandler struct {
+	linkService *services.PrefillLinkService
+	response    *middleware.ResponseHelper
+}
+
+// NewPrefillLinkHandler 创建预填链接处理器
+func NewPrefillLinkHandler(linkService *services.PrefillLinkService) *PrefillLinkHandler {
+	return &PrefillLinkHandler{
+		linkService: linkService,
+		response:    middleware.NewResponseHelper(),
+	}
+}
+
+// RegisterAdminRoutes 注册管理员路由
+func (h *PrefillLinkHandler) RegisterAdminRoutes(router *gin.RouterGroup) {
+	links := router.Group("/prefill-links")
+	{
+		links.GET("", h.ListLinks)
+		links.POST("", h.CreateLink)
+		links.GET("/:id", h.GetLink)
+		links.PUT("/:id", h.UpdateLink)
+		links.POST("/:id/revoke", h.RevokeLink)
+		links.DELETE("/:id", h.DeleteLink)
+	}
+}
+
+// ListLinks 获取预填链接及使用统计
+func (h *PrefillLinkHandler) ListLinks(c *gin.Context) {
+	links, err := h.linkService.List(context.Background())
+	if err != nil {
+		h.response.InternalServerError(c, "获取预填链接失败", err.Error())
+		return
+	}
+	h.response.Success(c, links)
+}
+
+// GetLink 获取预填链接详情
+func (h *PrefillLinkHandler) GetLink(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	link, err := h.linkService.Get(context.Background(), id)
+	if err != nil {
+		h.handleError(c, err, "获取预填链接失败")
+		return
+	}
+	h.response.Success(c, link)
+}
+
+// CreateLink 生成预填链接，返回签名令牌和建单页地址
+func (h *PrefillLinkHandler) CreateLink(c *gin.Context) {
+	var req models.PrefillLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数错误", err.Error())
+		return
+	}
+
+	link, err := h.linkService.Create(context.Background(), &req, c.GetUint("user_id"))
+	if err != nil {
+		h.handleError(c, err, "生成预填链接失败")
+		return
+	}
+	h.response.Created(c, link, "预填链接已生成")
+}
+
+// UpdateLink 更新预填链接，预填内容变化时返回新链接
+func (h *PrefillLinkHandler) UpdateLink(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	var req models.PrefillLinkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数错误", err.Error())
+		return
+	}
+
+	link, err := h.linkService.Update(context.Background(), id, &req)
+	if err != nil {
+		h.handleError(c, err, "更新预填链接失败")
+		return
+	}
+	h.response.Success(c, link, "预填链接已更新")
+}
+
+// RevokeLink 撤销预填链接
+func (h *PrefillLinkHandler) RevokeLink(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	link, err := h.linkService.Revoke(context.Background(), id)
+	if err != nil {
+		h.handleError(c, err, "撤销预填链接失败")
+		return
+	}
+	h.response.Success(c, link, "预填链接已撤销")
+}
+
+// DeleteLink 删除预填链接
+func (h *PrefillLinkHandler) DeleteLink(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.linkService.Delete(context.Background(), id); err != nil {
+		h.handleError(c, err, "删除预填链接失败")
+		return
+	}
+	h.response.Success(c, nil, "预填链接已删除")
+}
+
+// ExpandLink 校验预填令牌并返回建单表单默认值
+func (h *PrefillLinkHandler) ExpandLink(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		h.response.BadRequest(c, "缺少预填令牌")
+		return
+	}
+
+	expansion, err := h.linkService.Expand(c.Request.Context(), token)
+	if err != nil {
+		h.handleError(c, err, "展开预填链接失败")
+		return
+	}
+	h.response.Success(c, expansion)
+}
+
+func (h *PrefillLinkHandler) parseID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, "无效的ID")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func (h *PrefillLinkHandler) handleError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrPrefillLinkNotFound):
+		h.response.NotFound(c, "预填链接不存在")
+	case errors.Is(err, services.ErrInvalidPrefillLink):
+		h.response.BadRequest(c, "预填内容无效", err.Error())
+	case errors.Is(err, services.ErrInvalidPrefillToken):
+		h.response.BadRequest(c, "预填链接无效或已被修改")
+	case errors.Is(err, services.ErrPrefillLinkExpired):
+		h.response.Error(c, http.StatusGone, "预填链接已过期或已撤销")
+	default:
+		h.response.Error(c, http.StatusInternalServerError, message, err.Error())
+	}
+}
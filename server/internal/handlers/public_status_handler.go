@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// PublicStatusHandler 公开支持状态处理器
+type PublicStatusHandler struct {
+	statusService *services.PublicStatusService
+	response      *middleware.ResponseHelper
+}
+
+// NewPublicStatusHandler 创建公开支持状态处理器
+func NewPublicStatusHandler(statusService *services.PublicStatusService) *PublicStatusHandler {
+	return &PublicStatusHandler{
+		statusService: statusService,
+		response:      middleware.NewResponseHelper(),
+	}
+}
+
+// RegisterPublicRoutes 注册公开路由（无需登录）
+func (h *PublicStatusHandler) RegisterPublicRoutes(router *gin.RouterGroup) {
+	router.GET("", h.GetStatus)
+	router.GET("/badge.svg", h.GetBadge)
+}
+
+// GetStatus 获取汇总指标 JSON
+func (h *PublicStatusHandler) GetStatus(c *gin.Context) {
+	stats, err := h.statusService.Stats(c.Request.Context(), time.Now())
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	h.response.Success(c, stats)
+}
+
+// GetBadge 获取 SVG 徽章，metric 为 open_tickets（默认）或 first_response，label 可覆盖左侧文字
+func (h *PublicStatusHandler) GetBadge(c *gin.Context) {
+	metric := models.PublicStatusMetric(c.DefaultQuery("metric", string(models.PublicStatusMetricOpenTickets)))
+	if metric != models.PublicStatusMetricOpenTickets && metric != models.PublicStatusMetricFirstResponse {
+		h.response.BadRequest(c, "无效的指标")
+		return
+	}
+	customLabel := c.Query("label")
+	if len([]rune(customLabel)) > 40 {
+		h.response.BadRequest(c, "标签过长")
+		return
+	}
+
+	stats, err := h.statusService.Stats(c.Request.Context(), time.Now())
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	label, value, color := h.statusService.Badge(stats, metric)
+	if customLabel != "" {
+		label = customLabel
+	}
+	c.Data(http.StatusOK, "image/svg+xml; charset=utf-8", services.RenderBadgeSVG(label, value, color))
+}
+
+func (h *PublicStatusHandler) handleError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrPublicStatusDisabled) {
+		h.response.NotFound(c, "公开状态未开放")
+		return
+	}
+	h.response.Error(c, http.StatusInternalServerError, "获取支持状态失败", err.Error())
+}
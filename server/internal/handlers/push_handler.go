@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// PushHandler 浏览器推送订阅处理器
+type PushHandler struct {
+	pushService *services.PushNotificationService
+	response    *middleware.ResponseHelper
+}
+
+// NewPushHandler 创建浏览器推送订阅处理器
+func NewPushHandler(pushService *services.PushNotificationService) *PushHandler {
+	return &PushHandler{
+		pushService: pushService,
+		response:    middleware.NewResponseHelper(),
+	}
+}
+
+// RegisterRoutes 注册用户路由（需要认证）
+func (h *PushHandler) RegisterRoutes(router *gin.RouterGroup) {
+	push := router.Group("/push")
+	{
+		push.GET("/public-key", h.GetPublicKey)
+		push.GET("/subscriptions", h.ListSubscriptions)
+		push.POST("/subscriptions", h.Subscribe)
+		push.DELETE("/subscriptions", h.Unsubscribe)
+		push.POST("/test", h.SendTest)
+	}
+}
+
+// RegisterAdminRoutes 注册管理员路由
+func (h *PushHandler) RegisterAdminRoutes(router *gin.RouterGroup) {
+	router.POST("/push/vapid-keys", h.GenerateKeys)
+}
+
+// GetPublicKey 获取浏览器订阅使用的 VAPID 公钥
+func (h *PushHandler) GetPublicKey(c *gin.Context) {
+	publicKey, err := h.pushService.PublicKey()
+	if err != nil {
+		h.response.Success(c, gin.H{"enabled": false})
+		return
+	}
+	h.response.Success(c, gin.H{
+		"enabled":    true,
+		"public_key": publicKey,
+	})
+}
+
+// ListSubscriptions 当前用户已订阅推送的浏览器
+func (h *PushHandler) ListSubscriptions(c *gin.Context) {
+	subscriptions, err := h.pushService.ListSubscriptions(context.Background(), c.GetUint("user_id"))
+	if err != nil {
+		h.response.InternalServerError(c, "获取推送订阅失败", err.Error())
+		return
+	}
+	h.response.Success(c, subscriptions)
+}
+
+// Subscribe 注册浏览器推送订阅
+func (h *PushHandler) Subscribe(c *gin.Context) {
+	var req models.PushSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	subscription, err := h.pushService.Subscribe(context.Background(), c.GetUint("user_id"), &req, c.Request.UserAgent())
+	if err != nil {
+		h.handleError(c, err, "注册推送订阅失败")
+		return
+	}
+	h.response.Created(c, subscription, "已开启浏览器推送")
+}
+
+// Unsubscribe 取消浏览器推送订阅
+func (h *PushHandler) Unsubscribe(c *gin.Context) {
+	var req models.PushUnsubscribeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	if err := h.pushService.Unsubscribe(context.Background(), c.GetUint("user_id"), req.Endpoint); err != nil {
+		h.handleError(c, err, "取消推送订阅失败")
+		return
+	}
+	h.response.Success(c, nil, "已关闭浏览器推送")
+}
+
+// SendTest 向当前用户的浏览器发送测试推送
+func (h *PushHandler) SendTest(c *gin.Context) {
+	sent, err := h.pushService.SendTest(context.Background(), c.GetUint("user_id"))
+	if err != nil {
+		h.handleError(c, err, "发送测试推送失败")
+		return
+	}
+	h.response.Success(c, gin.H{"sent": sent}, "测试推送已发送")
+}
+
+// GenerateKeys 生成新的 VAPID 密钥对，已有订阅全部失效
+func (h *PushHandler) GenerateKeys(c *gin.Context) {
+	publicKey, removed, err := h.pushService.GenerateKeys(context.Background())
+	if err != nil {
+		h.response.InternalServerError(c, "生成VAPID密钥失败", err.Error())
+		return
+	}
+	h.response.Success(c, gin.H{
+		"public_key":            publicKey,
+		"removed_subscriptions": removed,
+	}, "VAPID密钥已更新")
+}
+
+func (h *PushHandler) handleError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrPushNotConfigured):
+		h.response.Error(c, http.StatusServiceUnavailable, "浏览器推送未启用")
+	case errors.Is(err, services.ErrPushSubscriptionNotFound):
+		h.response.NotFound(c, "推送订阅不存在")
+	case errors.Is(err, services.ErrInvalidPushSubscription):
+		h.response.BadRequest(c, err.Error())
+	default:
+		h.response.InternalServerError(c, message, err.Error())
+	}
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// QuotaHandler 套餐配额处理器
+type QuotaHandler struct {
+	quotaService *services.QuotaService
+	response     *middleware.ResponseHelper
+}
+
+// NewQuotaHandler 创建套餐配额处理器
+func NewQuotaHandler(quotaService *services.QuotaService) *QuotaHandler {
+	return &QuotaHandler{
+		quotaService: quotaService,
+		response:     middleware.NewResponseHelper(),
+	}
+}
+
+// RegisterAdminRoutes 注册管理员路由
+func (h *QuotaHandler) RegisterAdminRoutes(router *gin.RouterGroup) {
+	quota := router.Group("/quota")
+	{
+		quota.GET("", h.GetUsage)
+		quota.GET("/config", h.GetPolicy)
+		quota.PUT("/config", h.UpdatePolicy)
+	}
+}
+
+// GetUsage 获取各项配额的用量
+func (h *QuotaHandler) GetUsage(c *gin.Context) {
+	usage, err := h.quotaService.GetUsage(c.Request.Context(), time.Now())
+	if err != nil {
+		h.response.InternalServerError(c, "获取配额用量失败", err.Error())
+		return
+	}
+	h.response.Success(c, usage)
+}
+
+// GetPolicy 获取配额定义
+func (h *QuotaHandler) GetPolicy(c *gin.Context) {
+	policy, err := h.quotaService.GetPolicy(c.Request.Context())
+	if err != nil {
+		h.response.InternalServerError(c, "获取配额定义失败", err.Error())
+		return
+	}
+	h.response.Success(c, policy)
+}
+
+// UpdatePolicy 更新配额定义
+func (h *QuotaHandler) UpdatePolicy(c *gin.Context) {
+	var req models.QuotaPolicy
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	if err := h.quotaService.SetPolicy(c.Request.Context(), &req, c.GetUint("user_id")); err != nil {
+		h.response.BadRequest(c, err.Error())
+		return
+	}
+	h.response.Success(c, req, "配额定义已更新")
+}
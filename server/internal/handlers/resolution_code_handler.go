@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// ResolutionCodeHandler 解决代码处理器
+type ResolutionCodeHandler struct {
+	resolutionCodeService *services.ResolutionCodeService
+	response              *middleware.ResponseHelper
+}
+
+// NewResolutionCodeHandler 创建解决代码处理器
+func NewResolutionCodeHandler(resolutionCodeService *services.ResolutionCodeService) *ResolutionCodeHandler {
+	return &ResolutionCodeHandler{
+		resolutionCodeService: resolutionCodeService,
+		response:              middleware.NewResponseHelper(),
+	}
+}
+
+// RegisterAdminRoutes 注册管理员路由
+func (h *ResolutionCodeHandler) RegisterAdminRoutes(router *gin.RouterGroup) {
+	router.GET("/resolution-codes", h.GetConfig)
+	router.PUT("/resolution-codes", h.UpdateConfig)
+}
+
+// GetConfig 获取完整解决代码列表（含已停用）及是否必填
+func (h *ResolutionCodeHandler) GetConfig(c *gin.Context) {
+	codes, err := h.resolutionCodeService.GetConfig(c.Request.Context())
+	if err != nil {
+		h.response.InternalServerError(c, "获取解决代码失败", err.Error())
+		return
+	}
+	h.response.Success(c, gin.H{
+		"codes":    codes.Codes,
+		"required": h.resolutionCodeService.RequireCode(),
+	})
+}
+
+// UpdateConfig 更新解决代码列表
+func (h *ResolutionCodeHandler) UpdateConfig(c *gin.Context) {
+	var req models.ResolutionCodeConfig
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	if err := h.resolutionCodeService.SetConfig(c.Request.Context(), &req, c.GetUint("user_id")); err != nil {
+		h.response.BadRequest(c, err.Error())
+		return
+	}
+	h.response.Success(c, req, "解决代码已更新")
+}
+
+// ListActiveCodes 获取解决工单时可选择的解决代码
+func (h *ResolutionCodeHandler) ListActiveCodes(c *gin.Context) {
+	codes, err := h.resolutionCodeService.GetConfig(c.Request.Context())
+	if err != nil {
+		h.response.InternalServerError(c, "获取解决代码失败", err.Error())
+		return
+	}
+	h.response.Success(c, gin.H{
+		"codes":    codes.ActiveCodes(),
+		"required": h.resolutionCodeService.RequireCode(),
+	})
+}
+
+// GetBreakdown 按解决代码统计区间内解决的工单，默认最近 30 天，最长一年
+func (h *ResolutionCodeHandler) GetBreakdown(c *gin.Context) {
+	now := time.Now()
+	end := now
+	start := now.AddDate(0, 0, -30)
+	if value := c.Query("start_date"); value != "" {
+		t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			h.response.BadRequest(c, "start_date 需为 YYYY-MM-DD 格式")
+			return
+		}
+		start = t
+	}
+	if value := c.Query("end_date"); value != "" {
+		t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			h.response.BadRequest(c, "end_date 需为 YYYY-MM-DD 格式")
+			return
+		}
+		end = t.AddDate(0, 0, 1)
+	}
+	if !end.After(start) || end.Sub(start) > 366*24*time.Hour {
+		h.response.BadRequest(c, "统计区间无效，最长一年")
+		return
+	}
+
+	rows, err := h.resolutionCodeService.Breakdown(c.Request.Context(), start, end)
+	if err != nil {
+		h.response.InternalServerError(c, "获取解决代码统计失败", err.Error())
+		return
+	}
+	h.response.Success(c, gin.H{
+		"start_date": start,
+		"end_date":   end,
+		"rows":       rows,
+	})
+}
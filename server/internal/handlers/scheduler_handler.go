@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// SchedulerHandler 定时任务调度管理处理器
+type SchedulerHandler struct {
+	schedulerService *services.SchedulerService
+	response         *middleware.ResponseHelper
+}
+
+// NewSchedulerHandler 创建定时任务调度管理处理器
+func NewSchedulerHandler(schedulerService *services.SchedulerService) *SchedulerHandler {
+	return &SchedulerHandler{
+		schedulerService: schedulerService,
+		response:         middleware.NewResponseHelper(),
+	}
+}
+
+// RegisterAdminRoutes 注册管理员路由
+func (h *SchedulerHandler) RegisterAdminRoutes(router *gin.RouterGroup) {
+	scheduler := router.Group("/scheduler")
+	scheduler.GET("/jobs", h.ListJobs)
+	scheduler.GET("/jobs/:job_id", h.GetJob)
+	scheduler.PUT("/jobs/:job_id", h.UpdateJob)
+	scheduler.DELETE("/jobs/:job_id/schedule", h.ResetJob)
+	scheduler.POST("/jobs/:job_id/run", h.RunJob)
+	scheduler.GET("/cron-preview", h.PreviewCron)
+}
+
+// ListJobs 获取全部定时任务的调度与执行状态
+func (h *SchedulerHandler) ListJobs(c *gin.Context) {
+	h.response.Success(c, h.schedulerService.ListJobs())
+}
+
+// GetJob 获取定时任务详情及接下来的执行时间
+func (h *SchedulerHandler) GetJob(c *gin.Context) {
+	preview, err := strconv.Atoi(c.DefaultQuery("preview", "5"))
+	if err != nil || preview < 0 {
+		h.response.BadRequest(c, "无效的preview参数")
+		return
+	}
+
+	job, err := h.schedulerService.GetJob(c.Param("job_id"), preview)
+	if err != nil {
+		h.handleError(c, err, "获取定时任务失败")
+		return
+	}
+	h.response.Success(c, job)
+}
+
+// UpdateJob 修改定时任务的 cron 表达式、启用状态或超时时间，无需重新部署
+func (h *SchedulerHandler) UpdateJob(c *gin.Context) {
+	var req models.SchedulerJobUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	job, err := h.schedulerService.UpdateJobSchedule(c.Request.Context(), c.Param("job_id"), &req, c.GetUint("user_id"))
+	if err != nil {
+		h.handleError(c, err, "修改定时任务调度失败")
+		return
+	}
+	h.response.Success(c, job, "定时任务调度已更新")
+}
+
+// ResetJob 恢复定时任务的默认调度
+func (h *SchedulerHandler) ResetJob(c *gin.Context) {
+	job, err := h.schedulerService.ResetJobSchedule(c.Request.Context(), c.Param("job_id"), c.GetUint("user_id"))
+	if err != nil {
+		h.handleError(c, err, "恢复默认调度失败")
+		return
+	}
+	h.response.Success(c, job, "已恢复默认调度")
+}
+
+// RunJob 立即在后台执行定时任务，可覆盖任务参数
+func (h *SchedulerHandler) RunJob(c *gin.Context) {
+	var req models.SchedulerJobRunRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			h.response.BadRequest(c, "请求参数无效", err.Error())
+			return
+		}
+	}
+
+	job, err := h.schedulerService.RunJob(c.Param("job_id"), &req, time.Now())
+	if err != nil {
+		h.handleError(c, err, "执行定时任务失败")
+		return
+	}
+	c.JSON(http.StatusAccepted, middleware.StandardResponse{Code: 0, Msg: "定时任务已开始执行", Data: job})
+}
+
+// PreviewCron 预览 cron 表达式接下来的执行时间，保存前校验
+func (h *SchedulerHandler) PreviewCron(c *gin.Context) {
+	count, err := strconv.Atoi(c.DefaultQuery("count", "5"))
+	if err != nil || count < 1 {
+		h.response.BadRequest(c, "无效的count参数")
+		return
+	}
+
+	preview, err := h.schedulerService.PreviewCron(c.Query("cron_expr"), count, time.Now())
+	if err != nil {
+		h.handleError(c, err, "预览执行时间失败")
+		return
+	}
+	h.response.Success(c, preview)
+}
+
+func (h *SchedulerHandler) handleError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrSchedulerJobNotFound):
+		h.response.NotFound(c, "定时任务不存在")
+	case errors.Is(err, services.ErrSchedulerJobRunning):
+		h.response.Error(c, http.StatusConflict, "定时任务正在执行", nil)
+	case errors.Is(err, services.ErrInvalidSchedule), errors.Is(err, services.ErrInvalidJobParams):
+		h.response.BadRequest(c, err.Error())
+	default:
+		h.response.InternalServerError(c, message, err.Error())
+	}
+}
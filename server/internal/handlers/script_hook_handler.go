@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// ScriptHookHandler 脚本钩子管理处理器
+type ScriptHookHandler struct {
+	hookService *services.ScriptHookService
+	response    *middleware.ResponseHelper
+}
+
+// NewScriptHookHandler 创建脚本钩子处理器
+func NewScriptHookHandler(hookService *services.ScriptHookService) *ScriptHookHandler {
+	return &ScriptHookHandler{
+		hookService: hookService,
+		response:    middleware.NewResponseHelper(),
+	}
+}
+
+// RegisterAdminRoutes 注册管理员路由
+func (h *ScriptHookHandler) RegisterAdminRoutes(router *gin.RouterGroup) {
+	hooks := router.Group("/script-hooks")
+	{
+		hooks.GET("", h.ListHooks)
+		hooks.POST("", h.CreateHook)
+		hooks.POST("/test", h.TestHook)
+		hooks.GET("/:id", h.GetHook)
+		hooks.PUT("/:id", h.UpdateHook)
+		hooks.DELETE("/:id", h.DeleteHook)
+		hooks.GET("/:id/logs", h.ListLogs)
+	}
+}
+
+// ListHooks 获取已安装的脚本钩子，可按 event 过滤
+func (h *ScriptHookHandler) ListHooks(c *gin.Context) {
+	hooks, err := h.hookService.List(context.Background(), c.Query("event"))
+	if err != nil {
+		h.response.InternalServerError(c, "获取脚本钩子失败", err.Error())
+		return
+	}
+	h.response.Success(c, hooks)
+}
+
+// GetHook 获取脚本钩子详情
+func (h *ScriptHookHandler) GetHook(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	hook, err := h.hookService.Get(context.Background(), id)
+	if err != nil {
+		h.handleError(c, err, "获取脚本钩子失败")
+		return
+	}
+	h.response.Success(c, hook)
+}
+
+// CreateHook 安装脚本钩子，脚本有语法错误时返回 400
+func (h *ScriptHookHandler) CreateHook(c *gin.Context) {
+	var req models.ScriptHookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数错误", err.Error())
+		return
+	}
+
+	hook, err := h.hookService.Create(context.Background(), &req, c.GetUint("user_id"))
+	if err != nil {
+		h.handleError(c, err, "安装脚本钩子失败")
+		return
+	}
+	h.response.Created(c, hook, "脚本钩子已安装")
+}
+
+// UpdateHook 更新脚本、限制或启用状态
+func (h *ScriptHookHandler) UpdateHook(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	var req models.ScriptHookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数错误", err.Error())
+		return
+	}
+
+	hook, err := h.hookService.Update(context.Background(), id, &req, c.GetUint("user_id"))
+	if err != nil {
+		h.handleError(c, err, "更新脚本钩子失败")
+		return
+	}
+	h.response.Success(c, hook, "脚本钩子已更新")
+}
+
+// DeleteHook 卸载脚本钩子
+func (h *ScriptHookHandler) DeleteHook(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.hookService.Delete(context.Background(), id); err != nil {
+		h.handleError(c, err, "卸载脚本钩子失败")
+		return
+	}
+	h.response.Success(c, nil, "脚本钩子已卸载")
+}
+
+// TestHook 用示例数据试运行脚本，返回脚本调用的动作和输出
+func (h *ScriptHookHandler) TestHook(c *gin.Context) {
+	var req models.ScriptHookTestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数错误", err.Error())
+		return
+	}
+
+	result, err := h.hookService.Test(c.Request.Context(), &req)
+	if err != nil {
+		h.handleError(c, err, "试运行脚本失败")
+		return
+	}
+	h.response.Success(c, result)
+}
+
+// ListLogs 获取钩子的执行记录，可按 status 过滤
+func (h *ScriptHookHandler) ListLogs(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	logs, total, err := h.hookService.ListLogs(context.Background(), id, c.Query("status"), page, pageSize)
+	if err != nil {
+		h.handleError(c, err, "获取执行记录失败")
+		return
+	}
+	h.response.List(c, logs, total, page, pageSize, "获取执行记录成功")
+}
+
+func (h *ScriptHookHandler) parseID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, "无效的ID")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func (h *ScriptHookHandler) handleError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrScriptHookNotFound):
+		h.response.NotFound(c, "脚本钩子不存在")
+	case errors.Is(err, services.ErrInvalidScriptHook):
+		h.response.BadRequest(c, "脚本钩子无效", err.Error())
+	default:
+		h.response.Error(c, http.StatusInternalServerError, message, err.Error())
+	}
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// SessionPolicyHandler 按角色的会话策略管理处理器
+type SessionPolicyHandler struct {
+	policyService *services.SessionPolicyService
+	response      *middleware.ResponseHelper
+}
+
+// NewSessionPolicyHandler 创建会话策略处理器
+func NewSessionPolicyHandler(policyService *services.SessionPolicyService) *SessionPolicyHandler {
+	return &SessionPolicyHandler{
+		policyService: policyService,
+		response:      middleware.NewResponseHelper(),
+	}
+}
+
+// RegisterAdminRoutes 注册管理员路由
+func (h *SessionPolicyHandler) RegisterAdminRoutes(router *gin.RouterGroup) {
+	router.GET("/security/session-policy", h.GetPolicy)
+	router.PUT("/security/session-policy", h.UpdatePolicy)
+}
+
+// GetPolicy 获取按角色的会话策略
+func (h *SessionPolicyHandler) GetPolicy(c *gin.Context) {
+	policy, err := h.policyService.GetPolicy(c.Request.Context())
+	if err != nil {
+		h.response.InternalServerError(c, "获取会话策略失败", err.Error())
+		return
+	}
+	h.response.Success(c, policy)
+}
+
+// UpdatePolicy 更新按角色的会话策略，在下次登录或刷新令牌时生效
+func (h *SessionPolicyHandler) UpdatePolicy(c *gin.Context) {
+	var req models.SessionPolicyConfig
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	if err := h.policyService.SetPolicy(c.Request.Context(), &req, c.GetUint("user_id")); err != nil {
+		h.response.BadRequest(c, err.Error())
+		return
+	}
+	h.response.Success(c, req, "会话策略已更新")
+}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// SLAContractHandler 客户SLA合同处理器
+type SLAContractHandler struct {
+	contractService *services.SLAContractService
+	response        *middleware.ResponseHelper
+}
+
+// NewSLAContractHandler 创建客户SLA合同处理器
+func NewSLAContractHandler(contractService *services.SLAContractService) *SLAContractHandler {
+	return &SLAContractHandler{
+		contractService: contractService,
+		response:        middleware.NewResponseHelper(),
+	}
+}
+
+// RegisterAdminRoutes 注册管理员路由
+func (h *SLAContractHandler) RegisterAdminRoutes(router *gin.RouterGroup) {
+	contracts := router.Group("/sla-contracts")
+	{
+		contracts.GET("", h.ListContracts)
+		contracts.POST("", h.CreateContract)
+		contracts.GET("/:id", h.GetContract)
+		contracts.PUT("/:id", h.UpdateContract)
+		contracts.DELETE("/:id", h.DeleteContract)
+	}
+}
+
+// ListContracts 查询SLA合同
+func (h *SLAContractHandler) ListContracts(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	filter := &services.SLAContractFilter{
+		Company:  c.Query("company"),
+		Page:     page,
+		PageSize: pageSize,
+	}
+	if value := c.Query("is_active"); value != "" {
+		active := value == "true"
+		filter.IsActive = &active
+	}
+
+	contracts, total, err := h.contractService.List(context.Background(), filter)
+	if err != nil {
+		h.response.InternalServerError(c, "获取SLA合同失败", err.Error())
+		return
+	}
+	h.response.List(c, contracts, total, filter.Page, filter.PageSize, "获取SLA合同成功")
+}
+
+// CreateContract 创建SLA合同
+func (h *SLAContractHandler) CreateContract(c *gin.Context) {
+	var req models.SLAContractRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	contract, err := h.contractService.Create(context.Background(), &req, c.GetUint("user_id"))
+	if err != nil {
+		h.respondError(c, err, "创建SLA合同失败")
+		return
+	}
+	h.response.Created(c, contract, "SLA合同已创建")
+}
+
+// GetContract 获取SLA合同
+func (h *SLAContractHandler) GetContract(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+	contract, err := h.contractService.Get(context.Background(), id)
+	if err != nil {
+		h.respondError(c, err, "获取SLA合同失败")
+		return
+	}
+	h.response.Success(c, contract)
+}
+
+// UpdateContract 更新SLA合同
+func (h *SLAContractHandler) UpdateContract(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+	var req models.SLAContractRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	contract, err := h.contractService.Update(context.Background(), id, &req)
+	if err != nil {
+		h.respondError(c, err, "更新SLA合同失败")
+		return
+	}
+	h.response.Success(c, contract, "SLA合同已更新")
+}
+
+// DeleteContract 删除SLA合同
+func (h *SLAContractHandler) DeleteContract(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+	if err := h.contractService.Delete(context.Background(), id); err != nil {
+		h.respondError(c, err, "删除SLA合同失败")
+		return
+	}
+	h.response.Success(c, nil, "SLA合同已删除")
+}
+
+// GetComplianceReport 合同达成率报表，默认统计最近30天
+func (h *SLAContractHandler) GetComplianceReport(c *gin.Context) {
+	now := time.Now()
+	end := now
+	start := now.AddDate(0, 0, -30)
+	if value := c.Query("start_date"); value != "" {
+		t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			h.response.BadRequest(c, "start_date 需为 YYYY-MM-DD 格式")
+			return
+		}
+		start = t
+	}
+	if value := c.Query("end_date"); value != "" {
+		t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			h.response.BadRequest(c, "end_date 需为 YYYY-MM-DD 格式")
+			return
+		}
+		end = t.AddDate(0, 0, 1)
+	}
+	if !end.After(start) || end.Sub(start) > 366*24*time.Hour {
+		h.response.BadRequest(c, "统计区间无效，最长一年")
+		return
+	}
+
+	report, err := h.contractService.ComplianceReport(context.Background(), start, end, now)
+	if err != nil {
+		h.response.InternalServerError(c, "获取合同达成率失败", err.Error())
+		return
+	}
+	h.response.Success(c, gin.H{
+		"start_date": start,
+		"end_date":   end,
+		"contracts":  report,
+	})
+}
+
+func (h *SLAContractHandler) parseID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, "无效的SLA合同ID")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func (h *SLAContractHandler) respondError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrSLAContractNotFound):
+		h.response.NotFound(c, "SLA合同不存在")
+	case errors.Is(err, services.ErrInvalidSLAContract):
+		h.response.BadRequest(c, err.Error())
+	default:
+		h.response.InternalServerError(c, message, err.Error())
+	}
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/services"
+)
+
+// SLAReportHandler SLA达成率报表处理器
+type SLAReportHandler struct {
+	reportService *services.SLAReportService
+	response      *middleware.ResponseHelper
+}
+
+// NewSLAReportHandler 创建SLA达成率报表处理器
+func NewSLAReportHandler(reportService *services.SLAReportService) *SLAReportHandler {
+	return &SLAReportHandler{
+		reportService: reportService,
+		response:      middleware.NewResponseHelper(),
+	}
+}
+
+// GetComplianceReport 按分类、优先级、团队和月份统计响应与解决的SLA达成率，并与上一等长区间对比。
+// 默认最近30天，format=csv 时导出 CSV
+func (h *SLAReportHandler) GetComplianceReport(c *gin.Context) {
+	now := time.Now()
+	end := now
+	start := now.AddDate(0, 0, -30)
+	if value := c.Query("start_date"); value != "" {
+		t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			h.response.BadRequest(c, "start_date 需为 YYYY-MM-DD 格式")
+			return
+		}
+		start = t
+	}
+	if value := c.Query("end_date"); value != "" {
+		t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			h.response.BadRequest(c, "end_date 需为 YYYY-MM-DD 格式")
+			return
+		}
+		end = t.AddDate(0, 0, 1)
+	}
+	if !end.After(start) || end.Sub(start) > 366*24*time.Hour {
+		h.response.BadRequest(c, "统计区间无效，最长一年")
+		return
+	}
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		h.response.BadRequest(c, "format 只能为 json 或 csv")
+		return
+	}
+
+	report, err := h.reportService.ComplianceReport(c.Request.Context(), start, end, now)
+	if err != nil {
+		h.response.InternalServerError(c, "获取SLA达成率报表失败", err.Error())
+		return
+	}
+	if format == "json" {
+		h.response.Success(c, report)
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := h.reportService.WriteComplianceCSV(report, &buf); err != nil {
+		h.response.InternalServerError(c, "导出SLA达成率报表失败", err.Error())
+		return
+	}
+	filename := fmt.Sprintf("sla_compliance_%s_%s.csv", start.Format("20060102"), end.AddDate(0, 0, -1).Format("20060102"))
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// SLAWarningHandler SLA违约预警处理器
+type SLAWarningHandler struct {
+	warningService *services.SLAWarningService
+	response       *middleware.ResponseHelper
+}
+
+// NewSLAWarningHandler 创建SLA违约预警处理器
+func NewSLAWarningHandler(warningService *services.SLAWarningService) *SLAWarningHandler {
+	return &SLAWarningHandler{
+		warningService: warningService,
+		response:       middleware.NewResponseHelper(),
+	}
+}
+
+// GetAtRiskTickets 获取即将违约的工单（已越过预警阈值、尚未违约），按违约时间排序
+func (h *SLAWarningHandler) GetAtRiskTickets(c *gin.Context) {
+	var filter models.SLAAtRiskFilter
+	if c.Query("assigned_to_me") == "true" {
+		userID := c.GetUint("user_id")
+		filter.AssignedToID = &userID
+	}
+	if raw := c.Query("team_id"); raw != "" {
+		teamID, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			h.response.BadRequest(c, "无效的团队ID", err.Error())
+			return
+		}
+		id := uint(teamID)
+		filter.TeamID = &id
+	}
+	switch target := models.SLAWarningTarget(c.Query("target")); target {
+	case "", models.SLAWarningTargetResponse, models.SLAWarningTargetResolution:
+		filter.Target = target
+	default:
+		h.response.BadRequest(c, "无效的时限类型", "target must be response or resolution")
+		return
+	}
+
+	items, err := h.warningService.AtRiskTickets(c.Request.Context(), filter, time.Now())
+	if err != nil {
+		h.response.InternalServerError(c, "获取即将违约工单失败", err.Error())
+		return
+	}
+	h.response.Success(c, gin.H{"items": items, "total": len(items)}, "获取即将违约工单成功")
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/services"
+)
+
+// SMSHandler 短信发送统计处理器
+type SMSHandler struct {
+	smsService *services.SMSOTPService
+	response   *middleware.ResponseHelper
+}
+
+// NewSMSHandler 创建短信统计处理器
+func NewSMSHandler(smsService *services.SMSOTPService) *SMSHandler {
+	return &SMSHandler{
+		smsService: smsService,
+		response:   middleware.NewResponseHelper(),
+	}
+}
+
+// GetMetrics 统计区间内的短信发送量、送达率及费用，默认最近30天
+func (h *SMSHandler) GetMetrics(c *gin.Context) {
+	now := time.Now()
+	end := now
+	start := now.AddDate(0, 0, -30)
+	if value := c.Query("start_date"); value != "" {
+		t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			h.response.BadRequest(c, "start_date 需为 YYYY-MM-DD 格式")
+			return
+		}
+		start = t
+	}
+	if value := c.Query("end_date"); value != "" {
+		t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			h.response.BadRequest(c, "end_date 需为 YYYY-MM-DD 格式")
+			return
+		}
+		end = t.AddDate(0, 0, 1)
+	}
+	if !end.After(start) || end.Sub(start) > 366*24*time.Hour {
+		h.response.BadRequest(c, "统计区间无效，最长一年")
+		return
+	}
+
+	metrics, err := h.smsService.Metrics(c.Request.Context(), start, end)
+	if err != nil {
+		h.response.InternalServerError(c, "获取短信统计失败", err.Error())
+		return
+	}
+	h.response.Success(c, metrics)
+}
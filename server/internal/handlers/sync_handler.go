@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// syncGzipMinBytes 响应体超过该大小且客户端支持时使用 gzip 压缩
+const syncGzipMinBytes = 1024
+
+// SyncHandler 移动端增量同步处理器
+type SyncHandler struct {
+	syncService *services.SyncService
+	response    *middleware.ResponseHelper
+}
+
+// NewSyncHandler 创建增量同步处理器
+func NewSyncHandler(syncService *services.SyncService) *SyncHandler {
+	return &SyncHandler{
+		syncService: syncService,
+		response:    middleware.NewResponseHelper(),
+	}
+}
+
+// Sync 返回自游标以来变更的工单、评论、通知及需删除的记录。
+// 内容未变化且 If-None-Match 匹配时返回 304，客户端继续使用原游标
+func (h *SyncHandler) Sync(c *gin.Context) {
+	req := &services.SyncRequest{
+		Cursor: c.Query("cursor"),
+		Scope:  c.Query("scope"),
+	}
+	if since := c.Query("since"); since != "" {
+		if req.Cursor == "" {
+			if parsed, err := time.Parse(time.RFC3339, since); err == nil {
+				req.Since = &parsed
+			} else {
+				// since 也可直接传入上次返回的游标
+				req.Cursor = since
+			}
+		}
+	}
+	if limit := c.Query("limit"); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil {
+			h.response.BadRequest(c, "无效的limit参数")
+			return
+		}
+		req.Limit = parsed
+	}
+
+	result, err := h.syncService.Sync(c.Request.Context(), commentViewer(c), req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrSyncCursorExpired):
+			h.response.Error(c, http.StatusGone, "同步游标已过期，请重新全量同步")
+		case errors.Is(err, services.ErrInvalidSyncCursor):
+			h.response.BadRequest(c, "无效的同步游标", err.Error())
+		default:
+			h.response.InternalServerError(c, "同步失败", err.Error())
+		}
+		return
+	}
+
+	// ETag 只按变更内容计算，与游标和服务器时间无关
+	etag, err := syncETag(result)
+	if err != nil {
+		h.response.InternalServerError(c, "同步失败", err.Error())
+		return
+	}
+	c.Header("ETag", etag)
+	c.Header("Cache-Control", "private, no-cache")
+	c.Header("Vary", "Accept-Encoding")
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	body, err := json.Marshal(middleware.StandardResponse{Code: 0, Msg: "同步成功", Data: result})
+	if err != nil {
+		h.response.InternalServerError(c, "同步失败", err.Error())
+		return
+	}
+	if len(body) >= syncGzipMinBytes && strings.Contains(c.GetHeader("Accept-Encoding"), "gzip") {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(body); err == nil && zw.Close() == nil {
+			c.Header("Content-Encoding", "gzip")
+			body = buf.Bytes()
+		}
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
+
+func syncETag(result *models.SyncResponse) (string, error) {
+	content := *result
+	content.NextCursor = ""
+	content.ServerTime = time.Time{}
+	data, err := json.Marshal(content)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return `W/"` + hex.EncodeToString(sum[:16]) + `"`, nil
+}
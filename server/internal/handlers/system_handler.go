@@ -8,24 +8,100 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
+	"gongdan-system/internal/database"
+	"gongdan-system/internal/middleware"
 	"gongdan-system/internal/models"
 	"gongdan-system/internal/services"
 )
 
 // SystemHandler 系统配置处理器
 type SystemHandler struct {
-	db         *gorm.DB
-	cleanupSvc *services.CleanupService
+	db           *gorm.DB
+	cleanupSvc   *services.CleanupService
+	autoCloseSvc *services.AutoCloseService
+	agingSvc     *services.BacklogAgingService
+	surveySvc    *services.TicketSurveyService
+	proposalSvc  *services.TicketChangeProposalService
+	fieldPermSvc *services.TicketFieldPermissionService
+	transferSvc  *services.CategoryTransferService
+	calendarSvc  *services.BusinessCalendarService
+	matrixSvc    *services.PriorityMatrixService
+
+	httpSecuritySvc    *services.HTTPSecurityService
+	reloadHTTPSecurity func(ctx context.Context) error
+
+	maintenanceSvc *services.MaintenanceService
+	searchSvc      *services.SearchConfigService
+	jsonStorageSvc *services.JSONStorageService
+	auditForwarder *services.AuditForwarder
+
+	concurrencyLimiter *services.ConcurrencyLimiter
+	paginationGuard    *services.PaginationGuard
+	consistencySvc     *services.ConsistencyService
+
+	redis            *database.ResilientRedis
+	redisRateLimiter *middleware.DistributedRateLimiter
+	redisCache       *services.ReadThroughCache
 }
 
 // NewSystemHandler 创建系统配置处理器
 func NewSystemHandler(db *gorm.DB) *SystemHandler {
 	return &SystemHandler{
-		db:         db,
-		cleanupSvc: services.NewCleanupService(db),
+		db:           db,
+		cleanupSvc:   services.NewCleanupService(db),
+		autoCloseSvc: services.NewAutoCloseService(db),
+		agingSvc:     services.NewBacklogAgingService(db),
+		surveySvc:    services.NewTicketSurveyService(db),
+		proposalSvc:  services.NewTicketChangeProposalService(db),
+		fieldPermSvc: services.NewTicketFieldPermissionService(db),
+		transferSvc:  services.NewCategoryTransferService(db),
+		calendarSvc:  services.NewBusinessCalendarService(db),
+		matrixSvc:    services.NewPriorityMatrixService(db),
+
+		httpSecuritySvc: services.NewHTTPSecurityService(db, nil),
+		maintenanceSvc:  services.NewMaintenanceService(db),
+		searchSvc:       services.NewSearchConfigService(db),
+		jsonStorageSvc:  services.NewJSONStorageService(db),
+
+		concurrencyLimiter: services.NewConcurrencyLimiter(db),
+		paginationGuard:    services.NewPaginationGuard(db),
+		consistencySvc:     services.NewConsistencyService(db),
 	}
 }
 
+// SetHTTPSecurity 设置HTTP安全策略服务及热更新回调
+func (h *SystemHandler) SetHTTPSecurity(svc *services.HTTPSecurityService, reload func(ctx context.Context) error) {
+	h.httpSecuritySvc = svc
+	h.reloadHTTPSecurity = reload
+}
+
+// SetAuditForwarder 设置审计事件转发器，与认证及审计服务共享同一实例
+func (h *SystemHandler) SetAuditForwarder(forwarder *services.AuditForwarder) {
+	h.auditForwarder = forwarder
+}
+
+// SetMaintenanceService 设置维护模式服务，与中间件共享同一实例以便修改后立即生效
+func (h *SystemHandler) SetMaintenanceService(svc *services.MaintenanceService) {
+	h.maintenanceSvc = svc
+}
+
+// SetConcurrencyLimiter 设置并发限流器，与限流中间件共享同一实例以便读取运行指标
+func (h *SystemHandler) SetConcurrencyLimiter(limiter *services.ConcurrencyLimiter) {
+	h.concurrencyLimiter = limiter
+}
+
+// SetPaginationGuard 设置分页守卫，与分页中间件共享同一实例以便修改后立即生效
+func (h *SystemHandler) SetPaginationGuard(guard *services.PaginationGuard) {
+	h.paginationGuard = guard
+}
+
+// SetRedis 设置 Redis 客户端及依赖它的限流器、缓存，用于报告熔断状态和降级情况
+func (h *SystemHandler) SetRedis(redis *database.ResilientRedis, limiter *middleware.DistributedRateLimiter, cache *services.ReadThroughCache) {
+	h.redis = redis
+	h.redisRateLimiter = limiter
+	h.redisCache = cache
+}
+
 // RegisterRoutes 注册路由
 func (h *SystemHandler) RegisterRoutes(router *gin.RouterGroup) {
 	// 系统配置相关路由 - 仅管理员可访问
@@ -47,6 +123,73 @@ func (h *SystemHandler) RegisterRoutes(router *gin.RouterGroup) {
 		system.POST("/cleanup/execute-all", h.ExecuteAllCleanup)
 		system.GET("/cleanup/logs", h.GetCleanupLogs)
 		system.GET("/cleanup/stats", h.GetCleanupStats)
+
+		// 已解决工单自动关闭
+		system.GET("/auto-close/config", h.GetAutoClosePolicy)
+		system.PUT("/auto-close/config", h.UpdateAutoClosePolicy)
+		system.POST("/auto-close/execute", h.ExecuteAutoClose)
+		system.GET("/auto-close/stats", h.GetAutoCloseStats)
+
+		// 停滞工单提醒与升级
+		system.GET("/stale-tickets/config", h.GetStaleTicketPolicy)
+		system.PUT("/stale-tickets/config", h.UpdateStaleTicketPolicy)
+		system.POST("/stale-tickets/execute", h.ExecuteStaleTicketNudges)
+
+		// 满意度调查（低分阈值）
+		system.GET("/survey/config", h.GetSurveyPolicy)
+		system.PUT("/survey/config", h.UpdateSurveyPolicy)
+
+		// 工单变更提案（编辑需审核）
+		system.GET("/change-proposals/config", h.GetChangeProposalPolicy)
+		system.PUT("/change-proposals/config", h.UpdateChangeProposalPolicy)
+
+		// 工单字段编辑权限（按角色）
+		system.GET("/ticket-field-permissions/config", h.GetTicketFieldPermissionPolicy)
+		system.PUT("/ticket-field-permissions/config", h.UpdateTicketFieldPermissionPolicy)
+
+		// 工单转移分类（SLA计时与自动分配）
+		system.GET("/category-transfer/config", h.GetCategoryTransferPolicy)
+		system.PUT("/category-transfer/config", h.UpdateCategoryTransferPolicy)
+
+		// 营业日历（工作时间与节假日）
+		system.GET("/business-calendar", h.GetBusinessCalendar)
+		system.PUT("/business-calendar", h.UpdateBusinessCalendar)
+
+		// 影响×紧急程度优先级矩阵
+		system.GET("/priority-matrix", h.GetPriorityMatrix)
+		system.PUT("/priority-matrix", h.UpdatePriorityMatrix)
+
+		// CORS及安全响应头策略
+		system.GET("/http-security", h.GetHTTPSecurityPolicy)
+		system.PUT("/http-security", h.UpdateHTTPSecurityPolicy)
+
+		// 只读维护模式
+		system.GET("/maintenance", h.GetMaintenanceMode)
+		system.PUT("/maintenance", h.UpdateMaintenanceMode)
+		system.GET("/search-language", h.GetSearchLanguageConfig)
+		system.PUT("/search-language", h.UpdateSearchLanguageConfig)
+		system.POST("/search-language/reindex", h.MigrateSearchIndexes)
+		system.GET("/json-storage", h.GetJSONStorageStatus)
+		system.POST("/json-storage/migrate", h.MigrateJSONStorage)
+		system.GET("/audit-forwarding", h.GetAuditForwarding)
+		system.PUT("/audit-forwarding", h.UpdateAuditForwarding)
+		system.POST("/audit-forwarding/test", h.TestAuditForwarding)
+
+		// 高开销接口并发限流
+		system.GET("/concurrency-limits", h.GetConcurrencyLimits)
+		system.PUT("/concurrency-limits", h.UpdateConcurrencyLimits)
+		system.GET("/concurrency-limits/metrics", h.GetConcurrencyMetrics)
+
+		// 列表接口分页限制
+		system.GET("/pagination-limits", h.GetPaginationLimits)
+		system.PUT("/pagination-limits", h.UpdatePaginationLimits)
+
+		// Redis 熔断状态及降级统计
+		system.GET("/redis", h.GetRedisHealth)
+
+		// 数据一致性检查（冗余计数与孤立记录）
+		system.GET("/consistency", h.CheckConsistency)
+		system.POST("/consistency/repair", h.RepairConsistency)
 	}
 }
 
@@ -149,6 +292,7 @@ func (h *SystemHandler) CreateConfig(c *gin.Context) {
 		})
 		return
 	}
+	services.InvalidateConfigCache(c.Request.Context())
 
 	c.JSON(http.StatusCreated, gin.H{
 		"success": true,
@@ -224,6 +368,7 @@ func (h *SystemHandler) UpdateConfig(c *gin.Context) {
 		})
 		return
 	}
+	services.InvalidateConfigCache(c.Request.Context())
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -268,6 +413,7 @@ func (h *SystemHandler) DeleteConfig(c *gin.Context) {
 		})
 		return
 	}
+	services.InvalidateConfigCache(c.Request.Context())
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -490,4 +636,1042 @@ func (h *SystemHandler) GetCleanupStats(c *gin.Context) {
 		"success": true,
 		"data":    stats,
 	})
-}
\ No newline at end of file
+}
+
+// GetAutoClosePolicy 获取自动关闭策略
+func (h *SystemHandler) GetAutoClosePolicy(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	policy, err := h.autoCloseSvc.GetPolicy(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed_to_get_auto_close_policy",
+			"message": "Failed to retrieve auto close policy",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    policy,
+	})
+}
+
+// UpdateAutoClosePolicy 更新自动关闭策略
+func (h *SystemHandler) UpdateAutoClosePolicy(c *gin.Context) {
+	var req models.AutoClosePolicy
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "unauthorized",
+			"message": "User not authenticated",
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := h.autoCloseSvc.SetPolicy(ctx, &req, userID.(uint)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "failed_to_update_auto_close_policy",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Auto close policy updated successfully",
+		"data":    req,
+	})
+}
+
+// ExecuteAutoClose 手动执行一次自动关闭检查
+func (h *SystemHandler) ExecuteAutoClose(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Second)
+	defer cancel()
+
+	result, err := h.autoCloseSvc.ProcessStaleResolvedTickets(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed_to_execute_auto_close",
+			"message": err.Error(),
+			"data":    result,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+	})
+}
+
+// GetStaleTicketPolicy 获取停滞工单提醒策略
+func (h *SystemHandler) GetStaleTicketPolicy(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	policy, err := h.agingSvc.GetPolicy(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed_to_get_stale_ticket_policy",
+			"message": "Failed to retrieve stale ticket policy",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    policy,
+	})
+}
+
+// UpdateStaleTicketPolicy 更新停滞工单提醒策略
+func (h *SystemHandler) UpdateStaleTicketPolicy(c *gin.Context) {
+	var req models.StaleTicketPolicy
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := h.agingSvc.SetPolicy(ctx, &req, c.GetUint("user_id")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "failed_to_update_stale_ticket_policy",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Stale ticket policy updated successfully",
+		"data":    req,
+	})
+}
+
+// ExecuteStaleTicketNudges 手动执行一次停滞工单提醒检查
+func (h *SystemHandler) ExecuteStaleTicketNudges(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Second)
+	defer cancel()
+
+	result, err := h.agingSvc.ProcessStaleTickets(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed_to_execute_stale_ticket_nudges",
+			"message": err.Error(),
+			"data":    result,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    result,
+	})
+}
+
+// GetSurveyPolicy 获取满意度调查策略
+func (h *SystemHandler) GetSurveyPolicy(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	policy, err := h.surveySvc.GetPolicy(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed_to_get_survey_policy",
+			"message": "Failed to retrieve survey policy",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    policy,
+	})
+}
+
+// UpdateSurveyPolicy 更新满意度调查策略
+func (h *SystemHandler) UpdateSurveyPolicy(c *gin.Context) {
+	var req models.SurveyPolicy
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := h.surveySvc.SetPolicy(ctx, &req, c.GetUint("user_id")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "failed_to_update_survey_policy",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Survey policy updated successfully",
+		"data":    req,
+	})
+}
+
+// GetChangeProposalPolicy 获取工单变更提案策略
+func (h *SystemHandler) GetChangeProposalPolicy(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	policy, err := h.proposalSvc.GetPolicy(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed_to_get_change_proposal_policy",
+			"message": "Failed to retrieve change proposal policy",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    policy,
+	})
+}
+
+// UpdateChangeProposalPolicy 更新工单变更提案策略
+func (h *SystemHandler) UpdateChangeProposalPolicy(c *gin.Context) {
+	var req models.ChangeProposalPolicy
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := h.proposalSvc.SetPolicy(ctx, &req, c.GetUint("user_id")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "failed_to_update_change_proposal_policy",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Change proposal policy updated successfully",
+		"data":    req,
+	})
+}
+
+// GetTicketFieldPermissionPolicy 获取工单字段编辑权限策略
+func (h *SystemHandler) GetTicketFieldPermissionPolicy(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	policy, err := h.fieldPermSvc.GetPolicy(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed_to_get_field_permission_policy",
+			"message": "Failed to retrieve ticket field permission policy",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    gin.H{"policy": policy, "fields": models.TicketRestrictableFields},
+	})
+}
+
+// UpdateTicketFieldPermissionPolicy 更新工单字段编辑权限策略
+func (h *SystemHandler) UpdateTicketFieldPermissionPolicy(c *gin.Context) {
+	var req models.TicketFieldPermissionPolicy
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := h.fieldPermSvc.SetPolicy(ctx, &req, c.GetUint("user_id")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "failed_to_update_field_permission_policy",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Ticket field permission policy updated successfully",
+		"data":    req,
+	})
+}
+
+// GetCategoryTransferPolicy 获取工单转移分类策略
+func (h *SystemHandler) GetCategoryTransferPolicy(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	policy, err := h.transferSvc.GetPolicy(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed_to_get_category_transfer_policy",
+			"message": "Failed to retrieve category transfer policy",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    policy,
+	})
+}
+
+// UpdateCategoryTransferPolicy 更新工单转移分类策略
+func (h *SystemHandler) UpdateCategoryTransferPolicy(c *gin.Context) {
+	var req models.CategoryTransferPolicy
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := h.transferSvc.SetPolicy(ctx, &req, c.GetUint("user_id")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "failed_to_update_category_transfer_policy",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Category transfer policy updated successfully",
+		"data":    req,
+	})
+}
+
+// GetBusinessCalendar 获取营业日历配置
+func (h *SystemHandler) GetBusinessCalendar(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	calendar, err := h.calendarSvc.GetCalendar(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed_to_get_business_calendar",
+			"message": "Failed to retrieve business calendar",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    calendar,
+	})
+}
+
+// UpdateBusinessCalendar 更新营业日历配置
+func (h *SystemHandler) UpdateBusinessCalendar(c *gin.Context) {
+	var req models.BusinessCalendar
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := h.calendarSvc.SetCalendar(ctx, &req, c.GetUint("user_id")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "failed_to_update_business_calendar",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Business calendar updated successfully",
+		"data":    req,
+	})
+}
+
+// GetConcurrencyLimits 获取高开销接口并发限流配置
+func (h *SystemHandler) GetConcurrencyLimits(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	policy, err := h.concurrencyLimiter.GetPolicy(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed_to_get_concurrency_limits",
+			"message": "Failed to retrieve concurrency limits",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    policy,
+	})
+}
+
+// UpdateConcurrencyLimits 更新高开销接口并发限流配置
+func (h *SystemHandler) UpdateConcurrencyLimits(c *gin.Context) {
+	var req models.ConcurrencyLimitPolicy
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := h.concurrencyLimiter.SetPolicy(ctx, &req, c.GetUint("user_id")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "failed_to_update_concurrency_limits",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Concurrency limits updated successfully",
+		"data":    req,
+	})
+}
+
+// GetConcurrencyMetrics 获取各限流分组的当前并发、排队及拒绝统计
+func (h *SystemHandler) GetConcurrencyMetrics(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    h.concurrencyLimiter.Metrics(ctx),
+	})
+}
+
+// GetPaginationLimits 获取列表接口分页限制
+func (h *SystemHandler) GetPaginationLimits(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	policy, err := h.paginationGuard.GetPolicy(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed_to_get_pagination_limits",
+			"message": "Failed to retrieve pagination limits",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    policy,
+	})
+}
+
+// UpdatePaginationLimits 更新列表接口分页限制
+func (h *SystemHandler) UpdatePaginationLimits(c *gin.Context) {
+	var req models.PaginationPolicy
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := h.paginationGuard.SetPolicy(ctx, &req, c.GetUint("user_id")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "failed_to_update_pagination_limits",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Pagination limits updated successfully",
+		"data":    req,
+	})
+}
+
+// GetRedisHealth 获取 Redis 熔断器状态、限流降级次数及缓存命中统计
+func (h *SystemHandler) GetRedisHealth(c *gin.Context) {
+	if h.redis == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": true,
+			"data":    gin.H{"enabled": false},
+		})
+		return
+	}
+
+	data := gin.H{
+		"enabled": true,
+		"breaker": h.redis.Stats(),
+	}
+	if h.redisRateLimiter != nil {
+		data["rate_limit"] = gin.H{
+			"degraded":  h.redisRateLimiter.Degraded(),
+			"fallbacks": h.redisRateLimiter.Fallbacks(),
+		}
+	}
+	if h.redisCache != nil {
+		data["cache"] = h.redisCache.Stats()
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    data,
+	})
+}
+
+// GetAutoCloseStats 获取自动关闭统计（默认最近30天）
+func (h *SystemHandler) GetAutoCloseStats(c *gin.Context) {
+	days := 30
+	if daysStr := c.Query("days"); daysStr != "" {
+		if d, err := strconv.Atoi(daysStr); err == nil && d > 0 && d <= 365 {
+			days = d
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	stats, err := h.autoCloseSvc.GetStats(ctx, time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed_to_get_auto_close_stats",
+			"message": "Failed to retrieve auto close statistics",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    stats,
+	})
+}
+
+// GetPriorityMatrix 获取优先级矩阵
+func (h *SystemHandler) GetPriorityMatrix(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	matrix, err := h.matrixSvc.GetMatrix(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed_to_get_priority_matrix",
+			"message": "Failed to retrieve priority matrix",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    matrix,
+	})
+}
+
+// UpdatePriorityMatrix 更新优先级矩阵
+func (h *SystemHandler) UpdatePriorityMatrix(c *gin.Context) {
+	var req models.PriorityMatrix
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "unauthorized",
+			"message": "User not authenticated",
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := h.matrixSvc.SetMatrix(ctx, &req, userID.(uint)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "failed_to_update_priority_matrix",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Priority matrix updated successfully",
+		"data":    req,
+	})
+}
+
+// GetHTTPSecurityPolicy 获取CORS及安全响应头策略
+func (h *SystemHandler) GetHTTPSecurityPolicy(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	policy, err := h.httpSecuritySvc.GetPolicy(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed_to_get_http_security_policy",
+			"message": "Failed to retrieve http security policy",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    policy,
+	})
+}
+
+// UpdateHTTPSecurityPolicy 更新CORS及安全响应头策略，保存后立即生效
+func (h *SystemHandler) UpdateHTTPSecurityPolicy(c *gin.Context) {
+	var req models.HTTPSecurityPolicy
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "unauthorized",
+			"message": "User not authenticated",
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := h.httpSecuritySvc.SetPolicy(ctx, &req, userID.(uint)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "failed_to_update_http_security_policy",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if h.reloadHTTPSecurity != nil {
+		if err := h.reloadHTTPSecurity(ctx); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "failed_to_reload_http_security_policy",
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "HTTP security policy updated successfully",
+		"data":    req,
+	})
+}
+
+// GetMaintenanceMode 获取只读维护模式配置及当前生效状态
+func (h *SystemHandler) GetMaintenanceMode(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	mode, err := h.maintenanceSvc.GetMode(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed_to_get_maintenance_mode",
+			"message": "Failed to retrieve maintenance mode",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    mode,
+		"active":  mode.IsActive(time.Now()),
+	})
+}
+
+// UpdateMaintenanceMode 开启、预约或关闭只读维护模式
+func (h *SystemHandler) UpdateMaintenanceMode(c *gin.Context) {
+	var req models.MaintenanceMode
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "unauthorized",
+			"message": "User not authenticated",
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := h.maintenanceSvc.SetMode(ctx, &req, userID.(uint)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "failed_to_update_maintenance_mode",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Maintenance mode updated successfully",
+		"data":    req,
+		"active":  req.IsActive(time.Now()),
+	})
+}
+
+// GetSearchLanguageConfig 获取全文搜索语言配置、可用分词配置及现有索引
+func (h *SystemHandler) GetSearchLanguageConfig(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	status, err := h.searchSvc.GetStatus(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed_to_get_search_language_config",
+			"message": "Failed to retrieve search language config",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    status,
+	})
+}
+
+// UpdateSearchLanguageConfig 更新全文搜索语言配置
+func (h *SystemHandler) UpdateSearchLanguageConfig(c *gin.Context) {
+	var req models.SearchLanguageConfig
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "unauthorized",
+			"message": "User not authenticated",
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := h.searchSvc.SetConfig(ctx, &req, userID.(uint)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "failed_to_update_search_language_config",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Search language config updated successfully, run reindex to rebuild indexes",
+		"data":    req,
+	})
+}
+
+// MigrateSearchIndexes 按当前语言配置重建全文索引
+func (h *SystemHandler) MigrateSearchIndexes(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+
+	executed, err := h.searchSvc.MigrateIndexes(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":    "failed_to_migrate_search_indexes",
+			"message":  err.Error(),
+			"executed": executed,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"message":  "Search indexes migrated successfully",
+		"executed": executed,
+	})
+}
+
+// GetJSONStorageStatus 获取 JSON 列存储方式、列实际类型及待执行的迁移语句
+func (h *SystemHandler) GetJSONStorageStatus(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	status, err := h.jsonStorageSvc.GetStatus(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed_to_get_json_storage_status",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    status,
+	})
+}
+
+// MigrateJSONStorage 将 JSON 列转换为 DB_JSON_STORAGE 配置的存储方式并维护 GIN 索引
+func (h *SystemHandler) MigrateJSONStorage(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	executed, err := h.jsonStorageSvc.Migrate(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed_to_migrate_json_storage",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":  true,
+		"message":  "JSON column storage migrated successfully",
+		"executed": executed,
+	})
+}
+
+// GetAuditForwarding 获取审计事件转发配置及转发状态，令牌不返回明文
+func (h *SystemHandler) GetAuditForwarding(c *gin.Context) {
+	if h.auditForwarder == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "audit_forwarder_unavailable",
+			"message": "Audit forwarder is not initialized",
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	config, err := h.auditForwarder.GetConfig(ctx)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed_to_get_audit_forwarding",
+			"message": "Failed to retrieve audit forwarding config",
+		})
+		return
+	}
+
+	tokenSet := config.Token != ""
+	config.Token = ""
+
+	c.JSON(http.StatusOK, gin.H{
+		"success":   true,
+		"data":      config,
+		"token_set": tokenSet,
+		"stats":     h.auditForwarder.Stats(),
+	})
+}
+
+// UpdateAuditForwarding 更新审计事件转发配置，token 留空时保留原令牌
+func (h *SystemHandler) UpdateAuditForwarding(c *gin.Context) {
+	if h.auditForwarder == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "audit_forwarder_unavailable",
+			"message": "Audit forwarder is not initialized",
+		})
+		return
+	}
+
+	req := models.GetDefaultAuditForwardingConfig()
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":   "unauthorized",
+			"message": "User not authenticated",
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := h.auditForwarder.SetConfig(ctx, req, userID.(uint)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "failed_to_update_audit_forwarding",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	req.Token = ""
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Audit forwarding config updated successfully",
+		"data":    req,
+	})
+}
+
+// TestAuditForwarding 使用请求中的配置向收集器发送一条测试事件
+func (h *SystemHandler) TestAuditForwarding(c *gin.Context) {
+	if h.auditForwarder == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "audit_forwarder_unavailable",
+			"message": "Audit forwarder is not initialized",
+		})
+		return
+	}
+
+	req := models.GetDefaultAuditForwardingConfig()
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "invalid_request",
+			"message": "Invalid request body",
+			"details": err.Error(),
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	if req.Token == "" {
+		if current, err := h.auditForwarder.GetConfig(ctx); err == nil {
+			req.Token = current.Token
+		}
+	}
+
+	if err := h.auditForwarder.SendTest(ctx, req); err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":   "audit_forwarding_test_failed",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Test audit event delivered",
+	})
+}
+
+// CheckConsistency 检查冗余计数字段及孤立记录，只报告不修改
+func (h *SystemHandler) CheckConsistency(c *gin.Context) {
+	h.verifyConsistency(c, false)
+}
+
+// RepairConsistency 检查并修复冗余计数字段，删除孤立记录
+func (h *SystemHandler) RepairConsistency(c *gin.Context) {
+	h.verifyConsistency(c, true)
+}
+
+// verifyConsistency 执行一致性检查并返回报告
+func (h *SystemHandler) verifyConsistency(c *gin.Context, repair bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Second)
+	defer cancel()
+
+	report, err := h.consistencySvc.Verify(ctx, repair)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "failed_to_verify_consistency",
+			"message": err.Error(),
+			"data":    report,
+		})
+		return
+	}
+	if repair {
+		services.LogConsistencyReport(report)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    report,
+	})
+}
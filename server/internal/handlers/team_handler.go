@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// TeamHandler 团队及团队队列处理器
+type TeamHandler struct {
+	teamService *services.TeamService
+	response    *middleware.ResponseHelper
+}
+
+// NewTeamHandler 创建团队处理器
+func NewTeamHandler(teamService *services.TeamService) *TeamHandler {
+	return &TeamHandler{
+		teamService: teamService,
+		response:    middleware.NewResponseHelper(),
+	}
+}
+
+// RegisterAdminRoutes 注册团队管理路由（管理员）
+func (h *TeamHandler) RegisterAdminRoutes(router *gin.RouterGroup) {
+	teams := router.Group("/teams")
+	{
+		teams.GET("", h.ListTeams)
+		teams.POST("", h.CreateTeam)
+		teams.GET("/:id", h.GetTeam)
+		teams.PUT("/:id", h.UpdateTeam)
+		teams.DELETE("/:id", h.DeleteTeam)
+		teams.POST("/:id/members", h.AddMember)
+		teams.DELETE("/:id/members/:user_id", h.RemoveMember)
+	}
+}
+
+// ListTeams 获取团队列表，include_inactive=true 时包含已停用团队
+func (h *TeamHandler) ListTeams(c *gin.Context) {
+	includeInactive := c.Query("include_inactive") == "true"
+
+	teams, err := h.teamService.ListTeams(context.Background(), includeInactive)
+	if err != nil {
+		h.response.InternalServerError(c, "获取团队列表失败")
+		return
+	}
+
+	responses := make([]*models.TeamResponse, 0, len(teams))
+	for _, team := range teams {
+		responses = append(responses, team.ToResponse())
+	}
+	h.response.Success(c, responses, "获取团队列表成功")
+}
+
+// GetTeam 获取团队详情
+func (h *TeamHandler) GetTeam(c *gin.Context) {
+	id, ok := h.parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	team, err := h.teamService.GetTeam(context.Background(), id)
+	if err != nil {
+		h.handleError(c, err, "获取团队失败")
+		return
+	}
+	h.response.Success(c, team.ToResponse(), "获取团队成功")
+}
+
+// CreateTeam 创建团队
+func (h *TeamHandler) CreateTeam(c *gin.Context) {
+	var req models.TeamCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	team, err := h.teamService.CreateTeam(context.Background(), &req)
+	if err != nil {
+		h.handleError(c, err, "创建团队失败")
+		return
+	}
+	h.response.Created(c, team.ToResponse(), "创建团队成功")
+}
+
+// UpdateTeam 更新团队
+func (h *TeamHandler) UpdateTeam(c *gin.Context) {
+	id, ok := h.parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req models.TeamUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	team, err := h.teamService.UpdateTeam(context.Background(), id, &req)
+	if err != nil {
+		h.handleError(c, err, "更新团队失败")
+		return
+	}
+	h.response.Success(c, team.ToResponse(), "更新团队成功")
+}
+
+// DeleteTeam 删除团队
+func (h *TeamHandler) DeleteTeam(c *gin.Context) {
+	id, ok := h.parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.teamService.DeleteTeam(context.Background(), id); err != nil {
+		h.handleError(c, err, "删除团队失败")
+		return
+	}
+	h.response.Success(c, nil, "删除团队成功")
+}
+
+// AddMember 添加团队成员
+func (h *TeamHandler) AddMember(c *gin.Context) {
+	id, ok := h.parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req struct {
+		UserID uint `json:"user_id" binding:"required"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	if err := h.teamService.AddMember(context.Background(), id, req.UserID); err != nil {
+		h.handleError(c, err, "添加团队成员失败")
+		return
+	}
+	h.response.Success(c, nil, "添加团队成员成功")
+}
+
+// RemoveMember 移除团队成员
+func (h *TeamHandler) RemoveMember(c *gin.Context) {
+	id, ok := h.parseID(c, "id")
+	if !ok {
+		return
+	}
+	userID, ok := h.parseID(c, "user_id")
+	if !ok {
+		return
+	}
+
+	if err := h.teamService.RemoveMember(context.Background(), id, userID); err != nil {
+		h.handleError(c, err, "移除团队成员失败")
+		return
+	}
+	h.response.Success(c, nil, "移除团队成员成功")
+}
+
+// GetTeamQueues 获取团队队列概览（含各队列未认领工单数），mine=true 时仅返回自己所在的团队
+func (h *TeamHandler) GetTeamQueues(c *gin.Context) {
+	var memberID *uint
+	if c.Query("mine") == "true" {
+		userID := c.GetUint("user_id")
+		memberID = &userID
+	}
+
+	summaries, err := h.teamService.GetQueueSummaries(context.Background(), memberID)
+	if err != nil {
+		h.response.InternalServerError(c, "获取团队队列失败")
+		return
+	}
+	h.response.Success(c, summaries, "获取团队队列成功")
+}
+
+func (h *TeamHandler) parseID(c *gin.Context, param string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(param), 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, "无效的ID")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func (h *TeamHandler) handleError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrTeamNotFound):
+		h.response.NotFound(c, "团队不存在")
+	case errors.Is(err, services.ErrInvalidTeamSlug):
+		h.response.BadRequest(c, err.Error())
+	default:
+		h.response.Error(c, http.StatusInternalServerError, message, err.Error())
+	}
+}
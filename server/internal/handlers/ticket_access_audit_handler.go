@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// TicketAccessAuditHandler 保密工单访问日志处理器
+type TicketAccessAuditHandler struct {
+	auditService *services.TicketAccessAuditService
+	response     *middleware.ResponseHelper
+}
+
+// NewTicketAccessAuditHandler 创建保密工单访问日志处理器
+func NewTicketAccessAuditHandler(auditService *services.TicketAccessAuditService) *TicketAccessAuditHandler {
+	return &TicketAccessAuditHandler{
+		auditService: auditService,
+		response:     middleware.NewResponseHelper(),
+	}
+}
+
+// RegisterAdminRoutes 注册管理员路由
+func (h *TicketAccessAuditHandler) RegisterAdminRoutes(router *gin.RouterGroup) {
+	logs := router.Group("/ticket-access-logs")
+	{
+		logs.GET("", h.ListLogs)
+		logs.GET("/config", h.GetPolicy)
+		logs.PUT("/config", h.UpdatePolicy)
+	}
+}
+
+// ListLogs 查询保密工单访问日志
+func (h *TicketAccessAuditHandler) ListLogs(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "50"))
+	filter := &services.TicketAccessLogFilter{
+		Action:      models.TicketAccessAction(c.Query("action")),
+		NonAssignee: c.Query("non_assignee") == "true",
+		AlertedOnly: c.Query("alerted") == "true",
+		Page:        page,
+		PageSize:    pageSize,
+	}
+	switch filter.Action {
+	case "", models.TicketAccessView, models.TicketAccessHistory, models.TicketAccessComments, models.TicketAccessArchive:
+	default:
+		h.response.BadRequest(c, "action 只能为 view、history、comments 或 archive")
+		return
+	}
+
+	for name, target := range map[string]**uint{"ticket_id": &filter.TicketID, "user_id": &filter.UserID} {
+		if value := c.Query(name); value != "" {
+			id, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				h.response.BadRequest(c, "无效的"+name)
+				return
+			}
+			uid := uint(id)
+			*target = &uid
+		}
+	}
+	for name, target := range map[string]**time.Time{"start_time": &filter.StartTime, "end_time": &filter.EndTime} {
+		if value := c.Query(name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				h.response.BadRequest(c, name+" 需为 RFC3339 格式")
+				return
+			}
+			*target = &t
+		}
+	}
+
+	logs, total, err := h.auditService.ListLogs(context.Background(), filter)
+	if err != nil {
+		h.response.InternalServerError(c, "获取访问日志失败", err.Error())
+		return
+	}
+	h.response.List(c, logs, total, filter.Page, filter.PageSize, "获取访问日志成功")
+}
+
+// GetPolicy 获取访问审计策略
+func (h *TicketAccessAuditHandler) GetPolicy(c *gin.Context) {
+	policy, err := h.auditService.GetPolicy(context.Background())
+	if err != nil {
+		h.response.InternalServerError(c, "获取访问审计策略失败", err.Error())
+		return
+	}
+	h.response.Success(c, policy)
+}
+
+// UpdatePolicy 更新访问审计策略
+func (h *TicketAccessAuditHandler) UpdatePolicy(c *gin.Context) {
+	var req models.TicketAccessAuditPolicy
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	if err := h.auditService.SetPolicy(context.Background(), &req, c.GetUint("user_id")); err != nil {
+		h.response.BadRequest(c, err.Error())
+		return
+	}
+	h.response.Success(c, req, "访问审计策略已更新")
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// TicketArchiveHandler 工单完整归档导出处理器
+type TicketArchiveHandler struct {
+	archiveService *services.TicketArchiveService
+	response       *middleware.ResponseHelper
+}
+
+// NewTicketArchiveHandler 创建工单归档处理器
+func NewTicketArchiveHandler(archiveService *services.TicketArchiveService) *TicketArchiveHandler {
+	return &TicketArchiveHandler{
+		archiveService: archiveService,
+		response:       middleware.NewResponseHelper(),
+	}
+}
+
+// CreateArchive 创建工单归档任务，返回 202 及任务，通过任务接口查询进度
+func (h *TicketArchiveHandler) CreateArchive(c *gin.Context) {
+	ticketID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, "无效的工单ID")
+		return
+	}
+
+	// 请求体可省略，默认只包含客户可见内容
+	var req models.TicketArchiveRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	job, err := h.archiveService.CreateJob(c.Request.Context(), uint(ticketID), &req, c.GetUint("user_id"))
+	if err != nil {
+		h.handleError(c, err, "创建归档任务失败")
+		return
+	}
+	c.JSON(http.StatusAccepted, middleware.StandardResponse{Code: 0, Msg: "工单归档正在后台生成", Data: job})
+}
+
+// GetArchiveJob 获取归档任务进度，完成后附带限时签名下载链接
+func (h *TicketArchiveHandler) GetArchiveJob(c *gin.Context) {
+	ticketID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, "无效的工单ID")
+		return
+	}
+	jobID, err := strconv.ParseUint(c.Param("job_id"), 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, "无效的任务ID")
+		return
+	}
+
+	job, err := h.archiveService.GetJob(c.Request.Context(), uint(ticketID), uint(jobID))
+	if err != nil {
+		h.handleError(c, err, "获取归档任务失败")
+		return
+	}
+	h.response.Success(c, job, "获取归档任务成功")
+}
+
+// DownloadArchive 通过签名链接下载归档，无需登录
+func (h *TicketArchiveHandler) DownloadArchive(c *gin.Context) {
+	jobID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.response.NotFound(c, "归档不存在")
+		return
+	}
+
+	job, path, err := h.archiveService.SignedFile(c.Request.Context(), uint(jobID), c.Query("expires"), c.Query("signature"))
+	if err != nil {
+		h.handleError(c, err, "无法下载归档")
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Header("Content-Type", "application/zip")
+	c.FileAttachment(path, job.FileName)
+}
+
+func (h *TicketArchiveHandler) handleError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrTicketArchiveTicketNotFound):
+		h.response.NotFound(c, "工单不存在")
+	case errors.Is(err, services.ErrTicketArchiveNotFound):
+		h.response.NotFound(c, "归档任务不存在")
+	case errors.Is(err, services.ErrInvalidTicketArchiveLink):
+		h.response.Forbidden(c, "下载链接无效或已过期")
+	case errors.Is(err, services.ErrTicketArchiveNotReady):
+		h.response.Error(c, http.StatusConflict, "归档尚未生成")
+	case errors.Is(err, services.ErrTicketArchiveExpired):
+		h.response.Error(c, http.StatusGone, "归档文件已过期")
+	default:
+		h.response.Error(c, http.StatusInternalServerError, message, err.Error())
+	}
+}
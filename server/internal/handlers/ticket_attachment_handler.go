@@ -0,0 +1,199 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// TicketAttachmentHandler 工单附件处理器
+type TicketAttachmentHandler struct {
+	attachmentService *services.TicketAttachmentService
+	response          *middleware.ResponseHelper
+}
+
+// NewTicketAttachmentHandler 创建工单附件处理器
+func NewTicketAttachmentHandler(attachmentService *services.TicketAttachmentService) *TicketAttachmentHandler {
+	return &TicketAttachmentHandler{
+		attachmentService: attachmentService,
+		response:          middleware.NewResponseHelper(),
+	}
+}
+
+// RegisterAdminRoutes 注册管理员路由
+func (h *TicketAttachmentHandler) RegisterAdminRoutes(router *gin.RouterGroup) {
+	router.GET("/attachment-policy", h.GetPolicy)
+	router.PUT("/attachment-policy", h.UpdatePolicy)
+	router.GET("/attachment-encryption", h.GetEncryptionStatus)
+	router.POST("/attachment-encryption/rewrap", h.RewrapKeys)
+}
+
+// GetFormSchema 获取分类的建单表单约束（含附件限制），供客户端上传前预校验
+func (h *TicketAttachmentHandler) GetFormSchema(c *gin.Context) {
+	categoryID, ok := h.parseOptionalID(c, "category_id")
+	if !ok {
+		return
+	}
+	subcategoryID, ok := h.parseOptionalID(c, "subcategory_id")
+	if !ok {
+		return
+	}
+
+	schema, err := h.attachmentService.FormSchema(c.Request.Context(), categoryID, subcategoryID)
+	if err != nil {
+		if errors.Is(err, services.ErrCategoryNotActive) {
+			h.response.NotFound(c, "分类不存在")
+			return
+		}
+		h.response.InternalServerError(c, "获取表单约束失败", err.Error())
+		return
+	}
+	h.response.Success(c, schema)
+}
+
+// ListAttachments 获取工单附件
+func (h *TicketAttachmentHandler) ListAttachments(c *gin.Context) {
+	ticketID, ok := h.parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	attachments, err := h.attachmentService.List(c.Request.Context(), ticketID)
+	if err != nil {
+		h.response.InternalServerError(c, "获取附件失败", err.Error())
+		return
+	}
+	h.response.Success(c, attachments)
+}
+
+// UploadAttachment 上传工单附件，按工单分类的附件策略校验大小、类型与数量
+func (h *TicketAttachmentHandler) UploadAttachment(c *gin.Context) {
+	ticketID, ok := h.parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	file, header, err := c.Request.FormFile("file")
+	if err != nil {
+		h.response.BadRequest(c, "文件上传错误")
+		return
+	}
+	defer file.Close()
+
+	attachment, err := h.attachmentService.Upload(c.Request.Context(), ticketID, c.GetUint("user_id"), header.Filename, header.Size, file)
+	if err != nil {
+		if !writeUploadError(c, h.response, err) {
+			h.response.InternalServerError(c, "附件上传失败", err.Error())
+		}
+		return
+	}
+	h.response.Created(c, attachment, "附件上传成功")
+}
+
+// DownloadAttachment 下载工单附件
+func (h *TicketAttachmentHandler) DownloadAttachment(c *gin.Context) {
+	ticketID, ok := h.parseID(c, "id")
+	if !ok {
+		return
+	}
+	attachmentID, ok := h.parseID(c, "attachment_id")
+	if !ok {
+		return
+	}
+
+	attachment, reader, err := h.attachmentService.Open(c.Request.Context(), ticketID, attachmentID)
+	if err != nil {
+		if errors.Is(err, services.ErrAttachmentNotFound) {
+			h.response.NotFound(c, "附件不存在")
+			return
+		}
+		h.response.InternalServerError(c, "下载附件失败", err.Error())
+		return
+	}
+	defer reader.Close()
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename*=UTF-8''%s", url.PathEscape(attachment.OriginalName)))
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.DataFromReader(http.StatusOK, attachment.FileSize, attachment.MimeType, io.Reader(reader), nil)
+}
+
+// GetPolicy 获取附件策略
+func (h *TicketAttachmentHandler) GetPolicy(c *gin.Context) {
+	policy, err := h.attachmentService.GetPolicy(c.Request.Context())
+	if err != nil {
+		h.response.InternalServerError(c, "获取附件策略失败", err.Error())
+		return
+	}
+	h.response.Success(c, policy)
+}
+
+// UpdatePolicy 更新附件策略
+func (h *TicketAttachmentHandler) UpdatePolicy(c *gin.Context) {
+	var req models.AttachmentPolicy
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	if err := h.attachmentService.SetPolicy(c.Request.Context(), &req, c.GetUint("user_id")); err != nil {
+		h.response.BadRequest(c, err.Error())
+		return
+	}
+	h.response.Success(c, req, "附件策略已更新")
+}
+
+// GetEncryptionStatus 获取附件加密状态：当前主密钥及各主密钥包装的附件数
+func (h *TicketAttachmentHandler) GetEncryptionStatus(c *gin.Context) {
+	status, err := h.attachmentService.EncryptionStatus(c.Request.Context())
+	if err != nil {
+		h.response.InternalServerError(c, "获取附件加密状态失败", err.Error())
+		return
+	}
+	h.response.Success(c, status)
+}
+
+// RewrapKeys 轮换主密钥后用新的主要主密钥重新包装附件的数据密钥
+func (h *TicketAttachmentHandler) RewrapKeys(c *gin.Context) {
+	result, err := h.attachmentService.RewrapKeys(c.Request.Context(), time.Now())
+	if err != nil {
+		if errors.Is(err, services.ErrAttachmentEncryptionDisabled) {
+			h.response.Error(c, http.StatusConflict, "未配置附件加密主密钥", nil)
+			return
+		}
+		h.response.InternalServerError(c, "重新包装数据密钥失败", err.Error())
+		return
+	}
+	h.response.Success(c, result, "数据密钥已重新包装")
+}
+
+func (h *TicketAttachmentHandler) parseID(c *gin.Context, param string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(param), 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, "无效的ID")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func (h *TicketAttachmentHandler) parseOptionalID(c *gin.Context, param string) (*uint, bool) {
+	value := c.Query(param)
+	if value == "" {
+		return nil, true
+	}
+	id, err := strconv.ParseUint(value, 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, "无效的"+param+"参数")
+		return nil, false
+	}
+	parsed := uint(id)
+	return &parsed, true
+}
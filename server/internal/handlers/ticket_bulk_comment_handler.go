@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// TicketBulkCommentHandler 批量评论处理器
+type TicketBulkCommentHandler struct {
+	bulkCommentService *services.TicketBulkCommentService
+	response           *middleware.ResponseHelper
+}
+
+// NewTicketBulkCommentHandler 创建批量评论处理器
+func NewTicketBulkCommentHandler(bulkCommentService *services.TicketBulkCommentService) *TicketBulkCommentHandler {
+	return &TicketBulkCommentHandler{
+		bulkCommentService: bulkCommentService,
+		response:           middleware.NewResponseHelper(),
+	}
+}
+
+// CreateJob 向多个工单发表同一评论，后台执行，通过任务详情查看逐个工单的结果
+func (h *TicketBulkCommentHandler) CreateJob(c *gin.Context) {
+	var req models.TicketBulkCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	job, err := h.bulkCommentService.CreateJob(c.Request.Context(), &req, commentViewer(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidTicketBulkComment), errors.Is(err, services.ErrInvalidCommentVisibility):
+			h.response.BadRequest(c, "批量评论请求无效", err.Error())
+		case errors.Is(err, services.ErrTeamNotFound):
+			h.response.NotFound(c, "团队不存在")
+		case errors.Is(err, services.ErrCommentVisibilityForbidden):
+			h.response.Forbidden(c, "无权设置该可见范围")
+		case errors.Is(err, services.ErrNotTeamMember):
+			h.response.Forbidden(c, "不是该团队成员")
+		default:
+			h.response.InternalServerError(c, "创建批量评论失败", err.Error())
+		}
+		return
+	}
+
+	c.JSON(http.StatusAccepted, middleware.StandardResponse{
+		Code: 0,
+		Msg:  "批量评论已提交",
+		Data: job,
+	})
+}
+
+// ListJobs 当前用户创建的批量评论任务
+func (h *TicketBulkCommentHandler) ListJobs(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	jobs, total, err := h.bulkCommentService.ListJobs(c.Request.Context(), c.GetUint("user_id"), page, pageSize)
+	if err != nil {
+		h.response.InternalServerError(c, "获取批量评论任务失败", err.Error())
+		return
+	}
+	h.response.List(c, jobs, total, page, pageSize)
+}
+
+// GetJob 批量评论任务进度及逐个工单的结果
+func (h *TicketBulkCommentHandler) GetJob(c *gin.Context) {
+	jobID, err := strconv.ParseUint(c.Param("job_id"), 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, "无效的任务ID")
+		return
+	}
+
+	job, err := h.bulkCommentService.GetJob(c.Request.Context(), uint(jobID), commentViewer(c))
+	if err != nil {
+		if errors.Is(err, services.ErrTicketBulkCommentNotFound) {
+			h.response.NotFound(c, "批量评论任务不存在")
+			return
+		}
+		h.response.InternalServerError(c, "获取批量评论任务失败", err.Error())
+		return
+	}
+	h.response.Success(c, job)
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// TicketCallHandler 电话渠道通话记录处理器
+type TicketCallHandler struct {
+	callService *services.TicketCallService
+	response    *middleware.ResponseHelper
+}
+
+// NewTicketCallHandler 创建通话记录处理器
+func NewTicketCallHandler(callService *services.TicketCallService) *TicketCallHandler {
+	return &TicketCallHandler{
+		callService: callService,
+		response:    middleware.NewResponseHelper(),
+	}
+}
+
+// LogCall 记录通话，未指定 ticket_id 时新建来源为电话的工单
+func (h *TicketCallHandler) LogCall(c *gin.Context) {
+	var req models.TicketCallRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+	h.logCall(c, &req)
+}
+
+// LogTicketCall 记录通话并关联到路径中的工单
+func (h *TicketCallHandler) LogTicketCall(c *gin.Context) {
+	ticketID, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+	var req models.TicketCallRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+	req.TicketID = &ticketID
+	h.logCall(c, &req)
+}
+
+func (h *TicketCallHandler) logCall(c *gin.Context, req *models.TicketCallRequest) {
+	call, duplicate, err := h.callService.LogCall(c.Request.Context(), req, c.GetUint("user_id"))
+	if err != nil {
+		var quotaErr *services.QuotaExceededError
+		switch {
+		case errors.Is(err, services.ErrCallTicketNotFound):
+			h.response.NotFound(c, "工单不存在")
+		case errors.Is(err, services.ErrInvalidCallLog):
+			h.response.BadRequest(c, err.Error())
+		case errors.As(err, &quotaErr):
+			h.response.Error(c, http.StatusForbidden, quotaErr.Message(), quotaErr.Usage)
+		default:
+			h.response.InternalServerError(c, "记录通话失败", err.Error())
+		}
+		return
+	}
+	if duplicate {
+		h.response.Success(c, call, "通话已记录过")
+		return
+	}
+	h.response.Created(c, call, "通话已记录")
+}
+
+// ListCalls 工单的通话记录
+func (h *TicketCallHandler) ListCalls(c *gin.Context) {
+	ticketID, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+	calls, err := h.callService.ListCalls(c.Request.Context(), ticketID)
+	if err != nil {
+		if errors.Is(err, services.ErrCallTicketNotFound) {
+			h.response.NotFound(c, "工单不存在")
+			return
+		}
+		h.response.InternalServerError(c, "获取通话记录失败", err.Error())
+		return
+	}
+	h.response.Success(c, calls, "获取通话记录成功")
+}
+
+// GetCallVolume 统计区间内按客服或分类汇总的通话量，默认最近30天按客服汇总
+func (h *TicketCallHandler) GetCallVolume(c *gin.Context) {
+	now := time.Now()
+	end := now
+	start := now.AddDate(0, 0, -30)
+	if value := c.Query("start_date"); value != "" {
+		t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			h.response.BadRequest(c, "start_date 需为 YYYY-MM-DD 格式")
+			return
+		}
+		start = t
+	}
+	if value := c.Query("end_date"); value != "" {
+		t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+		if err != nil {
+			h.response.BadRequest(c, "end_date 需为 YYYY-MM-DD 格式")
+			return
+		}
+		end = t.AddDate(0, 0, 1)
+	}
+	if !end.After(start) || end.Sub(start) > 366*24*time.Hour {
+		h.response.BadRequest(c, "统计区间无效，最长一年")
+		return
+	}
+	groupBy := c.DefaultQuery("group_by", "agent")
+
+	rows, err := h.callService.CallVolume(c.Request.Context(), groupBy, start, end)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidCallLog) {
+			h.response.BadRequest(c, "group_by 只能为 agent 或 category")
+			return
+		}
+		h.response.InternalServerError(c, "获取通话量统计失败", err.Error())
+		return
+	}
+	h.response.Success(c, gin.H{
+		"start_date": start,
+		"end_date":   end,
+		"group_by":   groupBy,
+		"rows":       rows,
+	})
+}
+
+func (h *TicketCallHandler) parseID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, "无效的工单ID")
+		return 0, false
+	}
+	return uint(id), true
+}
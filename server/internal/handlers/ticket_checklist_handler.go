@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// TicketChecklistHandler 工单检查清单处理器
+type TicketChecklistHandler struct {
+	checklistService *services.TicketChecklistService
+	response         *middleware.ResponseHelper
+}
+
+// NewTicketChecklistHandler 创建工单检查清单处理器
+func NewTicketChecklistHandler(checklistService *services.TicketChecklistService) *TicketChecklistHandler {
+	return &TicketChecklistHandler{
+		checklistService: checklistService,
+		response:         middleware.NewResponseHelper(),
+	}
+}
+
+// ListItems 获取工单检查项及进度
+func (h *TicketChecklistHandler) ListItems(c *gin.Context) {
+	ticketID, ok := h.parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	items, progress, err := h.checklistService.List(context.Background(), ticketID)
+	if err != nil {
+		h.handleError(c, err, "获取检查项失败")
+		return
+	}
+	h.response.Success(c, gin.H{
+		"items":    items,
+		"progress": progress,
+	}, "获取检查项成功")
+}
+
+// AddItem 新增检查项
+func (h *TicketChecklistHandler) AddItem(c *gin.Context) {
+	ticketID, ok := h.parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req models.TicketChecklistItemRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	item, err := h.checklistService.Add(context.Background(), ticketID, &req, c.GetUint("user_id"))
+	if err != nil {
+		h.handleError(c, err, "新增检查项失败")
+		return
+	}
+	h.response.Created(c, item, "检查项已添加")
+}
+
+// UpdateItem 更新检查项（文本、完成状态、必填、负责人、截止时间）
+func (h *TicketChecklistHandler) UpdateItem(c *gin.Context) {
+	ticketID, ok := h.parseID(c, "id")
+	if !ok {
+		return
+	}
+	itemID, ok := h.parseID(c, "item_id")
+	if !ok {
+		return
+	}
+
+	var req models.TicketChecklistItemUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	item, err := h.checklistService.Update(context.Background(), ticketID, itemID, &req, c.GetUint("user_id"))
+	if err != nil {
+		h.handleError(c, err, "更新检查项失败")
+		return
+	}
+	h.response.Success(c, item, "检查项已更新")
+}
+
+// DeleteItem 删除检查项
+func (h *TicketChecklistHandler) DeleteItem(c *gin.Context) {
+	ticketID, ok := h.parseID(c, "id")
+	if !ok {
+		return
+	}
+	itemID, ok := h.parseID(c, "item_id")
+	if !ok {
+		return
+	}
+
+	if err := h.checklistService.Delete(context.Background(), ticketID, itemID); err != nil {
+		h.handleError(c, err, "删除检查项失败")
+		return
+	}
+	h.response.Success(c, nil, "检查项已删除")
+}
+
+// ReorderItems 调整检查项顺序
+func (h *TicketChecklistHandler) ReorderItems(c *gin.Context) {
+	ticketID, ok := h.parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req models.TicketChecklistReorderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	if err := h.checklistService.Reorder(context.Background(), ticketID, req.ItemIDs); err != nil {
+		h.handleError(c, err, "调整检查项顺序失败")
+		return
+	}
+	h.response.Success(c, nil, "检查项顺序已更新")
+}
+
+// ApplyTemplate 从工单模板追加检查项
+func (h *TicketChecklistHandler) ApplyTemplate(c *gin.Context) {
+	ticketID, ok := h.parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req models.TicketChecklistApplyTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	items, err := h.checklistService.ApplyTemplate(context.Background(), ticketID, req.TemplateID, c.GetUint("user_id"))
+	if err != nil {
+		h.handleError(c, err, "应用检查项模板失败")
+		return
+	}
+	h.response.Created(c, items, "已从模板添加检查项")
+}
+
+func (h *TicketChecklistHandler) parseID(c *gin.Context, param string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(param), 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, "无效的ID")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func (h *TicketChecklistHandler) handleError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrChecklistTicketNotFound):
+		h.response.NotFound(c, "工单不存在")
+	case errors.Is(err, services.ErrChecklistItemNotFound):
+		h.response.NotFound(c, "检查项不存在")
+	case errors.Is(err, services.ErrChecklistTemplateNotFound):
+		h.response.NotFound(c, "工单模板不存在")
+	case errors.Is(err, services.ErrInvalidChecklistItem):
+		h.response.BadRequest(c, err.Error())
+	default:
+		h.response.Error(c, http.StatusInternalServerError, message, err.Error())
+	}
+}
@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// TicketClassificationHandler 工单自动分类处理器
+type TicketClassificationHandler struct {
+	classificationService *services.TicketClassificationService
+	response              *middleware.ResponseHelper
+}
+
+// NewTicketClassificationHandler 创建工单自动分类处理器
+func NewTicketClassificationHandler(classificationService *services.TicketClassificationService) *TicketClassificationHandler {
+	return &TicketClassificationHandler{
+		classificationService: classificationService,
+		response:              middleware.NewResponseHelper(),
+	}
+}
+
+// RegisterAdminRoutes 注册管理员路由
+func (h *TicketClassificationHandler) RegisterAdminRoutes(router *gin.RouterGroup) {
+	classification := router.Group("/ticket-classification")
+	{
+		classification.GET("/config", h.GetConfig)
+		classification.PUT("/config", h.UpdateConfig)
+		classification.POST("/test", h.Test)
+		classification.GET("/logs", h.ListLogs)
+		classification.GET("/stats", h.GetStats)
+		classification.POST("/tickets/:id", h.ClassifyTicket)
+	}
+}
+
+// GetConfig 获取自动分类配置，api_key 不返回明文
+func (h *TicketClassificationHandler) GetConfig(c *gin.Context) {
+	config, err := h.classificationService.GetConfig(c.Request.Context())
+	if err != nil {
+		h.response.InternalServerError(c, "获取自动分类配置失败", err.Error())
+		return
+	}
+
+	secretsSet := gin.H{"api_key": config.APIKey != ""}
+	config.APIKey = ""
+	c.JSON(http.StatusOK, gin.H{
+		"success":     true,
+		"data":        config,
+		"secrets_set": secretsSet,
+	})
+}
+
+// UpdateConfig 更新自动分类配置，api_key 留空时保留原值
+func (h *TicketClassificationHandler) UpdateConfig(c *gin.Context) {
+	req := models.GetDefaultTicketClassificationConfig()
+	if err := c.ShouldBindJSON(req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	if err := h.classificationService.SetConfig(c.Request.Context(), req, c.GetUint("user_id")); err != nil {
+		h.response.BadRequest(c, err.Error())
+		return
+	}
+	h.response.Success(c, nil, "自动分类配置已更新")
+}
+
+// Test 用示例内容试运行分类，不保存结果
+func (h *TicketClassificationHandler) Test(c *gin.Context) {
+	var req models.TicketClassificationTestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	result, err := h.classificationService.Test(c.Request.Context(), &req)
+	if err != nil {
+		if errors.Is(err, services.ErrClassificationFailed) {
+			h.response.Error(c, http.StatusBadGateway, "分类服务请求失败", err.Error())
+			return
+		}
+		h.response.BadRequest(c, err.Error())
+		return
+	}
+	h.response.Success(c, result)
+}
+
+// ListLogs 分页获取分类记录，可按 status、ticket_id 过滤
+func (h *TicketClassificationHandler) ListLogs(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	ticketID, _ := strconv.ParseUint(c.Query("ticket_id"), 10, 32)
+
+	records, total, err := h.classificationService.ListLogs(c.Request.Context(), c.Query("status"), uint(ticketID), page, pageSize)
+	if err != nil {
+		h.response.InternalServerError(c, "获取分类记录失败", err.Error())
+		return
+	}
+	h.response.List(c, records, total, page, pageSize, "获取分类记录成功")
+}
+
+// GetStats 最近 days 天（默认 30）的分类结果及准确率
+func (h *TicketClassificationHandler) GetStats(c *gin.Context) {
+	days, _ := strconv.Atoi(c.DefaultQuery("days", "30"))
+	stats, err := h.classificationService.Stats(c.Request.Context(), days)
+	if err != nil {
+		h.response.InternalServerError(c, "获取分类统计失败", err.Error())
+		return
+	}
+	h.response.Success(c, stats)
+}
+
+// ClassifyTicket 立即对工单重新分类
+func (h *TicketClassificationHandler) ClassifyTicket(c *gin.Context) {
+	ticketID, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+	record, err := h.classificationService.Classify(c.Request.Context(), ticketID)
+	if err != nil && record == nil {
+		h.handleError(c, err, "工单分类失败")
+		return
+	}
+	if err != nil {
+		h.response.Error(c, http.StatusBadGateway, "分类服务请求失败", record)
+		return
+	}
+	h.response.Success(c, record, "工单分类完成")
+}
+
+// GetTicketClassification 获取工单最近一次分类结果及待确认的建议
+func (h *TicketClassificationHandler) GetTicketClassification(c *gin.Context) {
+	ticketID, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+	record, err := h.classificationService.GetForTicket(c.Request.Context(), ticketID)
+	if err != nil {
+		h.handleError(c, err, "获取工单分类失败")
+		return
+	}
+	h.response.Success(c, record)
+}
+
+// AcceptSuggestion 采纳分类建议，把未自动应用的建议字段写入工单
+func (h *TicketClassificationHandler) AcceptSuggestion(c *gin.Context) {
+	h.review(c, true, "已采纳分类建议")
+}
+
+// RejectSuggestion 拒绝分类建议
+func (h *TicketClassificationHandler) RejectSuggestion(c *gin.Context) {
+	h.review(c, false, "已拒绝分类建议")
+}
+
+func (h *TicketClassificationHandler) review(c *gin.Context, accept bool, message string) {
+	ticketID, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+	record, err := h.classificationService.Review(c.Request.Context(), ticketID, accept, c.GetUint("user_id"))
+	if err != nil {
+		h.handleError(c, err, "处理分类建议失败")
+		return
+	}
+	h.response.Success(c, record, message)
+}
+
+func (h *TicketClassificationHandler) parseID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, "无效的工单ID")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func (h *TicketClassificationHandler) handleError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrClassificationTicketNotFound):
+		h.response.NotFound(c, "工单不存在")
+	case errors.Is(err, services.ErrTicketClassificationNotFound):
+		h.response.NotFound(c, "工单没有分类记录")
+	case errors.Is(err, services.ErrClassificationNotConfigured):
+		h.response.BadRequest(c, "未启用工单自动分类")
+	case errors.Is(err, services.ErrClassificationNoSuggestion):
+		h.response.BadRequest(c, "没有待确认的分类建议")
+	default:
+		h.response.InternalServerError(c, message, err.Error())
+	}
+}
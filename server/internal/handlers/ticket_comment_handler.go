@@ -0,0 +1,332 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// TicketCommentHandler 工单评论处理器
+type TicketCommentHandler struct {
+	commentService *services.TicketCommentService
+	response       *middleware.ResponseHelper
+}
+
+// NewTicketCommentHandler 创建工单评论处理器
+func NewTicketCommentHandler(commentService *services.TicketCommentService) *TicketCommentHandler {
+	return &TicketCommentHandler{
+		commentService: commentService,
+		response:       middleware.NewResponseHelper(),
+	}
+}
+
+// canSeeInternalComments 客户及普通用户不能查看或发表内部评论
+func canSeeInternalComments(c *gin.Context) bool {
+	role := c.GetString("user_role")
+	return role != "" && role != string(models.RoleCustomer) && role != "user"
+}
+
+func commentViewer(c *gin.Context) services.CommentViewer {
+	return services.CommentViewer{UserID: c.GetUint("user_id"), Role: c.GetString("user_role")}
+}
+
+// writeVisibilityError 将可见范围相关错误映射为 HTTP 响应，返回是否已处理
+func (h *TicketCommentHandler) writeVisibilityError(c *gin.Context, err error) bool {
+	switch {
+	case errors.Is(err, services.ErrCommentNotFound):
+		h.response.NotFound(c, "评论不存在")
+	case errors.Is(err, services.ErrTeamNotFound):
+		h.response.NotFound(c, "团队不存在")
+	case errors.Is(err, services.ErrCommentVisibilityForbidden):
+		h.response.Forbidden(c, "无权设置该可见范围")
+	case errors.Is(err, services.ErrNotTeamMember):
+		h.response.Forbidden(c, "不是该团队成员")
+	case errors.Is(err, services.ErrInvalidCommentVisibility):
+		h.response.BadRequest(c, "无效的可见范围", err.Error())
+	default:
+		return false
+	}
+	return true
+}
+
+// RegisterAdminRoutes 注册评论相关的管理员路由
+func (h *TicketCommentHandler) RegisterAdminRoutes(r *gin.RouterGroup) {
+	r.GET("/comment-visibility-defaults", h.GetVisibilityDefaults)
+	r.PUT("/comment-visibility-defaults", h.UpdateVisibilityDefaults)
+}
+
+// GetComments 获取工单评论列表
+func (h *TicketCommentHandler) GetComments(c *gin.Context) {
+	ticketID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, "无效的工单ID")
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	includeInternal := c.Query("include_internal") == "true" && canSeeInternalComments(c)
+
+	comments, total, err := h.commentService.ListComments(context.Background(), uint(ticketID), commentViewer(c), includeInternal, page, pageSize)
+	if err != nil {
+		h.response.InternalServerError(c, "获取评论失败")
+		return
+	}
+
+	responses := make([]*models.TicketCommentResponse, 0, len(comments))
+	for _, comment := range comments {
+		responses = append(responses, comment.ToResponse())
+	}
+	h.response.List(c, responses, total, page, pageSize)
+}
+
+// CreateComment 添加工单评论，内容中的 @团队标识 会通知该团队成员
+func (h *TicketCommentHandler) CreateComment(c *gin.Context) {
+	ticketID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, "无效的工单ID")
+		return
+	}
+
+	var req models.TicketCommentCreateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+	req.TicketID = uint(ticketID)
+	if !canSeeInternalComments(c) && (req.Type == models.CommentTypeInternal ||
+		(req.Visibility != "" && req.Visibility != models.CommentVisibilityPublic)) {
+		h.response.Forbidden(c, "无权发表内部评论")
+		return
+	}
+
+	comment, err := h.commentService.CreateComment(context.Background(), uint(ticketID), &req, commentViewer(c))
+	if err != nil {
+		if h.writeVisibilityError(c, err) || writePIIBlockedError(c, h.response, err) || writeUploadError(c, h.response, err) {
+			return
+		}
+		var lockedErr *services.ReplyLockedError
+		if errors.As(err, &lockedErr) {
+			h.response.Error(c, http.StatusConflict, "其他客服正在回复该工单", lockedErr.Lock)
+			return
+		}
+		if errors.Is(err, services.ErrAttachmentNotFound) {
+			h.response.NotFound(c, "附件不存在或已关联其他评论", err.Error())
+			return
+		}
+		if errors.Is(err, services.ErrInvalidCommentQuote) {
+			h.response.BadRequest(c, "引用内容无效", err.Error())
+			return
+		}
+		if err.Error() == "ticket not found" {
+			h.response.NotFound(c, "工单不存在")
+			return
+		}
+		h.response.Error(c, http.StatusBadRequest, "添加评论失败", err.Error())
+		return
+	}
+
+	h.response.Created(c, comment.ToResponse(), "评论添加成功")
+}
+
+// GetDraft 获取当前用户在工单下的评论草稿及回复锁状态
+func (h *TicketCommentHandler) GetDraft(c *gin.Context) {
+	ticketID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, "无效的工单ID")
+		return
+	}
+
+	state, err := h.commentService.DraftService().GetDraft(c.Request.Context(), uint(ticketID), c.GetUint("user_id"))
+	if err != nil {
+		h.response.InternalServerError(c, "获取评论草稿失败")
+		return
+	}
+	h.response.Success(c, state, "获取评论草稿成功")
+}
+
+// SaveDraft 自动保存评论草稿，lock 为 true 时获取或续期回复锁
+func (h *TicketCommentHandler) SaveDraft(c *gin.Context) {
+	ticketID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, "无效的工单ID")
+		return
+	}
+
+	var req models.CommentDraftSaveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	state, err := h.commentService.DraftService().SaveDraft(c.Request.Context(), uint(ticketID), &req, commentViewer(c))
+	if err != nil {
+		if errors.Is(err, services.ErrDraftTicketNotFound) {
+			h.response.NotFound(c, "工单不存在")
+			return
+		}
+		h.response.InternalServerError(c, "保存评论草稿失败")
+		return
+	}
+	h.response.Success(c, state, "评论草稿已保存")
+}
+
+// DiscardDraft 放弃评论草稿并释放回复锁
+func (h *TicketCommentHandler) DiscardDraft(c *gin.Context) {
+	ticketID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, "无效的工单ID")
+		return
+	}
+
+	if err := h.commentService.DraftService().DiscardDraft(c.Request.Context(), uint(ticketID), c.GetUint("user_id")); err != nil {
+		h.response.InternalServerError(c, "删除评论草稿失败")
+		return
+	}
+	h.response.Success(c, nil, "评论草稿已删除")
+}
+
+// UpdateVisibility 切换评论可见范围，变更记录在工单历史中
+func (h *TicketCommentHandler) UpdateVisibility(c *gin.Context) {
+	ticketID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, "无效的工单ID")
+		return
+	}
+	commentID, err := strconv.ParseUint(c.Param("comment_id"), 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, "无效的评论ID")
+		return
+	}
+
+	var req models.CommentVisibilityUpdateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	comment, err := h.commentService.UpdateVisibility(c.Request.Context(), uint(ticketID), uint(commentID), &req, commentViewer(c))
+	if err != nil {
+		if h.writeVisibilityError(c, err) {
+			return
+		}
+		h.response.InternalServerError(c, "更新评论可见范围失败")
+		return
+	}
+
+	h.response.Success(c, comment.ToResponse(), "评论可见范围已更新")
+}
+
+// GetComposerDefaults 获取当前用户评论编辑器的默认可见范围
+func (h *TicketCommentHandler) GetComposerDefaults(c *gin.Context) {
+	defaultVisibility, allowed := h.commentService.ComposerOptions(c.Request.Context(), commentViewer(c))
+	h.response.Success(c, gin.H{
+		"default_visibility": defaultVisibility,
+		"allowed":            allowed,
+	})
+}
+
+// GetVisibilityDefaults 获取各角色评论默认可见范围（管理员）
+func (h *TicketCommentHandler) GetVisibilityDefaults(c *gin.Context) {
+	defaults, err := h.commentService.GetVisibilityDefaults(c.Request.Context())
+	if err != nil {
+		h.response.InternalServerError(c, "获取评论默认可见范围失败")
+		return
+	}
+	h.response.Success(c, defaults)
+}
+
+// UpdateVisibilityDefaults 更新各角色评论默认可见范围（管理员）
+func (h *TicketCommentHandler) UpdateVisibilityDefaults(c *gin.Context) {
+	var defaults models.CommentVisibilityDefaults
+	if err := c.ShouldBindJSON(&defaults); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	if err := h.commentService.SetVisibilityDefaults(c.Request.Context(), &defaults, c.GetUint("user_id")); err != nil {
+		if h.writeVisibilityError(c, err) {
+			return
+		}
+		h.response.InternalServerError(c, "更新评论默认可见范围失败")
+		return
+	}
+	h.response.Success(c, defaults, "评论默认可见范围已更新")
+}
+
+// TranslateComment 将评论翻译为目标语言，同一评论的同一目标语言只调用一次翻译服务
+func (h *TicketCommentHandler) TranslateComment(c *gin.Context) {
+	commentID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, "无效的评论ID")
+		return
+	}
+
+	var req models.CommentTranslateRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			h.response.BadRequest(c, "请求参数无效", err.Error())
+			return
+		}
+	}
+	if req.TargetLanguage == "" {
+		req.TargetLanguage = c.Query("target_language")
+	}
+
+	translation, cached, err := h.commentService.TranslationService().TranslateComment(c.Request.Context(), uint(commentID), req.TargetLanguage, commentViewer(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrCommentNotFound):
+			h.response.NotFound(c, "评论不存在")
+		case errors.Is(err, services.ErrInvalidTranslationLanguage):
+			h.response.BadRequest(c, "无效的目标语言", err.Error())
+		case errors.Is(err, services.ErrTranslationNotConfigured):
+			h.response.Error(c, http.StatusServiceUnavailable, "评论翻译未启用")
+		case errors.Is(err, services.ErrTranslationFailed):
+			h.response.Error(c, http.StatusBadGateway, "翻译服务暂不可用", err.Error())
+		default:
+			h.response.InternalServerError(c, "翻译评论失败")
+		}
+		return
+	}
+	h.response.Success(c, gin.H{
+		"translation": translation,
+		"cached":      cached,
+	})
+}
+
+// GetTranslationPreference 获取当前用户的评论翻译偏好
+func (h *TicketCommentHandler) GetTranslationPreference(c *gin.Context) {
+	preference, err := h.commentService.TranslationService().GetPreference(c.Request.Context(), c.GetUint("user_id"))
+	if err != nil {
+		h.response.InternalServerError(c, "获取翻译偏好失败")
+		return
+	}
+	h.response.Success(c, preference)
+}
+
+// UpdateTranslationPreference 更新当前用户的评论翻译偏好
+func (h *TicketCommentHandler) UpdateTranslationPreference(c *gin.Context) {
+	var req models.TranslationPreferenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	preference, err := h.commentService.TranslationService().UpdatePreference(c.Request.Context(), c.GetUint("user_id"), &req)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidTranslationLanguage) {
+			h.response.BadRequest(c, "无效的目标语言", err.Error())
+			return
+		}
+		h.response.InternalServerError(c, "更新翻译偏好失败")
+		return
+	}
+	h.response.Success(c, preference, "翻译偏好已更新")
+}
@@ -0,0 +1,41 @@
+package handlers
+
+import (
+	"log"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/services"
+)
+
+// TicketCountsHandler 导航角标数字处理器
+type TicketCountsHandler struct {
+	countsService *services.TicketCountsService
+	response      *middleware.ResponseHelper
+}
+
+// NewTicketCountsHandler 创建角标数字处理器
+func NewTicketCountsHandler(countsService *services.TicketCountsService) *TicketCountsHandler {
+	return &TicketCountsHandler{
+		countsService: countsService,
+		response:      middleware.NewResponseHelper(),
+	}
+}
+
+// GetCounts 返回我的未完结、未分配、逾期、SLA风险工单数及未读提及数，供导航角标轮询
+func (h *TicketCountsHandler) GetCounts(c *gin.Context) {
+	userID := c.GetUint("user_id")
+	if userID == 0 {
+		h.response.Unauthorized(c, "用户未认证")
+		return
+	}
+
+	counts, err := h.countsService.GetCounts(c.Request.Context(), userID, c.GetString("user_role"))
+	if err != nil {
+		log.Printf("Failed to get ticket counts for user %d: %v", userID, err)
+		h.response.InternalServerError(c, "获取工单数量失败")
+		return
+	}
+	c.Header("Cache-Control", "private, no-cache")
+	h.response.Success(c, counts, "获取工单数量成功")
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// TicketDataPurgeHandler 工单法律保留及客户数据删除处理器
+type TicketDataPurgeHandler struct {
+	purgeService *services.TicketDataPurgeService
+	response     *middleware.ResponseHelper
+}
+
+// NewTicketDataPurgeHandler 创建工单客户数据删除处理器
+func NewTicketDataPurgeHandler(purgeService *services.TicketDataPurgeService) *TicketDataPurgeHandler {
+	return &TicketDataPurgeHandler{
+		purgeService: purgeService,
+		response:     middleware.NewResponseHelper(),
+	}
+}
+
+// RegisterAdminRoutes 注册管理员路由
+func (h *TicketDataPurgeHandler) RegisterAdminRoutes(router *gin.RouterGroup) {
+	tickets := router.Group("/tickets")
+	{
+		tickets.GET("/legal-holds", h.ListLegalHolds)
+		tickets.POST("/deletion-certificates/verify", h.VerifyCertificate)
+		tickets.PUT("/:id/legal-hold", h.SetLegalHold)
+		tickets.POST("/:id/purge-pii", h.Purge)
+	}
+}
+
+// SetLegalHold 设置或解除工单法律保留
+func (h *TicketDataPurgeHandler) SetLegalHold(c *gin.Context) {
+	ticketID, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	var req models.TicketLegalHoldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	ticket, err := h.purgeService.SetLegalHold(context.Background(), ticketID, &req, c.GetUint("user_id"))
+	if err != nil {
+		h.handleError(c, err, "设置法律保留失败")
+		return
+	}
+	message := "法律保留已解除"
+	if ticket.LegalHold {
+		message = "法律保留已设置"
+	}
+	h.response.Success(c, ticket, message)
+}
+
+// ListLegalHolds 获取处于法律保留的工单
+func (h *TicketDataPurgeHandler) ListLegalHolds(c *gin.Context) {
+	tickets, err := h.purgeService.ListLegalHolds(context.Background())
+	if err != nil {
+		h.handleError(c, err, "获取法律保留工单失败")
+		return
+	}
+	h.response.Success(c, tickets, "获取法律保留工单成功")
+}
+
+// Purge 按删除请求清除或匿名化工单中的客户数据，返回删除证书
+func (h *TicketDataPurgeHandler) Purge(c *gin.Context) {
+	ticketID, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	var req models.TicketPurgeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	// 请求上下文带有审计中间件附加的客户端信息，写入删除证书审计记录
+	cert, err := h.purgeService.Purge(c.Request.Context(), ticketID, &req, c.GetUint("user_id"))
+	if err != nil {
+		h.handleError(c, err, "删除客户数据失败")
+		return
+	}
+	h.response.Success(c, cert, "客户数据已删除")
+}
+
+// VerifyCertificate 校验删除证书签名
+func (h *TicketDataPurgeHandler) VerifyCertificate(c *gin.Context) {
+	var cert models.TicketDeletionCertificate
+	if err := c.ShouldBindJSON(&cert); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	if err := h.purgeService.VerifyCertificate(context.Background(), &cert); err != nil {
+		if errors.Is(err, services.ErrDeletionCertificateInvalid) {
+			h.response.Success(c, gin.H{"valid": false}, "删除证书签名无效")
+			return
+		}
+		h.handleError(c, err, "校验删除证书失败")
+		return
+	}
+	h.response.Success(c, gin.H{"valid": true}, "删除证书签名有效")
+}
+
+func (h *TicketDataPurgeHandler) parseID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, "无效的工单ID")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func (h *TicketDataPurgeHandler) handleError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrTicketPurgeNotFound):
+		h.response.NotFound(c, "工单不存在")
+	case errors.Is(err, services.ErrTicketPurgeInvalid):
+		h.response.BadRequest(c, err.Error())
+	case errors.Is(err, services.ErrTicketLegalHold):
+		h.response.Error(c, http.StatusConflict, "工单处于法律保留，不能删除客户数据")
+	default:
+		h.response.Error(c, http.StatusInternalServerError, message, err.Error())
+	}
+}
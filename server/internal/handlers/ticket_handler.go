@@ -3,9 +3,12 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gongdan-system/internal/middleware"
@@ -15,8 +18,16 @@ import (
 
 // TicketHandler 工单处理器
 type TicketHandler struct {
-	ticketService services.TicketServiceInterface
-	response      *middleware.ResponseHelper
+	ticketService   services.TicketServiceInterface
+	proposalService *services.TicketChangeProposalService
+	fieldPermission *services.TicketFieldPermissionService
+	calendarService *services.BusinessCalendarService
+	spamService     *services.IntakeSpamService
+	checklist       *services.TicketChecklistService
+	inboxService    *services.InboxService
+	references      *services.ExternalReferenceService
+	categoryAccess  *services.CategoryMembershipService
+	response        *middleware.ResponseHelper
 }
 
 // NewTicketHandler 创建工单处理器
@@ -27,6 +38,58 @@ func NewTicketHandler(ticketService services.TicketServiceInterface) *TicketHand
 	}
 }
 
+// SetChangeProposalService 设置变更提案服务，启用后指定角色的编辑需审核
+func (h *TicketHandler) SetChangeProposalService(proposalService *services.TicketChangeProposalService) {
+	h.proposalService = proposalService
+}
+
+// SetFieldPermissionService 设置字段编辑权限服务，启用后按角色限制字段修改
+func (h *TicketHandler) SetFieldPermissionService(fieldPermission *services.TicketFieldPermissionService) {
+	h.fieldPermission = fieldPermission
+}
+
+// SetBusinessCalendarService 设置营业日历服务，用于在创建工单响应中返回截止时间建议
+func (h *TicketHandler) SetBusinessCalendarService(calendarService *services.BusinessCalendarService) {
+	h.calendarService = calendarService
+}
+
+// SetChecklistService 设置检查清单服务，用于在工单响应中返回检查项进度
+func (h *TicketHandler) SetChecklistService(checklistService *services.TicketChecklistService) {
+	h.checklist = checklistService
+}
+
+// SetInboxService 设置收件箱服务，用于在工单详情中返回邮件会话概要
+func (h *TicketHandler) SetInboxService(inboxService *services.InboxService) {
+	h.inboxService = inboxService
+}
+
+// SetExternalReferenceService 设置外部关联服务，用于在工单详情中返回外部关联并按外部编号反查工单
+func (h *TicketHandler) SetExternalReferenceService(references *services.ExternalReferenceService) {
+	h.references = references
+}
+
+// SetIntakeSpamService 设置受理垃圾检测服务，启用后客户提交的工单经过垃圾评分与限流
+func (h *TicketHandler) SetIntakeSpamService(spamService *services.IntakeSpamService) {
+	h.spamService = spamService
+}
+
+// SetCategoryMembershipService 设置分类成员服务，开启分类权限后坐席只能查看和处理所属分类的工单
+func (h *TicketHandler) SetCategoryMembershipService(categoryAccess *services.CategoryMembershipService) {
+	h.categoryAccess = categoryAccess
+}
+
+// categoryScoped 在上下文中附加当前用户的分类范围，用于过滤工单列表
+func (h *TicketHandler) categoryScoped(c *gin.Context, ctx context.Context) (context.Context, error) {
+	if h.categoryAccess == nil {
+		return ctx, nil
+	}
+	scope, err := h.categoryAccess.ScopeFor(ctx, c.GetUint("user_id"), c.GetString("user_role"))
+	if err != nil {
+		return nil, err
+	}
+	return services.WithCategoryScope(ctx, scope), nil
+}
+
 // GetTickets 获取工单列表
 func (h *TicketHandler) GetTickets(c *gin.Context) {
 	ctx := context.Background()
@@ -40,10 +103,25 @@ func (h *TicketHandler) GetTickets(c *gin.Context) {
 	assignedTo := c.Query("assigned_to")
 	createdBy := c.Query("created_by")
 	search := c.Query("search")
-	sortBy := c.DefaultQuery("sort_by", "created_at")
-	sortOrder := c.DefaultQuery("sort_order", "desc")
+	// 排序：sort=priority:desc,created_at:asc，兼容 sort_by/sort_order
+	sortSpec := c.Query("sort")
+	if sortSpec == "" && (c.Query("sort_by") != "" || c.Query("sort_order") != "") {
+		sortSpec = c.DefaultQuery("sort_by", "created_at") + ":" + c.DefaultQuery("sort_order", "desc")
+	}
+	sort, err := services.ParseSort(sortSpec)
+	if err != nil {
+		h.response.BadRequest(c, "排序参数无效", err.Error())
+		return
+	}
 
 	var tagsFilter []string
+	var customFieldsFilter map[string]interface{}
+	if rawFields := c.Query("custom_fields"); rawFields != "" {
+		if err := json.Unmarshal([]byte(rawFields), &customFieldsFilter); err != nil {
+			h.response.BadRequest(c, "custom_fields 必须是JSON对象")
+			return
+		}
+	}
 
 	if rawFilter := c.Query("filter"); rawFilter != "" {
 		var filterMap map[string]interface{}
@@ -77,20 +155,24 @@ func (h *TicketHandler) GetTickets(c *gin.Context) {
 			if len(tagsFilter) == 0 {
 				tagsFilter = extractFilterStrings(filterMap["tag"])
 			}
+			if fields, ok := filterMap["custom_fields"].(map[string]interface{}); ok && customFieldsFilter == nil {
+				customFieldsFilter = fields
+			}
 		}
 	}
 
 	// 构建过滤器
 	filters := services.TicketFilters{
-		Page:      page,
-		Limit:     pageSize,
-		Status:    status,
-		Priority:  priority,
-		Type:      ticketType,
-		Search:    search,
-		Tags:      tagsFilter,
-		SortBy:    sortBy,
-		SortOrder: sortOrder,
+		Page:     page,
+		Limit:    pageSize,
+		Status:   status,
+		Priority: priority,
+		Type:     ticketType,
+		Search:   search,
+		Tags:     tagsFilter,
+		Sort:     sort,
+
+		CustomFields: customFieldsFilter,
 	}
 
 	if assignedTo != "" {
@@ -107,19 +189,94 @@ func (h *TicketHandler) GetTickets(c *gin.Context) {
 		}
 	}
 
-	// 获取工单列表
+	// 团队队列视图：team_id 指定团队，unassigned=true 仅返回未认领工单
+	if teamID := c.Query("team_id"); teamID != "" {
+		if parsed, err := strconv.ParseUint(teamID, 10, 32); err == nil {
+			id := uint(parsed)
+			filters.TeamID = &id
+		}
+	}
+	filters.Unassigned = c.Query("unassigned") == "true"
+
+	// 获取工单列表，开启分类权限时坐席只能看到所属分类的工单
+	ctx, err = h.categoryScoped(c, ctx)
+	if err != nil {
+		h.response.InternalServerError(c, "获取分类权限失败: "+err.Error())
+		return
+	}
 	tickets, total, err := h.ticketService.GetTickets(ctx, filters)
+	if errors.Is(err, services.ErrInvalidSort) {
+		h.response.BadRequest(c, "排序参数无效", err.Error())
+		return
+	}
 	if err != nil {
 		h.response.InternalServerError(c, "获取工单列表失败: "+err.Error())
 		return
 	}
 
+	responses, err := h.listResponses(ctx, tickets)
+	if err != nil {
+		h.response.InternalServerError(c, "获取检查项进度失败: "+err.Error())
+		return
+	}
+
+	h.response.List(c, responses, total, page, pageSize, "获取工单列表成功")
+}
+
+// QueryTickets 按结构化条件（字段、运算符、取值，支持 and/or 嵌套）查询工单
+func (h *TicketHandler) QueryTickets(c *gin.Context) {
+	var req models.TicketQueryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	ctx, err := h.categoryScoped(c, c.Request.Context())
+	if err != nil {
+		h.response.InternalServerError(c, "获取分类权限失败: "+err.Error())
+		return
+	}
+	tickets, total, err := h.ticketService.QueryTickets(ctx, &req)
+	if err != nil {
+		var queryErr *services.TicketQueryError
+		if errors.As(err, &queryErr) {
+			h.response.BadRequest(c, "查询条件无效: "+queryErr.Error(), queryErr)
+			return
+		}
+		if middleware.IsPaginationError(err) {
+			middleware.AbortPagination(c, err)
+			return
+		}
+		h.response.InternalServerError(c, "查询工单失败: "+err.Error())
+		return
+	}
+
+	responses, err := h.listResponses(ctx, tickets)
+	if err != nil {
+		h.response.InternalServerError(c, "获取检查项进度失败: "+err.Error())
+		return
+	}
+	h.response.List(c, responses, total, req.Page, req.PageSize, "查询工单成功")
+}
+
+// listResponses 转换列表中的工单并附带检查项进度
+func (h *TicketHandler) listResponses(ctx context.Context, tickets []*models.Ticket) ([]*models.TicketResponse, error) {
 	responses := make([]*models.TicketResponse, len(tickets))
+	ticketIDs := make([]uint, len(tickets))
 	for i, ticket := range tickets {
 		responses[i] = ticket.ToResponse()
+		ticketIDs[i] = ticket.ID
 	}
-
-	h.response.List(c, responses, total, page, pageSize, "获取工单列表成功")
+	if h.checklist != nil {
+		progress, err := h.checklist.ProgressByTickets(ctx, ticketIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, response := range responses {
+			response.ChecklistProgress = progress[response.ID]
+		}
+	}
+	return responses, nil
 }
 
 func extractFilterStrings(value interface{}) []string {
@@ -169,8 +326,35 @@ func (h *TicketHandler) GetTicket(c *gin.Context) {
 		return
 	}
 
-	// 获取工单
-	ticket, err := h.ticketService.GetTicket(ctx, uint(id))
+	h.respondTicketDetail(c, ctx, uint(id))
+}
+
+// GetTicketByReference 按外部系统和编号反查工单，返回工单详情
+func (h *TicketHandler) GetTicketByReference(c *gin.Context) {
+	ctx := context.Background()
+	if h.references == nil {
+		h.response.NotFound(c, "工单不存在")
+		return
+	}
+
+	ticketID, err := h.references.FindTicketID(ctx, c.Query("system"), c.Query("key"))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidExternalReference):
+			h.response.BadRequest(c, "system 和 key 不能为空")
+		case errors.Is(err, services.ErrExternalReferenceNotFound):
+			h.response.NotFound(c, "未找到关联该外部编号的工单")
+		default:
+			h.response.InternalServerError(c, "查找工单失败")
+		}
+		return
+	}
+	h.respondTicketDetail(c, ctx, ticketID)
+}
+
+// respondTicketDetail 返回工单详情，附带检查项进度、邮件会话概要和外部关联
+func (h *TicketHandler) respondTicketDetail(c *gin.Context, ctx context.Context, id uint) {
+	ticket, err := h.ticketService.GetTicket(ctx, id)
 	if err != nil {
 		if err.Error() == "ticket not found" {
 			h.response.NotFound(c, "工单不存在")
@@ -179,8 +363,44 @@ func (h *TicketHandler) GetTicket(c *gin.Context) {
 		h.response.InternalServerError(c, "获取工单失败")
 		return
 	}
+	if h.categoryAccess != nil {
+		if err := h.categoryAccess.CheckTicketAccess(ctx, ticket, c.GetUint("user_id"), c.GetString("user_role")); err != nil {
+			if errors.Is(err, services.ErrCategoryAccessDenied) {
+				h.response.Forbidden(c, "无权访问该分类的工单")
+				return
+			}
+			h.response.InternalServerError(c, "获取分类权限失败")
+			return
+		}
+	}
+
+	response := ticket.ToResponse()
+	if h.checklist != nil {
+		progress, err := h.checklist.Progress(ctx, ticket.ID)
+		if err != nil {
+			h.response.InternalServerError(c, "获取检查项进度失败")
+			return
+		}
+		response.ChecklistProgress = progress
+	}
+	if h.inboxService != nil {
+		thread, err := h.inboxService.EmailThreadSummary(ctx, ticket.ID)
+		if err != nil {
+			h.response.InternalServerError(c, "获取邮件会话失败")
+			return
+		}
+		response.EmailThread = thread
+	}
+	if h.references != nil {
+		refs, err := h.references.List(ctx, ticket.ID)
+		if err != nil {
+			h.response.InternalServerError(c, "获取外部关联失败")
+			return
+		}
+		response.ExternalReferences = refs
+	}
 
-	h.response.Success(c, ticket.ToResponse(), "获取工单成功")
+	h.response.Success(c, response, "获取工单成功")
 }
 
 // CreateTicket 创建工单
@@ -201,14 +421,66 @@ func (h *TicketHandler) CreateTicket(c *gin.Context) {
 		return
 	}
 
+	// 客户从公开渠道提交的工单先经过垃圾检测
+	var quotaErr *services.QuotaExceededError
+	if h.spamService != nil && services.IsPublicIntakeRole(c.GetString("user_role")) {
+		ticket, quarantined, err := h.spamService.SubmitPortalTicket(ctx, &req, userID.(uint))
+		switch {
+		case errors.Is(err, services.ErrIntakeThrottled):
+			h.response.Error(c, http.StatusTooManyRequests, "提交过于频繁，请稍后再试")
+			return
+		case errors.Is(err, services.ErrInvalidImpactUrgency):
+			h.response.BadRequest(c, err.Error())
+			return
+		case errors.As(err, &quotaErr):
+			h.response.Error(c, http.StatusForbidden, quotaErr.Message(), quotaErr.Usage)
+			return
+		case writePIIBlockedError(c, h.response, err):
+			return
+		case err != nil:
+			h.response.InternalServerError(c, "创建工单失败: "+err.Error())
+			return
+		case quarantined != nil:
+			c.JSON(http.StatusAccepted, middleware.StandardResponse{
+				Code: 0,
+				Msg:  "工单已提交，审核通过后将进入处理队列",
+				Data: gin.H{"quarantine_id": quarantined.ID},
+			})
+			return
+		}
+		h.response.Created(c, ticket.ToResponse(), "工单创建成功")
+		return
+	}
+
 	// 创建工单
 	ticket, err := h.ticketService.CreateTicket(ctx, &req, userID.(uint))
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidImpactUrgency) {
+			h.response.BadRequest(c, err.Error())
+			return
+		}
+		if errors.As(err, &quotaErr) {
+			h.response.Error(c, http.StatusForbidden, quotaErr.Message(), quotaErr.Usage)
+			return
+		}
+		if writePIIBlockedError(c, h.response, err) {
+			return
+		}
 		h.response.InternalServerError(c, "创建工单失败: "+err.Error())
 		return
 	}
 
-	h.response.Created(c, ticket.ToResponse(), "工单创建成功")
+	response := ticket.ToResponse()
+	if h.calendarService != nil {
+		suggestions, err := h.calendarService.SuggestDueDates(ctx, time.Now())
+		if err != nil {
+			log.Printf("Warning: failed to compute due date suggestions: %v", err)
+		} else {
+			response.DueDateSuggestions = suggestions
+		}
+	}
+
+	h.response.Created(c, response, "工单创建成功")
 }
 
 // UpdateTicket 更新工单
@@ -237,6 +509,64 @@ func (h *TicketHandler) UpdateTicket(c *gin.Context) {
 		return
 	}
 
+	// 开启分类权限时只能修改所属分类的工单，指定的处理人须属于该分类
+	if h.categoryAccess != nil {
+		var assigneeID uint
+		if req.AssignedToID != nil {
+			assigneeID = *req.AssignedToID
+		}
+		if err := h.categoryAccess.AuthorizeTicket(ctx, uint(id), userID.(uint), c.GetString("user_role"), assigneeID); err != nil {
+			switch {
+			case errors.Is(err, services.ErrCategoryAccessDenied):
+				h.response.Forbidden(c, "无权处理该分类的工单")
+			case errors.Is(err, services.ErrAssigneeNotCategoryMember):
+				h.response.BadRequest(c, "处理人不属于工单所在分类")
+			default:
+				h.response.InternalServerError(c, "检查分类权限失败: "+err.Error())
+			}
+			return
+		}
+	}
+
+	// 按角色检查字段编辑权限，变更提案同样受限
+	if h.fieldPermission != nil {
+		if err := h.fieldPermission.CheckUpdate(ctx, uint(id), c.GetString("user_role"), &req); err != nil {
+			var permErr *services.TicketFieldPermissionError
+			switch {
+			case errors.As(err, &permErr):
+				h.response.Forbidden(c, "无权修改部分字段", gin.H{"fields": permErr.Fields})
+			case err.Error() == "ticket not found":
+				h.response.NotFound(c, "工单不存在")
+			default:
+				h.response.InternalServerError(c, "检查字段编辑权限失败: "+err.Error())
+			}
+			return
+		}
+	}
+
+	// 需审核的角色只生成变更提案，工单保持不变
+	if h.proposalService != nil && h.proposalService.RequiresReview(ctx, c.GetString("user_role")) {
+		proposal, err := h.proposalService.Propose(ctx, uint(id), &req, userID.(uint))
+		if err != nil {
+			if err.Error() == "ticket not found" {
+				h.response.NotFound(c, "工单不存在")
+				return
+			}
+			if errors.Is(err, services.ErrChangeProposalEmpty) {
+				h.response.BadRequest(c, err.Error())
+				return
+			}
+			h.response.InternalServerError(c, "提交变更提案失败: "+err.Error())
+			return
+		}
+		c.JSON(http.StatusAccepted, middleware.StandardResponse{
+			Code: 0,
+			Msg:  "变更已提交审核",
+			Data: proposal,
+		})
+		return
+	}
+
 	// 更新工单
 	ticket, err := h.ticketService.UpdateTicket(ctx, uint(id), &req, userID.(uint))
 	if err != nil {
@@ -244,6 +574,17 @@ func (h *TicketHandler) UpdateTicket(c *gin.Context) {
 			h.response.NotFound(c, "工单不存在")
 			return
 		}
+		if errors.Is(err, services.ErrInvalidImpactUrgency) || errors.Is(err, services.ErrResolutionCodeRequired) || errors.Is(err, services.ErrInvalidResolutionCode) {
+			h.response.BadRequest(c, err.Error())
+			return
+		}
+		if errors.Is(err, services.ErrChecklistIncomplete) {
+			h.response.Error(c, http.StatusConflict, "必填检查项未完成，不能解决工单", err.Error())
+			return
+		}
+		if writePIIBlockedError(c, h.response, err) {
+			return
+		}
 		h.response.InternalServerError(c, "更新工单失败: "+err.Error())
 		return
 	}
@@ -251,6 +592,30 @@ func (h *TicketHandler) UpdateTicket(c *gin.Context) {
 	h.response.Success(c, ticket.ToResponse(), "工单更新成功")
 }
 
+// GetEditableFields 获取当前用户对工单各字段的编辑权限，供前端禁用不可编辑的控件
+func (h *TicketHandler) GetEditableFields(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, "无效的工单ID")
+		return
+	}
+	if h.fieldPermission == nil {
+		h.response.InternalServerError(c, "字段编辑权限服务未初始化")
+		return
+	}
+
+	fields, err := h.fieldPermission.EditableFields(c.Request.Context(), uint(id), c.GetString("user_role"))
+	if err != nil {
+		if err.Error() == "ticket not found" {
+			h.response.NotFound(c, "工单不存在")
+			return
+		}
+		h.response.InternalServerError(c, "获取可编辑字段失败: "+err.Error())
+		return
+	}
+	h.response.Success(c, fields)
+}
+
 // DeleteTicket 删除工单
 func (h *TicketHandler) DeleteTicket(c *gin.Context) {
 	ctx := context.Background()
@@ -412,9 +777,20 @@ func (h *TicketHandler) BulkUpdateTickets(c *gin.Context) {
 	if req.Updates.CustomFields != nil {
 		bulkReq.CustomFields = req.Updates.CustomFields.ToMap()
 	}
+	if req.Updates.ResolutionCode != nil {
+		bulkReq.ResolutionCode = req.Updates.ResolutionCode
+	}
 
 	// 批量更新工单
 	err := h.ticketService.BulkUpdateTickets(ctx, bulkReq, userID.(uint))
+	if errors.Is(err, services.ErrChecklistIncomplete) {
+		h.response.Error(c, http.StatusConflict, "checklist_incomplete", err.Error())
+		return
+	}
+	if errors.Is(err, services.ErrResolutionCodeRequired) || errors.Is(err, services.ErrInvalidResolutionCode) {
+		h.response.Error(c, http.StatusBadRequest, "invalid_resolution_code", err.Error())
+		return
+	}
 	if err != nil {
 		h.response.Error(c, http.StatusInternalServerError, "bulk_update_failed", "Failed to bulk update tickets: "+err.Error())
 		return
@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// TicketHandoffHandler 换班交接处理器
+type TicketHandoffHandler struct {
+	handoffService *services.TicketHandoffService
+	response       *middleware.ResponseHelper
+}
+
+// NewTicketHandoffHandler 创建换班交接处理器
+func NewTicketHandoffHandler(handoffService *services.TicketHandoffService) *TicketHandoffHandler {
+	return &TicketHandoffHandler{
+		handoffService: handoffService,
+		response:       middleware.NewResponseHelper(),
+	}
+}
+
+// ListSchedules 获取交接计划列表
+func (h *TicketHandoffHandler) ListSchedules(c *gin.Context) {
+	schedules, err := h.handoffService.ListSchedules(c.Request.Context())
+	if err != nil {
+		h.response.InternalServerError(c, "获取交接计划失败", err.Error())
+		return
+	}
+	h.response.Success(c, schedules, "获取交接计划成功")
+}
+
+// GetSchedule 获取交接计划详情
+func (h *TicketHandoffHandler) GetSchedule(c *gin.Context) {
+	id, ok := h.parseID(c, "无效的交接计划ID")
+	if !ok {
+		return
+	}
+	schedule, err := h.handoffService.GetSchedule(c.Request.Context(), id)
+	if err != nil {
+		h.handleError(c, err, "获取交接计划失败")
+		return
+	}
+	h.response.Success(c, schedule, "获取交接计划成功")
+}
+
+// CreateSchedule 创建交接计划
+func (h *TicketHandoffHandler) CreateSchedule(c *gin.Context) {
+	if !h.requireManager(c) {
+		return
+	}
+	var req models.TicketHandoffScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+	schedule, err := h.handoffService.CreateSchedule(c.Request.Context(), &req, c.GetUint("user_id"))
+	if err != nil {
+		h.handleError(c, err, "创建交接计划失败")
+		return
+	}
+	h.response.Created(c, schedule, "创建交接计划成功")
+}
+
+// UpdateSchedule 更新交接计划
+func (h *TicketHandoffHandler) UpdateSchedule(c *gin.Context) {
+	if !h.requireManager(c) {
+		return
+	}
+	id, ok := h.parseID(c, "无效的交接计划ID")
+	if !ok {
+		return
+	}
+	var req models.TicketHandoffScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+	schedule, err := h.handoffService.UpdateSchedule(c.Request.Context(), id, &req)
+	if err != nil {
+		h.handleError(c, err, "更新交接计划失败")
+		return
+	}
+	h.response.Success(c, schedule, "更新交接计划成功")
+}
+
+// DeleteSchedule 删除交接计划
+func (h *TicketHandoffHandler) DeleteSchedule(c *gin.Context) {
+	if !h.requireManager(c) {
+		return
+	}
+	id, ok := h.parseID(c, "无效的交接计划ID")
+	if !ok {
+		return
+	}
+	if err := h.handoffService.DeleteSchedule(c.Request.Context(), id); err != nil {
+		h.handleError(c, err, "删除交接计划失败")
+		return
+	}
+	h.response.Success(c, nil, "删除交接计划成功")
+}
+
+// RunSchedule 立即执行交接计划
+func (h *TicketHandoffHandler) RunSchedule(c *gin.Context) {
+	if !h.requireManager(c) {
+		return
+	}
+	id, ok := h.parseID(c, "无效的交接计划ID")
+	if !ok {
+		return
+	}
+	handoff, err := h.handoffService.RunSchedule(c.Request.Context(), id, c.GetUint("user_id"))
+	if err != nil {
+		h.handleError(c, err, "执行交接计划失败")
+		return
+	}
+	h.response.Success(c, handoff, "交接完成")
+}
+
+// PreviewHandoff 预览将被转交的工单
+func (h *TicketHandoffHandler) PreviewHandoff(c *gin.Context) {
+	var req models.TicketHandoffRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+	preview, err := h.handoffService.Preview(c.Request.Context(), &req)
+	if err != nil {
+		h.handleError(c, err, "预览交接失败")
+		return
+	}
+	h.response.Success(c, preview, "预览交接成功")
+}
+
+// ExecuteHandoff 立即执行交接
+func (h *TicketHandoffHandler) ExecuteHandoff(c *gin.Context) {
+	if !h.requireManager(c) {
+		return
+	}
+	var req models.TicketHandoffRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+	handoff, err := h.handoffService.Execute(c.Request.Context(), &req, c.GetUint("user_id"))
+	if err != nil {
+		h.handleError(c, err, "执行交接失败")
+		return
+	}
+	h.response.Created(c, handoff, "交接完成")
+}
+
+// ListHandoffs 获取交接记录，可按 team_id 过滤
+func (h *TicketHandoffHandler) ListHandoffs(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	var teamID uint
+	if value, err := strconv.ParseUint(c.Query("team_id"), 10, 32); err == nil {
+		teamID = uint(value)
+	}
+
+	handoffs, total, err := h.handoffService.ListHandoffs(c.Request.Context(), teamID, page, pageSize)
+	if err != nil {
+		h.response.InternalServerError(c, "获取交接记录失败", err.Error())
+		return
+	}
+	h.response.List(c, handoffs, total, page, pageSize, "获取交接记录成功")
+}
+
+// GetHandoff 获取交接记录详情
+func (h *TicketHandoffHandler) GetHandoff(c *gin.Context) {
+	id, ok := h.parseID(c, "无效的交接记录ID")
+	if !ok {
+		return
+	}
+	handoff, err := h.handoffService.GetHandoff(c.Request.Context(), id)
+	if err != nil {
+		h.handleError(c, err, "获取交接记录失败")
+		return
+	}
+	h.response.Success(c, handoff, "获取交接记录成功")
+}
+
+// RegisterRoutes 注册换班交接路由
+func (h *TicketHandoffHandler) RegisterRoutes(router *gin.RouterGroup) {
+	router.GET("", h.ListHandoffs)
+	router.POST("", h.ExecuteHandoff)
+	router.POST("/preview", h.PreviewHandoff)
+	router.GET("/schedules", h.ListSchedules)
+	router.POST("/schedules", h.CreateSchedule)
+	router.GET("/schedules/:id", h.GetSchedule)
+	router.PUT("/schedules/:id", h.UpdateSchedule)
+	router.DELETE("/schedules/:id", h.DeleteSchedule)
+	router.POST("/schedules/:id/run", h.RunSchedule)
+	router.GET("/:id", h.GetHandoff)
+}
+
+func (h *TicketHandoffHandler) requireManager(c *gin.Context) bool {
+	if !services.CanManageHandoffs(c.GetString("user_role")) {
+		h.response.Forbidden(c, "仅管理员或主管可以执行交接")
+		return false
+	}
+	return true
+}
+
+func (h *TicketHandoffHandler) parseID(c *gin.Context, message string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, message)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func (h *TicketHandoffHandler) handleError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrTicketHandoffScheduleNotFound):
+		h.response.NotFound(c, "交接计划不存在")
+	case errors.Is(err, services.ErrTicketHandoffNotFound):
+		h.response.NotFound(c, "交接记录不存在")
+	case errors.Is(err, services.ErrInvalidTicketQuery):
+		h.response.BadRequest(c, "筛选条件无效: "+err.Error(), err)
+	case errors.Is(err, services.ErrInvalidTicketHandoff), errors.Is(err, services.ErrInvalidSchedule):
+		h.response.BadRequest(c, err.Error())
+	default:
+		h.response.Error(c, http.StatusInternalServerError, message, err.Error())
+	}
+}
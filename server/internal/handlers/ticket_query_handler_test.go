@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestTicketQuery_BodyPaginationLimits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open("file:ticket_query_handler?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&models.User{}, &models.Team{}, &models.Ticket{}, &models.TicketComment{}, &models.SystemConfig{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+	ctx := context.Background()
+	guard := services.NewPaginationGuard(db)
+	if err := guard.SetPolicy(ctx, &models.PaginationPolicy{Enabled: true, DefaultMaxPageSize: 50, MaxOffset: 1000}, 1); err != nil {
+		t.Fatalf("failed to set pagination policy: %v", err)
+	}
+
+	router := gin.New()
+	router.POST("/api/tickets/query", NewTicketHandler(services.NewTicketService(db)).QueryTickets)
+	query := func(pagination string) (int, map[string]interface{}) {
+		body := `{"filter":{"field":"status","operator":"eq","value":"open"}` + pagination + `}`
+		req := httptest.NewRequest(http.MethodPost, "/api/tickets/query", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		var decoded map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &decoded); err != nil {
+			t.Fatalf("invalid response body %q: %v", rec.Body.String(), err)
+		}
+		return rec.Code, decoded
+	}
+	listPage := func(body map[string]interface{}) (float64, float64) {
+		data, _ := body["data"].(map[string]interface{})
+		page, _ := data["page"].(float64)
+		pageSize, _ := data["page_size"].(float64)
+		return page, pageSize
+	}
+
+	// 请求体中的分页参数与查询参数使用相同的限制和错误响应
+	if code, body := query(`,"page_size":51`); code != http.StatusBadRequest || body["error"] != "page_size_too_large" {
+		t.Fatalf("expected oversized page to be rejected, got %d %v", code, body)
+	}
+	if code, body := query(`,"page":22,"page_size":50`); code != http.StatusBadRequest || body["error"] != "pagination_too_deep" || body["hint"] == nil {
+		t.Fatalf("expected deep page to be rejected, got %d %v", code, body)
+	}
+	if code, body := query(`,"page":21,"page_size":50`); code != http.StatusOK {
+		t.Fatalf("expected offset at the limit to be allowed, got %d %v", code, body)
+	}
+
+	// 缺省或无效的分页参数使用默认值
+	code, body := query(`,"page":0,"page_size":0`)
+	if page, pageSize := listPage(body); code != http.StatusOK || page != 1 || pageSize != 20 {
+		t.Fatalf("expected page 1 of 20, got %d page=%v page_size=%v", code, page, pageSize)
+	}
+
+	// 关闭限制时不报错，每页条数截断到上限
+	if err := guard.SetPolicy(ctx, &models.PaginationPolicy{Enabled: false, DefaultMaxPageSize: 50, MaxOffset: 1000,
+		MaxPageSizes: map[string]int{"POST /api/tickets/query": 10}}, 1); err != nil {
+		t.Fatalf("failed to set pagination policy: %v", err)
+	}
+	code, body = query(`,"page":500,"page_size":200`)
+	if page, pageSize := listPage(body); code != http.StatusOK || page != 500 || pageSize != 10 {
+		t.Fatalf("expected page size to be capped at 10, got %d page=%v page_size=%v", code, page, pageSize)
+	}
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// TicketReminderHandler 工单提醒处理器
+type TicketReminderHandler struct {
+	reminderService *services.TicketReminderService
+	response        *middleware.ResponseHelper
+}
+
+// NewTicketReminderHandler 创建工单提醒处理器
+func NewTicketReminderHandler(reminderService *services.TicketReminderService) *TicketReminderHandler {
+	return &TicketReminderHandler{
+		reminderService: reminderService,
+		response:        middleware.NewResponseHelper(),
+	}
+}
+
+// CreateReminder 为自己或同事设置工单提醒
+func (h *TicketReminderHandler) CreateReminder(c *gin.Context) {
+	ticketID, ok := h.parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	var req models.TicketReminderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	reminder, err := h.reminderService.Create(context.Background(), ticketID, &req, c.GetUint("user_id"))
+	if err != nil {
+		h.handleError(c, err, "设置提醒失败")
+		return
+	}
+	h.response.Created(c, reminder, "提醒已设置")
+}
+
+// ListTicketReminders 获取工单上与当前用户相关的提醒
+func (h *TicketReminderHandler) ListTicketReminders(c *gin.Context) {
+	ticketID, ok := h.parseID(c, "id")
+	if !ok {
+		return
+	}
+
+	reminders, err := h.reminderService.ListForTicket(context.Background(), ticketID, c.GetUint("user_id"), c.GetString("user_role"))
+	if err != nil {
+		h.handleError(c, err, "获取提醒失败")
+		return
+	}
+	h.response.Success(c, reminders, "获取提醒成功")
+}
+
+// ListMyReminders 获取发给当前用户的提醒，默认返回待提醒的
+func (h *TicketReminderHandler) ListMyReminders(c *gin.Context) {
+	status := models.TicketReminderStatus(c.Query("status"))
+	switch status {
+	case "", models.TicketReminderStatusPending, models.TicketReminderStatusSent,
+		models.TicketReminderStatusCompleted, models.TicketReminderStatusCancelled:
+	default:
+		h.response.BadRequest(c, "无效的提醒状态")
+		return
+	}
+
+	reminders, err := h.reminderService.ListForUser(context.Background(), c.GetUint("user_id"), status, 100)
+	if err != nil {
+		h.handleError(c, err, "获取提醒失败")
+		return
+	}
+	h.response.Success(c, reminders, "获取提醒成功")
+}
+
+// CancelReminder 取消待提醒的提醒
+func (h *TicketReminderHandler) CancelReminder(c *gin.Context) {
+	ticketID, ok := h.parseID(c, "id")
+	if !ok {
+		return
+	}
+	reminderID, ok := h.parseID(c, "reminder_id")
+	if !ok {
+		return
+	}
+
+	reminder, err := h.reminderService.Cancel(context.Background(), ticketID, reminderID, c.GetUint("user_id"), c.GetString("user_role"))
+	if err != nil {
+		h.handleError(c, err, "取消提醒失败")
+		return
+	}
+	h.response.Success(c, reminder, "提醒已取消")
+}
+
+func (h *TicketReminderHandler) parseID(c *gin.Context, name string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, "无效的ID")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func (h *TicketReminderHandler) handleError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrReminderNotFound):
+		h.response.NotFound(c, "提醒不存在")
+	case errors.Is(err, services.ErrReminderTicketNotFound):
+		h.response.NotFound(c, "工单不存在")
+	case errors.Is(err, services.ErrReminderForbidden):
+		h.response.Forbidden(c, "无权管理该提醒")
+	case errors.Is(err, services.ErrReminderInvalid):
+		h.response.BadRequest(c, err.Error())
+	case errors.Is(err, services.ErrReminderFinished):
+		h.response.Error(c, http.StatusConflict, err.Error())
+	default:
+		h.response.Error(c, http.StatusInternalServerError, message, err.Error())
+	}
+}
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// TicketSummaryHandler 工单摘要处理器
+type TicketSummaryHandler struct {
+	summaryService *services.TicketSummaryService
+	response       *middleware.ResponseHelper
+}
+
+// NewTicketSummaryHandler 创建工单摘要处理器
+func NewTicketSummaryHandler(summaryService *services.TicketSummaryService) *TicketSummaryHandler {
+	return &TicketSummaryHandler{
+		summaryService: summaryService,
+		response:       middleware.NewResponseHelper(),
+	}
+}
+
+// Summarize 生成工单摘要及建议的下一步操作，工单内容未变化时返回上次的结果
+func (h *TicketSummaryHandler) Summarize(c *gin.Context) {
+	ticketID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, "无效的工单ID")
+		return
+	}
+
+	// 请求体可省略
+	var req models.TicketSummarizeRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	summary, cached, err := h.summaryService.Summarize(c.Request.Context(), uint(ticketID), &req, c.GetUint("user_id"))
+	if err != nil {
+		h.handleError(c, err, "生成工单摘要失败")
+		return
+	}
+	h.response.Success(c, gin.H{"summary": summary, "cached": cached}, "生成工单摘要成功")
+}
+
+// ListSummaries 获取工单的摘要历史
+func (h *TicketSummaryHandler) ListSummaries(c *gin.Context) {
+	ticketID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, "无效的工单ID")
+		return
+	}
+
+	summaries, err := h.summaryService.List(c.Request.Context(), uint(ticketID))
+	if err != nil {
+		h.handleError(c, err, "获取工单摘要失败")
+		return
+	}
+	h.response.Success(c, gin.H{"items": summaries, "total": len(summaries)})
+}
+
+func (h *TicketSummaryHandler) handleError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrTicketSummaryTicketNotFound):
+		h.response.NotFound(c, "工单不存在")
+	case errors.Is(err, services.ErrTicketSummaryNotConfigured):
+		h.response.Error(c, http.StatusServiceUnavailable, "工单摘要未启用或未配置", err.Error())
+	case errors.Is(err, services.ErrTicketSummaryFailed):
+		h.response.Error(c, http.StatusBadGateway, "摘要服务请求失败", err.Error())
+	default:
+		h.response.Error(c, http.StatusInternalServerError, message, err.Error())
+	}
+}
@@ -1,15 +1,21 @@
 package handlers
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/models"
 	"gongdan-system/internal/services"
 )
 
 type TicketWorkflowHandler struct {
-	ticketService services.TicketServiceInterface
+	ticketService  services.TicketServiceInterface
+	surveyService  *services.TicketSurveyService
+	transferSvc    *services.CategoryTransferService
+	categoryAccess *services.CategoryMembershipService
 }
 
 func NewTicketWorkflowHandler(ticketService services.TicketServiceInterface) *TicketWorkflowHandler {
@@ -18,6 +24,85 @@ func NewTicketWorkflowHandler(ticketService services.TicketServiceInterface) *Ti
 	}
 }
 
+// SetSurveyService 设置满意度调查服务
+func (h *TicketWorkflowHandler) SetSurveyService(surveyService *services.TicketSurveyService) {
+	h.surveyService = surveyService
+}
+
+// SetCategoryTransferService 设置工单转移分类服务
+func (h *TicketWorkflowHandler) SetCategoryTransferService(transferSvc *services.CategoryTransferService) {
+	h.transferSvc = transferSvc
+}
+
+// SetCategoryMembershipService 设置分类成员服务，开启分类权限后坐席只能处理所属分类的工单，处理人须属于工单所在分类
+func (h *TicketWorkflowHandler) SetCategoryMembershipService(categoryAccess *services.CategoryMembershipService) {
+	h.categoryAccess = categoryAccess
+}
+
+// authorizeCategory 检查当前用户能否处理工单、处理人是否属于工单所在分类，不通过时写入响应并返回 false
+func (h *TicketWorkflowHandler) authorizeCategory(c *gin.Context, ticketID, assigneeID uint) bool {
+	if h.categoryAccess == nil {
+		return true
+	}
+	err := h.categoryAccess.AuthorizeTicket(c.Request.Context(), ticketID, c.GetUint("user_id"), c.GetString("user_role"), assigneeID)
+	return h.categoryResult(c, err)
+}
+
+// authorizeTargetCategory 检查当前用户是否属于工单要转入的分类，不通过时写入响应并返回 false
+func (h *TicketWorkflowHandler) authorizeTargetCategory(c *gin.Context, categoryID uint) bool {
+	if h.categoryAccess == nil {
+		return true
+	}
+	err := h.categoryAccess.AuthorizeCategory(c.Request.Context(), categoryID, c.GetUint("user_id"), c.GetString("user_role"))
+	return h.categoryResult(c, err)
+}
+
+// categoryResult 分类权限检查结果，不通过时写入响应并返回 false
+func (h *TicketWorkflowHandler) categoryResult(c *gin.Context, err error) bool {
+	if err == nil {
+		return true
+	}
+	status, message := http.StatusInternalServerError, "检查分类权限失败"
+	switch {
+	case errors.Is(err, services.ErrCategoryAccessDenied):
+		status, message = http.StatusForbidden, "无权处理该分类的工单"
+	case errors.Is(err, services.ErrAssigneeNotCategoryMember):
+		status, message = http.StatusBadRequest, "处理人不属于工单所在分类"
+	}
+	c.JSON(status, gin.H{
+		"success": false,
+		"message": message,
+		"error":   err.Error(),
+	})
+	return false
+}
+
+// authorizeCategoryBulk 批量操作的分类权限检查，存在无权处理的工单时整体拒绝并返回这些工单ID
+func (h *TicketWorkflowHandler) authorizeCategoryBulk(c *gin.Context, ticketIDs []uint, assigneeID uint) bool {
+	if h.categoryAccess == nil {
+		return true
+	}
+	denied, err := h.categoryAccess.DeniedTickets(c.Request.Context(), ticketIDs, c.GetUint("user_id"), c.GetString("user_role"), assigneeID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"success": false,
+			"message": "检查分类权限失败",
+			"error":   err.Error(),
+		})
+		return false
+	}
+	if len(denied) > 0 {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success":           false,
+			"message":           "部分工单不属于可处理的分类，或处理人不属于工单所在分类",
+			"error":             services.ErrCategoryAccessDenied.Error(),
+			"denied_ticket_ids": denied,
+		})
+		return false
+	}
+	return true
+}
+
 type AssignRequest struct {
 	AssignedToID uint   `json:"assigned_to_id" binding:"required"`
 	Comment      string `json:"comment"`
@@ -40,6 +125,7 @@ type StatusUpdateRequest struct {
 	Status          string `json:"status" binding:"required"`
 	Comment         string `json:"comment"`
 	ResolutionNotes string `json:"resolution_notes"`
+	ResolutionCode  string `json:"resolution_code"`
 }
 
 type BulkAssignRequest struct {
@@ -49,9 +135,10 @@ type BulkAssignRequest struct {
 }
 
 type BulkStatusRequest struct {
-	TicketIDs []uint `json:"ticket_ids" binding:"required"`
-	Status    string `json:"status" binding:"required"`
-	Comment   string `json:"comment"`
+	TicketIDs      []uint `json:"ticket_ids" binding:"required"`
+	Status         string `json:"status" binding:"required"`
+	Comment        string `json:"comment"`
+	ResolutionCode string `json:"resolution_code"`
 }
 
 func (h *TicketWorkflowHandler) AssignTicket(c *gin.Context) {
@@ -74,6 +161,10 @@ func (h *TicketWorkflowHandler) AssignTicket(c *gin.Context) {
 		return
 	}
 
+	if !h.authorizeCategory(c, uint(ticketID), req.AssignedToID) {
+		return
+	}
+
 	userID := c.GetUint("user_id")
 	ticket, err := h.ticketService.AssignTicket(uint(ticketID), req.AssignedToID, userID, req.Comment)
 	if err != nil {
@@ -92,6 +183,99 @@ func (h *TicketWorkflowHandler) AssignTicket(c *gin.Context) {
 	})
 }
 
+// ClaimTicket 认领未分配的工单（团队队列中的工单需为团队成员）
+func (h *TicketWorkflowHandler) ClaimTicket(c *gin.Context) {
+	ticketID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的工单ID",
+		})
+		return
+	}
+
+	if !h.authorizeCategory(c, uint(ticketID), 0) {
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	ticket, err := h.ticketService.ClaimTicket(context.Background(), uint(ticketID), userID, c.GetString("user_role"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		message := "认领工单失败"
+		switch {
+		case errors.Is(err, services.ErrTicketAlreadyClaimed):
+			status, message = http.StatusConflict, "工单已被分配"
+		case errors.Is(err, services.ErrNotTeamMember):
+			status, message = http.StatusForbidden, "只有所属团队成员可以认领该工单"
+		case errors.Is(err, services.ErrTicketNotFound):
+			status, message = http.StatusNotFound, "工单不存在"
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"message": message,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "工单认领成功",
+		"data":    ticket,
+	})
+}
+
+// SubmitSurvey 提交工单满意度评分
+func (h *TicketWorkflowHandler) SubmitSurvey(c *gin.Context) {
+	ticketID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的工单ID",
+		})
+		return
+	}
+
+	var req models.TicketSurveyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数错误",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	ticket, err := h.surveyService.SubmitSurvey(c.Request.Context(), uint(ticketID), c.GetUint("user_id"), c.GetString("user_role"), &req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		message := "提交评分失败"
+		switch {
+		case errors.Is(err, services.ErrSurveyForbidden):
+			status, message = http.StatusForbidden, "只有工单提交人可以评分"
+		case errors.Is(err, services.ErrSurveyNotAvailable):
+			status, message = http.StatusConflict, "工单解决或关闭后才能评分"
+		case errors.Is(err, services.ErrSurveyAlreadySubmitted):
+			status, message = http.StatusConflict, "该工单已评分"
+		case errors.Is(err, services.ErrTicketNotFound):
+			status, message = http.StatusNotFound, "工单不存在"
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"message": message,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "评分提交成功",
+		"data":    ticket.ToResponse(),
+	})
+}
+
 func (h *TicketWorkflowHandler) TransferTicket(c *gin.Context) {
 	ticketID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
@@ -112,6 +296,10 @@ func (h *TicketWorkflowHandler) TransferTicket(c *gin.Context) {
 		return
 	}
 
+	if !h.authorizeCategory(c, uint(ticketID), req.AssignedToID) {
+		return
+	}
+
 	userID := c.GetUint("user_id")
 	ticket, err := h.ticketService.TransferTicket(uint(ticketID), req.AssignedToID, userID, req.Comment, req.TransferReason)
 	if err != nil {
@@ -130,6 +318,70 @@ func (h *TicketWorkflowHandler) TransferTicket(c *gin.Context) {
 	})
 }
 
+// TransferCategory 将工单转移到新分类，按新分类重算SLA并执行分类自动分配
+func (h *TicketWorkflowHandler) TransferCategory(c *gin.Context) {
+	ticketID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "无效的工单ID",
+		})
+		return
+	}
+
+	var req models.TicketCategoryTransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "请求参数无效",
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	// 坐席须能处理该工单，且只能转入自己所属的分类
+	if !h.authorizeCategory(c, uint(ticketID), 0) || !h.authorizeTargetCategory(c, req.CategoryID) {
+		return
+	}
+
+	result, err := h.transferSvc.TransferCategory(c.Request.Context(), uint(ticketID), &req, c.GetUint("user_id"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		message := "工单转移分类失败"
+		switch {
+		case errors.Is(err, services.ErrCategoryNotActive):
+			status, message = http.StatusBadRequest, "目标分类不存在或未启用"
+		case errors.Is(err, services.ErrInvalidSubcategory):
+			status, message = http.StatusBadRequest, "子分类不属于目标分类"
+		case errors.Is(err, services.ErrCategoryTransferUnchanged):
+			status, message = http.StatusConflict, "工单已在目标分类中"
+		case errors.Is(err, services.ErrCategoryTransferClosed):
+			status, message = http.StatusConflict, "已关闭的工单不能转移分类"
+		case errors.Is(err, services.ErrTicketNotFound):
+			status, message = http.StatusNotFound, "工单不存在"
+		}
+		c.JSON(status, gin.H{
+			"success": false,
+			"message": message,
+			"error":   err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"ticket":              result.Ticket.ToResponse(),
+			"old_sla_due_date":    result.OldSLADueDate,
+			"new_sla_due_date":    result.NewSLADueDate,
+			"sla_source":          result.SLASource,
+			"clock_start":         result.ClockStart,
+			"auto_assigned_to_id": result.AutoAssignedToID,
+		},
+		"message": "工单转移分类成功",
+	})
+}
+
 func (h *TicketWorkflowHandler) EscalateTicket(c *gin.Context) {
 	ticketID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
@@ -150,6 +402,11 @@ func (h *TicketWorkflowHandler) EscalateTicket(c *gin.Context) {
 		return
 	}
 
+	// 升级对象即新的处理人，须属于工单所在分类
+	if !h.authorizeCategory(c, uint(ticketID), req.EscalateToID) {
+		return
+	}
+
 	userID := c.GetUint("user_id")
 	ticket, err := h.ticketService.EscalateTicket(uint(ticketID), req.EscalateToID, userID, req.Reason, req.Comment)
 	if err != nil {
@@ -188,8 +445,28 @@ func (h *TicketWorkflowHandler) UpdateTicketStatus(c *gin.Context) {
 		return
 	}
 
+	if !h.authorizeCategory(c, uint(ticketID), 0) {
+		return
+	}
+
 	userID := c.GetUint("user_id")
-	ticket, err := h.ticketService.UpdateTicketStatus(uint(ticketID), req.Status, userID, req.Comment, req.ResolutionNotes)
+	ticket, err := h.ticketService.UpdateTicketStatus(uint(ticketID), req.Status, userID, req.Comment, req.ResolutionNotes, req.ResolutionCode)
+	if errors.Is(err, services.ErrChecklistIncomplete) {
+		c.JSON(http.StatusConflict, gin.H{
+			"success": false,
+			"message": "必填检查项未完成，不能解决工单",
+			"error":   err.Error(),
+		})
+		return
+	}
+	if errors.Is(err, services.ErrResolutionCodeRequired) || errors.Is(err, services.ErrInvalidResolutionCode) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": "解决代码无效或未选择",
+			"error":   err.Error(),
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -267,7 +544,21 @@ func (h *TicketWorkflowHandler) GetUnassignedTickets(c *gin.Context) {
 	priority := c.Query("priority")
 	categoryID := c.Query("category_id")
 
-	tickets, total, err := h.ticketService.GetUnassignedTickets(priority, categoryID, limit)
+	ctx := c.Request.Context()
+	if h.categoryAccess != nil {
+		scope, err := h.categoryAccess.ScopeFor(ctx, c.GetUint("user_id"), c.GetString("user_role"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"success": false,
+				"message": "获取未分配工单失败",
+				"error":   err.Error(),
+			})
+			return
+		}
+		ctx = services.WithCategoryScope(ctx, scope)
+	}
+
+	tickets, total, err := h.ticketService.GetUnassignedTickets(ctx, priority, categoryID, limit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
@@ -337,6 +628,10 @@ func (h *TicketWorkflowHandler) BulkAssignTickets(c *gin.Context) {
 		return
 	}
 
+	if !h.authorizeCategoryBulk(c, req.TicketIDs, req.AssignedToID) {
+		return
+	}
+
 	userID := c.GetUint("user_id")
 	result, err := h.ticketService.BulkAssignTickets(req.TicketIDs, req.AssignedToID, userID, req.Comment)
 	if err != nil {
@@ -366,8 +661,12 @@ func (h *TicketWorkflowHandler) BulkUpdateStatus(c *gin.Context) {
 		return
 	}
 
+	if !h.authorizeCategoryBulk(c, req.TicketIDs, 0) {
+		return
+	}
+
 	userID := c.GetUint("user_id")
-	result, err := h.ticketService.BulkUpdateStatus(req.TicketIDs, req.Status, userID, req.Comment)
+	result, err := h.ticketService.BulkUpdateStatus(req.TicketIDs, req.Status, userID, req.Comment, req.ResolutionCode)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"success": false,
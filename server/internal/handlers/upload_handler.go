@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// UploadHandler 预签名上传处理器
+type UploadHandler struct {
+	uploadService *services.UploadService
+	response      *middleware.ResponseHelper
+}
+
+// NewUploadHandler 创建预签名上传处理器
+func NewUploadHandler(uploadService *services.UploadService) *UploadHandler {
+	return &UploadHandler{
+		uploadService: uploadService,
+		response:      middleware.NewResponseHelper(),
+	}
+}
+
+// Presign 申请上传地址，按工单分类的附件策略校验声明的大小与类型
+func (h *UploadHandler) Presign(c *gin.Context) {
+	var req models.UploadPresignRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	resp, err := h.uploadService.Presign(c.Request.Context(), c.GetUint("user_id"), &req, time.Now())
+	if err != nil {
+		if !writeUploadError(c, h.response, err) {
+			h.response.InternalServerError(c, "申请上传地址失败", err.Error())
+		}
+		return
+	}
+	h.response.Created(c, resp, "上传地址已签发")
+}
+
+// PutChunk 分片上传，分片按顺序提交并带 Content-Range: bytes start-end/total 头；
+// 没有 Content-Range 时请求体视为完整文件
+func (h *UploadHandler) PutChunk(c *gin.Context) {
+	start, total := int64(0), c.Request.ContentLength
+	if header := c.GetHeader("Content-Range"); header != "" {
+		var end int64
+		if _, err := fmt.Sscanf(header, "bytes %d-%d/%d", &start, &end, &total); err != nil || start < 0 || end < start || end >= total {
+			h.response.BadRequest(c, "无效的Content-Range")
+			return
+		}
+	}
+
+	progress, err := h.uploadService.PutChunk(c.Request.Context(), c.GetUint("user_id"), c.Param("upload_id"), start, total, c.Request.Body, time.Now())
+	if err != nil {
+		if !writeUploadError(c, h.response, err) {
+			h.response.InternalServerError(c, "上传失败", err.Error())
+		}
+		return
+	}
+	h.response.Success(c, progress, "分片已接收")
+}
+
+// writeUploadError 输出附件策略与预签名上传相关的错误，不属于这些错误时返回 false
+func writeUploadError(c *gin.Context, response *middleware.ResponseHelper, err error) bool {
+	var rejected *services.AttachmentRejectedError
+	var quotaErr *services.QuotaExceededError
+	switch {
+	case errors.As(err, &rejected):
+		status := http.StatusBadRequest
+		switch rejected.Reason {
+		case services.AttachmentRejectTooLarge:
+			status = http.StatusRequestEntityTooLarge
+		case services.AttachmentRejectMimeType:
+			status = http.StatusUnsupportedMediaType
+		case services.AttachmentRejectTooManyFiles:
+			status = http.StatusConflict
+		}
+		response.Error(c, status, rejected.Message(), rejected)
+	case errors.As(err, &quotaErr):
+		response.Error(c, http.StatusForbidden, quotaErr.Message(), quotaErr.Usage)
+	case errors.Is(err, services.ErrAttachmentTicketNotFound):
+		response.NotFound(c, "工单不存在")
+	case errors.Is(err, services.ErrUploadNotFound):
+		response.NotFound(c, "上传不存在或已提交", err.Error())
+	case errors.Is(err, services.ErrUploadExpired):
+		response.Error(c, http.StatusGone, "上传地址已过期，请重新上传", err.Error())
+	case errors.Is(err, services.ErrUploadIncomplete):
+		response.Error(c, http.StatusConflict, "文件尚未上传完成", err.Error())
+	case errors.Is(err, services.ErrInvalidUpload):
+		response.BadRequest(c, err.Error())
+	default:
+		return false
+	}
+	return true
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// UserAttributeHandler 用户自定义字段与技能标签处理器
+type UserAttributeHandler struct {
+	attributeService *services.UserAttributeService
+	response         *middleware.ResponseHelper
+}
+
+// NewUserAttributeHandler 创建用户属性处理器
+func NewUserAttributeHandler(attributeService *services.UserAttributeService) *UserAttributeHandler {
+	return &UserAttributeHandler{
+		attributeService: attributeService,
+		response:         middleware.NewResponseHelper(),
+	}
+}
+
+// RegisterAdminRoutes 注册管理员路由
+func (h *UserAttributeHandler) RegisterAdminRoutes(router *gin.RouterGroup) {
+	fields := router.Group("/user-fields")
+	{
+		fields.GET("", h.ListFields)
+		fields.POST("", h.CreateField)
+		fields.PUT("/:id", h.UpdateField)
+		fields.DELETE("/:id", h.DeleteField)
+	}
+
+	router.GET("/users/skills", h.ListSkills)
+	router.GET("/users/:id/attributes", h.GetUserAttributes)
+	router.PUT("/users/:id/fields", h.SetFieldValues)
+	router.PUT("/users/:id/skills", h.SetSkills)
+}
+
+// ListFields 获取用户自定义字段定义，include_inactive=true 时包含停用的字段
+func (h *UserAttributeHandler) ListFields(c *gin.Context) {
+	defs, err := h.attributeService.ListFieldDefinitions(c.Request.Context(), c.Query("include_inactive") == "true")
+	if err != nil {
+		h.handleError(c, err, "获取用户字段失败")
+		return
+	}
+	h.response.Success(c, defs, "获取用户字段成功")
+}
+
+// CreateField 创建用户自定义字段
+func (h *UserAttributeHandler) CreateField(c *gin.Context) {
+	var req models.UserFieldDefinitionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	def, err := h.attributeService.CreateFieldDefinition(c.Request.Context(), &req)
+	if err != nil {
+		h.handleError(c, err, "创建用户字段失败")
+		return
+	}
+	h.response.Created(c, def, "用户字段已创建")
+}
+
+// UpdateField 更新用户自定义字段
+func (h *UserAttributeHandler) UpdateField(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	var req models.UserFieldDefinitionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	def, err := h.attributeService.UpdateFieldDefinition(c.Request.Context(), id, &req)
+	if err != nil {
+		h.handleError(c, err, "更新用户字段失败")
+		return
+	}
+	h.response.Success(c, def, "用户字段已更新")
+}
+
+// DeleteField 删除用户自定义字段及所有用户的值
+func (h *UserAttributeHandler) DeleteField(c *gin.Context) {
+	id, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.attributeService.DeleteFieldDefinition(c.Request.Context(), id); err != nil {
+		h.handleError(c, err, "删除用户字段失败")
+		return
+	}
+	h.response.Success(c, nil, "用户字段已删除")
+}
+
+// ListSkills 获取已使用的技能标签
+func (h *UserAttributeHandler) ListSkills(c *gin.Context) {
+	skills, err := h.attributeService.ListSkills(c.Request.Context())
+	if err != nil {
+		h.handleError(c, err, "获取技能失败")
+		return
+	}
+	h.response.Success(c, skills, "获取技能成功")
+}
+
+// GetUserAttributes 获取用户的自定义字段值及技能
+func (h *UserAttributeHandler) GetUserAttributes(c *gin.Context) {
+	userID, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	attrs, err := h.attributeService.GetUserAttributes(c.Request.Context(), userID)
+	if err != nil {
+		h.handleError(c, err, "获取用户属性失败")
+		return
+	}
+	h.response.Success(c, attrs, "获取用户属性成功")
+}
+
+// SetFieldValues 设置用户的自定义字段值，请求体为字段标识到值的映射
+func (h *UserAttributeHandler) SetFieldValues(c *gin.Context) {
+	userID, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	var values map[string]interface{}
+	if err := c.ShouldBindJSON(&values); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	attrs, err := h.attributeService.SetFieldValues(c.Request.Context(), userID, values)
+	if err != nil {
+		h.handleError(c, err, "设置用户字段失败")
+		return
+	}
+	h.response.Success(c, attrs, "用户字段已更新")
+}
+
+// SetSkills 替换坐席的技能标签
+func (h *UserAttributeHandler) SetSkills(c *gin.Context) {
+	userID, ok := h.parseID(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Skills []models.UserSkillInput `json:"skills" binding:"dive"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.response.BadRequest(c, "请求参数无效", err.Error())
+		return
+	}
+
+	attrs, err := h.attributeService.SetSkills(c.Request.Context(), userID, req.Skills)
+	if err != nil {
+		h.handleError(c, err, "设置技能失败")
+		return
+	}
+	h.response.Success(c, attrs, "技能已更新")
+}
+
+func (h *UserAttributeHandler) parseID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		h.response.BadRequest(c, "无效的ID")
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func (h *UserAttributeHandler) handleError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrUserFieldNotFound):
+		h.response.NotFound(c, "用户字段不存在")
+	case errors.Is(err, services.ErrUserAttributesUserNotFound):
+		h.response.NotFound(c, "用户不存在")
+	case errors.Is(err, services.ErrUserFieldInvalid), errors.Is(err, services.ErrUserSkillInvalid):
+		h.response.BadRequest(c, err.Error())
+	case errors.Is(err, services.ErrUserFieldKeyExists):
+		h.response.Error(c, http.StatusConflict, "字段标识已存在")
+	default:
+		h.response.Error(c, http.StatusInternalServerError, message, err.Error())
+	}
+}
@@ -2,8 +2,11 @@ package handlers
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"regexp"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -13,6 +16,9 @@ import (
 	"gorm.io/gorm"
 )
 
+// identiconSeedPattern 默认头像种子格式，见 models.DefaultAvatarURL
+var identiconSeedPattern = regexp.MustCompile(`^[a-f0-9]{8,64}$`)
+
 // UserHandler 用户处理器
 type UserHandler struct {
 	userService          *services.UserService
@@ -302,7 +308,8 @@ func (h *UserHandler) ChangePassword(c *gin.Context) {
 		return
 	}
 
-	err := h.userService.ChangePassword(c.Request.Context(), userID, req.CurrentPassword, req.NewPassword)
+	ctx := services.WithAuditScope(c.Request.Context(), &userID, c.ClientIP(), c.Request.UserAgent())
+	err := h.userService.ChangePassword(ctx, userID, req.CurrentPassword, req.NewPassword)
 	if err != nil {
 		if err == auth.ErrInvalidCredentials {
 			c.JSON(http.StatusBadRequest, ApiResponse{
@@ -383,6 +390,14 @@ func (h *UserHandler) GetLoginHistory(c *gin.Context) {
 	}
 
 	histories, total, err := h.userService.GetLoginHistory(c.Request.Context(), userID, &req)
+	if errors.Is(err, services.ErrInvalidSort) {
+		c.JSON(http.StatusBadRequest, ApiResponse{
+			Code: 1,
+			Msg:  "排序参数无效: " + err.Error(),
+			Data: nil,
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, ApiResponse{
 			Code: 1,
@@ -481,15 +496,23 @@ func (h *UserHandler) UploadAvatar(c *gin.Context) {
 	}
 	defer file.Close()
 
-	avatarURL, err := h.userService.UploadAvatar(c.Request.Context(), userID, file, header)
+	result, err := h.userService.UploadAvatar(c.Request.Context(), userID, file, header)
 	if err != nil {
-		if err.Error() == "file too large: maximum 2MB allowed" {
+		switch {
+		case errors.Is(err, services.ErrAvatarTooLarge):
 			c.JSON(http.StatusRequestEntityTooLarge, ApiResponse{
 				Code: 1,
 				Msg:  "文件过大，最大支持2MB",
 				Data: nil,
 			})
 			return
+		case errors.Is(err, services.ErrAvatarUnsupportedType), errors.Is(err, services.ErrAvatarInvalidDimensions):
+			c.JSON(http.StatusBadRequest, ApiResponse{
+				Code: 1,
+				Msg:  "头像无效: " + err.Error(),
+				Data: nil,
+			})
+			return
 		}
 		c.JSON(http.StatusInternalServerError, ApiResponse{
 			Code: 1,
@@ -500,7 +523,8 @@ func (h *UserHandler) UploadAvatar(c *gin.Context) {
 	}
 
 	response := UploadAvatarResponse{
-		AvatarURL: avatarURL,
+		AvatarURL: result.AvatarURL,
+		Sizes:     result.Sizes,
 	}
 
 	c.JSON(http.StatusOK, ApiResponse{
@@ -510,6 +534,104 @@ func (h *UserHandler) UploadAvatar(c *gin.Context) {
 	})
 }
 
+// DeleteAvatar 删除头像
+// @Summary 删除用户头像
+// @Description 删除已上传的头像及其各尺寸文件，恢复为默认头像
+// @Tags 用户管理
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} ApiResponse{data=UploadAvatarResponse}
+// @Failure 401 {object} ApiResponse
+// @Failure 500 {object} ApiResponse
+// @Router /api/user/avatar [delete]
+func (h *UserHandler) DeleteAvatar(c *gin.Context) {
+	userID := getUserIDFromContext(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, ApiResponse{
+			Code: 1,
+			Msg:  "用户未认证",
+			Data: nil,
+		})
+		return
+	}
+
+	if err := h.userService.DeleteAvatar(c.Request.Context(), userID); err != nil {
+		c.JSON(http.StatusInternalServerError, ApiResponse{
+			Code: 1,
+			Msg:  "删除头像失败: " + err.Error(),
+			Data: nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, ApiResponse{
+		Code: 0,
+		Msg:  "头像已删除",
+		Data: UploadAvatarResponse{AvatarURL: models.DefaultAvatarURL(userID)},
+	})
+}
+
+// GetIdenticon 获取默认头像
+// @Summary 获取默认头像
+// @Description 根据种子生成对称像素风格的默认头像，内容只取决于种子，可长期缓存
+// @Tags 用户管理
+// @Produce png
+// @Param seed path string true "种子，如 3f2a9c1d0b7e6a54.png"
+// @Param size query int false "尺寸：64/128/256，默认128"
+// @Success 200 {file} binary
+// @Failure 400 {object} ApiResponse
+// @Router /api/avatars/identicon/{seed} [get]
+func (h *UserHandler) GetIdenticon(c *gin.Context) {
+	seed := strings.TrimSuffix(c.Param("seed"), ".png")
+	if !identiconSeedPattern.MatchString(seed) {
+		c.JSON(http.StatusBadRequest, ApiResponse{
+			Code: 1,
+			Msg:  "无效的头像标识",
+			Data: nil,
+		})
+		return
+	}
+
+	size := 128
+	if sizeStr := c.Query("size"); sizeStr != "" {
+		size, _ = strconv.Atoi(sizeStr)
+		valid := false
+		for _, allowed := range services.AvatarSizes {
+			if size == allowed {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			c.JSON(http.StatusBadRequest, ApiResponse{
+				Code: 1,
+				Msg:  "不支持的头像尺寸",
+				Data: nil,
+			})
+			return
+		}
+	}
+
+	etag := fmt.Sprintf(`"%s-%d"`, seed, size)
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.Header("ETag", etag)
+	if c.GetHeader("If-None-Match") == etag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	data, err := services.GenerateIdenticon(seed, size)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ApiResponse{
+			Code: 1,
+			Msg:  "生成头像失败",
+			Data: nil,
+		})
+		return
+	}
+	c.Data(http.StatusOK, "image/png", data)
+}
+
 // DeleteLoginSession 删除登录会话
 // @Summary 删除指定的登录会话
 // @Description 删除指定的登录会话（踢出特定设备）
@@ -584,7 +706,8 @@ type ChangePasswordRequest struct {
 
 // UploadAvatarResponse 上传头像响应
 type UploadAvatarResponse struct {
-	AvatarURL string `json:"avatar_url" example:"/uploads/avatars/avatar_1_1640000000.jpg"`
+	AvatarURL string         `json:"avatar_url" example:"/uploads/avatars/1/3f2a9c1d0b7e6a54_256.jpg"`
+	Sizes     map[int]string `json:"sizes,omitempty"` // 各标准尺寸的地址
 }
 
 // PaginatedResponse 分页响应
@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"strconv"
 	"time"
@@ -17,6 +19,7 @@ import (
 type WebhookHandler struct {
 	db                  *gorm.DB
 	notificationService *services.NotificationService
+	webhookLogService   *services.WebhookLogService
 }
 
 // NewWebhookHandler 创建Webhook处理器
@@ -24,6 +27,7 @@ func NewWebhookHandler(db *gorm.DB) *WebhookHandler {
 	return &WebhookHandler{
 		db:                  db,
 		notificationService: services.NewNotificationService(db),
+		webhookLogService:   services.NewWebhookLogService(db),
 	}
 }
 
@@ -39,12 +43,16 @@ type CreateWebhookRequest struct {
 	MessageTemplate string                        `json:"message_template"`
 	MessageFormat   string                        `json:"message_format"`
 	FilterRules     json.RawMessage               `json:"filter_rules"`
+	FieldFilters    []string                      `json:"field_filters"`
 	RetryCount      int                           `json:"retry_count"`
 	RetryInterval   int                           `json:"retry_interval"`
 	TimeoutSeconds  int                           `json:"timeout_seconds"`
 	IsAsync         bool                          `json:"is_async"`
 	RateLimit       int                           `json:"rate_limit"`
 	RateLimitWindow int                           `json:"rate_limit_window"`
+	// 日志保留天数，0 表示使用系统默认
+	LogRetentionDays       int `json:"log_retention_days" binding:"min=0,max=3650"`
+	FailedLogRetentionDays int `json:"failed_log_retention_days" binding:"min=0,max=3650"`
 }
 
 // UpdateWebhookRequest 更新webhook请求结构
@@ -59,6 +67,7 @@ type UpdateWebhookRequest struct {
 	MessageTemplate *string                        `json:"message_template"`
 	MessageFormat   *string                        `json:"message_format"`
 	FilterRules     *json.RawMessage               `json:"filter_rules"`
+	FieldFilters    *[]string                      `json:"field_filters"`
 	RetryCount      *int                           `json:"retry_count"`
 	RetryInterval   *int                           `json:"retry_interval"`
 	TimeoutSeconds  *int                           `json:"timeout_seconds"`
@@ -66,6 +75,9 @@ type UpdateWebhookRequest struct {
 	RateLimit       *int                           `json:"rate_limit"`
 	RateLimitWindow *int                           `json:"rate_limit_window"`
 	Status          *models.WebhookStatus          `json:"status"`
+
+	LogRetentionDays       *int `json:"log_retention_days" binding:"omitempty,min=0,max=3650"`
+	FailedLogRetentionDays *int `json:"failed_log_retention_days" binding:"omitempty,min=0,max=3650"`
 }
 
 // ListWebhooksResponse 列表响应结构
@@ -110,6 +122,15 @@ func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
 		return
 	}
 
+	if err := services.ValidateWebhookTemplate(req.MessageTemplate); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 1,
+			"msg":  "消息模板无效: " + err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
 	// 创建webhook配置
 	webhook := models.WebhookConfig{
 		Name:             req.Name,
@@ -122,6 +143,7 @@ func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
 		MessageTemplate:  req.MessageTemplate,
 		MessageFormat:    req.MessageFormat,
 		FilterRulesObj:   req.FilterRules,
+		FieldFiltersObj:  req.FieldFilters,
 		RetryCount:       req.RetryCount,
 		RetryInterval:    req.RetryInterval,
 		TimeoutSeconds:   req.TimeoutSeconds,
@@ -132,6 +154,9 @@ func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
 		CreatedBy:        userID.(uint),
 	}
 
+	webhook.LogRetentionDays = req.LogRetentionDays
+	webhook.FailedLogRetentionDays = req.FailedLogRetentionDays
+
 	// 设置默认值
 	if webhook.RetryCount == 0 {
 		webhook.RetryCount = 3
@@ -384,6 +409,14 @@ func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
 		webhook.EnabledEventsObj = *req.EnabledEvents
 	}
 	if req.MessageTemplate != nil {
+		if err := services.ValidateWebhookTemplate(*req.MessageTemplate); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code": 1,
+				"msg":  "消息模板无效: " + err.Error(),
+				"data": nil,
+			})
+			return
+		}
 		updates["message_template"] = *req.MessageTemplate
 	}
 	if req.MessageFormat != nil {
@@ -392,6 +425,14 @@ func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
 	if req.FilterRules != nil {
 		webhook.FilterRulesObj = *req.FilterRules
 	}
+	if req.FieldFilters != nil {
+		// 空数组表示取消字段过滤
+		updates["field_filters"] = ""
+		if len(*req.FieldFilters) > 0 {
+			filtersData, _ := json.Marshal(*req.FieldFilters)
+			updates["field_filters"] = string(filtersData)
+		}
+	}
 	if req.RetryCount != nil {
 		updates["retry_count"] = *req.RetryCount
 	}
@@ -413,6 +454,12 @@ func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
 	if req.Status != nil {
 		updates["status"] = *req.Status
 	}
+	if req.LogRetentionDays != nil {
+		updates["log_retention_days"] = *req.LogRetentionDays
+	}
+	if req.FailedLogRetentionDays != nil {
+		updates["failed_log_retention_days"] = *req.FailedLogRetentionDays
+	}
 
 	// 执行更新
 	if err := h.db.Model(&webhook).Updates(updates).Error; err != nil {
@@ -534,9 +581,73 @@ func (h *WebhookHandler) TestWebhook(c *gin.Context) {
 	})
 }
 
+// RenderTestWebhookRequest 模板试渲染请求，所有字段均可省略
+type RenderTestWebhookRequest struct {
+	EventType       models.WebhookEventType `json:"event_type"`       // 示例事件类型，默认 ticket.created
+	MessageTemplate *string                 `json:"message_template"` // 未保存的模板草稿，省略时使用已保存的模板
+	Data            map[string]interface{}  `json:"data"`             // 覆盖示例事件的 Data 字段
+}
+
+// RenderTestWebhook 试渲染webhook消息模板
+// @Summary 试渲染webhook消息模板
+// @Description 使用示例事件渲染消息模板并返回最终请求体，不实际发送
+// @Tags webhook
+// @Accept json
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Param request body RenderTestWebhookRequest false "试渲染参数"
+// @Success 200 {object} services.WebhookRenderResult
+// @Failure 400 {object} map[string]interface{}
+// @Router /api/webhooks/{id}/render-test [post]
+// @Security BearerAuth
+func (h *WebhookHandler) RenderTestWebhook(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 1,
+			"msg":  "无效的ID",
+			"data": nil,
+		})
+		return
+	}
+
+	var req RenderTestWebhookRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code": 1,
+				"msg":  "参数验证失败: " + err.Error(),
+				"data": nil,
+			})
+			return
+		}
+	}
+
+	event := services.SampleNotificationEvent(req.EventType)
+	for key, value := range req.Data {
+		event.Data[key] = value
+	}
+
+	result, err := h.notificationService.RenderTestWebhook(c.Request.Context(), uint(id), event, req.MessageTemplate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 1,
+			"msg":  err.Error(),
+			"data": nil,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"msg":  "渲染成功",
+		"data": result,
+	})
+}
+
 // GetWebhookLogs 获取webhook日志
 // @Summary 获取webhook日志
-// @Description 分页获取webhook执行日志
+// @Description 分页获取webhook执行日志，支持按状态、事件、HTTP状态码、时间范围、响应时间过滤及请求/响应内容搜索
 // @Tags webhook
 // @Accept json
 // @Produce json
@@ -544,7 +655,14 @@ func (h *WebhookHandler) TestWebhook(c *gin.Context) {
 // @Param page query int false "页码" default(1)
 // @Param page_size query int false "每页数量" default(20)
 // @Param status query string false "状态过滤"
+// @Param event_type query string false "事件类型"
+// @Param http_status query string false "HTTP状态码，如 500 或 5xx"
+// @Param start_date query string false "开始时间 (RFC3339 或 YYYY-MM-DD)"
+// @Param end_date query string false "结束时间 (RFC3339 或 YYYY-MM-DD，日期包含当天)"
+// @Param min_response_time query int false "最小响应时间(毫秒)"
+// @Param q query string false "在请求体、响应体、事件数据和错误信息中搜索"
 // @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /api/webhooks/{id}/logs [get]
 // @Security BearerAuth
@@ -559,37 +677,39 @@ func (h *WebhookHandler) GetWebhookLogs(c *gin.Context) {
 		return
 	}
 
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
-	status := c.Query("status")
-
-	if page < 1 {
-		page = 1
+	var filter models.WebhookLogFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 1,
+			"msg":  "查询参数无效: " + err.Error(),
+			"data": nil,
+		})
+		return
 	}
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 20
+	if filter.StartDate, err = parseWebhookLogTime(c.Query("start_date"), false); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 1,
+			"msg":  "start_date 需为 RFC3339 或 YYYY-MM-DD 格式",
+			"data": nil,
+		})
+		return
 	}
-
-	offset := (page - 1) * pageSize
-
-	// 构建查询
-	query := h.db.Model(&models.WebhookLog{}).Where("config_id = ?", uint(id))
-	
-	if status != "" {
-		query = query.Where("status = ?", status)
+	if filter.EndDate, err = parseWebhookLogTime(c.Query("end_date"), true); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code": 1,
+			"msg":  "end_date 需为 RFC3339 或 YYYY-MM-DD 格式",
+			"data": nil,
+		})
+		return
 	}
 
-	// 获取总数
-	var total int64
-	query.Count(&total)
-
-	// 获取数据
-	var logs []models.WebhookLog
-	if err := query.Order("created_at DESC").
-		Offset(offset).
-		Limit(pageSize).
-		Find(&logs).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+	logs, total, err := h.webhookLogService.ListLogs(c.Request.Context(), uint(id), &filter)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrInvalidWebhookLogFilter) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{
 			"code": 1,
 			"msg":  "获取日志失败: " + err.Error(),
 			"data": nil,
@@ -603,12 +723,30 @@ func (h *WebhookHandler) GetWebhookLogs(c *gin.Context) {
 		"data": gin.H{
 			"items": logs,
 			"total": total,
-			"page":  page,
-			"size":  pageSize,
+			"page":  filter.Page,
+			"size":  filter.PageSize,
 		},
 	})
 }
 
+// parseWebhookLogTime 解析日志查询时间，仅给出日期且作为结束时间时包含当天
+func parseWebhookLogTime(value string, endOfDay bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return nil, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return &t, nil
+}
+
 // GetWebhookStats 获取webhook统计
 // @Summary 获取webhook统计
 // @Description 获取webhook执行统计信息
@@ -647,7 +785,7 @@ func (h *WebhookHandler) GetWebhookStats(c *gin.Context) {
 	}
 
 	var webhook models.WebhookConfig
-	if err := h.db.Select("total_sent, total_success, total_failed").
+	if err := h.db.Select("id, total_sent, total_success, total_failed, log_retention_days, failed_log_retention_days").
 		First(&webhook, uint(id)).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"code": 1,
@@ -669,17 +807,18 @@ func (h *WebhookHandler) GetWebhookStats(c *gin.Context) {
 		Failed  int64  `json:"failed"`
 	}
 
-	rows, err := h.db.Raw(`
+	day := services.DialectOf(h.db).Date("created_at")
+	rows, err := h.db.Raw(fmt.Sprintf(`
 		SELECT 
-			DATE(created_at) as date,
+			%s as date,
 			COUNT(*) as sent,
 			COUNT(CASE WHEN status = 'success' THEN 1 END) as success,
 			COUNT(CASE WHEN status = 'failed' THEN 1 END) as failed
 		FROM webhook_logs 
 		WHERE config_id = ? AND created_at >= ?
-		GROUP BY DATE(created_at)
+		GROUP BY %s
 		ORDER BY date
-	`, uint(id), startTime).Rows()
+	`, day, day), uint(id), startTime).Rows()
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -702,6 +841,26 @@ func (h *WebhookHandler) GetWebhookStats(c *gin.Context) {
 		dailyStats = append(dailyStats, stat)
 	}
 
+	// 按请求地址汇总失败率，统计区间内的整体失败率由各地址合计
+	endpoints, err := h.webhookLogService.EndpointStats(c.Request.Context(), uint(id), startTime)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code": 1,
+			"msg":  "获取统计数据失败: " + err.Error(),
+			"data": nil,
+		})
+		return
+	}
+	var periodSent, periodFailed int64
+	for _, endpoint := range endpoints {
+		periodSent += endpoint.Sent
+		periodFailed += endpoint.Failed
+	}
+	periodFailureRate := 0.0
+	if periodSent > 0 {
+		periodFailureRate = math.Round(float64(periodFailed)/float64(periodSent)*10000) / 100
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"code": 0,
 		"msg":  "获取成功",
@@ -709,6 +868,13 @@ func (h *WebhookHandler) GetWebhookStats(c *gin.Context) {
 			"summary":     stats,
 			"daily_stats": dailyStats,
 			"period":      fmt.Sprintf("最近%d天", days),
+			"period_summary": gin.H{
+				"sent":         periodSent,
+				"failed":       periodFailed,
+				"failure_rate": periodFailureRate,
+			},
+			"endpoints":     endpoints,
+			"log_retention": h.webhookLogService.Retention(&webhook),
 		},
 	})
 }
\ No newline at end of file
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/middleware"
+	websocketPkg "gongdan-system/internal/websocket"
+)
+
+// WebSocketStatsHandler WebSocket 连接监控处理器
+type WebSocketStatsHandler struct {
+	hub      *websocketPkg.Hub
+	response *middleware.ResponseHelper
+}
+
+// NewWebSocketStatsHandler 创建 WebSocket 连接监控处理器
+func NewWebSocketStatsHandler(hub *websocketPkg.Hub) *WebSocketStatsHandler {
+	return &WebSocketStatsHandler{
+		hub:      hub,
+		response: middleware.NewResponseHelper(),
+	}
+}
+
+// RegisterAdminRoutes 注册管理员路由
+func (h *WebSocketStatsHandler) RegisterAdminRoutes(router *gin.RouterGroup) {
+	router.GET("/websocket/stats", h.GetStats)
+}
+
+// GetStats 当前连接数、各连接发送队列深度和丢弃计数，队列最深的连接在前
+func (h *WebSocketStatsHandler) GetStats(c *gin.Context) {
+	h.response.Success(c, h.hub.Stats())
+}
@@ -1,8 +1,10 @@
 package middleware
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"net/http"
 	"strings"
 	"time"
@@ -222,7 +224,20 @@ func PreventSelfOperation() gin.HandlerFunc {
 	}
 }
 
-// LogAdminOperation 记录管理员操作日志的中间件
+// auditBodyLimit 超过该大小的请求体不记录
+const auditBodyLimit = 64 * 1024
+
+// auditBodyPaths 记录脱敏请求体的路径（配置和用户变更）
+var auditBodyPaths = []string{
+	"/api/admin/configs",
+	"/api/admin/users",
+	"/api/admin/settings",
+	"/api/admin/system",
+	"/api/admin/email-config",
+}
+
+// LogAdminOperation 记录管理员操作日志的中间件。
+// 修改操作全部记录；配置和用户变更附带脱敏后的请求体，以及服务层通过 services.AddAuditChanges 记录的字段变更
 func LogAdminOperation(auditService services.AdminAuditServiceInterface) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
@@ -232,14 +247,23 @@ func LogAdminOperation(auditService services.AdminAuditServiceInterface) gin.Han
 		clientIP := c.ClientIP()
 		userAgent := c.Request.UserAgent()
 
+		important := auditService != nil && isImportantAdminOperation(method)
+		var requestBody string
+		if important {
+			var userIDPtr *uint
+			if userID, ok := GetCurrentUserID(c); ok {
+				userIDPtr = &userID
+			}
+			c.Request = c.Request.WithContext(services.WithAuditScope(c.Request.Context(), userIDPtr, clientIP, userAgent))
+			if shouldAuditBody(path) {
+				requestBody = readAuditBody(c)
+			}
+		}
+
 		// 执行下一个处理器
 		c.Next()
 
-		if auditService == nil {
-			return
-		}
-
-		if !isImportantAdminOperation(method, path) {
+		if !important {
 			return
 		}
 
@@ -262,9 +286,6 @@ func LogAdminOperation(auditService services.AdminAuditServiceInterface) gin.Han
 		}
 
 		ctx := c.Request.Context()
-		if ctx == nil {
-			ctx = context.Background()
-		}
 
 		action := fmt.Sprintf("%s %s", strings.ToUpper(method), path)
 		result := "success"
@@ -273,42 +294,60 @@ func LogAdminOperation(auditService services.AdminAuditServiceInterface) gin.Han
 		}
 
 		record := &services.AdminAuditRecord{
-			UserID:     userIDPtr,
-			Role:       role,
-			Action:     action,
-			Method:     method,
-			Path:       path,
-			StatusCode: statusCode,
-			ClientIP:   clientIP,
-			UserAgent:  userAgent,
-			Query:      query,
-			Latency:    latency,
-			Result:     result,
+			UserID:      userIDPtr,
+			Role:        role,
+			Action:      action,
+			Method:      method,
+			Path:        path,
+			StatusCode:  statusCode,
+			ClientIP:    clientIP,
+			UserAgent:   userAgent,
+			Query:       query,
+			Latency:     latency,
+			Result:      result,
+			RequestBody: requestBody,
+			Changes:     services.AuditChangesFrom(ctx),
 		}
 
-		if err := auditService.Record(ctx, record); err != nil {
+		if err := auditService.Record(context.WithoutCancel(ctx), record); err != nil {
 			fmt.Println("[ADMIN-OP] failed to record audit log:", err)
 		}
 	}
 }
 
-// isImportantAdminOperation 判断是否为重要的管理操作
-func isImportantAdminOperation(method, path string) bool {
-	// 定义需要记录的重要操作路径
-	importantPaths := []string{
-		"/api/admin/users",    // 用户管理
-		"/api/admin/settings", // 系统设置
-		"/api/admin/webhooks", // Webhook管理
-		"/api/admin/backup",   // 备份操作
+// isImportantAdminOperation 判断是否为需要记录的管理操作：挂载本中间件的路由上所有修改请求
+func isImportantAdminOperation(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
 	}
+	return true
+}
 
-	// 检查路径是否匹配
-	for _, importantPath := range importantPaths {
-		if strings.HasPrefix(path, importantPath) {
-			// 只记录非GET请求（修改操作）
-			return method != "GET"
+func shouldAuditBody(path string) bool {
+	for _, prefix := range auditBodyPaths {
+		if strings.HasPrefix(path, prefix) {
+			return true
 		}
 	}
-
 	return false
 }
+
+// readAuditBody 读取并还原请求体，返回脱敏后的内容
+func readAuditBody(c *gin.Context) string {
+	if c.Request.Body == nil {
+		return ""
+	}
+	if c.Request.ContentLength > auditBodyLimit {
+		return fmt.Sprintf("[body omitted, %d bytes]", c.Request.ContentLength)
+	}
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, auditBodyLimit+1))
+	c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), c.Request.Body))
+	if err != nil {
+		return ""
+	}
+	if len(body) > auditBodyLimit {
+		return fmt.Sprintf("[body omitted, more than %d bytes]", auditBodyLimit)
+	}
+	return services.RedactAuditBody(body)
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// ForwardPermissionDenials 将 401/403 响应作为权限拒绝事件转发到 SIEM
+func ForwardPermissionDenials(forwarder *services.AuditForwarder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		status := c.Writer.Status()
+		if status != http.StatusUnauthorized && status != http.StatusForbidden {
+			return
+		}
+
+		event := &services.AuditEvent{
+			Type:       models.AuditEventPermissionDenied,
+			Action:     fmt.Sprintf("%s %s", c.Request.Method, c.Request.URL.Path),
+			Result:     "denied",
+			ClientIP:   c.ClientIP(),
+			UserAgent:  c.Request.UserAgent(),
+			Method:     c.Request.Method,
+			Path:       c.Request.URL.Path,
+			StatusCode: status,
+		}
+		if userID, ok := GetCurrentUserID(c); ok {
+			event.UserID = &userID
+		}
+		if role, ok := GetCurrentUserRole(c); ok {
+			event.Role = role
+		}
+		forwarder.Emit(event)
+	}
+}
@@ -0,0 +1,297 @@
+package middleware
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CompressionConfig 响应压缩配置
+type CompressionConfig struct {
+	MinSize int // 响应体小于该字节数时不压缩
+	Level   int // 压缩级别 1-9，0 使用默认级别 5
+}
+
+// compressor 可复用的压缩写入器
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+	Reset(w io.Writer)
+}
+
+// compressionEncoder 支持的内容编码
+type compressionEncoder struct {
+	name string
+	new  func(level int) compressor
+}
+
+// compressionEncoders 按服务端偏好排序，客户端权重相同时选择靠前的编码。
+// brotli 需要引入第三方编码器，登记到此列表即可参与协商
+var compressionEncoders = []*compressionEncoder{
+	{name: "gzip", new: func(level int) compressor {
+		zw, _ := gzip.NewWriterLevel(io.Discard, level)
+		return zw
+	}},
+	{name: "deflate", new: func(level int) compressor {
+		zw, _ := flate.NewWriter(io.Discard, level)
+		return zw
+	}},
+}
+
+// compressibleTypes 会被压缩的响应类型前缀，图片、压缩包等二进制内容不再压缩
+var compressibleTypes = []string{
+	"text/",
+	"application/json",
+	"application/javascript",
+	"application/xml",
+	"application/x-ndjson",
+	"image/svg+xml",
+}
+
+// Compression 响应压缩中间件。按 Accept-Encoding 协商 gzip/deflate，
+// 响应体达到最小大小时才压缩；已设置 Content-Encoding、分段下载及 WebSocket 升级请求不处理
+func Compression(config CompressionConfig) gin.HandlerFunc {
+	if config.Level < flate.BestSpeed || config.Level > flate.BestCompression {
+		config.Level = 5
+	}
+	if config.MinSize < 0 {
+		config.MinSize = 0
+	}
+	// 复用压缩写入器，避免每个请求重新分配压缩字典
+	pools := make(map[*compressionEncoder]*sync.Pool, len(compressionEncoders))
+	for _, encoder := range compressionEncoders {
+		encoder := encoder
+		pools[encoder] = &sync.Pool{New: func() interface{} { return encoder.new(config.Level) }}
+	}
+
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodHead || c.GetHeader("Upgrade") != "" {
+			c.Next()
+			return
+		}
+		encoder := negotiateEncoding(c.GetHeader("Accept-Encoding"))
+		if encoder == nil {
+			c.Next()
+			return
+		}
+
+		w := &compressWriter{ResponseWriter: c.Writer, minSize: config.MinSize, encoding: encoder.name, pool: pools[encoder]}
+		c.Writer = w
+		defer func() {
+			if r := recover(); r != nil {
+				// 交给外层 Recovery 处理，丢弃尚未发送的内容
+				w.release()
+				c.Writer = w.ResponseWriter
+				panic(r)
+			}
+		}()
+
+		c.Next()
+		w.finish()
+		c.Writer = w.ResponseWriter
+	}
+}
+
+// negotiateEncoding 按 Accept-Encoding 的 q 值选择编码，q=0 表示不接受
+func negotiateEncoding(header string) *compressionEncoder {
+	if header == "" {
+		return nil
+	}
+	weights := make(map[string]float64)
+	wildcard := -1.0
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(value, 64); err == nil {
+				q = parsed
+			}
+		}
+		if name == "*" {
+			wildcard = q
+			continue
+		}
+		weights[name] = q
+	}
+
+	var best *compressionEncoder
+	bestQ := 0.0
+	for _, encoder := range compressionEncoders {
+		q, ok := weights[encoder.name]
+		if !ok {
+			q = wildcard
+		}
+		if q > bestQ {
+			best, bestQ = encoder, q
+		}
+	}
+	return best
+}
+
+// compressWriter 先缓存响应体，达到最小大小后决定是否压缩并开始输出
+type compressWriter struct {
+	gin.ResponseWriter
+	minSize  int
+	encoding string
+	pool     *sync.Pool
+
+	status  int
+	buf     []byte
+	started bool
+	zw      compressor
+}
+
+func (w *compressWriter) WriteHeader(code int) {
+	if !w.started && code > 0 {
+		w.status = code
+	}
+}
+
+// WriteHeaderNow 处理器要求立即发送响应头（如 304、流式输出），此时按已缓存的内容决定
+func (w *compressWriter) WriteHeaderNow() {
+	if !w.started {
+		w.start()
+	}
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if !w.started {
+		w.buf = append(w.buf, data...)
+		if len(w.buf) < w.minSize {
+			return len(data), nil
+		}
+		if err := w.start(); err != nil {
+			return 0, err
+		}
+		return len(data), nil
+	}
+	if w.zw != nil {
+		return w.zw.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *compressWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *compressWriter) Status() int {
+	if !w.started && w.status != 0 {
+		return w.status
+	}
+	return w.ResponseWriter.Status()
+}
+
+func (w *compressWriter) Written() bool {
+	return w.started || len(w.buf) > 0 || w.ResponseWriter.Written()
+}
+
+func (w *compressWriter) Size() int {
+	if !w.started {
+		if len(w.buf) == 0 {
+			return -1
+		}
+		return len(w.buf)
+	}
+	return w.ResponseWriter.Size()
+}
+
+func (w *compressWriter) Flush() {
+	if !w.started {
+		w.start()
+	}
+	if w.zw != nil {
+		w.zw.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// start 确定是否压缩，发送响应头及已缓存的内容
+func (w *compressWriter) start() error {
+	w.started = true
+	header := w.Header()
+	status := w.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if header.Get("Content-Type") == "" && len(w.buf) > 0 {
+		header.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+
+	if w.compressible(status) {
+		header.Add("Vary", "Accept-Encoding")
+		if len(w.buf) >= w.minSize {
+			header.Set("Content-Encoding", w.encoding)
+			header.Del("Content-Length")
+			// 压缩后内容不同，强 ETag 加上编码后缀，ETag 中间件比对时会去掉后缀
+			if etag := header.Get("ETag"); strings.HasPrefix(etag, `"`) && strings.HasSuffix(etag, `"`) {
+				header.Set("ETag", strings.TrimSuffix(etag, `"`)+"-"+w.encoding+`"`)
+			}
+			zw := w.pool.Get().(compressor)
+			zw.Reset(w.ResponseWriter)
+			w.zw = zw
+		}
+	}
+
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	buf := w.buf
+	w.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+	if w.zw != nil {
+		_, err := w.zw.Write(buf)
+		return err
+	}
+	_, err := w.ResponseWriter.Write(buf)
+	return err
+}
+
+func (w *compressWriter) compressible(status int) bool {
+	header := w.Header()
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusPartialContent || status == http.StatusNotModified {
+		return false
+	}
+	if header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" {
+		return false
+	}
+	contentType := strings.ToLower(header.Get("Content-Type"))
+	if strings.HasPrefix(contentType, "text/event-stream") {
+		return false
+	}
+	for _, prefix := range compressibleTypes {
+		if strings.HasPrefix(contentType, prefix) {
+			return true
+		}
+	}
+	return strings.Contains(contentType, "+json")
+}
+
+// finish 处理器返回后输出剩余内容并关闭压缩流
+func (w *compressWriter) finish() {
+	if !w.started && (w.status != 0 || len(w.buf) > 0) {
+		w.start()
+	}
+	if w.zw != nil {
+		w.zw.Close()
+		w.release()
+	}
+}
+
+func (w *compressWriter) release() {
+	if w.zw != nil {
+		w.zw.Reset(io.Discard)
+		w.pool.Put(w.zw)
+		w.zw = nil
+	}
+}
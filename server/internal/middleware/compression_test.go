@@ -0,0 +1,190 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// newCompressionRouter 创建挂载压缩中间件的测试路由
+func newCompressionRouter(minSize int) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Compression(CompressionConfig{MinSize: minSize}))
+
+	large := strings.Repeat(`{"ticket":"VPN 无法连接"},`, 200)
+	router.GET("/large", func(c *gin.Context) {
+		c.Header("Content-Length", strconv.Itoa(len(large)))
+		c.Data(http.StatusOK, "application/json; charset=utf-8", []byte(large))
+	})
+	router.GET("/small", func(c *gin.Context) {
+		c.Header("Content-Length", "11")
+		c.Data(http.StatusOK, "application/json; charset=utf-8", []byte(`{"ok":true}`))
+	})
+	router.GET("/encoded", func(c *gin.Context) {
+		c.Header("Content-Encoding", "gzip")
+		c.Data(http.StatusOK, "application/json", []byte(large))
+	})
+	router.GET("/image", func(c *gin.Context) {
+		c.Data(http.StatusOK, "image/png", []byte(large))
+	})
+	router.GET("/events", func(c *gin.Context) {
+		c.Header("Content-Type", "text/event-stream")
+		c.Status(http.StatusOK)
+		for i := 0; i < 3; i++ {
+			c.Writer.WriteString("data: " + large + "\n\n")
+			c.Writer.Flush()
+		}
+	})
+	router.GET("/partial", func(c *gin.Context) {
+		c.Header("Content-Range", "bytes 0-99/5000")
+		c.Data(http.StatusPartialContent, "application/json", []byte(large[:100]))
+	})
+	return router
+}
+
+// sendCompressionRequest 按给定的 Accept-Encoding 请求路径
+func sendCompressionRequest(router *gin.Engine, path, acceptEncoding string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+// decodeBody 按 Content-Encoding 解压响应体
+func decodeBody(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var reader io.Reader = bytes.NewReader(rec.Body.Bytes())
+	switch rec.Header().Get("Content-Encoding") {
+	case "gzip":
+		zr, err := gzip.NewReader(reader)
+		if err != nil {
+			t.Fatalf("invalid gzip body: %v", err)
+		}
+		reader = zr
+	case "deflate":
+		reader = flate.NewReader(reader)
+	}
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("failed to decode body: %v", err)
+	}
+	return string(body)
+}
+
+func TestCompression_NegotiatesEncoding(t *testing.T) {
+	router := newCompressionRouter(1024)
+	plain := decodeBody(t, sendCompressionRequest(router, "/large", ""))
+
+	for _, tc := range []struct {
+		acceptEncoding string
+		want           string
+	}{
+		{"", ""},
+		{"gzip", "gzip"},
+		{"deflate", "deflate"},
+		{"gzip, deflate, br", "gzip"},
+		{"gzip;q=0.5, deflate;q=0.8", "deflate"},
+		{"gzip;q=0, deflate;q=0", ""},
+		{"br", ""},
+		{"*", "gzip"},
+		{"*;q=0.1, gzip;q=0", "deflate"},
+		{"identity", ""},
+	} {
+		rec := sendCompressionRequest(router, "/large", tc.acceptEncoding)
+		if got := rec.Header().Get("Content-Encoding"); got != tc.want {
+			t.Errorf("Accept-Encoding %q: expected encoding %q, got %q", tc.acceptEncoding, tc.want, got)
+			continue
+		}
+		if body := decodeBody(t, rec); body != plain {
+			t.Errorf("Accept-Encoding %q: decoded body differs from the uncompressed response", tc.acceptEncoding)
+		}
+	}
+}
+
+func TestCompression_MinSizeAndHeaders(t *testing.T) {
+	router := newCompressionRouter(1024)
+
+	// 达到最小大小时压缩，去掉原始 Content-Length 并声明 Vary
+	rec := sendCompressionRequest(router, "/large", "gzip")
+	if rec.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatalf("expected large response to be compressed, got %v", rec.Header())
+	}
+	if got := rec.Header().Get("Content-Length"); got != "" {
+		t.Fatalf("expected Content-Length of the uncompressed body to be dropped, got %q", got)
+	}
+	if got := rec.Header().Get("Vary"); got != "Accept-Encoding" {
+		t.Fatalf("expected Vary: Accept-Encoding, got %q", got)
+	}
+	if rec.Body.Len() >= len(decodeBody(t, rec)) {
+		t.Fatalf("expected compressed body to be smaller")
+	}
+
+	// 小于最小大小时原样返回，保留 Content-Length，仍声明 Vary
+	rec = sendCompressionRequest(router, "/small", "gzip")
+	if got := rec.Header().Get("Content-Encoding"); got != "" {
+		t.Fatalf("expected small response not to be compressed, got %q", got)
+	}
+	if rec.Body.String() != `{"ok":true}` || rec.Header().Get("Content-Length") != "11" {
+		t.Fatalf("expected small response unchanged, got %q (Content-Length %q)", rec.Body.String(), rec.Header().Get("Content-Length"))
+	}
+	if got := rec.Header().Get("Vary"); got != "Accept-Encoding" {
+		t.Fatalf("expected Vary: Accept-Encoding on compressible response, got %q", got)
+	}
+
+	// 最小大小为 0 时小响应也压缩
+	rec = sendCompressionRequest(newCompressionRouter(0), "/small", "gzip")
+	if rec.Header().Get("Content-Encoding") != "gzip" || decodeBody(t, rec) != `{"ok":true}` {
+		t.Fatalf("expected small response to be compressed without a threshold, got %v", rec.Header())
+	}
+}
+
+func TestCompression_SkipsEncodedAndStreamingResponses(t *testing.T) {
+	router := newCompressionRouter(0)
+
+	// 已编码的响应不重复压缩
+	rec := sendCompressionRequest(router, "/encoded", "gzip")
+	if values := rec.Header().Values("Content-Encoding"); len(values) != 1 || values[0] != "gzip" {
+		t.Fatalf("expected the handler's Content-Encoding to be kept as is, got %v", values)
+	}
+	if strings.HasPrefix(rec.Body.String(), "\x1f\x8b") {
+		t.Fatalf("expected already encoded body not to be compressed again")
+	}
+
+	// 事件流逐条输出，不压缩
+	rec = sendCompressionRequest(router, "/events", "gzip")
+	if got := rec.Header().Get("Content-Encoding"); got != "" {
+		t.Fatalf("expected event stream not to be compressed, got %q", got)
+	}
+	if strings.Count(rec.Body.String(), "data: ") != 3 || !rec.Flushed {
+		t.Fatalf("expected event stream to be flushed uncompressed")
+	}
+
+	// 二进制内容、分段下载及 WebSocket 升级请求不压缩
+	for name, rec := range map[string]*httptest.ResponseRecorder{
+		"image":     sendCompressionRequest(router, "/image", "gzip"),
+		"partial":   sendCompressionRequest(router, "/partial", "gzip"),
+		"websocket": sendCompressionRequest(router, "/large", "gzip", "Upgrade", "websocket", "Connection", "Upgrade"),
+	} {
+		if got := rec.Header().Get("Content-Encoding"); got != "" {
+			t.Errorf("%s: expected no compression, got %q", name, got)
+		}
+		if got := rec.Header().Get("Vary"); got != "" {
+			t.Errorf("%s: expected no Vary header, got %q", name, got)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/services"
+)
+
+// ConcurrencyLimit 高开销接口并发限流中间件
+// 超出分组并发上限的请求排队等待，排队已满返回429，排队超时返回503，均带 Retry-After 提示
+func ConcurrencyLimit(limiter *services.ConcurrencyLimiter, group string) gin.HandlerFunc {
+	return ConcurrencyLimitIf(limiter, group, nil)
+}
+
+// ConcurrencyLimitIf 仅对满足条件的请求限流，如仅限制带搜索关键字的列表查询
+func ConcurrencyLimitIf(limiter *services.ConcurrencyLimiter, group string, match func(c *gin.Context) bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if match != nil && !match(c) {
+			c.Next()
+			return
+		}
+
+		release, limit, err := limiter.Acquire(c.Request.Context(), group)
+		if err != nil {
+			retryAfter := 1
+			if limit != nil && limit.QueueTimeoutMs > 1000 {
+				retryAfter = (limit.QueueTimeoutMs + 999) / 1000
+			}
+			status := http.StatusServiceUnavailable
+			code := "concurrency_queue_timeout"
+			if errors.Is(err, services.ErrConcurrencyQueueFull) {
+				status = http.StatusTooManyRequests
+				code = "concurrency_limit_exceeded"
+			}
+
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.AbortWithStatusJSON(status, gin.H{
+				"success":     false,
+				"error":       code,
+				"message":     "服务繁忙，请稍后重试",
+				"group":       group,
+				"retry_after": retryAfter,
+			})
+			return
+		}
+		defer release()
+
+		c.Next()
+	}
+}
@@ -0,0 +1,184 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestConcurrencyLimit_QueuesRejectsAndTimesOut(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open("file:concurrency_middleware?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&models.SystemConfig{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+	ctx := context.Background()
+
+	limiter := services.NewConcurrencyLimiter(db)
+	if err := limiter.SetPolicy(ctx, &models.ConcurrencyLimitPolicy{Enabled: true, Groups: map[string]*models.ConcurrencyLimit{
+		models.ConcurrencyGroupExport:    {MaxConcurrent: 1, MaxQueue: 1, QueueTimeoutMs: 5000},
+		models.ConcurrencyGroupAnalytics: {MaxConcurrent: 1, MaxQueue: 1, QueueTimeoutMs: 100},
+		models.ConcurrencyGroupSearch:    {MaxConcurrent: 1, MaxQueue: 0, QueueTimeoutMs: 0},
+	}}, 1); err != nil {
+		t.Fatalf("failed to set concurrency policy: %v", err)
+	}
+
+	// 处理器进入后阻塞，直到测试放行
+	entered := make(chan string, 4)
+	gates := map[string]chan struct{}{
+		models.ConcurrencyGroupExport:    make(chan struct{}),
+		models.ConcurrencyGroupAnalytics: make(chan struct{}),
+		models.ConcurrencyGroupSearch:    make(chan struct{}),
+	}
+	blocking := func(group string) gin.HandlerFunc {
+		return func(c *gin.Context) {
+			entered <- group
+			<-gates[group]
+			c.Status(http.StatusNoContent)
+		}
+	}
+	router := gin.New()
+	router.GET("/api/export", ConcurrencyLimit(limiter, models.ConcurrencyGroupExport), blocking(models.ConcurrencyGroupExport))
+	router.GET("/api/analytics", ConcurrencyLimit(limiter, models.ConcurrencyGroupAnalytics), blocking(models.ConcurrencyGroupAnalytics))
+	router.GET("/api/tickets", ConcurrencyLimitIf(limiter, models.ConcurrencyGroupSearch, func(c *gin.Context) bool {
+		return c.Query("search") != ""
+	}), func(c *gin.Context) {
+		if c.Query("search") != "" {
+			entered <- models.ConcurrencyGroupSearch
+			<-gates[models.ConcurrencyGroupSearch]
+		}
+		c.Status(http.StatusNoContent)
+	})
+
+	send := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+	sendAsync := func(wg *sync.WaitGroup, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		}()
+		return rec
+	}
+	waitQueued := func(group string, queued int) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			for _, m := range limiter.Metrics(ctx) {
+				if m.Group == group && m.Queued == queued {
+					return
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		t.Fatalf("timed out waiting for %d queued %s requests", queued, group)
+	}
+	// 每个阶段结束后清空已进入处理器的信号，避免影响下一阶段的等待
+	drain := func() {
+		for {
+			select {
+			case <-entered:
+			default:
+				return
+			}
+		}
+	}
+	decode := func(rec *httptest.ResponseRecorder) map[string]interface{} {
+		t.Helper()
+		var body map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("invalid response body %q: %v", rec.Body.String(), err)
+		}
+		return body
+	}
+
+	// 名额用完后排队，排队已满返回 429，放行后排队的请求继续处理
+	var wg sync.WaitGroup
+	first := sendAsync(&wg, "/api/export")
+	<-entered
+	queued := sendAsync(&wg, "/api/export")
+	waitQueued(models.ConcurrencyGroupExport, 1)
+	rec := send("/api/export")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "5" {
+		t.Fatalf("expected 429 with Retry-After 5 when the queue is full, got %d (%q)", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if body := decode(rec); body["error"] != "concurrency_limit_exceeded" || body["group"] != models.ConcurrencyGroupExport {
+		t.Fatalf("unexpected 429 body: %v", body)
+	}
+	close(gates[models.ConcurrencyGroupExport])
+	wg.Wait()
+	if first.Code != http.StatusNoContent || queued.Code != http.StatusNoContent {
+		t.Fatalf("expected running and queued requests to complete, got %d and %d", first.Code, queued.Code)
+	}
+	if rec := send("/api/export"); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected slots to be released, got %d", rec.Code)
+	}
+	drain()
+
+	// 排队超时返回 503
+	running := sendAsync(&wg, "/api/analytics")
+	<-entered
+	rec = send("/api/analytics")
+	if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") != "1" {
+		t.Fatalf("expected 503 with Retry-After 1 on queue timeout, got %d (%q)", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if body := decode(rec); body["error"] != "concurrency_queue_timeout" {
+		t.Fatalf("unexpected 503 body: %v", body)
+	}
+	close(gates[models.ConcurrencyGroupAnalytics])
+	wg.Wait()
+	if running.Code != http.StatusNoContent {
+		t.Fatalf("expected running request to complete, got %d", running.Code)
+	}
+	drain()
+
+	// 条件限流：只限制带搜索关键字的请求
+	searching := sendAsync(&wg, "/api/tickets?search=vpn")
+	<-entered
+	if rec := send("/api/tickets?search=printer"); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected second search to be rejected, got %d", rec.Code)
+	}
+	if rec := send("/api/tickets?status=open"); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected plain list request to bypass the limit, got %d", rec.Code)
+	}
+	close(gates[models.ConcurrencyGroupSearch])
+	wg.Wait()
+	if searching.Code != http.StatusNoContent {
+		t.Fatalf("expected running search to complete, got %d", searching.Code)
+	}
+	drain()
+
+	// 关闭限流后直接放行
+	if err := limiter.SetPolicy(ctx, &models.ConcurrencyLimitPolicy{Enabled: false}, 1); err != nil {
+		t.Fatalf("failed to disable concurrency limits: %v", err)
+	}
+	gates[models.ConcurrencyGroupExport] = make(chan struct{})
+	for i := 0; i < 3; i++ {
+		sendAsync(&wg, "/api/export")
+		<-entered
+	}
+	close(gates[models.ConcurrencyGroupExport])
+	wg.Wait()
+}
@@ -116,6 +116,9 @@ func CORS(config *CORSConfig) func(HTTPContext) {
 
 		// 设置 CORS 响应头
 		if allowOrigin != "" {
+			if allowOrigin != "*" {
+				setHeader(c, "Vary", "Origin")
+			}
 			setHeader(c, "Access-Control-Allow-Origin", allowOrigin)
 		}
 
@@ -134,8 +137,8 @@ func CORS(config *CORSConfig) func(HTTPContext) {
 			setHeader(c, "Access-Control-Expose-Headers", strings.Join(config.ExposeHeaders, ", "))
 		}
 
-		// 设置是否允许凭据
-		if config.AllowCredentials {
+		// 设置是否允许凭据（仅对允许的具体源；"*" 不能与凭据同时使用）
+		if config.AllowCredentials && allowOrigin != "" && allowOrigin != "*" {
 			setHeader(c, "Access-Control-Allow-Credentials", "true")
 		}
 
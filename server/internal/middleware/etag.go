@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CacheControl 为路由设置 Cache-Control，处理器可自行覆盖
+func CacheControl(value string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", value)
+		c.Next()
+	}
+}
+
+// ETag 为可缓存的 GET 接口按响应体计算强 ETag 并设置 Cache-Control，
+// If-None-Match 匹配时返回 304 且不发送响应体。只处理 200 响应，处理器已设置 ETag 时保留原值
+func ETag(cacheControl string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			c.Next()
+			return
+		}
+
+		w := &etagWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		defer func() {
+			if r := recover(); r != nil {
+				c.Writer = w.ResponseWriter
+				panic(r)
+			}
+		}()
+
+		c.Next()
+		c.Writer = w.ResponseWriter
+
+		status := w.Status()
+		header := c.Writer.Header()
+		if status == http.StatusOK {
+			etag := header.Get("ETag")
+			if etag == "" {
+				sum := sha256.Sum256(w.buf)
+				etag = `"` + hex.EncodeToString(sum[:16]) + `"`
+				header.Set("ETag", etag)
+			}
+			header.Set("Cache-Control", cacheControl)
+			if etagMatches(c.GetHeader("If-None-Match"), etag) {
+				header.Del("Content-Type")
+				header.Del("Content-Length")
+				c.Writer.WriteHeader(http.StatusNotModified)
+				c.Writer.WriteHeaderNow()
+				return
+			}
+		}
+
+		if w.status != 0 {
+			c.Writer.WriteHeader(w.status)
+		}
+		if len(w.buf) > 0 {
+			c.Writer.Write(w.buf)
+		}
+	}
+}
+
+// etagMatches If-None-Match 采用弱比较，并忽略压缩中间件追加的编码后缀
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+	if strings.TrimSpace(ifNoneMatch) == "*" {
+		return true
+	}
+	want := normalizeETag(etag)
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		if normalizeETag(candidate) == want {
+			return true
+		}
+	}
+	return false
+}
+
+func normalizeETag(etag string) string {
+	etag = strings.TrimPrefix(strings.TrimSpace(etag), "W/")
+	if !strings.HasSuffix(etag, `"`) {
+		return etag
+	}
+	for _, encoder := range compressionEncoders {
+		if trimmed, ok := strings.CutSuffix(etag, "-"+encoder.name+`"`); ok {
+			return trimmed + `"`
+		}
+	}
+	return etag
+}
+
+// etagWriter 缓存完整响应，处理器返回后再计算 ETag
+type etagWriter struct {
+	gin.ResponseWriter
+	status int
+	buf    []byte
+}
+
+func (w *etagWriter) WriteHeader(code int) {
+	if code > 0 {
+		w.status = code
+	}
+}
+
+func (w *etagWriter) WriteHeaderNow() {}
+
+func (w *etagWriter) Write(data []byte) (int, error) {
+	w.buf = append(w.buf, data...)
+	return len(data), nil
+}
+
+func (w *etagWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *etagWriter) Status() int {
+	if w.status != 0 {
+		return w.status
+	}
+	return http.StatusOK
+}
+
+func (w *etagWriter) Written() bool {
+	return w.status != 0 || len(w.buf) > 0
+}
+
+func (w *etagWriter) Size() int {
+	if len(w.buf) == 0 {
+		return -1
+	}
+	return len(w.buf)
+}
+
+func (w *etagWriter) Flush() {}
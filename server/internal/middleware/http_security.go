@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// HTTPSecurityManager 可热更新的CORS及安全响应头中间件管理器
+type HTTPSecurityManager struct {
+	service  *services.HTTPSecurityService
+	mu       sync.RWMutex
+	cors     func(HTTPContext)
+	security func(HTTPContext)
+}
+
+// NewHTTPSecurityManager 创建HTTP安全中间件管理器，并立即加载一次策略
+func NewHTTPSecurityManager(service *services.HTTPSecurityService) *HTTPSecurityManager {
+	m := &HTTPSecurityManager{service: service}
+	if err := m.Reload(context.Background()); err != nil {
+		log.Printf("Warning: failed to load http security policy, using defaults: %v", err)
+		m.Apply(models.GetDefaultHTTPSecurityPolicy())
+	}
+	return m
+}
+
+// Apply 应用新的策略
+func (m *HTTPSecurityManager) Apply(policy *models.HTTPSecurityPolicy) {
+	corsConfig := DefaultCORSConfig()
+	corsConfig.AllowOrigins = policy.AllowedOrigins
+	corsConfig.AllowMethods = policy.AllowedMethods
+	corsConfig.AllowHeaders = policy.AllowedHeaders
+	corsConfig.ExposeHeaders = policy.ExposeHeaders
+	corsConfig.AllowCredentials = policy.AllowCredentials
+	corsConfig.MaxAge = policy.MaxAge
+
+	securityConfig := &SecurityConfig{
+		ContentTypeNosniff:    true,
+		XFrameOptions:         policy.XFrameOptions,
+		HSTSMaxAge:            policy.HSTSMaxAge,
+		HSTSIncludeSubdomains: policy.HSTSIncludeSubdomains,
+		HSTSPreload:           policy.HSTSPreload,
+		ContentSecurityPolicy: policy.ContentSecurityPolicy,
+		ReferrerPolicy:        policy.ReferrerPolicy,
+		PermissionsPolicy:     policy.PermissionsPolicy,
+		CustomHeaders:         make(map[string]string),
+	}
+
+	m.mu.Lock()
+	m.cors = CORS(corsConfig)
+	m.security = SecurityMiddleware(securityConfig)
+	m.mu.Unlock()
+}
+
+// Reload 从配置存储重新加载策略
+func (m *HTTPSecurityManager) Reload(ctx context.Context) error {
+	policy, err := m.service.GetPolicy(ctx)
+	if err != nil {
+		return err
+	}
+	m.Apply(policy)
+	return nil
+}
+
+// StartAutoReload 定期重新加载策略，使其他实例上的配置修改也能生效
+func (m *HTTPSecurityManager) StartAutoReload(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := m.Reload(ctx); err != nil {
+					log.Printf("Warning: failed to reload http security policy: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// SecurityHeadersHandler 返回写入安全响应头的Gin中间件
+func (m *HTTPSecurityManager) SecurityHeadersHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		m.mu.RLock()
+		security := m.security
+		m.mu.RUnlock()
+
+		security(NewGinHTTPContext(c))
+	}
+}
+
+// CORSHandler 返回处理CORS（含预检请求）的Gin中间件
+func (m *HTTPSecurityManager) CORSHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		m.mu.RLock()
+		cors := m.cors
+		m.mu.RUnlock()
+
+		cors(NewGinHTTPContext(c))
+	}
+}
@@ -0,0 +1,206 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// newHTTPSecurityRouter 创建挂载 CORS 及安全响应头中间件的测试路由
+func newHTTPSecurityRouter(manager *HTTPSecurityManager) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(manager.SecurityHeadersHandler())
+	router.Use(manager.CORSHandler())
+	router.GET("/api/ping", func(c *gin.Context) { c.String(http.StatusOK, "pong") })
+	return router
+}
+
+// sendCORSRequest 发送带 Origin 的请求，method 为 OPTIONS 时作为预检请求
+func sendCORSRequest(router *gin.Engine, method, origin string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/api/ping", nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHTTPSecurity_CORSOriginsAndPreflight(t *testing.T) {
+	manager := &HTTPSecurityManager{}
+	policy := models.GetDefaultHTTPSecurityPolicy()
+	policy.AllowedOrigins = []string{"https://app.example.com", "*.partner.example.com"}
+	manager.Apply(policy)
+	router := newHTTPSecurityRouter(manager)
+
+	// 允许的源回写该源并允许凭据
+	rec := sendCORSRequest(router, http.MethodGet, "https://app.example.com", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Fatalf("expected allowed origin to be echoed, got %q", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+		t.Fatalf("expected credentials to be allowed, got %q", got)
+	}
+	if got := rec.Header().Get("Vary"); got != "Origin" {
+		t.Fatalf("expected Vary: Origin, got %q", got)
+	}
+	if got := sendCORSRequest(router, http.MethodGet, "https://eu.partner.example.com", nil).Header().Get("Access-Control-Allow-Origin"); got != "https://eu.partner.example.com" {
+		t.Fatalf("expected wildcard subdomain to be allowed, got %q", got)
+	}
+
+	// 不允许的源不返回 Allow-Origin，也不允许凭据
+	rec = sendCORSRequest(router, http.MethodGet, "https://evil.example.org", nil)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Fatalf("expected disallowed origin to get no Allow-Origin, got %q", got)
+	}
+	if got := rec.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+		t.Fatalf("expected disallowed origin to get no Allow-Credentials, got %q", got)
+	}
+
+	// 预检请求：允许的方法和请求头返回 204，否则拒绝
+	rec = sendCORSRequest(router, http.MethodOptions, "https://app.example.com", map[string]string{
+		"Access-Control-Request-Method":  http.MethodPatch,
+		"Access-Control-Request-Headers": "Content-Type, X-CSRF-Token",
+	})
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected preflight to return 204, got %d", rec.Code)
+	}
+	if rec.Header().Get("Access-Control-Allow-Methods") == "" || rec.Header().Get("Access-Control-Max-Age") != "86400" {
+		t.Fatalf("expected preflight to advertise methods and max age, got %v", rec.Header())
+	}
+	if rec.Body.Len() != 0 {
+		t.Fatalf("expected preflight not to reach the handler, got %q", rec.Body.String())
+	}
+	rec = sendCORSRequest(router, http.MethodOptions, "https://app.example.com", map[string]string{
+		"Access-Control-Request-Method": "TRACE",
+	})
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected preflight with disallowed method to return 405, got %d", rec.Code)
+	}
+	rec = sendCORSRequest(router, http.MethodOptions, "https://app.example.com", map[string]string{
+		"Access-Control-Request-Method":  http.MethodPost,
+		"Access-Control-Request-Headers": "X-Custom-Secret",
+	})
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected preflight with disallowed header to return 403, got %d", rec.Code)
+	}
+}
+
+func TestHTTPSecurity_WildcardNeverAllowsCredentials(t *testing.T) {
+	manager := &HTTPSecurityManager{}
+	policy := models.GetDefaultHTTPSecurityPolicy()
+	policy.AllowedOrigins = []string{"*"}
+	policy.AllowCredentials = true
+	manager.Apply(policy)
+	router := newHTTPSecurityRouter(manager)
+
+	for _, method := range []string{http.MethodGet, http.MethodOptions} {
+		rec := sendCORSRequest(router, method, "https://any.example.net", map[string]string{
+			"Access-Control-Request-Method": http.MethodGet,
+		})
+		if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+			t.Fatalf("%s: expected Allow-Origin *, got %q", method, got)
+		}
+		if got := rec.Header().Values("Access-Control-Allow-Credentials"); len(got) != 0 {
+			t.Fatalf("%s: expected no Allow-Credentials with wildcard origin, got %v", method, got)
+		}
+	}
+}
+
+func TestHTTPSecurity_ReloadAppliesStoredPolicy(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:http_security_middleware?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&models.SystemConfig{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+	ctx := context.Background()
+
+	defaults := models.GetDefaultHTTPSecurityPolicy()
+	defaults.AllowedOrigins = []string{"https://app.example.com"}
+	service := services.NewHTTPSecurityService(db, defaults)
+	manager := NewHTTPSecurityManager(service)
+	router := newHTTPSecurityRouter(manager)
+
+	rec := sendCORSRequest(router, http.MethodGet, "https://app.example.com", nil)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Fatalf("expected default origin to be allowed, got %q", got)
+	}
+	if rec.Header().Get("X-Frame-Options") != "DENY" || rec.Header().Get("X-Content-Type-Options") != "nosniff" ||
+		rec.Header().Get("Content-Security-Policy") == "" {
+		t.Fatalf("expected default security headers, got %v", rec.Header())
+	}
+
+	// 保存的策略在 Reload 后生效
+	updated := models.GetDefaultHTTPSecurityPolicy()
+	updated.AllowedOrigins = []string{"https://new.example.com"}
+	updated.XFrameOptions = "SAMEORIGIN"
+	updated.HSTSMaxAge = 0
+	if err := service.SetPolicy(ctx, updated, 1); err != nil {
+		t.Fatalf("failed to save policy: %v", err)
+	}
+	if got := sendCORSRequest(router, http.MethodGet, "https://new.example.com", nil).Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Fatalf("expected saved policy to wait for reload, got %q", got)
+	}
+	if err := manager.Reload(ctx); err != nil {
+		t.Fatalf("reload failed: %v", err)
+	}
+	rec = sendCORSRequest(router, http.MethodGet, "https://new.example.com", nil)
+	if got := rec.Header().Get("Access-Control-Allow-Origin"); got != "https://new.example.com" {
+		t.Fatalf("expected reloaded origin to be allowed, got %q", got)
+	}
+	if rec.Header().Get("X-Frame-Options") != "SAMEORIGIN" || rec.Header().Get("Strict-Transport-Security") != "" {
+		t.Fatalf("expected reloaded security headers, got %v", rec.Header())
+	}
+	if got := sendCORSRequest(router, http.MethodGet, "https://app.example.com", nil).Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Fatalf("expected the previous origin to be dropped after reload, got %q", got)
+	}
+}
+
+func TestHTTPSecurity_ValidateRejectsBadDefaults(t *testing.T) {
+	if err := models.GetDefaultHTTPSecurityPolicy().Validate(); err != nil {
+		t.Fatalf("expected built-in defaults to be valid, got %v", err)
+	}
+
+	for name, mutate := range map[string]func(*models.HTTPSecurityPolicy){
+		"wildcard with credentials": func(p *models.HTTPSecurityPolicy) { p.AllowedOrigins = []string{"https://app.example.com", "*"} },
+		"no origins":                func(p *models.HTTPSecurityPolicy) { p.AllowedOrigins = nil },
+		"max age too long":          func(p *models.HTTPSecurityPolicy) { p.MaxAge = 86401 },
+		"negative hsts":             func(p *models.HTTPSecurityPolicy) { p.HSTSMaxAge = -1 },
+		"unknown frame option":      func(p *models.HTTPSecurityPolicy) { p.XFrameOptions = "ALLOW-FROM https://example.com" },
+	} {
+		policy := models.GetDefaultHTTPSecurityPolicy()
+		mutate(policy)
+		if err := policy.Validate(); err == nil {
+			t.Errorf("%s: expected validation error", name)
+		}
+	}
+
+	// 不携带凭据时允许 "*"
+	policy := models.GetDefaultHTTPSecurityPolicy()
+	policy.AllowedOrigins = []string{"*"}
+	policy.AllowCredentials = false
+	if err := policy.Validate(); err != nil {
+		t.Fatalf("expected wildcard without credentials to be valid, got %v", err)
+	}
+}
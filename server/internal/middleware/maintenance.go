@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/services"
+)
+
+// MaintenanceMode 只读维护模式中间件
+// 维护模式生效期间，除豁免路径（默认为认证及管理员接口）外的写操作统一返回503
+func MaintenanceMode(service *services.MaintenanceService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+
+		mode := service.CurrentMode(c.Request.Context())
+		now := time.Now()
+		if !mode.IsActive(now) {
+			c.Next()
+			return
+		}
+
+		path := c.Request.URL.Path
+		for _, prefix := range mode.ExemptPrefixes {
+			if prefix != "" && strings.HasPrefix(path, prefix) {
+				c.Next()
+				return
+			}
+		}
+
+		response := gin.H{
+			"success": false,
+			"error":   "maintenance_mode",
+			"message": mode.Message,
+		}
+		if mode.EndAt != nil {
+			c.Header("Retry-After", strconv.Itoa(int(mode.EndAt.Sub(now).Seconds())+1))
+			response["end_at"] = mode.EndAt
+		}
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, response)
+	}
+}
@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestMaintenanceMode_BlocksWritesWhileActive(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open("file:maintenance_middleware?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&models.SystemConfig{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+	ctx := context.Background()
+	service := services.NewMaintenanceService(db)
+
+	router := gin.New()
+	router.Use(MaintenanceMode(service))
+	ok := func(c *gin.Context) { c.Status(http.StatusNoContent) }
+	router.GET("/api/tickets", ok)
+	router.POST("/api/tickets", ok)
+	router.DELETE("/api/tickets/:id", ok)
+	router.POST("/api/auth/login", ok)
+	router.PUT("/api/admin/system/maintenance", ok)
+
+	send := func(method, path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, path, nil))
+		return rec
+	}
+	setMode := func(mode *models.MaintenanceMode) {
+		t.Helper()
+		if err := service.SetMode(ctx, mode, 1); err != nil {
+			t.Fatalf("failed to set maintenance mode: %v", err)
+		}
+	}
+
+	// 未开启时写操作正常处理
+	if rec := send(http.MethodPost, "/api/tickets"); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected writes to pass when maintenance is off, got %d", rec.Code)
+	}
+
+	// 开启后写操作返回 503，读操作及豁免路径不受影响
+	setMode(&models.MaintenanceMode{Enabled: true, Message: "数据库升级中"})
+	for _, method := range []string{http.MethodPost, http.MethodDelete} {
+		path := "/api/tickets"
+		if method == http.MethodDelete {
+			path = "/api/tickets/1"
+		}
+		rec := send(method, path)
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Fatalf("%s: expected 503 during maintenance, got %d", method, rec.Code)
+		}
+		var body map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s: invalid response body: %v", method, err)
+		}
+		if body["error"] != "maintenance_mode" || body["message"] != "数据库升级中" {
+			t.Fatalf("%s: unexpected response body %v", method, body)
+		}
+		if got := rec.Header().Get("Retry-After"); got != "" {
+			t.Fatalf("%s: expected no Retry-After without an end time, got %q", method, got)
+		}
+	}
+	for _, tc := range []struct{ method, path string }{
+		{http.MethodGet, "/api/tickets"},
+		{http.MethodHead, "/api/tickets"},
+		{http.MethodPost, "/api/auth/login"},
+		{http.MethodPut, "/api/admin/system/maintenance"},
+	} {
+		if rec := send(tc.method, tc.path); rec.Code == http.StatusServiceUnavailable {
+			t.Fatalf("%s %s: expected to pass during maintenance, got 503", tc.method, tc.path)
+		}
+	}
+
+	// 预约的维护窗口：开始前不生效，期间返回 Retry-After，结束后自动恢复
+	start := time.Now().Add(time.Hour)
+	setMode(&models.MaintenanceMode{Enabled: true, StartAt: &start})
+	if rec := send(http.MethodPost, "/api/tickets"); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected writes to pass before the window starts, got %d", rec.Code)
+	}
+	start = time.Now().Add(-time.Minute)
+	end := time.Now().Add(10 * time.Minute)
+	setMode(&models.MaintenanceMode{Enabled: true, StartAt: &start, EndAt: &end})
+	rec := send(http.MethodPost, "/api/tickets")
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 inside the window, got %d", rec.Code)
+	}
+	retryAfter, err := strconv.Atoi(rec.Header().Get("Retry-After"))
+	if err != nil || retryAfter <= 0 || retryAfter > 601 {
+		t.Fatalf("expected Retry-After until the window ends, got %q", rec.Header().Get("Retry-After"))
+	}
+	start = time.Now().Add(-time.Hour)
+	end = time.Now().Add(-time.Minute)
+	setMode(&models.MaintenanceMode{Enabled: true, StartAt: &start, EndAt: &end})
+	if rec := send(http.MethodPost, "/api/tickets"); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected writes to pass after the window ends, got %d", rec.Code)
+	}
+
+	// 自定义豁免路径
+	setMode(&models.MaintenanceMode{Enabled: true, ExemptPrefixes: []string{"/api/tickets"}})
+	if rec := send(http.MethodPost, "/api/tickets"); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected custom exempt prefix to pass, got %d", rec.Code)
+	}
+	if rec := send(http.MethodPost, "/api/auth/login"); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected default exemptions to be replaced, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/services"
+)
+
+// PaginationGuard 列表接口分页守卫中间件
+// 带分页参数的 GET 请求每页条数超过上限或翻页过深时返回400，耗时超过阈值时记录慢查询日志
+func PaginationGuard(guard *services.PaginationGuard) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet || c.FullPath() == "" {
+			c.Next()
+			return
+		}
+
+		endpoint := c.Request.Method + " " + c.FullPath()
+		query := c.Request.URL.Query()
+		if _, configured := guard.CurrentPolicy(c.Request.Context()).MaxPageSizes[endpoint]; !configured && !services.IsListRequest(query) {
+			c.Next()
+			return
+		}
+
+		if err := guard.Check(c.Request.Context(), endpoint, query); err != nil {
+			AbortPagination(c, err)
+			return
+		}
+
+		start := time.Now()
+		c.Next()
+		guard.ObserveList(c.Request.Context(), endpoint, c.Request.URL.RawQuery, time.Since(start))
+	}
+}
+
+// AbortPagination 以400中止超出分页限制的请求，分页参数在请求体中的接口也使用同样的响应
+func AbortPagination(c *gin.Context, err error) {
+	response := gin.H{
+		"success": false,
+		"error":   "page_size_too_large",
+		"message": err.Error(),
+	}
+	if errors.Is(err, services.ErrPaginationTooDeep) {
+		response["error"] = "pagination_too_deep"
+		response["hint"] = "请缩小筛选范围，或改用游标分页（GET /api/sync、GET /api/tickets/changes）"
+	}
+	c.AbortWithStatusJSON(http.StatusBadRequest, response)
+}
+
+// IsPaginationError 错误是否为超出分页限制
+func IsPaginationError(err error) bool {
+	return errors.Is(err, services.ErrPageSizeTooLarge) || errors.Is(err, services.ErrPaginationTooDeep)
+}
@@ -0,0 +1,118 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestPaginationGuard_QueryParamLimits(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, err := gorm.Open(sqlite.Open("file:pagination_middleware?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+	if err := db.AutoMigrate(&models.SystemConfig{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+	ctx := context.Background()
+	guard := services.NewPaginationGuard(db)
+	if err := guard.SetPolicy(ctx, &models.PaginationPolicy{Enabled: true, DefaultMaxPageSize: 50, MaxOffset: 1000,
+		MaxPageSizes: map[string]int{"GET /api/admin/audit-logs": 200}}, 1); err != nil {
+		t.Fatalf("failed to set pagination policy: %v", err)
+	}
+
+	router := gin.New()
+	router.Use(PaginationGuard(guard))
+	ok := func(c *gin.Context) { c.Status(http.StatusNoContent) }
+	router.GET("/api/tickets", ok)
+	router.GET("/api/tickets/:id", ok)
+	router.POST("/api/tickets", ok)
+	router.GET("/api/admin/audit-logs", ok)
+
+	send := func(method, target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+		return rec
+	}
+	rejected := func(target, code string) map[string]interface{} {
+		t.Helper()
+		rec := send(http.MethodGet, target)
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d", target, rec.Code)
+		}
+		var body map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s: invalid response body: %v", target, err)
+		}
+		if body["error"] != code {
+			t.Fatalf("%s: expected error %q, got %v", target, code, body)
+		}
+		return body
+	}
+
+	// 每页条数：page_size 与 limit 均受上限约束，按接口单独配置的上限优先
+	for _, target := range []string{
+		"/api/tickets?page=1&page_size=50",
+		"/api/tickets?limit=50",
+		"/api/admin/audit-logs?limit=200",
+		"/api/tickets?page_size=abc",
+	} {
+		if rec := send(http.MethodGet, target); rec.Code != http.StatusNoContent {
+			t.Errorf("%s: expected 204, got %d", target, rec.Code)
+		}
+	}
+	rejected("/api/tickets?page_size=51", "page_size_too_large")
+	rejected("/api/tickets?page_size=10&limit=500", "page_size_too_large")
+	rejected("/api/admin/audit-logs?limit=201", "page_size_too_large")
+
+	// 跳过的记录数：offset 优先，否则按 (page-1)×每页条数
+	for _, target := range []string{
+		"/api/tickets?offset=1000&limit=10",
+		"/api/tickets?page=21&page_size=50",
+	} {
+		if rec := send(http.MethodGet, target); rec.Code != http.StatusNoContent {
+			t.Errorf("%s: expected 204, got %d", target, rec.Code)
+		}
+	}
+	if body := rejected("/api/tickets?offset=1001&limit=10", "pagination_too_deep"); body["hint"] == nil {
+		t.Fatalf("expected deep pagination to suggest cursor pagination, got %v", body)
+	}
+	rejected("/api/tickets?page=22&page_size=50", "pagination_too_deep")
+	rejected("/api/tickets?page=60", "pagination_too_deep")
+
+	// 非列表请求、写操作及未匹配的路由不受影响
+	for _, tc := range []struct{ method, target string }{
+		{http.MethodGet, "/api/tickets/1"},
+		{http.MethodGet, "/api/tickets?status=open"},
+		{http.MethodPost, "/api/tickets?page_size=1000"},
+	} {
+		if rec := send(tc.method, tc.target); rec.Code != http.StatusNoContent {
+			t.Errorf("%s %s: expected 204, got %d", tc.method, tc.target, rec.Code)
+		}
+	}
+	if rec := send(http.MethodGet, "/api/unknown?page_size=1000"); rec.Code != http.StatusNotFound {
+		t.Errorf("expected unmatched route to fall through to 404, got %d", rec.Code)
+	}
+
+	// 关闭限制后全部放行
+	if err := guard.SetPolicy(ctx, &models.PaginationPolicy{Enabled: false, DefaultMaxPageSize: 50}, 1); err != nil {
+		t.Fatalf("failed to disable pagination policy: %v", err)
+	}
+	if rec := send(http.MethodGet, "/api/tickets?page_size=1000&offset=999999"); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected disabled policy to allow everything, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// RateLimitStore 分布式限流计数存储，database.ResilientRedis 满足该接口
+type RateLimitStore interface {
+	Incr(ctx context.Context, key string) (int64, error)
+	Expire(ctx context.Context, key string, expiration time.Duration) error
+	Get(ctx context.Context, key string) (string, error)
+}
+
+// DistributedRateLimiter 基于 Redis 固定窗口计数的限流器，多个实例共享同一配额；
+// Redis 不可用（包括熔断）时自动降级为本实例的内存滑动窗口限流，恢复后自动切回
+type DistributedRateLimiter struct {
+	store    RateLimitStore
+	fallback RateLimiter
+	prefix   string
+	limit    int
+	window   time.Duration
+
+	degraded  atomic.Bool
+	fallbacks atomic.Int64
+}
+
+// NewDistributedRateLimiter 创建分布式限流器，store 为 nil 时只使用内存限流
+func NewDistributedRateLimiter(store RateLimitStore, prefix string, limit int, window time.Duration) *DistributedRateLimiter {
+	if window < time.Second {
+		window = time.Second
+	}
+	return &DistributedRateLimiter{
+		store:    store,
+		fallback: NewSlidingWindow(limit, window),
+		prefix:   prefix,
+		limit:    limit,
+		window:   window,
+	}
+}
+
+// Allow 检查是否允许请求
+func (l *DistributedRateLimiter) Allow(key string) bool {
+	return l.AllowN(key, 1)
+}
+
+// AllowN 检查是否允许n个请求
+func (l *DistributedRateLimiter) AllowN(key string, n int) bool {
+	if l.store == nil {
+		return l.fallback.AllowN(key, n)
+	}
+
+	ctx := context.Background()
+	windowKey := l.windowKey(key, time.Now())
+	var count int64
+	for i := 0; i < n; i++ {
+		value, err := l.store.Incr(ctx, windowKey)
+		if err != nil {
+			l.degraded.Store(true)
+			l.fallbacks.Add(1)
+			return l.fallback.AllowN(key, n)
+		}
+		count = value
+	}
+	l.degraded.Store(false)
+
+	if count == int64(n) {
+		// 窗口内首次计数时设置过期，键名带窗口序号，过期失败也不会影响下个窗口
+		_ = l.store.Expire(ctx, windowKey, l.window)
+	}
+	return count <= int64(l.limit)
+}
+
+// Remaining 获取剩余请求数
+func (l *DistributedRateLimiter) Remaining(key string) int {
+	if l.store == nil || l.degraded.Load() {
+		return l.fallback.Remaining(key)
+	}
+
+	used := 0
+	if value, err := l.store.Get(context.Background(), l.windowKey(key, time.Now())); err == nil {
+		used, _ = strconv.Atoi(value)
+	}
+	if used >= l.limit {
+		return 0
+	}
+	return l.limit - used
+}
+
+// Reset 获取窗口重置时间
+func (l *DistributedRateLimiter) Reset(key string) time.Time {
+	if l.store == nil || l.degraded.Load() {
+		return l.fallback.Reset(key)
+	}
+	seconds := int64(l.window.Seconds())
+	return time.Unix((time.Now().Unix()/seconds+1)*seconds, 0)
+}
+
+// Cleanup 清理内存限流数据，Redis 中的计数随窗口过期
+func (l *DistributedRateLimiter) Cleanup() {
+	l.fallback.Cleanup()
+}
+
+// Degraded 最近一次限流是否因 Redis 不可用而使用了内存限流
+func (l *DistributedRateLimiter) Degraded() bool {
+	return l.store == nil || l.degraded.Load()
+}
+
+// Fallbacks 降级为内存限流的累计次数
+func (l *DistributedRateLimiter) Fallbacks() int64 {
+	return l.fallbacks.Load()
+}
+
+func (l *DistributedRateLimiter) windowKey(key string, now time.Time) string {
+	return fmt.Sprintf("%s:%s:%d", l.prefix, key, now.Unix()/int64(l.window.Seconds()))
+}
+
+// GinClientIPKeyFunc 使用 Gin 解析的客户端IP（遵循受信任代理配置）作为限流键
+func GinClientIPKeyFunc(c HTTPContext) string {
+	if ginCtx, ok := c.(*GinHTTPContext); ok {
+		return ginCtx.ClientIP()
+	}
+	return IPKeyFunc(c)
+}
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// AuditTicketAccess 读取工单成功（2xx）后记录访问，只有保密工单会写入访问日志
+func AuditTicketAccess(service *services.TicketAccessAuditService, action models.TicketAccessAction) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if status := c.Writer.Status(); status < http.StatusOK || status >= http.StatusMultipleChoices {
+			return
+		}
+		ticketID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+		if err != nil {
+			return
+		}
+		userID, ok := GetCurrentUserID(c)
+		if !ok {
+			return
+		}
+		role, _ := GetCurrentUserRole(c)
+
+		if _, err := service.RecordAccess(c.Request.Context(), uint(ticketID), &services.TicketAccessEntry{
+			UserID:    userID,
+			Role:      role,
+			Action:    action,
+			ClientIP:  c.ClientIP(),
+			UserAgent: c.Request.UserAgent(),
+		}); err != nil {
+			log.Printf("Failed to record access to ticket %d: %v", ticketID, err)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+// AccountDeletionStatus 账户注销申请状态
+type AccountDeletionStatus string
+
+const (
+	AccountDeletionAwaitingConfirmation AccountDeletionStatus = "awaiting_confirmation" // 已申请，等待邮件确认
+	AccountDeletionPending              AccountDeletionStatus = "pending"               // 已确认，处于宽限期
+	AccountDeletionCancelled            AccountDeletionStatus = "cancelled"             // 已撤销（用户恢复或管理员取消）
+	AccountDeletionCompleted            AccountDeletionStatus = "completed"             // 已完成匿名化
+)
+
+// DeletedUserDisplayName 注销后用户的显示名称，工单、评论等内容仍归属该用户
+const DeletedUserDisplayName = "Deleted user"
+
+// AccountDeletionRequest 用户自助注销申请
+type AccountDeletionRequest struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	UserID   uint                  `json:"user_id" gorm:"not null;index"`
+	Username string                `json:"username" gorm:"size:50"` // 申请时的用户名，完成注销后清空
+	Email    string                `json:"email" gorm:"size:100"`   // 申请时的邮箱，完成注销后清空
+	Reason   string                `json:"reason" gorm:"size:500"`
+	Status   AccountDeletionStatus `json:"status" gorm:"size:30;not null;index"`
+
+	ConfirmTokenHash string     `json:"-" gorm:"size:64;index"` // 确认令牌的SHA-256哈希
+	ConfirmExpiresAt time.Time  `json:"confirm_expires_at"`
+	ConfirmedAt      *time.Time `json:"confirmed_at,omitempty"`
+	ScheduledAt      *time.Time `json:"scheduled_at,omitempty" gorm:"index"` // 宽限期结束、执行匿名化的时间
+	CancelledAt      *time.Time `json:"cancelled_at,omitempty"`
+	CancelledByID    *uint      `json:"cancelled_by_id,omitempty"` // 管理员取消时记录操作人
+	CompletedAt      *time.Time `json:"completed_at,omitempty"`
+	RequestIP        string     `json:"request_ip" gorm:"size:45"`
+}
+
+// TableName 指定表名
+func (AccountDeletionRequest) TableName() string {
+	return "account_deletion_requests"
+}
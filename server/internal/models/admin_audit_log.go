@@ -2,7 +2,8 @@ package models
 
 import "time"
 
-// AdminAuditLog 管理员操作审计日志
+// AdminAuditLog 管理员操作及账户安全事件审计日志。
+// 每条记录的 Hash 覆盖记录内容和上一条记录的 Hash，形成哈希链，任何修改或删除中间记录都可被校验发现
 type AdminAuditLog struct {
 	ID         uint      `json:"id" gorm:"primaryKey;autoIncrement"`
 	CreatedAt  time.Time `json:"created_at" gorm:"autoCreateTime"`
@@ -19,6 +20,13 @@ type AdminAuditLog struct {
 	LatencyMs  int64     `json:"latency_ms"`
 	Result     string    `json:"result" gorm:"size:100"`
 	Notes      string    `json:"notes" gorm:"type:text"`
+
+	EventType   string   `json:"event_type" gorm:"size:30;index"`         // admin_operation 或 auth（修改密码、两步验证、角色变更等）
+	RequestBody string   `json:"request_body,omitempty" gorm:"type:text"` // 脱敏后的请求体
+	Changes     JSONText `json:"changes,omitempty"`                       // 脱敏后的字段变更 [{field, old, new}]
+
+	PrevHash string `json:"prev_hash" gorm:"size:64"`
+	Hash     string `json:"hash" gorm:"size:64;index"`
 }
 
 // TableName 指定表名
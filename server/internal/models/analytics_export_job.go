@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// AnalyticsExportJobStatus 统计报表导出任务状态
+type AnalyticsExportJobStatus string
+
+const (
+	AnalyticsExportPending   AnalyticsExportJobStatus = "pending"   // 等待生成
+	AnalyticsExportRunning   AnalyticsExportJobStatus = "running"   // 生成中
+	AnalyticsExportCompleted AnalyticsExportJobStatus = "completed" // 已生成，可下载
+	AnalyticsExportFailed    AnalyticsExportJobStatus = "failed"    // 生成失败
+)
+
+// AnalyticsExportJob 后台生成的统计报表（XLSX），用于时间跨度较大的导出
+type AnalyticsExportJob struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	RangePreset string                   `json:"range_preset" gorm:"size:20"` // 7d, 30d, 90d, custom
+	StartDate   time.Time                `json:"start_date"`
+	EndDate     time.Time                `json:"end_date"`
+	Locale      string                   `json:"locale" gorm:"size:10"`
+	Status      AnalyticsExportJobStatus `json:"status" gorm:"size:20;not null;default:'pending';index"`
+
+	FileName string `json:"file_name,omitempty" gorm:"size:255"`
+	FilePath string `json:"-" gorm:"size:500"` // 导出目录下的相对路径
+	FileSize int64  `json:"file_size,omitempty"`
+	Error    string `json:"error,omitempty" gorm:"size:500"`
+
+	CreatedByID uint       `json:"created_by_id" gorm:"index"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty" gorm:"index"` // 过期后文件被清理，不再可下载
+}
+
+// TableName 指定表名
+func (AnalyticsExportJob) TableName() string {
+	return "analytics_export_jobs"
+}
@@ -0,0 +1,81 @@
+package models
+
+import "time"
+
+// DelegationStatus 分配委托状态
+type DelegationStatus string
+
+const (
+	DelegationStatusScheduled DelegationStatus = "scheduled" // 未到开始时间
+	DelegationStatusActive    DelegationStatus = "active"    // 生效中
+	DelegationStatusEnded     DelegationStatus = "ended"     // 已到期结束
+	DelegationStatusCancelled DelegationStatus = "cancelled" // 已取消
+)
+
+// AssignmentDelegation 处理人休假期间的分配委托：生效期间分配给委托人的新工单改派给代理人或团队队列
+type AssignmentDelegation struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+
+	FromUserID uint  `json:"from_user_id" gorm:"not null;index"`
+	ToUserID   *uint `json:"to_user_id,omitempty" gorm:"index"` // 代理人，与 ToTeamID 二选一
+	ToTeamID   *uint `json:"to_team_id,omitempty" gorm:"index"` // 代理团队队列
+
+	StartsAt         time.Time        `json:"starts_at" gorm:"not null;index"`
+	EndsAt           time.Time        `json:"ends_at" gorm:"not null;index"`
+	TransferExisting bool             `json:"transfer_existing" gorm:"default:false"` // 开始时转移委托人名下未完成的工单，结束时转回
+	Reason           string           `json:"reason" gorm:"size:255"`
+	Status           DelegationStatus `json:"status" gorm:"size:20;not null;default:'scheduled';index"`
+
+	CreatedByID      uint       `json:"created_by_id" gorm:"not null"`
+	ActivatedAt      *time.Time `json:"activated_at,omitempty"`
+	EndedAt          *time.Time `json:"ended_at,omitempty"`
+	TransferredCount int        `json:"transferred_count" gorm:"default:0"`
+	RevertedCount    int        `json:"reverted_count" gorm:"default:0"`
+
+	FromUser *User `json:"from_user,omitempty" gorm:"foreignKey:FromUserID"`
+	ToUser   *User `json:"to_user,omitempty" gorm:"foreignKey:ToUserID"`
+	ToTeam   *Team `json:"to_team,omitempty" gorm:"foreignKey:ToTeamID"`
+}
+
+// TableName 指定表名
+func (AssignmentDelegation) TableName() string {
+	return "assignment_delegations"
+}
+
+// IsEffectiveAt 判断委托在指定时刻是否生效
+func (d *AssignmentDelegation) IsEffectiveAt(t time.Time) bool {
+	if d.Status != DelegationStatusScheduled && d.Status != DelegationStatusActive {
+		return false
+	}
+	return !t.Before(d.StartsAt) && t.Before(d.EndsAt)
+}
+
+// DelegatedTicket 委托开始时转移的工单，委托结束时据此转回原处理人
+type DelegatedTicket struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+
+	DelegationID       uint       `json:"delegation_id" gorm:"not null;index"`
+	TicketID           uint       `json:"ticket_id" gorm:"not null;index"`
+	OriginalAssigneeID uint       `json:"original_assignee_id" gorm:"not null"`
+	OriginalTeamID     *uint      `json:"original_team_id,omitempty"`
+	RevertedAt         *time.Time `json:"reverted_at,omitempty"`
+}
+
+// TableName 指定表名
+func (DelegatedTicket) TableName() string {
+	return "delegated_tickets"
+}
+
+// DelegationCreateRequest 创建分配委托请求
+type DelegationCreateRequest struct {
+	FromUserID       *uint     `json:"from_user_id"` // 仅管理员/主管可为他人创建，默认为当前用户
+	ToUserID         *uint     `json:"to_user_id"`
+	ToTeamID         *uint     `json:"to_team_id"`
+	StartsAt         time.Time `json:"starts_at" binding:"required"`
+	EndsAt           time.Time `json:"ends_at" binding:"required"`
+	TransferExisting bool      `json:"transfer_existing"`
+	Reason           string    `json:"reason" binding:"max=255"`
+}
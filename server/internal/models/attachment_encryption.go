@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// AttachmentEncryption 附件静态加密元数据。文件内容用随机生成的数据密钥加密，
+// 数据密钥由主密钥包装后与附件一同保存；Algorithm 为空表示文件未加密
+type AttachmentEncryption struct {
+	Algorithm   string     `json:"algorithm,omitempty" gorm:"size:30;default:''"`
+	KeyID       string     `json:"key_id,omitempty" gorm:"size:100;default:'';index"` // 包装数据密钥的主密钥ID
+	WrappedKey  string     `json:"-" gorm:"size:512"`                                 // base64 编码的包装后数据密钥
+	NoncePrefix string     `json:"-" gorm:"size:32"`                                  // base64 编码的分段 nonce 前缀
+	WrappedAt   *time.Time `json:"wrapped_at,omitempty"`                              // 数据密钥最近一次包装的时间，轮换后更新
+}
+
+// Encrypted 文件是否加密存储
+func (e AttachmentEncryption) Encrypted() bool {
+	return e.Algorithm != ""
+}
+
+// AttachmentEncryptionStatus 附件加密状态，轮换主密钥后据此确认旧密钥不再被引用
+type AttachmentEncryptionStatus struct {
+	Enabled       bool             `json:"enabled"`
+	PrimaryKeyID  string           `json:"primary_key_id,omitempty"`
+	KeyIDs        []string         `json:"key_ids,omitempty"` // 密钥文件中可用的主密钥
+	ByKeyID       map[string]int64 `json:"by_key_id"`         // 各主密钥包装的附件数
+	Unencrypted   int64            `json:"unencrypted"`       // 启用加密前上传或直传对象存储的附件
+	PendingRewrap int64            `json:"pending_rewrap"`    // 仍由非主密钥包装的附件与上传
+}
+
+// AttachmentRewrapResult 数据密钥重新包装结果
+type AttachmentRewrapResult struct {
+	PrimaryKeyID string   `json:"primary_key_id"`
+	Rewrapped    int      `json:"rewrapped"`
+	Failed       int      `json:"failed"`
+	Errors       []string `json:"errors,omitempty"` // 失败的附件及原因，最多返回前若干条
+}
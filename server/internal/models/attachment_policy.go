@@ -0,0 +1,132 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AttachmentMaxFileSizeMB 单个附件大小的系统上限，规则未限制大小时也不能超过
+const AttachmentMaxFileSizeMB = 100
+
+// AttachmentRule 附件限制规则，字段为 0 或空表示不额外限制
+type AttachmentRule struct {
+	MaxFileSizeMB    int      `json:"max_file_size_mb"`   // 单个文件大小上限（MB），不超过系统上限
+	AllowedMimeTypes []string `json:"allowed_mime_types"` // 允许的 MIME 类型，支持 image/* 通配
+	MaxCount         int      `json:"max_count"`          // 每个工单的附件数上限
+}
+
+// MaxFileSizeBytes 单个文件允许的最大字节数
+func (r *AttachmentRule) MaxFileSizeBytes() int64 {
+	size := r.MaxFileSizeMB
+	if size <= 0 || size > AttachmentMaxFileSizeMB {
+		size = AttachmentMaxFileSizeMB
+	}
+	return int64(size) << 20
+}
+
+// AllowsMimeType 检查 MIME 类型是否允许上传
+func (r *AttachmentRule) AllowsMimeType(mimeType string) bool {
+	if len(r.AllowedMimeTypes) == 0 {
+		return true
+	}
+	mimeType = strings.ToLower(strings.TrimSpace(strings.SplitN(mimeType, ";", 2)[0]))
+	for _, allowed := range r.AllowedMimeTypes {
+		allowed = strings.ToLower(allowed)
+		if allowed == mimeType || allowed == "*/*" {
+			return true
+		}
+		if strings.HasSuffix(allowed, "/*") && strings.HasPrefix(mimeType, strings.TrimSuffix(allowed, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate 校验附件规则
+func (r *AttachmentRule) Validate() error {
+	if r.MaxFileSizeMB < 0 || r.MaxFileSizeMB > AttachmentMaxFileSizeMB {
+		return fmt.Errorf("max_file_size_mb must be between 0 and %d", AttachmentMaxFileSizeMB)
+	}
+	if r.MaxCount < 0 {
+		return fmt.Errorf("max_count must not be negative")
+	}
+	for _, mimeType := range r.AllowedMimeTypes {
+		parts := strings.Split(mimeType, "/")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" || (parts[0] == "*" && parts[1] != "*") {
+			return fmt.Errorf("invalid mime type %q", mimeType)
+		}
+	}
+	return nil
+}
+
+// AttachmentPolicy 工单附件策略：分类未单独配置时使用默认规则
+type AttachmentPolicy struct {
+	Default    AttachmentRule          `json:"default"`
+	Categories map[uint]AttachmentRule `json:"categories"` // 分类ID -> 规则，子分类的规则优先于父分类
+}
+
+// GetDefaultAttachmentPolicy 获取默认附件策略
+func GetDefaultAttachmentPolicy() *AttachmentPolicy {
+	return &AttachmentPolicy{
+		Default: AttachmentRule{
+			MaxFileSizeMB: 10,
+			AllowedMimeTypes: []string{
+				"image/*",
+				"text/plain",
+				"application/pdf",
+				"application/zip",
+				"application/msword",
+				"application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+				"application/vnd.ms-excel",
+				"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+			},
+			MaxCount: 20,
+		},
+		Categories: map[uint]AttachmentRule{},
+	}
+}
+
+// Validate 校验附件策略
+func (p *AttachmentPolicy) Validate() error {
+	if err := p.Default.Validate(); err != nil {
+		return fmt.Errorf("default: %w", err)
+	}
+	for categoryID, rule := range p.Categories {
+		if err := rule.Validate(); err != nil {
+			return fmt.Errorf("category %d: %w", categoryID, err)
+		}
+	}
+	return nil
+}
+
+// RuleFor 获取工单分类适用的规则，返回规则来源的分类ID（使用默认规则时为空）
+func (p *AttachmentPolicy) RuleFor(categoryID, subcategoryID *uint) (AttachmentRule, *uint) {
+	for _, id := range []*uint{subcategoryID, categoryID} {
+		if id == nil {
+			continue
+		}
+		if rule, ok := p.Categories[*id]; ok {
+			source := *id
+			return rule, &source
+		}
+	}
+	return p.Default, nil
+}
+
+// AttachmentSchema 附件上传约束，供客户端上传前预校验
+type AttachmentSchema struct {
+	MaxFileSizeMB    int      `json:"max_file_size_mb"`
+	MaxFileSizeBytes int64    `json:"max_file_size_bytes"`
+	AllowedMimeTypes []string `json:"allowed_mime_types"` // 为空表示不限制类型
+	MaxCount         int      `json:"max_count"`          // 0 表示不限制数量
+	SourceCategoryID *uint    `json:"source_category_id,omitempty"`
+}
+
+// TicketFormSchema 按分类生成的建单表单约束
+type TicketFormSchema struct {
+	CategoryID      *uint            `json:"category_id,omitempty"`
+	SubcategoryID   *uint            `json:"subcategory_id,omitempty"`
+	Template        string           `json:"template,omitempty"` // 分类的工单模板
+	RequireApproval bool             `json:"require_approval"`
+	Attachments     AttachmentSchema `json:"attachments"`
+}
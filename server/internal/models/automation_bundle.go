@@ -0,0 +1,110 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AutomationBundleFormatVersion 配置包格式版本
+const AutomationBundleFormatVersion = 1
+
+// 配置包导入冲突处理方式（按名称判断冲突）
+const (
+	ImportConflictSkip      = "skip"      // 保留现有配置
+	ImportConflictOverwrite = "overwrite" // 用配置包内容覆盖现有配置
+	ImportConflictRename    = "rename"    // 以新名称另存一份
+)
+
+// 规则修订类型
+const (
+	RuleRevisionBaseline = "baseline" // 启用修订历史前的原始内容
+	RuleRevisionCreate   = "create"
+	RuleRevisionUpdate   = "update"
+	RuleRevisionImport   = "import"
+	RuleRevisionRollback = "rollback"
+)
+
+// AutomationBundle 自动化配置包，用于在不同环境之间复制规则、SLA配置和工单模板。
+// 处理人等与环境相关的用户引用不会导出。
+type AutomationBundle struct {
+	FormatVersion int                     `json:"format_version"`
+	ExportedAt    time.Time               `json:"exported_at"`
+	Checksum      string                  `json:"checksum"` // 内容校验和，不含 exported_at
+	Rules         []AutomationRuleRequest `json:"rules"`
+	SLAConfigs    []SLAConfigRequest      `json:"sla_configs"`
+	Templates     []TicketTemplateRequest `json:"templates"`
+}
+
+// AutomationImportRequest 配置包导入请求
+type AutomationImportRequest struct {
+	Bundle     AutomationBundle `json:"bundle" binding:"required"`
+	OnConflict string           `json:"on_conflict" binding:"omitempty,oneof=skip overwrite rename"` // 默认 skip
+	DryRun     bool             `json:"dry_run"`                                                     // 只校验并返回导入计划，不写入
+}
+
+// AutomationImportItem 单个配置项的导入结果
+type AutomationImportItem struct {
+	Kind    string `json:"kind"` // rule, sla_config, template
+	Name    string `json:"name"`
+	Action  string `json:"action"` // created, overwritten, skipped, renamed
+	ID      uint   `json:"id,omitempty"`
+	NewName string `json:"new_name,omitempty"`
+}
+
+// AutomationImportResult 配置包导入结果
+type AutomationImportResult struct {
+	DryRun   bool                   `json:"dry_run"`
+	Checksum string                 `json:"checksum"`
+	Items    []AutomationImportItem `json:"items"`
+}
+
+// AutomationChecksumItem 单个配置项的校验和
+type AutomationChecksumItem struct {
+	Kind     string `json:"kind"`
+	Name     string `json:"name"`
+	Checksum string `json:"checksum"`
+}
+
+// AutomationChecksum 当前环境配置的校验和，用于比对不同环境的差异
+type AutomationChecksum struct {
+	Checksum string                   `json:"checksum"`
+	Sections map[string]string        `json:"sections"`
+	Items    []AutomationChecksumItem `json:"items"`
+}
+
+// AutomationRuleRevision 自动化规则修订历史
+type AutomationRuleRevision struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+
+	RuleID     uint   `json:"rule_id" gorm:"not null;uniqueIndex:idx_rule_revision_version"`
+	Version    int    `json:"version" gorm:"not null;uniqueIndex:idx_rule_revision_version"`
+	ChangeType string `json:"change_type" gorm:"size:20;not null"`
+	Note       string `json:"note" gorm:"size:255"`
+	Checksum   string `json:"checksum" gorm:"size:64"`
+	Snapshot   string `json:"-" gorm:"type:text;not null"` // 规则内容JSON
+
+	CreatedBy *uint `json:"created_by,omitempty" gorm:"index"`
+
+	Rule *AutomationRuleRequest `json:"rule" gorm:"-"`
+}
+
+// TableName 指定表名
+func (AutomationRuleRevision) TableName() string {
+	return "automation_rule_revisions"
+}
+
+// AfterFind GORM钩子 - 反序列化规则内容
+func (r *AutomationRuleRevision) AfterFind(tx *gorm.DB) error {
+	if r.Snapshot == "" {
+		return nil
+	}
+	var spec AutomationRuleRequest
+	if err := json.Unmarshal([]byte(r.Snapshot), &spec); err != nil {
+		return nil
+	}
+	r.Rule = &spec
+	return nil
+}
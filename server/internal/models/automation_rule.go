@@ -2,6 +2,7 @@ package models
 
 import (
 	"encoding/json"
+	"sort"
 	"time"
 )
 
@@ -19,7 +20,7 @@ type AutomationRule struct {
 	Priority    int    `json:"priority" gorm:"default:1;index"`         // 规则优先级，数字越小优先级越高
 
 	// 触发条件
-	TriggerEvent string `json:"trigger_event" gorm:"size:50;not null"` // ticket.created, ticket.updated, ticket.timeout
+	TriggerEvent string `json:"trigger_event" gorm:"size:50;not null"` // ticket.created, ticket.updated, ticket.timeout, survey.submitted, survey.low_score, intake.screened, category.changed, ticket.resolved
 	Conditions   string `json:"conditions" gorm:"type:json"`           // JSON格式的条件配置
 
 	// 执行动作
@@ -46,7 +47,7 @@ func (AutomationRule) TableName() string {
 
 // RuleCondition 规则条件结构
 type RuleCondition struct {
-	Field    string      `json:"field"`    // ticket字段名，如title、content、type、priority、status
+	Field    string      `json:"field"`    // ticket字段名，如title、content、type、priority、impact、urgency、status、resolution_code，营业日历字段is_business_hours、is_holiday、hours_since_created_business，用户属性字段assignee_skills、assignee_field.<key>、creator_field.<key>
 	Operator string      `json:"operator"` // eq, ne, contains, starts_with, ends_with, in, not_in, gt, lt, gte, lte, regex
 	Value    interface{} `json:"value"`    // 比较值
 	LogicOp  string      `json:"logic_op"` // and, or (与下一个条件的逻辑关系)
@@ -54,10 +55,25 @@ type RuleCondition struct {
 
 // RuleAction 规则动作结构
 type RuleAction struct {
-	Type   string                 `json:"type"`   // assign, set_priority, set_status, add_comment, notify, escalate
+	Type   string                 `json:"type"`   // assign, set_priority, set_status, add_comment, notify, escalate, create_ticket, create_war_room, assign_by_skill
 	Params map[string]interface{} `json:"params"` // 动作参数
 }
 
+// 满意度调查触发事件
+const (
+	TriggerSurveySubmitted = "survey.submitted" // 客户提交满意度评分
+	TriggerSurveyLowScore  = "survey.low_score" // 评分低于等于策略阈值
+)
+
+// TriggerIntakeScreened 公开渠道提交的工单通过垃圾检测并创建后触发，可按 spam_score、source 条件处理
+const TriggerIntakeScreened = "intake.screened"
+
+// TriggerCategoryChanged 工单通过转移分类操作更换分类后触发，此时SLA与自动分配已按新分类处理
+const TriggerCategoryChanged = "category.changed"
+
+// TriggerTicketResolved 工单被解决后触发，可按 resolution_code 条件处理
+const TriggerTicketResolved = "ticket.resolved"
+
 // GetConditions 解析条件JSON
 func (ar *AutomationRule) GetConditions() ([]RuleCondition, error) {
 	if ar.Conditions == "" {
@@ -151,10 +167,16 @@ type SLAConfig struct {
 	// 升级规则
 	EscalationRules string `json:"escalation_rules" gorm:"type:json"` // 升级规则JSON
 
+	// 违约前预警阈值（已用时限百分比），JSON 数组，为空时使用默认阈值 75%、90%
+	WarningThresholds string `json:"warning_thresholds" gorm:"size:100"`
+
 	// 统计信息
 	AppliedCount int64 `json:"applied_count" gorm:"default:0"`
 	ViolationCount int64 `json:"violation_count" gorm:"default:0"`
 	ComplianceRate float64 `json:"compliance_rate" gorm:"default:0"`
+
+	// 命中客户SLA合同时由合同覆盖响应/解决时限，不入库
+	ContractID *uint `json:"contract_id,omitempty" gorm:"-"`
 }
 
 // TableName 指定表名
@@ -179,6 +201,26 @@ type TimeRange struct {
 	End   string `json:"end"`   // HH:MM 格式
 }
 
+// ForWeekday 获取指定星期的工作时间，Start/End 为空表示不营业
+func (w *WorkingHours) ForWeekday(day time.Weekday) TimeRange {
+	switch day {
+	case time.Monday:
+		return w.Monday
+	case time.Tuesday:
+		return w.Tuesday
+	case time.Wednesday:
+		return w.Wednesday
+	case time.Thursday:
+		return w.Thursday
+	case time.Friday:
+		return w.Friday
+	case time.Saturday:
+		return w.Saturday
+	default:
+		return w.Sunday
+	}
+}
+
 // EscalationRule 升级规则
 type EscalationRule struct {
 	TriggerMinutes int    `json:"trigger_minutes"` // 触发升级的分钟数
@@ -217,6 +259,24 @@ func (sla *SLAConfig) GetEscalationRules() ([]EscalationRule, error) {
 	return rules, err
 }
 
+// DefaultSLAWarningThresholds 未配置预警阈值时使用的默认阈值（已用时限百分比）
+var DefaultSLAWarningThresholds = []int{75, 90}
+
+// GetWarningThresholds 获取违约前预警阈值，按从小到大排列
+func (sla *SLAConfig) GetWarningThresholds() []int {
+	var thresholds []int
+	if sla.WarningThresholds != "" {
+		if err := json.Unmarshal([]byte(sla.WarningThresholds), &thresholds); err != nil {
+			thresholds = nil
+		}
+	}
+	if len(thresholds) == 0 {
+		thresholds = append([]int(nil), DefaultSLAWarningThresholds...)
+	}
+	sort.Ints(thresholds)
+	return thresholds
+}
+
 // TicketTemplate 工单模板模型
 type TicketTemplate struct {
 	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
@@ -243,6 +303,9 @@ type TicketTemplate struct {
 	// 自定义字段
 	CustomFields string `json:"custom_fields" gorm:"type:json"` // 自定义字段配置
 
+	// 检查项模板，使用模板时追加到工单检查清单
+	ChecklistItems string `json:"checklist_items" gorm:"type:json"`
+
 	// 使用统计
 	UsageCount int64 `json:"usage_count" gorm:"default:0"`
 	
@@ -277,6 +340,17 @@ func (tt *TicketTemplate) GetCustomFields() ([]CustomField, error) {
 	return fields, err
 }
 
+// GetChecklistItems 获取检查项模板
+func (tt *TicketTemplate) GetChecklistItems() ([]ChecklistTemplateItem, error) {
+	if tt.ChecklistItems == "" {
+		return []ChecklistTemplateItem{}, nil
+	}
+
+	var items []ChecklistTemplateItem
+	err := json.Unmarshal([]byte(tt.ChecklistItems), &items)
+	return items, err
+}
+
 // AutomationLog 自动化执行日志
 type AutomationLog struct {
 	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
@@ -344,34 +418,36 @@ type AutomationRuleRequest struct {
 }
 
 type SLAConfigRequest struct {
-	Name            string             `json:"name" validate:"required,max=100"`
-	Description     string             `json:"description" validate:"max=500"`
-	IsActive        *bool              `json:"is_active,omitempty"`
-	IsDefault       *bool              `json:"is_default,omitempty"`
-	TicketType      *string            `json:"ticket_type,omitempty"`
-	Priority        *string            `json:"priority,omitempty"`
-	Category        *string            `json:"category,omitempty"`
-	AssignedUserID  *uint              `json:"assigned_user_id,omitempty"`
-	ResponseTime    int                `json:"response_time" validate:"required,min=1"`
-	ResolutionTime  int                `json:"resolution_time" validate:"required,min=1"`
-	WorkingHours    *WorkingHours      `json:"working_hours,omitempty"`
-	ExcludeWeekends *bool              `json:"exclude_weekends,omitempty"`
-	ExcludeHolidays *bool              `json:"exclude_holidays,omitempty"`
-	EscalationRules []EscalationRule   `json:"escalation_rules,omitempty"`
+	Name              string           `json:"name" validate:"required,max=100"`
+	Description       string           `json:"description" validate:"max=500"`
+	IsActive          *bool            `json:"is_active,omitempty"`
+	IsDefault         *bool            `json:"is_default,omitempty"`
+	TicketType        *string          `json:"ticket_type,omitempty"`
+	Priority          *string          `json:"priority,omitempty"`
+	Category          *string          `json:"category,omitempty"`
+	AssignedUserID    *uint            `json:"assigned_user_id,omitempty"`
+	ResponseTime      int              `json:"response_time" validate:"required,min=1"`
+	ResolutionTime    int              `json:"resolution_time" validate:"required,min=1"`
+	WorkingHours      *WorkingHours    `json:"working_hours,omitempty"`
+	ExcludeWeekends   *bool            `json:"exclude_weekends,omitempty"`
+	ExcludeHolidays   *bool            `json:"exclude_holidays,omitempty"`
+	EscalationRules   []EscalationRule `json:"escalation_rules,omitempty"`
+	WarningThresholds []int            `json:"warning_thresholds,omitempty"` // 违约前预警阈值（1-99），为空时使用默认阈值
 }
 
 type TicketTemplateRequest struct {
-	Name            string        `json:"name" validate:"required,max=100"`
-	Description     string        `json:"description" validate:"max=500"`
-	Category        string        `json:"category" validate:"required,max=50"`
-	IsActive        *bool         `json:"is_active,omitempty"`
-	TitleTemplate   string        `json:"title_template" validate:"max=200"`
-	ContentTemplate string        `json:"content_template"`
-	DefaultType     string        `json:"default_type" validate:"max=50"`
-	DefaultPriority string        `json:"default_priority" validate:"max=20"`
-	DefaultStatus   string        `json:"default_status" validate:"max=20"`
-	AssignToUserID  *uint         `json:"assign_to_user_id,omitempty"`
-	CustomFields    []CustomField `json:"custom_fields,omitempty"`
+	Name            string                  `json:"name" validate:"required,max=100"`
+	Description     string                  `json:"description" validate:"max=500"`
+	Category        string                  `json:"category" validate:"required,max=50"`
+	IsActive        *bool                   `json:"is_active,omitempty"`
+	TitleTemplate   string                  `json:"title_template" validate:"max=200"`
+	ContentTemplate string                  `json:"content_template"`
+	DefaultType     string                  `json:"default_type" validate:"max=50"`
+	DefaultPriority string                  `json:"default_priority" validate:"max=20"`
+	DefaultStatus   string                  `json:"default_status" validate:"max=20"`
+	AssignToUserID  *uint                   `json:"assign_to_user_id,omitempty"`
+	CustomFields    []CustomField           `json:"custom_fields,omitempty"`
+	ChecklistItems  []ChecklistTemplateItem `json:"checklist_items,omitempty"`
 }
 
 type QuickReplyRequest struct {
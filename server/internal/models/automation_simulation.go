@@ -0,0 +1,81 @@
+package models
+
+import "time"
+
+// AutomationSimulationStatus 规则模拟任务状态
+type AutomationSimulationStatus string
+
+const (
+	AutomationSimulationPending   AutomationSimulationStatus = "pending"   // 等待执行
+	AutomationSimulationRunning   AutomationSimulationStatus = "running"   // 执行中
+	AutomationSimulationCompleted AutomationSimulationStatus = "completed" // 已完成，可查看结果
+	AutomationSimulationFailed    AutomationSimulationStatus = "failed"    // 执行失败
+)
+
+// AutomationSimulationRequest 规则模拟请求，指定已有规则或直接提供条件和动作
+type AutomationSimulationRequest struct {
+	RuleID     *uint           `json:"rule_id"`
+	Conditions []RuleCondition `json:"conditions"`
+	Actions    []RuleAction    `json:"actions"`
+	StartDate  string          `json:"start_date" binding:"required"` // YYYY-MM-DD，按工单创建时间筛选
+	EndDate    string          `json:"end_date" binding:"required"`   // YYYY-MM-DD，含当天
+	SampleSize int             `json:"sample_size" binding:"omitempty,min=1,max=50"`
+	Async      bool            `json:"async"` // 强制后台执行
+}
+
+// AutomationSimulationSample 命中规则的示例工单
+type AutomationSimulationSample struct {
+	TicketID     uint           `json:"ticket_id"`
+	TicketNumber string         `json:"ticket_number"`
+	Title        string         `json:"title"`
+	Status       TicketStatus   `json:"status"`
+	Priority     TicketPriority `json:"priority"`
+	CreatedAt    time.Time      `json:"created_at"`
+}
+
+// AutomationSimulationAction 单个动作的预计执行量
+type AutomationSimulationAction struct {
+	Type       string  `json:"type"`
+	Executions int64   `json:"executions"` // 区间内预计执行次数
+	PerDay     float64 `json:"per_day"`    // 日均执行次数
+}
+
+// AutomationSimulationResult 规则模拟结果
+type AutomationSimulationResult struct {
+	RuleID           *uint                        `json:"rule_id,omitempty"`
+	StartDate        time.Time                    `json:"start_date"`
+	EndDate          time.Time                    `json:"end_date"`
+	Scanned          int64                        `json:"scanned"`
+	Matched          int64                        `json:"matched"`
+	MatchRate        float64                      `json:"match_rate"`
+	Samples          []AutomationSimulationSample `json:"samples"`
+	ProjectedActions []AutomationSimulationAction `json:"projected_actions"`
+}
+
+// AutomationSimulationJob 后台执行的规则模拟，用于时间范围较大的模拟
+type AutomationSimulationJob struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	RuleID    *uint                      `json:"rule_id,omitempty" gorm:"index"`
+	Spec      string                     `json:"-" gorm:"type:text"` // 模拟的条件和动作（JSON）
+	StartDate time.Time                  `json:"start_date"`
+	EndDate   time.Time                  `json:"end_date"`
+	Status    AutomationSimulationStatus `json:"status" gorm:"size:20;not null;default:'pending';index"`
+	Scanned   int64                      `json:"scanned"` // 已扫描的工单数，执行中持续更新
+	Total     int64                      `json:"total"`   // 区间内的工单总数
+
+	Result     string                      `json:"-" gorm:"type:text"`
+	ResultData *AutomationSimulationResult `json:"result,omitempty" gorm:"-"`
+	Error      string                      `json:"error,omitempty" gorm:"size:500"`
+
+	CreatedByID uint       `json:"created_by_id" gorm:"index"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+}
+
+// TableName 指定表名
+func (AutomationSimulationJob) TableName() string {
+	return "automation_simulation_jobs"
+}
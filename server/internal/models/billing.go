@@ -0,0 +1,202 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// BillingPeriodFormat 计费周期格式（自然月）
+const BillingPeriodFormat = "2006-01"
+
+var billingCurrencyPattern = regexp.MustCompile(`^[A-Z]{3}$`)
+
+// BillingRate 按客户邮箱或邮箱域名设置的小时费率
+type BillingRate struct {
+	Pattern string  `json:"pattern"`         // 小写的客户邮箱或邮箱域名，邮箱优先于域名
+	Rate    float64 `json:"rate"`            // 小时费率
+	Label   string  `json:"label,omitempty"` // 客户或公司名称，仅用于展示
+}
+
+// BillingRateConfig 计费小时费率配置
+type BillingRateConfig struct {
+	Currency      string        `json:"currency"`     // ISO 4217 币种代码，如 CNY、USD
+	DefaultRate   float64       `json:"default_rate"` // 未单独设置费率的客户使用的小时费率
+	CustomerRates []BillingRate `json:"customer_rates"`
+}
+
+// GetDefaultBillingRateConfig 获取默认费率配置
+func GetDefaultBillingRateConfig() *BillingRateConfig {
+	return &BillingRateConfig{
+		Currency:      "CNY",
+		DefaultRate:   0,
+		CustomerRates: []BillingRate{},
+	}
+}
+
+// Validate 校验费率配置，邮箱和域名统一为小写
+func (c *BillingRateConfig) Validate() error {
+	c.Currency = strings.ToUpper(strings.TrimSpace(c.Currency))
+	if !billingCurrencyPattern.MatchString(c.Currency) {
+		return fmt.Errorf("currency must be a 3-letter ISO 4217 code")
+	}
+	if c.DefaultRate < 0 {
+		return fmt.Errorf("default_rate must not be negative")
+	}
+	if c.CustomerRates == nil {
+		c.CustomerRates = []BillingRate{}
+	}
+	seen := make(map[string]bool, len(c.CustomerRates))
+	for i := range c.CustomerRates {
+		rate := &c.CustomerRates[i]
+		rate.Pattern = strings.ToLower(strings.TrimSpace(rate.Pattern))
+		rate.Label = strings.TrimSpace(rate.Label)
+		if rate.Pattern == "" {
+			return fmt.Errorf("customer_rates[%d].pattern is required", i)
+		}
+		if seen[rate.Pattern] {
+			return fmt.Errorf("customer_rates[%d].pattern %q is duplicated", i, rate.Pattern)
+		}
+		seen[rate.Pattern] = true
+		if rate.Rate < 0 {
+			return fmt.Errorf("customer_rates[%d].rate must not be negative", i)
+		}
+	}
+	return nil
+}
+
+// RateFor 客户适用的小时费率：邮箱 > 邮箱域名 > 默认费率
+func (c *BillingRateConfig) RateFor(email string) float64 {
+	email = strings.ToLower(strings.TrimSpace(email))
+	domain := EmailDomain(email)
+	rate, matched := c.DefaultRate, false
+	for _, entry := range c.CustomerRates {
+		if email != "" && entry.Pattern == email {
+			return entry.Rate
+		}
+		if !matched && domain != "" && entry.Pattern == domain {
+			rate, matched = entry.Rate, true
+		}
+	}
+	return rate
+}
+
+// LabelFor 费率配置中客户或公司的名称，没有时返回空
+func (c *BillingRateConfig) LabelFor(pattern string) string {
+	for _, entry := range c.CustomerRates {
+		if entry.Pattern == pattern {
+			return entry.Label
+		}
+	}
+	return ""
+}
+
+// TicketBillingReference 工单的计费参考信息，如合同号、采购单号或发票号及发票链接
+type TicketBillingReference struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	TicketID    uint   `json:"ticket_id" gorm:"not null;uniqueIndex"`
+	Reference   string `json:"reference" gorm:"size:100;not null;index"`
+	InvoiceURL  string `json:"invoice_url,omitempty" gorm:"size:1000"`
+	Note        string `json:"note,omitempty" gorm:"size:500"`
+	UpdatedByID uint   `json:"updated_by_id"`
+}
+
+// TableName 指定表名
+func (TicketBillingReference) TableName() string {
+	return "ticket_billing_references"
+}
+
+// TicketBillingReferenceRequest 设置工单计费参考请求
+type TicketBillingReferenceRequest struct {
+	Reference  string `json:"reference" binding:"required,max=100"`
+	InvoiceURL string `json:"invoice_url" binding:"omitempty,url,max=1000"`
+	Note       string `json:"note" binding:"max=500"`
+}
+
+// BillingPeriodLock 已开票而锁定的计费周期。锁定后该月的工时计费不能再修改，
+// 报表使用锁定时保存的费率
+type BillingPeriodLock struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+
+	Period           string `json:"period" gorm:"size:7;not null;uniqueIndex"` // YYYY-MM
+	InvoiceReference string `json:"invoice_reference,omitempty" gorm:"size:100"`
+	Rates            string `json:"-" gorm:"type:text"` // 锁定时的费率配置JSON
+	LockedByID       uint   `json:"locked_by_id"`
+}
+
+// TableName 指定表名
+func (BillingPeriodLock) TableName() string {
+	return "billing_period_locks"
+}
+
+// BillingPeriodLockRequest 锁定计费周期请求
+type BillingPeriodLockRequest struct {
+	InvoiceReference string `json:"invoice_reference" binding:"max=100"`
+}
+
+// WorklogBillableRequest 设置工时记录是否计费的请求
+type WorklogBillableRequest struct {
+	Billable     bool `json:"billable"`
+	BillableTime *int `json:"billable_time" binding:"omitempty,min=0,max=14400"` // 计费分钟数，为空时计费记录按花费时间计
+}
+
+// Worklog 带时间跟踪的评论
+type Worklog struct {
+	CommentID    uint      `json:"comment_id"`
+	CreatedAt    time.Time `json:"created_at"`
+	UserID       uint      `json:"user_id"`
+	UserName     string    `json:"user_name"`
+	WorkType     string    `json:"work_type,omitempty"`
+	TimeSpent    int       `json:"time_spent"`    // 分钟
+	BillableTime int       `json:"billable_time"` // 分钟，0 表示不计费
+	Locked       bool      `json:"locked"`        // 所在计费周期已锁定
+}
+
+// TicketWorklogs 工单的工时记录及合计
+type TicketWorklogs struct {
+	Items            []*Worklog              `json:"items"`
+	TotalTimeSpent   int                     `json:"total_time_spent"`
+	TotalBillable    int                     `json:"total_billable"`
+	BillingReference *TicketBillingReference `json:"billing_reference,omitempty"`
+}
+
+// BillableTicketLine 报表中一个工单在计费周期内的计费工时
+type BillableTicketLine struct {
+	TicketID         uint    `json:"ticket_id"`
+	TicketNumber     string  `json:"ticket_number"`
+	Title            string  `json:"title"`
+	CustomerEmail    string  `json:"customer_email"`
+	BillingReference string  `json:"billing_reference,omitempty"`
+	BillableMinutes  int     `json:"billable_minutes"`
+	BillableHours    float64 `json:"billable_hours"`
+	Rate             float64 `json:"rate"`
+	Amount           float64 `json:"amount"`
+}
+
+// BillableHoursRow 一个客户或公司在计费周期内的计费工时
+type BillableHoursRow struct {
+	Key             string                `json:"key"`   // 客户邮箱或公司邮箱域名
+	Label           string                `json:"label"` // 客户姓名或费率配置中的公司名称，没有时同 key
+	Tickets         int                   `json:"tickets"`
+	BillableMinutes int                   `json:"billable_minutes"`
+	BillableHours   float64               `json:"billable_hours"`
+	Amount          float64               `json:"amount"`
+	Lines           []*BillableTicketLine `json:"lines"`
+}
+
+// BillableHoursReport 月度计费工时报表
+type BillableHoursReport struct {
+	Period          string              `json:"period"`
+	GroupBy         string              `json:"group_by"` // customer 或 company
+	Currency        string              `json:"currency"`
+	Lock            *BillingPeriodLock  `json:"lock,omitempty"` // 已锁定时为锁定记录
+	BillableMinutes int                 `json:"billable_minutes"`
+	BillableHours   float64             `json:"billable_hours"`
+	Amount          float64             `json:"amount"`
+	Rows            []*BillableHoursRow `json:"rows"`
+}
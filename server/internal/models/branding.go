@@ -0,0 +1,177 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// 品牌资源类型
+const (
+	BrandingAssetLogo    = "logo"
+	BrandingAssetFavicon = "favicon"
+)
+
+// BrandingMaxTenants 租户覆盖数量上限
+const BrandingMaxTenants = 200
+
+var (
+	brandingColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+	brandingHostPattern  = regexp.MustCompile(`^([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z]{2,63}$`)
+)
+
+// BrandingSettings 品牌与白标设置。租户覆盖中为空的字段继承全局设置，
+// 全局设置中产品名称与 Logo 为空时使用系统名称（system.name）与系统 Logo（system.logo）
+type BrandingSettings struct {
+	ProductName  string `json:"product_name,omitempty"`
+	LogoURL      string `json:"logo_url,omitempty"`
+	FaviconURL   string `json:"favicon_url,omitempty"`
+	PrimaryColor string `json:"primary_color,omitempty"` // #RRGGBB
+	AccentColor  string `json:"accent_color,omitempty"`  // #RRGGBB
+	EmailDomain  string `json:"email_domain,omitempty"`  // 发件地址使用的自定义域名，需已在 SMTP 服务商处验证
+	PortalURL    string `json:"portal_url,omitempty"`    // 客户门户地址，邮件中的链接以此为前缀
+	FooterText   string `json:"footer_text,omitempty"`   // 邮件页脚，如版权信息
+}
+
+// BrandingConfig 品牌配置。多租户模式下按请求的门户域名匹配租户覆盖
+type BrandingConfig struct {
+	BrandingSettings
+	MultiTenant bool                        `json:"multi_tenant"`
+	Tenants     map[string]BrandingSettings `json:"tenants,omitempty"` // 键为租户门户域名，如 support.acme.com
+}
+
+// PublicBranding 门户公开接口返回的品牌信息，不含发件域名
+type PublicBranding struct {
+	ProductName  string `json:"product_name"`
+	LogoURL      string `json:"logo_url,omitempty"`
+	FaviconURL   string `json:"favicon_url,omitempty"`
+	PrimaryColor string `json:"primary_color"`
+	AccentColor  string `json:"accent_color"`
+	PortalURL    string `json:"portal_url"`
+	FooterText   string `json:"footer_text,omitempty"`
+	Tenant       string `json:"tenant,omitempty"` // 匹配到的租户域名
+}
+
+// GetDefaultBranding 获取默认品牌配置，与未配置品牌时邮件模板的样式一致；门户地址默认为前端地址（WEB_URL）
+func GetDefaultBranding() *BrandingConfig {
+	return &BrandingConfig{
+		BrandingSettings: BrandingSettings{
+			PrimaryColor: "#007bff",
+			AccentColor:  "#28a745",
+		},
+	}
+}
+
+// Merge 用 override 中非空的字段覆盖当前设置
+func (s BrandingSettings) Merge(override BrandingSettings) BrandingSettings {
+	pick := func(base, value string) string {
+		if value != "" {
+			return value
+		}
+		return base
+	}
+	return BrandingSettings{
+		ProductName:  pick(s.ProductName, override.ProductName),
+		LogoURL:      pick(s.LogoURL, override.LogoURL),
+		FaviconURL:   pick(s.FaviconURL, override.FaviconURL),
+		PrimaryColor: pick(s.PrimaryColor, override.PrimaryColor),
+		AccentColor:  pick(s.AccentColor, override.AccentColor),
+		EmailDomain:  pick(s.EmailDomain, override.EmailDomain),
+		PortalURL:    pick(s.PortalURL, override.PortalURL),
+		FooterText:   pick(s.FooterText, override.FooterText),
+	}
+}
+
+// Public 门户可见的品牌信息
+func (s BrandingSettings) Public() PublicBranding {
+	return PublicBranding{
+		ProductName:  s.ProductName,
+		LogoURL:      s.LogoURL,
+		FaviconURL:   s.FaviconURL,
+		PrimaryColor: s.PrimaryColor,
+		AccentColor:  s.AccentColor,
+		PortalURL:    s.PortalURL,
+		FooterText:   s.FooterText,
+	}
+}
+
+// Validate 校验品牌设置，空字段表示继承
+func (s *BrandingSettings) Validate() error {
+	if utf8.RuneCountInString(s.ProductName) > 100 {
+		return fmt.Errorf("product_name must not exceed 100 characters")
+	}
+	if utf8.RuneCountInString(s.FooterText) > 500 {
+		return fmt.Errorf("footer_text must not exceed 500 characters")
+	}
+	for name, value := range map[string]string{"logo_url": s.LogoURL, "favicon_url": s.FaviconURL} {
+		if value != "" && !strings.HasPrefix(value, "/") && !isHTTPURL(value) {
+			return fmt.Errorf("%s must be an http(s) URL or an absolute path", name)
+		}
+	}
+	for name, value := range map[string]string{"primary_color": s.PrimaryColor, "accent_color": s.AccentColor} {
+		if value != "" && !brandingColorPattern.MatchString(value) {
+			return fmt.Errorf("%s must be a hex color like #1a2b3c", name)
+		}
+	}
+	if s.EmailDomain != "" && !IsBrandingHost(s.EmailDomain) {
+		return fmt.Errorf("email_domain must be a lowercase domain name")
+	}
+	if s.PortalURL != "" && !isHTTPURL(s.PortalURL) {
+		return fmt.Errorf("portal_url must be an http(s) URL")
+	}
+	return nil
+}
+
+// Validate 校验品牌配置及租户覆盖
+func (c *BrandingConfig) Validate() error {
+	if err := c.BrandingSettings.Validate(); err != nil {
+		return err
+	}
+	if len(c.Tenants) > BrandingMaxTenants {
+		return fmt.Errorf("at most %d tenants are allowed", BrandingMaxTenants)
+	}
+	for host, settings := range c.Tenants {
+		if !IsBrandingHost(host) {
+			return fmt.Errorf("tenant %q must be a lowercase domain name", host)
+		}
+		if err := settings.Validate(); err != nil {
+			return fmt.Errorf("tenant %s: %w", host, err)
+		}
+	}
+	return nil
+}
+
+// Resolve 计算请求域名适用的品牌设置；未开启多租户或域名未配置覆盖时使用全局设置。
+// 返回匹配到的租户域名，未匹配时为空
+func (c *BrandingConfig) Resolve(host string) (BrandingSettings, string) {
+	if !c.MultiTenant {
+		return c.BrandingSettings, ""
+	}
+	host = NormalizeBrandingHost(host)
+	override, ok := c.Tenants[host]
+	if !ok {
+		return c.BrandingSettings, ""
+	}
+	return c.BrandingSettings.Merge(override), host
+}
+
+// NormalizeBrandingHost 去掉端口并转为小写
+func NormalizeBrandingHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if i := strings.LastIndex(host, ":"); i >= 0 && !strings.Contains(host[i:], "]") {
+		host = host[:i]
+	}
+	return strings.TrimSuffix(host, ".")
+}
+
+// IsBrandingHost 是否为合法的小写域名
+func IsBrandingHost(host string) bool {
+	return len(host) <= 253 && brandingHostPattern.MatchString(host)
+}
+
+func isHTTPURL(value string) bool {
+	parsed, err := url.Parse(value)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
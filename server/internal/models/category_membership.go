@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// CategoryMembership 坐席所属的分类队列。开启分类权限后，坐席只能查看和处理所属分类的工单
+type CategoryMembership struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+
+	UserID      uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_category_membership"`
+	User        *User     `json:"user,omitempty" gorm:"foreignKey:UserID"`
+	CategoryID  uint      `json:"category_id" gorm:"not null;uniqueIndex:idx_category_membership;index"`
+	Category    *Category `json:"category,omitempty" gorm:"foreignKey:CategoryID"`
+	CreatedByID uint      `json:"created_by_id"`
+}
+
+// TableName 指定表名
+func (CategoryMembership) TableName() string {
+	return "category_memberships"
+}
+
+// 批量维护分类成员的操作
+const (
+	CategoryMembershipAdd    = "add"
+	CategoryMembershipRemove = "remove"
+)
+
+// CategoryMembershipBulkRequest 批量添加或移除坐席与分类的所属关系（笛卡尔积）
+type CategoryMembershipBulkRequest struct {
+	Action      string `json:"action" binding:"required,oneof=add remove"`
+	UserIDs     []uint `json:"user_ids" binding:"required,min=1,max=500"`
+	CategoryIDs []uint `json:"category_ids" binding:"required,min=1,max=500"`
+}
+
+// CategoryMembershipReplaceRequest 替换坐席所属的全部分类，传空数组清空
+type CategoryMembershipReplaceRequest struct {
+	CategoryIDs []uint `json:"category_ids" binding:"max=500"`
+}
+
+// CategoryMembershipBulkResult 批量维护结果
+type CategoryMembershipBulkResult struct {
+	Added   int `json:"added"`
+	Removed int `json:"removed"`
+}
+
+// TicketAssignee 可分配工单的处理人
+type TicketAssignee struct {
+	ID       uint     `json:"id"`
+	Username string   `json:"username"`
+	Name     string   `json:"name"`
+	Email    string   `json:"email"`
+	Role     UserRole `json:"role"`
+}
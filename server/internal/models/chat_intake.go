@@ -0,0 +1,124 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// ChatPlatform 聊天平台
+type ChatPlatform string
+
+const (
+	ChatPlatformSlack    ChatPlatform = "slack"    // Slack 斜杠命令
+	ChatPlatformTelegram ChatPlatform = "telegram" // Telegram 机器人
+	ChatPlatformTeams    ChatPlatform = "teams"    // Microsoft Teams，仅用于升级作战室
+)
+
+// ChatIntegrationConfig 聊天平台建单集成配置，密钥不在接口中返回明文
+type ChatIntegrationConfig struct {
+	SlackEnabled        bool           `json:"slack_enabled"`
+	SlackSigningSecret  string         `json:"slack_signing_secret,omitempty"` // 校验斜杠命令请求签名
+	SlackBotToken       string         `json:"slack_bot_token,omitempty"`      // 发送确认消息及状态更新（chat:write）
+	TelegramEnabled     bool           `json:"telegram_enabled"`
+	TelegramBotToken    string         `json:"telegram_bot_token,omitempty"`
+	TelegramSecretToken string         `json:"telegram_secret_token,omitempty"` // setWebhook 时设置的 secret_token
+	DefaultType         TicketType     `json:"default_type"`
+	DefaultPriority     TicketPriority `json:"default_priority"`
+	ThreadStatusUpdates bool           `json:"thread_status_updates"` // 工单状态变更时回复到来源会话
+
+	// Microsoft Teams 应用（Microsoft Graph 客户端凭据），用于创建升级作战室频道
+	TeamsEnabled      bool   `json:"teams_enabled"`
+	TeamsTenantID     string `json:"teams_tenant_id,omitempty"`
+	TeamsClientID     string `json:"teams_client_id,omitempty"`
+	TeamsClientSecret string `json:"teams_client_secret,omitempty"`
+	TeamsTeamID       string `json:"teams_team_id,omitempty"` // 作战室频道创建在该团队下
+}
+
+// GetDefaultChatIntegrationConfig 获取默认聊天建单配置（默认关闭）
+func GetDefaultChatIntegrationConfig() *ChatIntegrationConfig {
+	return &ChatIntegrationConfig{
+		DefaultType:         TicketTypeRequest,
+		DefaultPriority:     TicketPriorityNormal,
+		ThreadStatusUpdates: true,
+	}
+}
+
+// Validate 校验聊天建单配置
+func (c *ChatIntegrationConfig) Validate() error {
+	if c.SlackEnabled && (c.SlackSigningSecret == "" || c.SlackBotToken == "") {
+		return fmt.Errorf("slack_signing_secret and slack_bot_token are required when slack is enabled")
+	}
+	if c.TelegramEnabled && (c.TelegramBotToken == "" || c.TelegramSecretToken == "") {
+		return fmt.Errorf("telegram_bot_token and telegram_secret_token are required when telegram is enabled")
+	}
+	if c.TeamsEnabled && (c.TeamsTenantID == "" || c.TeamsClientID == "" || c.TeamsClientSecret == "" || c.TeamsTeamID == "") {
+		return fmt.Errorf("teams_tenant_id, teams_client_id, teams_client_secret and teams_team_id are required when teams is enabled")
+	}
+	switch c.DefaultType {
+	case TicketTypeIncident, TicketTypeRequest, TicketTypeProblem, TicketTypeChange, TicketTypeComplaint, TicketTypeConsultation:
+	default:
+		return fmt.Errorf("invalid default_type: %s", c.DefaultType)
+	}
+	switch c.DefaultPriority {
+	case TicketPriorityLow, TicketPriorityNormal, TicketPriorityHigh, TicketPriorityUrgent, TicketPriorityCritical:
+	default:
+		return fmt.Errorf("invalid default_priority: %s", c.DefaultPriority)
+	}
+	return nil
+}
+
+// ChatUserMapping 聊天平台账号与系统用户邮箱的映射，聊天中提交的工单以映射到的用户身份创建
+type ChatUserMapping struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Platform         ChatPlatform `json:"platform" gorm:"size:20;not null;uniqueIndex:idx_chat_user_mapping"`
+	ExternalUserID   string       `json:"external_user_id" gorm:"size:100;not null;uniqueIndex:idx_chat_user_mapping"` // Slack user_id / Telegram from.id
+	ExternalUsername string       `json:"external_username" gorm:"size:100"`
+	Email            string       `json:"email" gorm:"size:255;not null;index"` // 小写邮箱，按邮箱匹配系统用户
+	CreatedByID      *uint        `json:"created_by_id,omitempty"`
+
+	// 查询时按邮箱解析出的用户，未注册时为空
+	UserID *uint `json:"user_id,omitempty" gorm:"-"`
+}
+
+// TableName 指定表名
+func (ChatUserMapping) TableName() string {
+	return "chat_user_mappings"
+}
+
+// ChatTicketThread 聊天中创建的工单与来源会话的对应关系，后续状态更新回复到该会话
+type ChatTicketThread struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+
+	TicketID       uint         `json:"ticket_id" gorm:"not null;index"`
+	Platform       ChatPlatform `json:"platform" gorm:"size:20;not null;uniqueIndex:idx_chat_ticket_thread"`
+	ChannelID      string       `json:"channel_id" gorm:"size:100;not null;uniqueIndex:idx_chat_ticket_thread"` // Slack channel_id / Telegram chat.id
+	ThreadID       string       `json:"thread_id" gorm:"size:100;not null;uniqueIndex:idx_chat_ticket_thread"`  // Slack 消息 ts / Telegram message_id
+	ExternalUserID string       `json:"external_user_id" gorm:"size:100"`
+}
+
+// TableName 指定表名
+func (ChatTicketThread) TableName() string {
+	return "chat_ticket_threads"
+}
+
+// ChatUserMappingRequest 创建或更新聊天账号映射请求
+type ChatUserMappingRequest struct {
+	Platform         ChatPlatform `json:"platform" binding:"required,oneof=slack telegram"`
+	ExternalUserID   string       `json:"external_user_id" binding:"required,max=100"`
+	ExternalUsername string       `json:"external_username" binding:"max=100"`
+	Email            string       `json:"email" binding:"required,email"`
+}
+
+// ChatIntakeMessage 聊天平台提交的建单消息
+type ChatIntakeMessage struct {
+	Platform         ChatPlatform
+	ExternalUserID   string
+	ExternalUsername string
+	ChannelID        string
+	MessageID        string // 来源消息ID（Telegram），Slack 斜杠命令没有消息ID
+	Text             string
+}
@@ -0,0 +1,81 @@
+package models
+
+import "time"
+
+// 回复锁释放原因
+const (
+	ReplyLockReasonAcquired  = "acquired"  // 开始回复
+	ReplyLockReasonSubmitted = "submitted" // 已提交回复
+	ReplyLockReasonDiscarded = "discarded" // 放弃草稿
+	ReplyLockReasonExpired   = "expired"   // 超时未续期
+)
+
+// TicketCommentDraft 工单评论草稿，每个用户在每个工单下最多一份，自动保存
+type TicketCommentDraft struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+
+	TicketID uint `json:"ticket_id" gorm:"not null;uniqueIndex:idx_comment_draft_ticket_user"`
+	UserID   uint `json:"user_id" gorm:"not null;uniqueIndex:idx_comment_draft_ticket_user"`
+
+	Content       string            `json:"content" gorm:"type:text"`
+	ContentType   string            `json:"content_type" gorm:"size:20;default:'text'"`
+	Type          CommentType       `json:"type" gorm:"size:20"`
+	Visibility    CommentVisibility `json:"visibility" gorm:"size:20"`
+	VisibleTeamID *uint             `json:"visible_team_id,omitempty"`
+	ParentID      *uint             `json:"parent_id,omitempty"`
+}
+
+// TableName 指定表名
+func (TicketCommentDraft) TableName() string {
+	return "ticket_comment_drafts"
+}
+
+// TicketReplyLock 工单回复软锁（"某某正在回复"），同一工单同时只有一个持有者，超时未续期自动失效
+type TicketReplyLock struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+
+	TicketID  uint      `json:"ticket_id" gorm:"not null;uniqueIndex"`
+	UserID    uint      `json:"user_id" gorm:"not null;index"`
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null;index"`
+
+	User *User `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
+
+// TableName 指定表名
+func (TicketReplyLock) TableName() string {
+	return "ticket_reply_locks"
+}
+
+// CommentDraftSaveRequest 自动保存评论草稿请求
+type CommentDraftSaveRequest struct {
+	Content     string            `json:"content"`
+	ContentType string            `json:"content_type" binding:"omitempty,oneof=text html markdown"`
+	Type        CommentType       `json:"type" binding:"omitempty,oneof=public internal"`
+	Visibility  CommentVisibility `json:"visibility" binding:"omitempty,oneof=public internal team"`
+	TeamID      *uint             `json:"visible_team_id"`
+	ParentID    *uint             `json:"parent_id"`
+	Lock        bool              `json:"lock"` // 获取或续期回复锁，仅客服及以上角色有效
+}
+
+// CommentDraftState 草稿及当前回复锁状态
+type CommentDraftState struct {
+	Draft         *TicketCommentDraft `json:"draft"`
+	Lock          *TicketReplyLock    `json:"lock"`            // 当前有效的回复锁，可能由其他人持有
+	LockedByOther bool                `json:"locked_by_other"` // 其他人正在回复
+}
+
+// TicketReplyLockEvent 回复锁变更事件，通过 WebSocket 推送给在线的客服
+type TicketReplyLockEvent struct {
+	TicketID  uint       `json:"ticket_id"`
+	Locked    bool       `json:"locked"`
+	UserID    uint       `json:"user_id"`
+	UserName  string     `json:"user_name"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Reason    string     `json:"reason"`
+
+	Recipients []uint `json:"-"`
+}
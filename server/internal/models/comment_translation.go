@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// TranslationProvider 翻译服务提供方
+type TranslationProvider string
+
+const (
+	TranslationProviderDeepL  TranslationProvider = "deepl"
+	TranslationProviderGoogle TranslationProvider = "google"
+	TranslationProviderAzure  TranslationProvider = "azure"
+)
+
+// CommentTranslation 评论译文缓存，同一评论每种目标语言一条，评论内容变化后重新翻译
+type CommentTranslation struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	CommentID      uint                `json:"comment_id" gorm:"not null;uniqueIndex:idx_comment_translation_lang"`
+	TargetLanguage string              `json:"target_language" gorm:"size:10;not null;uniqueIndex:idx_comment_translation_lang"`
+	SourceLanguage string              `json:"source_language" gorm:"size:10"` // 服务商识别出的原文语言
+	Provider       TranslationProvider `json:"provider" gorm:"size:20"`
+	Content        string              `json:"content" gorm:"type:text"`
+	SourceHash     string              `json:"-" gorm:"size:64"` // 原文 sha256，用于判断缓存是否过期
+}
+
+// TableName 指定表名
+func (CommentTranslation) TableName() string {
+	return "comment_translations"
+}
+
+// TranslationPreference 用户的评论翻译偏好
+type TranslationPreference struct {
+	ID        uint      `json:"-" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"-"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	UserID         uint   `json:"-" gorm:"uniqueIndex;not null"`
+	AutoTranslate  bool   `json:"auto_translate" gorm:"default:false"` // 客户发表的评论自动翻译为目标语言
+	TargetLanguage string `json:"target_language" gorm:"size:10"`      // 为空时使用用户的界面语言
+}
+
+// TableName 指定表名
+func (TranslationPreference) TableName() string {
+	return "user_translation_preferences"
+}
+
+// TranslationPreferenceRequest 更新翻译偏好请求
+type TranslationPreferenceRequest struct {
+	AutoTranslate  *bool   `json:"auto_translate"`
+	TargetLanguage *string `json:"target_language" binding:"omitempty,max=10"`
+}
+
+// CommentTranslateRequest 翻译评论请求
+type CommentTranslateRequest struct {
+	TargetLanguage string `json:"target_language" binding:"omitempty,max=10"` // 为空时使用翻译偏好或界面语言
+}
@@ -0,0 +1,125 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"time"
+)
+
+// ConfigFormat 字符串配置的格式提示，供管理界面选择输入控件
+type ConfigFormat string
+
+const (
+	ConfigFormatTimezone  ConfigFormat = "timezone"  // IANA 时区名称
+	ConfigFormatURL       ConfigFormat = "url"       // http(s) 地址，允许为空
+	ConfigFormatMultiline ConfigFormat = "multiline" // 多行文本，如邮件模板
+)
+
+// ConfigSchemaEntry 系统配置项的定义：类型、默认值、校验规则及界面提示
+type ConfigSchemaEntry struct {
+	Key         string `json:"key"`
+	Type        string `json:"type"` // string, int, bool, json
+	Default     string `json:"default"`
+	Description string `json:"description"`
+	Category    string `json:"category"`
+	Group       string `json:"group"`
+
+	// 校验规则
+	Min     *int         `json:"min,omitempty"`     // 整数最小值
+	Max     *int         `json:"max,omitempty"`     // 整数最大值
+	Enum    []string     `json:"enum,omitempty"`    // 字符串可选值
+	Pattern string       `json:"pattern,omitempty"` // 字符串正则，值为空时不校验
+	Format  ConfigFormat `json:"format,omitempty"`
+
+	// 界面提示
+	Secret          bool   `json:"secret,omitempty"`           // 敏感值，界面应以密码框展示
+	RestartRequired bool   `json:"restart_required,omitempty"` // 修改后需要重启服务才能生效
+	ManagedBy       string `json:"managed_by,omitempty"`       // 由专用接口维护的JSON策略，不能通过通用配置接口修改
+}
+
+// Validate 按定义校验配置值
+func (e *ConfigSchemaEntry) Validate(value string) error {
+	switch e.Type {
+	case "int":
+		var intValue int
+		if err := json.Unmarshal([]byte(value), &intValue); err != nil {
+			return fmt.Errorf("配置值必须是整数: %s", e.Key)
+		}
+		if e.Min != nil && intValue < *e.Min {
+			return fmt.Errorf("配置值不能小于 %d: %s", *e.Min, e.Key)
+		}
+		if e.Max != nil && intValue > *e.Max {
+			return fmt.Errorf("配置值不能大于 %d: %s", *e.Max, e.Key)
+		}
+	case "bool":
+		var boolValue bool
+		if err := json.Unmarshal([]byte(value), &boolValue); err != nil {
+			return fmt.Errorf("配置值必须是布尔值: %s", e.Key)
+		}
+	case "json":
+		var jsonValue interface{}
+		if err := json.Unmarshal([]byte(value), &jsonValue); err != nil {
+			return fmt.Errorf("配置值必须是有效JSON: %s", e.Key)
+		}
+	case "string":
+		return e.validateString(value)
+	default:
+		return fmt.Errorf("不支持的配置值类型: %s", e.Type)
+	}
+	return nil
+}
+
+func (e *ConfigSchemaEntry) validateString(value string) error {
+	if len(e.Enum) > 0 {
+		valid := false
+		for _, option := range e.Enum {
+			if value == option {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("配置值必须是以下之一 %v: %s", e.Enum, e.Key)
+		}
+	}
+	if e.Pattern != "" && value != "" {
+		matched, err := regexp.MatchString(e.Pattern, value)
+		if err != nil || !matched {
+			return fmt.Errorf("配置值格式不正确: %s", e.Key)
+		}
+	}
+	switch e.Format {
+	case ConfigFormatTimezone:
+		if value == "" {
+			return fmt.Errorf("时区不能为空: %s", e.Key)
+		}
+		if _, err := time.LoadLocation(value); err != nil {
+			return fmt.Errorf("无效的时区 %q: %s", value, e.Key)
+		}
+	case ConfigFormatURL:
+		if value == "" {
+			return nil
+		}
+		parsed, err := url.Parse(value)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return fmt.Errorf("配置值必须是 http(s) 地址: %s", e.Key)
+		}
+	}
+	return nil
+}
+
+// ConfigKeyIssue 配置项与定义不一致的记录
+type ConfigKeyIssue struct {
+	Key    string `json:"key"`
+	Value  string `json:"value,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// ConfigSchemaReport 数据库配置与配置定义的比对结果
+type ConfigSchemaReport struct {
+	UnknownKeys []ConfigKeyIssue `json:"unknown_keys"` // 数据库中存在但未定义的配置（孤立配置）
+	Invalid     []ConfigKeyIssue `json:"invalid"`      // 类型或取值不符合定义的配置
+	MissingKeys []string         `json:"missing_keys"` // 已定义但数据库中不存在的配置，读取时使用默认值
+}
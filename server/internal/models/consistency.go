@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// 数据一致性检查项
+const (
+	ConsistencyCheckTicketComments      = "ticket_comment_count"         // 工单评论数
+	ConsistencyCheckCategoryTickets     = "category_ticket_count"        // 分类工单数
+	ConsistencyCheckCategoryActive      = "category_active_ticket_count" // 分类未完结工单数
+	ConsistencyCheckUserAssigned        = "user_tickets_assigned"        // 用户被分配工单数
+	ConsistencyCheckOrphanTicketHistory = "orphan_ticket_history"        // 工单已删除的历史记录
+)
+
+// ConsistencyIssue 一条不一致记录
+type ConsistencyIssue struct {
+	EntityID uint   `json:"entity_id"`
+	Stored   int64  `json:"stored"` // 冗余字段当前值
+	Actual   int64  `json:"actual"` // 按明细重新统计的值
+	Detail   string `json:"detail,omitempty"`
+}
+
+// ConsistencyCheckResult 单个检查项的结果，Issues 最多保留前若干条样本
+type ConsistencyCheckResult struct {
+	Check         string             `json:"check"`
+	Description   string             `json:"description"`
+	Discrepancies int                `json:"discrepancies"`
+	Repaired      int                `json:"repaired"`
+	Issues        []ConsistencyIssue `json:"issues"`
+	Error         string             `json:"error,omitempty"`
+}
+
+// ConsistencyReport 数据一致性检查报告
+type ConsistencyReport struct {
+	Repair             bool                      `json:"repair"`
+	StartedAt          time.Time                 `json:"started_at"`
+	FinishedAt         time.Time                 `json:"finished_at"`
+	TotalDiscrepancies int                       `json:"total_discrepancies"`
+	TotalRepaired      int                       `json:"total_repaired"`
+	Results            []*ConsistencyCheckResult `json:"results"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import "time"
+
+// DownloadArtifactKind 下载中心的文件类型
+type DownloadArtifactKind string
+
+const (
+	DownloadKindAnalyticsExport DownloadArtifactKind = "analytics_export" // 统计报表导出
+	DownloadKindTicketArchive   DownloadArtifactKind = "ticket_archive"   // 工单完整归档
+)
+
+// DownloadArtifact 下载中心记录：后台导出或报表生成完成后为发起人创建。
+// 文件过期被清理后记录仍保留一段时间，可按原参数重新生成
+type DownloadArtifact struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+
+	UserID   uint                 `json:"user_id" gorm:"not null;index"`
+	Kind     DownloadArtifactKind `json:"kind" gorm:"size:30;not null"`
+	SourceID uint                 `json:"source_id"` // 生成该文件的导出任务ID
+	Title    string               `json:"title" gorm:"size:255"`
+	FileName string               `json:"file_name" gorm:"size:255"`
+	FilePath string               `json:"-" gorm:"size:500"` // 导出目录下的相对路径
+	FileSize int64                `json:"file_size"`
+	Params   string               `json:"-" gorm:"type:text"` // 重新生成所需的参数JSON
+
+	ExpiresAt        time.Time  `json:"expires_at" gorm:"index"`      // 过期后文件被清理，不再可下载
+	PurgedAt         *time.Time `json:"purged_at,omitempty"`          // 文件清理时间
+	NotificationID   *uint      `json:"notification_id,omitempty"`    // 生成完成时发送的通知
+	RegeneratedJobID *uint      `json:"regenerated_job_id,omitempty"` // 最近一次重新生成创建的导出任务
+
+	// 以下字段仅在查询时计算
+	Expired           bool       `json:"expired" gorm:"-"`
+	DownloadURL       string     `json:"download_url,omitempty" gorm:"-"`
+	DownloadExpiresAt *time.Time `json:"download_expires_at,omitempty" gorm:"-"`
+}
+
+// TableName 指定表名
+func (DownloadArtifact) TableName() string {
+	return "download_artifacts"
+}
+
+// DownloadRegenerateResponse 重新生成的结果，新文件生成完成后另行通知并出现在下载列表中
+type DownloadRegenerateResponse struct {
+	Kind  DownloadArtifactKind `json:"kind"`
+	JobID uint                 `json:"job_id"`
+}
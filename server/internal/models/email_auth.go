@@ -0,0 +1,204 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// EmailAuthResult SPF/DKIM/DMARC 单项认证结果，取值与 Authentication-Results 头（RFC 8601）一致
+type EmailAuthResult string
+
+const (
+	EmailAuthNone      EmailAuthResult = "none"      // 未认证或发件域未发布记录
+	EmailAuthPass      EmailAuthResult = "pass"      // 通过
+	EmailAuthFail      EmailAuthResult = "fail"      // 失败
+	EmailAuthSoftFail  EmailAuthResult = "softfail"  // SPF 软失败（~all）
+	EmailAuthNeutral   EmailAuthResult = "neutral"   // SPF 中立（?all）
+	EmailAuthTempError EmailAuthResult = "temperror" // DNS 临时错误
+	EmailAuthPermError EmailAuthResult = "permerror" // 记录格式错误
+)
+
+// ParseEmailAuthResult 解析认证结果，未知取值视为 none
+func ParseEmailAuthResult(value string) EmailAuthResult {
+	switch result := EmailAuthResult(strings.ToLower(strings.TrimSpace(value))); result {
+	case EmailAuthPass, EmailAuthFail, EmailAuthSoftFail, EmailAuthNeutral, EmailAuthTempError, EmailAuthPermError:
+		return result
+	case "hardfail":
+		return EmailAuthFail
+	default:
+		return EmailAuthNone
+	}
+}
+
+// EmailAuthVerdict 邮件整体认证结论
+type EmailAuthVerdict string
+
+const (
+	EmailAuthVerdictPass EmailAuthVerdict = "pass" // 发件域通过 DMARC 或对齐的 SPF/DKIM 认证
+	EmailAuthVerdictFail EmailAuthVerdict = "fail" // DMARC 失败或 SPF 硬失败且没有通过的 DKIM 签名，疑似伪造
+	EmailAuthVerdictNone EmailAuthVerdict = "none" // 缺少认证信息，无法判断
+)
+
+// EmailAuthAction 认证失败时对邮件的处理方式
+type EmailAuthAction string
+
+const (
+	EmailAuthActionAccept     EmailAuthAction = "accept"     // 仅标注认证结果，照常进入收件箱
+	EmailAuthActionQuarantine EmailAuthAction = "quarantine" // 进入隔离区等待管理员审核
+	EmailAuthActionReject     EmailAuthAction = "reject"     // 拒收，保存为 rejected 状态以便审计
+)
+
+// EmailAuthentication 收件的 SPF/DKIM/DMARC 认证结果
+type EmailAuthentication struct {
+	SPF         EmailAuthResult  `json:"spf" gorm:"size:20"`
+	SPFDomain   string           `json:"spf_domain,omitempty" gorm:"size:255"` // SPF 校验的域名（信封发件人或 HELO）
+	DKIM        EmailAuthResult  `json:"dkim" gorm:"size:20"`
+	DKIMDomain  string           `json:"dkim_domain,omitempty" gorm:"size:255"` // 通过签名的 d= 域名
+	DMARC       EmailAuthResult  `json:"dmarc" gorm:"size:20"`
+	DMARCPolicy string           `json:"dmarc_policy,omitempty" gorm:"size:20"` // 发件域发布的 p=（none/quarantine/reject）
+	Verdict     EmailAuthVerdict `json:"verdict" gorm:"size:20;index"`
+	Action      EmailAuthAction  `json:"action,omitempty" gorm:"size:20"`
+	Source      string           `json:"source,omitempty" gorm:"size:100"` // 结果来源：可信网关的 authserv-id，本系统校验时为 local
+}
+
+// Summary 返回 "spf=pass dkim=pass dmarc=pass" 形式的结果摘要，未认证时返回空字符串
+func (a *EmailAuthentication) Summary() string {
+	if a == nil || a.Verdict == "" {
+		return ""
+	}
+	value := func(result EmailAuthResult) EmailAuthResult {
+		if result == "" {
+			return EmailAuthNone
+		}
+		return result
+	}
+	return fmt.Sprintf("spf=%s dkim=%s dmarc=%s", value(a.SPF), value(a.DKIM), value(a.DMARC))
+}
+
+// EmailAuthPolicy 收件 SPF/DKIM/DMARC 认证及防伪造策略
+type EmailAuthPolicy struct {
+	Enabled bool `json:"enabled"`
+	// TrustedAuthServIDs 信任其 Authentication-Results 头的收信网关 authserv-id，
+	// 未列出的网关写入的结果会被忽略，防止发件人自行伪造该头
+	TrustedAuthServIDs []string `json:"trusted_authserv_ids"`
+	// VerifySPF 网关未提供 SPF 结果且请求带有客户端 IP 时由本系统查询 DNS 校验 SPF
+	VerifySPF bool `json:"verify_spf"`
+	// FailAction 认证结论为 fail 时的处理方式
+	FailAction EmailAuthAction `json:"fail_action"`
+	// HonorSenderPolicy DMARC 失败时按发件域发布的 p= 处理（reject/quarantine/none 对应拒收/隔离/接收），覆盖 FailAction
+	HonorSenderPolicy bool `json:"honor_sender_policy"`
+	// ProtectedDomains 受保护的域名（通常是本公司域名），声称来自这些域名但认证未通过的邮件一律拒收
+	ProtectedDomains []string `json:"protected_domains"`
+}
+
+// GetDefaultEmailAuthPolicy 获取默认收件认证策略
+func GetDefaultEmailAuthPolicy() *EmailAuthPolicy {
+	return &EmailAuthPolicy{
+		Enabled:            true,
+		TrustedAuthServIDs: []string{},
+		VerifySPF:          true,
+		FailAction:         EmailAuthActionQuarantine,
+		HonorSenderPolicy:  true,
+		ProtectedDomains:   []string{},
+	}
+}
+
+// Validate 校验收件认证策略，并规范化网关标识与域名
+func (p *EmailAuthPolicy) Validate() error {
+	switch p.FailAction {
+	case EmailAuthActionAccept, EmailAuthActionQuarantine, EmailAuthActionReject:
+	default:
+		return fmt.Errorf("fail_action must be accept, quarantine or reject")
+	}
+
+	ids := make([]string, 0, len(p.TrustedAuthServIDs))
+	for _, id := range p.TrustedAuthServIDs {
+		id = strings.ToLower(strings.TrimSpace(id))
+		if id == "" {
+			continue
+		}
+		if strings.ContainsAny(id, " \t;") {
+			return fmt.Errorf("invalid authserv-id %q", id)
+		}
+		ids = append(ids, id)
+	}
+	p.TrustedAuthServIDs = ids
+
+	domains := make([]string, 0, len(p.ProtectedDomains))
+	for _, domain := range p.ProtectedDomains {
+		domain = strings.Trim(strings.ToLower(strings.TrimSpace(domain)), ".")
+		if domain == "" {
+			continue
+		}
+		if !strings.Contains(domain, ".") || strings.ContainsAny(domain, " \t@") {
+			return fmt.Errorf("invalid protected domain %q", domain)
+		}
+		domains = append(domains, domain)
+	}
+	p.ProtectedDomains = domains
+	return nil
+}
+
+// TrustsAuthServID 判断是否信任某个网关写入的认证结果
+func (p *EmailAuthPolicy) TrustsAuthServID(id string) bool {
+	id = strings.ToLower(strings.TrimSpace(id))
+	for _, trusted := range p.TrustedAuthServIDs {
+		if trusted == id {
+			return true
+		}
+	}
+	return false
+}
+
+// IsProtectedDomain 判断域名是否为受保护域名或其子域名
+func (p *EmailAuthPolicy) IsProtectedDomain(domain string) bool {
+	domain = strings.ToLower(domain)
+	for _, protected := range p.ProtectedDomains {
+		if domain == protected || strings.HasSuffix(domain, "."+protected) {
+			return true
+		}
+	}
+	return false
+}
+
+// ActionFor 按认证结果给出处理方式：受保护域名未通过认证一律拒收，其余仅在结论为 fail 时处理
+func (p *EmailAuthPolicy) ActionFor(auth *EmailAuthentication, fromDomain string) EmailAuthAction {
+	if p.IsProtectedDomain(fromDomain) && auth.Verdict != EmailAuthVerdictPass {
+		return EmailAuthActionReject
+	}
+	if auth.Verdict != EmailAuthVerdictFail {
+		return EmailAuthActionAccept
+	}
+	if p.HonorSenderPolicy && auth.DMARC == EmailAuthFail {
+		switch auth.DMARCPolicy {
+		case "reject":
+			return EmailAuthActionReject
+		case "quarantine":
+			return EmailAuthActionQuarantine
+		case "none":
+			return EmailAuthActionAccept
+		}
+	}
+	return p.FailAction
+}
+
+// EmailSenderTrustLevel 发件人可信度
+type EmailSenderTrustLevel string
+
+const (
+	EmailSenderTrusted    EmailSenderTrustLevel = "trusted"    // 最近一封通过认证，且通过次数不少于失败次数
+	EmailSenderUnverified EmailSenderTrustLevel = "unverified" // 没有可用的认证结果
+	EmailSenderSuspicious EmailSenderTrustLevel = "suspicious" // 最近一封或多数邮件认证失败，可能被冒用
+)
+
+// EmailSenderTrust 按发件人地址汇总的近期认证情况，供客服判断邮件是否可信
+type EmailSenderTrust struct {
+	Address      string                `json:"address"`
+	Level        EmailSenderTrustLevel `json:"level"`
+	PassCount    int64                 `json:"pass_count"`
+	FailCount    int64                 `json:"fail_count"`
+	LastVerdict  EmailAuthVerdict      `json:"last_verdict,omitempty"`
+	LastSeenAt   *time.Time            `json:"last_seen_at,omitempty"`
+	WindowInDays int                   `json:"window_days"`
+}
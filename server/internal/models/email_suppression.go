@@ -0,0 +1,87 @@
+package models
+
+import "time"
+
+// EmailAddressState 邮箱地址的投递状态
+type EmailAddressState string
+
+const (
+	EmailAddressOK         EmailAddressState = "ok"          // 正常
+	EmailAddressSoftBounce EmailAddressState = "soft_bounce" // 临时退信（邮箱已满、暂时拒收等），仍会发送
+	EmailAddressHardBounce EmailAddressState = "hard_bounce" // 永久退信（地址不存在等），停止发送
+	EmailAddressComplained EmailAddressState = "complained"  // 收件人投诉为垃圾邮件，停止发送
+)
+
+// Suppressed 该状态是否停止向地址发送邮件
+func (s EmailAddressState) Suppressed() bool {
+	return s == EmailAddressHardBounce || s == EmailAddressComplained
+}
+
+// EmailAddressStatus 邮箱地址的退信/投诉状态，发送前按地址检查
+type EmailAddressStatus struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Email           string            `json:"email" gorm:"size:255;not null;uniqueIndex"` // 小写
+	UserID          *uint             `json:"user_id,omitempty" gorm:"index"`             // 使用该邮箱的用户
+	Status          EmailAddressState `json:"status" gorm:"size:20;not null;index"`
+	SoftBounceCount int               `json:"soft_bounce_count"` // 上次恢复正常以来的连续临时退信次数
+	LastEvent       string            `json:"last_event,omitempty" gorm:"size:20"`
+	LastEventAt     *time.Time        `json:"last_event_at,omitempty"`
+	LastReason      string            `json:"last_reason,omitempty" gorm:"size:500"`
+	LastProvider    string            `json:"last_provider,omitempty" gorm:"size:20"`
+
+	// 管理员手动恢复
+	ReenabledByID  *uint      `json:"reenabled_by_id,omitempty"`
+	ReenabledAt    *time.Time `json:"reenabled_at,omitempty"`
+	ReenableReason string     `json:"reenable_reason,omitempty" gorm:"size:500"`
+}
+
+// TableName 指定表名
+func (EmailAddressStatus) TableName() string {
+	return "email_address_statuses"
+}
+
+// EmailDeliveryEventType 服务商回调的投递事件类型
+type EmailDeliveryEventType string
+
+const (
+	EmailEventBounce    EmailDeliveryEventType = "bounce"
+	EmailEventComplaint EmailDeliveryEventType = "complaint"
+	EmailEventDelivered EmailDeliveryEventType = "delivered"
+	EmailEventReenabled EmailDeliveryEventType = "reenabled" // 管理员手动恢复
+)
+
+// EmailDeliveryEvent 邮箱地址的退信、投诉及手动恢复记录（送达事件只用于重置临时退信，不记录）
+type EmailDeliveryEvent struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+
+	Email     string                 `json:"email" gorm:"size:255;not null;index"`
+	Type      EmailDeliveryEventType `json:"type" gorm:"size:20;not null"`
+	Permanent bool                   `json:"permanent"` // 永久退信
+	Provider  string                 `json:"provider,omitempty" gorm:"size:20"`
+	MessageID string                 `json:"message_id,omitempty" gorm:"size:255"`
+	Reason    string                 `json:"reason,omitempty" gorm:"size:500"`
+	ActorID   *uint                  `json:"actor_id,omitempty"` // 手动恢复的管理员
+
+	FromStatus EmailAddressState `json:"from_status" gorm:"size:20"`
+	ToStatus   EmailAddressState `json:"to_status" gorm:"size:20"`
+}
+
+// TableName 指定表名
+func (EmailDeliveryEvent) TableName() string {
+	return "email_delivery_events"
+}
+
+// EmailAddressDetail 邮箱地址状态及最近事件
+type EmailAddressDetail struct {
+	*EmailAddressStatus
+	Events []*EmailDeliveryEvent `json:"events"`
+}
+
+// EmailReenableRequest 手动恢复向退信/投诉地址发送邮件
+type EmailReenableRequest struct {
+	Reason string `json:"reason" binding:"required,min=10,max=500"` // 恢复理由，记录在事件中
+}
@@ -16,7 +16,7 @@ type EmailVerification struct {
 
 	// 验证信息
 	Email    string    `json:"email" gorm:"size:100;not null;index"`
-	Token    string    `json:"token" gorm:"size:255;uniqueIndex;not null"`
+	Token    string    `json:"-" gorm:"size:255;uniqueIndex;not null"` // 令牌的SHA-256哈希
 	Type     string    `json:"type" gorm:"size:20;not null;default:'email_verification'"` // email_verification, email_change
 	NewEmail string    `json:"new_email" gorm:"size:100"`                                 // 用于邮箱变更
 	Code     string    `json:"code" gorm:"size:10"`                                       // 验证码（可选）
@@ -29,6 +29,12 @@ type EmailVerification struct {
 	// 请求信息
 	IPAddress string `json:"ip_address" gorm:"size:45"`
 	UserAgent string `json:"user_agent" gorm:"size:500"`
+
+	// 使用信息（一次性令牌）
+	Used          bool       `json:"used" gorm:"default:false"`
+	UsedAt        *time.Time `json:"used_at,omitempty"`
+	UsedIP        string     `json:"used_ip" gorm:"size:45"`
+	UsedUserAgent string     `json:"used_user_agent" gorm:"size:500"`
 	
 	// 重试信息
 	AttemptCount int       `json:"attempt_count" gorm:"default:0"`
@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// ExternalReference 工单关联的外部系统记录（如 CRM 客户 ID、订单号）。
+// 同一外部系统的同一编号只能关联一个工单，可据此反查工单
+type ExternalReference struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	TicketID    uint   `json:"ticket_id" gorm:"not null;index"`
+	System      string `json:"system" gorm:"size:50;not null;uniqueIndex:idx_external_reference_system_key"` // 外部系统标识，小写，如 crm、shop
+	Key         string `json:"key" gorm:"size:255;not null;uniqueIndex:idx_external_reference_system_key"`   // 外部系统中的编号
+	URL         string `json:"url,omitempty" gorm:"size:1000"`                                               // 外部记录链接
+	CreatedByID uint   `json:"created_by_id"`
+}
+
+// TableName 指定表名
+func (ExternalReference) TableName() string {
+	return "external_references"
+}
+
+// ExternalReferenceRequest 关联外部记录请求
+type ExternalReferenceRequest struct {
+	System string `json:"system" binding:"required,max=50"`
+	Key    string `json:"key" binding:"required,max=255"`
+	URL    string `json:"url,omitempty" binding:"omitempty,max=1000"`
+}
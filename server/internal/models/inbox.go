@@ -0,0 +1,210 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// InboundEmailStatus 收件分拣状态
+type InboundEmailStatus string
+
+const (
+	InboundEmailStatusPending     InboundEmailStatus = "pending"     // 待分拣
+	InboundEmailStatusConverted   InboundEmailStatus = "converted"   // 已转为工单
+	InboundEmailStatusMerged      InboundEmailStatus = "merged"      // 已合并到现有工单
+	InboundEmailStatusSpam        InboundEmailStatus = "spam"        // 垃圾邮件
+	InboundEmailStatusQuarantined InboundEmailStatus = "quarantined" // 垃圾检测或认证失败拦截，等待管理员审核
+	InboundEmailStatusRejected    InboundEmailStatus = "rejected"    // SPF/DKIM/DMARC 认证失败被拒收
+)
+
+// InboundEmail 邮件渠道收到的、尚未转为工单的邮件
+type InboundEmail struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	MessageID   string    `json:"message_id" gorm:"size:255;index"` // 邮件 Message-ID，用于去重
+	InReplyTo   string    `json:"in_reply_to,omitempty" gorm:"size:255"`
+	References  string    `json:"references,omitempty" gorm:"type:text"`     // References 头中的 Message-ID，空格分隔
+	ThreadID    string    `json:"thread_id,omitempty" gorm:"size:255;index"` // 会话首封邮件的 Message-ID
+	FromAddress string    `json:"from_address" gorm:"size:255;not null;index"`
+	FromName    string    `json:"from_name" gorm:"size:100"`
+	ToAddress   string    `json:"to_address" gorm:"size:255"`
+	Subject     string    `json:"subject" gorm:"size:500"`
+	Body        string    `json:"body" gorm:"type:text"`
+	ReceivedAt  time.Time `json:"received_at" gorm:"index"`
+
+	Status       InboundEmailStatus `json:"status" gorm:"size:20;not null;default:'pending';index"`
+	SnoozedUntil *time.Time         `json:"snoozed_until,omitempty"`
+	SpamScore    int                `json:"spam_score" gorm:"default:0"`
+
+	// SPF/DKIM/DMARC 认证结果
+	Auth EmailAuthentication `json:"authentication" gorm:"embedded;embeddedPrefix:auth_"`
+
+	// 转换或合并后的目标工单
+	TicketID      *uint      `json:"ticket_id,omitempty" gorm:"index"`
+	ProcessedByID *uint      `json:"processed_by_id,omitempty"`
+	ProcessedAt   *time.Time `json:"processed_at,omitempty"`
+}
+
+// TableName 指定表名
+func (InboundEmail) TableName() string {
+	return "inbound_emails"
+}
+
+// EmailThreadMessage 邮件 Message-ID 与工单的映射。客户回复时按 In-Reply-To/References
+// 中的任一 Message-ID 找到工单，追加为评论而不是重复建单
+type EmailThreadMessage struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+
+	MessageID      string `json:"message_id" gorm:"size:255;not null;uniqueIndex"`
+	ThreadID       string `json:"thread_id" gorm:"size:255;not null;index"`
+	TicketID       uint   `json:"ticket_id" gorm:"not null;index"`
+	InboundEmailID *uint  `json:"inbound_email_id,omitempty" gorm:"index"`
+}
+
+// TableName 指定表名
+func (EmailThreadMessage) TableName() string {
+	return "email_thread_messages"
+}
+
+// NormalizeMessageID 规范化 Message-ID 为带尖括号的形式，空值返回空字符串
+func NormalizeMessageID(id string) string {
+	id = strings.Trim(strings.TrimSpace(id), "<>")
+	if id == "" || strings.ContainsAny(id, " \t<>") {
+		return ""
+	}
+	return "<" + id + ">"
+}
+
+// ParseMessageIDs 解析 References/In-Reply-To 头中的 Message-ID 列表，按出现顺序去重
+func ParseMessageIDs(header string) []string {
+	var ids []string
+	seen := map[string]bool{}
+	add := func(raw string) {
+		if id := NormalizeMessageID(raw); id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	if strings.Contains(header, "<") {
+		for _, part := range strings.Split(header, "<")[1:] {
+			if end := strings.Index(part, ">"); end > 0 {
+				add(part[:end])
+			}
+		}
+		return ids
+	}
+	for _, part := range strings.FieldsFunc(header, func(r rune) bool { return r == ' ' || r == ',' || r == '\t' || r == '\n' }) {
+		add(part)
+	}
+	return ids
+}
+
+// TicketEmailThread 工单关联的原始邮件往来，按收到时间排序
+type TicketEmailThread struct {
+	TicketID  uint                `json:"ticket_id"`
+	ThreadIDs []string            `json:"thread_ids"`
+	Messages  []*InboundEmail     `json:"messages"`
+	Senders   []*EmailSenderTrust `json:"senders"` // 会话中各发件人的可信度
+}
+
+// EmailThreadSummary 工单详情中的邮件会话概要
+type EmailThreadSummary struct {
+	ThreadID       string    `json:"thread_id"`
+	MessageCount   int64     `json:"message_count"`
+	LastReceivedAt time.Time `json:"last_received_at"`
+}
+
+// InboxBlocklistKind 黑名单条目类型
+type InboxBlocklistKind string
+
+const (
+	InboxBlocklistSender InboxBlocklistKind = "sender" // 发件人地址
+	InboxBlocklistDomain InboxBlocklistKind = "domain" // 发件人域名
+)
+
+// InboxBlocklistEntry 发件人/域名黑名单，由标记垃圾邮件自动累积
+type InboxBlocklistEntry struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Pattern     string             `json:"pattern" gorm:"size:255;not null;uniqueIndex"` // 小写的邮箱地址或域名
+	Kind        InboxBlocklistKind `json:"kind" gorm:"size:20;not null"`
+	SpamCount   int                `json:"spam_count" gorm:"default:0"` // 被标记为垃圾邮件的次数
+	IsBlocked   bool               `json:"is_blocked" gorm:"default:false;index"`
+	CreatedByID *uint              `json:"created_by_id,omitempty"`
+}
+
+// TableName 指定表名
+func (InboxBlocklistEntry) TableName() string {
+	return "inbox_blocklist"
+}
+
+// EmailDomain 返回邮箱地址的小写域名部分
+func EmailDomain(address string) string {
+	at := strings.LastIndex(address, "@")
+	if at < 0 {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(address[at+1:]))
+}
+
+// InboundEmailCreateRequest 收件入库请求（由邮件网关或收信任务调用）
+type InboundEmailCreateRequest struct {
+	MessageID  string     `json:"message_id"`
+	InReplyTo  string     `json:"in_reply_to"`
+	References string     `json:"references"` // 原始 References 头
+	From       string     `json:"from" binding:"required,email"`
+	FromName   string     `json:"from_name"`
+	To         string     `json:"to"`
+	Subject    string     `json:"subject" binding:"max=500"`
+	Body       string     `json:"body"`
+	ReceivedAt *time.Time `json:"received_at"`
+
+	// 认证信息：收信网关写入的 Authentication-Results 头（可多个），以及本系统校验 SPF 所需的连接信息
+	AuthenticationResults []string `json:"authentication_results"`
+	ClientIP              string   `json:"client_ip" binding:"omitempty,ip"`
+	Helo                  string   `json:"helo"`
+	MailFrom              string   `json:"mail_from"` // 信封发件人（Return-Path）
+}
+
+// InboxConvertRequest 将邮件转为工单的请求，可同时分配处理人或团队
+type InboxConvertRequest struct {
+	Title          string         `json:"title" binding:"max=255"`
+	Type           TicketType     `json:"type"`
+	Priority       TicketPriority `json:"priority"`
+	CategoryID     *uint          `json:"category_id"`
+	AssignedToID   *uint          `json:"assigned_to_id"`
+	AssignedTeamID *uint          `json:"assigned_team_id"`
+}
+
+// InboxAssignRequest 收件箱中工单的分配请求
+type InboxAssignRequest struct {
+	AssignedToID   *uint `json:"assigned_to_id"`
+	AssignedTeamID *uint `json:"assigned_team_id"`
+}
+
+// InboxItem 收件箱条目，邮件和待分拣工单统一展示
+type InboxItem struct {
+	Kind         string                `json:"kind"` // email / ticket
+	ID           uint                  `json:"id"`
+	Subject      string                `json:"subject"`
+	FromAddress  string                `json:"from_address"`
+	FromName     string                `json:"from_name"`
+	Preview      string                `json:"preview"`
+	ReceivedAt   time.Time             `json:"received_at"`
+	SnoozedUntil *time.Time            `json:"snoozed_until,omitempty"`
+	SenderTrust  EmailSenderTrustLevel `json:"sender_trust,omitempty"`
+
+	// 仅邮件条目
+	Authentication *EmailAuthentication `json:"authentication,omitempty"`
+
+	// 仅工单条目
+	TicketNumber string         `json:"ticket_number,omitempty"`
+	Priority     TicketPriority `json:"priority,omitempty"`
+	TeamID       *uint          `json:"assigned_team_id,omitempty"`
+}
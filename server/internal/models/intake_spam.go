@@ -0,0 +1,143 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// IntakeThrottle 单个发件人在一个渠道内的提交频率限制
+type IntakeThrottle struct {
+	Limit         int `json:"limit"`          // 时间窗口内最多提交次数，0 表示不限制
+	WindowMinutes int `json:"window_minutes"` // 时间窗口（分钟）
+}
+
+// IntakeSpamPolicy 公开渠道（邮件、客户门户）受理的垃圾检测与限流策略
+type IntakeSpamPolicy struct {
+	Enabled         bool                      `json:"enabled"`
+	QuarantineScore int                       `json:"quarantine_score"` // 评分大于等于该值时进入隔离区，不进入客服队列
+	MaxLinks        int                       `json:"max_links"`        // 链接数超过该值开始计分
+	BlockedPhrases  []string                  `json:"blocked_phrases"`  // 命中即计分的短语，不区分大小写
+	Throttles       map[string]IntakeThrottle `json:"throttles"`        // 按渠道（email、web、chat、mobile）限流
+}
+
+// GetDefaultIntakeSpamPolicy 获取默认垃圾检测策略
+func GetDefaultIntakeSpamPolicy() *IntakeSpamPolicy {
+	return &IntakeSpamPolicy{
+		Enabled:         true,
+		QuarantineScore: 60,
+		MaxLinks:        3,
+		BlockedPhrases:  []string{"免费领取", "加微信", "刷单", "代开发票", "casino", "viagra", "crypto investment"},
+		Throttles: map[string]IntakeThrottle{
+			string(TicketSourceEmail):  {Limit: 10, WindowMinutes: 60},
+			string(TicketSourceWeb):    {Limit: 5, WindowMinutes: 10},
+			string(TicketSourceChat):   {Limit: 10, WindowMinutes: 10},
+			string(TicketSourceMobile): {Limit: 5, WindowMinutes: 10},
+		},
+	}
+}
+
+// Validate 校验垃圾检测策略
+func (p *IntakeSpamPolicy) Validate() error {
+	if p.QuarantineScore < 1 || p.QuarantineScore > 100 {
+		return fmt.Errorf("quarantine_score must be between 1 and 100")
+	}
+	if p.MaxLinks < 0 {
+		return fmt.Errorf("max_links must not be negative")
+	}
+	for channel, throttle := range p.Throttles {
+		switch TicketSource(channel) {
+		case TicketSourceEmail, TicketSourceWeb, TicketSourceChat, TicketSourceMobile:
+		default:
+			return fmt.Errorf("unsupported throttle channel %q", channel)
+		}
+		if throttle.Limit < 0 || (throttle.Limit > 0 && throttle.WindowMinutes < 1) {
+			return fmt.Errorf("throttle for %s requires a non-negative limit and a window of at least 1 minute", channel)
+		}
+	}
+	phrases := p.BlockedPhrases[:0]
+	for _, phrase := range p.BlockedPhrases {
+		if phrase = strings.TrimSpace(phrase); phrase != "" {
+			phrases = append(phrases, phrase)
+		}
+	}
+	p.BlockedPhrases = phrases
+	return nil
+}
+
+// IntakeSpamVerdict 垃圾检测结果
+type IntakeSpamVerdict struct {
+	Score      int      `json:"score"` // 0-100
+	Reasons    []string `json:"reasons"`
+	Throttled  bool     `json:"throttled"`  // 超过渠道限流
+	Quarantine bool     `json:"quarantine"` // 需进入隔离区人工审核
+}
+
+// IntakeQuarantineStatus 隔离区条目状态
+type IntakeQuarantineStatus string
+
+const (
+	IntakeQuarantinePending  IntakeQuarantineStatus = "pending"  // 待审核
+	IntakeQuarantineReleased IntakeQuarantineStatus = "released" // 已放行
+	IntakeQuarantineRejected IntakeQuarantineStatus = "rejected" // 已确认为垃圾
+)
+
+// IntakeQuarantineItem 被垃圾检测或限流拦截、等待管理员审核的公开渠道提交
+type IntakeQuarantineItem struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Channel      TicketSource `json:"channel" gorm:"size:20;not null;index"`
+	Sender       string       `json:"sender" gorm:"size:255;index"` // 小写的发件人邮箱
+	SenderUserID *uint        `json:"sender_user_id,omitempty" gorm:"index"`
+	Subject      string       `json:"subject" gorm:"size:500"`
+	Body         string       `json:"body" gorm:"type:text"`
+	Score        int          `json:"score" gorm:"index"`
+	Reasons      string       `json:"-" gorm:"type:text"` // 命中原因JSON
+	ReasonList   []string     `json:"reasons" gorm:"-"`
+	Throttled    bool         `json:"throttled" gorm:"default:false"`
+
+	// 邮件渠道关联收件；门户渠道保存原始建单请求，放行时据此创建工单
+	InboundEmailID *uint  `json:"inbound_email_id,omitempty" gorm:"index"`
+	Payload        string `json:"-" gorm:"type:text"`
+
+	Status       IntakeQuarantineStatus `json:"status" gorm:"size:20;not null;default:'pending';index"`
+	TicketID     *uint                  `json:"ticket_id,omitempty"`
+	ReviewedByID *uint                  `json:"reviewed_by_id,omitempty"`
+	ReviewedAt   *time.Time             `json:"reviewed_at,omitempty"`
+	ReviewNote   string                 `json:"review_note,omitempty" gorm:"size:500"`
+}
+
+// TableName 指定表名
+func (IntakeQuarantineItem) TableName() string {
+	return "intake_quarantine"
+}
+
+// BeforeSave GORM钩子 - 将命中原因序列化为JSON字符串
+func (q *IntakeQuarantineItem) BeforeSave(tx *gorm.DB) error {
+	if q.ReasonList == nil {
+		q.ReasonList = []string{}
+	}
+	data, err := json.Marshal(q.ReasonList)
+	if err != nil {
+		return err
+	}
+	q.Reasons = string(data)
+	return nil
+}
+
+// AfterFind GORM钩子 - 反序列化命中原因
+func (q *IntakeQuarantineItem) AfterFind(tx *gorm.DB) error {
+	q.ReasonList = parseStringSliceFromJSON(q.Reasons)
+	return nil
+}
+
+// IntakeQuarantineReviewRequest 隔离区审核请求
+type IntakeQuarantineReviewRequest struct {
+	Note        string `json:"note" binding:"max=500"`
+	BlockSender bool   `json:"block_sender"` // 拒绝时同时拉黑发件人
+}
@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+// IntegrationTicketEvent 集成触发器返回的工单事件（兼容 Zapier/Make 轮询触发器）
+type IntegrationTicketEvent struct {
+	// ID 事件唯一标识，供 Zapier 等平台去重；新建事件为工单ID，更新事件包含更新时间
+	ID         string          `json:"id"`
+	TicketID   uint            `json:"ticket_id"`
+	Event      string          `json:"event"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Ticket     *TicketResponse `json:"ticket"`
+}
+
+// IntegrationTriggerPage 集成触发器分页结果
+type IntegrationTriggerPage struct {
+	Items      []*IntegrationTicketEvent `json:"items"`
+	NextCursor string                    `json:"next_cursor,omitempty"`
+	HasMore    bool                      `json:"has_more"`
+}
+
+// IntegrationAuthInfo 集成连接测试返回的账户信息
+type IntegrationAuthInfo struct {
+	UserID   uint   `json:"user_id"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+	Role     string `json:"role"`
+	// Label Zapier/Make 连接列表中显示的名称
+	Label string `json:"label"`
+}
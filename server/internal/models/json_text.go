@@ -0,0 +1,76 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// JSON 列存储方式
+const (
+	JSONStorageText  = "text"  // 以 TEXT 保存 JSON 字符串（默认，兼容 SQLite）
+	JSONStorageJSONB = "jsonb" // PostgreSQL 原生 jsonb，支持 GIN 索引与包含查询
+)
+
+var nativeJSONStorage atomic.Bool
+
+// SetJSONStorage 设置 JSON 列存储方式，需在迁移和建立查询之前调用
+func SetJSONStorage(mode string) error {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "", JSONStorageText:
+		nativeJSONStorage.Store(false)
+	case JSONStorageJSONB:
+		nativeJSONStorage.Store(true)
+	default:
+		return fmt.Errorf("unsupported JSON storage %q, expected text or jsonb", mode)
+	}
+	return nil
+}
+
+// UsesNativeJSON 判断数据库连接是否使用原生 jsonb 存储 JSON 列，非 PostgreSQL 始终为 false
+func UsesNativeJSON(db *gorm.DB) bool {
+	return nativeJSONStorage.Load() && db.Dialector.Name() == "postgres"
+}
+
+// JSONText 以字符串形式读写的 JSON 列，按存储方式映射为 TEXT 或 jsonb
+type JSONText string
+
+// GormDBDataType 按数据库与存储方式返回列类型
+func (JSONText) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	if UsesNativeJSON(db) {
+		return JSONStorageJSONB
+	}
+	return "text"
+}
+
+// Value 实现 driver.Valuer；jsonb 不接受空字符串，空值写入 NULL
+func (j JSONText) Value() (driver.Value, error) {
+	if j == "" && nativeJSONStorage.Load() {
+		return nil, nil
+	}
+	return string(j), nil
+}
+
+// Scan 实现 sql.Scanner，兼容 TEXT 与 jsonb 返回的字符串或字节
+func (j *JSONText) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*j = ""
+	case string:
+		*j = JSONText(v)
+	case []byte:
+		*j = JSONText(v)
+	default:
+		return fmt.Errorf("cannot scan %T into JSONText", value)
+	}
+	return nil
+}
+
+// String 返回原始 JSON 字符串
+func (j JSONText) String() string {
+	return string(j)
+}
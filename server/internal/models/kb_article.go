@@ -0,0 +1,159 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// KBArticleStatus 知识库文章状态
+type KBArticleStatus string
+
+const (
+	KBArticleStatusDraft     KBArticleStatus = "draft"     // 草稿，仅客服可见
+	KBArticleStatusPublished KBArticleStatus = "published" // 已发布
+	KBArticleStatusArchived  KBArticleStatus = "archived"  // 已归档，不再出现在搜索和推荐中
+)
+
+// KBArticleVisibility 知识库文章可见范围
+type KBArticleVisibility string
+
+const (
+	KBVisibilityInternal KBArticleVisibility = "internal" // 仅客服及以上可见
+	KBVisibilityPublic   KBArticleVisibility = "public"   // 客户也可见
+)
+
+// KBArticle 知识库文章（Markdown 正文）
+type KBArticle struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+
+	Title      string              `json:"title" gorm:"size:255;not null"`
+	Summary    string              `json:"summary" gorm:"size:500"`
+	Content    string              `json:"content" gorm:"type:text;not null"` // Markdown
+	CategoryID *uint               `json:"category_id,omitempty" gorm:"index"`
+	Tags       string              `json:"-" gorm:"type:text"` // 标签JSON
+	TagsObj    []string            `json:"tags" gorm:"-"`
+	Status     KBArticleStatus     `json:"status" gorm:"size:20;not null;default:'draft';index"`
+	Visibility KBArticleVisibility `json:"visibility" gorm:"size:20;not null;default:'internal';index"`
+
+	AuthorID    uint       `json:"author_id" gorm:"not null;index"`
+	UpdatedByID *uint      `json:"updated_by_id,omitempty"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+
+	// 统计
+	ViewCount       int64 `json:"view_count" gorm:"default:0"`
+	LinkCount       int64 `json:"link_count" gorm:"default:0"`       // 被工单引用的次数
+	ResolutionCount int64 `json:"resolution_count" gorm:"default:0"` // 作为工单解决方案的次数
+	DeflectionCount int64 `json:"deflection_count" gorm:"default:0"` // 客户阅读后放弃提交工单的次数
+
+	Category *Category `json:"category,omitempty" gorm:"foreignKey:CategoryID"`
+	Author   *User     `json:"author,omitempty" gorm:"foreignKey:AuthorID"`
+}
+
+// TableName 指定表名
+func (KBArticle) TableName() string {
+	return "kb_articles"
+}
+
+// BeforeSave GORM钩子 - 将标签序列化为JSON字符串
+func (a *KBArticle) BeforeSave(tx *gorm.DB) error {
+	if a.TagsObj == nil {
+		a.TagsObj = []string{}
+	}
+	tagsData, err := json.Marshal(a.TagsObj)
+	if err != nil {
+		return err
+	}
+	a.Tags = string(tagsData)
+	return nil
+}
+
+// AfterFind GORM钩子 - 反序列化标签
+func (a *KBArticle) AfterFind(tx *gorm.DB) error {
+	a.TagsObj = parseStringSliceFromJSON(a.Tags)
+	return nil
+}
+
+// IsVisibleTo 判断文章对指定角色是否可见：客服及以上可见全部，其他用户只能看到已发布的公开文章
+func (a *KBArticle) IsVisibleTo(role string) bool {
+	if IsKBStaffRole(role) {
+		return true
+	}
+	return a.Status == KBArticleStatusPublished && a.Visibility == KBVisibilityPublic
+}
+
+// IsKBStaffRole 判断角色是否可查看内部及未发布文章
+func IsKBStaffRole(role string) bool {
+	switch role {
+	case string(RoleAgent), string(RoleSupervisor), string(RoleAdmin), "superuser":
+		return true
+	}
+	return false
+}
+
+// KBLinkType 文章与工单的关联类型
+type KBLinkType string
+
+const (
+	KBLinkReference  KBLinkType = "reference"  // 参考资料
+	KBLinkResolution KBLinkType = "resolution" // 解决方案
+)
+
+// KBArticleTicketLink 文章与工单的关联
+type KBArticleTicketLink struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+
+	ArticleID  uint       `json:"article_id" gorm:"not null;uniqueIndex:idx_kb_link_article_ticket"`
+	TicketID   uint       `json:"ticket_id" gorm:"not null;uniqueIndex:idx_kb_link_article_ticket;index"`
+	LinkType   KBLinkType `json:"link_type" gorm:"size:20;not null;default:'reference'"`
+	LinkedByID uint       `json:"linked_by_id" gorm:"not null"`
+
+	Article *KBArticle `json:"article,omitempty" gorm:"foreignKey:ArticleID"`
+}
+
+// TableName 指定表名
+func (KBArticleTicketLink) TableName() string {
+	return "kb_article_ticket_links"
+}
+
+// KBArticleCreateRequest 创建文章请求
+type KBArticleCreateRequest struct {
+	Title      string              `json:"title" binding:"required,max=255"`
+	Summary    string              `json:"summary" binding:"max=500"`
+	Content    string              `json:"content" binding:"required"`
+	CategoryID *uint               `json:"category_id"`
+	Tags       StringList          `json:"tags"`
+	Status     KBArticleStatus     `json:"status" binding:"omitempty,oneof=draft published archived"`
+	Visibility KBArticleVisibility `json:"visibility" binding:"omitempty,oneof=internal public"`
+}
+
+// KBArticleUpdateRequest 更新文章请求
+type KBArticleUpdateRequest struct {
+	Title      *string              `json:"title" binding:"omitempty,max=255"`
+	Summary    *string              `json:"summary" binding:"omitempty,max=500"`
+	Content    *string              `json:"content"`
+	CategoryID *uint                `json:"category_id"` // 传 0 表示取消分类
+	Tags       StringList           `json:"tags"`
+	Status     *KBArticleStatus     `json:"status" binding:"omitempty,oneof=draft published archived"`
+	Visibility *KBArticleVisibility `json:"visibility" binding:"omitempty,oneof=internal public"`
+}
+
+// KBLinkRequest 关联文章到工单的请求
+type KBLinkRequest struct {
+	ArticleID    uint `json:"article_id" binding:"required"`
+	AsResolution bool `json:"as_resolution"` // 作为解决方案关联
+}
+
+// KBArticleSuggestion 文章推荐结果
+type KBArticleSuggestion struct {
+	ID         uint                `json:"id"`
+	Title      string              `json:"title"`
+	Summary    string              `json:"summary"`
+	Visibility KBArticleVisibility `json:"visibility"`
+	Score      int                 `json:"score"`
+	Matched    []string            `json:"matched_keywords"`
+}
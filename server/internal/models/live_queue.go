@@ -0,0 +1,134 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// LiveQueueEvent 工单列表实时变更类型
+type LiveQueueEvent string
+
+const (
+	LiveQueueEventCreated  LiveQueueEvent = "created"  // 新建的工单
+	LiveQueueEventUpdated  LiveQueueEvent = "updated"  // 字段变更
+	LiveQueueEventAssigned LiveQueueEvent = "assigned" // 分配、转移、升级或认领
+	LiveQueueEventRemoved  LiveQueueEvent = "removed"  // 已删除或不再符合筛选条件
+)
+
+// LiveQueueFilter 工单列表的筛选条件，由服务端判断变更的工单是否属于该列表。
+// 各条件之间为“且”，列表型条件内为“或”；Tags 需全部包含
+type LiveQueueFilter struct {
+	Status     []string `json:"status,omitempty"`
+	Priority   []string `json:"priority,omitempty"`
+	Type       []string `json:"type,omitempty"`
+	AssigneeID *uint    `json:"assignee_id,omitempty"`
+	Mine       bool     `json:"mine,omitempty"` // 指派给当前用户
+	CreatorID  *uint    `json:"creator_id,omitempty"`
+	TeamID     *uint    `json:"team_id,omitempty"`
+	CategoryID *uint    `json:"category_id,omitempty"`
+	Unassigned bool     `json:"unassigned,omitempty"`
+	Tags       []string `json:"tags,omitempty"`
+}
+
+// LiveQueueTicket 列表实时更新推送的精简工单
+type LiveQueueTicket struct {
+	ID             uint           `json:"id"`
+	TicketNumber   string         `json:"number"`
+	Title          string         `json:"title"`
+	Status         TicketStatus   `json:"status"`
+	Priority       TicketPriority `json:"priority"`
+	Type           TicketType     `json:"type"`
+	AssignedToID   *uint          `json:"assigned_to_id,omitempty"`
+	AssignedTeamID *uint          `json:"assigned_team_id,omitempty"`
+	CategoryID     *uint          `json:"category_id,omitempty"`
+	CreatedByID    uint           `json:"created_by_id"`
+	Tags           []string       `json:"tags,omitempty"`
+	DueDate        *time.Time     `json:"due_date,omitempty"`
+	SLABreached    bool           `json:"sla_breached,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+}
+
+// NewLiveQueueTicket 由工单生成精简结构
+func NewLiveQueueTicket(ticket *Ticket) *LiveQueueTicket {
+	item := &LiveQueueTicket{
+		ID:             ticket.ID,
+		TicketNumber:   ticket.TicketNumber,
+		Title:          ticket.Title,
+		Status:         ticket.Status,
+		Priority:       ticket.Priority,
+		Type:           ticket.Type,
+		AssignedToID:   ticket.AssignedToID,
+		AssignedTeamID: ticket.AssignedTeamID,
+		CategoryID:     ticket.CategoryID,
+		CreatedByID:    ticket.CreatedByID,
+		DueDate:        ticket.DueDate,
+		SLABreached:    ticket.SLABreached,
+		CreatedAt:      ticket.CreatedAt,
+		UpdatedAt:      ticket.UpdatedAt,
+	}
+	if len(ticket.Tags) > 0 {
+		json.Unmarshal([]byte(ticket.Tags), &item.Tags)
+	}
+	return item
+}
+
+// Matches 工单是否符合筛选条件，viewerID 用于 Mine
+func (f *LiveQueueFilter) Matches(ticket *LiveQueueTicket, viewerID uint) bool {
+	if len(f.Status) > 0 && !containsString(f.Status, string(ticket.Status)) {
+		return false
+	}
+	if len(f.Priority) > 0 && !containsString(f.Priority, string(ticket.Priority)) {
+		return false
+	}
+	if len(f.Type) > 0 && !containsString(f.Type, string(ticket.Type)) {
+		return false
+	}
+	if f.Mine && (ticket.AssignedToID == nil || *ticket.AssignedToID != viewerID) {
+		return false
+	}
+	if f.AssigneeID != nil && (ticket.AssignedToID == nil || *ticket.AssignedToID != *f.AssigneeID) {
+		return false
+	}
+	if f.Unassigned && ticket.AssignedToID != nil {
+		return false
+	}
+	if f.CreatorID != nil && ticket.CreatedByID != *f.CreatorID {
+		return false
+	}
+	if f.TeamID != nil && (ticket.AssignedTeamID == nil || *ticket.AssignedTeamID != *f.TeamID) {
+		return false
+	}
+	if f.CategoryID != nil && (ticket.CategoryID == nil || *ticket.CategoryID != *f.CategoryID) {
+		return false
+	}
+	for _, tag := range f.Tags {
+		if !containsString(ticket.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// LiveQueueChange 单个工单的列表变更，removed 时不含 Ticket
+type LiveQueueChange struct {
+	Event    LiveQueueEvent   `json:"event"`
+	TicketID uint             `json:"ticket_id"`
+	Ticket   *LiveQueueTicket `json:"ticket,omitempty"`
+}
+
+// LiveQueueChanges 游标之后的列表变更（WebSocket 不可用时轮询）
+type LiveQueueChanges struct {
+	Changes    []*LiveQueueChange `json:"changes"`
+	NextCursor string             `json:"next_cursor"`
+	HasMore    bool               `json:"has_more"` // 达到条数上限，应立即用 next_cursor 继续拉取
+}
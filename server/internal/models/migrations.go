@@ -425,7 +425,7 @@ CREATE TABLE IF NOT EXISTS otp_codes (
     user_id INTEGER NOT NULL REFERENCES users(id),
     
     -- OTP信息
-    code VARCHAR(20) NOT NULL,
+    code VARCHAR(64) NOT NULL, -- 验证码哈希
     type VARCHAR(30) NOT NULL,
     status VARCHAR(20) DEFAULT 'pending' CHECK (status IN ('pending', 'used', 'expired', 'revoked', 'failed')),
     expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
@@ -549,10 +549,9 @@ CREATE INDEX IF NOT EXISTS idx_ticket_histories_created_date ON ticket_histories
 CREATE INDEX IF NOT EXISTS idx_otp_codes_user_type_status ON otp_codes(user_id, type, status);
 CREATE INDEX IF NOT EXISTS idx_otp_codes_expires_status ON otp_codes(expires_at, status);
 
--- 全文搜索索引
-CREATE INDEX IF NOT EXISTS idx_tickets_title_gin ON tickets USING gin(to_tsvector('english', title));
-CREATE INDEX IF NOT EXISTS idx_tickets_description_gin ON tickets USING gin(to_tsvector('english', description));
-CREATE INDEX IF NOT EXISTS idx_ticket_comments_content_gin ON ticket_comments USING gin(to_tsvector('english', content));
+-- 全文搜索索引（simple 配置，可按语言配置重建）
+CREATE INDEX IF NOT EXISTS idx_tickets_fts_simple ON tickets USING gin(to_tsvector('simple', coalesce(title, '') || ' ' || coalesce(description, '')));
+CREATE INDEX IF NOT EXISTS idx_ticket_comments_fts_simple ON ticket_comments USING gin(to_tsvector('simple', coalesce(content, '')));
 
 -- 通知表索引
 CREATE INDEX IF NOT EXISTS idx_notifications_recipient_read ON notifications(recipient_id, is_read);
@@ -18,6 +18,8 @@ const (
 	NotificationTypeSystemMaintenance   NotificationType = "system_maintenance"   // 系统维护
 	NotificationTypeUserMention         NotificationType = "user_mention"         // 用户提及
 	NotificationTypeSystemAlert         NotificationType = "system_alert"         // 系统警报
+	NotificationTypeTicketReminder      NotificationType = "ticket_reminder"      // 工单提醒
+	NotificationTypeDownloadReady       NotificationType = "download_ready"       // 导出文件已生成
 )
 
 // NotificationPriority 通知优先级
@@ -38,6 +40,7 @@ const (
 	NotificationChannelEmail    NotificationChannel = "email"     // 邮件通知
 	NotificationChannelWebhook  NotificationChannel = "webhook"   // Webhook通知
 	NotificationChannelWebSocket NotificationChannel = "websocket" // WebSocket实时通知
+	NotificationChannelPush     NotificationChannel = "push"      // 浏览器推送（Web Push）
 )
 
 // Notification 通知模型
@@ -51,7 +54,7 @@ type Notification struct {
 	Title       string               `json:"title" gorm:"size:255;not null" validate:"required,max=255"`
 	Content     string               `json:"content" gorm:"type:text" validate:"required"`
 	Priority    NotificationPriority `json:"priority" gorm:"size:20;not null;default:'normal'" validate:"required,oneof=low normal high urgent"`
-	Channel     NotificationChannel  `json:"channel" gorm:"size:20;not null;default:'in_app'" validate:"required,oneof=in_app email webhook websocket"`
+	Channel     NotificationChannel  `json:"channel" gorm:"size:20;not null;default:'in_app'" validate:"required,oneof=in_app email webhook websocket push"`
 
 	// 接收者信息
 	RecipientID uint  `json:"recipient_id" gorm:"not null;index"`
@@ -67,6 +70,9 @@ type Notification struct {
 	RelatedTicketID *uint  `json:"related_ticket_id" gorm:"index"`
 	RelatedTicket   *Ticket `json:"related_ticket,omitempty" gorm:"foreignKey:RelatedTicketID"`
 
+	// 分组信息：同一工单同类型的通知在时间窗口内归为一组，GroupID 为组内第一条通知的ID
+	GroupID *uint `json:"group_id,omitempty" gorm:"index"`
+
 	// 状态信息
 	IsRead     bool       `json:"is_read" gorm:"default:false"`
 	ReadAt     *time.Time `json:"read_at"`
@@ -82,7 +88,7 @@ type Notification struct {
 	MaxRetries   int        `json:"max_retries" gorm:"default:3"`
 
 	// 元数据
-	Metadata       string     `json:"metadata" gorm:"type:text"`        // JSON格式存储额外数据
+	Metadata       JSONText   `json:"metadata"`                         // JSON格式存储额外数据，按存储方式为 TEXT 或 jsonb
 	ActionURL      string     `json:"action_url" gorm:"size:500"`       // 操作链接
 	ExpiresAt      *time.Time `json:"expires_at"`                       // 过期时间
 	ScheduledAt    *time.Time `json:"scheduled_at"`                     // 计划发送时间
@@ -95,6 +101,66 @@ func (Notification) TableName() string {
 	return "notifications"
 }
 
+// NotificationArchive 通知归档模型，保存已清理的历史通知
+type NotificationArchive struct {
+	ID         uint      `json:"id" gorm:"primaryKey"` // 保留原通知ID
+	CreatedAt  time.Time `json:"created_at" gorm:"index"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	ArchivedAt time.Time `json:"archived_at" gorm:"not null;index"`
+
+	Type     NotificationType     `json:"type" gorm:"size:50;not null;index"`
+	Title    string               `json:"title" gorm:"size:255;not null"`
+	Content  string               `json:"content" gorm:"type:text"`
+	Priority NotificationPriority `json:"priority" gorm:"size:20"`
+	Channel  NotificationChannel  `json:"channel" gorm:"size:20"`
+
+	RecipientID     uint   `json:"recipient_id" gorm:"not null;index"`
+	SenderID        *uint  `json:"sender_id"`
+	RelatedType     string `json:"related_type" gorm:"size:50"`
+	RelatedID       *uint  `json:"related_id"`
+	RelatedTicketID *uint  `json:"related_ticket_id" gorm:"index"`
+
+	IsRead         bool       `json:"is_read"`
+	ReadAt         *time.Time `json:"read_at"`
+	IsSent         bool       `json:"is_sent"`
+	SentAt         *time.Time `json:"sent_at"`
+	Metadata       string     `json:"metadata" gorm:"type:text"`
+	ActionURL      string     `json:"action_url" gorm:"size:500"`
+	DeliveryStatus string     `json:"delivery_status" gorm:"size:50"`
+}
+
+// TableName 指定表名
+func (NotificationArchive) TableName() string {
+	return "notifications_archive"
+}
+
+// ToArchive 转换为归档记录
+func (n *Notification) ToArchive(archivedAt time.Time) *NotificationArchive {
+	return &NotificationArchive{
+		ID:              n.ID,
+		CreatedAt:       n.CreatedAt,
+		UpdatedAt:       n.UpdatedAt,
+		ArchivedAt:      archivedAt,
+		Type:            n.Type,
+		Title:           n.Title,
+		Content:         n.Content,
+		Priority:        n.Priority,
+		Channel:         n.Channel,
+		RecipientID:     n.RecipientID,
+		SenderID:        n.SenderID,
+		RelatedType:     n.RelatedType,
+		RelatedID:       n.RelatedID,
+		RelatedTicketID: n.RelatedTicketID,
+		IsRead:          n.IsRead,
+		ReadAt:          n.ReadAt,
+		IsSent:          n.IsSent,
+		SentAt:          n.SentAt,
+		Metadata:        n.Metadata.String(),
+		ActionURL:       n.ActionURL,
+		DeliveryStatus:  n.DeliveryStatus,
+	}
+}
+
 // MarkAsRead 标记为已读
 func (n *Notification) MarkAsRead() {
 	if !n.IsRead {
@@ -156,7 +222,7 @@ type NotificationCreateRequest struct {
 	Title           string               `json:"title" validate:"required,max=255"`
 	Content         string               `json:"content" validate:"required"`
 	Priority        NotificationPriority `json:"priority" validate:"omitempty,oneof=low normal high urgent"`
-	Channel         NotificationChannel  `json:"channel" validate:"omitempty,oneof=in_app email webhook websocket"`
+	Channel         NotificationChannel  `json:"channel" validate:"omitempty,oneof=in_app email webhook websocket push"`
 	RecipientID     uint                 `json:"recipient_id" validate:"required"`
 	SenderID        *uint                `json:"sender_id"`
 	RelatedType     string               `json:"related_type"`
@@ -183,6 +249,7 @@ type NotificationResponse struct {
 	RelatedType     string                 `json:"related_type"`
 	RelatedID       *uint                  `json:"related_id"`
 	RelatedTicket   *TicketResponse        `json:"related_ticket,omitempty"`
+	GroupID         *uint                  `json:"group_id,omitempty"`
 	IsRead          bool                   `json:"is_read"`
 	ReadAt          *time.Time             `json:"read_at"`
 	IsSent          bool                   `json:"is_sent"`
@@ -219,6 +286,7 @@ func (n *Notification) ToResponse() *NotificationResponse {
 		ScheduledAt:    n.ScheduledAt,
 		ExpiresAt:      n.ExpiresAt,
 		DeliveryStatus: n.DeliveryStatus,
+		GroupID:        n.GroupID,
 	}
 
 	// 处理关联用户
@@ -258,6 +326,25 @@ type NotificationFilter struct {
 	Offset         int                    `json:"offset"`
 	OrderBy        string                 `json:"order_by"`  // created_at, priority, type
 	OrderDir       string                 `json:"order_dir"` // asc, desc
+	Sort           []SortField            `json:"sort"`      // 多字段排序，优先于 OrderBy/OrderDir
+}
+
+// NotificationGroup 通知分组摘要，同一工单同类型的多条通知合并为一条
+type NotificationGroup struct {
+	GroupID         uint                  `json:"group_id"`
+	Type            NotificationType      `json:"type"`
+	RelatedTicketID *uint                 `json:"related_ticket_id"`
+	Count           int64                 `json:"count"`
+	UnreadCount     int64                 `json:"unread_count"`
+	IsRead          bool                  `json:"is_read"`
+	Summary         string                `json:"summary"`
+	Latest          *NotificationResponse `json:"latest"` // 组内最新一条通知
+}
+
+// NotificationClearOptions 用户清理通知的过滤条件
+type NotificationClearOptions struct {
+	ReadOnly  bool       `json:"read_only"`  // 仅清理已读通知
+	OlderThan *time.Time `json:"older_than"` // 仅清理早于该时间的通知
 }
 
 // NotificationPreference 用户通知偏好设置
@@ -274,6 +361,7 @@ type NotificationPreference struct {
 	EmailEnabled     bool                `json:"email_enabled" gorm:"default:true"`
 	InAppEnabled     bool                `json:"in_app_enabled" gorm:"default:true"`
 	WebhookEnabled   bool                `json:"webhook_enabled" gorm:"default:false"`
+	PushEnabled      *bool               `json:"push_enabled,omitempty"` // 浏览器推送，为空表示启用
 	
 	// 通知时间设置
 	DoNotDisturbStart *time.Time `json:"do_not_disturb_start"`
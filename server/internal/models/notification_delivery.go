@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// NotificationDeliveryStatus 渠道投递状态
+type NotificationDeliveryStatus string
+
+const (
+	NotificationDeliveryPending   NotificationDeliveryStatus = "pending"   // 等待发送（如合并窗口内、等待重试）
+	NotificationDeliveryDelivered NotificationDeliveryStatus = "delivered" // 已送达渠道
+	NotificationDeliveryFailed    NotificationDeliveryStatus = "failed"    // 发送失败
+	NotificationDeliverySkipped   NotificationDeliveryStatus = "skipped"   // 按偏好、退信名单等规则未发送
+)
+
+// NotificationDelivery 通知在单个渠道上的投递记录。同一通知按渠道和目标（邮箱地址、Webhook 名称等）各保留一条，
+// 重试时更新同一条记录
+type NotificationDelivery struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+
+	NotificationID uint                       `json:"notification_id" gorm:"not null;uniqueIndex:idx_notification_delivery_target"`
+	Channel        NotificationChannel        `json:"channel" gorm:"size:20;not null;uniqueIndex:idx_notification_delivery_target"`
+	Target         string                     `json:"target" gorm:"size:255;not null;default:'';uniqueIndex:idx_notification_delivery_target"`
+	Status         NotificationDeliveryStatus `json:"status" gorm:"size:20;not null;index"`
+	Attempts       int                        `json:"attempts" gorm:"default:0"`
+	LastError      string                     `json:"last_error,omitempty" gorm:"type:text"` // 失败原因或跳过原因
+	LastAttemptAt  *time.Time                 `json:"last_attempt_at,omitempty"`
+	DeliveredAt    *time.Time                 `json:"delivered_at,omitempty"`
+}
+
+// TableName 指定表名
+func (NotificationDelivery) TableName() string {
+	return "notification_deliveries"
+}
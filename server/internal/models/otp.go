@@ -51,7 +51,7 @@ type OTPCode struct {
 	User   *User `json:"user,omitempty" gorm:"foreignKey:UserID"`
 
 	// OTP信息
-	Code      string    `json:"code" gorm:"size:20;not null;index" validate:"required"`
+	Code      string    `json:"-" gorm:"size:64;not null;index" validate:"required"` // 验证码的SHA-256哈希，原始验证码只出现在短信或邮件中
 	Type      OTPType   `json:"type" gorm:"size:30;not null;index" validate:"required"`
 	Status    OTPStatus `json:"status" gorm:"size:20;not null;default:'pending'" validate:"required"`
 	ExpiresAt time.Time `json:"expires_at" gorm:"not null;index"`
@@ -16,7 +16,7 @@ type PasswordReset struct {
 
 	// 重置信息
 	Email     string    `json:"email" gorm:"size:100;not null;index"`
-	Token     string    `json:"token" gorm:"size:255;uniqueIndex;not null"`
+	Token     string    `json:"-" gorm:"size:255;uniqueIndex;not null"` // 令牌的SHA-256哈希
 	Code      string    `json:"code" gorm:"size:10"`       // 验证码（可选）
 	ExpiresAt time.Time `json:"expires_at" gorm:"not null;index"`
 
@@ -30,6 +30,10 @@ type PasswordReset struct {
 	IPAddress   string `json:"ip_address" gorm:"size:45"`
 	UserAgent   string `json:"user_agent" gorm:"size:500"`
 	RequestedBy string `json:"requested_by" gorm:"size:100"` // email or username
+
+	// 使用信息
+	UsedIP        string `json:"used_ip" gorm:"size:45"`
+	UsedUserAgent string `json:"used_user_agent" gorm:"size:500"`
 	
 	// 重试信息
 	AttemptCount int        `json:"attempt_count" gorm:"default:0"`
@@ -0,0 +1,79 @@
+package models
+
+import "time"
+
+// PendingUploadStatus 预签名上传状态
+type PendingUploadStatus string
+
+const (
+	PendingUploadStatusPending  PendingUploadStatus = "pending"  // 已签发上传地址，文件尚未传完
+	PendingUploadStatusUploaded PendingUploadStatus = "uploaded" // 文件已传完并通过校验，等待随评论提交
+)
+
+// 预签名上传方式
+const (
+	UploadModeDirect  = "direct"  // 客户端直接上传到对象存储
+	UploadModeChunked = "chunked" // 客户端分片上传到本服务
+)
+
+// PendingUpload 通过预签名地址上传、尚未关联到工单附件的文件。
+// 随评论提交后转为 TicketAttachment 并删除本记录；过期未关联的由清理任务删除
+type PendingUpload struct {
+	ID        uint      `json:"-" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	UploadID     string              `json:"upload_id" gorm:"size:64;not null;uniqueIndex"` // 客户端引用的上传ID，随机生成
+	UserID       uint                `json:"user_id" gorm:"not null;index"`
+	TicketID     uint                `json:"ticket_id" gorm:"not null;index"`
+	Mode         string              `json:"mode" gorm:"size:20;not null"`
+	Status       PendingUploadStatus `json:"status" gorm:"size:20;not null;index"`
+	OriginalName string              `json:"original_name" gorm:"size:255;not null"`
+	Extension    string              `json:"extension" gorm:"size:10"`
+	DeclaredSize int64               `json:"declared_size"`             // 签发时声明的大小，上传内容必须与之一致
+	ReceivedSize int64               `json:"received_size"`             // 分片上传已接收的字节数
+	ChunkCount   int                 `json:"chunk_count"`               // 分片上传已接收的分片数
+	MimeType     string              `json:"mime_type" gorm:"size:100"` // 上传完成后按内容识别的类型
+	Hash         string              `json:"hash,omitempty" gorm:"size:64"`
+	StoragePath  string              `json:"-" gorm:"size:500;not null"` // 最终文件的存储键
+	StorageType  string              `json:"-" gorm:"size:20;not null"`
+	ExpiresAt    time.Time           `json:"expires_at" gorm:"index"` // 上传地址的过期时间
+
+	Encryption AttachmentEncryption `json:"-" gorm:"embedded;embeddedPrefix:encryption_"` // 分片合并后加密存储时的元数据，提交时复制到附件
+}
+
+// TableName 指定表名
+func (PendingUpload) TableName() string {
+	return "pending_uploads"
+}
+
+// UploadPresignRequest 申请预签名上传地址请求
+type UploadPresignRequest struct {
+	TicketID    uint   `json:"ticket_id" binding:"required"`
+	FileName    string `json:"file_name" binding:"required,max=255"`
+	Size        int64  `json:"size" binding:"required,min=1"`
+	ContentType string `json:"content_type" binding:"required,max=100"`
+}
+
+// UploadPresignResponse 预签名上传地址。
+// direct 方式按 method、upload_url、headers 直接上传；chunked 方式按 chunk_size 依次 PUT 分片，
+// 每个分片带 Content-Range: bytes start-end/total 头
+type UploadPresignResponse struct {
+	UploadID  string            `json:"upload_id"`
+	Mode      string            `json:"mode"`
+	Method    string            `json:"method"`
+	UploadURL string            `json:"upload_url"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	ChunkSize int64             `json:"chunk_size,omitempty"`
+	MaxSize   int64             `json:"max_size"`
+	ExpiresAt time.Time         `json:"expires_at"`
+}
+
+// UploadChunkResponse 分片上传进度
+type UploadChunkResponse struct {
+	UploadID     string              `json:"upload_id"`
+	Status       PendingUploadStatus `json:"status"`
+	ReceivedSize int64               `json:"received_size"`
+	TotalSize    int64               `json:"total_size"`
+	MimeType     string              `json:"mime_type,omitempty"`
+}
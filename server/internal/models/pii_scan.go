@@ -0,0 +1,209 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// PIIAction 检测到敏感信息时的处理方式
+type PIIAction string
+
+const (
+	PIIActionWarn  PIIAction = "warn"  // 原样保存，记录检测结果并提示
+	PIIActionMask  PIIAction = "mask"  // 脱敏后保存，原文不落库
+	PIIActionBlock PIIAction = "block" // 拒绝保存，提示提交人删除敏感信息
+)
+
+// PIIDetector 内置敏感信息检测器
+type PIIDetector string
+
+const (
+	PIIDetectorCreditCard PIIDetector = "credit_card" // 银行卡号（13-19 位，Luhn 校验）
+	PIIDetectorNationalID PIIDetector = "national_id" // 居民身份证号（18 位，校验码校验）
+	PIIDetectorPassword   PIIDetector = "password"    // 「密码: xxx」形式粘贴的密码
+)
+
+// IsValidPIIAction 判断处理方式是否有效
+func IsValidPIIAction(action PIIAction) bool {
+	switch action {
+	case PIIActionWarn, PIIActionMask, PIIActionBlock:
+		return true
+	}
+	return false
+}
+
+// IsValidPIIDetector 判断内置检测器是否存在
+func IsValidPIIDetector(detector PIIDetector) bool {
+	switch detector {
+	case PIIDetectorCreditCard, PIIDetectorNationalID, PIIDetectorPassword:
+		return true
+	}
+	return false
+}
+
+// PIIPattern 管理员自定义的检测规则
+type PIIPattern struct {
+	Name    string `json:"name"`    // 规则名称，出现在检测结果和工单动态中
+	Pattern string `json:"pattern"` // Go 正则表达式，匹配内容整体脱敏
+	Enabled bool   `json:"enabled"`
+}
+
+// PIICategoryRule 按工单分类覆盖全局设置
+type PIICategoryRule struct {
+	CategoryID uint          `json:"category_id"`
+	Enabled    bool          `json:"enabled"`             // 为 false 时该分类不检测
+	Action     PIIAction     `json:"action,omitempty"`    // 为空时使用全局处理方式
+	Detectors  []PIIDetector `json:"detectors,omitempty"` // 为空时使用全局内置检测器
+}
+
+// PIIScanConfig 工单内容敏感信息检测配置
+type PIIScanConfig struct {
+	Enabled        bool              `json:"enabled"`
+	Action         PIIAction         `json:"action"`
+	Detectors      []PIIDetector     `json:"detectors"`       // 启用的内置检测器
+	CustomPatterns []PIIPattern      `json:"custom_patterns"` // 自定义检测规则，对所有启用检测的分类生效
+	Categories     []PIICategoryRule `json:"categories"`      // 未配置的分类使用全局设置
+}
+
+// GetDefaultPIIScanConfig 获取默认配置：默认关闭，开启后对全部内置检测器脱敏保存
+func GetDefaultPIIScanConfig() *PIIScanConfig {
+	return &PIIScanConfig{
+		Enabled:        false,
+		Action:         PIIActionMask,
+		Detectors:      []PIIDetector{PIIDetectorCreditCard, PIIDetectorNationalID, PIIDetectorPassword},
+		CustomPatterns: []PIIPattern{},
+		Categories:     []PIICategoryRule{},
+	}
+}
+
+// Validate 校验检测配置
+func (c *PIIScanConfig) Validate() error {
+	if !IsValidPIIAction(c.Action) {
+		return fmt.Errorf("invalid action %q", c.Action)
+	}
+	if err := validatePIIDetectors(c.Detectors); err != nil {
+		return err
+	}
+
+	if len(c.CustomPatterns) > 50 {
+		return fmt.Errorf("at most 50 custom patterns are allowed")
+	}
+	names := make(map[string]bool, len(c.CustomPatterns))
+	for i := range c.CustomPatterns {
+		pattern := &c.CustomPatterns[i]
+		pattern.Name = strings.TrimSpace(pattern.Name)
+		if pattern.Name == "" || len(pattern.Name) > 50 {
+			return fmt.Errorf("custom pattern name is required and must be at most 50 characters")
+		}
+		key := strings.ToLower(pattern.Name)
+		if names[key] || IsValidPIIDetector(PIIDetector(key)) {
+			return fmt.Errorf("duplicate custom pattern name %q", pattern.Name)
+		}
+		names[key] = true
+		if pattern.Pattern == "" || len(pattern.Pattern) > 500 {
+			return fmt.Errorf("pattern %q must be between 1 and 500 characters", pattern.Name)
+		}
+		re, err := regexp.Compile(pattern.Pattern)
+		if err != nil {
+			return fmt.Errorf("pattern %q is not a valid regular expression: %v", pattern.Name, err)
+		}
+		if re.MatchString("") {
+			return fmt.Errorf("pattern %q must not match empty text", pattern.Name)
+		}
+	}
+
+	categories := make(map[uint]bool, len(c.Categories))
+	for _, rule := range c.Categories {
+		if rule.CategoryID == 0 || categories[rule.CategoryID] {
+			return fmt.Errorf("category rules require a unique category_id")
+		}
+		categories[rule.CategoryID] = true
+		if rule.Action != "" && !IsValidPIIAction(rule.Action) {
+			return fmt.Errorf("invalid action %q for category %d", rule.Action, rule.CategoryID)
+		}
+		if err := validatePIIDetectors(rule.Detectors); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validatePIIDetectors(detectors []PIIDetector) error {
+	seen := make(map[PIIDetector]bool, len(detectors))
+	for _, detector := range detectors {
+		if !IsValidPIIDetector(detector) {
+			return fmt.Errorf("unknown detector %q", detector)
+		}
+		if seen[detector] {
+			return fmt.Errorf("duplicate detector %q", detector)
+		}
+		seen[detector] = true
+	}
+	return nil
+}
+
+// ForCategory 得到分类实际生效的检测设置，返回 false 表示该分类不检测
+func (c *PIIScanConfig) ForCategory(categoryID *uint) (PIIAction, []PIIDetector, bool) {
+	if !c.Enabled {
+		return "", nil, false
+	}
+	action, detectors := c.Action, c.Detectors
+	if categoryID != nil {
+		for _, rule := range c.Categories {
+			if rule.CategoryID != *categoryID {
+				continue
+			}
+			if !rule.Enabled {
+				return "", nil, false
+			}
+			if rule.Action != "" {
+				action = rule.Action
+			}
+			if len(rule.Detectors) > 0 {
+				detectors = rule.Detectors
+			}
+			break
+		}
+	}
+	return action, detectors, true
+}
+
+// PIIFinding 一类敏感信息的命中次数，不包含原文
+type PIIFinding struct {
+	Type  string `json:"type"` // 内置检测器或自定义规则名称
+	Count int    `json:"count"`
+}
+
+// PIIScanResult 检测结果
+type PIIScanResult struct {
+	Action   PIIAction    `json:"action,omitempty"` // 未命中时为空
+	Findings []PIIFinding `json:"findings"`
+	Masked   string       `json:"masked"` // 脱敏后的文本
+}
+
+// Detected 是否检测到敏感信息
+func (r *PIIScanResult) Detected() bool {
+	return r != nil && len(r.Findings) > 0
+}
+
+// Notice 转换为随保存结果返回的提示
+func (r *PIIScanResult) Notice() *PIIScanNotice {
+	if !r.Detected() {
+		return nil
+	}
+	return &PIIScanNotice{Action: r.Action, Findings: r.Findings}
+}
+
+// PIIScanNotice 保存工单描述或评论时的检测提示，仅在创建/更新的响应中返回
+type PIIScanNotice struct {
+	Action   PIIAction    `json:"action"`
+	Findings []PIIFinding `json:"findings"`
+}
+
+// PIIScanTestRequest 管理员试运行检测请求
+type PIIScanTestRequest struct {
+	Text       string         `json:"text" binding:"required,max=20000"`
+	CategoryID *uint          `json:"category_id"`
+	Config     *PIIScanConfig `json:"config"` // 为空时使用已保存的配置；试运行时视为已开启
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// PortalRealtimeToken 客户门户实时连接令牌，用于建立某个工单的 WebSocket 连接
+type PortalRealtimeToken struct {
+	Token     string    `json:"token"`
+	TicketID  uint      `json:"ticket_id"`
+	ExpiresAt time.Time `json:"expires_at"` // 仅限制建立连接的时间，连接建立后保持有效
+	URL       string    `json:"url"`        // 连接地址，含令牌
+}
+
+// CustomerPresence 客户在线状态，推送给订阅该工单的客服
+type CustomerPresence struct {
+	TicketID   uint      `json:"ticket_id"`
+	CustomerID uint      `json:"customer_id"`
+	Online     bool      `json:"online"`  // 客户有任何实时连接
+	Viewing    bool      `json:"viewing"` // 客户正在门户中查看该工单
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// AgentTypingEvent 客服输入状态，推送给正在门户中查看该工单的客户，不含客服身份
+type AgentTypingEvent struct {
+	TicketID  uint `json:"ticket_id"`
+	Typing    bool `json:"typing"`
+	ExpiresIn int  `json:"expires_in,omitempty"` // 秒，未收到后续事件时客户端在此之后隐藏提示
+}
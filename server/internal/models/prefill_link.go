@@ -0,0 +1,68 @@
+package models
+
+import "time"
+
+// PrefillLink 建单预填链接：分类、标题前缀、优先级和自定义字段编码进签名链接，
+// 嵌入内部系统的“报告问题”按钮，打开后带出预填的建单表单
+type PrefillLink struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Name        string `json:"name" gorm:"size:100;not null"`
+	Description string `json:"description" gorm:"type:text"`
+
+	// 预填内容
+	CategoryID   *uint          `json:"category_id,omitempty"`
+	TitlePrefix  string         `json:"title_prefix" gorm:"size:100"`
+	Type         TicketType     `json:"type,omitempty" gorm:"size:20"`
+	Priority     TicketPriority `json:"priority,omitempty" gorm:"size:20"`
+	CustomFields JSONText       `json:"custom_fields"`
+
+	// 签名密钥，仅用于校验链接；Version 在修改预填内容后递增，旧链接随即失效
+	Secret    string     `json:"-" gorm:"size:64;not null"`
+	Version   int        `json:"version" gorm:"not null"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+
+	// 使用统计
+	OpenCount    int64      `json:"open_count" gorm:"not null;default:0"`   // 链接被展开的次数
+	TicketCount  int64      `json:"ticket_count" gorm:"not null;default:0"` // 通过链接创建的工单数
+	LastOpenedAt *time.Time `json:"last_opened_at,omitempty"`
+
+	CreatedByID uint `json:"created_by_id"`
+
+	// 非持久化字段：带签名的令牌和前端建单页地址
+	Token string `json:"token,omitempty" gorm:"-"`
+	URL   string `json:"url,omitempty" gorm:"-"`
+}
+
+// TableName 指定表名
+func (PrefillLink) TableName() string {
+	return "prefill_links"
+}
+
+// PrefillLinkRequest 创建/更新预填链接请求
+type PrefillLinkRequest struct {
+	Name         string                 `json:"name" binding:"required,max=100"`
+	Description  string                 `json:"description" binding:"max=500"`
+	CategoryID   *uint                  `json:"category_id"`
+	TitlePrefix  string                 `json:"title_prefix" binding:"max=100"`
+	Type         TicketType             `json:"type" binding:"omitempty,oneof=incident request problem change complaint consultation"`
+	Priority     TicketPriority         `json:"priority" binding:"omitempty,oneof=low normal high urgent critical"`
+	CustomFields map[string]interface{} `json:"custom_fields"` // 仅支持字符串、数字和布尔值
+	ExpiresAt    *time.Time             `json:"expires_at"`
+}
+
+// PrefillExpansion 展开后的建单表单默认值
+type PrefillExpansion struct {
+	LinkID       uint                   `json:"link_id"`
+	LinkName     string                 `json:"link_name"`
+	CategoryID   *uint                  `json:"category_id,omitempty"`
+	CategoryName string                 `json:"category_name,omitempty"`
+	TitlePrefix  string                 `json:"title_prefix,omitempty"`
+	Type         TicketType             `json:"type,omitempty"`
+	Priority     TicketPriority         `json:"priority,omitempty"`
+	CustomFields map[string]interface{} `json:"custom_fields"`
+	Warnings     []string               `json:"warnings,omitempty"` // 链接创建后失效的预填项，如分类已停用
+}
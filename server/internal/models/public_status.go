@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// PublicStatusMetric 公开状态徽章展示的指标
+type PublicStatusMetric string
+
+const (
+	PublicStatusMetricOpenTickets   PublicStatusMetric = "open_tickets"   // 未完结工单数
+	PublicStatusMetricFirstResponse PublicStatusMetric = "first_response" // 本周平均首次响应时间
+)
+
+// PublicSupportStats 公开的支持服务汇总指标，不包含任何工单明细
+type PublicSupportStats struct {
+	OpenTickets             int64     `json:"open_tickets"`
+	AvgFirstResponseMinutes *float64  `json:"avg_first_response_minutes"` // 本周首次回复的工单的平均响应分钟数，本周无回复时为空
+	RepliedThisWeek         int64     `json:"replied_this_week"`
+	WeekStart               time.Time `json:"week_start"`
+	GeneratedAt             time.Time `json:"generated_at"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// PushSubscription 用户浏览器的 Web Push 订阅，一个用户可以在多个浏览器订阅
+type PushSubscription struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	UserID       uint   `json:"user_id" gorm:"not null;index"`
+	Endpoint     string `json:"endpoint" gorm:"type:text;not null"`
+	EndpointHash string `json:"-" gorm:"size:64;not null;uniqueIndex"` // endpoint 的 SHA-256，用于去重
+	P256dh       string `json:"-" gorm:"size:100;not null"`            // 浏览器公钥（base64url）
+	Auth         string `json:"-" gorm:"size:50;not null"`             // 认证密钥（base64url）
+	UserAgent    string `json:"user_agent" gorm:"size:255"`
+
+	LastUsedAt   *time.Time `json:"last_used_at,omitempty"`
+	FailureCount int        `json:"failure_count" gorm:"default:0"`
+}
+
+// TableName 指定表名
+func (PushSubscription) TableName() string {
+	return "push_subscriptions"
+}
+
+// PushSubscriptionRequest 注册浏览器推送订阅，与浏览器 PushSubscription.toJSON() 的结构一致
+type PushSubscriptionRequest struct {
+	Endpoint string `json:"endpoint" binding:"required,url"`
+	Keys     struct {
+		P256dh string `json:"p256dh" binding:"required"`
+		Auth   string `json:"auth" binding:"required"`
+	} `json:"keys" binding:"required"`
+}
+
+// PushUnsubscribeRequest 取消浏览器推送订阅
+type PushUnsubscribeRequest struct {
+	Endpoint string `json:"endpoint" binding:"required"`
+}
+
+// PushMessage 推送给浏览器 Service Worker 的消息内容
+type PushMessage struct {
+	Title          string `json:"title"`
+	Body           string `json:"body"`
+	URL            string `json:"url,omitempty"`
+	Tag            string `json:"tag,omitempty"` // 相同 tag 的推送在浏览器中相互替换
+	NotificationID uint   `json:"notification_id,omitempty"`
+	Count          int    `json:"count"` // 本条推送合并的通知数
+}
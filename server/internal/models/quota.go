@@ -0,0 +1,111 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// QuotaResource 配额资源
+type QuotaResource string
+
+const (
+	QuotaResourceAgents            QuotaResource = "agents"             // 客服席位（客服与主管）
+	QuotaResourceMonthlyTickets    QuotaResource = "monthly_tickets"    // 当月新建工单数
+	QuotaResourceAttachmentStorage QuotaResource = "attachment_storage" // 附件存储（字节）
+)
+
+// QuotaStatus 配额使用状态
+type QuotaStatus string
+
+const (
+	QuotaStatusOK       QuotaStatus = "ok"       // 低于 80%
+	QuotaStatusWarning  QuotaStatus = "warning"  // 达到 80%
+	QuotaStatusExceeded QuotaStatus = "exceeded" // 达到 100%，宽限额度内仍可创建
+	QuotaStatusBlocked  QuotaStatus = "blocked"  // 超出宽限额度，新建被拒绝
+)
+
+// QuotaWarningThresholds 触发管理员通知的使用率阈值（百分比）
+var QuotaWarningThresholds = []int{80, 100}
+
+// QuotaPolicy 套餐配额定义，0 表示不限制。
+// 软配额：达到上限后在宽限比例内仍允许创建，超出宽限后才拒绝
+type QuotaPolicy struct {
+	Enabled             bool    `json:"enabled"`
+	MaxAgents           int     `json:"max_agents"`            // 客服席位上限
+	MonthlyTickets      int     `json:"monthly_tickets"`       // 每月新建工单上限
+	AttachmentStorageGB float64 `json:"attachment_storage_gb"` // 附件存储上限（GB）
+	GracePercent        int     `json:"grace_percent"`         // 超出上限后的宽限比例
+}
+
+// GetDefaultQuotaPolicy 获取默认配额（未启用）
+func GetDefaultQuotaPolicy() *QuotaPolicy {
+	return &QuotaPolicy{
+		Enabled:      false,
+		GracePercent: 10,
+	}
+}
+
+// Validate 校验配额定义
+func (p *QuotaPolicy) Validate() error {
+	if p.MaxAgents < 0 || p.MonthlyTickets < 0 || p.AttachmentStorageGB < 0 {
+		return fmt.Errorf("quota limits must not be negative")
+	}
+	if p.GracePercent < 0 || p.GracePercent > 100 {
+		return fmt.Errorf("grace_percent must be between 0 and 100")
+	}
+	return nil
+}
+
+// Limit 获取资源上限，附件存储换算为字节；0 表示不限制
+func (p *QuotaPolicy) Limit(resource QuotaResource) int64 {
+	switch resource {
+	case QuotaResourceAgents:
+		return int64(p.MaxAgents)
+	case QuotaResourceMonthlyTickets:
+		return int64(p.MonthlyTickets)
+	case QuotaResourceAttachmentStorage:
+		return int64(p.AttachmentStorageGB * (1 << 30))
+	}
+	return 0
+}
+
+// HardLimit 含宽限的上限，超出后拒绝新建
+func (p *QuotaPolicy) HardLimit(resource QuotaResource) int64 {
+	limit := p.Limit(resource)
+	return limit + limit*int64(p.GracePercent)/100
+}
+
+// QuotaUsageItem 单项资源的配额使用情况
+type QuotaUsageItem struct {
+	Resource    QuotaResource `json:"resource"`
+	Used        int64         `json:"used"`
+	Limit       int64         `json:"limit"`      // 0 表示不限制
+	HardLimit   int64         `json:"hard_limit"` // 含宽限的上限
+	Percent     float64       `json:"percent"`
+	Status      QuotaStatus   `json:"status"`
+	PeriodStart *time.Time    `json:"period_start,omitempty"` // 按月统计的资源的统计起点
+}
+
+// QuotaUsage 配额使用概览
+type QuotaUsage struct {
+	Policy    *QuotaPolicy     `json:"policy"`
+	Items     []QuotaUsageItem `json:"items"`
+	CheckedAt time.Time        `json:"checked_at"`
+}
+
+// QuotaAlert 已发送的配额阈值通知，同一资源同一周期每个阈值只通知一次
+type QuotaAlert struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+
+	Resource  QuotaResource `json:"resource" gorm:"size:30;not null;uniqueIndex:idx_quota_alert"`
+	Period    string        `json:"period" gorm:"size:20;not null;uniqueIndex:idx_quota_alert"` // 按月资源为 2006-01，其他为空
+	Threshold int           `json:"threshold" gorm:"not null;uniqueIndex:idx_quota_alert"`
+	Used      int64         `json:"used"`
+	Limit     int64         `json:"limit"`
+}
+
+// TableName 指定表名
+func (QuotaAlert) TableName() string {
+	return "quota_alerts"
+}
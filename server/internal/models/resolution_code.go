@@ -0,0 +1,99 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var resolutionCodePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,49}$`)
+
+// ResolutionCode 解决代码，工单解决时选择，用于统计问题的处理结果
+type ResolutionCode struct {
+	Code        string `json:"code"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Active      bool   `json:"active"` // 停用后不可再选择，历史工单保留
+}
+
+// ResolutionCodeConfig 解决代码列表配置
+type ResolutionCodeConfig struct {
+	Codes []ResolutionCode `json:"codes"`
+}
+
+// GetDefaultResolutionCodeConfig 获取默认解决代码列表
+func GetDefaultResolutionCodeConfig() *ResolutionCodeConfig {
+	return &ResolutionCodeConfig{
+		Codes: []ResolutionCode{
+			{Code: "fixed", Name: "已修复", Description: "问题已彻底修复", Active: true},
+			{Code: "workaround", Name: "临时方案", Description: "提供了规避方案，根因未解决", Active: true},
+			{Code: "duplicate", Name: "重复工单", Description: "与其他工单为同一问题", Active: true},
+			{Code: "no_fault", Name: "无故障", Description: "系统工作正常，未发现问题", Active: true},
+			{Code: "customer_error", Name: "客户操作问题", Description: "由客户使用或配置不当导致", Active: true},
+			{Code: "cannot_reproduce", Name: "无法复现", Description: "无法复现客户描述的问题", Active: true},
+			{Code: "wont_fix", Name: "不予处理", Description: "确认问题存在但不计划处理", Active: true},
+		},
+	}
+}
+
+// Validate 校验解决代码列表
+func (c *ResolutionCodeConfig) Validate() error {
+	if len(c.Codes) > 100 {
+		return fmt.Errorf("at most 100 resolution codes are allowed")
+	}
+	seen := make(map[string]bool, len(c.Codes))
+	for i := range c.Codes {
+		code := &c.Codes[i]
+		code.Code = strings.ToLower(strings.TrimSpace(code.Code))
+		code.Name = strings.TrimSpace(code.Name)
+		if !resolutionCodePattern.MatchString(code.Code) {
+			return fmt.Errorf("code %d: must match %s", i+1, resolutionCodePattern.String())
+		}
+		if code.Name == "" {
+			return fmt.Errorf("code %s: name is required", code.Code)
+		}
+		if seen[code.Code] {
+			return fmt.Errorf("duplicate code: %s", code.Code)
+		}
+		seen[code.Code] = true
+	}
+	return nil
+}
+
+// Find 按代码查找解决代码
+func (c *ResolutionCodeConfig) Find(code string) (*ResolutionCode, bool) {
+	for i := range c.Codes {
+		if c.Codes[i].Code == code {
+			return &c.Codes[i], true
+		}
+	}
+	return nil, false
+}
+
+// ActiveCodes 返回可选择的解决代码
+func (c *ResolutionCodeConfig) ActiveCodes() []ResolutionCode {
+	codes := make([]ResolutionCode, 0, len(c.Codes))
+	for _, code := range c.Codes {
+		if code.Active {
+			codes = append(codes, code)
+		}
+	}
+	return codes
+}
+
+// ResolutionCodeCategoryCount 解决代码下的分类分布
+type ResolutionCodeCategoryCount struct {
+	CategoryID *uint  `json:"category_id"`
+	Name       string `json:"name"`
+	Tickets    int    `json:"tickets"`
+}
+
+// ResolutionCodeBreakdownRow 按解决代码统计的一行，Code 为空表示解决时未选择代码
+type ResolutionCodeBreakdownRow struct {
+	Code               string                        `json:"code"`
+	Name               string                        `json:"name"`
+	Tickets            int                           `json:"tickets"`
+	Share              float64                       `json:"share"`                // 占统计区间内已解决工单的比例
+	AvgResolutionHours float64                       `json:"avg_resolution_hours"` // 从创建到解决的平均小时数
+	Categories         []ResolutionCodeCategoryCount `json:"categories"`           // 工单数最多的前 5 个分类
+}
@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+// SchedulerJobMaxTimeoutSeconds 定时任务超时时间的上限
+const SchedulerJobMaxTimeoutSeconds = 3600
+
+// 定时任务参数类型
+const (
+	SchedulerJobParamTime = "time" // RFC3339 时间
+	SchedulerJobParamBool = "bool"
+)
+
+// SchedulerJobOverride 管理员对定时任务调度的覆盖，未设置的字段使用任务的默认值
+type SchedulerJobOverride struct {
+	CronExpr       string `json:"cron_expr,omitempty"`
+	IsActive       *bool  `json:"is_active,omitempty"`
+	TimeoutSeconds int    `json:"timeout_seconds,omitempty"`
+}
+
+// SchedulerJobParam 立即执行时可覆盖的任务参数
+type SchedulerJobParam struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Description string `json:"description"`
+}
+
+// SchedulerJobInfo 定时任务的调度与执行状态
+type SchedulerJobInfo struct {
+	ID              string              `json:"id"`
+	Name            string              `json:"name"`
+	Description     string              `json:"description"`
+	CronExpr        string              `json:"cron_expr"`
+	DefaultCronExpr string              `json:"default_cron_expr"`
+	IsActive        bool                `json:"is_active"`
+	TimeoutSeconds  int                 `json:"timeout_seconds"`
+	Overridden      bool                `json:"overridden"` // 调度是否被管理员修改过
+	Running         bool                `json:"running"`
+	LastRun         *time.Time          `json:"last_run,omitempty"`
+	LastDuration    string              `json:"last_duration,omitempty"`
+	LastError       string              `json:"last_error,omitempty"`
+	NextRun         *time.Time          `json:"next_run,omitempty"` // 停用的任务不返回
+	RunCount        int64               `json:"run_count"`
+	ErrorCount      int64               `json:"error_count"`
+	Params          []SchedulerJobParam `json:"params,omitempty"`
+	UpcomingRuns    []time.Time         `json:"upcoming_runs,omitempty"` // 详情接口返回接下来的执行时间
+}
+
+// SchedulerJobUpdateRequest 修改定时任务调度请求，只修改提供的字段
+type SchedulerJobUpdateRequest struct {
+	CronExpr       *string `json:"cron_expr"`
+	IsActive       *bool   `json:"is_active"`
+	TimeoutSeconds *int    `json:"timeout_seconds"`
+}
+
+// SchedulerJobRunRequest 立即执行定时任务请求
+type SchedulerJobRunRequest struct {
+	Params         map[string]interface{} `json:"params"`
+	TimeoutSeconds int                    `json:"timeout_seconds"` // 本次执行的超时时间，0 使用任务的超时时间
+}
+
+// SchedulerCronPreview cron 表达式的执行时间预览
+type SchedulerCronPreview struct {
+	CronExpr string      `json:"cron_expr"`
+	Runs     []time.Time `json:"runs"`
+}
@@ -0,0 +1,123 @@
+package models
+
+import "time"
+
+// ScriptHookEvent 脚本钩子的挂载点
+type ScriptHookEvent string
+
+const (
+	ScriptHookTicketCreated        ScriptHookEvent = "ticket.created"        // 工单创建后
+	ScriptHookTicketUpdated        ScriptHookEvent = "ticket.updated"        // 工单字段变更后
+	ScriptHookCommentCreated       ScriptHookEvent = "comment.created"       // 评论创建后
+	ScriptHookNotificationDispatch ScriptHookEvent = "notification.dispatch" // 通知保存和发送前，可修改或跳过
+)
+
+// Valid 是否为支持的挂载点
+func (e ScriptHookEvent) Valid() bool {
+	switch e {
+	case ScriptHookTicketCreated, ScriptHookTicketUpdated, ScriptHookCommentCreated, ScriptHookNotificationDispatch:
+		return true
+	}
+	return false
+}
+
+// ScriptHookStatus 单次执行结果
+type ScriptHookStatus string
+
+const (
+	ScriptHookStatusSuccess       ScriptHookStatus = "success"
+	ScriptHookStatusError         ScriptHookStatus = "error"          // 语法或运行错误、动作参数无效
+	ScriptHookStatusTimeout       ScriptHookStatus = "timeout"        // 超过执行时间
+	ScriptHookStatusLimitExceeded ScriptHookStatus = "limit_exceeded" // 超过执行步数或内存上限
+)
+
+// ScriptHook 管理员安装的脚本钩子，在挂载点以沙箱方式执行，通过内置动作修改工单或通知
+type ScriptHook struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Name        string          `json:"name" gorm:"size:100;not null"`
+	Description string          `json:"description" gorm:"type:text"`
+	Event       ScriptHookEvent `json:"event" gorm:"size:50;not null;index"`
+	Script      string          `json:"script" gorm:"type:text;not null"`
+	Enabled     bool            `json:"enabled" gorm:"not null;index"`
+	Position    int             `json:"position" gorm:"not null"` // 同一挂载点按 position、id 升序执行
+
+	TimeoutMillis int `json:"timeout_ms" gorm:"column:timeout_ms;not null"`
+	MemoryLimitKB int `json:"memory_limit_kb" gorm:"column:memory_limit_kb;not null"`
+
+	// 连续失败达到阈值后自动停用，重新启用时清零
+	ConsecutiveFailures int              `json:"consecutive_failures" gorm:"not null;default:0"`
+	LastRunAt           *time.Time       `json:"last_run_at,omitempty"`
+	LastStatus          ScriptHookStatus `json:"last_status,omitempty" gorm:"size:20"`
+	DisabledReason      string           `json:"disabled_reason,omitempty" gorm:"size:255"`
+
+	CreatedByID uint  `json:"created_by_id"`
+	UpdatedByID *uint `json:"updated_by_id,omitempty"`
+}
+
+// TableName 指定表名
+func (ScriptHook) TableName() string {
+	return "script_hooks"
+}
+
+// ScriptHookAction 脚本调用的动作，执行成功后由系统应用
+type ScriptHookAction struct {
+	Name string        `json:"name"`
+	Args []interface{} `json:"args"`
+}
+
+// ScriptHookLog 钩子执行记录
+type ScriptHookLog struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+
+	HookID       uint             `json:"hook_id" gorm:"not null;index"`
+	Event        ScriptHookEvent  `json:"event" gorm:"size:50;not null"`
+	ResourceType string           `json:"resource_type" gorm:"size:30"` // ticket、comment、notification
+	ResourceID   uint             `json:"resource_id"`
+	Status       ScriptHookStatus `json:"status" gorm:"size:20;not null;index"`
+
+	DurationMicros int64    `json:"duration_us" gorm:"column:duration_us"`
+	Steps          int      `json:"steps"`
+	Actions        JSONText `json:"actions"`                 // 已应用的动作列表
+	Output         string   `json:"output" gorm:"type:text"` // log() 输出
+	Error          string   `json:"error,omitempty" gorm:"type:text"`
+}
+
+// TableName 指定表名
+func (ScriptHookLog) TableName() string {
+	return "script_hook_logs"
+}
+
+// ScriptHookRequest 创建/更新脚本钩子请求
+type ScriptHookRequest struct {
+	Name          string          `json:"name" binding:"required,max=100"`
+	Description   string          `json:"description" binding:"max=500"`
+	Event         ScriptHookEvent `json:"event" binding:"required"`
+	Script        string          `json:"script" binding:"required,max=65536"`
+	Enabled       *bool           `json:"enabled,omitempty"`
+	Position      int             `json:"position"`
+	TimeoutMillis int             `json:"timeout_ms" binding:"omitempty,min=10,max=5000"`       // 默认 200
+	MemoryLimitKB int             `json:"memory_limit_kb" binding:"omitempty,min=64,max=16384"` // 默认 1024
+}
+
+// ScriptHookTestRequest 试运行脚本，不应用动作也不记录日志
+type ScriptHookTestRequest struct {
+	Event         ScriptHookEvent        `json:"event" binding:"required"`
+	Script        string                 `json:"script" binding:"required,max=65536"`
+	Payload       map[string]interface{} `json:"payload"` // 脚本可见的变量，如 ticket、changes、comment、notification
+	TimeoutMillis int                    `json:"timeout_ms" binding:"omitempty,min=10,max=5000"`
+	MemoryLimitKB int                    `json:"memory_limit_kb" binding:"omitempty,min=64,max=16384"`
+}
+
+// ScriptHookTestResult 试运行结果
+type ScriptHookTestResult struct {
+	Status         ScriptHookStatus   `json:"status"`
+	Error          string             `json:"error,omitempty"`
+	Actions        []ScriptHookAction `json:"actions"`
+	Output         []string           `json:"output"`
+	Steps          int                `json:"steps"`
+	DurationMicros int64              `json:"duration_us"`
+}
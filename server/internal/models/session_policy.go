@@ -0,0 +1,103 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// sessionPolicyRoles 可以单独设置会话策略的角色
+var sessionPolicyRoles = []UserRole{RoleAdmin, RoleSupervisor, RoleAgent, RoleCustomer}
+
+// RoleSessionPolicy 单个角色的会话与可信设备策略，数值为 0 时沿用全局设置。
+// 角色策略只能比全局设置更严格：刷新令牌有效期、可信设备有效期和数量取两者中较小的值
+type RoleSessionPolicy struct {
+	RefreshTokenTTLHours  int  `json:"refresh_token_ttl_hours"`  // 刷新令牌有效期（小时）
+	TrustedDeviceTTLHours int  `json:"trusted_device_ttl_hours"` // 可信设备有效期（小时）
+	MaxTrustedDevices     int  `json:"max_trusted_devices"`      // 每个用户的可信设备数量上限
+	RequireOTP            bool `json:"require_otp"`              // 必须启用第二因子（TOTP 或短信验证码）
+	IdleTimeoutMinutes    int  `json:"idle_timeout_minutes"`     // 超过该时间没有任何请求的会话不能再刷新令牌，0 表示不限制
+	IdleWarningMinutes    int  `json:"idle_warning_minutes"`     // 空闲到期前多久提示用户，0 表示默认（5 分钟，不超过空闲时间的一半）
+}
+
+// SessionPolicyConfig 按角色的会话策略，未配置的角色沿用全局设置
+type SessionPolicyConfig struct {
+	Roles map[UserRole]*RoleSessionPolicy `json:"roles"`
+}
+
+// GetDefaultSessionPolicyConfig 获取默认会话策略（所有角色沿用全局设置）
+func GetDefaultSessionPolicyConfig() *SessionPolicyConfig {
+	return &SessionPolicyConfig{Roles: map[UserRole]*RoleSessionPolicy{}}
+}
+
+// Validate 校验会话策略
+func (p *SessionPolicyConfig) Validate() error {
+	if p.Roles == nil {
+		p.Roles = map[UserRole]*RoleSessionPolicy{}
+	}
+	for role, policy := range p.Roles {
+		if !isSessionPolicyRole(role) {
+			return fmt.Errorf("unknown role %q", role)
+		}
+		if policy == nil {
+			delete(p.Roles, role)
+			continue
+		}
+		if policy.RefreshTokenTTLHours < 0 || policy.RefreshTokenTTLHours > 8760 {
+			return fmt.Errorf("%s: refresh_token_ttl_hours must be between 0 and 8760", role)
+		}
+		if policy.TrustedDeviceTTLHours < 0 || policy.TrustedDeviceTTLHours > 8760 {
+			return fmt.Errorf("%s: trusted_device_ttl_hours must be between 0 and 8760", role)
+		}
+		if policy.MaxTrustedDevices < 0 || policy.MaxTrustedDevices > 100 {
+			return fmt.Errorf("%s: max_trusted_devices must be between 0 and 100", role)
+		}
+		if policy.IdleTimeoutMinutes != 0 && (policy.IdleTimeoutMinutes < 5 || policy.IdleTimeoutMinutes > 43200) {
+			return fmt.Errorf("%s: idle_timeout_minutes must be 0 or between 5 and 43200", role)
+		}
+		if policy.IdleWarningMinutes < 0 || (policy.IdleWarningMinutes > 0 && policy.IdleWarningMinutes >= policy.IdleTimeoutMinutes) {
+			return fmt.Errorf("%s: idle_warning_minutes must be less than idle_timeout_minutes", role)
+		}
+	}
+	return nil
+}
+
+// ForRole 获取角色的策略，未配置时返回 nil
+func (p *SessionPolicyConfig) ForRole(role UserRole) *RoleSessionPolicy {
+	if p == nil {
+		return nil
+	}
+	return p.Roles[role]
+}
+
+func isSessionPolicyRole(role UserRole) bool {
+	for _, r := range sessionPolicyRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// EffectiveSessionPolicy 合并全局设置与角色策略后实际生效的会话策略
+type EffectiveSessionPolicy struct {
+	Role                   UserRole `json:"role"`
+	AccessTokenTTLSeconds  int64    `json:"access_token_ttl_seconds"`
+	RefreshTokenTTLSeconds int64    `json:"refresh_token_ttl_seconds"`
+	TrustedDeviceTTLHours  int      `json:"trusted_device_ttl_hours"`
+	MaxTrustedDevices      int      `json:"max_trusted_devices"`
+	RequireOTP             bool     `json:"require_otp"`
+	IdleTimeoutMinutes     int      `json:"idle_timeout_minutes"`
+	IdleWarningMinutes     int      `json:"idle_warning_minutes"`
+}
+
+// SessionIdleStatus 会话空闲状态，前端据此在空闲到期前提示用户。
+// 到期后不能再刷新令牌，当前访问令牌在有效期内仍可使用
+type SessionIdleStatus struct {
+	IdleTimeoutMinutes int       `json:"idle_timeout_minutes"`
+	LastActivityAt     time.Time `json:"last_activity_at"`
+	WarningAt          time.Time `json:"warning_at"` // 开始提示的时间
+	ExpiresAt          time.Time `json:"expires_at"` // 空闲到期时间
+	RemainingSeconds   int64     `json:"remaining_seconds"`
+	Warning            bool      `json:"warning"` // 已进入提示期
+	Expired            bool      `json:"expired"`
+}
@@ -0,0 +1,79 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// SLAContractMatchType 合同匹配客户的方式
+type SLAContractMatchType string
+
+const (
+	SLAContractMatchEmail  SLAContractMatchType = "email"  // 指定客户邮箱（显式合同）
+	SLAContractMatchDomain SLAContractMatchType = "domain" // 客户邮箱域名（公司级合同）
+)
+
+// SLAContract 按客户/公司签订的SLA合同，在有效期内优先于分类、优先级SLA配置
+type SLAContract struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Name    string `json:"name" gorm:"size:100;not null"`
+	Company string `json:"company" gorm:"size:100"`
+	Tier    string `json:"tier" gorm:"size:30"` // 客户等级，如 gold、silver，仅用于展示和报表
+
+	MatchType SLAContractMatchType `json:"match_type" gorm:"size:10;not null"`
+	Pattern   string               `json:"pattern" gorm:"size:255;not null;index"` // 小写的邮箱地址或域名
+	Priority  *string              `json:"priority,omitempty" gorm:"size:20"`      // 为空表示适用所有优先级
+
+	ResponseTime    int  `json:"response_time" gorm:"not null"`   // 首次响应时限（分钟）
+	ResolutionTime  int  `json:"resolution_time" gorm:"not null"` // 解决时限（分钟）
+	ExcludeWeekends bool `json:"exclude_weekends" gorm:"default:true"`
+
+	ValidFrom   time.Time  `json:"valid_from" gorm:"not null;index"`
+	ValidUntil  *time.Time `json:"valid_until,omitempty" gorm:"index"` // 为空表示长期有效
+	IsActive    bool       `json:"is_active" gorm:"default:true;index"`
+	CreatedByID uint       `json:"created_by_id"`
+}
+
+// TableName 指定表名
+func (SLAContract) TableName() string {
+	return "sla_contracts"
+}
+
+// CoversTime 合同在指定时间是否有效
+func (c *SLAContract) CoversTime(at time.Time) bool {
+	if !c.IsActive || at.Before(c.ValidFrom) {
+		return false
+	}
+	return c.ValidUntil == nil || at.Before(*c.ValidUntil)
+}
+
+// MatchesCustomer 合同是否适用于该客户邮箱
+func (c *SLAContract) MatchesCustomer(email string) bool {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return false
+	}
+	if c.MatchType == SLAContractMatchEmail {
+		return c.Pattern == email
+	}
+	return c.Pattern == EmailDomain(email)
+}
+
+// SLAContractRequest 创建/更新SLA合同请求
+type SLAContractRequest struct {
+	Name            string               `json:"name" binding:"required,max=100"`
+	Company         string               `json:"company" binding:"max=100"`
+	Tier            string               `json:"tier" binding:"max=30"`
+	MatchType       SLAContractMatchType `json:"match_type" binding:"required,oneof=email domain"`
+	Pattern         string               `json:"pattern" binding:"required,max=255"`
+	Priority        *string              `json:"priority,omitempty"`
+	ResponseTime    int                  `json:"response_time" binding:"required,min=1"`
+	ResolutionTime  int                  `json:"resolution_time" binding:"required,min=1"`
+	ExcludeWeekends *bool                `json:"exclude_weekends,omitempty"`
+	ValidFrom       time.Time            `json:"valid_from" binding:"required"`
+	ValidUntil      *time.Time           `json:"valid_until,omitempty"`
+	IsActive        *bool                `json:"is_active,omitempty"`
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// SLAComplianceRow 一个分组在统计区间内的SLA达成情况。
+// 尚未响应/解决且未超时的工单不计入达标或违约
+type SLAComplianceRow struct {
+	Key                string  `json:"key"`   // 分类/团队ID、优先级或月份（YYYY-MM），无分类/团队时为 none
+	Label              string  `json:"label"` // 分类/团队名称，其他分组同 key
+	Tickets            int     `json:"tickets"`
+	ResponseMet        int     `json:"response_met"`
+	ResponseBreached   int     `json:"response_breached"`
+	ResolutionMet      int     `json:"resolution_met"`
+	ResolutionBreached int     `json:"resolution_breached"`
+	ResponseRate       float64 `json:"response_rate"`   // 已判定工单中响应达标的百分比
+	ResolutionRate     float64 `json:"resolution_rate"` // 已判定工单中解决达标的百分比
+
+	// 与上一等长区间对比，上一区间没有已判定工单时为空；变化为百分点
+	PreviousResponseRate   *float64 `json:"previous_response_rate,omitempty"`
+	PreviousResolutionRate *float64 `json:"previous_resolution_rate,omitempty"`
+	ResponseRateChange     *float64 `json:"response_rate_change,omitempty"`
+	ResolutionRateChange   *float64 `json:"resolution_rate_change,omitempty"`
+}
+
+// SLAComplianceReport 按分类、优先级、团队和月份分组的SLA达成率报表
+type SLAComplianceReport struct {
+	StartDate         time.Time `json:"start_date"`
+	EndDate           time.Time `json:"end_date"`
+	PreviousStartDate time.Time `json:"previous_start_date"`
+	PreviousEndDate   time.Time `json:"previous_end_date"`
+	Timezone          string    `json:"timezone"`  // 营业日历时区，月份按该时区划分
+	Untracked         int       `json:"untracked"` // 没有适用SLA配置的工单数
+
+	Overall    *SLAComplianceRow   `json:"overall"`
+	ByCategory []*SLAComplianceRow `json:"by_category"`
+	ByPriority []*SLAComplianceRow `json:"by_priority"`
+	ByTeam     []*SLAComplianceRow `json:"by_team"`
+	ByMonth    []*SLAComplianceRow `json:"by_month"` // 月份本身即为趋势，不与上一区间对比
+}
@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+// SLAWarningTarget SLA预警针对的时限
+type SLAWarningTarget string
+
+const (
+	SLAWarningTargetResponse   SLAWarningTarget = "response"   // 首次响应时限
+	SLAWarningTargetResolution SLAWarningTarget = "resolution" // 解决时限
+)
+
+// SLAWarning 已发送的违约前预警。同一工单的同一时限在每个阈值只预警一次；
+// 时限变化（如调整优先级后命中其他SLA配置）时按新时限重新预警
+type SLAWarning struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+
+	TicketID      uint             `json:"ticket_id" gorm:"not null;uniqueIndex:idx_sla_warning_dedupe"`
+	Target        SLAWarningTarget `json:"target" gorm:"size:20;not null;uniqueIndex:idx_sla_warning_dedupe"`
+	Threshold     int              `json:"threshold" gorm:"not null;uniqueIndex:idx_sla_warning_dedupe"`
+	TargetMinutes int              `json:"target_minutes" gorm:"not null;uniqueIndex:idx_sla_warning_dedupe"`
+
+	SLAConfigID    *uint     `json:"sla_config_id,omitempty" gorm:"index"`
+	ContractID     *uint     `json:"contract_id,omitempty"`
+	ElapsedPercent float64   `json:"elapsed_percent"`
+	BreachAt       time.Time `json:"breach_at"`
+	RecipientCount int       `json:"recipient_count"`
+}
+
+// TableName 指定表名
+func (SLAWarning) TableName() string {
+	return "sla_warnings"
+}
+
+// SLAAtRiskTicket 已越过预警阈值、尚未违约的工单时限
+type SLAAtRiskTicket struct {
+	TicketID         uint             `json:"ticket_id"`
+	TicketNumber     string           `json:"ticket_number"`
+	Title            string           `json:"title"`
+	Status           string           `json:"status"`
+	Priority         string           `json:"priority"`
+	AssignedToID     *uint            `json:"assigned_to_id,omitempty"`
+	AssignedTeamID   *uint            `json:"assigned_team_id,omitempty"`
+	Target           SLAWarningTarget `json:"target"`
+	TargetMinutes    int              `json:"target_minutes"`
+	ElapsedPercent   float64          `json:"elapsed_percent"`
+	Threshold        int              `json:"threshold"`         // 已越过的最高预警阈值
+	RemainingMinutes float64          `json:"remaining_minutes"` // 距违约的剩余时长，按营业时间计时的SLA为营业分钟
+	BreachAt         time.Time        `json:"breach_at"`
+	SLAConfigID      *uint            `json:"sla_config_id,omitempty"`
+	ContractID       *uint            `json:"contract_id,omitempty"`
+}
+
+// SLAAtRiskFilter 即将违约工单的筛选条件
+type SLAAtRiskFilter struct {
+	AssignedToID *uint
+	TeamID       *uint
+	Target       SLAWarningTarget
+}
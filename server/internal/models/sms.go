@@ -0,0 +1,73 @@
+package models
+
+import "time"
+
+// SMSProviderName 短信服务提供方
+type SMSProviderName string
+
+const (
+	SMSProviderTwilio SMSProviderName = "twilio"
+	SMSProviderAliyun SMSProviderName = "aliyun"
+)
+
+// SMSMessageStatus 短信发送状态
+type SMSMessageStatus string
+
+const (
+	SMSMessageSent   SMSMessageStatus = "sent"   // 服务商已受理
+	SMSMessageFailed SMSMessageStatus = "failed" // 服务商拒绝或请求失败
+)
+
+// SMSMessage 短信发送记录，用于发送频率限制与费用、送达统计，不保存验证码
+type SMSMessage struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+
+	UserID            *uint            `json:"user_id" gorm:"index"`
+	Purpose           OTPType          `json:"purpose" gorm:"size:30;index"`
+	Provider          SMSProviderName  `json:"provider" gorm:"size:20"`
+	Recipient         string           `json:"recipient" gorm:"size:32;index"`
+	IPAddress         string           `json:"ip_address" gorm:"size:45;index"`
+	Status            SMSMessageStatus `json:"status" gorm:"size:20;not null"`
+	ProviderMessageID string           `json:"provider_message_id,omitempty" gorm:"size:100"`
+	Error             string           `json:"error,omitempty" gorm:"size:500"`
+	Segments          int              `json:"segments" gorm:"default:1"` // 计费条数
+	Cost              float64          `json:"cost"`                      // 按配置的单价估算
+}
+
+// TableName 指定表名
+func (SMSMessage) TableName() string {
+	return "sms_messages"
+}
+
+// SMSMetricsRow 按服务商或用途汇总的短信统计
+type SMSMetricsRow struct {
+	Key    string  `json:"key"`
+	Sent   int64   `json:"sent"`
+	Failed int64   `json:"failed"`
+	Cost   float64 `json:"cost"`
+}
+
+// SMSDailyMetrics 每日短信统计
+type SMSDailyMetrics struct {
+	Date   string  `json:"date"`
+	Sent   int64   `json:"sent"`
+	Failed int64   `json:"failed"`
+	Cost   float64 `json:"cost"`
+}
+
+// SMSMetrics 区间内的短信费用与送达统计
+type SMSMetrics struct {
+	StartDate        time.Time          `json:"start_date"`
+	EndDate          time.Time          `json:"end_date"`
+	Sent             int64              `json:"sent"`
+	Failed           int64              `json:"failed"`
+	DeliveryRate     float64            `json:"delivery_rate"` // 服务商受理的比例
+	CodesVerified    int64              `json:"codes_verified"`
+	VerificationRate float64            `json:"verification_rate"` // 已发送验证码中被成功使用的比例
+	Cost             float64            `json:"cost"`
+	Currency         string             `json:"currency"`
+	ByProvider       []*SMSMetricsRow   `json:"by_provider"`
+	ByPurpose        []*SMSMetricsRow   `json:"by_purpose"`
+	Daily            []*SMSDailyMetrics `json:"daily"`
+}
@@ -0,0 +1,15 @@
+package models
+
+// SortDirection 排序方向
+type SortDirection string
+
+const (
+	SortAsc  SortDirection = "asc"
+	SortDesc SortDirection = "desc"
+)
+
+// SortField 列表排序条件中的一项，Field 为接口对外的字段名
+type SortField struct {
+	Field     string        `json:"field"`
+	Direction SortDirection `json:"direction"`
+}
@@ -0,0 +1,88 @@
+package models
+
+import "time"
+
+// SyncEntity 增量同步的实体类型
+type SyncEntity string
+
+const (
+	SyncEntityTicket       SyncEntity = "ticket"
+	SyncEntityComment      SyncEntity = "comment"
+	SyncEntityNotification SyncEntity = "notification"
+)
+
+// SyncTombstone 物理删除记录，供移动端增量同步时删除本地缓存
+type SyncTombstone struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+
+	EntityType SyncEntity `json:"entity_type" gorm:"size:20;not null"`
+	EntityID   uint       `json:"entity_id" gorm:"not null"`
+	UserID     *uint      `json:"user_id,omitempty" gorm:"index"` // 仅对该用户可见（如通知），为空时对所有人可见
+}
+
+// TableName 指定表名
+func (SyncTombstone) TableName() string {
+	return "sync_tombstones"
+}
+
+// SyncTicket 同步用的精简工单
+type SyncTicket struct {
+	ID             uint           `json:"id"`
+	TicketNumber   string         `json:"number"`
+	Title          string         `json:"title"`
+	Status         TicketStatus   `json:"status"`
+	Priority       TicketPriority `json:"priority"`
+	AssignedToID   *uint          `json:"assigned_to_id,omitempty"`
+	AssignedTeamID *uint          `json:"assigned_team_id,omitempty"`
+	CreatedByID    uint           `json:"created_by_id"`
+	CustomerName   string         `json:"customer_name,omitempty"`
+	DueDate        *time.Time     `json:"due_date,omitempty"`
+	SLABreached    bool           `json:"sla_breached,omitempty"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+}
+
+// SyncComment 同步用的精简评论
+type SyncComment struct {
+	ID          uint              `json:"id"`
+	TicketID    uint              `json:"ticket_id"`
+	UserID      uint              `json:"user_id"`
+	Content     string            `json:"content"`
+	ContentType string            `json:"content_type,omitempty"`
+	Visibility  CommentVisibility `json:"visibility"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+// SyncNotification 同步用的精简站内通知
+type SyncNotification struct {
+	ID              uint                 `json:"id"`
+	Type            NotificationType     `json:"type"`
+	Title           string               `json:"title"`
+	Content         string               `json:"content"`
+	Priority        NotificationPriority `json:"priority"`
+	IsRead          bool                 `json:"is_read"`
+	RelatedTicketID *uint                `json:"related_ticket_id,omitempty"`
+	ActionURL       string               `json:"action_url,omitempty"`
+	CreatedAt       time.Time            `json:"created_at"`
+	UpdatedAt       time.Time            `json:"updated_at"`
+}
+
+// SyncDeletion 客户端需要删除的本地记录
+type SyncDeletion struct {
+	Type   SyncEntity `json:"type"`
+	ID     uint       `json:"id"`
+	Reason string     `json:"reason"` // deleted, hidden（可见范围变更）, out_of_scope（改派出同步范围）
+}
+
+// SyncResponse 增量同步结果
+type SyncResponse struct {
+	Tickets       []SyncTicket       `json:"tickets"`
+	Comments      []SyncComment      `json:"comments"`
+	Notifications []SyncNotification `json:"notifications"`
+	Deleted       []SyncDeletion     `json:"deleted"`
+	NextCursor    string             `json:"next_cursor"`
+	HasMore       bool               `json:"has_more"` // 有实体达到条数上限，应立即用 next_cursor 继续拉取
+	ServerTime    time.Time          `json:"server_time"`
+}
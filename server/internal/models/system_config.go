@@ -2,7 +2,11 @@ package models
 
 import (
 	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
 	"time"
+	"unicode"
 )
 
 // SystemConfig 系统配置模型
@@ -114,8 +118,17 @@ type CleanupConfig struct {
 	CleanupEnabled            bool `json:"cleanup_enabled"`              // 是否启用自动清理
 	CleanupSchedule           string `json:"cleanup_schedule"`            // 清理计划（cron格式）
 	MaxRecordsPerCleanup      int  `json:"max_records_per_cleanup"`      // 每次清理的最大记录数
+
+	NotificationRetentionDays int    `json:"notification_retention_days"` // 已读通知保留天数
+	NotificationCleanupMode   string `json:"notification_cleanup_mode"`   // archive: 移入归档表, delete: 直接删除
 }
 
+// 通知清理模式
+const (
+	NotificationCleanupModeArchive = "archive"
+	NotificationCleanupModeDelete  = "delete"
+)
+
 // GetDefaultCleanupConfig 获取默认清理配置
 func GetDefaultCleanupConfig() *CleanupConfig {
 	return &CleanupConfig{
@@ -123,7 +136,809 @@ func GetDefaultCleanupConfig() *CleanupConfig {
 		CleanupEnabled:            true,
 		CleanupSchedule:           "0 2 * * *", // 每天凌晨2点执行
 		MaxRecordsPerCleanup:      1000,
+		NotificationRetentionDays: 90,
+		NotificationCleanupMode:   NotificationCleanupModeArchive,
+	}
+}
+
+// AutoCloseThresholds 自动关闭阈值
+type AutoCloseThresholds struct {
+	ReminderAfterDays int `json:"reminder_after_days"` // 解决后多少天无客户回复发送提醒
+	CloseAfterDays    int `json:"close_after_days"`    // 提醒后多少天仍无回复自动关闭
+}
+
+// AutoClosePolicy 已解决工单自动关闭策略
+type AutoClosePolicy struct {
+	Enabled                bool                           `json:"enabled"`                  // 是否启用自动关闭
+	AutoCloseThresholds                                   // 默认阈值
+	CategoryOverrides      map[string]AutoCloseThresholds `json:"category_overrides"`       // 按分类ID覆盖阈值
+	ExcludedTags           []string                       `json:"excluded_tags"`            // 带有这些标签的工单不自动关闭（如 vip、awaiting_approval）
+	ExcludedCustomerEmails []string                       `json:"excluded_customer_emails"` // VIP客户邮箱，不自动关闭
+	BatchSize              int                            `json:"batch_size"`               // 每批处理的工单数
+}
+
+// GetDefaultAutoClosePolicy 获取默认自动关闭策略
+func GetDefaultAutoClosePolicy() *AutoClosePolicy {
+	return &AutoClosePolicy{
+		Enabled: false,
+		AutoCloseThresholds: AutoCloseThresholds{
+			ReminderAfterDays: 3,
+			CloseAfterDays:    4,
+		},
+		CategoryOverrides:      map[string]AutoCloseThresholds{},
+		ExcludedTags:           []string{"vip", "awaiting_approval"},
+		ExcludedCustomerEmails: []string{},
+		BatchSize:              100,
+	}
+}
+
+// ThresholdsFor 获取指定分类适用的阈值
+func (p *AutoClosePolicy) ThresholdsFor(categoryID *uint) AutoCloseThresholds {
+	if categoryID != nil && p.CategoryOverrides != nil {
+		if override, ok := p.CategoryOverrides[fmt.Sprintf("%d", *categoryID)]; ok {
+			return override
+		}
+	}
+	return p.AutoCloseThresholds
+}
+
+// StaleTicketPolicy 停滞工单提醒策略
+type StaleTicketPolicy struct {
+	Enabled           bool `json:"enabled"`             // 是否启用停滞提醒
+	StaleAfterDays    int  `json:"stale_after_days"`    // 多少天无更新视为停滞
+	EscalateAfterDays int  `json:"escalate_after_days"` // 提醒后宽限多少天仍无更新则升级至团队负责人
+	BatchSize         int  `json:"batch_size"`          // 每批处理的工单数
+}
+
+// GetDefaultStaleTicketPolicy 获取默认停滞工单提醒策略
+func GetDefaultStaleTicketPolicy() *StaleTicketPolicy {
+	return &StaleTicketPolicy{
+		Enabled:           false,
+		StaleAfterDays:    7,
+		EscalateAfterDays: 2,
+		BatchSize:         100,
+	}
+}
+
+// Validate 校验停滞工单提醒策略
+func (p *StaleTicketPolicy) Validate() error {
+	if p.StaleAfterDays < 1 || p.StaleAfterDays > 365 {
+		return fmt.Errorf("stale_after_days must be between 1 and 365")
+	}
+	if p.EscalateAfterDays < 1 || p.EscalateAfterDays > 90 {
+		return fmt.Errorf("escalate_after_days must be between 1 and 90")
+	}
+	if p.BatchSize <= 0 || p.BatchSize > 1000 {
+		p.BatchSize = 100
+	}
+	return nil
+}
+
+// SurveyPolicy 满意度调查策略
+type SurveyPolicy struct {
+	LowScoreThreshold int  `json:"low_score_threshold"` // 评分小于等于该值时触发 survey.low_score
+	AllowResubmit     bool `json:"allow_resubmit"`      // 是否允许客户修改已提交的评分
+}
+
+// GetDefaultSurveyPolicy 获取默认满意度调查策略
+func GetDefaultSurveyPolicy() *SurveyPolicy {
+	return &SurveyPolicy{
+		LowScoreThreshold: 2,
+		AllowResubmit:     false,
+	}
+}
+
+// Validate 校验满意度调查策略
+func (p *SurveyPolicy) Validate() error {
+	if p.LowScoreThreshold < 1 || p.LowScoreThreshold > 4 {
+		return fmt.Errorf("low_score_threshold must be between 1 and 4")
+	}
+	return nil
+}
+
+// Holiday 节假日（整天不营业）
+type Holiday struct {
+	Date string `json:"date"` // YYYY-MM-DD
+	Name string `json:"name"`
+}
+
+// BusinessCalendar 组织统一的营业日历：工作时间、时区及节假日
+type BusinessCalendar struct {
+	Timezone     string       `json:"timezone"` // IANA 时区，如 Asia/Shanghai
+	WorkingHours WorkingHours `json:"working_hours"`
+	Holidays     []Holiday    `json:"holidays"`
+	DueTime      string       `json:"due_time"` // 截止时间建议使用的时刻（HH:MM），为空时使用当天下班时间
+}
+
+// GetDefaultBusinessCalendar 获取默认营业日历（周一至周五 09:00-18:00）
+func GetDefaultBusinessCalendar() *BusinessCalendar {
+	workday := TimeRange{Start: "09:00", End: "18:00"}
+	return &BusinessCalendar{
+		Timezone: "Asia/Shanghai",
+		WorkingHours: WorkingHours{
+			Monday:    workday,
+			Tuesday:   workday,
+			Wednesday: workday,
+			Thursday:  workday,
+			Friday:    workday,
+		},
+		Holidays: []Holiday{},
+		DueTime:  "17:00",
+	}
+}
+
+// Validate 校验营业日历
+func (c *BusinessCalendar) Validate() error {
+	if _, err := time.LoadLocation(c.Timezone); err != nil {
+		return fmt.Errorf("invalid timezone: %s", c.Timezone)
+	}
+	for _, day := range []time.Weekday{time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday} {
+		r := c.WorkingHours.ForWeekday(day)
+		if r.Start == "" && r.End == "" {
+			continue
+		}
+		start, err1 := ParseClock(r.Start)
+		end, err2 := ParseClock(r.End)
+		if err1 != nil || err2 != nil || start >= end {
+			return fmt.Errorf("invalid working hours for %s: %s-%s", strings.ToLower(day.String()), r.Start, r.End)
+		}
+	}
+	for _, h := range c.Holidays {
+		if _, err := time.Parse("2006-01-02", h.Date); err != nil {
+			return fmt.Errorf("invalid holiday date: %s", h.Date)
+		}
+	}
+	if c.DueTime != "" {
+		if _, err := ParseClock(c.DueTime); err != nil {
+			return fmt.Errorf("invalid due_time: %s", c.DueTime)
+		}
+	}
+	return nil
+}
+
+// ParseClock 解析 HH:MM，返回距当天零点的时长
+func ParseClock(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// ChangeProposalPolicy 工单变更提案策略：指定角色的编辑先提交审核，批准后才生效
+type ChangeProposalPolicy struct {
+	Enabled       bool     `json:"enabled"`
+	ProposerRoles []string `json:"proposer_roles"` // 编辑需审核的角色
+	ReviewerRoles []string `json:"reviewer_roles"` // 可审核提案的角色
+}
+
+// GetDefaultChangeProposalPolicy 获取默认变更提案策略（默认关闭）
+func GetDefaultChangeProposalPolicy() *ChangeProposalPolicy {
+	return &ChangeProposalPolicy{
+		Enabled:       false,
+		ProposerRoles: []string{string(RoleAgent)},
+		ReviewerRoles: []string{string(RoleSupervisor), string(RoleAdmin)},
+	}
+}
+
+// Validate 校验变更提案策略
+func (p *ChangeProposalPolicy) Validate() error {
+	valid := map[string]bool{
+		string(RoleAdmin): true, string(RoleAgent): true, string(RoleCustomer): true, string(RoleSupervisor): true,
+	}
+	for _, role := range append(append([]string{}, p.ProposerRoles...), p.ReviewerRoles...) {
+		if !valid[role] {
+			return fmt.Errorf("unknown role: %s", role)
+		}
+	}
+	if p.Enabled && len(p.ReviewerRoles) == 0 {
+		return fmt.Errorf("reviewer_roles is required when change proposals are enabled")
+	}
+	for _, proposer := range p.ProposerRoles {
+		for _, reviewer := range p.ReviewerRoles {
+			if proposer == reviewer {
+				return fmt.Errorf("role %s cannot be both proposer and reviewer", proposer)
+			}
+		}
+	}
+	return nil
+}
+
+// RequiresReview 判断该角色的编辑是否需要提交审核
+func (p *ChangeProposalPolicy) RequiresReview(role string) bool {
+	if !p.Enabled {
+		return false
+	}
+	for _, r := range p.ProposerRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// CanReview 判断该角色是否可以审核提案
+func (p *ChangeProposalPolicy) CanReview(role string) bool {
+	for _, r := range p.ReviewerRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// TicketRestrictableFields 可按角色限制编辑的工单字段，与更新请求的 JSON 字段名一致
+var TicketRestrictableFields = []string{
+	"title", "description", "type", "priority", "status", "source", "impact", "urgency",
+	"assigned_to_id", "assigned_team_id", "tags", "due_date", "sla_due_date",
+	"customer_email", "customer_phone", "customer_name", "custom_fields", "is_confidential", "resolution_code",
+}
+
+// TicketFieldPermissionPolicy 工单字段编辑权限策略：Fields 中列出的字段只有对应角色可以修改，
+// 未列出的字段不受限制；管理员始终可以编辑全部字段
+type TicketFieldPermissionPolicy struct {
+	Enabled bool                `json:"enabled"`
+	Fields  map[string][]string `json:"fields"` // 字段名 → 可编辑的角色
+}
+
+// GetDefaultTicketFieldPermissionPolicy 获取默认字段编辑权限策略（默认关闭，开启后优先级、截止时间、SLA 与客户信息仅主管和管理员可改）
+func GetDefaultTicketFieldPermissionPolicy() *TicketFieldPermissionPolicy {
+	managers := []string{string(RoleSupervisor), string(RoleAdmin)}
+	return &TicketFieldPermissionPolicy{
+		Enabled: false,
+		Fields: map[string][]string{
+			"priority":       managers,
+			"due_date":       managers,
+			"sla_due_date":   managers,
+			"customer_email": managers,
+			"customer_phone": managers,
+			"customer_name":  managers,
+		},
+	}
+}
+
+// Validate 校验字段编辑权限策略
+func (p *TicketFieldPermissionPolicy) Validate() error {
+	validRoles := map[string]bool{
+		string(RoleAdmin): true, string(RoleAgent): true, string(RoleCustomer): true, string(RoleSupervisor): true,
+	}
+	validFields := make(map[string]bool, len(TicketRestrictableFields))
+	for _, field := range TicketRestrictableFields {
+		validFields[field] = true
+	}
+	for field, roles := range p.Fields {
+		if !validFields[field] {
+			return fmt.Errorf("unknown ticket field: %s", field)
+		}
+		for _, role := range roles {
+			if !validRoles[role] {
+				return fmt.Errorf("unknown role: %s", role)
+			}
+		}
+	}
+	return nil
+}
+
+// CanEdit 判断该角色是否可以修改字段
+func (p *TicketFieldPermissionPolicy) CanEdit(role, field string) bool {
+	if !p.Enabled || role == string(RoleAdmin) {
+		return true
+	}
+	roles, restricted := p.Fields[field]
+	if !restricted {
+		return true
+	}
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowedRoles 可修改字段的角色，未限制时返回 nil
+func (p *TicketFieldPermissionPolicy) AllowedRoles(field string) []string {
+	if !p.Enabled {
+		return nil
+	}
+	roles, restricted := p.Fields[field]
+	if !restricted {
+		return nil
+	}
+	allowed := []string{string(RoleAdmin)}
+	for _, role := range roles {
+		if role != string(RoleAdmin) {
+			allowed = append(allowed, role)
+		}
+	}
+	return allowed
+}
+
+// TicketFieldAccess 单个字段的编辑权限
+type TicketFieldAccess struct {
+	Field        string   `json:"field"`
+	Editable     bool     `json:"editable"`
+	AllowedRoles []string `json:"allowed_roles,omitempty"` // 受限字段可编辑的角色
+}
+
+// TicketEditableFields 当前用户对工单各字段的编辑权限
+type TicketEditableFields struct {
+	TicketID   uint                `json:"ticket_id"`
+	Role       string              `json:"role"`
+	Restricted bool                `json:"restricted"` // 字段编辑权限策略是否开启
+	Fields     []TicketFieldAccess `json:"fields"`
+}
+
+// CategoryTransferPolicy 工单跨分类转移策略
+type CategoryTransferPolicy struct {
+	KeepOriginalClock bool `json:"keep_original_clock"` // 按工单创建时间重算SLA截止时间，否则从转移时刻重新计时
+	ReassignAssigned  bool `json:"reassign_assigned"`   // 已分配的工单也按新分类的自动分配人改派，否则只处理未分配工单
+}
+
+// GetDefaultCategoryTransferPolicy 获取默认分类转移策略
+func GetDefaultCategoryTransferPolicy() *CategoryTransferPolicy {
+	return &CategoryTransferPolicy{
+		KeepOriginalClock: false,
+		ReassignAssigned:  false,
+	}
+}
+
+// Validate 校验分类转移策略
+func (p *CategoryTransferPolicy) Validate() error {
+	return nil
+}
+
+// PriorityMatrix 影响×紧急程度优先级矩阵
+type PriorityMatrix struct {
+	Enabled bool                                              `json:"enabled"` // 启用后由矩阵决定工单优先级
+	Matrix  map[TicketImpact]map[TicketUrgency]TicketPriority `json:"matrix"`  // impact -> urgency -> priority
+}
+
+// GetDefaultPriorityMatrix 获取默认ITIL优先级矩阵
+func GetDefaultPriorityMatrix() *PriorityMatrix {
+	return &PriorityMatrix{
+		Enabled: false,
+		Matrix: map[TicketImpact]map[TicketUrgency]TicketPriority{
+			TicketImpactHigh: {
+				TicketUrgencyHigh:   TicketPriorityCritical,
+				TicketUrgencyMedium: TicketPriorityUrgent,
+				TicketUrgencyLow:    TicketPriorityHigh,
+			},
+			TicketImpactMedium: {
+				TicketUrgencyHigh:   TicketPriorityUrgent,
+				TicketUrgencyMedium: TicketPriorityHigh,
+				TicketUrgencyLow:    TicketPriorityNormal,
+			},
+			TicketImpactLow: {
+				TicketUrgencyHigh:   TicketPriorityHigh,
+				TicketUrgencyMedium: TicketPriorityNormal,
+				TicketUrgencyLow:    TicketPriorityLow,
+			},
+		},
+	}
+}
+
+// Resolve 根据影响和紧急程度计算优先级
+func (m *PriorityMatrix) Resolve(impact TicketImpact, urgency TicketUrgency) (TicketPriority, bool) {
+	row, ok := m.Matrix[impact]
+	if !ok {
+		return "", false
+	}
+	priority, ok := row[urgency]
+	return priority, ok
+}
+
+// Validate 校验矩阵是否覆盖所有影响/紧急程度组合
+func (m *PriorityMatrix) Validate() error {
+	levels := []string{"low", "medium", "high"}
+	for _, impact := range levels {
+		for _, urgency := range levels {
+			priority, ok := m.Resolve(TicketImpact(impact), TicketUrgency(urgency))
+			if !ok {
+				return fmt.Errorf("matrix missing entry for impact=%s urgency=%s", impact, urgency)
+			}
+			switch priority {
+			case TicketPriorityLow, TicketPriorityNormal, TicketPriorityHigh, TicketPriorityUrgent, TicketPriorityCritical:
+			default:
+				return fmt.Errorf("invalid priority %q for impact=%s urgency=%s", priority, impact, urgency)
+			}
+		}
+	}
+	return nil
+}
+
+// HTTPSecurityPolicy HTTP安全策略（CORS及安全响应头）
+type HTTPSecurityPolicy struct {
+	// CORS
+	AllowedOrigins   []string `json:"allowed_origins"`   // 允许的源，支持 *.example.com 通配
+	AllowedMethods   []string `json:"allowed_methods"`   // 允许的方法
+	AllowedHeaders   []string `json:"allowed_headers"`   // 允许的请求头
+	ExposeHeaders    []string `json:"expose_headers"`    // 暴露给客户端的响应头
+	AllowCredentials bool     `json:"allow_credentials"` // 是否允许携带凭据
+	MaxAge           int      `json:"max_age"`           // 预检缓存时间（秒）
+
+	// 安全响应头
+	ContentSecurityPolicy string `json:"content_security_policy"`
+	HSTSMaxAge            int    `json:"hsts_max_age"` // 0 表示不发送HSTS
+	HSTSIncludeSubdomains bool   `json:"hsts_include_subdomains"`
+	HSTSPreload           bool   `json:"hsts_preload"`
+	XFrameOptions         string `json:"x_frame_options"`
+	ReferrerPolicy        string `json:"referrer_policy"`
+	PermissionsPolicy     string `json:"permissions_policy"`
+}
+
+// GetDefaultHTTPSecurityPolicy 获取默认HTTP安全策略
+func GetDefaultHTTPSecurityPolicy() *HTTPSecurityPolicy {
+	return &HTTPSecurityPolicy{
+		AllowedOrigins: []string{"http://localhost:3000", "http://localhost:5173"},
+		AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"},
+		AllowedHeaders: []string{
+			"Origin", "Content-Length", "Content-Type", "Authorization", "Accept",
+			"Accept-Encoding", "Accept-Language", "X-Requested-With", "X-CSRF-Token", "X-Request-ID",
+		},
+		ExposeHeaders:         []string{"Content-Length", "X-Request-ID", "X-Response-Time"},
+		AllowCredentials:      true,
+		MaxAge:                86400,
+		ContentSecurityPolicy: "default-src 'self'; frame-ancestors 'none'",
+		HSTSMaxAge:            31536000,
+		HSTSIncludeSubdomains: true,
+		XFrameOptions:         "DENY",
+		ReferrerPolicy:        "strict-origin-when-cross-origin",
+		PermissionsPolicy:     "geolocation=(), microphone=(), camera=()",
+	}
+}
+
+// Validate 校验HTTP安全策略
+func (p *HTTPSecurityPolicy) Validate() error {
+	if len(p.AllowedOrigins) == 0 {
+		return fmt.Errorf("allowed_origins must not be empty")
+	}
+	for _, origin := range p.AllowedOrigins {
+		if origin == "*" && p.AllowCredentials {
+			return fmt.Errorf("wildcard origin '*' cannot be combined with allow_credentials")
+		}
+	}
+	if p.MaxAge < 0 || p.MaxAge > 86400 {
+		return fmt.Errorf("max_age must be between 0 and 86400")
+	}
+	if p.HSTSMaxAge < 0 {
+		return fmt.Errorf("hsts_max_age must not be negative")
+	}
+	switch p.XFrameOptions {
+	case "", "DENY", "SAMEORIGIN":
+	default:
+		return fmt.Errorf("x_frame_options must be DENY or SAMEORIGIN")
+	}
+	return nil
+}
+
+// MaintenanceMode 只读维护模式配置
+type MaintenanceMode struct {
+	Enabled        bool       `json:"enabled"`            // 是否开启（或预约）维护模式
+	Message        string     `json:"message"`            // 返回给客户端的维护提示
+	StartAt        *time.Time `json:"start_at,omitempty"` // 计划开始时间，为空表示立即生效
+	EndAt          *time.Time `json:"end_at,omitempty"`   // 计划结束时间，到期自动退出，为空表示需手动关闭
+	ExemptPrefixes []string   `json:"exempt_prefixes"`    // 维护期间仍允许写操作的路径前缀
+}
+
+// GetDefaultMaintenanceMode 获取默认维护模式配置
+func GetDefaultMaintenanceMode() *MaintenanceMode {
+	return &MaintenanceMode{
+		Enabled:        false,
+		Message:        "系统维护中，暂时只支持只读访问，请稍后再试",
+		ExemptPrefixes: []string{"/api/auth/", "/api/admin/"},
+	}
+}
+
+// IsActive 判断维护模式在指定时间是否生效
+func (m *MaintenanceMode) IsActive(now time.Time) bool {
+	if !m.Enabled {
+		return false
+	}
+	if m.StartAt != nil && now.Before(*m.StartAt) {
+		return false
+	}
+	if m.EndAt != nil && !now.Before(*m.EndAt) {
+		return false
+	}
+	return true
+}
+
+// IsExpired 判断维护窗口是否已结束
+func (m *MaintenanceMode) IsExpired(now time.Time) bool {
+	return m.Enabled && m.EndAt != nil && !now.Before(*m.EndAt)
+}
+
+// Validate 校验维护模式配置
+func (m *MaintenanceMode) Validate() error {
+	if m.StartAt != nil && m.EndAt != nil && !m.EndAt.After(*m.StartAt) {
+		return fmt.Errorf("end_at must be after start_at")
+	}
+	if len(m.Message) > 500 {
+		return fmt.Errorf("message must not exceed 500 characters")
+	}
+	return nil
+}
+
+// 并发限流分组
+const (
+	ConcurrencyGroupExport    = "export"
+	ConcurrencyGroupSearch    = "search"
+	ConcurrencyGroupAnalytics = "analytics"
+)
+
+// ConcurrencyLimit 单个路由分组的并发限制
+type ConcurrencyLimit struct {
+	MaxConcurrent  int `json:"max_concurrent"`   // 同时处理的请求数
+	MaxQueue       int `json:"max_queue"`        // 排队等待的请求数上限，超出返回429
+	QueueTimeoutMs int `json:"queue_timeout_ms"` // 排队超时时间，超时返回503
+}
+
+// ConcurrencyLimitPolicy 高开销接口并发限流策略
+type ConcurrencyLimitPolicy struct {
+	Enabled bool                         `json:"enabled"`
+	Groups  map[string]*ConcurrencyLimit `json:"groups"` // export / search / analytics
+}
+
+// GetDefaultConcurrencyLimitPolicy 获取默认并发限流策略
+func GetDefaultConcurrencyLimitPolicy() *ConcurrencyLimitPolicy {
+	return &ConcurrencyLimitPolicy{
+		Enabled: true,
+		Groups: map[string]*ConcurrencyLimit{
+			ConcurrencyGroupExport:    {MaxConcurrent: 2, MaxQueue: 4, QueueTimeoutMs: 10000},
+			ConcurrencyGroupSearch:    {MaxConcurrent: 8, MaxQueue: 16, QueueTimeoutMs: 3000},
+			ConcurrencyGroupAnalytics: {MaxConcurrent: 4, MaxQueue: 8, QueueTimeoutMs: 5000},
+		},
+	}
+}
+
+// Validate 校验并发限流策略，缺失的分组使用默认值
+func (p *ConcurrencyLimitPolicy) Validate() error {
+	defaults := GetDefaultConcurrencyLimitPolicy().Groups
+	if p.Groups == nil {
+		p.Groups = map[string]*ConcurrencyLimit{}
+	}
+	for name, limit := range p.Groups {
+		if _, ok := defaults[name]; !ok {
+			return fmt.Errorf("unknown concurrency group: %s", name)
+		}
+		if limit == nil {
+			return fmt.Errorf("%s: limit is required", name)
+		}
+		if limit.MaxConcurrent < 1 || limit.MaxConcurrent > 1000 {
+			return fmt.Errorf("%s: max_concurrent must be between 1 and 1000", name)
+		}
+		if limit.MaxQueue < 0 || limit.MaxQueue > 10000 {
+			return fmt.Errorf("%s: max_queue must be between 0 and 10000", name)
+		}
+		if limit.QueueTimeoutMs < 0 || limit.QueueTimeoutMs > 120000 {
+			return fmt.Errorf("%s: queue_timeout_ms must be between 0 and 120000", name)
+		}
+	}
+	for name, limit := range defaults {
+		if _, ok := p.Groups[name]; !ok {
+			p.Groups[name] = limit
+		}
+	}
+	return nil
+}
+
+var paginationEndpointPattern = regexp.MustCompile(`^(GET|POST) /api/\S+$`)
+
+// PaginationPolicy 列表接口分页限制：限制每页条数和跳过的记录数，并记录慢列表查询
+type PaginationPolicy struct {
+	Enabled            bool           `json:"enabled"`
+	DefaultMaxPageSize int            `json:"default_max_page_size"` // 未单独配置的接口每页最大条数（page_size / limit）
+	MaxPageSizes       map[string]int `json:"max_page_sizes"`        // 按接口单独设置的每页最大条数，键为 "GET /api/tickets" 形式的路由
+	MaxOffset          int            `json:"max_offset"`            // 跳过的记录数（offset 或 (page-1)×每页条数）上限，0 表示不限制
+	SlowQueryMs        int            `json:"slow_query_ms"`         // 列表请求耗时超过该值时记录慢查询日志，0 表示不记录
+}
+
+// GetDefaultPaginationPolicy 获取默认分页限制
+func GetDefaultPaginationPolicy() *PaginationPolicy {
+	return &PaginationPolicy{
+		Enabled:            true,
+		DefaultMaxPageSize: 100,
+		MaxPageSizes: map[string]int{
+			"GET /api/admin/audit-logs": 200,
+		},
+		MaxOffset:   10000,
+		SlowQueryMs: 1000,
+	}
+}
+
+// Validate 校验分页限制
+func (p *PaginationPolicy) Validate() error {
+	if p.DefaultMaxPageSize < 1 || p.DefaultMaxPageSize > 10000 {
+		return fmt.Errorf("default_max_page_size must be between 1 and 10000")
+	}
+	if p.MaxPageSizes == nil {
+		p.MaxPageSizes = map[string]int{}
+	}
+	for endpoint, size := range p.MaxPageSizes {
+		if !paginationEndpointPattern.MatchString(endpoint) {
+			return fmt.Errorf("max_page_sizes: %q must look like \"GET /api/tickets\"", endpoint)
+		}
+		if size < 1 || size > 10000 {
+			return fmt.Errorf("max_page_sizes: %s must be between 1 and 10000", endpoint)
+		}
+	}
+	if p.MaxOffset < 0 || p.MaxOffset > 10000000 {
+		return fmt.Errorf("max_offset must be between 0 and 10000000")
+	}
+	if p.SlowQueryMs < 0 || p.SlowQueryMs > 600000 {
+		return fmt.Errorf("slow_query_ms must be between 0 and 600000")
+	}
+	return nil
+}
+
+// MaxPageSizeFor 接口适用的每页最大条数
+func (p *PaginationPolicy) MaxPageSizeFor(endpoint string) int {
+	if size, ok := p.MaxPageSizes[endpoint]; ok {
+		return size
+	}
+	return p.DefaultMaxPageSize
+}
+
+// 全文搜索内容语言
+const (
+	SearchLanguageChinese = "zh"
+	SearchLanguageEnglish = "en"
+	SearchLanguageAuto    = "auto" // 中英混合，按查询内容识别语言
+	SearchLanguageMixed   = "mixed"
+)
+
+var textSearchConfigNamePattern = regexp.MustCompile(`^[a-z_][a-z0-9_]{0,62}$`)
+
+// SearchLanguageConfig 全文搜索语言配置
+type SearchLanguageConfig struct {
+	Language       string   `json:"language"`        // 内容主要语言：zh、en、auto
+	ChineseConfigs []string `json:"chinese_configs"` // 中文分词配置候选（如 zhparser、pg_jieba 提供的配置），按顺序取第一个可用的
+	EnglishConfig  string   `json:"english_config"`  // 英文文本搜索配置
+	FallbackConfig string   `json:"fallback_config"` // 候选配置均不可用时的回退配置
+}
+
+// GetDefaultSearchLanguageConfig 获取默认全文搜索语言配置
+func GetDefaultSearchLanguageConfig() *SearchLanguageConfig {
+	return &SearchLanguageConfig{
+		Language:       SearchLanguageAuto,
+		ChineseConfigs: []string{"chinese", "jiebacfg"},
+		EnglishConfig:  "english",
+		FallbackConfig: "simple",
+	}
+}
+
+// Validate 校验全文搜索语言配置，配置名会拼接进索引表达式，因此只允许小写标识符
+func (c *SearchLanguageConfig) Validate() error {
+	switch c.Language {
+	case SearchLanguageChinese, SearchLanguageEnglish, SearchLanguageAuto:
+	default:
+		return fmt.Errorf("language must be one of zh, en, auto")
+	}
+	names := append([]string{c.EnglishConfig, c.FallbackConfig}, c.ChineseConfigs...)
+	for _, name := range names {
+		if !textSearchConfigNamePattern.MatchString(name) {
+			return fmt.Errorf("invalid text search config name: %q", name)
+		}
+	}
+	return nil
+}
+
+// DetectTextLanguage 根据汉字与拉丁字母的占比识别文本语言，返回 zh、en 或 mixed
+func DetectTextLanguage(text string) string {
+	var han, latin int
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			han++
+		case r < unicode.MaxASCII && unicode.IsLetter(r):
+			latin++
+		}
+	}
+
+	switch {
+	case han == 0 && latin == 0:
+		return SearchLanguageMixed
+	case latin == 0:
+		return SearchLanguageChinese
+	case han == 0:
+		return SearchLanguageEnglish
+	default:
+		return SearchLanguageMixed
+	}
+}
+
+// 审计事件转发类型
+const (
+	AuditEventAdminOperation   = "admin_operation"
+	AuditEventAuth             = "auth"
+	AuditEventPermissionDenied = "permission_denied"
+)
+
+// AuditForwardingConfig 审计事件转发（SIEM）配置
+type AuditForwardingConfig struct {
+	Enabled              bool     `json:"enabled"`                // 是否开启转发
+	Transport            string   `json:"transport"`              // syslog 或 http
+	Address              string   `json:"address"`                // syslog 收集器地址 host:port
+	TLS                  bool     `json:"tls"`                    // syslog 是否使用 TLS
+	TLSSkipVerify        bool     `json:"tls_skip_verify"`        // 跳过证书校验（仅用于测试环境）
+	URL                  string   `json:"url"`                    // HTTP 收集器地址，如 Splunk HEC
+	Token                string   `json:"token,omitempty"`        // HTTP 收集器令牌
+	HTTPFormat           string   `json:"http_format"`            // csv 或 hec（Splunk HTTP Event Collector）
+	EventTypes           []string `json:"event_types"`            // 转发的事件类型，为空表示全部
+	BufferSize           int      `json:"buffer_size"`            // 内存缓冲事件数，满时丢弃最旧的事件
+	BatchSize            int      `json:"batch_size"`             // 每批发送的事件数
+	MaxRetries           int      `json:"max_retries"`            // 单批发送的最大重试次数
+	FlushIntervalSeconds int      `json:"flush_interval_seconds"` // 缓冲未满时的发送间隔
+}
+
+// GetDefaultAuditForwardingConfig 获取默认审计事件转发配置
+func GetDefaultAuditForwardingConfig() *AuditForwardingConfig {
+	return &AuditForwardingConfig{
+		Enabled:              false,
+		Transport:            "syslog",
+		HTTPFormat:           "csv",
+		EventTypes:           []string{AuditEventAdminOperation, AuditEventAuth, AuditEventPermissionDenied},
+		BufferSize:           10000,
+		BatchSize:            100,
+		MaxRetries:           5,
+		FlushIntervalSeconds: 5,
+	}
+}
+
+// Validate 校验审计事件转发配置
+func (c *AuditForwardingConfig) Validate() error {
+	switch c.Transport {
+	case "syslog":
+		if c.Enabled && c.Address == "" {
+			return fmt.Errorf("address is required for syslog transport")
+		}
+	case "http":
+		if c.Enabled && c.URL == "" {
+			return fmt.Errorf("url is required for http transport")
+		}
+		if c.HTTPFormat != "csv" && c.HTTPFormat != "hec" {
+			return fmt.Errorf("http_format must be csv or hec")
+		}
+	default:
+		return fmt.Errorf("transport must be syslog or http")
+	}
+	for _, eventType := range c.EventTypes {
+		switch eventType {
+		case AuditEventAdminOperation, AuditEventAuth, AuditEventPermissionDenied:
+		default:
+			return fmt.Errorf("unsupported event type: %s", eventType)
+		}
+	}
+	if c.BufferSize < 100 || c.BufferSize > 1000000 {
+		return fmt.Errorf("buffer_size must be between 100 and 1000000")
+	}
+	if c.BatchSize < 1 || c.BatchSize > 1000 {
+		return fmt.Errorf("batch_size must be between 1 and 1000")
+	}
+	if c.MaxRetries < 0 || c.MaxRetries > 20 {
+		return fmt.Errorf("max_retries must be between 0 and 20")
+	}
+	if c.FlushIntervalSeconds < 1 || c.FlushIntervalSeconds > 300 {
+		return fmt.Errorf("flush_interval_seconds must be between 1 and 300")
+	}
+	return nil
+}
+
+// ForwardsEvent 判断事件类型是否需要转发
+func (c *AuditForwardingConfig) ForwardsEvent(eventType string) bool {
+	if !c.Enabled {
+		return false
+	}
+	if len(c.EventTypes) == 0 {
+		return true
+	}
+	for _, t := range c.EventTypes {
+		if t == eventType {
+			return true
+		}
 	}
+	return false
 }
 
 // SystemConfigRequest 系统配置请求
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// teamMentionPattern 评论中的团队提及，如 @support-l2
+var teamMentionPattern = regexp.MustCompile(`(?:^|[^\w@.])@([a-z0-9][a-z0-9_-]{0,49})`)
+
+// teamSlugPattern 团队标识格式
+var teamSlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,49}$`)
+
+// Team 团队模型，可作为工单的团队队列
+type Team struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+
+	Name        string `json:"name" gorm:"size:100;not null;uniqueIndex" validate:"required,max=100"`
+	Slug        string `json:"slug" gorm:"size:50;not null;uniqueIndex" validate:"required,max=50"` // 用于@提及，如 support-l2
+	Description string `json:"description" gorm:"type:text"`
+	IsActive    bool   `json:"is_active" gorm:"default:true;index"`
+
+	LeadID *uint `json:"lead_id,omitempty" gorm:"index"` // 团队负责人，接收停滞工单升级提醒
+	Lead   *User `json:"lead,omitempty" gorm:"foreignKey:LeadID"`
+
+	Members []User `json:"members,omitempty" gorm:"many2many:team_members"`
+}
+
+// TableName 指定表名
+func (Team) TableName() string {
+	return "teams"
+}
+
+// IsValidTeamSlug 检查团队标识是否合法（小写字母、数字、下划线和连字符）
+func IsValidTeamSlug(slug string) bool {
+	return teamSlugPattern.MatchString(slug)
+}
+
+// ExtractTeamMentions 提取内容中提及的团队标识（去重，保持出现顺序）
+func ExtractTeamMentions(content string) []string {
+	matches := teamMentionPattern.FindAllStringSubmatch(strings.ToLower(content), -1)
+	seen := make(map[string]bool, len(matches))
+	slugs := make([]string, 0, len(matches))
+	for _, match := range matches {
+		slug := match[1]
+		if !seen[slug] {
+			seen[slug] = true
+			slugs = append(slugs, slug)
+		}
+	}
+	return slugs
+}
+
+// TeamCreateRequest 团队创建请求
+type TeamCreateRequest struct {
+	Name        string `json:"name" binding:"required,max=100"`
+	Slug        string `json:"slug" binding:"required,max=50"`
+	Description string `json:"description"`
+	MemberIDs   []uint `json:"member_ids"`
+	LeadID      *uint  `json:"lead_id"`
+}
+
+// TeamUpdateRequest 团队更新请求
+type TeamUpdateRequest struct {
+	Name        *string `json:"name" binding:"omitempty,max=100"`
+	Slug        *string `json:"slug" binding:"omitempty,max=50"`
+	Description *string `json:"description"`
+	IsActive    *bool   `json:"is_active"`
+	LeadID      *uint   `json:"lead_id"` // 传 0 清除负责人
+}
+
+// TeamResponse 团队响应
+type TeamResponse struct {
+	ID          uint            `json:"id"`
+	Name        string          `json:"name"`
+	Slug        string          `json:"slug"`
+	Description string          `json:"description"`
+	IsActive    bool            `json:"is_active"`
+	LeadID      *uint           `json:"lead_id,omitempty"`
+	Members     []*UserResponse `json:"members,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// ToResponse 转换为响应格式
+func (t *Team) ToResponse() *TeamResponse {
+	response := &TeamResponse{
+		ID:          t.ID,
+		Name:        t.Name,
+		Slug:        t.Slug,
+		Description: t.Description,
+		IsActive:    t.IsActive,
+		LeadID:      t.LeadID,
+		CreatedAt:   t.CreatedAt,
+		UpdatedAt:   t.UpdatedAt,
+	}
+	for i := range t.Members {
+		response.Members = append(response.Members, t.Members[i].ToResponse())
+	}
+	return response
+}
+
+// TeamQueueSummary 团队队列概览
+type TeamQueueSummary struct {
+	TeamID          uint   `json:"team_id"`
+	Name            string `json:"name"`
+	Slug            string `json:"slug"`
+	UnassignedCount int64  `json:"unassigned_count"` // 队列中尚未被认领的未完结工单数
+	OpenCount       int64  `json:"open_count"`       // 队列中全部未完结工单数
+}
@@ -29,6 +29,60 @@ const (
 	TicketPriorityCritical TicketPriority = "critical" // 严重
 )
 
+// IsValid 检查优先级取值是否合法
+func (p TicketPriority) IsValid() bool {
+	switch p {
+	case TicketPriorityLow, TicketPriorityNormal, TicketPriorityHigh, TicketPriorityUrgent, TicketPriorityCritical:
+		return true
+	default:
+		return false
+	}
+}
+
+// TicketImpact 业务影响范围
+type TicketImpact string
+
+const (
+	TicketImpactLow    TicketImpact = "low"    // 个人
+	TicketImpactMedium TicketImpact = "medium" // 部门/团队
+	TicketImpactHigh   TicketImpact = "high"   // 全公司/核心业务
+)
+
+// TicketUrgency 紧急程度
+type TicketUrgency string
+
+const (
+	TicketUrgencyLow    TicketUrgency = "low"
+	TicketUrgencyMedium TicketUrgency = "medium"
+	TicketUrgencyHigh   TicketUrgency = "high"
+)
+
+// IsValidImpactUrgencyLevel 检查影响/紧急程度取值是否合法
+func IsValidImpactUrgencyLevel(level string) bool {
+	switch level {
+	case "low", "medium", "high":
+		return true
+	default:
+		return false
+	}
+}
+
+// DefaultImpactUrgencyForPriority 根据已有优先级推导影响和紧急程度（用于历史数据迁移）
+func DefaultImpactUrgencyForPriority(priority TicketPriority) (TicketImpact, TicketUrgency) {
+	switch priority {
+	case TicketPriorityCritical:
+		return TicketImpactHigh, TicketUrgencyHigh
+	case TicketPriorityUrgent:
+		return TicketImpactHigh, TicketUrgencyMedium
+	case TicketPriorityHigh:
+		return TicketImpactMedium, TicketUrgencyMedium
+	case TicketPriorityLow:
+		return TicketImpactLow, TicketUrgencyLow
+	default:
+		return TicketImpactMedium, TicketUrgencyLow
+	}
+}
+
 // TicketType 工单类型枚举
 type TicketType string
 
@@ -41,6 +95,16 @@ const (
 	TicketTypeConsultation TicketType = "consultation" // 咨询
 )
 
+// IsValid 检查工单类型取值是否合法
+func (t TicketType) IsValid() bool {
+	switch t {
+	case TicketTypeIncident, TicketTypeRequest, TicketTypeProblem, TicketTypeChange, TicketTypeComplaint, TicketTypeConsultation:
+		return true
+	default:
+		return false
+	}
+}
+
 // TicketSource 工单来源枚举
 type TicketSource string
 
@@ -71,18 +135,26 @@ type Ticket struct {
 	Status   TicketStatus   `json:"status" gorm:"size:20;not null;default:'open'" validate:"required,oneof=open in_progress pending resolved closed cancelled"`
 	Source   TicketSource   `json:"source" gorm:"size:20;not null;default:'web'" validate:"required,oneof=web email phone chat api mobile"`
 
+	// 影响×紧急程度（可选，启用优先级矩阵时决定实际优先级）
+	Impact  TicketImpact  `json:"impact,omitempty" gorm:"size:20;index" validate:"omitempty,oneof=low medium high"`
+	Urgency TicketUrgency `json:"urgency,omitempty" gorm:"size:20;index" validate:"omitempty,oneof=low medium high"`
+
 	// 用户关联
 	CreatedByID  uint  `json:"created_by_id" gorm:"not null;index"`
 	CreatedBy    *User `json:"created_by,omitempty" gorm:"foreignKey:CreatedByID"`
 	AssignedToID *uint `json:"assigned_to_id,omitempty" gorm:"index"`
 	AssignedTo   *User `json:"assigned_to,omitempty" gorm:"foreignKey:AssignedToID"`
 
+	// 团队队列（可分配给团队，由成员认领）
+	AssignedTeamID *uint `json:"assigned_team_id,omitempty" gorm:"index"`
+	AssignedTeam   *Team `json:"assigned_team,omitempty" gorm:"foreignKey:AssignedTeamID"`
+
 	// 分类和标签
 	CategoryID    *uint     `json:"category_id,omitempty" gorm:"index"`
 	Category      *Category `json:"category,omitempty" gorm:"foreignKey:CategoryID"`
 	SubcategoryID *uint     `json:"subcategory_id,omitempty" gorm:"index"`
 	Subcategory   *Category `json:"subcategory,omitempty" gorm:"foreignKey:SubcategoryID"`
-	Tags          string    `json:"tags" gorm:"type:text"` // JSON格式存储标签列表
+	Tags          JSONText  `json:"tags"` // JSON格式存储标签列表，按存储方式为 TEXT 或 jsonb
 
 	// 时间跟踪
 	DueDate      *time.Time `json:"due_date,omitempty"`
@@ -90,6 +162,9 @@ type Ticket struct {
 	ClosedAt     *time.Time `json:"closed_at,omitempty"`
 	FirstReplyAt *time.Time `json:"first_reply_at,omitempty"`
 
+	// 解决代码（解决时选择，重新打开后清空）
+	ResolutionCode string `json:"resolution_code,omitempty" gorm:"size:50;index"`
+
 	// SLA相关
 	SLABreached    bool       `json:"sla_breached" gorm:"default:false"`
 	SLADueDate     *time.Time `json:"sla_due_date,omitempty"`
@@ -102,9 +177,19 @@ type Ticket struct {
 	CustomerName  string `json:"customer_name" gorm:"size:100"`
 
 	// 附加信息
-	Attachments   string `json:"attachments" gorm:"type:text"`    // JSON格式存储附件列表
-	CustomFields  string `json:"custom_fields" gorm:"type:text"`  // JSON格式存储自定义字段
-	InternalNotes string `json:"internal_notes" gorm:"type:text"` // 内部备注
+	Attachments   string   `json:"attachments" gorm:"type:text"`    // JSON格式存储附件列表
+	CustomFields  JSONText `json:"custom_fields"`                   // JSON格式存储自定义字段，按存储方式为 TEXT 或 jsonb
+	InternalNotes string   `json:"internal_notes" gorm:"type:text"` // 内部备注
+
+	// 保密工单：详情、历史和评论的读取记录写入访问日志
+	IsConfidential bool `json:"is_confidential" gorm:"default:false;index"`
+
+	// 法律保留：保留期间不能清除或匿名化客户数据
+	LegalHold       bool       `json:"legal_hold" gorm:"default:false;index"`
+	LegalHoldReason string     `json:"legal_hold_reason,omitempty" gorm:"size:255"`
+	LegalHoldByID   *uint      `json:"legal_hold_by_id,omitempty"`
+	LegalHoldAt     *time.Time `json:"legal_hold_at,omitempty"`
+	PIIPurgedAt     *time.Time `json:"pii_purged_at,omitempty" gorm:"column:pii_purged_at"` // 按删除请求清除或匿名化客户数据的时间
 
 	// 统计信息
 	ViewCount     int    `json:"view_count" gorm:"default:0"`
@@ -113,11 +198,24 @@ type Ticket struct {
 	RatingComment string `json:"rating_comment" gorm:"type:text"` // 评分备注
 
 	// 工作流扩展字段
-	IsEscalated bool `json:"is_escalated" gorm:"default:false"` // 是否已升级
+	IsEscalated         bool       `json:"is_escalated" gorm:"default:false"` // 是否已升级
+	AutoCloseReminderAt *time.Time `json:"auto_close_reminder_at,omitempty"`  // 自动关闭提醒发送时间
+	StaleNudgedAt       *time.Time `json:"stale_nudged_at,omitempty"`         // 停滞提醒发送时间
+	StaleEscalatedAt    *time.Time `json:"stale_escalated_at,omitempty"`      // 停滞升级至团队负责人的时间
+	TriageSnoozedUntil  *time.Time `json:"triage_snoozed_until,omitempty"`    // 在收件箱中暂缓分拣至该时间
+	SpamScore           int        `json:"spam_score" gorm:"default:0"`       // 公开渠道受理时的垃圾评分（0-100）
+
+	// 父子工单
+	ParentTicketID *uint   `json:"parent_ticket_id,omitempty" gorm:"index"`
+	ParentTicket   *Ticket `json:"parent_ticket,omitempty" gorm:"foreignKey:ParentTicketID"`
+	ChainDepth     int     `json:"chain_depth" gorm:"default:0"` // 由自动化连续创建的层级深度
 
 	// 关联关系
 	Comments []TicketComment `json:"comments,omitempty" gorm:"foreignKey:TicketID"`
 	History  []TicketHistory `json:"history,omitempty" gorm:"foreignKey:TicketID"`
+
+	// 保存描述时的敏感信息检测提示，不入库
+	PIIScan *PIIScanNotice `json:"pii_scan,omitempty" gorm:"-"`
 }
 
 // TableName 指定表名
@@ -155,6 +253,21 @@ func (t *Ticket) IsSLABreached() bool {
 	return t.SLABreached || (t.SLADueDate != nil && t.SLADueDate.Before(time.Now()) && !t.IsClosed() && !t.IsResolved())
 }
 
+// TagList 解析标签列表
+func (t *Ticket) TagList() []string {
+	return parseStringSliceFromJSON(t.Tags.String())
+}
+
+// HasTag 检查工单是否包含指定标签（忽略大小写）
+func (t *Ticket) HasTag(tag string) bool {
+	for _, item := range t.TagList() {
+		if strings.EqualFold(item, tag) {
+			return true
+		}
+	}
+	return false
+}
+
 // CanBeAssigned 检查工单是否可以分配
 func (t *Ticket) CanBeAssigned() bool {
 	return t.Status == TicketStatusOpen || t.Status == TicketStatusInProgress
@@ -170,46 +283,167 @@ func (t *Ticket) CanBeClosed() bool {
 	return t.Status == TicketStatusResolved
 }
 
+// TicketFieldChange 工单字段变更（用于Webhook变更订阅）
+type TicketFieldChange struct {
+	Field  string      `json:"field"`  // 字段名，与工单JSON字段一致
+	Before interface{} `json:"before"` // 变更前的值
+	After  interface{} `json:"after"`  // 变更后的值
+}
+
+// DiffTicketFields 计算两个工单快照之间的字段变更
+func DiffTicketFields(before, after *Ticket) []TicketFieldChange {
+	var changes []TicketFieldChange
+	add := func(field string, oldValue, newValue interface{}) {
+		changes = append(changes, TicketFieldChange{Field: field, Before: oldValue, After: newValue})
+	}
+
+	if before.Title != after.Title {
+		add("title", before.Title, after.Title)
+	}
+	if before.Description != after.Description {
+		add("description", before.Description, after.Description)
+	}
+	if before.Status != after.Status {
+		add("status", before.Status, after.Status)
+	}
+	if before.Priority != after.Priority {
+		add("priority", before.Priority, after.Priority)
+	}
+	if before.Type != after.Type {
+		add("type", before.Type, after.Type)
+	}
+	if before.Source != after.Source {
+		add("source", before.Source, after.Source)
+	}
+	if before.Impact != after.Impact {
+		add("impact", before.Impact, after.Impact)
+	}
+	if before.Urgency != after.Urgency {
+		add("urgency", before.Urgency, after.Urgency)
+	}
+	if !equalUintPtr(before.AssignedToID, after.AssignedToID) {
+		add("assigned_to_id", before.AssignedToID, after.AssignedToID)
+	}
+	if !equalUintPtr(before.AssignedTeamID, after.AssignedTeamID) {
+		add("assigned_team_id", before.AssignedTeamID, after.AssignedTeamID)
+	}
+	if !equalUintPtr(before.CategoryID, after.CategoryID) {
+		add("category_id", before.CategoryID, after.CategoryID)
+	}
+	if !equalUintPtr(before.SubcategoryID, after.SubcategoryID) {
+		add("subcategory_id", before.SubcategoryID, after.SubcategoryID)
+	}
+	if !equalTimePtr(before.DueDate, after.DueDate) {
+		add("due_date", before.DueDate, after.DueDate)
+	}
+	if !equalTimePtr(before.SLADueDate, after.SLADueDate) {
+		add("sla_due_date", before.SLADueDate, after.SLADueDate)
+	}
+	if before.Tags != after.Tags {
+		add("tags", before.TagList(), after.TagList())
+	}
+	if before.CustomFields != after.CustomFields {
+		add("custom_fields", before.CustomFields, after.CustomFields)
+	}
+	if before.CustomerEmail != after.CustomerEmail {
+		add("customer_email", before.CustomerEmail, after.CustomerEmail)
+	}
+	if before.CustomerPhone != after.CustomerPhone {
+		add("customer_phone", before.CustomerPhone, after.CustomerPhone)
+	}
+	if before.CustomerName != after.CustomerName {
+		add("customer_name", before.CustomerName, after.CustomerName)
+	}
+	if before.IsConfidential != after.IsConfidential {
+		add("is_confidential", before.IsConfidential, after.IsConfidential)
+	}
+	if before.ResolutionCode != after.ResolutionCode {
+		add("resolution_code", before.ResolutionCode, after.ResolutionCode)
+	}
+
+	return changes
+}
+
+func equalUintPtr(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func equalTimePtr(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
+
 // TicketCreateRequest 工单创建请求
 type TicketCreateRequest struct {
-	Title         string         `json:"title" validate:"required,max=255"`
-	Description   string         `json:"description" validate:"required"`
-	Type          TicketType     `json:"type" validate:"required,oneof=incident request problem change complaint consultation"`
-	Priority      TicketPriority `json:"priority" validate:"required,oneof=low normal high urgent critical"`
-	Status        *TicketStatus  `json:"status" validate:"omitempty,oneof=open in_progress pending resolved closed cancelled"`
-	Source        TicketSource   `json:"source" validate:"required,oneof=web email phone chat api mobile"`
-	AssignedToID  *uint          `json:"assigned_to_id"`
-	CategoryID    *uint          `json:"category_id"`
-	SubcategoryID *uint          `json:"subcategory_id"`
-	Tags          StringList     `json:"tags"`
-	DueDate       *time.Time     `json:"due_date"`
-	CustomerEmail string         `json:"customer_email" validate:"omitempty,email"`
-	CustomerPhone string         `json:"customer_phone"`
-	CustomerName  string         `json:"customer_name"`
-	Attachments   []string       `json:"attachments"`
-	CustomFields  *JSONMap       `json:"custom_fields"`
+	Title          string         `json:"title" validate:"required,max=255"`
+	Description    string         `json:"description" validate:"required"`
+	Type           TicketType     `json:"type" validate:"required,oneof=incident request problem change complaint consultation"`
+	Priority       TicketPriority `json:"priority" validate:"required,oneof=low normal high urgent critical"`
+	Status         *TicketStatus  `json:"status" validate:"omitempty,oneof=open in_progress pending resolved closed cancelled"`
+	Source         TicketSource   `json:"source" validate:"required,oneof=web email phone chat api mobile"`
+	Impact         *TicketImpact  `json:"impact" validate:"omitempty,oneof=low medium high"`
+	Urgency        *TicketUrgency `json:"urgency" validate:"omitempty,oneof=low medium high"`
+	AssignedToID   *uint          `json:"assigned_to_id"`
+	AssignedTeamID *uint          `json:"assigned_team_id"`
+	CategoryID     *uint          `json:"category_id"`
+	SubcategoryID  *uint          `json:"subcategory_id"`
+	Tags           StringList     `json:"tags"`
+	DueDate        *time.Time     `json:"due_date"`
+	CustomerEmail  string         `json:"customer_email" validate:"omitempty,email"`
+	CustomerPhone  string         `json:"customer_phone"`
+	CustomerName   string         `json:"customer_name"`
+	Attachments    []string       `json:"attachments"`
+	CustomFields   *JSONMap       `json:"custom_fields"`
+	IsConfidential bool           `json:"is_confidential"`
+	PrefillToken   string         `json:"prefill_token,omitempty"` // 通过预填链接建单时回传，用于统计
+
+	SpamScore int `json:"-"` // 公开渠道垃圾评分，由受理检测填写
 }
 
 // TicketUpdateRequest 工单更新请求
 type TicketUpdateRequest struct {
-	Title         *string         `json:"title" validate:"omitempty,max=255"`
-	Description   *string         `json:"description"`
-	Type          *TicketType     `json:"type" validate:"omitempty,oneof=incident request problem change complaint consultation"`
-	Priority      *TicketPriority `json:"priority" validate:"omitempty,oneof=low normal high urgent critical"`
-	Status        *TicketStatus   `json:"status" validate:"omitempty,oneof=open in_progress pending resolved closed cancelled"`
-	Source        *TicketSource   `json:"source" validate:"omitempty,oneof=web email phone chat api mobile"`
-	AssignedToID  *uint           `json:"assigned_to_id"`
-	CategoryID    *uint           `json:"category_id"`
-	SubcategoryID *uint           `json:"subcategory_id"`
-	Tags          StringList      `json:"tags"`
-	DueDate       *time.Time      `json:"due_date"`
-	CustomerEmail *string         `json:"customer_email" validate:"omitempty,email"`
-	CustomerPhone *string         `json:"customer_phone"`
-	CustomerName  *string         `json:"customer_name"`
-	InternalNotes *string         `json:"internal_notes"`
-	Rating        *int            `json:"rating" validate:"omitempty,min=1,max=5"`
-	RatingComment *string         `json:"rating_comment"`
-	CustomFields  *JSONMap        `json:"custom_fields"`
+	Title          *string         `json:"title" validate:"omitempty,max=255"`
+	Description    *string         `json:"description"`
+	Type           *TicketType     `json:"type" validate:"omitempty,oneof=incident request problem change complaint consultation"`
+	Priority       *TicketPriority `json:"priority" validate:"omitempty,oneof=low normal high urgent critical"`
+	Status         *TicketStatus   `json:"status" validate:"omitempty,oneof=open in_progress pending resolved closed cancelled"`
+	Source         *TicketSource   `json:"source" validate:"omitempty,oneof=web email phone chat api mobile"`
+	Impact         *TicketImpact   `json:"impact" validate:"omitempty,oneof=low medium high"`
+	Urgency        *TicketUrgency  `json:"urgency" validate:"omitempty,oneof=low medium high"`
+	AssignedToID   *uint           `json:"assigned_to_id"`
+	AssignedTeamID *uint           `json:"assigned_team_id"`
+	CategoryID     *uint           `json:"category_id"`
+	SubcategoryID  *uint           `json:"subcategory_id"`
+	Tags           StringList      `json:"tags"`
+	DueDate        *time.Time      `json:"due_date"`
+	SLADueDate     *time.Time      `json:"sla_due_date"` // 手动覆盖 SLA 截止时间
+	CustomerEmail  *string         `json:"customer_email" validate:"omitempty,email"`
+	CustomerPhone  *string         `json:"customer_phone"`
+	CustomerName   *string         `json:"customer_name"`
+	InternalNotes  *string         `json:"internal_notes"`
+	Rating         *int            `json:"rating" validate:"omitempty,min=1,max=5"`
+	RatingComment  *string         `json:"rating_comment"`
+	CustomFields   *JSONMap        `json:"custom_fields"`
+	IsConfidential *bool           `json:"is_confidential"`
+	ResolutionCode *string         `json:"resolution_code" validate:"omitempty,max=50"` // 解决或已解决工单的解决代码
+}
+
+// TicketSurveyRequest 满意度调查提交请求
+type TicketSurveyRequest struct {
+	Rating  int    `json:"rating" binding:"required,min=1,max=5"`
+	Comment string `json:"comment" binding:"max=2000"`
+}
+
+// TicketCategoryTransferRequest 工单转移分类请求
+type TicketCategoryTransferRequest struct {
+	CategoryID    uint   `json:"category_id" binding:"required"`
+	SubcategoryID *uint  `json:"subcategory_id"`
+	Comment       string `json:"comment" binding:"max=2000"`
 }
 
 // TicketResponse 工单响应
@@ -224,13 +458,18 @@ type TicketResponse struct {
 	Priority       TicketPriority         `json:"priority"`
 	Status         TicketStatus           `json:"status"`
 	Source         TicketSource           `json:"source"`
+	Impact         TicketImpact           `json:"impact,omitempty"`
+	Urgency        TicketUrgency          `json:"urgency,omitempty"`
 	CreatedBy      *UserResponse          `json:"created_by,omitempty"`
 	AssignedTo     *UserResponse          `json:"assigned_to,omitempty"`
+	AssignedTeamID *uint                  `json:"assigned_team_id,omitempty"`
+	AssignedTeam   *TeamResponse          `json:"assigned_team,omitempty"`
 	Category       *CategoryResponse      `json:"category,omitempty"`
 	Subcategory    *CategoryResponse      `json:"subcategory,omitempty"`
 	Tags           []string               `json:"tags"`
 	DueDate        *time.Time             `json:"due_date"`
 	ResolvedAt     *time.Time             `json:"resolved_at"`
+	ResolutionCode string                 `json:"resolution_code,omitempty"`
 	ClosedAt       *time.Time             `json:"closed_at"`
 	FirstReplyAt   *time.Time             `json:"first_reply_at"`
 	SLABreached    bool                   `json:"sla_breached"`
@@ -246,10 +485,33 @@ type TicketResponse struct {
 	CommentCount   int                    `json:"comment_count"`
 	Rating         *int                   `json:"rating"`
 	RatingComment  string                 `json:"rating_comment"`
+	IsConfidential bool                   `json:"is_confidential"`
 
 	// 工作流计算字段
 	IsOverdue   bool `json:"is_overdue"`   // 是否逾期
 	IsEscalated bool `json:"is_escalated"` // 是否已升级
+
+	// 检查项进度，工单没有检查项时不返回
+	ChecklistProgress *ChecklistProgress `json:"checklist_progress,omitempty"`
+
+	// 邮件会话概要，仅工单详情返回，工单没有关联邮件时不返回
+	EmailThread *EmailThreadSummary `json:"email_thread,omitempty"`
+
+	// 外部系统关联（CRM、订单号等），仅工单详情返回
+	ExternalReferences []*ExternalReference `json:"external_references,omitempty"`
+
+	// 仅创建工单时返回：按营业日历计算的截止时间建议
+	DueDateSuggestions []DueDateSuggestion `json:"due_date_suggestions,omitempty"`
+
+	// 仅创建/更新描述时返回：描述中检测到的敏感信息
+	PIIScan *PIIScanNotice `json:"pii_scan,omitempty"`
+}
+
+// DueDateSuggestion 截止时间建议，如「下一个工作日 17:00」
+type DueDateSuggestion struct {
+	Key     string    `json:"key"` // today / next_business_day / in_3_business_days / in_5_business_days
+	Label   string    `json:"label"`
+	DueDate time.Time `json:"due_date"`
 }
 
 // ToResponse 转换为响应格式
@@ -265,8 +527,11 @@ func (t *Ticket) ToResponse() *TicketResponse {
 		Priority:       t.Priority,
 		Status:         t.Status,
 		Source:         t.Source,
+		Impact:         t.Impact,
+		Urgency:        t.Urgency,
 		DueDate:        t.DueDate,
 		ResolvedAt:     t.ResolvedAt,
+		ResolutionCode: t.ResolutionCode,
 		ClosedAt:       t.ClosedAt,
 		FirstReplyAt:   t.FirstReplyAt,
 		SLABreached:    t.SLABreached,
@@ -276,14 +541,17 @@ func (t *Ticket) ToResponse() *TicketResponse {
 		CustomerEmail:  t.CustomerEmail,
 		CustomerPhone:  t.CustomerPhone,
 		CustomerName:   t.CustomerName,
+		AssignedTeamID: t.AssignedTeamID,
 		ViewCount:      t.ViewCount,
 		CommentCount:   t.CommentCount,
 		Rating:         t.Rating,
 		RatingComment:  t.RatingComment,
+		IsConfidential: t.IsConfidential,
 
 		// 计算字段
 		IsOverdue:   t.IsOverdue(),
 		IsEscalated: t.IsEscalated,
+		PIIScan:     t.PIIScan,
 	}
 
 	// 处理关联用户
@@ -293,6 +561,9 @@ func (t *Ticket) ToResponse() *TicketResponse {
 	if t.AssignedTo != nil {
 		response.AssignedTo = t.AssignedTo.ToResponse()
 	}
+	if t.AssignedTeam != nil {
+		response.AssignedTeam = t.AssignedTeam.ToResponse()
+	}
 
 	// 处理分类
 	if t.Category != nil {
@@ -303,9 +574,9 @@ func (t *Ticket) ToResponse() *TicketResponse {
 	}
 
 	// 解析JSON字段
-	response.Tags = parseStringSliceFromJSON(t.Tags)
+	response.Tags = parseStringSliceFromJSON(t.Tags.String())
 	response.Attachments = parseStringSliceFromJSON(t.Attachments)
-	response.CustomFields = parseCustomFieldsFromJSON(t.CustomFields)
+	response.CustomFields = parseCustomFieldsFromJSON(t.CustomFields.String())
 
 	return response
 }
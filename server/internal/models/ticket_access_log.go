@@ -0,0 +1,70 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// TicketAccessAction 保密工单的读取类型
+type TicketAccessAction string
+
+const (
+	TicketAccessView     TicketAccessAction = "view"     // 查看工单详情
+	TicketAccessHistory  TicketAccessAction = "history"  // 查看工单历史
+	TicketAccessComments TicketAccessAction = "comments" // 查看工单评论
+	TicketAccessArchive  TicketAccessAction = "archive"  // 导出工单归档
+)
+
+// TicketAccessLog 保密工单读取记录，用于合规审计
+type TicketAccessLog struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+
+	TicketID     uint               `json:"ticket_id" gorm:"not null;index:idx_ticket_access_ticket_user"`
+	TicketNumber string             `json:"ticket_number" gorm:"size:50"`
+	UserID       uint               `json:"user_id" gorm:"not null;index:idx_ticket_access_ticket_user;index"`
+	Username     string             `json:"username" gorm:"size:100"`
+	Role         string             `json:"role" gorm:"size:20"`
+	Action       TicketAccessAction `json:"action" gorm:"size:20;not null"`
+	ClientIP     string             `json:"client_ip" gorm:"size:64"`
+	UserAgent    string             `json:"user_agent" gorm:"size:255"`
+	IsAssignee   bool               `json:"is_assignee" gorm:"default:false;index"` // 读取时是否为工单处理人
+	AlertedAt    *time.Time         `json:"alerted_at,omitempty"`                   // 本次读取触发了重复访问告警
+}
+
+// TableName 指定表名
+func (TicketAccessLog) TableName() string {
+	return "ticket_access_logs"
+}
+
+// TicketAccessAuditPolicy 保密工单访问审计策略
+type TicketAccessAuditPolicy struct {
+	RetentionDays      int  `json:"retention_days"`       // 访问记录保留天数
+	AlertEnabled       bool `json:"alert_enabled"`        // 非处理人重复访问时通知管理员
+	AlertThreshold     int  `json:"alert_threshold"`      // 同一用户在时间窗口内读取同一工单的次数达到该值时告警
+	AlertWindowMinutes int  `json:"alert_window_minutes"` // 统计时间窗口（分钟），窗口内同一用户同一工单只告警一次
+}
+
+// GetDefaultTicketAccessAuditPolicy 获取默认访问审计策略
+func GetDefaultTicketAccessAuditPolicy() *TicketAccessAuditPolicy {
+	return &TicketAccessAuditPolicy{
+		RetentionDays:      365,
+		AlertEnabled:       false,
+		AlertThreshold:     5,
+		AlertWindowMinutes: 60,
+	}
+}
+
+// Validate 校验访问审计策略
+func (p *TicketAccessAuditPolicy) Validate() error {
+	if p.RetentionDays < 1 || p.RetentionDays > 3650 {
+		return fmt.Errorf("retention_days must be between 1 and 3650")
+	}
+	if p.AlertThreshold < 2 || p.AlertThreshold > 1000 {
+		return fmt.Errorf("alert_threshold must be between 2 and 1000")
+	}
+	if p.AlertWindowMinutes < 1 || p.AlertWindowMinutes > 7*24*60 {
+		return fmt.Errorf("alert_window_minutes must be between 1 and 10080")
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package models
+
+import "time"
+
+// TicketArchiveStatus 工单归档任务状态
+type TicketArchiveStatus string
+
+const (
+	TicketArchivePending   TicketArchiveStatus = "pending"   // 等待生成
+	TicketArchiveRunning   TicketArchiveStatus = "running"   // 生成中
+	TicketArchiveCompleted TicketArchiveStatus = "completed" // 已生成，可下载
+	TicketArchiveFailed    TicketArchiveStatus = "failed"    // 生成失败
+)
+
+// 归档生成阶段
+const (
+	TicketArchiveStageCollecting  = "collecting"  // 读取工单、评论及历史
+	TicketArchiveStageRendering   = "rendering"   // 生成PDF
+	TicketArchiveStageAttachments = "attachments" // 打包附件
+	TicketArchiveStageDone        = "done"
+)
+
+// TicketArchiveRequest 工单归档请求
+type TicketArchiveRequest struct {
+	// 包含内部及团队可见的评论和不对用户展示的历史，默认只包含客户可见内容（适用于客户移交）
+	IncludeInternal bool `json:"include_internal"`
+}
+
+// TicketArchiveJob 后台生成的工单完整归档（ZIP），用于法律调取及客户移交
+type TicketArchiveJob struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	TicketID        uint                `json:"ticket_id" gorm:"not null;index"`
+	TicketNumber    string              `json:"ticket_number" gorm:"size:50"`
+	IncludeInternal bool                `json:"include_internal"`
+	Status          TicketArchiveStatus `json:"status" gorm:"size:20;not null;default:'pending';index"`
+	Stage           string              `json:"stage,omitempty" gorm:"size:20"`
+	Progress        int                 `json:"progress"` // 0-100
+
+	FileName           string `json:"file_name,omitempty" gorm:"size:255"`
+	FilePath           string `json:"-" gorm:"size:500"` // 导出目录下的相对路径
+	FileSize           int64  `json:"file_size,omitempty"`
+	Checksum           string `json:"checksum,omitempty" gorm:"size:64"` // ZIP 文件的 SHA-256
+	SkippedAttachments int    `json:"skipped_attachments"`               // 无法读取或未通过病毒扫描的附件数，详见 manifest.json
+	Error              string `json:"error,omitempty" gorm:"size:500"`
+
+	CreatedByID uint       `json:"created_by_id" gorm:"index"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty" gorm:"index"` // 过期后文件被清理，不再可下载
+
+	// 签名下载链接，仅在获取已完成的任务时生成
+	DownloadURL       string     `json:"download_url,omitempty" gorm:"-"`
+	DownloadExpiresAt *time.Time `json:"download_expires_at,omitempty" gorm:"-"`
+}
+
+// TableName 指定表名
+func (TicketArchiveJob) TableName() string {
+	return "ticket_archive_jobs"
+}
+
+// TicketArchiveManifest 归档清单（manifest.json），记录各文件的 SHA-256 以便核验完整性
+type TicketArchiveManifest struct {
+	TicketID        uint                        `json:"ticket_id"`
+	TicketNumber    string                      `json:"ticket_number"`
+	GeneratedAt     time.Time                   `json:"generated_at"`
+	GeneratedByID   uint                        `json:"generated_by_id"`
+	IncludeInternal bool                        `json:"include_internal"`
+	Files           []TicketArchiveManifestFile `json:"files"`
+	Skipped         []TicketArchiveSkippedFile  `json:"skipped"`
+}
+
+// TicketArchiveManifestFile 归档中的文件
+type TicketArchiveManifestFile struct {
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// TicketArchiveSkippedFile 未能打包的附件
+type TicketArchiveSkippedFile struct {
+	AttachmentID uint   `json:"attachment_id"`
+	Name         string `json:"name"`
+	Reason       string `json:"reason"`
+}
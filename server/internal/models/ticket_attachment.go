@@ -46,6 +46,7 @@ type TicketAttachment struct {
 	StorageType string `json:"storage_type" gorm:"size:20;default:'local'"` // local, s3, gcs, azure
 	StorageUrl  string `json:"storage_url" gorm:"size:500"`
 	ThumbnailUrl string `json:"thumbnail_url" gorm:"size:500"`
+	Encryption   AttachmentEncryption `json:"encryption" gorm:"embedded;embeddedPrefix:encryption_"`
 	
 	// 访问控制
 	IsPublic     bool   `json:"is_public" gorm:"default:false"`
@@ -0,0 +1,107 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TicketBulkCommentStatus 批量评论任务状态
+type TicketBulkCommentStatus string
+
+const (
+	TicketBulkCommentPending   TicketBulkCommentStatus = "pending"   // 等待执行
+	TicketBulkCommentRunning   TicketBulkCommentStatus = "running"   // 执行中
+	TicketBulkCommentCompleted TicketBulkCommentStatus = "completed" // 已完成（可能包含失败的工单）
+	TicketBulkCommentFailed    TicketBulkCommentStatus = "failed"    // 任务中断
+)
+
+// TicketBulkCommentResult 单个工单的评论结果
+type TicketBulkCommentResult struct {
+	TicketID     uint   `json:"ticket_id"`
+	TicketNumber string `json:"ticket_number,omitempty"`
+	Success      bool   `json:"success"`
+	CommentID    uint   `json:"comment_id,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+// TicketBulkCommentJob 后台执行的批量评论任务，同一内容逐个工单发表，内容支持工单变量
+type TicketBulkCommentJob struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Content         string            `json:"content" gorm:"type:text;not null"` // 评论模板，如 {{ticket.number}}
+	ContentType     string            `json:"content_type" gorm:"size:20;default:'text'"`
+	Type            CommentType       `json:"type" gorm:"size:20;not null"`
+	Visibility      CommentVisibility `json:"visibility" gorm:"size:20;not null"`
+	VisibleTeamID   *uint             `json:"visible_team_id,omitempty"`
+	IgnoreReplyLock bool              `json:"ignore_reply_lock"`
+
+	TicketIDs    string                  `json:"-" gorm:"type:text"` // 目标工单ID JSON
+	TicketIDList []uint                  `json:"ticket_ids" gorm:"-"`
+	Status       TicketBulkCommentStatus `json:"status" gorm:"size:20;not null;default:'pending';index"`
+
+	Total      int                       `json:"total"`
+	Succeeded  int                       `json:"succeeded"`
+	Failed     int                       `json:"failed"`
+	Notified   int                       `json:"notified"`           // 发出的汇总通知数
+	Results    string                    `json:"-" gorm:"type:text"` // 逐个工单的执行结果JSON
+	ResultList []TicketBulkCommentResult `json:"results" gorm:"-"`
+	Error      string                    `json:"error,omitempty" gorm:"size:500"`
+
+	CreatedByID uint       `json:"created_by_id" gorm:"index"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+}
+
+// TableName 指定表名
+func (TicketBulkCommentJob) TableName() string {
+	return "ticket_bulk_comment_jobs"
+}
+
+// BeforeSave GORM钩子 - 序列化目标工单与执行结果
+func (j *TicketBulkCommentJob) BeforeSave(tx *gorm.DB) error {
+	if j.TicketIDList == nil {
+		j.TicketIDList = []uint{}
+	}
+	if j.ResultList == nil {
+		j.ResultList = []TicketBulkCommentResult{}
+	}
+	ids, err := json.Marshal(j.TicketIDList)
+	if err != nil {
+		return err
+	}
+	results, err := json.Marshal(j.ResultList)
+	if err != nil {
+		return err
+	}
+	j.TicketIDs = string(ids)
+	j.Results = string(results)
+	return nil
+}
+
+// AfterFind GORM钩子 - 反序列化目标工单与执行结果
+func (j *TicketBulkCommentJob) AfterFind(tx *gorm.DB) error {
+	j.TicketIDList = []uint{}
+	j.ResultList = []TicketBulkCommentResult{}
+	if j.TicketIDs != "" {
+		_ = json.Unmarshal([]byte(j.TicketIDs), &j.TicketIDList)
+	}
+	if j.Results != "" {
+		_ = json.Unmarshal([]byte(j.Results), &j.ResultList)
+	}
+	return nil
+}
+
+// TicketBulkCommentRequest 批量评论请求
+type TicketBulkCommentRequest struct {
+	TicketIDs       []uint            `json:"ticket_ids" binding:"required,min=1,max=500"`
+	Content         string            `json:"content" binding:"required"`
+	ContentType     string            `json:"content_type" binding:"omitempty,oneof=text html markdown"`
+	Type            CommentType       `json:"type" binding:"omitempty,oneof=public internal"`
+	Visibility      CommentVisibility `json:"visibility" binding:"omitempty,oneof=public internal team"`
+	TeamID          *uint             `json:"visible_team_id"`
+	IgnoreReplyLock bool              `json:"ignore_reply_lock"` // 其他客服持有回复锁的工单仍然发表
+}
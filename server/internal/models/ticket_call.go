@@ -0,0 +1,73 @@
+package models
+
+import "time"
+
+// CallDirection 通话方向
+type CallDirection string
+
+const (
+	CallDirectionInbound  CallDirection = "inbound"  // 客户来电
+	CallDirectionOutbound CallDirection = "outbound" // 客服外呼
+)
+
+// TicketCallLog 电话渠道的通话记录，可用于新建工单或关联到已有工单
+type TicketCallLog struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	TicketID  uint          `json:"ticket_id" gorm:"not null;index"`
+	AgentID   uint          `json:"agent_id" gorm:"not null;index"` // 接听或外呼的客服
+	Agent     *User         `json:"agent,omitempty" gorm:"foreignKey:AgentID"`
+	Direction CallDirection `json:"direction" gorm:"size:20;not null"`
+
+	PhoneNumber     string    `json:"phone_number" gorm:"size:50"` // 对方号码
+	StartedAt       time.Time `json:"started_at" gorm:"not null;index"`
+	DurationSeconds int       `json:"duration_seconds"`
+	RecordingURL    string    `json:"recording_url,omitempty" gorm:"size:1000"`         // 录音在呼叫中心的地址，系统不保存录音文件
+	ExternalCallID  string    `json:"external_call_id,omitempty" gorm:"size:100;index"` // 呼叫中心的通话ID，重复上报时返回已有记录
+	Notes           string    `json:"notes,omitempty" gorm:"type:text"`
+
+	CreatedTicket bool `json:"created_ticket"` // 是否由本次通话新建工单
+	LoggedByID    uint `json:"logged_by_id"`
+}
+
+// TableName 指定表名
+func (TicketCallLog) TableName() string {
+	return "ticket_call_logs"
+}
+
+// TicketCallRequest 记录通话请求。关联已有工单时通过路径或 ticket_id 指定，
+// 否则以 title/description 等字段新建来源为电话的工单
+type TicketCallRequest struct {
+	TicketID        *uint         `json:"ticket_id,omitempty"`
+	Direction       CallDirection `json:"direction" binding:"required,oneof=inbound outbound"`
+	PhoneNumber     string        `json:"phone_number" binding:"max=50"`
+	StartedAt       *time.Time    `json:"started_at,omitempty"` // 为空时按结束时间减去时长计算
+	DurationSeconds int           `json:"duration_seconds" binding:"min=0,max=86400"`
+	RecordingURL    string        `json:"recording_url" binding:"max=1000"`
+	ExternalCallID  string        `json:"external_call_id" binding:"max=100"`
+	Notes           string        `json:"notes"`
+	AgentID         *uint         `json:"agent_id,omitempty"` // 为空时为记录人
+
+	// 新建工单
+	Title         string         `json:"title" binding:"max=255"`
+	Description   string         `json:"description"`
+	Type          TicketType     `json:"type"`
+	Priority      TicketPriority `json:"priority"`
+	CategoryID    *uint          `json:"category_id,omitempty"`
+	CustomerName  string         `json:"customer_name"`
+	CustomerEmail string         `json:"customer_email" binding:"omitempty,email"`
+}
+
+// CallVolumeRow 通话量统计行，按客服或分类汇总
+type CallVolumeRow struct {
+	Key          uint   `json:"key"` // 客服ID或分类ID，0 表示未分类
+	Name         string `json:"name"`
+	Calls        int    `json:"calls"`
+	Inbound      int    `json:"inbound"`
+	Outbound     int    `json:"outbound"`
+	TotalSeconds int    `json:"total_seconds"`
+	AvgSeconds   int    `json:"avg_seconds"`
+	NewTickets   int    `json:"new_tickets"` // 由通话新建的工单数
+}
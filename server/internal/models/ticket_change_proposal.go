@@ -0,0 +1,62 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ChangeProposalStatus 变更提案状态
+type ChangeProposalStatus string
+
+const (
+	ChangeProposalPending  ChangeProposalStatus = "pending"  // 待审核
+	ChangeProposalApproved ChangeProposalStatus = "approved" // 已批准并应用
+	ChangeProposalRejected ChangeProposalStatus = "rejected" // 已驳回
+)
+
+// TicketChangeProposal 工单变更提案，需审核人批准后才应用到工单
+type TicketChangeProposal struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	TicketID   uint                 `json:"ticket_id" gorm:"not null;index"`
+	ProposerID uint                 `json:"proposer_id" gorm:"not null;index"`
+	Status     ChangeProposalStatus `json:"status" gorm:"size:20;not null;default:'pending';index"`
+	Changes    string               `json:"-" gorm:"type:text;not null"` // TicketUpdateRequest 的 JSON
+
+	ReviewerID    *uint      `json:"reviewer_id,omitempty"`
+	ReviewComment string     `json:"review_comment" gorm:"size:1000"`
+	ReviewedAt    *time.Time `json:"reviewed_at,omitempty"`
+
+	Ticket   *Ticket `json:"ticket,omitempty" gorm:"foreignKey:TicketID"`
+	Proposer *User   `json:"proposer,omitempty" gorm:"foreignKey:ProposerID"`
+	Reviewer *User   `json:"reviewer,omitempty" gorm:"foreignKey:ReviewerID"`
+}
+
+// TableName 指定表名
+func (TicketChangeProposal) TableName() string {
+	return "ticket_change_proposals"
+}
+
+// UpdateRequest 解析提案中的工单变更
+func (p *TicketChangeProposal) UpdateRequest() (*TicketUpdateRequest, error) {
+	var req TicketUpdateRequest
+	if err := json.Unmarshal([]byte(p.Changes), &req); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+// ChangeProposalReviewRequest 审核提案请求
+type ChangeProposalReviewRequest struct {
+	Comment string `json:"comment" binding:"max=1000"`
+}
+
+// ChangeProposalResponse 提案详情，附带与工单当前值的差异
+type ChangeProposalResponse struct {
+	*TicketChangeProposal
+	RequestedChanges *TicketUpdateRequest `json:"requested_changes"`
+	Diff             []TicketFieldChange  `json:"diff"` // 仅待审核提案：相对工单当前值的差异
+
+}
@@ -0,0 +1,89 @@
+package models
+
+import "time"
+
+// TicketChecklistItem 工单内的检查项（轻量子任务）
+type TicketChecklistItem struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	TicketID   uint   `json:"ticket_id" gorm:"not null;index"`
+	Text       string `json:"text" gorm:"size:500;not null"`
+	IsDone     bool   `json:"is_done" gorm:"default:false"`
+	IsRequired bool   `json:"is_required" gorm:"default:false"` // 开启阻止解决时，必填项未完成的工单不能标记为已解决
+	Position   int    `json:"position" gorm:"default:0"`
+
+	AssigneeID *uint      `json:"assignee_id,omitempty" gorm:"index"`
+	Assignee   *User      `json:"assignee,omitempty" gorm:"foreignKey:AssigneeID"`
+	DueDate    *time.Time `json:"due_date,omitempty"`
+
+	CompletedAt   *time.Time `json:"completed_at,omitempty"`
+	CompletedByID *uint      `json:"completed_by_id,omitempty"`
+	CreatedByID   uint       `json:"created_by_id"`
+	TemplateID    *uint      `json:"template_id,omitempty"` // 来源工单模板
+}
+
+// TableName 指定表名
+func (TicketChecklistItem) TableName() string {
+	return "ticket_checklist_items"
+}
+
+// ChecklistProgress 工单检查项完成进度
+type ChecklistProgress struct {
+	Total        int `json:"total"`
+	Done         int `json:"done"`
+	Required     int `json:"required"`
+	RequiredDone int `json:"required_done"`
+	Percent      int `json:"percent"` // 已完成项占全部检查项的百分比
+}
+
+// Add 计入一个检查项
+func (p *ChecklistProgress) Add(done, required bool) {
+	p.Total++
+	if done {
+		p.Done++
+	}
+	if required {
+		p.Required++
+		if done {
+			p.RequiredDone++
+		}
+	}
+	p.Percent = p.Done * 100 / p.Total
+}
+
+// ChecklistTemplateItem 工单模板中的检查项定义
+type ChecklistTemplateItem struct {
+	Text       string `json:"text"`
+	IsRequired bool   `json:"is_required,omitempty"`
+	DueInHours int    `json:"due_in_hours,omitempty"` // 相对应用时间的截止时长，0 表示无截止时间
+}
+
+// TicketChecklistItemRequest 新增检查项请求
+type TicketChecklistItemRequest struct {
+	Text       string     `json:"text" binding:"required,max=500"`
+	IsRequired bool       `json:"is_required"`
+	AssigneeID *uint      `json:"assignee_id,omitempty"`
+	DueDate    *time.Time `json:"due_date,omitempty"`
+}
+
+// TicketChecklistItemUpdateRequest 更新检查项请求，assignee_id 为 0 表示取消指派
+type TicketChecklistItemUpdateRequest struct {
+	Text         *string    `json:"text,omitempty" binding:"omitempty,max=500"`
+	IsDone       *bool      `json:"is_done,omitempty"`
+	IsRequired   *bool      `json:"is_required,omitempty"`
+	AssigneeID   *uint      `json:"assignee_id,omitempty"`
+	DueDate      *time.Time `json:"due_date,omitempty"`
+	ClearDueDate bool       `json:"clear_due_date,omitempty"`
+}
+
+// TicketChecklistReorderRequest 检查项排序请求，按数组顺序排列
+type TicketChecklistReorderRequest struct {
+	ItemIDs []uint `json:"item_ids" binding:"required,min=1"`
+}
+
+// TicketChecklistApplyTemplateRequest 从工单模板追加检查项
+type TicketChecklistApplyTemplateRequest struct {
+	TemplateID uint `json:"template_id" binding:"required"`
+}
@@ -0,0 +1,238 @@
+package models
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ClassifierProvider 工单自动分类服务提供方
+type ClassifierProvider string
+
+const (
+	ClassifierProviderKeyword ClassifierProvider = "keyword" // 内置关键词规则
+	ClassifierProviderHTTP    ClassifierProvider = "http"    // 自建模型 HTTP 接口
+	ClassifierProviderOpenAI  ClassifierProvider = "openai"  // OpenAI 兼容的 Chat Completions 接口
+)
+
+// 可自动应用的分类字段
+const (
+	ClassificationFieldType     = "type"
+	ClassificationFieldPriority = "priority"
+	ClassificationFieldCategory = "category"
+)
+
+// TicketClassificationRule 关键词分类规则，标题或描述命中任一关键词时给出规则中设置的字段
+type TicketClassificationRule struct {
+	Name       string         `json:"name"`
+	Keywords   []string       `json:"keywords"`
+	Type       TicketType     `json:"type,omitempty"`
+	Priority   TicketPriority `json:"priority,omitempty"`
+	CategoryID *uint          `json:"category_id,omitempty"`
+}
+
+// TicketClassificationConfig 工单自动分类配置。
+// 置信度不低于 AutoApplyThreshold 的字段直接写入工单，介于 SuggestThreshold 与 AutoApplyThreshold 之间的作为建议等待坐席确认
+type TicketClassificationConfig struct {
+	Enabled            bool                       `json:"enabled"`
+	Provider           ClassifierProvider         `json:"provider"`
+	Endpoint           string                     `json:"endpoint,omitempty"` // http 必填；openai 为空时使用 https://api.openai.com/v1
+	APIKey             string                     `json:"api_key,omitempty"`  // 以 Bearer 令牌发送，接口中不返回明文
+	Model              string                     `json:"model,omitempty"`    // openai 使用的模型名称
+	TimeoutSeconds     int                        `json:"timeout_seconds"`
+	AutoApplyThreshold float64                    `json:"auto_apply_threshold"`
+	SuggestThreshold   float64                    `json:"suggest_threshold"`
+	ApplyFields        []string                   `json:"apply_fields"` // 允许自动应用的字段：type、priority、category
+	Rules              []TicketClassificationRule `json:"rules"`        // keyword 服务商使用的规则
+}
+
+// GetDefaultTicketClassificationConfig 获取默认分类配置（默认关闭，使用内置关键词规则）
+func GetDefaultTicketClassificationConfig() *TicketClassificationConfig {
+	return &TicketClassificationConfig{
+		Provider:           ClassifierProviderKeyword,
+		TimeoutSeconds:     10,
+		AutoApplyThreshold: 0.8,
+		SuggestThreshold:   0.4,
+		ApplyFields:        []string{ClassificationFieldType, ClassificationFieldPriority},
+		Rules: []TicketClassificationRule{
+			{Name: "故障", Keywords: []string{"bug", "error", "crash", "fail", "报错", "崩溃", "故障"}, Type: TicketTypeIncident},
+			{Name: "需求", Keywords: []string{"feature", "enhancement", "improvement", "新功能", "需求"}, Type: TicketTypeRequest},
+			{Name: "咨询", Keywords: []string{"help", "question", "how to", "guidance", "咨询", "如何"}, Type: TicketTypeConsultation},
+			{Name: "紧急", Keywords: []string{"urgent", "critical", "emergency", "asap", "immediately", "紧急"}, Priority: TicketPriorityHigh},
+		},
+	}
+}
+
+// Validate 校验分类配置
+func (c *TicketClassificationConfig) Validate() error {
+	switch c.Provider {
+	case ClassifierProviderKeyword:
+	case ClassifierProviderHTTP, ClassifierProviderOpenAI:
+		if c.Provider == ClassifierProviderHTTP && c.Endpoint == "" {
+			return fmt.Errorf("endpoint is required for http provider")
+		}
+		if c.Endpoint != "" {
+			parsed, err := url.Parse(c.Endpoint)
+			if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+				return fmt.Errorf("endpoint must be an http(s) url")
+			}
+		}
+		if c.Provider == ClassifierProviderOpenAI && (c.APIKey == "" || c.Model == "") {
+			return fmt.Errorf("api_key and model are required for openai provider")
+		}
+	default:
+		return fmt.Errorf("invalid provider: %s", c.Provider)
+	}
+	if c.TimeoutSeconds < 1 || c.TimeoutSeconds > 60 {
+		return fmt.Errorf("timeout_seconds must be between 1 and 60")
+	}
+	if c.SuggestThreshold < 0 || c.SuggestThreshold > 1 || c.AutoApplyThreshold < 0 || c.AutoApplyThreshold > 1 {
+		return fmt.Errorf("thresholds must be between 0 and 1")
+	}
+	if c.SuggestThreshold > c.AutoApplyThreshold {
+		return fmt.Errorf("suggest_threshold must not exceed auto_apply_threshold")
+	}
+	for _, field := range c.ApplyFields {
+		switch field {
+		case ClassificationFieldType, ClassificationFieldPriority, ClassificationFieldCategory:
+		default:
+			return fmt.Errorf("invalid apply field: %s", field)
+		}
+	}
+	if len(c.Rules) > 200 {
+		return fmt.Errorf("at most 200 rules are allowed")
+	}
+	for i := range c.Rules {
+		rule := &c.Rules[i]
+		keywords := rule.Keywords[:0]
+		for _, keyword := range rule.Keywords {
+			if keyword = strings.ToLower(strings.TrimSpace(keyword)); keyword != "" {
+				keywords = append(keywords, keyword)
+			}
+		}
+		rule.Keywords = keywords
+		if len(rule.Keywords) == 0 {
+			return fmt.Errorf("rule %d: keywords are required", i+1)
+		}
+		if rule.Type == "" && rule.Priority == "" && rule.CategoryID == nil {
+			return fmt.Errorf("rule %d: at least one of type, priority or category_id is required", i+1)
+		}
+		if rule.Type != "" && !rule.Type.IsValid() {
+			return fmt.Errorf("rule %d: invalid type %s", i+1, rule.Type)
+		}
+		if rule.Priority != "" && !rule.Priority.IsValid() {
+			return fmt.Errorf("rule %d: invalid priority %s", i+1, rule.Priority)
+		}
+	}
+	return nil
+}
+
+// AppliesField 字段是否允许自动应用
+func (c *TicketClassificationConfig) AppliesField(field string) bool {
+	for _, f := range c.ApplyFields {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// ClassifiedValue 分类服务对单个字段给出的值及置信度（0~1）
+type ClassifiedValue struct {
+	Value      string  `json:"value"`
+	Confidence float64 `json:"confidence"`
+}
+
+// TicketClassificationResult 分类服务返回的结果，未识别的字段为空。
+// category 可以是分类ID、名称或 slug；language 为 BCP 47 语言代码
+type TicketClassificationResult struct {
+	Type     *ClassifiedValue `json:"type,omitempty"`
+	Priority *ClassifiedValue `json:"priority,omitempty"`
+	Category *ClassifiedValue `json:"category,omitempty"`
+	Language *ClassifiedValue `json:"language,omitempty"`
+}
+
+// TicketClassificationStatus 分类记录状态
+type TicketClassificationStatus string
+
+const (
+	ClassificationStatusApplied   TicketClassificationStatus = "applied"   // 已自动应用，可能还有待确认的建议
+	ClassificationStatusSuggested TicketClassificationStatus = "suggested" // 等待坐席确认
+	ClassificationStatusAccepted  TicketClassificationStatus = "accepted"  // 坐席已采纳建议
+	ClassificationStatusRejected  TicketClassificationStatus = "rejected"  // 坐席已拒绝建议
+	ClassificationStatusNoMatch   TicketClassificationStatus = "no_match"  // 没有达到建议阈值的字段
+	ClassificationStatusFailed    TicketClassificationStatus = "failed"    // 调用分类服务失败
+)
+
+// TicketClassification 工单自动分类记录，用于展示建议及评估分类准确率。
+// 建议值只保存达到建议阈值的字段，AppliedFields 为已自动应用的字段（逗号分隔）
+type TicketClassification struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	TicketID  uint                       `json:"ticket_id" gorm:"not null;index"`
+	Provider  ClassifierProvider         `json:"provider" gorm:"size:20;not null"`
+	Status    TicketClassificationStatus `json:"status" gorm:"size:20;not null;index"`
+	LatencyMs int64                      `json:"latency_ms"`
+	Error     string                     `json:"error,omitempty" gorm:"type:text"`
+
+	SuggestedType       TicketType     `json:"suggested_type,omitempty" gorm:"size:20"`
+	TypeConfidence      float64        `json:"type_confidence"`
+	SuggestedPriority   TicketPriority `json:"suggested_priority,omitempty" gorm:"size:20"`
+	PriorityConfidence  float64        `json:"priority_confidence"`
+	SuggestedCategoryID *uint          `json:"suggested_category_id,omitempty"`
+	CategoryConfidence  float64        `json:"category_confidence"`
+	Language            string         `json:"language,omitempty" gorm:"size:10"`
+	LanguageConfidence  float64        `json:"language_confidence"`
+	AppliedFields       string         `json:"applied_fields" gorm:"size:100"`
+
+	ReviewedByID *uint      `json:"reviewed_by_id,omitempty"`
+	ReviewedAt   *time.Time `json:"reviewed_at,omitempty"`
+}
+
+// TableName 指定表名
+func (TicketClassification) TableName() string {
+	return "ticket_classifications"
+}
+
+// HasSuggestion 是否还有未应用的建议字段
+func (c *TicketClassification) HasSuggestion() bool {
+	return (c.SuggestedType != "" && !c.IsApplied(ClassificationFieldType)) ||
+		(c.SuggestedPriority != "" && !c.IsApplied(ClassificationFieldPriority)) ||
+		(c.SuggestedCategoryID != nil && !c.IsApplied(ClassificationFieldCategory))
+}
+
+// IsApplied 字段是否已自动应用
+func (c *TicketClassification) IsApplied(field string) bool {
+	for _, f := range strings.Split(c.AppliedFields, ",") {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// TicketClassificationTestRequest 用示例内容试运行分类，config 为空时使用已保存的配置
+type TicketClassificationTestRequest struct {
+	Title       string                      `json:"title" binding:"required,max=255"`
+	Description string                      `json:"description"`
+	Config      *TicketClassificationConfig `json:"config"`
+}
+
+// ClassificationFieldAccuracy 单个字段的分类准确率：以工单当前值为准
+type ClassificationFieldAccuracy struct {
+	Suggested int     `json:"suggested"`
+	Correct   int     `json:"correct"`
+	Accuracy  float64 `json:"accuracy"`
+}
+
+// TicketClassificationStats 分类结果统计
+type TicketClassificationStats struct {
+	Since        time.Time                               `json:"since"`
+	Total        int                                     `json:"total"`
+	ByStatus     map[TicketClassificationStatus]int      `json:"by_status"`
+	Fields       map[string]*ClassificationFieldAccuracy `json:"fields"`
+	AvgLatencyMs float64                                 `json:"avg_latency_ms"`
+}
@@ -1,7 +1,10 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 // CommentType 评论类型枚举
@@ -13,6 +16,24 @@ const (
 	CommentTypeSystem   CommentType = "system"   // 系统评论
 )
 
+// CommentVisibility 评论可见范围枚举
+type CommentVisibility string
+
+const (
+	CommentVisibilityPublic   CommentVisibility = "public"   // 客户可见
+	CommentVisibilityInternal CommentVisibility = "internal" // 仅内部人员可见
+	CommentVisibilityTeam     CommentVisibility = "team"     // 仅指定团队成员及管理员可见
+)
+
+// IsValidCommentVisibility 检查评论可见范围是否合法
+func IsValidCommentVisibility(visibility CommentVisibility) bool {
+	switch visibility {
+	case CommentVisibilityPublic, CommentVisibilityInternal, CommentVisibilityTeam:
+		return true
+	}
+	return false
+}
+
 // TicketComment 工单评论模型
 type TicketComment struct {
 	ID        uint       `json:"id" gorm:"primaryKey;autoIncrement"`
@@ -31,6 +52,10 @@ type TicketComment struct {
 	ContentType string      `json:"content_type" gorm:"size:20;default:'text'"` // text, html, markdown
 	Type        CommentType `json:"type" gorm:"size:20;not null;default:'public'" validate:"required,oneof=public internal system"`
 
+	// 可见范围，非 public 的评论不会出现在客户可访问的接口及邮件中
+	Visibility    CommentVisibility `json:"visibility" gorm:"size:20;not null;default:'public';index"`
+	VisibleTeamID *uint             `json:"visible_team_id,omitempty" gorm:"index"`
+
 	// 附件和元数据
 	Attachments string `json:"attachments" gorm:"type:text"` // JSON格式存储附件列表
 	Metadata    string `json:"metadata" gorm:"type:text"`    // JSON格式存储元数据
@@ -63,6 +88,12 @@ type TicketComment struct {
 	IsHelpful      *bool `json:"is_helpful,omitempty"` // 是否有帮助
 	HelpfulCount   int   `json:"helpful_count" gorm:"default:0"`
 	UnhelpfulCount int   `json:"unhelpful_count" gorm:"default:0"`
+
+	// 按查看者的翻译偏好附带的译文，不入库
+	Translation *CommentTranslation `json:"translation,omitempty" gorm:"-"`
+
+	// 发表时的敏感信息检测提示，不入库
+	PIIScan *PIIScanNotice `json:"pii_scan,omitempty" gorm:"-"`
 }
 
 // TableName 指定表名
@@ -70,6 +101,37 @@ func (TicketComment) TableName() string {
 	return "ticket_comments"
 }
 
+// BeforeSave 保存前同步可见范围与评论类型
+func (tc *TicketComment) BeforeSave(tx *gorm.DB) error {
+	tc.SyncVisibility()
+	return nil
+}
+
+// SyncVisibility 同步可见范围与评论类型：未设置可见范围时按类型推断，非公开评论的类型统一为 internal
+func (tc *TicketComment) SyncVisibility() {
+	if tc.Visibility == "" {
+		if tc.Type == CommentTypeInternal {
+			tc.Visibility = CommentVisibilityInternal
+		} else {
+			tc.Visibility = CommentVisibilityPublic
+		}
+	}
+	if tc.Visibility != CommentVisibilityPublic && tc.Type == CommentTypePublic {
+		tc.Type = CommentTypeInternal
+	}
+	if tc.Visibility == CommentVisibilityPublic && tc.Type == CommentTypeInternal {
+		tc.Type = CommentTypePublic
+	}
+	if tc.Visibility != CommentVisibilityTeam {
+		tc.VisibleTeamID = nil
+	}
+}
+
+// IsCustomerVisible 检查评论是否可对客户展示
+func (tc *TicketComment) IsCustomerVisible() bool {
+	return !tc.IsDeleted && tc.Visibility == CommentVisibilityPublic && tc.Type != CommentTypeInternal
+}
+
 // IsPublic 检查是否为公开评论
 func (tc *TicketComment) IsPublic() bool {
 	return tc.Type == CommentTypePublic
@@ -111,12 +173,31 @@ type TicketCommentCreateRequest struct {
 	Content      string                 `json:"content" validate:"required"`
 	ContentType  string                 `json:"content_type" validate:"omitempty,oneof=text html markdown"`
 	Type         CommentType            `json:"type" validate:"required,oneof=public internal system"`
+	Visibility   CommentVisibility      `json:"visibility" validate:"omitempty,oneof=public internal team"`
+	TeamID       *uint                  `json:"visible_team_id"`
 	ParentID     *uint                  `json:"parent_id"`
 	Attachments  []string               `json:"attachments"`
 	TimeSpent    *int                   `json:"time_spent" validate:"omitempty,min=0"`
 	BillableTime *int                   `json:"billable_time" validate:"omitempty,min=0"`
 	WorkType     string                 `json:"work_type"`
 	Metadata     map[string]interface{} `json:"metadata"`
+
+	IgnoreReplyLock bool     `json:"ignore_reply_lock"` // 其他客服正在回复时仍然提交
+	UploadIDs       []string `json:"upload_ids"`        // 预签名上传的文件，提交后转为评论附件；内容中的 upload://<upload_id> 替换为附件地址
+	AttachmentIDs   []uint   `json:"attachment_ids"`    // 已上传到工单、尚未关联评论的附件，提交后关联到该评论
+
+	// 引用回复：quote_text 为空时引用原评论全文，否则须为原评论中的一段原文
+	QuoteCommentID *uint  `json:"quote_comment_id"`
+	QuoteText      string `json:"quote_text"`
+}
+
+// CommentQuote 评论引用的原文信息，保存在评论元数据的 quote 字段中
+type CommentQuote struct {
+	CommentID  uint      `json:"comment_id"`
+	AuthorID   uint      `json:"author_id"`
+	AuthorName string    `json:"author_name"`
+	QuotedAt   time.Time `json:"quoted_at"` // 原评论的发表时间
+	Text       string    `json:"text"`
 }
 
 // TicketCommentUpdateRequest 评论更新请求
@@ -141,6 +222,8 @@ type TicketCommentResponse struct {
 	Content          string                  `json:"content"`
 	ContentType      string                  `json:"content_type"`
 	Type             CommentType             `json:"type"`
+	Visibility       CommentVisibility       `json:"visibility"`
+	VisibleTeamID    *uint                   `json:"visible_team_id,omitempty"`
 	Attachments      []string                `json:"attachments"`
 	Metadata         map[string]interface{}  `json:"metadata"`
 	IsEdited         bool                    `json:"is_edited"`
@@ -157,6 +240,8 @@ type TicketCommentResponse struct {
 	IsHelpful        *bool                   `json:"is_helpful"`
 	HelpfulCount     int                     `json:"helpful_count"`
 	UnhelpfulCount   int                     `json:"unhelpful_count"`
+	Quote            *CommentQuote           `json:"quote,omitempty"`
+	PIIScan          *PIIScanNotice          `json:"pii_scan,omitempty"` // 仅发表时返回
 }
 
 // ToResponse 转换为响应格式
@@ -169,6 +254,8 @@ func (tc *TicketComment) ToResponse() *TicketCommentResponse {
 		Content:          tc.Content,
 		ContentType:      tc.ContentType,
 		Type:             tc.Type,
+		Visibility:       tc.Visibility,
+		VisibleTeamID:    tc.VisibleTeamID,
 		IsEdited:         tc.IsEdited,
 		EditedAt:         tc.EditedAt,
 		IsDeleted:        tc.IsDeleted,
@@ -181,6 +268,7 @@ func (tc *TicketComment) ToResponse() *TicketCommentResponse {
 		IsHelpful:        tc.IsHelpful,
 		HelpfulCount:     tc.HelpfulCount,
 		UnhelpfulCount:   tc.UnhelpfulCount,
+		PIIScan:          tc.PIIScan,
 	}
 
 	// 处理关联用户
@@ -199,9 +287,57 @@ func (tc *TicketComment) ToResponse() *TicketCommentResponse {
 		}
 	}
 
-	// TODO: 解析JSON字段
-	// response.Attachments = parseAttachmentsFromJSON(tc.Attachments)
-	// response.Metadata = parseMetadataFromJSON(tc.Metadata)
+	if tc.Attachments != "" {
+		_ = json.Unmarshal([]byte(tc.Attachments), &response.Attachments)
+	}
+	if tc.Metadata != "" {
+		_ = json.Unmarshal([]byte(tc.Metadata), &response.Metadata)
+	}
+	response.Quote = tc.GetQuote()
 
 	return response
 }
+
+// GetQuote 解析评论元数据中的引用信息，未引用其他评论时返回 nil
+func (tc *TicketComment) GetQuote() *CommentQuote {
+	if tc.Metadata == "" {
+		return nil
+	}
+	var metadata struct {
+		Quote *CommentQuote `json:"quote"`
+	}
+	if err := json.Unmarshal([]byte(tc.Metadata), &metadata); err != nil {
+		return nil
+	}
+	return metadata.Quote
+}
+
+// CommentVisibilityUpdateRequest 评论可见范围变更请求
+type CommentVisibilityUpdateRequest struct {
+	Visibility CommentVisibility `json:"visibility" binding:"required"`
+	TeamID     *uint             `json:"visible_team_id"`
+}
+
+// CommentVisibilityDefaults 各角色发表评论时的默认可见范围
+type CommentVisibilityDefaults struct {
+	Roles map[string]CommentVisibility `json:"roles"`
+}
+
+// GetDefaultCommentVisibilityDefaults 获取默认的评论可见范围配置
+func GetDefaultCommentVisibilityDefaults() *CommentVisibilityDefaults {
+	return &CommentVisibilityDefaults{
+		Roles: map[string]CommentVisibility{
+			string(RoleAdmin):      CommentVisibilityPublic,
+			string(RoleSupervisor): CommentVisibilityPublic,
+			string(RoleAgent):      CommentVisibilityPublic,
+		},
+	}
+}
+
+// For 获取角色的默认可见范围，未配置时为 public
+func (d *CommentVisibilityDefaults) For(role string) CommentVisibility {
+	if visibility, ok := d.Roles[role]; ok && IsValidCommentVisibility(visibility) {
+		return visibility
+	}
+	return CommentVisibilityPublic
+}
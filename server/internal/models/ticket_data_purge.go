@@ -0,0 +1,55 @@
+package models
+
+import "time"
+
+// TicketPurgeMode 工单客户数据删除方式
+type TicketPurgeMode string
+
+const (
+	// TicketPurgeModeAnonymize 匿名化：清空客户联系信息，替换文本中的邮箱、姓名、电话，删除客户上传的附件，保留工单内容
+	TicketPurgeModeAnonymize TicketPurgeMode = "anonymize"
+	// TicketPurgeModePurge 清除：在匿名化基础上删除客户评论、全部附件、原始邮件和通话记录，工单描述替换为占位文本
+	TicketPurgeModePurge TicketPurgeMode = "purge"
+)
+
+// AuditActionTicketPIIPurge 删除证书在审计日志中的操作名
+const AuditActionTicketPIIPurge = "ticket_pii_purge"
+
+// TicketPurgeRequest 工单客户数据删除请求
+type TicketPurgeRequest struct {
+	Mode   TicketPurgeMode `json:"mode" binding:"required,oneof=anonymize purge"`
+	Reason string          `json:"reason" binding:"required,max=500"` // 删除依据，如数据主体请求编号
+}
+
+// TicketLegalHoldRequest 设置或解除工单法律保留
+type TicketLegalHoldRequest struct {
+	Hold   bool   `json:"hold"`
+	Reason string `json:"reason" binding:"max=255"` // 设置保留时必填
+}
+
+// TicketPurgeCounts 删除证书中记录的处理数量
+type TicketPurgeCounts struct {
+	CustomerFields     int `json:"customer_fields"`     // 清空的客户联系信息字段
+	CommentsRedacted   int `json:"comments_redacted"`   // 替换了个人信息的评论
+	CommentsDeleted    int `json:"comments_deleted"`    // 删除的客户评论
+	AttachmentsDeleted int `json:"attachments_deleted"` // 删除的附件
+	EmailsRedacted     int `json:"emails_redacted"`     // 匿名化的原始邮件
+	EmailsDeleted      int `json:"emails_deleted"`      // 删除的原始邮件
+	CallLogs           int `json:"call_logs"`           // 清除号码或删除的通话记录
+	HistoryRedacted    int `json:"history_redacted"`    // 替换了个人信息的工单历史
+}
+
+// TicketDeletionCertificate 工单客户数据删除证书，签名覆盖除 signature 外的全部字段，写入审计日志
+type TicketDeletionCertificate struct {
+	CertificateID string            `json:"certificate_id"`
+	TicketID      uint              `json:"ticket_id"`
+	TicketNumber  string            `json:"ticket_number"`
+	Mode          TicketPurgeMode   `json:"mode"`
+	Reason        string            `json:"reason"`
+	SubjectHash   string            `json:"subject_hash,omitempty"` // 客户邮箱（小写）的 SHA-256，用于核对删除请求而不保留邮箱
+	Counts        TicketPurgeCounts `json:"counts"`
+	PerformedByID uint              `json:"performed_by_id"`
+	PerformedAt   time.Time         `json:"performed_at"`
+	Algorithm     string            `json:"algorithm"`
+	Signature     string            `json:"signature"`
+}
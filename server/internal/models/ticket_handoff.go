@@ -0,0 +1,167 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TicketHandoffTrigger 交接的触发方式
+type TicketHandoffTrigger string
+
+const (
+	TicketHandoffManual    TicketHandoffTrigger = "manual"    // 手动执行
+	TicketHandoffScheduled TicketHandoffTrigger = "scheduled" // 按交接计划定时执行
+)
+
+// DefaultTicketHandoffTemplate 默认交接摘要模板，占位符见 TicketHandoffSchedule.NoteTemplate
+const DefaultTicketHandoffTemplate = `交接摘要：{{from_team}} → {{to_team}}
+工单：{{ticket_number}} {{title}}
+状态 / 优先级：{{status}} / {{priority}}
+原处理人：{{previous_assignee}}
+最近更新：{{last_activity}}
+
+待确认问题：
+{{open_questions}}
+
+下一步：
+{{next_steps}}`
+
+// TicketHandoffSchedule 跨时区交接计划：按 cron 表达式在换班时将一个团队中符合条件的未完成工单
+// 转交给另一个团队，并为每个工单生成交接摘要
+type TicketHandoffSchedule struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+
+	Name       string `json:"name" gorm:"size:100;not null"`
+	FromTeamID uint   `json:"from_team_id" gorm:"not null;index"`
+	ToTeamID   uint   `json:"to_team_id" gorm:"not null;index"`
+
+	Filter     string           `json:"-" gorm:"type:text"` // 附加筛选条件 JSON
+	FilterNode *TicketQueryNode `json:"filter" gorm:"-"`    // 与 /api/tickets/query 相同的条件树，为空时转交全部未完成工单
+
+	// CronExpr 换班时间，支持 CRON_TZ=Asia/Shanghai 前缀指定时区
+	CronExpr string `json:"cron_expr" gorm:"size:100;not null"`
+	// NoteTemplate 交接摘要模板，为空时使用默认模板。占位符：{{ticket_number}} {{title}} {{status}} {{priority}}
+	// {{from_team}} {{to_team}} {{previous_assignee}} {{last_activity}} {{open_questions}} {{next_steps}}
+	NoteTemplate  string `json:"note_template" gorm:"type:text"`
+	OpenQuestions string `json:"open_questions" gorm:"type:text"` // 写入每个工单摘要的固定待确认问题
+	NextSteps     string `json:"next_steps" gorm:"type:text"`     // 写入每个工单摘要的固定下一步，未完成的检查项会自动追加
+	IsActive      bool   `json:"is_active" gorm:"default:true;index"`
+
+	NextRunAt   *time.Time `json:"next_run_at,omitempty" gorm:"index"`
+	LastRunAt   *time.Time `json:"last_run_at,omitempty"`
+	CreatedByID uint       `json:"created_by_id" gorm:"not null"`
+
+	FromTeam *Team `json:"from_team,omitempty" gorm:"foreignKey:FromTeamID"`
+	ToTeam   *Team `json:"to_team,omitempty" gorm:"foreignKey:ToTeamID"`
+}
+
+// TableName 指定表名
+func (TicketHandoffSchedule) TableName() string {
+	return "ticket_handoff_schedules"
+}
+
+// BeforeSave GORM钩子 - 序列化筛选条件
+func (s *TicketHandoffSchedule) BeforeSave(tx *gorm.DB) error {
+	if s.FilterNode == nil {
+		s.Filter = ""
+		return nil
+	}
+	data, err := json.Marshal(s.FilterNode)
+	if err != nil {
+		return err
+	}
+	s.Filter = string(data)
+	return nil
+}
+
+// AfterFind GORM钩子 - 反序列化筛选条件
+func (s *TicketHandoffSchedule) AfterFind(tx *gorm.DB) error {
+	s.FilterNode = nil
+	if s.Filter != "" {
+		node := &TicketQueryNode{}
+		if err := json.Unmarshal([]byte(s.Filter), node); err == nil {
+			s.FilterNode = node
+		}
+	}
+	return nil
+}
+
+// TicketHandoff 一次交接的执行记录
+type TicketHandoff struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime;index"`
+
+	ScheduleID   *uint                `json:"schedule_id,omitempty" gorm:"index"`
+	FromTeamID   uint                 `json:"from_team_id" gorm:"not null;index"`
+	ToTeamID     uint                 `json:"to_team_id" gorm:"not null;index"`
+	Trigger      TicketHandoffTrigger `json:"trigger" gorm:"size:20;not null"`
+	TicketCount  int                  `json:"ticket_count" gorm:"default:0"`
+	ExecutedByID *uint                `json:"executed_by_id,omitempty"` // 定时执行时为空
+
+	Items []TicketHandoffItem `json:"items,omitempty" gorm:"foreignKey:HandoffID"`
+}
+
+// TableName 指定表名
+func (TicketHandoff) TableName() string {
+	return "ticket_handoffs"
+}
+
+// TicketHandoffItem 交接中转交的单个工单及其交接摘要评论
+type TicketHandoffItem struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+
+	HandoffID          uint   `json:"handoff_id" gorm:"not null;index"`
+	TicketID           uint   `json:"ticket_id" gorm:"not null;index"`
+	TicketNumber       string `json:"ticket_number" gorm:"size:50"`
+	PreviousAssigneeID *uint  `json:"previous_assignee_id,omitempty"`
+	CommentID          uint   `json:"comment_id"`
+}
+
+// TableName 指定表名
+func (TicketHandoffItem) TableName() string {
+	return "ticket_handoff_items"
+}
+
+// TicketHandoffScheduleRequest 创建或更新交接计划的请求
+type TicketHandoffScheduleRequest struct {
+	Name          string           `json:"name" binding:"required,max=100"`
+	FromTeamID    uint             `json:"from_team_id" binding:"required"`
+	ToTeamID      uint             `json:"to_team_id" binding:"required"`
+	Filter        *TicketQueryNode `json:"filter"`
+	CronExpr      string           `json:"cron_expr" binding:"required,max=100"`
+	NoteTemplate  string           `json:"note_template"`
+	OpenQuestions string           `json:"open_questions"`
+	NextSteps     string           `json:"next_steps"`
+	IsActive      *bool            `json:"is_active"`
+}
+
+// TicketHandoffRequest 立即执行交接或预览待交接工单的请求
+type TicketHandoffRequest struct {
+	FromTeamID    uint             `json:"from_team_id" binding:"required"`
+	ToTeamID      uint             `json:"to_team_id"` // 预览时可不填
+	Filter        *TicketQueryNode `json:"filter"`
+	NoteTemplate  string           `json:"note_template"`
+	OpenQuestions string           `json:"open_questions"`
+	NextSteps     string           `json:"next_steps"`
+}
+
+// TicketHandoffPreview 交接预览：将被转交的工单
+type TicketHandoffPreview struct {
+	Total   int64                      `json:"total"`
+	Tickets []TicketHandoffPreviewItem `json:"tickets"`
+}
+
+// TicketHandoffPreviewItem 预览中的工单概要
+type TicketHandoffPreviewItem struct {
+	ID           uint           `json:"id"`
+	TicketNumber string         `json:"ticket_number"`
+	Title        string         `json:"title"`
+	Status       TicketStatus   `json:"status"`
+	Priority     TicketPriority `json:"priority"`
+	AssignedToID *uint          `json:"assigned_to_id,omitempty"`
+}
@@ -26,6 +26,8 @@ const (
 	HistoryActionReject         HistoryAction = "reject"          // 拒绝
 	HistoryActionApprove        HistoryAction = "approve"         // 批准
 	HistoryActionSystem         HistoryAction = "system"          // 系统操作
+	HistoryActionCall           HistoryAction = "call"            // 电话通话记录
+	HistoryActionRedaction      HistoryAction = "redaction"       // 敏感信息检测与脱敏
 )
 
 // TicketHistory 工单历史记录模型
@@ -51,9 +53,9 @@ type TicketHistory struct {
 	NewValue  string `json:"new_value" gorm:"type:text"` // 新值
 
 	// 元数据
-	SourceIP  string `json:"source_ip" gorm:"size:45"`
-	UserAgent string `json:"user_agent" gorm:"size:500"`
-	Metadata  string `json:"metadata" gorm:"type:text"` // JSON格式存储元数据
+	SourceIP  string   `json:"source_ip" gorm:"size:45"`
+	UserAgent string   `json:"user_agent" gorm:"size:500"`
+	Metadata  JSONText `json:"metadata"` // JSON格式存储元数据，按存储方式为 TEXT 或 jsonb
 
 	// 关联记录
 	CommentID    *uint          `json:"comment_id" gorm:"index"` // 关联的评论ID
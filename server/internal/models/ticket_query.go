@@ -0,0 +1,37 @@
+package models
+
+// TicketQueryLogic 条件组的组合方式
+type TicketQueryLogic string
+
+const (
+	TicketQueryAnd TicketQueryLogic = "and"
+	TicketQueryOr  TicketQueryLogic = "or"
+)
+
+// TicketQueryNode 工单查询条件树的节点：设置 field 的是单个条件，否则是条件组。
+// 单个条件示例：{"field": "priority", "operator": "in", "value": ["high", "urgent"]}；
+// 条件组示例：{"logic": "or", "conditions": [...]}，条件组可以嵌套
+type TicketQueryNode struct {
+	// 单个条件
+	Field    string      `json:"field,omitempty"`    // 工单字段、tags 或 custom_fields.<键>
+	Operator string      `json:"operator,omitempty"` // eq、ne、in、not_in、gt、gte、lt、lte、between、contains、not_contains、starts_with、is_null、is_not_null、exists、not_exists
+	Value    interface{} `json:"value,omitempty"`    // 日期字段支持 now-7d、today+1d 等相对时间
+
+	// 条件组
+	Logic      TicketQueryLogic  `json:"logic,omitempty"` // 为空时为 and
+	Conditions []TicketQueryNode `json:"conditions,omitempty"`
+}
+
+// IsGroup 是否为条件组
+func (n *TicketQueryNode) IsGroup() bool {
+	return n.Field == ""
+}
+
+// TicketQueryRequest 高级工单查询请求
+type TicketQueryRequest struct {
+	Filter    TicketQueryNode `json:"filter"`
+	Page      int             `json:"page"`
+	PageSize  int             `json:"page_size"`
+	SortBy    string          `json:"sort_by"`    // 可排序的工单字段，默认 created_at
+	SortOrder string          `json:"sort_order"` // asc 或 desc，默认 desc
+}
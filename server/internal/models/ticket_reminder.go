@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// TicketReminderStatus 工单提醒状态
+type TicketReminderStatus string
+
+const (
+	TicketReminderStatusPending   TicketReminderStatus = "pending"   // 等待提醒（周期提醒在工单完结前保持此状态）
+	TicketReminderStatusSent      TicketReminderStatus = "sent"      // 单次提醒已发送
+	TicketReminderStatusCompleted TicketReminderStatus = "completed" // 周期提醒因工单已完结而结束
+	TicketReminderStatusCancelled TicketReminderStatus = "cancelled" // 已取消
+)
+
+// TicketReminder 坐席为自己或同事设置的工单提醒，到时按接收人的通知偏好发送；
+// RepeatDays 大于 0 时每隔 N 天重复提醒，直到工单解决或关闭
+type TicketReminder struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+
+	TicketID    uint                 `json:"ticket_id" gorm:"not null;index"`
+	UserID      uint                 `json:"user_id" gorm:"not null;index"` // 接收提醒的坐席
+	CreatedByID uint                 `json:"created_by_id" gorm:"not null"`
+	Note        string               `json:"note" gorm:"size:500"`
+	RemindAt    time.Time            `json:"remind_at" gorm:"not null;index"` // 下一次提醒时间
+	RepeatDays  int                  `json:"repeat_days" gorm:"default:0"`
+	Status      TicketReminderStatus `json:"status" gorm:"size:20;not null;default:'pending';index"`
+
+	SentCount   int        `json:"sent_count" gorm:"default:0"`
+	LastSentAt  *time.Time `json:"last_sent_at,omitempty"`
+	CancelledAt *time.Time `json:"cancelled_at,omitempty"`
+
+	Ticket *Ticket `json:"ticket,omitempty" gorm:"foreignKey:TicketID"`
+	User   *User   `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
+
+// TableName 指定表名
+func (TicketReminder) TableName() string {
+	return "ticket_reminders"
+}
+
+// TicketReminderRequest 创建工单提醒请求
+type TicketReminderRequest struct {
+	UserID     *uint     `json:"user_id"` // 提醒的同事，默认为当前用户
+	RemindAt   time.Time `json:"remind_at" binding:"required"`
+	Note       string    `json:"note" binding:"max=500"`
+	RepeatDays int       `json:"repeat_days"` // 每隔 N 天重复提醒直到工单完结，0 表示只提醒一次
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TicketSummaryProvider 工单摘要生成服务提供方
+type TicketSummaryProvider string
+
+const (
+	// TicketSummaryProviderOpenAI OpenAI Chat Completions 接口，兼容该接口的自建服务（vLLM、Ollama 等）同样使用此类型
+	TicketSummaryProviderOpenAI    TicketSummaryProvider = "openai"
+	TicketSummaryProviderAnthropic TicketSummaryProvider = "anthropic" // Anthropic Messages 接口
+)
+
+// TicketSummaryTrigger 摘要的生成方式
+type TicketSummaryTrigger string
+
+const (
+	TicketSummaryManual  TicketSummaryTrigger = "manual"  // 客服手动生成
+	TicketSummaryHandoff TicketSummaryTrigger = "handoff" // 换班交接后自动生成
+)
+
+// TicketSummary 模型生成的工单摘要及建议的下一步操作，保留历次结果及所用模型
+type TicketSummary struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+
+	TicketID       uint                  `json:"ticket_id" gorm:"not null;index"`
+	Trigger        TicketSummaryTrigger  `json:"trigger" gorm:"size:20;not null"`
+	Summary        string                `json:"summary" gorm:"type:text"`
+	NextActions    string                `json:"-" gorm:"type:text"` // 建议操作JSON
+	NextActionList []string              `json:"next_actions" gorm:"-"`
+	Provider       TicketSummaryProvider `json:"provider" gorm:"size:20"`
+	Model          string                `json:"model" gorm:"size:100"`         // 服务商返回的实际模型版本
+	PromptVersion  string                `json:"prompt_version" gorm:"size:20"` // 提示词版本，提示词调整后旧摘要不再复用
+	CommentCount   int                   `json:"comment_count"`                 // 生成时纳入的评论数
+	InputTokens    int                   `json:"input_tokens"`
+	OutputTokens   int                   `json:"output_tokens"`
+	SourceHash     string                `json:"-" gorm:"size:64"`        // 工单内容、模型及提示词的 sha256，内容未变化时复用
+	CreatedByID    *uint                 `json:"created_by_id,omitempty"` // 自动生成时为空
+}
+
+// TableName 指定表名
+func (TicketSummary) TableName() string {
+	return "ticket_summaries"
+}
+
+// BeforeSave GORM钩子 - 将建议操作序列化为JSON字符串
+func (s *TicketSummary) BeforeSave(tx *gorm.DB) error {
+	if s.NextActionList == nil {
+		s.NextActionList = []string{}
+	}
+	data, err := json.Marshal(s.NextActionList)
+	if err != nil {
+		return err
+	}
+	s.NextActions = string(data)
+	return nil
+}
+
+// AfterFind GORM钩子 - 反序列化建议操作
+func (s *TicketSummary) AfterFind(tx *gorm.DB) error {
+	s.NextActionList = []string{}
+	if s.NextActions != "" {
+		_ = json.Unmarshal([]byte(s.NextActions), &s.NextActionList)
+	}
+	return nil
+}
+
+// TicketSummarizeRequest 生成工单摘要请求
+type TicketSummarizeRequest struct {
+	Force bool `json:"force"` // 工单内容未变化时也重新生成
+}
@@ -48,3 +48,31 @@ func TestParseCustomFieldsHandlesInvalidJSON(t *testing.T) {
 		t.Fatalf("expected empty map, got %v", result)
 	}
 }
+
+func TestDiffTicketFieldsAndFieldFilters(t *testing.T) {
+	assignee := uint(5)
+	before := &Ticket{Title: "login bug", Status: TicketStatusOpen, Priority: TicketPriorityHigh}
+	after := *before
+	after.Status = TicketStatusInProgress
+	after.AssignedToID = &assignee
+
+	changes := DiffTicketFields(before, &after)
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changes, got %d: %+v", len(changes), changes)
+	}
+	if changes[0].Field != "status" || changes[0].Before != TicketStatusOpen || changes[0].After != TicketStatusInProgress {
+		t.Fatalf("unexpected status change: %+v", changes[0])
+	}
+	if changes[1].Field != "assigned_to_id" {
+		t.Fatalf("unexpected assignee change: %+v", changes[1])
+	}
+
+	config := &WebhookConfig{FieldFiltersObj: []string{"priority"}}
+	if config.MatchesFieldChanges([]string{"status", "assigned_to_id"}) {
+		t.Fatalf("expected priority-only filter to skip status changes")
+	}
+	config.FieldFiltersObj = []string{"assigned_to_id"}
+	if !config.MatchesFieldChanges([]string{"status", "assigned_to_id"}) {
+		t.Fatalf("expected assignee filter to match")
+	}
+}
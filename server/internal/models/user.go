@@ -1,6 +1,9 @@
 package models
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
 	"time"
 )
 
@@ -56,7 +59,8 @@ type User struct {
 	PhoneVerified    bool       `json:"phone_verified" gorm:"default:false"`
 	PhoneVerifiedAt  *time.Time `json:"phone_verified_at,omitempty"`
 	TwoFactorEnabled bool       `json:"two_factor_enabled" gorm:"default:false"`
-	TwoFactorSecret  string     `json:"-" gorm:"size:255"` // TOTP密钥
+	TwoFactorSecret  string     `json:"-" gorm:"size:255"`                                           // TOTP密钥
+	SMSOTPEnabled    bool       `json:"sms_otp_enabled" gorm:"column:sms_otp_enabled;default:false"` // 登录时可使用短信验证码作为第二因子，需先验证手机号
 	BackupCodes      string     `json:"-" gorm:"type:text"`
 
 	// 登录相关
@@ -192,6 +196,7 @@ type UserResponse struct {
 	EmailVerified    bool       `json:"email_verified"`
 	PhoneVerified    bool       `json:"phone_verified"`
 	TwoFactorEnabled bool       `json:"two_factor_enabled"`
+	SMSOTPEnabled    bool       `json:"sms_otp_enabled"`
 	LastLoginAt      *time.Time `json:"last_login_at"`
 	Department       string     `json:"department"`
 	JobTitle         string     `json:"job_title"`
@@ -201,8 +206,21 @@ type UserResponse struct {
 	TicketsResolved  int        `json:"tickets_resolved"`
 }
 
+// IdenticonURLPrefix 默认头像（identicon）访问路径前缀
+const IdenticonURLPrefix = "/api/avatars/identicon/"
+
+// DefaultAvatarURL 未上传头像的用户使用的默认头像地址，种子由用户ID派生，不暴露邮箱等信息
+func DefaultAvatarURL(userID uint) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("user:%d", userID)))
+	return IdenticonURLPrefix + hex.EncodeToString(sum[:])[:16] + ".png"
+}
+
 // ToResponse 转换为响应格式
 func (u *User) ToResponse() *UserResponse {
+	avatar := u.Avatar
+	if avatar == "" && u.ID != 0 {
+		avatar = DefaultAvatarURL(u.ID)
+	}
 	return &UserResponse{
 		ID:               u.ID,
 		CreatedAt:        u.CreatedAt,
@@ -213,7 +231,7 @@ func (u *User) ToResponse() *UserResponse {
 		FirstName:        u.FirstName,
 		LastName:         u.LastName,
 		DisplayName:      u.DisplayName,
-		Avatar:           u.Avatar,
+		Avatar:           avatar,
 		Timezone:         u.Timezone,
 		Language:         u.Language,
 		Role:             u.Role,
@@ -221,6 +239,7 @@ func (u *User) ToResponse() *UserResponse {
 		EmailVerified:    u.EmailVerified,
 		PhoneVerified:    u.PhoneVerified,
 		TwoFactorEnabled: u.TwoFactorEnabled,
+		SMSOTPEnabled:    u.SMSOTPEnabled,
 		LastLoginAt:      u.LastLoginAt,
 		Department:       u.Department,
 		JobTitle:         u.JobTitle,
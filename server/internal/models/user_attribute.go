@@ -0,0 +1,120 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UserFieldType 用户自定义字段类型
+type UserFieldType string
+
+const (
+	UserFieldTypeText        UserFieldType = "text"
+	UserFieldTypeNumber      UserFieldType = "number"
+	UserFieldTypeBool        UserFieldType = "bool"
+	UserFieldTypeDate        UserFieldType = "date"         // YYYY-MM-DD
+	UserFieldTypeSelect      UserFieldType = "select"       // 单选，值必须在 options 中
+	UserFieldTypeMultiSelect UserFieldType = "multi_select" // 多选，如持有的认证
+)
+
+// UserFieldDefinition 管理员定义的用户自定义字段，如成本中心、班次、认证
+type UserFieldDefinition struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+
+	Key         string        `json:"key" gorm:"size:64;not null;uniqueIndex"` // 字段标识，用于过滤参数 field.<key> 及自动化条件
+	Label       string        `json:"label" gorm:"size:100;not null"`
+	Type        UserFieldType `json:"type" gorm:"size:20;not null"`
+	Options     string        `json:"-" gorm:"type:text"` // 选项JSON
+	OptionList  []string      `json:"options" gorm:"-"`
+	Description string        `json:"description,omitempty" gorm:"size:500"`
+	IsActive    bool          `json:"is_active" gorm:"default:true;index"`
+	SortOrder   int           `json:"sort_order" gorm:"default:0"`
+}
+
+// TableName 指定表名
+func (UserFieldDefinition) TableName() string {
+	return "user_field_definitions"
+}
+
+// BeforeSave GORM钩子 - 将选项序列化为JSON字符串
+func (d *UserFieldDefinition) BeforeSave(tx *gorm.DB) error {
+	if d.OptionList == nil {
+		d.OptionList = []string{}
+	}
+	data, err := json.Marshal(d.OptionList)
+	if err != nil {
+		return err
+	}
+	d.Options = string(data)
+	return nil
+}
+
+// AfterFind GORM钩子 - 反序列化选项
+func (d *UserFieldDefinition) AfterFind(tx *gorm.DB) error {
+	d.OptionList = parseStringSliceFromJSON(d.Options)
+	return nil
+}
+
+// UserFieldValue 用户自定义字段的值，统一按文本保存：多选为JSON数组，布尔为 true/false
+type UserFieldValue struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"autoUpdateTime"`
+
+	UserID  uint   `json:"user_id" gorm:"not null;uniqueIndex:idx_user_field_value"`
+	FieldID uint   `json:"field_id" gorm:"not null;uniqueIndex:idx_user_field_value;index"`
+	Value   string `json:"value" gorm:"type:text"`
+}
+
+// TableName 指定表名
+func (UserFieldValue) TableName() string {
+	return "user_field_values"
+}
+
+// UserSkill 坐席技能标签，供按技能分配及自动化条件使用
+type UserSkill struct {
+	ID        uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	CreatedAt time.Time `json:"created_at" gorm:"autoCreateTime"`
+
+	UserID uint   `json:"user_id" gorm:"not null;uniqueIndex:idx_user_skill"`
+	Skill  string `json:"skill" gorm:"size:50;not null;uniqueIndex:idx_user_skill;index"` // 小写标识，如 billing、lang-fr
+	Level  int    `json:"level" gorm:"not null;default:1"`                                // 熟练度 1-5
+}
+
+// TableName 指定表名
+func (UserSkill) TableName() string {
+	return "user_skills"
+}
+
+// UserFieldDefinitionRequest 创建或更新用户自定义字段
+type UserFieldDefinitionRequest struct {
+	Key         string        `json:"key" binding:"required,max=64"`
+	Label       string        `json:"label" binding:"required,max=100"`
+	Type        UserFieldType `json:"type" binding:"required,oneof=text number bool date select multi_select"`
+	Options     []string      `json:"options"`
+	Description string        `json:"description" binding:"max=500"`
+	IsActive    *bool         `json:"is_active"`
+	SortOrder   int           `json:"sort_order"`
+}
+
+// UserSkillInput 设置坐席技能
+type UserSkillInput struct {
+	Skill string `json:"skill" binding:"required,max=50"`
+	Level int    `json:"level"` // 默认 1
+}
+
+// UserAttributes 用户的自定义字段值及技能
+type UserAttributes struct {
+	UserID uint                   `json:"user_id"`
+	Fields map[string]interface{} `json:"fields"` // 按字段类型返回：数字、布尔、字符串或字符串数组
+	Skills []UserSkill            `json:"skills"`
+}
+
+// SkillSummary 技能及拥有该技能的坐席数
+type SkillSummary struct {
+	Skill  string `json:"skill"`
+	Agents int64  `json:"agents"`
+}
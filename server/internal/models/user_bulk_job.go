@@ -0,0 +1,103 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UserBulkAction 用户批量操作类型
+type UserBulkAction string
+
+const (
+	UserBulkActionChangeRole         UserBulkAction = "change_role"          // 修改角色
+	UserBulkActionForcePasswordReset UserBulkAction = "force_password_reset" // 强制重置密码：作废当前密码并注销所有会话
+)
+
+// UserBulkJobStatus 批量操作任务状态
+type UserBulkJobStatus string
+
+const (
+	UserBulkJobPending   UserBulkJobStatus = "pending"   // 等待执行
+	UserBulkJobRunning   UserBulkJobStatus = "running"   // 执行中
+	UserBulkJobCompleted UserBulkJobStatus = "completed" // 已完成（可能包含失败的用户）
+	UserBulkJobFailed    UserBulkJobStatus = "failed"    // 任务中断
+)
+
+// UserBulkResult 单个用户的执行结果
+type UserBulkResult struct {
+	UserID  uint   `json:"user_id"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// UserBulkJob 后台执行的用户批量操作任务
+type UserBulkJob struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Action     UserBulkAction    `json:"action" gorm:"size:30;not null"`
+	Role       UserRole          `json:"role,omitempty" gorm:"size:20"` // change_role 的目标角色
+	UserIDs    string            `json:"-" gorm:"type:text"`            // 目标用户ID JSON
+	UserIDList []uint            `json:"user_ids" gorm:"-"`
+	Status     UserBulkJobStatus `json:"status" gorm:"size:20;not null;default:'pending';index"`
+
+	Total      int              `json:"total"`
+	Succeeded  int              `json:"succeeded"`
+	Failed     int              `json:"failed"`
+	Results    string           `json:"-" gorm:"type:text"` // 逐个用户的执行结果JSON
+	ResultList []UserBulkResult `json:"results" gorm:"-"`
+	Error      string           `json:"error,omitempty" gorm:"size:500"`
+
+	CreatedByID uint       `json:"created_by_id" gorm:"index"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+}
+
+// TableName 指定表名
+func (UserBulkJob) TableName() string {
+	return "user_bulk_jobs"
+}
+
+// BeforeSave GORM钩子 - 序列化目标用户与执行结果
+func (j *UserBulkJob) BeforeSave(tx *gorm.DB) error {
+	if j.UserIDList == nil {
+		j.UserIDList = []uint{}
+	}
+	if j.ResultList == nil {
+		j.ResultList = []UserBulkResult{}
+	}
+	ids, err := json.Marshal(j.UserIDList)
+	if err != nil {
+		return err
+	}
+	results, err := json.Marshal(j.ResultList)
+	if err != nil {
+		return err
+	}
+	j.UserIDs = string(ids)
+	j.Results = string(results)
+	return nil
+}
+
+// AfterFind GORM钩子 - 反序列化目标用户与执行结果
+func (j *UserBulkJob) AfterFind(tx *gorm.DB) error {
+	j.UserIDList = []uint{}
+	j.ResultList = []UserBulkResult{}
+	if j.UserIDs != "" {
+		_ = json.Unmarshal([]byte(j.UserIDs), &j.UserIDList)
+	}
+	if j.Results != "" {
+		_ = json.Unmarshal([]byte(j.Results), &j.ResultList)
+	}
+	return nil
+}
+
+// UserBulkJobRequest 创建用户批量操作请求
+type UserBulkJobRequest struct {
+	Action  UserBulkAction `json:"action" binding:"required,oneof=change_role force_password_reset"`
+	UserIDs []uint         `json:"user_ids" binding:"required,min=1,max=1000"`
+	Role    UserRole       `json:"role" binding:"omitempty,oneof=admin agent customer supervisor"`
+}
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"time"
+)
+
+// UserInvitationStatus 用户邀请状态
+type UserInvitationStatus string
+
+const (
+	UserInvitationPending  UserInvitationStatus = "pending"  // 等待接受（过期后查询时显示为 expired）
+	UserInvitationAccepted UserInvitationStatus = "accepted" // 已接受并创建账户
+	UserInvitationRevoked  UserInvitationStatus = "revoked"  // 管理员已撤销
+	UserInvitationExpired  UserInvitationStatus = "expired"  // 仅用于查询过滤与展示，不落库
+)
+
+// UserInvitation 管理员发出的账户邀请，被邀请人通过邮件中的链接设置密码后创建账户
+type UserInvitation struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	Email     string               `json:"email" gorm:"size:100;not null;index"`
+	Role      UserRole             `json:"role" gorm:"size:20;not null"`
+	Status    UserInvitationStatus `json:"status" gorm:"size:20;not null;default:'pending';index"`
+	TokenHash string               `json:"-" gorm:"size:64;uniqueIndex"` // 令牌的SHA-256哈希，原始令牌只出现在邮件中；重发时轮换
+	ExpiresAt time.Time            `json:"expires_at" gorm:"not null"`
+
+	InvitedByID uint       `json:"invited_by_id" gorm:"index"`
+	SendCount   int        `json:"send_count" gorm:"default:0"`
+	LastSentAt  *time.Time `json:"last_sent_at,omitempty"`
+
+	AcceptedAt     *time.Time `json:"accepted_at,omitempty"`
+	AcceptedUserID *uint      `json:"accepted_user_id,omitempty"`
+	RevokedAt      *time.Time `json:"revoked_at,omitempty"`
+}
+
+// TableName 指定表名
+func (UserInvitation) TableName() string {
+	return "user_invitations"
+}
+
+// IsExpired 待接受的邀请是否已过期
+func (i *UserInvitation) IsExpired(now time.Time) bool {
+	return i.Status == UserInvitationPending && !now.Before(i.ExpiresAt)
+}
+
+// DisplayStatus 展示用状态，过期的待接受邀请显示为 expired
+func (i *UserInvitation) DisplayStatus(now time.Time) UserInvitationStatus {
+	if i.IsExpired(now) {
+		return UserInvitationExpired
+	}
+	return i.Status
+}
+
+// UserInviteEntry 单个被邀请人
+type UserInviteEntry struct {
+	Email string   `json:"email" binding:"required,email"`
+	Role  UserRole `json:"role" binding:"required,oneof=admin agent customer supervisor"`
+}
+
+// UserInviteRequest 批量邀请请求
+type UserInviteRequest struct {
+	Invitations    []UserInviteEntry `json:"invitations" binding:"required,min=1,max=500,dive"`
+	ExpiresInHours int               `json:"expires_in_hours" binding:"omitempty,min=1,max=720"` // 为空时使用系统配置
+}
+
+// UserInviteResult 单个被邀请人的处理结果
+type UserInviteResult struct {
+	Email        string     `json:"email"`
+	Role         UserRole   `json:"role"`
+	Success      bool       `json:"success"`
+	Error        string     `json:"error,omitempty"`
+	InvitationID uint       `json:"invitation_id,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	EmailSent    bool       `json:"email_sent"` // 邮件发送失败时邀请仍然有效，可稍后重发
+	Token        string     `json:"-"`          // 写入邀请邮件的原始令牌
+}
+
+// UserInvitationSummary 各状态的邀请数量
+type UserInvitationSummary struct {
+	Pending  int64 `json:"pending"`
+	Accepted int64 `json:"accepted"`
+	Expired  int64 `json:"expired"`
+	Revoked  int64 `json:"revoked"`
+}
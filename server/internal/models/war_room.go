@@ -0,0 +1,36 @@
+package models
+
+import "time"
+
+// WarRoomStatus 作战室状态
+type WarRoomStatus string
+
+const (
+	WarRoomStatusActive   WarRoomStatus = "active"   // 同步中
+	WarRoomStatusArchived WarRoomStatus = "archived" // 工单已解决，频道已归档
+)
+
+// TicketWarRoom 紧急工单的升级作战室：专用聊天频道或已有频道中的线程。
+// 创建后工单的重要历史会同步到作战室，工单解决或关闭后归档
+type TicketWarRoom struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	TicketID    uint          `json:"ticket_id" gorm:"not null;uniqueIndex"`
+	Platform    ChatPlatform  `json:"platform" gorm:"size:20;not null"`
+	ChannelID   string        `json:"channel_id" gorm:"size:100;not null"`
+	ChannelName string        `json:"channel_name" gorm:"size:100"`
+	ThreadID    string        `json:"thread_id,omitempty" gorm:"size:100"` // 线程模式下的根消息，为空表示专用频道
+	Status      WarRoomStatus `json:"status" gorm:"size:20;not null;index"`
+	Invited     int           `json:"invited"` // 成功邀请的成员数
+
+	LastHistoryID uint       `json:"last_history_id"` // 已同步到作战室的最后一条历史
+	LastError     string     `json:"last_error,omitempty" gorm:"type:text"`
+	ArchivedAt    *time.Time `json:"archived_at,omitempty"`
+}
+
+// TableName 指定表名
+func (TicketWarRoom) TableName() string {
+	return "ticket_war_rooms"
+}
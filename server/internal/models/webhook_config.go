@@ -74,6 +74,10 @@ type WebhookConfig struct {
 	FilterRules    string          `json:"filter_rules" gorm:"type:text"` // JSON对象存储过滤规则
 	FilterRulesObj json.RawMessage `json:"filter_rules_obj,omitempty" gorm:"-"` // 运行时解析字段
 
+	// 字段级变更订阅：仅当这些工单字段发生变化时触发 ticket.updated 等变更事件，为空表示不过滤
+	FieldFilters    string   `json:"field_filters" gorm:"type:text"` // JSON数组存储字段名
+	FieldFiltersObj []string `json:"field_filters_list,omitempty" gorm:"-"` // 运行时解析字段
+
 	// 高级配置
 	RetryCount     int  `json:"retry_count" gorm:"default:3" validate:"min=0,max=10"`
 	RetryInterval  int  `json:"retry_interval" gorm:"default:60" validate:"min=5,max=3600"` // 秒
@@ -84,6 +88,10 @@ type WebhookConfig struct {
 	RateLimit       int `json:"rate_limit" gorm:"default:60" validate:"min=1,max=1000"`     // 每分钟最大请求数
 	RateLimitWindow int `json:"rate_limit_window" gorm:"default:60" validate:"min=60,max=3600"` // 限流窗口(秒)
 
+	// 日志保留：0 表示使用系统默认保留天数
+	LogRetentionDays       int `json:"log_retention_days" gorm:"default:0" validate:"min=0,max=3650"`        // 成功日志保留天数
+	FailedLogRetentionDays int `json:"failed_log_retention_days" gorm:"default:0" validate:"min=0,max=3650"` // 失败日志保留天数
+
 	// 监控统计
 	LastTriggeredAt *time.Time `json:"last_triggered_at,omitempty"`
 	LastSuccessAt   *time.Time `json:"last_success_at,omitempty"`
@@ -116,6 +124,15 @@ func (w *WebhookConfig) BeforeSave(tx *gorm.DB) error {
 		w.FilterRules = string(w.FilterRulesObj)
 	}
 
+	// 将FieldFiltersObj序列化为JSON字符串
+	if len(w.FieldFiltersObj) > 0 {
+		filtersData, err := json.Marshal(w.FieldFiltersObj)
+		if err != nil {
+			return err
+		}
+		w.FieldFilters = string(filtersData)
+	}
+
 	return nil
 }
 
@@ -134,6 +151,14 @@ func (w *WebhookConfig) AfterFind(tx *gorm.DB) error {
 		w.FilterRulesObj = json.RawMessage(w.FilterRules)
 	}
 
+	// 反序列化FieldFilters
+	if w.FieldFilters != "" {
+		var fields []string
+		if err := json.Unmarshal([]byte(w.FieldFilters), &fields); err == nil {
+			w.FieldFiltersObj = fields
+		}
+	}
+
 	return nil
 }
 
@@ -147,6 +172,21 @@ func (w *WebhookConfig) IsEventEnabled(eventType WebhookEventType) bool {
 	return false
 }
 
+// MatchesFieldChanges 检查变更字段是否命中字段订阅；未配置字段过滤时总是命中
+func (w *WebhookConfig) MatchesFieldChanges(changedFields []string) bool {
+	if len(w.FieldFiltersObj) == 0 {
+		return true
+	}
+	for _, filter := range w.FieldFiltersObj {
+		for _, field := range changedFields {
+			if filter == field {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // GetProviderConfig 获取提供商特定配置
 func (w *WebhookConfig) GetProviderConfig() map[string]interface{} {
 	config := make(map[string]interface{})
@@ -213,4 +253,34 @@ type WebhookLog struct {
 	SourceIP    string `json:"source_ip" gorm:"size:45"`
 	TraceID     string `json:"trace_id" gorm:"size:100;index"` // 分布式追踪ID
 	Environment string `json:"environment" gorm:"size:20"`     // 环境标识
-}
\ No newline at end of file
+}
+// WebhookLogFilter Webhook日志查询条件
+type WebhookLogFilter struct {
+	Status          string           `form:"status"`            // success, failed, retrying, pending
+	EventType       WebhookEventType `form:"event_type"`        // 事件类型
+	HTTPStatus      string           `form:"http_status"`       // 精确状态码（如 500）或状态类（如 5xx），0 表示无响应
+	StartDate       *time.Time       `form:"-"`                 // 创建时间起（含）
+	EndDate         *time.Time       `form:"-"`                 // 创建时间止（不含）
+	MinResponseTime *int64           `form:"min_response_time"` // 响应时间下限(毫秒)
+	Query           string           `form:"q"`                 // 在请求体、响应体、事件数据和错误信息中搜索
+	Page            int              `form:"page"`
+	PageSize        int              `form:"page_size"`
+}
+
+// WebhookEndpointStats 单个请求地址在统计区间内的投递情况
+type WebhookEndpointStats struct {
+	RequestURL      string     `json:"request_url"`
+	Sent            int64      `json:"sent"`
+	Success         int64      `json:"success"`
+	Failed          int64      `json:"failed"`
+	FailureRate     float64    `json:"failure_rate"` // 失败占比(%)
+	AvgResponseTime float64    `json:"avg_response_time"`
+	MaxResponseTime int64      `json:"max_response_time"`
+	LastFailureAt   *time.Time `json:"last_failure_at,omitempty"`
+}
+
+// WebhookLogRetention Webhook生效的日志保留天数，0 表示不清理
+type WebhookLogRetention struct {
+	SuccessDays int `json:"success_days"`
+	FailedDays  int `json:"failed_days"`
+}
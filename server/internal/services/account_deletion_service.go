@@ -0,0 +1,373 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultAccountDeletionGraceDays = 14
+	accountDeletionConfirmTTL       = 24 * time.Hour
+)
+
+var (
+	// ErrAccountDeletionPending 账户已处于注销宽限期
+	ErrAccountDeletionPending = errors.New("account deletion already pending")
+	// ErrAccountDeletionNotFound 注销申请不存在或确认链接已失效
+	ErrAccountDeletionNotFound = errors.New("account deletion request not found")
+	// ErrAccountDeletionNotAllowed 该账户不允许自助注销
+	ErrAccountDeletionNotAllowed = errors.New("account deletion not allowed")
+)
+
+// AccountDeletionFilter 注销申请查询条件
+type AccountDeletionFilter struct {
+	Status   models.AccountDeletionStatus
+	Page     int
+	PageSize int
+}
+
+// AccountDeletionService 用户自助注销：邮件确认、宽限期、撤销登录凭据及到期匿名化
+type AccountDeletionService struct {
+	db            *gorm.DB
+	configService *ConfigService
+	avatarService *AvatarService
+}
+
+// NewAccountDeletionService 创建账户注销服务
+func NewAccountDeletionService(db *gorm.DB) *AccountDeletionService {
+	return &AccountDeletionService{
+		db:            db,
+		configService: NewConfigService(db),
+	}
+}
+
+// SetAvatarService 设置头像服务，匿名化时一并删除已上传的头像文件
+func (s *AccountDeletionService) SetAvatarService(avatarService *AvatarService) {
+	s.avatarService = avatarService
+}
+
+// GraceDays 注销宽限期天数
+func (s *AccountDeletionService) GraceDays() int {
+	if days, err := s.configService.GetConfigInt(KeyAccountDeletionGraceDays); err == nil && days >= 0 {
+		return days
+	}
+	return defaultAccountDeletionGraceDays
+}
+
+// CreateRequest 创建注销申请，返回写入邮件的确认令牌；之前未确认的申请将被撤销
+func (s *AccountDeletionService) CreateRequest(ctx context.Context, userID uint, reason, ipAddress string) (*models.AccountDeletionRequest, string, error) {
+	var user models.User
+	if err := s.db.WithContext(ctx).First(&user, userID).Error; err != nil {
+		return nil, "", fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.Status == models.UserStatusDeleted {
+		return nil, "", ErrAccountDeletionNotAllowed
+	}
+	// 保留至少一个可用的管理员账户
+	if user.Role == models.RoleAdmin {
+		var admins int64
+		if err := s.db.WithContext(ctx).Model(&models.User{}).
+			Where("role = ? AND status = ? AND id <> ?", models.RoleAdmin, models.UserStatusActive, userID).
+			Count(&admins).Error; err != nil {
+			return nil, "", fmt.Errorf("failed to count admins: %w", err)
+		}
+		if admins == 0 {
+			return nil, "", fmt.Errorf("%w: the last administrator cannot be deleted", ErrAccountDeletionNotAllowed)
+		}
+	}
+
+	if pending, err := s.activeRequest(ctx, userID); err != nil {
+		return nil, "", err
+	} else if pending != nil {
+		return nil, "", ErrAccountDeletionPending
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, "", fmt.Errorf("failed to generate confirmation token: %w", err)
+	}
+	token := hex.EncodeToString(raw)
+
+	now := time.Now()
+	request := &models.AccountDeletionRequest{
+		UserID:           userID,
+		Username:         user.Username,
+		Email:            user.Email,
+		Reason:           truncateString(reason, 497),
+		Status:           models.AccountDeletionAwaitingConfirmation,
+		ConfirmTokenHash: hashAccountDeletionToken(token),
+		ConfirmExpiresAt: now.Add(accountDeletionConfirmTTL),
+		RequestIP:        ipAddress,
+	}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.AccountDeletionRequest{}).
+			Where("user_id = ? AND status = ?", userID, models.AccountDeletionAwaitingConfirmation).
+			Updates(map[string]interface{}{"status": models.AccountDeletionCancelled, "cancelled_at": now}).Error; err != nil {
+			return err
+		}
+		return tx.Create(request).Error
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create account deletion request: %w", err)
+	}
+	return request, token, nil
+}
+
+// Confirm 通过邮件令牌确认注销，进入宽限期并立即撤销所有登录凭据
+func (s *AccountDeletionService) Confirm(ctx context.Context, token string) (*models.AccountDeletionRequest, error) {
+	if token == "" {
+		return nil, ErrAccountDeletionNotFound
+	}
+
+	var request models.AccountDeletionRequest
+	err := s.db.WithContext(ctx).
+		Where("confirm_token_hash = ? AND status = ? AND confirm_expires_at > ?",
+			hashAccountDeletionToken(token), models.AccountDeletionAwaitingConfirmation, time.Now()).
+		First(&request).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAccountDeletionNotFound
+		}
+		return nil, fmt.Errorf("failed to get account deletion request: %w", err)
+	}
+
+	now := time.Now()
+	scheduled := now.AddDate(0, 0, s.GraceDays())
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.AccountDeletionRequest{}).
+			Where("id = ? AND status = ?", request.ID, models.AccountDeletionAwaitingConfirmation).
+			Updates(map[string]interface{}{
+				"status":             models.AccountDeletionPending,
+				"confirmed_at":       now,
+				"scheduled_at":       scheduled,
+				"confirm_token_hash": "",
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrAccountDeletionNotFound
+		}
+		return revokeUserAccess(tx, request.UserID, now)
+	})
+	if err != nil {
+		if errors.Is(err, ErrAccountDeletionNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to confirm account deletion: %w", err)
+	}
+
+	request.Status = models.AccountDeletionPending
+	request.ConfirmedAt = &now
+	request.ScheduledAt = &scheduled
+	return &request, nil
+}
+
+// PendingDeletion 返回处于宽限期的注销计划时间，没有时返回 nil
+func (s *AccountDeletionService) PendingDeletion(ctx context.Context, userID uint) (*time.Time, error) {
+	request, err := s.activeRequest(ctx, userID)
+	if err != nil || request == nil {
+		return nil, err
+	}
+	return request.ScheduledAt, nil
+}
+
+// CancelDeletion 用户在宽限期内恢复账户
+func (s *AccountDeletionService) CancelDeletion(ctx context.Context, userID uint) error {
+	result := s.db.WithContext(ctx).Model(&models.AccountDeletionRequest{}).
+		Where("user_id = ? AND status = ?", userID, models.AccountDeletionPending).
+		Updates(map[string]interface{}{"status": models.AccountDeletionCancelled, "cancelled_at": time.Now()})
+	if result.Error != nil {
+		return fmt.Errorf("failed to cancel account deletion: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrAccountDeletionNotFound
+	}
+	return nil
+}
+
+// AdminCancel 管理员取消处于宽限期或等待确认的注销申请
+func (s *AccountDeletionService) AdminCancel(ctx context.Context, id, adminID uint) (*models.AccountDeletionRequest, error) {
+	var request models.AccountDeletionRequest
+	if err := s.db.WithContext(ctx).First(&request, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAccountDeletionNotFound
+		}
+		return nil, fmt.Errorf("failed to get account deletion request: %w", err)
+	}
+	if request.Status != models.AccountDeletionPending && request.Status != models.AccountDeletionAwaitingConfirmation {
+		return nil, ErrAccountDeletionNotFound
+	}
+
+	now := time.Now()
+	request.Status = models.AccountDeletionCancelled
+	request.CancelledAt = &now
+	request.CancelledByID = &adminID
+	request.ConfirmTokenHash = ""
+	if err := s.db.WithContext(ctx).Save(&request).Error; err != nil {
+		return nil, fmt.Errorf("failed to cancel account deletion: %w", err)
+	}
+	return &request, nil
+}
+
+// List 分页查询注销申请，默认按计划执行时间排序
+func (s *AccountDeletionService) List(ctx context.Context, filter *AccountDeletionFilter) ([]*models.AccountDeletionRequest, int64, error) {
+	query := s.db.WithContext(ctx).Model(&models.AccountDeletionRequest{})
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count account deletion requests: %w", err)
+	}
+
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 || filter.PageSize > 100 {
+		filter.PageSize = 20
+	}
+	var requests []*models.AccountDeletionRequest
+	if err := query.Order("scheduled_at ASC, created_at DESC").
+		Offset((filter.Page - 1) * filter.PageSize).
+		Limit(filter.PageSize).
+		Find(&requests).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list account deletion requests: %w", err)
+	}
+	return requests, total, nil
+}
+
+// FinalizeDue 对宽限期已结束的账户执行匿名化，返回处理数量
+func (s *AccountDeletionService) FinalizeDue(ctx context.Context, now time.Time) (int, error) {
+	var due []models.AccountDeletionRequest
+	if err := s.db.WithContext(ctx).
+		Where("status = ? AND scheduled_at <= ?", models.AccountDeletionPending, now).
+		Order("scheduled_at ASC").Limit(100).Find(&due).Error; err != nil {
+		return 0, fmt.Errorf("failed to find due account deletions: %w", err)
+	}
+
+	finalized := 0
+	for i := range due {
+		if err := s.finalize(ctx, &due[i], now); err != nil {
+			log.Printf("Failed to finalize account deletion %d for user %d: %v", due[i].ID, due[i].UserID, err)
+			continue
+		}
+		finalized++
+	}
+	return finalized, nil
+}
+
+// finalize 匿名化个人信息。用户记录保留以维持工单、评论的归属，显示为 Deleted user
+func (s *AccountDeletionService) finalize(ctx context.Context, request *models.AccountDeletionRequest, now time.Time) error {
+	if s.avatarService != nil {
+		if err := s.avatarService.Remove(ctx, request.UserID); err != nil {
+			log.Printf("Failed to remove avatar of deleted user %d: %v", request.UserID, err)
+		}
+	}
+
+	unusable := make([]byte, 32)
+	if _, err := rand.Read(unusable); err != nil {
+		return fmt.Errorf("failed to generate password placeholder: %w", err)
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("id = ?", request.UserID).Updates(map[string]interface{}{
+			"username":           fmt.Sprintf("deleted_user_%d", request.UserID),
+			"email":              fmt.Sprintf("deleted_user_%d@deleted.invalid", request.UserID),
+			"phone":              "",
+			"password_hash":      "!" + hex.EncodeToString(unusable), // 不对应任何密码
+			"first_name":         "",
+			"last_name":          "",
+			"display_name":       models.DeletedUserDisplayName,
+			"avatar":             "",
+			"department":         "",
+			"job_title":          "",
+			"manager_id":         nil,
+			"permissions":        "",
+			"status":             models.UserStatusDeleted,
+			"email_verified":     false,
+			"phone_verified":     false,
+			"two_factor_enabled": false,
+			"sms_otp_enabled":    false,
+			"two_factor_secret":  "",
+			"backup_codes":       "",
+			"last_login_ip":      "",
+			"deleted_at":         now,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to anonymize user: %w", err)
+		}
+		if err := tx.Exec("DELETE FROM user_profiles WHERE user_id = ?", request.UserID).Error; err != nil {
+			return fmt.Errorf("failed to delete user profile: %w", err)
+		}
+		if err := tx.Model(&models.LoginHistory{}).Where("user_id = ?", request.UserID).
+			Updates(map[string]interface{}{
+				"username":   fmt.Sprintf("deleted_user_%d", request.UserID),
+				"email":      fmt.Sprintf("deleted_user_%d@deleted.invalid", request.UserID),
+				"ip_address": "",
+				"user_agent": "",
+				"country":    "",
+				"region":     "",
+				"city":       "",
+			}).Error; err != nil {
+			return fmt.Errorf("failed to anonymize login history: %w", err)
+		}
+		if err := revokeUserAccess(tx, request.UserID, now); err != nil {
+			return err
+		}
+		return tx.Model(&models.AccountDeletionRequest{}).Where("id = ?", request.ID).Updates(map[string]interface{}{
+			"status":       models.AccountDeletionCompleted,
+			"completed_at": now,
+			"username":     "",
+			"email":        "",
+			"reason":       "",
+			"request_ip":   "",
+		}).Error
+	})
+}
+
+// activeRequest 获取处于宽限期的注销申请
+func (s *AccountDeletionService) activeRequest(ctx context.Context, userID uint) (*models.AccountDeletionRequest, error) {
+	var request models.AccountDeletionRequest
+	err := s.db.WithContext(ctx).
+		Where("user_id = ? AND status = ?", userID, models.AccountDeletionPending).
+		First(&request).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get account deletion request: %w", err)
+	}
+	return &request, nil
+}
+
+// revokeUserAccess 撤销刷新令牌、可信设备并结束活跃会话
+func revokeUserAccess(tx *gorm.DB, userID uint, now time.Time) error {
+	if err := tx.Exec("UPDATE refresh_tokens SET revoked = ?, revoked_at = ? WHERE user_id = ? AND revoked = ?",
+		true, now, userID, false).Error; err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	if err := tx.Model(&models.OTPTrustedDevice{}).Where("user_id = ? AND revoked = ?", userID, false).
+		Updates(map[string]interface{}{"revoked": true, "expires_at": now}).Error; err != nil {
+		return fmt.Errorf("failed to revoke trusted devices: %w", err)
+	}
+	if err := tx.Model(&models.LoginHistory{}).Where("user_id = ? AND is_active = ?", userID, true).
+		Updates(map[string]interface{}{"is_active": false, "logout_time": now}).Error; err != nil {
+		return fmt.Errorf("failed to end sessions: %w", err)
+	}
+	return nil
+}
+
+func hashAccountDeletionToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestAccountDeletion_GracePeriodAndFinalize(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:account_deletion_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.UserProfile{}, &models.Ticket{}, &models.LoginHistory{},
+		&models.OTPTrustedDevice{}, &models.SystemConfig{}, &models.AccountDeletionRequest{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+	if err := db.Exec("CREATE TABLE IF NOT EXISTS refresh_tokens (id INTEGER PRIMARY KEY, user_id INTEGER, token TEXT, revoked BOOLEAN DEFAULT false, revoked_at DATETIME)").Error; err != nil {
+		t.Fatalf("failed to create refresh_tokens: %v", err)
+	}
+
+	ctx := context.Background()
+	svc := NewAccountDeletionService(db)
+	if err := svc.configService.SetConfig(KeyAccountDeletionGraceDays, "3", "int", "", CategorySecurity, "account_deletion"); err != nil {
+		t.Fatalf("failed to set grace days: %v", err)
+	}
+
+	admin := models.User{Username: "ad-admin", Email: "ad-admin@example.com", PasswordHash: "x", Role: models.RoleAdmin, Status: models.UserStatusActive}
+	user := models.User{Username: "ad-user", Email: "ad-user@example.com", PasswordHash: "x", Role: models.RoleCustomer,
+		Status: models.UserStatusActive, FirstName: "Han", LastName: "Meimei"}
+	for _, u := range []*models.User{&admin, &user} {
+		if err := db.Create(u).Error; err != nil {
+			t.Fatalf("failed to seed user: %v", err)
+		}
+	}
+	ticket := models.Ticket{TicketNumber: "AD-1", Title: "keep me", Status: models.TicketStatusOpen, Priority: models.TicketPriorityLow,
+		Type: models.TicketTypeRequest, Source: models.TicketSourceWeb, CreatedByID: user.ID}
+	if err := db.Create(&ticket).Error; err != nil {
+		t.Fatalf("failed to seed ticket: %v", err)
+	}
+	db.Exec("INSERT INTO refresh_tokens (user_id, token, revoked) VALUES (?, ?, ?)", user.ID, "rt-1", false)
+
+	// 唯一的管理员不能注销
+	if _, _, err := svc.CreateRequest(ctx, admin.ID, "", "127.0.0.1"); !errors.Is(err, ErrAccountDeletionNotAllowed) {
+		t.Fatalf("expected last admin deletion to be rejected, got %v", err)
+	}
+
+	_, token, err := svc.CreateRequest(ctx, user.ID, "no longer needed", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("create request failed: %v", err)
+	}
+	if _, err := svc.Confirm(ctx, "wrong-token"); !errors.Is(err, ErrAccountDeletionNotFound) {
+		t.Fatalf("expected invalid token to be rejected, got %v", err)
+	}
+	request, err := svc.Confirm(ctx, token)
+	if err != nil {
+		t.Fatalf("confirm failed: %v", err)
+	}
+	if request.ScheduledAt == nil || request.ScheduledAt.Sub(time.Now()) < 71*time.Hour {
+		t.Fatalf("expected deletion to be scheduled after the 3-day grace period, got %v", request.ScheduledAt)
+	}
+	var active int64
+	db.Raw("SELECT COUNT(*) FROM refresh_tokens WHERE user_id = ? AND revoked = ?", user.ID, false).Scan(&active)
+	if active != 0 {
+		t.Fatalf("expected sessions to be revoked on confirmation, %d still active", active)
+	}
+	if _, _, err := svc.CreateRequest(ctx, user.ID, "", "127.0.0.1"); !errors.Is(err, ErrAccountDeletionPending) {
+		t.Fatalf("expected duplicate request to be rejected, got %v", err)
+	}
+
+	// 宽限期内恢复
+	if err := svc.CancelDeletion(ctx, user.ID); err != nil {
+		t.Fatalf("cancel failed: %v", err)
+	}
+	if scheduled, err := svc.PendingDeletion(ctx, user.ID); err != nil || scheduled != nil {
+		t.Fatalf("expected no pending deletion after restore, got %v (%v)", scheduled, err)
+	}
+
+	_, token, _ = svc.CreateRequest(ctx, user.ID, "", "127.0.0.1")
+	request, err = svc.Confirm(ctx, token)
+	if err != nil {
+		t.Fatalf("second confirm failed: %v", err)
+	}
+	if n, err := svc.FinalizeDue(ctx, time.Now()); err != nil || n != 0 {
+		t.Fatalf("expected nothing to finalize within the grace period, got %d (%v)", n, err)
+	}
+	if n, err := svc.FinalizeDue(ctx, request.ScheduledAt.Add(time.Minute)); err != nil || n != 1 {
+		t.Fatalf("expected one account to be finalized, got %d (%v)", n, err)
+	}
+
+	var deleted models.User
+	db.First(&deleted, user.ID)
+	if deleted.Status != models.UserStatusDeleted || deleted.Email == user.Email || deleted.FirstName != "" ||
+		deleted.GetFullName() != models.DeletedUserDisplayName {
+		t.Fatalf("expected user to be anonymized, got %+v", deleted)
+	}
+	var kept models.Ticket
+	db.First(&kept, ticket.ID)
+	if kept.CreatedByID != user.ID {
+		t.Fatalf("expected ticket ownership to be preserved, got creator %d", kept.CreatedByID)
+	}
+	var completed models.AccountDeletionRequest
+	db.First(&completed, request.ID)
+	if completed.Status != models.AccountDeletionCompleted || completed.Email != "" {
+		t.Fatalf("expected request to be completed with PII cleared, got %+v", completed)
+	}
+}
@@ -2,8 +2,15 @@ package services
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"log"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"gongdan-system/internal/models"
@@ -26,6 +33,10 @@ type AdminAuditRecord struct {
 	Latency    time.Duration
 	Result     string
 	Notes      string
+
+	EventType   string        // 为空时为 admin_operation
+	RequestBody string        // 脱敏后的请求体
+	Changes     []AuditChange // 字段变更，写入前脱敏
 }
 
 // AdminAuditFilter 审计日志查询过滤条件
@@ -36,6 +47,7 @@ type AdminAuditFilter struct {
 	Path      string
 	Status    *int
 	Keyword   string
+	EventType string
 	StartTime *time.Time
 	EndTime   *time.Time
 	Page      int
@@ -59,17 +71,38 @@ type AdminAuditListItem struct {
 	LatencyMs  int64     `json:"latency_ms"`
 	Result     string    `json:"result"`
 	Notes      string    `json:"notes"`
+
+	EventType   string          `json:"event_type"`
+	RequestBody string          `json:"request_body,omitempty"`
+	Changes     json.RawMessage `json:"changes,omitempty"`
+	Hash        string          `json:"hash"`
+}
+
+// AdminAuditVerifyResult 哈希链校验结果
+type AdminAuditVerifyResult struct {
+	Valid      bool   `json:"valid"`
+	Checked    int64  `json:"checked"`                // 校验的记录数
+	Legacy     int64  `json:"legacy"`                 // 启用哈希链之前的记录数，不参与校验
+	BrokenAtID *uint  `json:"broken_at_id,omitempty"` // 第一条校验失败的记录
+	Reason     string `json:"reason,omitempty"`       // content_modified、chain_broken、missing_hash
+	LastID     uint   `json:"last_id,omitempty"`      // 最后一条记录，可与外部保存的值比对以发现尾部被删除
+	LastHash   string `json:"last_hash,omitempty"`
 }
 
 // AdminAuditServiceInterface 定义服务接口
 type AdminAuditServiceInterface interface {
 	Record(ctx context.Context, record *AdminAuditRecord) error
 	List(ctx context.Context, filter *AdminAuditFilter) ([]*models.AdminAuditLog, int64, error)
+	Verify(ctx context.Context) (*AdminAuditVerifyResult, error)
 }
 
 // AdminAuditService 管理员审计日志服务
 type AdminAuditService struct {
-	db *gorm.DB
+	db        *gorm.DB
+	forwarder *AuditForwarder
+
+	// 串行写入，保证哈希链按插入顺序连接
+	chainMu sync.Mutex
 }
 
 // NewAdminAuditService 创建新的审计日志服务
@@ -77,31 +110,46 @@ func NewAdminAuditService(db *gorm.DB) *AdminAuditService {
 	return &AdminAuditService{db: db}
 }
 
+// SetForwarder 设置审计事件转发器，记录的操作日志同时转发到 SIEM
+func (s *AdminAuditService) SetForwarder(forwarder *AuditForwarder) {
+	s.forwarder = forwarder
+}
+
 // Record 记录管理员操作日志
 func (s *AdminAuditService) Record(ctx context.Context, record *AdminAuditRecord) error {
 	if record == nil {
 		return errors.New("audit record cannot be nil")
 	}
 
+	// 按列长度截断后再计算哈希，保证读回的内容与哈希一致
 	auditLog := &models.AdminAuditLog{
-		UserID:     record.UserID,
-		Username:   strings.TrimSpace(record.Username),
-		Role:       strings.TrimSpace(record.Role),
-		Action:     strings.TrimSpace(record.Action),
-		Method:     strings.ToUpper(strings.TrimSpace(record.Method)),
-		Path:       record.Path,
-		StatusCode: record.StatusCode,
-		ClientIP:   record.ClientIP,
-		UserAgent:  record.UserAgent,
-		Query:      record.Query,
-		LatencyMs:  record.Latency.Milliseconds(),
-		Result:     record.Result,
-		Notes:      record.Notes,
+		UserID:      record.UserID,
+		Username:    clipAuditString(strings.TrimSpace(record.Username), 100),
+		Role:        clipAuditString(strings.TrimSpace(record.Role), 50),
+		Action:      clipAuditString(strings.TrimSpace(record.Action), 255),
+		Method:      clipAuditString(strings.ToUpper(strings.TrimSpace(record.Method)), 20),
+		Path:        clipAuditString(record.Path, 255),
+		StatusCode:  record.StatusCode,
+		ClientIP:    clipAuditString(record.ClientIP, 64),
+		UserAgent:   clipAuditString(record.UserAgent, 255),
+		Query:       clipAuditString(record.Query, 500),
+		LatencyMs:   record.Latency.Milliseconds(),
+		Result:      clipAuditString(record.Result, 100),
+		Notes:       record.Notes,
+		EventType:   record.EventType,
+		RequestBody: record.RequestBody,
 	}
 
 	if auditLog.Method == "" {
 		auditLog.Method = "UNKNOWN"
 	}
+	if auditLog.EventType == "" {
+		auditLog.EventType = models.AuditEventAdminOperation
+	}
+	if len(record.Changes) > 0 {
+		data, _ := json.Marshal(redactAuditChanges(record.Changes))
+		auditLog.Changes = models.JSONText(data)
+	}
 
 	// 如果未提供用户名或角色，则尝试从数据库读取
 	if auditLog.UserID != nil && (auditLog.Username == "" || auditLog.Role == "") {
@@ -116,7 +164,156 @@ func (s *AdminAuditService) Record(ctx context.Context, record *AdminAuditRecord
 		}
 	}
 
-	return s.db.WithContext(ctx).Create(auditLog).Error
+	if err := s.appendToChain(ctx, auditLog); err != nil {
+		return err
+	}
+
+	s.forwarder.Emit(&AuditEvent{
+		Time:       auditLog.CreatedAt,
+		Type:       auditLog.EventType,
+		Action:     auditLog.Action,
+		Result:     auditLog.Result,
+		UserID:     auditLog.UserID,
+		Username:   auditLog.Username,
+		Role:       auditLog.Role,
+		ClientIP:   auditLog.ClientIP,
+		UserAgent:  auditLog.UserAgent,
+		Method:     auditLog.Method,
+		Path:       auditLog.Path,
+		StatusCode: auditLog.StatusCode,
+		Detail:     auditLog.Notes,
+	})
+	return nil
+}
+
+// RecordSecurityEvent 记录非管理接口产生的账户安全事件（修改密码、两步验证、角色变更等）。
+// 操作人和客户端信息未提供时从请求上下文读取；服务未初始化时忽略
+func (s *AdminAuditService) RecordSecurityEvent(ctx context.Context, record *AdminAuditRecord) {
+	if s == nil || record == nil {
+		return
+	}
+	userID, clientIP, userAgent := AuditActorFrom(ctx)
+	if record.UserID == nil {
+		record.UserID = userID
+	}
+	if record.ClientIP == "" {
+		record.ClientIP = clientIP
+	}
+	if record.UserAgent == "" {
+		record.UserAgent = userAgent
+	}
+	if record.Method == "" {
+		record.Method = "EVENT"
+	}
+	if record.Result == "" {
+		record.Result = "success"
+	}
+	record.EventType = models.AuditEventAuth
+	if err := s.Record(context.WithoutCancel(ctx), record); err != nil {
+		log.Printf("Failed to record security event %s: %v", record.Action, err)
+	}
+}
+
+// appendToChain 以上一条记录的哈希为前缀计算本条哈希并写入
+func (s *AdminAuditService) appendToChain(ctx context.Context, auditLog *models.AdminAuditLog) error {
+	s.chainMu.Lock()
+	defer s.chainMu.Unlock()
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var prevHashes []string
+		if err := tx.Model(&models.AdminAuditLog{}).Order("id DESC").Limit(1).Pluck("hash", &prevHashes).Error; err != nil {
+			return err
+		}
+		if len(prevHashes) > 0 {
+			auditLog.PrevHash = prevHashes[0]
+		}
+		auditLog.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+		auditLog.Hash = adminAuditHash(auditLog)
+		return tx.Create(auditLog).Error
+	})
+}
+
+// adminAuditHash 对记录内容按固定顺序、带长度前缀计算 SHA-256
+func adminAuditHash(l *models.AdminAuditLog) string {
+	userID := ""
+	if l.UserID != nil {
+		userID = strconv.FormatUint(uint64(*l.UserID), 10)
+	}
+	fields := []string{
+		l.PrevHash,
+		strconv.FormatInt(l.CreatedAt.UnixMicro(), 10),
+		userID, l.Username, l.Role, l.EventType, l.Action, l.Method, l.Path,
+		strconv.Itoa(l.StatusCode), l.ClientIP, l.UserAgent, l.Query,
+		strconv.FormatInt(l.LatencyMs, 10), l.Result, l.Notes, l.RequestBody,
+		canonicalAuditJSON(string(l.Changes)),
+	}
+	h := sha256.New()
+	for _, field := range fields {
+		fmt.Fprintf(h, "%d:%s;", len(field), field)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// canonicalAuditJSON jsonb 会调整键顺序和空白，按统一格式重新序列化后再参与哈希
+func canonicalAuditJSON(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	var value interface{}
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		return raw
+	}
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+// Verify 按写入顺序校验哈希链。启用哈希链之前的记录不参与校验；
+// 按保留策略清理过的日志从第一条保留的记录开始校验
+func (s *AdminAuditService) Verify(ctx context.Context) (*AdminAuditVerifyResult, error) {
+	result := &AdminAuditVerifyResult{Valid: true}
+	var prevHash string
+	started := false
+
+	var batch []*models.AdminAuditLog
+	err := s.db.WithContext(ctx).Model(&models.AdminAuditLog{}).
+		FindInBatches(&batch, 500, func(tx *gorm.DB, _ int) error {
+			for _, entry := range batch {
+				if entry.Hash == "" {
+					if !started {
+						result.Legacy++
+						continue
+					}
+					result.fail(entry.ID, "missing_hash")
+					return errAuditVerifyStop
+				}
+				if started && entry.PrevHash != prevHash {
+					result.fail(entry.ID, "chain_broken")
+					return errAuditVerifyStop
+				}
+				if adminAuditHash(entry) != entry.Hash {
+					result.fail(entry.ID, "content_modified")
+					return errAuditVerifyStop
+				}
+				started = true
+				prevHash = entry.Hash
+				result.Checked++
+				result.LastID = entry.ID
+				result.LastHash = entry.Hash
+			}
+			return nil
+		}).Error
+	if err != nil && !errors.Is(err, errAuditVerifyStop) {
+		return nil, fmt.Errorf("failed to verify audit logs: %w", err)
+	}
+	return result, nil
+}
+
+var errAuditVerifyStop = errors.New("audit verification stopped")
+
+func (r *AdminAuditVerifyResult) fail(id uint, reason string) {
+	r.Valid = false
+	r.BrokenAtID = &id
+	r.Reason = reason
 }
 
 // List 获取管理员操作日志列表
@@ -142,9 +339,12 @@ func (s *AdminAuditService) List(ctx context.Context, filter *AdminAuditFilter)
 	if filter.Status != nil {
 		query = query.Where("status_code = ?", *filter.Status)
 	}
+	if filter.EventType != "" {
+		query = query.Where("event_type = ?", filter.EventType)
+	}
 	if filter.Keyword != "" {
 		like := "%" + filter.Keyword + "%"
-		query = query.Where("username ILIKE ? OR path ILIKE ? OR action ILIKE ?", like, like, like)
+		query = query.Where(DialectOf(s.db).AnyILike("username", "path", "action"), like, like, like)
 	}
 	if filter.StartTime != nil {
 		query = query.Where("created_at >= ?", *filter.StartTime)
@@ -202,6 +402,13 @@ func ConvertAuditLogs(logs []*models.AdminAuditLog) []*AdminAuditListItem {
 			LatencyMs:  log.LatencyMs,
 			Result:     log.Result,
 			Notes:      log.Notes,
+
+			EventType:   log.EventType,
+			RequestBody: log.RequestBody,
+			Hash:        log.Hash,
+		}
+		if log.Changes != "" {
+			item.Changes = json.RawMessage(log.Changes)
 		}
 		items[i] = item
 	}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestAdminAudit_HashChainRedactionAndSecurityEvents(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:admin_audit_chain_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.AdminAuditLog{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+
+	ctx := context.Background()
+	svc := NewAdminAuditService(db)
+
+	// 启用哈希链之前的记录不参与校验
+	db.Create(&models.AdminAuditLog{Action: "legacy", Method: "POST"})
+
+	adminID := uint(1)
+	scoped := WithAuditScope(ctx, &adminID, "10.0.0.1", "test-agent")
+	AddAuditChanges(scoped, DiffAuditFields(
+		map[string]interface{}{KeyPushVAPIDPrivateKey: "old-secret", KeySystemName: "Old", "role": models.RoleAgent},
+		map[string]interface{}{KeyPushVAPIDPrivateKey: "new-secret", KeySystemName: "New", "role": models.RoleAgent},
+	)...)
+	body := RedactAuditBody([]byte(`{"key":"notify.push_vapid_private_key","value":"s3cr3t","nested":{"password":"p"},"name":"ok"}`))
+	if strings.Contains(body, "s3cr3t") || strings.Contains(body, `"p"`) || !strings.Contains(body, `"name":"ok"`) {
+		t.Fatalf("request body not redacted: %s", body)
+	}
+	if err := svc.Record(ctx, &AdminAuditRecord{UserID: &adminID, Action: "PUT /api/admin/configs/batch", Method: "PUT",
+		RequestBody: body, Changes: AuditChangesFrom(scoped)}); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	svc.RecordSecurityEvent(scoped, &AdminAuditRecord{Action: "role_change", Changes: []AuditChange{{Field: "role", Old: models.RoleAgent, New: models.RoleAdmin}}})
+	svc.RecordSecurityEvent(ctx, &AdminAuditRecord{UserID: &adminID, Action: "password_change"})
+
+	var logs []models.AdminAuditLog
+	db.Order("id ASC").Find(&logs)
+	if len(logs) != 4 {
+		t.Fatalf("expected 4 logs, got %d", len(logs))
+	}
+	changes := string(logs[1].Changes)
+	if strings.Contains(changes, "secret") || !strings.Contains(changes, auditRedacted) || !strings.Contains(changes, `"New"`) || strings.Contains(changes, `"role"`) {
+		t.Fatalf("unexpected changes %s", changes)
+	}
+	if logs[2].EventType != models.AuditEventAuth || logs[2].ClientIP != "10.0.0.1" || logs[2].UserID == nil || *logs[2].UserID != adminID {
+		t.Fatalf("security event should take actor and client from context: %+v", logs[2])
+	}
+	if logs[1].PrevHash != "" || logs[2].PrevHash != logs[1].Hash || logs[3].PrevHash != logs[2].Hash {
+		t.Fatal("records are not chained")
+	}
+
+	result, err := svc.Verify(ctx)
+	if err != nil || !result.Valid || result.Checked != 3 || result.Legacy != 1 || result.LastHash != logs[3].Hash {
+		t.Fatalf("expected intact chain, got %+v err=%v", result, err)
+	}
+
+	// 修改内容
+	db.Model(&models.AdminAuditLog{}).Where("id = ?", logs[2].ID).Update("notes", "tampered")
+	result, _ = svc.Verify(ctx)
+	if result.Valid || result.Reason != "content_modified" || *result.BrokenAtID != logs[2].ID {
+		t.Fatalf("expected content_modified at %d, got %+v", logs[2].ID, result)
+	}
+	db.Model(&models.AdminAuditLog{}).Where("id = ?", logs[2].ID).Update("notes", "")
+
+	// 删除中间记录
+	db.Delete(&models.AdminAuditLog{}, logs[2].ID)
+	result, _ = svc.Verify(ctx)
+	if result.Valid || result.Reason != "chain_broken" || *result.BrokenAtID != logs[3].ID {
+		t.Fatalf("expected chain_broken at %d, got %+v", logs[3].ID, result)
+	}
+}
@@ -2,7 +2,14 @@ package services
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
+	"log"
+	"strconv"
 	"strings"
 	"time"
 
@@ -13,27 +20,43 @@ import (
 
 // AdminUserService 管理员用户管理服务
 type AdminUserService struct {
-	db *gorm.DB
+	db           *gorm.DB
+	quotaService *QuotaService
+	auditLog     *AdminAuditService
 }
 
 // NewAdminUserService 创建管理员用户管理服务
 func NewAdminUserService(db *gorm.DB) *AdminUserService {
 	return &AdminUserService{
-		db: db,
+		db:           db,
+		quotaService: NewQuotaService(db),
 	}
 }
 
 // UserListRequest 用户列表请求
 type UserListRequest struct {
-	Page     int                `form:"page" binding:"omitempty,min=1"`
-	PageSize int                `form:"page_size" binding:"omitempty,min=1,max=100"`
-	Role     *models.UserRole   `form:"role" binding:"omitempty,oneof=admin agent customer supervisor"`
-	Status   *models.UserStatus `form:"status" binding:"omitempty,oneof=active inactive suspended deleted"`
-	Search   string             `form:"search" binding:"omitempty,max=100"`
-	OrderBy  string             `form:"order_by" binding:"omitempty,oneof=id username email created_at updated_at last_login_at"`
-	Order    string             `form:"order" binding:"omitempty,oneof=asc desc"`
+	Page          int                `form:"page" binding:"omitempty,min=1"`
+	PageSize      int                `form:"page_size" binding:"omitempty,min=1,max=100"`
+	Role          *models.UserRole   `form:"role" binding:"omitempty,oneof=admin agent customer supervisor"`
+	Status        *models.UserStatus `form:"status" binding:"omitempty,oneof=active inactive suspended deleted"`
+	Department    string             `form:"department" binding:"omitempty,max=100"`
+	EmailVerified *bool              `form:"email_verified"`
+	OTPEnabled    *bool              `form:"otp_enabled"`
+	LastLoginFrom string             `form:"last_login_from"` // RFC3339 或 YYYY-MM-DD
+	LastLoginTo   string             `form:"last_login_to"`   // RFC3339 或 YYYY-MM-DD（包含当天）
+	Search        string             `form:"search" binding:"omitempty,max=100"`
+	Skills        string             `form:"skills" binding:"omitempty,max=500"` // 逗号分隔，需具备全部技能
+	Fields        map[string]string  `form:"-"`                                  // 自定义字段过滤，查询参数 field.<key>=<value>
+	OrderBy       string             `form:"order_by" binding:"omitempty,oneof=id username email display_name role status department created_at updated_at last_login_at"`
+	Order         string             `form:"order" binding:"omitempty,oneof=asc desc"`
 }
 
+// ErrInvalidUserListFilter 用户列表过滤条件无效
+var ErrInvalidUserListFilter = errors.New("invalid user list filter")
+
+// userExportLimit 单次导出的最大用户数
+const userExportLimit = 50000
+
 // UserListResponse 用户列表响应
 type UserListResponse struct {
 	Items    []*models.UserResponse `json:"items"`
@@ -59,24 +82,9 @@ func (s *AdminUserService) GetUserList(ctx context.Context, req *UserListRequest
 		req.Order = "desc"
 	}
 
-	query := s.db.WithContext(ctx).Model(&models.User{})
-
-	// 过滤条件
-	if req.Role != nil {
-		query = query.Where("role = ?", *req.Role)
-	}
-
-	if req.Status != nil {
-		query = query.Where("status = ?", *req.Status)
-	}
-
-	// 搜索条件（用户名、邮箱、姓名）
-	if req.Search != "" {
-		search := "%" + req.Search + "%"
-		query = query.Where(
-			"username LIKE ? OR email LIKE ? OR first_name LIKE ? OR last_name LIKE ? OR display_name LIKE ?",
-			search, search, search, search, search,
-		)
+	query, err := s.buildUserListQuery(ctx, req)
+	if err != nil {
+		return nil, err
 	}
 
 	// 统计总数
@@ -120,6 +128,150 @@ func (s *AdminUserService) GetUserList(ctx context.Context, req *UserListRequest
 	}, nil
 }
 
+// buildUserListQuery 按列表请求构建过滤条件，列表与导出共用
+func (s *AdminUserService) buildUserListQuery(ctx context.Context, req *UserListRequest) (*gorm.DB, error) {
+	query := s.db.WithContext(ctx).Model(&models.User{})
+
+	// 过滤条件
+	if req.Role != nil {
+		query = query.Where("role = ?", *req.Role)
+	}
+	if req.Status != nil {
+		query = query.Where("status = ?", *req.Status)
+	}
+	if department := strings.TrimSpace(req.Department); department != "" {
+		query = query.Where("department = ?", department)
+	}
+	if req.EmailVerified != nil {
+		query = query.Where("email_verified = ?", *req.EmailVerified)
+	}
+	if req.OTPEnabled != nil {
+		query = query.Where("two_factor_enabled = ?", *req.OTPEnabled)
+	}
+	if req.LastLoginFrom != "" {
+		from, _, err := parseUserListTime(req.LastLoginFrom)
+		if err != nil {
+			return nil, fmt.Errorf("%w: last_login_from %v", ErrInvalidUserListFilter, err)
+		}
+		query = query.Where("last_login_at >= ?", from)
+	}
+	if req.LastLoginTo != "" {
+		to, dateOnly, err := parseUserListTime(req.LastLoginTo)
+		if err != nil {
+			return nil, fmt.Errorf("%w: last_login_to %v", ErrInvalidUserListFilter, err)
+		}
+		if dateOnly {
+			to = to.AddDate(0, 0, 1)
+		}
+		query = query.Where("last_login_at < ?", to)
+	}
+
+	// 技能及自定义字段
+	var skills []string
+	for _, skill := range strings.Split(req.Skills, ",") {
+		if skill = strings.TrimSpace(skill); skill != "" {
+			skills = append(skills, skill)
+		}
+	}
+	query, err := applyUserAttributeFilters(s.db.WithContext(ctx), query, skills, req.Fields)
+	if err != nil {
+		return nil, err
+	}
+
+	// 搜索条件（用户名、邮箱、姓名）：不区分大小写，多个关键词需全部命中
+	for _, term := range strings.Fields(strings.ToLower(req.Search)) {
+		search := "%" + escapeLikePattern(term) + "%"
+		query = query.Where(
+			`LOWER(username) LIKE ? ESCAPE '\' OR LOWER(email) LIKE ? ESCAPE '\' OR LOWER(first_name) LIKE ? ESCAPE '\' OR LOWER(last_name) LIKE ? ESCAPE '\' OR LOWER(display_name) LIKE ? ESCAPE '\'`,
+			search, search, search, search, search,
+		)
+	}
+
+	return query, nil
+}
+
+// parseUserListTime 解析 RFC3339 或 YYYY-MM-DD 格式的时间，返回是否为日期格式
+func parseUserListTime(value string) (time.Time, bool, error) {
+	value = strings.TrimSpace(value)
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, false, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("expected RFC3339 or YYYY-MM-DD")
+	}
+	return t, true, nil
+}
+
+// ExportUsersCSV 按列表过滤条件与排序导出用户 CSV，返回导出行数
+func (s *AdminUserService) ExportUsersCSV(ctx context.Context, req *UserListRequest, w io.Writer) (int, error) {
+	query, err := s.buildUserListQuery(ctx, req)
+	if err != nil {
+		return 0, err
+	}
+	orderBy, order := req.OrderBy, req.Order
+	if orderBy == "" {
+		orderBy = "created_at"
+	}
+	if order == "" {
+		order = "desc"
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{
+		"id", "username", "email", "display_name", "first_name", "last_name", "role", "status",
+		"department", "job_title", "email_verified", "otp_enabled", "last_login_at", "created_at",
+	}); err != nil {
+		return 0, err
+	}
+
+	formatTime := func(t *time.Time) string {
+		if t == nil {
+			return ""
+		}
+		return t.Format(time.RFC3339)
+	}
+
+	rows, err := query.Order(fmt.Sprintf("%s %s, id ASC", orderBy, strings.ToUpper(order))).Limit(userExportLimit).Rows()
+	if err != nil {
+		return 0, fmt.Errorf("failed to export users: %w", err)
+	}
+	defer rows.Close()
+
+	exported := 0
+	for rows.Next() {
+		var user models.User
+		if err := s.db.ScanRows(rows, &user); err != nil {
+			return exported, fmt.Errorf("failed to scan user: %w", err)
+		}
+		if err := writer.Write([]string{
+			strconv.FormatUint(uint64(user.ID), 10),
+			user.Username,
+			user.Email,
+			user.DisplayName,
+			user.FirstName,
+			user.LastName,
+			string(user.Role),
+			string(user.Status),
+			user.Department,
+			user.JobTitle,
+			strconv.FormatBool(user.EmailVerified),
+			strconv.FormatBool(user.TwoFactorEnabled),
+			formatTime(user.LastLoginAt),
+			user.CreatedAt.Format(time.RFC3339),
+		}); err != nil {
+			return exported, err
+		}
+		exported++
+	}
+	if err := rows.Err(); err != nil {
+		return exported, fmt.Errorf("failed to export users: %w", err)
+	}
+
+	writer.Flush()
+	return exported, writer.Error()
+}
+
 // GetUserByID 根据ID获取用户详细信息
 func (s *AdminUserService) GetUserByID(ctx context.Context, userID uint) (*models.User, error) {
 	var user models.User
@@ -158,6 +310,11 @@ func (s *AdminUserService) CreateUser(ctx context.Context, req *models.UserCreat
 		return nil, fmt.Errorf("failed to check email: %w", err)
 	}
 
+	// 检查客服席位配额
+	if err := s.quotaService.CheckAgents(ctx, req.Role); err != nil {
+		return nil, err
+	}
+
 	// 加密密码
 	hashedPassword, err := s.hashPassword(req.Password)
 	if err != nil {
@@ -186,6 +343,12 @@ func (s *AdminUserService) CreateUser(ctx context.Context, req *models.UserCreat
 		return nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
+	if isQuotaSeatRole(user.Role) {
+		if err := s.quotaService.NotifyThresholds(ctx, models.QuotaResourceAgents, time.Now()); err != nil {
+			log.Printf("Warning: failed to check agent quota thresholds: %v", err)
+		}
+	}
+
 	// 重新加载用户信息（包含关联数据）
 	err = s.db.WithContext(ctx).Preload("Manager").First(user, user.ID).Error
 	if err != nil {
@@ -195,6 +358,43 @@ func (s *AdminUserService) CreateUser(ctx context.Context, req *models.UserCreat
 	return user, nil
 }
 
+// SetAuditLog 设置审计日志服务，角色变更和管理员重置密码记录为安全事件
+func (s *AdminUserService) SetAuditLog(auditLog *AdminAuditService) {
+	s.auditLog = auditLog
+}
+
+// userAuditFields 审计日志中对比的用户字段
+func userAuditFields(user *models.User) map[string]interface{} {
+	return map[string]interface{}{
+		"email":           user.Email,
+		"email_verified":  user.EmailVerified,
+		"phone":           user.Phone,
+		"phone_verified":  user.PhoneVerified,
+		"sms_otp_enabled": user.SMSOTPEnabled,
+		"first_name":      user.FirstName,
+		"last_name":       user.LastName,
+		"display_name":    user.DisplayName,
+		"avatar":          user.Avatar,
+		"timezone":        user.Timezone,
+		"language":        user.Language,
+		"role":            user.Role,
+		"status":          user.Status,
+		"department":      user.Department,
+		"job_title":       user.JobTitle,
+		"manager_id":      user.ManagerID,
+	}
+}
+
+// recordRoleChange 角色变更记录为安全事件，actorID 为空时取请求上下文中的操作人
+func (s *AdminUserService) recordRoleChange(ctx context.Context, actorID *uint, user *models.User, oldRole, newRole models.UserRole) {
+	s.auditLog.RecordSecurityEvent(ctx, &AdminAuditRecord{
+		UserID:  actorID,
+		Action:  "role_change",
+		Notes:   fmt.Sprintf("user_id=%d username=%s", user.ID, user.Username),
+		Changes: []AuditChange{{Field: "role", Old: oldRole, New: newRole}},
+	})
+}
+
 // UpdateUser 更新用户信息
 func (s *AdminUserService) UpdateUser(ctx context.Context, userID uint, req *models.UserUpdateRequest) (*models.User, error) {
 	user := &models.User{}
@@ -205,6 +405,10 @@ func (s *AdminUserService) UpdateUser(ctx context.Context, userID uint, req *mod
 		return nil, fmt.Errorf("failed to find user: %w", err)
 	}
 
+	wasSeat := isQuotaSeatRole(user.Role)
+	before := userAuditFields(user)
+	oldRole := user.Role
+
 	// 构建更新数据
 	updates := make(map[string]interface{})
 
@@ -223,6 +427,7 @@ func (s *AdminUserService) UpdateUser(ctx context.Context, userID uint, req *mod
 	if req.Phone != nil {
 		updates["phone"] = *req.Phone
 		updates["phone_verified"] = false // 手机变更需要重新验证
+		updates["sms_otp_enabled"] = false
 	}
 
 	if req.FirstName != nil {
@@ -250,6 +455,12 @@ func (s *AdminUserService) UpdateUser(ctx context.Context, userID uint, req *mod
 	}
 
 	if req.Role != nil {
+		// 客户升级为客服时占用新的席位
+		if !wasSeat {
+			if err := s.quotaService.CheckAgents(ctx, *req.Role); err != nil {
+				return nil, err
+			}
+		}
 		updates["role"] = *req.Role
 	}
 
@@ -274,6 +485,15 @@ func (s *AdminUserService) UpdateUser(ctx context.Context, userID uint, req *mod
 		if err := s.db.WithContext(ctx).Model(user).Updates(updates).Error; err != nil {
 			return nil, fmt.Errorf("failed to update user: %w", err)
 		}
+		AddAuditChanges(ctx, DiffAuditFields(before, updates)...)
+		if req.Role != nil && *req.Role != oldRole {
+			s.recordRoleChange(ctx, nil, user, oldRole, *req.Role)
+		}
+	}
+	if req.Role != nil && isQuotaSeatRole(*req.Role) && !wasSeat {
+		if err := s.quotaService.NotifyThresholds(ctx, models.QuotaResourceAgents, time.Now()); err != nil {
+			log.Printf("Warning: failed to check agent quota thresholds: %v", err)
+		}
 	}
 
 	// 重新加载用户信息
@@ -312,6 +532,10 @@ func (s *AdminUserService) ResetUserPassword(ctx context.Context, userID uint, n
 	if err := s.db.WithContext(ctx).Model(user).Updates(updates).Error; err != nil {
 		return fmt.Errorf("failed to reset password: %w", err)
 	}
+	s.auditLog.RecordSecurityEvent(ctx, &AdminAuditRecord{
+		Action: "admin_password_reset",
+		Notes:  fmt.Sprintf("user_id=%d username=%s", user.ID, user.Username),
+	})
 
 	return nil
 }
@@ -495,6 +719,202 @@ type UserStatsResponse struct {
 	NewUsersThisWeek  int64            `json:"new_users_this_week"`
 }
 
+var (
+	// ErrUserBulkJobNotFound 批量操作任务不存在
+	ErrUserBulkJobNotFound = errors.New("user bulk job not found")
+	// ErrInvalidUserBulkJob 批量操作请求无效
+	ErrInvalidUserBulkJob = errors.New("invalid user bulk job")
+)
+
+// CreateBulkJob 创建用户批量操作任务并在后台执行，逐个用户记录执行结果
+func (s *AdminUserService) CreateBulkJob(ctx context.Context, req *models.UserBulkJobRequest, actorID uint) (*models.UserBulkJob, error) {
+	job, err := s.createBulkJob(ctx, req, actorID)
+	if err != nil {
+		return nil, err
+	}
+	go s.runBulkJob(context.Background(), job.ID)
+	return job, nil
+}
+
+func (s *AdminUserService) createBulkJob(ctx context.Context, req *models.UserBulkJobRequest, actorID uint) (*models.UserBulkJob, error) {
+	if req.Action == models.UserBulkActionChangeRole && req.Role == "" {
+		return nil, fmt.Errorf("%w: role is required for change_role", ErrInvalidUserBulkJob)
+	}
+
+	seen := make(map[uint]bool, len(req.UserIDs))
+	userIDs := make([]uint, 0, len(req.UserIDs))
+	for _, id := range req.UserIDs {
+		if id != 0 && !seen[id] {
+			seen[id] = true
+			userIDs = append(userIDs, id)
+		}
+	}
+	if len(userIDs) == 0 {
+		return nil, fmt.Errorf("%w: user_ids is empty", ErrInvalidUserBulkJob)
+	}
+
+	job := &models.UserBulkJob{
+		Action:      req.Action,
+		UserIDList:  userIDs,
+		Status:      models.UserBulkJobPending,
+		Total:       len(userIDs),
+		CreatedByID: actorID,
+	}
+	if req.Action == models.UserBulkActionChangeRole {
+		job.Role = req.Role
+	}
+	if err := s.db.WithContext(ctx).Create(job).Error; err != nil {
+		return nil, fmt.Errorf("failed to create bulk job: %w", err)
+	}
+	return job, nil
+}
+
+// GetBulkJob 获取批量操作任务及逐个用户的结果
+func (s *AdminUserService) GetBulkJob(ctx context.Context, id uint) (*models.UserBulkJob, error) {
+	var job models.UserBulkJob
+	if err := s.db.WithContext(ctx).First(&job, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserBulkJobNotFound
+		}
+		return nil, fmt.Errorf("failed to get bulk job: %w", err)
+	}
+	return &job, nil
+}
+
+// ListBulkJobs 分页获取批量操作任务，按创建时间倒序
+func (s *AdminUserService) ListBulkJobs(ctx context.Context, page, pageSize int) ([]*models.UserBulkJob, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	var total int64
+	if err := s.db.WithContext(ctx).Model(&models.UserBulkJob{}).Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count bulk jobs: %w", err)
+	}
+	var jobs []*models.UserBulkJob
+	if err := s.db.WithContext(ctx).Order("created_at DESC").
+		Offset((page - 1) * pageSize).Limit(pageSize).Find(&jobs).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to get bulk jobs: %w", err)
+	}
+	return jobs, total, nil
+}
+
+// runBulkJob 逐个用户执行批量操作，每处理一个用户保存一次进度
+func (s *AdminUserService) runBulkJob(ctx context.Context, jobID uint) {
+	job, err := s.GetBulkJob(ctx, jobID)
+	if err != nil {
+		log.Printf("Failed to load user bulk job %d: %v", jobID, err)
+		return
+	}
+
+	now := time.Now()
+	job.Status = models.UserBulkJobRunning
+	job.StartedAt = &now
+	if err := s.db.WithContext(ctx).Save(job).Error; err != nil {
+		log.Printf("Failed to start user bulk job %d: %v", jobID, err)
+		return
+	}
+
+	for _, userID := range job.UserIDList {
+		result := models.UserBulkResult{UserID: userID, Success: true}
+		if err := s.applyBulkAction(ctx, job, userID); err != nil {
+			result.Success = false
+			result.Error = err.Error()
+			job.Failed++
+		} else {
+			job.Succeeded++
+		}
+		job.ResultList = append(job.ResultList, result)
+		if err := s.db.WithContext(ctx).Save(job).Error; err != nil {
+			log.Printf("Failed to save progress of user bulk job %d: %v", jobID, err)
+			s.db.WithContext(ctx).Model(&models.UserBulkJob{}).Where("id = ?", jobID).
+				Updates(map[string]interface{}{"status": models.UserBulkJobFailed, "error": err.Error()})
+			return
+		}
+	}
+
+	finished := time.Now()
+	job.Status = models.UserBulkJobCompleted
+	job.FinishedAt = &finished
+	if err := s.db.WithContext(ctx).Save(job).Error; err != nil {
+		log.Printf("Failed to finish user bulk job %d: %v", jobID, err)
+	}
+}
+
+// applyBulkAction 对单个用户执行批量操作
+func (s *AdminUserService) applyBulkAction(ctx context.Context, job *models.UserBulkJob, userID uint) error {
+	var user models.User
+	if err := s.db.WithContext(ctx).First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("user not found")
+		}
+		return fmt.Errorf("failed to find user: %w", err)
+	}
+
+	switch job.Action {
+	case models.UserBulkActionChangeRole:
+		if user.Role == job.Role {
+			return nil
+		}
+		if userID == job.CreatedByID {
+			return fmt.Errorf("cannot change your own role")
+		}
+		if user.Role == models.RoleAdmin && user.Status == models.UserStatusActive {
+			var adminCount int64
+			if err := s.db.WithContext(ctx).Model(&models.User{}).
+				Where("role = ? AND status = ?", models.RoleAdmin, models.UserStatusActive).
+				Count(&adminCount).Error; err != nil {
+				return fmt.Errorf("failed to count admin users: %w", err)
+			}
+			if adminCount <= 1 {
+				return fmt.Errorf("cannot change the role of the last active admin")
+			}
+		}
+		if !isQuotaSeatRole(user.Role) {
+			if err := s.quotaService.CheckAgents(ctx, job.Role); err != nil {
+				return err
+			}
+		}
+		oldRole := user.Role
+		if err := s.db.WithContext(ctx).Model(&user).Update("role", job.Role).Error; err != nil {
+			return fmt.Errorf("failed to change role: %w", err)
+		}
+		s.recordRoleChange(ctx, &job.CreatedByID, &user, oldRole, job.Role)
+		return nil
+
+	case models.UserBulkActionForcePasswordReset:
+		// 以随机密码作废当前密码并撤销刷新令牌，用户需通过找回密码流程设置新密码
+		token := make([]byte, 32)
+		if _, err := rand.Read(token); err != nil {
+			return fmt.Errorf("failed to generate password: %w", err)
+		}
+		hashedPassword, err := s.hashPassword(hex.EncodeToString(token))
+		if err != nil {
+			return fmt.Errorf("failed to hash password: %w", err)
+		}
+		now := time.Now()
+		return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&user).Updates(map[string]interface{}{
+				"password_hash":     hashedPassword,
+				"password_reset_at": now,
+			}).Error; err != nil {
+				return fmt.Errorf("failed to reset password: %w", err)
+			}
+			if err := tx.Exec("UPDATE refresh_tokens SET revoked = ?, revoked_at = ? WHERE user_id = ? AND revoked = ?",
+				true, now, userID, false).Error; err != nil {
+				return fmt.Errorf("failed to revoke sessions: %w", err)
+			}
+			return nil
+		})
+
+	default:
+		return fmt.Errorf("unsupported action %q", job.Action)
+	}
+}
+
 // hashPassword 加密密码
 func (s *AdminUserService) hashPassword(password string) (string, error) {
 	hashedBytes, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
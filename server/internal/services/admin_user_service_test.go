@@ -0,0 +1,140 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupAdminUserTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file:admin_user_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+
+	if err := db.AutoMigrate(&models.User{}, &models.UserBulkJob{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+	if err := db.Exec("CREATE TABLE IF NOT EXISTS refresh_tokens (id INTEGER PRIMARY KEY, user_id INTEGER, token TEXT, revoked BOOLEAN DEFAULT false, revoked_at DATETIME)").Error; err != nil {
+		t.Fatalf("failed to create refresh_tokens: %v", err)
+	}
+
+	return db
+}
+
+func TestAdminUser_ListFiltersAndExport(t *testing.T) {
+	db := setupAdminUserTestDB(t)
+	ctx := context.Background()
+	svc := NewAdminUserService(db)
+
+	recent := time.Now().Add(-2 * time.Hour)
+	old := time.Now().AddDate(0, -3, 0)
+	users := []models.User{
+		{Username: "alice", Email: "alice@example.com", PasswordHash: "x", DisplayName: "Alice Wang", Role: models.RoleAgent, Status: models.UserStatusActive, Department: "Support", EmailVerified: true, TwoFactorEnabled: true, LastLoginAt: &recent},
+		{Username: "bob", Email: "bob@example.com", PasswordHash: "x", DisplayName: "Bob Li", Role: models.RoleAgent, Status: models.UserStatusActive, Department: "Support", LastLoginAt: &old},
+		{Username: "carol", Email: "carol@example.com", PasswordHash: "x", DisplayName: "Carol 100%", Role: models.RoleCustomer, Status: models.UserStatusInactive, Department: "Sales"},
+	}
+	if err := db.Create(&users).Error; err != nil {
+		t.Fatalf("failed to seed users: %v", err)
+	}
+
+	verified := true
+	list, err := svc.GetUserList(ctx, &UserListRequest{Department: "Support", EmailVerified: &verified, OTPEnabled: &verified})
+	if err != nil || list.Total != 1 || list.Items[0].Username != "alice" {
+		t.Fatalf("expected only alice to match, got %+v (%v)", list, err)
+	}
+
+	list, err = svc.GetUserList(ctx, &UserListRequest{LastLoginFrom: time.Now().AddDate(0, 0, -7).Format("2006-01-02")})
+	if err != nil || list.Total != 1 {
+		t.Fatalf("expected one recent login, got %d (%v)", list.Total, err)
+	}
+
+	list, err = svc.GetUserList(ctx, &UserListRequest{Search: "WANG alice"})
+	if err != nil || list.Total != 1 {
+		t.Fatalf("expected case-insensitive multi-term search to match alice, got %d (%v)", list.Total, err)
+	}
+	if list, _ = svc.GetUserList(ctx, &UserListRequest{Search: "0%"}); list.Total != 1 {
+		t.Fatalf("expected LIKE wildcards in search to be escaped, got %d", list.Total)
+	}
+
+	if _, err := svc.GetUserList(ctx, &UserListRequest{LastLoginTo: "yesterday"}); !errors.Is(err, ErrInvalidUserListFilter) {
+		t.Fatalf("expected invalid date to be rejected, got %v", err)
+	}
+
+	var buf bytes.Buffer
+	count, err := svc.ExportUsersCSV(ctx, &UserListRequest{Department: "Support", OrderBy: "username", Order: "asc"}, &buf)
+	if err != nil || count != 2 {
+		t.Fatalf("expected 2 exported users, got %d (%v)", count, err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil || len(records) != 3 || records[1][1] != "alice" || records[2][1] != "bob" {
+		t.Fatalf("unexpected csv output: %v (%v)", records, err)
+	}
+}
+
+func TestAdminUser_BulkJobs(t *testing.T) {
+	db := setupAdminUserTestDB(t)
+	ctx := context.Background()
+	svc := NewAdminUserService(db)
+
+	admin := models.User{Username: "bulk-admin", Email: "bulk-admin@example.com", PasswordHash: "x", Role: models.RoleAdmin, Status: models.UserStatusActive}
+	agent := models.User{Username: "bulk-agent", Email: "bulk-agent@example.com", PasswordHash: "old-hash", Role: models.RoleAgent, Status: models.UserStatusActive}
+	for _, user := range []*models.User{&admin, &agent} {
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("failed to seed user: %v", err)
+		}
+	}
+
+	if _, err := svc.CreateBulkJob(ctx, &models.UserBulkJobRequest{Action: models.UserBulkActionChangeRole, UserIDs: []uint{agent.ID}}, admin.ID); !errors.Is(err, ErrInvalidUserBulkJob) {
+		t.Fatalf("expected change_role without role to be rejected, got %v", err)
+	}
+
+	job, err := svc.createBulkJob(ctx, &models.UserBulkJobRequest{
+		Action:  models.UserBulkActionChangeRole,
+		UserIDs: []uint{agent.ID, admin.ID, agent.ID, 9999},
+		Role:    models.RoleSupervisor,
+	}, admin.ID)
+	if err != nil || job.Total != 3 {
+		t.Fatalf("expected deduplicated job with 3 users, got %+v (%v)", job, err)
+	}
+	svc.runBulkJob(ctx, job.ID)
+
+	job, err = svc.GetBulkJob(ctx, job.ID)
+	if err != nil || job.Status != models.UserBulkJobCompleted || job.Succeeded != 1 || job.Failed != 2 || len(job.ResultList) != 3 {
+		t.Fatalf("unexpected job result: %+v (%v)", job, err)
+	}
+	if job.ResultList[1].Success || job.ResultList[2].Error != "user not found" {
+		t.Fatalf("expected self role change and missing user to fail, got %+v", job.ResultList)
+	}
+	var updated models.User
+	db.First(&updated, agent.ID)
+	if updated.Role != models.RoleSupervisor {
+		t.Fatalf("expected agent to become supervisor, got %s", updated.Role)
+	}
+
+	db.Exec("INSERT INTO refresh_tokens (user_id, token, revoked) VALUES (?, ?, ?)", agent.ID, "t1", false)
+	job, err = svc.createBulkJob(ctx, &models.UserBulkJobRequest{Action: models.UserBulkActionForcePasswordReset, UserIDs: []uint{agent.ID}}, admin.ID)
+	if err != nil {
+		t.Fatalf("create reset job failed: %v", err)
+	}
+	svc.runBulkJob(ctx, job.ID)
+
+	db.First(&updated, agent.ID)
+	if updated.PasswordHash == "old-hash" || updated.PasswordResetAt == nil {
+		t.Fatalf("expected password to be invalidated")
+	}
+	var active int64
+	db.Raw("SELECT COUNT(*) FROM refresh_tokens WHERE user_id = ? AND revoked = ?", agent.ID, false).Scan(&active)
+	if active != 0 {
+		t.Fatalf("expected sessions to be revoked, %d still active", active)
+	}
+}
@@ -0,0 +1,580 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// AnalyticsExportSyncMaxDays 超过该天数的时间范围只能通过后台任务导出
+	AnalyticsExportSyncMaxDays = 31
+	// analyticsExportMaxDays 自定义时间范围的最大天数
+	analyticsExportMaxDays = 3 * 366
+	// analyticsExportRetention 后台导出文件的保留时间
+	analyticsExportRetention = 7 * 24 * time.Hour
+)
+
+var (
+	// ErrInvalidExportRange 导出时间范围无效
+	ErrInvalidExportRange = errors.New("invalid export range")
+	// ErrAnalyticsExportNotFound 导出任务不存在
+	ErrAnalyticsExportNotFound = errors.New("analytics export job not found")
+	// ErrAnalyticsExportNotReady 导出任务尚未完成
+	ErrAnalyticsExportNotReady = errors.New("analytics export job not ready")
+	// ErrAnalyticsExportExpired 导出文件已过期
+	ErrAnalyticsExportExpired = errors.New("analytics export file expired")
+)
+
+// AnalyticsExportRange 导出时间范围，Start/End 为闭区间
+type AnalyticsExportRange struct {
+	Preset string
+	Start  time.Time
+	End    time.Time
+}
+
+// Days 时间范围覆盖的天数
+func (r *AnalyticsExportRange) Days() int {
+	return int(r.End.Sub(r.Start).Hours()/24) + 1
+}
+
+// ResolveAnalyticsExportRange 解析预设范围（7d、30d、90d）或自定义范围（custom，需提供起止日期）
+func ResolveAnalyticsExportRange(preset string, start, end *time.Time, now time.Time) (*AnalyticsExportRange, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	endOfToday := today.Add(24*time.Hour - time.Nanosecond)
+
+	switch preset {
+	case "7d", "30d", "90d":
+		days := map[string]int{"7d": 7, "30d": 30, "90d": 90}[preset]
+		return &AnalyticsExportRange{Preset: preset, Start: today.AddDate(0, 0, -(days - 1)), End: endOfToday}, nil
+	case "", "custom":
+		if start == nil || end == nil {
+			return nil, fmt.Errorf("%w: start_date and end_date are required for custom range", ErrInvalidExportRange)
+		}
+		s := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, now.Location())
+		e := time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, now.Location()).Add(24*time.Hour - time.Nanosecond)
+		if e.Before(s) {
+			return nil, fmt.Errorf("%w: end_date is before start_date", ErrInvalidExportRange)
+		}
+		r := &AnalyticsExportRange{Preset: "custom", Start: s, End: e}
+		if r.Days() > analyticsExportMaxDays {
+			return nil, fmt.Errorf("%w: range exceeds %d days", ErrInvalidExportRange, analyticsExportMaxDays)
+		}
+		return r, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown preset %q", ErrInvalidExportRange, preset)
+	}
+}
+
+// analyticsExportLabels 报表表头与枚举值的多语言文本
+var analyticsExportLabels = map[string]map[string]string{
+	"zh-CN": {
+		"sheet.status": "按状态统计", "sheet.sla": "SLA达成率", "sheet.agents": "坐席绩效",
+		"col.status": "状态", "col.count": "工单数", "col.percent": "占比",
+		"col.priority": "优先级", "col.sla_total": "设有SLA", "col.sla_met": "达成", "col.sla_breached": "违约", "col.sla_rate": "达成率",
+		"col.agent": "处理人", "col.assigned": "分配工单", "col.resolved": "已解决", "col.avg_resolution": "平均解决时长(小时)",
+		"chart.status": "工单状态分布", "chart.sla": "各优先级SLA达成情况", "chart.agents": "坐席已解决工单",
+		"open": "开放", "in_progress": "处理中", "pending": "等待中", "resolved": "已解决", "closed": "已关闭", "cancelled": "已取消",
+		"low": "低", "normal": "普通", "high": "高", "urgent": "紧急", "critical": "严重",
+		"unassigned": "未分配", "total": "合计",
+	},
+	"en-US": {
+		"sheet.status": "Tickets by Status", "sheet.sla": "SLA Compliance", "sheet.agents": "Agent Performance",
+		"col.status": "Status", "col.count": "Tickets", "col.percent": "Share",
+		"col.priority": "Priority", "col.sla_total": "With SLA", "col.sla_met": "Met", "col.sla_breached": "Breached", "col.sla_rate": "Compliance",
+		"col.agent": "Agent", "col.assigned": "Assigned", "col.resolved": "Resolved", "col.avg_resolution": "Avg Resolution (hours)",
+		"chart.status": "Ticket Status Distribution", "chart.sla": "SLA Compliance by Priority", "chart.agents": "Tickets Resolved by Agent",
+		"open": "Open", "in_progress": "In Progress", "pending": "Pending", "resolved": "Resolved", "closed": "Closed", "cancelled": "Cancelled",
+		"low": "Low", "normal": "Normal", "high": "High", "urgent": "Urgent", "critical": "Critical",
+		"unassigned": "Unassigned", "total": "Total",
+	},
+}
+
+// NormalizeAnalyticsExportLocale 返回支持的语言，未知语言回退为 zh-CN
+func NormalizeAnalyticsExportLocale(locale string) string {
+	if _, ok := analyticsExportLabels[locale]; ok {
+		return locale
+	}
+	if len(locale) >= 2 && locale[:2] == "en" {
+		return "en-US"
+	}
+	return "zh-CN"
+}
+
+// AnalyticsExportService 生成多工作表的 XLSX 统计报表，大范围导出在后台生成并提供下载
+type AnalyticsExportService struct {
+	db        *gorm.DB
+	dir       string // 后台导出文件目录，不应位于静态文件路由下
+	downloads *DownloadCenterService
+}
+
+// NewAnalyticsExportService 创建统计报表导出服务
+func NewAnalyticsExportService(db *gorm.DB, dir string) *AnalyticsExportService {
+	return &AnalyticsExportService{db: db, dir: dir}
+}
+
+// analyticsExportTicket 报表统计所需的工单字段
+type analyticsExportTicket struct {
+	Status       models.TicketStatus
+	Priority     models.TicketPriority
+	AssignedToID *uint
+	SLABreached  bool
+	SLADueDate   *time.Time
+	ResolvedAt   *time.Time
+	CreatedAt    time.Time
+}
+
+// slaBreached 已标记违约、解决晚于SLA截止时间或至今未解决且已超时
+func (t *analyticsExportTicket) slaBreached(now time.Time) bool {
+	if t.SLABreached {
+		return true
+	}
+	if t.ResolvedAt != nil {
+		return t.ResolvedAt.After(*t.SLADueDate)
+	}
+	return t.SLADueDate.Before(now)
+}
+
+type analyticsAgentRow struct {
+	name            string
+	assigned        int
+	resolved        int
+	resolutionHours float64
+	slaTotal        int
+	slaBreached     int
+}
+
+// BuildWorkbook 生成报表：按状态统计、各优先级SLA达成率、坐席绩效，各工作表附带图表
+func (s *AnalyticsExportService) BuildWorkbook(ctx context.Context, r *AnalyticsExportRange, locale string) (*bytes.Buffer, error) {
+	labels := analyticsExportLabels[NormalizeAnalyticsExportLocale(locale)]
+	label := func(key string) string {
+		if v, ok := labels[key]; ok {
+			return v
+		}
+		return key
+	}
+
+	statusCounts := make(map[models.TicketStatus]int)
+	type slaRow struct{ total, breached int }
+	slaByPriority := make(map[models.TicketPriority]*slaRow)
+	agents := make(map[uint]*analyticsAgentRow)
+	unassigned := &analyticsAgentRow{name: label("unassigned")}
+	total := 0
+	now := time.Now()
+
+	var batch []analyticsExportTicket
+	err := s.db.WithContext(ctx).Model(&models.Ticket{}).
+		Select("id", "status", "priority", "assigned_to_id", "sla_breached", "sla_due_date", "resolved_at", "created_at").
+		Where("created_at >= ? AND created_at <= ?", r.Start, r.End).
+		FindInBatches(&batch, 1000, func(tx *gorm.DB, _ int) error {
+			for i := range batch {
+				t := &batch[i]
+				total++
+				statusCounts[t.Status]++
+
+				agent := unassigned
+				if t.AssignedToID != nil {
+					if agents[*t.AssignedToID] == nil {
+						agents[*t.AssignedToID] = &analyticsAgentRow{}
+					}
+					agent = agents[*t.AssignedToID]
+				}
+				agent.assigned++
+				if t.ResolvedAt != nil {
+					agent.resolved++
+					agent.resolutionHours += t.ResolvedAt.Sub(t.CreatedAt).Hours()
+				}
+
+				if t.SLADueDate != nil {
+					row := slaByPriority[t.Priority]
+					if row == nil {
+						row = &slaRow{}
+						slaByPriority[t.Priority] = row
+					}
+					row.total++
+					agent.slaTotal++
+					if t.slaBreached(now) {
+						row.breached++
+						agent.slaBreached++
+					}
+				}
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tickets: %w", err)
+	}
+
+	if len(agents) > 0 {
+		ids := make([]uint, 0, len(agents))
+		for id := range agents {
+			ids = append(ids, id)
+		}
+		var users []models.User
+		if err := s.db.WithContext(ctx).Select("id", "username", "first_name", "last_name").Where("id IN ?", ids).Find(&users).Error; err != nil {
+			return nil, fmt.Errorf("failed to load agents: %w", err)
+		}
+		for _, u := range users {
+			agents[u.ID].name = u.GetFullName()
+		}
+		for id, a := range agents {
+			if a.name == "" {
+				a.name = fmt.Sprintf("#%d", id)
+			}
+		}
+	}
+
+	f := excelize.NewFile()
+	defer f.Close()
+
+	headerStyle, err := f.NewStyle(&excelize.Style{
+		Font: &excelize.Font{Bold: true},
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{"#DCE6F1"}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	percentStyle, err := f.NewStyle(&excelize.Style{NumFmt: 10})
+	if err != nil {
+		return nil, err
+	}
+	decimalStyle, err := f.NewStyle(&excelize.Style{NumFmt: 2})
+	if err != nil {
+		return nil, err
+	}
+
+	// 按状态统计
+	statusSheet := label("sheet.status")
+	if err := f.SetSheetName("Sheet1", statusSheet); err != nil {
+		return nil, err
+	}
+	rows := [][]interface{}{{label("col.status"), label("col.count"), label("col.percent")}}
+	for _, status := range []models.TicketStatus{models.TicketStatusOpen, models.TicketStatusInProgress, models.TicketStatusPending,
+		models.TicketStatusResolved, models.TicketStatusClosed, models.TicketStatusCancelled} {
+		rows = append(rows, []interface{}{label(string(status)), statusCounts[status], ratio(statusCounts[status], total)})
+	}
+	rows = append(rows, []interface{}{label("total"), total, ratio(total, total)})
+	if err := writeAnalyticsSheet(f, statusSheet, rows, headerStyle, map[string]int{"C": percentStyle}); err != nil {
+		return nil, err
+	}
+	if err := addAnalyticsChart(f, statusSheet, excelize.Pie, label("chart.status"), len(rows)-2, []string{"B"}); err != nil {
+		return nil, err
+	}
+
+	// 各优先级SLA达成率
+	slaSheet := label("sheet.sla")
+	if _, err := f.NewSheet(slaSheet); err != nil {
+		return nil, err
+	}
+	rows = [][]interface{}{{label("col.priority"), label("col.sla_total"), label("col.sla_met"), label("col.sla_breached"), label("col.sla_rate")}}
+	slaTotal, slaBreachedTotal := 0, 0
+	for _, priority := range []models.TicketPriority{models.TicketPriorityLow, models.TicketPriorityNormal, models.TicketPriorityHigh,
+		models.TicketPriorityUrgent, models.TicketPriorityCritical} {
+		row := slaByPriority[priority]
+		if row == nil {
+			row = &slaRow{}
+		}
+		slaTotal += row.total
+		slaBreachedTotal += row.breached
+		rows = append(rows, []interface{}{label(string(priority)), row.total, row.total - row.breached, row.breached, ratio(row.total-row.breached, row.total)})
+	}
+	rows = append(rows, []interface{}{label("total"), slaTotal, slaTotal - slaBreachedTotal, slaBreachedTotal, ratio(slaTotal-slaBreachedTotal, slaTotal)})
+	if err := writeAnalyticsSheet(f, slaSheet, rows, headerStyle, map[string]int{"E": percentStyle}); err != nil {
+		return nil, err
+	}
+	if err := addAnalyticsChart(f, slaSheet, excelize.ColStacked, label("chart.sla"), len(rows)-2, []string{"C", "D"}); err != nil {
+		return nil, err
+	}
+
+	// 坐席绩效，按已解决数量倒序
+	agentSheet := label("sheet.agents")
+	if _, err := f.NewSheet(agentSheet); err != nil {
+		return nil, err
+	}
+	agentRows := make([]*analyticsAgentRow, 0, len(agents)+1)
+	for _, a := range agents {
+		agentRows = append(agentRows, a)
+	}
+	sort.Slice(agentRows, func(i, j int) bool {
+		if agentRows[i].resolved != agentRows[j].resolved {
+			return agentRows[i].resolved > agentRows[j].resolved
+		}
+		return agentRows[i].name < agentRows[j].name
+	})
+	if unassigned.assigned > 0 {
+		agentRows = append(agentRows, unassigned)
+	}
+	rows = [][]interface{}{{label("col.agent"), label("col.assigned"), label("col.resolved"), label("col.avg_resolution"),
+		label("col.sla_breached"), label("col.sla_rate")}}
+	for _, a := range agentRows {
+		avg := 0.0
+		if a.resolved > 0 {
+			avg = a.resolutionHours / float64(a.resolved)
+		}
+		rows = append(rows, []interface{}{a.name, a.assigned, a.resolved, avg, a.slaBreached, ratio(a.slaTotal-a.slaBreached, a.slaTotal)})
+	}
+	if err := writeAnalyticsSheet(f, agentSheet, rows, headerStyle, map[string]int{"D": decimalStyle, "F": percentStyle}); err != nil {
+		return nil, err
+	}
+	if len(agentRows) > 0 {
+		// 图表最多展示前20名
+		n := len(agentRows)
+		if n > 20 {
+			n = 20
+		}
+		if err := addAnalyticsChart(f, agentSheet, excelize.Bar, label("chart.agents"), n, []string{"C"}); err != nil {
+			return nil, err
+		}
+	}
+
+	f.SetActiveSheet(0)
+	return f.WriteToBuffer()
+}
+
+// ratio 计算比例，分母为0时返回0
+func ratio(part, whole int) float64 {
+	if whole == 0 {
+		return 0
+	}
+	return float64(part) / float64(whole)
+}
+
+// writeAnalyticsSheet 写入表头及数据行，columnStyles 为列号到数字格式样式的映射
+func writeAnalyticsSheet(f *excelize.File, sheet string, rows [][]interface{}, headerStyle int, columnStyles map[string]int) error {
+	for i, row := range rows {
+		cell, err := excelize.CoordinatesToCellName(1, i+1)
+		if err != nil {
+			return err
+		}
+		if err := f.SetSheetRow(sheet, cell, &row); err != nil {
+			return err
+		}
+	}
+	lastCol, err := excelize.ColumnNumberToName(len(rows[0]))
+	if err != nil {
+		return err
+	}
+	if err := f.SetCellStyle(sheet, "A1", lastCol+"1", headerStyle); err != nil {
+		return err
+	}
+	for col, style := range columnStyles {
+		if err := f.SetCellStyle(sheet, fmt.Sprintf("%s2", col), fmt.Sprintf("%s%d", col, len(rows)), style); err != nil {
+			return err
+		}
+	}
+	if err := f.SetColWidth(sheet, "A", "A", 20); err != nil {
+		return err
+	}
+	return f.SetColWidth(sheet, "B", lastCol, 16)
+}
+
+// addAnalyticsChart 以A列为分类、valueCols 为数据系列，在表格右侧插入图表
+func addAnalyticsChart(f *excelize.File, sheet string, chartType excelize.ChartType, title string, dataRows int, valueCols []string) error {
+	if dataRows < 1 {
+		return nil
+	}
+	last := dataRows + 1
+	series := make([]excelize.ChartSeries, 0, len(valueCols))
+	for _, col := range valueCols {
+		series = append(series, excelize.ChartSeries{
+			Name:       fmt.Sprintf("'%s'!$%s$1", sheet, col),
+			Categories: fmt.Sprintf("'%s'!$A$2:$A$%d", sheet, last),
+			Values:     fmt.Sprintf("'%s'!$%s$2:$%s$%d", sheet, col, col, last),
+		})
+	}
+	return f.AddChart(sheet, "H2", &excelize.Chart{
+		Type:   chartType,
+		Series: series,
+		Title:  []excelize.RichTextRun{{Text: title}},
+		Legend: excelize.ChartLegend{Position: "bottom"},
+		Format: excelize.GraphicOptions{OffsetX: 10, OffsetY: 10},
+	})
+}
+
+// SetDownloadCenter 设置下载中心，报表生成后通知发起人并记入其下载列表
+func (s *AnalyticsExportService) SetDownloadCenter(downloads *DownloadCenterService) {
+	s.downloads = downloads
+	downloads.RegisterSource(models.DownloadKindAnalyticsExport, s)
+}
+
+// analyticsExportDownloadParams 重新生成报表所需的参数，沿用原时间范围
+type analyticsExportDownloadParams struct {
+	RangePreset string    `json:"range_preset"`
+	StartDate   time.Time `json:"start_date"`
+	EndDate     time.Time `json:"end_date"`
+	Locale      string    `json:"locale"`
+}
+
+// RegenerateDownload 按下载记录的参数重新创建导出任务
+func (s *AnalyticsExportService) RegenerateDownload(ctx context.Context, params string, userID uint) (uint, error) {
+	var p analyticsExportDownloadParams
+	if err := json.Unmarshal([]byte(params), &p); err != nil {
+		return 0, fmt.Errorf("invalid analytics export download params: %w", err)
+	}
+	job, err := s.CreateJob(ctx, &AnalyticsExportRange{Preset: p.RangePreset, Start: p.StartDate, End: p.EndDate}, p.Locale, userID)
+	if err != nil {
+		return 0, err
+	}
+	return job.ID, nil
+}
+
+// CreateJob 创建后台导出任务，生成完成后可通过下载接口获取文件
+func (s *AnalyticsExportService) CreateJob(ctx context.Context, r *AnalyticsExportRange, locale string, actorID uint) (*models.AnalyticsExportJob, error) {
+	if _, err := s.PurgeExpired(ctx, time.Now()); err != nil {
+		log.Printf("Failed to purge expired analytics exports: %v", err)
+	}
+
+	job := &models.AnalyticsExportJob{
+		RangePreset: r.Preset,
+		StartDate:   r.Start,
+		EndDate:     r.End,
+		Locale:      NormalizeAnalyticsExportLocale(locale),
+		Status:      models.AnalyticsExportPending,
+		CreatedByID: actorID,
+	}
+	if err := s.db.WithContext(ctx).Create(job).Error; err != nil {
+		return nil, fmt.Errorf("failed to create analytics export job: %w", err)
+	}
+	go s.runJob(context.Background(), job.ID)
+	return job, nil
+}
+
+// GetJob 获取导出任务
+func (s *AnalyticsExportService) GetJob(ctx context.Context, id uint) (*models.AnalyticsExportJob, error) {
+	var job models.AnalyticsExportJob
+	if err := s.db.WithContext(ctx).First(&job, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAnalyticsExportNotFound
+		}
+		return nil, fmt.Errorf("failed to get analytics export job: %w", err)
+	}
+	return &job, nil
+}
+
+// JobFile 获取已完成任务的文件路径
+func (s *AnalyticsExportService) JobFile(ctx context.Context, id uint) (*models.AnalyticsExportJob, string, error) {
+	job, err := s.GetJob(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+	if job.Status != models.AnalyticsExportCompleted {
+		return nil, "", ErrAnalyticsExportNotReady
+	}
+	if job.ExpiresAt != nil && time.Now().After(*job.ExpiresAt) {
+		return nil, "", ErrAnalyticsExportExpired
+	}
+	return job, filepath.Join(s.dir, filepath.FromSlash(job.FilePath)), nil
+}
+
+// runJob 生成报表并写入导出目录
+func (s *AnalyticsExportService) runJob(ctx context.Context, jobID uint) {
+	job, err := s.GetJob(ctx, jobID)
+	if err != nil {
+		log.Printf("Failed to load analytics export job %d: %v", jobID, err)
+		return
+	}
+
+	started := time.Now()
+	job.Status = models.AnalyticsExportRunning
+	job.StartedAt = &started
+	if err := s.db.WithContext(ctx).Save(job).Error; err != nil {
+		log.Printf("Failed to start analytics export job %d: %v", jobID, err)
+		return
+	}
+
+	fail := func(err error) {
+		log.Printf("Analytics export job %d failed: %v", jobID, err)
+		finished := time.Now()
+		s.db.WithContext(ctx).Model(&models.AnalyticsExportJob{}).Where("id = ?", jobID).Updates(map[string]interface{}{
+			"status":      models.AnalyticsExportFailed,
+			"error":       truncateString(err.Error(), 497),
+			"finished_at": &finished,
+		})
+	}
+
+	buf, err := s.BuildWorkbook(ctx, &AnalyticsExportRange{Preset: job.RangePreset, Start: job.StartDate, End: job.EndDate}, job.Locale)
+	if err != nil {
+		fail(err)
+		return
+	}
+
+	relPath := fmt.Sprintf("analytics/job_%d.xlsx", job.ID)
+	fullPath := filepath.Join(s.dir, filepath.FromSlash(relPath))
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+		fail(fmt.Errorf("failed to create export directory: %w", err))
+		return
+	}
+	size := int64(buf.Len())
+	if err := os.WriteFile(fullPath, buf.Bytes(), 0o644); err != nil {
+		fail(fmt.Errorf("failed to write export file: %w", err))
+		return
+	}
+
+	finished := time.Now()
+	expires := finished.Add(analyticsExportRetention)
+	job.Status = models.AnalyticsExportCompleted
+	job.FileName = AnalyticsExportFileName(job.StartDate, job.EndDate)
+	job.FilePath = relPath
+	job.FileSize = size
+	job.FinishedAt = &finished
+	job.ExpiresAt = &expires
+	if err := s.db.WithContext(ctx).Save(job).Error; err != nil {
+		log.Printf("Failed to finish analytics export job %d: %v", jobID, err)
+		return
+	}
+
+	if s.downloads != nil {
+		params, _ := json.Marshal(analyticsExportDownloadParams{RangePreset: job.RangePreset, StartDate: job.StartDate, EndDate: job.EndDate, Locale: job.Locale})
+		if err := s.downloads.Publish(ctx, &models.DownloadArtifact{
+			UserID:    job.CreatedByID,
+			Kind:      models.DownloadKindAnalyticsExport,
+			SourceID:  job.ID,
+			Title:     fmt.Sprintf("统计报表 %s ~ %s", job.StartDate.Format("2006-01-02"), job.EndDate.Format("2006-01-02")),
+			FileName:  job.FileName,
+			FilePath:  relPath,
+			FileSize:  size,
+			Params:    string(params),
+			ExpiresAt: expires,
+		}); err != nil {
+			log.Printf("Failed to publish analytics export job %d to download center: %v", jobID, err)
+		}
+	}
+}
+
+// PurgeExpired 删除过期的导出文件及任务记录
+func (s *AnalyticsExportService) PurgeExpired(ctx context.Context, now time.Time) (int, error) {
+	var jobs []models.AnalyticsExportJob
+	if err := s.db.WithContext(ctx).Where("expires_at IS NOT NULL AND expires_at < ?", now).Find(&jobs).Error; err != nil {
+		return 0, fmt.Errorf("failed to find expired analytics exports: %w", err)
+	}
+	purged := 0
+	for _, job := range jobs {
+		if job.FilePath != "" {
+			if err := os.Remove(filepath.Join(s.dir, filepath.FromSlash(job.FilePath))); err != nil && !errors.Is(err, os.ErrNotExist) {
+				log.Printf("Failed to remove analytics export file for job %d: %v", job.ID, err)
+				continue
+			}
+		}
+		if err := s.db.WithContext(ctx).Delete(&models.AnalyticsExportJob{}, job.ID).Error; err != nil {
+			return purged, fmt.Errorf("failed to delete analytics export job: %w", err)
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// AnalyticsExportFileName 报表文件名
+func AnalyticsExportFileName(start, end time.Time) string {
+	return fmt.Sprintf("analytics_%s_%s.xlsx", start.Format("20060102"), end.Format("20060102"))
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestResolveAnalyticsExportRange(t *testing.T) {
+	now := time.Date(2026, 3, 15, 14, 30, 0, 0, time.UTC)
+
+	r, err := ResolveAnalyticsExportRange("7d", nil, nil, now)
+	if err != nil {
+		t.Fatalf("resolve 7d failed: %v", err)
+	}
+	if !r.Start.Equal(time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC)) || r.Days() != 7 {
+		t.Fatalf("unexpected 7d range: %s - %s (%d days)", r.Start, r.End, r.Days())
+	}
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2026, 1, 31, 0, 0, 0, 0, time.UTC)
+	if r, err = ResolveAnalyticsExportRange("custom", &start, &end, now); err != nil || r.Days() != 31 {
+		t.Fatalf("expected 31-day custom range, got %+v (%v)", r, err)
+	}
+	if _, err := ResolveAnalyticsExportRange("custom", &end, &start, now); !errors.Is(err, ErrInvalidExportRange) {
+		t.Fatalf("expected reversed range to be rejected, got %v", err)
+	}
+	if _, err := ResolveAnalyticsExportRange("365d", nil, nil, now); !errors.Is(err, ErrInvalidExportRange) {
+		t.Fatalf("expected unknown preset to be rejected, got %v", err)
+	}
+}
+
+func TestAnalyticsExport_WorkbookAndAsyncJob(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:analytics_export_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Ticket{}, &models.AnalyticsExportJob{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+
+	ctx := context.Background()
+	svc := NewAnalyticsExportService(db, t.TempDir())
+
+	agent := models.User{Username: "ae-agent", Email: "ae-agent@example.com", PasswordHash: "x", Role: models.RoleAgent,
+		Status: models.UserStatusActive, FirstName: "Li", LastName: "Lei"}
+	if err := db.Create(&agent).Error; err != nil {
+		t.Fatalf("failed to seed user: %v", err)
+	}
+
+	now := time.Now()
+	past := now.Add(-2 * time.Hour)
+	future := now.Add(2 * time.Hour)
+	resolved := now.Add(-time.Hour)
+	tickets := []models.Ticket{
+		{TicketNumber: "AE-1", Title: "a", Status: models.TicketStatusResolved, Priority: models.TicketPriorityHigh, Type: models.TicketTypeRequest,
+			Source: models.TicketSourceWeb, CreatedByID: agent.ID, AssignedToID: &agent.ID, SLADueDate: &future, ResolvedAt: &resolved},
+		{TicketNumber: "AE-2", Title: "b", Status: models.TicketStatusOpen, Priority: models.TicketPriorityHigh, Type: models.TicketTypeRequest,
+			Source: models.TicketSourceWeb, CreatedByID: agent.ID, AssignedToID: &agent.ID, SLADueDate: &past},
+		{TicketNumber: "AE-3", Title: "c", Status: models.TicketStatusOpen, Priority: models.TicketPriorityLow, Type: models.TicketTypeRequest,
+			Source: models.TicketSourceWeb, CreatedByID: agent.ID},
+	}
+	for i := range tickets {
+		if err := db.Create(&tickets[i]).Error; err != nil {
+			t.Fatalf("failed to seed ticket: %v", err)
+		}
+	}
+
+	r, _ := ResolveAnalyticsExportRange("7d", nil, nil, now)
+	buf, err := svc.BuildWorkbook(ctx, r, "en-US")
+	if err != nil {
+		t.Fatalf("build workbook failed: %v", err)
+	}
+	f, err := excelize.OpenReader(buf)
+	if err != nil {
+		t.Fatalf("failed to open generated workbook: %v", err)
+	}
+	defer f.Close()
+
+	sheets := f.GetSheetList()
+	if len(sheets) != 3 || sheets[0] != "Tickets by Status" || sheets[1] != "SLA Compliance" || sheets[2] != "Agent Performance" {
+		t.Fatalf("unexpected sheets: %v", sheets)
+	}
+	if v, _ := f.GetCellValue("Tickets by Status", "B2"); v != "2" {
+		t.Fatalf("expected 2 open tickets, got %q", v)
+	}
+	// High 优先级：2 张设有SLA，1 张违约
+	if met, _ := f.GetCellValue("SLA Compliance", "C4"); met != "1" {
+		t.Fatalf("expected 1 high priority ticket to meet SLA, got %q", met)
+	}
+	if breached, _ := f.GetCellValue("SLA Compliance", "D4"); breached != "1" {
+		t.Fatalf("expected 1 breached high priority ticket, got %q", breached)
+	}
+	if name, _ := f.GetCellValue("Agent Performance", "A2"); name != "Li Lei" {
+		t.Fatalf("expected agent row first, got %q", name)
+	}
+	if name, _ := f.GetCellValue("Agent Performance", "A3"); name != "Unassigned" {
+		t.Fatalf("expected unassigned row last, got %q", name)
+	}
+
+	job, err := svc.CreateJob(ctx, r, "zh-CN", agent.ID)
+	if err != nil {
+		t.Fatalf("create job failed: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, err = svc.GetJob(ctx, job.ID)
+		if err != nil {
+			t.Fatalf("get job failed: %v", err)
+		}
+		if job.Status == models.AnalyticsExportCompleted || job.Status == models.AnalyticsExportFailed || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if job.Status != models.AnalyticsExportCompleted || job.FileSize == 0 {
+		t.Fatalf("expected job to complete, got %+v", job)
+	}
+	if _, path, err := svc.JobFile(ctx, job.ID); err != nil || path == "" {
+		t.Fatalf("expected downloadable file, got %q (%v)", path, err)
+	}
+
+	if purged, err := svc.PurgeExpired(ctx, job.ExpiresAt.Add(time.Minute)); err != nil || purged != 1 {
+		t.Fatalf("expected expired export to be purged, got %d (%v)", purged, err)
+	}
+	if _, _, err := svc.JobFile(ctx, job.ID); !errors.Is(err, ErrAnalyticsExportNotFound) {
+		t.Fatalf("expected purged job to be gone, got %v", err)
+	}
+}
@@ -342,11 +342,12 @@ func (s *AnalyticsService) getTicketStats(ctx context.Context) (*AnalyticsTicket
 		AvgHours float64 `gorm:"column:avg_hours"`
 	}
 	
-	err = s.db.WithContext(ctx).Raw(`
-		SELECT AVG(EXTRACT(epoch FROM (updated_at - created_at))/3600) as avg_hours
+	hours := DialectOf(s.db).HoursBetween("created_at", "updated_at")
+	err = s.db.WithContext(ctx).Raw(fmt.Sprintf(`
+		SELECT AVG(%s) as avg_hours
 		FROM tickets 
 		WHERE status != 'open' AND updated_at > created_at
-	`).Scan(&avgResponse).Error
+	`, hours)).Scan(&avgResponse).Error
 	
 	if err == nil {
 		stats.AvgResponseTime = avgResponse.AvgHours
@@ -357,11 +358,11 @@ func (s *AnalyticsService) getTicketStats(ctx context.Context) (*AnalyticsTicket
 		AvgHours float64 `gorm:"column:avg_hours"`
 	}
 	
-	err = s.db.WithContext(ctx).Raw(`
-		SELECT AVG(EXTRACT(epoch FROM (updated_at - created_at))/3600) as avg_hours
+	err = s.db.WithContext(ctx).Raw(fmt.Sprintf(`
+		SELECT AVG(%s) as avg_hours
 		FROM tickets 
 		WHERE status IN ('resolved', 'closed') AND updated_at > created_at
-	`).Scan(&avgResolution).Error
+	`, hours)).Scan(&avgResolution).Error
 	
 	if err == nil {
 		stats.AvgResolutionTime = avgResolution.AvgHours
@@ -518,17 +519,18 @@ func (s *AnalyticsService) GetTimeRangeStats(ctx context.Context, startDate, end
 // getDailyTicketTrend 获取每日工单趋势
 func (s *AnalyticsService) getDailyTicketTrend(ctx context.Context, startDate, endDate time.Time) ([]DailyCount, error) {
 	var results []struct {
-		Date  time.Time `gorm:"column:date"`
-		Count int64     `gorm:"column:count"`
+		Date  SQLDate `gorm:"column:date"`
+		Count int64   `gorm:"column:count"`
 	}
 	
-	err := s.db.WithContext(ctx).Raw(`
-		SELECT DATE(created_at) as date, COUNT(*) as count
+	day := DialectOf(s.db).Date("created_at")
+	err := s.db.WithContext(ctx).Raw(fmt.Sprintf(`
+		SELECT %s as date, COUNT(*) as count
 		FROM tickets 
 		WHERE created_at >= ? AND created_at <= ?
-		GROUP BY DATE(created_at)
+		GROUP BY %s
 		ORDER BY date
-	`, startDate, endDate).Scan(&results).Error
+	`, day, day), startDate, endDate).Scan(&results).Error
 	
 	if err != nil {
 		return nil, err
@@ -537,7 +539,7 @@ func (s *AnalyticsService) getDailyTicketTrend(ctx context.Context, startDate, e
 	trend := make([]DailyCount, len(results))
 	for i, r := range results {
 		trend[i] = DailyCount{
-			Date:  r.Date,
+			Date:  r.Date.Time,
 			Count: r.Count,
 		}
 	}
@@ -548,17 +550,18 @@ func (s *AnalyticsService) getDailyTicketTrend(ctx context.Context, startDate, e
 // getDailyUserActivityTrend 获取每日用户活动趋势
 func (s *AnalyticsService) getDailyUserActivityTrend(ctx context.Context, startDate, endDate time.Time) ([]DailyCount, error) {
 	var results []struct {
-		Date  time.Time `gorm:"column:date"`
-		Count int64     `gorm:"column:count"`
+		Date  SQLDate `gorm:"column:date"`
+		Count int64   `gorm:"column:count"`
 	}
 	
-	err := s.db.WithContext(ctx).Raw(`
-		SELECT DATE(login_time) as date, COUNT(DISTINCT user_id) as count
+	day := DialectOf(s.db).Date("login_time")
+	err := s.db.WithContext(ctx).Raw(fmt.Sprintf(`
+		SELECT %s as date, COUNT(DISTINCT user_id) as count
 		FROM login_histories 
 		WHERE login_time >= ? AND login_time <= ?
-		GROUP BY DATE(login_time)
+		GROUP BY %s
 		ORDER BY date
-	`, startDate, endDate).Scan(&results).Error
+	`, day, day), startDate, endDate).Scan(&results).Error
 	
 	if err != nil {
 		return nil, err
@@ -567,7 +570,7 @@ func (s *AnalyticsService) getDailyUserActivityTrend(ctx context.Context, startD
 	trend := make([]DailyCount, len(results))
 	for i, r := range results {
 		trend[i] = DailyCount{
-			Date:  r.Date,
+			Date:  r.Date.Time,
 			Count: r.Count,
 		}
 	}
@@ -578,17 +581,18 @@ func (s *AnalyticsService) getDailyUserActivityTrend(ctx context.Context, startD
 // getDailyCommentTrend 获取每日评论趋势
 func (s *AnalyticsService) getDailyCommentTrend(ctx context.Context, startDate, endDate time.Time) ([]DailyCount, error) {
 	var results []struct {
-		Date  time.Time `gorm:"column:date"`
-		Count int64     `gorm:"column:count"`
+		Date  SQLDate `gorm:"column:date"`
+		Count int64   `gorm:"column:count"`
 	}
 	
-	err := s.db.WithContext(ctx).Raw(`
-		SELECT DATE(created_at) as date, COUNT(*) as count
+	day := DialectOf(s.db).Date("created_at")
+	err := s.db.WithContext(ctx).Raw(fmt.Sprintf(`
+		SELECT %s as date, COUNT(*) as count
 		FROM ticket_comments 
 		WHERE created_at >= ? AND created_at <= ?
-		GROUP BY DATE(created_at)
+		GROUP BY %s
 		ORDER BY date
-	`, startDate, endDate).Scan(&results).Error
+	`, day, day), startDate, endDate).Scan(&results).Error
 	
 	if err != nil {
 		return nil, err
@@ -597,7 +601,7 @@ func (s *AnalyticsService) getDailyCommentTrend(ctx context.Context, startDate,
 	trend := make([]DailyCount, len(results))
 	for i, r := range results {
 		trend[i] = DailyCount{
-			Date:  r.Date,
+			Date:  r.Date.Time,
 			Count: r.Count,
 		}
 	}
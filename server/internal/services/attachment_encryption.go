@@ -0,0 +1,450 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"gongdan-system/internal/models"
+)
+
+// 附件静态加密参数
+const (
+	// AttachmentCipherAlgorithm 文件按 64KiB 分段以 AES-256-GCM 加密，每段独立认证，
+	// nonce 由随机前缀、段序号及末段标记组成，可检测段的截断、重排与替换
+	AttachmentCipherAlgorithm = "aes-256-gcm-stream"
+
+	attachmentDataKeySize     = 32
+	attachmentSegmentSize     = 64 << 10
+	attachmentNoncePrefixSize = 7
+	attachmentRewrapBatch     = 200
+	attachmentRewrapMaxErrors = 20
+)
+
+var (
+	// ErrAttachmentEncryptionDisabled 未配置附件加密主密钥
+	ErrAttachmentEncryptionDisabled = errors.New("attachment encryption is not configured")
+	// ErrAttachmentKeyNotFound 包装数据密钥的主密钥不可用（已从密钥文件中移除）
+	ErrAttachmentKeyNotFound = errors.New("attachment master key not found")
+	// ErrAttachmentDecrypt 数据密钥解包或文件解密失败，密钥不匹配或内容被篡改
+	ErrAttachmentDecrypt = errors.New("failed to decrypt attachment")
+	// ErrInvalidKeyFile 主密钥文件格式无效
+	ErrInvalidKeyFile = errors.New("invalid attachment key file")
+)
+
+// AttachmentKeyProvider 主密钥提供方，负责包装与解包附件的数据密钥。
+// 本地密钥文件由 LocalKeyProvider 实现；接入 KMS 时实现本接口，主密钥不离开 KMS
+type AttachmentKeyProvider interface {
+	// PrimaryKeyID 新数据密钥使用的主密钥
+	PrimaryKeyID() string
+	// KeyIDs 可用于解包的全部主密钥
+	KeyIDs() []string
+	// WrapKey 用主要主密钥包装数据密钥，返回所用的主密钥ID
+	WrapKey(ctx context.Context, dataKey []byte) (string, []byte, error)
+	// UnwrapKey 用指定的主密钥解包数据密钥
+	UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// attachmentKeyFile 本地密钥文件格式：{"primary": "k2", "keys": {"k1": "<base64>", "k2": "<base64>"}}，
+// 每个主密钥为 32 字节。轮换时添加新密钥并改为 primary，重新包装完成后再移除旧密钥
+type attachmentKeyFile struct {
+	Primary string            `json:"primary"`
+	Keys    map[string]string `json:"keys"`
+}
+
+// LocalKeyProvider 基于本地密钥文件的主密钥提供方，数据密钥以 AES-256-GCM 包装，主密钥ID作为附加认证数据
+type LocalKeyProvider struct {
+	primary string
+	keys    map[string]cipher.AEAD
+}
+
+// LoadLocalKeyProvider 从密钥文件加载主密钥
+func LoadLocalKeyProvider(path string) (*LocalKeyProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	var file attachmentKeyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKeyFile, err)
+	}
+	keys := make(map[string][]byte, len(file.Keys))
+	for id, encoded := range file.Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("%w: key %s is not valid base64", ErrInvalidKeyFile, id)
+		}
+		keys[id] = key
+	}
+	return NewLocalKeyProvider(file.Primary, keys)
+}
+
+// NewLocalKeyProvider 创建本地主密钥提供方，primary 必须是 keys 中的一个
+func NewLocalKeyProvider(primary string, keys map[string][]byte) (*LocalKeyProvider, error) {
+	provider := &LocalKeyProvider{primary: primary, keys: make(map[string]cipher.AEAD, len(keys))}
+	for id, key := range keys {
+		if id == "" || len(id) > 100 {
+			return nil, fmt.Errorf("%w: key id must be 1-100 characters", ErrInvalidKeyFile)
+		}
+		if len(key) != attachmentDataKeySize {
+			return nil, fmt.Errorf("%w: key %s must be %d bytes", ErrInvalidKeyFile, id, attachmentDataKeySize)
+		}
+		aead, err := newAttachmentAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+		provider.keys[id] = aead
+	}
+	if _, ok := provider.keys[primary]; !ok {
+		return nil, fmt.Errorf("%w: primary key %q not found", ErrInvalidKeyFile, primary)
+	}
+	return provider, nil
+}
+
+// PrimaryKeyID 主要主密钥ID
+func (p *LocalKeyProvider) PrimaryKeyID() string { return p.primary }
+
+// KeyIDs 全部主密钥ID
+func (p *LocalKeyProvider) KeyIDs() []string {
+	ids := make([]string, 0, len(p.keys))
+	for id := range p.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// WrapKey 用主要主密钥包装数据密钥，结果为 nonce 与密文的拼接
+func (p *LocalKeyProvider) WrapKey(ctx context.Context, dataKey []byte) (string, []byte, error) {
+	aead := p.keys[p.primary]
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(dataKey)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return p.primary, aead.Seal(nonce, nonce, dataKey, []byte(p.primary)), nil
+}
+
+// UnwrapKey 解包数据密钥
+func (p *LocalKeyProvider) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	aead, ok := p.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrAttachmentKeyNotFound, keyID)
+	}
+	if len(wrapped) < aead.NonceSize() {
+		return nil, ErrAttachmentDecrypt
+	}
+	dataKey, err := aead.Open(nil, wrapped[:aead.NonceSize()], wrapped[aead.NonceSize():], []byte(keyID))
+	if err != nil {
+		return nil, ErrAttachmentDecrypt
+	}
+	return dataKey, nil
+}
+
+// AttachmentCipher 附件加解密：每个文件生成独立的数据密钥，数据密钥由主密钥包装后保存在附件记录中
+type AttachmentCipher struct {
+	keys AttachmentKeyProvider
+}
+
+// NewAttachmentCipher 创建附件加解密器
+func NewAttachmentCipher(keys AttachmentKeyProvider) *AttachmentCipher {
+	return &AttachmentCipher{keys: keys}
+}
+
+// Encrypt 返回加密 r 的读取器及需随附件保存的加密元数据，文件内容边读边加密
+func (c *AttachmentCipher) Encrypt(ctx context.Context, r io.Reader, now time.Time) (io.Reader, *models.AttachmentEncryption, error) {
+	dataKey := make([]byte, attachmentDataKeySize)
+	prefix := make([]byte, attachmentNoncePrefixSize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, nil, fmt.Errorf("failed to generate data key: %w", err)
+	}
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, nil, fmt.Errorf("failed to generate nonce prefix: %w", err)
+	}
+	aead, err := newAttachmentAEAD(dataKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyID, wrapped, err := c.keys.WrapKey(ctx, dataKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+
+	encryption := &models.AttachmentEncryption{
+		Algorithm:   AttachmentCipherAlgorithm,
+		KeyID:       keyID,
+		WrappedKey:  base64.StdEncoding.EncodeToString(wrapped),
+		NoncePrefix: base64.StdEncoding.EncodeToString(prefix),
+		WrappedAt:   &now,
+	}
+	reader := &segmentReader{
+		aead:    aead,
+		prefix:  prefix,
+		src:     bufio.NewReaderSize(r, attachmentSegmentSize),
+		segment: make([]byte, attachmentSegmentSize),
+		encrypt: true,
+	}
+	return reader, encryption, nil
+}
+
+// Decrypt 返回解密 r 的读取器，关闭时关闭 r。数据密钥在返回前解包，密钥不可用时立即报错；
+// 内容被篡改或截断时读取返回 ErrAttachmentDecrypt
+func (c *AttachmentCipher) Decrypt(ctx context.Context, encryption models.AttachmentEncryption, r io.ReadCloser) (io.ReadCloser, error) {
+	if encryption.Algorithm != AttachmentCipherAlgorithm {
+		return nil, fmt.Errorf("%w: unsupported algorithm %q", ErrAttachmentDecrypt, encryption.Algorithm)
+	}
+	dataKey, err := c.unwrap(ctx, encryption)
+	if err != nil {
+		return nil, err
+	}
+	prefix, err := base64.StdEncoding.DecodeString(encryption.NoncePrefix)
+	if err != nil || len(prefix) != attachmentNoncePrefixSize {
+		return nil, fmt.Errorf("%w: invalid nonce prefix", ErrAttachmentDecrypt)
+	}
+	aead, err := newAttachmentAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	reader := &segmentReader{
+		aead:    aead,
+		prefix:  prefix,
+		src:     bufio.NewReaderSize(r, attachmentSegmentSize+aead.Overhead()),
+		segment: make([]byte, attachmentSegmentSize+aead.Overhead()),
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{reader, r}, nil
+}
+
+// Rewrap 用主要主密钥重新包装数据密钥，文件内容不变。已由主要主密钥包装时返回 false
+func (c *AttachmentCipher) Rewrap(ctx context.Context, encryption *models.AttachmentEncryption, now time.Time) (bool, error) {
+	if encryption.KeyID == c.keys.PrimaryKeyID() {
+		return false, nil
+	}
+	dataKey, err := c.unwrap(ctx, *encryption)
+	if err != nil {
+		return false, err
+	}
+	keyID, wrapped, err := c.keys.WrapKey(ctx, dataKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to wrap data key: %w", err)
+	}
+	encryption.KeyID = keyID
+	encryption.WrappedKey = base64.StdEncoding.EncodeToString(wrapped)
+	encryption.WrappedAt = &now
+	return true, nil
+}
+
+func (c *AttachmentCipher) unwrap(ctx context.Context, encryption models.AttachmentEncryption) ([]byte, error) {
+	wrapped, err := base64.StdEncoding.DecodeString(encryption.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid wrapped key", ErrAttachmentDecrypt)
+	}
+	dataKey, err := c.keys.UnwrapKey(ctx, encryption.KeyID, wrapped)
+	if err != nil {
+		return nil, err
+	}
+	if len(dataKey) != attachmentDataKeySize {
+		return nil, fmt.Errorf("%w: invalid data key", ErrAttachmentDecrypt)
+	}
+	return dataKey, nil
+}
+
+func newAttachmentAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return aead, nil
+}
+
+// segmentReader 分段加密或解密。每次处理一段，读到源末尾的段标记为末段；
+// 解密时末段缺失（文件被截断）或末段后仍有数据都会认证失败
+type segmentReader struct {
+	aead    cipher.AEAD
+	prefix  []byte
+	src     *bufio.Reader
+	segment []byte
+	buf     []byte // 复用的输出缓冲
+	out     []byte // 尚未读走的输出
+	counter uint32
+	encrypt bool
+	done    bool
+	err     error
+}
+
+func (r *segmentReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.done {
+			return 0, io.EOF
+		}
+		r.err = r.next()
+	}
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+// next 读取并处理下一段
+func (r *segmentReader) next() error {
+	n, err := io.ReadFull(r.src, r.segment)
+	last := false
+	switch {
+	case err == io.EOF || err == io.ErrUnexpectedEOF:
+		last = true
+	case err != nil:
+		return err
+	default:
+		if _, err := r.src.Peek(1); err == io.EOF {
+			last = true
+		} else if err != nil {
+			return err
+		}
+	}
+
+	nonce := make([]byte, 0, r.aead.NonceSize())
+	nonce = append(nonce, r.prefix...)
+	nonce = binary.BigEndian.AppendUint32(nonce, r.counter)
+	if last {
+		nonce = append(nonce, 1)
+	} else {
+		nonce = append(nonce, 0)
+	}
+	r.counter++
+	r.done = last
+
+	if r.encrypt {
+		r.buf = r.aead.Seal(r.buf[:0], nonce, r.segment[:n], nil)
+	} else if r.buf, err = r.aead.Open(r.buf[:0], nonce, r.segment[:n], nil); err != nil {
+		return ErrAttachmentDecrypt
+	}
+	r.out = r.buf
+	return nil
+}
+
+// attachmentKeyRow 重新包装时读取的加密元数据
+type attachmentKeyRow struct {
+	ID          uint
+	Algorithm   string `gorm:"column:encryption_algorithm"`
+	KeyID       string `gorm:"column:encryption_key_id"`
+	WrappedKey  string `gorm:"column:encryption_wrapped_key"`
+	NoncePrefix string `gorm:"column:encryption_nonce_prefix"`
+}
+
+// EncryptionStatus 统计附件的加密情况：各主密钥包装的附件数、未加密的附件数及待重新包装数
+func (s *TicketAttachmentService) EncryptionStatus(ctx context.Context) (*models.AttachmentEncryptionStatus, error) {
+	status := &models.AttachmentEncryptionStatus{Enabled: s.cipher != nil, ByKeyID: map[string]int64{}}
+	if s.cipher != nil {
+		status.PrimaryKeyID = s.cipher.keys.PrimaryKeyID()
+		status.KeyIDs = s.cipher.keys.KeyIDs()
+	}
+
+	for _, table := range []string{"ticket_attachments", "pending_uploads"} {
+		var rows []struct {
+			KeyID string
+			Count int64
+		}
+		if err := s.db.WithContext(ctx).Table(table).
+			Select("encryption_key_id AS key_id, COUNT(*) AS count").
+			Where("encryption_algorithm <> ''").
+			Group("encryption_key_id").
+			Scan(&rows).Error; err != nil {
+			return nil, fmt.Errorf("failed to count encrypted attachments: %w", err)
+		}
+		for _, row := range rows {
+			if table == "ticket_attachments" {
+				status.ByKeyID[row.KeyID] += row.Count
+			}
+			if row.KeyID != status.PrimaryKeyID {
+				status.PendingRewrap += row.Count
+			}
+		}
+	}
+
+	if err := s.db.WithContext(ctx).Model(&models.TicketAttachment{}).
+		Where("encryption_algorithm IS NULL OR encryption_algorithm = ''").
+		Count(&status.Unencrypted).Error; err != nil {
+		return nil, fmt.Errorf("failed to count unencrypted attachments: %w", err)
+	}
+	return status, nil
+}
+
+// RewrapKeys 用主要主密钥重新包装其他主密钥包装的数据密钥，文件内容无需重新加密。
+// 轮换主密钥时先在密钥文件中添加新密钥并设为 primary，重新包装完成且无失败后再移除旧密钥
+func (s *TicketAttachmentService) RewrapKeys(ctx context.Context, now time.Time) (*models.AttachmentRewrapResult, error) {
+	if s.cipher == nil {
+		return nil, ErrAttachmentEncryptionDisabled
+	}
+	result := &models.AttachmentRewrapResult{PrimaryKeyID: s.cipher.keys.PrimaryKeyID()}
+	for _, table := range []string{"ticket_attachments", "pending_uploads"} {
+		if err := s.rewrapTable(ctx, table, result, now); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// rewrapTable 分批重新包装表中的数据密钥，单个附件失败（如主密钥已移除）时记录并继续
+func (s *TicketAttachmentService) rewrapTable(ctx context.Context, table string, result *models.AttachmentRewrapResult, now time.Time) error {
+	var lastID uint
+	for {
+		var rows []attachmentKeyRow
+		if err := s.db.WithContext(ctx).Table(table).
+			Select("id", "encryption_algorithm", "encryption_key_id", "encryption_wrapped_key", "encryption_nonce_prefix").
+			Where("id > ? AND encryption_algorithm <> '' AND encryption_key_id <> ?", lastID, result.PrimaryKeyID).
+			Order("id ASC").
+			Limit(attachmentRewrapBatch).
+			Scan(&rows).Error; err != nil {
+			return fmt.Errorf("failed to find attachments to rewrap: %w", err)
+		}
+
+		for _, row := range rows {
+			lastID = row.ID
+			encryption := models.AttachmentEncryption{
+				Algorithm:   row.Algorithm,
+				KeyID:       row.KeyID,
+				WrappedKey:  row.WrappedKey,
+				NoncePrefix: row.NoncePrefix,
+			}
+			if _, err := s.cipher.Rewrap(ctx, &encryption, now); err != nil {
+				result.Failed++
+				if len(result.Errors) < attachmentRewrapMaxErrors {
+					result.Errors = append(result.Errors, fmt.Sprintf("%s #%d: %v", table, row.ID, err))
+				}
+				continue
+			}
+			// 仅在包装未被并发修改时更新，避免覆盖另一次轮换的结果
+			if err := s.db.WithContext(ctx).Table(table).
+				Where("id = ? AND encryption_key_id = ?", row.ID, row.KeyID).
+				Updates(map[string]interface{}{
+					"encryption_key_id":      encryption.KeyID,
+					"encryption_wrapped_key": encryption.WrappedKey,
+					"encryption_wrapped_at":  encryption.WrappedAt,
+				}).Error; err != nil {
+				return fmt.Errorf("failed to update wrapped key: %w", err)
+			}
+			result.Rewrapped++
+		}
+		if len(rows) < attachmentRewrapBatch {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestAttachmentEncryption_EncryptedAtRestAndKeyRotation(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:attachment_encryption_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Category{}, &models.Ticket{}, &models.TicketHistory{}, &models.TicketAttachment{},
+		&models.PendingUpload{}, &models.SystemConfig{}, &models.QuotaAlert{}, &models.Notification{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+	ctx := context.Background()
+
+	user := models.User{Username: "enc-user", Email: "enc-user@example.com", PasswordHash: "x", Role: models.RoleAgent, Status: models.UserStatusActive}
+	db.Create(&user)
+	ticket := models.Ticket{TicketNumber: "T-ENC-1", Title: "encrypted", Status: models.TicketStatusOpen, CreatedByID: user.ID}
+	db.Create(&ticket)
+
+	k1, k2 := make([]byte, 32), make([]byte, 32)
+	rand.Read(k1)
+	rand.Read(k2)
+	keyFile := filepath.Join(t.TempDir(), "keys.json")
+	os.WriteFile(keyFile, []byte(`{"primary": "k1", "keys": {"k1": "`+base64.StdEncoding.EncodeToString(k1)+`"}}`), 0o600)
+	keys, err := LoadLocalKeyProvider(keyFile)
+	if err != nil {
+		t.Fatalf("load key file failed: %v", err)
+	}
+	if _, err := NewLocalKeyProvider("k2", map[string][]byte{"k1": k1}); !errors.Is(err, ErrInvalidKeyFile) {
+		t.Fatalf("expected missing primary key to be rejected, got %v", err)
+	}
+
+	baseDir := t.TempDir()
+	svc := NewTicketAttachmentService(db, NewLocalFileStorage(baseDir, "/uploads"))
+	svc.SetCipher(NewAttachmentCipher(keys))
+
+	// 跨多个分段的文件加密存储，下载时透明解密
+	content := append([]byte("%PDF-1.4\n"), bytes.Repeat([]byte("secret "), 3*attachmentSegmentSize/7)...)
+	attachment, err := svc.Upload(ctx, ticket.ID, user.ID, "report.pdf", int64(len(content)), bytes.NewReader(content))
+	if err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+	if attachment.Encryption.Algorithm != AttachmentCipherAlgorithm || attachment.Encryption.KeyID != "k1" || attachment.MimeType != "application/pdf" || attachment.FileSize != int64(len(content)) {
+		t.Fatalf("unexpected attachment %+v", attachment)
+	}
+	stored, _ := os.ReadFile(filepath.Join(baseDir, filepath.FromSlash(attachment.StoragePath)))
+	if bytes.Contains(stored, []byte("secret")) || bytes.HasPrefix(stored, []byte("%PDF")) {
+		t.Fatal("expected attachment to be encrypted at rest")
+	}
+	readAll := func() ([]byte, error) {
+		_, reader, err := svc.Open(ctx, ticket.ID, attachment.ID)
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		return io.ReadAll(reader)
+	}
+	if got, err := readAll(); err != nil || !bytes.Equal(got, content) {
+		t.Fatalf("expected decrypted content, got %d bytes, %v", len(got), err)
+	}
+
+	// 篡改或截断的文件解密失败
+	path := filepath.Join(baseDir, filepath.FromSlash(attachment.StoragePath))
+	os.WriteFile(path, stored[:attachmentSegmentSize+16], 0o644)
+	if _, err := readAll(); !errors.Is(err, ErrAttachmentDecrypt) {
+		t.Fatalf("expected truncated file to fail, got %v", err)
+	}
+	tampered := append([]byte(nil), stored...)
+	tampered[len(tampered)-1] ^= 1
+	os.WriteFile(path, tampered, 0o644)
+	if _, err := readAll(); !errors.Is(err, ErrAttachmentDecrypt) {
+		t.Fatalf("expected tampered file to fail, got %v", err)
+	}
+	os.WriteFile(path, stored, 0o644)
+
+	// 未配置主密钥时加密的附件无法下载
+	if _, _, err := NewTicketAttachmentService(db, NewLocalFileStorage(baseDir, "/uploads")).Open(ctx, ticket.ID, attachment.ID); !errors.Is(err, ErrAttachmentEncryptionDisabled) {
+		t.Fatalf("expected encrypted attachment to require a key, got %v", err)
+	}
+
+	// 轮换：新主密钥包装数据密钥，文件内容不变，旧密钥移除后仍可下载
+	rotated, _ := NewLocalKeyProvider("k2", map[string][]byte{"k1": k1, "k2": k2})
+	svc.SetCipher(NewAttachmentCipher(rotated))
+	status, err := svc.EncryptionStatus(ctx)
+	if err != nil || status.PrimaryKeyID != "k2" || status.ByKeyID["k1"] != 1 || status.PendingRewrap != 1 {
+		t.Fatalf("unexpected status before rewrap %+v, %v", status, err)
+	}
+	result, err := svc.RewrapKeys(ctx, time.Now())
+	if err != nil || result.Rewrapped != 1 || result.Failed != 0 {
+		t.Fatalf("unexpected rewrap result %+v, %v", result, err)
+	}
+	if again, _ := svc.RewrapKeys(ctx, time.Now()); again.Rewrapped != 0 {
+		t.Fatalf("expected rewrap to be idempotent, got %+v", again)
+	}
+	onlyK2, _ := NewLocalKeyProvider("k2", map[string][]byte{"k2": k2})
+	svc.SetCipher(NewAttachmentCipher(onlyK2))
+	if got, err := readAll(); err != nil || !bytes.Equal(got, content) {
+		t.Fatalf("expected content after rotation, got %d bytes, %v", len(got), err)
+	}
+	if after, _ := os.ReadFile(path); !bytes.Equal(after, stored) {
+		t.Fatal("expected rewrap to leave the stored file unchanged")
+	}
+	status, _ = svc.EncryptionStatus(ctx)
+	if status.ByKeyID["k2"] != 1 || status.PendingRewrap != 0 {
+		t.Fatalf("unexpected status after rewrap %+v", status)
+	}
+}
@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	auditRedacted       = "[REDACTED]"
+	auditMaxRequestBody = 4000 // 审计日志保存的请求体最大字符数（脱敏后）
+)
+
+// auditSecretMarkers 字段名包含这些片段时按敏感信息脱敏
+var auditSecretMarkers = []string{"password", "secret", "token", "private_key", "api_key", "apikey", "backup_codes", "credential"}
+
+// AuditChange 审计日志中的字段变更，敏感字段的新旧值均脱敏
+type AuditChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// auditScope 单个请求的审计上下文：操作人、客户端信息和服务层记录的字段变更
+type auditScope struct {
+	userID    *uint
+	clientIP  string
+	userAgent string
+
+	mu      sync.Mutex
+	changes []AuditChange
+}
+
+type auditScopeKey struct{}
+
+// WithAuditScope 在请求上下文中附加审计信息，服务层据此记录操作人、客户端和字段变更
+func WithAuditScope(ctx context.Context, userID *uint, clientIP, userAgent string) context.Context {
+	return context.WithValue(ctx, auditScopeKey{}, &auditScope{userID: userID, clientIP: clientIP, userAgent: userAgent})
+}
+
+func auditScopeFrom(ctx context.Context) *auditScope {
+	if ctx == nil {
+		return nil
+	}
+	scope, _ := ctx.Value(auditScopeKey{}).(*auditScope)
+	return scope
+}
+
+// AuditActorFrom 获取请求上下文中的操作人和客户端信息
+func AuditActorFrom(ctx context.Context) (userID *uint, clientIP, userAgent string) {
+	scope := auditScopeFrom(ctx)
+	if scope == nil {
+		return nil, "", ""
+	}
+	return scope.userID, scope.clientIP, scope.userAgent
+}
+
+// AddAuditChanges 记录本次请求的字段变更，由审计中间件写入操作日志；上下文没有审计信息时忽略
+func AddAuditChanges(ctx context.Context, changes ...AuditChange) {
+	scope := auditScopeFrom(ctx)
+	if scope == nil || len(changes) == 0 {
+		return
+	}
+	scope.mu.Lock()
+	scope.changes = append(scope.changes, changes...)
+	scope.mu.Unlock()
+}
+
+// AuditChangesFrom 获取本次请求记录的字段变更
+func AuditChangesFrom(ctx context.Context) []AuditChange {
+	scope := auditScopeFrom(ctx)
+	if scope == nil {
+		return nil
+	}
+	scope.mu.Lock()
+	defer scope.mu.Unlock()
+	return append([]AuditChange(nil), scope.changes...)
+}
+
+// DiffAuditFields 比较修改前后的字段，返回按字段名排序的变更；after 中没有的字段视为未修改
+func DiffAuditFields(before, after map[string]interface{}) []AuditChange {
+	var changes []AuditChange
+	for field, newValue := range after {
+		oldValue := before[field]
+		if auditValueEqual(oldValue, newValue) {
+			continue
+		}
+		changes = append(changes, AuditChange{Field: field, Old: oldValue, New: newValue})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+func auditValueEqual(a, b interface{}) bool {
+	a, b = derefAuditValue(a), derefAuditValue(b)
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return fmt.Sprint(a) == fmt.Sprint(b)
+}
+
+func derefAuditValue(value interface{}) interface{} {
+	v := reflect.ValueOf(value)
+	for v.IsValid() && v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil
+	}
+	return v.Interface()
+}
+
+// isSecretAuditField 字段或配置键是否为敏感信息
+func isSecretAuditField(field string) bool {
+	if entry, ok := LookupConfigSchema(field); ok && entry.Secret {
+		return true
+	}
+	lower := strings.ToLower(field)
+	for _, marker := range auditSecretMarkers {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactAuditChanges 敏感字段只保留“已修改”的事实
+func redactAuditChanges(changes []AuditChange) []AuditChange {
+	redacted := make([]AuditChange, len(changes))
+	for i, change := range changes {
+		change.Old, change.New = derefAuditValue(change.Old), derefAuditValue(change.New)
+		if isSecretAuditField(change.Field) {
+			if change.Old != nil && change.Old != "" {
+				change.Old = auditRedacted
+			}
+			if change.New != nil && change.New != "" {
+				change.New = auditRedacted
+			}
+		}
+		redacted[i] = change
+	}
+	return redacted
+}
+
+// RedactAuditBody 脱敏 JSON 请求体：敏感键名的值，以及 {"key": 敏感配置键, "value": ...} 中的值。
+// 非 JSON 请求体只记录长度
+func RedactAuditBody(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return fmt.Sprintf("[non-JSON body, %d bytes]", len(body))
+	}
+	data, _ := json.Marshal(redactAuditValue(value))
+	return clipAuditString(string(data), auditMaxRequestBody)
+}
+
+func redactAuditValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		configKey, _ := v["key"].(string)
+		secretConfig := configKey != "" && isSecretAuditField(configKey)
+		for key, item := range v {
+			if isSecretAuditField(key) || (secretConfig && key == "value") {
+				v[key] = auditRedacted
+				continue
+			}
+			v[key] = redactAuditValue(item)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactAuditValue(item)
+		}
+		return v
+	}
+	return value
+}
+
+// clipAuditString 按字符截断，避免超出列长度或截断多字节字符
+func clipAuditString(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:max])
+}
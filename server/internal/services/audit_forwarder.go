@@ -0,0 +1,596 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+// KeyAuditForwarding 审计事件转发配置键
+const KeyAuditForwarding = "security.audit_forwarding"
+
+// auditForwardingConfigRefresh 转发配置刷新间隔
+const auditForwardingConfigRefresh = 30 * time.Second
+
+// auditEventCSVHeader 审计事件 CSV 列
+var auditEventCSVHeader = []string{
+	"time", "type", "action", "result", "user_id", "username", "role",
+	"client_ip", "user_agent", "method", "path", "status_code", "detail",
+}
+
+// AuditEvent 转发到 SIEM 的审计事件
+type AuditEvent struct {
+	Time       time.Time `json:"time"`
+	Type       string    `json:"type"`
+	Action     string    `json:"action"`
+	Result     string    `json:"result"`
+	UserID     *uint     `json:"user_id,omitempty"`
+	Username   string    `json:"username,omitempty"`
+	Role       string    `json:"role,omitempty"`
+	ClientIP   string    `json:"client_ip,omitempty"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	Method     string    `json:"method,omitempty"`
+	Path       string    `json:"path,omitempty"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Detail     string    `json:"detail,omitempty"`
+}
+
+// CSVRecord 按 auditEventCSVHeader 的列顺序输出
+func (e *AuditEvent) CSVRecord() []string {
+	userID := ""
+	if e.UserID != nil {
+		userID = strconv.FormatUint(uint64(*e.UserID), 10)
+	}
+	statusCode := ""
+	if e.StatusCode != 0 {
+		statusCode = strconv.Itoa(e.StatusCode)
+	}
+	return []string{
+		e.Time.UTC().Format(time.RFC3339Nano), e.Type, e.Action, e.Result, userID, e.Username, e.Role,
+		e.ClientIP, e.UserAgent, e.Method, e.Path, statusCode, e.Detail,
+	}
+}
+
+// writeAuditCSV 将事件写为 CSV，header 为 true 时先写表头
+func writeAuditCSV(w io.Writer, events []*AuditEvent, header bool) error {
+	writer := csv.NewWriter(w)
+	if header {
+		if err := writer.Write(auditEventCSVHeader); err != nil {
+			return err
+		}
+	}
+	for _, event := range events {
+		if err := writer.Write(event.CSVRecord()); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// AuditForwarderStats 转发状态统计
+type AuditForwarderStats struct {
+	Enabled    bool       `json:"enabled"`
+	Buffered   int        `json:"buffered"`
+	Sent       int64      `json:"sent"`
+	Dropped    int64      `json:"dropped"`
+	Failures   int64      `json:"failures"`
+	LastError  string     `json:"last_error,omitempty"`
+	LastSentAt *time.Time `json:"last_sent_at,omitempty"`
+}
+
+// auditSink 审计事件发送目标
+type auditSink interface {
+	Send(ctx context.Context, events []*AuditEvent) error
+	Close() error
+}
+
+// AuditForwarder 审计事件转发器
+// 事件写入内存缓冲后立即返回，不阻塞请求；后台按批发送，失败时保留缓冲并指数退避重试，
+// 缓冲写满时丢弃最旧的事件，以限制下游不可用时的内存占用。
+type AuditForwarder struct {
+	db *gorm.DB
+
+	mu         sync.Mutex
+	config     *models.AuditForwardingConfig
+	configAt   time.Time
+	buffer     []*AuditEvent
+	sink       auditSink
+	sinkConfig string
+	stats      AuditForwarderStats
+
+	wake chan struct{}
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// NewAuditForwarder 创建审计事件转发器
+func NewAuditForwarder(db *gorm.DB) *AuditForwarder {
+	return &AuditForwarder{
+		db:   db,
+		wake: make(chan struct{}, 1),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+}
+
+// GetConfig 从配置存储读取转发配置
+func (f *AuditForwarder) GetConfig(ctx context.Context) (*models.AuditForwardingConfig, error) {
+	var config models.SystemConfig
+	err := f.db.WithContext(ctx).
+		Where("key = ? AND is_active = ?", KeyAuditForwarding, true).
+		First(&config).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.GetDefaultAuditForwardingConfig(), nil
+		}
+		return nil, fmt.Errorf("failed to get audit forwarding config: %w", err)
+	}
+
+	forwarding := models.GetDefaultAuditForwardingConfig()
+	if err := config.GetJSONValue(forwarding); err != nil {
+		log.Printf("Warning: failed to parse audit forwarding config, using defaults: %v", err)
+		return models.GetDefaultAuditForwardingConfig(), nil
+	}
+
+	return forwarding, nil
+}
+
+// SetConfig 保存转发配置并立即生效；令牌为空时保留原有令牌
+func (f *AuditForwarder) SetConfig(ctx context.Context, forwarding *models.AuditForwardingConfig, userID uint) error {
+	if err := forwarding.Validate(); err != nil {
+		return err
+	}
+
+	var existing models.SystemConfig
+	err := f.db.WithContext(ctx).Where("key = ?", KeyAuditForwarding).First(&existing).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to check existing audit forwarding config: %w", err)
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		config := models.SystemConfig{
+			Key:         KeyAuditForwarding,
+			Category:    CategorySecurity,
+			Group:       "audit",
+			Description: "审计事件转发（SIEM）",
+			IsActive:    true,
+			UpdatedBy:   &userID,
+		}
+		if err := config.SetValue(forwarding); err != nil {
+			return fmt.Errorf("failed to set audit forwarding config value: %w", err)
+		}
+		if err := f.db.WithContext(ctx).Create(&config).Error; err != nil {
+			return fmt.Errorf("failed to create audit forwarding config: %w", err)
+		}
+	} else {
+		if forwarding.Token == "" {
+			previous := models.GetDefaultAuditForwardingConfig()
+			if err := existing.GetJSONValue(previous); err == nil {
+				forwarding.Token = previous.Token
+			}
+		}
+		if err := existing.SetValue(forwarding); err != nil {
+			return fmt.Errorf("failed to set audit forwarding config value: %w", err)
+		}
+		existing.UpdatedBy = &userID
+		existing.Version++
+
+		if err := f.db.WithContext(ctx).Save(&existing).Error; err != nil {
+			return fmt.Errorf("failed to update audit forwarding config: %w", err)
+		}
+	}
+
+	f.mu.Lock()
+	f.config = forwarding
+	f.configAt = time.Now()
+	f.mu.Unlock()
+	f.signal()
+
+	return nil
+}
+
+// currentConfig 获取缓存的转发配置；读取失败时沿用上一次的配置
+func (f *AuditForwarder) currentConfig() *models.AuditForwardingConfig {
+	f.mu.Lock()
+	cached, cachedAt := f.config, f.configAt
+	f.mu.Unlock()
+
+	if cached != nil && time.Since(cachedAt) < auditForwardingConfigRefresh {
+		return cached
+	}
+
+	config, err := f.GetConfig(context.Background())
+	if err != nil {
+		log.Printf("Warning: failed to refresh audit forwarding config: %v", err)
+		if cached != nil {
+			return cached
+		}
+		return models.GetDefaultAuditForwardingConfig()
+	}
+
+	f.mu.Lock()
+	f.config = config
+	f.configAt = time.Now()
+	f.mu.Unlock()
+
+	return config
+}
+
+// Emit 写入审计事件；未开启转发或事件类型未订阅时直接忽略
+func (f *AuditForwarder) Emit(event *AuditEvent) {
+	if f == nil || event == nil {
+		return
+	}
+	config := f.currentConfig()
+	if !config.ForwardsEvent(event.Type) {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	f.mu.Lock()
+	if overflow := len(f.buffer) + 1 - config.BufferSize; overflow > 0 {
+		f.buffer = f.buffer[overflow:]
+		f.stats.Dropped += int64(overflow)
+	}
+	f.buffer = append(f.buffer, event)
+	full := len(f.buffer) >= config.BatchSize
+	f.mu.Unlock()
+
+	if full {
+		f.signal()
+	}
+}
+
+func (f *AuditForwarder) signal() {
+	select {
+	case f.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start 启动后台发送协程
+func (f *AuditForwarder) Start() {
+	go f.run()
+}
+
+// Stop 停止后台发送，并尽力发送剩余事件
+func (f *AuditForwarder) Stop() {
+	f.once.Do(func() {
+		close(f.stop)
+		<-f.done
+	})
+}
+
+func (f *AuditForwarder) run() {
+	defer close(f.done)
+
+	backoff := time.Duration(0)
+	for {
+		config := f.currentConfig()
+		wait := time.Duration(config.FlushIntervalSeconds) * time.Second
+		if backoff > 0 {
+			wait = backoff
+		}
+
+		select {
+		case <-f.stop:
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			f.flush(ctx, config)
+			cancel()
+			f.closeSink()
+			return
+		case <-f.wake:
+			if backoff > 0 {
+				continue
+			}
+		case <-time.After(wait):
+		}
+
+		if err := f.flush(context.Background(), config); err != nil {
+			backoff = nextAuditBackoff(backoff)
+			continue
+		}
+		backoff = 0
+	}
+}
+
+// nextAuditBackoff 指数退避，1秒起最长1分钟
+func nextAuditBackoff(current time.Duration) time.Duration {
+	if current == 0 {
+		return time.Second
+	}
+	if next := current * 2; next < time.Minute {
+		return next
+	}
+	return time.Minute
+}
+
+// flush 按批发送缓冲中的事件，每批失败时重试 MaxRetries 次，仍失败则保留在缓冲中等待退避后重试
+func (f *AuditForwarder) flush(ctx context.Context, config *models.AuditForwardingConfig) error {
+	if !config.Enabled {
+		f.mu.Lock()
+		f.buffer = nil
+		f.mu.Unlock()
+		f.closeSink()
+		return nil
+	}
+
+	for {
+		f.mu.Lock()
+		n := len(f.buffer)
+		if n > config.BatchSize {
+			n = config.BatchSize
+		}
+		batch := append([]*AuditEvent(nil), f.buffer[:n]...)
+		f.mu.Unlock()
+		if len(batch) == 0 {
+			return nil
+		}
+
+		var err error
+		for attempt := 0; attempt <= config.MaxRetries; attempt++ {
+			if attempt > 0 {
+				select {
+				case <-time.After(time.Duration(attempt) * 200 * time.Millisecond):
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}
+			if err = f.send(ctx, config, batch); err == nil {
+				break
+			}
+		}
+
+		f.mu.Lock()
+		if err != nil {
+			f.stats.Failures++
+			f.stats.LastError = err.Error()
+			f.mu.Unlock()
+			log.Printf("Failed to forward %d audit events: %v", len(batch), err)
+			return err
+		}
+		// 发送期间缓冲可能因溢出丢弃了头部事件，按本批最后一条事件定位已发送部分
+		last := batch[len(batch)-1]
+		for i := 0; i < len(batch) && i < len(f.buffer); i++ {
+			if f.buffer[i] == last {
+				f.buffer = f.buffer[i+1:]
+				break
+			}
+		}
+		now := time.Now()
+		f.stats.Sent += int64(len(batch))
+		f.stats.LastSentAt = &now
+		f.stats.LastError = ""
+		f.mu.Unlock()
+	}
+}
+
+// send 使用与当前配置匹配的发送目标发送一批事件，配置变化时重建连接
+func (f *AuditForwarder) send(ctx context.Context, config *models.AuditForwardingConfig, events []*AuditEvent) error {
+	key := fmt.Sprintf("%s|%s|%t|%t|%s|%s|%s", config.Transport, config.Address, config.TLS, config.TLSSkipVerify, config.URL, config.Token, config.HTTPFormat)
+
+	f.mu.Lock()
+	if f.sink != nil && f.sinkConfig != key {
+		_ = f.sink.Close()
+		f.sink = nil
+	}
+	if f.sink == nil {
+		f.sink = newAuditSink(config)
+		f.sinkConfig = key
+	}
+	sink := f.sink
+	f.mu.Unlock()
+
+	return sink.Send(ctx, events)
+}
+
+func (f *AuditForwarder) closeSink() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.sink != nil {
+		_ = f.sink.Close()
+		f.sink = nil
+	}
+}
+
+// Stats 获取转发状态统计
+func (f *AuditForwarder) Stats() AuditForwarderStats {
+	config := f.currentConfig()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	stats := f.stats
+	stats.Enabled = config.Enabled
+	stats.Buffered = len(f.buffer)
+	return stats
+}
+
+// SendTest 使用给定配置同步发送一条测试事件，用于保存前验证收集器连通性
+func (f *AuditForwarder) SendTest(ctx context.Context, config *models.AuditForwardingConfig) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	sink := newAuditSink(config)
+	defer sink.Close()
+
+	return sink.Send(ctx, []*AuditEvent{{
+		Time:   time.Now(),
+		Type:   models.AuditEventAdminOperation,
+		Action: "audit_forwarding.test",
+		Result: "success",
+		Detail: "SIEM forwarding connectivity test",
+	}})
+}
+
+func newAuditSink(config *models.AuditForwardingConfig) auditSink {
+	if config.Transport == "http" {
+		return &httpAuditSink{
+			url:    config.URL,
+			token:  config.Token,
+			format: config.HTTPFormat,
+			client: &http.Client{Timeout: 10 * time.Second},
+		}
+	}
+	return &syslogAuditSink{
+		address:       config.Address,
+		useTLS:        config.TLS,
+		tlsSkipVerify: config.TLSSkipVerify,
+	}
+}
+
+// syslogAuditSink 通过 TCP/TLS 发送 RFC 5424 syslog 消息（RFC 6587 octet-counting 分帧），消息体为 CSV 行
+type syslogAuditSink struct {
+	address       string
+	useTLS        bool
+	tlsSkipVerify bool
+	conn          net.Conn
+}
+
+func (s *syslogAuditSink) connect(ctx context.Context) error {
+	dialer := &net.Dialer{Timeout: 10 * time.Second}
+	if s.useTLS {
+		conn, err := (&tls.Dialer{
+			NetDialer: dialer,
+			Config:    &tls.Config{InsecureSkipVerify: s.tlsSkipVerify}, // #nosec G402 -- 由管理员显式开启
+		}).DialContext(ctx, "tcp", s.address)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+		return nil
+	}
+	conn, err := dialer.DialContext(ctx, "tcp", s.address)
+	if err != nil {
+		return err
+	}
+	s.conn = conn
+	return nil
+}
+
+func (s *syslogAuditSink) Send(ctx context.Context, events []*AuditEvent) error {
+	if s.conn == nil {
+		if err := s.connect(ctx); err != nil {
+			return fmt.Errorf("failed to connect syslog collector: %w", err)
+		}
+	}
+
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "-"
+	}
+
+	var payload bytes.Buffer
+	for _, event := range events {
+		var line bytes.Buffer
+		if err := writeAuditCSV(&line, []*AuditEvent{event}, false); err != nil {
+			return err
+		}
+		// facility local0(16)，失败事件使用 warning(4)，其余使用 notice(5)
+		severity := 5
+		if event.Result != "success" {
+			severity = 4
+		}
+		message := fmt.Sprintf("<%d>1 %s %s chronodesk - %s - %s",
+			16*8+severity, event.Time.UTC().Format(time.RFC3339Nano), hostname, event.Type,
+			bytes.TrimRight(line.Bytes(), "\n"))
+		fmt.Fprintf(&payload, "%d %s", len(message), message)
+	}
+
+	_ = s.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	if _, err := s.conn.Write(payload.Bytes()); err != nil {
+		_ = s.conn.Close()
+		s.conn = nil
+		return fmt.Errorf("failed to write syslog messages: %w", err)
+	}
+	return nil
+}
+
+func (s *syslogAuditSink) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// httpAuditSink 发送到 HTTP 收集器：csv 格式为带表头的 text/csv，hec 格式为 Splunk HTTP Event Collector 批量事件
+type httpAuditSink struct {
+	url    string
+	token  string
+	format string
+	client *http.Client
+}
+
+func (s *httpAuditSink) Send(ctx context.Context, events []*AuditEvent) error {
+	var body bytes.Buffer
+	contentType := "text/csv; charset=utf-8"
+	authorization := ""
+	if s.token != "" {
+		authorization = "Bearer " + s.token
+	}
+
+	if s.format == "hec" {
+		contentType = "application/json"
+		if s.token != "" {
+			authorization = "Splunk " + s.token
+		}
+		encoder := json.NewEncoder(&body)
+		for _, event := range events {
+			if err := encoder.Encode(map[string]interface{}{
+				"time":       float64(event.Time.UnixNano()) / float64(time.Second),
+				"source":     "chronodesk",
+				"sourcetype": "chronodesk:audit",
+				"event":      event,
+			}); err != nil {
+				return err
+			}
+		}
+	} else if err := writeAuditCSV(&body, events, true); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post audit events: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("collector responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (s *httpAuditSink) Close() error {
+	return nil
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestAuditForwarder_HTTPCSVDelivery(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:audit_forwarder_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.SystemConfig{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+
+	received := make(chan [][]string, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		records, err := csv.NewReader(r.Body).ReadAll()
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		received <- records
+	}))
+	defer collector.Close()
+
+	forwarder := NewAuditForwarder(db)
+	config := models.GetDefaultAuditForwardingConfig()
+	config.Enabled = true
+	config.Transport = "http"
+	config.URL = collector.URL
+	config.Token = "secret"
+	config.BatchSize = 2
+	config.EventTypes = []string{models.AuditEventAuth}
+	if err := forwarder.SetConfig(context.Background(), config, 1); err != nil {
+		t.Fatalf("failed to save config: %v", err)
+	}
+	forwarder.Start()
+	defer forwarder.Stop()
+
+	userID := uint(7)
+	forwarder.Emit(&AuditEvent{Type: models.AuditEventPermissionDenied, Action: "GET /api/admin/users"}) // 未订阅，忽略
+	forwarder.Emit(&AuditEvent{Type: models.AuditEventAuth, Action: "login", Result: "failure", UserID: &userID, Detail: "invalid password"})
+	forwarder.Emit(&AuditEvent{Type: models.AuditEventAuth, Action: "login", Result: "success", UserID: &userID})
+
+	select {
+	case records := <-received:
+		if len(records) != 3 || records[0][0] != "time" {
+			t.Fatalf("unexpected csv payload: %v", records)
+		}
+		if records[1][2] != "login" || records[1][3] != "failure" || records[1][4] != "7" || records[1][12] != "invalid password" {
+			t.Fatalf("unexpected first event row: %v", records[1])
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("collector did not receive audit events")
+	}
+
+	// 收集器响应后统计才会更新
+	deadline := time.Now().Add(2 * time.Second)
+	for forwarder.Stats().Sent != 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if stats := forwarder.Stats(); stats.Sent != 2 || stats.Buffered != 0 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
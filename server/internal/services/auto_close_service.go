@@ -0,0 +1,388 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+// KeyTicketAutoClosePolicy 自动关闭策略配置键
+const KeyTicketAutoClosePolicy = "ticket.auto_close_policy"
+
+// AutoCloseService 已解决工单自动关闭服务
+type AutoCloseService struct {
+	db                  *gorm.DB
+	notificationService NotificationServiceInterface
+	historyWriter       *HistoryWriter
+}
+
+// NewAutoCloseService 创建自动关闭服务
+func NewAutoCloseService(db *gorm.DB) *AutoCloseService {
+	return &AutoCloseService{
+		db:                  db,
+		notificationService: NewNotificationService(db),
+	}
+}
+
+// SetHistoryWriter 设置异步历史写入器，自动关闭提醒等系统历史异步批量写入
+func (s *AutoCloseService) SetHistoryWriter(writer *HistoryWriter) {
+	s.historyWriter = writer
+}
+
+// AutoCloseRunResult 单次执行结果
+type AutoCloseRunResult struct {
+	Scanned       int `json:"scanned"`
+	RemindersSent int `json:"reminders_sent"`
+	Closed        int `json:"closed"`
+	Excluded      int `json:"excluded"`
+	Skipped       int `json:"skipped"` // 客户已回复等原因跳过
+	Failed        int `json:"failed"`
+}
+
+// AutoCloseStats 自动关闭统计
+type AutoCloseStats struct {
+	Since            time.Time `json:"since"`
+	RemindersSent    int64     `json:"reminders_sent"`
+	AutoClosed       int64     `json:"auto_closed"`
+	AwaitingReminder int64     `json:"awaiting_reminder"`
+	AwaitingClose    int64     `json:"awaiting_close"`
+}
+
+const (
+	autoCloseReminderField = "auto_close_reminder"
+	autoCloseField         = "auto_close"
+)
+
+// GetPolicy 获取自动关闭策略
+func (s *AutoCloseService) GetPolicy(ctx context.Context) (*models.AutoClosePolicy, error) {
+	var config models.SystemConfig
+	err := s.db.WithContext(ctx).
+		Where("key = ? AND is_active = ?", KeyTicketAutoClosePolicy, true).
+		First(&config).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.GetDefaultAutoClosePolicy(), nil
+		}
+		return nil, fmt.Errorf("failed to get auto close policy: %w", err)
+	}
+
+	policy := models.GetDefaultAutoClosePolicy()
+	if err := config.GetJSONValue(policy); err != nil {
+		log.Printf("Warning: failed to parse auto close policy, using defaults: %v", err)
+		return models.GetDefaultAutoClosePolicy(), nil
+	}
+
+	if policy.BatchSize <= 0 || policy.BatchSize > 1000 {
+		policy.BatchSize = 100
+	}
+
+	return policy, nil
+}
+
+// SetPolicy 保存自动关闭策略
+func (s *AutoCloseService) SetPolicy(ctx context.Context, policy *models.AutoClosePolicy, userID uint) error {
+	if err := validateAutoCloseThresholds(policy.AutoCloseThresholds); err != nil {
+		return err
+	}
+	for categoryID, thresholds := range policy.CategoryOverrides {
+		if err := validateAutoCloseThresholds(thresholds); err != nil {
+			return fmt.Errorf("category %s: %w", categoryID, err)
+		}
+	}
+
+	var existing models.SystemConfig
+	err := s.db.WithContext(ctx).Where("key = ?", KeyTicketAutoClosePolicy).First(&existing).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to check existing policy: %w", err)
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		config := models.SystemConfig{
+			Key:         KeyTicketAutoClosePolicy,
+			Category:    CategoryTicket,
+			Group:       "workflow",
+			Description: "已解决工单自动关闭策略",
+			IsActive:    true,
+			UpdatedBy:   &userID,
+		}
+		if err := config.SetValue(policy); err != nil {
+			return fmt.Errorf("failed to set policy value: %w", err)
+		}
+		if err := s.db.WithContext(ctx).Create(&config).Error; err != nil {
+			return fmt.Errorf("failed to create policy: %w", err)
+		}
+		return nil
+	}
+
+	if err := existing.SetValue(policy); err != nil {
+		return fmt.Errorf("failed to set policy value: %w", err)
+	}
+	existing.UpdatedBy = &userID
+	existing.Version++
+
+	if err := s.db.WithContext(ctx).Save(&existing).Error; err != nil {
+		return fmt.Errorf("failed to update policy: %w", err)
+	}
+
+	return nil
+}
+
+func validateAutoCloseThresholds(t models.AutoCloseThresholds) error {
+	if t.ReminderAfterDays < 1 || t.ReminderAfterDays > 90 {
+		return fmt.Errorf("reminder_after_days must be between 1 and 90")
+	}
+	if t.CloseAfterDays < 1 || t.CloseAfterDays > 90 {
+		return fmt.Errorf("close_after_days must be between 1 and 90")
+	}
+	return nil
+}
+
+// ProcessStaleResolvedTickets 处理长期无回复的已解决工单：先提醒，再自动关闭
+func (s *AutoCloseService) ProcessStaleResolvedTickets(ctx context.Context) (*AutoCloseRunResult, error) {
+	result := &AutoCloseRunResult{}
+
+	policy, err := s.GetPolicy(ctx)
+	if err != nil {
+		return result, err
+	}
+	if !policy.Enabled {
+		return result, nil
+	}
+
+	now := time.Now()
+	var tickets []models.Ticket
+
+	err = s.db.WithContext(ctx).
+		Where("status = ? AND resolved_at IS NOT NULL", models.TicketStatusResolved).
+		Order("id ASC").
+		FindInBatches(&tickets, policy.BatchSize, func(tx *gorm.DB, batch int) error {
+			for i := range tickets {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				result.Scanned++
+				s.processTicket(ctx, &tickets[i], policy, now, result)
+			}
+			return nil
+		}).Error
+
+	if err != nil {
+		return result, fmt.Errorf("failed to process resolved tickets: %w", err)
+	}
+
+	log.Printf("Auto close processed %d resolved tickets: %d reminders, %d closed, %d excluded",
+		result.Scanned, result.RemindersSent, result.Closed, result.Excluded)
+	return result, nil
+}
+
+func (s *AutoCloseService) processTicket(ctx context.Context, ticket *models.Ticket, policy *models.AutoClosePolicy, now time.Time, result *AutoCloseRunResult) {
+	if isAutoCloseExcluded(ticket, policy) {
+		result.Excluded++
+		return
+	}
+
+	thresholds := policy.ThresholdsFor(ticket.CategoryID)
+
+	if ticket.AutoCloseReminderAt == nil {
+		if now.Sub(*ticket.ResolvedAt) < time.Duration(thresholds.ReminderAfterDays)*24*time.Hour {
+			return
+		}
+		if s.hasCustomerReplySince(ctx, ticket, *ticket.ResolvedAt) {
+			result.Skipped++
+			return
+		}
+		if err := s.sendReminder(ctx, ticket, thresholds, now); err != nil {
+			log.Printf("Failed to send auto close reminder for ticket %d: %v", ticket.ID, err)
+			result.Failed++
+			return
+		}
+		result.RemindersSent++
+		return
+	}
+
+	if now.Sub(*ticket.AutoCloseReminderAt) < time.Duration(thresholds.CloseAfterDays)*24*time.Hour {
+		return
+	}
+	if s.hasCustomerReplySince(ctx, ticket, *ticket.AutoCloseReminderAt) {
+		result.Skipped++
+		return
+	}
+	if err := s.closeTicket(ctx, ticket, now); err != nil {
+		log.Printf("Failed to auto close ticket %d: %v", ticket.ID, err)
+		result.Failed++
+		return
+	}
+	result.Closed++
+}
+
+// isAutoCloseExcluded 检查工单是否命中排除规则（VIP客户、等待审批等）
+func isAutoCloseExcluded(ticket *models.Ticket, policy *models.AutoClosePolicy) bool {
+	for _, tag := range policy.ExcludedTags {
+		if ticket.HasTag(tag) {
+			return true
+		}
+	}
+	for _, email := range policy.ExcludedCustomerEmails {
+		if ticket.CustomerEmail != "" && strings.EqualFold(strings.TrimSpace(email), ticket.CustomerEmail) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasCustomerReplySince 检查客户在指定时间后是否有公开回复
+func (s *AutoCloseService) hasCustomerReplySince(ctx context.Context, ticket *models.Ticket, since time.Time) bool {
+	var count int64
+	s.db.WithContext(ctx).Model(&models.TicketComment{}).
+		Where("ticket_id = ? AND user_id = ? AND type = ? AND created_at > ?",
+			ticket.ID, ticket.CreatedByID, models.CommentTypePublic, since).
+		Count(&count)
+	return count > 0
+}
+
+func (s *AutoCloseService) sendReminder(ctx context.Context, ticket *models.Ticket, thresholds models.AutoCloseThresholds, now time.Time) error {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(ticket).Update("auto_close_reminder_at", now).Error; err != nil {
+			return err
+		}
+
+		history := &models.TicketHistory{
+			TicketID:    ticket.ID,
+			Action:      models.HistoryActionSystem,
+			Description: fmt.Sprintf("工单已解决 %d 天未收到客户回复，已发送自动关闭提醒", thresholds.ReminderAfterDays),
+			FieldName:   autoCloseReminderField,
+			NewValue:    now.Format(time.RFC3339),
+			IsVisible:   true,
+			IsSystem:    true,
+			IsAutomated: true,
+		}
+		return recordHistory(tx, s.historyWriter, history)
+	})
+	if err != nil {
+		return err
+	}
+	ticket.AutoCloseReminderAt = &now
+
+	_, err = s.notificationService.CreateNotification(ctx, &models.NotificationCreateRequest{
+		Type:            models.NotificationTypeTicketResolved,
+		Title:           fmt.Sprintf("工单即将自动关闭 - %s", ticket.Title),
+		Content:         fmt.Sprintf("工单 #%s 已解决，如仍有问题请在 %d 天内回复，否则工单将自动关闭", ticket.TicketNumber, thresholds.CloseAfterDays),
+		Priority:        models.NotificationPriorityNormal,
+		Channel:         models.NotificationChannelInApp,
+		RecipientID:     ticket.CreatedByID,
+		RelatedType:     "ticket",
+		RelatedID:       &ticket.ID,
+		RelatedTicketID: &ticket.ID,
+		ActionURL:       fmt.Sprintf("/tickets/%d", ticket.ID),
+		Metadata: map[string]interface{}{
+			"ticket_number":    ticket.TicketNumber,
+			"close_after_days": thresholds.CloseAfterDays,
+		},
+	})
+	return err
+}
+
+func (s *AutoCloseService) closeTicket(ctx context.Context, ticket *models.Ticket, now time.Time) error {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{
+			"status":     models.TicketStatusClosed,
+			"closed_at":  now,
+			"updated_at": now,
+		}
+		res := tx.Model(&models.Ticket{}).
+			Where("id = ? AND status = ?", ticket.ID, models.TicketStatusResolved).
+			Updates(updates)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return fmt.Errorf("ticket status changed concurrently")
+		}
+
+		comment := &models.TicketComment{
+			TicketID: ticket.ID,
+			UserID:   1, // 系统用户
+			Content:  "工单已解决且长期未收到客户回复，系统已自动关闭。如问题仍存在，请重新提交工单。",
+			Type:     models.CommentTypeSystem,
+		}
+		if err := tx.Create(comment).Error; err != nil {
+			return err
+		}
+
+		history := &models.TicketHistory{
+			TicketID:    ticket.ID,
+			Action:      models.HistoryActionClose,
+			Description: "工单因长期无客户回复被自动关闭",
+			FieldName:   autoCloseField,
+			OldValue:    string(models.TicketStatusResolved),
+			NewValue:    string(models.TicketStatusClosed),
+			CommentID:   &comment.ID,
+			IsVisible:   true,
+			IsSystem:    true,
+			IsAutomated: true,
+			IsImportant: true,
+		}
+		return tx.Create(history).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	ticket.Status = models.TicketStatusClosed
+	ticket.ClosedAt = &now
+
+	_, err = s.notificationService.CreateNotification(ctx, &models.NotificationCreateRequest{
+		Type:            models.NotificationTypeTicketClosed,
+		Title:           fmt.Sprintf("工单已自动关闭 - %s", ticket.Title),
+		Content:         fmt.Sprintf("工单 #%s 长期未收到回复，系统已自动关闭", ticket.TicketNumber),
+		Priority:        models.NotificationPriorityNormal,
+		Channel:         models.NotificationChannelInApp,
+		RecipientID:     ticket.CreatedByID,
+		RelatedType:     "ticket",
+		RelatedID:       &ticket.ID,
+		RelatedTicketID: &ticket.ID,
+		ActionURL:       fmt.Sprintf("/tickets/%d", ticket.ID),
+		Metadata: map[string]interface{}{
+			"ticket_number": ticket.TicketNumber,
+			"auto_closed":   true,
+		},
+	})
+	return err
+}
+
+// GetStats 获取自动关闭统计数据
+func (s *AutoCloseService) GetStats(ctx context.Context, since time.Time) (*AutoCloseStats, error) {
+	stats := &AutoCloseStats{Since: since}
+
+	if err := s.db.WithContext(ctx).Model(&models.TicketHistory{}).
+		Where("field_name = ? AND is_automated = ? AND created_at >= ?", autoCloseReminderField, true, since).
+		Count(&stats.RemindersSent).Error; err != nil {
+		return nil, fmt.Errorf("failed to count reminders: %w", err)
+	}
+
+	if err := s.db.WithContext(ctx).Model(&models.TicketHistory{}).
+		Where("field_name = ? AND is_automated = ? AND created_at >= ?", autoCloseField, true, since).
+		Count(&stats.AutoClosed).Error; err != nil {
+		return nil, fmt.Errorf("failed to count auto closed tickets: %w", err)
+	}
+
+	if err := s.db.WithContext(ctx).Model(&models.Ticket{}).
+		Where("status = ? AND auto_close_reminder_at IS NULL", models.TicketStatusResolved).
+		Count(&stats.AwaitingReminder).Error; err != nil {
+		return nil, fmt.Errorf("failed to count tickets awaiting reminder: %w", err)
+	}
+
+	if err := s.db.WithContext(ctx).Model(&models.Ticket{}).
+		Where("status = ? AND auto_close_reminder_at IS NOT NULL", models.TicketStatusResolved).
+		Count(&stats.AwaitingClose).Error; err != nil {
+		return nil, fmt.Errorf("failed to count tickets awaiting close: %w", err)
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupAutoCloseTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file:auto_close_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+
+	if err := db.AutoMigrate(&models.User{}, &models.Ticket{}, &models.TicketComment{}, &models.TicketHistory{}, &models.Notification{}, &models.SystemConfig{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+
+	return db
+}
+
+func TestAutoCloseService_ReminderThenClose(t *testing.T) {
+	db := setupAutoCloseTestDB(t)
+	ctx := context.Background()
+	svc := NewAutoCloseService(db)
+
+	customer := models.User{Username: "customer1", Email: "customer1@example.com", PasswordHash: "hashed", Role: models.RoleCustomer, Status: models.UserStatusActive}
+	if err := db.Create(&customer).Error; err != nil {
+		t.Fatalf("failed to seed user: %v", err)
+	}
+
+	policy := models.GetDefaultAutoClosePolicy()
+	policy.Enabled = true
+	if err := svc.SetPolicy(ctx, policy, customer.ID); err != nil {
+		t.Fatalf("failed to save policy: %v", err)
+	}
+
+	resolvedAt := time.Now().AddDate(0, 0, -5)
+	tickets := []models.Ticket{
+		{TicketNumber: "AC-001", Title: "stale", Status: models.TicketStatusResolved, Priority: models.TicketPriorityNormal, Type: models.TicketTypeRequest, Source: models.TicketSourceWeb, CreatedByID: customer.ID, ResolvedAt: &resolvedAt},
+		{TicketNumber: "AC-002", Title: "vip", Status: models.TicketStatusResolved, Priority: models.TicketPriorityNormal, Type: models.TicketTypeRequest, Source: models.TicketSourceWeb, CreatedByID: customer.ID, ResolvedAt: &resolvedAt, Tags: `["VIP"]`},
+	}
+	if err := db.Create(&tickets).Error; err != nil {
+		t.Fatalf("failed to seed tickets: %v", err)
+	}
+
+	result, err := svc.ProcessStaleResolvedTickets(ctx)
+	if err != nil {
+		t.Fatalf("first run failed: %v", err)
+	}
+	if result.RemindersSent != 1 || result.Excluded != 1 || result.Closed != 0 {
+		t.Fatalf("unexpected first run result: %+v", result)
+	}
+
+	// 模拟提醒已发出超过关闭阈值
+	remindedAt := time.Now().AddDate(0, 0, -policy.CloseAfterDays-1)
+	if err := db.Model(&models.Ticket{}).Where("id = ?", tickets[0].ID).Update("auto_close_reminder_at", remindedAt).Error; err != nil {
+		t.Fatalf("failed to backdate reminder: %v", err)
+	}
+
+	result, err = svc.ProcessStaleResolvedTickets(ctx)
+	if err != nil {
+		t.Fatalf("second run failed: %v", err)
+	}
+	if result.Closed != 1 {
+		t.Fatalf("expected 1 ticket closed, got %+v", result)
+	}
+
+	var closed models.Ticket
+	if err := db.First(&closed, tickets[0].ID).Error; err != nil {
+		t.Fatalf("failed to reload ticket: %v", err)
+	}
+	if closed.Status != models.TicketStatusClosed || closed.ClosedAt == nil {
+		t.Fatalf("expected ticket to be closed, got status %s", closed.Status)
+	}
+
+	stats, err := svc.GetStats(ctx, time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("failed to get stats: %v", err)
+	}
+	if stats.RemindersSent != 1 || stats.AutoClosed != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestAutoClosePolicy_ThresholdsFor(t *testing.T) {
+	policy := models.GetDefaultAutoClosePolicy()
+	policy.CategoryOverrides["7"] = models.AutoCloseThresholds{ReminderAfterDays: 10, CloseAfterDays: 5}
+
+	categoryID := uint(7)
+	if got := policy.ThresholdsFor(&categoryID); got.ReminderAfterDays != 10 {
+		t.Fatalf("expected category override, got %+v", got)
+	}
+
+	otherID := uint(8)
+	if got := policy.ThresholdsFor(&otherID); got.ReminderAfterDays != policy.ReminderAfterDays {
+		t.Fatalf("expected default thresholds, got %+v", got)
+	}
+}
+
+func TestAutoCloseService_ReopenedTicketRestartsCountdown(t *testing.T) {
+	db := newTestDB(t, &models.User{}, &models.Category{}, &models.Ticket{}, &models.TicketComment{}, &models.TicketHistory{}, &models.TicketChecklistItem{},
+		&models.Notification{}, &models.SystemConfig{}, &models.AutomationRule{}, &models.AutomationRuleRevision{}, &models.AutomationLog{})
+	ctx := context.Background()
+	svc := NewAutoCloseService(db)
+
+	agent := models.User{Username: "ac-agent", Email: "ac-agent@example.com", PasswordHash: "x", Role: models.RoleAgent, Status: models.UserStatusActive}
+	if err := db.Create(&agent).Error; err != nil {
+		t.Fatalf("failed to seed user: %v", err)
+	}
+	policy := models.GetDefaultAutoClosePolicy()
+	policy.Enabled = true
+	if err := svc.SetPolicy(ctx, policy, agent.ID); err != nil {
+		t.Fatalf("failed to save policy: %v", err)
+	}
+
+	// 工单早已解决，重新打开后刚刚再次解决
+	resolvedAt := time.Now().AddDate(0, 0, -policy.ReminderAfterDays-5)
+	ticket := models.Ticket{TicketNumber: "AC-101", Title: "reopened", Status: models.TicketStatusResolved, Priority: models.TicketPriorityNormal,
+		Type: models.TicketTypeRequest, Source: models.TicketSourceWeb, CreatedByID: agent.ID, ResolvedAt: &resolvedAt}
+	if err := db.Create(&ticket).Error; err != nil {
+		t.Fatalf("failed to seed ticket: %v", err)
+	}
+	tickets := NewTicketService(db).(*TicketService)
+	if _, err := tickets.UpdateTicketStatus(ticket.ID, string(models.TicketStatusOpen), agent.ID, "", "", ""); err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	resolved, err := tickets.UpdateTicketStatus(ticket.ID, string(models.TicketStatusResolved), agent.ID, "", "", "")
+	if err != nil {
+		t.Fatalf("resolve failed: %v", err)
+	}
+	if resolved.ResolvedAt == nil || !resolved.ResolvedAt.After(resolvedAt) {
+		t.Fatalf("expected resolved_at to be refreshed, got %v", resolved.ResolvedAt)
+	}
+
+	result, err := svc.ProcessStaleResolvedTickets(ctx)
+	if err != nil {
+		t.Fatalf("auto close run failed: %v", err)
+	}
+	if result.RemindersSent != 0 || result.Closed != 0 {
+		t.Fatalf("expected re-resolved ticket to start a new countdown, got %+v", result)
+	}
+
+	// 通过更新接口重新打开再解决同样重新计时
+	db.Model(&ticket).UpdateColumn("resolved_at", resolvedAt)
+	open, resolvedStatus := models.TicketStatusOpen, models.TicketStatusResolved
+	if _, err := tickets.UpdateTicket(ctx, ticket.ID, &models.TicketUpdateRequest{Status: &open}, agent.ID); err != nil {
+		t.Fatalf("reopen via update failed: %v", err)
+	}
+	if _, err := tickets.UpdateTicket(ctx, ticket.ID, &models.TicketUpdateRequest{Status: &resolvedStatus}, agent.ID); err != nil {
+		t.Fatalf("resolve via update failed: %v", err)
+	}
+	if result, err := svc.ProcessStaleResolvedTickets(ctx); err != nil || result.RemindersSent != 0 || result.Closed != 0 {
+		t.Fatalf("expected re-resolved ticket to start a new countdown, got %+v (%v)", result, err)
+	}
+}
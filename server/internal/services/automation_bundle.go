@@ -0,0 +1,858 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+// automationNameMaxLength 规则、SLA配置和模板名称的最大长度
+const automationNameMaxLength = 100
+
+var (
+	// ErrAutomationChecksumMismatch 配置包内容与其校验和不一致（导出后被修改或损坏）
+	ErrAutomationChecksumMismatch = errors.New("bundle checksum mismatch")
+	// errAutomationDryRun 试运行结束时用于回滚事务
+	errAutomationDryRun = errors.New("automation import dry run")
+)
+
+var (
+	validRuleTypes = map[string]bool{
+		"assignment": true, "classification": true, "escalation": true, "sla": true,
+	}
+	validRuleOperators = map[string]bool{
+		"eq": true, "ne": true, "contains": true, "starts_with": true, "ends_with": true,
+		"in": true, "not_in": true, "gt": true, "gte": true, "lt": true, "lte": true, "regex": true,
+	}
+	validRuleActions = map[string]bool{
+		"assign": true, "set_priority": true, "set_status": true, "add_comment": true,
+		"notify": true, "escalate": true, "create_ticket": true, "create_war_room": true, "assign_by_skill": true,
+	}
+)
+
+// AutomationBundleError 配置包校验失败，包含全部问题
+type AutomationBundleError struct {
+	Problems []string
+}
+
+func (e *AutomationBundleError) Error() string {
+	return "invalid automation bundle: " + strings.Join(e.Problems, "; ")
+}
+
+// ExportBundle 导出全部自动化规则、SLA配置和工单模板
+func (s *AutomationService) ExportBundle(ctx context.Context) (*models.AutomationBundle, error) {
+	bundle, err := s.currentBundle(ctx)
+	if err != nil {
+		return nil, err
+	}
+	checksum, err := bundleChecksum(bundle)
+	if err != nil {
+		return nil, err
+	}
+	bundle.FormatVersion = models.AutomationBundleFormatVersion
+	bundle.ExportedAt = time.Now()
+	bundle.Checksum = checksum.Checksum
+	return bundle, nil
+}
+
+// Checksum 计算当前环境配置的校验和，与导出配置包中的 checksum 一致
+func (s *AutomationService) Checksum(ctx context.Context) (*models.AutomationChecksum, error) {
+	bundle, err := s.currentBundle(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return bundleChecksum(bundle)
+}
+
+// ImportBundle 校验并导入配置包，同名配置按 on_conflict 处理；dry_run 时只返回导入计划
+func (s *AutomationService) ImportBundle(ctx context.Context, req *models.AutomationImportRequest, userID uint) (*models.AutomationImportResult, error) {
+	bundle := &req.Bundle
+	normalizeBundle(bundle)
+	if err := validateBundle(bundle); err != nil {
+		return nil, err
+	}
+
+	checksum, err := bundleChecksum(bundle)
+	if err != nil {
+		return nil, err
+	}
+	if bundle.Checksum != "" && bundle.Checksum != checksum.Checksum {
+		return nil, ErrAutomationChecksumMismatch
+	}
+
+	onConflict := req.OnConflict
+	if onConflict == "" {
+		onConflict = models.ImportConflictSkip
+	}
+
+	result := &models.AutomationImportResult{DryRun: req.DryRun, Checksum: checksum.Checksum}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i := range bundle.Rules {
+			item, err := s.importRule(tx, &bundle.Rules[i], onConflict, userID)
+			if err != nil {
+				return err
+			}
+			result.Items = append(result.Items, *item)
+		}
+		for i := range bundle.SLAConfigs {
+			item, err := importSLAConfig(tx, &bundle.SLAConfigs[i], onConflict)
+			if err != nil {
+				return err
+			}
+			result.Items = append(result.Items, *item)
+		}
+		for i := range bundle.Templates {
+			item, err := importTemplate(tx, &bundle.Templates[i], onConflict, userID)
+			if err != nil {
+				return err
+			}
+			result.Items = append(result.Items, *item)
+		}
+		if req.DryRun {
+			return errAutomationDryRun
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errAutomationDryRun) {
+		return nil, err
+	}
+
+	if req.DryRun {
+		for i := range result.Items {
+			result.Items[i].ID = 0
+		}
+	}
+	return result, nil
+}
+
+// GetRuleRevisions 获取规则修订历史（新版本在前）
+func (s *AutomationService) GetRuleRevisions(ctx context.Context, ruleID uint) ([]models.AutomationRuleRevision, error) {
+	if _, err := s.GetRuleByID(ctx, ruleID); err != nil {
+		return nil, err
+	}
+
+	var revisions []models.AutomationRuleRevision
+	if err := s.db.WithContext(ctx).Where("rule_id = ?", ruleID).Order("version DESC").Find(&revisions).Error; err != nil {
+		return nil, fmt.Errorf("failed to get rule revisions: %w", err)
+	}
+	return revisions, nil
+}
+
+// RollbackRule 将规则恢复为指定版本的内容，回滚本身也记录为新版本
+func (s *AutomationService) RollbackRule(ctx context.Context, ruleID uint, version int, userID uint) (*models.AutomationRule, error) {
+	var rule models.AutomationRule
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&rule, ruleID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("rule not found")
+			}
+			return fmt.Errorf("failed to get rule: %w", err)
+		}
+
+		var revision models.AutomationRuleRevision
+		if err := tx.Where("rule_id = ? AND version = ?", ruleID, version).First(&revision).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("revision not found")
+			}
+			return fmt.Errorf("failed to get revision: %w", err)
+		}
+		if revision.Rule == nil {
+			return fmt.Errorf("revision %d has an unreadable snapshot", version)
+		}
+
+		if err := s.ensureBaselineRevision(tx, &rule); err != nil {
+			return err
+		}
+		if err := applyRuleSpec(&rule, revision.Rule); err != nil {
+			return err
+		}
+		rule.UpdatedBy = &userID
+		if err := tx.Save(&rule).Error; err != nil {
+			return fmt.Errorf("failed to rollback rule: %w", err)
+		}
+		return s.recordRuleRevision(tx, &rule, models.RuleRevisionRollback, fmt.Sprintf("回滚到版本 %d", version), &userID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &rule, nil
+}
+
+// recordRuleRevision 以规则当前内容追加一个修订版本
+func (s *AutomationService) recordRuleRevision(tx *gorm.DB, rule *models.AutomationRule, changeType, note string, userID *uint) error {
+	spec, err := ruleSpec(rule)
+	if err != nil {
+		return err
+	}
+	snapshot, err := json.Marshal(spec)
+	if err != nil {
+		return fmt.Errorf("failed to encode rule snapshot: %w", err)
+	}
+
+	var latest int
+	if err := tx.Model(&models.AutomationRuleRevision{}).
+		Where("rule_id = ?", rule.ID).
+		Select("COALESCE(MAX(version), 0)").
+		Scan(&latest).Error; err != nil {
+		return fmt.Errorf("failed to get latest revision: %w", err)
+	}
+
+	revision := &models.AutomationRuleRevision{
+		RuleID:     rule.ID,
+		Version:    latest + 1,
+		ChangeType: changeType,
+		Note:       note,
+		Checksum:   sha256Hex(snapshot),
+		Snapshot:   string(snapshot),
+		CreatedBy:  userID,
+	}
+	if err := tx.Create(revision).Error; err != nil {
+		return fmt.Errorf("failed to record rule revision: %w", err)
+	}
+	return nil
+}
+
+// ensureBaselineRevision 规则还没有修订历史时（在启用修订历史前创建），先保存修改前的内容作为基线版本
+func (s *AutomationService) ensureBaselineRevision(tx *gorm.DB, rule *models.AutomationRule) error {
+	var count int64
+	if err := tx.Model(&models.AutomationRuleRevision{}).Where("rule_id = ?", rule.ID).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to count rule revisions: %w", err)
+	}
+	if count > 0 {
+		return nil
+	}
+	return s.recordRuleRevision(tx, rule, models.RuleRevisionBaseline, "", nil)
+}
+
+func (s *AutomationService) importRule(tx *gorm.DB, spec *models.AutomationRuleRequest, onConflict string, userID uint) (*models.AutomationImportItem, error) {
+	item := &models.AutomationImportItem{Kind: "rule", Name: spec.Name, Action: "created"}
+
+	var existing models.AutomationRule
+	err := tx.Where("name = ?", spec.Name).Order("id ASC").First(&existing).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to check existing rule: %w", err)
+	}
+
+	if err == nil {
+		switch onConflict {
+		case models.ImportConflictSkip:
+			item.Action = "skipped"
+			item.ID = existing.ID
+			return item, nil
+		case models.ImportConflictOverwrite:
+			if err := s.ensureBaselineRevision(tx, &existing); err != nil {
+				return nil, err
+			}
+			if err := applyRuleSpec(&existing, spec); err != nil {
+				return nil, err
+			}
+			existing.UpdatedBy = &userID
+			if err := tx.Save(&existing).Error; err != nil {
+				return nil, fmt.Errorf("failed to overwrite rule %q: %w", spec.Name, err)
+			}
+			if err := s.recordRuleRevision(tx, &existing, models.RuleRevisionImport, "导入覆盖", &userID); err != nil {
+				return nil, err
+			}
+			item.Action = "overwritten"
+			item.ID = existing.ID
+			return item, nil
+		case models.ImportConflictRename:
+			name, err := uniqueAutomationName(tx, &models.AutomationRule{}, spec.Name)
+			if err != nil {
+				return nil, err
+			}
+			item.Action = "renamed"
+			item.NewName = name
+		}
+	}
+
+	rule := &models.AutomationRule{CreatedBy: userID}
+	if err := applyRuleSpec(rule, spec); err != nil {
+		return nil, err
+	}
+	if item.NewName != "" {
+		rule.Name = item.NewName
+	}
+	if err := tx.Create(rule).Error; err != nil {
+		return nil, fmt.Errorf("failed to import rule %q: %w", spec.Name, err)
+	}
+	if err := s.recordRuleRevision(tx, rule, models.RuleRevisionImport, "导入创建", &userID); err != nil {
+		return nil, err
+	}
+	item.ID = rule.ID
+	return item, nil
+}
+
+func importSLAConfig(tx *gorm.DB, spec *models.SLAConfigRequest, onConflict string) (*models.AutomationImportItem, error) {
+	item := &models.AutomationImportItem{Kind: "sla_config", Name: spec.Name, Action: "created"}
+
+	var config models.SLAConfig
+	err := tx.Where("name = ?", spec.Name).Order("id ASC").First(&config).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to check existing SLA config: %w", err)
+	}
+
+	if err == nil {
+		switch onConflict {
+		case models.ImportConflictSkip:
+			item.Action = "skipped"
+			item.ID = config.ID
+			return item, nil
+		case models.ImportConflictOverwrite:
+			item.Action = "overwritten"
+		case models.ImportConflictRename:
+			name, err := uniqueAutomationName(tx, &models.SLAConfig{}, spec.Name)
+			if err != nil {
+				return nil, err
+			}
+			item.Action = "renamed"
+			item.NewName = name
+			config = models.SLAConfig{}
+		}
+	} else {
+		config = models.SLAConfig{}
+	}
+
+	if err := applySLASpec(&config, spec); err != nil {
+		return nil, err
+	}
+	if item.NewName != "" {
+		config.Name = item.NewName
+	}
+	if config.IsDefault {
+		if err := tx.Model(&models.SLAConfig{}).
+			Where("is_default = ? AND id <> ?", true, config.ID).
+			Update("is_default", false).Error; err != nil {
+			return nil, fmt.Errorf("failed to update existing default config: %w", err)
+		}
+	}
+	if err := tx.Save(&config).Error; err != nil {
+		return nil, fmt.Errorf("failed to import SLA config %q: %w", spec.Name, err)
+	}
+	item.ID = config.ID
+	return item, nil
+}
+
+func importTemplate(tx *gorm.DB, spec *models.TicketTemplateRequest, onConflict string, userID uint) (*models.AutomationImportItem, error) {
+	item := &models.AutomationImportItem{Kind: "template", Name: spec.Name, Action: "created"}
+
+	var template models.TicketTemplate
+	err := tx.Where("name = ?", spec.Name).Order("id ASC").First(&template).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to check existing template: %w", err)
+	}
+
+	if err == nil {
+		switch onConflict {
+		case models.ImportConflictSkip:
+			item.Action = "skipped"
+			item.ID = template.ID
+			return item, nil
+		case models.ImportConflictOverwrite:
+			item.Action = "overwritten"
+		case models.ImportConflictRename:
+			name, err := uniqueAutomationName(tx, &models.TicketTemplate{}, spec.Name)
+			if err != nil {
+				return nil, err
+			}
+			item.Action = "renamed"
+			item.NewName = name
+			template = models.TicketTemplate{CreatedBy: userID}
+		}
+	} else {
+		template = models.TicketTemplate{CreatedBy: userID}
+	}
+
+	if err := applyTemplateSpec(&template, spec); err != nil {
+		return nil, err
+	}
+	if item.NewName != "" {
+		template.Name = item.NewName
+	}
+	if err := tx.Omit("AssignToUser", "CreatedUser").Save(&template).Error; err != nil {
+		return nil, fmt.Errorf("failed to import template %q: %w", spec.Name, err)
+	}
+	item.ID = template.ID
+	return item, nil
+}
+
+// currentBundle 读取当前环境的全部配置（按名称排序，不含校验和）
+func (s *AutomationService) currentBundle(ctx context.Context) (*models.AutomationBundle, error) {
+	db := s.db.WithContext(ctx)
+	bundle := &models.AutomationBundle{
+		Rules:      []models.AutomationRuleRequest{},
+		SLAConfigs: []models.SLAConfigRequest{},
+		Templates:  []models.TicketTemplateRequest{},
+	}
+
+	var rules []models.AutomationRule
+	if err := db.Order("name ASC, id ASC").Find(&rules).Error; err != nil {
+		return nil, fmt.Errorf("failed to get rules: %w", err)
+	}
+	for i := range rules {
+		spec, err := ruleSpec(&rules[i])
+		if err != nil {
+			return nil, err
+		}
+		bundle.Rules = append(bundle.Rules, *spec)
+	}
+
+	var configs []models.SLAConfig
+	if err := db.Order("name ASC, id ASC").Find(&configs).Error; err != nil {
+		return nil, fmt.Errorf("failed to get SLA configs: %w", err)
+	}
+	for i := range configs {
+		spec, err := slaSpec(&configs[i])
+		if err != nil {
+			return nil, err
+		}
+		bundle.SLAConfigs = append(bundle.SLAConfigs, *spec)
+	}
+
+	var templates []models.TicketTemplate
+	if err := db.Order("name ASC, id ASC").Find(&templates).Error; err != nil {
+		return nil, fmt.Errorf("failed to get templates: %w", err)
+	}
+	for i := range templates {
+		spec, err := templateSpec(&templates[i])
+		if err != nil {
+			return nil, err
+		}
+		bundle.Templates = append(bundle.Templates, *spec)
+	}
+
+	normalizeBundle(bundle)
+	return bundle, nil
+}
+
+// ruleSpec 将规则转换为可导出的定义
+func ruleSpec(rule *models.AutomationRule) (*models.AutomationRuleRequest, error) {
+	conditions, err := rule.GetConditions()
+	if err != nil {
+		return nil, fmt.Errorf("rule %d has invalid conditions: %w", rule.ID, err)
+	}
+	actions, err := rule.GetActions()
+	if err != nil {
+		return nil, fmt.Errorf("rule %d has invalid actions: %w", rule.ID, err)
+	}
+	isActive := rule.IsActive
+	priority := rule.Priority
+	spec := &models.AutomationRuleRequest{
+		Name:         rule.Name,
+		Description:  rule.Description,
+		RuleType:     rule.RuleType,
+		IsActive:     &isActive,
+		Priority:     &priority,
+		TriggerEvent: rule.TriggerEvent,
+		Conditions:   conditions,
+		Actions:      actions,
+	}
+	normalizeRuleSpec(spec)
+	return spec, nil
+}
+
+// applyRuleSpec 将规则定义写入规则模型（不保存）
+func applyRuleSpec(rule *models.AutomationRule, spec *models.AutomationRuleRequest) error {
+	rule.Name = spec.Name
+	rule.Description = spec.Description
+	rule.RuleType = spec.RuleType
+	rule.TriggerEvent = spec.TriggerEvent
+	rule.IsActive = spec.IsActive == nil || *spec.IsActive
+	rule.Priority = 1
+	if spec.Priority != nil {
+		rule.Priority = *spec.Priority
+	}
+	if err := rule.SetConditions(spec.Conditions); err != nil {
+		return fmt.Errorf("invalid conditions: %w", err)
+	}
+	if err := rule.SetActions(spec.Actions); err != nil {
+		return fmt.Errorf("invalid actions: %w", err)
+	}
+	return nil
+}
+
+func slaSpec(config *models.SLAConfig) (*models.SLAConfigRequest, error) {
+	spec := &models.SLAConfigRequest{
+		Name:            config.Name,
+		Description:     config.Description,
+		IsActive:        boolPtr(config.IsActive),
+		IsDefault:       boolPtr(config.IsDefault),
+		TicketType:      config.TicketType,
+		Priority:        config.Priority,
+		Category:        config.Category,
+		ResponseTime:    config.ResponseTime,
+		ResolutionTime:  config.ResolutionTime,
+		ExcludeWeekends: boolPtr(config.ExcludeWeekends),
+		ExcludeHolidays: boolPtr(config.ExcludeHolidays),
+	}
+	if config.WorkingHours != "" {
+		var hours models.WorkingHours
+		if err := json.Unmarshal([]byte(config.WorkingHours), &hours); err != nil {
+			return nil, fmt.Errorf("SLA config %d has invalid working hours: %w", config.ID, err)
+		}
+		spec.WorkingHours = &hours
+	}
+	if config.EscalationRules != "" {
+		rules, err := config.GetEscalationRules()
+		if err != nil {
+			return nil, fmt.Errorf("SLA config %d has invalid escalation rules: %w", config.ID, err)
+		}
+		spec.EscalationRules = rules
+	}
+	if config.WarningThresholds != "" {
+		spec.WarningThresholds = config.GetWarningThresholds()
+	}
+	return spec, nil
+}
+
+func applySLASpec(config *models.SLAConfig, spec *models.SLAConfigRequest) error {
+	config.Name = spec.Name
+	config.Description = spec.Description
+	config.IsActive = spec.IsActive == nil || *spec.IsActive
+	config.IsDefault = spec.IsDefault != nil && *spec.IsDefault
+	config.TicketType = spec.TicketType
+	config.Priority = spec.Priority
+	config.Category = spec.Category
+	config.ResponseTime = spec.ResponseTime
+	config.ResolutionTime = spec.ResolutionTime
+	config.ExcludeWeekends = spec.ExcludeWeekends == nil || *spec.ExcludeWeekends
+	config.ExcludeHolidays = spec.ExcludeHolidays == nil || *spec.ExcludeHolidays
+
+	config.WorkingHours = ""
+	if spec.WorkingHours != nil {
+		data, err := json.Marshal(spec.WorkingHours)
+		if err != nil {
+			return fmt.Errorf("invalid working hours: %w", err)
+		}
+		config.WorkingHours = string(data)
+	}
+	config.EscalationRules = ""
+	if len(spec.EscalationRules) > 0 {
+		data, err := json.Marshal(spec.EscalationRules)
+		if err != nil {
+			return fmt.Errorf("invalid escalation rules: %w", err)
+		}
+		config.EscalationRules = string(data)
+	}
+	thresholds, err := encodeSLAWarningThresholds(spec.WarningThresholds)
+	if err != nil {
+		return err
+	}
+	config.WarningThresholds = thresholds
+	return nil
+}
+
+func templateSpec(template *models.TicketTemplate) (*models.TicketTemplateRequest, error) {
+	fields, err := template.GetCustomFields()
+	if err != nil {
+		return nil, fmt.Errorf("template %d has invalid custom fields: %w", template.ID, err)
+	}
+	spec := &models.TicketTemplateRequest{
+		Name:            template.Name,
+		Description:     template.Description,
+		Category:        template.Category,
+		IsActive:        boolPtr(template.IsActive),
+		TitleTemplate:   template.TitleTemplate,
+		ContentTemplate: template.ContentTemplate,
+		DefaultType:     template.DefaultType,
+		DefaultPriority: template.DefaultPriority,
+		DefaultStatus:   template.DefaultStatus,
+	}
+	if len(fields) > 0 {
+		spec.CustomFields = fields
+	}
+	checklist, err := template.GetChecklistItems()
+	if err != nil {
+		return nil, fmt.Errorf("template %d has invalid checklist items: %w", template.ID, err)
+	}
+	if len(checklist) > 0 {
+		spec.ChecklistItems = checklist
+	}
+	return spec, nil
+}
+
+func applyTemplateSpec(template *models.TicketTemplate, spec *models.TicketTemplateRequest) error {
+	template.Name = spec.Name
+	template.Description = spec.Description
+	template.Category = spec.Category
+	template.IsActive = spec.IsActive == nil || *spec.IsActive
+	template.TitleTemplate = spec.TitleTemplate
+	template.ContentTemplate = spec.ContentTemplate
+	template.DefaultType = spec.DefaultType
+	template.DefaultPriority = spec.DefaultPriority
+	template.DefaultStatus = spec.DefaultStatus
+
+	template.CustomFields = ""
+	if len(spec.CustomFields) > 0 {
+		data, err := json.Marshal(spec.CustomFields)
+		if err != nil {
+			return fmt.Errorf("invalid custom fields: %w", err)
+		}
+		template.CustomFields = string(data)
+	}
+	template.ChecklistItems = ""
+	if len(spec.ChecklistItems) > 0 {
+		data, err := json.Marshal(spec.ChecklistItems)
+		if err != nil {
+			return fmt.Errorf("invalid checklist items: %w", err)
+		}
+		template.ChecklistItems = string(data)
+	}
+	return nil
+}
+
+// normalizeBundle 补齐默认值并去掉环境相关字段，保证同样的配置得到同样的校验和
+func normalizeBundle(bundle *models.AutomationBundle) {
+	for i := range bundle.Rules {
+		normalizeRuleSpec(&bundle.Rules[i])
+	}
+	for i := range bundle.SLAConfigs {
+		spec := &bundle.SLAConfigs[i]
+		spec.Name = strings.TrimSpace(spec.Name)
+		spec.AssignedUserID = nil
+		if spec.IsActive == nil {
+			spec.IsActive = boolPtr(true)
+		}
+		if spec.IsDefault == nil {
+			spec.IsDefault = boolPtr(false)
+		}
+		if spec.ExcludeWeekends == nil {
+			spec.ExcludeWeekends = boolPtr(true)
+		}
+		if spec.ExcludeHolidays == nil {
+			spec.ExcludeHolidays = boolPtr(true)
+		}
+		if len(spec.EscalationRules) == 0 {
+			spec.EscalationRules = nil
+		}
+	}
+	for i := range bundle.Templates {
+		spec := &bundle.Templates[i]
+		spec.Name = strings.TrimSpace(spec.Name)
+		spec.AssignToUserID = nil
+		if spec.IsActive == nil {
+			spec.IsActive = boolPtr(true)
+		}
+		if len(spec.CustomFields) == 0 {
+			spec.CustomFields = nil
+		}
+		if len(spec.ChecklistItems) == 0 {
+			spec.ChecklistItems = nil
+		}
+	}
+}
+
+func normalizeRuleSpec(spec *models.AutomationRuleRequest) {
+	spec.Name = strings.TrimSpace(spec.Name)
+	if spec.IsActive == nil {
+		spec.IsActive = boolPtr(true)
+	}
+	if spec.Priority == nil {
+		priority := 1
+		spec.Priority = &priority
+	}
+	if spec.Conditions == nil {
+		spec.Conditions = []models.RuleCondition{}
+	}
+	if spec.Actions == nil {
+		spec.Actions = []models.RuleAction{}
+	}
+}
+
+// validateBundle 校验配置包，一次返回全部问题
+func validateBundle(bundle *models.AutomationBundle) error {
+	var problems []string
+	addf := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if bundle.FormatVersion > models.AutomationBundleFormatVersion {
+		addf("unsupported format_version %d", bundle.FormatVersion)
+	}
+
+	checkName := func(kind string, index int, name string, seen map[string]bool) {
+		switch {
+		case name == "":
+			addf("%s[%d]: name is required", kind, index)
+		case utf8.RuneCountInString(name) > automationNameMaxLength:
+			addf("%s[%d]: name exceeds %d characters", kind, index, automationNameMaxLength)
+		case seen[name]:
+			addf("%s[%d]: duplicate name %q", kind, index, name)
+		}
+		seen[name] = true
+	}
+
+	seen := map[string]bool{}
+	for i, rule := range bundle.Rules {
+		checkName("rules", i, rule.Name, seen)
+		if !validRuleTypes[rule.RuleType] {
+			addf("rules[%d]: invalid rule_type %q", i, rule.RuleType)
+		}
+		if strings.TrimSpace(rule.TriggerEvent) == "" {
+			addf("rules[%d]: trigger_event is required", i)
+		}
+		if *rule.Priority < 1 || *rule.Priority > 100 {
+			addf("rules[%d]: priority must be between 1 and 100", i)
+		}
+		for _, problem := range ruleSpecProblems(rule.Conditions, rule.Actions) {
+			addf("rules[%d].%s", i, problem)
+		}
+	}
+
+	seen = map[string]bool{}
+	defaults := 0
+	for i, config := range bundle.SLAConfigs {
+		checkName("sla_configs", i, config.Name, seen)
+		if config.ResponseTime < 1 || config.ResolutionTime < 1 {
+			addf("sla_configs[%d]: response_time and resolution_time must be at least 1 minute", i)
+		}
+		if _, err := encodeSLAWarningThresholds(config.WarningThresholds); err != nil {
+			addf("sla_configs[%d]: warning_thresholds must be 1-99 with at most %d values", i, maxSLAWarningThresholds)
+		}
+		if *config.IsDefault {
+			defaults++
+		}
+	}
+	if defaults > 1 {
+		addf("sla_configs: at most one config can be default")
+	}
+
+	seen = map[string]bool{}
+	for i, template := range bundle.Templates {
+		checkName("templates", i, template.Name, seen)
+		if strings.TrimSpace(template.Category) == "" {
+			addf("templates[%d]: category is required", i)
+		}
+	}
+
+	if len(problems) > 0 {
+		return &AutomationBundleError{Problems: problems}
+	}
+	return nil
+}
+
+// ruleSpecProblems 校验规则的条件和动作，返回 conditions[i]/actions[i] 开头的问题描述
+func ruleSpecProblems(conditions []models.RuleCondition, actions []models.RuleAction) []string {
+	var problems []string
+	for j, condition := range conditions {
+		if condition.Field == "" {
+			problems = append(problems, fmt.Sprintf("conditions[%d]: field is required", j))
+		}
+		if !validRuleOperators[condition.Operator] {
+			problems = append(problems, fmt.Sprintf("conditions[%d]: invalid operator %q", j, condition.Operator))
+		} else if condition.Operator == "regex" {
+			if _, err := regexp.Compile(fmt.Sprintf("%v", condition.Value)); err != nil {
+				problems = append(problems, fmt.Sprintf("conditions[%d]: invalid regex: %v", j, err))
+			}
+		}
+	}
+	for j, action := range actions {
+		if !validRuleActions[action.Type] {
+			problems = append(problems, fmt.Sprintf("actions[%d]: invalid action type %q", j, action.Type))
+		}
+	}
+	return problems
+}
+
+// bundleChecksum 计算配置包内容校验和：逐项计算后按名称排序汇总，与导出顺序和导出时间无关
+func bundleChecksum(bundle *models.AutomationBundle) (*models.AutomationChecksum, error) {
+	result := &models.AutomationChecksum{Sections: map[string]string{}}
+
+	section := func(kind string, names []string, specs []interface{}) error {
+		items := make([]models.AutomationChecksumItem, 0, len(specs))
+		for i, spec := range specs {
+			data, err := json.Marshal(spec)
+			if err != nil {
+				return fmt.Errorf("failed to encode %s %q: %w", kind, names[i], err)
+			}
+			items = append(items, models.AutomationChecksumItem{Kind: kind, Name: names[i], Checksum: sha256Hex(data)})
+		}
+		sort.SliceStable(items, func(i, j int) bool {
+			if items[i].Name != items[j].Name {
+				return items[i].Name < items[j].Name
+			}
+			return items[i].Checksum < items[j].Checksum
+		})
+
+		var sb strings.Builder
+		for _, item := range items {
+			sb.WriteString(item.Name + ":" + item.Checksum + "\n")
+		}
+		result.Sections[kind] = sha256Hex([]byte(sb.String()))
+		result.Items = append(result.Items, items...)
+		return nil
+	}
+
+	var names []string
+	var specs []interface{}
+	for _, rule := range bundle.Rules {
+		names = append(names, rule.Name)
+		specs = append(specs, rule)
+	}
+	if err := section("rule", names, specs); err != nil {
+		return nil, err
+	}
+
+	names, specs = nil, nil
+	for _, config := range bundle.SLAConfigs {
+		names = append(names, config.Name)
+		specs = append(specs, config)
+	}
+	if err := section("sla_config", names, specs); err != nil {
+		return nil, err
+	}
+
+	names, specs = nil, nil
+	for _, template := range bundle.Templates {
+		names = append(names, template.Name)
+		specs = append(specs, template)
+	}
+	if err := section("template", names, specs); err != nil {
+		return nil, err
+	}
+
+	result.Checksum = sha256Hex([]byte(fmt.Sprintf("rule:%s\nsla_config:%s\ntemplate:%s\n",
+		result.Sections["rule"], result.Sections["sla_config"], result.Sections["template"])))
+	return result, nil
+}
+
+// uniqueAutomationName 生成不与现有记录重名的名称，如 "规则 (2)"
+func uniqueAutomationName(tx *gorm.DB, model interface{}, name string) (string, error) {
+	for n := 2; n < 1000; n++ {
+		suffix := fmt.Sprintf(" (%d)", n)
+		base := []rune(name)
+		if max := automationNameMaxLength - utf8.RuneCountInString(suffix); len(base) > max {
+			base = base[:max]
+		}
+		candidate := string(base) + suffix
+
+		var count int64
+		if err := tx.Model(model).Where("name = ?", candidate).Count(&count).Error; err != nil {
+			return "", fmt.Errorf("failed to check name %q: %w", candidate, err)
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+	}
+	return "", fmt.Errorf("no available name for %q", name)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func boolPtr(v bool) *bool {
+	return &v
+}
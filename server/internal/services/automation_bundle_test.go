@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func openAutomationBundleDB(t *testing.T, name string) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file:"+name+"?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.AutomationRule{}, &models.AutomationRuleRevision{},
+		&models.SLAConfig{}, &models.TicketTemplate{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+	return db
+}
+
+func TestAutomationBundle_ExportImportAndRollback(t *testing.T) {
+	ctx := context.Background()
+	source := NewAutomationService(openAutomationBundleDB(t, "bundle_source"))
+	target := NewAutomationService(openAutomationBundleDB(t, "bundle_target"))
+
+	rule, err := source.CreateRule(ctx, &models.AutomationRuleRequest{
+		Name:         "VIP 分配",
+		RuleType:     "assignment",
+		TriggerEvent: "ticket.created",
+		Conditions:   []models.RuleCondition{{Field: "priority", Operator: "eq", Value: "urgent"}},
+		Actions:      []models.RuleAction{{Type: "set_priority", Params: map[string]interface{}{"priority": "high"}}},
+	}, 1)
+	if err != nil {
+		t.Fatalf("create rule failed: %v", err)
+	}
+	if _, err := source.CreateSLAConfig(ctx, &models.SLAConfigRequest{Name: "标准", ResponseTime: 60, ResolutionTime: 480}); err != nil {
+		t.Fatalf("create SLA config failed: %v", err)
+	}
+
+	bundle, err := source.ExportBundle(ctx)
+	if err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+
+	result, err := target.ImportBundle(ctx, &models.AutomationImportRequest{Bundle: *bundle}, 1)
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if len(result.Items) != 2 || result.Items[0].Action != "created" {
+		t.Fatalf("unexpected import result: %+v", result.Items)
+	}
+	targetSum, _ := target.Checksum(ctx)
+	if targetSum.Checksum != bundle.Checksum {
+		t.Fatalf("expected environments to have equal checksums after import")
+	}
+
+	// 同名规则按 rename 导入
+	result, err = target.ImportBundle(ctx, &models.AutomationImportRequest{Bundle: *bundle, OnConflict: models.ImportConflictRename}, 1)
+	if err != nil {
+		t.Fatalf("rename import failed: %v", err)
+	}
+	if result.Items[0].Action != "renamed" || result.Items[0].NewName != "VIP 分配 (2)" {
+		t.Fatalf("expected renamed rule, got %+v", result.Items[0])
+	}
+
+	tampered := *bundle
+	tampered.Rules = append([]models.AutomationRuleRequest(nil), bundle.Rules...)
+	tampered.Rules[0].Description = "edited"
+	if _, err := target.ImportBundle(ctx, &models.AutomationImportRequest{Bundle: tampered}, 1); !errors.Is(err, ErrAutomationChecksumMismatch) {
+		t.Fatalf("expected checksum mismatch, got %v", err)
+	}
+	tampered.Checksum = ""
+	tampered.Rules[0].RuleType = "unknown"
+	var bundleErr *AutomationBundleError
+	if _, err := target.ImportBundle(ctx, &models.AutomationImportRequest{Bundle: tampered}, 1); !errors.As(err, &bundleErr) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+
+	// 修订历史与回滚
+	if err := source.UpdateRule(ctx, rule.ID, &models.AutomationRuleRequest{
+		Name: "VIP 分配", RuleType: "assignment", TriggerEvent: "ticket.updated",
+	}, 2); err != nil {
+		t.Fatalf("update rule failed: %v", err)
+	}
+	revisions, err := source.GetRuleRevisions(ctx, rule.ID)
+	if err != nil || len(revisions) != 2 || revisions[0].Version != 2 {
+		t.Fatalf("expected 2 revisions, got %d (%v)", len(revisions), err)
+	}
+
+	restored, err := source.RollbackRule(ctx, rule.ID, 1, 2)
+	if err != nil {
+		t.Fatalf("rollback failed: %v", err)
+	}
+	if restored.TriggerEvent != "ticket.created" {
+		t.Fatalf("expected trigger event to be restored, got %q", restored.TriggerEvent)
+	}
+	sourceSum, _ := source.Checksum(ctx)
+	if sourceSum.Checksum != bundle.Checksum {
+		t.Fatalf("expected rollback to restore the exported content")
+	}
+}
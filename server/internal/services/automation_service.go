@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"math"
 	"regexp"
 	"strconv"
 	"strings"
@@ -17,12 +18,25 @@ import (
 
 // AutomationService 自动化服务
 type AutomationService struct {
-	db *gorm.DB
+	db              *gorm.DB
+	calendarService *BusinessCalendarService
+	warRoomService  *WarRoomService
+	historyWriter   *HistoryWriter
 }
 
 // NewAutomationService 创建自动化服务实例
 func NewAutomationService(db *gorm.DB) *AutomationService {
-	return &AutomationService{db: db}
+	return &AutomationService{
+		db:              db,
+		calendarService: NewBusinessCalendarService(db),
+		warRoomService:  NewWarRoomService(db),
+	}
+}
+
+// SetHistoryWriter 设置异步历史写入器，规则动作（作战室、自动分类）产生的系统历史异步批量写入
+func (s *AutomationService) SetHistoryWriter(writer *HistoryWriter) {
+	s.historyWriter = writer
+	s.warRoomService.SetHistoryWriter(writer)
 }
 
 // AutomationRuleService 自动化规则相关方法
@@ -54,8 +68,14 @@ func (s *AutomationService) CreateRule(ctx context.Context, req *models.Automati
 		return nil, fmt.Errorf("invalid actions: %w", err)
 	}
 
-	if err := s.db.WithContext(ctx).Create(rule).Error; err != nil {
-		return nil, fmt.Errorf("failed to create rule: %w", err)
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(rule).Error; err != nil {
+			return fmt.Errorf("failed to create rule: %w", err)
+		}
+		return s.recordRuleRevision(tx, rule, models.RuleRevisionCreate, "", &userID)
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return rule, nil
@@ -127,30 +147,44 @@ func (s *AutomationService) UpdateRule(ctx context.Context, ruleID uint, req *mo
 		updates["priority"] = *req.Priority
 	}
 
-	// 更新条件和动作
-	if err := rule.SetConditions(req.Conditions); err != nil {
-		return fmt.Errorf("invalid conditions: %w", err)
-	}
-	if err := rule.SetActions(req.Actions); err != nil {
-		return fmt.Errorf("invalid actions: %w", err)
-	}
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := s.ensureBaselineRevision(tx, rule); err != nil {
+			return err
+		}
 
-	updates["conditions"] = rule.Conditions
-	updates["actions"] = rule.Actions
+		// 更新条件和动作
+		if err := rule.SetConditions(req.Conditions); err != nil {
+			return fmt.Errorf("invalid conditions: %w", err)
+		}
+		if err := rule.SetActions(req.Actions); err != nil {
+			return fmt.Errorf("invalid actions: %w", err)
+		}
+
+		updates["conditions"] = rule.Conditions
+		updates["actions"] = rule.Actions
 
-	return s.db.WithContext(ctx).Model(rule).Updates(updates).Error
+		if err := tx.Model(rule).Updates(updates).Error; err != nil {
+			return err
+		}
+		if err := tx.First(rule, rule.ID).Error; err != nil {
+			return fmt.Errorf("failed to reload rule: %w", err)
+		}
+		return s.recordRuleRevision(tx, rule, models.RuleRevisionUpdate, "", &userID)
+	})
 }
 
 // DeleteRule 删除规则
 func (s *AutomationService) DeleteRule(ctx context.Context, ruleID uint) error {
-	result := s.db.WithContext(ctx).Delete(&models.AutomationRule{}, ruleID)
-	if result.Error != nil {
-		return fmt.Errorf("failed to delete rule: %w", result.Error)
-	}
-	if result.RowsAffected == 0 {
-		return fmt.Errorf("rule not found")
-	}
-	return nil
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&models.AutomationRule{}, ruleID)
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete rule: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("rule not found")
+		}
+		return tx.Where("rule_id = ?", ruleID).Delete(&models.AutomationRuleRevision{}).Error
+	})
 }
 
 // ExecuteRules 执行自动化规则
@@ -201,7 +235,7 @@ func (s *AutomationService) executeRule(ctx context.Context, rule *models.Automa
 		return errors.New(errorMsg)
 	}
 
-	if !s.evaluateConditions(conditions, ticket) {
+	if !s.evaluateConditions(ctx, conditions, ticket) {
 		return nil // 条件不匹配，跳过执行
 	}
 
@@ -224,14 +258,24 @@ func (s *AutomationService) executeRule(ctx context.Context, rule *models.Automa
 }
 
 // evaluateConditions 评估条件
-func (s *AutomationService) evaluateConditions(conditions []models.RuleCondition, ticket *models.Ticket) bool {
+func (s *AutomationService) evaluateConditions(ctx context.Context, conditions []models.RuleCondition, ticket *models.Ticket) bool {
 	if len(conditions) == 0 {
 		return true // 无条件则总是匹配
 	}
 
+	return s.matchConditions(conditions, ticket, s.newConditionCalendar(ctx, time.Now()))
+}
+
+// newConditionCalendar 创建条件评估上下文，now 为时间类条件的参考时间
+func (s *AutomationService) newConditionCalendar(ctx context.Context, now time.Time) *conditionCalendar {
+	return &conditionCalendar{ctx: ctx, service: s.calendarService, now: now, attributes: NewUserAttributeService(s.db)}
+}
+
+// matchConditions 按逻辑操作符依次组合各条件的结果
+func (s *AutomationService) matchConditions(conditions []models.RuleCondition, ticket *models.Ticket, calendar *conditionCalendar) bool {
 	result := true
 	for i, condition := range conditions {
-		conditionResult := s.evaluateCondition(&condition, ticket)
+		conditionResult := s.evaluateCondition(&condition, ticket, calendar)
 
 		if i == 0 {
 			result = conditionResult
@@ -250,9 +294,12 @@ func (s *AutomationService) evaluateConditions(conditions []models.RuleCondition
 }
 
 // evaluateCondition 评估单个条件
-func (s *AutomationService) evaluateCondition(condition *models.RuleCondition, ticket *models.Ticket) bool {
-	fieldValue := s.getTicketFieldValue(condition.Field, ticket)
+func (s *AutomationService) evaluateCondition(condition *models.RuleCondition, ticket *models.Ticket, calendar *conditionCalendar) bool {
+	fieldValue := s.getTicketFieldValue(condition.Field, ticket, calendar)
 	conditionValue := condition.Value
+	if list, ok := fieldValue.([]string); ok {
+		return evaluateListCondition(condition.Operator, list, conditionValue)
+	}
 
 	switch condition.Operator {
 	case "eq":
@@ -302,8 +349,8 @@ func (s *AutomationService) evaluateCondition(condition *models.RuleCondition, t
 	}
 }
 
-// getTicketFieldValue 获取工单字段值
-func (s *AutomationService) getTicketFieldValue(field string, ticket *models.Ticket) interface{} {
+// getTicketFieldValue 获取工单字段值，营业日历相关字段按需加载日历，日历不可用时为 nil
+func (s *AutomationService) getTicketFieldValue(field string, ticket *models.Ticket, calendar *conditionCalendar) interface{} {
 	switch field {
 	case "title":
 		return ticket.Title
@@ -313,6 +360,10 @@ func (s *AutomationService) getTicketFieldValue(field string, ticket *models.Tic
 		return ticket.Type
 	case "priority":
 		return ticket.Priority
+	case "impact":
+		return ticket.Impact
+	case "urgency":
+		return ticket.Urgency
 	case "status":
 		return ticket.Status
 	case "assigned_user_id":
@@ -326,9 +377,143 @@ func (s *AutomationService) getTicketFieldValue(field string, ticket *models.Tic
 		return ticket.CreatedAt.Format(time.RFC3339)
 	case "updated_at":
 		return ticket.UpdatedAt.Format(time.RFC3339)
+	case "rating":
+		if ticket.Rating != nil {
+			return *ticket.Rating
+		}
+		return nil
+	case "rating_comment":
+		return ticket.RatingComment
+	case "source":
+		return ticket.Source
+	case "spam_score":
+		return ticket.SpamScore
+	case "resolution_code":
+		return ticket.ResolutionCode
+	case "category_id":
+		if ticket.CategoryID != nil {
+			return *ticket.CategoryID
+		}
+		return nil
+	case "is_business_hours":
+		if clock := calendar.clock(); clock != nil {
+			return clock.isOpen(ticket.CreatedAt.In(clock.loc))
+		}
+		return nil
+	case "is_holiday":
+		if clock := calendar.clock(); clock != nil {
+			return clock.isHoliday(ticket.CreatedAt)
+		}
+		return nil
+	case "hours_since_created_business":
+		if clock := calendar.clock(); clock != nil {
+			return math.Round(clock.businessHoursBetween(ticket.CreatedAt, calendar.now)*100) / 100
+		}
+		return nil
+	case "assignee_skills":
+		if ticket.AssignedToID == nil {
+			return []string{}
+		}
+		if attrs := calendar.userAttributes(*ticket.AssignedToID); attrs != nil {
+			skills := make([]string, 0, len(attrs.Skills))
+			for _, skill := range attrs.Skills {
+				skills = append(skills, skill.Skill)
+			}
+			return skills
+		}
+		return nil
 	default:
+		// 处理人及提交人的自定义字段：assignee_field.<key>、creator_field.<key>
+		if key, ok := strings.CutPrefix(field, "assignee_field."); ok && ticket.AssignedToID != nil {
+			if attrs := calendar.userAttributes(*ticket.AssignedToID); attrs != nil {
+				return attrs.Fields[key]
+			}
+		} else if key, ok := strings.CutPrefix(field, "creator_field."); ok {
+			if attrs := calendar.userAttributes(ticket.CreatedByID); attrs != nil {
+				return attrs.Fields[key]
+			}
+		}
+		return nil
+	}
+}
+
+// evaluateListCondition 评估列表字段（技能、多选字段）：contains 为包含某一项，in 为包含任一项，not_in 为都不包含
+func evaluateListCondition(operator string, list []string, conditionValue interface{}) bool {
+	has := func(value interface{}) bool {
+		target := strings.ToLower(fmt.Sprintf("%v", value))
+		for _, item := range list {
+			if strings.ToLower(item) == target {
+				return true
+			}
+		}
+		return false
+	}
+	switch operator {
+	case "contains":
+		return has(conditionValue)
+	case "in", "not_in":
+		values, ok := conditionValue.([]interface{})
+		if !ok {
+			return false
+		}
+		for _, v := range values {
+			if has(v) {
+				return operator == "in"
+			}
+		}
+		return operator == "not_in"
+	default:
+		return false
+	}
+}
+
+// conditionCalendar 规则条件使用的营业日历及用户属性，同一次评估内只加载一次
+type conditionCalendar struct {
+	ctx     context.Context
+	service *BusinessCalendarService
+	now     time.Time
+
+	loaded bool
+	cached *businessClock
+
+	attributes *UserAttributeService
+	users      map[uint]*models.UserAttributes
+}
+
+// userAttributes 获取处理人或提交人的自定义字段及技能，加载失败时为 nil
+func (c *conditionCalendar) userAttributes(userID uint) *models.UserAttributes {
+	if c == nil || c.attributes == nil {
+		return nil
+	}
+	if attrs, ok := c.users[userID]; ok {
+		return attrs
+	}
+	attrs, err := c.attributes.loadAttributes(c.ctx, userID)
+	if err != nil {
+		log.Printf("Warning: user attributes unavailable for automation conditions: %v", err)
+		attrs = nil
+	}
+	if c.users == nil {
+		c.users = make(map[uint]*models.UserAttributes)
+	}
+	c.users[userID] = attrs
+	return attrs
+}
+
+func (c *conditionCalendar) clock() *businessClock {
+	if c == nil || c.service == nil {
 		return nil
 	}
+	if !c.loaded {
+		c.loaded = true
+		calendar, err := c.service.GetCalendar(c.ctx)
+		if err != nil {
+			log.Printf("Warning: business calendar unavailable for automation conditions: %v", err)
+			return nil
+		}
+		c.cached = newBusinessClock(calendar)
+	}
+	return c.cached
 }
 
 // compareValues 比较值
@@ -388,6 +573,8 @@ func (s *AutomationService) executeAction(ctx context.Context, action *models.Ru
 	switch action.Type {
 	case "assign":
 		return s.executeAssignAction(ctx, action, ticket)
+	case "assign_by_skill":
+		return s.executeAssignBySkillAction(ctx, action, ticket)
 	case "set_priority":
 		return s.executeSetPriorityAction(ctx, action, ticket)
 	case "set_status":
@@ -398,6 +585,10 @@ func (s *AutomationService) executeAction(ctx context.Context, action *models.Ru
 		return s.executeNotifyAction(ctx, action, ticket)
 	case "escalate":
 		return s.executeEscalateAction(ctx, action, ticket)
+	case "create_ticket":
+		return s.executeCreateTicketAction(ctx, action, ticket)
+	case "create_war_room":
+		return s.executeCreateWarRoomAction(ctx, action, ticket)
 	default:
 		return fmt.Errorf("unknown action type: %s", action.Type)
 	}
@@ -430,6 +621,51 @@ func (s *AutomationService) executeAssignAction(ctx context.Context, action *mod
 	return s.db.WithContext(ctx).Model(ticket).Updates(updates).Error
 }
 
+// executeAssignBySkillAction 按技能分配：在具备全部技能的在职坐席中选择未完结工单最少的
+// 参数: skills(必填，字符串数组), min_level(可选，默认 1), team_id(可选，只在该团队成员中选择)
+func (s *AutomationService) executeAssignBySkillAction(ctx context.Context, action *models.RuleAction, ticket *models.Ticket) error {
+	rawSkills, ok := action.Params["skills"].([]interface{})
+	if !ok || len(rawSkills) == 0 {
+		return fmt.Errorf("skills parameter required for assign_by_skill action")
+	}
+	skills := make([]string, 0, len(rawSkills))
+	for _, skill := range rawSkills {
+		skills = append(skills, fmt.Sprintf("%v", skill))
+	}
+	minLevel := 1
+	if v, ok := action.Params["min_level"]; ok {
+		level, err := s.toUint(v)
+		if err != nil {
+			return fmt.Errorf("invalid min_level: %w", err)
+		}
+		minLevel = int(level)
+	}
+	var teamID *uint
+	if v, ok := action.Params["team_id"]; ok {
+		id, err := s.toUint(v)
+		if err != nil {
+			return fmt.Errorf("invalid team_id: %w", err)
+		}
+		teamID = &id
+	}
+
+	candidates, err := NewUserAttributeService(s.db).FindAgentsBySkills(ctx, skills, minLevel, teamID)
+	if err != nil {
+		return err
+	}
+	if len(candidates) == 0 {
+		return fmt.Errorf("no active agent has skills %v", skills)
+	}
+
+	assigneeID := candidates[0].UserID
+	if err := s.db.WithContext(ctx).Model(&models.Ticket{}).Where("id = ?", ticket.ID).
+		Updates(map[string]interface{}{"assigned_to_id": assigneeID, "updated_at": time.Now()}).Error; err != nil {
+		return fmt.Errorf("failed to assign ticket: %w", err)
+	}
+	ticket.AssignedToID = &assigneeID
+	return nil
+}
+
 // executeSetPriorityAction 执行设置优先级动作
 func (s *AutomationService) executeSetPriorityAction(ctx context.Context, action *models.RuleAction, ticket *models.Ticket) error {
 	priorityParam, ok := action.Params["priority"]
@@ -487,7 +723,35 @@ func (s *AutomationService) executeSetStatusAction(ctx context.Context, action *
 		"updated_at": time.Now(),
 	}
 
-	return s.db.WithContext(ctx).Model(ticket).Updates(updates).Error
+	// 已解决/已关闭的工单被改回处理状态时视为重新打开（如低分评价触发）
+	reopen := (ticket.Status == models.TicketStatusResolved || ticket.Status == models.TicketStatusClosed) &&
+		(status == string(models.TicketStatusOpen) || status == string(models.TicketStatusInProgress))
+	if !reopen {
+		return s.db.WithContext(ctx).Model(ticket).Updates(updates).Error
+	}
+
+	updates["resolved_at"] = nil
+	updates["closed_at"] = nil
+	updates["resolution_code"] = ""
+	oldStatus := ticket.Status
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(ticket).Updates(updates).Error; err != nil {
+			return err
+		}
+		history := &models.TicketHistory{
+			TicketID:    ticket.ID,
+			Action:      models.HistoryActionReopen,
+			Description: "自动化规则重新打开了工单",
+			FieldName:   "status",
+			OldValue:    string(oldStatus),
+			NewValue:    status,
+			IsVisible:   true,
+			IsSystem:    true,
+			IsAutomated: true,
+			IsImportant: true,
+		}
+		return tx.Create(history).Error
+	})
 }
 
 // executeAddCommentAction 执行添加评论动作
@@ -513,13 +777,140 @@ func (s *AutomationService) executeAddCommentAction(ctx context.Context, action
 }
 
 // executeNotifyAction 执行通知动作
+// 参数: recipients(assignee/creator/team_lead/supervisors，默认assignee), user_ids,
+// title, content, priority；title/content 支持 {{ticket.rating}} 等变量替换
 func (s *AutomationService) executeNotifyAction(ctx context.Context, action *models.RuleAction, ticket *models.Ticket) error {
-	// 这里可以集成通知服务
-	// 暂时只记录日志
-	log.Printf("Notification action executed for ticket %d", ticket.ID)
+	recipientIDs, err := s.resolveNotifyRecipients(ctx, action, ticket)
+	if err != nil {
+		return err
+	}
+	if len(recipientIDs) == 0 {
+		log.Printf("Notify action for ticket %d has no recipients", ticket.ID)
+		return nil
+	}
+
+	title := fmt.Sprintf("工单提醒 - %s", ticket.Title)
+	if v, ok := action.Params["title"]; ok {
+		title = renderTicketVariables(fmt.Sprintf("%v", v), ticket)
+	}
+	content := fmt.Sprintf("工单 #%s 触发了自动化规则", ticket.TicketNumber)
+	if v, ok := action.Params["content"]; ok {
+		content = renderTicketVariables(fmt.Sprintf("%v", v), ticket)
+	}
+	priority := models.NotificationPriorityNormal
+	if v, ok := action.Params["priority"]; ok {
+		priority = models.NotificationPriority(fmt.Sprintf("%v", v))
+	}
+
+	metadata := map[string]interface{}{
+		"ticket_number": ticket.TicketNumber,
+	}
+	if ticket.Rating != nil {
+		metadata["rating"] = *ticket.Rating
+		metadata["rating_comment"] = ticket.RatingComment
+	}
+
+	notificationService := NewNotificationService(s.db)
+	for _, recipientID := range recipientIDs {
+		if _, err := notificationService.CreateNotification(ctx, &models.NotificationCreateRequest{
+			Type:            models.NotificationTypeSystemAlert,
+			Title:           title,
+			Content:         content,
+			Priority:        priority,
+			Channel:         models.NotificationChannelInApp,
+			RecipientID:     recipientID,
+			RelatedType:     "ticket",
+			RelatedID:       &ticket.ID,
+			RelatedTicketID: &ticket.ID,
+			ActionURL:       fmt.Sprintf("/tickets/%d", ticket.ID),
+			Metadata:        metadata,
+		}); err != nil {
+			return fmt.Errorf("failed to notify user %d: %w", recipientID, err)
+		}
+	}
 	return nil
 }
 
+// resolveNotifyRecipients 解析通知动作的接收人并去重
+func (s *AutomationService) resolveNotifyRecipients(ctx context.Context, action *models.RuleAction, ticket *models.Ticket) ([]uint, error) {
+	var kinds []string
+	switch v := action.Params["recipients"].(type) {
+	case nil:
+		if _, ok := action.Params["user_ids"]; !ok {
+			kinds = []string{"assignee"}
+		}
+	case string:
+		kinds = []string{v}
+	case []interface{}:
+		for _, item := range v {
+			kinds = append(kinds, fmt.Sprintf("%v", item))
+		}
+	default:
+		return nil, fmt.Errorf("invalid recipients: %v", v)
+	}
+
+	var ids []uint
+	for _, kind := range kinds {
+		switch kind {
+		case "assignee":
+			if ticket.AssignedToID != nil {
+				ids = append(ids, *ticket.AssignedToID)
+			}
+		case "creator":
+			ids = append(ids, ticket.CreatedByID)
+		case "team_lead":
+			var leadIDs []uint
+			query := s.db.WithContext(ctx).Model(&models.Team{}).Where("teams.is_active = ? AND teams.lead_id IS NOT NULL", true)
+			if ticket.AssignedTeamID != nil {
+				query = query.Where("teams.id = ?", *ticket.AssignedTeamID)
+			} else if ticket.AssignedToID != nil {
+				query = query.Joins("JOIN team_members ON team_members.team_id = teams.id").
+					Where("team_members.user_id = ?", *ticket.AssignedToID).Distinct()
+			} else {
+				continue
+			}
+			if err := query.Pluck("teams.lead_id", &leadIDs).Error; err != nil {
+				return nil, fmt.Errorf("failed to get team leads: %w", err)
+			}
+			ids = append(ids, leadIDs...)
+		case "supervisors":
+			var supervisorIDs []uint
+			if err := s.db.WithContext(ctx).Model(&models.User{}).
+				Where("role = ? AND status = ?", models.RoleSupervisor, models.UserStatusActive).
+				Pluck("id", &supervisorIDs).Error; err != nil {
+				return nil, fmt.Errorf("failed to get supervisors: %w", err)
+			}
+			ids = append(ids, supervisorIDs...)
+		default:
+			return nil, fmt.Errorf("invalid recipient: %s", kind)
+		}
+	}
+
+	if v, ok := action.Params["user_ids"]; ok {
+		list, ok := v.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("user_ids must be an array")
+		}
+		for _, item := range list {
+			id, err := s.toUint(item)
+			if err != nil {
+				return nil, fmt.Errorf("invalid user_ids: %w", err)
+			}
+			ids = append(ids, id)
+		}
+	}
+
+	seen := make(map[uint]bool, len(ids))
+	unique := ids[:0]
+	for _, id := range ids {
+		if id != 0 && !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique, nil
+}
+
 // executeEscalateAction 执行升级动作
 func (s *AutomationService) executeEscalateAction(ctx context.Context, action *models.RuleAction, ticket *models.Ticket) error {
 	// 升级逻辑，比如分配给管理员
@@ -542,6 +933,250 @@ func (s *AutomationService) executeEscalateAction(ctx context.Context, action *m
 	return s.db.WithContext(ctx).Model(ticket).Updates(updates).Error
 }
 
+const (
+	// defaultMaxChainDepth 自动创建子工单的默认最大链深度
+	defaultMaxChainDepth = 3
+	// maxChainDepthLimit 规则可配置的链深度上限，防止规则互相触发无限创建工单
+	maxChainDepthLimit = 10
+)
+
+// executeCreateTicketAction 执行创建子工单动作
+// 参数: template_id(可选), title, description, type, priority, assign_to_user_id,
+// assign_to(parent_assignee/parent_creator), max_chain_depth
+// title/description 支持 {{ticket.number}} 等变量替换
+// 使用模板时模板中的检查项一并添加到子工单
+func (s *AutomationService) executeCreateTicketAction(ctx context.Context, action *models.RuleAction, ticket *models.Ticket) error {
+	maxDepth := defaultMaxChainDepth
+	if v, ok := action.Params["max_chain_depth"]; ok {
+		depth, err := s.toUint(v)
+		if err != nil {
+			return fmt.Errorf("invalid max_chain_depth: %w", err)
+		}
+		maxDepth = int(depth)
+		if depth > maxChainDepthLimit {
+			maxDepth = maxChainDepthLimit
+		}
+	}
+	if ticket.ChainDepth >= maxDepth {
+		return fmt.Errorf("max chain depth %d reached for ticket %d", maxDepth, ticket.ID)
+	}
+
+	titleTpl := ""
+	descTpl := ""
+	ticketType := models.TicketTypeRequest
+	priority := ticket.Priority
+	var assigneeID *uint
+	var template *models.TicketTemplate
+	var checklist []models.ChecklistTemplateItem
+
+	if v, ok := action.Params["template_id"]; ok {
+		templateID, err := s.toUint(v)
+		if err != nil {
+			return fmt.Errorf("invalid template_id: %w", err)
+		}
+		var tpl models.TicketTemplate
+		if err := s.db.WithContext(ctx).First(&tpl, templateID).Error; err != nil {
+			return fmt.Errorf("template not found: %w", err)
+		}
+		titleTpl = tpl.TitleTemplate
+		descTpl = tpl.ContentTemplate
+		if tpl.DefaultType != "" {
+			ticketType = models.TicketType(tpl.DefaultType)
+		}
+		if tpl.DefaultPriority != "" {
+			priority = models.TicketPriority(tpl.DefaultPriority)
+		}
+		assigneeID = tpl.AssignToUserID
+		if checklist, err = tpl.GetChecklistItems(); err != nil {
+			return fmt.Errorf("template %d has invalid checklist items: %w", tpl.ID, err)
+		}
+		template = &tpl
+		s.db.WithContext(ctx).Model(&tpl).UpdateColumn("usage_count", gorm.Expr("usage_count + 1"))
+	}
+
+	if v, ok := action.Params["title"]; ok {
+		titleTpl = fmt.Sprintf("%v", v)
+	}
+	if v, ok := action.Params["description"]; ok {
+		descTpl = fmt.Sprintf("%v", v)
+	}
+	if v, ok := action.Params["type"]; ok {
+		ticketType = models.TicketType(fmt.Sprintf("%v", v))
+	}
+	if v, ok := action.Params["priority"]; ok {
+		priority = models.TicketPriority(fmt.Sprintf("%v", v))
+	}
+	if titleTpl == "" {
+		return fmt.Errorf("title or template_id parameter required for create_ticket action")
+	}
+
+	if v, ok := action.Params["assign_to_user_id"]; ok {
+		userID, err := s.toUint(v)
+		if err != nil {
+			return fmt.Errorf("invalid assign_to_user_id: %w", err)
+		}
+		assigneeID = &userID
+	} else if v, ok := action.Params["assign_to"]; ok {
+		switch fmt.Sprintf("%v", v) {
+		case "parent_assignee":
+			assigneeID = ticket.AssignedToID
+		case "parent_creator":
+			creatorID := ticket.CreatedByID
+			assigneeID = &creatorID
+		default:
+			return fmt.Errorf("invalid assign_to: %v", v)
+		}
+	}
+
+	title := renderTicketVariables(titleTpl, ticket)
+	// 按字符截断，避免截断多字节字符
+	if runes := []rune(title); len(runes) > 255 {
+		title = string(runes[:255])
+	}
+
+	// 同一父工单下已存在同名子工单时不再重复创建，避免定时规则反复触发
+	var existing int64
+	if err := s.db.WithContext(ctx).Model(&models.Ticket{}).
+		Where("parent_ticket_id = ? AND title = ?", ticket.ID, title).
+		Count(&existing).Error; err != nil {
+		return fmt.Errorf("failed to check existing child tickets: %w", err)
+	}
+	if existing > 0 {
+		return nil
+	}
+
+	// 系统用户ID，可以配置
+	systemUserID := uint(1)
+	parentID := ticket.ID
+	child := &models.Ticket{
+		TicketNumber:   newTicketNumber(),
+		Title:          title,
+		Description:    renderTicketVariables(descTpl, ticket),
+		Type:           ticketType,
+		Priority:       priority,
+		Status:         models.TicketStatusOpen,
+		Source:         models.TicketSourceAPI,
+		CreatedByID:    systemUserID,
+		AssignedToID:   assigneeID,
+		CategoryID:     ticket.CategoryID,
+		CustomerEmail:  ticket.CustomerEmail,
+		CustomerPhone:  ticket.CustomerPhone,
+		CustomerName:   ticket.CustomerName,
+		ParentTicketID: &parentID,
+		ChainDepth:     ticket.ChainDepth + 1,
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(child).Error; err != nil {
+			return fmt.Errorf("failed to create child ticket: %w", err)
+		}
+
+		histories := []models.TicketHistory{
+			{
+				TicketID:    child.ID,
+				Action:      models.HistoryActionCreate,
+				Description: fmt.Sprintf("由自动化规则从工单 #%s 创建", ticket.TicketNumber),
+				FieldName:   "parent_ticket_id",
+				NewValue:    strconv.FormatUint(uint64(ticket.ID), 10),
+				IsSystem:    true,
+				IsAutomated: true,
+			},
+			{
+				TicketID:    ticket.ID,
+				Action:      models.HistoryActionSplit,
+				Description: fmt.Sprintf("自动化规则创建了子工单 #%s", child.TicketNumber),
+				FieldName:   "child_ticket",
+				NewValue:    strconv.FormatUint(uint64(child.ID), 10),
+				IsSystem:    true,
+				IsAutomated: true,
+			},
+		}
+		if err := tx.Create(&histories).Error; err != nil {
+			return fmt.Errorf("failed to record child ticket history: %w", err)
+		}
+
+		if len(checklist) > 0 {
+			if _, err := createChecklistFromTemplate(tx, child.ID, template, checklist, systemUserID); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// executeCreateWarRoomAction 执行创建升级作战室动作，仅对严重优先级工单生效，每个工单只创建一次
+// 参数: platform(slack/teams，默认slack), channel_id(在已有频道中发起线程，不新建频道),
+// channel_prefix, invite(assignee/team/on_call，默认全部), on_call_user_ids
+func (s *AutomationService) executeCreateWarRoomAction(ctx context.Context, action *models.RuleAction, ticket *models.Ticket) error {
+	opts := &WarRoomOptions{}
+	if v, ok := action.Params["platform"]; ok {
+		opts.Platform = models.ChatPlatform(fmt.Sprintf("%v", v))
+	}
+	if v, ok := action.Params["channel_id"]; ok {
+		opts.ChannelID = fmt.Sprintf("%v", v)
+	}
+	if v, ok := action.Params["channel_prefix"]; ok {
+		opts.ChannelPrefix = fmt.Sprintf("%v", v)
+	}
+	switch v := action.Params["invite"].(type) {
+	case nil:
+	case string:
+		opts.Invite = []string{v}
+	case []interface{}:
+		for _, item := range v {
+			opts.Invite = append(opts.Invite, fmt.Sprintf("%v", item))
+		}
+	default:
+		return fmt.Errorf("invalid invite: %v", v)
+	}
+	if v, ok := action.Params["on_call_user_ids"]; ok {
+		list, ok := v.([]interface{})
+		if !ok {
+			return fmt.Errorf("on_call_user_ids must be an array")
+		}
+		for _, item := range list {
+			id, err := s.toUint(item)
+			if err != nil {
+				return fmt.Errorf("invalid on_call_user_ids: %w", err)
+			}
+			opts.OnCallUserIDs = append(opts.OnCallUserIDs, id)
+		}
+	}
+
+	_, err := s.warRoomService.Open(ctx, ticket, opts)
+	if errors.Is(err, ErrWarRoomExists) || errors.Is(err, ErrWarRoomNotCritical) {
+		return nil
+	}
+	return err
+}
+
+// renderTicketVariables 替换模板中的工单变量
+func renderTicketVariables(tpl string, ticket *models.Ticket) string {
+	if tpl == "" {
+		return ""
+	}
+
+	rating := ""
+	if ticket.Rating != nil {
+		rating = strconv.Itoa(*ticket.Rating)
+	}
+	replacer := strings.NewReplacer(
+		"{{ticket.id}}", strconv.FormatUint(uint64(ticket.ID), 10),
+		"{{ticket.number}}", ticket.TicketNumber,
+		"{{ticket.title}}", ticket.Title,
+		"{{ticket.description}}", ticket.Description,
+		"{{ticket.type}}", string(ticket.Type),
+		"{{ticket.priority}}", string(ticket.Priority),
+		"{{ticket.status}}", string(ticket.Status),
+		"{{ticket.customer_name}}", ticket.CustomerName,
+		"{{ticket.customer_email}}", ticket.CustomerEmail,
+		"{{ticket.rating}}", rating,
+		"{{ticket.rating_comment}}", ticket.RatingComment,
+	)
+	return replacer.Replace(tpl)
+}
+
 // toUint 转换为uint
 func (s *AutomationService) toUint(value interface{}) (uint, error) {
 	switch v := value.(type) {
@@ -681,6 +1316,12 @@ func (s *AutomationService) CreateSLAConfig(ctx context.Context, req *models.SLA
 		config.EscalationRules = string(escalationJSON)
 	}
 
+	thresholds, err := encodeSLAWarningThresholds(req.WarningThresholds)
+	if err != nil {
+		return nil, err
+	}
+	config.WarningThresholds = thresholds
+
 	// 如果设置为默认配置，需要取消其他默认配置
 	if config.IsDefault {
 		if err := s.db.WithContext(ctx).Model(&models.SLAConfig{}).
@@ -719,8 +1360,63 @@ func (s *AutomationService) GetSLAConfigs(ctx context.Context, isActive *bool, p
 	return configs, total, nil
 }
 
-// GetSLAConfigForTicket 为工单获取适用的SLA配置
+// GetSLAConfigForTicket 为工单获取适用的SLA配置。客户命中有效的SLA合同时，
+// 合同的响应/解决时限优先于按类型、优先级匹配的配置，工作时间与升级规则沿用匹配到的配置
 func (s *AutomationService) GetSLAConfigForTicket(ctx context.Context, ticket *models.Ticket) (*models.SLAConfig, error) {
+	contract, err := s.GetSLAContractForTicket(ctx, ticket)
+	if err != nil {
+		// 合同查询失败不影响按配置计算SLA
+		log.Printf("Failed to get SLA contract for ticket %d: %v", ticket.ID, err)
+	}
+
+	config, err := s.matchSLAConfig(ctx, ticket)
+	if contract == nil {
+		return config, err
+	}
+	if err != nil {
+		config = &models.SLAConfig{ExcludeHolidays: true}
+	}
+	config.Name = contract.Name
+	config.ResponseTime = contract.ResponseTime
+	config.ResolutionTime = contract.ResolutionTime
+	config.ExcludeWeekends = contract.ExcludeWeekends
+	config.ContractID = &contract.ID
+	return config, nil
+}
+
+// GetSLAContractForTicket 按工单客户邮箱（未填写时取提交人邮箱）及创建时间查找有效的SLA合同，没有时返回 nil
+func (s *AutomationService) GetSLAContractForTicket(ctx context.Context, ticket *models.Ticket) (*models.SLAContract, error) {
+	email := ticket.CustomerEmail
+	if email == "" && ticket.CreatedByID != 0 {
+		if ticket.CreatedBy != nil {
+			email = ticket.CreatedBy.Email
+		} else if err := s.db.WithContext(ctx).Model(&models.User{}).
+			Where("id = ?", ticket.CreatedByID).Pluck("email", &email).Error; err != nil {
+			return nil, fmt.Errorf("failed to get ticket customer: %w", err)
+		}
+	}
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return nil, nil
+	}
+
+	at := ticket.CreatedAt
+	if at.IsZero() {
+		at = time.Now()
+	}
+	var contracts []models.SLAContract
+	if err := s.db.WithContext(ctx).
+		Where("is_active = ? AND valid_from <= ? AND (valid_until IS NULL OR valid_until > ?)", true, at, at).
+		Where("(match_type = ? AND pattern = ?) OR (match_type = ? AND pattern = ?)",
+			models.SLAContractMatchEmail, email, models.SLAContractMatchDomain, models.EmailDomain(email)).
+		Find(&contracts).Error; err != nil {
+		return nil, fmt.Errorf("failed to get SLA contracts: %w", err)
+	}
+	return selectSLAContract(contracts, email, string(ticket.Priority), at), nil
+}
+
+// matchSLAConfig 按工单类型、优先级、处理人匹配SLA配置，没有匹配时使用默认配置
+func (s *AutomationService) matchSLAConfig(ctx context.Context, ticket *models.Ticket) (*models.SLAConfig, error) {
 	query := s.db.WithContext(ctx).Where("is_active = ?", true)
 
 	// 按优先级查找最匹配的配置
@@ -855,6 +1551,15 @@ func (s *AutomationService) CreateTemplate(ctx context.Context, req *models.Tick
 		template.CustomFields = string(customFieldsJSON)
 	}
 
+	// 设置检查项模板
+	if len(req.ChecklistItems) > 0 {
+		checklistJSON, err := json.Marshal(req.ChecklistItems)
+		if err != nil {
+			return nil, fmt.Errorf("invalid checklist items: %w", err)
+		}
+		template.ChecklistItems = string(checklistJSON)
+	}
+
 	if err := s.db.WithContext(ctx).Create(template).Error; err != nil {
 		return nil, fmt.Errorf("failed to create template: %w", err)
 	}
@@ -1011,37 +1716,10 @@ func (s *AutomationService) BatchAssignTickets(ctx context.Context, ticketIDs []
 	return s.BatchUpdateTickets(ctx, ticketIDs, updates)
 }
 
-// ClassifyTicket 工单自动分类
+// ClassifyTicket 工单自动分类，使用管理员配置的分类服务，高置信度的字段直接写入工单
 func (s *AutomationService) ClassifyTicket(ctx context.Context, ticket *models.Ticket) error {
-	// 基于关键词的简单分类逻辑
-	content := strings.ToLower(ticket.Title + " " + ticket.Description)
-
-	// 定义分类规则
-	classificationRules := map[string][]string{
-		"bug":     {"bug", "error", "issue", "problem", "crash", "fail"},
-		"feature": {"feature", "enhancement", "improvement", "add", "new"},
-		"support": {"help", "support", "question", "how to", "guidance"},
-		"urgent":  {"urgent", "critical", "emergency", "asap", "immediately"},
-	}
-
-	// 应用分类规则
-	for category, keywords := range classificationRules {
-		for _, keyword := range keywords {
-			if strings.Contains(content, keyword) {
-				// 更新工单类型或添加标签
-				updates := map[string]interface{}{
-					"type":       category,
-					"updated_at": time.Now(),
-				}
-
-				if category == "urgent" {
-					updates["priority"] = "high"
-				}
-
-				return s.db.WithContext(ctx).Model(ticket).Updates(updates).Error
-			}
-		}
-	}
-
-	return nil
+	classifier := NewTicketClassificationService(s.db)
+	classifier.SetHistoryWriter(s.historyWriter)
+	_, err := classifier.Classify(ctx, ticket.ID)
+	return err
 }
@@ -2,7 +2,10 @@ package services
 
 import (
 	"context"
+	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
 
 	"gongdan-system/internal/models"
 	"gorm.io/driver/sqlite"
@@ -110,3 +113,131 @@ func TestAutomationServiceGetRulesFilters(t *testing.T) {
 		t.Fatalf("expected search to match 1 rule, got total=%d len=%d", total, len(rules))
 	}
 }
+
+func setupAutomationTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file:automation_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+
+	if err := db.AutoMigrate(&models.User{}, &models.Ticket{}, &models.TicketHistory{}, &models.TicketTemplate{}, &models.SystemConfig{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+
+	return db
+}
+
+func TestAutomationService_CreateTicketAction(t *testing.T) {
+	db := setupAutomationTestDB(t)
+	ctx := context.Background()
+	svc := NewAutomationService(db)
+
+	parent := models.Ticket{TicketNumber: "BUG-001", Title: "login bug", Status: models.TicketStatusResolved, Priority: models.TicketPriorityHigh, Type: models.TicketTypeProblem, Source: models.TicketSourceWeb, CreatedByID: 1}
+	if err := db.Create(&parent).Error; err != nil {
+		t.Fatalf("failed to seed ticket: %v", err)
+	}
+
+	action := &models.RuleAction{
+		Type: "create_ticket",
+		Params: map[string]interface{}{
+			"title":           "QA verify {{ticket.number}}",
+			"max_chain_depth": float64(1),
+		},
+	}
+
+	// 重复执行只应创建一个子工单
+	for i := 0; i < 2; i++ {
+		if err := svc.executeAction(ctx, action, &parent); err != nil {
+			t.Fatalf("create_ticket action failed: %v", err)
+		}
+	}
+
+	var children []models.Ticket
+	if err := db.Where("parent_ticket_id = ?", parent.ID).Find(&children).Error; err != nil {
+		t.Fatalf("failed to load children: %v", err)
+	}
+	if len(children) != 1 {
+		t.Fatalf("expected 1 child ticket, got %d", len(children))
+	}
+	child := children[0]
+	if child.Title != "QA verify BUG-001" || child.ChainDepth != 1 || child.Priority != models.TicketPriorityHigh {
+		t.Fatalf("unexpected child ticket: %+v", child)
+	}
+
+	// 达到最大链深度后不再继续创建
+	if err := svc.executeAction(ctx, action, &child); err == nil {
+		t.Fatalf("expected max chain depth error")
+	}
+
+	// 标题按字符截断；规则配置的链深度不超过上限
+	deep := models.Ticket{TicketNumber: "BUG-002", Title: "deep", Status: models.TicketStatusOpen, Priority: models.TicketPriorityNormal, Type: models.TicketTypeProblem, Source: models.TicketSourceWeb, CreatedByID: 1, ChainDepth: maxChainDepthLimit}
+	if err := db.Create(&deep).Error; err != nil {
+		t.Fatalf("failed to seed ticket: %v", err)
+	}
+	longTitle := &models.RuleAction{
+		Type:   "create_ticket",
+		Params: map[string]interface{}{"title": strings.Repeat("回访", 200), "max_chain_depth": float64(1000)},
+	}
+	if err := svc.executeAction(ctx, longTitle, &deep); err == nil {
+		t.Fatalf("expected chain depth to be capped at %d", maxChainDepthLimit)
+	}
+	deep.ChainDepth = 0
+	db.Model(&deep).UpdateColumn("chain_depth", 0)
+	if err := svc.executeAction(ctx, longTitle, &deep); err != nil {
+		t.Fatalf("create_ticket action failed: %v", err)
+	}
+	var truncated models.Ticket
+	if err := db.Where("parent_ticket_id = ?", deep.ID).First(&truncated).Error; err != nil {
+		t.Fatalf("failed to load child: %v", err)
+	}
+	if !utf8.ValidString(truncated.Title) || utf8.RuneCountInString(truncated.Title) != 255 {
+		t.Fatalf("expected title truncated to 255 characters, got %d", utf8.RuneCountInString(truncated.Title))
+	}
+}
+
+func TestAutomationService_BusinessCalendarConditions(t *testing.T) {
+	db := setupAutomationTestDB(t)
+	ctx := context.Background()
+	svc := NewAutomationService(db)
+
+	calendar := models.GetDefaultBusinessCalendar()
+	calendar.Holidays = []models.Holiday{{Date: "2024-10-01", Name: "国庆节"}}
+	if err := svc.calendarService.SetCalendar(ctx, calendar, 1); err != nil {
+		t.Fatalf("set calendar failed: %v", err)
+	}
+
+	loc, _ := time.LoadLocation("Asia/Shanghai")
+	at := func(value string) time.Time {
+		ts, err := time.ParseInLocation("2006-01-02 15:04", value, loc)
+		if err != nil {
+			t.Fatalf("bad time %q: %v", value, err)
+		}
+		return ts.UTC()
+	}
+	now := at("2024-10-14 10:00") // 周一
+
+	cases := []struct {
+		name      string
+		createdAt string
+		condition models.RuleCondition
+		want      bool
+	}{
+		{"workday within hours", "2024-10-08 10:00", models.RuleCondition{Field: "is_business_hours", Operator: "eq", Value: true}, true},
+		{"workday after hours", "2024-10-08 19:30", models.RuleCondition{Field: "is_business_hours", Operator: "eq", Value: false}, true},
+		{"weekend is closed but not a holiday", "2024-10-12 10:00", models.RuleCondition{Field: "is_holiday", Operator: "eq", Value: false}, true},
+		{"holiday", "2024-10-01 10:00", models.RuleCondition{Field: "is_holiday", Operator: "eq", Value: true}, true},
+		{"holiday is closed", "2024-10-01 10:00", models.RuleCondition{Field: "is_business_hours", Operator: "eq", Value: true}, false},
+		// 周五 17:00 创建，至周一 10:00 只经过 2 个营业小时
+		{"business hours skip weekend", "2024-10-11 17:00", models.RuleCondition{Field: "hours_since_created_business", Operator: "eq", Value: 2}, true},
+		{"business hours threshold", "2024-10-11 17:00", models.RuleCondition{Field: "hours_since_created_business", Operator: "gte", Value: 4}, false},
+	}
+	for _, tc := range cases {
+		ticket := &models.Ticket{CreatedAt: at(tc.createdAt)}
+		calendar := &conditionCalendar{ctx: ctx, service: svc.calendarService, now: now}
+		if got := svc.evaluateCondition(&tc.condition, ticket, calendar); got != tc.want {
+			t.Errorf("%s: expected %v, got %v (value %v)", tc.name, tc.want, got, svc.getTicketFieldValue(tc.condition.Field, ticket, calendar))
+		}
+	}
+}
@@ -0,0 +1,289 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// AutomationSimulationSyncMaxTickets 区间内工单数超过该值时模拟在后台执行
+	AutomationSimulationSyncMaxTickets = 2000
+	// automationSimulationMaxDays 模拟时间范围的最大天数
+	automationSimulationMaxDays = 366
+	// automationSimulationDefaultSamples 默认返回的示例工单数
+	automationSimulationDefaultSamples = 10
+	// automationSimulationRetention 模拟任务记录的保留时间
+	automationSimulationRetention = 7 * 24 * time.Hour
+	// automationSimulationBatchSize 每批加载的工单数
+	automationSimulationBatchSize = 500
+)
+
+var (
+	// ErrInvalidAutomationSimulation 模拟请求无效
+	ErrInvalidAutomationSimulation = errors.New("invalid automation simulation")
+	// ErrAutomationSimulationNotFound 模拟任务不存在
+	ErrAutomationSimulationNotFound = errors.New("automation simulation job not found")
+)
+
+// automationSimulationSpec 待模拟的规则内容，后台任务以JSON保存
+type automationSimulationSpec struct {
+	Conditions []models.RuleCondition `json:"conditions"`
+	Actions    []models.RuleAction    `json:"actions"`
+	SampleSize int                    `json:"sample_size"`
+}
+
+// SimulateRule 用历史工单评估规则条件，只读取数据，不执行任何动作。
+// 区间内工单较多或 async=true 时创建后台任务并返回任务，否则直接返回结果
+func (s *AutomationService) SimulateRule(ctx context.Context, req *models.AutomationSimulationRequest, actorID uint) (*models.AutomationSimulationResult, *models.AutomationSimulationJob, error) {
+	start, end, err := parseSimulationRange(req.StartDate, req.EndDate)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	spec := &automationSimulationSpec{Conditions: req.Conditions, Actions: req.Actions, SampleSize: req.SampleSize}
+	if req.RuleID != nil {
+		rule, err := s.GetRuleByID(ctx, *req.RuleID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if spec.Conditions, err = rule.GetConditions(); err != nil {
+			return nil, nil, fmt.Errorf("%w: failed to parse rule conditions: %v", ErrInvalidAutomationSimulation, err)
+		}
+		if spec.Actions, err = rule.GetActions(); err != nil {
+			return nil, nil, fmt.Errorf("%w: failed to parse rule actions: %v", ErrInvalidAutomationSimulation, err)
+		}
+	}
+	if spec.SampleSize <= 0 {
+		spec.SampleSize = automationSimulationDefaultSamples
+	}
+	if problems := ruleSpecProblems(spec.Conditions, spec.Actions); len(problems) > 0 {
+		return nil, nil, fmt.Errorf("%w: %s", ErrInvalidAutomationSimulation, strings.Join(problems, "; "))
+	}
+
+	total, err := s.countSimulationTickets(ctx, start, end)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !req.Async && total <= AutomationSimulationSyncMaxTickets {
+		result, err := s.runSimulation(ctx, spec, start, end, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+		result.RuleID = req.RuleID
+		return result, nil, nil
+	}
+
+	job, err := s.createSimulationJob(ctx, spec, req.RuleID, start, end, total, actorID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return nil, job, nil
+}
+
+// GetSimulationJob 获取模拟任务，已完成的任务附带结果
+func (s *AutomationService) GetSimulationJob(ctx context.Context, id uint) (*models.AutomationSimulationJob, error) {
+	var job models.AutomationSimulationJob
+	if err := s.db.WithContext(ctx).First(&job, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAutomationSimulationNotFound
+		}
+		return nil, fmt.Errorf("failed to get automation simulation job: %w", err)
+	}
+	if job.Result != "" {
+		var result models.AutomationSimulationResult
+		if err := json.Unmarshal([]byte(job.Result), &result); err != nil {
+			return nil, fmt.Errorf("failed to decode simulation result: %w", err)
+		}
+		job.ResultData = &result
+	}
+	return &job, nil
+}
+
+// parseSimulationRange 解析起止日期（YYYY-MM-DD），结束日期包含当天
+func parseSimulationRange(startDate, endDate string) (time.Time, time.Time, error) {
+	start, err := time.ParseInLocation("2006-01-02", startDate, time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: start_date must be YYYY-MM-DD", ErrInvalidAutomationSimulation)
+	}
+	endDay, err := time.ParseInLocation("2006-01-02", endDate, time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: end_date must be YYYY-MM-DD", ErrInvalidAutomationSimulation)
+	}
+	if endDay.Before(start) {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: end_date is before start_date", ErrInvalidAutomationSimulation)
+	}
+	end := endDay.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	if simulationDays(start, end) > automationSimulationMaxDays {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: range exceeds %d days", ErrInvalidAutomationSimulation, automationSimulationMaxDays)
+	}
+	return start, end, nil
+}
+
+// simulationDays 时间范围覆盖的天数
+func simulationDays(start, end time.Time) int {
+	return int(end.Sub(start).Hours()/24) + 1
+}
+
+func (s *AutomationService) simulationTickets(ctx context.Context, start, end time.Time) *gorm.DB {
+	return s.db.WithContext(ctx).Model(&models.Ticket{}).
+		Where("deleted_at IS NULL AND created_at >= ? AND created_at <= ?", start, end)
+}
+
+func (s *AutomationService) countSimulationTickets(ctx context.Context, start, end time.Time) (int64, error) {
+	var total int64
+	if err := s.simulationTickets(ctx, start, end).Count(&total).Error; err != nil {
+		return 0, fmt.Errorf("failed to count tickets: %w", err)
+	}
+	return total, nil
+}
+
+// runSimulation 逐批评估工单。时间类条件以工单创建时间为参考时间，
+// 处理人技能等用户属性使用当前值；progress 在每批结束后接收已扫描的工单数
+func (s *AutomationService) runSimulation(ctx context.Context, spec *automationSimulationSpec, start, end time.Time, progress func(scanned int64)) (*models.AutomationSimulationResult, error) {
+	result := &models.AutomationSimulationResult{
+		StartDate: start,
+		EndDate:   end,
+		Samples:   []models.AutomationSimulationSample{},
+	}
+	calendar := s.newConditionCalendar(ctx, time.Now())
+
+	var batch []models.Ticket
+	err := s.simulationTickets(ctx, start, end).
+		FindInBatches(&batch, automationSimulationBatchSize, func(tx *gorm.DB, _ int) error {
+			for i := range batch {
+				ticket := &batch[i]
+				result.Scanned++
+				calendar.now = ticket.CreatedAt
+				if !s.matchConditions(spec.Conditions, ticket, calendar) {
+					continue
+				}
+				result.Matched++
+				if len(result.Samples) < spec.SampleSize {
+					result.Samples = append(result.Samples, models.AutomationSimulationSample{
+						TicketID:     ticket.ID,
+						TicketNumber: ticket.TicketNumber,
+						Title:        ticket.Title,
+						Status:       ticket.Status,
+						Priority:     ticket.Priority,
+						CreatedAt:    ticket.CreatedAt,
+					})
+				}
+			}
+			if progress != nil {
+				progress(result.Scanned)
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan tickets: %w", err)
+	}
+
+	if result.Scanned > 0 {
+		result.MatchRate = math.Round(float64(result.Matched)/float64(result.Scanned)*10000) / 10000
+	}
+	days := float64(simulationDays(start, end))
+	result.ProjectedActions = make([]models.AutomationSimulationAction, 0, len(spec.Actions))
+	for _, action := range spec.Actions {
+		result.ProjectedActions = append(result.ProjectedActions, models.AutomationSimulationAction{
+			Type:       action.Type,
+			Executions: result.Matched,
+			PerDay:     math.Round(float64(result.Matched)/days*100) / 100,
+		})
+	}
+	return result, nil
+}
+
+// createSimulationJob 保存模拟任务并在后台执行
+func (s *AutomationService) createSimulationJob(ctx context.Context, spec *automationSimulationSpec, ruleID *uint, start, end time.Time, total int64, actorID uint) (*models.AutomationSimulationJob, error) {
+	if err := s.db.WithContext(ctx).Where("created_at < ?", time.Now().Add(-automationSimulationRetention)).
+		Delete(&models.AutomationSimulationJob{}).Error; err != nil {
+		log.Printf("Failed to purge old automation simulations: %v", err)
+	}
+
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode simulation spec: %w", err)
+	}
+	job := &models.AutomationSimulationJob{
+		RuleID:      ruleID,
+		Spec:        string(data),
+		StartDate:   start,
+		EndDate:     end,
+		Status:      models.AutomationSimulationPending,
+		Total:       total,
+		CreatedByID: actorID,
+	}
+	if err := s.db.WithContext(ctx).Create(job).Error; err != nil {
+		return nil, fmt.Errorf("failed to create automation simulation job: %w", err)
+	}
+	go s.runSimulationJob(context.Background(), job.ID)
+	return job, nil
+}
+
+// runSimulationJob 执行后台模拟任务并保存结果
+func (s *AutomationService) runSimulationJob(ctx context.Context, jobID uint) {
+	job, err := s.GetSimulationJob(ctx, jobID)
+	if err != nil {
+		log.Printf("Failed to load automation simulation job %d: %v", jobID, err)
+		return
+	}
+
+	jobs := s.db.WithContext(ctx).Model(&models.AutomationSimulationJob{}).Where("id = ?", jobID).Session(&gorm.Session{})
+	fail := func(err error) {
+		log.Printf("Automation simulation job %d failed: %v", jobID, err)
+		finished := time.Now()
+		jobs.Updates(map[string]interface{}{
+			"status":      models.AutomationSimulationFailed,
+			"error":       truncateString(err.Error(), 497),
+			"finished_at": &finished,
+		})
+	}
+
+	started := time.Now()
+	if err := jobs.Updates(map[string]interface{}{
+		"status":     models.AutomationSimulationRunning,
+		"started_at": &started,
+	}).Error; err != nil {
+		log.Printf("Failed to start automation simulation job %d: %v", jobID, err)
+		return
+	}
+
+	var spec automationSimulationSpec
+	if err := json.Unmarshal([]byte(job.Spec), &spec); err != nil {
+		fail(fmt.Errorf("failed to decode simulation spec: %w", err))
+		return
+	}
+
+	result, err := s.runSimulation(ctx, &spec, job.StartDate, job.EndDate, func(scanned int64) {
+		jobs.Update("scanned", scanned)
+	})
+	if err != nil {
+		fail(err)
+		return
+	}
+	result.RuleID = job.RuleID
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		fail(fmt.Errorf("failed to encode simulation result: %w", err))
+		return
+	}
+	finished := time.Now()
+	if err := jobs.Updates(map[string]interface{}{
+		"status":      models.AutomationSimulationCompleted,
+		"scanned":     result.Scanned,
+		"result":      string(data),
+		"finished_at": &finished,
+	}).Error; err != nil {
+		log.Printf("Failed to finish automation simulation job %d: %v", jobID, err)
+	}
+}
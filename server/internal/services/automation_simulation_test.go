@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestAutomationSimulation_ReadOnlyMatchesAndBackgroundJob(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:automation_simulation_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Ticket{}, &models.SystemConfig{}, &models.AutomationRule{},
+		&models.AutomationRuleRevision{}, &models.AutomationLog{}, &models.AutomationSimulationJob{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+	ctx := context.Background()
+	svc := NewAutomationService(db)
+
+	day := time.Date(2026, 9, 1, 10, 0, 0, 0, time.Local)
+	for i := 0; i < 6; i++ {
+		priority := models.TicketPriorityNormal
+		if i%2 == 0 {
+			priority = models.TicketPriorityUrgent
+		}
+		ticket := models.Ticket{TicketNumber: fmt.Sprintf("SIM-%d", i), Title: fmt.Sprintf("ticket %d", i), Status: models.TicketStatusOpen,
+			Priority: priority, Type: models.TicketTypeIncident, Source: models.TicketSourceWeb, CreatedByID: 1, CreatedAt: day.AddDate(0, 0, i)}
+		if err := db.Create(&ticket).Error; err != nil {
+			t.Fatalf("failed to seed ticket: %v", err)
+		}
+	}
+
+	rule, err := svc.CreateRule(ctx, &models.AutomationRuleRequest{
+		Name:         "紧急工单升级",
+		RuleType:     "escalation",
+		TriggerEvent: "ticket.created",
+		Conditions:   []models.RuleCondition{{Field: "priority", Operator: "eq", Value: "urgent"}},
+		Actions: []models.RuleAction{
+			{Type: "set_status", Params: map[string]interface{}{"status": "in_progress"}},
+			{Type: "notify", Params: map[string]interface{}{"recipients": []interface{}{"supervisors"}}},
+		},
+	}, 1)
+	if err != nil {
+		t.Fatalf("create rule failed: %v", err)
+	}
+
+	// 2026-09-01 至 09-04 共 4 天，其中 09-01、09-03 为紧急工单
+	result, job, err := svc.SimulateRule(ctx, &models.AutomationSimulationRequest{RuleID: &rule.ID, StartDate: "2026-09-01", EndDate: "2026-09-04", SampleSize: 1}, 1)
+	if err != nil || job != nil {
+		t.Fatalf("expected synchronous simulation, got job %v, err %v", job, err)
+	}
+	if result.Scanned != 4 || result.Matched != 2 || result.MatchRate != 0.5 || len(result.Samples) != 1 || result.Samples[0].TicketNumber != "SIM-0" {
+		t.Fatalf("unexpected simulation result %+v", result)
+	}
+	if len(result.ProjectedActions) != 2 || result.ProjectedActions[1].Type != "notify" ||
+		result.ProjectedActions[1].Executions != 2 || result.ProjectedActions[1].PerDay != 0.5 {
+		t.Fatalf("unexpected projected actions %+v", result.ProjectedActions)
+	}
+
+	// 只读：工单、规则统计和执行日志均不变
+	var changed, logs int64
+	db.Model(&models.Ticket{}).Where("status <> ?", models.TicketStatusOpen).Count(&changed)
+	db.Model(&models.AutomationLog{}).Count(&logs)
+	reloaded, _ := svc.GetRuleByID(ctx, rule.ID)
+	if changed != 0 || logs != 0 || reloaded.ExecutionCount != 0 {
+		t.Fatalf("simulation must not modify data: changed=%d logs=%d executions=%d", changed, logs, reloaded.ExecutionCount)
+	}
+
+	if _, _, err := svc.SimulateRule(ctx, &models.AutomationSimulationRequest{
+		Conditions: []models.RuleCondition{{Field: "title", Operator: "like", Value: "x"}},
+		StartDate:  "2026-09-01", EndDate: "2026-09-04",
+	}, 1); !errors.Is(err, ErrInvalidAutomationSimulation) {
+		t.Fatalf("expected invalid operator to be rejected, got %v", err)
+	}
+	if _, _, err := svc.SimulateRule(ctx, &models.AutomationSimulationRequest{StartDate: "2026-09-04", EndDate: "2026-09-01"}, 1); !errors.Is(err, ErrInvalidAutomationSimulation) {
+		t.Fatalf("expected reversed range to be rejected, got %v", err)
+	}
+
+	// 后台任务：直接提供条件，结果与同步模拟一致
+	_, job, err = svc.SimulateRule(ctx, &models.AutomationSimulationRequest{
+		Conditions: []models.RuleCondition{{Field: "priority", Operator: "eq", Value: "urgent"}},
+		Actions:    []models.RuleAction{{Type: "escalate"}},
+		StartDate:  "2026-09-01", EndDate: "2026-09-30", Async: true,
+	}, 1)
+	if err != nil || job == nil || job.Total != 6 {
+		t.Fatalf("expected background job for 6 tickets, got %+v, %v", job, err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		job, err = svc.GetSimulationJob(ctx, job.ID)
+		if err != nil {
+			t.Fatalf("get simulation job failed: %v", err)
+		}
+		if job.Status == models.AutomationSimulationCompleted || job.Status == models.AutomationSimulationFailed || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if job.Status != models.AutomationSimulationCompleted || job.ResultData == nil || job.Scanned != 6 ||
+		job.ResultData.Matched != 3 || job.ResultData.ProjectedActions[0].PerDay != 0.1 {
+		t.Fatalf("unexpected background simulation %+v result %+v", job, job.ResultData)
+	}
+	if _, err := svc.GetSimulationJob(ctx, 9999); !errors.Is(err, ErrAutomationSimulationNotFound) {
+		t.Fatalf("expected missing job to be not found, got %v", err)
+	}
+}
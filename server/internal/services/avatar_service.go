@@ -0,0 +1,310 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif" // 注册GIF解码器
+	"image/jpeg"
+	"image/png"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// MaxAvatarFileSize 头像文件大小上限
+	MaxAvatarFileSize  = 2 * 1024 * 1024
+	minAvatarDimension = 64
+	maxAvatarDimension = 4096
+	// avatarPrimarySize 写入 users.avatar 的默认尺寸
+	avatarPrimarySize = 256
+)
+
+// AvatarSizes 头像标准尺寸（正方形边长）
+var AvatarSizes = []int{64, 128, 256}
+
+var (
+	// ErrAvatarTooLarge 头像文件过大
+	ErrAvatarTooLarge = errors.New("file too large: maximum 2MB allowed")
+	// ErrAvatarUnsupportedType 头像格式不支持
+	ErrAvatarUnsupportedType = errors.New("unsupported avatar type: only JPEG, PNG and GIF are allowed")
+	// ErrAvatarInvalidDimensions 头像尺寸不符合要求
+	ErrAvatarInvalidDimensions = fmt.Errorf("avatar dimensions must be between %dx%d and %dx%d pixels",
+		minAvatarDimension, minAvatarDimension, maxAvatarDimension, maxAvatarDimension)
+)
+
+// allowedAvatarTypes 按文件内容识别的 MIME 类型，不信任扩展名
+var allowedAvatarTypes = map[string]string{
+	"image/jpeg": "jpeg",
+	"image/png":  "png",
+	"image/gif":  "gif",
+}
+
+// AvatarService 头像处理服务：校验、裁剪缩放、存储及旧头像清理
+type AvatarService struct {
+	db      *gorm.DB
+	storage FileStorage
+}
+
+// NewAvatarService 创建头像服务
+func NewAvatarService(db *gorm.DB, storage FileStorage) *AvatarService {
+	return &AvatarService{
+		db:      db,
+		storage: storage,
+	}
+}
+
+// AvatarUploadResult 头像上传结果
+type AvatarUploadResult struct {
+	AvatarURL string         `json:"avatar_url"`
+	Sizes     map[int]string `json:"sizes"`
+	Hash      string         `json:"hash"`
+}
+
+// Upload 处理并保存用户头像，成功后删除该用户之前的头像文件
+func (s *AvatarService) Upload(ctx context.Context, userID uint, r io.Reader) (*AvatarUploadResult, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxAvatarFileSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read avatar: %w", err)
+	}
+	if len(data) > MaxAvatarFileSize {
+		return nil, ErrAvatarTooLarge
+	}
+
+	format, ok := allowedAvatarTypes[http.DetectContentType(data)]
+	if !ok {
+		return nil, ErrAvatarUnsupportedType
+	}
+
+	// 先读取尺寸再完整解码，避免超大图片耗尽内存
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrAvatarUnsupportedType
+	}
+	if cfg.Width < minAvatarDimension || cfg.Height < minAvatarDimension ||
+		cfg.Width > maxAvatarDimension || cfg.Height > maxAvatarDimension {
+		return nil, ErrAvatarInvalidDimensions
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrAvatarUnsupportedType
+	}
+
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])[:16]
+	ext := "png"
+	if format == "jpeg" {
+		ext = "jpg"
+	}
+
+	square := cropToSquare(src)
+	result := &AvatarUploadResult{Sizes: make(map[int]string, len(AvatarSizes)), Hash: hash}
+	for _, size := range AvatarSizes {
+		var buf bytes.Buffer
+		resized := resizeImage(square, size)
+		if ext == "jpg" {
+			err = jpeg.Encode(&buf, resized, &jpeg.Options{Quality: 88})
+		} else {
+			err = png.Encode(&buf, resized)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode avatar: %w", err)
+		}
+
+		key := avatarKey(userID, hash, size, ext)
+		if err := s.storage.Put(ctx, key, &buf, "image/"+format); err != nil {
+			return nil, err
+		}
+		result.Sizes[size] = s.storage.URL(key)
+	}
+	result.AvatarURL = result.Sizes[avatarPrimarySize]
+
+	var user models.User
+	if err := s.db.WithContext(ctx).Select("id", "avatar").First(&user, userID).Error; err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if err := s.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ?", userID).
+		Update("avatar", result.AvatarURL).Error; err != nil {
+		return nil, fmt.Errorf("failed to update avatar: %w", err)
+	}
+
+	if user.Avatar != result.AvatarURL {
+		s.deleteStoredAvatar(ctx, userID, user.Avatar)
+	}
+	return result, nil
+}
+
+// Remove 删除用户头像，之后使用默认头像
+func (s *AvatarService) Remove(ctx context.Context, userID uint) error {
+	var user models.User
+	if err := s.db.WithContext(ctx).Select("id", "avatar").First(&user, userID).Error; err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if err := s.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ?", userID).
+		Update("avatar", "").Error; err != nil {
+		return fmt.Errorf("failed to clear avatar: %w", err)
+	}
+	s.deleteStoredAvatar(ctx, userID, user.Avatar)
+	return nil
+}
+
+// deleteStoredAvatar 删除由本服务生成的旧头像各尺寸文件，外部URL不处理
+func (s *AvatarService) deleteStoredAvatar(ctx context.Context, userID uint, avatarURL string) {
+	prefix := s.storage.URL(fmt.Sprintf("avatars/%d/", userID))
+	if avatarURL == "" || !strings.HasPrefix(avatarURL, prefix) {
+		return
+	}
+
+	name := strings.TrimPrefix(avatarURL, prefix)
+	sep := strings.LastIndex(name, "_")
+	dot := strings.LastIndex(name, ".")
+	if sep <= 0 || dot <= sep {
+		return
+	}
+	hash, ext := name[:sep], name[dot+1:]
+
+	for _, size := range AvatarSizes {
+		if err := s.storage.Delete(ctx, avatarKey(userID, hash, size, ext)); err != nil {
+			log.Printf("Failed to delete old avatar for user %d: %v", userID, err)
+		}
+	}
+}
+
+func avatarKey(userID uint, hash string, size int, ext string) string {
+	return fmt.Sprintf("avatars/%d/%s_%d.%s", userID, hash, size, ext)
+}
+
+// cropToSquare 居中裁剪为正方形
+func cropToSquare(src image.Image) image.Image {
+	b := src.Bounds()
+	side := b.Dx()
+	if b.Dy() < side {
+		side = b.Dy()
+	}
+	x0 := b.Min.X + (b.Dx()-side)/2
+	y0 := b.Min.Y + (b.Dy()-side)/2
+
+	dst := image.NewRGBA(image.Rect(0, 0, side, side))
+	draw.Draw(dst, dst.Bounds(), src, image.Pt(x0, y0), draw.Src)
+	return dst
+}
+
+// resizeImage 使用区域平均缩放正方形图片，放大时退化为最近邻
+func resizeImage(src image.Image, size int) *image.RGBA {
+	rgba, ok := src.(*image.RGBA)
+	if !ok {
+		rgba = image.NewRGBA(src.Bounds())
+		draw.Draw(rgba, rgba.Bounds(), src, src.Bounds().Min, draw.Src)
+	}
+	b := rgba.Bounds()
+	srcW, srcH := b.Dx(), b.Dy()
+
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	for y := 0; y < size; y++ {
+		sy0 := y * srcH / size
+		sy1 := (y + 1) * srcH / size
+		if sy1 <= sy0 {
+			sy1 = sy0 + 1
+		}
+		for x := 0; x < size; x++ {
+			sx0 := x * srcW / size
+			sx1 := (x + 1) * srcW / size
+			if sx1 <= sx0 {
+				sx1 = sx0 + 1
+			}
+
+			var r, g, bl, a, n uint32
+			for sy := sy0; sy < sy1; sy++ {
+				offset := rgba.PixOffset(b.Min.X+sx0, b.Min.Y+sy)
+				for sx := sx0; sx < sx1; sx++ {
+					r += uint32(rgba.Pix[offset])
+					g += uint32(rgba.Pix[offset+1])
+					bl += uint32(rgba.Pix[offset+2])
+					a += uint32(rgba.Pix[offset+3])
+					offset += 4
+					n++
+				}
+			}
+			dst.SetRGBA(x, y, color.RGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(bl / n), A: uint8(a / n)})
+		}
+	}
+	return dst
+}
+
+// GenerateIdenticon 根据种子生成对称的 5x5 像素风格默认头像（PNG）
+func GenerateIdenticon(seed string, size int) ([]byte, error) {
+	sum := sha256.Sum256([]byte(seed))
+
+	fg := identiconColor(sum[0], sum[1])
+	bg := color.RGBA{R: 0xf0, G: 0xf0, B: 0xf0, A: 0xff}
+
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: bg}, image.Point{}, draw.Src)
+
+	const cells = 5
+	margin := size / 10
+	cell := (size - 2*margin) / cells
+	margin = (size - cell*cells) / 2
+
+	for row := 0; row < cells; row++ {
+		for col := 0; col < (cells+1)/2; col++ {
+			// 每格由哈希的一位决定是否着色，左右镜像
+			bit := row*3 + col
+			if sum[2+bit/8]&(1<<(uint(bit)%8)) == 0 {
+				continue
+			}
+			for _, c := range []int{col, cells - 1 - col} {
+				rect := image.Rect(margin+c*cell, margin+row*cell, margin+(c+1)*cell, margin+(row+1)*cell)
+				draw.Draw(img, rect, &image.Uniform{C: fg}, image.Point{}, draw.Src)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode identicon: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// identiconColor 由哈希选取色相，固定饱和度和亮度，保证与浅色背景有足够对比度
+func identiconColor(h1, h2 byte) color.RGBA {
+	hue := float64(uint16(h1)<<8|uint16(h2)) / 65536.0 * 360.0
+	const s, l = 0.55, 0.5
+
+	c := (1 - math.Abs(2*l-1)) * s
+	x := c * (1 - math.Abs(math.Mod(hue/60.0, 2)-1))
+	m := l - c/2
+
+	var r, g, b float64
+	switch {
+	case hue < 60:
+		r, g, b = c, x, 0
+	case hue < 120:
+		r, g, b = x, c, 0
+	case hue < 180:
+		r, g, b = 0, c, x
+	case hue < 240:
+		r, g, b = 0, x, c
+	case hue < 300:
+		r, g, b = x, 0, c
+	default:
+		r, g, b = c, 0, x
+	}
+	return color.RGBA{R: uint8((r + m) * 255), G: uint8((g + m) * 255), B: uint8((b + m) * 255), A: 0xff}
+}
@@ -0,0 +1,98 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func encodeTestPNG(t *testing.T, w, h int, c color.Color) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("failed to encode png: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestAvatarService_UploadReplacesOldFiles(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:avatar_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+
+	dir := t.TempDir()
+	svc := NewAvatarService(db, NewLocalFileStorage(dir, "/uploads"))
+	ctx := context.Background()
+
+	user := models.User{Username: "avatar-user", Email: "avatar@example.com", PasswordHash: "x", Role: models.RoleCustomer}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("failed to seed user: %v", err)
+	}
+
+	first, err := svc.Upload(ctx, user.ID, bytes.NewReader(encodeTestPNG(t, 300, 200, color.RGBA{R: 200, A: 255})))
+	if err != nil {
+		t.Fatalf("first upload failed: %v", err)
+	}
+	if len(first.Sizes) != len(AvatarSizes) || !strings.Contains(first.AvatarURL, first.Hash) {
+		t.Fatalf("unexpected upload result: %+v", first)
+	}
+	for _, url := range first.Sizes {
+		f, err := os.Open(filepath.Join(dir, strings.TrimPrefix(url, "/uploads/")))
+		if err != nil {
+			t.Fatalf("expected stored avatar %s: %v", url, err)
+		}
+		cfg, _, err := image.DecodeConfig(f)
+		f.Close()
+		if err != nil || cfg.Width != cfg.Height {
+			t.Fatalf("expected square avatar for %s, got %+v (%v)", url, cfg, err)
+		}
+	}
+
+	second, err := svc.Upload(ctx, user.ID, bytes.NewReader(encodeTestPNG(t, 128, 128, color.RGBA{B: 200, A: 255})))
+	if err != nil {
+		t.Fatalf("second upload failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, strings.TrimPrefix(first.AvatarURL, "/uploads/"))); !os.IsNotExist(err) {
+		t.Fatalf("expected old avatar to be deleted, stat err: %v", err)
+	}
+
+	var reloaded models.User
+	db.First(&reloaded, user.ID)
+	if reloaded.Avatar != second.AvatarURL {
+		t.Fatalf("expected avatar %s, got %s", second.AvatarURL, reloaded.Avatar)
+	}
+
+	if _, err := svc.Upload(ctx, user.ID, bytes.NewReader(encodeTestPNG(t, 32, 32, color.White))); !errors.Is(err, ErrAvatarInvalidDimensions) {
+		t.Fatalf("expected ErrAvatarInvalidDimensions, got %v", err)
+	}
+	if _, err := svc.Upload(ctx, user.ID, strings.NewReader("%PDF-1.4 not an image")); !errors.Is(err, ErrAvatarUnsupportedType) {
+		t.Fatalf("expected ErrAvatarUnsupportedType, got %v", err)
+	}
+
+	a, _ := GenerateIdenticon("3f2a9c1d0b7e6a54", 64)
+	b, _ := GenerateIdenticon("3f2a9c1d0b7e6a54", 64)
+	if len(a) == 0 || !bytes.Equal(a, b) {
+		t.Fatalf("expected deterministic identicon")
+	}
+}
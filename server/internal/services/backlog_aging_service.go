@@ -0,0 +1,476 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+// KeyTicketStalePolicy 停滞工单提醒策略配置键
+const KeyTicketStalePolicy = "ticket.stale_policy"
+
+const (
+	staleNudgeField      = "stale_nudge"
+	staleEscalationField = "stale_escalation"
+	maxStaleReportItems  = 500
+)
+
+// agingBuckets 积压账龄分段（按创建时长，单位天，MaxDays 为 0 表示无上限）
+var agingBuckets = []struct {
+	Label   string
+	MinDays int
+	MaxDays int
+}{
+	{"0-1d", 0, 1},
+	{"1-3d", 1, 3},
+	{"3-7d", 3, 7},
+	{"7-14d", 7, 14},
+	{"14-30d", 14, 30},
+	{"30d+", 30, 0},
+}
+
+// BacklogAgingService 工单积压账龄与停滞提醒服务
+type BacklogAgingService struct {
+	db                  *gorm.DB
+	notificationService NotificationServiceInterface
+	historyWriter       *HistoryWriter
+}
+
+// NewBacklogAgingService 创建积压账龄服务
+func NewBacklogAgingService(db *gorm.DB) *BacklogAgingService {
+	return &BacklogAgingService{
+		db:                  db,
+		notificationService: NewNotificationService(db),
+	}
+}
+
+// SetHistoryWriter 设置异步历史写入器，账龄提醒等系统历史异步批量写入
+func (s *BacklogAgingService) SetHistoryWriter(writer *HistoryWriter) {
+	s.historyWriter = writer
+}
+
+// AgingBucket 账龄分段统计
+type AgingBucket struct {
+	Label    string           `json:"label"`
+	MinDays  int              `json:"min_days"`
+	MaxDays  int              `json:"max_days,omitempty"`
+	Total    int64            `json:"total"`
+	ByStatus map[string]int64 `json:"by_status"`
+}
+
+// StaleTicketItem 停滞工单条目
+type StaleTicketItem struct {
+	TicketID         uint       `json:"ticket_id"`
+	TicketNumber     string     `json:"ticket_number"`
+	Title            string     `json:"title"`
+	Status           string     `json:"status"`
+	Priority         string     `json:"priority"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+	DaysStale        int        `json:"days_stale"`
+	StaleNudgedAt    *time.Time `json:"stale_nudged_at,omitempty"`
+	StaleEscalatedAt *time.Time `json:"stale_escalated_at,omitempty"`
+}
+
+// AssigneeStaleList 处理人的停滞工单列表，AssigneeID 为空表示未分配
+type AssigneeStaleList struct {
+	AssigneeID   *uint              `json:"assignee_id"`
+	AssigneeName string             `json:"assignee_name"`
+	Count        int                `json:"count"`
+	Tickets      []*StaleTicketItem `json:"tickets"`
+}
+
+// BacklogAgingReport 积压账龄报表
+type BacklogAgingReport struct {
+	GeneratedAt     time.Time            `json:"generated_at"`
+	StaleAfterDays  int                  `json:"stale_after_days"`
+	TotalOpen       int64                `json:"total_open"`
+	TotalStale      int64                `json:"total_stale"`
+	Buckets         []*AgingBucket       `json:"buckets"`
+	StaleByAssignee []*AssigneeStaleList `json:"stale_by_assignee"`
+	Truncated       bool                 `json:"truncated"` // 停滞工单超过上限时仅返回最久未更新的部分
+}
+
+// StaleNudgeRunResult 单次停滞提醒执行结果
+type StaleNudgeRunResult struct {
+	Scanned   int `json:"scanned"`
+	Nudged    int `json:"nudged"`
+	Escalated int `json:"escalated"`
+	NoLead    int `json:"no_lead"` // 需升级但找不到团队负责人
+	Failed    int `json:"failed"`
+}
+
+// GetPolicy 获取停滞工单提醒策略
+func (s *BacklogAgingService) GetPolicy(ctx context.Context) (*models.StaleTicketPolicy, error) {
+	var config models.SystemConfig
+	err := s.db.WithContext(ctx).
+		Where("key = ? AND is_active = ?", KeyTicketStalePolicy, true).
+		First(&config).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.GetDefaultStaleTicketPolicy(), nil
+		}
+		return nil, fmt.Errorf("failed to get stale ticket policy: %w", err)
+	}
+
+	policy := models.GetDefaultStaleTicketPolicy()
+	if err := config.GetJSONValue(policy); err != nil {
+		log.Printf("Warning: failed to parse stale ticket policy, using defaults: %v", err)
+		return models.GetDefaultStaleTicketPolicy(), nil
+	}
+	if err := policy.Validate(); err != nil {
+		log.Printf("Warning: invalid stale ticket policy, using defaults: %v", err)
+		return models.GetDefaultStaleTicketPolicy(), nil
+	}
+	return policy, nil
+}
+
+// SetPolicy 保存停滞工单提醒策略
+func (s *BacklogAgingService) SetPolicy(ctx context.Context, policy *models.StaleTicketPolicy, userID uint) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	var existing models.SystemConfig
+	err := s.db.WithContext(ctx).Where("key = ?", KeyTicketStalePolicy).First(&existing).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to check existing policy: %w", err)
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		config := models.SystemConfig{
+			Key:         KeyTicketStalePolicy,
+			Category:    CategoryTicket,
+			Group:       "workflow",
+			Description: "停滞工单提醒与升级策略",
+			IsActive:    true,
+			UpdatedBy:   &userID,
+		}
+		if err := config.SetValue(policy); err != nil {
+			return fmt.Errorf("failed to set policy value: %w", err)
+		}
+		if err := s.db.WithContext(ctx).Create(&config).Error; err != nil {
+			return fmt.Errorf("failed to create policy: %w", err)
+		}
+		return nil
+	}
+
+	if err := existing.SetValue(policy); err != nil {
+		return fmt.Errorf("failed to set policy value: %w", err)
+	}
+	existing.UpdatedBy = &userID
+	existing.Version++
+
+	if err := s.db.WithContext(ctx).Save(&existing).Error; err != nil {
+		return fmt.Errorf("failed to update policy: %w", err)
+	}
+	return nil
+}
+
+// GetAgingReport 生成积压账龄报表，staleDays <= 0 时使用策略中的停滞天数
+func (s *BacklogAgingService) GetAgingReport(ctx context.Context, staleDays int) (*BacklogAgingReport, error) {
+	if staleDays <= 0 {
+		policy, err := s.GetPolicy(ctx)
+		if err != nil {
+			return nil, err
+		}
+		staleDays = policy.StaleAfterDays
+	}
+
+	now := time.Now()
+	report := &BacklogAgingReport{
+		GeneratedAt:     now,
+		StaleAfterDays:  staleDays,
+		Buckets:         make([]*AgingBucket, 0, len(agingBuckets)),
+		StaleByAssignee: []*AssigneeStaleList{},
+	}
+
+	// 按账龄分段统计：用创建时间区间逐段计数，避免依赖数据库特定的日期函数
+	for _, b := range agingBuckets {
+		bucket := &AgingBucket{Label: b.Label, MinDays: b.MinDays, MaxDays: b.MaxDays, ByStatus: map[string]int64{}}
+
+		var rows []struct {
+			Status string
+			Count  int64
+		}
+		query := s.openTicketsQuery(ctx).
+			Select("status, COUNT(*) AS count").
+			Where("created_at <= ?", now.AddDate(0, 0, -b.MinDays))
+		if b.MaxDays > 0 {
+			query = query.Where("created_at > ?", now.AddDate(0, 0, -b.MaxDays))
+		}
+		if err := query.Group("status").Scan(&rows).Error; err != nil {
+			return nil, fmt.Errorf("failed to count aging bucket %s: %w", b.Label, err)
+		}
+		for _, row := range rows {
+			bucket.ByStatus[row.Status] = row.Count
+			bucket.Total += row.Count
+		}
+		report.TotalOpen += bucket.Total
+		report.Buckets = append(report.Buckets, bucket)
+	}
+
+	cutoff := now.AddDate(0, 0, -staleDays)
+	if err := s.openTicketsQuery(ctx).Where("updated_at < ?", cutoff).Count(&report.TotalStale).Error; err != nil {
+		return nil, fmt.Errorf("failed to count stale tickets: %w", err)
+	}
+
+	var tickets []models.Ticket
+	if err := s.openTicketsQuery(ctx).
+		Preload("AssignedTo").
+		Where("updated_at < ?", cutoff).
+		Order("updated_at ASC").
+		Limit(maxStaleReportItems).
+		Find(&tickets).Error; err != nil {
+		return nil, fmt.Errorf("failed to get stale tickets: %w", err)
+	}
+	report.Truncated = report.TotalStale > int64(len(tickets))
+
+	lists := make(map[uint]*AssigneeStaleList)
+	for i := range tickets {
+		ticket := &tickets[i]
+		var key uint
+		if ticket.AssignedToID != nil {
+			key = *ticket.AssignedToID
+		}
+
+		list, ok := lists[key]
+		if !ok {
+			list = &AssigneeStaleList{AssigneeID: ticket.AssignedToID, AssigneeName: "未分配", Tickets: []*StaleTicketItem{}}
+			if ticket.AssignedTo != nil {
+				list.AssigneeName = ticket.AssignedTo.GetFullName()
+			}
+			lists[key] = list
+			report.StaleByAssignee = append(report.StaleByAssignee, list)
+		}
+
+		list.Tickets = append(list.Tickets, &StaleTicketItem{
+			TicketID:         ticket.ID,
+			TicketNumber:     ticket.TicketNumber,
+			Title:            ticket.Title,
+			Status:           string(ticket.Status),
+			Priority:         string(ticket.Priority),
+			UpdatedAt:        ticket.UpdatedAt,
+			DaysStale:        int(now.Sub(ticket.UpdatedAt).Hours() / 24),
+			StaleNudgedAt:    ticket.StaleNudgedAt,
+			StaleEscalatedAt: ticket.StaleEscalatedAt,
+		})
+		list.Count++
+	}
+
+	return report, nil
+}
+
+func (s *BacklogAgingService) openTicketsQuery(ctx context.Context) *gorm.DB {
+	return s.db.WithContext(ctx).Model(&models.Ticket{}).
+		Where("deleted_at IS NULL AND status NOT IN ?", closedTicketStatuses)
+}
+
+// ProcessStaleTickets 提醒处理人跟进停滞工单，宽限期后仍无更新则升级至团队负责人
+func (s *BacklogAgingService) ProcessStaleTickets(ctx context.Context) (*StaleNudgeRunResult, error) {
+	result := &StaleNudgeRunResult{}
+
+	policy, err := s.GetPolicy(ctx)
+	if err != nil {
+		return result, err
+	}
+	if !policy.Enabled {
+		return result, nil
+	}
+
+	now := time.Now()
+	cutoff := now.AddDate(0, 0, -policy.StaleAfterDays)
+	var tickets []models.Ticket
+
+	err = s.openTicketsQuery(ctx).
+		Where("assigned_to_id IS NOT NULL AND updated_at < ?", cutoff).
+		Order("id ASC").
+		FindInBatches(&tickets, policy.BatchSize, func(tx *gorm.DB, batch int) error {
+			for i := range tickets {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				result.Scanned++
+				s.processStaleTicket(ctx, &tickets[i], policy, now, result)
+			}
+			return nil
+		}).Error
+
+	if err != nil {
+		return result, fmt.Errorf("failed to process stale tickets: %w", err)
+	}
+
+	log.Printf("Stale ticket check processed %d tickets: %d nudged, %d escalated, %d without team lead",
+		result.Scanned, result.Nudged, result.Escalated, result.NoLead)
+	return result, nil
+}
+
+func (s *BacklogAgingService) processStaleTicket(ctx context.Context, ticket *models.Ticket, policy *models.StaleTicketPolicy, now time.Time, result *StaleNudgeRunResult) {
+	// 提醒后工单有过更新又再次停滞，视为新一轮停滞
+	if ticket.StaleNudgedAt == nil || ticket.UpdatedAt.After(*ticket.StaleNudgedAt) {
+		if err := s.sendNudge(ctx, ticket, now); err != nil {
+			log.Printf("Failed to send stale nudge for ticket %d: %v", ticket.ID, err)
+			result.Failed++
+			return
+		}
+		result.Nudged++
+		return
+	}
+
+	if ticket.StaleEscalatedAt != nil {
+		return
+	}
+	if now.Sub(*ticket.StaleNudgedAt) < time.Duration(policy.EscalateAfterDays)*24*time.Hour {
+		return
+	}
+
+	leadIDs, err := s.findTeamLeads(ctx, ticket)
+	if err != nil {
+		log.Printf("Failed to find team lead for ticket %d: %v", ticket.ID, err)
+		result.Failed++
+		return
+	}
+	if len(leadIDs) == 0 {
+		result.NoLead++
+		return
+	}
+	if err := s.escalate(ctx, ticket, leadIDs, policy, now); err != nil {
+		log.Printf("Failed to escalate stale ticket %d: %v", ticket.ID, err)
+		result.Failed++
+		return
+	}
+	result.Escalated++
+}
+
+// findTeamLeads 优先使用工单所属团队的负责人，否则使用处理人所在团队的负责人
+func (s *BacklogAgingService) findTeamLeads(ctx context.Context, ticket *models.Ticket) ([]uint, error) {
+	var leadIDs []uint
+	if ticket.AssignedTeamID != nil {
+		if err := s.db.WithContext(ctx).Model(&models.Team{}).
+			Where("id = ? AND is_active = ? AND lead_id IS NOT NULL", *ticket.AssignedTeamID, true).
+			Pluck("lead_id", &leadIDs).Error; err != nil {
+			return nil, err
+		}
+	}
+	if len(leadIDs) == 0 {
+		if err := s.db.WithContext(ctx).Model(&models.Team{}).
+			Joins("JOIN team_members ON team_members.team_id = teams.id").
+			Where("team_members.user_id = ? AND teams.is_active = ? AND teams.lead_id IS NOT NULL", *ticket.AssignedToID, true).
+			Distinct().
+			Pluck("teams.lead_id", &leadIDs).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	// 处理人本身是负责人时无需再升级给自己
+	filtered := leadIDs[:0]
+	for _, id := range leadIDs {
+		if id != *ticket.AssignedToID {
+			filtered = append(filtered, id)
+		}
+	}
+	return filtered, nil
+}
+
+func (s *BacklogAgingService) sendNudge(ctx context.Context, ticket *models.Ticket, now time.Time) error {
+	daysStale := int(now.Sub(ticket.UpdatedAt).Hours() / 24)
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 使用 UpdateColumns 避免刷新 updated_at，否则提醒本身会被当作工单更新
+		if err := tx.Model(&models.Ticket{}).Where("id = ?", ticket.ID).UpdateColumns(map[string]interface{}{
+			"stale_nudged_at":    now,
+			"stale_escalated_at": nil,
+		}).Error; err != nil {
+			return err
+		}
+
+		history := &models.TicketHistory{
+			TicketID:    ticket.ID,
+			Action:      models.HistoryActionSystem,
+			Description: fmt.Sprintf("工单已 %d 天无更新，已提醒处理人跟进", daysStale),
+			FieldName:   staleNudgeField,
+			NewValue:    now.Format(time.RFC3339),
+			IsVisible:   false,
+			IsSystem:    true,
+			IsAutomated: true,
+		}
+		return recordHistory(tx, s.historyWriter, history)
+	})
+	if err != nil {
+		return err
+	}
+	ticket.StaleNudgedAt = &now
+	ticket.StaleEscalatedAt = nil
+
+	_, err = s.notificationService.CreateNotification(ctx, &models.NotificationCreateRequest{
+		Type:            models.NotificationTypeTicketOverdue,
+		Title:           fmt.Sprintf("工单长时间未更新 - %s", ticket.Title),
+		Content:         fmt.Sprintf("工单 #%s 已 %d 天无更新，请尽快跟进", ticket.TicketNumber, daysStale),
+		Priority:        models.NotificationPriorityNormal,
+		Channel:         models.NotificationChannelInApp,
+		RecipientID:     *ticket.AssignedToID,
+		RelatedType:     "ticket",
+		RelatedID:       &ticket.ID,
+		RelatedTicketID: &ticket.ID,
+		ActionURL:       fmt.Sprintf("/tickets/%d", ticket.ID),
+		Metadata: map[string]interface{}{
+			"ticket_number": ticket.TicketNumber,
+			"days_stale":    daysStale,
+		},
+	})
+	return err
+}
+
+func (s *BacklogAgingService) escalate(ctx context.Context, ticket *models.Ticket, leadIDs []uint, policy *models.StaleTicketPolicy, now time.Time) error {
+	daysStale := int(now.Sub(ticket.UpdatedAt).Hours() / 24)
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Ticket{}).Where("id = ?", ticket.ID).
+			UpdateColumn("stale_escalated_at", now).Error; err != nil {
+			return err
+		}
+
+		history := &models.TicketHistory{
+			TicketID:    ticket.ID,
+			Action:      models.HistoryActionEscalate,
+			Description: fmt.Sprintf("停滞提醒 %d 天后仍无更新，已升级至团队负责人", policy.EscalateAfterDays),
+			FieldName:   staleEscalationField,
+			NewValue:    now.Format(time.RFC3339),
+			IsVisible:   false,
+			IsSystem:    true,
+			IsAutomated: true,
+			IsImportant: true,
+		}
+		return tx.Create(history).Error
+	})
+	if err != nil {
+		return err
+	}
+	ticket.StaleEscalatedAt = &now
+
+	for _, leadID := range leadIDs {
+		_, err := s.notificationService.CreateNotification(ctx, &models.NotificationCreateRequest{
+			Type:            models.NotificationTypeTicketOverdue,
+			Title:           fmt.Sprintf("停滞工单升级 - %s", ticket.Title),
+			Content:         fmt.Sprintf("工单 #%s 已 %d 天无更新，提醒处理人后仍未跟进", ticket.TicketNumber, daysStale),
+			Priority:        models.NotificationPriorityHigh,
+			Channel:         models.NotificationChannelInApp,
+			RecipientID:     leadID,
+			RelatedType:     "ticket",
+			RelatedID:       &ticket.ID,
+			RelatedTicketID: &ticket.ID,
+			ActionURL:       fmt.Sprintf("/tickets/%d", ticket.ID),
+			Metadata: map[string]interface{}{
+				"ticket_number":  ticket.TicketNumber,
+				"days_stale":     daysStale,
+				"assigned_to_id": *ticket.AssignedToID,
+			},
+		})
+		if err != nil {
+			log.Printf("Failed to notify team lead %d of stale ticket %d: %v", leadID, ticket.ID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupBacklogAgingTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file:backlog_aging_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+
+	if err := db.AutoMigrate(&models.User{}, &models.Team{}, &models.Ticket{}, &models.TicketHistory{}, &models.Notification{}, &models.SystemConfig{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+
+	return db
+}
+
+func TestBacklogAging_ReportNudgeAndEscalate(t *testing.T) {
+	db := setupBacklogAgingTestDB(t)
+	ctx := context.Background()
+	svc := NewBacklogAgingService(db)
+
+	agent := models.User{Username: "aging-agent", Email: "aging-agent@example.com", PasswordHash: "x", Role: models.RoleAgent}
+	lead := models.User{Username: "aging-lead", Email: "aging-lead@example.com", PasswordHash: "x", Role: models.RoleSupervisor}
+	for _, u := range []*models.User{&agent, &lead} {
+		if err := db.Create(u).Error; err != nil {
+			t.Fatalf("failed to seed user: %v", err)
+		}
+	}
+
+	team, err := NewTeamService(db).CreateTeam(ctx, &models.TeamCreateRequest{Name: "Aging L1", Slug: "aging-l1", MemberIDs: []uint{agent.ID}, LeadID: &lead.ID})
+	if err != nil {
+		t.Fatalf("failed to create team: %v", err)
+	}
+
+	ticket := models.Ticket{TicketNumber: "AG-001", Title: "stale ticket", Status: models.TicketStatusInProgress, Priority: models.TicketPriorityNormal, Type: models.TicketTypeRequest, Source: models.TicketSourceWeb, CreatedByID: lead.ID, AssignedToID: &agent.ID, AssignedTeamID: &team.ID}
+	if err := db.Create(&ticket).Error; err != nil {
+		t.Fatalf("failed to seed ticket: %v", err)
+	}
+	tenDaysAgo := time.Now().AddDate(0, 0, -10)
+	if err := db.Model(&models.Ticket{}).Where("id = ?", ticket.ID).
+		UpdateColumns(map[string]interface{}{"created_at": tenDaysAgo, "updated_at": tenDaysAgo}).Error; err != nil {
+		t.Fatalf("failed to backdate ticket: %v", err)
+	}
+
+	report, err := svc.GetAgingReport(ctx, 0)
+	if err != nil {
+		t.Fatalf("failed to build aging report: %v", err)
+	}
+	if report.TotalOpen != 1 || report.TotalStale != 1 {
+		t.Fatalf("unexpected report totals: %+v", report)
+	}
+	for _, bucket := range report.Buckets {
+		want := int64(0)
+		if bucket.Label == "7-14d" {
+			want = 1
+		}
+		if bucket.Total != want {
+			t.Fatalf("bucket %s: expected %d, got %d", bucket.Label, want, bucket.Total)
+		}
+	}
+	if len(report.StaleByAssignee) != 1 || report.StaleByAssignee[0].AssigneeID == nil || *report.StaleByAssignee[0].AssigneeID != agent.ID {
+		t.Fatalf("expected stale list for agent, got %+v", report.StaleByAssignee)
+	}
+
+	policy := models.GetDefaultStaleTicketPolicy()
+	policy.Enabled = true
+	if err := svc.SetPolicy(ctx, policy, lead.ID); err != nil {
+		t.Fatalf("failed to save policy: %v", err)
+	}
+
+	result, err := svc.ProcessStaleTickets(ctx)
+	if err != nil {
+		t.Fatalf("first run failed: %v", err)
+	}
+	if result.Nudged != 1 || result.Escalated != 0 {
+		t.Fatalf("unexpected first run result: %+v", result)
+	}
+
+	// 宽限期内不重复提醒也不升级
+	result, err = svc.ProcessStaleTickets(ctx)
+	if err != nil {
+		t.Fatalf("second run failed: %v", err)
+	}
+	if result.Nudged != 0 || result.Escalated != 0 {
+		t.Fatalf("expected no action within grace period, got %+v", result)
+	}
+
+	nudgedAt := time.Now().AddDate(0, 0, -policy.EscalateAfterDays-1)
+	if err := db.Model(&models.Ticket{}).Where("id = ?", ticket.ID).UpdateColumn("stale_nudged_at", nudgedAt).Error; err != nil {
+		t.Fatalf("failed to backdate nudge: %v", err)
+	}
+
+	result, err = svc.ProcessStaleTickets(ctx)
+	if err != nil {
+		t.Fatalf("third run failed: %v", err)
+	}
+	if result.Escalated != 1 {
+		t.Fatalf("expected escalation to team lead, got %+v", result)
+	}
+
+	var leadNotifications int64
+	db.Model(&models.Notification{}).Where("recipient_id = ?", lead.ID).Count(&leadNotifications)
+	if leadNotifications != 1 {
+		t.Fatalf("expected 1 notification for team lead, got %d", leadNotifications)
+	}
+
+	var reloaded models.Ticket
+	if err := db.First(&reloaded, ticket.ID).Error; err != nil {
+		t.Fatalf("failed to reload ticket: %v", err)
+	}
+	if reloaded.StaleEscalatedAt == nil || !reloaded.UpdatedAt.Before(nudgedAt) {
+		t.Fatalf("expected escalation marker without touching updated_at, got %+v", reloaded)
+	}
+}
@@ -0,0 +1,528 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+// KeyBillingRates 计费小时费率配置键
+const KeyBillingRates = "billing.hourly_rates"
+
+const (
+	BillingGroupByCustomer = "customer" // 按提交工单的客户汇总
+	BillingGroupByCompany  = "company"  // 按客户邮箱域名汇总
+)
+
+var (
+	// ErrBillingTicketNotFound 工单不存在
+	ErrBillingTicketNotFound = errors.New("ticket not found")
+	// ErrWorklogNotFound 评论不存在或已删除
+	ErrWorklogNotFound = errors.New("worklog not found")
+	// ErrInvalidWorklogBilling 计费工时无效
+	ErrInvalidWorklogBilling = errors.New("invalid worklog billing")
+	// ErrBillingReferenceNotFound 工单未设置计费参考
+	ErrBillingReferenceNotFound = errors.New("billing reference not found")
+	// ErrInvalidBillingPeriod 计费周期格式无效或尚未结束
+	ErrInvalidBillingPeriod = errors.New("invalid billing period")
+	// ErrInvalidBillingReport 报表分组方式无效
+	ErrInvalidBillingReport = errors.New("invalid billing report parameters")
+	// ErrBillingPeriodLocked 计费周期已开票锁定，不能修改计费工时
+	ErrBillingPeriodLocked = errors.New("billing period is locked")
+	// ErrBillingPeriodNotLocked 计费周期未锁定
+	ErrBillingPeriodNotLocked = errors.New("billing period is not locked")
+)
+
+// BillingService 工时计费服务：标记评论工时是否计费、维护工单计费参考、
+// 生成按客户或公司的月度计费工时报表，开票后锁定计费周期
+type BillingService struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+// NewBillingService 创建工时计费服务
+func NewBillingService(db *gorm.DB) *BillingService {
+	return &BillingService{db: db, now: time.Now}
+}
+
+// GetRates 获取小时费率配置，未保存或无法解析时使用默认配置
+func (s *BillingService) GetRates(ctx context.Context) (*models.BillingRateConfig, error) {
+	var config models.SystemConfig
+	err := s.db.WithContext(ctx).
+		Where("key = ? AND is_active = ?", KeyBillingRates, true).
+		First(&config).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.GetDefaultBillingRateConfig(), nil
+		}
+		return nil, fmt.Errorf("failed to get billing rates: %w", err)
+	}
+
+	cfg := models.GetDefaultBillingRateConfig()
+	if err := config.GetJSONValue(cfg); err != nil {
+		log.Printf("Warning: failed to parse billing rates, using defaults: %v", err)
+		return models.GetDefaultBillingRateConfig(), nil
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Printf("Warning: invalid billing rates, using defaults: %v", err)
+		return models.GetDefaultBillingRateConfig(), nil
+	}
+	return cfg, nil
+}
+
+// SetRates 保存小时费率配置，已锁定的计费周期仍使用锁定时的费率
+func (s *BillingService) SetRates(ctx context.Context, cfg *models.BillingRateConfig, userID uint) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	var existing models.SystemConfig
+	err := s.db.WithContext(ctx).Where("key = ?", KeyBillingRates).First(&existing).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to check existing config: %w", err)
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		config := models.SystemConfig{
+			Key:         KeyBillingRates,
+			Category:    CategoryTicket,
+			Group:       "billing",
+			Description: "计费小时费率",
+			IsActive:    true,
+			UpdatedBy:   &userID,
+		}
+		if err := config.SetValue(cfg); err != nil {
+			return fmt.Errorf("failed to set config value: %w", err)
+		}
+		if err := s.db.WithContext(ctx).Create(&config).Error; err != nil {
+			return fmt.Errorf("failed to create config: %w", err)
+		}
+		return nil
+	}
+
+	if err := existing.SetValue(cfg); err != nil {
+		return fmt.Errorf("failed to set config value: %w", err)
+	}
+	existing.UpdatedBy = &userID
+	existing.Version++
+	if err := s.db.WithContext(ctx).Save(&existing).Error; err != nil {
+		return fmt.Errorf("failed to update config: %w", err)
+	}
+	return nil
+}
+
+// ListWorklogs 获取工单中带时间跟踪的评论及合计
+func (s *BillingService) ListWorklogs(ctx context.Context, ticketID uint) (*models.TicketWorklogs, error) {
+	if err := s.ensureTicket(ctx, ticketID); err != nil {
+		return nil, err
+	}
+	var comments []models.TicketComment
+	if err := s.db.WithContext(ctx).Preload("User").
+		Where("ticket_id = ? AND is_deleted = ? AND (time_spent > 0 OR billable_time > 0)", ticketID, false).
+		Order("created_at ASC, id ASC").Find(&comments).Error; err != nil {
+		return nil, fmt.Errorf("failed to list worklogs: %w", err)
+	}
+	locked, err := s.lockedPeriods(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.TicketWorklogs{Items: make([]*models.Worklog, 0, len(comments))}
+	for i := range comments {
+		worklog := newWorklog(&comments[i], locked)
+		result.Items = append(result.Items, worklog)
+		result.TotalTimeSpent += worklog.TimeSpent
+		result.TotalBillable += worklog.BillableTime
+	}
+	if reference, err := s.GetReference(ctx, ticketID); err == nil {
+		result.BillingReference = reference
+	} else if !errors.Is(err, ErrBillingReferenceNotFound) {
+		return nil, err
+	}
+	return result, nil
+}
+
+// SetWorklogBillable 设置评论工时是否计费。计费时未指定分钟数则按花费时间计；
+// 评论所在的计费周期已锁定时返回 ErrBillingPeriodLocked
+func (s *BillingService) SetWorklogBillable(ctx context.Context, ticketID, commentID uint, req *models.WorklogBillableRequest) (*models.Worklog, error) {
+	var comment models.TicketComment
+	if err := s.db.WithContext(ctx).Preload("User").
+		Where("id = ? AND ticket_id = ? AND is_deleted = ?", commentID, ticketID, false).
+		First(&comment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWorklogNotFound
+		}
+		return nil, fmt.Errorf("failed to get comment: %w", err)
+	}
+
+	minutes := 0
+	if req.Billable {
+		switch {
+		case req.BillableTime != nil:
+			minutes = *req.BillableTime
+		case comment.TimeSpent != nil:
+			minutes = *comment.TimeSpent
+		}
+		if minutes <= 0 {
+			return nil, fmt.Errorf("%w: billable_time is required when no time is recorded", ErrInvalidWorklogBilling)
+		}
+	}
+
+	period := comment.CreatedAt.In(time.Local).Format(models.BillingPeriodFormat)
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.BillingPeriodLock{}).Where("period = ?", period).Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to check billing period: %w", err)
+		}
+		if count > 0 {
+			return ErrBillingPeriodLocked
+		}
+		return tx.Model(&comment).Update("billable_time", minutes).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	comment.BillableTime = &minutes
+	return newWorklog(&comment, nil), nil
+}
+
+// GetReference 获取工单的计费参考
+func (s *BillingService) GetReference(ctx context.Context, ticketID uint) (*models.TicketBillingReference, error) {
+	var reference models.TicketBillingReference
+	if err := s.db.WithContext(ctx).Where("ticket_id = ?", ticketID).First(&reference).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBillingReferenceNotFound
+		}
+		return nil, fmt.Errorf("failed to get billing reference: %w", err)
+	}
+	return &reference, nil
+}
+
+// SetReference 设置或替换工单的计费参考
+func (s *BillingService) SetReference(ctx context.Context, ticketID uint, req *models.TicketBillingReferenceRequest, userID uint) (*models.TicketBillingReference, error) {
+	if err := s.ensureTicket(ctx, ticketID); err != nil {
+		return nil, err
+	}
+	reference, err := s.GetReference(ctx, ticketID)
+	if err != nil && !errors.Is(err, ErrBillingReferenceNotFound) {
+		return nil, err
+	}
+	if reference == nil {
+		reference = &models.TicketBillingReference{TicketID: ticketID}
+	}
+	reference.Reference = req.Reference
+	reference.InvoiceURL = req.InvoiceURL
+	reference.Note = req.Note
+	reference.UpdatedByID = userID
+	if err := s.db.WithContext(ctx).Save(reference).Error; err != nil {
+		return nil, fmt.Errorf("failed to save billing reference: %w", err)
+	}
+	return reference, nil
+}
+
+// DeleteReference 删除工单的计费参考
+func (s *BillingService) DeleteReference(ctx context.Context, ticketID uint) error {
+	result := s.db.WithContext(ctx).Where("ticket_id = ?", ticketID).Delete(&models.TicketBillingReference{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete billing reference: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrBillingReferenceNotFound
+	}
+	return nil
+}
+
+// ListLocks 获取已锁定的计费周期，最近的在前
+func (s *BillingService) ListLocks(ctx context.Context) ([]*models.BillingPeriodLock, error) {
+	locks := []*models.BillingPeriodLock{}
+	if err := s.db.WithContext(ctx).Order("period DESC").Find(&locks).Error; err != nil {
+		return nil, fmt.Errorf("failed to list billing period locks: %w", err)
+	}
+	return locks, nil
+}
+
+// LockPeriod 开票后锁定计费周期，只能锁定已结束的月份；保存当前费率，之后的报表使用该费率
+func (s *BillingService) LockPeriod(ctx context.Context, period string, req *models.BillingPeriodLockRequest, userID uint) (*models.BillingPeriodLock, error) {
+	_, end, err := parseBillingPeriod(period)
+	if err != nil {
+		return nil, err
+	}
+	if end.After(s.now()) {
+		return nil, fmt.Errorf("%w: only ended periods can be locked", ErrInvalidBillingPeriod)
+	}
+	rates, err := s.GetRates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	snapshot, err := json.Marshal(rates)
+	if err != nil {
+		return nil, err
+	}
+
+	lock := &models.BillingPeriodLock{
+		Period:           period,
+		InvoiceReference: req.InvoiceReference,
+		Rates:            string(snapshot),
+		LockedByID:       userID,
+	}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.BillingPeriodLock{}).Where("period = ?", period).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrBillingPeriodLocked
+		}
+		return tx.Create(lock).Error
+	})
+	if err != nil {
+		if errors.Is(err, ErrBillingPeriodLocked) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to lock billing period: %w", err)
+	}
+	return lock, nil
+}
+
+// UnlockPeriod 解除计费周期锁定（如需要重新开票）
+func (s *BillingService) UnlockPeriod(ctx context.Context, period string) error {
+	if _, _, err := parseBillingPeriod(period); err != nil {
+		return err
+	}
+	result := s.db.WithContext(ctx).Where("period = ?", period).Delete(&models.BillingPeriodLock{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to unlock billing period: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrBillingPeriodNotLocked
+	}
+	return nil
+}
+
+// Report 统计计费周期内按客户或公司汇总的计费工时及金额。
+// 工时按评论的发表时间归入月份，已锁定的周期使用锁定时的费率
+func (s *BillingService) Report(ctx context.Context, period, groupBy string) (*models.BillableHoursReport, error) {
+	start, end, err := parseBillingPeriod(period)
+	if err != nil {
+		return nil, err
+	}
+	if groupBy == "" {
+		groupBy = BillingGroupByCustomer
+	}
+	if groupBy != BillingGroupByCustomer && groupBy != BillingGroupByCompany {
+		return nil, fmt.Errorf("%w: group_by must be customer or company", ErrInvalidBillingReport)
+	}
+
+	report := &models.BillableHoursReport{Period: period, GroupBy: groupBy, Rows: []*models.BillableHoursRow{}}
+	var lock models.BillingPeriodLock
+	err = s.db.WithContext(ctx).Where("period = ?", period).First(&lock).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to get billing period lock: %w", err)
+	}
+	rates := models.GetDefaultBillingRateConfig()
+	if err == nil {
+		report.Lock = &lock
+		if err := json.Unmarshal([]byte(lock.Rates), rates); err != nil {
+			return nil, fmt.Errorf("failed to parse locked billing rates: %w", err)
+		}
+	} else if rates, err = s.GetRates(ctx); err != nil {
+		return nil, err
+	}
+	report.Currency = rates.Currency
+
+	var totals []struct {
+		TicketID uint
+		Minutes  int
+	}
+	if err := s.db.WithContext(ctx).Model(&models.TicketComment{}).
+		Select("ticket_id, SUM(billable_time) AS minutes").
+		Where("is_deleted = ? AND billable_time > 0 AND created_at >= ? AND created_at < ?", false, start, end).
+		Group("ticket_id").Scan(&totals).Error; err != nil {
+		return nil, fmt.Errorf("failed to sum billable time: %w", err)
+	}
+	if len(totals) == 0 {
+		return report, nil
+	}
+
+	ticketIDs := make([]uint, 0, len(totals))
+	for _, total := range totals {
+		ticketIDs = append(ticketIDs, total.TicketID)
+	}
+	// 已删除的工单在删除前产生的工时同样计费
+	var tickets []models.Ticket
+	if err := s.db.WithContext(ctx).Unscoped().Preload("CreatedBy").
+		Where("id IN ?", ticketIDs).Find(&tickets).Error; err != nil {
+		return nil, fmt.Errorf("failed to get tickets: %w", err)
+	}
+	ticketsByID := make(map[uint]*models.Ticket, len(tickets))
+	for i := range tickets {
+		ticketsByID[tickets[i].ID] = &tickets[i]
+	}
+	var references []models.TicketBillingReference
+	if err := s.db.WithContext(ctx).Where("ticket_id IN ?", ticketIDs).Find(&references).Error; err != nil {
+		return nil, fmt.Errorf("failed to get billing references: %w", err)
+	}
+	referencesByTicket := make(map[uint]string, len(references))
+	for _, reference := range references {
+		referencesByTicket[reference.TicketID] = reference.Reference
+	}
+
+	rows := make(map[string]*models.BillableHoursRow)
+	for _, total := range totals {
+		ticket, ok := ticketsByID[total.TicketID]
+		if !ok {
+			continue
+		}
+		email, name := "", ""
+		if ticket.CreatedBy != nil {
+			email, name = ticket.CreatedBy.Email, ticket.CreatedBy.GetFullName()
+		}
+		rate := rates.RateFor(email)
+		line := &models.BillableTicketLine{
+			TicketID:         ticket.ID,
+			TicketNumber:     ticket.TicketNumber,
+			Title:            ticket.Title,
+			CustomerEmail:    email,
+			BillingReference: referencesByTicket[ticket.ID],
+			BillableMinutes:  total.Minutes,
+			BillableHours:    billableHours(total.Minutes),
+			Rate:             rate,
+			Amount:           billingAmount(total.Minutes, rate),
+		}
+
+		key, label := strings.ToLower(email), name
+		if groupBy == BillingGroupByCompany {
+			key = models.EmailDomain(email)
+			label = rates.LabelFor(key)
+		}
+		if key == "" {
+			key = "none"
+		}
+		if label == "" {
+			label = key
+		}
+		row, ok := rows[key]
+		if !ok {
+			row = &models.BillableHoursRow{Key: key, Label: label}
+			rows[key] = row
+			report.Rows = append(report.Rows, row)
+		}
+		row.Lines = append(row.Lines, line)
+		row.Tickets++
+		row.BillableMinutes += line.BillableMinutes
+		row.Amount += line.Amount
+		report.BillableMinutes += line.BillableMinutes
+		report.Amount += line.Amount
+	}
+
+	sort.Slice(report.Rows, func(i, j int) bool { return report.Rows[i].Key < report.Rows[j].Key })
+	for _, row := range report.Rows {
+		sort.Slice(row.Lines, func(i, j int) bool { return row.Lines[i].TicketNumber < row.Lines[j].TicketNumber })
+		row.BillableHours = billableHours(row.BillableMinutes)
+		row.Amount = math.Round(row.Amount*100) / 100
+	}
+	report.BillableHours = billableHours(report.BillableMinutes)
+	report.Amount = math.Round(report.Amount*100) / 100
+	return report, nil
+}
+
+// WriteReportCSV 将报表按工单逐行导出为 CSV，便于导入开票系统
+func (s *BillingService) WriteReportCSV(report *models.BillableHoursReport, w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{
+		"period", "group_key", "group_label", "ticket_number", "title", "customer_email", "billing_reference",
+		"billable_minutes", "billable_hours", "rate", "amount", "currency",
+	}); err != nil {
+		return err
+	}
+
+	formatAmount := func(v float64) string { return strconv.FormatFloat(v, 'f', 2, 64) }
+	for _, row := range report.Rows {
+		for _, line := range row.Lines {
+			if err := writer.Write([]string{
+				report.Period, row.Key, row.Label, line.TicketNumber, line.Title, line.CustomerEmail, line.BillingReference,
+				strconv.Itoa(line.BillableMinutes), formatAmount(line.BillableHours), formatAmount(line.Rate),
+				formatAmount(line.Amount), report.Currency,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func (s *BillingService) ensureTicket(ctx context.Context, ticketID uint) error {
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.Ticket{}).Where("id = ?", ticketID).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to get ticket: %w", err)
+	}
+	if count == 0 {
+		return ErrBillingTicketNotFound
+	}
+	return nil
+}
+
+// lockedPeriods 已锁定的计费周期集合
+func (s *BillingService) lockedPeriods(ctx context.Context) (map[string]bool, error) {
+	var periods []string
+	if err := s.db.WithContext(ctx).Model(&models.BillingPeriodLock{}).Pluck("period", &periods).Error; err != nil {
+		return nil, fmt.Errorf("failed to list billing period locks: %w", err)
+	}
+	locked := make(map[string]bool, len(periods))
+	for _, period := range periods {
+		locked[period] = true
+	}
+	return locked, nil
+}
+
+func newWorklog(comment *models.TicketComment, locked map[string]bool) *models.Worklog {
+	worklog := &models.Worklog{
+		CommentID: comment.ID,
+		CreatedAt: comment.CreatedAt,
+		UserID:    comment.UserID,
+		WorkType:  comment.WorkType,
+		Locked:    locked[comment.CreatedAt.In(time.Local).Format(models.BillingPeriodFormat)],
+	}
+	if comment.User != nil {
+		worklog.UserName = comment.User.GetFullName()
+	}
+	if comment.TimeSpent != nil {
+		worklog.TimeSpent = *comment.TimeSpent
+	}
+	if comment.BillableTime != nil {
+		worklog.BillableTime = *comment.BillableTime
+	}
+	return worklog
+}
+
+// parseBillingPeriod 解析 YYYY-MM 格式的计费周期，返回本地时区的月初及下月初
+func parseBillingPeriod(period string) (time.Time, time.Time, error) {
+	start, err := time.ParseInLocation(models.BillingPeriodFormat, period, time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: period must be in YYYY-MM format", ErrInvalidBillingPeriod)
+	}
+	return start, start.AddDate(0, 1, 0), nil
+}
+
+// billableHours 分钟转换为小时，保留两位小数
+func billableHours(minutes int) float64 {
+	return math.Round(float64(minutes)/60*100) / 100
+}
+
+// billingAmount 按小时费率计算金额，保留两位小数
+func billingAmount(minutes int, rate float64) float64 {
+	return math.Round(float64(minutes)/60*rate*100) / 100
+}
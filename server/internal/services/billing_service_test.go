@@ -0,0 +1,134 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+)
+
+func TestBilling_ReportRatesAndPeriodLocks(t *testing.T) {
+	db := newTestDB(t, &models.User{}, &models.Ticket{}, &models.TicketComment{}, &models.SystemConfig{},
+		&models.TicketBillingReference{}, &models.BillingPeriodLock{})
+	ctx := context.Background()
+	svc := NewBillingService(db)
+	svc.now = func() time.Time { return time.Date(2026, 10, 16, 9, 0, 0, 0, time.Local) }
+
+	agent := models.User{Username: "bill-agent", Email: "bill-agent@example.com", PasswordHash: "x", Role: models.RoleAgent, Status: models.UserStatusActive}
+	alice := models.User{Username: "alice", Email: "alice@acme.com", PasswordHash: "x", Role: models.RoleCustomer, Status: models.UserStatusActive}
+	bob := models.User{Username: "bob", Email: "Bob@acme.com", PasswordHash: "x", Role: models.RoleCustomer, Status: models.UserStatusActive}
+	carol := models.User{Username: "carol", Email: "carol@globex.com", PasswordHash: "x", Role: models.RoleCustomer, Status: models.UserStatusActive}
+	for _, user := range []*models.User{&agent, &alice, &bob, &carol} {
+		db.Create(user)
+	}
+	newTicket := func(number string, customer *models.User) *models.Ticket {
+		ticket := &models.Ticket{TicketNumber: number, Title: "Ticket " + number, Status: models.TicketStatusOpen, Priority: models.TicketPriorityNormal,
+			Type: models.TicketTypeRequest, Source: models.TicketSourceWeb, CreatedByID: customer.ID}
+		db.Create(ticket)
+		return ticket
+	}
+	minutes := func(v int) *int { return &v }
+	september := time.Date(2026, 9, 10, 10, 0, 0, 0, time.Local)
+	worklog := func(ticket *models.Ticket, spent int, billable *int, at time.Time) *models.TicketComment {
+		comment := &models.TicketComment{TicketID: ticket.ID, UserID: agent.ID, Content: "work", Type: models.CommentTypeInternal,
+			TimeSpent: &spent, BillableTime: billable, CreatedAt: at}
+		db.Create(comment)
+		return comment
+	}
+
+	t1, t2, t3 := newTicket("B-1", &alice), newTicket("B-2", &bob), newTicket("B-3", &carol)
+	w1 := worklog(t1, 90, nil, september)
+	worklog(t1, 30, minutes(30), september.Add(time.Hour))
+	worklog(t2, 60, minutes(60), september)
+	worklog(t3, 120, minutes(45), september)
+	worklog(t3, 60, minutes(60), september.AddDate(0, 1, 0)) // 十月，不计入九月
+
+	if err := svc.SetRates(ctx, &models.BillingRateConfig{Currency: "cny", DefaultRate: 100, CustomerRates: []models.BillingRate{
+		{Pattern: "ACME.com", Rate: 200, Label: "Acme Inc"},
+		{Pattern: "bob@acme.com", Rate: 300},
+	}}, agent.ID); err != nil {
+		t.Fatalf("set rates failed: %v", err)
+	}
+	if err := svc.SetRates(ctx, &models.BillingRateConfig{Currency: "CNY", CustomerRates: []models.BillingRate{{Pattern: "a.com"}, {Pattern: "A.com"}}}, agent.ID); err == nil {
+		t.Fatalf("expected duplicated rate pattern to be rejected")
+	}
+
+	// 计费工时：未指定分钟数时按花费时间计
+	updated, err := svc.SetWorklogBillable(ctx, t1.ID, w1.ID, &models.WorklogBillableRequest{Billable: true})
+	if err != nil || updated.BillableTime != 90 {
+		t.Fatalf("unexpected worklog %+v (%v)", updated, err)
+	}
+	if _, err := svc.SetReference(ctx, t1.ID, &models.TicketBillingReferenceRequest{Reference: "PO-42"}, agent.ID); err != nil {
+		t.Fatalf("set reference failed: %v", err)
+	}
+	worklogs, err := svc.ListWorklogs(ctx, t1.ID)
+	if err != nil || len(worklogs.Items) != 2 || worklogs.TotalTimeSpent != 120 || worklogs.TotalBillable != 120 ||
+		worklogs.BillingReference == nil || worklogs.BillingReference.Reference != "PO-42" {
+		t.Fatalf("unexpected worklogs %+v (%v)", worklogs, err)
+	}
+
+	report, err := svc.Report(ctx, "2026-09", BillingGroupByCustomer)
+	if err != nil {
+		t.Fatalf("report failed: %v", err)
+	}
+	// alice 2h×200，bob 1h×300（邮箱优先于域名），carol 0.75h×100
+	if report.Currency != "CNY" || len(report.Rows) != 3 || report.BillableMinutes != 225 || report.Amount != 775 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if row := report.Rows[0]; row.Key != "alice@acme.com" || row.BillableHours != 2 || row.Amount != 400 ||
+		row.Lines[0].BillingReference != "PO-42" {
+		t.Fatalf("unexpected customer row %+v", row)
+	}
+	company, err := svc.Report(ctx, "2026-09", BillingGroupByCompany)
+	if err != nil || len(company.Rows) != 2 || company.Rows[0].Key != "acme.com" || company.Rows[0].Label != "Acme Inc" ||
+		company.Rows[0].Tickets != 2 || company.Rows[0].Amount != 700 {
+		t.Fatalf("unexpected company report %+v (%v)", company, err)
+	}
+	var buf bytes.Buffer
+	if err := svc.WriteReportCSV(company, &buf); err != nil {
+		t.Fatalf("write csv failed: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(buf.String()), "\n"); len(lines) != 4 ||
+		!strings.Contains(lines[1], "2026-09,acme.com,Acme Inc,B-1,Ticket B-1,alice@acme.com,PO-42,120,2.00,200.00,400.00,CNY") {
+		t.Fatalf("unexpected csv %q", buf.String())
+	}
+
+	// 当月尚未结束不能锁定；锁定后不能修改工时，报表使用锁定时的费率
+	if _, err := svc.LockPeriod(ctx, "2026-10", &models.BillingPeriodLockRequest{}, agent.ID); !errors.Is(err, ErrInvalidBillingPeriod) {
+		t.Fatalf("expected current period lock to be rejected, got %v", err)
+	}
+	if _, err := svc.LockPeriod(ctx, "2026-09", &models.BillingPeriodLockRequest{InvoiceReference: "INV-9"}, agent.ID); err != nil {
+		t.Fatalf("lock period failed: %v", err)
+	}
+	if _, err := svc.LockPeriod(ctx, "2026-09", &models.BillingPeriodLockRequest{}, agent.ID); !errors.Is(err, ErrBillingPeriodLocked) {
+		t.Fatalf("expected duplicate lock to be rejected, got %v", err)
+	}
+	if _, err := svc.SetWorklogBillable(ctx, t1.ID, w1.ID, &models.WorklogBillableRequest{Billable: false}); !errors.Is(err, ErrBillingPeriodLocked) {
+		t.Fatalf("expected locked period to reject changes, got %v", err)
+	}
+	svc.SetRates(ctx, &models.BillingRateConfig{Currency: "USD", DefaultRate: 1}, agent.ID)
+	locked, err := svc.Report(ctx, "2026-09", BillingGroupByCustomer)
+	if err != nil || locked.Lock == nil || locked.Lock.InvoiceReference != "INV-9" || locked.Currency != "CNY" || locked.Amount != 775 {
+		t.Fatalf("expected locked report to use snapshot rates, got %+v (%v)", locked, err)
+	}
+	if worklogs, _ := svc.ListWorklogs(ctx, t1.ID); !worklogs.Items[0].Locked {
+		t.Fatalf("expected worklog in locked period to be marked locked")
+	}
+
+	if err := svc.UnlockPeriod(ctx, "2026-09"); err != nil {
+		t.Fatalf("unlock failed: %v", err)
+	}
+	if err := svc.UnlockPeriod(ctx, "2026-09"); !errors.Is(err, ErrBillingPeriodNotLocked) {
+		t.Fatalf("expected not locked, got %v", err)
+	}
+	if _, err := svc.SetWorklogBillable(ctx, t1.ID, w1.ID, &models.WorklogBillableRequest{Billable: false}); err != nil {
+		t.Fatalf("expected unlocked period to accept changes, got %v", err)
+	}
+	if _, err := svc.Report(ctx, "2026/09", ""); !errors.Is(err, ErrInvalidBillingPeriod) {
+		t.Fatalf("expected invalid period, got %v", err)
+	}
+}
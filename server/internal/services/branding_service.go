@@ -0,0 +1,312 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+// KeySystemBranding 品牌与白标配置键
+const KeySystemBranding = "system.branding"
+
+const (
+	// MaxBrandingAssetSize 品牌资源（Logo、favicon）文件大小上限
+	MaxBrandingAssetSize = 1 << 20
+	// brandingCacheTTL 品牌配置缓存时间，其他实例修改后最迟在该时间后生效
+	brandingCacheTTL = 30 * time.Second
+	// defaultBrandingPortalURL 未设置前端地址时的门户地址，与 WEB_URL 的默认值一致
+	defaultBrandingPortalURL = "http://localhost:3000"
+)
+
+var (
+	// ErrBrandingTenantNotFound 租户覆盖不存在
+	ErrBrandingTenantNotFound = errors.New("branding tenant not found")
+	// ErrInvalidBrandingAsset 资源类型不支持或文件不是允许的图片格式
+	ErrInvalidBrandingAsset = errors.New("invalid branding asset: only PNG, JPEG, GIF, WebP and ICO images are allowed")
+	// ErrBrandingAssetTooLarge 资源文件过大
+	ErrBrandingAssetTooLarge = errors.New("branding asset too large: maximum 1MB allowed")
+)
+
+// allowedBrandingAssetTypes 按文件内容识别的 MIME 类型及存储扩展名，不接受 SVG 以免脚本注入
+var allowedBrandingAssetTypes = map[string]string{
+	"image/png":    "png",
+	"image/jpeg":   "jpg",
+	"image/gif":    "gif",
+	"image/webp":   "webp",
+	"image/x-icon": "ico",
+}
+
+// BrandingService 品牌与白标服务：管理产品名称、Logo、配色、发件域名及门户地址，
+// 供邮件模板与客户门户使用，多租户模式下按门户域名应用租户覆盖
+type BrandingService struct {
+	db            *gorm.DB
+	storage       FileStorage
+	configService *ConfigService
+	webURL        string
+
+	mu       sync.RWMutex
+	cached   *models.BrandingConfig
+	cachedAt time.Time
+}
+
+// NewBrandingService 创建品牌服务，storage 为空时不支持上传资源
+func NewBrandingService(db *gorm.DB, storage FileStorage) *BrandingService {
+	return &BrandingService{
+		db:            db,
+		storage:       storage,
+		configService: NewConfigService(db),
+	}
+}
+
+// SetWebURL 设置前端地址，作为未配置门户地址时的默认值
+func (s *BrandingService) SetWebURL(webURL string) {
+	s.webURL = webURL
+}
+
+// GetConfig 从配置存储读取品牌配置
+func (s *BrandingService) GetConfig(ctx context.Context) (*models.BrandingConfig, error) {
+	var config models.SystemConfig
+	err := s.db.WithContext(ctx).
+		Where("key = ? AND is_active = ?", KeySystemBranding, true).
+		First(&config).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.GetDefaultBranding(), nil
+		}
+		return nil, fmt.Errorf("failed to get branding: %w", err)
+	}
+
+	branding := &models.BrandingConfig{}
+	if err := config.GetJSONValue(branding); err != nil {
+		log.Printf("Warning: failed to parse branding config, using defaults: %v", err)
+		return models.GetDefaultBranding(), nil
+	}
+	return branding, nil
+}
+
+// SetConfig 保存品牌配置并刷新缓存
+func (s *BrandingService) SetConfig(ctx context.Context, branding *models.BrandingConfig, userID uint) error {
+	if err := branding.Validate(); err != nil {
+		return err
+	}
+
+	var existing models.SystemConfig
+	err := s.db.WithContext(ctx).Where("key = ?", KeySystemBranding).First(&existing).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to check existing branding: %w", err)
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		config := models.SystemConfig{
+			Key:         KeySystemBranding,
+			Category:    CategorySystem,
+			Group:       "branding",
+			Description: "品牌与白标设置",
+			IsActive:    true,
+			UpdatedBy:   &userID,
+		}
+		if err := config.SetValue(branding); err != nil {
+			return fmt.Errorf("failed to set branding value: %w", err)
+		}
+		if err := s.db.WithContext(ctx).Create(&config).Error; err != nil {
+			return fmt.Errorf("failed to create branding: %w", err)
+		}
+	} else {
+		if err := existing.SetValue(branding); err != nil {
+			return fmt.Errorf("failed to set branding value: %w", err)
+		}
+		existing.UpdatedBy = &userID
+		existing.Version++
+
+		if err := s.db.WithContext(ctx).Save(&existing).Error; err != nil {
+			return fmt.Errorf("failed to update branding: %w", err)
+		}
+	}
+
+	s.mu.Lock()
+	s.cached = branding
+	s.cachedAt = time.Now()
+	s.mu.Unlock()
+	return nil
+}
+
+// UploadAsset 上传 Logo 或 favicon 并写入全局设置或指定租户的覆盖，返回更新后的配置
+func (s *BrandingService) UploadAsset(ctx context.Context, kind, tenant string, r io.Reader, userID uint) (*models.BrandingConfig, error) {
+	if s.storage == nil || (kind != models.BrandingAssetLogo && kind != models.BrandingAssetFavicon) {
+		return nil, ErrInvalidBrandingAsset
+	}
+	branding, err := s.GetConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	settings := branding.BrandingSettings
+	if tenant != "" {
+		var ok bool
+		if settings, ok = branding.Tenants[tenant]; !ok {
+			return nil, ErrBrandingTenantNotFound
+		}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r, MaxBrandingAssetSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read branding asset: %w", err)
+	}
+	if len(data) > MaxBrandingAssetSize {
+		return nil, ErrBrandingAssetTooLarge
+	}
+	mimeType := http.DetectContentType(data)
+	ext, ok := allowedBrandingAssetTypes[mimeType]
+	if !ok {
+		return nil, ErrInvalidBrandingAsset
+	}
+
+	// 文件名包含内容哈希，可长期缓存，替换后旧地址不受影响
+	sum := sha256.Sum256(data)
+	key := fmt.Sprintf("branding/%s_%s.%s", kind, hex.EncodeToString(sum[:])[:16], ext)
+	if err := s.storage.Put(ctx, key, bytes.NewReader(data), mimeType); err != nil {
+		return nil, err
+	}
+	if kind == models.BrandingAssetLogo {
+		settings.LogoURL = s.storage.URL(key)
+	} else {
+		settings.FaviconURL = s.storage.URL(key)
+	}
+
+	if tenant != "" {
+		branding.Tenants[tenant] = settings
+	} else {
+		branding.BrandingSettings = settings
+	}
+	if err := s.SetConfig(ctx, branding, userID); err != nil {
+		return nil, err
+	}
+	return branding, nil
+}
+
+// Resolve 计算请求域名适用的品牌设置，未设置的字段使用系统名称、系统 Logo 及默认配色。
+// 返回匹配到的租户域名，未匹配时为空
+func (s *BrandingService) Resolve(ctx context.Context, host string) (models.BrandingSettings, string) {
+	settings, tenant := s.current(ctx).Resolve(host)
+	defaults := models.GetDefaultBranding().BrandingSettings
+	defaults.PortalURL = defaultBrandingPortalURL
+	if s.webURL != "" {
+		defaults.PortalURL = s.webURL
+	}
+	settings = defaults.Merge(settings)
+	if settings.ProductName == "" {
+		settings.ProductName = s.configService.GetConfigWithDefault(KeySystemName, "工单管理系统")
+	}
+	if settings.LogoURL == "" {
+		settings.LogoURL = s.configService.GetConfigWithDefault(KeySystemLogo, "")
+	}
+	if settings.FooterText == "" {
+		settings.FooterText = s.configService.GetConfigWithDefault(KeySystemCopyright, "")
+	}
+	if settings.FooterText == "" {
+		settings.FooterText = fmt.Sprintf("© %d %s", time.Now().Year(), settings.ProductName)
+	}
+	return settings, tenant
+}
+
+// Sender 按品牌设置调整发件人：配置了发件域名时替换地址的域名，配置了产品名称时作为发件人名称
+func (s *BrandingService) Sender(ctx context.Context, fromEmail, fromName string) (string, string) {
+	branding := s.current(ctx)
+	if branding.EmailDomain != "" {
+		if at := strings.LastIndex(fromEmail, "@"); at > 0 {
+			fromEmail = fromEmail[:at+1] + branding.EmailDomain
+		}
+	}
+	if branding.ProductName != "" {
+		fromName = branding.ProductName
+	}
+	return fromEmail, fromName
+}
+
+// Render 替换邮件主题与正文中的品牌变量。邮件按全局设置渲染，接收者不属于具体租户
+func (s *BrandingService) Render(ctx context.Context, subject, body string) (string, string) {
+	settings, _ := s.Resolve(ctx, "")
+	return renderBranding(subject, settings, false), renderBranding(body, settings, true)
+}
+
+// RenderBrandedEmail 渲染邮件中的品牌变量，branding 为空时使用默认品牌
+func RenderBrandedEmail(ctx context.Context, branding *BrandingService, subject, body string) (string, string) {
+	if branding != nil {
+		return branding.Render(ctx, subject, body)
+	}
+	settings := models.GetDefaultBranding().BrandingSettings
+	settings.ProductName = "工单管理系统"
+	settings.PortalURL = defaultBrandingPortalURL
+	settings.FooterText = fmt.Sprintf("© %d %s", time.Now().Year(), settings.ProductName)
+	return renderBranding(subject, settings, false), renderBranding(body, settings, true)
+}
+
+// BrandedSender 按品牌设置调整发件人，branding 为空时原样返回
+func BrandedSender(ctx context.Context, branding *BrandingService, fromEmail, fromName string) (string, string) {
+	if branding != nil {
+		return branding.Sender(ctx, fromEmail, fromName)
+	}
+	return fromEmail, fromName
+}
+
+// current 获取缓存的品牌配置；读取失败时沿用上一次的配置
+func (s *BrandingService) current(ctx context.Context) *models.BrandingConfig {
+	s.mu.RLock()
+	cached, cachedAt := s.cached, s.cachedAt
+	s.mu.RUnlock()
+
+	if cached != nil && time.Since(cachedAt) < brandingCacheTTL {
+		return cached
+	}
+
+	branding, err := s.GetConfig(ctx)
+	if err != nil {
+		log.Printf("Warning: failed to refresh branding: %v", err)
+		if cached != nil {
+			return cached
+		}
+		return models.GetDefaultBranding()
+	}
+
+	s.mu.Lock()
+	s.cached = branding
+	s.cachedAt = time.Now()
+	s.mu.Unlock()
+	return branding
+}
+
+// renderBranding 替换模板中的品牌变量：{{.BrandProductName}}、{{.BrandLogoImg}}、{{.BrandPrimaryColor}}、
+// {{.BrandAccentColor}}、{{.BrandPortalURL}}、{{.BrandFooter}}。正文中的值做 HTML 转义
+func renderBranding(template string, settings models.BrandingSettings, escape bool) string {
+	value := func(v string) string {
+		if escape {
+			return html.EscapeString(v)
+		}
+		return v
+	}
+	logo := ""
+	if settings.LogoURL != "" && escape {
+		logo = fmt.Sprintf(`<img src="%s" alt="%s" style="max-height: 40px; display: block; margin: 0 auto 10px;">`,
+			html.EscapeString(settings.LogoURL), html.EscapeString(settings.ProductName))
+	}
+	return strings.NewReplacer(
+		"{{.BrandProductName}}", value(settings.ProductName),
+		"{{.BrandLogoImg}}", logo,
+		"{{.BrandPrimaryColor}}", settings.PrimaryColor,
+		"{{.BrandAccentColor}}", settings.AccentColor,
+		"{{.BrandPortalURL}}", value(strings.TrimRight(settings.PortalURL, "/")),
+		"{{.BrandFooter}}", value(settings.FooterText),
+	).Replace(template)
+}
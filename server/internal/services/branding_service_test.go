@@ -0,0 +1,94 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestBrandingService_TenantOverridesAssetsAndEmailRendering(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:branding_service_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.SystemConfig{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+	ctx := context.Background()
+	svc := NewBrandingService(db, NewLocalFileStorage(t.TempDir(), "/uploads"))
+	svc.SetWebURL("https://support.example.com")
+	db.Create(&models.SystemConfig{Key: KeySystemName, Value: "Example Desk", ValueType: "string", IsActive: true})
+
+	// 未配置时使用系统名称与默认配色
+	settings, tenant := svc.Resolve(ctx, "acme.example.org")
+	if settings.ProductName != "Example Desk" || settings.PrimaryColor != "#007bff" || settings.PortalURL != "https://support.example.com" || tenant != "" {
+		t.Fatalf("unexpected default branding %+v, %q", settings, tenant)
+	}
+
+	invalid := []*models.BrandingConfig{
+		{BrandingSettings: models.BrandingSettings{PrimaryColor: "blue"}},
+		{BrandingSettings: models.BrandingSettings{PortalURL: "javascript:alert(1)"}},
+		{BrandingSettings: models.BrandingSettings{EmailDomain: "Not A Domain"}},
+		{Tenants: map[string]models.BrandingSettings{"ACME.example.org": {}}},
+	}
+	for _, config := range invalid {
+		if err := svc.SetConfig(ctx, config, 1); err == nil {
+			t.Fatalf("expected %+v to be rejected", config)
+		}
+	}
+
+	config := &models.BrandingConfig{
+		BrandingSettings: models.BrandingSettings{ProductName: "Acme Help", PrimaryColor: "#112233", EmailDomain: "mail.acme.com"},
+		MultiTenant:      true,
+		Tenants: map[string]models.BrandingSettings{
+			"acme.example.org": {ProductName: "Acme <Support>", PortalURL: "https://acme.example.org/"},
+		},
+	}
+	if err := svc.SetConfig(ctx, config, 1); err != nil {
+		t.Fatalf("set branding failed: %v", err)
+	}
+
+	// 租户覆盖按门户域名匹配（忽略端口与大小写），空字段继承全局设置
+	settings, tenant = svc.Resolve(ctx, "ACME.example.org:8443")
+	if tenant != "acme.example.org" || settings.ProductName != "Acme <Support>" || settings.PrimaryColor != "#112233" || settings.PortalURL != "https://acme.example.org/" {
+		t.Fatalf("unexpected tenant branding %+v, %q", settings, tenant)
+	}
+	if settings, tenant := svc.Resolve(ctx, "other.example.org"); settings.ProductName != "Acme Help" || tenant != "" {
+		t.Fatalf("expected global branding for unknown host, got %+v, %q", settings, tenant)
+	}
+
+	// 资源按内容识别类型，写入指定租户的覆盖
+	var logo bytes.Buffer
+	png.Encode(&logo, image.NewRGBA(image.Rect(0, 0, 8, 8)))
+	updated, err := svc.UploadAsset(ctx, models.BrandingAssetLogo, "acme.example.org", bytes.NewReader(logo.Bytes()), 1)
+	if err != nil || !strings.HasPrefix(updated.Tenants["acme.example.org"].LogoURL, "/uploads/branding/logo_") || updated.LogoURL != "" {
+		t.Fatalf("unexpected upload result %+v, %v", updated, err)
+	}
+	if _, err := svc.UploadAsset(ctx, models.BrandingAssetLogo, "", strings.NewReader("<svg onload=alert(1)></svg>"), 1); !errors.Is(err, ErrInvalidBrandingAsset) {
+		t.Fatalf("expected svg to be rejected, got %v", err)
+	}
+	if _, err := svc.UploadAsset(ctx, models.BrandingAssetFavicon, "missing.example.org", bytes.NewReader(logo.Bytes()), 1); !errors.Is(err, ErrBrandingTenantNotFound) {
+		t.Fatalf("expected unknown tenant, got %v", err)
+	}
+
+	// 邮件按全局设置渲染，正文中的值做 HTML 转义，发件地址使用自定义域名
+	config.ProductName = "Acme & Co"
+	if err := svc.SetConfig(ctx, config, 1); err != nil {
+		t.Fatalf("set branding failed: %v", err)
+	}
+	subject, body := svc.Render(ctx, "Welcome to {{.BrandProductName}}", `<h1 style="color: {{.BrandPrimaryColor}}">{{.BrandProductName}}</h1><a href="{{.BrandPortalURL}}/login">`)
+	if subject != "Welcome to Acme & Co" || body != `<h1 style="color: #112233">Acme &amp; Co</h1><a href="https://support.example.com/login">` {
+		t.Fatalf("unexpected rendered email %q, %q", subject, body)
+	}
+	if email, name := svc.Sender(ctx, "noreply@ticketsystem.com", "工单系统"); email != "noreply@mail.acme.com" || name != "Acme & Co" {
+		t.Fatalf("unexpected sender %q <%s>", name, email)
+	}
+}
@@ -0,0 +1,325 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+	_ "time/tzdata" // 容器镜像可能不带时区数据库
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+// KeyBusinessCalendar 组织营业日历配置键
+const KeyBusinessCalendar = "system.business_calendar"
+
+// businessCalendarSearchDays 查找下一个营业日时最多向后搜索的天数
+const businessCalendarSearchDays = 366
+
+// CalendarStatus 营业日历及当前营业状态
+type CalendarStatus struct {
+	Timezone           string                     `json:"timezone"`
+	Now                time.Time                  `json:"now"`
+	IsOpen             bool                       `json:"is_open"`
+	NextOpenAt         *time.Time                 `json:"next_open_at,omitempty"`  // 当前不营业时的下次开始营业时间
+	NextCloseAt        *time.Time                 `json:"next_close_at,omitempty"` // 当前营业时的今日下班时间
+	WorkingHours       models.WorkingHours        `json:"working_hours"`
+	UpcomingHolidays   []models.Holiday           `json:"upcoming_holidays"`
+	DueDateSuggestions []models.DueDateSuggestion `json:"due_date_suggestions"`
+}
+
+// BusinessCalendarService 组织营业日历服务，供截止时间建议等按工作时间计算的功能共用
+type BusinessCalendarService struct {
+	db *gorm.DB
+}
+
+// NewBusinessCalendarService 创建营业日历服务
+func NewBusinessCalendarService(db *gorm.DB) *BusinessCalendarService {
+	return &BusinessCalendarService{db: db}
+}
+
+// GetCalendar 获取营业日历
+func (s *BusinessCalendarService) GetCalendar(ctx context.Context) (*models.BusinessCalendar, error) {
+	var config models.SystemConfig
+	err := s.db.WithContext(ctx).
+		Where("key = ? AND is_active = ?", KeyBusinessCalendar, true).
+		First(&config).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.GetDefaultBusinessCalendar(), nil
+		}
+		return nil, fmt.Errorf("failed to get business calendar: %w", err)
+	}
+
+	calendar := models.GetDefaultBusinessCalendar()
+	if err := config.GetJSONValue(calendar); err != nil {
+		log.Printf("Warning: failed to parse business calendar, using defaults: %v", err)
+		return models.GetDefaultBusinessCalendar(), nil
+	}
+	if err := calendar.Validate(); err != nil {
+		log.Printf("Warning: invalid business calendar, using defaults: %v", err)
+		return models.GetDefaultBusinessCalendar(), nil
+	}
+	return calendar, nil
+}
+
+// SetCalendar 保存营业日历
+func (s *BusinessCalendarService) SetCalendar(ctx context.Context, calendar *models.BusinessCalendar, userID uint) error {
+	if err := calendar.Validate(); err != nil {
+		return err
+	}
+	sort.Slice(calendar.Holidays, func(i, j int) bool {
+		return calendar.Holidays[i].Date < calendar.Holidays[j].Date
+	})
+
+	var existing models.SystemConfig
+	err := s.db.WithContext(ctx).Where("key = ?", KeyBusinessCalendar).First(&existing).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to check existing business calendar: %w", err)
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		config := models.SystemConfig{
+			Key:         KeyBusinessCalendar,
+			Category:    CategorySystem,
+			Group:       "business_calendar",
+			Description: "组织营业日历（工作时间与节假日）",
+			IsActive:    true,
+			UpdatedBy:   &userID,
+		}
+		if err := config.SetValue(calendar); err != nil {
+			return fmt.Errorf("failed to set business calendar value: %w", err)
+		}
+		if err := s.db.WithContext(ctx).Create(&config).Error; err != nil {
+			return fmt.Errorf("failed to create business calendar: %w", err)
+		}
+		return nil
+	}
+
+	if err := existing.SetValue(calendar); err != nil {
+		return fmt.Errorf("failed to set business calendar value: %w", err)
+	}
+	existing.UpdatedBy = &userID
+	existing.Version++
+
+	if err := s.db.WithContext(ctx).Save(&existing).Error; err != nil {
+		return fmt.Errorf("failed to update business calendar: %w", err)
+	}
+	return nil
+}
+
+// Status 获取营业日历及指定时刻的营业状态
+func (s *BusinessCalendarService) Status(ctx context.Context, now time.Time) (*CalendarStatus, error) {
+	calendar, err := s.GetCalendar(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	b := newBusinessClock(calendar)
+	now = now.In(b.loc)
+	status := &CalendarStatus{
+		Timezone:           calendar.Timezone,
+		Now:                now,
+		IsOpen:             b.isOpen(now),
+		WorkingHours:       calendar.WorkingHours,
+		UpcomingHolidays:   b.upcomingHolidays(now, 10),
+		DueDateSuggestions: b.suggestDueDates(now),
+	}
+	if status.IsOpen {
+		_, end, _ := b.hours(now)
+		status.NextCloseAt = &end
+	} else if next, ok := b.nextOpen(now); ok {
+		status.NextOpenAt = &next
+	}
+	return status, nil
+}
+
+// SuggestDueDates 按营业日历给出截止时间建议
+func (s *BusinessCalendarService) SuggestDueDates(ctx context.Context, now time.Time) ([]models.DueDateSuggestion, error) {
+	calendar, err := s.GetCalendar(ctx)
+	if err != nil {
+		return nil, err
+	}
+	b := newBusinessClock(calendar)
+	return b.suggestDueDates(now.In(b.loc)), nil
+}
+
+// businessClock 基于营业日历的时间计算
+type businessClock struct {
+	calendar *models.BusinessCalendar
+	loc      *time.Location
+	holidays map[string]string
+}
+
+func newBusinessClock(calendar *models.BusinessCalendar) *businessClock {
+	loc, err := time.LoadLocation(calendar.Timezone)
+	if err != nil {
+		loc = time.Local
+	}
+	holidays := make(map[string]string, len(calendar.Holidays))
+	for _, h := range calendar.Holidays {
+		holidays[h.Date] = h.Name
+	}
+	return &businessClock{calendar: calendar, loc: loc, holidays: holidays}
+}
+
+// hours 返回某天的营业起止时间，节假日或无工作时间时 ok 为 false
+func (b *businessClock) hours(day time.Time) (start, end time.Time, ok bool) {
+	if _, holiday := b.holidays[day.Format("2006-01-02")]; holiday {
+		return time.Time{}, time.Time{}, false
+	}
+	r := b.calendar.WorkingHours.ForWeekday(day.Weekday())
+	startOffset, err1 := models.ParseClock(r.Start)
+	endOffset, err2 := models.ParseClock(r.End)
+	if err1 != nil || err2 != nil || startOffset >= endOffset {
+		return time.Time{}, time.Time{}, false
+	}
+	midnight := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, b.loc)
+	return midnight.Add(startOffset), midnight.Add(endOffset), true
+}
+
+func (b *businessClock) isOpen(t time.Time) bool {
+	start, end, ok := b.hours(t)
+	return ok && !t.Before(start) && t.Before(end)
+}
+
+// nextOpen 返回 t 之后最近的开始营业时间
+func (b *businessClock) nextOpen(t time.Time) (time.Time, bool) {
+	for i := 0; i < businessCalendarSearchDays; i++ {
+		day := t.AddDate(0, 0, i)
+		if start, _, ok := b.hours(day); ok && start.After(t) {
+			return start, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// isHoliday 判断 t 所在日期（营业日历时区）是否为节假日，普通周末不算节假日
+func (b *businessClock) isHoliday(t time.Time) bool {
+	_, ok := b.holidays[t.In(b.loc).Format("2006-01-02")]
+	return ok
+}
+
+// businessHoursBetween 统计 from 到 to 之间落在营业时间内的时长（小时）
+func (b *businessClock) businessHoursBetween(from, to time.Time) float64 {
+	if !to.After(from) {
+		return 0
+	}
+	from, to = from.In(b.loc), to.In(b.loc)
+	var total time.Duration
+	for day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, b.loc); day.Before(to); day = day.AddDate(0, 0, 1) {
+		start, end, ok := b.hours(day)
+		if !ok {
+			continue
+		}
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		if end.After(start) {
+			total += end.Sub(start)
+		}
+	}
+	return total.Hours()
+}
+
+// addBusinessMinutes 返回从 from 起累计 minutes 分钟营业时间后的时刻，营业日历中找不到足够营业时间时 ok 为 false
+func (b *businessClock) addBusinessMinutes(from time.Time, minutes float64) (time.Time, bool) {
+	from = from.In(b.loc)
+	remaining := time.Duration(minutes * float64(time.Minute))
+	day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, b.loc)
+	for i := 0; i < businessCalendarSearchDays; i, day = i+1, day.AddDate(0, 0, 1) {
+		start, end, ok := b.hours(day)
+		if !ok || !end.After(from) {
+			continue
+		}
+		if start.Before(from) {
+			start = from
+		}
+		available := end.Sub(start)
+		if remaining <= available {
+			return start.Add(remaining), true
+		}
+		remaining -= available
+	}
+	return time.Time{}, false
+}
+
+// dueOn 返回某个营业日的建议截止时刻：due_time 与下班时间取较早者
+func (b *businessClock) dueOn(day time.Time) time.Time {
+	start, end, _ := b.hours(day)
+	if offset, err := models.ParseClock(b.calendar.DueTime); err == nil {
+		midnight := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, b.loc)
+		if due := midnight.Add(offset); due.After(start) && due.Before(end) {
+			return due
+		}
+	}
+	return end
+}
+
+// addBusinessDays 返回 from 之后第 n 个营业日
+func (b *businessClock) addBusinessDays(from time.Time, n int) (time.Time, bool) {
+	day := from
+	for i := 0; i < businessCalendarSearchDays && n > 0; i++ {
+		day = day.AddDate(0, 0, 1)
+		if _, _, ok := b.hours(day); ok {
+			n--
+		}
+	}
+	return day, n == 0
+}
+
+func (b *businessClock) suggestDueDates(now time.Time) []models.DueDateSuggestion {
+	suggestions := make([]models.DueDateSuggestion, 0, 4)
+	if _, _, ok := b.hours(now); ok {
+		if due := b.dueOn(now); due.After(now) {
+			suggestions = append(suggestions, models.DueDateSuggestion{
+				Key:     "today",
+				Label:   "今天 " + due.Format("15:04"),
+				DueDate: due,
+			})
+		}
+	}
+
+	for _, option := range []struct {
+		key   string
+		days  int
+		label string
+	}{
+		{"next_business_day", 1, "下一个工作日"},
+		{"in_3_business_days", 3, "3个工作日后"},
+		{"in_5_business_days", 5, "5个工作日后"},
+	} {
+		day, ok := b.addBusinessDays(now, option.days)
+		if !ok {
+			break
+		}
+		due := b.dueOn(day)
+		suggestions = append(suggestions, models.DueDateSuggestion{
+			Key:     option.key,
+			Label:   fmt.Sprintf("%s %s", option.label, due.Format("15:04")),
+			DueDate: due,
+		})
+	}
+	return suggestions
+}
+
+func (b *businessClock) upcomingHolidays(now time.Time, limit int) []models.Holiday {
+	today := now.Format("2006-01-02")
+	upcoming := make([]models.Holiday, 0, limit)
+	for _, h := range b.calendar.Holidays {
+		if h.Date >= today {
+			upcoming = append(upcoming, h)
+		}
+	}
+	sort.Slice(upcoming, func(i, j int) bool { return upcoming[i].Date < upcoming[j].Date })
+	if len(upcoming) > limit {
+		upcoming = upcoming[:limit]
+	}
+	return upcoming
+}
@@ -0,0 +1,402 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrCategoryAccessDenied 坐席不属于工单所在分类
+	ErrCategoryAccessDenied = errors.New("user is not a member of the ticket's category")
+	// ErrAssigneeNotCategoryMember 处理人不属于工单所在分类
+	ErrAssigneeNotCategoryMember = errors.New("assignee is not a member of the ticket's category")
+	// ErrCategoryMembershipUserInvalid 用户不存在或不是坐席
+	ErrCategoryMembershipUserInvalid = errors.New("user not found or not an agent")
+	// ErrCategoryMembershipCategoryNotFound 分类不存在
+	ErrCategoryMembershipCategoryNotFound = errors.New("category not found")
+	// ErrCategoryMembershipTicketNotFound 工单不存在
+	ErrCategoryMembershipTicketNotFound = errors.New("ticket not found")
+)
+
+// categoryStaffRoles 可以加入分类的角色
+var categoryStaffRoles = []models.UserRole{models.RoleAgent, models.RoleSupervisor, models.RoleAdmin}
+
+// CategoryScope 坐席可访问的分类范围：所属分类、未分类的工单以及分配给自己的工单
+type CategoryScope struct {
+	UserID      uint
+	CategoryIDs []uint
+}
+
+type categoryScopeKey struct{}
+
+// WithCategoryScope 在请求上下文中附加分类范围，工单列表查询据此过滤；scope 为 nil 表示不限制
+func WithCategoryScope(ctx context.Context, scope *CategoryScope) context.Context {
+	if scope == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, categoryScopeKey{}, scope)
+}
+
+// CategoryScopeFrom 获取请求上下文中的分类范围，未设置时返回 nil
+func CategoryScopeFrom(ctx context.Context) *CategoryScope {
+	if ctx == nil {
+		return nil
+	}
+	scope, _ := ctx.Value(categoryScopeKey{}).(*CategoryScope)
+	return scope
+}
+
+// Apply 将分类范围应用到工单查询
+func (sc *CategoryScope) Apply(query *gorm.DB) *gorm.DB {
+	if sc == nil {
+		return query
+	}
+	if len(sc.CategoryIDs) == 0 {
+		return query.Where("(tickets.category_id IS NULL OR tickets.assigned_to_id = ?)", sc.UserID)
+	}
+	return query.Where("(tickets.category_id IS NULL OR tickets.category_id IN ? OR tickets.assigned_to_id = ?)", sc.CategoryIDs, sc.UserID)
+}
+
+// Allows 工单是否在分类范围内
+func (sc *CategoryScope) Allows(ticket *models.Ticket) bool {
+	if sc == nil || ticket.CategoryID == nil {
+		return true
+	}
+	if ticket.AssignedToID != nil && *ticket.AssignedToID == sc.UserID {
+		return true
+	}
+	for _, id := range sc.CategoryIDs {
+		if id == *ticket.CategoryID {
+			return true
+		}
+	}
+	return false
+}
+
+// Includes 分类是否在范围内
+func (sc *CategoryScope) Includes(categoryID uint) bool {
+	if sc == nil {
+		return true
+	}
+	for _, id := range sc.CategoryIDs {
+		if id == categoryID {
+			return true
+		}
+	}
+	return false
+}
+
+// CategoryMembershipService 坐席分类成员及分类权限
+type CategoryMembershipService struct {
+	db            *gorm.DB
+	configService *ConfigService
+}
+
+// NewCategoryMembershipService 创建分类成员服务
+func NewCategoryMembershipService(db *gorm.DB) *CategoryMembershipService {
+	return &CategoryMembershipService{
+		db:            db,
+		configService: NewConfigService(db),
+	}
+}
+
+// Enabled 是否开启分类权限
+func (s *CategoryMembershipService) Enabled() bool {
+	enabled, err := s.configService.GetConfigBool(KeyTicketCategoryScopeEnabled)
+	return err == nil && enabled
+}
+
+// ScopeFor 用户的分类范围。未开启分类权限或管理员、主管不受限制时返回 nil
+func (s *CategoryMembershipService) ScopeFor(ctx context.Context, userID uint, role string) (*CategoryScope, error) {
+	if role != string(models.RoleAgent) || !s.Enabled() {
+		return nil, nil
+	}
+	categoryIDs, err := s.categoryIDsOf(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &CategoryScope{UserID: userID, CategoryIDs: categoryIDs}, nil
+}
+
+// CheckTicketAccess 检查用户是否可以查看和处理工单
+func (s *CategoryMembershipService) CheckTicketAccess(ctx context.Context, ticket *models.Ticket, userID uint, role string) error {
+	scope, err := s.ScopeFor(ctx, userID, role)
+	if err != nil {
+		return err
+	}
+	if !scope.Allows(ticket) {
+		return ErrCategoryAccessDenied
+	}
+	return nil
+}
+
+// AuthorizeTicket 检查用户是否可以处理工单；assigneeID 非 0 时同时检查处理人是否属于工单所在分类。
+// 工单不存在时不报错，交由后续操作处理
+func (s *CategoryMembershipService) AuthorizeTicket(ctx context.Context, ticketID, userID uint, role string, assigneeID uint) error {
+	if !s.Enabled() {
+		return nil
+	}
+	tickets, err := s.loadTickets(ctx, []uint{ticketID})
+	if err != nil || len(tickets) == 0 {
+		return err
+	}
+	actor, assignee, err := s.scopes(ctx, userID, role, assigneeID)
+	if err != nil {
+		return err
+	}
+	return authorizeTicket(tickets[0], actor, assignee)
+}
+
+// AuthorizeCategory 检查用户是否属于分类，用于把工单转入该分类前的校验
+func (s *CategoryMembershipService) AuthorizeCategory(ctx context.Context, categoryID, userID uint, role string) error {
+	scope, err := s.ScopeFor(ctx, userID, role)
+	if err != nil {
+		return err
+	}
+	if !scope.Includes(categoryID) {
+		return ErrCategoryAccessDenied
+	}
+	return nil
+}
+
+// DeniedTickets 批量操作中无权处理的工单ID（用户无权访问或处理人不属于工单所在分类）
+func (s *CategoryMembershipService) DeniedTickets(ctx context.Context, ticketIDs []uint, userID uint, role string, assigneeID uint) ([]uint, error) {
+	if len(ticketIDs) == 0 || !s.Enabled() {
+		return nil, nil
+	}
+	tickets, err := s.loadTickets(ctx, ticketIDs)
+	if err != nil {
+		return nil, err
+	}
+	actor, assignee, err := s.scopes(ctx, userID, role, assigneeID)
+	if err != nil {
+		return nil, err
+	}
+	denied := make([]uint, 0)
+	for _, ticket := range tickets {
+		if authorizeTicket(ticket, actor, assignee) != nil {
+			denied = append(denied, ticket.ID)
+		}
+	}
+	return denied, nil
+}
+
+// scopes 操作人与处理人的分类范围，处理人只有坐席受分类限制
+func (s *CategoryMembershipService) scopes(ctx context.Context, userID uint, role string, assigneeID uint) (*CategoryScope, *CategoryScope, error) {
+	actor, err := s.ScopeFor(ctx, userID, role)
+	if err != nil || assigneeID == 0 {
+		return actor, nil, err
+	}
+	var assignee models.User
+	if err := s.db.WithContext(ctx).Select("id", "role").First(&assignee, assigneeID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return actor, nil, nil // 处理人是否存在由分配逻辑校验
+		}
+		return nil, nil, fmt.Errorf("failed to load assignee: %w", err)
+	}
+	if assignee.Role != models.RoleAgent {
+		return actor, nil, nil
+	}
+	categoryIDs, err := s.categoryIDsOf(ctx, assigneeID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return actor, &CategoryScope{UserID: assigneeID, CategoryIDs: categoryIDs}, nil
+}
+
+// loadTickets 按ID读取工单的分类和处理人
+func (s *CategoryMembershipService) loadTickets(ctx context.Context, ticketIDs []uint) ([]*models.Ticket, error) {
+	var tickets []*models.Ticket
+	if err := s.db.WithContext(ctx).Select("id", "category_id", "assigned_to_id").
+		Where("id IN ?", ticketIDs).Order("id").Find(&tickets).Error; err != nil {
+		return nil, fmt.Errorf("failed to load tickets: %w", err)
+	}
+	return tickets, nil
+}
+
+// authorizeTicket 按操作人和处理人的分类范围检查工单
+func authorizeTicket(ticket *models.Ticket, actor, assignee *CategoryScope) error {
+	if !actor.Allows(ticket) {
+		return ErrCategoryAccessDenied
+	}
+	if !assignee.Allows(ticket) {
+		return ErrAssigneeNotCategoryMember
+	}
+	return nil
+}
+
+// Assignees 可接手工单的处理人：管理员、主管，以及（开启分类权限且工单有分类时）该分类的坐席或（否则）全部坐席
+func (s *CategoryMembershipService) Assignees(ctx context.Context, ticketID uint) ([]*models.TicketAssignee, error) {
+	tickets, err := s.loadTickets(ctx, []uint{ticketID})
+	if err != nil {
+		return nil, err
+	}
+	if len(tickets) == 0 {
+		return nil, ErrCategoryMembershipTicketNotFound
+	}
+	ticket := tickets[0]
+
+	query := s.db.WithContext(ctx).Model(&models.User{}).Where("status = ?", models.UserStatusActive)
+	if ticket.CategoryID != nil && s.Enabled() {
+		query = query.Where("(role IN ? OR (role = ? AND id IN (?)))",
+			[]models.UserRole{models.RoleAdmin, models.RoleSupervisor}, models.RoleAgent,
+			s.db.Model(&models.CategoryMembership{}).Select("user_id").Where("category_id = ?", *ticket.CategoryID))
+	} else {
+		query = query.Where("role IN ?", categoryStaffRoles)
+	}
+
+	var users []*models.User
+	if err := query.Order("username ASC").Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("failed to list assignees: %w", err)
+	}
+	assignees := make([]*models.TicketAssignee, len(users))
+	for i, user := range users {
+		assignees[i] = &models.TicketAssignee{ID: user.ID, Username: user.Username, Name: user.GetFullName(), Email: user.Email, Role: user.Role}
+	}
+	return assignees, nil
+}
+
+// List 分类成员列表，可按用户或分类过滤
+func (s *CategoryMembershipService) List(ctx context.Context, userID, categoryID uint) ([]*models.CategoryMembership, error) {
+	query := s.db.WithContext(ctx).Preload("User").Preload("Category")
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+	if categoryID != 0 {
+		query = query.Where("category_id = ?", categoryID)
+	}
+	var memberships []*models.CategoryMembership
+	if err := query.Order("category_id ASC, user_id ASC").Find(&memberships).Error; err != nil {
+		return nil, fmt.Errorf("failed to list category memberships: %w", err)
+	}
+	return memberships, nil
+}
+
+// Bulk 批量添加或移除用户与分类的所属关系，已存在或不存在的关系跳过
+func (s *CategoryMembershipService) Bulk(ctx context.Context, req *models.CategoryMembershipBulkRequest, operatorID uint) (*models.CategoryMembershipBulkResult, error) {
+	userIDs, categoryIDs := uniqueUints(req.UserIDs), uniqueUints(req.CategoryIDs)
+	result := &models.CategoryMembershipBulkResult{}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if req.Action == models.CategoryMembershipRemove {
+			deleted := tx.Where("user_id IN ? AND category_id IN ?", userIDs, categoryIDs).Delete(&models.CategoryMembership{})
+			if deleted.Error != nil {
+				return fmt.Errorf("failed to remove category memberships: %w", deleted.Error)
+			}
+			result.Removed = int(deleted.RowsAffected)
+			return nil
+		}
+
+		if err := validateMembershipTargets(tx, userIDs, categoryIDs); err != nil {
+			return err
+		}
+		for _, userID := range userIDs {
+			added, err := addMemberships(tx, userID, categoryIDs, operatorID)
+			if err != nil {
+				return err
+			}
+			result.Added += added
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Replace 替换用户所属的全部分类
+func (s *CategoryMembershipService) Replace(ctx context.Context, userID uint, categoryIDs []uint, operatorID uint) (*models.CategoryMembershipBulkResult, error) {
+	categoryIDs = uniqueUints(categoryIDs)
+	result := &models.CategoryMembershipBulkResult{}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := validateMembershipTargets(tx, []uint{userID}, categoryIDs); err != nil {
+			return err
+		}
+		stale := tx.Where("user_id = ?", userID)
+		if len(categoryIDs) > 0 {
+			stale = stale.Where("category_id NOT IN ?", categoryIDs)
+		}
+		deleted := stale.Delete(&models.CategoryMembership{})
+		if deleted.Error != nil {
+			return fmt.Errorf("failed to remove category memberships: %w", deleted.Error)
+		}
+		result.Removed = int(deleted.RowsAffected)
+
+		added, err := addMemberships(tx, userID, categoryIDs, operatorID)
+		result.Added = added
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// categoryIDsOf 用户所属的分类ID
+func (s *CategoryMembershipService) categoryIDsOf(ctx context.Context, userID uint) ([]uint, error) {
+	var categoryIDs []uint
+	if err := s.db.WithContext(ctx).Model(&models.CategoryMembership{}).
+		Where("user_id = ?", userID).Order("category_id").
+		Pluck("category_id", &categoryIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to load category memberships: %w", err)
+	}
+	return categoryIDs, nil
+}
+
+// validateMembershipTargets 用户须为坐席、主管或管理员，分类须存在
+func validateMembershipTargets(tx *gorm.DB, userIDs, categoryIDs []uint) error {
+	var users int64
+	if err := tx.Model(&models.User{}).Where("id IN ? AND role IN ?", userIDs, categoryStaffRoles).Count(&users).Error; err != nil {
+		return fmt.Errorf("failed to check users: %w", err)
+	}
+	if int(users) != len(userIDs) {
+		return ErrCategoryMembershipUserInvalid
+	}
+	if len(categoryIDs) == 0 {
+		return nil
+	}
+	var categories int64
+	if err := tx.Model(&models.Category{}).Where("id IN ? AND deleted_at IS NULL", categoryIDs).Count(&categories).Error; err != nil {
+		return fmt.Errorf("failed to check categories: %w", err)
+	}
+	if int(categories) != len(categoryIDs) {
+		return ErrCategoryMembershipCategoryNotFound
+	}
+	return nil
+}
+
+// addMemberships 添加用户与分类的所属关系，返回新增数量
+func addMemberships(tx *gorm.DB, userID uint, categoryIDs []uint, operatorID uint) (int, error) {
+	if len(categoryIDs) == 0 {
+		return 0, nil
+	}
+	memberships := make([]models.CategoryMembership, len(categoryIDs))
+	for i, categoryID := range categoryIDs {
+		memberships[i] = models.CategoryMembership{UserID: userID, CategoryID: categoryID, CreatedByID: operatorID}
+	}
+	created := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&memberships)
+	if created.Error != nil {
+		return 0, fmt.Errorf("failed to add category memberships: %w", created.Error)
+	}
+	return int(created.RowsAffected), nil
+}
+
+// uniqueUints 去重并忽略 0
+func uniqueUints(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	result := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if id != 0 && !seen[id] {
+			seen[id] = true
+			result = append(result, id)
+		}
+	}
+	return result
+}
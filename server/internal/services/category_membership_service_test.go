@@ -0,0 +1,141 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gongdan-system/internal/models"
+)
+
+func TestCategoryMembership_ScopesTicketsAndAssignees(t *testing.T) {
+	db := newTestDB(t, &models.User{}, &models.Team{}, &models.Category{}, &models.Ticket{}, &models.TicketComment{},
+		&models.TicketHistory{}, &models.SystemConfig{}, &models.CategoryMembership{})
+	ctx := context.Background()
+	svc := NewCategoryMembershipService(db)
+
+	admin := models.User{Username: "cm-admin", Email: "cm-admin@example.com", PasswordHash: "x", Role: models.RoleAdmin, Status: models.UserStatusActive}
+	network := models.User{Username: "cm-network", Email: "cm-network@example.com", PasswordHash: "x", Role: models.RoleAgent, Status: models.UserStatusActive}
+	billing := models.User{Username: "cm-billing", Email: "cm-billing@example.com", PasswordHash: "x", Role: models.RoleAgent, Status: models.UserStatusActive}
+	customer := models.User{Username: "cm-customer", Email: "cm-customer@example.com", PasswordHash: "x", Role: models.RoleCustomer, Status: models.UserStatusActive}
+	for _, user := range []*models.User{&admin, &network, &billing, &customer} {
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("failed to seed user: %v", err)
+		}
+	}
+	networking := models.Category{Name: "网络", Slug: "cm-network", Type: models.CategoryTypeTechnical, Status: models.CategoryStatusActive, CreatedBy: admin.ID}
+	accounts := models.Category{Name: "账务", Slug: "cm-billing", Type: models.CategoryTypeGeneral, Status: models.CategoryStatusActive, CreatedBy: admin.ID}
+	db.Create(&networking)
+	db.Create(&accounts)
+
+	newTicket := func(number string, categoryID, assigneeID *uint) *models.Ticket {
+		ticket := models.Ticket{TicketNumber: number, Title: number, Status: models.TicketStatusOpen, Priority: models.TicketPriorityNormal,
+			Type: models.TicketTypeIncident, Source: models.TicketSourceWeb, CreatedByID: customer.ID, CategoryID: categoryID, AssignedToID: assigneeID}
+		if err := db.Create(&ticket).Error; err != nil {
+			t.Fatalf("failed to seed ticket: %v", err)
+		}
+		return &ticket
+	}
+	vpn := newTicket("CM-VPN", &networking.ID, nil)
+	invoice := newTicket("CM-INVOICE", &accounts.ID, nil)
+	handedOver := newTicket("CM-HANDOVER", &accounts.ID, &network.ID)
+	newTicket("CM-GENERAL", nil, nil)
+
+	// 成员维护：客户不能加入分类，重复添加跳过
+	if _, err := svc.Bulk(ctx, &models.CategoryMembershipBulkRequest{Action: models.CategoryMembershipAdd,
+		UserIDs: []uint{customer.ID}, CategoryIDs: []uint{networking.ID}}, admin.ID); !errors.Is(err, ErrCategoryMembershipUserInvalid) {
+		t.Fatalf("expected customer to be rejected, got %v", err)
+	}
+	if _, err := svc.Bulk(ctx, &models.CategoryMembershipBulkRequest{Action: models.CategoryMembershipAdd,
+		UserIDs: []uint{network.ID}, CategoryIDs: []uint{9999}}, admin.ID); !errors.Is(err, ErrCategoryMembershipCategoryNotFound) {
+		t.Fatalf("expected unknown category to be rejected, got %v", err)
+	}
+	result, err := svc.Bulk(ctx, &models.CategoryMembershipBulkRequest{Action: models.CategoryMembershipAdd,
+		UserIDs: []uint{network.ID, billing.ID}, CategoryIDs: []uint{networking.ID, accounts.ID}}, admin.ID)
+	if err != nil || result.Added != 4 {
+		t.Fatalf("expected 4 memberships, got %+v (%v)", result, err)
+	}
+	if result, err = svc.Replace(ctx, network.ID, []uint{networking.ID, networking.ID}, admin.ID); err != nil || result.Added != 0 || result.Removed != 1 {
+		t.Fatalf("expected replace to drop billing membership, got %+v (%v)", result, err)
+	}
+	if result, err = svc.Bulk(ctx, &models.CategoryMembershipBulkRequest{Action: models.CategoryMembershipRemove,
+		UserIDs: []uint{billing.ID}, CategoryIDs: []uint{networking.ID}}, admin.ID); err != nil || result.Removed != 1 {
+		t.Fatalf("expected one membership removed, got %+v (%v)", result, err)
+	}
+	if memberships, _ := svc.List(ctx, 0, accounts.ID); len(memberships) != 1 || memberships[0].UserID != billing.ID {
+		t.Fatalf("unexpected billing members %+v", memberships)
+	}
+
+	// 未开启分类权限时不限制
+	if scope, err := svc.ScopeFor(ctx, network.ID, string(models.RoleAgent)); err != nil || scope != nil {
+		t.Fatalf("expected no scope while disabled, got %+v (%v)", scope, err)
+	}
+	if err := svc.AuthorizeTicket(ctx, invoice.ID, network.ID, string(models.RoleAgent), network.ID); err != nil {
+		t.Fatalf("expected no restriction while disabled, got %v", err)
+	}
+
+	if err := NewConfigService(db).SetConfig(KeyTicketCategoryScopeEnabled, "true", "bool", "", CategoryTicket, "workflow"); err != nil {
+		t.Fatalf("failed to enable category scope: %v", err)
+	}
+
+	// 列表：所属分类、未分类以及分配给自己的工单
+	scope, err := svc.ScopeFor(ctx, network.ID, string(models.RoleAgent))
+	if err != nil || scope == nil {
+		t.Fatalf("expected agent scope, got %+v (%v)", scope, err)
+	}
+	tickets, total, err := NewTicketService(db).GetTickets(WithCategoryScope(ctx, scope), TicketFilters{Page: 1, Limit: 20})
+	if err != nil || total != 3 {
+		t.Fatalf("expected 3 visible tickets, got %d (%v)", total, err)
+	}
+	for _, ticket := range tickets {
+		if ticket.ID == invoice.ID {
+			t.Fatalf("expected billing ticket to be hidden")
+		}
+	}
+	if scope, _ := svc.ScopeFor(ctx, admin.ID, string(models.RoleAdmin)); scope != nil {
+		t.Fatalf("expected admin to be unrestricted")
+	}
+
+	// 单个工单与分配
+	if err := svc.AuthorizeTicket(ctx, invoice.ID, network.ID, string(models.RoleAgent), 0); !errors.Is(err, ErrCategoryAccessDenied) {
+		t.Fatalf("expected access to be denied, got %v", err)
+	}
+	if err := svc.AuthorizeTicket(ctx, handedOver.ID, network.ID, string(models.RoleAgent), 0); err != nil {
+		t.Fatalf("expected assignee to keep access, got %v", err)
+	}
+	if err := svc.AuthorizeTicket(ctx, vpn.ID, admin.ID, string(models.RoleAdmin), billing.ID); !errors.Is(err, ErrAssigneeNotCategoryMember) {
+		t.Fatalf("expected non-member assignee to be rejected, got %v", err)
+	}
+	if err := svc.AuthorizeTicket(ctx, vpn.ID, network.ID, string(models.RoleAgent), admin.ID); err != nil {
+		t.Fatalf("expected admin assignee to be allowed, got %v", err)
+	}
+	if err := svc.AuthorizeTicket(ctx, 9999, network.ID, string(models.RoleAgent), 0); err != nil {
+		t.Fatalf("expected missing ticket to be left to the caller, got %v", err)
+	}
+	if err := svc.AuthorizeCategory(ctx, accounts.ID, network.ID, string(models.RoleAgent)); !errors.Is(err, ErrCategoryAccessDenied) {
+		t.Fatalf("expected non-member category to be denied, got %v", err)
+	}
+	if err := svc.AuthorizeCategory(ctx, networking.ID, network.ID, string(models.RoleAgent)); err != nil {
+		t.Fatalf("expected member category to be allowed, got %v", err)
+	}
+	denied, err := svc.DeniedTickets(ctx, []uint{vpn.ID, invoice.ID, handedOver.ID}, network.ID, string(models.RoleAgent), 0)
+	if err != nil || len(denied) != 1 || denied[0] != invoice.ID {
+		t.Fatalf("expected only billing ticket denied, got %v (%v)", denied, err)
+	}
+
+	// 分配候选人：工单所在分类的坐席及管理员
+	assignees, err := svc.Assignees(ctx, vpn.ID)
+	if err != nil {
+		t.Fatalf("list assignees failed: %v", err)
+	}
+	names := map[string]bool{}
+	for _, assignee := range assignees {
+		names[assignee.Username] = true
+	}
+	if len(assignees) != 2 || !names["cm-admin"] || !names["cm-network"] {
+		t.Fatalf("unexpected assignees %+v", names)
+	}
+	if _, err := svc.Assignees(ctx, 9999); !errors.Is(err, ErrCategoryMembershipTicketNotFound) {
+		t.Fatalf("expected missing ticket, got %v", err)
+	}
+}
@@ -0,0 +1,378 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+// KeyTicketCategoryTransferPolicy 工单转移分类策略配置键
+const KeyTicketCategoryTransferPolicy = "ticket.category_transfer_policy"
+
+var (
+	// ErrCategoryTransferUnchanged 目标分类与工单当前分类相同
+	ErrCategoryTransferUnchanged = errors.New("ticket is already in the target category")
+	// ErrCategoryTransferClosed 已关闭的工单不能转移分类
+	ErrCategoryTransferClosed = errors.New("closed tickets cannot be transferred to another category")
+	// ErrCategoryNotActive 目标分类不存在或未启用
+	ErrCategoryNotActive = errors.New("category not found or inactive")
+	// ErrInvalidSubcategory 子分类不属于目标分类
+	ErrInvalidSubcategory = errors.New("subcategory does not belong to the target category")
+)
+
+// CategoryTransferResult 转移分类结果
+type CategoryTransferResult struct {
+	Ticket           *models.Ticket `json:"ticket"`
+	OldSLADueDate    *time.Time     `json:"old_sla_due_date"`
+	NewSLADueDate    *time.Time     `json:"new_sla_due_date"`
+	SLASource        string         `json:"sla_source"` // 新截止时间的来源说明，未找到适用SLA时为空
+	ClockStart       time.Time      `json:"clock_start"`
+	AutoAssignedToID *uint          `json:"auto_assigned_to_id,omitempty"`
+}
+
+// CategoryTransferService 工单转移分类服务：按新分类重算SLA、执行分类自动分配并触发 category.changed 规则
+type CategoryTransferService struct {
+	db                *gorm.DB
+	automationService *AutomationService
+	delegationService *DelegationService
+}
+
+// NewCategoryTransferService 创建工单转移分类服务
+func NewCategoryTransferService(db *gorm.DB) *CategoryTransferService {
+	return &CategoryTransferService{
+		db:                db,
+		automationService: NewAutomationService(db),
+		delegationService: NewDelegationService(db),
+	}
+}
+
+// GetPolicy 获取分类转移策略
+func (s *CategoryTransferService) GetPolicy(ctx context.Context) (*models.CategoryTransferPolicy, error) {
+	var config models.SystemConfig
+	err := s.db.WithContext(ctx).
+		Where("key = ? AND is_active = ?", KeyTicketCategoryTransferPolicy, true).
+		First(&config).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.GetDefaultCategoryTransferPolicy(), nil
+		}
+		return nil, fmt.Errorf("failed to get category transfer policy: %w", err)
+	}
+
+	policy := &models.CategoryTransferPolicy{}
+	if err := config.GetJSONValue(policy); err != nil {
+		log.Printf("Warning: failed to parse category transfer policy, using defaults: %v", err)
+		return models.GetDefaultCategoryTransferPolicy(), nil
+	}
+	if err := policy.Validate(); err != nil {
+		log.Printf("Warning: invalid category transfer policy, using defaults: %v", err)
+		return models.GetDefaultCategoryTransferPolicy(), nil
+	}
+	return policy, nil
+}
+
+// SetPolicy 保存分类转移策略
+func (s *CategoryTransferService) SetPolicy(ctx context.Context, policy *models.CategoryTransferPolicy, userID uint) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	var existing models.SystemConfig
+	err := s.db.WithContext(ctx).Where("key = ?", KeyTicketCategoryTransferPolicy).First(&existing).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to check existing policy: %w", err)
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		config := models.SystemConfig{
+			Key:         KeyTicketCategoryTransferPolicy,
+			Category:    CategoryTicket,
+			Group:       "workflow",
+			Description: "工单转移分类时的SLA重算与自动分配策略",
+			IsActive:    true,
+			UpdatedBy:   &userID,
+		}
+		if err := config.SetValue(policy); err != nil {
+			return fmt.Errorf("failed to set policy value: %w", err)
+		}
+		if err := s.db.WithContext(ctx).Create(&config).Error; err != nil {
+			return fmt.Errorf("failed to create policy: %w", err)
+		}
+		return nil
+	}
+
+	if err := existing.SetValue(policy); err != nil {
+		return fmt.Errorf("failed to set policy value: %w", err)
+	}
+	existing.UpdatedBy = &userID
+	existing.Version++
+
+	if err := s.db.WithContext(ctx).Save(&existing).Error; err != nil {
+		return fmt.Errorf("failed to update policy: %w", err)
+	}
+	return nil
+}
+
+// loadActiveCategory 获取启用状态的分类
+func (s *CategoryTransferService) loadActiveCategory(ctx context.Context, id uint) (*models.Category, error) {
+	var category models.Category
+	err := s.db.WithContext(ctx).
+		Where("id = ? AND status = ? AND deleted_at IS NULL", id, models.CategoryStatusActive).
+		First(&category).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCategoryNotActive
+		}
+		return nil, fmt.Errorf("failed to get category: %w", err)
+	}
+	return &category, nil
+}
+
+// recalculateSLA 按新分类计算解决截止时间：客户SLA合同优先，其次是子分类或分类配置的 SLA 小时数，
+// 否则按工单类型/优先级匹配 SLA 配置。clockStart 为计时起点
+func (s *CategoryTransferService) recalculateSLA(ctx context.Context, ticket *models.Ticket, category, subcategory *models.Category, clockStart time.Time) (*time.Time, string) {
+	contract, err := s.automationService.GetSLAContractForTicket(ctx, ticket)
+	if err != nil {
+		log.Printf("Failed to get SLA contract for ticket %d: %v", ticket.ID, err)
+	}
+	if contract == nil {
+		for _, c := range []*models.Category{subcategory, category} {
+			if c != nil && c.SLAHours != nil && *c.SLAHours > 0 {
+				due := clockStart.Add(time.Duration(*c.SLAHours) * time.Hour)
+				return &due, fmt.Sprintf("分类「%s」SLA %d 小时", c.Name, *c.SLAHours)
+			}
+		}
+	}
+
+	config, err := s.automationService.GetSLAConfigForTicket(ctx, ticket)
+	if err != nil {
+		return nil, ""
+	}
+	clocked := *ticket
+	clocked.CreatedAt = clockStart
+	_, resolution, err := s.automationService.CalculateSLADeadlines(ctx, &clocked, config)
+	if err != nil {
+		log.Printf("Failed to calculate SLA deadlines for ticket %d: %v", ticket.ID, err)
+		return nil, ""
+	}
+	if config.ContractID != nil {
+		return &resolution, fmt.Sprintf("SLA合同「%s」", config.Name)
+	}
+	return &resolution, fmt.Sprintf("SLA配置「%s」", config.Name)
+}
+
+// TransferCategory 将工单转移到新分类：重算SLA截止时间（按策略从转移时刻或创建时间计时），
+// 按新分类的自动分配人分配工单，记录历史后触发 category.changed 自动化规则
+func (s *CategoryTransferService) TransferCategory(ctx context.Context, ticketID uint, req *models.TicketCategoryTransferRequest, userID uint) (*CategoryTransferResult, error) {
+	policy, err := s.GetPolicy(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var ticket models.Ticket
+	if err := s.db.WithContext(ctx).Where("deleted_at IS NULL").First(&ticket, ticketID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: %d", ErrTicketNotFound, ticketID)
+		}
+		return nil, fmt.Errorf("failed to get ticket: %w", err)
+	}
+	if ticket.Status == models.TicketStatusClosed {
+		return nil, ErrCategoryTransferClosed
+	}
+	if sameUintPtr(ticket.CategoryID, &req.CategoryID) && sameUintPtr(ticket.SubcategoryID, req.SubcategoryID) {
+		return nil, ErrCategoryTransferUnchanged
+	}
+
+	category, err := s.loadActiveCategory(ctx, req.CategoryID)
+	if err != nil {
+		return nil, err
+	}
+	var subcategory *models.Category
+	if req.SubcategoryID != nil {
+		if subcategory, err = s.loadActiveCategory(ctx, *req.SubcategoryID); err != nil {
+			return nil, err
+		}
+		if subcategory.ParentID == nil || *subcategory.ParentID != category.ID {
+			return nil, ErrInvalidSubcategory
+		}
+	}
+
+	now := time.Now()
+	oldCategoryID, oldSubcategoryID := ticket.CategoryID, ticket.SubcategoryID
+	oldAssigneeID := ticket.AssignedToID
+	oldDue := ticket.SLADueDate
+
+	clockStart := now
+	if policy.KeepOriginalClock {
+		clockStart = ticket.CreatedAt
+	}
+	ticket.CategoryID = &category.ID
+	ticket.SubcategoryID = req.SubcategoryID
+	newDue, slaSource := s.recalculateSLA(ctx, &ticket, category, subcategory, clockStart)
+	if newDue == nil {
+		newDue = oldDue
+	}
+
+	// 分类自动分配：子分类配置优先，默认只处理未分配的工单
+	var route *DelegationRoute
+	autoAssignID := category.AutoAssignUserID
+	if subcategory != nil && subcategory.AutoAssignUserID != nil {
+		autoAssignID = subcategory.AutoAssignUserID
+	}
+	if autoAssignID != nil && !sameUintPtr(ticket.AssignedToID, autoAssignID) &&
+		(ticket.AssignedToID == nil || policy.ReassignAssigned) {
+		if route, err = s.delegationService.Route(ctx, *autoAssignID, now); err != nil {
+			return nil, err
+		}
+		ticket.AssignedToID = autoAssignID
+		if route != nil {
+			ticket.AssignedToID = route.UserID
+			if route.TeamID != nil {
+				ticket.AssignedTeamID = route.TeamID
+			}
+		}
+	} else {
+		autoAssignID = nil
+	}
+
+	updates := map[string]interface{}{
+		"category_id":      ticket.CategoryID,
+		"subcategory_id":   ticket.SubcategoryID,
+		"sla_due_date":     newDue,
+		"sla_breached":     newDue != nil && newDue.Before(now),
+		"assigned_to_id":   ticket.AssignedToID,
+		"assigned_team_id": ticket.AssignedTeamID,
+		"updated_at":       now,
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Ticket{}).Where("id = ?", ticket.ID).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to transfer ticket category: %w", err)
+		}
+
+		description := fmt.Sprintf("工单从分类 ID: %s 转移到分类「%s」", formatOptionalID(oldCategoryID), category.Name)
+		if subcategory != nil {
+			description += fmt.Sprintf(" / 「%s」", subcategory.Name)
+		}
+		if req.Comment != "" {
+			description += fmt.Sprintf(" - %s", req.Comment)
+		}
+		histories := []*models.TicketHistory{{
+			TicketID:    ticket.ID,
+			UserID:      &userID,
+			Action:      models.HistoryActionTransfer,
+			Description: description,
+			FieldName:   "category_id",
+			OldValue:    formatOptionalID(oldCategoryID),
+			NewValue:    fmt.Sprintf("%d", category.ID),
+			IsVisible:   true,
+			IsImportant: true,
+		}}
+		if !sameUintPtr(oldSubcategoryID, ticket.SubcategoryID) {
+			histories = append(histories, &models.TicketHistory{
+				TicketID:    ticket.ID,
+				UserID:      &userID,
+				Action:      models.HistoryActionUpdate,
+				Description: "子分类随分类转移更新",
+				FieldName:   "subcategory_id",
+				OldValue:    formatOptionalID(oldSubcategoryID),
+				NewValue:    formatOptionalID(ticket.SubcategoryID),
+				IsVisible:   true,
+			})
+		}
+
+		slaDescription := "未找到适用的SLA配置，保留原SLA截止时间"
+		if slaSource != "" {
+			clock := "从转移时刻重新计时"
+			if policy.KeepOriginalClock {
+				clock = "沿用工单创建时间计时"
+			}
+			slaDescription = fmt.Sprintf("分类转移后按%s重算SLA截止时间（%s）", slaSource, clock)
+		}
+		histories = append(histories, &models.TicketHistory{
+			TicketID:    ticket.ID,
+			UserID:      &userID,
+			Action:      models.HistoryActionSystem,
+			Description: slaDescription,
+			FieldName:   "sla_due_date",
+			OldValue:    formatHistoryTime(oldDue),
+			NewValue:    formatHistoryTime(newDue),
+			IsVisible:   true,
+			IsSystem:    true,
+			IsAutomated: true,
+		})
+
+		if autoAssignID != nil {
+			assignDescription := fmt.Sprintf("按分类「%s」的自动分配设置分配给用户 ID: %d", category.Name, *autoAssignID)
+			if subcategory != nil && subcategory.AutoAssignUserID != nil {
+				assignDescription = fmt.Sprintf("按子分类「%s」的自动分配设置分配给用户 ID: %d", subcategory.Name, *autoAssignID)
+			}
+			if route != nil {
+				assignDescription += "；" + route.Description(*autoAssignID)
+			}
+			histories = append(histories, &models.TicketHistory{
+				TicketID:    ticket.ID,
+				UserID:      &userID,
+				Action:      models.HistoryActionAssign,
+				Description: assignDescription,
+				FieldName:   "assigned_to_id",
+				OldValue:    getAssigneeValue(oldAssigneeID),
+				NewValue:    getAssigneeValue(ticket.AssignedToID),
+				IsVisible:   true,
+				IsAutomated: true,
+			})
+		}
+		return tx.Create(&histories).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	ticket.SLADueDate = newDue
+	ticket.SLABreached = updates["sla_breached"].(bool)
+	if err := s.automationService.ExecuteRules(ctx, models.TriggerCategoryChanged, &ticket); err != nil {
+		log.Printf("Failed to run category.changed rules for ticket %d: %v", ticket.ID, err)
+	}
+
+	// 规则可能已修改工单，返回最新状态
+	if err := s.db.WithContext(ctx).First(&ticket, ticket.ID).Error; err != nil {
+		return nil, fmt.Errorf("failed to reload ticket: %w", err)
+	}
+	return &CategoryTransferResult{
+		Ticket:           &ticket,
+		OldSLADueDate:    oldDue,
+		NewSLADueDate:    newDue,
+		SLASource:        slaSource,
+		ClockStart:       clockStart,
+		AutoAssignedToID: autoAssignID,
+	}, nil
+}
+
+// formatHistoryTime 格式化历史记录中的时间值，空值返回空字符串
+func formatHistoryTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// formatOptionalID 格式化历史记录中的可空ID，空值返回空字符串
+func formatOptionalID(id *uint) string {
+	if id == nil {
+		return ""
+	}
+	return fmt.Sprintf("%d", *id)
+}
+
+// sameUintPtr 比较两个可空ID是否相同
+func sameUintPtr(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
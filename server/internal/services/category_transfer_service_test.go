@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestCategoryTransfer_RecalculatesSLAAndAutoAssigns(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:category_transfer_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Team{}, &models.Category{}, &models.Ticket{}, &models.TicketHistory{},
+		&models.SystemConfig{}, &models.AutomationRule{}, &models.AutomationRuleRevision{}, &models.AutomationLog{}, &models.SLAConfig{},
+		&models.AssignmentDelegation{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+
+	ctx := context.Background()
+	svc := NewCategoryTransferService(db)
+
+	agent := models.User{Username: "ct-agent", Email: "ct-agent@example.com", PasswordHash: "x", Role: models.RoleAgent}
+	network := models.User{Username: "ct-network", Email: "ct-network@example.com", PasswordHash: "x", Role: models.RoleAgent}
+	db.Create(&agent)
+	db.Create(&network)
+
+	slaHours := 4
+	general := models.Category{Name: "通用", Slug: "ct-general", Type: models.CategoryTypeGeneral, Status: models.CategoryStatusActive, CreatedBy: agent.ID}
+	networking := models.Category{Name: "网络", Slug: "ct-network", Type: models.CategoryTypeTechnical, Status: models.CategoryStatusActive,
+		SLAHours: &slaHours, AutoAssignUserID: &network.ID, CreatedBy: agent.ID}
+	db.Create(&general)
+	db.Create(&networking)
+
+	rule, err := NewAutomationService(db).CreateRule(ctx, &models.AutomationRuleRequest{
+		Name:         "网络工单提升优先级",
+		RuleType:     "priority",
+		TriggerEvent: models.TriggerCategoryChanged,
+		Conditions:   []models.RuleCondition{{Field: "category_id", Operator: "eq", Value: float64(networking.ID)}},
+		Actions:      []models.RuleAction{{Type: "set_priority", Params: map[string]interface{}{"priority": "high"}}},
+	}, agent.ID)
+	if err != nil {
+		t.Fatalf("create rule failed: %v", err)
+	}
+
+	created := time.Now().Add(-48 * time.Hour)
+	ticket := models.Ticket{TicketNumber: "CT-001", Title: "VPN 无法连接", Status: models.TicketStatusOpen, Priority: models.TicketPriorityNormal,
+		Type: models.TicketTypeIncident, Source: models.TicketSourceWeb, CreatedByID: agent.ID, CategoryID: &general.ID}
+	if err := db.Create(&ticket).Error; err != nil {
+		t.Fatalf("failed to seed ticket: %v", err)
+	}
+	db.Model(&ticket).Update("created_at", created)
+
+	if _, err := svc.TransferCategory(ctx, ticket.ID, &models.TicketCategoryTransferRequest{CategoryID: general.ID}, agent.ID); !errors.Is(err, ErrCategoryTransferUnchanged) {
+		t.Fatalf("expected unchanged category to be rejected, got %v", err)
+	}
+
+	// 默认从转移时刻重新计时，并分配给新分类的自动分配人
+	before := time.Now()
+	result, err := svc.TransferCategory(ctx, ticket.ID, &models.TicketCategoryTransferRequest{CategoryID: networking.ID, Comment: "网络问题"}, agent.ID)
+	if err != nil {
+		t.Fatalf("transfer failed: %v", err)
+	}
+	if result.NewSLADueDate == nil || result.NewSLADueDate.Before(before.Add(4*time.Hour)) {
+		t.Fatalf("expected SLA to restart from transfer time, got %v", result.NewSLADueDate)
+	}
+	if result.Ticket.SLABreached {
+		t.Fatalf("expected restarted SLA not to be breached")
+	}
+	if result.Ticket.AssignedToID == nil || *result.Ticket.AssignedToID != network.ID {
+		t.Fatalf("expected auto-assignment to category owner, got %v", result.Ticket.AssignedToID)
+	}
+	if result.Ticket.Priority != models.TicketPriorityHigh {
+		t.Fatalf("expected category.changed rule %d to raise priority, got %s", rule.ID, result.Ticket.Priority)
+	}
+
+	var histories []models.TicketHistory
+	db.Where("ticket_id = ?", ticket.ID).Order("id").Find(&histories)
+	fields := map[string]bool{}
+	for _, h := range histories {
+		fields[h.FieldName] = true
+	}
+	if !fields["category_id"] || !fields["sla_due_date"] || !fields["assigned_to_id"] {
+		t.Fatalf("expected category, SLA and assignment history, got %+v", fields)
+	}
+
+	// 保留原始计时：按创建时间重算，超过 4 小时即判定违约；已分配工单默认不改派
+	if err := svc.SetPolicy(ctx, &models.CategoryTransferPolicy{KeepOriginalClock: true}, agent.ID); err != nil {
+		t.Fatalf("set policy failed: %v", err)
+	}
+	db.Model(&models.Ticket{}).Where("id = ?", ticket.ID).Update("assigned_to_id", agent.ID)
+	if _, err := svc.TransferCategory(ctx, ticket.ID, &models.TicketCategoryTransferRequest{CategoryID: general.ID}, agent.ID); err != nil {
+		t.Fatalf("transfer back failed: %v", err)
+	}
+	result, err = svc.TransferCategory(ctx, ticket.ID, &models.TicketCategoryTransferRequest{CategoryID: networking.ID}, agent.ID)
+	if err != nil {
+		t.Fatalf("transfer with original clock failed: %v", err)
+	}
+	if result.NewSLADueDate == nil || !result.NewSLADueDate.Before(time.Now()) || !result.Ticket.SLABreached {
+		t.Fatalf("expected original clock to yield a breached SLA, got %v", result.NewSLADueDate)
+	}
+	if result.AutoAssignedToID != nil || *result.Ticket.AssignedToID != agent.ID {
+		t.Fatalf("expected assigned ticket to keep its assignee, got %v", result.Ticket.AssignedToID)
+	}
+}
@@ -0,0 +1,598 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+// KeyChatIntegration 聊天平台建单集成配置键
+const KeyChatIntegration = "integration.chat"
+
+const (
+	chatTitleMaxRunes = 120
+	// slackSignatureMaxAge Slack 请求时间戳允许的最大偏差，防止重放
+	slackSignatureMaxAge = 5 * time.Minute
+)
+
+var (
+	// ErrChatPlatformDisabled 聊天平台建单未启用
+	ErrChatPlatformDisabled = errors.New("chat platform intake is disabled")
+	// ErrChatSignatureInvalid 请求签名或密钥校验失败
+	ErrChatSignatureInvalid = errors.New("invalid chat request signature")
+	// ErrChatUserNotMapped 聊天账号未关联系统用户
+	ErrChatUserNotMapped = errors.New("chat user is not mapped to an active account")
+	// ErrChatTicketTextEmpty 建单命令缺少问题描述
+	ErrChatTicketTextEmpty = errors.New("ticket description is required")
+	// ErrChatUserMappingNotFound 聊天账号映射不存在
+	ErrChatUserMappingNotFound = errors.New("chat user mapping not found")
+)
+
+// loadChatIntegrationConfig 读取聊天建单配置，未配置或解析失败时返回默认配置
+func loadChatIntegrationConfig(ctx context.Context, db *gorm.DB) (*models.ChatIntegrationConfig, error) {
+	var config models.SystemConfig
+	err := db.WithContext(ctx).
+		Where("key = ? AND is_active = ?", KeyChatIntegration, true).
+		First(&config).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.GetDefaultChatIntegrationConfig(), nil
+		}
+		return nil, fmt.Errorf("failed to get chat integration config: %w", err)
+	}
+
+	chat := models.GetDefaultChatIntegrationConfig()
+	if err := config.GetJSONValue(chat); err != nil {
+		log.Printf("Warning: failed to parse chat integration config, using defaults: %v", err)
+		return models.GetDefaultChatIntegrationConfig(), nil
+	}
+	return chat, nil
+}
+
+// ChatNotifier 向 Slack/Telegram 发送消息，并把工单状态更新回复到来源会话
+type ChatNotifier struct {
+	db              *gorm.DB
+	client          *http.Client
+	slackAPIBase    string
+	telegramAPIBase string
+}
+
+// NewChatNotifier 创建聊天消息发送器
+func NewChatNotifier(db *gorm.DB) *ChatNotifier {
+	return &ChatNotifier{
+		db:              db,
+		client:          &http.Client{Timeout: 10 * time.Second},
+		slackAPIBase:    "https://slack.com/api",
+		telegramAPIBase: "https://api.telegram.org",
+	}
+}
+
+// postJSON 调用聊天平台接口并解析响应
+func (n *ChatNotifier) postJSON(ctx context.Context, url, bearer string, payload interface{}, result interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("unexpected response (HTTP %d): %w", resp.StatusCode, err)
+	}
+	return nil
+}
+
+// postSlack 发送 Slack 消息，threadTS 非空时回复到线程，返回消息 ts
+func (n *ChatNotifier) postSlack(ctx context.Context, token, channel, threadTS, text string) (string, error) {
+	payload := map[string]interface{}{"channel": channel, "text": text}
+	if threadTS != "" {
+		payload["thread_ts"] = threadTS
+	}
+	var result struct {
+		OK    bool   `json:"ok"`
+		TS    string `json:"ts"`
+		Error string `json:"error"`
+	}
+	if err := n.postJSON(ctx, n.slackAPIBase+"/chat.postMessage", token, payload, &result); err != nil {
+		return "", err
+	}
+	if !result.OK {
+		return "", fmt.Errorf("slack chat.postMessage failed: %s", result.Error)
+	}
+	return result.TS, nil
+}
+
+// postTelegram 发送 Telegram 消息，replyTo 非空时回复该消息，返回消息ID
+func (n *ChatNotifier) postTelegram(ctx context.Context, token, chatID, replyTo, text string) (string, error) {
+	payload := map[string]interface{}{"chat_id": chatID, "text": text}
+	if replyTo != "" {
+		if id, err := strconv.ParseInt(replyTo, 10, 64); err == nil {
+			payload["reply_to_message_id"] = id
+			payload["allow_sending_without_reply"] = true
+		}
+	}
+	var result struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+		Result      struct {
+			MessageID int64 `json:"message_id"`
+		} `json:"result"`
+	}
+	if err := n.postJSON(ctx, n.telegramAPIBase+"/bot"+token+"/sendMessage", "", payload, &result); err != nil {
+		return "", err
+	}
+	if !result.OK {
+		return "", fmt.Errorf("telegram sendMessage failed: %s", result.Description)
+	}
+	return strconv.FormatInt(result.Result.MessageID, 10), nil
+}
+
+// post 按平台发送消息
+func (n *ChatNotifier) post(ctx context.Context, config *models.ChatIntegrationConfig, platform models.ChatPlatform, channel, threadID, text string) (string, error) {
+	switch platform {
+	case models.ChatPlatformSlack:
+		if !config.SlackEnabled {
+			return "", ErrChatPlatformDisabled
+		}
+		return n.postSlack(ctx, config.SlackBotToken, channel, threadID, text)
+	case models.ChatPlatformTelegram:
+		if !config.TelegramEnabled {
+			return "", ErrChatPlatformDisabled
+		}
+		return n.postTelegram(ctx, config.TelegramBotToken, channel, threadID, text)
+	default:
+		return "", fmt.Errorf("unsupported chat platform: %s", platform)
+	}
+}
+
+// NotifyStatusChanged 将工单状态变更回复到创建该工单的聊天会话
+func (n *ChatNotifier) NotifyStatusChanged(ctx context.Context, ticket *models.Ticket, oldStatus models.TicketStatus) error {
+	var threads []models.ChatTicketThread
+	if err := n.db.WithContext(ctx).Where("ticket_id = ?", ticket.ID).Find(&threads).Error; err != nil {
+		return fmt.Errorf("failed to get chat threads: %w", err)
+	}
+	if len(threads) == 0 {
+		return nil
+	}
+
+	config, err := loadChatIntegrationConfig(ctx, n.db)
+	if err != nil {
+		return err
+	}
+	if !config.ThreadStatusUpdates {
+		return nil
+	}
+
+	text := fmt.Sprintf("工单 #%s 状态更新：%s → %s", ticket.TicketNumber,
+		getStatusLabel(string(oldStatus)), getStatusLabel(string(ticket.Status)))
+
+	var failures []string
+	for _, thread := range threads {
+		if _, err := n.post(ctx, config, thread.Platform, thread.ChannelID, thread.ThreadID, text); err != nil {
+			failures = append(failures, fmt.Sprintf("%s %s: %v", thread.Platform, thread.ChannelID, err))
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("failed to post chat status updates: %s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+// ChatIntakeResult 聊天建单结果
+type ChatIntakeResult struct {
+	Ticket    *models.Ticket
+	Link      string
+	Reply     string // 回复给提交人的确认消息
+	Threaded  bool   // 确认消息已发送到会话，后续状态更新将回复到该会话
+	Duplicate bool   // 同一消息已建过单（平台重试）
+}
+
+// ChatIntakeService Slack 斜杠命令 / Telegram 机器人建单服务
+type ChatIntakeService struct {
+	db            *gorm.DB
+	ticketService TicketServiceInterface
+	notifier      *ChatNotifier
+	webURL        string
+}
+
+// NewChatIntakeService 创建聊天建单服务
+func NewChatIntakeService(db *gorm.DB) *ChatIntakeService {
+	return &ChatIntakeService{
+		db:            db,
+		ticketService: NewTicketService(db),
+		notifier:      NewChatNotifier(db),
+	}
+}
+
+// SetWebURL 设置前端地址，用于生成工单链接
+func (s *ChatIntakeService) SetWebURL(webURL string) {
+	s.webURL = strings.TrimRight(webURL, "/")
+}
+
+// GetConfig 获取聊天建单配置
+func (s *ChatIntakeService) GetConfig(ctx context.Context) (*models.ChatIntegrationConfig, error) {
+	return loadChatIntegrationConfig(ctx, s.db)
+}
+
+// SetConfig 保存聊天建单配置，密钥留空时保留原值
+func (s *ChatIntakeService) SetConfig(ctx context.Context, chat *models.ChatIntegrationConfig, userID uint) error {
+	var existing models.SystemConfig
+	err := s.db.WithContext(ctx).Where("key = ?", KeyChatIntegration).First(&existing).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to check existing chat integration config: %w", err)
+	}
+
+	if err == nil {
+		previous := models.GetDefaultChatIntegrationConfig()
+		if err := existing.GetJSONValue(previous); err == nil {
+			if chat.SlackSigningSecret == "" {
+				chat.SlackSigningSecret = previous.SlackSigningSecret
+			}
+			if chat.SlackBotToken == "" {
+				chat.SlackBotToken = previous.SlackBotToken
+			}
+			if chat.TelegramBotToken == "" {
+				chat.TelegramBotToken = previous.TelegramBotToken
+			}
+			if chat.TelegramSecretToken == "" {
+				chat.TelegramSecretToken = previous.TelegramSecretToken
+			}
+			if chat.TeamsClientSecret == "" {
+				chat.TeamsClientSecret = previous.TeamsClientSecret
+			}
+		}
+	}
+	if err := chat.Validate(); err != nil {
+		return err
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		config := models.SystemConfig{
+			Key:         KeyChatIntegration,
+			Category:    CategoryTicket,
+			Group:       "integration",
+			Description: "Slack/Telegram 聊天建单集成",
+			IsActive:    true,
+			UpdatedBy:   &userID,
+		}
+		if err := config.SetValue(chat); err != nil {
+			return fmt.Errorf("failed to set chat integration config value: %w", err)
+		}
+		if err := s.db.WithContext(ctx).Create(&config).Error; err != nil {
+			return fmt.Errorf("failed to create chat integration config: %w", err)
+		}
+		return nil
+	}
+
+	if err := existing.SetValue(chat); err != nil {
+		return fmt.Errorf("failed to set chat integration config value: %w", err)
+	}
+	existing.UpdatedBy = &userID
+	existing.Version++
+	if err := s.db.WithContext(ctx).Save(&existing).Error; err != nil {
+		return fmt.Errorf("failed to update chat integration config: %w", err)
+	}
+	return nil
+}
+
+// VerifySlackRequest 校验 Slack 请求签名（v0 HMAC-SHA256）及时间戳
+func (s *ChatIntakeService) VerifySlackRequest(ctx context.Context, timestamp, signature string, body []byte) error {
+	config, err := s.GetConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if !config.SlackEnabled {
+		return ErrChatPlatformDisabled
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrChatSignatureInvalid
+	}
+	if age := time.Since(time.Unix(ts, 0)); age > slackSignatureMaxAge || age < -slackSignatureMaxAge {
+		return ErrChatSignatureInvalid
+	}
+
+	mac := hmac.New(sha256.New, []byte(config.SlackSigningSecret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrChatSignatureInvalid
+	}
+	return nil
+}
+
+// VerifyTelegramRequest 校验 Telegram Webhook 的 secret_token
+func (s *ChatIntakeService) VerifyTelegramRequest(ctx context.Context, secretToken string) error {
+	config, err := s.GetConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if !config.TelegramEnabled {
+		return ErrChatPlatformDisabled
+	}
+	if subtle.ConstantTimeCompare([]byte(config.TelegramSecretToken), []byte(secretToken)) != 1 {
+		return ErrChatSignatureInvalid
+	}
+	return nil
+}
+
+// parseChatTicketText 去掉 /ticket 命令前缀（含 Telegram 的 @机器人名）和首尾引号
+func parseChatTicketText(text string) string {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "/ticket") {
+		text = strings.TrimPrefix(text, "/ticket")
+		if strings.HasPrefix(text, "@") {
+			if i := strings.IndexAny(text, " \n"); i >= 0 {
+				text = text[i:]
+			} else {
+				text = ""
+			}
+		}
+	}
+	text = strings.TrimSpace(text)
+	for _, quote := range [][2]string{{`"`, `"`}, {"“", "”"}, {"'", "'"}} {
+		if len(text) >= len(quote[0])+len(quote[1]) && strings.HasPrefix(text, quote[0]) && strings.HasSuffix(text, quote[1]) {
+			text = strings.TrimSpace(text[len(quote[0]) : len(text)-len(quote[1])])
+			break
+		}
+	}
+	return text
+}
+
+// chatTicketTitle 取第一行作为工单标题
+func chatTicketTitle(text string) string {
+	title := strings.TrimSpace(strings.SplitN(text, "\n", 2)[0])
+	if utf8.RuneCountInString(title) <= chatTitleMaxRunes {
+		return title
+	}
+	return string([]rune(title)[:chatTitleMaxRunes]) + "…"
+}
+
+// resolveMappedUser 按聊天账号映射的邮箱查找启用的系统用户
+func (s *ChatIntakeService) resolveMappedUser(ctx context.Context, platform models.ChatPlatform, externalUserID string) (*models.User, error) {
+	var mapping models.ChatUserMapping
+	err := s.db.WithContext(ctx).
+		Where("platform = ? AND external_user_id = ?", platform, externalUserID).
+		First(&mapping).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrChatUserNotMapped
+		}
+		return nil, fmt.Errorf("failed to get chat user mapping: %w", err)
+	}
+
+	var user models.User
+	err = s.db.WithContext(ctx).
+		Where("lower(email) = ? AND status = ?", mapping.Email, models.UserStatusActive).
+		First(&user).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrChatUserNotMapped
+		}
+		return nil, fmt.Errorf("failed to get mapped user: %w", err)
+	}
+	return &user, nil
+}
+
+// ticketLink 生成工单链接
+func (s *ChatIntakeService) ticketLink(ticketID uint) string {
+	return fmt.Sprintf("%s/tickets/%d", s.webURL, ticketID)
+}
+
+// HandleMessage 以映射用户的身份创建工单，向来源会话发送工单号和链接，并记录会话用于回复后续状态更新。
+// Telegram 按消息ID去重；Slack 斜杠命令没有消息ID，以确认消息的 ts 作为线程
+func (s *ChatIntakeService) HandleMessage(ctx context.Context, msg *models.ChatIntakeMessage) (*ChatIntakeResult, error) {
+	config, err := s.GetConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if (msg.Platform == models.ChatPlatformSlack && !config.SlackEnabled) ||
+		(msg.Platform == models.ChatPlatformTelegram && !config.TelegramEnabled) {
+		return nil, ErrChatPlatformDisabled
+	}
+
+	if msg.MessageID != "" {
+		var thread models.ChatTicketThread
+		err := s.db.WithContext(ctx).
+			Where("platform = ? AND channel_id = ? AND thread_id = ?", msg.Platform, msg.ChannelID, msg.MessageID).
+			First(&thread).Error
+		if err == nil {
+			ticket, err := s.ticketService.GetTicket(ctx, thread.TicketID)
+			if err != nil {
+				return nil, err
+			}
+			return &ChatIntakeResult{Ticket: ticket, Link: s.ticketLink(ticket.ID), Threaded: true, Duplicate: true}, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("failed to check chat thread: %w", err)
+		}
+	}
+
+	text := parseChatTicketText(msg.Text)
+	if text == "" {
+		return nil, ErrChatTicketTextEmpty
+	}
+	user, err := s.resolveMappedUser(ctx, msg.Platform, msg.ExternalUserID)
+	if err != nil {
+		return nil, err
+	}
+
+	ticket, err := s.ticketService.CreateTicket(ctx, &models.TicketCreateRequest{
+		Title:         chatTicketTitle(text),
+		Description:   text,
+		Type:          config.DefaultType,
+		Priority:      config.DefaultPriority,
+		Source:        models.TicketSourceChat,
+		CustomerEmail: user.Email,
+		CustomerName:  user.GetFullName(),
+	}, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	platformName := "Slack"
+	if msg.Platform == models.ChatPlatformTelegram {
+		platformName = "Telegram"
+	}
+	sender := msg.ExternalUsername
+	if sender == "" {
+		sender = msg.ExternalUserID
+	}
+	history := &models.TicketHistory{
+		TicketID:    ticket.ID,
+		UserID:      &user.ID,
+		Action:      models.HistoryActionCreate,
+		Description: fmt.Sprintf("通过 %s 提交（账号 %s，会话 %s）", platformName, sender, msg.ChannelID),
+		FieldName:   "chat_platform",
+		NewValue:    string(msg.Platform),
+		IsVisible:   false,
+	}
+	if err := s.db.WithContext(ctx).Create(history).Error; err != nil {
+		return nil, fmt.Errorf("failed to record chat intake history: %w", err)
+	}
+
+	result := &ChatIntakeResult{Ticket: ticket, Link: s.ticketLink(ticket.ID)}
+	result.Reply = fmt.Sprintf("已创建工单 #%s：%s\n%s", ticket.TicketNumber, ticket.Title, result.Link)
+
+	threadID, err := s.notifier.post(ctx, config, msg.Platform, msg.ChannelID, msg.MessageID, result.Reply)
+	if err != nil {
+		// 确认消息发送失败不影响建单，Slack 仍可通过命令响应告知提交人
+		log.Printf("Failed to post chat confirmation for ticket %d: %v", ticket.ID, err)
+		return result, nil
+	}
+	if msg.MessageID != "" {
+		threadID = msg.MessageID
+	}
+
+	thread := &models.ChatTicketThread{
+		TicketID:       ticket.ID,
+		Platform:       msg.Platform,
+		ChannelID:      msg.ChannelID,
+		ThreadID:       threadID,
+		ExternalUserID: msg.ExternalUserID,
+	}
+	if err := s.db.WithContext(ctx).Create(thread).Error; err != nil {
+		return nil, fmt.Errorf("failed to save chat thread: %w", err)
+	}
+	result.Threaded = true
+	return result, nil
+}
+
+// Reply 向 Telegram 会话回复提示消息（如账号未关联），Slack 通过命令响应直接返回
+func (s *ChatIntakeService) Reply(ctx context.Context, msg *models.ChatIntakeMessage, text string) error {
+	config, err := s.GetConfig(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = s.notifier.post(ctx, config, msg.Platform, msg.ChannelID, msg.MessageID, text)
+	return err
+}
+
+// ListMappings 获取聊天账号映射，并解析对应的系统用户
+func (s *ChatIntakeService) ListMappings(ctx context.Context, platform models.ChatPlatform) ([]*models.ChatUserMapping, error) {
+	query := s.db.WithContext(ctx).Model(&models.ChatUserMapping{})
+	if platform != "" {
+		query = query.Where("platform = ?", platform)
+	}
+	var mappings []*models.ChatUserMapping
+	if err := query.Order("platform, external_user_id").Find(&mappings).Error; err != nil {
+		return nil, fmt.Errorf("failed to list chat user mappings: %w", err)
+	}
+
+	emails := make([]string, 0, len(mappings))
+	for _, mapping := range mappings {
+		emails = append(emails, mapping.Email)
+	}
+	if len(emails) == 0 {
+		return mappings, nil
+	}
+	var users []models.User
+	if err := s.db.WithContext(ctx).Select("id", "email").
+		Where("lower(email) IN ? AND status = ?", emails, models.UserStatusActive).
+		Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("failed to resolve mapped users: %w", err)
+	}
+	userIDs := make(map[string]uint, len(users))
+	for _, user := range users {
+		userIDs[strings.ToLower(user.Email)] = user.ID
+	}
+	for _, mapping := range mappings {
+		if id, ok := userIDs[mapping.Email]; ok {
+			mapping.UserID = &id
+		}
+	}
+	return mappings, nil
+}
+
+// SaveMapping 创建或更新聊天账号映射（同一平台账号只保留一条）
+func (s *ChatIntakeService) SaveMapping(ctx context.Context, req *models.ChatUserMappingRequest, userID uint) (*models.ChatUserMapping, error) {
+	externalID := strings.TrimSpace(req.ExternalUserID)
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+
+	var mapping models.ChatUserMapping
+	err := s.db.WithContext(ctx).
+		Where("platform = ? AND external_user_id = ?", req.Platform, externalID).
+		First(&mapping).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to get chat user mapping: %w", err)
+	}
+
+	mapping.Platform = req.Platform
+	mapping.ExternalUserID = externalID
+	mapping.ExternalUsername = strings.TrimSpace(req.ExternalUsername)
+	mapping.Email = email
+	if mapping.ID == 0 {
+		mapping.CreatedByID = &userID
+	}
+	if err := s.db.WithContext(ctx).Save(&mapping).Error; err != nil {
+		return nil, fmt.Errorf("failed to save chat user mapping: %w", err)
+	}
+
+	var user models.User
+	if err := s.db.WithContext(ctx).Select("id").
+		Where("lower(email) = ? AND status = ?", email, models.UserStatusActive).
+		First(&user).Error; err == nil {
+		mapping.UserID = &user.ID
+	}
+	return &mapping, nil
+}
+
+// DeleteMapping 删除聊天账号映射
+func (s *ChatIntakeService) DeleteMapping(ctx context.Context, id uint) error {
+	result := s.db.WithContext(ctx).Delete(&models.ChatUserMapping{}, id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete chat user mapping: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrChatUserMappingNotFound
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// fakeChatAPI 模拟 Slack/Telegram 发送消息接口，记录收到的请求
+type fakeChatAPI struct {
+	mu       sync.Mutex
+	messages []map[string]interface{}
+}
+
+func (f *fakeChatAPI) handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		payload["path"] = r.URL.Path
+		f.mu.Lock()
+		f.messages = append(f.messages, payload)
+		n := len(f.messages)
+		f.mu.Unlock()
+
+		if strings.HasPrefix(r.URL.Path, "/slack/") {
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "ts": "1700000000." + strconv.Itoa(n)})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "result": map[string]interface{}{"message_id": 9000 + n}})
+	})
+}
+
+func (f *fakeChatAPI) sent() []map[string]interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]map[string]interface{}(nil), f.messages...)
+}
+
+func TestChatIntake_CreatesTicketAndRepliesInThread(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:chat_intake_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Team{}, &models.Ticket{}, &models.TicketHistory{}, &models.SystemConfig{},
+		&models.ChatUserMapping{}, &models.ChatTicketThread{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+
+	api := &fakeChatAPI{}
+	server := httptest.NewServer(api.handler())
+	defer server.Close()
+
+	ctx := context.Background()
+	svc := NewChatIntakeService(db)
+	svc.SetWebURL("https://desk.example.com/")
+	svc.notifier.slackAPIBase = server.URL + "/slack"
+	svc.notifier.telegramAPIBase = server.URL + "/telegram"
+
+	user := models.User{Username: "chat-user", Email: "chat-user@example.com", PasswordHash: "x", Role: models.RoleCustomer, Status: models.UserStatusActive}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("failed to seed user: %v", err)
+	}
+
+	if err := svc.SetConfig(ctx, &models.ChatIntegrationConfig{SlackEnabled: true, DefaultType: models.TicketTypeRequest, DefaultPriority: models.TicketPriorityNormal}, user.ID); err == nil {
+		t.Fatalf("expected enabling slack without secrets to be rejected")
+	}
+	config := models.GetDefaultChatIntegrationConfig()
+	config.SlackEnabled = true
+	config.SlackSigningSecret = "signing-secret"
+	config.SlackBotToken = "xoxb-test"
+	config.TelegramEnabled = true
+	config.TelegramBotToken = "tg-token"
+	config.TelegramSecretToken = "tg-secret"
+	if err := svc.SetConfig(ctx, config, user.ID); err != nil {
+		t.Fatalf("set config failed: %v", err)
+	}
+	// 密钥留空时保留原值
+	config.SlackSigningSecret = ""
+	if err := svc.SetConfig(ctx, config, user.ID); err != nil {
+		t.Fatalf("update config failed: %v", err)
+	}
+
+	// Slack 签名校验
+	body := []byte("user_id=U1&channel_id=C1&text=%22printer%22")
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, []byte("signing-secret"))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	signature := "v0=" + hex.EncodeToString(mac.Sum(nil))
+	if err := svc.VerifySlackRequest(ctx, timestamp, signature, body); err != nil {
+		t.Fatalf("expected valid slack signature, got %v", err)
+	}
+	if err := svc.VerifySlackRequest(ctx, timestamp, signature, append(body, 'x')); !errors.Is(err, ErrChatSignatureInvalid) {
+		t.Fatalf("expected tampered body to be rejected, got %v", err)
+	}
+	stale := strconv.FormatInt(time.Now().Add(-10*time.Minute).Unix(), 10)
+	if err := svc.VerifySlackRequest(ctx, stale, signature, body); !errors.Is(err, ErrChatSignatureInvalid) {
+		t.Fatalf("expected stale timestamp to be rejected, got %v", err)
+	}
+	if err := svc.VerifyTelegramRequest(ctx, "wrong"); !errors.Is(err, ErrChatSignatureInvalid) {
+		t.Fatalf("expected wrong telegram secret to be rejected, got %v", err)
+	}
+
+	slackMsg := &models.ChatIntakeMessage{Platform: models.ChatPlatformSlack, ExternalUserID: "U1", ChannelID: "C1", Text: `"打印机无法打印"`}
+	if _, err := svc.HandleMessage(ctx, slackMsg); !errors.Is(err, ErrChatUserNotMapped) {
+		t.Fatalf("expected unmapped user to be rejected, got %v", err)
+	}
+
+	mapping, err := svc.SaveMapping(ctx, &models.ChatUserMappingRequest{Platform: models.ChatPlatformSlack, ExternalUserID: "U1", Email: "Chat-User@Example.com"}, user.ID)
+	if err != nil || mapping.UserID == nil || *mapping.UserID != user.ID {
+		t.Fatalf("expected mapping to resolve user, got %+v (%v)", mapping, err)
+	}
+	if _, err := svc.SaveMapping(ctx, &models.ChatUserMappingRequest{Platform: models.ChatPlatformTelegram, ExternalUserID: "42", Email: "chat-user@example.com"}, user.ID); err != nil {
+		t.Fatalf("save telegram mapping failed: %v", err)
+	}
+
+	result, err := svc.HandleMessage(ctx, slackMsg)
+	if err != nil {
+		t.Fatalf("slack intake failed: %v", err)
+	}
+	if result.Ticket.Title != "打印机无法打印" || result.Ticket.CreatedByID != user.ID || result.Ticket.Source != models.TicketSourceChat {
+		t.Fatalf("unexpected ticket: %+v", result.Ticket)
+	}
+	if !result.Threaded || result.Link != "https://desk.example.com/tickets/"+strconv.Itoa(int(result.Ticket.ID)) {
+		t.Fatalf("unexpected intake result: %+v", result)
+	}
+	var slackThread models.ChatTicketThread
+	if err := db.Where("ticket_id = ?", result.Ticket.ID).First(&slackThread).Error; err != nil || slackThread.ThreadID != "1700000000.1" {
+		t.Fatalf("expected slack thread to use confirmation ts, got %+v (%v)", slackThread, err)
+	}
+
+	// Telegram 同一消息重复推送不重复建单
+	tgMsg := &models.ChatIntakeMessage{Platform: models.ChatPlatformTelegram, ExternalUserID: "42", ChannelID: "-100", MessageID: "77", Text: "/ticket@desk_bot VPN 断开\n从早上开始"}
+	first, err := svc.HandleMessage(ctx, tgMsg)
+	if err != nil || first.Ticket.Title != "VPN 断开" {
+		t.Fatalf("telegram intake failed: %+v (%v)", first, err)
+	}
+	again, err := svc.HandleMessage(ctx, tgMsg)
+	if err != nil || !again.Duplicate || again.Ticket.ID != first.Ticket.ID {
+		t.Fatalf("expected duplicate telegram message to return existing ticket, got %+v (%v)", again, err)
+	}
+
+	ticket := first.Ticket
+	ticket.Status = models.TicketStatusResolved
+	if err := svc.notifier.NotifyStatusChanged(ctx, ticket, models.TicketStatusOpen); err != nil {
+		t.Fatalf("notify status changed failed: %v", err)
+	}
+	sent := api.sent()
+	last := sent[len(sent)-1]
+	if last["path"] != "/telegram/bottg-token/sendMessage" || last["reply_to_message_id"] != float64(77) ||
+		!strings.Contains(last["text"].(string), ticket.TicketNumber) {
+		t.Fatalf("expected status update to reply to the source message, got %+v", last)
+	}
+	if len(sent) != 3 {
+		t.Fatalf("expected 3 chat messages (2 confirmations + 1 status update), got %d", len(sent))
+	}
+}
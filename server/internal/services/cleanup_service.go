@@ -161,6 +161,120 @@ func (t *LoginHistoryCleanupTask) Execute(ctx context.Context, config *models.Cl
 	return result, nil
 }
 
+// NotificationCleanupTask 已读通知归档/清理任务
+type NotificationCleanupTask struct {
+	db *gorm.DB
+}
+
+// GetName 获取任务名称
+func (t *NotificationCleanupTask) GetName() string {
+	return "notifications"
+}
+
+// Execute 将超过保留期的已读通知移入归档表或直接删除
+func (t *NotificationCleanupTask) Execute(ctx context.Context, config *models.CleanupConfig) (*CleanupResult, error) {
+	result := &CleanupResult{
+		TaskType:  t.GetName(),
+		StartTime: time.Now(),
+	}
+
+	if config.NotificationRetentionDays < 1 {
+		result.EndTime = time.Now()
+		result.ErrorMessage = "retention days must be at least 1"
+		return result, fmt.Errorf("invalid notification retention days: %d", config.NotificationRetentionDays)
+	}
+
+	archive := config.NotificationCleanupMode != models.NotificationCleanupModeDelete
+	cutoffDate := time.Now().AddDate(0, 0, -config.NotificationRetentionDays)
+	log.Printf("🧹 开始清理已读通知 - 处理 %v 之前的记录 (保留 %d 天, 归档: %v)",
+		cutoffDate.Format("2006-01-02 15:04:05"), config.NotificationRetentionDays, archive)
+
+	batchSize := config.MaxRecordsPerCleanup
+	if batchSize <= 0 || batchSize > 10000 {
+		batchSize = 1000
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			result.EndTime = time.Now()
+			result.ErrorMessage = "cleanup was cancelled"
+			return result, ctx.Err()
+		default:
+		}
+
+		var notifications []models.Notification
+		if err := t.db.WithContext(ctx).
+			Where("is_read = ? AND created_at < ?", true, cutoffDate).
+			Order("id ASC").
+			Limit(batchSize).
+			Find(&notifications).Error; err != nil {
+			result.EndTime = time.Now()
+			result.ErrorMessage = fmt.Sprintf("failed to load notifications: %v", err)
+			return result, err
+		}
+
+		if len(notifications) == 0 {
+			break
+		}
+		result.RecordsProcessed += len(notifications)
+
+		ids := make([]uint, 0, len(notifications))
+		for i := range notifications {
+			ids = append(ids, notifications[i].ID)
+		}
+
+		var batchDeleted int64
+		err := t.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if archive {
+				archivedAt := time.Now()
+				archives := make([]*models.NotificationArchive, 0, len(notifications))
+				for i := range notifications {
+					archives = append(archives, notifications[i].ToArchive(archivedAt))
+				}
+				if err := tx.Create(&archives).Error; err != nil {
+					return fmt.Errorf("failed to archive notifications: %w", err)
+				}
+			}
+
+			deleteResult := tx.Where("id IN ?", ids).Delete(&models.Notification{})
+			if deleteResult.Error != nil {
+				return fmt.Errorf("failed to delete notifications: %w", deleteResult.Error)
+			}
+			batchDeleted = deleteResult.RowsAffected
+			// 移动端增量同步据此删除本地缓存
+			tombstones := make([]models.SyncTombstone, 0, len(notifications))
+			for i := range notifications {
+				recipientID := notifications[i].RecipientID
+				tombstones = append(tombstones, models.SyncTombstone{EntityType: models.SyncEntityNotification, EntityID: notifications[i].ID, UserID: &recipientID})
+			}
+			if err := tx.Create(&tombstones).Error; err != nil {
+				return fmt.Errorf("failed to record sync tombstones: %w", err)
+			}
+			return nil
+		})
+		if err != nil {
+			result.EndTime = time.Now()
+			result.ErrorMessage = err.Error()
+			return result, err
+		}
+
+		result.RecordsDeleted += int(batchDeleted)
+		log.Printf("🗑️  已处理 %d 条通知 (累计: %d)", batchDeleted, result.RecordsDeleted)
+
+		if len(notifications) < batchSize {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	result.EndTime = time.Now()
+	log.Printf("✅ 通知清理完成: 处理了 %d 条记录，耗时 %v",
+		result.RecordsDeleted, result.EndTime.Sub(result.StartTime))
+
+	return result, nil
+}
+
 // GetCleanupConfig 获取清理配置
 func (s *CleanupService) GetCleanupConfig(ctx context.Context) (*models.CleanupConfig, error) {
 	var config models.SystemConfig
@@ -192,6 +306,12 @@ func (s *CleanupService) GetCleanupConfig(ctx context.Context) (*models.CleanupC
 	if cleanupConfig.CleanupSchedule == "" {
 		cleanupConfig.CleanupSchedule = "0 2 * * *"
 	}
+	if cleanupConfig.NotificationRetentionDays < 1 {
+		cleanupConfig.NotificationRetentionDays = 90
+	}
+	if cleanupConfig.NotificationCleanupMode == "" {
+		cleanupConfig.NotificationCleanupMode = models.NotificationCleanupModeArchive
+	}
 
 	return &cleanupConfig, nil
 }
@@ -205,6 +325,20 @@ func (s *CleanupService) SetCleanupConfig(ctx context.Context, config *models.Cl
 	if config.MaxRecordsPerCleanup < 100 || config.MaxRecordsPerCleanup > 10000 {
 		return fmt.Errorf("max records per cleanup must be between 100 and 10000")
 	}
+	if config.NotificationRetentionDays == 0 {
+		config.NotificationRetentionDays = 90
+	}
+	if config.NotificationCleanupMode == "" {
+		config.NotificationCleanupMode = models.NotificationCleanupModeArchive
+	}
+	if config.NotificationRetentionDays < 1 || config.NotificationRetentionDays > 3650 {
+		return fmt.Errorf("notification retention days must be between 1 and 3650")
+	}
+	switch config.NotificationCleanupMode {
+	case models.NotificationCleanupModeArchive, models.NotificationCleanupModeDelete:
+	default:
+		return fmt.Errorf("notification cleanup mode must be 'archive' or 'delete'")
+	}
 
 	// 查找现有配置
 	var existingConfig models.SystemConfig
@@ -265,13 +399,18 @@ func (s *CleanupService) ExecuteCleanup(ctx context.Context, taskType string, tr
 		return nil
 	}
 
+	retentionDays := config.LoginHistoryRetentionDays
+	if taskType == "notifications" {
+		retentionDays = config.NotificationRetentionDays
+	}
+
 	// 创建清理日志
 	cleanupLog := &models.CleanupLog{
 		TaskType:      taskType,
 		Status:        "started",
 		StartTime:     time.Now(),
-		RetentionDays: config.LoginHistoryRetentionDays,
-		CutoffDate:    time.Now().AddDate(0, 0, -config.LoginHistoryRetentionDays),
+		RetentionDays: retentionDays,
+		CutoffDate:    time.Now().AddDate(0, 0, -retentionDays),
 		TriggerType:   triggerType,
 		TriggerBy:     userID,
 	}
@@ -285,6 +424,8 @@ func (s *CleanupService) ExecuteCleanup(ctx context.Context, taskType string, tr
 	switch taskType {
 	case "login_history":
 		task = &LoginHistoryCleanupTask{db: s.db}
+	case "notifications":
+		task = &NotificationCleanupTask{db: s.db}
 	default:
 		// 更新日志状态为失败
 		cleanupLog.Status = "failed"
@@ -323,7 +464,7 @@ func (s *CleanupService) ExecuteCleanup(ctx context.Context, taskType string, tr
 
 // ExecuteAllCleanupTasks 执行所有清理任务
 func (s *CleanupService) ExecuteAllCleanupTasks(ctx context.Context, triggerType string, userID *uint) error {
-	taskTypes := []string{"login_history"} // 可以扩展其他任务类型
+	taskTypes := []string{"login_history", "notifications"} // 可以扩展其他任务类型
 	
 	var lastError error
 	successCount := 0
@@ -386,6 +527,14 @@ func (s *CleanupService) GetCleanupStats(ctx context.Context) (*CleanupStatsResp
 	}
 	stats.LoginHistoryCount = loginHistoryCount
 
+	// 获取通知及归档记录数
+	if err := s.db.WithContext(ctx).Model(&models.Notification{}).Count(&stats.NotificationCount).Error; err != nil {
+		return nil, fmt.Errorf("failed to count notifications: %w", err)
+	}
+	if err := s.db.WithContext(ctx).Model(&models.NotificationArchive{}).Count(&stats.ArchivedNotificationCount).Error; err != nil {
+		return nil, fmt.Errorf("failed to count archived notifications: %w", err)
+	}
+
 	// 获取清理日志统计
 	var totalCleanups int64
 	if err := s.db.WithContext(ctx).Model(&models.CleanupLog{}).Count(&totalCleanups).Error; err != nil {
@@ -439,6 +588,8 @@ func (s *CleanupService) GetCleanupStats(ctx context.Context) (*CleanupStatsResp
 // CleanupStatsResponse 清理统计响应
 type CleanupStatsResponse struct {
 	LoginHistoryCount    int64                  `json:"login_history_count"`
+	NotificationCount    int64                  `json:"notification_count"`
+	ArchivedNotificationCount int64             `json:"archived_notification_count"`
 	TotalCleanups        int64                  `json:"total_cleanups"`
 	SuccessfulCleanups   int64                  `json:"successful_cleanups"`
 	LastCleanupTime      *time.Time             `json:"last_cleanup_time,omitempty"`
@@ -0,0 +1,270 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+// replyLockTTL 回复锁有效期，编辑器自动保存时续期
+const replyLockTTL = 2 * time.Minute
+
+// TicketReplyLockHook 回复锁变更时调用，由 WebSocket 模块注册用于实时推送
+var TicketReplyLockHook func(ctx context.Context, event *models.TicketReplyLockEvent)
+
+var (
+	// ErrDraftTicketNotFound 草稿对应的工单不存在
+	ErrDraftTicketNotFound = errors.New("ticket not found")
+	// ErrTicketReplyLocked 其他客服正在回复该工单
+	ErrTicketReplyLocked = errors.New("another agent is replying to this ticket")
+)
+
+// ReplyLockedError 提交公开回复时工单被其他客服锁定
+type ReplyLockedError struct {
+	Lock *models.TicketReplyLock
+}
+
+func (e *ReplyLockedError) Error() string {
+	name := fmt.Sprintf("user %d", e.Lock.UserID)
+	if e.Lock.User != nil {
+		name = e.Lock.User.GetFullName()
+	}
+	return fmt.Sprintf("%s is replying to this ticket until %s", name, e.Lock.ExpiresAt.Format(time.RFC3339))
+}
+
+// Is 使 errors.Is(err, ErrTicketReplyLocked) 成立
+func (e *ReplyLockedError) Is(target error) bool {
+	return target == ErrTicketReplyLocked
+}
+
+// CommentDraftService 评论草稿及回复锁服务
+type CommentDraftService struct {
+	db *gorm.DB
+}
+
+// NewCommentDraftService 创建评论草稿服务
+func NewCommentDraftService(db *gorm.DB) *CommentDraftService {
+	return &CommentDraftService{db: db}
+}
+
+// GetDraft 获取用户在工单下的草稿及当前回复锁
+func (s *CommentDraftService) GetDraft(ctx context.Context, ticketID, userID uint) (*models.CommentDraftState, error) {
+	var draft models.TicketCommentDraft
+	err := s.db.WithContext(ctx).Where("ticket_id = ? AND user_id = ?", ticketID, userID).First(&draft).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to get comment draft: %w", err)
+	}
+
+	state := &models.CommentDraftState{}
+	if err == nil {
+		state.Draft = &draft
+	}
+	if state.Lock, err = s.ActiveLock(ctx, ticketID, time.Now()); err != nil {
+		return nil, err
+	}
+	state.LockedByOther = state.Lock != nil && state.Lock.UserID != userID
+	return state, nil
+}
+
+// SaveDraft 自动保存草稿；请求 lock 时为客服获取或续期回复锁，锁被他人持有时仍保存草稿
+func (s *CommentDraftService) SaveDraft(ctx context.Context, ticketID uint, req *models.CommentDraftSaveRequest, viewer CommentViewer) (*models.CommentDraftState, error) {
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.Ticket{}).Where("id = ?", ticketID).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to get ticket: %w", err)
+	}
+	if count == 0 {
+		return nil, ErrDraftTicketNotFound
+	}
+
+	draft := models.TicketCommentDraft{TicketID: ticketID, UserID: viewer.UserID}
+	err := s.db.WithContext(ctx).Where("ticket_id = ? AND user_id = ?", ticketID, viewer.UserID).First(&draft).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to get comment draft: %w", err)
+	}
+	draft.Content = req.Content
+	draft.ContentType = req.ContentType
+	if draft.ContentType == "" {
+		draft.ContentType = "text"
+	}
+	draft.Type = req.Type
+	draft.Visibility = req.Visibility
+	draft.VisibleTeamID = req.TeamID
+	draft.ParentID = req.ParentID
+	if viewer.IsCustomer() {
+		draft.Type = models.CommentTypePublic
+		draft.Visibility = models.CommentVisibilityPublic
+		draft.VisibleTeamID = nil
+	}
+	if err := s.db.WithContext(ctx).Save(&draft).Error; err != nil {
+		return nil, fmt.Errorf("failed to save comment draft: %w", err)
+	}
+
+	state := &models.CommentDraftState{Draft: &draft}
+	now := time.Now()
+	if req.Lock && !viewer.IsCustomer() {
+		acquired, err := s.acquireLock(ctx, ticketID, viewer.UserID, now)
+		if err != nil {
+			return nil, err
+		}
+		if state.Lock, err = s.ActiveLock(ctx, ticketID, now); err != nil {
+			return nil, err
+		}
+		if acquired && state.Lock != nil {
+			s.publish(ctx, state.Lock, true, models.ReplyLockReasonAcquired)
+		}
+	} else if state.Lock, err = s.ActiveLock(ctx, ticketID, now); err != nil {
+		return nil, err
+	}
+	state.LockedByOther = state.Lock != nil && state.Lock.UserID != viewer.UserID
+	return state, nil
+}
+
+// DiscardDraft 删除草稿并释放用户持有的回复锁
+func (s *CommentDraftService) DiscardDraft(ctx context.Context, ticketID, userID uint) error {
+	return s.finish(ctx, ticketID, userID, models.ReplyLockReasonDiscarded)
+}
+
+// CompleteDraft 评论提交后清除草稿并释放回复锁
+func (s *CommentDraftService) CompleteDraft(ctx context.Context, ticketID, userID uint) error {
+	return s.finish(ctx, ticketID, userID, models.ReplyLockReasonSubmitted)
+}
+
+// ActiveLock 获取工单当前有效的回复锁，没有时返回 nil
+func (s *CommentDraftService) ActiveLock(ctx context.Context, ticketID uint, now time.Time) (*models.TicketReplyLock, error) {
+	var lock models.TicketReplyLock
+	err := s.db.WithContext(ctx).Preload("User").
+		Where("ticket_id = ? AND expires_at > ?", ticketID, now).
+		First(&lock).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get reply lock: %w", err)
+	}
+	return &lock, nil
+}
+
+// CheckReplyAllowed 其他客服持有有效回复锁时返回 ReplyLockedError
+func (s *CommentDraftService) CheckReplyAllowed(ctx context.Context, ticketID, userID uint) error {
+	lock, err := s.ActiveLock(ctx, ticketID, time.Now())
+	if err != nil {
+		return err
+	}
+	if lock != nil && lock.UserID != userID {
+		return &ReplyLockedError{Lock: lock}
+	}
+	return nil
+}
+
+// ExpireLocks 删除已过期的回复锁并推送释放事件，返回处理数量
+func (s *CommentDraftService) ExpireLocks(ctx context.Context, now time.Time) (int, error) {
+	var locks []models.TicketReplyLock
+	if err := s.db.WithContext(ctx).Preload("User").Where("expires_at <= ?", now).Find(&locks).Error; err != nil {
+		return 0, fmt.Errorf("failed to find expired reply locks: %w", err)
+	}
+
+	expired := 0
+	for i := range locks {
+		// 条件删除，避免删除期间刚被续期的锁
+		result := s.db.WithContext(ctx).Where("id = ? AND expires_at <= ?", locks[i].ID, now).Delete(&models.TicketReplyLock{})
+		if result.Error != nil {
+			return expired, fmt.Errorf("failed to expire reply lock: %w", result.Error)
+		}
+		if result.RowsAffected > 0 {
+			expired++
+			s.publish(ctx, &locks[i], false, models.ReplyLockReasonExpired)
+		}
+	}
+	return expired, nil
+}
+
+// acquireLock 获取或续期回复锁，返回是否为新获取；锁被他人持有时不做修改
+func (s *CommentDraftService) acquireLock(ctx context.Context, ticketID, userID uint, now time.Time) (bool, error) {
+	expiresAt := now.Add(replyLockTTL)
+	acquired := false
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("ticket_id = ? AND expires_at <= ?", ticketID, now).Delete(&models.TicketReplyLock{}).Error; err != nil {
+			return fmt.Errorf("failed to clear expired reply lock: %w", err)
+		}
+
+		result := tx.Model(&models.TicketReplyLock{}).
+			Where("ticket_id = ? AND user_id = ?", ticketID, userID).
+			Update("expires_at", expiresAt)
+		if result.Error != nil {
+			return fmt.Errorf("failed to renew reply lock: %w", result.Error)
+		}
+		if result.RowsAffected > 0 {
+			return nil
+		}
+
+		var held int64
+		if err := tx.Model(&models.TicketReplyLock{}).Where("ticket_id = ?", ticketID).Count(&held).Error; err != nil {
+			return fmt.Errorf("failed to check reply lock: %w", err)
+		}
+		if held > 0 {
+			return nil
+		}
+		if err := tx.Create(&models.TicketReplyLock{TicketID: ticketID, UserID: userID, ExpiresAt: expiresAt}).Error; err != nil {
+			return fmt.Errorf("failed to acquire reply lock: %w", err)
+		}
+		acquired = true
+		return nil
+	})
+	return acquired, err
+}
+
+// finish 删除草稿并释放用户持有的回复锁
+func (s *CommentDraftService) finish(ctx context.Context, ticketID, userID uint, reason string) error {
+	if err := s.db.WithContext(ctx).Where("ticket_id = ? AND user_id = ?", ticketID, userID).
+		Delete(&models.TicketCommentDraft{}).Error; err != nil {
+		return fmt.Errorf("failed to delete comment draft: %w", err)
+	}
+
+	var lock models.TicketReplyLock
+	err := s.db.WithContext(ctx).Preload("User").Where("ticket_id = ? AND user_id = ?", ticketID, userID).First(&lock).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get reply lock: %w", err)
+	}
+	if err := s.db.WithContext(ctx).Delete(&lock).Error; err != nil {
+		return fmt.Errorf("failed to release reply lock: %w", err)
+	}
+	s.publish(ctx, &lock, false, reason)
+	return nil
+}
+
+// publish 向在职客服推送回复锁变更事件
+func (s *CommentDraftService) publish(ctx context.Context, lock *models.TicketReplyLock, locked bool, reason string) {
+	if TicketReplyLockHook == nil {
+		return
+	}
+
+	event := &models.TicketReplyLockEvent{
+		TicketID: lock.TicketID,
+		Locked:   locked,
+		UserID:   lock.UserID,
+		Reason:   reason,
+	}
+	if lock.User != nil {
+		event.UserName = lock.User.GetFullName()
+	}
+	if locked {
+		expiresAt := lock.ExpiresAt
+		event.ExpiresAt = &expiresAt
+	}
+	staffRoles := []models.UserRole{models.RoleAgent, models.RoleSupervisor, models.RoleAdmin, "superuser"}
+	if err := s.db.WithContext(ctx).Model(&models.User{}).
+		Where("role IN ? AND status = ?", staffRoles, models.UserStatusActive).
+		Pluck("id", &event.Recipients).Error; err != nil {
+		log.Printf("Failed to load reply lock recipients for ticket %d: %v", lock.TicketID, err)
+		return
+	}
+	TicketReplyLockHook(ctx, event)
+}
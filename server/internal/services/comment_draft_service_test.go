@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestCommentDraft_AutosaveAndReplyLock(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:comment_drafts?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Team{}, &models.Ticket{}, &models.TicketComment{},
+		&models.SystemConfig{}, &models.TicketCommentDraft{}, &models.TicketReplyLock{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+
+	alice := models.User{Username: "alice", Email: "alice@example.com", PasswordHash: "x", Role: models.RoleAgent, Status: models.UserStatusActive}
+	bob := models.User{Username: "bob", Email: "bob@example.com", PasswordHash: "x", Role: models.RoleAgent, Status: models.UserStatusActive}
+	db.Create(&alice)
+	db.Create(&bob)
+	ticket := models.Ticket{TicketNumber: "T-DRAFT-1", Title: "打印机无法使用", Description: "desc", CreatedByID: alice.ID}
+	if err := db.Create(&ticket).Error; err != nil {
+		t.Fatalf("failed to create ticket: %v", err)
+	}
+
+	var events []*models.TicketReplyLockEvent
+	TicketReplyLockHook = func(ctx context.Context, event *models.TicketReplyLockEvent) { events = append(events, event) }
+	defer func() { TicketReplyLockHook = nil }()
+
+	ctx := context.Background()
+	comments := NewTicketCommentService(db, nil)
+	drafts := comments.DraftService()
+	aliceViewer := CommentViewer{UserID: alice.ID, Role: string(models.RoleAgent)}
+	bobViewer := CommentViewer{UserID: bob.ID, Role: string(models.RoleAgent)}
+
+	state, err := drafts.SaveDraft(ctx, ticket.ID, &models.CommentDraftSaveRequest{Content: "您好，", Lock: true}, aliceViewer)
+	if err != nil {
+		t.Fatalf("save draft failed: %v", err)
+	}
+	if state.Lock == nil || state.Lock.UserID != alice.ID || state.LockedByOther {
+		t.Fatalf("expected alice to hold the reply lock, got %+v", state.Lock)
+	}
+	if len(events) != 1 || !events[0].Locked || len(events[0].Recipients) != 2 {
+		t.Fatalf("expected one lock acquired event for both agents, got %+v", events)
+	}
+
+	// 续期不会重复推送
+	if _, err := drafts.SaveDraft(ctx, ticket.ID, &models.CommentDraftSaveRequest{Content: "您好，请重启", Lock: true}, aliceViewer); err != nil {
+		t.Fatalf("renew draft failed: %v", err)
+	}
+	state, err = drafts.SaveDraft(ctx, ticket.ID, &models.CommentDraftSaveRequest{Content: "我来回复", Lock: true}, bobViewer)
+	if err != nil || !state.LockedByOther || len(events) != 1 {
+		t.Fatalf("expected bob to see the lock held by alice, got %+v (%v)", state, err)
+	}
+
+	_, err = comments.CreateComment(ctx, ticket.ID, &models.TicketCommentCreateRequest{Content: "我来回复", Type: models.CommentTypePublic}, bobViewer)
+	if !errors.Is(err, ErrTicketReplyLocked) {
+		t.Fatalf("expected reply locked error, got %v", err)
+	}
+	if _, err := comments.CreateComment(ctx, ticket.ID, &models.TicketCommentCreateRequest{Content: "内部备注", Type: models.CommentTypeInternal}, bobViewer); err != nil {
+		t.Fatalf("internal note should not be blocked by reply lock: %v", err)
+	}
+
+	if _, err := comments.CreateComment(ctx, ticket.ID, &models.TicketCommentCreateRequest{Content: "您好，请重启", Type: models.CommentTypePublic}, aliceViewer); err != nil {
+		t.Fatalf("lock holder reply failed: %v", err)
+	}
+	state, _ = drafts.GetDraft(ctx, ticket.ID, alice.ID)
+	if state.Draft != nil || state.Lock != nil {
+		t.Fatalf("expected draft and lock to be cleared after submit, got %+v", state)
+	}
+	if last := events[len(events)-1]; last.Locked || last.Reason != models.ReplyLockReasonSubmitted {
+		t.Fatalf("expected submitted release event, got %+v", last)
+	}
+
+	// 锁过期后由定时任务清除
+	if _, err := drafts.SaveDraft(ctx, ticket.ID, &models.CommentDraftSaveRequest{Content: "我来回复", Lock: true}, bobViewer); err != nil {
+		t.Fatalf("bob lock failed: %v", err)
+	}
+	expired, err := drafts.ExpireLocks(ctx, time.Now().Add(replyLockTTL+time.Second))
+	if err != nil || expired != 1 {
+		t.Fatalf("expected one expired lock, got %d (%v)", expired, err)
+	}
+	if last := events[len(events)-1]; last.UserID != bob.ID || last.Reason != models.ReplyLockReasonExpired {
+		t.Fatalf("expected expired release event for bob, got %+v", last)
+	}
+}
@@ -0,0 +1,330 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// commentTranslationTimeout 后台自动翻译单条评论的超时时间
+const commentTranslationTimeout = 30 * time.Second
+
+var (
+	// ErrTranslationNotConfigured 未启用评论翻译或未配置服务商密钥
+	ErrTranslationNotConfigured = errors.New("comment translation is not configured")
+	// ErrTranslationFailed 翻译服务商请求失败
+	ErrTranslationFailed = errors.New("translation provider request failed")
+	// ErrInvalidTranslationLanguage 目标语言代码不合法
+	ErrInvalidTranslationLanguage = errors.New("invalid target language")
+)
+
+// translationLanguagePattern BCP 47 语言代码的常用形式，如 en、zh-CN、zh-Hans
+var translationLanguagePattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,4})?$`)
+
+// CommentTranslationService 评论翻译服务：调用配置的服务商翻译评论，并按目标语言缓存译文
+type CommentTranslationService struct {
+	db            *gorm.DB
+	configService *ConfigService
+	client        *http.Client
+	newTranslator func(config *TranslatorConfig) (Translator, error)
+
+	// 正在后台翻译的 评论ID/目标语言，避免重复请求服务商
+	inflight sync.Map
+	// background 进行中的后台翻译，测试中等待其完成
+	background sync.WaitGroup
+}
+
+// NewCommentTranslationService 创建评论翻译服务
+func NewCommentTranslationService(db *gorm.DB) *CommentTranslationService {
+	service := &CommentTranslationService{
+		db:            db,
+		configService: NewConfigService(db),
+		client:        &http.Client{Timeout: 15 * time.Second},
+	}
+	service.newTranslator = func(config *TranslatorConfig) (Translator, error) {
+		return NewTranslator(config, service.client)
+	}
+	return service
+}
+
+// translator 按系统配置创建翻译客户端，未启用或缺少密钥时返回 ErrTranslationNotConfigured
+func (s *CommentTranslationService) translator() (Translator, error) {
+	enabled, err := s.configService.GetConfigBool(KeyTranslationEnabled)
+	if err != nil || !enabled {
+		return nil, ErrTranslationNotConfigured
+	}
+	return s.newTranslator(&TranslatorConfig{
+		Provider: models.TranslationProvider(s.configService.GetConfigWithDefault(KeyTranslationProvider, string(models.TranslationProviderDeepL))),
+		APIKey:   s.configService.GetConfigWithDefault(KeyTranslationAPIKey, ""),
+		Region:   s.configService.GetConfigWithDefault(KeyTranslationRegion, ""),
+		Endpoint: s.configService.GetConfigWithDefault(KeyTranslationEndpoint, ""),
+	})
+}
+
+// GetPreference 获取用户的翻译偏好，目标语言为空时返回用户的界面语言
+func (s *CommentTranslationService) GetPreference(ctx context.Context, userID uint) (*models.TranslationPreference, error) {
+	preference := &models.TranslationPreference{UserID: userID}
+	err := s.db.WithContext(ctx).Where("user_id = ?", userID).First(preference).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to get translation preference: %w", err)
+	}
+	if preference.TargetLanguage == "" {
+		var user models.User
+		if err := s.db.WithContext(ctx).Select("id", "language").First(&user, userID).Error; err == nil {
+			preference.TargetLanguage = user.Language
+		}
+	}
+	if preference.TargetLanguage == "" {
+		preference.TargetLanguage = "zh-CN"
+	}
+	return preference, nil
+}
+
+// UpdatePreference 更新用户的翻译偏好
+func (s *CommentTranslationService) UpdatePreference(ctx context.Context, userID uint, req *models.TranslationPreferenceRequest) (*models.TranslationPreference, error) {
+	var preference models.TranslationPreference
+	err := s.db.WithContext(ctx).Where("user_id = ?", userID).First(&preference).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to get translation preference: %w", err)
+	}
+	preference.UserID = userID
+	if req.AutoTranslate != nil {
+		preference.AutoTranslate = *req.AutoTranslate
+	}
+	if req.TargetLanguage != nil {
+		language := ""
+		if *req.TargetLanguage != "" {
+			if language, err = normalizeTranslationLanguage(*req.TargetLanguage); err != nil {
+				return nil, err
+			}
+		}
+		preference.TargetLanguage = language
+	}
+	if err := s.db.WithContext(ctx).Save(&preference).Error; err != nil {
+		return nil, fmt.Errorf("failed to save translation preference: %w", err)
+	}
+	return s.GetPreference(ctx, userID)
+}
+
+// TranslateComment 将查看者可见的评论翻译为目标语言，目标语言为空时使用查看者的翻译偏好。
+// 返回的 bool 表示译文是否来自缓存
+func (s *CommentTranslationService) TranslateComment(ctx context.Context, commentID uint, targetLanguage string, viewer CommentViewer) (*models.CommentTranslation, bool, error) {
+	var comment models.TicketComment
+	query := s.db.WithContext(ctx).Model(&models.TicketComment{}).Where("id = ? AND is_deleted = ?", commentID, false)
+	if err := scopeVisibleComments(s.db, query, viewer, true).First(&comment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, false, ErrCommentNotFound
+		}
+		return nil, false, fmt.Errorf("failed to get comment: %w", err)
+	}
+
+	if targetLanguage == "" {
+		preference, err := s.GetPreference(ctx, viewer.UserID)
+		if err != nil {
+			return nil, false, err
+		}
+		targetLanguage = preference.TargetLanguage
+	}
+	language, err := normalizeTranslationLanguage(targetLanguage)
+	if err != nil {
+		return nil, false, err
+	}
+	return s.translate(ctx, &comment, language)
+}
+
+// translate 返回缓存的译文，缓存不存在或原文已修改时调用服务商翻译并写入缓存
+func (s *CommentTranslationService) translate(ctx context.Context, comment *models.TicketComment, language string) (*models.CommentTranslation, bool, error) {
+	sourceHash := commentContentHash(comment.Content)
+	var cached models.CommentTranslation
+	err := s.db.WithContext(ctx).Where("comment_id = ? AND target_language = ?", comment.ID, language).First(&cached).Error
+	if err == nil && cached.SourceHash == sourceHash {
+		return &cached, true, nil
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, fmt.Errorf("failed to get cached translation: %w", err)
+	}
+
+	translator, err := s.translator()
+	if err != nil {
+		return nil, false, err
+	}
+	result, err := translator.Translate(ctx, comment.Content, language)
+	if err != nil {
+		return nil, false, err
+	}
+
+	translation := &models.CommentTranslation{
+		CommentID:      comment.ID,
+		TargetLanguage: language,
+		SourceLanguage: result.SourceLanguage,
+		Provider:       translator.Provider(),
+		Content:        result.Text,
+		SourceHash:     sourceHash,
+	}
+	if err := s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "comment_id"}, {Name: "target_language"}},
+		DoUpdates: clause.AssignmentColumns([]string{"source_language", "provider", "content", "source_hash", "updated_at"}),
+	}).Create(translation).Error; err != nil {
+		return nil, false, fmt.Errorf("failed to cache translation: %w", err)
+	}
+	return translation, false, nil
+}
+
+// AutoTranslate 将客户发表的评论翻译为开启自动翻译的客服所需的各目标语言，在后台调用
+func (s *CommentTranslationService) AutoTranslate(ctx context.Context, comment *models.TicketComment) {
+	if _, err := s.translator(); err != nil {
+		return
+	}
+
+	var preferences []models.TranslationPreference
+	if err := s.db.WithContext(ctx).Model(&models.TranslationPreference{}).
+		Joins("JOIN users ON users.id = user_translation_preferences.user_id").
+		Where("user_translation_preferences.auto_translate = ? AND users.status = ? AND users.role NOT IN ?",
+			true, models.UserStatusActive, []string{string(models.RoleCustomer), "user"}).
+		Find(&preferences).Error; err != nil {
+		log.Printf("Failed to load translation preferences: %v", err)
+		return
+	}
+
+	languages := make(map[string]bool)
+	for _, preference := range preferences {
+		resolved, err := s.GetPreference(ctx, preference.UserID)
+		if err != nil {
+			continue
+		}
+		if language, err := normalizeTranslationLanguage(resolved.TargetLanguage); err == nil {
+			languages[language] = true
+		}
+	}
+	for language := range languages {
+		s.translateInBackground(comment, language)
+	}
+}
+
+// autoTranslateInBackground 在后台执行 AutoTranslate
+func (s *CommentTranslationService) autoTranslateInBackground(comment *models.TicketComment) {
+	s.background.Add(1)
+	go func() {
+		defer s.background.Done()
+		s.AutoTranslate(context.Background(), comment)
+	}()
+}
+
+// AttachTranslations 为开启自动翻译的查看者附带客户评论的缓存译文；
+// 尚未翻译的评论在后台翻译，下次加载时返回
+func (s *CommentTranslationService) AttachTranslations(ctx context.Context, viewer CommentViewer, comments []*models.TicketComment) {
+	if viewer.IsCustomer() || len(comments) == 0 {
+		return
+	}
+	preference, err := s.GetPreference(ctx, viewer.UserID)
+	if err != nil || !preference.AutoTranslate {
+		return
+	}
+	language, err := normalizeTranslationLanguage(preference.TargetLanguage)
+	if err != nil {
+		return
+	}
+
+	customerComments := make(map[uint]*models.TicketComment)
+	ids := make([]uint, 0, len(comments))
+	for _, comment := range comments {
+		if comment.User != nil && (CommentViewer{Role: string(comment.User.Role)}).IsCustomer() {
+			customerComments[comment.ID] = comment
+			ids = append(ids, comment.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	var translations []models.CommentTranslation
+	if err := s.db.WithContext(ctx).Where("comment_id IN ? AND target_language = ?", ids, language).
+		Find(&translations).Error; err != nil {
+		log.Printf("Failed to load comment translations: %v", err)
+		return
+	}
+	for i := range translations {
+		translation := &translations[i]
+		comment := customerComments[translation.CommentID]
+		if translation.SourceHash != commentContentHash(comment.Content) {
+			continue
+		}
+		delete(customerComments, comment.ID)
+		if !sameLanguage(translation.SourceLanguage, language) {
+			comment.Translation = translation
+		}
+	}
+
+	if len(customerComments) == 0 {
+		return
+	}
+	if _, err := s.translator(); err != nil {
+		return
+	}
+	for _, comment := range customerComments {
+		s.translateInBackground(comment, language)
+	}
+}
+
+// translateInBackground 在后台翻译评论，同一评论与目标语言同时只有一个请求
+func (s *CommentTranslationService) translateInBackground(comment *models.TicketComment, language string) {
+	key := fmt.Sprintf("%d/%s", comment.ID, language)
+	if _, loaded := s.inflight.LoadOrStore(key, true); loaded {
+		return
+	}
+	snapshot := *comment
+	s.background.Add(1)
+	go func() {
+		defer s.background.Done()
+		defer s.inflight.Delete(key)
+		ctx, cancel := context.WithTimeout(context.Background(), commentTranslationTimeout)
+		defer cancel()
+		if _, _, err := s.translate(ctx, &snapshot, language); err != nil {
+			log.Printf("Failed to translate comment %d to %s: %v", snapshot.ID, language, err)
+		}
+	}()
+}
+
+// normalizeTranslationLanguage 规范化语言代码：主语言小写，地区大写，文字首字母大写，如 zh-cn -> zh-CN，zh-hans -> zh-Hans
+func normalizeTranslationLanguage(language string) (string, error) {
+	language = strings.TrimSpace(strings.ReplaceAll(language, "_", "-"))
+	if !translationLanguagePattern.MatchString(language) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidTranslationLanguage, language)
+	}
+	parts := strings.SplitN(language, "-", 2)
+	normalized := strings.ToLower(parts[0])
+	if len(parts) == 2 {
+		switch subtag := parts[1]; len(subtag) {
+		case 4:
+			normalized += "-" + strings.ToUpper(subtag[:1]) + strings.ToLower(subtag[1:])
+		default:
+			normalized += "-" + strings.ToUpper(subtag)
+		}
+	}
+	return normalized, nil
+}
+
+// sameLanguage 比较主语言，如 zh 与 zh-CN 视为相同
+func sameLanguage(a, b string) bool {
+	primary := func(language string) string {
+		return strings.ToLower(strings.SplitN(language, "-", 2)[0])
+	}
+	return a != "" && primary(a) == primary(b)
+}
+
+func commentContentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
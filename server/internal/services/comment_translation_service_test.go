@@ -0,0 +1,213 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"gongdan-system/internal/models"
+)
+
+// fakeTranslator 记录调用次数，译文为 "[目标语言] 原文"
+type fakeTranslator struct {
+	calls int32
+}
+
+func (f *fakeTranslator) Provider() models.TranslationProvider {
+	return models.TranslationProviderDeepL
+}
+
+func (f *fakeTranslator) Translate(ctx context.Context, text, targetLanguage string) (*TranslationResult, error) {
+	atomic.AddInt32(&f.calls, 1)
+	return &TranslationResult{Text: "[" + targetLanguage + "] " + text, SourceLanguage: "en"}, nil
+}
+
+func TestCommentTranslation_CachesPerLanguageAndAutoTranslates(t *testing.T) {
+	db := newTestDB(t, &models.User{}, &models.Team{}, &models.Ticket{}, &models.TicketComment{}, &models.SystemConfig{},
+		&models.CommentTranslation{}, &models.TranslationPreference{})
+	// 后台翻译与测试并发写库，共享缓存的内存库并发写会返回 table is locked，限制为单连接
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("failed to get sqlite connection: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	ctx := context.Background()
+	comments := NewTicketCommentService(db, nil)
+	svc := comments.TranslationService()
+	t.Cleanup(svc.background.Wait) // 关闭测试库前等待后台翻译结束
+	fake := &fakeTranslator{}
+	svc.newTranslator = func(config *TranslatorConfig) (Translator, error) {
+		if config.APIKey == "" {
+			return nil, ErrTranslationNotConfigured
+		}
+		return fake, nil
+	}
+
+	agent := models.User{Username: "tr-agent", Email: "tr-agent@example.com", PasswordHash: "x", Role: models.RoleAgent, Status: models.UserStatusActive, Language: "zh-CN"}
+	customer := models.User{Username: "tr-customer", Email: "tr-customer@example.com", PasswordHash: "x", Role: models.RoleCustomer, Status: models.UserStatusActive, Language: "en"}
+	for _, user := range []*models.User{&agent, &customer} {
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("failed to seed user: %v", err)
+		}
+	}
+	ticket := models.Ticket{TicketNumber: "TR-1", Title: "Login issue", Status: models.TicketStatusOpen, Priority: models.TicketPriorityNormal,
+		Type: models.TicketTypeRequest, Source: models.TicketSourceWeb, CreatedByID: customer.ID}
+	if err := db.Create(&ticket).Error; err != nil {
+		t.Fatalf("failed to seed ticket: %v", err)
+	}
+	agentViewer := CommentViewer{UserID: agent.ID, Role: string(models.RoleAgent)}
+	customerViewer := CommentViewer{UserID: customer.ID, Role: string(models.RoleCustomer)}
+
+	comment, err := comments.CreateComment(ctx, ticket.ID, &models.TicketCommentCreateRequest{Content: "I cannot log in"}, customerViewer)
+	if err != nil {
+		t.Fatalf("create comment failed: %v", err)
+	}
+
+	// 未启用时返回未配置
+	if _, _, err := svc.TranslateComment(ctx, comment.ID, "zh-CN", agentViewer); !errors.Is(err, ErrTranslationNotConfigured) {
+		t.Fatalf("expected translation not configured, got %v", err)
+	}
+	if err := svc.configService.SetConfig(KeyTranslationEnabled, "true", "bool", "", CategoryTicket, "translation"); err != nil {
+		t.Fatalf("failed to enable translation: %v", err)
+	}
+	if err := svc.configService.SetConfig(KeyTranslationAPIKey, "secret", "string", "", CategoryTicket, "translation"); err != nil {
+		t.Fatalf("failed to set translation api key: %v", err)
+	}
+
+	translation, cached, err := svc.TranslateComment(ctx, comment.ID, "zh-cn", agentViewer)
+	if err != nil || cached || translation.Content != "[zh-CN] I cannot log in" || translation.SourceLanguage != "en" {
+		t.Fatalf("unexpected translation %+v cached=%v err=%v", translation, cached, err)
+	}
+	if _, cached, _ := svc.TranslateComment(ctx, comment.ID, "zh-CN", agentViewer); !cached || atomic.LoadInt32(&fake.calls) != 1 {
+		t.Fatalf("expected cached translation, calls=%d", fake.calls)
+	}
+	// 未指定目标语言时使用界面语言，与上面的缓存相同
+	if translation, cached, _ := svc.TranslateComment(ctx, comment.ID, "", agentViewer); !cached || translation.TargetLanguage != "zh-CN" {
+		t.Fatalf("expected target language from user language, got %+v", translation)
+	}
+	if _, _, err := svc.TranslateComment(ctx, comment.ID, "not a language", agentViewer); !errors.Is(err, ErrInvalidTranslationLanguage) {
+		t.Fatalf("expected invalid language error, got %v", err)
+	}
+
+	// 原文修改后重新翻译
+	if err := db.Model(&models.TicketComment{}).Where("id = ?", comment.ID).Update("content", "I still cannot log in").Error; err != nil {
+		t.Fatalf("failed to update comment: %v", err)
+	}
+	if translation, cached, _ := svc.TranslateComment(ctx, comment.ID, "zh-CN", agentViewer); cached || !strings.Contains(translation.Content, "still") {
+		t.Fatalf("expected stale cache to be refreshed, got %+v", translation)
+	}
+
+	// 客户看不到内部评论，也不能翻译
+	internal, err := comments.CreateComment(ctx, ticket.ID, &models.TicketCommentCreateRequest{Content: "check logs", Type: models.CommentTypeInternal}, agentViewer)
+	if err != nil {
+		t.Fatalf("create internal comment failed: %v", err)
+	}
+	if _, _, err := svc.TranslateComment(ctx, internal.ID, "en", customerViewer); !errors.Is(err, ErrCommentNotFound) {
+		t.Fatalf("expected internal comment to be hidden from customer, got %v", err)
+	}
+
+	// 开启自动翻译后，客户的新评论在后台翻译为客服的目标语言，列表中附带译文
+	enabled, language := true, "ja"
+	preference, err := svc.UpdatePreference(ctx, agent.ID, &models.TranslationPreferenceRequest{AutoTranslate: &enabled, TargetLanguage: &language})
+	if err != nil || !preference.AutoTranslate || preference.TargetLanguage != "ja" {
+		t.Fatalf("unexpected preference %+v (%v)", preference, err)
+	}
+	incoming, err := comments.CreateComment(ctx, ticket.ID, &models.TicketCommentCreateRequest{Content: "Any update?"}, customerViewer)
+	if err != nil {
+		t.Fatalf("create comment failed: %v", err)
+	}
+	svc.background.Wait()
+	var count int64
+	if err := db.Model(&models.CommentTranslation{}).Where("comment_id = ? AND target_language = ?", incoming.ID, "ja").Count(&count).Error; err != nil || count != 1 {
+		t.Fatalf("expected incoming customer comment to be translated in background, got %d (%v)", count, err)
+	}
+
+	list, _, err := comments.ListComments(ctx, ticket.ID, agentViewer, true, 1, 20)
+	if err != nil {
+		t.Fatalf("list comments failed: %v", err)
+	}
+	for _, c := range list {
+		switch c.ID {
+		case incoming.ID:
+			if c.Translation == nil || c.Translation.Content != "[ja] Any update?" {
+				t.Fatalf("expected translation attached to customer comment, got %+v", c.Translation)
+			}
+		case internal.ID:
+			if c.Translation != nil {
+				t.Fatalf("agent comments should not be auto-translated")
+			}
+		}
+	}
+	customerList, _, _ := comments.ListComments(ctx, ticket.ID, customerViewer, false, 1, 20)
+	for _, c := range customerList {
+		if c.Translation != nil {
+			t.Fatalf("customers should not receive auto translations")
+		}
+	}
+}
+
+func TestTranslators_ProviderRequests(t *testing.T) {
+	var got *http.Request
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		data := make([]byte, r.ContentLength)
+		r.Body.Read(data)
+		body = string(data)
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/deepl"):
+			json.NewEncoder(w).Encode(map[string]interface{}{"translations": []map[string]string{{"detected_source_language": "EN", "text": "你好"}}})
+		case strings.HasPrefix(r.URL.Path, "/google"):
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"translations": []map[string]string{{"translatedText": "你好", "detectedSourceLanguage": "en"}}}})
+		case strings.HasPrefix(r.URL.Path, "/azure"):
+			json.NewEncoder(w).Encode([]map[string]interface{}{{"detectedLanguage": map[string]string{"language": "en"}, "translations": []map[string]string{{"text": "你好"}}}})
+		default:
+			http.Error(w, "quota exceeded", http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	cases := []struct {
+		provider models.TranslationProvider
+		path     string
+		check    func() bool
+	}{
+		{models.TranslationProviderDeepL, "/deepl", func() bool {
+			return got.Header.Get("Authorization") == "DeepL-Auth-Key k" && strings.Contains(body, `"target_lang":"ZH-HANS"`)
+		}},
+		{models.TranslationProviderGoogle, "/google", func() bool {
+			return got.URL.Query().Get("key") == "k" && strings.Contains(body, `"target":"zh-CN"`)
+		}},
+		{models.TranslationProviderAzure, "/azure", func() bool {
+			return got.Header.Get("Ocp-Apim-Subscription-Key") == "k" && got.Header.Get("Ocp-Apim-Subscription-Region") == "eastasia" &&
+				got.URL.Query().Get("to") == "zh-Hans"
+		}},
+	}
+	for _, tc := range cases {
+		translator, err := NewTranslator(&TranslatorConfig{Provider: tc.provider, APIKey: "k", Region: "eastasia", Endpoint: server.URL + tc.path}, server.Client())
+		if err != nil {
+			t.Fatalf("%s: create translator failed: %v", tc.provider, err)
+		}
+		result, err := translator.Translate(context.Background(), "hello", "zh-CN")
+		if err != nil || result.Text != "你好" || result.SourceLanguage != "en" {
+			t.Fatalf("%s: unexpected result %+v (%v)", tc.provider, result, err)
+		}
+		if !tc.check() {
+			t.Fatalf("%s: unexpected request %s %s", tc.provider, got.URL.String(), body)
+		}
+	}
+
+	translator, _ := NewTranslator(&TranslatorConfig{Provider: models.TranslationProviderDeepL, APIKey: "k", Endpoint: server.URL + "/error"}, server.Client())
+	if _, err := translator.Translate(context.Background(), "hello", "de"); !errors.Is(err, ErrTranslationFailed) {
+		t.Fatalf("expected provider error, got %v", err)
+	}
+	if _, err := NewTranslator(&TranslatorConfig{Provider: "babel", APIKey: "k"}, nil); !errors.Is(err, ErrTranslationNotConfigured) {
+		t.Fatalf("expected unknown provider error, got %v", err)
+	}
+}
@@ -0,0 +1,291 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+// KeySystemConcurrencyLimits 高开销接口并发限流配置键
+const KeySystemConcurrencyLimits = "system.concurrency_limits"
+
+// concurrencyPolicyCacheTTL 限流配置缓存时间，请求路径上只读缓存
+const concurrencyPolicyCacheTTL = 10 * time.Second
+
+var (
+	// ErrConcurrencyQueueFull 排队已满，请求被直接拒绝
+	ErrConcurrencyQueueFull = errors.New("too many concurrent requests")
+	// ErrConcurrencyQueueTimeout 排队等待超时
+	ErrConcurrencyQueueTimeout = errors.New("timed out waiting for a free slot")
+)
+
+// ConcurrencyLimiter 按路由分组的并发限流器：超出并发上限的请求先排队，排队满或超时则拒绝
+type ConcurrencyLimiter struct {
+	db *gorm.DB
+
+	mu       sync.RWMutex
+	cached   *models.ConcurrencyLimitPolicy
+	cachedAt time.Time
+
+	groupsMu sync.Mutex
+	groups   map[string]*concurrencyGroup
+}
+
+// concurrencyGroup 单个分组的信号量及统计
+type concurrencyGroup struct {
+	mu      sync.Mutex
+	active  int
+	waiters []chan struct{} // 先进先出
+
+	admitted      int64
+	queuedTotal   int64
+	rejectedFull  int64
+	rejectedTimed int64
+}
+
+// ConcurrencyGroupMetrics 分组运行指标
+type ConcurrencyGroupMetrics struct {
+	Group         string `json:"group"`
+	MaxConcurrent int    `json:"max_concurrent"`
+	MaxQueue      int    `json:"max_queue"`
+	Active        int    `json:"active"`
+	Queued        int    `json:"queued"`
+	Admitted      int64  `json:"admitted"`         // 累计放行（含排队后放行）
+	QueuedTotal   int64  `json:"queued_total"`     // 累计进入排队的请求
+	RejectedFull  int64  `json:"rejected_full"`    // 排队已满被拒绝（429）
+	RejectedTimed int64  `json:"rejected_timeout"` // 排队超时被拒绝（503）
+}
+
+// NewConcurrencyLimiter 创建并发限流器
+func NewConcurrencyLimiter(db *gorm.DB) *ConcurrencyLimiter {
+	return &ConcurrencyLimiter{
+		db:     db,
+		groups: make(map[string]*concurrencyGroup),
+	}
+}
+
+// GetPolicy 从配置存储读取并发限流策略
+func (l *ConcurrencyLimiter) GetPolicy(ctx context.Context) (*models.ConcurrencyLimitPolicy, error) {
+	var config models.SystemConfig
+	err := l.db.WithContext(ctx).
+		Where("key = ? AND is_active = ?", KeySystemConcurrencyLimits, true).
+		First(&config).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.GetDefaultConcurrencyLimitPolicy(), nil
+		}
+		return nil, fmt.Errorf("failed to get concurrency limits: %w", err)
+	}
+
+	policy := models.GetDefaultConcurrencyLimitPolicy()
+	policy.Groups = nil
+	if err := config.GetJSONValue(policy); err != nil {
+		log.Printf("Warning: failed to parse concurrency limits, using defaults: %v", err)
+		return models.GetDefaultConcurrencyLimitPolicy(), nil
+	}
+	if err := policy.Validate(); err != nil {
+		log.Printf("Warning: invalid concurrency limits, using defaults: %v", err)
+		return models.GetDefaultConcurrencyLimitPolicy(), nil
+	}
+	return policy, nil
+}
+
+// CurrentPolicy 获取缓存的并发限流策略；读取失败时沿用上一次的配置
+func (l *ConcurrencyLimiter) CurrentPolicy(ctx context.Context) *models.ConcurrencyLimitPolicy {
+	l.mu.RLock()
+	cached, cachedAt := l.cached, l.cachedAt
+	l.mu.RUnlock()
+
+	if cached != nil && time.Since(cachedAt) < concurrencyPolicyCacheTTL {
+		return cached
+	}
+
+	policy, err := l.GetPolicy(ctx)
+	if err != nil {
+		log.Printf("Warning: failed to refresh concurrency limits: %v", err)
+		if cached != nil {
+			return cached
+		}
+		return models.GetDefaultConcurrencyLimitPolicy()
+	}
+
+	l.mu.Lock()
+	l.cached = policy
+	l.cachedAt = time.Now()
+	l.mu.Unlock()
+
+	return policy
+}
+
+// SetPolicy 保存并发限流策略并刷新缓存，新上限对后续请求立即生效
+func (l *ConcurrencyLimiter) SetPolicy(ctx context.Context, policy *models.ConcurrencyLimitPolicy, userID uint) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	var existing models.SystemConfig
+	err := l.db.WithContext(ctx).Where("key = ?", KeySystemConcurrencyLimits).First(&existing).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to check existing concurrency limits: %w", err)
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		config := models.SystemConfig{
+			Key:         KeySystemConcurrencyLimits,
+			Category:    CategorySystem,
+			Group:       "performance",
+			Description: "高开销接口并发限流",
+			IsActive:    true,
+			UpdatedBy:   &userID,
+		}
+		if err := config.SetValue(policy); err != nil {
+			return fmt.Errorf("failed to set concurrency limits value: %w", err)
+		}
+		if err := l.db.WithContext(ctx).Create(&config).Error; err != nil {
+			return fmt.Errorf("failed to create concurrency limits: %w", err)
+		}
+	} else {
+		if err := existing.SetValue(policy); err != nil {
+			return fmt.Errorf("failed to set concurrency limits value: %w", err)
+		}
+		existing.UpdatedBy = &userID
+		existing.Version++
+
+		if err := l.db.WithContext(ctx).Save(&existing).Error; err != nil {
+			return fmt.Errorf("failed to update concurrency limits: %w", err)
+		}
+	}
+
+	l.mu.Lock()
+	l.cached = policy
+	l.cachedAt = time.Now()
+	l.mu.Unlock()
+
+	return nil
+}
+
+// Acquire 为分组申请一个并发名额，成功时返回的 release 必须调用；
+// 限流关闭或分组未配置时直接放行
+func (l *ConcurrencyLimiter) Acquire(ctx context.Context, group string) (release func(), limit *models.ConcurrencyLimit, err error) {
+	policy := l.CurrentPolicy(ctx)
+	limit = policy.Groups[group]
+	if !policy.Enabled || limit == nil {
+		return func() {}, limit, nil
+	}
+
+	g := l.group(group)
+	g.mu.Lock()
+	if g.active < limit.MaxConcurrent {
+		g.active++
+		g.mu.Unlock()
+		atomic.AddInt64(&g.admitted, 1)
+		return l.releaser(group, g), limit, nil
+	}
+	if len(g.waiters) >= limit.MaxQueue {
+		g.mu.Unlock()
+		atomic.AddInt64(&g.rejectedFull, 1)
+		return nil, limit, ErrConcurrencyQueueFull
+	}
+	ch := make(chan struct{})
+	g.waiters = append(g.waiters, ch)
+	g.mu.Unlock()
+	atomic.AddInt64(&g.queuedTotal, 1)
+
+	timer := time.NewTimer(time.Duration(limit.QueueTimeoutMs) * time.Millisecond)
+	defer timer.Stop()
+
+	select {
+	case <-ch:
+		atomic.AddInt64(&g.admitted, 1)
+		return l.releaser(group, g), limit, nil
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	g.mu.Lock()
+	for i, w := range g.waiters {
+		if w == ch {
+			g.waiters = append(g.waiters[:i], g.waiters[i+1:]...)
+			g.mu.Unlock()
+			atomic.AddInt64(&g.rejectedTimed, 1)
+			return nil, limit, ErrConcurrencyQueueTimeout
+		}
+	}
+	g.mu.Unlock()
+	// 超时的同时已被唤醒，名额已转交给本请求
+	atomic.AddInt64(&g.admitted, 1)
+	return l.releaser(group, g), limit, nil
+}
+
+// releaser 释放名额并按当前上限唤醒排队的请求，调整上限后逐步收敛
+func (l *ConcurrencyLimiter) releaser(group string, g *concurrencyGroup) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			max := 0
+			if limit := l.CurrentPolicy(context.Background()).Groups[group]; limit != nil {
+				max = limit.MaxConcurrent
+			}
+
+			g.mu.Lock()
+			defer g.mu.Unlock()
+			g.active--
+			for len(g.waiters) > 0 && (g.active < max || max == 0) {
+				ch := g.waiters[0]
+				g.waiters = g.waiters[1:]
+				g.active++
+				close(ch)
+			}
+		})
+	}
+}
+
+func (l *ConcurrencyLimiter) group(name string) *concurrencyGroup {
+	l.groupsMu.Lock()
+	defer l.groupsMu.Unlock()
+	g, ok := l.groups[name]
+	if !ok {
+		g = &concurrencyGroup{}
+		l.groups[name] = g
+	}
+	return g
+}
+
+// Metrics 获取各分组的运行指标
+func (l *ConcurrencyLimiter) Metrics(ctx context.Context) []*ConcurrencyGroupMetrics {
+	policy := l.CurrentPolicy(ctx)
+
+	names := make([]string, 0, len(policy.Groups))
+	for name := range policy.Groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	metrics := make([]*ConcurrencyGroupMetrics, 0, len(names))
+	for _, name := range names {
+		g := l.group(name)
+		g.mu.Lock()
+		m := &ConcurrencyGroupMetrics{
+			Group:         name,
+			MaxConcurrent: policy.Groups[name].MaxConcurrent,
+			MaxQueue:      policy.Groups[name].MaxQueue,
+			Active:        g.active,
+			Queued:        len(g.waiters),
+		}
+		g.mu.Unlock()
+		m.Admitted = atomic.LoadInt64(&g.admitted)
+		m.QueuedTotal = atomic.LoadInt64(&g.queuedTotal)
+		m.RejectedFull = atomic.LoadInt64(&g.rejectedFull)
+		m.RejectedTimed = atomic.LoadInt64(&g.rejectedTimed)
+		metrics = append(metrics, m)
+	}
+	return metrics
+}
@@ -0,0 +1,359 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// configCacheLocalTTL 本实例缓存的最长保留时间，失效通知丢失时的兜底
+	configCacheLocalTTL = 10 * time.Minute
+	// configCacheRedisTTL Redis 中共享缓存的过期时间，旧版本的缓存随之过期
+	configCacheRedisTTL = time.Hour
+	// configVersionPollInterval 未订阅到失效通知时轮询配置版本的间隔
+	configVersionPollInterval = 5 * time.Second
+	// configSubscribeRetryInterval 订阅断开后重新订阅的间隔
+	configSubscribeRetryInterval = 30 * time.Second
+	// configCacheTopKeys 统计中列出的查询最频繁的配置数
+	configCacheTopKeys = 10
+)
+
+// ConfigCacheStore 配置共享缓存存储，database.ResilientRedis 满足该接口
+type ConfigCacheStore interface {
+	CacheStore
+	Incr(ctx context.Context, key string) (int64, error)
+}
+
+// ConfigCacheBus 跨实例的配置失效通知，database.ResilientRedis 满足该接口
+type ConfigCacheBus interface {
+	Publish(ctx context.Context, channel, message string) error
+	Subscribe(ctx context.Context, channel string) (<-chan string, error)
+}
+
+// configCacheEntry 缓存的配置值，found 为 false 表示配置不存在（同样缓存，避免反复查询）
+type configCacheEntry struct {
+	Value   string `json:"value"`
+	Found   bool   `json:"found"`
+	version int64
+	expires time.Time
+}
+
+// ConfigLookupRate 单个配置的查询次数
+type ConfigLookupRate struct {
+	Key       string  `json:"key"`
+	Lookups   int64   `json:"lookups"`
+	PerMinute float64 `json:"per_minute"`
+}
+
+// ConfigCacheStats 配置缓存统计
+type ConfigCacheStats struct {
+	Since               time.Time          `json:"since"`
+	Lookups             int64              `json:"lookups"`
+	LookupsPerMinute    float64            `json:"lookups_per_minute"`
+	LocalHits           int64              `json:"local_hits"`
+	RedisHits           int64              `json:"redis_hits"`
+	DBLoads             int64              `json:"db_loads"` // 未命中或 Redis 不可用，均回源数据库
+	HitRate             float64            `json:"hit_rate"`
+	Invalidations       int64              `json:"invalidations"`        // 本实例写入配置触发的失效
+	RemoteInvalidations int64              `json:"remote_invalidations"` // 收到其他实例的失效通知或轮询发现版本变化
+	StoreErrors         int64              `json:"store_errors"`
+	Version             int64              `json:"version"`
+	Subscribed          bool               `json:"subscribed"` // false 时按 configVersionPollInterval 轮询版本
+	LocalEntries        int                `json:"local_entries"`
+	TopKeys             []ConfigLookupRate `json:"top_keys"`
+}
+
+// ConfigCache 配置读穿缓存：本实例内存 → Redis → 数据库。
+// 缓存按配置版本区分，任一实例写入配置后版本加一并通过 Redis 发布订阅通知其他实例，
+// 旧版本的缓存即全部失效；Redis 不可用时退回本实例内存缓存，写入时清空本实例缓存
+type ConfigCache struct {
+	store  ConfigCacheStore
+	bus    ConfigCacheBus
+	prefix string
+	now    func() time.Time
+
+	version    atomic.Int64
+	subscribed atomic.Bool
+	lastPoll   atomic.Int64
+	// staleShared 版本号加一失败时记录当时的版本（加一存储，0 表示无），该版本在 Redis 中的缓存不再可信
+	staleShared atomic.Int64
+
+	mu      sync.RWMutex
+	entries map[string]*configCacheEntry
+
+	statsMu   sync.Mutex
+	since     time.Time
+	keyCounts map[string]int64
+
+	lookups             atomic.Int64
+	localHits           atomic.Int64
+	redisHits           atomic.Int64
+	dbLoads             atomic.Int64
+	invalidations       atomic.Int64
+	remoteInvalidations atomic.Int64
+	storeErrors         atomic.Int64
+}
+
+// NewConfigCache 创建配置缓存，store 或 bus 为 nil 时只使用本实例内存缓存
+func NewConfigCache(store ConfigCacheStore, bus ConfigCacheBus, prefix string) *ConfigCache {
+	c := &ConfigCache{
+		store:     store,
+		bus:       bus,
+		prefix:    prefix,
+		now:       time.Now,
+		entries:   make(map[string]*configCacheEntry),
+		keyCounts: make(map[string]int64),
+	}
+	c.since = c.now()
+	return c
+}
+
+// sharedConfigCache 进程内共享的配置缓存，未设置时同一数据库连接的 ConfigService 共用一个内存缓存
+var sharedConfigCache *ConfigCache
+
+// localConfigCaches 未注册共享缓存时按数据库连接区分的内存缓存
+var localConfigCaches sync.Map
+
+// configCacheFor 返回 ConfigService 使用的缓存
+func configCacheFor(db *gorm.DB) *ConfigCache {
+	if sharedConfigCache != nil {
+		return sharedConfigCache
+	}
+	cache, _ := localConfigCaches.LoadOrStore(db, NewConfigCache(nil, nil, ""))
+	return cache.(*ConfigCache)
+}
+
+// SetConfigCache 注册进程内共享的配置缓存，之后创建的 ConfigService 都使用该缓存
+func SetConfigCache(cache *ConfigCache) {
+	sharedConfigCache = cache
+}
+
+// InvalidateConfigCache 绕过 ConfigService 直接写入系统配置后调用，使共享缓存失效
+func InvalidateConfigCache(ctx context.Context) {
+	if sharedConfigCache != nil {
+		sharedConfigCache.Invalidate(ctx)
+	}
+}
+
+func (c *ConfigCache) versionKey() string {
+	return c.prefix + ":version"
+}
+
+func (c *ConfigCache) channel() string {
+	return c.prefix + ":invalidate"
+}
+
+func (c *ConfigCache) entryKey(version int64, key string) string {
+	return fmt.Sprintf("%s:v%d:%s", c.prefix, version, key)
+}
+
+// Start 读取当前配置版本并订阅失效通知，订阅断开或 Redis 不可用时定期重试
+func (c *ConfigCache) Start(ctx context.Context) {
+	if c.store == nil {
+		return
+	}
+	c.pollVersion(ctx)
+	if c.bus == nil {
+		return
+	}
+	go func() {
+		for {
+			messages, err := c.bus.Subscribe(ctx, c.channel())
+			if err == nil {
+				c.subscribed.Store(true)
+				// 订阅建立前可能错过通知
+				c.pollVersion(ctx)
+				for message := range messages {
+					if version, err := strconv.ParseInt(message, 10, 64); err == nil {
+						c.observeVersion(version)
+					}
+				}
+				c.subscribed.Store(false)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(configSubscribeRetryInterval):
+			}
+		}
+	}()
+}
+
+// observeVersion 记录其他实例发布的新版本
+func (c *ConfigCache) observeVersion(version int64) {
+	for {
+		current := c.version.Load()
+		if version <= current {
+			return
+		}
+		if c.version.CompareAndSwap(current, version) {
+			c.remoteInvalidations.Add(1)
+			return
+		}
+	}
+}
+
+// pollVersion 从 Redis 读取配置版本
+func (c *ConfigCache) pollVersion(ctx context.Context) {
+	c.lastPoll.Store(c.now().UnixNano())
+	raw, err := c.store.Get(ctx, c.versionKey())
+	if err != nil {
+		return
+	}
+	if version, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		c.observeVersion(version)
+	}
+}
+
+// maybePollVersion 未订阅到失效通知时按间隔轮询版本
+func (c *ConfigCache) maybePollVersion(ctx context.Context) {
+	if c.store == nil || c.subscribed.Load() {
+		return
+	}
+	last := c.lastPoll.Load()
+	if c.now().UnixNano()-last < int64(configVersionPollInterval) {
+		return
+	}
+	if c.lastPoll.CompareAndSwap(last, c.now().UnixNano()) {
+		c.pollVersion(ctx)
+	}
+}
+
+// Get 读取配置；缓存未命中时调用 load 从数据库加载，found 为 false 表示配置不存在
+func (c *ConfigCache) Get(ctx context.Context, key string, load func() (value string, found bool, err error)) (string, bool, error) {
+	c.lookups.Add(1)
+	c.statsMu.Lock()
+	c.keyCounts[key]++
+	c.statsMu.Unlock()
+
+	c.maybePollVersion(ctx)
+	version := c.version.Load()
+
+	c.mu.RLock()
+	entry, ok := c.entries[key]
+	c.mu.RUnlock()
+	if ok && entry.version == version && c.now().Before(entry.expires) {
+		c.localHits.Add(1)
+		return entry.Value, entry.Found, nil
+	}
+
+	useShared := c.store != nil && c.staleShared.Load() != version+1
+	if useShared {
+		if raw, err := c.store.Get(ctx, c.entryKey(version, key)); err == nil {
+			var shared configCacheEntry
+			if json.Unmarshal([]byte(raw), &shared) == nil {
+				c.redisHits.Add(1)
+				c.storeLocal(key, &shared, version)
+				return shared.Value, shared.Found, nil
+			}
+		}
+	}
+
+	c.dbLoads.Add(1)
+	value, found, err := load()
+	if err != nil {
+		return "", false, err
+	}
+	loaded := &configCacheEntry{Value: value, Found: found}
+	c.storeLocal(key, loaded, version)
+	if useShared {
+		data, _ := json.Marshal(loaded)
+		if err := c.store.Set(ctx, c.entryKey(version, key), string(data), configCacheRedisTTL); err != nil {
+			c.storeErrors.Add(1)
+		}
+	}
+	return value, found, nil
+}
+
+// storeLocal 写入本实例缓存；加载期间版本已变化的结果不缓存
+func (c *ConfigCache) storeLocal(key string, entry *configCacheEntry, version int64) {
+	if c.version.Load() != version {
+		return
+	}
+	entry.version = version
+	entry.expires = c.now().Add(configCacheLocalTTL)
+	c.mu.Lock()
+	c.entries[key] = entry
+	c.mu.Unlock()
+}
+
+// Invalidate 配置写入数据库后调用：配置版本加一并通知其他实例。
+// Redis 不可用时只清空本实例缓存，其他实例的缓存随 configCacheLocalTTL 过期
+func (c *ConfigCache) Invalidate(ctx context.Context) {
+	c.invalidations.Add(1)
+	c.mu.Lock()
+	c.entries = make(map[string]*configCacheEntry)
+	c.mu.Unlock()
+
+	if c.store == nil {
+		c.version.Add(1)
+		return
+	}
+	version, err := c.store.Incr(ctx, c.versionKey())
+	if err != nil {
+		c.storeErrors.Add(1)
+		c.staleShared.Store(c.version.Load() + 1)
+		log.Printf("Failed to bump config cache version, only local cache cleared: %v", err)
+		return
+	}
+	c.observeVersion(version)
+	if c.bus != nil {
+		if err := c.bus.Publish(ctx, c.channel(), strconv.FormatInt(version, 10)); err != nil {
+			c.storeErrors.Add(1)
+		}
+	}
+}
+
+// Stats 返回命中统计及查询频率
+func (c *ConfigCache) Stats() ConfigCacheStats {
+	stats := ConfigCacheStats{
+		Lookups:             c.lookups.Load(),
+		LocalHits:           c.localHits.Load(),
+		RedisHits:           c.redisHits.Load(),
+		DBLoads:             c.dbLoads.Load(),
+		Invalidations:       c.invalidations.Load(),
+		RemoteInvalidations: c.remoteInvalidations.Load(),
+		StoreErrors:         c.storeErrors.Load(),
+		Version:             c.version.Load(),
+		Subscribed:          c.subscribed.Load(),
+		TopKeys:             []ConfigLookupRate{},
+	}
+	if stats.Lookups > 0 {
+		stats.HitRate = float64(stats.LocalHits+stats.RedisHits) / float64(stats.Lookups)
+	}
+	c.mu.RLock()
+	stats.LocalEntries = len(c.entries)
+	c.mu.RUnlock()
+
+	c.statsMu.Lock()
+	stats.Since = c.since
+	minutes := c.now().Sub(c.since).Minutes()
+	if minutes < 1 {
+		minutes = 1
+	}
+	for key, count := range c.keyCounts {
+		stats.TopKeys = append(stats.TopKeys, ConfigLookupRate{Key: key, Lookups: count, PerMinute: float64(count) / minutes})
+	}
+	c.statsMu.Unlock()
+
+	stats.LookupsPerMinute = float64(stats.Lookups) / minutes
+	sort.Slice(stats.TopKeys, func(i, j int) bool {
+		if stats.TopKeys[i].Lookups != stats.TopKeys[j].Lookups {
+			return stats.TopKeys[i].Lookups > stats.TopKeys[j].Lookups
+		}
+		return stats.TopKeys[i].Key < stats.TopKeys[j].Key
+	})
+	if len(stats.TopKeys) > configCacheTopKeys {
+		stats.TopKeys = stats.TopKeys[:configCacheTopKeys]
+	}
+	return stats
+}
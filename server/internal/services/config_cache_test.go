@@ -0,0 +1,196 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// fakeConfigRedis 多个实例共享的内存 Redis，支持发布订阅
+type fakeConfigRedis struct {
+	mu          sync.Mutex
+	down        bool
+	data        map[string]string
+	subscribers []chan string
+}
+
+func (f *fakeConfigRedis) Get(ctx context.Context, key string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.down {
+		return "", errors.New("redis unavailable")
+	}
+	value, ok := f.data[key]
+	if !ok {
+		return "", errors.New("key not found")
+	}
+	return value, nil
+}
+
+func (f *fakeConfigRedis) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.down {
+		return errors.New("redis unavailable")
+	}
+	f.data[key] = value.(string)
+	return nil
+}
+
+func (f *fakeConfigRedis) Del(ctx context.Context, keys ...string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, key := range keys {
+		delete(f.data, key)
+	}
+	return nil
+}
+
+func (f *fakeConfigRedis) Incr(ctx context.Context, key string) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.down {
+		return 0, errors.New("redis unavailable")
+	}
+	value, _ := strconv.ParseInt(f.data[key], 10, 64)
+	value++
+	f.data[key] = strconv.FormatInt(value, 10)
+	return value, nil
+}
+
+func (f *fakeConfigRedis) Publish(ctx context.Context, channel, message string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, subscriber := range f.subscribers {
+		subscriber <- message
+	}
+	return nil
+}
+
+func (f *fakeConfigRedis) Subscribe(ctx context.Context, channel string) (<-chan string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	messages := make(chan string, 16)
+	f.subscribers = append(f.subscribers, messages)
+	return messages, nil
+}
+
+func TestConfigCache_SharesAndInvalidatesAcrossInstances(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:config_cache_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.SystemConfig{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	redis := &fakeConfigRedis{data: map[string]string{}}
+	cacheA := NewConfigCache(redis, redis, "cache:config")
+	cacheB := NewConfigCache(redis, redis, "cache:config")
+	cacheA.Start(ctx)
+	cacheB.Start(ctx)
+	instanceA := &ConfigService{db: db, cache: cacheA}
+	instanceB := &ConfigService{db: db, cache: cacheB}
+
+	if err := instanceA.SetConfig(KeyTrustedDeviceTTLHours, "720", "int", "", CategorySecurity, ""); err != nil {
+		t.Fatalf("set config failed: %v", err)
+	}
+	waitForVersion := func(cache *ConfigCache, version int64) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for cache.Stats().Version < version {
+			if time.Now().After(deadline) {
+				t.Fatalf("expected cache to reach version %d, got %d", version, cache.Stats().Version)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	waitForVersion(cacheB, 1)
+
+	// A 回源数据库并写入 Redis，之后本实例命中；B 从 Redis 命中
+	for i := 0; i < 3; i++ {
+		if hours, err := instanceA.GetConfigInt(KeyTrustedDeviceTTLHours); err != nil || hours != 720 {
+			t.Fatalf("unexpected config %d, %v", hours, err)
+		}
+	}
+	if hours, _ := instanceB.GetConfigInt(KeyTrustedDeviceTTLHours); hours != 720 {
+		t.Fatalf("unexpected config on instance B %d", hours)
+	}
+	statsA, statsB := cacheA.Stats(), cacheB.Stats()
+	if statsA.DBLoads != 1 || statsA.LocalHits != 2 || statsB.RedisHits != 1 || statsB.DBLoads != 0 {
+		t.Fatalf("unexpected cache stats A=%+v B=%+v", statsA, statsB)
+	}
+
+	// 不存在的配置同样缓存
+	for i := 0; i < 2; i++ {
+		if value := instanceA.GetConfigWithDefault("security.not_configured", "fallback"); value != "fallback" {
+			t.Fatalf("unexpected default %q", value)
+		}
+	}
+	if cacheA.Stats().DBLoads != 2 {
+		t.Fatalf("expected missing config to be cached, got %+v", cacheA.Stats())
+	}
+
+	// B 写入后通过发布订阅通知 A，A 不再返回旧值
+	if err := instanceB.SetConfig(KeyTrustedDeviceTTLHours, "24", "int", "", CategorySecurity, ""); err != nil {
+		t.Fatalf("set config failed: %v", err)
+	}
+	waitForVersion(cacheA, 2)
+	if hours, _ := instanceA.GetConfigInt(KeyTrustedDeviceTTLHours); hours != 24 {
+		t.Fatalf("expected invalidated value on instance A, got %d", hours)
+	}
+	if cacheA.Stats().RemoteInvalidations < 1 || !cacheA.Stats().Subscribed {
+		t.Fatalf("expected remote invalidation, got %+v", cacheA.Stats())
+	}
+
+	// 未订阅的实例按间隔轮询版本
+	cacheC := NewConfigCache(redis, nil, "cache:config")
+	clock := time.Now()
+	cacheC.now = func() time.Time { return clock }
+	cacheC.Start(ctx)
+	instanceC := &ConfigService{db: db, cache: cacheC}
+	if hours, _ := instanceC.GetConfigInt(KeyTrustedDeviceTTLHours); hours != 24 {
+		t.Fatalf("unexpected config on instance C %d", hours)
+	}
+	if err := instanceA.SetConfig(KeyTrustedDeviceTTLHours, "48", "int", "", CategorySecurity, ""); err != nil {
+		t.Fatalf("set config failed: %v", err)
+	}
+	if hours, _ := instanceC.GetConfigInt(KeyTrustedDeviceTTLHours); hours != 24 {
+		t.Fatalf("expected cached value before poll interval, got %d", hours)
+	}
+	clock = clock.Add(configVersionPollInterval)
+	if hours, _ := instanceC.GetConfigInt(KeyTrustedDeviceTTLHours); hours != 48 {
+		t.Fatalf("expected polled invalidation on instance C, got %d", hours)
+	}
+
+	// Redis 不可用时回源数据库，本实例写入仍立即生效
+	redis.mu.Lock()
+	redis.down = true
+	redis.mu.Unlock()
+	if err := instanceA.SetConfig(KeyTrustedDeviceTTLHours, "12", "int", "", CategorySecurity, ""); err != nil {
+		t.Fatalf("set config failed: %v", err)
+	}
+	if hours, err := instanceA.GetConfigInt(KeyTrustedDeviceTTLHours); err != nil || hours != 12 {
+		t.Fatalf("expected fallback to database, got %d, %v", hours, err)
+	}
+	redis.mu.Lock()
+	redis.down = false
+	redis.mu.Unlock()
+	if hours, _ := instanceA.GetConfigInt(KeyTrustedDeviceTTLHours); hours != 12 {
+		t.Fatalf("expected written value after Redis recovers, got %d", hours)
+	}
+
+	stats := cacheA.Stats()
+	if stats.Lookups == 0 || stats.LookupsPerMinute <= 0 || len(stats.TopKeys) == 0 || stats.TopKeys[0].Key != KeyTrustedDeviceTTLHours {
+		t.Fatalf("unexpected lookup metrics %+v", stats)
+	}
+}
@@ -0,0 +1,270 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"gongdan-system/internal/models"
+)
+
+var (
+	// ErrUnknownConfigKey 配置键未在配置定义中登记
+	ErrUnknownConfigKey = errors.New("unknown config key")
+	// ErrManagedConfigKey 配置由专用接口维护，不能通过通用配置接口修改
+	ErrManagedConfigKey = errors.New("config key is managed by a dedicated endpoint")
+)
+
+func schemaInt(v int) *int {
+	return &v
+}
+
+// configSchema 系统配置定义，是配置键、类型与默认值的唯一来源。
+// 新增配置项时在此登记，InitDefaultConfigs 据此创建默认配置
+var configSchema = []models.ConfigSchemaEntry{
+	// 系统基础信息
+	{Key: KeySystemName, Type: "string", Default: "工单管理系统", Description: "系统名称", Category: CategorySystem, Group: "basic"},
+	{Key: KeySystemVersion, Type: "string", Default: "1.0.0", Description: "系统版本", Category: CategorySystem, Group: "basic"},
+	{Key: KeySystemDescription, Type: "string", Default: "现代化的工单管理系统", Description: "系统描述", Category: CategorySystem, Group: "basic"},
+	{Key: KeySystemLogo, Type: "string", Default: "", Description: "系统Logo地址", Category: CategorySystem, Group: "basic", Format: models.ConfigFormatURL},
+	{Key: KeySystemCopyright, Type: "string", Default: "", Description: "版权信息", Category: CategorySystem, Group: "basic"},
+	{Key: KeySystemTimezone, Type: "string", Default: "Asia/Shanghai", Description: "系统时区", Category: CategorySystem, Group: "basic", Format: models.ConfigFormatTimezone, RestartRequired: true},
+	{Key: KeyScriptHooksEnabled, Type: "bool", Default: "true", Description: "执行管理员安装的脚本钩子，关闭后所有钩子暂停执行", Category: CategorySystem, Group: "script_hooks"},
+	{Key: KeyScriptHookFailureThreshold, Type: "int", Default: "5", Description: "脚本钩子连续失败多少次后自动停用，0 表示不自动停用", Category: CategorySystem, Group: "script_hooks", Min: schemaInt(0), Max: schemaInt(100)},
+	{Key: KeyPublicStatusEnabled, Type: "bool", Default: "false", Description: "开放无需登录的支持状态接口及 SVG 徽章（未完结工单数、本周平均首次响应时间），可嵌入 Wiki 和看板", Category: CategorySystem, Group: "public_status"},
+
+	// 安全策略
+	{Key: KeyPasswordMinLength, Type: "int", Default: "8", Description: "密码最小长度", Category: CategorySecurity, Group: "password", Min: schemaInt(6), Max: schemaInt(128)},
+	{Key: KeyPasswordRequireUpper, Type: "bool", Default: "true", Description: "密码需要大写字母", Category: CategorySecurity, Group: "password"},
+	{Key: KeyPasswordRequireLower, Type: "bool", Default: "true", Description: "密码需要小写字母", Category: CategorySecurity, Group: "password"},
+	{Key: KeyPasswordRequireDigit, Type: "bool", Default: "true", Description: "密码需要数字", Category: CategorySecurity, Group: "password"},
+	{Key: KeyPasswordRequireSymbol, Type: "bool", Default: "false", Description: "密码需要特殊字符", Category: CategorySecurity, Group: "password"},
+	{Key: KeyMaxLoginAttempts, Type: "int", Default: "5", Description: "最大登录尝试次数", Category: CategorySecurity, Group: "login", Min: schemaInt(1), Max: schemaInt(100)},
+	{Key: KeyLoginLockDuration, Type: "int", Default: "300", Description: "登录锁定时长(秒)", Category: CategorySecurity, Group: "login", Min: schemaInt(0), Max: schemaInt(86400)},
+	{Key: KeySessionTimeout, Type: "int", Default: "3600", Description: "会话超时时长(秒)", Category: CategorySecurity, Group: "session", Min: schemaInt(300), Max: schemaInt(2592000), RestartRequired: true},
+	{Key: KeyTwoFactorRequired, Type: "bool", Default: "false", Description: "是否强制双因子认证", Category: CategorySecurity, Group: "auth"},
+	{Key: KeyTrustedDeviceTTLHours, Type: "int", Default: "720", Description: "可信设备有效期(小时)", Category: CategorySecurity, Group: "trusted_device", Min: schemaInt(1), Max: schemaInt(8760)},
+	{Key: KeyTrustedDeviceMaxPerUser, Type: "int", Default: "5", Description: "每个用户允许的可信设备数量", Category: CategorySecurity, Group: "trusted_device", Min: schemaInt(1), Max: schemaInt(100)},
+	{Key: KeyPasswordResetTTLMinutes, Type: "int", Default: "60", Description: "密码重置链接有效期(分钟)", Category: CategorySecurity, Group: "token", Min: schemaInt(5), Max: schemaInt(1440)},
+	{Key: KeyEmailVerificationTTLHours, Type: "int", Default: "24", Description: "邮箱验证链接有效期(小时)", Category: CategorySecurity, Group: "token", Min: schemaInt(1), Max: schemaInt(720)},
+	{Key: KeyMagicLinkEnabled, Type: "bool", Default: "false", Description: "启用邮件免密登录链接", Category: CategorySecurity, Group: "magic_link"},
+	{Key: KeyMagicLinkTTLMinutes, Type: "int", Default: "15", Description: "免密登录链接有效期(分钟)", Category: CategorySecurity, Group: "magic_link", Min: schemaInt(1), Max: schemaInt(1440)},
+	{Key: KeyMagicLinkMaxPerHour, Type: "int", Default: "5", Description: "每个账户每小时可申请的免密登录链接数量", Category: CategorySecurity, Group: "magic_link", Min: schemaInt(1), Max: schemaInt(100)},
+	{Key: KeyAccountDeletionGraceDays, Type: "int", Default: "14", Description: "账户注销宽限期(天)，期间登录可恢复账户", Category: CategorySecurity, Group: "account_deletion", Min: schemaInt(0), Max: schemaInt(365)},
+	{Key: KeyDataDeletionSigningKey, Type: "string", Default: "", Description: "工单客户数据删除证书的签名密钥，为空时首次删除自动生成", Category: CategorySecurity, Group: "account_deletion", Secret: true},
+	{Key: KeyTicketArchiveSigningKey, Type: "string", Default: "", Description: "工单归档下载链接的签名密钥，为空时首次生成下载链接时自动生成", Category: CategorySecurity, Group: "ticket_archive", Secret: true},
+	{Key: KeyTicketArchiveLinkTTL, Type: "int", Default: "60", Description: "工单归档下载链接有效期(分钟)", Category: CategorySecurity, Group: "ticket_archive", Min: schemaInt(5), Max: schemaInt(1440)},
+	{Key: KeyDownloadSigningKey, Type: "string", Default: "", Description: "下载中心链接的签名密钥，为空时首次生成下载链接时自动生成", Category: CategorySecurity, Group: "downloads", Secret: true},
+	{Key: KeyDownloadLinkTTL, Type: "int", Default: "60", Description: "下载中心签名链接有效期(分钟)", Category: CategorySecurity, Group: "downloads", Min: schemaInt(5), Max: schemaInt(1440)},
+	{Key: KeyPortalRealtimeSigningKey, Type: "string", Default: "", Description: "客户门户实时连接令牌的签名密钥，为空时首次签发自动生成", Category: CategorySecurity, Group: "portal_realtime", Secret: true},
+	{Key: KeyUserInvitationTTLHours, Type: "int", Default: "72", Description: "用户邀请链接有效期(小时)", Category: CategorySecurity, Group: "token", Min: schemaInt(1), Max: schemaInt(720)},
+	{Key: KeySMSEnabled, Type: "bool", Default: "false", Description: "启用短信验证码(手机验证、短信登录验证)", Category: CategorySecurity, Group: "sms"},
+	{Key: KeySMSProvider, Type: "string", Default: "twilio", Description: "短信服务提供方(twilio, aliyun)", Category: CategorySecurity, Group: "sms",
+		Enum: []string{string(models.SMSProviderTwilio), string(models.SMSProviderAliyun)}},
+	{Key: KeySMSAccessKeyID, Type: "string", Default: "", Description: "Twilio Account SID 或阿里云 AccessKey ID", Category: CategorySecurity, Group: "sms"},
+	{Key: KeySMSAccessSecret, Type: "string", Default: "", Description: "Twilio Auth Token 或阿里云 AccessKey Secret", Category: CategorySecurity, Group: "sms", Secret: true},
+	{Key: KeySMSSender, Type: "string", Default: "", Description: "Twilio 发送号码/Messaging Service SID，或阿里云短信签名", Category: CategorySecurity, Group: "sms"},
+	{Key: KeySMSTemplateCode, Type: "string", Default: "", Description: "阿里云短信模板CODE，模板变量为 ${code}", Category: CategorySecurity, Group: "sms"},
+	{Key: KeySMSEndpoint, Type: "string", Default: "", Description: "短信服务地址，为空时使用服务商默认地址", Category: CategorySecurity, Group: "sms", Format: models.ConfigFormatURL},
+	{Key: KeySMSCodeTTLMinutes, Type: "int", Default: "5", Description: "短信验证码有效期(分钟)", Category: CategorySecurity, Group: "sms", Min: schemaInt(1), Max: schemaInt(30)},
+	{Key: KeySMSResendSeconds, Type: "int", Default: "60", Description: "同一用途重新发送验证码的间隔(秒)", Category: CategorySecurity, Group: "sms", Min: schemaInt(0), Max: schemaInt(3600)},
+	{Key: KeySMSMaxPerHour, Type: "int", Default: "5", Description: "每个账户、每个手机号每小时可发送的短信数量", Category: CategorySecurity, Group: "sms", Min: schemaInt(1), Max: schemaInt(100)},
+	{Key: KeySMSMaxPerIPHour, Type: "int", Default: "20", Description: "每个IP每小时可触发的短信数量", Category: CategorySecurity, Group: "sms", Min: schemaInt(1), Max: schemaInt(1000)},
+	{Key: KeySMSCostPerMessage, Type: "string", Default: "0", Description: "每条短信单价，用于费用统计", Category: CategorySecurity, Group: "sms", Pattern: `^\d+(\.\d{1,4})?$`},
+	{Key: KeySMSCostCurrency, Type: "string", Default: "CNY", Description: "短信费用币种", Category: CategorySecurity, Group: "sms", Pattern: `^[A-Z]{3}$`},
+
+	// 邮件模板
+	{Key: KeyEmailWelcomeTemplate, Type: "string", Default: "", Description: "欢迎邮件模板，为空时使用内置模板", Category: CategoryEmail, Group: "templates", Format: models.ConfigFormatMultiline},
+	{Key: KeyEmailResetTemplate, Type: "string", Default: "", Description: "密码重置邮件模板，为空时使用内置模板", Category: CategoryEmail, Group: "templates", Format: models.ConfigFormatMultiline},
+	{Key: KeyEmailTicketTemplate, Type: "string", Default: "", Description: "工单邮件模板，为空时使用内置模板", Category: CategoryEmail, Group: "templates", Format: models.ConfigFormatMultiline},
+	{Key: KeyEmailNotifyTemplate, Type: "string", Default: "", Description: "通知邮件模板，为空时使用内置模板", Category: CategoryEmail, Group: "templates", Format: models.ConfigFormatMultiline},
+	{Key: KeyEmailBounceWebhookToken, Type: "string", Default: "", Description: "退信/投诉回调令牌，为空时回调不可用", Category: CategoryEmail, Group: "bounces", Secret: true},
+	{Key: KeyEmailSoftBounceThreshold, Type: "int", Default: "3", Description: "连续临时退信达到该次数后停止发送", Category: CategoryEmail, Group: "bounces", Min: schemaInt(1), Max: schemaInt(20)},
+
+	// 工单默认配置
+	{Key: KeyTicketDefaultPriority, Type: "string", Default: "normal", Description: "工单默认优先级", Category: CategoryTicket, Group: "defaults",
+		Enum: []string{string(models.TicketPriorityLow), string(models.TicketPriorityNormal), string(models.TicketPriorityHigh), string(models.TicketPriorityUrgent), string(models.TicketPriorityCritical)}},
+	{Key: KeyTicketDefaultType, Type: "string", Default: "general", Description: "工单默认类型", Category: CategoryTicket, Group: "defaults"},
+	{Key: KeyTicketAutoAssign, Type: "bool", Default: "false", Description: "是否自动分配工单", Category: CategoryTicket, Group: "workflow"},
+	{Key: KeyTicketSLAEnabled, Type: "bool", Default: "true", Description: "是否启用SLA", Category: CategoryTicket, Group: "workflow"},
+	{Key: KeyTicketChecklistBlockResolve, Type: "bool", Default: "false", Description: "必填检查项未完成时禁止解决工单", Category: CategoryTicket, Group: "workflow"},
+	{Key: KeyTicketRequireResolutionCode, Type: "bool", Default: "false", Description: "解决工单时必须选择解决代码", Category: CategoryTicket, Group: "workflow"},
+	{Key: KeyTicketCategoryScopeEnabled, Type: "bool", Default: "false", Description: "坐席只能查看和处理所属分类的工单", Category: CategoryTicket, Group: "workflow"},
+	{Key: KeyTranslationEnabled, Type: "bool", Default: "false", Description: "启用评论翻译", Category: CategoryTicket, Group: "translation"},
+	{Key: KeyTranslationProvider, Type: "string", Default: "deepl", Description: "翻译服务提供方(deepl, google, azure)", Category: CategoryTicket, Group: "translation",
+		Enum: []string{string(models.TranslationProviderDeepL), string(models.TranslationProviderGoogle), string(models.TranslationProviderAzure)}},
+	{Key: KeyTranslationAPIKey, Type: "string", Default: "", Description: "翻译服务API密钥", Category: CategoryTicket, Group: "translation", Secret: true},
+	{Key: KeyTranslationRegion, Type: "string", Default: "", Description: "Azure翻译资源所在区域", Category: CategoryTicket, Group: "translation"},
+	{Key: KeyTranslationEndpoint, Type: "string", Default: "", Description: "翻译服务地址，为空时使用服务商默认地址", Category: CategoryTicket, Group: "translation", Format: models.ConfigFormatURL},
+	{Key: KeySummaryEnabled, Type: "bool", Default: "false", Description: "启用工单摘要生成，工单内容将发送给配置的模型服务", Category: CategoryTicket, Group: "summary"},
+	{Key: KeySummaryProvider, Type: "string", Default: "openai", Description: "摘要模型接口类型(openai 兼容接口，含自建服务；anthropic)", Category: CategoryTicket, Group: "summary",
+		Enum: []string{string(models.TicketSummaryProviderOpenAI), string(models.TicketSummaryProviderAnthropic)}},
+	{Key: KeySummaryAPIKey, Type: "string", Default: "", Description: "摘要模型服务API密钥，自建服务不需要时可留空", Category: CategoryTicket, Group: "summary", Secret: true},
+	{Key: KeySummaryEndpoint, Type: "string", Default: "", Description: "摘要模型服务地址，为空时使用服务商默认地址；填写自建服务地址可将数据保留在内网", Category: CategoryTicket, Group: "summary", Format: models.ConfigFormatURL},
+	{Key: KeySummaryModel, Type: "string", Default: "gpt-4o-mini", Description: "摘要使用的模型名称", Category: CategoryTicket, Group: "summary"},
+	{Key: KeySummaryOnHandoff, Type: "bool", Default: "false", Description: "换班交接后自动为工单生成摘要", Category: CategoryTicket, Group: "summary"},
+	{Key: KeySummaryMinComments, Type: "int", Default: "5", Description: "交接时自动生成摘要的最少评论数，评论较少的工单不生成", Category: CategoryTicket, Group: "summary", Min: schemaInt(0), Max: schemaInt(1000)},
+	{Key: KeyLiveQueueBatchMillis, Type: "int", Default: "1000", Description: "工单列表实时更新的合并推送间隔(毫秒)", Category: CategoryTicket, Group: "live_queue", Min: schemaInt(200), Max: schemaInt(10000), RestartRequired: true},
+
+	// 系统通知
+	{Key: KeyNotifyEmailEnabled, Type: "bool", Default: "true", Description: "启用邮件通知", Category: CategoryNotify, Group: "channels"},
+	{Key: KeyNotifyWebSocketEnabled, Type: "bool", Default: "true", Description: "启用WebSocket通知", Category: CategoryNotify, Group: "channels"},
+	{Key: KeyNotifyInAppEnabled, Type: "bool", Default: "true", Description: "启用应用内通知", Category: CategoryNotify, Group: "channels"},
+	{Key: KeyNotifyEmailCoalesceSec, Type: "int", Default: "120", Description: "同一接收者同一工单的邮件合并窗口(秒)，0表示不合并", Category: CategoryNotify, Group: "email", Min: schemaInt(0), Max: schemaInt(3600)},
+	{Key: KeyNotifyEmailFailoverInApp, Type: "bool", Default: "true", Description: "邮件通道不可用时将受影响的邮件通知转为应用内通知", Category: CategoryNotify, Group: "email"},
+	{Key: KeyNotifyEmailFailoverTypes, Type: "string", Default: "", Description: "转为应用内通知的通知类型，逗号分隔，为空表示全部类型", Category: CategoryNotify, Group: "email"},
+	{Key: KeyNotifyEmailAlertMinutes, Type: "int", Default: "10", Description: "邮件通道持续不可用多少分钟后通知管理员", Category: CategoryNotify, Group: "email", Min: schemaInt(1), Max: schemaInt(1440)},
+	{Key: KeyPushEnabled, Type: "bool", Default: "false", Description: "启用浏览器推送通知", Category: CategoryNotify, Group: "push"},
+	{Key: KeyPushVAPIDPublicKey, Type: "string", Default: "", Description: "VAPID公钥(base64url)，提供给浏览器订阅", Category: CategoryNotify, Group: "push", Pattern: `^[A-Za-z0-9_-]+$`},
+	{Key: KeyPushVAPIDPrivateKey, Type: "string", Default: "", Description: "VAPID私钥(base64url)", Category: CategoryNotify, Group: "push", Pattern: `^[A-Za-z0-9_-]+$`, Secret: true},
+	{Key: KeyPushVAPIDSubject, Type: "string", Default: "mailto:admin@example.com", Description: "VAPID联系方式(mailto: 或 https: 地址)", Category: CategoryNotify, Group: "push", Pattern: `^(mailto:|https://)\S+$`},
+	{Key: KeyPushBatchSeconds, Type: "int", Default: "60", Description: "浏览器推送合并窗口(秒)，窗口内的后续通知合并为一条推送，0表示不合并", Category: CategoryNotify, Group: "push", Min: schemaInt(0), Max: schemaInt(3600)},
+	{Key: KeyWSSendQueueSize, Type: "int", Default: "256", Description: "每个WebSocket连接的发送队列长度，队列满时丢弃最旧的消息", Category: CategoryNotify, Group: "websocket", Min: schemaInt(16), Max: schemaInt(4096), RestartRequired: true},
+	{Key: KeyWSWriteTimeoutSec, Type: "int", Default: "10", Description: "WebSocket单次写入超时(秒)", Category: CategoryNotify, Group: "websocket", Min: schemaInt(1), Max: schemaInt(60), RestartRequired: true},
+	{Key: KeyWSSlowClientTimeoutSec, Type: "int", Default: "30", Description: "队列中最早的消息等待超过该时间(秒)时断开慢连接", Category: CategoryNotify, Group: "websocket", Min: schemaInt(5), Max: schemaInt(600), RestartRequired: true},
+	{Key: KeyWSMaxDrops, Type: "int", Default: "500", Description: "连续丢弃多少条消息后断开慢连接，0表示不按丢弃数断开", Category: CategoryNotify, Group: "websocket", Min: schemaInt(0), Max: schemaInt(100000), RestartRequired: true},
+	{Key: KeyPortalPresenceVisible, Type: "bool", Default: "true", Description: "客户门户显示客服正在输入，关闭后客户看不到客服的输入状态", Category: CategoryNotify, Group: "portal_presence"},
+	{Key: KeyPortalPresenceIntervalMs, Type: "int", Default: "2000", Description: "每个连接在同一工单上发送输入/在线状态事件的最小间隔(毫秒)，更频繁的事件被丢弃", Category: CategoryNotify, Group: "portal_presence", Min: schemaInt(200), Max: schemaInt(60000)},
+	{Key: KeyWebhookLogRetentionDays, Type: "int", Default: "30", Description: "Webhook成功日志保留天数，0表示不清理", Category: CategoryNotify, Group: "webhook", Min: schemaInt(0), Max: schemaInt(3650)},
+	{Key: KeyWebhookFailedLogRetentionDays, Type: "int", Default: "90", Description: "Webhook失败日志保留天数，0表示不清理", Category: CategoryNotify, Group: "webhook", Min: schemaInt(0), Max: schemaInt(3650)},
+
+	// 由专用接口维护的JSON策略，未保存时使用代码中的默认策略
+	{Key: "cleanup", Type: "json", Description: "系统数据清理配置", Category: CategorySystem, Group: "cleanup", ManagedBy: "/api/admin/system/cleanup/config"},
+	{Key: KeySystemMaintenanceMode, Type: "json", Description: "只读维护模式", Category: CategorySystem, Group: "maintenance", ManagedBy: "/api/admin/system/maintenance"},
+	{Key: KeyBusinessCalendar, Type: "json", Description: "营业日历", Category: CategorySystem, Group: "calendar", ManagedBy: "/api/admin/system/business-calendar"},
+	{Key: KeySystemBranding, Type: "json", Description: "品牌与白标设置", Category: CategorySystem, Group: "branding", ManagedBy: "/api/admin/branding"},
+	{Key: KeySystemConcurrencyLimits, Type: "json", Description: "高开销接口并发限制", Category: CategorySystem, Group: "concurrency", ManagedBy: "/api/admin/system/concurrency-limits"},
+	{Key: KeySystemPaginationLimits, Type: "json", Description: "列表接口分页限制", Category: CategorySystem, Group: "performance", ManagedBy: "/api/admin/system/pagination-limits"},
+	{Key: KeyQuotaPolicy, Type: "json", Description: "租户配额策略", Category: CategorySystem, Group: "quota", ManagedBy: "/api/admin/quota/config"},
+	{Key: KeySecurityHTTPPolicy, Type: "json", Description: "CORS及安全响应头策略", Category: CategorySecurity, Group: "http", ManagedBy: "/api/admin/system/http-security"},
+	{Key: KeyTicketAccessAuditPolicy, Type: "json", Description: "工单访问审计策略", Category: CategorySecurity, Group: "audit", ManagedBy: "/api/admin/ticket-access-logs/config"},
+	{Key: KeyAuditForwarding, Type: "json", Description: "审计日志转发", Category: CategorySecurity, Group: "audit", ManagedBy: "/api/admin/system/audit-forwarding"},
+	{Key: KeySessionPolicy, Type: "json", Description: "按角色的会话与可信设备策略", Category: CategorySecurity, Group: "session", ManagedBy: "/api/admin/security/session-policy"},
+	{Key: KeyLoginIPProtection, Type: "json", Description: "按IP的登录暴力破解防护", Category: CategorySecurity, Group: "login", ManagedBy: "/api/admin/security/login-ip-protection"},
+	{Key: KeyTicketAutoClosePolicy, Type: "json", Description: "已解决工单自动关闭策略", Category: CategoryTicket, Group: "workflow", ManagedBy: "/api/admin/system/auto-close/config"},
+	{Key: KeyTicketStalePolicy, Type: "json", Description: "停滞工单提醒与升级策略", Category: CategoryTicket, Group: "workflow", ManagedBy: "/api/admin/system/stale-tickets/config"},
+	{Key: KeyTicketSurveyPolicy, Type: "json", Description: "满意度调查策略", Category: CategoryTicket, Group: "survey", ManagedBy: "/api/admin/system/survey/config"},
+	{Key: KeyTicketChangeProposalPolicy, Type: "json", Description: "工单变更提案策略", Category: CategoryTicket, Group: "workflow", ManagedBy: "/api/admin/system/change-proposals/config"},
+	{Key: KeyTicketFieldPermissionPolicy, Type: "json", Description: "工单字段编辑权限策略", Category: CategoryTicket, Group: "workflow", ManagedBy: "/api/admin/system/ticket-field-permissions/config"},
+	{Key: KeyTicketCategoryTransferPolicy, Type: "json", Description: "工单转移分类策略", Category: CategoryTicket, Group: "workflow", ManagedBy: "/api/admin/system/category-transfer/config"},
+	{Key: KeyTicketPriorityMatrix, Type: "json", Description: "影响×紧急程度优先级矩阵", Category: CategoryTicket, Group: "priority", ManagedBy: "/api/admin/system/priority-matrix"},
+	{Key: KeyCommentVisibilityDefaults, Type: "json", Description: "评论默认可见范围", Category: CategoryTicket, Group: "comments", ManagedBy: "/api/admin/comment-visibility-defaults"},
+	{Key: KeyTicketAttachmentPolicy, Type: "json", Description: "分类附件策略", Category: CategoryTicket, Group: "attachments", ManagedBy: "/api/admin/attachment-policy"},
+	{Key: KeyTicketResolutionCodes, Type: "json", Description: "工单解决代码", Category: CategoryTicket, Group: "workflow", ManagedBy: "/api/admin/resolution-codes"},
+	{Key: KeyBillingRates, Type: "json", Description: "计费小时费率", Category: CategoryTicket, Group: "billing", ManagedBy: "/api/admin/billing/rates"},
+	{Key: KeyTicketClassification, Type: "json", Description: "工单自动分类", Category: CategoryTicket, Group: "classification", ManagedBy: "/api/admin/ticket-classification/config"},
+	{Key: KeyIntakeSpamPolicy, Type: "json", Description: "进件垃圾拦截策略", Category: CategoryTicket, Group: "intake", ManagedBy: "/api/admin/intake/spam-policy"},
+	{Key: KeyEmailAuthPolicy, Type: "json", Description: "收件 SPF/DKIM/DMARC 认证策略", Category: CategorySecurity, Group: "intake", ManagedBy: "/api/admin/intake/email-auth-policy"},
+	{Key: KeyChatIntegration, Type: "json", Description: "聊天平台集成", Category: CategoryNotify, Group: "chat", ManagedBy: "/api/admin/integrations/chat/config"},
+	{Key: KeySearchLanguageConfig, Type: "json", Description: "全文搜索语言配置", Category: CategorySystem, Group: "search", ManagedBy: "/api/admin/system/search-language"},
+	{Key: KeyPIIScanConfig, Type: "json", Description: "工单内容敏感信息检测", Category: CategorySecurity, Group: "pii", ManagedBy: "/api/admin/pii-scan/config"},
+}
+
+var configSchemaIndex = func() map[string]*models.ConfigSchemaEntry {
+	index := make(map[string]*models.ConfigSchemaEntry, len(configSchema))
+	for i := range configSchema {
+		index[configSchema[i].Key] = &configSchema[i]
+	}
+	return index
+}()
+
+// ConfigSchema 返回全部配置定义的副本，按分类、分组、键排序
+func ConfigSchema() []models.ConfigSchemaEntry {
+	entries := make([]models.ConfigSchemaEntry, len(configSchema))
+	copy(entries, configSchema)
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Category != entries[j].Category {
+			return entries[i].Category < entries[j].Category
+		}
+		if entries[i].Group != entries[j].Group {
+			return entries[i].Group < entries[j].Group
+		}
+		return entries[i].Key < entries[j].Key
+	})
+	return entries
+}
+
+// LookupConfigSchema 查找配置定义
+func LookupConfigSchema(key string) (models.ConfigSchemaEntry, bool) {
+	entry, ok := configSchemaIndex[key]
+	if !ok {
+		return models.ConfigSchemaEntry{}, false
+	}
+	return *entry, true
+}
+
+// ApplyConfigSchema 用配置定义补全请求中未填写的类型、描述、分类和分组
+func ApplyConfigSchema(config *models.SystemConfig) {
+	entry, ok := configSchemaIndex[config.Key]
+	if !ok {
+		return
+	}
+	if config.ValueType == "" {
+		config.ValueType = entry.Type
+	}
+	if config.Description == "" {
+		config.Description = entry.Description
+	}
+	if config.Category == "" {
+		config.Category = entry.Category
+	}
+	if config.Group == "" {
+		config.Group = entry.Group
+	}
+}
+
+// CheckConfigSchema 比对数据库中的配置与配置定义，找出孤立配置、无效取值及缺失的配置
+func (s *ConfigService) CheckConfigSchema() (*models.ConfigSchemaReport, error) {
+	var configs []models.SystemConfig
+	if err := s.db.Order("key").Find(&configs).Error; err != nil {
+		return nil, err
+	}
+
+	report := &models.ConfigSchemaReport{
+		UnknownKeys: []models.ConfigKeyIssue{},
+		Invalid:     []models.ConfigKeyIssue{},
+		MissingKeys: []string{},
+	}
+	stored := make(map[string]bool, len(configs))
+	for _, config := range configs {
+		stored[config.Key] = true
+		entry, ok := configSchemaIndex[config.Key]
+		if !ok {
+			report.UnknownKeys = append(report.UnknownKeys, models.ConfigKeyIssue{Key: config.Key, Value: truncateString(config.Value, 200), Reason: "配置未定义"})
+			continue
+		}
+
+		issue := models.ConfigKeyIssue{Key: config.Key}
+		if !entry.Secret && entry.ManagedBy == "" {
+			issue.Value = config.Value
+		}
+		if config.ValueType != entry.Type {
+			issue.Reason = fmt.Sprintf("类型应为 %s，实际为 %s", entry.Type, config.ValueType)
+			report.Invalid = append(report.Invalid, issue)
+			continue
+		}
+		if err := entry.Validate(config.Value); err != nil {
+			issue.Reason = err.Error()
+			report.Invalid = append(report.Invalid, issue)
+		}
+	}
+
+	for _, entry := range configSchema {
+		// 专用接口维护的策略未保存时使用代码默认值，不算缺失
+		if entry.ManagedBy == "" && !stored[entry.Key] {
+			report.MissingKeys = append(report.MissingKeys, entry.Key)
+		}
+	}
+	sort.Strings(report.MissingKeys)
+	return report, nil
+}
+
+// schemaValidValues 可选值列表的JSON表示，写入 SystemConfig.ValidValues
+func schemaValidValues(enum []string) string {
+	if len(enum) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(enum)
+	return string(data)
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestConfigSchema_ValidatesWritesAndReportsOrphans(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:config_schema_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.SystemConfig{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+	svc := NewConfigService(db)
+
+	// 每个配置定义的默认值都应通过自身校验
+	for _, entry := range ConfigSchema() {
+		if entry.ManagedBy != "" {
+			continue
+		}
+		if err := entry.Validate(entry.Default); err != nil {
+			t.Fatalf("default of %s is invalid: %v", entry.Key, err)
+		}
+	}
+
+	cases := []struct {
+		key, value, valueType string
+		valid                 bool
+	}{
+		{KeyPasswordMinLength, "12", "int", true},
+		{KeyPasswordMinLength, "4", "int", false},
+		{KeyPasswordMinLength, "abc", "", false},
+		{KeyPasswordMinLength, "12", "string", false},
+		{KeyTicketDefaultPriority, "urgent", "string", true},
+		{KeyTicketDefaultPriority, "asap", "string", false},
+		{KeySystemTimezone, "Europe/Berlin", "string", true},
+		{KeySystemTimezone, "Mars/Olympus", "string", false},
+		{KeyPushVAPIDSubject, "https://example.com/contact", "string", true},
+		{KeyPushVAPIDSubject, "admin@example.com", "string", false},
+		{KeyTranslationEndpoint, "", "string", true},
+		{KeyTranslationEndpoint, "ftp://example.com", "string", false},
+	}
+	for _, tc := range cases {
+		err := svc.ValidateConfig(tc.key, tc.value, tc.valueType)
+		if (err == nil) != tc.valid {
+			t.Fatalf("%s=%q (%s): expected valid=%v, got %v", tc.key, tc.value, tc.valueType, tc.valid, err)
+		}
+	}
+	if err := svc.ValidateConfig("custom.unknown", "x", "string"); !errors.Is(err, ErrUnknownConfigKey) {
+		t.Fatalf("expected unknown key error, got %v", err)
+	}
+	if err := svc.ValidateConfig(KeyTicketPriorityMatrix, "{}", "json"); !errors.Is(err, ErrManagedConfigKey) {
+		t.Fatalf("expected managed key error, got %v", err)
+	}
+
+	// 补全请求中未填写的类型和分类
+	config := models.SystemConfig{Key: KeySessionTimeout, Value: "7200"}
+	ApplyConfigSchema(&config)
+	if config.ValueType != "int" || config.Category != CategorySecurity || config.Description == "" {
+		t.Fatalf("expected schema defaults applied, got %+v", config)
+	}
+
+	if err := svc.InitDefaultConfigs(); err != nil {
+		t.Fatalf("init default configs failed: %v", err)
+	}
+	var priority models.SystemConfig
+	db.Where("key = ?", KeyTicketDefaultPriority).First(&priority)
+	if priority.Value != "normal" || priority.ValidValues == "" {
+		t.Fatalf("expected default config created from schema, got %+v", priority)
+	}
+	var managed int64
+	db.Model(&models.SystemConfig{}).Where("key = ?", KeyTicketPriorityMatrix).Count(&managed)
+	if managed != 0 {
+		t.Fatalf("managed policies should not be created by init")
+	}
+
+	// 孤立配置、无效取值及缺失配置
+	db.Create(&models.SystemConfig{Key: "legacy.theme", Value: "dark", ValueType: "string"})
+	db.Model(&models.SystemConfig{}).Where("key = ?", KeyMaxLoginAttempts).Update("value", "0")
+	db.Model(&models.SystemConfig{}).Where("key = ?", KeyTranslationAPIKey).Updates(map[string]interface{}{"value": "secret", "value_type": "int"})
+	db.Where("key = ?", KeyNotifyInAppEnabled).Delete(&models.SystemConfig{})
+
+	report, err := svc.CheckConfigSchema()
+	if err != nil {
+		t.Fatalf("check config schema failed: %v", err)
+	}
+	if len(report.UnknownKeys) != 1 || report.UnknownKeys[0].Key != "legacy.theme" {
+		t.Fatalf("expected orphan key reported, got %+v", report.UnknownKeys)
+	}
+	if len(report.Invalid) != 2 {
+		t.Fatalf("expected two invalid configs, got %+v", report.Invalid)
+	}
+	for _, issue := range report.Invalid {
+		if issue.Key == KeyTranslationAPIKey && issue.Value != "" {
+			t.Fatalf("secret values must not be reported")
+		}
+	}
+	if len(report.MissingKeys) != 1 || report.MissingKeys[0] != KeyNotifyInAppEnabled {
+		t.Fatalf("expected missing key reported, got %v", report.MissingKeys)
+	}
+}
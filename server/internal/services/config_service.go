@@ -1,14 +1,15 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
-	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/patrickmn/go-cache"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"gongdan-system/internal/models"
 )
@@ -16,7 +17,7 @@ import (
 // ConfigService 系统配置服务
 type ConfigService struct {
 	db    *gorm.DB
-	cache *cache.Cache
+	cache *ConfigCache
 }
 
 // ConfigCategory 配置分类常量
@@ -39,18 +40,53 @@ const (
 	KeySystemCopyright   = "system.copyright"
 	KeySystemTimezone    = "system.timezone"
 
+	// 脚本钩子
+	KeyScriptHooksEnabled         = "system.script_hooks_enabled"
+	KeyScriptHookFailureThreshold = "system.script_hook_failure_threshold"
+
+	// 公开状态徽章
+	KeyPublicStatusEnabled = "system.public_status_enabled"
+
 	// 安全策略
-	KeyPasswordMinLength       = "security.password_min_length"
-	KeyPasswordRequireUpper    = "security.password_require_upper"
-	KeyPasswordRequireLower    = "security.password_require_lower"
-	KeyPasswordRequireDigit    = "security.password_require_digit"
-	KeyPasswordRequireSymbol   = "security.password_require_symbol"
-	KeyMaxLoginAttempts        = "security.max_login_attempts"
-	KeyLoginLockDuration       = "security.login_lock_duration"
-	KeySessionTimeout          = "security.session_timeout"
-	KeyTwoFactorRequired       = "security.two_factor_required"
-	KeyTrustedDeviceTTLHours   = "security.trusted_device_ttl_hours"
-	KeyTrustedDeviceMaxPerUser = "security.trusted_device_max_per_user"
+	KeyPasswordMinLength         = "security.password_min_length"
+	KeyPasswordRequireUpper      = "security.password_require_upper"
+	KeyPasswordRequireLower      = "security.password_require_lower"
+	KeyPasswordRequireDigit      = "security.password_require_digit"
+	KeyPasswordRequireSymbol     = "security.password_require_symbol"
+	KeyMaxLoginAttempts          = "security.max_login_attempts"
+	KeyLoginLockDuration         = "security.login_lock_duration"
+	KeySessionTimeout            = "security.session_timeout"
+	KeyTwoFactorRequired         = "security.two_factor_required"
+	KeyTrustedDeviceTTLHours     = "security.trusted_device_ttl_hours"
+	KeyTrustedDeviceMaxPerUser   = "security.trusted_device_max_per_user"
+	KeyPasswordResetTTLMinutes   = "security.password_reset_ttl_minutes"
+	KeyEmailVerificationTTLHours = "security.email_verification_ttl_hours"
+	KeyMagicLinkEnabled          = "security.magic_link_enabled"
+	KeyMagicLinkTTLMinutes       = "security.magic_link_ttl_minutes"
+	KeyMagicLinkMaxPerHour       = "security.magic_link_max_per_hour"
+	KeyAccountDeletionGraceDays  = "security.account_deletion_grace_days"
+	KeyDataDeletionSigningKey    = "security.data_deletion_signing_key"
+	KeyTicketArchiveSigningKey   = "security.ticket_archive_signing_key"
+	KeyTicketArchiveLinkTTL      = "security.ticket_archive_link_ttl_minutes"
+	KeyDownloadSigningKey        = "security.download_signing_key"
+	KeyDownloadLinkTTL           = "security.download_link_ttl_minutes"
+	KeyPortalRealtimeSigningKey  = "security.portal_realtime_signing_key"
+	KeyUserInvitationTTLHours    = "security.invitation_ttl_hours"
+
+	// 短信验证码（手机验证与登录第二因子）
+	KeySMSEnabled        = "security.sms_enabled"
+	KeySMSProvider       = "security.sms_provider"
+	KeySMSAccessKeyID    = "security.sms_access_key_id"
+	KeySMSAccessSecret   = "security.sms_access_secret"
+	KeySMSSender         = "security.sms_sender"
+	KeySMSTemplateCode   = "security.sms_template_code"
+	KeySMSEndpoint       = "security.sms_endpoint"
+	KeySMSCodeTTLMinutes = "security.sms_code_ttl_minutes"
+	KeySMSResendSeconds  = "security.sms_resend_seconds"
+	KeySMSMaxPerHour     = "security.sms_max_per_hour"
+	KeySMSMaxPerIPHour   = "security.sms_max_per_ip_hour"
+	KeySMSCostPerMessage = "security.sms_cost_per_message"
+	KeySMSCostCurrency   = "security.sms_cost_currency"
 
 	// 邮件配置
 	KeyEmailWelcomeTemplate = "email.welcome_template"
@@ -58,26 +94,78 @@ const (
 	KeyEmailTicketTemplate  = "email.ticket_template"
 	KeyEmailNotifyTemplate  = "email.notify_template"
 
+	// 邮件退信与投诉处理
+	KeyEmailBounceWebhookToken  = "email.bounce_webhook_token"
+	KeyEmailSoftBounceThreshold = "email.soft_bounce_threshold"
+
 	// 工单默认配置
 	KeyTicketDefaultPriority = "ticket.default_priority"
 	KeyTicketDefaultType     = "ticket.default_type"
 	KeyTicketAutoAssign      = "ticket.auto_assign"
 	KeyTicketSLAEnabled      = "ticket.sla_enabled"
 
+	KeyTicketChecklistBlockResolve = "ticket.checklist_block_resolve"
+	KeyTicketRequireResolutionCode = "ticket.require_resolution_code"
+	KeyTicketCategoryScopeEnabled  = "ticket.category_scope_enabled"
+
+	// 评论翻译
+	KeyTranslationEnabled  = "ticket.translation_enabled"
+	KeyTranslationProvider = "ticket.translation_provider"
+	KeyTranslationAPIKey   = "ticket.translation_api_key"
+	KeyTranslationRegion   = "ticket.translation_region"
+	KeyTranslationEndpoint = "ticket.translation_endpoint"
+
+	// 工单摘要（由大语言模型生成）
+	KeySummaryEnabled     = "ticket.summary_enabled"
+	KeySummaryProvider    = "ticket.summary_provider"
+	KeySummaryAPIKey      = "ticket.summary_api_key"
+	KeySummaryEndpoint    = "ticket.summary_endpoint"
+	KeySummaryModel       = "ticket.summary_model"
+	KeySummaryOnHandoff   = "ticket.summary_on_handoff"
+	KeySummaryMinComments = "ticket.summary_min_comments"
+
+	// 工单列表实时更新
+	KeyLiveQueueBatchMillis = "ticket.live_queue_batch_ms"
+
 	// 系统通知
 	KeyNotifyEmailEnabled     = "notify.email_enabled"
 	KeyNotifyWebSocketEnabled = "notify.websocket_enabled"
 	KeyNotifyInAppEnabled     = "notify.inapp_enabled"
+	KeyNotifyEmailCoalesceSec = "notify.email_coalesce_seconds"
+
+	// 邮件通道故障转移
+	KeyNotifyEmailFailoverInApp = "notify.email_failover_inapp"
+	KeyNotifyEmailFailoverTypes = "notify.email_failover_types"
+	KeyNotifyEmailAlertMinutes  = "notify.email_alert_minutes"
+
+	// 浏览器推送（Web Push）
+	KeyPushEnabled         = "notify.push_enabled"
+	KeyPushVAPIDPublicKey  = "notify.push_vapid_public_key"
+	KeyPushVAPIDPrivateKey = "notify.push_vapid_private_key"
+	KeyPushVAPIDSubject    = "notify.push_vapid_subject"
+	KeyPushBatchSeconds    = "notify.push_batch_seconds"
+
+	// WebSocket 连接发送队列
+	KeyWSSendQueueSize        = "notify.ws_send_queue_size"
+	KeyWSWriteTimeoutSec      = "notify.ws_write_timeout_seconds"
+	KeyWSSlowClientTimeoutSec = "notify.ws_slow_client_timeout_seconds"
+	KeyWSMaxDrops             = "notify.ws_max_drops"
+
+	// 客户门户实时输入/在线状态
+	KeyPortalPresenceVisible    = "notify.portal_presence_visible"
+	KeyPortalPresenceIntervalMs = "notify.portal_presence_interval_ms"
+
+	// Webhook 日志保留（单个 Webhook 可单独设置）
+	KeyWebhookLogRetentionDays       = "notify.webhook_log_retention_days"
+	KeyWebhookFailedLogRetentionDays = "notify.webhook_failed_log_retention_days"
 )
 
 // NewConfigService 创建配置服务
 func NewConfigService(db *gorm.DB) *ConfigService {
-	// 创建缓存实例，默认过期时间10分钟，清理间隔30秒
-	c := cache.New(10*time.Minute, 30*time.Second)
-
+	// 优先使用进程内共享的缓存（由 main 注册，跨实例失效），否则同一数据库连接共用内存缓存
 	return &ConfigService{
 		db:    db,
-		cache: c,
+		cache: configCacheFor(db),
 	}
 }
 
@@ -85,38 +173,33 @@ func NewConfigService(db *gorm.DB) *ConfigService {
 func (s *ConfigService) InitDefaultConfigs() error {
 	log.Println("🔧 初始化系统默认配置...")
 
-	defaultConfigs := []models.SystemConfig{
-		// 系统基础信息
-		{Key: KeySystemName, Value: "工单管理系统", ValueType: "string", Description: "系统名称", Category: CategorySystem, Group: "basic"},
-		{Key: KeySystemVersion, Value: "1.0.0", ValueType: "string", Description: "系统版本", Category: CategorySystem, Group: "basic"},
-		{Key: KeySystemDescription, Value: "现代化的工单管理系统", ValueType: "string", Description: "系统描述", Category: CategorySystem, Group: "basic"},
-		{Key: KeySystemTimezone, Value: "Asia/Shanghai", ValueType: "string", Description: "系统时区", Category: CategorySystem, Group: "basic"},
-
-		// 安全策略
-		{Key: KeyPasswordMinLength, Value: "8", ValueType: "int", Description: "密码最小长度", Category: CategorySecurity, Group: "password"},
-		{Key: KeyPasswordRequireUpper, Value: "true", ValueType: "bool", Description: "密码需要大写字母", Category: CategorySecurity, Group: "password"},
-		{Key: KeyPasswordRequireLower, Value: "true", ValueType: "bool", Description: "密码需要小写字母", Category: CategorySecurity, Group: "password"},
-		{Key: KeyPasswordRequireDigit, Value: "true", ValueType: "bool", Description: "密码需要数字", Category: CategorySecurity, Group: "password"},
-		{Key: KeyPasswordRequireSymbol, Value: "false", ValueType: "bool", Description: "密码需要特殊字符", Category: CategorySecurity, Group: "password"},
-		{Key: KeyMaxLoginAttempts, Value: "5", ValueType: "int", Description: "最大登录尝试次数", Category: CategorySecurity, Group: "login"},
-		{Key: KeyLoginLockDuration, Value: "300", ValueType: "int", Description: "登录锁定时长(秒)", Category: CategorySecurity, Group: "login"},
-		{Key: KeySessionTimeout, Value: "3600", ValueType: "int", Description: "会话超时时长(秒)", Category: CategorySecurity, Group: "session"},
-		{Key: KeyTwoFactorRequired, Value: "false", ValueType: "bool", Description: "是否强制双因子认证", Category: CategorySecurity, Group: "auth"},
-		{Key: KeyTrustedDeviceTTLHours, Value: "720", ValueType: "int", Description: "可信设备有效期(小时)", Category: CategorySecurity, Group: "trusted_device"},
-		{Key: KeyTrustedDeviceMaxPerUser, Value: "5", ValueType: "int", Description: "每个用户允许的可信设备数量", Category: CategorySecurity, Group: "trusted_device"},
-
-		// 工单默认配置
-		{Key: KeyTicketDefaultPriority, Value: "normal", ValueType: "string", Description: "工单默认优先级", Category: CategoryTicket, Group: "defaults"},
-		{Key: KeyTicketDefaultType, Value: "general", ValueType: "string", Description: "工单默认类型", Category: CategoryTicket, Group: "defaults"},
-		{Key: KeyTicketAutoAssign, Value: "false", ValueType: "bool", Description: "是否自动分配工单", Category: CategoryTicket, Group: "workflow"},
-		{Key: KeyTicketSLAEnabled, Value: "true", ValueType: "bool", Description: "是否启用SLA", Category: CategoryTicket, Group: "workflow"},
-
-		// 系统通知
-		{Key: KeyNotifyEmailEnabled, Value: "true", ValueType: "bool", Description: "启用邮件通知", Category: CategoryNotify, Group: "channels"},
-		{Key: KeyNotifyWebSocketEnabled, Value: "true", ValueType: "bool", Description: "启用WebSocket通知", Category: CategoryNotify, Group: "channels"},
-		{Key: KeyNotifyInAppEnabled, Value: "true", ValueType: "bool", Description: "启用应用内通知", Category: CategoryNotify, Group: "channels"},
+	// 默认配置来自配置定义，专用接口维护的JSON策略未保存时使用代码默认值，不在此创建
+	var defaultConfigs []models.SystemConfig
+	for _, entry := range configSchema {
+		if entry.ManagedBy != "" {
+			continue
+		}
+		defaultConfigs = append(defaultConfigs, models.SystemConfig{
+			Key:          entry.Key,
+			Value:        entry.Default,
+			ValueType:    entry.Type,
+			Description:  entry.Description,
+			Category:     entry.Category,
+			Group:        entry.Group,
+			DefaultValue: entry.Default,
+			MinValue:     entry.Min,
+			MaxValue:     entry.Max,
+			ValidValues:  schemaValidValues(entry.Enum),
+		})
 	}
 
+	created := 0
+	defer func() {
+		// 新建的配置可能已作为不存在缓存
+		if created > 0 {
+			s.cache.Invalidate(context.Background())
+		}
+	}()
 	for _, config := range defaultConfigs {
 		// 检查配置是否已存在
 		var existing models.SystemConfig
@@ -127,6 +210,7 @@ func (s *ConfigService) InitDefaultConfigs() error {
 					log.Printf("❌ 创建默认配置失败 %s: %v", config.Key, err)
 					return err
 				}
+				created++
 				log.Printf("✅ 创建默认配置: %s = %s", config.Key, config.Value)
 			} else {
 				log.Printf("❌ 查询配置失败: %v", err)
@@ -141,24 +225,24 @@ func (s *ConfigService) InitDefaultConfigs() error {
 
 // GetConfig 获取配置值
 func (s *ConfigService) GetConfig(key string) (string, error) {
-	// 先从缓存获取
-	if value, found := s.cache.Get(key); found {
-		return value.(string), nil
-	}
-
-	// 缓存不存在，从数据库查询
-	var config models.SystemConfig
-	if err := s.db.Where("key = ?", key).First(&config).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return "", fmt.Errorf("配置不存在: %s", key)
+	// 依次读取本实例缓存、Redis，都未命中时从数据库查询并写回
+	value, found, err := s.cache.Get(context.Background(), key, func() (string, bool, error) {
+		var config models.SystemConfig
+		if err := s.db.Where("key = ?", key).First(&config).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return "", false, nil
+			}
+			return "", false, err
 		}
+		return config.Value, true, nil
+	})
+	if err != nil {
 		return "", err
 	}
-
-	// 存入缓存
-	s.cache.Set(key, config.Value, cache.DefaultExpiration)
-
-	return config.Value, nil
+	if !found {
+		return "", fmt.Errorf("配置不存在: %s", key)
+	}
+	return value, nil
 }
 
 // GetConfigWithDefault 获取配置值，如果不存在返回默认值
@@ -241,20 +325,56 @@ func (s *ConfigService) SetConfig(key, value, valueType, description, category,
 		s.logConfigChange(key, value, "UPDATE")
 	}
 
-	// 更新缓存
-	s.cache.Set(key, value, cache.DefaultExpiration)
+	// 使所有实例的配置缓存失效
+	s.cache.Invalidate(context.Background())
 
 	return nil
 }
 
+// SetConfigIfEmpty 配置不存在或值为空时写入 value，已有值时保持不变，返回最终保存的值。
+// 使用插入忽略冲突加条件更新，多个实例同时写入时只有一个值生效
+func (s *ConfigService) SetConfigIfEmpty(key, value, valueType, description, category, group string) (string, error) {
+	config := models.SystemConfig{
+		Key:         key,
+		ValueType:   valueType,
+		Description: description,
+		Category:    category,
+		Group:       group,
+	}
+	if err := s.db.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "key"}}, DoNothing: true}).Create(&config).Error; err != nil {
+		return "", err
+	}
+	result := s.db.Model(&models.SystemConfig{}).
+		Where("key = ? AND (value = '' OR value IS NULL)", key).
+		Update("value", value)
+	if result.Error != nil {
+		return "", result.Error
+	}
+	if result.RowsAffected > 0 {
+		logged := value
+		if entry, ok := LookupConfigSchema(key); ok && entry.Secret {
+			logged = "******"
+		}
+		s.logConfigChange(key, logged, "CREATE")
+	}
+
+	var stored models.SystemConfig
+	if err := s.db.Where("key = ?", key).First(&stored).Error; err != nil {
+		return "", err
+	}
+	// 本实例可能已缓存空值或不存在
+	s.cache.Invalidate(context.Background())
+	return stored.Value, nil
+}
+
 // DeleteConfig 删除配置
 func (s *ConfigService) DeleteConfig(key string) error {
 	if err := s.db.Where("key = ?", key).Delete(&models.SystemConfig{}).Error; err != nil {
 		return err
 	}
 
-	// 从缓存删除
-	s.cache.Delete(key)
+	// 使所有实例的配置缓存失效
+	s.cache.Invalidate(context.Background())
 
 	// 记录配置变更日志
 	s.logConfigChange(key, "", "DELETE")
@@ -330,26 +450,30 @@ func (s *ConfigService) BatchUpdateConfigs(configs []models.SystemConfig) error
 			}
 			s.logConfigChange(config.Key, config.Value, "BATCH_UPDATE")
 		}
-
-		// 更新缓存
-		s.cache.Set(config.Key, config.Value, cache.DefaultExpiration)
 	}
 
-	return tx.Commit().Error
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+	// 提交后再失效，避免其他实例在提交前重新加载到旧值
+	s.cache.Invalidate(context.Background())
+	return nil
 }
 
-// ClearCache 清空配置缓存
+// ClearCache 清空配置缓存（所有实例）
 func (s *ConfigService) ClearCache() {
-	s.cache.Flush()
+	s.cache.Invalidate(context.Background())
 	log.Println("🧹 系统配置缓存已清空")
 }
 
-// GetCacheStats 获取缓存统计信息
+// GetCacheStats 获取缓存统计信息，包括命中率和各配置的查询频率
 func (s *ConfigService) GetCacheStats() map[string]interface{} {
+	stats := s.cache.Stats()
 	return map[string]interface{}{
-		"item_count":         s.cache.ItemCount(),
-		"default_expiration": "10m",
-		"cleanup_interval":   "30s",
+		"item_count":         stats.LocalEntries,
+		"default_expiration": configCacheLocalTTL.String(),
+		"shared":             s.cache.store != nil,
+		"stats":              stats,
 	}
 }
 
@@ -382,6 +506,21 @@ func (s *ConfigService) ImportConfigs(data []byte) error {
 		return fmt.Errorf("JSON格式错误: %v", err)
 	}
 
+	// 已定义的配置按定义校验；导出文件可能包含孤立配置，照原样导入，可通过配置定义接口发现
+	for _, config := range configs {
+		entry, ok := configSchemaIndex[config.Key]
+		if !ok {
+			log.Printf("⚠️ 导入未定义的配置: %s", config.Key)
+			continue
+		}
+		if config.ValueType != entry.Type {
+			return fmt.Errorf("配置值类型应为 %s: %s", entry.Type, config.Key)
+		}
+		if err := entry.Validate(config.Value); err != nil {
+			return err
+		}
+	}
+
 	tx := s.db.Begin()
 	if tx.Error != nil {
 		return tx.Error
@@ -399,45 +538,33 @@ func (s *ConfigService) ImportConfigs(data []byte) error {
 			return fmt.Errorf("导入配置失败 %s: %v", config.Key, err)
 		}
 
-		// 更新缓存
-		s.cache.Set(config.Key, config.Value, cache.DefaultExpiration)
-
 		// 记录配置变更日志
 		s.logConfigChange(config.Key, config.Value, "IMPORT")
 	}
 
-	// 清空缓存以确保一致性
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+	// 提交后清空缓存以确保一致性
 	s.ClearCache()
 
 	log.Printf("✅ 成功导入 %d 个配置项", len(configs))
-	return tx.Commit().Error
+	return nil
 }
 
-// ValidateConfig 验证配置值
+// ValidateConfig 按配置定义验证配置值。未定义的配置键及专用接口维护的策略不能通过通用配置接口写入
 func (s *ConfigService) ValidateConfig(key, value, valueType string) error {
-	switch valueType {
-	case "int":
-		var intValue int
-		if err := json.Unmarshal([]byte(value), &intValue); err != nil {
-			return fmt.Errorf("配置值必须是整数: %s", key)
-		}
-	case "bool":
-		var boolValue bool
-		if err := json.Unmarshal([]byte(value), &boolValue); err != nil {
-			return fmt.Errorf("配置值必须是布尔值: %s", key)
-		}
-	case "json":
-		var jsonValue interface{}
-		if err := json.Unmarshal([]byte(value), &jsonValue); err != nil {
-			return fmt.Errorf("配置值必须是有效JSON: %s", key)
-		}
-	case "string":
-		// 字符串类型无需验证
-	default:
-		return fmt.Errorf("不支持的配置值类型: %s", valueType)
+	entry, ok := configSchemaIndex[key]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownConfigKey, key)
 	}
-
-	return nil
+	if entry.ManagedBy != "" {
+		return fmt.Errorf("%w: %s (%s)", ErrManagedConfigKey, key, entry.ManagedBy)
+	}
+	if valueType != "" && valueType != entry.Type {
+		return fmt.Errorf("配置值类型应为 %s: %s", entry.Type, key)
+	}
+	return entry.Validate(value)
 }
 
 // GetSecurityPolicy 获取安全策略配置
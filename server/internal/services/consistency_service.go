@@ -0,0 +1,208 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// consistencyIssueSamples 每个检查项在报告中保留的不一致记录数
+	consistencyIssueSamples = 100
+	// consistencyRepairBatchSize 每次修复的记录数
+	consistencyRepairBatchSize = 500
+)
+
+// counterCheck 冗余计数字段检查：table.column 应等于 actual 子查询的结果，
+// actual 中以表名引用外层记录，修复时直接用于 UPDATE
+type counterCheck struct {
+	check       string
+	description string
+	table       string
+	column      string
+	actual      string
+	args        []interface{}
+}
+
+// counterRow 计数不一致的记录
+type counterRow struct {
+	EntityID uint
+	Stored   int64
+	Actual   int64
+}
+
+// ConsistencyService 数据一致性检查服务，检查冗余计数字段及孤立记录，可选修复
+type ConsistencyService struct {
+	db *gorm.DB
+}
+
+// NewConsistencyService 创建数据一致性检查服务
+func NewConsistencyService(db *gorm.DB) *ConsistencyService {
+	return &ConsistencyService{db: db}
+}
+
+// counterChecks 需要检查的冗余计数字段
+func (s *ConsistencyService) counterChecks() []counterCheck {
+	return []counterCheck{
+		{
+			check:       models.ConsistencyCheckTicketComments,
+			description: "tickets.comment_count 与未删除评论数",
+			table:       "tickets",
+			column:      "comment_count",
+			actual:      "SELECT COUNT(*) FROM ticket_comments WHERE ticket_comments.ticket_id = tickets.id AND ticket_comments.deleted_at IS NULL",
+		},
+		{
+			check:       models.ConsistencyCheckCategoryTickets,
+			description: "categories.ticket_count 与分类下工单数",
+			table:       "categories",
+			column:      "ticket_count",
+			actual:      "SELECT COUNT(*) FROM tickets WHERE tickets.category_id = categories.id AND tickets.deleted_at IS NULL",
+		},
+		{
+			check:       models.ConsistencyCheckCategoryActive,
+			description: "categories.active_ticket_count 与分类下未完结工单数",
+			table:       "categories",
+			column:      "active_ticket_count",
+			actual:      "SELECT COUNT(*) FROM tickets WHERE tickets.category_id = categories.id AND tickets.deleted_at IS NULL AND tickets.status NOT IN ?",
+			args:        []interface{}{closedTicketStatuses},
+		},
+		{
+			check:       models.ConsistencyCheckUserAssigned,
+			description: "users.tickets_assigned 与分配给用户的工单数",
+			table:       "users",
+			column:      "tickets_assigned",
+			actual:      "SELECT COUNT(*) FROM tickets WHERE tickets.assigned_to_id = users.id AND tickets.deleted_at IS NULL",
+		},
+	}
+}
+
+// Verify 执行全部一致性检查；repair 为 true 时按明细修正计数并删除孤立记录。
+// 单个检查项失败时记录在结果中并继续执行其余检查项。
+func (s *ConsistencyService) Verify(ctx context.Context, repair bool) (*models.ConsistencyReport, error) {
+	report := &models.ConsistencyReport{Repair: repair, StartedAt: time.Now()}
+
+	for _, check := range s.counterChecks() {
+		result := s.verifyCounter(ctx, check, repair)
+		report.Results = append(report.Results, result)
+	}
+	report.Results = append(report.Results, s.verifyOrphanHistory(ctx, repair))
+
+	failed := 0
+	for _, result := range report.Results {
+		report.TotalDiscrepancies += result.Discrepancies
+		report.TotalRepaired += result.Repaired
+		if result.Error != "" {
+			failed++
+		}
+	}
+	report.FinishedAt = time.Now()
+	if failed == len(report.Results) {
+		return report, fmt.Errorf("all consistency checks failed: %s", report.Results[0].Error)
+	}
+	return report, nil
+}
+
+// verifyCounter 检查并修复一个冗余计数字段
+func (s *ConsistencyService) verifyCounter(ctx context.Context, check counterCheck, repair bool) *models.ConsistencyCheckResult {
+	result := &models.ConsistencyCheckResult{Check: check.check, Description: check.description, Issues: []models.ConsistencyIssue{}}
+
+	query := fmt.Sprintf(
+		"SELECT entity_id, stored, actual FROM (SELECT %[1]s.id AS entity_id, %[1]s.%[2]s AS stored, (%[3]s) AS actual FROM %[1]s WHERE %[1]s.deleted_at IS NULL) counters WHERE stored <> actual ORDER BY entity_id",
+		check.table, check.column, check.actual)
+	var rows []counterRow
+	if err := s.db.WithContext(ctx).Raw(query, check.args...).Scan(&rows).Error; err != nil {
+		result.Error = fmt.Sprintf("failed to check %s: %v", check.check, err)
+		return result
+	}
+
+	result.Discrepancies = len(rows)
+	ids := make([]uint, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.EntityID)
+		if len(result.Issues) < consistencyIssueSamples {
+			result.Issues = append(result.Issues, models.ConsistencyIssue{EntityID: row.EntityID, Stored: row.Stored, Actual: row.Actual})
+		}
+	}
+	if !repair || len(ids) == 0 {
+		return result
+	}
+
+	update := fmt.Sprintf("UPDATE %[1]s SET %[2]s = (%[3]s) WHERE %[1]s.id IN ?", check.table, check.column, check.actual)
+	for start := 0; start < len(ids); start += consistencyRepairBatchSize {
+		end := start + consistencyRepairBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		args := append(append([]interface{}{}, check.args...), ids[start:end])
+		exec := s.db.WithContext(ctx).Exec(update, args...)
+		if exec.Error != nil {
+			result.Error = fmt.Sprintf("failed to repair %s: %v", check.check, exec.Error)
+			return result
+		}
+		result.Repaired += int(exec.RowsAffected)
+	}
+	return result
+}
+
+// verifyOrphanHistory 检查工单已被删除的历史记录
+func (s *ConsistencyService) verifyOrphanHistory(ctx context.Context, repair bool) *models.ConsistencyCheckResult {
+	result := &models.ConsistencyCheckResult{
+		Check:       models.ConsistencyCheckOrphanTicketHistory,
+		Description: "ticket_histories 中工单已不存在的记录",
+		Issues:      []models.ConsistencyIssue{},
+	}
+
+	orphan := s.db.WithContext(ctx).Model(&models.TicketHistory{}).
+		Where("NOT EXISTS (SELECT 1 FROM tickets WHERE tickets.id = ticket_histories.ticket_id)")
+	var histories []models.TicketHistory
+	if err := orphan.Select("id", "ticket_id").Order("id").Find(&histories).Error; err != nil {
+		result.Error = fmt.Sprintf("failed to check orphan ticket history: %v", err)
+		return result
+	}
+
+	result.Discrepancies = len(histories)
+	ids := make([]uint, 0, len(histories))
+	for _, history := range histories {
+		ids = append(ids, history.ID)
+		if len(result.Issues) < consistencyIssueSamples {
+			result.Issues = append(result.Issues, models.ConsistencyIssue{
+				EntityID: history.ID,
+				Detail:   fmt.Sprintf("ticket %d not found", history.TicketID),
+			})
+		}
+	}
+	if !repair {
+		return result
+	}
+
+	for start := 0; start < len(ids); start += consistencyRepairBatchSize {
+		end := start + consistencyRepairBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		exec := s.db.WithContext(ctx).Where("id IN ?", ids[start:end]).Delete(&models.TicketHistory{})
+		if exec.Error != nil {
+			result.Error = fmt.Sprintf("failed to delete orphan ticket history: %v", exec.Error)
+			return result
+		}
+		result.Repaired += int(exec.RowsAffected)
+	}
+	return result
+}
+
+// LogConsistencyReport 输出一致性检查报告摘要
+func LogConsistencyReport(report *models.ConsistencyReport) {
+	for _, result := range report.Results {
+		switch {
+		case result.Error != "":
+			log.Printf("Consistency check %s failed: %s", result.Check, result.Error)
+		case result.Discrepancies > 0:
+			log.Printf("Consistency check %s: %d discrepancies, %d repaired", result.Check, result.Discrepancies, result.Repaired)
+		}
+	}
+	log.Printf("Consistency check finished: %d discrepancies, %d repaired", report.TotalDiscrepancies, report.TotalRepaired)
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestConsistencyService_VerifyAndRepair(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:consistency?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Category{}, &models.Ticket{}, &models.TicketComment{}, &models.TicketHistory{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+
+	agent := models.User{Username: "agent", Email: "agent@example.com", PasswordHash: "x", Role: models.RoleAgent, TicketsAssigned: 5}
+	db.Create(&agent)
+	category := models.Category{Name: "硬件", TicketCount: 1}
+	db.Create(&category)
+	open := models.Ticket{TicketNumber: "T-C-1", Title: "a", Description: "d", CreatedByID: agent.ID, AssignedToID: &agent.ID, CategoryID: &category.ID}
+	closed := models.Ticket{TicketNumber: "T-C-2", Title: "b", Description: "d", CreatedByID: agent.ID, CategoryID: &category.ID, Status: models.TicketStatusClosed}
+	db.Create(&open)
+	db.Create(&closed)
+	db.Create(&models.TicketComment{TicketID: open.ID, UserID: agent.ID, Content: "c"})
+	db.Create(&models.TicketHistory{TicketID: 999, Action: models.HistoryActionStatusChange, Description: "orphan"})
+	db.Create(&models.TicketHistory{TicketID: open.ID, Action: models.HistoryActionStatusChange, Description: "ok"})
+
+	service := NewConsistencyService(db)
+	ctx := context.Background()
+	report, err := service.Verify(ctx, false)
+	if err != nil {
+		t.Fatalf("verify failed: %v", err)
+	}
+	expected := map[string]int{
+		models.ConsistencyCheckTicketComments:      1, // comment_count 0，实际 1
+		models.ConsistencyCheckCategoryTickets:     1, // ticket_count 1，实际 2
+		models.ConsistencyCheckCategoryActive:      1, // active_ticket_count 0，实际 1
+		models.ConsistencyCheckUserAssigned:        1, // tickets_assigned 5，实际 1
+		models.ConsistencyCheckOrphanTicketHistory: 1,
+	}
+	for _, result := range report.Results {
+		if result.Error != "" || result.Discrepancies != expected[result.Check] || result.Repaired != 0 {
+			t.Fatalf("unexpected result for %s: %+v", result.Check, result)
+		}
+	}
+
+	report, err = service.Verify(ctx, true)
+	if err != nil || report.TotalRepaired != 5 {
+		t.Fatalf("expected 5 repaired records, got %+v (%v)", report, err)
+	}
+	var user models.User
+	db.First(&user, agent.ID)
+	var cat models.Category
+	db.First(&cat, category.ID)
+	if user.TicketsAssigned != 1 || cat.TicketCount != 2 || cat.ActiveTicketCount != 1 {
+		t.Fatalf("counters not repaired: assigned=%d tickets=%d active=%d", user.TicketsAssigned, cat.TicketCount, cat.ActiveTicketCount)
+	}
+
+	report, _ = service.Verify(ctx, false)
+	if report.TotalDiscrepancies != 0 {
+		t.Fatalf("expected no discrepancies after repair, got %d", report.TotalDiscrepancies)
+	}
+}
@@ -0,0 +1,352 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	dashboardSectionTimeout = 5 * time.Second
+	dashboardListLimit      = 10
+	dashboardActivityLimit  = 15
+	// dashboardSLARiskWindow SLA 到期时间在此窗口内的未完结工单视为有风险
+	dashboardSLARiskWindow = 4 * time.Hour
+	// dashboardCacheTTL 仪表板结果缓存时间，未读通知数不缓存
+	dashboardCacheTTL = 30 * time.Second
+)
+
+// DashboardService 首页仪表板聚合服务，一次请求返回多个区块，各区块并行查询、互不影响
+type DashboardService struct {
+	db                  *gorm.DB
+	notificationService NotificationServiceInterface
+	reminderService     *TicketReminderService
+	cache               *ReadThroughCache
+}
+
+// NewDashboardService 创建仪表板服务
+func NewDashboardService(db *gorm.DB) *DashboardService {
+	return &DashboardService{
+		db:                  db,
+		notificationService: NewNotificationService(db),
+		reminderService:     NewTicketReminderService(db),
+	}
+}
+
+// SetCache 设置结果缓存，未设置或 Redis 不可用时直接查询数据库
+func (s *DashboardService) SetCache(cache *ReadThroughCache) {
+	s.cache = cache
+}
+
+// DashboardStats 当前用户范围内的工单统计
+type DashboardStats struct {
+	Total        int64            `json:"total"`
+	ByStatus     map[string]int64 `json:"by_status"`
+	Open         int64            `json:"open"` // 未完结工单数
+	Overdue      int64            `json:"overdue"`
+	SLABreached  int64            `json:"sla_breached"`
+	HighPriority int64            `json:"high_priority"`
+	Error        string           `json:"error,omitempty"`
+}
+
+// DashboardTicketList 仪表板工单列表区块
+type DashboardTicketList struct {
+	Items []*models.TicketResponse `json:"items"`
+	Total int64                    `json:"total"`
+	Error string                   `json:"error,omitempty"`
+}
+
+// DashboardActivity 最近动态区块
+type DashboardActivity struct {
+	Items []*models.TicketHistoryResponse `json:"items"`
+	Error string                          `json:"error,omitempty"`
+}
+
+// DashboardNotifications 通知区块
+type DashboardNotifications struct {
+	UnreadCount int64  `json:"unread_count"`
+	Error       string `json:"error,omitempty"`
+}
+
+// DashboardReminders 当前坐席待提醒的工单提醒区块，Due 为已到提醒时间、等待定时任务发送的数量
+type DashboardReminders struct {
+	Items []models.TicketReminder `json:"items"`
+	Due   int                     `json:"due"`
+	Error string                  `json:"error,omitempty"`
+}
+
+// DashboardResponse 仪表板响应，单个区块失败时仅在该区块的 error 字段中返回错误
+type DashboardResponse struct {
+	GeneratedAt   time.Time               `json:"generated_at"`
+	Scope         string                  `json:"scope"` // all / assigned / own
+	Stats         *DashboardStats         `json:"stats"`
+	MyOpenTickets *DashboardTicketList    `json:"my_open_tickets"`
+	SLARisks      *DashboardTicketList    `json:"sla_risks"`
+	Activity      *DashboardActivity      `json:"recent_activity"`
+	Notifications *DashboardNotifications `json:"notifications"`
+	Reminders     *DashboardReminders     `json:"reminders"`
+}
+
+// dashboardScope 按角色限定可见工单：管理员和主管查看全部，客服查看分配给自己或团队的工单，客户只看自己提交的工单
+type dashboardScope struct {
+	userID   uint
+	name     string
+	customer bool
+}
+
+func newDashboardScope(userID uint, role string) dashboardScope {
+	viewer := CommentViewer{UserID: userID, Role: role}
+	switch {
+	case viewer.IsCustomer():
+		return dashboardScope{userID: userID, name: "own", customer: true}
+	case viewer.seesAllTeams():
+		return dashboardScope{userID: userID, name: "all"}
+	default:
+		return dashboardScope{userID: userID, name: "assigned"}
+	}
+}
+
+func (sc dashboardScope) apply(db, query *gorm.DB) *gorm.DB {
+	switch sc.name {
+	case "own":
+		return query.Where("tickets.created_by_id = ?", sc.userID)
+	case "assigned":
+		return query.Where("tickets.assigned_to_id = ? OR tickets.assigned_team_id IN (?)",
+			sc.userID, db.Table("team_members").Select("team_id").Where("user_id = ?", sc.userID))
+	}
+	return query
+}
+
+// GetDashboard 获取仪表板，工单相关区块按用户缓存，所有区块均成功时才写入缓存
+func (s *DashboardService) GetDashboard(ctx context.Context, userID uint, role string) *DashboardResponse {
+	if s.cache == nil {
+		return s.buildDashboard(ctx, userID, role)
+	}
+
+	start := time.Now()
+	resp := &DashboardResponse{}
+	s.cache.GetOrLoad(ctx, fmt.Sprintf("%d:%s", userID, role), dashboardCacheTTL, resp, func() (bool, error) {
+		*resp = *s.buildDashboard(ctx, userID, role)
+		return resp.complete(), nil
+	})
+
+	// 命中缓存时单独刷新未读通知数，用户读完通知后角标立即更新
+	if resp.GeneratedAt.Before(start) {
+		resp.Notifications = &DashboardNotifications{}
+		count, err := s.notificationService.GetUnreadCount(ctx, userID)
+		resp.Notifications.UnreadCount = count
+		if err != nil {
+			resp.Notifications.Error = err.Error()
+		}
+	}
+	return resp
+}
+
+// complete 所有区块均查询成功
+func (r *DashboardResponse) complete() bool {
+	return r.Stats.Error == "" && r.MyOpenTickets.Error == "" && r.SLARisks.Error == "" &&
+		r.Activity.Error == "" && r.Notifications.Error == "" && r.Reminders.Error == ""
+}
+
+// buildDashboard 并行查询各区块并组装仪表板
+func (s *DashboardService) buildDashboard(ctx context.Context, userID uint, role string) *DashboardResponse {
+	scope := newDashboardScope(userID, role)
+	resp := &DashboardResponse{
+		GeneratedAt:   time.Now(),
+		Scope:         scope.name,
+		Stats:         &DashboardStats{ByStatus: map[string]int64{}},
+		MyOpenTickets: &DashboardTicketList{Items: []*models.TicketResponse{}},
+		SLARisks:      &DashboardTicketList{Items: []*models.TicketResponse{}},
+		Activity:      &DashboardActivity{Items: []*models.TicketHistoryResponse{}},
+		Notifications: &DashboardNotifications{},
+		Reminders:     &DashboardReminders{Items: []models.TicketReminder{}},
+	}
+
+	sections := []struct {
+		name   string
+		run    func(ctx context.Context) error
+		setErr func(msg string)
+	}{
+		{"stats", func(ctx context.Context) error { return s.loadStats(ctx, scope, resp.Stats) }, func(m string) { resp.Stats.Error = m }},
+		{"my_open_tickets", func(ctx context.Context) error { return s.loadMyOpenTickets(ctx, scope, resp.MyOpenTickets) }, func(m string) { resp.MyOpenTickets.Error = m }},
+		{"sla_risks", func(ctx context.Context) error { return s.loadSLARisks(ctx, scope, resp.SLARisks) }, func(m string) { resp.SLARisks.Error = m }},
+		{"recent_activity", func(ctx context.Context) error { return s.loadActivity(ctx, scope, resp.Activity) }, func(m string) { resp.Activity.Error = m }},
+		{"notifications", func(ctx context.Context) error {
+			count, err := s.notificationService.GetUnreadCount(ctx, userID)
+			resp.Notifications.UnreadCount = count
+			return err
+		}, func(m string) { resp.Notifications.Error = m }},
+		{"reminders", func(ctx context.Context) error { return s.loadReminders(ctx, scope, resp.Reminders) }, func(m string) { resp.Reminders.Error = m }},
+	}
+
+	var wg sync.WaitGroup
+	for _, section := range sections {
+		wg.Add(1)
+		go func(name string, run func(ctx context.Context) error, setErr func(string)) {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					log.Printf("Dashboard section %s panicked: %v", name, r)
+					setErr("internal error")
+				}
+			}()
+
+			sectionCtx, cancel := context.WithTimeout(ctx, dashboardSectionTimeout)
+			defer cancel()
+			if err := run(sectionCtx); err != nil {
+				log.Printf("Dashboard section %s failed for user %d: %v", name, userID, err)
+				setErr(err.Error())
+			}
+		}(section.name, section.run, section.setErr)
+	}
+	wg.Wait()
+
+	return resp
+}
+
+func (s *DashboardService) scopedTickets(ctx context.Context, scope dashboardScope) *gorm.DB {
+	query := s.db.WithContext(ctx).Model(&models.Ticket{}).Where("tickets.deleted_at IS NULL")
+	return scope.apply(s.db, query)
+}
+
+func (s *DashboardService) loadStats(ctx context.Context, scope dashboardScope, stats *DashboardStats) error {
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	if err := s.scopedTickets(ctx, scope).
+		Select("tickets.status AS status, COUNT(*) AS count").
+		Group("tickets.status").
+		Scan(&rows).Error; err != nil {
+		return fmt.Errorf("failed to count tickets by status: %w", err)
+	}
+
+	byStatus := make(map[string]int64, len(rows))
+	var total, open int64
+	for _, row := range rows {
+		byStatus[row.Status] = row.Count
+		total += row.Count
+	}
+	open = total
+	for _, status := range closedTicketStatuses {
+		open -= byStatus[string(status)]
+	}
+
+	now := time.Now()
+	var overdue, breached, high int64
+	if err := s.scopedTickets(ctx, scope).
+		Where("tickets.due_date < ? AND tickets.status NOT IN ?", now, closedTicketStatuses).
+		Count(&overdue).Error; err != nil {
+		return fmt.Errorf("failed to count overdue tickets: %w", err)
+	}
+	if err := s.scopedTickets(ctx, scope).
+		Where("tickets.status NOT IN ?", closedTicketStatuses).
+		Where("tickets.sla_breached = ? OR tickets.sla_due_date < ?", true, now).
+		Count(&breached).Error; err != nil {
+		return fmt.Errorf("failed to count SLA breached tickets: %w", err)
+	}
+	if err := s.scopedTickets(ctx, scope).
+		Where("tickets.status NOT IN ? AND tickets.priority IN ?", closedTicketStatuses,
+			[]models.TicketPriority{models.TicketPriorityHigh, models.TicketPriorityUrgent, models.TicketPriorityCritical}).
+		Count(&high).Error; err != nil {
+		return fmt.Errorf("failed to count high priority tickets: %w", err)
+	}
+
+	stats.Total = total
+	stats.ByStatus = byStatus
+	stats.Open = open
+	stats.Overdue = overdue
+	stats.SLABreached = breached
+	stats.HighPriority = high
+	return nil
+}
+
+// loadMyOpenTickets 客服为分配给自己的未完结工单，客户为自己提交的未完结工单
+func (s *DashboardService) loadMyOpenTickets(ctx context.Context, scope dashboardScope, list *DashboardTicketList) error {
+	query := s.db.WithContext(ctx).Model(&models.Ticket{}).
+		Where("deleted_at IS NULL AND status NOT IN ?", closedTicketStatuses)
+	if scope.customer {
+		query = query.Where("created_by_id = ?", scope.userID)
+	} else {
+		query = query.Where("assigned_to_id = ?", scope.userID)
+	}
+	return s.fillTicketList(query, "updated_at DESC", list)
+}
+
+// loadSLARisks 已违约或即将到期的未完结工单，客户不返回该区块内容
+func (s *DashboardService) loadSLARisks(ctx context.Context, scope dashboardScope, list *DashboardTicketList) error {
+	if scope.customer {
+		return nil
+	}
+	query := s.scopedTickets(ctx, scope).
+		Where("tickets.status NOT IN ?", closedTicketStatuses).
+		Where("tickets.sla_breached = ? OR (tickets.sla_due_date IS NOT NULL AND tickets.sla_due_date < ?)",
+			true, time.Now().Add(dashboardSLARiskWindow))
+	return s.fillTicketList(query, "tickets.sla_due_date ASC", list)
+}
+
+// loadReminders 发给当前坐席的待提醒工单提醒，按提醒时间排序，客户不返回该区块内容
+func (s *DashboardService) loadReminders(ctx context.Context, scope dashboardScope, reminders *DashboardReminders) error {
+	if scope.customer {
+		return nil
+	}
+	items, err := s.reminderService.ListForUser(ctx, scope.userID, models.TicketReminderStatusPending, dashboardListLimit)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, item := range items {
+		if !item.RemindAt.After(now) {
+			reminders.Due++
+		}
+	}
+	reminders.Items = items
+	return nil
+}
+
+func (s *DashboardService) fillTicketList(query *gorm.DB, order string, list *DashboardTicketList) error {
+	if err := query.Count(&list.Total).Error; err != nil {
+		return fmt.Errorf("failed to count tickets: %w", err)
+	}
+
+	var tickets []models.Ticket
+	if err := query.Preload("AssignedTo").Preload("AssignedTeam").
+		Order(order).Limit(dashboardListLimit).
+		Find(&tickets).Error; err != nil {
+		return fmt.Errorf("failed to get tickets: %w", err)
+	}
+
+	list.Items = make([]*models.TicketResponse, 0, len(tickets))
+	for i := range tickets {
+		list.Items = append(list.Items, tickets[i].ToResponse())
+	}
+	return nil
+}
+
+// loadActivity 范围内工单的最近动态，客户只能看到对用户可见的记录
+func (s *DashboardService) loadActivity(ctx context.Context, scope dashboardScope, activity *DashboardActivity) error {
+	ticketIDs := s.scopedTickets(ctx, scope).Select("tickets.id")
+
+	query := s.db.WithContext(ctx).Model(&models.TicketHistory{}).
+		Preload("User").
+		Where("ticket_id IN (?)", ticketIDs)
+	if scope.customer {
+		query = query.Where("is_visible = ?", true)
+	}
+
+	var histories []models.TicketHistory
+	if err := query.Order("created_at DESC").Limit(dashboardActivityLimit).Find(&histories).Error; err != nil {
+		return fmt.Errorf("failed to get recent activity: %w", err)
+	}
+
+	activity.Items = make([]*models.TicketHistoryResponse, 0, len(histories))
+	for i := range histories {
+		activity.Items = append(activity.Items, histories[i].ToResponse())
+	}
+	return nil
+}
@@ -0,0 +1,505 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+// delegationMaxHops 代理人本身也在休假时最多继续转交的次数
+const delegationMaxHops = 5
+
+var (
+	// ErrDelegationNotFound 委托不存在
+	ErrDelegationNotFound = errors.New("delegation not found")
+	// ErrDelegationForbidden 无权管理该委托
+	ErrDelegationForbidden = errors.New("not allowed to manage this delegation")
+	// ErrDelegationInvalid 委托参数无效
+	ErrDelegationInvalid = errors.New("invalid delegation")
+	// ErrDelegationOverlap 与委托人已有的委托时间重叠
+	ErrDelegationOverlap = errors.New("delegation overlaps an existing delegation")
+	// ErrDelegationFinished 委托已结束或已取消
+	ErrDelegationFinished = errors.New("delegation has already ended")
+)
+
+// DelegationFilters 委托列表过滤条件
+type DelegationFilters struct {
+	UserID   uint // 委托人或代理人
+	Status   models.DelegationStatus
+	Page     int
+	PageSize int
+}
+
+// DelegationRunResult 定时处理结果
+type DelegationRunResult struct {
+	Activated   int `json:"activated"`
+	Ended       int `json:"ended"`
+	Transferred int `json:"transferred"`
+	Reverted    int `json:"reverted"`
+}
+
+// DelegationRoute 分配改派结果：UserID 为空时进入 TeamID 对应的团队队列
+type DelegationRoute struct {
+	Delegation *models.AssignmentDelegation
+	UserID     *uint
+	TeamID     *uint
+}
+
+// Description 生成工单历史中的改派说明
+func (r *DelegationRoute) Description(originalAssigneeID uint) string {
+	if r.UserID != nil {
+		return fmt.Sprintf("用户 ID: %d 休假委托生效，工单改派给用户 ID: %d", originalAssigneeID, *r.UserID)
+	}
+	return fmt.Sprintf("用户 ID: %d 休假委托生效，工单改派到团队队列 ID: %d", originalAssigneeID, *r.TeamID)
+}
+
+// DelegationService 处理人休假分配委托服务
+type DelegationService struct {
+	db                  *gorm.DB
+	notificationService NotificationServiceInterface
+}
+
+// NewDelegationService 创建分配委托服务
+func NewDelegationService(db *gorm.DB) *DelegationService {
+	return &DelegationService{
+		db:                  db,
+		notificationService: NewNotificationService(db),
+	}
+}
+
+// CanManageDelegations 判断角色是否可以管理他人的委托
+func CanManageDelegations(role string) bool {
+	return role == string(models.RoleAdmin) || role == string(models.RoleSupervisor) || role == "superuser"
+}
+
+// Create 创建委托；开始时间已到时立即生效
+func (s *DelegationService) Create(ctx context.Context, req *models.DelegationCreateRequest, actorID uint, actorRole string) (*models.AssignmentDelegation, error) {
+	fromUserID := actorID
+	if req.FromUserID != nil && *req.FromUserID != actorID {
+		if !CanManageDelegations(actorRole) {
+			return nil, ErrDelegationForbidden
+		}
+		fromUserID = *req.FromUserID
+	}
+
+	now := time.Now()
+	if (req.ToUserID == nil) == (req.ToTeamID == nil) {
+		return nil, fmt.Errorf("%w: exactly one of to_user_id and to_team_id is required", ErrDelegationInvalid)
+	}
+	if !req.EndsAt.After(req.StartsAt) {
+		return nil, fmt.Errorf("%w: ends_at must be after starts_at", ErrDelegationInvalid)
+	}
+	if !req.EndsAt.After(now) {
+		return nil, fmt.Errorf("%w: ends_at must be in the future", ErrDelegationInvalid)
+	}
+
+	if err := s.ensureAgent(ctx, fromUserID, "from_user_id"); err != nil {
+		return nil, err
+	}
+	if req.ToUserID != nil {
+		if *req.ToUserID == fromUserID {
+			return nil, fmt.Errorf("%w: cannot delegate to yourself", ErrDelegationInvalid)
+		}
+		if err := s.ensureAgent(ctx, *req.ToUserID, "to_user_id"); err != nil {
+			return nil, err
+		}
+	}
+	if req.ToTeamID != nil {
+		var count int64
+		if err := s.db.WithContext(ctx).Model(&models.Team{}).
+			Where("id = ? AND is_active = ?", *req.ToTeamID, true).
+			Count(&count).Error; err != nil {
+			return nil, fmt.Errorf("failed to check team: %w", err)
+		}
+		if count == 0 {
+			return nil, fmt.Errorf("%w: team not found or inactive", ErrDelegationInvalid)
+		}
+	}
+
+	var overlapping int64
+	if err := s.db.WithContext(ctx).Model(&models.AssignmentDelegation{}).
+		Where("from_user_id = ? AND status IN ?", fromUserID,
+			[]models.DelegationStatus{models.DelegationStatusScheduled, models.DelegationStatusActive}).
+		Where("starts_at < ? AND ends_at > ?", req.EndsAt, req.StartsAt).
+		Count(&overlapping).Error; err != nil {
+		return nil, fmt.Errorf("failed to check overlapping delegations: %w", err)
+	}
+	if overlapping > 0 {
+		return nil, ErrDelegationOverlap
+	}
+
+	delegation := &models.AssignmentDelegation{
+		FromUserID:       fromUserID,
+		ToUserID:         req.ToUserID,
+		ToTeamID:         req.ToTeamID,
+		StartsAt:         req.StartsAt,
+		EndsAt:           req.EndsAt,
+		TransferExisting: req.TransferExisting,
+		Reason:           req.Reason,
+		Status:           models.DelegationStatusScheduled,
+		CreatedByID:      actorID,
+	}
+	if err := s.db.WithContext(ctx).Create(delegation).Error; err != nil {
+		return nil, fmt.Errorf("failed to create delegation: %w", err)
+	}
+
+	if delegation.ToUserID != nil {
+		s.notify(ctx, *delegation.ToUserID, "你已被设置为休假代理人",
+			fmt.Sprintf("%s 至 %s 期间，分配给用户 ID: %d 的工单将改派给你",
+				delegation.StartsAt.Format("2006-01-02 15:04"), delegation.EndsAt.Format("2006-01-02 15:04"), fromUserID),
+			delegation)
+	}
+
+	if !now.Before(delegation.StartsAt) {
+		if _, err := s.activate(ctx, delegation, now); err != nil {
+			return nil, err
+		}
+	}
+	return s.Get(ctx, delegation.ID)
+}
+
+// Get 获取委托详情
+func (s *DelegationService) Get(ctx context.Context, id uint) (*models.AssignmentDelegation, error) {
+	var delegation models.AssignmentDelegation
+	err := s.db.WithContext(ctx).
+		Preload("FromUser").Preload("ToUser").Preload("ToTeam").
+		First(&delegation, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDelegationNotFound
+		}
+		return nil, fmt.Errorf("failed to get delegation: %w", err)
+	}
+	return &delegation, nil
+}
+
+// List 获取委托列表，UserID 非零时只返回该用户作为委托人或代理人的委托
+func (s *DelegationService) List(ctx context.Context, filters DelegationFilters) ([]models.AssignmentDelegation, int64, error) {
+	query := s.db.WithContext(ctx).Model(&models.AssignmentDelegation{})
+	if filters.UserID != 0 {
+		query = query.Where("from_user_id = ? OR to_user_id = ?", filters.UserID, filters.UserID)
+	}
+	if filters.Status != "" {
+		query = query.Where("status = ?", filters.Status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count delegations: %w", err)
+	}
+
+	if filters.Page < 1 {
+		filters.Page = 1
+	}
+	if filters.PageSize < 1 || filters.PageSize > 100 {
+		filters.PageSize = 20
+	}
+
+	var delegations []models.AssignmentDelegation
+	err := query.Preload("FromUser").Preload("ToUser").Preload("ToTeam").
+		Order("starts_at DESC").
+		Offset((filters.Page - 1) * filters.PageSize).
+		Limit(filters.PageSize).
+		Find(&delegations).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list delegations: %w", err)
+	}
+	return delegations, total, nil
+}
+
+// Cancel 取消委托；生效中的委托会立即结束并转回已转移的工单
+func (s *DelegationService) Cancel(ctx context.Context, id uint, actorID uint, actorRole string) (*models.AssignmentDelegation, error) {
+	delegation, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if delegation.FromUserID != actorID && delegation.CreatedByID != actorID && !CanManageDelegations(actorRole) {
+		return nil, ErrDelegationForbidden
+	}
+
+	switch delegation.Status {
+	case models.DelegationStatusActive:
+		if _, err := s.end(ctx, delegation, time.Now(), models.DelegationStatusCancelled); err != nil {
+			return nil, err
+		}
+	case models.DelegationStatusScheduled:
+		result := s.db.WithContext(ctx).Model(&models.AssignmentDelegation{}).
+			Where("id = ? AND status = ?", id, models.DelegationStatusScheduled).
+			Updates(map[string]interface{}{"status": models.DelegationStatusCancelled, "ended_at": time.Now()})
+		if result.Error != nil {
+			return nil, fmt.Errorf("failed to cancel delegation: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return nil, ErrDelegationFinished
+		}
+	default:
+		return nil, ErrDelegationFinished
+	}
+	return s.Get(ctx, id)
+}
+
+// Route 查找指定处理人在某时刻生效的委托；代理人也在休假时继续沿委托链转交
+func (s *DelegationService) Route(ctx context.Context, assigneeID uint, at time.Time) (*DelegationRoute, error) {
+	var route *DelegationRoute
+	visited := map[uint]bool{assigneeID: true}
+	current := assigneeID
+
+	for hop := 0; hop < delegationMaxHops; hop++ {
+		var delegation models.AssignmentDelegation
+		err := s.db.WithContext(ctx).
+			Where("from_user_id = ? AND status IN ? AND starts_at <= ? AND ends_at > ?", current,
+				[]models.DelegationStatus{models.DelegationStatusScheduled, models.DelegationStatusActive}, at, at).
+			Order("starts_at DESC").
+			First(&delegation).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to find delegation: %w", err)
+		}
+
+		route = &DelegationRoute{Delegation: &delegation, UserID: delegation.ToUserID, TeamID: delegation.ToTeamID}
+		if delegation.ToUserID == nil || visited[*delegation.ToUserID] {
+			break
+		}
+		visited[*delegation.ToUserID] = true
+		current = *delegation.ToUserID
+	}
+	return route, nil
+}
+
+// ProcessDue 激活到达开始时间的委托、结束到期的委托，由定时任务调用
+func (s *DelegationService) ProcessDue(ctx context.Context, now time.Time) (*DelegationRunResult, error) {
+	result := &DelegationRunResult{}
+
+	var due []models.AssignmentDelegation
+	if err := s.db.WithContext(ctx).
+		Where("status = ? AND starts_at <= ? AND ends_at > ?", models.DelegationStatusScheduled, now, now).
+		Find(&due).Error; err != nil {
+		return nil, fmt.Errorf("failed to find due delegations: %w", err)
+	}
+	for i := range due {
+		transferred, err := s.activate(ctx, &due[i], now)
+		if err != nil {
+			log.Printf("Failed to activate delegation %d: %v", due[i].ID, err)
+			continue
+		}
+		result.Activated++
+		result.Transferred += transferred
+	}
+
+	var expired []models.AssignmentDelegation
+	if err := s.db.WithContext(ctx).
+		Where("status IN ? AND ends_at <= ?",
+			[]models.DelegationStatus{models.DelegationStatusScheduled, models.DelegationStatusActive}, now).
+		Find(&expired).Error; err != nil {
+		return nil, fmt.Errorf("failed to find expired delegations: %w", err)
+	}
+	for i := range expired {
+		reverted, err := s.end(ctx, &expired[i], now, models.DelegationStatusEnded)
+		if err != nil {
+			log.Printf("Failed to end delegation %d: %v", expired[i].ID, err)
+			continue
+		}
+		result.Ended++
+		result.Reverted += reverted
+	}
+	return result, nil
+}
+
+// activate 将委托置为生效，并按需转移委托人名下未完成的工单
+func (s *DelegationService) activate(ctx context.Context, delegation *models.AssignmentDelegation, now time.Time) (int, error) {
+	transferred := 0
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.AssignmentDelegation{}).
+			Where("id = ? AND status = ?", delegation.ID, models.DelegationStatusScheduled).
+			Updates(map[string]interface{}{"status": models.DelegationStatusActive, "activated_at": now})
+		if result.Error != nil {
+			return fmt.Errorf("failed to activate delegation: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrDelegationFinished
+		}
+		if !delegation.TransferExisting {
+			return nil
+		}
+
+		var tickets []models.Ticket
+		if err := tx.Where("assigned_to_id = ? AND status NOT IN ?", delegation.FromUserID, closedTicketStatuses).
+			Find(&tickets).Error; err != nil {
+			return fmt.Errorf("failed to find tickets to transfer: %w", err)
+		}
+
+		route := &DelegationRoute{Delegation: delegation, UserID: delegation.ToUserID, TeamID: delegation.ToTeamID}
+		histories := NewHistoryBatch()
+		for _, ticket := range tickets {
+			record := &models.DelegatedTicket{
+				DelegationID:       delegation.ID,
+				TicketID:           ticket.ID,
+				OriginalAssigneeID: delegation.FromUserID,
+				OriginalTeamID:     ticket.AssignedTeamID,
+			}
+			if err := tx.Create(record).Error; err != nil {
+				return fmt.Errorf("failed to record delegated ticket: %w", err)
+			}
+
+			updates := map[string]interface{}{"assigned_to_id": route.UserID, "updated_at": now}
+			if route.TeamID != nil {
+				updates["assigned_team_id"] = *route.TeamID
+			}
+			if err := tx.Model(&models.Ticket{}).Where("id = ?", ticket.ID).Updates(updates).Error; err != nil {
+				return fmt.Errorf("failed to transfer ticket %d: %w", ticket.ID, err)
+			}
+			histories.Add(delegationHistory(ticket.ID, models.HistoryActionTransfer,
+				route.Description(delegation.FromUserID), getAssigneeValue(&delegation.FromUserID), getAssigneeValue(route.UserID)))
+			transferred++
+		}
+		if err := histories.Flush(tx); err != nil {
+			return err
+		}
+
+		return tx.Model(&models.AssignmentDelegation{}).Where("id = ?", delegation.ID).
+			Update("transferred_count", transferred).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	delegation.Status = models.DelegationStatusActive
+	delegation.ActivatedAt = &now
+	delegation.TransferredCount = transferred
+
+	s.notify(ctx, delegation.FromUserID, "休假委托已生效",
+		fmt.Sprintf("委托期间新分配给你的工单将被改派，至 %s 结束；已转移 %d 个未完成工单",
+			delegation.EndsAt.Format("2006-01-02 15:04"), transferred), delegation)
+	if delegation.ToUserID != nil && transferred > 0 {
+		s.notify(ctx, *delegation.ToUserID, "休假代理已生效",
+			fmt.Sprintf("用户 ID: %d 的 %d 个未完成工单已转移给你", delegation.FromUserID, transferred), delegation)
+	}
+	return transferred, nil
+}
+
+// end 结束委托，将仍由代理人或团队队列持有的转移工单转回原处理人
+func (s *DelegationService) end(ctx context.Context, delegation *models.AssignmentDelegation, now time.Time, status models.DelegationStatus) (int, error) {
+	reverted := 0
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.AssignmentDelegation{}).
+			Where("id = ? AND status IN ?", delegation.ID,
+				[]models.DelegationStatus{models.DelegationStatusScheduled, models.DelegationStatusActive}).
+			Updates(map[string]interface{}{"status": status, "ended_at": now})
+		if result.Error != nil {
+			return fmt.Errorf("failed to end delegation: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrDelegationFinished
+		}
+
+		var records []models.DelegatedTicket
+		if err := tx.Where("delegation_id = ? AND reverted_at IS NULL", delegation.ID).Find(&records).Error; err != nil {
+			return fmt.Errorf("failed to find delegated tickets: %w", err)
+		}
+
+		histories := NewHistoryBatch()
+		for _, record := range records {
+			// 委托期间已被手动改派或已完成的工单保持不变
+			query := tx.Model(&models.Ticket{}).
+				Where("id = ? AND status NOT IN ?", record.TicketID, closedTicketStatuses)
+			if delegation.ToUserID != nil {
+				query = query.Where("assigned_to_id = ?", *delegation.ToUserID)
+			} else {
+				query = query.Where("assigned_to_id IS NULL AND assigned_team_id = ?", *delegation.ToTeamID)
+			}
+			updateResult := query.Updates(map[string]interface{}{
+				"assigned_to_id":   record.OriginalAssigneeID,
+				"assigned_team_id": record.OriginalTeamID,
+				"updated_at":       now,
+			})
+			if updateResult.Error != nil {
+				return fmt.Errorf("failed to revert ticket %d: %w", record.TicketID, updateResult.Error)
+			}
+			if updateResult.RowsAffected == 0 {
+				continue
+			}
+
+			if err := tx.Model(&models.DelegatedTicket{}).Where("id = ?", record.ID).Update("reverted_at", now).Error; err != nil {
+				return fmt.Errorf("failed to mark delegated ticket: %w", err)
+			}
+			description := fmt.Sprintf("休假委托结束，工单转回用户 ID: %d", record.OriginalAssigneeID)
+			histories.Add(delegationHistory(record.TicketID, models.HistoryActionTransfer,
+				description, getAssigneeValue(delegation.ToUserID), getAssigneeValue(&record.OriginalAssigneeID)))
+			reverted++
+		}
+		if err := histories.Flush(tx); err != nil {
+			return err
+		}
+
+		return tx.Model(&models.AssignmentDelegation{}).Where("id = ?", delegation.ID).
+			Update("reverted_count", reverted).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	delegation.Status = status
+	delegation.EndedAt = &now
+	delegation.RevertedCount = reverted
+
+	s.notify(ctx, delegation.FromUserID, "休假委托已结束",
+		fmt.Sprintf("新工单将重新分配给你，已转回 %d 个工单", reverted), delegation)
+	return reverted, nil
+}
+
+func (s *DelegationService) ensureAgent(ctx context.Context, userID uint, field string) error {
+	var user models.User
+	if err := s.db.WithContext(ctx).Select("id", "role", "status").First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: %s user not found", ErrDelegationInvalid, field)
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.IsCustomer() || user.Status == models.UserStatusSuspended {
+		return fmt.Errorf("%w: %s must be an active agent", ErrDelegationInvalid, field)
+	}
+	return nil
+}
+
+func (s *DelegationService) notify(ctx context.Context, recipientID uint, title, content string, delegation *models.AssignmentDelegation) {
+	_, err := s.notificationService.CreateNotification(ctx, &models.NotificationCreateRequest{
+		Type:        models.NotificationTypeTicketAssigned,
+		Title:       title,
+		Content:     content,
+		Priority:    models.NotificationPriorityNormal,
+		Channel:     models.NotificationChannelInApp,
+		RecipientID: recipientID,
+		RelatedType: "delegation",
+		RelatedID:   &delegation.ID,
+		Metadata: map[string]interface{}{
+			"delegation_id": delegation.ID,
+			"from_user_id":  delegation.FromUserID,
+		},
+	})
+	if err != nil {
+		log.Printf("Failed to send delegation notification to user %d: %v", recipientID, err)
+	}
+}
+
+// delegationHistory 委托引起的分配变更历史（系统自动操作）
+func delegationHistory(ticketID uint, action models.HistoryAction, description, oldValue, newValue string) *models.TicketHistory {
+	return &models.TicketHistory{
+		TicketID:    ticketID,
+		Action:      action,
+		Description: description,
+		FieldName:   "assigned_to_id",
+		OldValue:    oldValue,
+		NewValue:    newValue,
+		IsVisible:   true,
+		IsSystem:    true,
+		IsAutomated: true,
+		IsImportant: true,
+	}
+}
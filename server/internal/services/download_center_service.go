@@ -0,0 +1,253 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// downloadHistoryRetention 文件过期后下载记录的保留时间，期间可重新生成
+	downloadHistoryRetention = 30 * 24 * time.Hour
+	// downloadFilePath 签名下载地址，无需登录
+	downloadFilePath = "/api/downloads/%d/file?expires=%d&signature=%s"
+	// defaultDownloadLinkTTL 未配置时签名下载链接的有效期
+	defaultDownloadLinkTTL = 60 * time.Minute
+)
+
+var (
+	// ErrDownloadNotFound 下载记录不存在或不属于当前用户
+	ErrDownloadNotFound = errors.New("download not found")
+	// ErrDownloadExpired 文件已过期，需重新生成
+	ErrDownloadExpired = errors.New("download expired")
+	// ErrInvalidDownloadLink 下载链接签名无效或已过期
+	ErrInvalidDownloadLink = errors.New("invalid or expired download link")
+	// ErrDownloadNotRegenerable 该类型的文件不支持重新生成
+	ErrDownloadNotRegenerable = errors.New("download cannot be regenerated")
+)
+
+// DownloadSource 可发布到下载中心的后台导出
+type DownloadSource interface {
+	// RegenerateDownload 按下载记录保存的参数重新创建导出任务，返回新任务ID
+	RegenerateDownload(ctx context.Context, params string, userID uint) (uint, error)
+	// PurgeExpired 删除过期的导出文件及任务记录
+	PurgeExpired(ctx context.Context, now time.Time) (int, error)
+}
+
+// DownloadCenterService 下载中心：后台导出完成后通知发起人并提供签名下载链接，
+// 保留历史记录、支持重新生成，过期文件由调度任务清理
+type DownloadCenterService struct {
+	db                  *gorm.DB
+	configService       *ConfigService
+	notificationService *NotificationService
+	signer              *hmacSigner
+	dir                 string // 导出目录，与各导出服务一致
+	now                 func() time.Time
+
+	mu      sync.RWMutex
+	sources map[models.DownloadArtifactKind]DownloadSource
+}
+
+// NewDownloadCenterService 创建下载中心服务
+func NewDownloadCenterService(db *gorm.DB, dir string) *DownloadCenterService {
+	configService := NewConfigService(db)
+	return &DownloadCenterService{
+		db:                  db,
+		configService:       configService,
+		notificationService: NewNotificationService(db),
+		signer:              newHMACSigner(configService, KeyDownloadSigningKey, "下载中心链接的签名密钥", "downloads"),
+		dir:                 dir,
+		now:                 time.Now,
+		sources:             make(map[models.DownloadArtifactKind]DownloadSource),
+	}
+}
+
+// RegisterSource 注册导出来源，用于重新生成及清理过期文件
+func (s *DownloadCenterService) RegisterSource(kind models.DownloadArtifactKind, source DownloadSource) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sources[kind] = source
+}
+
+// Publish 登记已生成的文件并向发起人发送带签名下载链接的应用内通知
+func (s *DownloadCenterService) Publish(ctx context.Context, artifact *models.DownloadArtifact) error {
+	if err := s.db.WithContext(ctx).Create(artifact).Error; err != nil {
+		return fmt.Errorf("failed to create download record: %w", err)
+	}
+	if err := s.attachDownloadLink(artifact, s.now()); err != nil {
+		return err
+	}
+
+	notification, err := s.notificationService.CreateNotification(ctx, &models.NotificationCreateRequest{
+		Type:        models.NotificationTypeDownloadReady,
+		Title:       fmt.Sprintf("%s 已生成", artifact.Title),
+		Content:     fmt.Sprintf("%s 已生成，请在 %s 前下载", artifact.FileName, artifact.ExpiresAt.Format("2006-01-02 15:04")),
+		Priority:    models.NotificationPriorityNormal,
+		Channel:     models.NotificationChannelInApp,
+		RecipientID: artifact.UserID,
+		RelatedType: "download",
+		RelatedID:   &artifact.ID,
+		ActionURL:   artifact.DownloadURL,
+		Metadata: map[string]interface{}{
+			"download_id": artifact.ID,
+			"kind":        artifact.Kind,
+			"file_name":   artifact.FileName,
+			"expires_at":  artifact.ExpiresAt,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to notify download: %w", err)
+	}
+	if InAppNotificationHook != nil && notification.ID != 0 {
+		InAppNotificationHook(ctx, notification)
+	}
+	artifact.NotificationID = &notification.ID
+	return s.db.WithContext(ctx).Model(artifact).Update("notification_id", notification.ID).Error
+}
+
+// List 用户的下载记录，按生成时间倒序，未过期的附带签名下载链接
+func (s *DownloadCenterService) List(ctx context.Context, userID uint, page, pageSize int) ([]*models.DownloadArtifact, int64, error) {
+	query := s.db.WithContext(ctx).Model(&models.DownloadArtifact{}).Where("user_id = ?", userID)
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count downloads: %w", err)
+	}
+
+	var artifacts []*models.DownloadArtifact
+	if err := query.Order("created_at DESC, id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&artifacts).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list downloads: %w", err)
+	}
+	now := s.now()
+	for _, artifact := range artifacts {
+		artifact.Expired = artifact.PurgedAt != nil || !now.Before(artifact.ExpiresAt)
+		if artifact.Expired {
+			continue
+		}
+		if err := s.attachDownloadLink(artifact, now); err != nil {
+			return nil, 0, err
+		}
+	}
+	return artifacts, total, nil
+}
+
+// Regenerate 按原参数重新创建导出任务，新文件生成后另行通知
+func (s *DownloadCenterService) Regenerate(ctx context.Context, id, userID uint) (*models.DownloadRegenerateResponse, error) {
+	var artifact models.DownloadArtifact
+	if err := s.db.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).First(&artifact).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrDownloadNotFound
+		}
+		return nil, fmt.Errorf("failed to get download: %w", err)
+	}
+
+	s.mu.RLock()
+	source := s.sources[artifact.Kind]
+	s.mu.RUnlock()
+	if source == nil || artifact.Params == "" {
+		return nil, ErrDownloadNotRegenerable
+	}
+
+	jobID, err := source.RegenerateDownload(ctx, artifact.Params, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.db.WithContext(ctx).Model(&artifact).Update("regenerated_job_id", jobID).Error; err != nil {
+		return nil, fmt.Errorf("failed to update download: %w", err)
+	}
+	return &models.DownloadRegenerateResponse{Kind: artifact.Kind, JobID: jobID}, nil
+}
+
+// SignedFile 校验签名下载链接，返回下载记录及文件路径
+func (s *DownloadCenterService) SignedFile(ctx context.Context, id uint, expires, signature string) (*models.DownloadArtifact, string, error) {
+	now := s.now()
+	valid, err := s.signer.VerifyLink("download", id, expires, signature, now)
+	if err != nil {
+		return nil, "", err
+	}
+	if !valid {
+		return nil, "", ErrInvalidDownloadLink
+	}
+
+	var artifact models.DownloadArtifact
+	if err := s.db.WithContext(ctx).First(&artifact, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", ErrDownloadNotFound
+		}
+		return nil, "", fmt.Errorf("failed to get download: %w", err)
+	}
+	if artifact.PurgedAt != nil || !now.Before(artifact.ExpiresAt) {
+		return nil, "", ErrDownloadExpired
+	}
+	return &artifact, filepath.Join(s.dir, filepath.FromSlash(artifact.FilePath)), nil
+}
+
+// PurgeExpired 清理各导出来源的过期任务，删除过期文件并标记下载记录，
+// 过期超过保留期的下载记录一并删除。返回清理的文件数
+func (s *DownloadCenterService) PurgeExpired(ctx context.Context, now time.Time) (int, error) {
+	s.mu.RLock()
+	sources := make(map[models.DownloadArtifactKind]DownloadSource, len(s.sources))
+	for kind, source := range s.sources {
+		sources[kind] = source
+	}
+	s.mu.RUnlock()
+	for kind, source := range sources {
+		if _, err := source.PurgeExpired(ctx, now); err != nil {
+			log.Printf("Failed to purge expired %s exports: %v", kind, err)
+		}
+	}
+
+	var artifacts []models.DownloadArtifact
+	if err := s.db.WithContext(ctx).Where("purged_at IS NULL AND expires_at <= ?", now).Find(&artifacts).Error; err != nil {
+		return 0, fmt.Errorf("failed to find expired downloads: %w", err)
+	}
+	purged := 0
+	for _, artifact := range artifacts {
+		if artifact.FilePath != "" {
+			if err := os.Remove(filepath.Join(s.dir, filepath.FromSlash(artifact.FilePath))); err != nil && !errors.Is(err, os.ErrNotExist) {
+				log.Printf("Failed to remove download file %d: %v", artifact.ID, err)
+				continue
+			}
+		}
+		if err := s.db.WithContext(ctx).Model(&artifact).Update("purged_at", now).Error; err != nil {
+			return purged, fmt.Errorf("failed to mark download purged: %w", err)
+		}
+		purged++
+	}
+
+	if err := s.db.WithContext(ctx).Where("purged_at IS NOT NULL AND expires_at < ?", now.Add(-downloadHistoryRetention)).
+		Delete(&models.DownloadArtifact{}).Error; err != nil {
+		return purged, fmt.Errorf("failed to delete old downloads: %w", err)
+	}
+	return purged, nil
+}
+
+// attachDownloadLink 生成签名下载链接，有效期为 security.download_link_ttl_minutes 且不超过文件保留期限，
+// 链接到期后可从下载列表重新获取
+func (s *DownloadCenterService) attachDownloadLink(artifact *models.DownloadArtifact, now time.Time) error {
+	if !now.Before(artifact.ExpiresAt) {
+		return nil
+	}
+	ttl := defaultDownloadLinkTTL
+	if value, err := s.configService.GetConfigInt(KeyDownloadLinkTTL); err == nil && value > 0 {
+		ttl = time.Duration(value) * time.Minute
+	}
+	expires := now.Add(ttl)
+	if expires.After(artifact.ExpiresAt) {
+		expires = artifact.ExpiresAt
+	}
+	signature, err := s.signer.SignLink("download", artifact.ID, expires)
+	if err != nil {
+		return err
+	}
+	artifact.DownloadURL = fmt.Sprintf(downloadFilePath, artifact.ID, expires.Unix(), signature)
+	artifact.DownloadExpiresAt = &expires
+	return nil
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+)
+
+func TestDownloadCenter_PublishListRegenerateAndPurge(t *testing.T) {
+	db := newTestDB(t, &models.User{}, &models.Ticket{}, &models.AnalyticsExportJob{}, &models.SystemConfig{},
+		&models.Notification{}, &models.DownloadArtifact{})
+	ctx := context.Background()
+	dir := t.TempDir()
+	downloads := NewDownloadCenterService(db, dir)
+	exports := NewAnalyticsExportService(db, dir)
+	exports.SetDownloadCenter(downloads)
+
+	owner := models.User{Username: "dc-owner", Email: "dc-owner@example.com", PasswordHash: "x", Role: models.RoleAgent, Status: models.UserStatusActive}
+	other := models.User{Username: "dc-other", Email: "dc-other@example.com", PasswordHash: "x", Role: models.RoleAgent, Status: models.UserStatusActive}
+	db.Create(&owner)
+	db.Create(&other)
+
+	r, _ := ResolveAnalyticsExportRange("7d", nil, nil, time.Now())
+	job, err := exports.CreateJob(ctx, r, "zh-CN", owner.ID)
+	if err != nil {
+		t.Fatalf("create job failed: %v", err)
+	}
+	waitForDownloads := func(count int64) {
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			var published int64
+			db.Model(&models.DownloadArtifact{}).Where("user_id = ? AND notification_id IS NOT NULL", owner.ID).Count(&published)
+			if published >= count {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("expected %d published downloads, got %d", count, published)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	}
+	waitForDownloads(1)
+
+	// 完成通知附带签名下载链接
+	var notification models.Notification
+	if err := db.Where("recipient_id = ? AND type = ?", owner.ID, models.NotificationTypeDownloadReady).First(&notification).Error; err != nil {
+		t.Fatalf("expected download notification: %v", err)
+	}
+	if !strings.HasPrefix(notification.ActionURL, "/api/downloads/") {
+		t.Fatalf("expected signed download link, got %q", notification.ActionURL)
+	}
+
+	items, total, err := downloads.List(ctx, owner.ID, 1, 20)
+	if err != nil || total != 1 || items[0].SourceID != job.ID || items[0].Expired || items[0].DownloadURL == "" {
+		t.Fatalf("unexpected downloads %+v (%d, %v)", items, total, err)
+	}
+	if _, total, _ := downloads.List(ctx, other.ID, 1, 20); total != 0 {
+		t.Fatalf("expected other users to see no downloads, got %d", total)
+	}
+
+	link, _ := url.Parse(items[0].DownloadURL)
+	artifact, path, err := downloads.SignedFile(ctx, items[0].ID, link.Query().Get("expires"), link.Query().Get("signature"))
+	if err != nil || artifact.FileName != AnalyticsExportFileName(r.Start, r.End) {
+		t.Fatalf("expected signed link to resolve, got %+v (%v)", artifact, err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Size() != artifact.FileSize {
+		t.Fatalf("expected exported file on disk, got %v", err)
+	}
+	if _, _, err := downloads.SignedFile(ctx, items[0].ID, link.Query().Get("expires"), "bad"); !errors.Is(err, ErrInvalidDownloadLink) {
+		t.Fatalf("expected tampered link to be rejected, got %v", err)
+	}
+
+	// 重新生成只允许本人，新文件完成后另行登记
+	if _, err := downloads.Regenerate(ctx, items[0].ID, other.ID); !errors.Is(err, ErrDownloadNotFound) {
+		t.Fatalf("expected other user to be rejected, got %v", err)
+	}
+	regenerated, err := downloads.Regenerate(ctx, items[0].ID, owner.ID)
+	if err != nil || regenerated.Kind != models.DownloadKindAnalyticsExport || regenerated.JobID == job.ID {
+		t.Fatalf("unexpected regenerate result %+v (%v)", regenerated, err)
+	}
+	waitForDownloads(2)
+
+	// 过期后文件被清理，记录保留并标记过期，超过保留期后删除
+	expired := items[0].ExpiresAt.Add(time.Minute)
+	purged, err := downloads.PurgeExpired(ctx, expired)
+	if err != nil || purged != 2 {
+		t.Fatalf("expected both files to be purged, got %d (%v)", purged, err)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected file to be removed, got %v", err)
+	}
+	if _, _, err := downloads.SignedFile(ctx, items[0].ID, link.Query().Get("expires"), link.Query().Get("signature")); !errors.Is(err, ErrDownloadExpired) {
+		t.Fatalf("expected purged download to be expired, got %v", err)
+	}
+	if items, total, _ = downloads.List(ctx, owner.ID, 1, 20); total != 2 || !items[0].Expired || items[0].DownloadURL != "" {
+		t.Fatalf("expected expired history without links, got %+v", items)
+	}
+	var jobs int64
+	db.Model(&models.AnalyticsExportJob{}).Count(&jobs)
+	if jobs != 0 {
+		t.Fatalf("expected source jobs to be purged, got %d", jobs)
+	}
+
+	if _, err := downloads.PurgeExpired(ctx, expired.Add(downloadHistoryRetention+time.Hour)); err != nil {
+		t.Fatalf("purge history failed: %v", err)
+	}
+	if _, total, _ = downloads.List(ctx, owner.ID, 1, 20); total != 0 {
+		t.Fatalf("expected old history to be deleted, got %d", total)
+	}
+}
+
+func TestDownloadCenter_SignedLinkExpiresBeforeFile(t *testing.T) {
+	db := newTestDB(t, &models.SystemConfig{}, &models.DownloadArtifact{})
+	ctx := context.Background()
+	downloads := NewDownloadCenterService(db, t.TempDir())
+	now := time.Now()
+	downloads.now = func() time.Time { return now }
+	if err := NewConfigService(db).SetConfig(KeyDownloadLinkTTL, "5", "int", "", CategorySecurity, "downloads"); err != nil {
+		t.Fatalf("failed to set link ttl: %v", err)
+	}
+
+	artifact := models.DownloadArtifact{UserID: 1, Kind: models.DownloadKindAnalyticsExport, FileName: "report.xlsx", FilePath: "report.xlsx",
+		ExpiresAt: now.Add(7 * 24 * time.Hour)}
+	db.Create(&artifact)
+
+	// 链接有效期为配置的分钟数，而不是文件保留期
+	items, _, err := downloads.List(ctx, 1, 1, 20)
+	if err != nil || len(items) != 1 || items[0].DownloadExpiresAt == nil {
+		t.Fatalf("unexpected downloads %+v (%v)", items, err)
+	}
+	if want := now.Add(5 * time.Minute); !items[0].DownloadExpiresAt.Equal(want) {
+		t.Fatalf("expected link to expire at %v, got %v", want, items[0].DownloadExpiresAt)
+	}
+	link, _ := url.Parse(items[0].DownloadURL)
+	expires, signature := link.Query().Get("expires"), link.Query().Get("signature")
+	if _, _, err := downloads.SignedFile(ctx, artifact.ID, expires, signature); err != nil {
+		t.Fatalf("expected fresh link to work, got %v", err)
+	}
+
+	// 到期后链接失效，文件仍在保留期内，可从列表获取新链接
+	now = now.Add(5*time.Minute + time.Second)
+	if _, _, err := downloads.SignedFile(ctx, artifact.ID, expires, signature); !errors.Is(err, ErrInvalidDownloadLink) {
+		t.Fatalf("expected expired link to be rejected, got %v", err)
+	}
+	items, _, _ = downloads.List(ctx, 1, 1, 20)
+	link, _ = url.Parse(items[0].DownloadURL)
+	if _, _, err := downloads.SignedFile(ctx, artifact.ID, link.Query().Get("expires"), link.Query().Get("signature")); err != nil {
+		t.Fatalf("expected new link to work, got %v", err)
+	}
+
+	// 保留期最后几分钟签发的链接不超过文件过期时间
+	now = artifact.ExpiresAt.Add(-time.Minute)
+	items, _, _ = downloads.List(ctx, 1, 1, 20)
+	if !items[0].DownloadExpiresAt.Equal(artifact.ExpiresAt) {
+		t.Fatalf("expected link capped at file expiry, got %v", items[0].DownloadExpiresAt)
+	}
+}
@@ -0,0 +1,690 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+// KeyEmailAuthPolicy 收件 SPF/DKIM/DMARC 认证策略配置键
+const KeyEmailAuthPolicy = "intake.email_auth_policy"
+
+const (
+	// emailAuthDNSTimeout 单封邮件认证过程中 DNS 查询的总超时
+	emailAuthDNSTimeout = 5 * time.Second
+	// spfMaxLookups SPF 评估中需要 DNS 查询的机制上限（RFC 7208 4.6.4）
+	spfMaxLookups = 10
+	// emailSenderTrustDays 发件人可信度统计的时间范围（天）
+	emailSenderTrustDays = 90
+)
+
+var authResultsCommentPattern = regexp.MustCompile(`\(([^)]*)\)`)
+
+// emailAuthResolver 认证所需的 DNS 查询，*net.Resolver 满足该接口，测试中可替换
+type emailAuthResolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+	LookupMX(ctx context.Context, name string) ([]*net.MX, error)
+}
+
+// EmailAuthService 收件 SPF/DKIM/DMARC 认证：采信可信收信网关写入的 Authentication-Results 头，
+// 网关未提供 SPF 结果时按客户端 IP 自行校验，并根据 SPF/DKIM 结果与发件域对齐情况评估 DMARC
+type EmailAuthService struct {
+	db       *gorm.DB
+	resolver emailAuthResolver
+}
+
+// NewEmailAuthService 创建收件认证服务
+func NewEmailAuthService(db *gorm.DB) *EmailAuthService {
+	return &EmailAuthService{db: db, resolver: net.DefaultResolver}
+}
+
+// GetPolicy 获取收件认证策略
+func (s *EmailAuthService) GetPolicy(ctx context.Context) (*models.EmailAuthPolicy, error) {
+	var config models.SystemConfig
+	err := s.db.WithContext(ctx).
+		Where("key = ? AND is_active = ?", KeyEmailAuthPolicy, true).
+		First(&config).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.GetDefaultEmailAuthPolicy(), nil
+		}
+		return nil, fmt.Errorf("failed to get email auth policy: %w", err)
+	}
+
+	policy := models.GetDefaultEmailAuthPolicy()
+	if err := config.GetJSONValue(policy); err != nil {
+		log.Printf("Warning: failed to parse email auth policy, using defaults: %v", err)
+		return models.GetDefaultEmailAuthPolicy(), nil
+	}
+	if err := policy.Validate(); err != nil {
+		log.Printf("Warning: invalid email auth policy, using defaults: %v", err)
+		return models.GetDefaultEmailAuthPolicy(), nil
+	}
+	return policy, nil
+}
+
+// SetPolicy 保存收件认证策略
+func (s *EmailAuthService) SetPolicy(ctx context.Context, policy *models.EmailAuthPolicy, userID uint) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	var existing models.SystemConfig
+	err := s.db.WithContext(ctx).Where("key = ?", KeyEmailAuthPolicy).First(&existing).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to check existing policy: %w", err)
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		config := models.SystemConfig{
+			Key:         KeyEmailAuthPolicy,
+			Category:    CategorySecurity,
+			Group:       "intake",
+			Description: "收件 SPF/DKIM/DMARC 认证与防伪造策略",
+			IsActive:    true,
+			UpdatedBy:   &userID,
+		}
+		if err := config.SetValue(policy); err != nil {
+			return fmt.Errorf("failed to set policy value: %w", err)
+		}
+		if err := s.db.WithContext(ctx).Create(&config).Error; err != nil {
+			return fmt.Errorf("failed to create policy: %w", err)
+		}
+		return nil
+	}
+
+	if err := existing.SetValue(policy); err != nil {
+		return fmt.Errorf("failed to set policy value: %w", err)
+	}
+	existing.UpdatedBy = &userID
+	existing.Version++
+
+	if err := s.db.WithContext(ctx).Save(&existing).Error; err != nil {
+		return fmt.Errorf("failed to update policy: %w", err)
+	}
+	return nil
+}
+
+// Authenticate 认证收件并按策略给出处理方式，策略关闭时返回 nil
+func (s *EmailAuthService) Authenticate(ctx context.Context, req *models.InboundEmailCreateRequest) (*models.EmailAuthentication, error) {
+	policy, err := s.GetPolicy(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !policy.Enabled {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, emailAuthDNSTimeout)
+	defer cancel()
+
+	fromDomain := models.EmailDomain(req.From)
+	auth := &models.EmailAuthentication{SPF: models.EmailAuthNone, DKIM: models.EmailAuthNone, DMARC: models.EmailAuthNone}
+
+	// 只采信策略中列出的网关写入的结果，发件人自带的 Authentication-Results 头一律忽略
+	var dkimDomains []string
+	dmarcReported := false
+	for _, header := range req.AuthenticationResults {
+		servID, entries := parseAuthenticationResults(header)
+		if !policy.TrustsAuthServID(servID) {
+			continue
+		}
+		auth.Source = servID
+		for _, entry := range entries {
+			switch entry.method {
+			case "spf":
+				auth.SPF = entry.result
+				auth.SPFDomain = authResultDomain(entry.props["smtp.mailfrom"], entry.props["smtp.helo"])
+			case "dkim":
+				if entry.result == models.EmailAuthPass {
+					if domain := authResultDomain(entry.props["header.d"], entry.props["header.i"]); domain != "" {
+						dkimDomains = append(dkimDomains, domain)
+					}
+				} else if auth.DKIM != models.EmailAuthPass {
+					auth.DKIM = entry.result
+				}
+			case "dmarc":
+				auth.DMARC = entry.result
+				auth.DMARCPolicy = entry.policy
+				dmarcReported = true
+			}
+		}
+	}
+	if len(dkimDomains) > 0 {
+		auth.DKIM = models.EmailAuthPass
+		auth.DKIMDomain = dkimDomains[0]
+		for _, domain := range dkimDomains {
+			if emailDomainsAligned(domain, fromDomain, false) {
+				auth.DKIMDomain = domain
+				break
+			}
+		}
+	}
+
+	// 网关没有给出 SPF 结果时按连接 IP 自行校验，依次以信封发件人、HELO、From 的域名为准
+	if auth.SPF == models.EmailAuthNone && policy.VerifySPF {
+		if ip := net.ParseIP(strings.TrimSpace(req.ClientIP)); ip != nil {
+			spfDomain := authResultDomain(req.MailFrom, req.Helo)
+			if spfDomain == "" {
+				spfDomain = fromDomain
+			}
+			if spfDomain != "" {
+				check := &spfCheck{ctx: ctx, resolver: s.resolver, ip: ip}
+				auth.SPF = check.evaluate(spfDomain, 0)
+				auth.SPFDomain = spfDomain
+				if auth.Source == "" {
+					auth.Source = "local"
+				}
+			}
+		}
+	}
+
+	// 网关未报告 DMARC 时按发件域发布的记录评估；网关报告失败但没有附带 p= 时补查策略
+	if fromDomain != "" && (auth.SPF != models.EmailAuthNone || auth.DKIM != models.EmailAuthNone) {
+		if !dmarcReported {
+			s.evaluateDMARC(ctx, auth, fromDomain)
+		} else if auth.DMARC == models.EmailAuthFail && auth.DMARCPolicy == "" {
+			if record, err := s.lookupDMARC(ctx, fromDomain); err == nil && record != nil {
+				auth.DMARCPolicy = record.policyFor(fromDomain)
+			}
+		}
+	}
+
+	auth.Verdict = emailAuthVerdict(auth, fromDomain)
+	auth.Action = policy.ActionFor(auth, fromDomain)
+	return auth, nil
+}
+
+// evaluateDMARC 按发件域 DMARC 记录评估：通过的 SPF 或 DKIM 任一与 From 域名对齐即通过
+func (s *EmailAuthService) evaluateDMARC(ctx context.Context, auth *models.EmailAuthentication, fromDomain string) {
+	record, err := s.lookupDMARC(ctx, fromDomain)
+	if err != nil {
+		auth.DMARC = models.EmailAuthTempError
+		return
+	}
+	if record == nil {
+		auth.DMARC = models.EmailAuthNone
+		return
+	}
+	auth.DMARCPolicy = record.policyFor(fromDomain)
+
+	spfAligned := auth.SPF == models.EmailAuthPass && emailDomainsAligned(auth.SPFDomain, fromDomain, record.strictSPF)
+	dkimAligned := auth.DKIM == models.EmailAuthPass && emailDomainsAligned(auth.DKIMDomain, fromDomain, record.strictDKIM)
+	if spfAligned || dkimAligned {
+		auth.DMARC = models.EmailAuthPass
+	} else {
+		auth.DMARC = models.EmailAuthFail
+	}
+}
+
+// dmarcRecord 解析后的 DMARC 记录
+type dmarcRecord struct {
+	policy          string
+	subdomainPolicy string
+	strictSPF       bool
+	strictDKIM      bool
+	domain          string
+}
+
+// policyFor 返回记录对发件域适用的策略，组织域记录对子域名优先使用 sp=
+func (r *dmarcRecord) policyFor(fromDomain string) string {
+	if fromDomain != r.domain && r.subdomainPolicy != "" {
+		return r.subdomainPolicy
+	}
+	return r.policy
+}
+
+// lookupDMARC 查询发件域的 DMARC 记录，没有时回退到组织域；都没有时返回 nil
+func (s *EmailAuthService) lookupDMARC(ctx context.Context, fromDomain string) (*dmarcRecord, error) {
+	domains := []string{fromDomain}
+	if org := organizationalDomain(fromDomain); org != fromDomain {
+		domains = append(domains, org)
+	}
+	for _, domain := range domains {
+		txts, err := s.resolver.LookupTXT(ctx, "_dmarc."+domain)
+		if err != nil {
+			if isDNSNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		for _, txt := range txts {
+			if record := parseDMARCRecord(txt); record != nil {
+				record.domain = domain
+				return record, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// parseDMARCRecord 解析 DMARC TXT 记录，不是 v=DMARC1 记录或缺少 p= 时返回 nil
+func parseDMARCRecord(txt string) *dmarcRecord {
+	tags := map[string]string{}
+	for i, part := range strings.Split(txt, ";") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		value = strings.TrimSpace(value)
+		if i == 0 && (name != "v" || !strings.EqualFold(value, "DMARC1")) {
+			return nil
+		}
+		tags[name] = strings.ToLower(value)
+	}
+	record := &dmarcRecord{
+		policy:          tags["p"],
+		subdomainPolicy: tags["sp"],
+		strictSPF:       tags["aspf"] == "s",
+		strictDKIM:      tags["adkim"] == "s",
+	}
+	switch record.policy {
+	case "none", "quarantine", "reject":
+	default:
+		return nil
+	}
+	return record
+}
+
+// emailAuthVerdict 汇总整体结论：DMARC 有结果时以其为准，否则看 SPF/DKIM 是否与发件域对齐通过
+func emailAuthVerdict(auth *models.EmailAuthentication, fromDomain string) models.EmailAuthVerdict {
+	switch {
+	case auth.DMARC == models.EmailAuthPass:
+		return models.EmailAuthVerdictPass
+	case auth.DMARC == models.EmailAuthFail:
+		return models.EmailAuthVerdictFail
+	case auth.DKIM == models.EmailAuthPass && emailDomainsAligned(auth.DKIMDomain, fromDomain, false),
+		auth.SPF == models.EmailAuthPass && emailDomainsAligned(auth.SPFDomain, fromDomain, false):
+		return models.EmailAuthVerdictPass
+	case auth.SPF == models.EmailAuthFail && auth.DKIM != models.EmailAuthPass:
+		return models.EmailAuthVerdictFail
+	default:
+		return models.EmailAuthVerdictNone
+	}
+}
+
+// SenderTrust 按近期收件的认证结论汇总发件人可信度，地址不区分大小写，没有记录的地址为 unverified
+func (s *EmailAuthService) SenderTrust(ctx context.Context, addresses []string) (map[string]*models.EmailSenderTrust, error) {
+	result := make(map[string]*models.EmailSenderTrust, len(addresses))
+	var normalized []string
+	for _, address := range addresses {
+		address = strings.ToLower(strings.TrimSpace(address))
+		if address == "" || result[address] != nil {
+			continue
+		}
+		normalized = append(normalized, address)
+		result[address] = &models.EmailSenderTrust{Address: address, Level: models.EmailSenderUnverified, WindowInDays: emailSenderTrustDays}
+	}
+	if len(normalized) == 0 {
+		return result, nil
+	}
+
+	var emails []models.InboundEmail
+	since := time.Now().AddDate(0, 0, -emailSenderTrustDays)
+	if err := s.db.WithContext(ctx).Select("from_address", "auth_verdict", "received_at").
+		Where("from_address IN ? AND received_at >= ? AND auth_verdict IN ?", normalized, since,
+			[]models.EmailAuthVerdict{models.EmailAuthVerdictPass, models.EmailAuthVerdictFail}).
+		Order("received_at ASC, id ASC").Find(&emails).Error; err != nil {
+		return nil, fmt.Errorf("failed to get sender authentication history: %w", err)
+	}
+	for i := range emails {
+		email := &emails[i]
+		trust := result[email.FromAddress]
+		if trust == nil {
+			continue
+		}
+		if email.Auth.Verdict == models.EmailAuthVerdictPass {
+			trust.PassCount++
+		} else {
+			trust.FailCount++
+		}
+		trust.LastVerdict = email.Auth.Verdict
+		trust.LastSeenAt = &email.ReceivedAt
+	}
+	for _, trust := range result {
+		switch {
+		case trust.PassCount+trust.FailCount == 0:
+			trust.Level = models.EmailSenderUnverified
+		case trust.LastVerdict == models.EmailAuthVerdictFail || trust.FailCount > trust.PassCount:
+			trust.Level = models.EmailSenderSuspicious
+		default:
+			trust.Level = models.EmailSenderTrusted
+		}
+	}
+	return result, nil
+}
+
+// authResultEntry Authentication-Results 头中的一项方法结果
+type authResultEntry struct {
+	method string
+	result models.EmailAuthResult
+	props  map[string]string
+	policy string // dmarc 结果注释或属性中的发件域策略
+}
+
+// parseAuthenticationResults 解析 Authentication-Results 头（RFC 8601），返回 authserv-id 与各方法结果
+func parseAuthenticationResults(header string) (string, []authResultEntry) {
+	header = strings.Join(strings.Fields(header), " ")
+	parts := strings.Split(header, ";")
+	servFields := strings.Fields(authResultsCommentPattern.ReplaceAllString(parts[0], " "))
+	if len(servFields) == 0 {
+		return "", nil
+	}
+
+	var entries []authResultEntry
+	for _, part := range parts[1:] {
+		var policy string
+		for _, comment := range authResultsCommentPattern.FindAllStringSubmatch(part, -1) {
+			for _, field := range strings.Fields(comment[1]) {
+				if name, value, ok := strings.Cut(field, "="); ok && strings.EqualFold(name, "p") {
+					policy = strings.ToLower(value)
+				}
+			}
+		}
+		fields := strings.Fields(authResultsCommentPattern.ReplaceAllString(part, " "))
+		if len(fields) == 0 {
+			continue
+		}
+		method, value, ok := strings.Cut(fields[0], "=")
+		if !ok {
+			continue
+		}
+		entry := authResultEntry{
+			method: strings.ToLower(method),
+			result: models.ParseEmailAuthResult(value),
+			props:  map[string]string{},
+			policy: policy,
+		}
+		for _, field := range fields[1:] {
+			if name, value, ok := strings.Cut(field, "="); ok {
+				entry.props[strings.ToLower(name)] = strings.Trim(value, `"`)
+			}
+		}
+		if published := strings.ToLower(entry.props["policy.published-domain-policy"]); published != "" {
+			entry.policy = published
+		}
+		switch entry.policy {
+		case "none", "quarantine", "reject":
+		default:
+			entry.policy = ""
+		}
+		entries = append(entries, entry)
+	}
+	return strings.ToLower(servFields[0]), entries
+}
+
+// authResultDomain 从邮箱地址或域名中取出小写域名，依次尝试各候选值
+func authResultDomain(values ...string) string {
+	for _, value := range values {
+		value = strings.Trim(strings.TrimSpace(value), "<>")
+		if value == "" {
+			continue
+		}
+		if strings.Contains(value, "@") {
+			value = models.EmailDomain(value)
+		}
+		if value = strings.Trim(strings.ToLower(value), "."); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// emailDomainsAligned 判断认证域名与 From 域名是否对齐：严格模式要求完全相同，宽松模式只要求组织域相同
+func emailDomainsAligned(authDomain, fromDomain string, strict bool) bool {
+	if authDomain == "" || fromDomain == "" {
+		return false
+	}
+	if strict {
+		return authDomain == fromDomain
+	}
+	return organizationalDomain(authDomain) == organizationalDomain(fromDomain)
+}
+
+// organizationalDomain 近似计算组织域：取最后两级，常见的二级国家后缀（如 co.uk、com.cn）取最后三级
+func organizationalDomain(domain string) string {
+	labels := strings.Split(strings.ToLower(strings.Trim(domain, ".")), ".")
+	if len(labels) <= 2 {
+		return strings.Join(labels, ".")
+	}
+	keep := 2
+	switch labels[len(labels)-2] {
+	case "co", "com", "net", "org", "gov", "edu", "ac":
+		if len(labels[len(labels)-1]) == 2 {
+			keep = 3
+		}
+	}
+	return strings.Join(labels[len(labels)-keep:], ".")
+}
+
+// isDNSNotFound 判断 DNS 错误是否为记录不存在
+func isDNSNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
+
+// spfCheck 单次 SPF 评估（RFC 7208），记录已用的 DNS 查询次数。
+// 不支持宏展开，含宏的机制视为不匹配；ptr 机制已不建议使用，同样视为不匹配
+type spfCheck struct {
+	ctx      context.Context
+	resolver emailAuthResolver
+	ip       net.IP
+	lookups  int
+}
+
+// evaluate 评估域名的 SPF 记录
+func (c *spfCheck) evaluate(domain string, depth int) models.EmailAuthResult {
+	if depth > spfMaxLookups {
+		return models.EmailAuthPermError
+	}
+	txts, err := c.resolver.LookupTXT(c.ctx, domain)
+	if err != nil {
+		if isDNSNotFound(err) {
+			return models.EmailAuthNone
+		}
+		return models.EmailAuthTempError
+	}
+	var record string
+	for _, txt := range txts {
+		if lower := strings.ToLower(txt); lower == "v=spf1" || strings.HasPrefix(lower, "v=spf1 ") {
+			if record != "" {
+				return models.EmailAuthPermError
+			}
+			record = txt
+		}
+	}
+	if record == "" {
+		return models.EmailAuthNone
+	}
+
+	var redirect string
+	for _, term := range strings.Fields(record)[1:] {
+		if name, value, ok := strings.Cut(term, "="); ok && !strings.ContainsAny(name, ":/") {
+			if strings.EqualFold(name, "redirect") {
+				redirect = value
+			}
+			continue
+		}
+
+		qualifier := models.EmailAuthPass
+		switch term[0] {
+		case '+':
+			term = term[1:]
+		case '-':
+			qualifier, term = models.EmailAuthFail, term[1:]
+		case '~':
+			qualifier, term = models.EmailAuthSoftFail, term[1:]
+		case '?':
+			qualifier, term = models.EmailAuthNeutral, term[1:]
+		}
+		matched, result := c.match(term, domain, depth)
+		if result != "" {
+			return result
+		}
+		if matched {
+			return qualifier
+		}
+	}
+
+	if redirect != "" {
+		if !c.countLookup() {
+			return models.EmailAuthPermError
+		}
+		result := c.evaluate(strings.ToLower(redirect), depth+1)
+		if result == models.EmailAuthNone {
+			return models.EmailAuthPermError
+		}
+		return result
+	}
+	return models.EmailAuthNeutral
+}
+
+// match 判断客户端 IP 是否命中机制，出错时返回 temperror/permerror
+func (c *spfCheck) match(term, domain string, depth int) (bool, models.EmailAuthResult) {
+	name, arg := term, ""
+	if i := strings.IndexAny(term, ":/"); i >= 0 {
+		name, arg = term[:i], term[i:]
+		arg = strings.TrimPrefix(arg, ":")
+	}
+	name = strings.ToLower(name)
+	if strings.Contains(arg, "%") {
+		return false, ""
+	}
+
+	switch name {
+	case "all":
+		return true, ""
+	case "ip4", "ip6":
+		if !strings.Contains(arg, "/") {
+			if name == "ip4" {
+				arg += "/32"
+			} else {
+				arg += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(arg)
+		if err != nil {
+			return false, models.EmailAuthPermError
+		}
+		return network.Contains(c.ip), ""
+	case "a", "mx":
+		host, v4Bits, v6Bits, ok := parseSPFDualCIDR(arg, domain)
+		if !ok {
+			return false, models.EmailAuthPermError
+		}
+		if !c.countLookup() {
+			return false, models.EmailAuthPermError
+		}
+		hosts := []string{host}
+		if name == "mx" {
+			mxs, err := c.resolver.LookupMX(c.ctx, host)
+			if err != nil && !isDNSNotFound(err) {
+				return false, models.EmailAuthTempError
+			}
+			hosts = hosts[:0]
+			for i, mx := range mxs {
+				if i >= spfMaxLookups {
+					return false, models.EmailAuthPermError
+				}
+				hosts = append(hosts, strings.TrimSuffix(mx.Host, "."))
+			}
+		}
+		for _, host := range hosts {
+			addrs, err := c.resolver.LookupIPAddr(c.ctx, host)
+			if err != nil {
+				if isDNSNotFound(err) {
+					continue
+				}
+				return false, models.EmailAuthTempError
+			}
+			for _, addr := range addrs {
+				ip, bits, size := addr.IP, v6Bits, 128
+				if v4 := ip.To4(); v4 != nil {
+					ip, bits, size = v4, v4Bits, 32
+				}
+				network := &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, size)}
+				if network.Contains(c.ip) {
+					return true, ""
+				}
+			}
+		}
+		return false, ""
+	case "include":
+		if arg == "" {
+			return false, models.EmailAuthPermError
+		}
+		if !c.countLookup() {
+			return false, models.EmailAuthPermError
+		}
+		switch result := c.evaluate(strings.ToLower(arg), depth+1); result {
+		case models.EmailAuthPass:
+			return true, ""
+		case models.EmailAuthFail, models.EmailAuthSoftFail, models.EmailAuthNeutral:
+			return false, ""
+		case models.EmailAuthTempError:
+			return false, models.EmailAuthTempError
+		default:
+			return false, models.EmailAuthPermError
+		}
+	case "exists":
+		if arg == "" {
+			return false, models.EmailAuthPermError
+		}
+		if !c.countLookup() {
+			return false, models.EmailAuthPermError
+		}
+		addrs, err := c.resolver.LookupIPAddr(c.ctx, arg)
+		if err != nil && !isDNSNotFound(err) {
+			return false, models.EmailAuthTempError
+		}
+		return len(addrs) > 0, ""
+	case "ptr":
+		if !c.countLookup() {
+			return false, models.EmailAuthPermError
+		}
+		return false, ""
+	default:
+		return false, models.EmailAuthPermError
+	}
+}
+
+// countLookup 计入一次 DNS 查询，超过上限时返回 false
+func (c *spfCheck) countLookup() bool {
+	c.lookups++
+	return c.lookups <= spfMaxLookups
+}
+
+// parseSPFDualCIDR 解析 a/mx 机制的参数 "domain/24//64"，域名缺省为当前域名
+func parseSPFDualCIDR(arg, domain string) (string, int, int, bool) {
+	host := arg
+	v4Bits, v6Bits := 32, 128
+	if i := strings.Index(arg, "/"); i >= 0 {
+		host = arg[:i]
+		cidr := arg[i:]
+		if j := strings.Index(cidr, "//"); j >= 0 {
+			if _, err := fmt.Sscanf(cidr[j+2:], "%d", &v6Bits); err != nil || v6Bits < 0 || v6Bits > 128 {
+				return "", 0, 0, false
+			}
+			cidr = cidr[:j]
+		}
+		if cidr != "" {
+			if _, err := fmt.Sscanf(cidr[1:], "%d", &v4Bits); err != nil || v4Bits < 0 || v4Bits > 32 {
+				return "", 0, 0, false
+			}
+		}
+	}
+	if host == "" {
+		host = domain
+	}
+	return strings.ToLower(host), v4Bits, v6Bits, true
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// fakeAuthResolver 按固定表应答的 DNS，未登记的名称返回记录不存在
+type fakeAuthResolver struct {
+	txt map[string][]string
+	ips map[string][]string
+}
+
+func (r *fakeAuthResolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	if records, ok := r.txt[name]; ok {
+		return records, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: name, IsNotFound: true}
+}
+
+func (r *fakeAuthResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	var addrs []net.IPAddr
+	for _, ip := range r.ips[host] {
+		addrs = append(addrs, net.IPAddr{IP: net.ParseIP(ip)})
+	}
+	if len(addrs) == 0 {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+	return addrs, nil
+}
+
+func (r *fakeAuthResolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	return []*net.MX{{Host: "mail." + name + ".", Pref: 10}}, nil
+}
+
+func TestEmailAuth_VerifiesAndEnforcesPolicy(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:email_auth_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Team{}, &models.Ticket{}, &models.TicketComment{}, &models.TicketHistory{},
+		&models.SystemConfig{}, &models.InboundEmail{}, &models.InboxBlocklistEntry{}, &models.AssignmentDelegation{}, &models.IntakeQuarantineItem{},
+		&models.EmailThreadMessage{}, &models.EmailLog{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+	ctx := context.Background()
+
+	agent := models.User{Username: "auth-agent", Email: "auth-agent@example.com", PasswordHash: "x", Role: models.RoleAgent}
+	db.Create(&agent)
+
+	svc := NewInboxService(db)
+	svc.authService.resolver = &fakeAuthResolver{
+		txt: map[string][]string{
+			"good.com":           {"v=spf1 include:_spf.good.com mx -all"},
+			"_spf.good.com":      {"v=spf1 ip4:203.0.113.0/24 ~all"},
+			"_dmarc.good.com":    {"v=DMARC1; p=reject; rua=mailto:dmarc@good.com"},
+			"_dmarc.partner.com": {"v=DMARC1; p=quarantine"},
+		},
+		ips: map[string][]string{"mail.good.com": {"192.0.2.25"}},
+	}
+	policy := models.GetDefaultEmailAuthPolicy()
+	policy.TrustedAuthServIDs = []string{"MX.Gateway.Local"}
+	policy.ProtectedDomains = []string{"ourco.com"}
+	if err := svc.authService.SetPolicy(ctx, policy, agent.ID); err != nil {
+		t.Fatalf("set policy failed: %v", err)
+	}
+	if err := svc.authService.SetPolicy(ctx, &models.EmailAuthPolicy{FailAction: "drop"}, agent.ID); err == nil {
+		t.Fatal("expected invalid fail_action to be rejected")
+	}
+
+	// 本系统按客户端 IP 校验 SPF（include 与 mx 机制），SPF 对齐即 DMARC 通过
+	genuine, err := svc.Ingest(ctx, &models.InboundEmailCreateRequest{MessageID: "<g1@good.com>", From: "alice@good.com", Subject: "VPN down", Body: "help",
+		ClientIP: "203.0.113.7", MailFrom: "bounce@good.com"})
+	if err != nil {
+		t.Fatalf("ingest failed: %v", err)
+	}
+	if genuine.Status != models.InboundEmailStatusPending || genuine.Auth.SPF != models.EmailAuthPass || genuine.Auth.DMARC != models.EmailAuthPass ||
+		genuine.Auth.Verdict != models.EmailAuthVerdictPass || genuine.Auth.Source != "local" {
+		t.Fatalf("unexpected genuine email %+v", genuine.Auth)
+	}
+	viaMX, _ := svc.Ingest(ctx, &models.InboundEmailCreateRequest{MessageID: "<g2@good.com>", From: "alice@good.com", Subject: "again", ClientIP: "192.0.2.25"})
+	if viaMX.Auth.SPF != models.EmailAuthPass {
+		t.Fatalf("expected mx mechanism to pass, got %+v", viaMX.Auth)
+	}
+
+	// 冒用发件域：SPF 失败、DMARC 失败，按发件域 p=reject 拒收
+	spoofed, err := svc.Ingest(ctx, &models.InboundEmailCreateRequest{MessageID: "<s1@evil.test>", From: "alice@good.com", Subject: "wire transfer",
+		ClientIP: "198.51.100.9"})
+	if err != nil {
+		t.Fatalf("ingest failed: %v", err)
+	}
+	if spoofed.Status != models.InboundEmailStatusRejected || spoofed.Auth.SPF != models.EmailAuthFail || spoofed.Auth.DMARC != models.EmailAuthFail ||
+		spoofed.Auth.DMARCPolicy != "reject" || spoofed.Auth.Action != models.EmailAuthActionReject {
+		t.Fatalf("unexpected spoofed email %s %+v", spoofed.Status, spoofed.Auth)
+	}
+
+	// 未信任网关的 Authentication-Results 被忽略；可信网关报告 DMARC 失败时按 p=quarantine 进入隔离区
+	forged, _ := svc.Ingest(ctx, &models.InboundEmailCreateRequest{MessageID: "<f1@partner.com>", From: "bob@partner.com", Subject: "invoice",
+		AuthenticationResults: []string{"attacker.example; spf=pass smtp.mailfrom=bob@partner.com; dmarc=pass header.from=partner.com"}})
+	if forged.Auth.Verdict != models.EmailAuthVerdictNone || forged.Status != models.InboundEmailStatusPending {
+		t.Fatalf("expected untrusted header to be ignored, got %s %+v", forged.Status, forged.Auth)
+	}
+	quarantined, _ := svc.Ingest(ctx, &models.InboundEmailCreateRequest{MessageID: "<q1@partner.com>", From: "bob@partner.com", Subject: "invoice",
+		AuthenticationResults: []string{"mx.gateway.local;\r\n spf=softfail smtp.mailfrom=bounce@bulk.test;\r\n dkim=pass header.d=bulk.test; dmarc=fail header.from=partner.com"}})
+	if quarantined.Status != models.InboundEmailStatusQuarantined || quarantined.Auth.DKIMDomain != "bulk.test" || quarantined.Auth.DMARCPolicy != "quarantine" ||
+		quarantined.Auth.Source != "mx.gateway.local" {
+		t.Fatalf("unexpected quarantined email %s %+v", quarantined.Status, quarantined.Auth)
+	}
+	var item models.IntakeQuarantineItem
+	if err := db.Where("inbound_email_id = ?", quarantined.ID).First(&item).Error; err != nil || !strings.Contains(item.Reasons, "dmarc=fail") {
+		t.Fatalf("expected quarantine item with auth reason, got %+v, %v", item, err)
+	}
+
+	// 声称来自受保护域名但没有认证结果的邮件一律拒收
+	internal, _ := svc.Ingest(ctx, &models.InboundEmailCreateRequest{MessageID: "<i1@ourco.com>", From: "ceo@mail.ourco.com", Subject: "urgent"})
+	if internal.Status != models.InboundEmailStatusRejected {
+		t.Fatalf("expected protected domain email to be rejected, got %s", internal.Status)
+	}
+
+	// 转为工单及后续回复时，历史记录和评论元数据带有认证结果
+	ticket, err := svc.ConvertEmail(ctx, genuine.ID, &models.InboxConvertRequest{}, agent.ID)
+	if err != nil {
+		t.Fatalf("convert failed: %v", err)
+	}
+	var history models.TicketHistory
+	db.Where("ticket_id = ? AND field_name = ?", ticket.ID, "inbound_email_id").First(&history)
+	if !strings.Contains(history.Description, "spf=pass dkim=none dmarc=pass") {
+		t.Fatalf("expected history to carry auth summary, got %q", history.Description)
+	}
+	reply, _ := svc.Ingest(ctx, &models.InboundEmailCreateRequest{MessageID: "<g3@good.com>", InReplyTo: "<g1@good.com>", From: "alice@good.com",
+		Subject: "Re: VPN down", Body: "still broken", ClientIP: "203.0.113.8"})
+	if reply.Status != models.InboundEmailStatusMerged {
+		t.Fatalf("expected reply to be merged, got %s", reply.Status)
+	}
+	var comment models.TicketComment
+	db.Where("ticket_id = ?", ticket.ID).Order("id DESC").First(&comment)
+	if !strings.Contains(comment.Metadata, `"email_authentication"`) || !strings.Contains(comment.Metadata, `"verdict":"pass"`) {
+		t.Fatalf("expected comment metadata to carry auth result, got %q", comment.Metadata)
+	}
+
+	// 发件人可信度：最近一封通过且通过多于失败为 trusted，最近一封失败为 suspicious，没有认证结果为 unverified
+	trust, err := svc.authService.SenderTrust(ctx, []string{"Alice@good.com", "bob@partner.com", "nobody@else.com"})
+	if err != nil {
+		t.Fatalf("sender trust failed: %v", err)
+	}
+	if trust["alice@good.com"].Level != models.EmailSenderTrusted || trust["alice@good.com"].PassCount != 3 || trust["alice@good.com"].FailCount != 1 {
+		t.Fatalf("unexpected trust for genuine sender %+v", trust["alice@good.com"])
+	}
+	if trust["bob@partner.com"].Level != models.EmailSenderSuspicious || trust["nobody@else.com"].Level != models.EmailSenderUnverified {
+		t.Fatalf("unexpected trust %+v %+v", trust["bob@partner.com"], trust["nobody@else.com"])
+	}
+	if _, err := svc.Ingest(ctx, &models.InboundEmailCreateRequest{MessageID: "<g4@good.com>", From: "alice@good.com", Subject: "thanks", ClientIP: "203.0.113.9"}); err != nil {
+		t.Fatalf("ingest failed: %v", err)
+	}
+	items, _, err := svc.List(ctx, InboxListOptions{Kind: "email"})
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	for _, item := range items {
+		if item.FromAddress == "alice@good.com" && (item.SenderTrust != models.EmailSenderTrusted || item.Authentication == nil) {
+			t.Fatalf("unexpected inbox item %+v", item)
+		}
+	}
+	thread, err := svc.GetTicketEmailThread(ctx, ticket.ID)
+	if err != nil || len(thread.Senders) != 1 || thread.Senders[0].Level != models.EmailSenderTrusted {
+		t.Fatalf("unexpected thread senders %+v, %v", thread, err)
+	}
+}
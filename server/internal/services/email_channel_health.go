@@ -0,0 +1,335 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/textproto"
+	"strings"
+	"sync"
+	"time"
+
+	"gongdan-system/internal/models"
+)
+
+const (
+	// deliveryStatusQueuedSMTP 邮件通道不可用，邮件排队等待通道恢复后重发
+	deliveryStatusQueuedSMTP = "queued_smtp"
+	// deliveryStatusSMTPUnavailable 邮件通道长时间不可用，放弃发送
+	deliveryStatusSMTPUnavailable = "failed_smtp_unavailable"
+
+	// emailChannelFailureThreshold 连续多少次通道错误后判定邮件通道不可用
+	emailChannelFailureThreshold = 3
+	// emailChannelProbeInterval 通道不可用期间，每隔该时间放行一封邮件探测 SMTP 是否恢复
+	emailChannelProbeInterval = time.Minute
+	// emailQueueRetryInterval 排队邮件的重发间隔
+	emailQueueRetryInterval = time.Minute
+	// emailQueueMaxAge 排队邮件超过该时间仍未发出则放弃
+	emailQueueMaxAge = 24 * time.Hour
+	// emailQueueBatchSize 每次重发的排队邮件数量上限
+	emailQueueBatchSize = 100
+	// emailFailoverDedupWindow 接收者在邮件创建前后该时间内已有同类应用内通知时不再补发
+	emailFailoverDedupWindow = 10 * time.Minute
+)
+
+// InAppNotificationHook 邮件转为应用内通知或发送邮件通道告警后调用，由 WebSocket 模块注册用于实时推送
+var InAppNotificationHook func(ctx context.Context, notification *models.Notification)
+
+// EmailChannelStatus 邮件通道健康状态
+type EmailChannelStatus struct {
+	Healthy             bool       `json:"healthy"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	UnhealthySince      *time.Time `json:"unhealthy_since,omitempty"`
+	LastFailureAt       *time.Time `json:"last_failure_at,omitempty"`
+	LastSuccessAt       *time.Time `json:"last_success_at,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+	Alerted             bool       `json:"alerted"` // 已向管理员发送不可用告警
+	QueuedEmails        int64      `json:"queued_emails"`
+}
+
+// EmailChannelHealth 跟踪 SMTP 发送结果。连续通道错误达到阈值后判定不可用，
+// 不可用期间邮件排队，只定期放行一封用于探测，发送成功即恢复
+type EmailChannelHealth struct {
+	mu  sync.Mutex
+	now func() time.Time
+
+	failures       int
+	firstFailureAt *time.Time
+	unhealthySince *time.Time
+	lastFailureAt  *time.Time
+	lastSuccessAt  *time.Time
+	lastProbeAt    time.Time
+	lastError      string
+	alerted        bool
+}
+
+// NewEmailChannelHealth 创建邮件通道健康状态
+func NewEmailChannelHealth() *EmailChannelHealth {
+	return &EmailChannelHealth{now: time.Now}
+}
+
+// RecordSuccess 记录一次发送成功，通道恢复可用
+func (h *EmailChannelHealth) RecordSuccess() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := h.now()
+	h.failures = 0
+	h.firstFailureAt = nil
+	h.unhealthySince = nil
+	h.lastSuccessAt = &now
+	h.lastError = ""
+}
+
+// RecordFailure 记录一次通道错误（连接、认证、服务暂不可用等）
+func (h *EmailChannelHealth) RecordFailure(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	now := h.now()
+	h.failures++
+	if h.firstFailureAt == nil {
+		h.firstFailureAt = &now
+	}
+	h.lastFailureAt = &now
+	h.lastError = err.Error()
+	if h.unhealthySince == nil && h.failures >= emailChannelFailureThreshold {
+		since := *h.firstFailureAt
+		h.unhealthySince = &since
+	}
+}
+
+// AllowSend 通道可用时放行；不可用时每个探测间隔只放行一封
+func (h *EmailChannelHealth) AllowSend() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.unhealthySince == nil {
+		return true
+	}
+	now := h.now()
+	if now.Sub(h.lastProbeAt) < emailChannelProbeInterval ||
+		(h.lastFailureAt != nil && now.Sub(*h.lastFailureAt) < emailChannelProbeInterval) {
+		return false
+	}
+	h.lastProbeAt = now
+	return true
+}
+
+// Healthy 邮件通道是否可用
+func (h *EmailChannelHealth) Healthy() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.unhealthySince == nil
+}
+
+// Status 当前健康状态快照，不含排队邮件数
+func (h *EmailChannelHealth) Status() *EmailChannelStatus {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return &EmailChannelStatus{
+		Healthy:             h.unhealthySince == nil,
+		ConsecutiveFailures: h.failures,
+		UnhealthySince:      h.unhealthySince,
+		LastFailureAt:       h.lastFailureAt,
+		LastSuccessAt:       h.lastSuccessAt,
+		LastError:           h.lastError,
+		Alerted:             h.alerted,
+	}
+}
+
+// alertDue 通道不可用持续达到 after 且本次故障尚未告警时返回 true，每次故障只返回一次
+func (h *EmailChannelHealth) alertDue(after time.Duration) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.alerted || h.unhealthySince == nil || h.now().Sub(*h.unhealthySince) < after {
+		return false
+	}
+	h.alerted = true
+	return true
+}
+
+// recoveryDue 已告警的故障恢复后返回 true，只返回一次
+func (h *EmailChannelHealth) recoveryDue() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if !h.alerted || h.unhealthySince != nil {
+		return false
+	}
+	h.alerted = false
+	return true
+}
+
+// isEmailChannelError 发送错误是否属于邮件通道问题。550-553 为收件地址被拒，
+// 只影响单个收件人，其他错误（连接失败、认证失败、4xx 暂时错误等）视为通道不可用
+func isEmailChannelError(err error) bool {
+	var protoErr *textproto.Error
+	if errors.As(err, &protoErr) {
+		return protoErr.Code < 550 || protoErr.Code > 553
+	}
+	return true
+}
+
+// holdForChannel 邮件通道不可用时将邮件排队等待重发，首次排队时按配置补发应用内通知。
+// 排队超过 emailQueueMaxAge 的邮件放弃发送并返回错误
+func (s *EmailNotificationService) holdForChannel(ctx context.Context, notification *models.Notification, cause string) error {
+	now := time.Now()
+	if now.Sub(notification.CreatedAt) > emailQueueMaxAge {
+		notification.DeliveryStatus = deliveryStatusSMTPUnavailable
+		notification.ErrorMessage = fmt.Sprintf("邮件通道超过 %s 不可用，放弃发送: %s", emailQueueMaxAge, cause)
+		s.db.Save(notification)
+		return errors.New(notification.ErrorMessage)
+	}
+
+	firstHold := notification.DeliveryStatus != deliveryStatusQueuedSMTP
+	nextRetry := now.Add(emailQueueRetryInterval)
+	notification.DeliveryStatus = deliveryStatusQueuedSMTP
+	notification.ErrorMessage = cause
+	notification.NextRetryAt = &nextRetry
+	s.db.Save(notification)
+
+	if firstHold {
+		s.elevateToInApp(ctx, notification)
+	}
+	return nil
+}
+
+// elevateToInApp 邮件无法送出时为接收者补发一条应用内通知（同时通过 WebSocket 推送），
+// 接收者已有同类型、同工单的应用内通知时不重复发送
+func (s *EmailNotificationService) elevateToInApp(ctx context.Context, notification *models.Notification) {
+	if s.notificationService == nil || !s.failoverEnabledFor(notification.Type) {
+		return
+	}
+
+	query := s.db.WithContext(ctx).Model(&models.Notification{}).
+		Where("recipient_id = ? AND type = ? AND channel IN ? AND created_at >= ?", notification.RecipientID, notification.Type,
+			[]models.NotificationChannel{models.NotificationChannelInApp, models.NotificationChannelWebSocket},
+			notification.CreatedAt.Add(-emailFailoverDedupWindow))
+	if notification.RelatedTicketID != nil {
+		query = query.Where("related_ticket_id = ?", *notification.RelatedTicketID)
+	}
+	var existing int64
+	if err := query.Count(&existing).Error; err != nil || existing > 0 {
+		return
+	}
+
+	inApp, err := s.notificationService.CreateNotification(ctx, &models.NotificationCreateRequest{
+		Type:            notification.Type,
+		Title:           notification.Title,
+		Content:         notification.Content,
+		Priority:        notification.Priority,
+		Channel:         models.NotificationChannelInApp,
+		RecipientID:     notification.RecipientID,
+		SenderID:        notification.SenderID,
+		RelatedType:     notification.RelatedType,
+		RelatedID:       notification.RelatedID,
+		RelatedTicketID: notification.RelatedTicketID,
+		ActionURL:       notification.ActionURL,
+		Metadata:        map[string]interface{}{"email_fallback_for": notification.ID},
+	})
+	if err != nil {
+		log.Printf("邮件转应用内通知失败 (notification: %d): %v", notification.ID, err)
+		return
+	}
+	if InAppNotificationHook != nil && inApp.ID != 0 {
+		InAppNotificationHook(ctx, inApp)
+	}
+}
+
+// failoverEnabledFor 邮件通道不可用时该类型的通知是否转为应用内通知，类型列表为空表示全部类型
+func (s *EmailNotificationService) failoverEnabledFor(notificationType models.NotificationType) bool {
+	if enabled, err := s.configService.GetConfigBool(KeyNotifyEmailFailoverInApp); err == nil && !enabled {
+		return false
+	}
+	types := strings.TrimSpace(s.configService.GetConfigWithDefault(KeyNotifyEmailFailoverTypes, ""))
+	if types == "" {
+		return true
+	}
+	for _, item := range strings.Split(types, ",") {
+		if models.NotificationType(strings.TrimSpace(item)) == notificationType {
+			return true
+		}
+	}
+	return false
+}
+
+// ChannelStatus 邮件通道健康状态及排队等待重发的邮件数
+func (s *EmailNotificationService) ChannelStatus(ctx context.Context) (*EmailChannelStatus, error) {
+	status := s.health.Status()
+	if err := s.db.WithContext(ctx).Model(&models.Notification{}).
+		Where("channel = ? AND is_sent = ? AND delivery_status = ?", models.NotificationChannelEmail, false, deliveryStatusQueuedSMTP).
+		Count(&status.QueuedEmails).Error; err != nil {
+		return nil, fmt.Errorf("统计排队邮件失败: %w", err)
+	}
+	return status, nil
+}
+
+// MonitorChannel 重发到期的排队邮件；通道不可用持续达到告警阈值时通知管理员，恢复后再通知一次
+func (s *EmailNotificationService) MonitorChannel(ctx context.Context) error {
+	var queued []*models.Notification
+	if err := s.db.WithContext(ctx).Preload("Recipient").
+		Where("channel = ? AND is_sent = ? AND delivery_status = ? AND next_retry_at <= ?",
+			models.NotificationChannelEmail, false, deliveryStatusQueuedSMTP, time.Now()).
+		Order("created_at ASC").Limit(emailQueueBatchSize).
+		Find(&queued).Error; err != nil {
+		return fmt.Errorf("获取排队邮件失败: %w", err)
+	}
+	for _, notification := range queued {
+		if err := s.deliver(ctx, notification); err != nil {
+			log.Printf("重发排队邮件失败 (ID: %d): %v", notification.ID, err)
+		}
+		// 探测失败时其余邮件继续排队，等待下次探测
+		if !s.health.Healthy() {
+			break
+		}
+	}
+
+	alertMinutes, err := s.configService.GetConfigInt(KeyNotifyEmailAlertMinutes)
+	if err != nil || alertMinutes <= 0 {
+		alertMinutes = 10
+	}
+	if s.health.alertDue(time.Duration(alertMinutes) * time.Minute) {
+		status, err := s.ChannelStatus(ctx)
+		if err != nil {
+			return err
+		}
+		s.notifyAdmins(ctx, "邮件通道不可用",
+			fmt.Sprintf("SMTP 自 %s 起无法发送邮件，%d 封邮件正在排队等待重发，受影响的通知已转为应用内通知。最近错误：%s",
+				status.UnhealthySince.Format("2006-01-02 15:04:05"), status.QueuedEmails, status.LastError),
+			models.NotificationPriorityUrgent)
+	}
+	if s.health.recoveryDue() {
+		s.notifyAdmins(ctx, "邮件通道已恢复", "SMTP 已恢复，排队的邮件将陆续重发", models.NotificationPriorityNormal)
+	}
+	return nil
+}
+
+// notifyAdmins 向所有启用的管理员发送应用内系统告警
+func (s *EmailNotificationService) notifyAdmins(ctx context.Context, title, content string, priority models.NotificationPriority) {
+	if s.notificationService == nil {
+		return
+	}
+	var adminIDs []uint
+	if err := s.db.WithContext(ctx).Model(&models.User{}).
+		Where("role = ? AND status = ?", models.RoleAdmin, models.UserStatusActive).
+		Pluck("id", &adminIDs).Error; err != nil {
+		log.Printf("获取管理员列表失败: %v", err)
+		return
+	}
+	for _, adminID := range adminIDs {
+		notification, err := s.notificationService.CreateNotification(ctx, &models.NotificationCreateRequest{
+			Type:        models.NotificationTypeSystemAlert,
+			Title:       title,
+			Content:     content,
+			Priority:    priority,
+			Channel:     models.NotificationChannelInApp,
+			RecipientID: adminID,
+			RelatedType: "system",
+		})
+		if err != nil {
+			log.Printf("发送邮件通道告警失败 (admin: %d): %v", adminID, err)
+			continue
+		}
+		if InAppNotificationHook != nil && notification.ID != 0 {
+			InAppNotificationHook(ctx, notification)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/textproto"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+)
+
+func TestEmailChannel_QueuesFailsOverAndRecovers(t *testing.T) {
+	db := newTestDB(t, &models.User{}, &models.Ticket{}, &models.Notification{}, &models.NotificationDelivery{}, &models.NotificationPreference{},
+		&models.SystemConfig{}, &models.EmailAddressStatus{})
+	ctx := context.Background()
+	ns := NewNotificationService(db)
+	emails := NewEmailNotificationService(db, stubEmailConfigService{}, ns).(*EmailNotificationService)
+	clock := time.Now()
+	emails.health.now = func() time.Time { return clock }
+	emails.configService.SetConfig(KeyNotifyEmailAlertMinutes, "5", "int", "", CategoryNotify, "email")
+
+	var pushed []*models.Notification
+	InAppNotificationHook = func(ctx context.Context, notification *models.Notification) { pushed = append(pushed, notification) }
+	t.Cleanup(func() { InAppNotificationHook = nil })
+
+	down, attempts := true, 0
+	emails.send = func(config *models.EmailConfig, to, subject, body string) error {
+		attempts++
+		if down {
+			return errors.New("dial tcp 127.0.0.1:25: connect: connection refused")
+		}
+		return nil
+	}
+
+	admin := models.User{Username: "ec-admin", Email: "ec-admin@example.com", PasswordHash: "x", Role: models.RoleAdmin, Status: models.UserStatusActive}
+	agent := models.User{Username: "ec-health-agent", Email: "ec-health-agent@example.com", PasswordHash: "x", Role: models.RoleAgent, Status: models.UserStatusActive}
+	db.Create(&admin)
+	db.Create(&agent)
+	send := func(title string) *models.Notification {
+		notification := &models.Notification{Type: models.NotificationTypeTicketAssigned, Title: title, Content: title,
+			Channel: models.NotificationChannelEmail, RecipientID: agent.ID}
+		db.Create(notification)
+		emails.SendEmailNotification(ctx, notification)
+		return notification
+	}
+	inApp := func(recipientID uint) []models.Notification {
+		var list []models.Notification
+		db.Where("recipient_id = ? AND channel = ?", recipientID, models.NotificationChannelInApp).Order("id").Find(&list)
+		return list
+	}
+
+	// 通道错误时排队并补发一条应用内通知，同类通知不重复补发
+	first := send("assigned-1")
+	if first.DeliveryStatus != deliveryStatusQueuedSMTP || first.NextRetryAt == nil || first.RetryCount != 0 {
+		t.Fatalf("expected email to be queued, got %+v", first)
+	}
+	send("assigned-2")
+	send("assigned-3")
+	if list := inApp(agent.ID); len(list) != 1 || list[0].Title != "assigned-1" || len(pushed) != 1 {
+		t.Fatalf("expected a single in-app fallback, got %d (pushed %d)", len(list), len(pushed))
+	}
+
+	// 连续失败后判定不可用，后续邮件不再尝试发送
+	if emails.health.Healthy() {
+		t.Fatalf("expected channel to be unhealthy after %d failures", emailChannelFailureThreshold)
+	}
+	send("assigned-4")
+	if attempts != 3 {
+		t.Fatalf("expected held email not to be attempted, attempts=%d", attempts)
+	}
+	status, err := emails.ChannelStatus(ctx)
+	if err != nil || status.Healthy || status.QueuedEmails != 4 || status.LastError == "" {
+		t.Fatalf("unexpected channel status %+v (%v)", status, err)
+	}
+
+	releaseQueue := func() {
+		db.Model(&models.Notification{}).Where("delivery_status = ?", deliveryStatusQueuedSMTP).
+			Update("next_retry_at", time.Now().Add(-time.Second))
+	}
+
+	// 不可用达到告警阈值时通知管理员一次；探测仍失败时其余邮件继续排队
+	clock = clock.Add(6 * time.Minute)
+	releaseQueue()
+	if err := emails.MonitorChannel(ctx); err != nil {
+		t.Fatalf("monitor failed: %v", err)
+	}
+	if attempts != 4 {
+		t.Fatalf("expected a single probe, attempts=%d", attempts)
+	}
+	emails.MonitorChannel(ctx)
+	if alerts := inApp(admin.ID); len(alerts) != 1 || alerts[0].Type != models.NotificationTypeSystemAlert ||
+		alerts[0].Priority != models.NotificationPriorityUrgent {
+		t.Fatalf("expected one urgent alert for admin, got %+v", alerts)
+	}
+
+	// SMTP 恢复后排队邮件全部重发，并通知管理员已恢复
+	down = false
+	clock = clock.Add(2 * time.Minute)
+	releaseQueue()
+	if err := emails.MonitorChannel(ctx); err != nil {
+		t.Fatalf("monitor failed: %v", err)
+	}
+	var sent int64
+	db.Model(&models.Notification{}).Where("channel = ? AND is_sent = ?", models.NotificationChannelEmail, true).Count(&sent)
+	if sent != 4 {
+		t.Fatalf("expected queued emails to be resent, got %d", sent)
+	}
+	if alerts := inApp(admin.ID); len(alerts) != 2 || alerts[1].Title != "邮件通道已恢复" {
+		t.Fatalf("expected recovery notice, got %+v", alerts)
+	}
+	if status, _ := emails.ChannelStatus(ctx); !status.Healthy || status.QueuedEmails != 0 || status.Alerted {
+		t.Fatalf("unexpected recovered status %+v", status)
+	}
+
+	// 排队超过最长时间后放弃
+	down = true
+	stale := &models.Notification{Type: models.NotificationTypeTicketAssigned, Title: "stale", Content: "stale",
+		Channel: models.NotificationChannelEmail, RecipientID: agent.ID, CreatedAt: time.Now().Add(-25 * time.Hour)}
+	db.Create(stale)
+	if err := emails.SendEmailNotification(ctx, stale); err == nil || stale.DeliveryStatus != deliveryStatusSMTPUnavailable {
+		t.Fatalf("expected stale email to be given up, got %q (%v)", stale.DeliveryStatus, err)
+	}
+}
+
+func TestIsEmailChannelError(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{errors.New("connection refused"), true},
+		{&textproto.Error{Code: 421, Msg: "service not available"}, true},
+		{&textproto.Error{Code: 535, Msg: "authentication failed"}, true},
+		{fmt.Errorf("send: %w", &textproto.Error{Code: 550, Msg: "mailbox unavailable"}), false},
+		{&textproto.Error{Code: 553, Msg: "mailbox name not allowed"}, false},
+	}
+	for _, tc := range cases {
+		if got := isEmailChannelError(tc.err); got != tc.want {
+			t.Errorf("isEmailChannelError(%v) = %v, want %v", tc.err, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"gongdan-system/internal/models"
+)
+
+// 支持的退信/投诉回调来源
+const (
+	EmailEventProviderSES      = "ses"
+	EmailEventProviderSendGrid = "sendgrid"
+	EmailEventProviderMailgun  = "mailgun"
+	EmailEventProviderGeneric  = "generic"
+)
+
+// emailEvent 各服务商回调统一后的投递事件
+type emailEvent struct {
+	Email     string
+	Type      models.EmailDeliveryEventType
+	Permanent bool
+	Reason    string
+	MessageID string
+	Provider  string
+}
+
+// sesSubscription SNS 订阅确认，需访问 SubscribeURL 后才会推送通知
+type sesSubscription struct {
+	SubscribeURL string
+}
+
+// parseEmailEvents 解析服务商回调；SES 订阅确认时返回 subscription
+func parseEmailEvents(provider string, body []byte) ([]*emailEvent, *sesSubscription, error) {
+	switch provider {
+	case EmailEventProviderSES:
+		return parseSESEvents(body)
+	case EmailEventProviderSendGrid:
+		events, err := parseSendGridEvents(body)
+		return events, nil, err
+	case EmailEventProviderMailgun:
+		events, err := parseMailgunEvents(body)
+		return events, nil, err
+	case EmailEventProviderGeneric:
+		events, err := parseGenericEmailEvents(body)
+		return events, nil, err
+	default:
+		return nil, nil, ErrUnknownEmailEventProvider
+	}
+}
+
+// parseSESEvents 解析经 SNS 推送的 SES 通知，也接受未经 SNS 包装的通知（如 EventBridge）
+func parseSESEvents(body []byte) ([]*emailEvent, *sesSubscription, error) {
+	var envelope struct {
+		Type         string `json:"Type"`
+		Message      string `json:"Message"`
+		SubscribeURL string `json:"SubscribeURL"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidEmailEventPayload, err)
+	}
+	switch envelope.Type {
+	case "SubscriptionConfirmation":
+		if err := validateSNSURL(envelope.SubscribeURL); err != nil {
+			return nil, nil, err
+		}
+		return nil, &sesSubscription{SubscribeURL: envelope.SubscribeURL}, nil
+	case "Notification":
+		body = []byte(envelope.Message)
+	}
+
+	var notification struct {
+		NotificationType string `json:"notificationType"`
+		EventType        string `json:"eventType"`
+		Mail             struct {
+			MessageID string `json:"messageId"`
+		} `json:"mail"`
+		Bounce struct {
+			BounceType        string `json:"bounceType"`
+			BounceSubType     string `json:"bounceSubType"`
+			BouncedRecipients []struct {
+				EmailAddress   string `json:"emailAddress"`
+				DiagnosticCode string `json:"diagnosticCode"`
+			} `json:"bouncedRecipients"`
+		} `json:"bounce"`
+		Complaint struct {
+			ComplaintFeedbackType string `json:"complaintFeedbackType"`
+			ComplainedRecipients  []struct {
+				EmailAddress string `json:"emailAddress"`
+			} `json:"complainedRecipients"`
+		} `json:"complaint"`
+		Delivery struct {
+			Recipients []string `json:"recipients"`
+		} `json:"delivery"`
+	}
+	if err := json.Unmarshal(body, &notification); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidEmailEventPayload, err)
+	}
+
+	kind := notification.NotificationType
+	if kind == "" {
+		kind = notification.EventType
+	}
+	var events []*emailEvent
+	switch kind {
+	case "Bounce":
+		// Transient 与 Undetermined 按临时退信处理
+		permanent := notification.Bounce.BounceType == "Permanent"
+		for _, r := range notification.Bounce.BouncedRecipients {
+			reason := r.DiagnosticCode
+			if reason == "" {
+				reason = strings.TrimSpace(notification.Bounce.BounceType + " " + notification.Bounce.BounceSubType)
+			}
+			events = append(events, &emailEvent{Email: r.EmailAddress, Type: models.EmailEventBounce, Permanent: permanent,
+				Reason: reason, MessageID: notification.Mail.MessageID})
+		}
+	case "Complaint":
+		for _, r := range notification.Complaint.ComplainedRecipients {
+			events = append(events, &emailEvent{Email: r.EmailAddress, Type: models.EmailEventComplaint,
+				Reason: notification.Complaint.ComplaintFeedbackType, MessageID: notification.Mail.MessageID})
+		}
+	case "Delivery":
+		for _, recipient := range notification.Delivery.Recipients {
+			events = append(events, &emailEvent{Email: recipient, Type: models.EmailEventDelivered, MessageID: notification.Mail.MessageID})
+		}
+	}
+	return events, nil, nil
+}
+
+// validateSNSURL 订阅确认地址必须是 AWS SNS 的 HTTPS 地址
+func validateSNSURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "https" {
+		return fmt.Errorf("%w: invalid SubscribeURL", ErrInvalidEmailEventPayload)
+	}
+	host := u.Hostname()
+	if !strings.HasPrefix(host, "sns.") || !strings.HasSuffix(host, ".amazonaws.com") {
+		return fmt.Errorf("%w: SubscribeURL is not an SNS endpoint", ErrInvalidEmailEventPayload)
+	}
+	return nil
+}
+
+// parseSendGridEvents 解析 SendGrid Event Webhook（事件数组）
+func parseSendGridEvents(body []byte) ([]*emailEvent, error) {
+	var payload []struct {
+		Email     string `json:"email"`
+		Event     string `json:"event"`
+		Type      string `json:"type"`
+		Reason    string `json:"reason"`
+		MessageID string `json:"sg_message_id"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEmailEventPayload, err)
+	}
+	var events []*emailEvent
+	for _, item := range payload {
+		event := &emailEvent{Email: item.Email, Reason: item.Reason, MessageID: item.MessageID}
+		switch item.Event {
+		case "bounce":
+			// type 为 blocked 表示被收件服务器临时拦截
+			event.Type, event.Permanent = models.EmailEventBounce, item.Type != "blocked"
+		case "spamreport":
+			event.Type = models.EmailEventComplaint
+		case "delivered":
+			event.Type = models.EmailEventDelivered
+		default:
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// parseMailgunEvents 解析 Mailgun Webhook（单个 event-data）
+func parseMailgunEvents(body []byte) ([]*emailEvent, error) {
+	var payload struct {
+		EventData struct {
+			Event          string `json:"event"`
+			Severity       string `json:"severity"`
+			Recipient      string `json:"recipient"`
+			Reason         string `json:"reason"`
+			DeliveryStatus struct {
+				Message     string `json:"message"`
+				Description string `json:"description"`
+			} `json:"delivery-status"`
+			Message struct {
+				Headers struct {
+					MessageID string `json:"message-id"`
+				} `json:"headers"`
+			} `json:"message"`
+		} `json:"event-data"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEmailEventPayload, err)
+	}
+	data := payload.EventData
+	event := &emailEvent{Email: data.Recipient, MessageID: data.Message.Headers.MessageID}
+	switch data.Event {
+	case "failed":
+		event.Type, event.Permanent = models.EmailEventBounce, data.Severity == "permanent"
+		event.Reason = data.DeliveryStatus.Description
+		if event.Reason == "" {
+			event.Reason = data.DeliveryStatus.Message
+		}
+		if event.Reason == "" {
+			event.Reason = data.Reason
+		}
+	case "complained":
+		event.Type = models.EmailEventComplaint
+	case "delivered":
+		event.Type = models.EmailEventDelivered
+	default:
+		return nil, nil
+	}
+	return []*emailEvent{event}, nil
+}
+
+// parseGenericEmailEvents 解析通用格式：{"events":[{"email","type","permanent","reason","message_id"}]}
+func parseGenericEmailEvents(body []byte) ([]*emailEvent, error) {
+	var payload struct {
+		Events []struct {
+			Email     string                        `json:"email"`
+			Type      models.EmailDeliveryEventType `json:"type"`
+			Permanent bool                          `json:"permanent"`
+			Reason    string                        `json:"reason"`
+			MessageID string                        `json:"message_id"`
+		} `json:"events"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEmailEventPayload, err)
+	}
+	events := make([]*emailEvent, 0, len(payload.Events))
+	for _, item := range payload.Events {
+		switch item.Type {
+		case models.EmailEventBounce, models.EmailEventComplaint, models.EmailEventDelivered:
+		default:
+			return nil, fmt.Errorf("%w: unsupported event type %q", ErrInvalidEmailEventPayload, item.Type)
+		}
+		events = append(events, &emailEvent{Email: item.Email, Type: item.Type, Permanent: item.Permanent,
+			Reason: item.Reason, MessageID: item.MessageID})
+	}
+	return events, nil
+}
@@ -0,0 +1,281 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"log"
+	"strings"
+	"time"
+
+	"gongdan-system/internal/models"
+)
+
+// deliveryStatusCoalescing 邮件处于合并窗口，等待与同一工单的其他邮件一并发送
+const deliveryStatusCoalescing = "coalescing"
+
+// coalesceWindow 同一接收者同一工单的邮件合并窗口，0 表示不合并
+func (s *EmailNotificationService) coalesceWindow() time.Duration {
+	seconds, err := s.configService.GetConfigInt(KeyNotifyEmailCoalesceSec)
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// shouldCoalesce 只合并首次发送的工单相关邮件，重试按单封发送
+func (s *EmailNotificationService) shouldCoalesce(notification *models.Notification) bool {
+	if notification.RelatedTicketID == nil || notification.RetryCount > 0 {
+		return false
+	}
+	return notification.DeliveryStatus == deliveryStatusCoalescing || s.coalesceWindow() > 0
+}
+
+// enqueueCoalesced 将邮件放入合并窗口。窗口从该组第一封邮件开始计时，结束后统一发送
+func (s *EmailNotificationService) enqueueCoalesced(ctx context.Context, notification *models.Notification) error {
+	recipientID, ticketID := notification.RecipientID, *notification.RelatedTicketID
+
+	// 进程重启后由待发送任务重新拾取：窗口已过则直接发送
+	if notification.DeliveryStatus == deliveryStatusCoalescing {
+		if notification.ScheduledAt == nil || !notification.ScheduledAt.After(time.Now()) {
+			return s.flushCoalesced(ctx, recipientID, ticketID)
+		}
+		s.scheduleCoalesceFlush(recipientID, ticketID, time.Until(*notification.ScheduledAt))
+		return nil
+	}
+
+	window := s.coalesceWindow()
+	flushAt := time.Now().Add(window)
+	notification.DeliveryStatus = deliveryStatusCoalescing
+	notification.ScheduledAt = &flushAt
+	if err := s.db.WithContext(ctx).Model(notification).Updates(map[string]interface{}{
+		"delivery_status": deliveryStatusCoalescing,
+		"scheduled_at":    flushAt,
+	}).Error; err != nil {
+		return fmt.Errorf("更新邮件合并状态失败: %w", err)
+	}
+	trackEmailDelivery(ctx, s.db, notification, nil)
+	s.scheduleCoalesceFlush(recipientID, ticketID, window)
+	return nil
+}
+
+// scheduleCoalesceFlush 为接收者+工单组合启动一次合并发送，窗口内已有计时则复用
+func (s *EmailNotificationService) scheduleCoalesceFlush(recipientID, ticketID uint, delay time.Duration) {
+	key := fmt.Sprintf("%d:%d", recipientID, ticketID)
+
+	s.coalesceMu.Lock()
+	defer s.coalesceMu.Unlock()
+	if _, ok := s.coalescePending[key]; ok {
+		return
+	}
+	s.coalescePending[key] = struct{}{}
+
+	time.AfterFunc(delay, func() {
+		s.coalesceMu.Lock()
+		delete(s.coalescePending, key)
+		s.coalesceMu.Unlock()
+
+		if err := s.flushCoalesced(context.Background(), recipientID, ticketID); err != nil {
+			log.Printf("发送合并邮件失败 (recipient: %d, ticket: %d): %v", recipientID, ticketID, err)
+		}
+	})
+}
+
+// flushCoalesced 发送合并窗口内积累的邮件：只有一封时按原模板发送，多封时发送一封汇总邮件
+func (s *EmailNotificationService) flushCoalesced(ctx context.Context, recipientID, ticketID uint) (err error) {
+	var pending []*models.Notification
+	if err := s.db.WithContext(ctx).
+		Where("recipient_id = ? AND related_ticket_id = ? AND channel = ? AND is_sent = ? AND delivery_status = ?",
+			recipientID, ticketID, models.NotificationChannelEmail, false, deliveryStatusCoalescing).
+		Preload("Recipient").
+		Preload("Sender").
+		Preload("RelatedTicket").
+		Order("created_at ASC, id ASC").
+		Find(&pending).Error; err != nil {
+		return fmt.Errorf("查询待合并邮件失败: %w", err)
+	}
+
+	// 逐条应用用户偏好与评论可见性，被跳过的邮件不进入汇总
+	batch := make([]*models.Notification, 0, len(pending))
+	for _, notification := range pending {
+		if notification.Recipient == nil {
+			continue
+		}
+		emailEnabled, err := s.isEmailEnabledForUser(ctx, recipientID, notification.Type)
+		if err != nil {
+			return fmt.Errorf("检查用户邮件偏好失败: %w", err)
+		}
+		switch {
+		case !emailEnabled:
+			notification.DeliveryStatus = "skipped_user_preference"
+			s.db.Save(notification)
+			trackEmailDelivery(ctx, s.db, notification, nil)
+		case !s.isCommentVisibleToRecipient(ctx, notification):
+			notification.DeliveryStatus = "skipped_internal_comment"
+			s.db.Save(notification)
+			trackEmailDelivery(ctx, s.db, notification, nil)
+		default:
+			batch = append(batch, notification)
+		}
+	}
+
+	switch len(batch) {
+	case 0:
+		return nil
+	case 1:
+		batch[0].DeliveryStatus = ""
+		return s.deliver(ctx, batch[0])
+	}
+
+	// 汇总邮件中的每条通知各自记录投递结果
+	defer func() {
+		for _, notification := range batch {
+			trackEmailDelivery(ctx, s.db, notification, err)
+		}
+	}()
+
+	canSend, err := s.emailConfigService.CanSendEmail(ctx)
+	if err != nil {
+		return fmt.Errorf("检查邮件发送状态失败: %w", err)
+	}
+	if !canSend {
+		return fmt.Errorf("系统邮件功能未启用")
+	}
+	smtpConfig, err := s.emailConfigService.GetSMTPConfig(ctx)
+	if err != nil {
+		return fmt.Errorf("获取SMTP配置失败: %w", err)
+	}
+
+	recipient := batch[0].Recipient
+	if recipient.Email == "" {
+		for _, notification := range batch {
+			notification.DeliveryStatus = "failed_no_email"
+			notification.ErrorMessage = "用户未设置邮箱地址"
+			s.db.Save(notification)
+		}
+		return fmt.Errorf("用户未设置邮箱地址")
+	}
+	if suppressed, err := s.isSuppressed(ctx, recipient.Email); err != nil {
+		return err
+	} else if suppressed {
+		for _, notification := range batch {
+			notification.DeliveryStatus = "skipped_suppressed"
+			notification.ErrorMessage = "邮箱地址已退信或投诉，停止发送"
+			s.db.Save(notification)
+		}
+		return nil
+	}
+
+	subject, body := s.renderCoalescedEmail(batch)
+	subject, body = RenderBrandedEmail(ctx, s.branding, subject, body)
+	if !s.health.AllowSend() {
+		for _, notification := range batch {
+			if holdErr := s.holdForChannel(ctx, notification, "邮件通道不可用，等待恢复后重发"); holdErr != nil {
+				err = holdErr
+			}
+		}
+		return err
+	}
+	if err := s.send(smtpConfig, recipient.Email, subject, body); err != nil && isEmailChannelError(err) {
+		// 通道不可用时排队，恢复后按单封重发
+		s.health.RecordFailure(err)
+		for _, notification := range batch {
+			s.holdForChannel(ctx, notification, err.Error())
+		}
+		return fmt.Errorf("发送合并邮件失败: %w", err)
+	} else if err != nil {
+		s.health.RecordSuccess()
+		// 失败后按单封重试
+		for _, notification := range batch {
+			notification.ErrorMessage = err.Error()
+			notification.DeliveryStatus = "failed"
+			notification.IncrementRetry(time.Minute * 5)
+			s.db.Save(notification)
+		}
+		return fmt.Errorf("发送合并邮件失败: %w", err)
+	}
+	s.health.RecordSuccess()
+
+	for _, notification := range batch {
+		notification.MarkAsSent()
+		notification.MarkAsDelivered()
+		notification.DeliveryStatus = "delivered"
+		if err := s.db.Save(notification).Error; err != nil {
+			return fmt.Errorf("更新通知状态失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// renderCoalescedEmail 渲染汇总邮件，按时间顺序列出窗口内的所有变更
+func (s *EmailNotificationService) renderCoalescedEmail(batch []*models.Notification) (string, string) {
+	first := batch[0]
+	ticketLabel := fmt.Sprintf("工单 #%d", *first.RelatedTicketID)
+	if first.RelatedTicket != nil {
+		ticketLabel = fmt.Sprintf("%s %s", first.RelatedTicket.TicketNumber, first.RelatedTicket.Title)
+	}
+	subject := fmt.Sprintf("工单动态汇总 - %s（%d 条更新）", ticketLabel, len(batch))
+
+	var items strings.Builder
+	for _, notification := range batch {
+		sender := ""
+		if notification.Sender != nil {
+			sender = fmt.Sprintf("<p><strong>操作人：</strong>%s</p>", html.EscapeString(notification.Sender.Username))
+		}
+		fmt.Fprintf(&items, `
+            <div class="notification">
+                <h3>%s</h3>
+                <p><strong>时间：</strong>%s</p>%s
+                <p>%s</p>
+            </div>`,
+			html.EscapeString(notification.Title),
+			notification.CreatedAt.Format("2006-01-02 15:04:05"),
+			sender,
+			html.EscapeString(notification.Content))
+	}
+
+	actionLink := ""
+	if first.ActionURL != "" {
+		actionLink = fmt.Sprintf(`<a href="%s" class="button">查看工单</a>`, html.EscapeString(first.ActionURL))
+	}
+
+	body := fmt.Sprintf(`<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <title>工单动态汇总</title>
+    <style>
+        body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
+        .container { max-width: 600px; margin: 0 auto; padding: 20px; }
+        .header { background-color: {{.BrandPrimaryColor}}; color: white; padding: 20px; text-align: center; }
+        .content { padding: 20px; background-color: #f9f9f9; }
+        .notification { background-color: white; padding: 15px; margin: 15px 0; border-radius: 4px; border-left: 4px solid {{.BrandPrimaryColor}}; }
+        .button { display: inline-block; padding: 12px 24px; background-color: {{.BrandPrimaryColor}}; color: white; text-decoration: none; border-radius: 4px; margin: 20px 0; }
+        .footer { padding: 20px; text-align: center; color: #666; font-size: 12px; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            {{.BrandLogoImg}}<h1>📋 工单动态汇总</h1>
+        </div>
+        <div class="content">
+            <h2>您好，%s！</h2>
+            <p>工单 <strong>%s</strong> 在短时间内有 %d 条更新：</p>
+            %s
+            %s
+        </div>
+        <div class="footer">
+            <p>{{.BrandFooter}}</p>
+        </div>
+    </div>
+</body>
+</html>`,
+		html.EscapeString(first.Recipient.Username),
+		html.EscapeString(ticketLabel),
+		len(batch),
+		items.String(),
+		actionLink)
+
+	return subject, body
+}
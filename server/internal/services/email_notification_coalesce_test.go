@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+)
+
+// stubEmailConfigService 始终允许发送的邮件配置
+type stubEmailConfigService struct {
+	EmailConfigServiceInterface
+}
+
+func (stubEmailConfigService) CanSendEmail(ctx context.Context) (bool, error) { return true, nil }
+
+func (stubEmailConfigService) GetSMTPConfig(ctx context.Context) (*models.EmailConfig, error) {
+	return &models.EmailConfig{SMTPHost: "localhost", FromEmail: "noreply@example.com"}, nil
+}
+
+func TestEmailNotification_CoalescesBurstPerRecipientAndTicket(t *testing.T) {
+	db := newTestDB(t, &models.User{}, &models.Ticket{}, &models.Notification{}, &models.NotificationPreference{}, &models.SystemConfig{},
+		&models.EmailAddressStatus{}, &models.NotificationDelivery{})
+
+	ctx := context.Background()
+	svc := NewEmailNotificationService(db, stubEmailConfigService{}, nil).(*EmailNotificationService)
+	if err := svc.configService.SetConfig(KeyNotifyEmailCoalesceSec, "1", "int", "", CategoryNotify, "email"); err != nil {
+		t.Fatalf("failed to set coalesce window: %v", err)
+	}
+
+	var mu sync.Mutex
+	var sent []string
+	svc.send = func(config *models.EmailConfig, to, subject, body string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		sent = append(sent, to+"|"+subject)
+		return nil
+	}
+
+	user := models.User{Username: "ec-agent", Email: "ec-agent@example.com", PasswordHash: "x", Role: models.RoleAgent, Status: models.UserStatusActive}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("failed to seed user: %v", err)
+	}
+	tickets := []models.Ticket{
+		{TicketNumber: "EC-1", Title: "burst", Status: models.TicketStatusOpen, Priority: models.TicketPriorityLow,
+			Type: models.TicketTypeRequest, Source: models.TicketSourceWeb, CreatedByID: user.ID},
+		{TicketNumber: "EC-2", Title: "single", Status: models.TicketStatusOpen, Priority: models.TicketPriorityLow,
+			Type: models.TicketTypeRequest, Source: models.TicketSourceWeb, CreatedByID: user.ID},
+	}
+	for i := range tickets {
+		if err := db.Create(&tickets[i]).Error; err != nil {
+			t.Fatalf("failed to seed ticket: %v", err)
+		}
+	}
+
+	notify := func(ticketID uint, title string) {
+		n := &models.Notification{Type: models.NotificationTypeTicketStatusChanged, Title: title, Content: title,
+			Channel: models.NotificationChannelEmail, RecipientID: user.ID, RelatedTicketID: &ticketID}
+		if err := db.Create(n).Error; err != nil {
+			t.Fatalf("failed to create notification: %v", err)
+		}
+		if err := svc.SendEmailNotification(ctx, n); err != nil {
+			t.Fatalf("send notification failed: %v", err)
+		}
+	}
+	notify(tickets[0].ID, "状态变更为处理中")
+	notify(tickets[0].ID, "优先级调整")
+	notify(tickets[0].ID, "处理人变更")
+	notify(tickets[1].ID, "状态变更为已解决")
+
+	mu.Lock()
+	if len(sent) != 0 {
+		t.Fatalf("expected no email within the coalescing window, got %v", sent)
+	}
+	mu.Unlock()
+
+	// 汇总邮件发送后才标记已发送，等待两者都完成
+	var unsent int64
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		done := len(sent) >= 2
+		mu.Unlock()
+		if done {
+			if err := db.Model(&models.Notification{}).Where("is_sent = ?", false).Count(&unsent).Error; err != nil {
+				t.Fatalf("failed to count unsent notifications: %v", err)
+			}
+			done = unsent == 0
+		}
+		if done || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(sent) != 2 {
+		t.Fatalf("expected one email per ticket, got %v", sent)
+	}
+	var summary, single bool
+	for _, s := range sent {
+		summary = summary || strings.Contains(s, "工单动态汇总 - EC-1 burst（3 条更新）")
+		single = single || strings.Contains(s, "工单状态更新 - 状态变更为已解决")
+	}
+	if !summary || !single {
+		t.Fatalf("unexpected emails: %v", sent)
+	}
+	if unsent != 0 {
+		t.Fatalf("expected all notifications to be marked sent, %d remaining", unsent)
+	}
+}
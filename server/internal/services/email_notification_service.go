@@ -3,9 +3,12 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"html"
 	"net/smtp"
 	"strings"
+	"sync"
 	"time"
 
 	"gorm.io/gorm"
@@ -17,6 +20,9 @@ type EmailNotificationServiceInterface interface {
 	SendEmailNotification(ctx context.Context, notification *models.Notification) error
 	SendBulkEmailNotifications(ctx context.Context, notifications []*models.Notification) error
 	GetEmailTemplate(notificationType models.NotificationType) (*EmailTemplate, error)
+	ChannelStatus(ctx context.Context) (*EmailChannelStatus, error)
+	MonitorChannel(ctx context.Context) error
+	SetBranding(branding *BrandingService)
 }
 
 // EmailTemplate 邮件模板结构
@@ -31,6 +37,15 @@ type EmailNotificationService struct {
 	db                   *gorm.DB
 	emailConfigService   EmailConfigServiceInterface
 	notificationService  NotificationServiceInterface
+	configService        *ConfigService
+	send                 func(config *models.EmailConfig, to, subject, body string) error
+	suppression          *EmailSuppressionService
+	health               *EmailChannelHealth
+	branding             *BrandingService
+
+	// 合并窗口内等待发送的接收者+工单组合
+	coalesceMu      sync.Mutex
+	coalescePending map[string]struct{}
 }
 
 // NewEmailNotificationService 创建邮件通知服务
@@ -39,11 +54,22 @@ func NewEmailNotificationService(
 	emailConfigService EmailConfigServiceInterface,
 	notificationService NotificationServiceInterface,
 ) EmailNotificationServiceInterface {
-	return &EmailNotificationService{
+	service := &EmailNotificationService{
 		db:                  db,
 		emailConfigService:  emailConfigService,
 		notificationService: notificationService,
+		configService:       NewConfigService(db),
+		suppression:         NewEmailSuppressionService(db),
+		health:              NewEmailChannelHealth(),
+		coalescePending:     make(map[string]struct{}),
 	}
+	service.send = service.sendEmail
+	return service
+}
+
+// SetBranding 设置品牌服务，邮件中的品牌变量和发件人按品牌设置渲染；未设置时使用默认品牌
+func (s *EmailNotificationService) SetBranding(branding *BrandingService) {
+	s.branding = branding
 }
 
 // SendEmailNotification 发送邮件通知
@@ -53,6 +79,18 @@ func (s *EmailNotificationService) SendEmailNotification(ctx context.Context, no
 		return nil
 	}
 
+	// 同一工单短时间内的多封邮件合并为一封汇总邮件
+	if s.shouldCoalesce(notification) {
+		return s.enqueueCoalesced(ctx, notification)
+	}
+
+	return s.deliver(ctx, notification)
+}
+
+// deliver 立即发送单条邮件通知，结果记入通知的邮件渠道投递记录
+func (s *EmailNotificationService) deliver(ctx context.Context, notification *models.Notification) (err error) {
+	defer func() { trackEmailDelivery(ctx, s.db, notification, err) }()
+
 	// 检查系统是否可以发送邮件
 	canSend, err := s.emailConfigService.CanSendEmail(ctx)
 	if err != nil {
@@ -86,6 +124,13 @@ func (s *EmailNotificationService) SendEmailNotification(ctx context.Context, no
 		return nil
 	}
 
+	// 内部评论不得通过邮件发送给看不到它的接收者
+	if !s.isCommentVisibleToRecipient(ctx, notification) {
+		notification.DeliveryStatus = "skipped_internal_comment"
+		s.db.Save(notification)
+		return nil
+	}
+
 	// 检查用户邮箱是否有效
 	if notification.Recipient.Email == "" {
 		notification.DeliveryStatus = "failed_no_email"
@@ -94,6 +139,16 @@ func (s *EmailNotificationService) SendEmailNotification(ctx context.Context, no
 		return fmt.Errorf("用户未设置邮箱地址")
 	}
 
+	// 永久退信或投诉的地址停止发送
+	if suppressed, err := s.isSuppressed(ctx, notification.Recipient.Email); err != nil {
+		return err
+	} else if suppressed {
+		notification.DeliveryStatus = "skipped_suppressed"
+		notification.ErrorMessage = "邮箱地址已退信或投诉，停止发送"
+		s.db.Save(notification)
+		return nil
+	}
+
 	// 获取邮件模板
 	template, err := s.GetEmailTemplate(notification.Type)
 	if err != nil {
@@ -105,11 +160,25 @@ func (s *EmailNotificationService) SendEmailNotification(ctx context.Context, no
 	if err != nil {
 		return fmt.Errorf("渲染邮件内容失败: %w", err)
 	}
+	subject, htmlBody = RenderBrandedEmail(ctx, s.branding, subject, htmlBody)
+
+	// 邮件通道不可用时排队，等待恢复后重发
+	if !s.health.AllowSend() {
+		return s.holdForChannel(ctx, notification, "邮件通道不可用，等待恢复后重发")
+	}
 
 	// 发送邮件
-	err = s.sendEmail(smtpConfig, notification.Recipient.Email, subject, htmlBody)
+	err = s.send(smtpConfig, notification.Recipient.Email, subject, htmlBody)
+	if err != nil && isEmailChannelError(err) {
+		s.health.RecordFailure(err)
+		if holdErr := s.holdForChannel(ctx, notification, err.Error()); holdErr != nil {
+			return holdErr
+		}
+		return fmt.Errorf("发送邮件失败: %w", err)
+	}
+	s.health.RecordSuccess()
 	if err != nil {
-		// 更新失败状态
+		// 收件地址被拒，按失败重试
 		notification.ErrorMessage = err.Error()
 		notification.DeliveryStatus = "failed"
 		notification.IncrementRetry(time.Minute * 5) // 5分钟后重试
@@ -196,6 +265,12 @@ func (s *EmailNotificationService) GetEmailTemplate(notificationType models.Noti
 			HTMLBody: s.getTicketOverdueHTMLTemplate(),
 			TextBody: s.getTicketOverdueTextTemplate(),
 		}, nil
+	case models.NotificationTypeTicketReminder:
+		return &EmailTemplate{
+			Subject:  "工单提醒 - {{.Title}}",
+			HTMLBody: s.getDefaultHTMLTemplate(),
+			TextBody: s.getDefaultTextTemplate(),
+		}, nil
 	case models.NotificationTypeSystemMaintenance:
 		return &EmailTemplate{
 			Subject:  "系统维护通知 - {{.Title}}",
@@ -233,13 +308,23 @@ func (s *EmailNotificationService) isEmailEnabledForUser(ctx context.Context, us
 	return preference.EmailEnabled, nil
 }
 
+// isSuppressed 地址是否因退信或投诉停止发送
+func (s *EmailNotificationService) isSuppressed(ctx context.Context, email string) (bool, error) {
+	err := s.suppression.CheckSendable(ctx, email)
+	if errors.Is(err, ErrEmailSuppressed) {
+		return true, nil
+	}
+	return false, err
+}
+
 // sendEmail 发送邮件
 func (s *EmailNotificationService) sendEmail(config *models.EmailConfig, to, subject, body string) error {
 	// 创建SMTP认证
 	auth := smtp.PlainAuth("", config.SMTPUsername, config.SMTPPassword, config.SMTPHost)
 	
-	// 构建邮件消息
-	msg := s.buildEmailMessage(config.FromEmail, config.FromName, to, subject, body)
+	// 构建邮件消息，发件人按品牌设置调整，SMTP 信封发件人保持不变
+	fromEmail, fromName := BrandedSender(context.Background(), s.branding, config.FromEmail, config.FromName)
+	msg := s.buildEmailMessage(fromEmail, fromName, to, subject, body)
 	
 	// 发送邮件
 	addr := fmt.Sprintf("%s:%d", config.SMTPHost, config.SMTPPort)
@@ -328,10 +413,59 @@ func (s *EmailNotificationService) buildTemplateData(notification *models.Notifi
 			}
 		}
 	}
+
+	// 评论附件，没有附件时替换为空
+	data["AttachmentsHTML"], data["AttachmentsText"] = s.commentAttachmentsData(notification)
 	
 	return data
 }
 
+// commentAttachmentsData 生成评论通知中的附件列表（HTML 与纯文本），链接指向门户地址下的附件下载接口
+func (s *EmailNotificationService) commentAttachmentsData(notification *models.Notification) (string, string) {
+	if notification.Metadata == "" {
+		return "", ""
+	}
+	var metadata struct {
+		CommentID json.Number `json:"comment_id"`
+	}
+	if err := json.Unmarshal([]byte(notification.Metadata), &metadata); err != nil {
+		return "", ""
+	}
+	commentID, err := metadata.CommentID.Int64()
+	if err != nil || commentID <= 0 {
+		return "", ""
+	}
+
+	var attachments []models.TicketAttachment
+	if err := s.db.Where("comment_id = ? AND deleted_at IS NULL", commentID).
+		Order("id ASC").Find(&attachments).Error; err != nil || len(attachments) == 0 {
+		return "", ""
+	}
+
+	var htmlList, textList strings.Builder
+	htmlList.WriteString(`<p><strong>附件：</strong></p><ul>`)
+	textList.WriteString("附件：\n")
+	for _, attachment := range attachments {
+		link := "{{.BrandPortalURL}}" + fmt.Sprintf(ticketAttachmentDownloadPath, attachment.TicketID, attachment.ID)
+		size := formatAttachmentSize(attachment.FileSize)
+		htmlList.WriteString(fmt.Sprintf(`<li><a href="%s">%s</a> (%s)</li>`, link, html.EscapeString(attachment.OriginalName), size))
+		textList.WriteString(fmt.Sprintf("- %s (%s)：%s\n", attachment.OriginalName, size, link))
+	}
+	htmlList.WriteString("</ul>")
+	return htmlList.String(), textList.String()
+}
+
+// formatAttachmentSize 附件大小的可读形式
+func formatAttachmentSize(size int64) string {
+	switch {
+	case size >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(size)/(1<<20))
+	case size >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(size)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", size)
+}
+
 // renderTemplate 简单模板渲染
 func (s *EmailNotificationService) renderTemplate(template string, data map[string]interface{}) string {
 	result := template
@@ -355,10 +489,10 @@ func (s *EmailNotificationService) getTicketAssignedHTMLTemplate() string {
     <style>
         body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
         .container { max-width: 600px; margin: 0 auto; padding: 20px; }
-        .header { background-color: #007bff; color: white; padding: 20px; text-align: center; }
+        .header { background-color: {{.BrandPrimaryColor}}; color: white; padding: 20px; text-align: center; }
         .content { padding: 20px; background-color: #f9f9f9; }
         .ticket-info { background-color: white; padding: 15px; margin: 15px 0; border-radius: 4px; }
-        .button { display: inline-block; padding: 12px 24px; background-color: #007bff; color: white; text-decoration: none; border-radius: 4px; margin: 20px 0; }
+        .button { display: inline-block; padding: 12px 24px; background-color: {{.BrandPrimaryColor}}; color: white; text-decoration: none; border-radius: 4px; margin: 20px 0; }
         .footer { padding: 20px; text-align: center; color: #666; font-size: 12px; }
         .priority-high { border-left: 4px solid #dc3545; }
         .priority-normal { border-left: 4px solid #28a745; }
@@ -368,7 +502,7 @@ func (s *EmailNotificationService) getTicketAssignedHTMLTemplate() string {
 <body>
     <div class="container">
         <div class="header">
-            <h1>🎫 新工单已分配</h1>
+            {{.BrandLogoImg}}<h1>🎫 新工单已分配</h1>
         </div>
         <div class="content">
             <h2>您好，{{.RecipientName}}！</h2>
@@ -388,7 +522,7 @@ func (s *EmailNotificationService) getTicketAssignedHTMLTemplate() string {
             <p>请尽快登录系统查看和处理此工单。</p>
         </div>
         <div class="footer">
-            <p>© 2024 工单系统. 保留所有权利.</p>
+            <p>{{.BrandFooter}}</p>
             <p>如果您不想接收此类邮件，请在系统中修改通知设置。</p>
         </div>
     </div>
@@ -415,7 +549,7 @@ func (s *EmailNotificationService) getTicketStatusChangedHTMLTemplate() string {
 <body>
     <div class="container">
         <div class="header">
-            <h1>📊 工单状态更新</h1>
+            {{.BrandLogoImg}}<h1>📊 工单状态更新</h1>
         </div>
         <div class="content">
             <h2>您好，{{.RecipientName}}！</h2>
@@ -433,7 +567,7 @@ func (s *EmailNotificationService) getTicketStatusChangedHTMLTemplate() string {
             <a href="{{.ActionURL}}" class="button">查看工单详情</a>
         </div>
         <div class="footer">
-            <p>© 2024 工单系统. 保留所有权利.</p>
+            <p>{{.BrandFooter}}</p>
         </div>
     </div>
 </body>
@@ -459,7 +593,7 @@ func (s *EmailNotificationService) getTicketCommentedHTMLTemplate() string {
 <body>
     <div class="container">
         <div class="header">
-            <h1>💬 工单新回复</h1>
+            {{.BrandLogoImg}}<h1>💬 工单新回复</h1>
         </div>
         <div class="content">
             <h2>您好，{{.RecipientName}}！</h2>
@@ -472,12 +606,13 @@ func (s *EmailNotificationService) getTicketCommentedHTMLTemplate() string {
                 <p><strong>回复时间：</strong>{{.CreatedAt}}</p>
                 <p><strong>回复内容：</strong></p>
                 <p>{{.Content}}</p>
+                {{.AttachmentsHTML}}
             </div>
             
             <a href="{{.ActionURL}}" class="button">查看完整对话</a>
         </div>
         <div class="footer">
-            <p>© 2024 工单系统. 保留所有权利.</p>
+            <p>{{.BrandFooter}}</p>
         </div>
     </div>
 </body>
@@ -503,7 +638,7 @@ func (s *EmailNotificationService) getTicketCreatedHTMLTemplate() string {
 <body>
     <div class="container">
         <div class="header">
-            <h1>🆕 新工单创建</h1>
+            {{.BrandLogoImg}}<h1>🆕 新工单创建</h1>
         </div>
         <div class="content">
             <h2>您好，{{.RecipientName}}！</h2>
@@ -522,7 +657,7 @@ func (s *EmailNotificationService) getTicketCreatedHTMLTemplate() string {
             <a href="{{.ActionURL}}" class="button">查看工单详情</a>
         </div>
         <div class="footer">
-            <p>© 2024 工单系统. 保留所有权利.</p>
+            <p>{{.BrandFooter}}</p>
         </div>
     </div>
 </body>
@@ -548,7 +683,7 @@ func (s *EmailNotificationService) getTicketOverdueHTMLTemplate() string {
 <body>
     <div class="container">
         <div class="header">
-            <h1>⚠️ 工单即将逾期</h1>
+            {{.BrandLogoImg}}<h1>⚠️ 工单即将逾期</h1>
         </div>
         <div class="content">
             <h2>您好，{{.RecipientName}}！</h2>
@@ -565,7 +700,7 @@ func (s *EmailNotificationService) getTicketOverdueHTMLTemplate() string {
             <a href="{{.ActionURL}}" class="button">立即处理</a>
         </div>
         <div class="footer">
-            <p>© 2024 工单系统. 保留所有权利.</p>
+            <p>{{.BrandFooter}}</p>
         </div>
     </div>
 </body>
@@ -590,7 +725,7 @@ func (s *EmailNotificationService) getSystemMaintenanceHTMLTemplate() string {
 <body>
     <div class="container">
         <div class="header">
-            <h1>🔧 系统维护通知</h1>
+            {{.BrandLogoImg}}<h1>🔧 系统维护通知</h1>
         </div>
         <div class="content">
             <h2>尊敬的用户，{{.RecipientName}}！</h2>
@@ -606,7 +741,7 @@ func (s *EmailNotificationService) getSystemMaintenanceHTMLTemplate() string {
             <p>维护期间可能会影响系统正常使用，请您提前做好准备。感谢您的理解与配合！</p>
         </div>
         <div class="footer">
-            <p>© 2024 工单系统. 保留所有权利.</p>
+            <p>{{.BrandFooter}}</p>
         </div>
     </div>
 </body>
@@ -631,7 +766,7 @@ func (s *EmailNotificationService) getSystemAlertHTMLTemplate() string {
 <body>
     <div class="container">
         <div class="header">
-            <h1>🚨 系统警报</h1>
+            {{.BrandLogoImg}}<h1>🚨 系统警报</h1>
         </div>
         <div class="content">
             <h2>您好，{{.RecipientName}}！</h2>
@@ -648,7 +783,7 @@ func (s *EmailNotificationService) getSystemAlertHTMLTemplate() string {
             <p>请管理员及时查看和处理此警报。</p>
         </div>
         <div class="footer">
-            <p>© 2024 工单系统. 保留所有权利.</p>
+            <p>{{.BrandFooter}}</p>
         </div>
     </div>
 </body>
@@ -664,16 +799,16 @@ func (s *EmailNotificationService) getDefaultHTMLTemplate() string {
     <style>
         body { font-family: Arial, sans-serif; line-height: 1.6; color: #333; }
         .container { max-width: 600px; margin: 0 auto; padding: 20px; }
-        .header { background-color: #007bff; color: white; padding: 20px; text-align: center; }
+        .header { background-color: {{.BrandPrimaryColor}}; color: white; padding: 20px; text-align: center; }
         .content { padding: 20px; background-color: #f9f9f9; }
-        .notification { background-color: white; padding: 15px; margin: 15px 0; border-radius: 4px; border-left: 4px solid #007bff; }
+        .notification { background-color: white; padding: 15px; margin: 15px 0; border-radius: 4px; border-left: 4px solid {{.BrandPrimaryColor}}; }
         .footer { padding: 20px; text-align: center; color: #666; font-size: 12px; }
     </style>
 </head>
 <body>
     <div class="container">
         <div class="header">
-            <h1>📧 系统通知</h1>
+            {{.BrandLogoImg}}<h1>📧 系统通知</h1>
         </div>
         <div class="content">
             <h2>您好，{{.RecipientName}}！</h2>
@@ -684,10 +819,11 @@ func (s *EmailNotificationService) getDefaultHTMLTemplate() string {
                 <p><strong>通知时间：</strong>{{.CreatedAt}}</p>
                 <p><strong>通知内容：</strong></p>
                 <p>{{.Content}}</p>
+                {{.AttachmentsHTML}}
             </div>
         </div>
         <div class="footer">
-            <p>© 2024 工单系统. 保留所有权利.</p>
+            <p>{{.BrandFooter}}</p>
         </div>
     </div>
 </body>
@@ -713,7 +849,7 @@ func (s *EmailNotificationService) getTicketAssignedTextTemplate() string {
 请访问以下链接查看详情：{{.ActionURL}}
 
 ---
-工单系统`
+{{.BrandProductName}}`
 }
 
 func (s *EmailNotificationService) getTicketStatusChangedTextTemplate() string {
@@ -734,7 +870,7 @@ func (s *EmailNotificationService) getTicketStatusChangedTextTemplate() string {
 请访问以下链接查看详情：{{.ActionURL}}
 
 ---
-工单系统`
+{{.BrandProductName}}`
 }
 
 func (s *EmailNotificationService) getTicketCommentedTextTemplate() string {
@@ -752,10 +888,11 @@ func (s *EmailNotificationService) getTicketCommentedTextTemplate() string {
 回复内容：
 {{.Content}}
 
+{{.AttachmentsText}}
 请访问以下链接查看完整对话：{{.ActionURL}}
 
 ---
-工单系统`
+{{.BrandProductName}}`
 }
 
 func (s *EmailNotificationService) getTicketCreatedTextTemplate() string {
@@ -777,7 +914,7 @@ func (s *EmailNotificationService) getTicketCreatedTextTemplate() string {
 请访问以下链接查看详情：{{.ActionURL}}
 
 ---
-工单系统`
+{{.BrandProductName}}`
 }
 
 func (s *EmailNotificationService) getTicketOverdueTextTemplate() string {
@@ -798,7 +935,7 @@ func (s *EmailNotificationService) getTicketOverdueTextTemplate() string {
 请立即访问以下链接处理：{{.ActionURL}}
 
 ---
-工单系统`
+{{.BrandProductName}}`
 }
 
 func (s *EmailNotificationService) getSystemMaintenanceTextTemplate() string {
@@ -818,7 +955,7 @@ func (s *EmailNotificationService) getSystemMaintenanceTextTemplate() string {
 感谢您的理解与配合！
 
 ---
-工单系统`
+{{.BrandProductName}}`
 }
 
 func (s *EmailNotificationService) getSystemAlertTextTemplate() string {
@@ -838,7 +975,7 @@ func (s *EmailNotificationService) getSystemAlertTextTemplate() string {
 请管理员及时查看和处理此警报。
 
 ---
-工单系统`
+{{.BrandProductName}}`
 }
 
 func (s *EmailNotificationService) getDefaultTextTemplate() string {
@@ -854,6 +991,27 @@ func (s *EmailNotificationService) getDefaultTextTemplate() string {
 通知内容：
 {{.Content}}
 
+{{.AttachmentsText}}
 ---
-工单系统`
-}
\ No newline at end of file
+{{.BrandProductName}}`
+}
+
+// isCommentVisibleToRecipient 通知关联评论时，检查接收者能否查看该评论
+func (s *EmailNotificationService) isCommentVisibleToRecipient(ctx context.Context, notification *models.Notification) bool {
+	if notification.Metadata == "" || notification.Recipient == nil {
+		return true
+	}
+	var metadata struct {
+		CommentID uint `json:"comment_id"`
+	}
+	if err := json.Unmarshal([]byte(notification.Metadata), &metadata); err != nil || metadata.CommentID == 0 {
+		return true
+	}
+
+	var comment models.TicketComment
+	if err := s.db.WithContext(ctx).First(&comment, metadata.CommentID).Error; err != nil {
+		// 评论无法确认时按不可见处理
+		return false
+	}
+	return commentVisibleToUser(s.db.WithContext(ctx), &comment, notification.Recipient)
+}
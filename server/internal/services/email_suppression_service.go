@@ -0,0 +1,302 @@
+package services
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"gongdan-system/internal/models"
+)
+
+const (
+	// emailSoftBounceDefaultThreshold 连续临时退信达到该次数后按永久退信处理
+	emailSoftBounceDefaultThreshold = 3
+	// emailAddressDetailEvents 地址详情返回的最近事件数
+	emailAddressDetailEvents = 50
+)
+
+var (
+	// ErrEmailSuppressed 地址已永久退信或投诉，停止发送
+	ErrEmailSuppressed = errors.New("email address is suppressed")
+	// ErrEmailEventWebhookDisabled 未配置回调令牌
+	ErrEmailEventWebhookDisabled = errors.New("email event webhook is not configured")
+	// ErrEmailEventWebhookUnauthorized 回调令牌错误
+	ErrEmailEventWebhookUnauthorized = errors.New("invalid email event webhook token")
+	// ErrUnknownEmailEventProvider 不支持的回调来源
+	ErrUnknownEmailEventProvider = errors.New("unknown email event provider")
+	// ErrInvalidEmailEventPayload 回调内容无法解析
+	ErrInvalidEmailEventPayload = errors.New("invalid email event payload")
+	// ErrEmailAddressNotFound 邮箱地址状态不存在
+	ErrEmailAddressNotFound = errors.New("email address status not found")
+	// ErrEmailAddressNotSuppressed 地址状态正常，无需恢复
+	ErrEmailAddressNotSuppressed = errors.New("email address is not bounced or complained")
+)
+
+// EmailSuppressionService 处理服务商的退信/投诉回调，维护每个邮箱地址的投递状态；
+// 永久退信和投诉的地址停止发送，管理员可填写理由后手动恢复
+type EmailSuppressionService struct {
+	db            *gorm.DB
+	configService *ConfigService
+	client        *http.Client
+	now           func() time.Time
+}
+
+// NewEmailSuppressionService 创建邮件退信处理服务
+func NewEmailSuppressionService(db *gorm.DB) *EmailSuppressionService {
+	return &EmailSuppressionService{
+		db:            db,
+		configService: NewConfigService(db),
+		client:        &http.Client{Timeout: 10 * time.Second},
+		now:           time.Now,
+	}
+}
+
+func normalizeEmailAddress(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// CheckSendable 发送前检查地址状态，已停止发送时返回 ErrEmailSuppressed
+func (s *EmailSuppressionService) CheckSendable(ctx context.Context, email string) error {
+	var status models.EmailAddressStatus
+	err := s.db.WithContext(ctx).Select("status").Where("email = ?", normalizeEmailAddress(email)).Take(&status).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to check email address status: %w", err)
+	}
+	if status.Status.Suppressed() {
+		return fmt.Errorf("%w (%s)", ErrEmailSuppressed, status.Status)
+	}
+	return nil
+}
+
+// VerifyWebhookToken 校验回调令牌（配置项 email.bounce_webhook_token），未配置时回调不可用
+func (s *EmailSuppressionService) VerifyWebhookToken(token string) error {
+	expected := s.configService.GetConfigWithDefault(KeyEmailBounceWebhookToken, "")
+	if expected == "" {
+		return ErrEmailEventWebhookDisabled
+	}
+	if subtle.ConstantTimeCompare([]byte(expected), []byte(token)) != 1 {
+		return ErrEmailEventWebhookUnauthorized
+	}
+	return nil
+}
+
+// HandleWebhook 解析服务商回调并更新地址状态，返回处理的事件数。
+// SES 的 SNS 订阅确认请求会自动确认
+func (s *EmailSuppressionService) HandleWebhook(ctx context.Context, provider string, body []byte) (int, error) {
+	events, subscription, err := parseEmailEvents(provider, body)
+	if err != nil {
+		return 0, err
+	}
+	if subscription != nil {
+		return 0, s.confirmSNSSubscription(ctx, subscription.SubscribeURL)
+	}
+
+	processed := 0
+	for _, event := range events {
+		event.Provider = provider
+		if normalizeEmailAddress(event.Email) == "" {
+			continue
+		}
+		if err := s.RecordEvent(ctx, event); err != nil {
+			return processed, err
+		}
+		processed++
+	}
+	return processed, nil
+}
+
+func (s *EmailSuppressionService) confirmSNSSubscription(ctx context.Context, subscribeURL string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, subscribeURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to build subscription confirmation: %w", err)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to confirm SNS subscription: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to confirm SNS subscription: status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// RecordEvent 按投递事件更新地址状态：投诉和永久退信停止发送；连续临时退信达到阈值后按永久退信处理；
+// 送达事件使临时退信的地址恢复正常（不会恢复已停止发送的地址）
+func (s *EmailSuppressionService) RecordEvent(ctx context.Context, event *emailEvent) error {
+	email := normalizeEmailAddress(event.Email)
+	threshold, err := s.configService.GetConfigInt(KeyEmailSoftBounceThreshold)
+	if err != nil || threshold < 1 {
+		threshold = emailSoftBounceDefaultThreshold
+	}
+	now := s.now()
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var status models.EmailAddressStatus
+		err := tx.Where("email = ?", email).Take(&status).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if event.Type == models.EmailEventDelivered {
+				return nil
+			}
+			status = models.EmailAddressStatus{Email: email, Status: models.EmailAddressOK}
+		case err != nil:
+			return fmt.Errorf("failed to get email address status: %w", err)
+		}
+
+		from := status.Status
+		switch event.Type {
+		case models.EmailEventComplaint:
+			status.Status = models.EmailAddressComplained
+		case models.EmailEventBounce:
+			if event.Permanent {
+				if status.Status != models.EmailAddressComplained {
+					status.Status = models.EmailAddressHardBounce
+				}
+			} else {
+				status.SoftBounceCount++
+				if !status.Status.Suppressed() {
+					status.Status = models.EmailAddressSoftBounce
+					if status.SoftBounceCount >= threshold {
+						status.Status = models.EmailAddressHardBounce
+					}
+				}
+			}
+		case models.EmailEventDelivered:
+			if status.Status != models.EmailAddressSoftBounce {
+				return nil
+			}
+			status.Status = models.EmailAddressOK
+			status.SoftBounceCount = 0
+			return tx.Save(&status).Error
+		}
+
+		var user models.User
+		if err := tx.Select("id").Where("LOWER(email) = ?", email).Take(&user).Error; err == nil {
+			status.UserID = &user.ID
+		}
+		status.LastEvent = string(event.Type)
+		status.LastEventAt = &now
+		status.LastReason = truncateString(event.Reason, 497)
+		status.LastProvider = event.Provider
+		if err := tx.Save(&status).Error; err != nil {
+			return fmt.Errorf("failed to save email address status: %w", err)
+		}
+
+		record := &models.EmailDeliveryEvent{
+			CreatedAt:  now,
+			Email:      email,
+			Type:       event.Type,
+			Permanent:  event.Permanent,
+			Provider:   event.Provider,
+			MessageID:  truncateString(event.MessageID, 252),
+			Reason:     status.LastReason,
+			FromStatus: from,
+			ToStatus:   status.Status,
+		}
+		if err := tx.Create(record).Error; err != nil {
+			return fmt.Errorf("failed to record email event: %w", err)
+		}
+		return nil
+	})
+}
+
+// ListAddresses 分页查询地址状态，默认只返回非正常状态，status 为 all 时返回全部
+func (s *EmailSuppressionService) ListAddresses(ctx context.Context, status, search string, page, pageSize int) ([]*models.EmailAddressStatus, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	query := s.db.WithContext(ctx).Model(&models.EmailAddressStatus{})
+	switch status {
+	case "":
+		query = query.Where("status <> ?", models.EmailAddressOK)
+	case "all":
+	default:
+		query = query.Where("status = ?", status)
+	}
+	if search = normalizeEmailAddress(search); search != "" {
+		query = query.Where("email LIKE ?", "%"+search+"%")
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count email addresses: %w", err)
+	}
+	var items []*models.EmailAddressStatus
+	if err := query.Order("updated_at DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&items).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to get email addresses: %w", err)
+	}
+	return items, total, nil
+}
+
+// GetAddress 获取地址状态及最近的事件
+func (s *EmailSuppressionService) GetAddress(ctx context.Context, id uint) (*models.EmailAddressDetail, error) {
+	var status models.EmailAddressStatus
+	if err := s.db.WithContext(ctx).First(&status, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrEmailAddressNotFound
+		}
+		return nil, fmt.Errorf("failed to get email address status: %w", err)
+	}
+	detail := &models.EmailAddressDetail{EmailAddressStatus: &status, Events: []*models.EmailDeliveryEvent{}}
+	if err := s.db.WithContext(ctx).Where("email = ?", status.Email).Order("created_at DESC, id DESC").
+		Limit(emailAddressDetailEvents).Find(&detail.Events).Error; err != nil {
+		return nil, fmt.Errorf("failed to get email events: %w", err)
+	}
+	return detail, nil
+}
+
+// Reenable 管理员填写理由后恢复向地址发送邮件，同时清零临时退信计数
+func (s *EmailSuppressionService) Reenable(ctx context.Context, id uint, reason string, adminID uint) (*models.EmailAddressStatus, error) {
+	reason = strings.TrimSpace(reason)
+	now := s.now()
+	var status models.EmailAddressStatus
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&status, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrEmailAddressNotFound
+			}
+			return fmt.Errorf("failed to get email address status: %w", err)
+		}
+		if status.Status == models.EmailAddressOK {
+			return ErrEmailAddressNotSuppressed
+		}
+
+		from := status.Status
+		status.Status = models.EmailAddressOK
+		status.SoftBounceCount = 0
+		status.ReenabledByID = &adminID
+		status.ReenabledAt = &now
+		status.ReenableReason = reason
+		if err := tx.Save(&status).Error; err != nil {
+			return fmt.Errorf("failed to save email address status: %w", err)
+		}
+		return tx.Create(&models.EmailDeliveryEvent{
+			CreatedAt:  now,
+			Email:      status.Email,
+			Type:       models.EmailEventReenabled,
+			Reason:     reason,
+			ActorID:    &adminID,
+			FromStatus: from,
+			ToStatus:   models.EmailAddressOK,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
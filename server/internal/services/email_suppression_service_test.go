@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestEmailSuppression_BouncesComplaintsAndReenable(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:email_suppression_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Notification{}, &models.NotificationPreference{}, &models.SystemConfig{},
+		&models.EmailAddressStatus{}, &models.EmailDeliveryEvent{}, &models.NotificationDelivery{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+
+	ctx := context.Background()
+	svc := NewEmailSuppressionService(db)
+	admin := models.User{Username: "es-admin", Email: "es-admin@example.com", PasswordHash: "x", Role: models.RoleAdmin, Status: models.UserStatusActive}
+	customer := models.User{Username: "es-customer", Email: "Bounced@Example.com", PasswordHash: "x", Role: models.RoleCustomer, Status: models.UserStatusActive}
+	for _, u := range []*models.User{&admin, &customer} {
+		if err := db.Create(u).Error; err != nil {
+			t.Fatalf("failed to seed user: %v", err)
+		}
+	}
+
+	// 未配置令牌时回调不可用
+	if err := svc.VerifyWebhookToken("anything"); !errors.Is(err, ErrEmailEventWebhookDisabled) {
+		t.Fatalf("expected webhook to be disabled without token, got %v", err)
+	}
+	svc.configService.SetConfig(KeyEmailBounceWebhookToken, "hook-secret", "string", "", CategoryEmail, "bounces")
+	if err := svc.VerifyWebhookToken("wrong"); !errors.Is(err, ErrEmailEventWebhookUnauthorized) {
+		t.Fatalf("expected wrong token to be rejected, got %v", err)
+	}
+	if err := svc.VerifyWebhookToken("hook-secret"); err != nil {
+		t.Fatalf("expected token to be accepted, got %v", err)
+	}
+
+	// SES 永久退信（经 SNS 包装）停止发送，并关联到用户
+	ses := `{"Type":"Notification","Message":"{\"notificationType\":\"Bounce\",\"mail\":{\"messageId\":\"m-1\"},\"bounce\":{\"bounceType\":\"Permanent\",\"bouncedRecipients\":[{\"emailAddress\":\"bounced@example.com\",\"diagnosticCode\":\"550 5.1.1 user unknown\"}]}}"}`
+	if n, err := svc.HandleWebhook(ctx, EmailEventProviderSES, []byte(ses)); err != nil || n != 1 {
+		t.Fatalf("ses bounce failed: %d %v", n, err)
+	}
+	if err := svc.CheckSendable(ctx, "BOUNCED@example.com"); !errors.Is(err, ErrEmailSuppressed) {
+		t.Fatalf("expected hard-bounced address to be suppressed, got %v", err)
+	}
+	lookup := func(email string) *models.EmailAddressStatus {
+		var status models.EmailAddressStatus
+		db.Where("email = ?", email).First(&status)
+		return &status
+	}
+	status := lookup("bounced@example.com")
+	if status.Status != models.EmailAddressHardBounce || status.UserID == nil || *status.UserID != customer.ID || status.LastReason != "550 5.1.1 user unknown" {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+
+	// SendGrid：临时退信可继续发送，送达后恢复正常；连续达到阈值后停止发送
+	softBounce := `[{"email":"soft@example.com","event":"bounce","type":"blocked","reason":"mailbox full"},{"email":"x@example.com","event":"open"}]`
+	if n, err := svc.HandleWebhook(ctx, EmailEventProviderSendGrid, []byte(softBounce)); err != nil || n != 1 {
+		t.Fatalf("sendgrid soft bounce failed: %d %v", n, err)
+	}
+	if err := svc.CheckSendable(ctx, "soft@example.com"); err != nil {
+		t.Fatalf("expected soft-bounced address to stay sendable, got %v", err)
+	}
+	svc.HandleWebhook(ctx, EmailEventProviderSendGrid, []byte(`[{"email":"soft@example.com","event":"delivered"}]`))
+	if status := lookup("soft@example.com"); status.Status != models.EmailAddressOK || status.SoftBounceCount != 0 {
+		t.Fatalf("expected delivery to reset soft bounce, got %+v", status)
+	}
+	for i := 0; i < 3; i++ {
+		svc.HandleWebhook(ctx, EmailEventProviderSendGrid, []byte(softBounce))
+	}
+	if err := svc.CheckSendable(ctx, "soft@example.com"); !errors.Is(err, ErrEmailSuppressed) {
+		t.Fatalf("expected repeated soft bounces to suppress, got %v", err)
+	}
+
+	// Mailgun 投诉；通用格式拒绝未知类型
+	complaint := `{"event-data":{"event":"complained","recipient":"angry@example.com","message":{"headers":{"message-id":"m-2"}}}}`
+	if n, err := svc.HandleWebhook(ctx, EmailEventProviderMailgun, []byte(complaint)); err != nil || n != 1 {
+		t.Fatalf("mailgun complaint failed: %d %v", n, err)
+	}
+	if _, err := svc.HandleWebhook(ctx, EmailEventProviderGeneric, []byte(`{"events":[{"email":"a@example.com","type":"opened"}]}`)); !errors.Is(err, ErrInvalidEmailEventPayload) {
+		t.Fatalf("expected unknown generic event to be rejected, got %v", err)
+	}
+	if _, err := svc.HandleWebhook(ctx, "postmark", []byte(`{}`)); !errors.Is(err, ErrUnknownEmailEventProvider) {
+		t.Fatalf("expected unknown provider error, got %v", err)
+	}
+	// 订阅确认地址必须是 SNS
+	confirm := `{"Type":"SubscriptionConfirmation","SubscribeURL":"https://evil.example.com/confirm"}`
+	if _, err := svc.HandleWebhook(ctx, EmailEventProviderSES, []byte(confirm)); !errors.Is(err, ErrInvalidEmailEventPayload) {
+		t.Fatalf("expected non-SNS subscribe url to be rejected, got %v", err)
+	}
+
+	items, total, err := svc.ListAddresses(ctx, "", "", 1, 20)
+	if err != nil || total != 3 || len(items) != 3 {
+		t.Fatalf("expected three suppressed addresses, got %d %v", total, err)
+	}
+	if _, total, _ := svc.ListAddresses(ctx, string(models.EmailAddressComplained), "", 1, 20); total != 1 {
+		t.Fatalf("expected one complained address, got %d", total)
+	}
+
+	// 永久退信的地址跳过通知邮件
+	notifier := NewEmailNotificationService(db, stubEmailConfigService{}, nil).(*EmailNotificationService)
+	sent := 0
+	notifier.send = func(config *models.EmailConfig, to, subject, body string) error {
+		sent++
+		return nil
+	}
+	notification := &models.Notification{Type: models.NotificationTypeSystemAlert, Title: "alert", Content: "alert",
+		Channel: models.NotificationChannelEmail, RecipientID: customer.ID}
+	db.Create(notification)
+	if err := notifier.SendEmailNotification(ctx, notification); err != nil || sent != 0 || notification.DeliveryStatus != "skipped_suppressed" {
+		t.Fatalf("expected notification to be skipped: sent=%d status=%s err=%v", sent, notification.DeliveryStatus, err)
+	}
+
+	// 手动恢复需记录理由与操作人
+	bounced := lookup("bounced@example.com")
+	reenabled, err := svc.Reenable(ctx, bounced.ID, "customer fixed their mailbox", admin.ID)
+	if err != nil || reenabled.Status != models.EmailAddressOK || reenabled.ReenabledByID == nil || *reenabled.ReenabledByID != admin.ID {
+		t.Fatalf("reenable failed: %+v %v", reenabled, err)
+	}
+	if _, err := svc.Reenable(ctx, bounced.ID, "again please", admin.ID); !errors.Is(err, ErrEmailAddressNotSuppressed) {
+		t.Fatalf("expected reenabling an ok address to fail, got %v", err)
+	}
+	if err := svc.CheckSendable(ctx, "bounced@example.com"); err != nil {
+		t.Fatalf("expected reenabled address to be sendable, got %v", err)
+	}
+	detail, err := svc.GetAddress(ctx, bounced.ID)
+	if err != nil || len(detail.Events) != 2 || detail.Events[0].Type != models.EmailEventReenabled ||
+		detail.Events[0].FromStatus != models.EmailAddressHardBounce || detail.Events[1].MessageID != "m-1" {
+		t.Fatalf("unexpected address detail: %+v %v", detail, err)
+	}
+}
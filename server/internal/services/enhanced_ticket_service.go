@@ -149,35 +149,27 @@ func (s *EnhancedTicketService) UpdateTicketWithNotification(ctx context.Context
 	// 加载关联数据
 	s.db.Preload("CreatedBy").Preload("AssignedTo").Preload("Category").First(ticket, ticket.ID)
 
-	// 检查是否有重要字段变化
+	// ticket.updated（含字段变更）已由 UpdateTicket 发送，这里只补充解决/关闭事件
 	var eventType models.WebhookEventType
 	var title string
 	var description string
 
-	if oldTicket.Status != ticket.Status {
-		switch ticket.Status {
-		case models.TicketStatusResolved:
-			eventType = models.WebhookEventTicketResolved
-			title = fmt.Sprintf("工单解决: %s", ticket.Title)
-			description = fmt.Sprintf("工单状态已更新为: 已解决")
-		case models.TicketStatusClosed:
-			eventType = models.WebhookEventTicketClosed
-			title = fmt.Sprintf("工单关闭: %s", ticket.Title)
-			description = fmt.Sprintf("工单状态已更新为: 已关闭")
-		default:
-			eventType = models.WebhookEventTicketUpdated
-			title = fmt.Sprintf("工单更新: %s", ticket.Title)
-			description = fmt.Sprintf("工单状态从 %s 更新为 %s", oldTicket.Status, ticket.Status)
-		}
-	} else if oldTicket.Priority != ticket.Priority {
-		eventType = models.WebhookEventTicketUpdated
-		title = fmt.Sprintf("工单更新: %s", ticket.Title)
-		description = fmt.Sprintf("工单优先级从 %s 更新为 %s", oldTicket.Priority, ticket.Priority)
-	} else {
-		eventType = models.WebhookEventTicketUpdated
-		title = fmt.Sprintf("工单更新: %s", ticket.Title)
-		description = "工单信息已更新"
+	if oldTicket.Status == ticket.Status {
+		return ticket, nil
+	}
+	switch ticket.Status {
+	case models.TicketStatusResolved:
+		eventType = models.WebhookEventTicketResolved
+		title = fmt.Sprintf("工单解决: %s", ticket.Title)
+		description = fmt.Sprintf("工单状态已更新为: 已解决")
+	case models.TicketStatusClosed:
+		eventType = models.WebhookEventTicketClosed
+		title = fmt.Sprintf("工单关闭: %s", ticket.Title)
+		description = fmt.Sprintf("工单状态已更新为: 已关闭")
+	default:
+		return ticket, nil
 	}
+	changes := models.DiffTicketFields(&oldTicket, ticket)
 
 	// 异步发送通知
 	go func() {
@@ -201,6 +193,7 @@ func (s *EnhancedTicketService) UpdateTicketWithNotification(ctx context.Context
 			},
 			Timestamp: time.Now(),
 			UserID:    &userID,
+			Changes:   changes,
 		}
 
 		if err := s.notificationService.SendNotification(context.Background(), event); err != nil {
@@ -145,9 +145,11 @@ func (s *EscalationService) HandleSLAViolation(ctx context.Context, ticket *mode
 		}
 	}
 
-	// 更新SLA统计
-	if err := s.updateSLAStats(ctx, status.SLAConfig.ID, false); err != nil {
-		log.Printf("Failed to update SLA stats: %v", err)
+	// 更新SLA统计（仅由合同提供时限、没有对应配置时跳过）
+	if status.SLAConfig.ID != 0 {
+		if err := s.updateSLAStats(ctx, status.SLAConfig.ID, false); err != nil {
+			log.Printf("Failed to update SLA stats: %v", err)
+		}
 	}
 
 	// 记录违规日志
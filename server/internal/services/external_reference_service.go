@@ -0,0 +1,232 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrExternalReferenceNotFound 外部关联不存在
+	ErrExternalReferenceNotFound = errors.New("external reference not found")
+	// ErrExternalReferenceTicketNotFound 工单不存在
+	ErrExternalReferenceTicketNotFound = errors.New("ticket not found")
+	// ErrInvalidExternalReference 外部关联参数无效
+	ErrInvalidExternalReference = errors.New("invalid external reference")
+	// ErrExternalReferenceConflict 外部编号已关联到其他工单
+	ErrExternalReferenceConflict = errors.New("external reference already attached")
+)
+
+// ExternalReferenceConflictError 外部编号已关联的工单
+type ExternalReferenceConflictError struct {
+	TicketID uint
+}
+
+func (e *ExternalReferenceConflictError) Error() string {
+	return fmt.Sprintf("external reference already attached to ticket %d", e.TicketID)
+}
+
+// Is 使 errors.Is(err, ErrExternalReferenceConflict) 成立
+func (e *ExternalReferenceConflictError) Is(target error) bool {
+	return target == ErrExternalReferenceConflict
+}
+
+var externalReferenceSystemPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,49}$`)
+
+// ExternalReferenceService 工单外部关联服务
+type ExternalReferenceService struct {
+	db *gorm.DB
+}
+
+// NewExternalReferenceService 创建工单外部关联服务
+func NewExternalReferenceService(db *gorm.DB) *ExternalReferenceService {
+	return &ExternalReferenceService{db: db}
+}
+
+// List 获取工单的外部关联
+func (s *ExternalReferenceService) List(ctx context.Context, ticketID uint) ([]*models.ExternalReference, error) {
+	if err := s.ensureTicket(s.db.WithContext(ctx), ticketID); err != nil {
+		return nil, err
+	}
+	refs, err := s.ListByTickets(ctx, []uint{ticketID})
+	if err != nil {
+		return nil, err
+	}
+	if refs[ticketID] == nil {
+		return []*models.ExternalReference{}, nil
+	}
+	return refs[ticketID], nil
+}
+
+// ListByTickets 批量获取工单的外部关联，没有关联的工单不在结果中
+func (s *ExternalReferenceService) ListByTickets(ctx context.Context, ticketIDs []uint) (map[uint][]*models.ExternalReference, error) {
+	result := make(map[uint][]*models.ExternalReference)
+	if len(ticketIDs) == 0 {
+		return result, nil
+	}
+
+	var refs []*models.ExternalReference
+	if err := s.db.WithContext(ctx).
+		Where("ticket_id IN ?", ticketIDs).
+		Order("system ASC, id ASC").
+		Find(&refs).Error; err != nil {
+		return nil, fmt.Errorf("failed to list external references: %w", err)
+	}
+	for _, ref := range refs {
+		result[ref.TicketID] = append(result[ref.TicketID], ref)
+	}
+	return result, nil
+}
+
+// Attach 为工单关联外部记录。同一系统的同一编号已关联到该工单时更新链接；
+// 已关联到其他工单时返回 *ExternalReferenceConflictError，原工单已删除时改为关联到当前工单
+func (s *ExternalReferenceService) Attach(ctx context.Context, ticketID uint, req *models.ExternalReferenceRequest, userID uint) (*models.ExternalReference, error) {
+	system, key, link, err := normalizeExternalReference(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var ref models.ExternalReference
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := s.ensureTicket(tx, ticketID); err != nil {
+			return err
+		}
+
+		err := tx.Where("system = ? AND key = ?", system, key).First(&ref).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to check external reference: %w", err)
+		}
+		if err == nil && ref.TicketID != ticketID {
+			if err := s.ensureTicket(tx, ref.TicketID); err == nil {
+				return &ExternalReferenceConflictError{TicketID: ref.TicketID}
+			} else if !errors.Is(err, ErrExternalReferenceTicketNotFound) {
+				return err
+			}
+		}
+
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ref = models.ExternalReference{TicketID: ticketID, System: system, Key: key, URL: link, CreatedByID: userID}
+			if err := tx.Create(&ref).Error; err != nil {
+				return fmt.Errorf("failed to create external reference: %w", err)
+			}
+			return nil
+		}
+		ref.TicketID = ticketID
+		ref.URL = link
+		ref.CreatedByID = userID
+		if err := tx.Save(&ref).Error; err != nil {
+			return fmt.Errorf("failed to update external reference: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ref, nil
+}
+
+// Detach 解除工单的外部关联
+func (s *ExternalReferenceService) Detach(ctx context.Context, ticketID, refID uint) error {
+	result := s.db.WithContext(ctx).Where("ticket_id = ?", ticketID).Delete(&models.ExternalReference{}, refID)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete external reference: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrExternalReferenceNotFound
+	}
+	return nil
+}
+
+// FindTicketID 按外部系统和编号反查工单，关联的工单已删除时视为不存在
+func (s *ExternalReferenceService) FindTicketID(ctx context.Context, system, key string) (uint, error) {
+	system = strings.ToLower(strings.TrimSpace(system))
+	key = strings.TrimSpace(key)
+	if system == "" || key == "" {
+		return 0, fmt.Errorf("%w: system and key are required", ErrInvalidExternalReference)
+	}
+
+	var ref models.ExternalReference
+	if err := s.db.WithContext(ctx).Where("system = ? AND key = ?", system, key).First(&ref).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, ErrExternalReferenceNotFound
+		}
+		return 0, fmt.Errorf("failed to find external reference: %w", err)
+	}
+	if err := s.ensureTicket(s.db.WithContext(ctx), ref.TicketID); err != nil {
+		if errors.Is(err, ErrExternalReferenceTicketNotFound) {
+			return 0, ErrExternalReferenceNotFound
+		}
+		return 0, err
+	}
+	return ref.TicketID, nil
+}
+
+func (s *ExternalReferenceService) ensureTicket(db *gorm.DB, ticketID uint) error {
+	var ticket models.Ticket
+	if err := db.Select("id").First(&ticket, ticketID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrExternalReferenceTicketNotFound
+		}
+		return fmt.Errorf("failed to get ticket: %w", err)
+	}
+	return nil
+}
+
+// normalizeExternalReference 系统标识转为小写并校验，链接仅允许 http/https
+func normalizeExternalReference(req *models.ExternalReferenceRequest) (string, string, string, error) {
+	system := strings.ToLower(strings.TrimSpace(req.System))
+	key := strings.TrimSpace(req.Key)
+	link := strings.TrimSpace(req.URL)
+	if !externalReferenceSystemPattern.MatchString(system) {
+		return "", "", "", fmt.Errorf("%w: system must match %s", ErrInvalidExternalReference, externalReferenceSystemPattern.String())
+	}
+	if key == "" || len(key) > 255 {
+		return "", "", "", fmt.Errorf("%w: key must be 1-255 characters", ErrInvalidExternalReference)
+	}
+	if link != "" {
+		parsed, err := url.Parse(link)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return "", "", "", fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidExternalReference)
+		}
+	}
+	return system, key, link, nil
+}
+
+// attachTicketExternalReferences 在工单类 Webhook 事件数据中附带外部关联
+func attachTicketExternalReferences(ctx context.Context, db *gorm.DB, event *NotificationEvent) {
+	if db == nil || event.ResourceType != "ticket" || event.ResourceID == 0 {
+		return
+	}
+	if _, ok := event.Data["external_references"]; ok {
+		return
+	}
+
+	var refs []models.ExternalReference
+	if err := db.WithContext(ctx).
+		Where("ticket_id = ?", event.ResourceID).
+		Order("system ASC, id ASC").
+		Find(&refs).Error; err != nil {
+		return
+	}
+	items := make([]map[string]interface{}, 0, len(refs))
+	for _, ref := range refs {
+		item := map[string]interface{}{"system": ref.System, "key": ref.Key}
+		if ref.URL != "" {
+			item["url"] = ref.URL
+		}
+		items = append(items, item)
+	}
+
+	data := make(map[string]interface{}, len(event.Data)+1)
+	for k, v := range event.Data {
+		data[k] = v
+	}
+	data["external_references"] = items
+	event.Data = data
+}
@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestExternalReference_AttachLookupAndWebhookPayload(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:external_reference_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Team{}, &models.Ticket{}, &models.TicketComment{}, &models.TicketHistory{},
+		&models.SystemConfig{}, &models.ExternalReference{}, &models.SyncTombstone{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+	ctx := context.Background()
+
+	agent := models.User{Username: "ref-agent", Email: "ref-agent@example.com", PasswordHash: "x", Role: models.RoleAgent}
+	db.Create(&agent)
+	tickets := NewTicketService(db).(*TicketService)
+	create := func(title string) *models.Ticket {
+		ticket, err := tickets.CreateTicket(ctx, &models.TicketCreateRequest{Title: title, Type: models.TicketTypeRequest, Priority: models.TicketPriorityNormal}, agent.ID)
+		if err != nil {
+			t.Fatalf("failed to create ticket: %v", err)
+		}
+		return ticket
+	}
+	first, second := create("order missing"), create("refund")
+
+	svc := NewExternalReferenceService(db)
+	ref, err := svc.Attach(ctx, first.ID, &models.ExternalReferenceRequest{System: " CRM ", Key: "ACC-1001", URL: "https://crm.example.com/accounts/1001"}, agent.ID)
+	if err != nil || ref.System != "crm" {
+		t.Fatalf("attach failed: %+v, %v", ref, err)
+	}
+	if _, err := svc.Attach(ctx, first.ID, &models.ExternalReferenceRequest{System: "shop", Key: "SO-42"}, agent.ID); err != nil {
+		t.Fatalf("attach order failed: %v", err)
+	}
+
+	// 同一编号重复关联到本工单时更新链接，关联到其他工单时冲突
+	updated, err := svc.Attach(ctx, first.ID, &models.ExternalReferenceRequest{System: "crm", Key: "ACC-1001", URL: "https://crm.example.com/a/1001"}, agent.ID)
+	if err != nil || updated.ID != ref.ID || updated.URL != "https://crm.example.com/a/1001" {
+		t.Fatalf("expected reattach to update url, got %+v, %v", updated, err)
+	}
+	var conflictErr *ExternalReferenceConflictError
+	if _, err := svc.Attach(ctx, second.ID, &models.ExternalReferenceRequest{System: "crm", Key: "ACC-1001"}, agent.ID); !errors.As(err, &conflictErr) || conflictErr.TicketID != first.ID {
+		t.Fatalf("expected conflict with ticket %d, got %v", first.ID, err)
+	}
+	for _, bad := range []models.ExternalReferenceRequest{{System: "crm system", Key: "1"}, {System: "crm", Key: " "}, {System: "crm", Key: "1", URL: "javascript:alert(1)"}} {
+		if _, err := svc.Attach(ctx, first.ID, &bad, agent.ID); !errors.Is(err, ErrInvalidExternalReference) {
+			t.Fatalf("expected %+v to be rejected, got %v", bad, err)
+		}
+	}
+
+	if id, err := svc.FindTicketID(ctx, "CRM", "ACC-1001"); err != nil || id != first.ID {
+		t.Fatalf("expected lookup to find ticket %d, got %d, %v", first.ID, id, err)
+	}
+	if _, err := svc.FindTicketID(ctx, "crm", "ACC-9999"); !errors.Is(err, ErrExternalReferenceNotFound) {
+		t.Fatalf("expected unknown key to be not found, got %v", err)
+	}
+
+	event := &NotificationEvent{Type: models.WebhookEventTicketUpdated, ResourceID: first.ID, ResourceType: "ticket", Data: map[string]interface{}{"title": first.Title}}
+	attachTicketExternalReferences(ctx, db, event)
+	payload, ok := event.Data["external_references"].([]map[string]interface{})
+	if !ok || len(payload) != 2 || payload[0]["system"] != "crm" || payload[0]["url"] != "https://crm.example.com/a/1001" || payload[1]["key"] != "SO-42" {
+		t.Fatalf("unexpected webhook references %+v", event.Data)
+	}
+
+	// 删除工单后编号释放，可关联到其他工单
+	refs, _ := svc.List(ctx, first.ID)
+	if err := svc.Detach(ctx, first.ID, refs[1].ID); err != nil {
+		t.Fatalf("detach failed: %v", err)
+	}
+	if err := svc.Detach(ctx, second.ID, refs[0].ID); !errors.Is(err, ErrExternalReferenceNotFound) {
+		t.Fatalf("expected detach from another ticket to fail, got %v", err)
+	}
+	if err := tickets.DeleteTicket(ctx, first.ID, agent.ID, string(models.RoleAgent)); err != nil {
+		t.Fatalf("delete ticket failed: %v", err)
+	}
+	if _, err := svc.Attach(ctx, second.ID, &models.ExternalReferenceRequest{System: "crm", Key: "ACC-1001"}, agent.ID); err != nil {
+		t.Fatalf("expected released key to attach to another ticket, got %v", err)
+	}
+	if id, _ := svc.FindTicketID(ctx, "crm", "ACC-1001"); id != second.ID {
+		t.Fatalf("expected lookup to follow the key to ticket %d, got %d", second.ID, id)
+	}
+}
@@ -0,0 +1,112 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ErrInvalidStorageKey 存储键非法（为空、绝对路径或包含 ..）
+var ErrInvalidStorageKey = errors.New("invalid storage key")
+
+// FileStorage 文件存储后端，键为以 / 分隔的相对路径，如 avatars/1/abc_128.png
+type FileStorage interface {
+	Put(ctx context.Context, key string, r io.Reader, contentType string) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	URL(key string) string
+	Type() string // 与 TicketAttachment.StorageType 一致：local, s3, gcs, azure
+}
+
+// LocalFileStorage 本地磁盘存储，文件通过 urlPrefix 下的静态路由访问
+type LocalFileStorage struct {
+	baseDir   string
+	urlPrefix string
+}
+
+// NewLocalFileStorage 创建本地磁盘存储
+func NewLocalFileStorage(baseDir, urlPrefix string) *LocalFileStorage {
+	return &LocalFileStorage{
+		baseDir:   baseDir,
+		urlPrefix: strings.TrimRight(urlPrefix, "/"),
+	}
+}
+
+// Type 存储类型
+func (s *LocalFileStorage) Type() string { return "local" }
+
+// URL 获取文件访问地址
+func (s *LocalFileStorage) URL(key string) string {
+	return s.urlPrefix + "/" + strings.TrimLeft(key, "/")
+}
+
+// Put 写入文件，先写临时文件再重命名，避免读到写了一半的文件
+func (s *LocalFileStorage) Put(ctx context.Context, key string, r io.Reader, contentType string) error {
+	fullPath, err := s.resolve(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+		return fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(fullPath), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName)
+
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close file: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpName, fullPath); err != nil {
+		return fmt.Errorf("failed to store file: %w", err)
+	}
+	return nil
+}
+
+// Open 打开文件读取
+func (s *LocalFileStorage) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	fullPath, err := s.resolve(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(fullPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	return file, nil
+}
+
+// Delete 删除文件，文件不存在时视为成功
+func (s *LocalFileStorage) Delete(ctx context.Context, key string) error {
+	fullPath, err := s.resolve(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(fullPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+	return nil
+}
+
+func (s *LocalFileStorage) resolve(key string) (string, error) {
+	cleaned := path.Clean("/" + key)
+	if key == "" || strings.Contains(key, "..") || cleaned == "/" {
+		return "", ErrInvalidStorageKey
+	}
+	return filepath.Join(s.baseDir, filepath.FromSlash(strings.TrimPrefix(cleaned, "/"))), nil
+}
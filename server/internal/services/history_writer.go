@@ -0,0 +1,297 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// historyInsertBatchSize 单条多行 INSERT 写入的历史记录数
+	historyInsertBatchSize = 500
+	// historyDedupeWindow 与工单最近一条历史完全相同且在此时间内的记录视为重复
+	historyDedupeWindow = time.Minute
+	// historyQueueFlushInterval 异步历史记录的写入间隔
+	historyQueueFlushInterval = 2 * time.Second
+	// historyQueueMaxBuffer 异步缓冲上限，写满时改为同步写入，不丢弃记录
+	historyQueueMaxBuffer = 20000
+)
+
+// sameHistoryEntry 判断两条历史记录内容是否相同（不比较时间）
+func sameHistoryEntry(a, b *models.TicketHistory) bool {
+	return a.TicketID == b.TicketID && a.Action == b.Action && a.FieldName == b.FieldName &&
+		a.OldValue == b.OldValue && a.NewValue == b.NewValue && a.Description == b.Description &&
+		getAssigneeValue(a.UserID) == getAssigneeValue(b.UserID)
+}
+
+// isCriticalHistory 重要或用户操作产生的历史必须与业务数据在同一事务中写入，
+// 其余系统/自动化产生的历史可以异步写入
+func isCriticalHistory(h *models.TicketHistory) bool {
+	return h.IsImportant || (!h.IsSystem && !h.IsAutomated)
+}
+
+// HistoryBatch 收集一个事务内产生的工单历史，最后用多行 INSERT 一次写入，
+// 并去除同一工单连续重复的记录
+type HistoryBatch struct {
+	entries []*models.TicketHistory
+	last    map[uint]*models.TicketHistory
+	Skipped int
+}
+
+// NewHistoryBatch 创建历史记录批次
+func NewHistoryBatch() *HistoryBatch {
+	return &HistoryBatch{last: make(map[uint]*models.TicketHistory)}
+}
+
+// Add 加入一条历史记录，与该工单上一条记录相同时忽略并返回 false
+func (b *HistoryBatch) Add(history *models.TicketHistory) bool {
+	if previous, ok := b.last[history.TicketID]; ok && sameHistoryEntry(previous, history) {
+		b.Skipped++
+		return false
+	}
+	b.last[history.TicketID] = history
+	b.entries = append(b.entries, history)
+	return true
+}
+
+// Len 待写入的记录数
+func (b *HistoryBatch) Len() int {
+	return len(b.entries)
+}
+
+// Flush 在 tx 中写入批次内的记录；每个工单的第一条记录与库中最近一条相同时也会被去除
+func (b *HistoryBatch) Flush(tx *gorm.DB) error {
+	if len(b.entries) == 0 {
+		return nil
+	}
+
+	entries, err := dedupeAgainstLatest(tx, b.entries)
+	if err != nil {
+		return err
+	}
+	b.Skipped += len(b.entries) - len(entries)
+	b.entries = nil
+	b.last = make(map[uint]*models.TicketHistory)
+	if len(entries) == 0 {
+		return nil
+	}
+	if err := tx.CreateInBatches(entries, historyInsertBatchSize).Error; err != nil {
+		return fmt.Errorf("failed to create history records: %w", err)
+	}
+	return nil
+}
+
+// dedupeAgainstLatest 去除与工单最近一条已保存历史相同的首条记录，一次查询所有工单
+func dedupeAgainstLatest(tx *gorm.DB, entries []*models.TicketHistory) ([]*models.TicketHistory, error) {
+	ticketIDs := make([]uint, 0, len(entries))
+	seen := make(map[uint]bool)
+	for _, entry := range entries {
+		if !seen[entry.TicketID] {
+			seen[entry.TicketID] = true
+			ticketIDs = append(ticketIDs, entry.TicketID)
+		}
+	}
+
+	var latest []*models.TicketHistory
+	err := tx.Model(&models.TicketHistory{}).
+		Where("id IN (?)", tx.Model(&models.TicketHistory{}).Select("MAX(id)").
+			Where("ticket_id IN ? AND created_at > ?", ticketIDs, time.Now().Add(-historyDedupeWindow)).
+			Group("ticket_id")).
+		Find(&latest).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to load latest history records: %w", err)
+	}
+	if len(latest) == 0 {
+		return entries, nil
+	}
+
+	latestByTicket := make(map[uint]*models.TicketHistory, len(latest))
+	for _, history := range latest {
+		latestByTicket[history.TicketID] = history
+	}
+	filtered := entries[:0:0]
+	first := make(map[uint]bool)
+	for _, entry := range entries {
+		if !first[entry.TicketID] {
+			first[entry.TicketID] = true
+			if previous, ok := latestByTicket[entry.TicketID]; ok && sameHistoryEntry(previous, entry) {
+				continue
+			}
+		}
+		filtered = append(filtered, entry)
+	}
+	return filtered, nil
+}
+
+// recordHistory 写入历史记录：关键记录在 tx 中批量写入，非关键记录交给异步写入器；
+// writer 为 nil 时全部同步写入
+func recordHistory(tx *gorm.DB, writer *HistoryWriter, histories ...*models.TicketHistory) error {
+	batch := NewHistoryBatch()
+	for _, history := range histories {
+		if isCriticalHistory(history) || !writer.Enqueue(history) {
+			batch.Add(history)
+		}
+	}
+	return batch.Flush(tx)
+}
+
+// HistoryWriterStats 异步历史写入统计
+type HistoryWriterStats struct {
+	Buffered  int        `json:"buffered"`
+	Written   int64      `json:"written"`
+	Skipped   int64      `json:"skipped"`
+	Failures  int64      `json:"failures"`
+	LastError string     `json:"last_error,omitempty"`
+	LastFlush *time.Time `json:"last_flush,omitempty"`
+}
+
+// HistoryWriter 非关键工单历史的异步写入器
+// 记录写入内存缓冲后立即返回，后台定时或缓冲达到批大小时批量写入；失败时保留缓冲并退避重试，
+// 缓冲写满时 Enqueue 返回 false，由调用方同步写入。
+type HistoryWriter struct {
+	db *gorm.DB
+
+	mu     sync.Mutex
+	buffer []*models.TicketHistory
+	stats  HistoryWriterStats
+
+	wake chan struct{}
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// NewHistoryWriter 创建异步历史写入器
+func NewHistoryWriter(db *gorm.DB) *HistoryWriter {
+	return &HistoryWriter{
+		db:   db,
+		wake: make(chan struct{}, 1),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+}
+
+// Enqueue 加入异步缓冲，写入器已停止或缓冲已满时返回 false
+func (w *HistoryWriter) Enqueue(history *models.TicketHistory) bool {
+	if w == nil {
+		return false
+	}
+	select {
+	case <-w.stop:
+		return false
+	default:
+	}
+	w.mu.Lock()
+	if len(w.buffer) >= historyQueueMaxBuffer {
+		w.mu.Unlock()
+		return false
+	}
+	if history.CreatedAt.IsZero() {
+		history.CreatedAt = time.Now()
+	}
+	w.buffer = append(w.buffer, history)
+	full := len(w.buffer) >= historyInsertBatchSize
+	w.mu.Unlock()
+
+	if full {
+		select {
+		case w.wake <- struct{}{}:
+		default:
+		}
+	}
+	return true
+}
+
+// Start 启动后台写入
+func (w *HistoryWriter) Start() {
+	go w.run()
+}
+
+// Stop 停止后台写入，并写入剩余记录
+func (w *HistoryWriter) Stop() {
+	w.once.Do(func() {
+		close(w.stop)
+		<-w.done
+	})
+}
+
+func (w *HistoryWriter) run() {
+	defer close(w.done)
+
+	backoff := time.Duration(0)
+	for {
+		wait := historyQueueFlushInterval
+		if backoff > 0 {
+			wait = backoff
+		}
+
+		select {
+		case <-w.stop:
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			if err := w.Flush(ctx); err != nil {
+				log.Printf("Failed to flush ticket history on shutdown: %v", err)
+			}
+			cancel()
+			return
+		case <-w.wake:
+			if backoff > 0 {
+				continue
+			}
+		case <-time.After(wait):
+		}
+
+		if err := w.Flush(context.Background()); err != nil {
+			backoff = nextAuditBackoff(backoff)
+			continue
+		}
+		backoff = 0
+	}
+}
+
+// Flush 写入缓冲中的全部记录，失败时放回缓冲
+func (w *HistoryWriter) Flush(ctx context.Context) error {
+	w.mu.Lock()
+	pending := w.buffer
+	w.buffer = nil
+	w.mu.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+
+	batch := NewHistoryBatch()
+	for _, history := range pending {
+		batch.Add(history)
+	}
+	err := w.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return batch.Flush(tx)
+	})
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	now := time.Now()
+	if err != nil {
+		w.buffer = append(pending, w.buffer...)
+		w.stats.Failures++
+		w.stats.LastError = err.Error()
+		log.Printf("Failed to write %d queued ticket history records: %v", len(pending), err)
+		return err
+	}
+	w.stats.Written += int64(len(pending) - batch.Skipped)
+	w.stats.Skipped += int64(batch.Skipped)
+	w.stats.LastFlush = &now
+	return nil
+}
+
+// Stats 返回写入统计
+func (w *HistoryWriter) Stats() HistoryWriterStats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	stats := w.stats
+	stats.Buffered = len(w.buffer)
+	return stats
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestHistoryBatch_DedupesAndWritesAsync(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:history_writer?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Ticket{}, &models.TicketHistory{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+
+	entry := func(ticketID uint, value string) *models.TicketHistory {
+		return &models.TicketHistory{TicketID: ticketID, Action: models.HistoryActionStatusChange,
+			FieldName: "status", NewValue: value, Description: "状态变更为 " + value, IsSystem: true, IsAutomated: true}
+	}
+	count := func() int64 {
+		var n int64
+		db.Model(&models.TicketHistory{}).Count(&n)
+		return n
+	}
+
+	batch := NewHistoryBatch()
+	batch.Add(entry(1, "open"))
+	batch.Add(entry(1, "open")) // 连续重复
+	batch.Add(entry(2, "open"))
+	batch.Add(entry(1, "closed"))
+	if err := batch.Flush(db); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+	if count() != 3 || batch.Skipped != 1 {
+		t.Fatalf("expected 3 records and 1 skipped, got %d records, %d skipped", count(), batch.Skipped)
+	}
+
+	// 与库中最近一条相同的记录同样去除
+	batch = NewHistoryBatch()
+	batch.Add(entry(1, "closed"))
+	batch.Add(entry(2, "pending"))
+	if err := batch.Flush(db); err != nil {
+		t.Fatalf("second flush failed: %v", err)
+	}
+	if count() != 4 {
+		t.Fatalf("expected duplicate of latest persisted entry to be skipped, got %d records", count())
+	}
+
+	// 没有写入器时非关键记录同步写入
+	if err := recordHistory(db, nil, entry(3, "open")); err != nil || count() != 5 {
+		t.Fatalf("expected synchronous write without writer, got %d records (%v)", count(), err)
+	}
+
+	writer := NewHistoryWriter(db)
+
+	important := entry(3, "closed")
+	important.IsImportant = true
+	if err := recordHistory(db, writer, entry(4, "open"), entry(4, "open"), important); err != nil {
+		t.Fatalf("record history failed: %v", err)
+	}
+	if count() != 6 {
+		t.Fatalf("expected only the important record to be written synchronously, got %d records", count())
+	}
+	if err := writer.Flush(context.Background()); err != nil {
+		t.Fatalf("writer flush failed: %v", err)
+	}
+	stats := writer.Stats()
+	if count() != 7 || stats.Written != 1 || stats.Skipped != 1 || stats.Buffered != 0 {
+		t.Fatalf("unexpected async write result: %d records, stats %+v", count(), stats)
+	}
+
+	writer.Start()
+	writer.Stop()
+	if writer.Enqueue(entry(5, "open")) {
+		t.Fatalf("expected stopped writer to reject new records")
+	}
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+// KeySecurityHTTPPolicy HTTP安全策略（CORS及安全响应头）配置键
+const KeySecurityHTTPPolicy = "security.http_policy"
+
+// HTTPSecurityService HTTP安全策略服务
+type HTTPSecurityService struct {
+	db       *gorm.DB
+	defaults *models.HTTPSecurityPolicy
+}
+
+// NewHTTPSecurityService 创建HTTP安全策略服务，defaults 为数据库中无配置时使用的策略（通常来自环境变量）
+func NewHTTPSecurityService(db *gorm.DB, defaults *models.HTTPSecurityPolicy) *HTTPSecurityService {
+	if defaults == nil {
+		defaults = models.GetDefaultHTTPSecurityPolicy()
+	}
+	return &HTTPSecurityService{
+		db:       db,
+		defaults: defaults,
+	}
+}
+
+// GetPolicy 获取当前HTTP安全策略
+func (s *HTTPSecurityService) GetPolicy(ctx context.Context) (*models.HTTPSecurityPolicy, error) {
+	var config models.SystemConfig
+	err := s.db.WithContext(ctx).
+		Where("key = ? AND is_active = ?", KeySecurityHTTPPolicy, true).
+		First(&config).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return s.defaultPolicy(), nil
+		}
+		return nil, fmt.Errorf("failed to get http security policy: %w", err)
+	}
+
+	policy := s.defaultPolicy()
+	if err := config.GetJSONValue(policy); err != nil {
+		log.Printf("Warning: failed to parse http security policy, using defaults: %v", err)
+		return s.defaultPolicy(), nil
+	}
+	if err := policy.Validate(); err != nil {
+		log.Printf("Warning: stored http security policy is invalid, using defaults: %v", err)
+		return s.defaultPolicy(), nil
+	}
+
+	return policy, nil
+}
+
+// SetPolicy 保存HTTP安全策略
+func (s *HTTPSecurityService) SetPolicy(ctx context.Context, policy *models.HTTPSecurityPolicy, userID uint) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	var existing models.SystemConfig
+	err := s.db.WithContext(ctx).Where("key = ?", KeySecurityHTTPPolicy).First(&existing).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to check existing policy: %w", err)
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		config := models.SystemConfig{
+			Key:         KeySecurityHTTPPolicy,
+			Category:    CategorySecurity,
+			Group:       "http",
+			Description: "CORS及安全响应头策略",
+			IsActive:    true,
+			UpdatedBy:   &userID,
+		}
+		if err := config.SetValue(policy); err != nil {
+			return fmt.Errorf("failed to set policy value: %w", err)
+		}
+		if err := s.db.WithContext(ctx).Create(&config).Error; err != nil {
+			return fmt.Errorf("failed to create policy: %w", err)
+		}
+		return nil
+	}
+
+	if err := existing.SetValue(policy); err != nil {
+		return fmt.Errorf("failed to set policy value: %w", err)
+	}
+	existing.UpdatedBy = &userID
+	existing.Version++
+
+	if err := s.db.WithContext(ctx).Save(&existing).Error; err != nil {
+		return fmt.Errorf("failed to update policy: %w", err)
+	}
+
+	return nil
+}
+
+// defaultPolicy 返回默认策略的副本，避免调用方修改共享的默认值
+func (s *HTTPSecurityService) defaultPolicy() *models.HTTPSecurityPolicy {
+	policy := *s.defaults
+	policy.AllowedOrigins = append([]string(nil), s.defaults.AllowedOrigins...)
+	policy.AllowedMethods = append([]string(nil), s.defaults.AllowedMethods...)
+	policy.AllowedHeaders = append([]string(nil), s.defaults.AllowedHeaders...)
+	policy.ExposeHeaders = append([]string(nil), s.defaults.ExposeHeaders...)
+	return &policy
+}
@@ -0,0 +1,1014 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	inboxMaxSnooze    = 30 * 24 * time.Hour
+	inboxPreviewRunes = 200
+	inboxMaxWindow    = 1000
+	// inboxDomainBlockThreshold 同一域名被标记垃圾邮件达到该次数后自动拉黑整个域名
+	inboxDomainBlockThreshold = 3
+)
+
+// publicMailDomains 公共邮箱域名只拉黑具体发件人，不自动拉黑域名
+var publicMailDomains = map[string]bool{
+	"gmail.com": true, "outlook.com": true, "hotmail.com": true, "live.com": true,
+	"yahoo.com": true, "icloud.com": true, "qq.com": true, "163.com": true,
+	"126.com": true, "foxmail.com": true, "sina.com": true, "aliyun.com": true,
+}
+
+var (
+	// ErrInboundEmailNotFound 收件不存在
+	ErrInboundEmailNotFound = errors.New("inbound email not found")
+	// ErrInboxItemProcessed 收件已被其他人处理
+	ErrInboxItemProcessed = errors.New("inbox item already processed")
+	// ErrInboxTicketNotTriage 工单不是待分拣的邮件工单
+	ErrInboxTicketNotTriage = errors.New("ticket is not an email ticket awaiting triage")
+	// ErrInvalidSnoozeTime 暂缓时间无效
+	ErrInvalidSnoozeTime = errors.New("snooze time must be in the future and within 30 days")
+	// ErrInboxAssigneeRequired 分配时需要指定处理人或团队
+	ErrInboxAssigneeRequired = errors.New("assigned_to_id or assigned_team_id is required")
+	// ErrInvalidBlocklistPattern 黑名单条目格式无效
+	ErrInvalidBlocklistPattern = errors.New("pattern must be an email address or a domain")
+	// ErrInboxTicketNotFound 工单不存在
+	ErrInboxTicketNotFound = errors.New("ticket not found")
+)
+
+// InboxService 邮件渠道共享收件箱：待分拣邮件和新建邮件工单的统一视图及分拣操作
+type InboxService struct {
+	db            *gorm.DB
+	ticketService *TicketService
+	spamService   *IntakeSpamService
+	authService   *EmailAuthService
+}
+
+// NewInboxService 创建收件箱服务
+func NewInboxService(db *gorm.DB) *InboxService {
+	return &InboxService{
+		db:            db,
+		ticketService: NewTicketService(db).(*TicketService),
+		spamService:   NewIntakeSpamService(db),
+		authService:   NewEmailAuthService(db),
+	}
+}
+
+// AuthService 返回收件认证服务，供管理员维护认证策略
+func (s *InboxService) AuthService() *EmailAuthService {
+	return s.authService
+}
+
+// InboxListOptions 收件箱查询参数
+type InboxListOptions struct {
+	Kind           string // email / ticket，为空时两者都返回
+	Search         string
+	IncludeSnoozed bool
+	Page           int
+	PageSize       int
+}
+
+// List 按收到时间倒序返回收件箱条目
+func (s *InboxService) List(ctx context.Context, opts InboxListOptions) ([]*models.InboxItem, int64, error) {
+	if opts.Page < 1 {
+		opts.Page = 1
+	}
+	if opts.PageSize < 1 || opts.PageSize > 100 {
+		opts.PageSize = 20
+	}
+	// 两个来源各取前 page*pageSize 条再合并排序，保证跨来源分页结果正确
+	window := opts.Page * opts.PageSize
+	if window > inboxMaxWindow {
+		window = inboxMaxWindow
+	}
+	now := time.Now()
+	search := strings.ToLower(strings.TrimSpace(opts.Search))
+
+	var items []*models.InboxItem
+	var total int64
+
+	if opts.Kind == "" || opts.Kind == "email" {
+		query := s.db.WithContext(ctx).Model(&models.InboundEmail{}).
+			Where("status = ?", models.InboundEmailStatusPending)
+		if !opts.IncludeSnoozed {
+			query = query.Where("snoozed_until IS NULL OR snoozed_until <= ?", now)
+		}
+		if search != "" {
+			like := "%" + search + "%"
+			query = query.Where("lower(subject) LIKE ? OR lower(from_address) LIKE ?", like, like)
+		}
+
+		var count int64
+		if err := query.Count(&count).Error; err != nil {
+			return nil, 0, fmt.Errorf("failed to count inbound emails: %w", err)
+		}
+		var emails []models.InboundEmail
+		if err := query.Order("received_at DESC").Limit(window).Find(&emails).Error; err != nil {
+			return nil, 0, fmt.Errorf("failed to get inbound emails: %w", err)
+		}
+		total += count
+		for i := range emails {
+			e := &emails[i]
+			item := &models.InboxItem{
+				Kind:         "email",
+				ID:           e.ID,
+				Subject:      e.Subject,
+				FromAddress:  e.FromAddress,
+				FromName:     e.FromName,
+				Preview:      inboxPreview(e.Body),
+				ReceivedAt:   e.ReceivedAt,
+				SnoozedUntil: e.SnoozedUntil,
+			}
+			if e.Auth.Verdict != "" {
+				item.Authentication = &e.Auth
+			}
+			items = append(items, item)
+		}
+	}
+
+	if opts.Kind == "" || opts.Kind == "ticket" {
+		query := s.triageTickets(ctx)
+		if !opts.IncludeSnoozed {
+			query = query.Where("triage_snoozed_until IS NULL OR triage_snoozed_until <= ?", now)
+		}
+		if search != "" {
+			like := "%" + search + "%"
+			query = query.Where("lower(title) LIKE ? OR lower(customer_email) LIKE ?", like, like)
+		}
+
+		var count int64
+		if err := query.Count(&count).Error; err != nil {
+			return nil, 0, fmt.Errorf("failed to count triage tickets: %w", err)
+		}
+		var tickets []models.Ticket
+		if err := query.Order("created_at DESC").Limit(window).Find(&tickets).Error; err != nil {
+			return nil, 0, fmt.Errorf("failed to get triage tickets: %w", err)
+		}
+		total += count
+		for i := range tickets {
+			t := &tickets[i]
+			items = append(items, &models.InboxItem{
+				Kind:         "ticket",
+				ID:           t.ID,
+				Subject:      t.Title,
+				FromAddress:  t.CustomerEmail,
+				FromName:     t.CustomerName,
+				Preview:      inboxPreview(t.Description),
+				ReceivedAt:   t.CreatedAt,
+				SnoozedUntil: t.TriageSnoozedUntil,
+				TicketNumber: t.TicketNumber,
+				Priority:     t.Priority,
+				TeamID:       t.AssignedTeamID,
+			})
+		}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].ReceivedAt.After(items[j].ReceivedAt)
+	})
+	start := (opts.Page - 1) * opts.PageSize
+	if start >= len(items) {
+		return []*models.InboxItem{}, total, nil
+	}
+	end := start + opts.PageSize
+	if end > len(items) {
+		end = len(items)
+	}
+	items = items[start:end]
+
+	// 标注发件人可信度，客服据此识别冒用客户地址的邮件
+	addresses := make([]string, 0, len(items))
+	for _, item := range items {
+		addresses = append(addresses, item.FromAddress)
+	}
+	trust, err := s.authService.SenderTrust(ctx, addresses)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, item := range items {
+		if sender := trust[strings.ToLower(item.FromAddress)]; sender != nil {
+			item.SenderTrust = sender.Level
+		}
+	}
+	return items, total, nil
+}
+
+// triageTickets 待分拣的邮件工单：来源为邮件、仍为 open 且未分配处理人和团队
+func (s *InboxService) triageTickets(ctx context.Context) *gorm.DB {
+	return s.db.WithContext(ctx).Model(&models.Ticket{}).
+		Where("deleted_at IS NULL AND source = ? AND status = ?", models.TicketSourceEmail, models.TicketStatusOpen).
+		Where("assigned_to_id IS NULL AND assigned_team_id IS NULL")
+}
+
+// Ingest 收件入库，相同 Message-ID 只保存一次，黑名单发件人直接标记为垃圾邮件；
+// SPF/DKIM/DMARC 认证失败的邮件按认证策略拒收或进入隔离区；
+// 垃圾评分达到阈值或发件人超过限流的邮件进入隔离区，不出现在收件箱中；
+// 回复已有工单邮件会话（In-Reply-To/References 命中）的邮件直接追加为该工单的评论
+func (s *InboxService) Ingest(ctx context.Context, req *models.InboundEmailCreateRequest) (*models.InboundEmail, error) {
+	messageID := models.NormalizeMessageID(req.MessageID)
+	if messageID != "" {
+		var existing models.InboundEmail
+		err := s.db.WithContext(ctx).Where("message_id = ?", messageID).First(&existing).Error
+		if err == nil {
+			return &existing, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("failed to check duplicate email: %w", err)
+		}
+	}
+
+	from := strings.ToLower(strings.TrimSpace(req.From))
+	receivedAt := time.Now()
+	if req.ReceivedAt != nil {
+		receivedAt = *req.ReceivedAt
+	}
+
+	// 会话标识取 References 中最早的 Message-ID，没有时依次取 In-Reply-To 和本邮件的 Message-ID
+	references := models.ParseMessageIDs(req.References)
+	var inReplyTo string
+	if ids := models.ParseMessageIDs(req.InReplyTo); len(ids) > 0 {
+		inReplyTo = ids[0]
+	}
+	threadID := messageID
+	if len(references) > 0 {
+		threadID = references[0]
+	} else if inReplyTo != "" {
+		threadID = inReplyTo
+	}
+
+	email := &models.InboundEmail{
+		MessageID:   messageID,
+		InReplyTo:   inReplyTo,
+		References:  strings.Join(references, " "),
+		ThreadID:    threadID,
+		FromAddress: from,
+		FromName:    req.FromName,
+		ToAddress:   req.To,
+		Subject:     req.Subject,
+		Body:        req.Body,
+		ReceivedAt:  receivedAt,
+		Status:      models.InboundEmailStatusPending,
+	}
+
+	blocked, err := s.isBlocked(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	if blocked {
+		email.Status = models.InboundEmailStatusSpam
+		if err := s.db.WithContext(ctx).Create(email).Error; err != nil {
+			return nil, fmt.Errorf("failed to save inbound email: %w", err)
+		}
+		return email, nil
+	}
+
+	auth, err := s.authService.Authenticate(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if auth != nil {
+		email.Auth = *auth
+	}
+	if email.Auth.Action == models.EmailAuthActionReject {
+		email.Status = models.InboundEmailStatusRejected
+		if err := s.db.WithContext(ctx).Create(email).Error; err != nil {
+			return nil, fmt.Errorf("failed to save inbound email: %w", err)
+		}
+		return email, nil
+	}
+
+	verdict, err := s.spamService.Screen(ctx, &IntakeSubmission{
+		Channel: models.TicketSourceEmail,
+		Sender:  from,
+		Subject: req.Subject,
+		Body:    req.Body,
+	})
+	if err != nil {
+		return nil, err
+	}
+	email.SpamScore = verdict.Score
+	if email.Auth.Action == models.EmailAuthActionQuarantine {
+		verdict.Reasons = append(verdict.Reasons, "email authentication failed: "+email.Auth.Summary())
+	}
+	quarantine := verdict.Quarantine || verdict.Throttled || email.Auth.Action == models.EmailAuthActionQuarantine
+	if quarantine {
+		email.Status = models.InboundEmailStatusQuarantined
+	}
+
+	var threadTicket *models.Ticket
+	if !quarantine {
+		if threadTicket, err = s.findThreadTicket(ctx, inReplyTo, references); err != nil {
+			return nil, err
+		}
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if threadTicket != nil {
+			now := time.Now()
+			email.Status = models.InboundEmailStatusMerged
+			email.TicketID = &threadTicket.ID
+			email.ProcessedAt = &now
+		}
+		if err := tx.Create(email).Error; err != nil {
+			return fmt.Errorf("failed to save inbound email: %w", err)
+		}
+		if quarantine {
+			return s.spamService.QuarantineEmail(tx, email, verdict)
+		}
+		if threadTicket == nil {
+			return nil
+		}
+		if err := s.appendEmailToTicket(tx, email, threadTicket, nil, "客户回复邮件已自动追加到工单"); err != nil {
+			return err
+		}
+		return s.linkThread(tx, email, threadTicket.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return email, nil
+}
+
+// findThreadTicket 按 In-Reply-To 和 References（从近到远）查找邮件会话所属的工单，
+// 同时匹配收件映射和系统发出邮件的 Message-ID
+func (s *InboxService) findThreadTicket(ctx context.Context, inReplyTo string, references []string) (*models.Ticket, error) {
+	var candidates []string
+	if inReplyTo != "" {
+		candidates = append(candidates, inReplyTo)
+	}
+	for i := len(references) - 1; i >= 0; i-- {
+		if references[i] != inReplyTo {
+			candidates = append(candidates, references[i])
+		}
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	ticketByMessage := map[string]uint{}
+	var mappings []models.EmailThreadMessage
+	if err := s.db.WithContext(ctx).Where("message_id IN ?", candidates).Find(&mappings).Error; err != nil {
+		return nil, fmt.Errorf("failed to look up email thread: %w", err)
+	}
+	for _, m := range mappings {
+		ticketByMessage[m.MessageID] = m.TicketID
+	}
+	var sent []models.EmailLog
+	if err := s.db.WithContext(ctx).Select("message_id", "ticket_id").
+		Where("message_id IN ? AND ticket_id IS NOT NULL", candidates).Find(&sent).Error; err != nil {
+		return nil, fmt.Errorf("failed to look up sent emails: %w", err)
+	}
+	for _, sentEmail := range sent {
+		if _, ok := ticketByMessage[sentEmail.MessageID]; !ok {
+			ticketByMessage[sentEmail.MessageID] = *sentEmail.TicketID
+		}
+	}
+
+	for _, candidate := range candidates {
+		ticketID, ok := ticketByMessage[candidate]
+		if !ok {
+			continue
+		}
+		var ticket models.Ticket
+		err := s.db.WithContext(ctx).Select("id", "created_by_id").Where("deleted_at IS NULL").First(&ticket, ticketID).Error
+		if err == nil {
+			return &ticket, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("failed to get thread ticket: %w", err)
+		}
+	}
+	return nil, nil
+}
+
+// linkThread 记录邮件及其会话首封邮件的 Message-ID 与工单的映射，已存在的映射保持不变
+func (s *InboxService) linkThread(tx *gorm.DB, email *models.InboundEmail, ticketID uint) error {
+	threadID := email.ThreadID
+	if threadID == "" {
+		threadID = email.MessageID
+	}
+	if threadID == "" {
+		return nil
+	}
+
+	rows := []models.EmailThreadMessage{}
+	if email.MessageID != "" {
+		rows = append(rows, models.EmailThreadMessage{MessageID: email.MessageID, ThreadID: threadID, TicketID: ticketID, InboundEmailID: &email.ID})
+	}
+	if threadID != email.MessageID {
+		rows = append(rows, models.EmailThreadMessage{MessageID: threadID, ThreadID: threadID, TicketID: ticketID})
+	}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error; err != nil {
+		return fmt.Errorf("failed to link email thread: %w", err)
+	}
+	return nil
+}
+
+// appendEmailToTicket 将邮件作为公开评论追加到工单；发件人已注册时以其身份发表，否则记在工单创建人名下
+func (s *InboxService) appendEmailToTicket(tx *gorm.DB, email *models.InboundEmail, ticket *models.Ticket, actorID *uint, description string) error {
+	authorID := ticket.CreatedByID
+	if actorID != nil {
+		authorID = *actorID
+	}
+	var customer models.User
+	if err := tx.Select("id").Where("lower(email) = ?", email.FromAddress).First(&customer).Error; err == nil {
+		authorID = customer.ID
+	}
+
+	comment := &models.TicketComment{
+		TicketID: ticket.ID,
+		UserID:   authorID,
+		Content:  fmt.Sprintf("来自 %s 的邮件：%s\n\n%s", email.FromAddress, email.Subject, email.Body),
+		Type:     models.CommentTypePublic,
+	}
+	// 认证结果写入评论元数据，客服界面据此在评论旁展示
+	if email.Auth.Verdict != "" {
+		metadata, err := json.Marshal(map[string]interface{}{"inbound_email_id": email.ID, "email_authentication": email.Auth})
+		if err != nil {
+			return fmt.Errorf("failed to encode comment metadata: %w", err)
+		}
+		comment.Metadata = string(metadata)
+	}
+	if err := tx.Create(comment).Error; err != nil {
+		return fmt.Errorf("failed to create comment: %w", err)
+	}
+
+	history := &models.TicketHistory{
+		TicketID:    ticket.ID,
+		UserID:      actorID,
+		Action:      models.HistoryActionMerge,
+		Description: fmt.Sprintf("%s（发件人 %s%s）", description, email.FromAddress, emailAuthNote(&email.Auth)),
+		FieldName:   "inbound_email_id",
+		NewValue:    fmt.Sprintf("%d", email.ID),
+		CommentID:   &comment.ID,
+		IsVisible:   true,
+	}
+	return tx.Create(history).Error
+}
+
+// emailAuthNote 工单历史中的认证结果标注，未认证时为空
+func emailAuthNote(auth *models.EmailAuthentication) string {
+	if summary := auth.Summary(); summary != "" {
+		return "，认证结果 " + summary
+	}
+	return ""
+}
+
+// ConvertEmail 将待分拣邮件转为工单，可同时指定处理人或团队
+func (s *InboxService) ConvertEmail(ctx context.Context, emailID uint, req *models.InboxConvertRequest, userID uint) (*models.Ticket, error) {
+	email, err := s.getEmail(ctx, emailID)
+	if err != nil {
+		return nil, err
+	}
+	// 先抢占状态，避免两人同时转换出重复工单
+	if err := s.claimEmail(ctx, emailID, models.InboundEmailStatusConverted, userID); err != nil {
+		return nil, err
+	}
+
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		title = strings.TrimSpace(email.Subject)
+	}
+	if title == "" {
+		title = "(无主题)"
+	}
+	description := email.Body
+	if strings.TrimSpace(description) == "" {
+		description = title
+	}
+	ticketType := req.Type
+	if ticketType == "" {
+		ticketType = models.TicketTypeRequest
+	}
+	priority := req.Priority
+	if priority == "" {
+		priority = models.TicketPriorityNormal
+	}
+
+	// 发件人已注册时以其身份建单，使客户能在门户中看到该工单
+	creatorID := userID
+	var customer models.User
+	if err := s.db.WithContext(ctx).Select("id").Where("lower(email) = ?", email.FromAddress).First(&customer).Error; err == nil {
+		creatorID = customer.ID
+	}
+
+	ticket, err := s.ticketService.CreateTicket(ctx, &models.TicketCreateRequest{
+		Title:          title,
+		Description:    description,
+		Type:           ticketType,
+		Priority:       priority,
+		Source:         models.TicketSourceEmail,
+		AssignedToID:   req.AssignedToID,
+		AssignedTeamID: req.AssignedTeamID,
+		CategoryID:     req.CategoryID,
+		CustomerEmail:  email.FromAddress,
+		CustomerName:   email.FromName,
+		SpamScore:      email.SpamScore,
+	}, creatorID)
+	if err != nil {
+		// 建单失败时放回收件箱
+		s.db.WithContext(ctx).Model(&models.InboundEmail{}).Where("id = ?", emailID).
+			Updates(map[string]interface{}{"status": models.InboundEmailStatusPending, "processed_by_id": nil, "processed_at": nil})
+		return nil, err
+	}
+
+	if err := s.db.WithContext(ctx).Model(&models.InboundEmail{}).Where("id = ?", emailID).
+		Update("ticket_id", ticket.ID).Error; err != nil {
+		return nil, fmt.Errorf("failed to link inbound email: %w", err)
+	}
+	if err := s.linkThread(s.db.WithContext(ctx), email, ticket.ID); err != nil {
+		return nil, err
+	}
+	if err := s.absorbThreadEmails(ctx, email, ticket, userID); err != nil {
+		return nil, err
+	}
+	history := &models.TicketHistory{
+		TicketID:    ticket.ID,
+		UserID:      &userID,
+		Action:      models.HistoryActionCreate,
+		Description: fmt.Sprintf("由收件箱邮件转为工单（发件人 %s%s）", email.FromAddress, emailAuthNote(&email.Auth)),
+		FieldName:   "inbound_email_id",
+		NewValue:    fmt.Sprintf("%d", emailID),
+		IsVisible:   false,
+	}
+	if err := s.db.WithContext(ctx).Create(history).Error; err != nil {
+		return nil, fmt.Errorf("failed to record conversion history: %w", err)
+	}
+	return ticket, nil
+}
+
+// MergeEmail 将待分拣邮件作为公开评论合并到已有工单
+func (s *InboxService) MergeEmail(ctx context.Context, emailID, ticketID, userID uint) (*models.Ticket, error) {
+	email, err := s.getEmail(ctx, emailID)
+	if err != nil {
+		return nil, err
+	}
+
+	var ticket models.Ticket
+	if err := s.db.WithContext(ctx).Where("deleted_at IS NULL").First(&ticket, ticketID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInboxTicketNotFound
+		}
+		return nil, fmt.Errorf("failed to get ticket: %w", err)
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&models.InboundEmail{}).
+			Where("id = ? AND status = ?", emailID, models.InboundEmailStatusPending).
+			Updates(map[string]interface{}{
+				"status":          models.InboundEmailStatusMerged,
+				"ticket_id":       ticketID,
+				"processed_by_id": userID,
+				"processed_at":    now,
+			})
+		if result.Error != nil {
+			return fmt.Errorf("failed to update inbound email: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrInboxItemProcessed
+		}
+
+		if err := s.appendEmailToTicket(tx, email, &ticket, &userID, "合并了收件箱邮件"); err != nil {
+			return err
+		}
+		// 之后同一会话的回复直接追加到该工单
+		return s.linkThread(tx, email, ticketID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.ticketService.GetTicket(ctx, ticketID)
+}
+
+// absorbThreadEmails 邮件转为工单后，将收件箱中同一会话的其他待分拣邮件一并追加到该工单
+func (s *InboxService) absorbThreadEmails(ctx context.Context, email *models.InboundEmail, ticket *models.Ticket, userID uint) error {
+	if email.ThreadID == "" {
+		return nil
+	}
+	var pending []*models.InboundEmail
+	if err := s.db.WithContext(ctx).
+		Where("thread_id = ? AND status = ? AND id <> ?", email.ThreadID, models.InboundEmailStatusPending, email.ID).
+		Order("received_at ASC").Find(&pending).Error; err != nil {
+		return fmt.Errorf("failed to get thread emails: %w", err)
+	}
+
+	for _, other := range pending {
+		err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			result := tx.Model(&models.InboundEmail{}).
+				Where("id = ? AND status = ?", other.ID, models.InboundEmailStatusPending).
+				Updates(map[string]interface{}{
+					"status":          models.InboundEmailStatusMerged,
+					"ticket_id":       ticket.ID,
+					"processed_by_id": userID,
+					"processed_at":    time.Now(),
+				})
+			if result.Error != nil {
+				return fmt.Errorf("failed to update inbound email: %w", result.Error)
+			}
+			if result.RowsAffected == 0 {
+				return nil
+			}
+			if err := s.appendEmailToTicket(tx, other, ticket, &userID, "同一邮件会话的邮件随转换追加到工单"); err != nil {
+				return err
+			}
+			return s.linkThread(tx, other, ticket.ID)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetTicketEmailThread 获取工单关联的全部原始邮件，按收到时间排序
+func (s *InboxService) GetTicketEmailThread(ctx context.Context, ticketID uint) (*models.TicketEmailThread, error) {
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.Ticket{}).Where("id = ? AND deleted_at IS NULL", ticketID).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to get ticket: %w", err)
+	}
+	if count == 0 {
+		return nil, ErrInboxTicketNotFound
+	}
+
+	thread := &models.TicketEmailThread{TicketID: ticketID, ThreadIDs: []string{}, Messages: []*models.InboundEmail{}}
+	if err := s.db.WithContext(ctx).Where("ticket_id = ?", ticketID).
+		Order("received_at ASC, id ASC").Find(&thread.Messages).Error; err != nil {
+		return nil, fmt.Errorf("failed to get thread emails: %w", err)
+	}
+	if err := s.db.WithContext(ctx).Model(&models.EmailThreadMessage{}).
+		Where("ticket_id = ?", ticketID).Distinct().Order("thread_id").Pluck("thread_id", &thread.ThreadIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to get thread ids: %w", err)
+	}
+
+	var addresses []string
+	for _, message := range thread.Messages {
+		addresses = append(addresses, message.FromAddress)
+	}
+	trust, err := s.authService.SenderTrust(ctx, addresses)
+	if err != nil {
+		return nil, err
+	}
+	thread.Senders = make([]*models.EmailSenderTrust, 0, len(trust))
+	for _, address := range addresses {
+		if sender := trust[address]; sender != nil {
+			thread.Senders = append(thread.Senders, sender)
+			delete(trust, address)
+		}
+	}
+	return thread, nil
+}
+
+// EmailThreadSummary 工单邮件会话概要，工单没有关联邮件时返回 nil
+func (s *InboxService) EmailThreadSummary(ctx context.Context, ticketID uint) (*models.EmailThreadSummary, error) {
+	var emails []models.InboundEmail
+	if err := s.db.WithContext(ctx).Select("thread_id", "message_id", "received_at").
+		Where("ticket_id = ?", ticketID).Order("received_at ASC, id ASC").Find(&emails).Error; err != nil {
+		return nil, fmt.Errorf("failed to get thread emails: %w", err)
+	}
+	if len(emails) == 0 {
+		return nil, nil
+	}
+	threadID := emails[0].ThreadID
+	if threadID == "" {
+		threadID = emails[0].MessageID
+	}
+	return &models.EmailThreadSummary{
+		ThreadID:       threadID,
+		MessageCount:   int64(len(emails)),
+		LastReceivedAt: emails[len(emails)-1].ReceivedAt,
+	}, nil
+}
+
+// AssignTicket 在收件箱中为待分拣的邮件工单指定处理人和/或团队
+func (s *InboxService) AssignTicket(ctx context.Context, ticketID uint, req *models.InboxAssignRequest, userID uint) (*models.Ticket, error) {
+	if req.AssignedToID == nil && req.AssignedTeamID == nil {
+		return nil, ErrInboxAssigneeRequired
+	}
+	if err := s.ensureTriageTicket(ctx, ticketID); err != nil {
+		return nil, err
+	}
+
+	if req.AssignedTeamID != nil {
+		if err := s.ticketService.ensureActiveTeam(ctx, *req.AssignedTeamID); err != nil {
+			return nil, err
+		}
+		err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(&models.Ticket{}).Where("id = ?", ticketID).
+				Updates(map[string]interface{}{
+					"assigned_team_id":     *req.AssignedTeamID,
+					"triage_snoozed_until": nil,
+					"updated_at":           time.Now(),
+				}).Error; err != nil {
+				return fmt.Errorf("failed to assign team: %w", err)
+			}
+			history := &models.TicketHistory{
+				TicketID:    ticketID,
+				UserID:      &userID,
+				Action:      models.HistoryActionAssign,
+				Description: fmt.Sprintf("从收件箱分配到团队 ID: %d", *req.AssignedTeamID),
+				FieldName:   "assigned_team_id",
+				NewValue:    fmt.Sprintf("%d", *req.AssignedTeamID),
+				IsVisible:   true,
+			}
+			return tx.Create(history).Error
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if req.AssignedToID != nil {
+		return s.ticketService.AssignTicket(ticketID, *req.AssignedToID, userID, "从收件箱分配")
+	}
+	return s.ticketService.GetTicket(ctx, ticketID)
+}
+
+// SnoozeEmail 暂缓处理收件，到期后重新出现在收件箱
+func (s *InboxService) SnoozeEmail(ctx context.Context, emailID uint, until time.Time) error {
+	if err := validateSnooze(until); err != nil {
+		return err
+	}
+	result := s.db.WithContext(ctx).Model(&models.InboundEmail{}).
+		Where("id = ? AND status = ?", emailID, models.InboundEmailStatusPending).
+		Update("snoozed_until", until)
+	if result.Error != nil {
+		return fmt.Errorf("failed to snooze email: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		if _, err := s.getEmail(ctx, emailID); err != nil {
+			return err
+		}
+		return ErrInboxItemProcessed
+	}
+	return nil
+}
+
+// SnoozeTicket 暂缓分拣邮件工单
+func (s *InboxService) SnoozeTicket(ctx context.Context, ticketID uint, until time.Time) error {
+	if err := validateSnooze(until); err != nil {
+		return err
+	}
+	if err := s.ensureTriageTicket(ctx, ticketID); err != nil {
+		return err
+	}
+	// 不更新 updated_at，暂缓不算作工单处理进展
+	return s.db.WithContext(ctx).Model(&models.Ticket{}).Where("id = ?", ticketID).
+		UpdateColumn("triage_snoozed_until", until).Error
+}
+
+// MarkEmailSpam 标记收件为垃圾邮件并训练黑名单
+func (s *InboxService) MarkEmailSpam(ctx context.Context, emailID uint, blockDomain bool, userID uint) error {
+	email, err := s.getEmail(ctx, emailID)
+	if err != nil {
+		return err
+	}
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.InboundEmail{}).
+			Where("id = ? AND status = ?", emailID, models.InboundEmailStatusPending).
+			Updates(map[string]interface{}{
+				"status":          models.InboundEmailStatusSpam,
+				"processed_by_id": userID,
+				"processed_at":    time.Now(),
+			})
+		if result.Error != nil {
+			return fmt.Errorf("failed to mark email as spam: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrInboxItemProcessed
+		}
+		return s.trainSpam(tx, email.FromAddress, blockDomain, userID)
+	})
+}
+
+// MarkEmailNotSpam 将误判的垃圾邮件放回收件箱，并解除发件人黑名单
+func (s *InboxService) MarkEmailNotSpam(ctx context.Context, emailID uint) error {
+	email, err := s.getEmail(ctx, emailID)
+	if err != nil {
+		return err
+	}
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.InboundEmail{}).
+			Where("id = ? AND status = ?", emailID, models.InboundEmailStatusSpam).
+			Updates(map[string]interface{}{
+				"status":          models.InboundEmailStatusPending,
+				"processed_by_id": nil,
+				"processed_at":    nil,
+			})
+		if result.Error != nil {
+			return fmt.Errorf("failed to restore email: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrInboxItemProcessed
+		}
+		return tx.Model(&models.InboxBlocklistEntry{}).
+			Where("pattern = ? AND kind = ?", email.FromAddress, models.InboxBlocklistSender).
+			Updates(map[string]interface{}{"is_blocked": false, "spam_count": 0}).Error
+	})
+}
+
+// MarkTicketSpam 将待分拣的邮件工单标记为垃圾邮件：取消工单并训练黑名单
+func (s *InboxService) MarkTicketSpam(ctx context.Context, ticketID uint, blockDomain bool, userID uint) error {
+	if err := s.ensureTriageTicket(ctx, ticketID); err != nil {
+		return err
+	}
+	var ticket models.Ticket
+	if err := s.db.WithContext(ctx).Select("id", "customer_email").First(&ticket, ticketID).Error; err != nil {
+		return fmt.Errorf("failed to get ticket: %w", err)
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Ticket{}).Where("id = ?", ticketID).
+			Updates(map[string]interface{}{
+				"status":     models.TicketStatusCancelled,
+				"updated_at": time.Now(),
+			}).Error; err != nil {
+			return fmt.Errorf("failed to cancel ticket: %w", err)
+		}
+		history := &models.TicketHistory{
+			TicketID:    ticketID,
+			UserID:      &userID,
+			Action:      models.HistoryActionStatusChange,
+			Description: "在收件箱中标记为垃圾邮件",
+			FieldName:   "status",
+			OldValue:    string(models.TicketStatusOpen),
+			NewValue:    string(models.TicketStatusCancelled),
+			IsVisible:   false,
+		}
+		if err := tx.Create(history).Error; err != nil {
+			return err
+		}
+		if ticket.CustomerEmail == "" {
+			return nil
+		}
+		return s.trainSpam(tx, strings.ToLower(ticket.CustomerEmail), blockDomain, userID)
+	})
+}
+
+// ListBlocklist 获取黑名单条目（含仅有计数尚未拉黑的域名）
+func (s *InboxService) ListBlocklist(ctx context.Context) ([]*models.InboxBlocklistEntry, error) {
+	var entries []*models.InboxBlocklistEntry
+	if err := s.db.WithContext(ctx).Order("is_blocked DESC, spam_count DESC, pattern ASC").Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to get blocklist: %w", err)
+	}
+	return entries, nil
+}
+
+// AddBlocklistEntry 手动拉黑发件人地址或域名
+func (s *InboxService) AddBlocklistEntry(ctx context.Context, pattern string, userID uint) (*models.InboxBlocklistEntry, error) {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	kind := models.InboxBlocklistDomain
+	if strings.Contains(pattern, "@") {
+		kind = models.InboxBlocklistSender
+		if models.EmailDomain(pattern) == "" || strings.HasPrefix(pattern, "@") {
+			return nil, ErrInvalidBlocklistPattern
+		}
+	} else if !strings.Contains(pattern, ".") || strings.ContainsAny(pattern, " /") {
+		return nil, ErrInvalidBlocklistPattern
+	}
+
+	var entry models.InboxBlocklistEntry
+	err := s.db.WithContext(ctx).Where("pattern = ?", pattern).First(&entry).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to check blocklist: %w", err)
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		entry = models.InboxBlocklistEntry{Pattern: pattern, Kind: kind, IsBlocked: true, CreatedByID: &userID}
+		if err := s.db.WithContext(ctx).Create(&entry).Error; err != nil {
+			return nil, fmt.Errorf("failed to create blocklist entry: %w", err)
+		}
+		return &entry, nil
+	}
+	if err := s.db.WithContext(ctx).Model(&entry).Update("is_blocked", true).Error; err != nil {
+		return nil, fmt.Errorf("failed to update blocklist entry: %w", err)
+	}
+	return &entry, nil
+}
+
+// DeleteBlocklistEntry 删除黑名单条目（同时清除训练计数）
+func (s *InboxService) DeleteBlocklistEntry(ctx context.Context, id uint) error {
+	result := s.db.WithContext(ctx).Delete(&models.InboxBlocklistEntry{}, id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete blocklist entry: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("blocklist entry not found")
+	}
+	return nil
+}
+
+// trainSpam 拉黑发件人并累加域名计数，域名计数达到阈值或显式要求时拉黑域名
+func (s *InboxService) trainSpam(tx *gorm.DB, address string, blockDomain bool, userID uint) error {
+	if err := s.bumpBlocklist(tx, address, models.InboxBlocklistSender, true, userID); err != nil {
+		return err
+	}
+
+	domain := models.EmailDomain(address)
+	if domain == "" {
+		return nil
+	}
+	var entry models.InboxBlocklistEntry
+	err := tx.Where("pattern = ?", domain).First(&entry).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	block := blockDomain || (!publicMailDomains[domain] && entry.SpamCount+1 >= inboxDomainBlockThreshold)
+	return s.bumpBlocklist(tx, domain, models.InboxBlocklistDomain, block, userID)
+}
+
+func (s *InboxService) bumpBlocklist(tx *gorm.DB, pattern string, kind models.InboxBlocklistKind, block bool, userID uint) error {
+	var entry models.InboxBlocklistEntry
+	err := tx.Where("pattern = ?", pattern).First(&entry).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		entry = models.InboxBlocklistEntry{Pattern: pattern, Kind: kind, SpamCount: 1, IsBlocked: block, CreatedByID: &userID}
+		return tx.Create(&entry).Error
+	}
+	if err != nil {
+		return err
+	}
+	updates := map[string]interface{}{"spam_count": gorm.Expr("spam_count + 1")}
+	if block {
+		updates["is_blocked"] = true
+	}
+	return tx.Model(&entry).Updates(updates).Error
+}
+
+func (s *InboxService) isBlocked(ctx context.Context, address string) (bool, error) {
+	patterns := []string{address}
+	if domain := models.EmailDomain(address); domain != "" {
+		patterns = append(patterns, domain)
+	}
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.InboxBlocklistEntry{}).
+		Where("pattern IN ? AND is_blocked = ?", patterns, true).
+		Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to check blocklist: %w", err)
+	}
+	return count > 0, nil
+}
+
+func (s *InboxService) getEmail(ctx context.Context, emailID uint) (*models.InboundEmail, error) {
+	var email models.InboundEmail
+	if err := s.db.WithContext(ctx).First(&email, emailID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInboundEmailNotFound
+		}
+		return nil, fmt.Errorf("failed to get inbound email: %w", err)
+	}
+	return &email, nil
+}
+
+func (s *InboxService) claimEmail(ctx context.Context, emailID uint, status models.InboundEmailStatus, userID uint) error {
+	result := s.db.WithContext(ctx).Model(&models.InboundEmail{}).
+		Where("id = ? AND status = ?", emailID, models.InboundEmailStatusPending).
+		Updates(map[string]interface{}{
+			"status":          status,
+			"processed_by_id": userID,
+			"processed_at":    time.Now(),
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update inbound email: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrInboxItemProcessed
+	}
+	return nil
+}
+
+func (s *InboxService) ensureTriageTicket(ctx context.Context, ticketID uint) error {
+	var count int64
+	if err := s.triageTickets(ctx).Where("id = ?", ticketID).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check ticket: %w", err)
+	}
+	if count == 0 {
+		return ErrInboxTicketNotTriage
+	}
+	return nil
+}
+
+func validateSnooze(until time.Time) error {
+	now := time.Now()
+	if !until.After(now) || until.After(now.Add(inboxMaxSnooze)) {
+		return ErrInvalidSnoozeTime
+	}
+	return nil
+}
+
+func inboxPreview(body string) string {
+	preview := strings.Join(strings.Fields(body), " ")
+	if utf8.RuneCountInString(preview) <= inboxPreviewRunes {
+		return preview
+	}
+	return string([]rune(preview)[:inboxPreviewRunes]) + "…"
+}
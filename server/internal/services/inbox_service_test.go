@@ -0,0 +1,198 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupInboxTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file:inbox_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+
+	if err := db.AutoMigrate(&models.User{}, &models.Team{}, &models.Ticket{}, &models.TicketComment{}, &models.TicketHistory{},
+		&models.SystemConfig{}, &models.InboundEmail{}, &models.InboxBlocklistEntry{}, &models.AssignmentDelegation{}, &models.IntakeQuarantineItem{},
+		&models.EmailThreadMessage{}, &models.EmailLog{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+
+	return db
+}
+
+func TestInbox_TriageFlowAndSpamTraining(t *testing.T) {
+	db := setupInboxTestDB(t)
+	ctx := context.Background()
+	svc := NewInboxService(db)
+
+	agent := models.User{Username: "inbox-agent", Email: "inbox-agent@example.com", PasswordHash: "x", Role: models.RoleAgent}
+	if err := db.Create(&agent).Error; err != nil {
+		t.Fatalf("failed to seed user: %v", err)
+	}
+
+	emailTicket := models.Ticket{TicketNumber: "IN-001", Title: "printer broken", Status: models.TicketStatusOpen, Priority: models.TicketPriorityNormal, Type: models.TicketTypeIncident, Source: models.TicketSourceEmail, CreatedByID: agent.ID, CustomerEmail: "alice@customer.com"}
+	if err := db.Create(&emailTicket).Error; err != nil {
+		t.Fatalf("failed to seed ticket: %v", err)
+	}
+
+	first, err := svc.Ingest(ctx, &models.InboundEmailCreateRequest{MessageID: "<m1@customer.com>", From: "Bob@Customer.com", Subject: "VPN down", Body: "cannot connect"})
+	if err != nil {
+		t.Fatalf("ingest failed: %v", err)
+	}
+	dup, err := svc.Ingest(ctx, &models.InboundEmailCreateRequest{MessageID: "<m1@customer.com>", From: "bob@customer.com"})
+	if err != nil || dup.ID != first.ID {
+		t.Fatalf("expected duplicate message to be deduplicated, got %+v (%v)", dup, err)
+	}
+
+	items, total, err := svc.List(ctx, InboxListOptions{})
+	if err != nil || total != 2 || len(items) != 2 {
+		t.Fatalf("expected 2 inbox items, got %d/%d (%v)", len(items), total, err)
+	}
+
+	if err := svc.SnoozeTicket(ctx, emailTicket.ID, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("snooze failed: %v", err)
+	}
+	if _, total, _ := svc.List(ctx, InboxListOptions{}); total != 1 {
+		t.Fatalf("expected snoozed ticket to be hidden, total %d", total)
+	}
+
+	ticket, err := svc.ConvertEmail(ctx, first.ID, &models.InboxConvertRequest{AssignedToID: &agent.ID}, agent.ID)
+	if err != nil {
+		t.Fatalf("convert failed: %v", err)
+	}
+	if ticket.Source != models.TicketSourceEmail || ticket.CustomerEmail != "bob@customer.com" || ticket.Title != "VPN down" {
+		t.Fatalf("unexpected converted ticket: %+v", ticket)
+	}
+	if _, err := svc.ConvertEmail(ctx, first.ID, &models.InboxConvertRequest{}, agent.ID); !errors.Is(err, ErrInboxItemProcessed) {
+		t.Fatalf("expected ErrInboxItemProcessed on second convert, got %v", err)
+	}
+
+	followUp, _ := svc.Ingest(ctx, &models.InboundEmailCreateRequest{From: "bob@customer.com", Subject: "Re: VPN down", Body: "still broken"})
+	if _, err := svc.MergeEmail(ctx, followUp.ID, ticket.ID, agent.ID); err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+	var comments int64
+	db.Model(&models.TicketComment{}).Where("ticket_id = ?", ticket.ID).Count(&comments)
+	if comments != 1 {
+		t.Fatalf("expected merged email as comment, got %d", comments)
+	}
+
+	// 同一域名三个发件人被标记后整个域名被拉黑
+	for _, from := range []string{"a@spam.example", "b@spam.example", "c@spam.example"} {
+		email, err := svc.Ingest(ctx, &models.InboundEmailCreateRequest{From: from, Subject: "win"})
+		if err != nil {
+			t.Fatalf("ingest failed: %v", err)
+		}
+		if err := svc.MarkEmailSpam(ctx, email.ID, false, agent.ID); err != nil {
+			t.Fatalf("mark spam failed: %v", err)
+		}
+	}
+	blocked, err := svc.Ingest(ctx, &models.InboundEmailCreateRequest{From: "new@spam.example", Subject: "win again"})
+	if err != nil || blocked.Status != models.InboundEmailStatusSpam {
+		t.Fatalf("expected new sender from blocked domain to be spam, got %+v (%v)", blocked, err)
+	}
+
+	// 公共邮箱域名只拉黑发件人
+	gmail, _ := svc.Ingest(ctx, &models.InboundEmailCreateRequest{From: "x@gmail.com", Subject: "hi"})
+	for i := 0; i < inboxDomainBlockThreshold; i++ {
+		svc.trainSpam(db, "x@gmail.com", false, agent.ID)
+	}
+	if err := svc.MarkEmailSpam(ctx, gmail.ID, false, agent.ID); err != nil {
+		t.Fatalf("mark spam failed: %v", err)
+	}
+	other, _ := svc.Ingest(ctx, &models.InboundEmailCreateRequest{From: "y@gmail.com", Subject: "hello"})
+	if other.Status != models.InboundEmailStatusPending {
+		t.Fatalf("expected public mail domain not to be blocked, got %s", other.Status)
+	}
+
+	if err := svc.MarkEmailNotSpam(ctx, gmail.ID); err != nil {
+		t.Fatalf("not-spam failed: %v", err)
+	}
+	again, _ := svc.Ingest(ctx, &models.InboundEmailCreateRequest{From: "x@gmail.com", Subject: "hi again"})
+	if again.Status != models.InboundEmailStatusPending {
+		t.Fatalf("expected sender to be unblocked after not-spam, got %s", again.Status)
+	}
+}
+
+func TestInbox_EmailThreadDedup(t *testing.T) {
+	db := setupInboxTestDB(t)
+	ctx := context.Background()
+	svc := NewInboxService(db)
+
+	agent := models.User{Username: "thread-agent", Email: "thread-agent@example.com", PasswordHash: "x", Role: models.RoleAgent}
+	if err := db.Create(&agent).Error; err != nil {
+		t.Fatalf("failed to seed user: %v", err)
+	}
+
+	if ids := models.ParseMessageIDs("<a@x.com> <b@x.com>\n <a@x.com>"); len(ids) != 2 || ids[0] != "<a@x.com>" || ids[1] != "<b@x.com>" {
+		t.Fatalf("unexpected parsed message ids: %v", ids)
+	}
+
+	original, err := svc.Ingest(ctx, &models.InboundEmailCreateRequest{MessageID: "thread-root@customer.com", From: "carol@customer.com", Subject: "Laptop slow", Body: "very slow"})
+	if err != nil {
+		t.Fatalf("ingest failed: %v", err)
+	}
+	if original.MessageID != "<thread-root@customer.com>" || original.ThreadID != original.MessageID {
+		t.Fatalf("expected normalized message id as thread id, got %+v", original)
+	}
+	// 转换前收到的回复留在收件箱，转换时一并合入
+	early, _ := svc.Ingest(ctx, &models.InboundEmailCreateRequest{MessageID: "<thread-2@customer.com>", InReplyTo: "<thread-root@customer.com>", From: "carol@customer.com", Subject: "Re: Laptop slow", Body: "also hot"})
+	if early.Status != models.InboundEmailStatusPending || early.ThreadID != original.MessageID {
+		t.Fatalf("expected early reply to stay pending in the same thread, got %+v", early)
+	}
+
+	ticket, err := svc.ConvertEmail(ctx, original.ID, &models.InboxConvertRequest{}, agent.ID)
+	if err != nil {
+		t.Fatalf("convert failed: %v", err)
+	}
+	if got, _ := svc.getEmail(ctx, early.ID); got.Status != models.InboundEmailStatusMerged || got.TicketID == nil || *got.TicketID != ticket.ID {
+		t.Fatalf("expected pending thread email to be merged on convert, got %+v", got)
+	}
+
+	// 转换后的回复直接追加为评论，不再进入收件箱
+	reply, err := svc.Ingest(ctx, &models.InboundEmailCreateRequest{MessageID: "<thread-3@customer.com>", InReplyTo: "<thread-2@customer.com>",
+		References: "<thread-root@customer.com> <thread-2@customer.com>", From: "carol@customer.com", Subject: "Re: Laptop slow", Body: "any update?"})
+	if err != nil {
+		t.Fatalf("ingest reply failed: %v", err)
+	}
+	if reply.Status != models.InboundEmailStatusMerged || reply.TicketID == nil || *reply.TicketID != ticket.ID {
+		t.Fatalf("expected reply to be appended to ticket, got %+v", reply)
+	}
+	var comments int64
+	db.Model(&models.TicketComment{}).Where("ticket_id = ?", ticket.ID).Count(&comments)
+	if comments != 2 {
+		t.Fatalf("expected 2 thread comments, got %d", comments)
+	}
+
+	// 回复系统发出的通知邮件同样能关联到工单
+	db.Create(&models.EmailLog{MessageID: "<notify-1@gongdan.local>", TicketID: &ticket.ID, From: "noreply@gongdan.local", To: `["carol@customer.com"]`, Subject: "工单已创建"})
+	viaNotice, _ := svc.Ingest(ctx, &models.InboundEmailCreateRequest{InReplyTo: "<notify-1@gongdan.local>", From: "carol@customer.com", Subject: "Re: 工单已创建"})
+	if viaNotice.Status != models.InboundEmailStatusMerged {
+		t.Fatalf("expected reply to notification to be appended, got %s", viaNotice.Status)
+	}
+
+	unrelated, _ := svc.Ingest(ctx, &models.InboundEmailCreateRequest{InReplyTo: "<unknown@elsewhere.com>", From: "carol@customer.com", Subject: "new issue"})
+	if unrelated.Status != models.InboundEmailStatusPending {
+		t.Fatalf("expected unknown thread to stay pending, got %s", unrelated.Status)
+	}
+
+	thread, err := svc.GetTicketEmailThread(ctx, ticket.ID)
+	if err != nil || len(thread.Messages) != 4 || thread.Messages[0].ID != original.ID {
+		t.Fatalf("unexpected ticket email thread: %+v (%v)", thread, err)
+	}
+	summary, err := svc.EmailThreadSummary(ctx, ticket.ID)
+	if err != nil || summary == nil || summary.MessageCount != 4 || summary.ThreadID != original.MessageID {
+		t.Fatalf("unexpected thread summary: %+v (%v)", summary, err)
+	}
+	if _, err := svc.GetTicketEmailThread(ctx, 999999); !errors.Is(err, ErrInboxTicketNotFound) {
+		t.Fatalf("expected ErrInboxTicketNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,540 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+// KeyIntakeSpamPolicy 公开渠道垃圾检测策略配置键
+const KeyIntakeSpamPolicy = "intake.spam_policy"
+
+// intakeReputationWindow 统计发件人历史拒绝记录的时间范围
+const intakeReputationWindow = 90 * 24 * time.Hour
+
+var intakeLinkPattern = regexp.MustCompile(`(?i)(https?://|www\.)[^\s<>"']+`)
+
+var (
+	// ErrIntakeThrottled 发件人在当前渠道提交过于频繁
+	ErrIntakeThrottled = errors.New("too many submissions, please try again later")
+	// ErrQuarantineItemNotFound 隔离区条目不存在
+	ErrQuarantineItemNotFound = errors.New("quarantine item not found")
+	// ErrQuarantineItemReviewed 隔离区条目已被审核
+	ErrQuarantineItemReviewed = errors.New("quarantine item already reviewed")
+)
+
+// IntakeSubmission 待检测的公开渠道提交
+type IntakeSubmission struct {
+	Channel models.TicketSource
+	Sender  string // 发件人邮箱
+	UserID  *uint  // 门户提交人
+	Subject string
+	Body    string
+}
+
+// IsPublicIntakeRole 判断角色提交的工单是否属于公开渠道受理，需经过垃圾检测
+func IsPublicIntakeRole(role string) bool {
+	return role == "user" || role == string(models.RoleCustomer)
+}
+
+// IntakeSpamService 公开渠道受理的垃圾评分、限流和隔离区审核
+type IntakeSpamService struct {
+	db                *gorm.DB
+	ticketService     *TicketService
+	automationService *AutomationService
+}
+
+// NewIntakeSpamService 创建受理垃圾检测服务
+func NewIntakeSpamService(db *gorm.DB) *IntakeSpamService {
+	return &IntakeSpamService{
+		db:                db,
+		ticketService:     NewTicketService(db).(*TicketService),
+		automationService: NewAutomationService(db),
+	}
+}
+
+// GetPolicy 获取垃圾检测策略
+func (s *IntakeSpamService) GetPolicy(ctx context.Context) (*models.IntakeSpamPolicy, error) {
+	var config models.SystemConfig
+	err := s.db.WithContext(ctx).
+		Where("key = ? AND is_active = ?", KeyIntakeSpamPolicy, true).
+		First(&config).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.GetDefaultIntakeSpamPolicy(), nil
+		}
+		return nil, fmt.Errorf("failed to get intake spam policy: %w", err)
+	}
+
+	policy := models.GetDefaultIntakeSpamPolicy()
+	if err := config.GetJSONValue(policy); err != nil {
+		log.Printf("Warning: failed to parse intake spam policy, using defaults: %v", err)
+		return models.GetDefaultIntakeSpamPolicy(), nil
+	}
+	if err := policy.Validate(); err != nil {
+		log.Printf("Warning: invalid intake spam policy, using defaults: %v", err)
+		return models.GetDefaultIntakeSpamPolicy(), nil
+	}
+	return policy, nil
+}
+
+// SetPolicy 保存垃圾检测策略
+func (s *IntakeSpamService) SetPolicy(ctx context.Context, policy *models.IntakeSpamPolicy, userID uint) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	var existing models.SystemConfig
+	err := s.db.WithContext(ctx).Where("key = ?", KeyIntakeSpamPolicy).First(&existing).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to check existing policy: %w", err)
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		config := models.SystemConfig{
+			Key:         KeyIntakeSpamPolicy,
+			Category:    CategorySecurity,
+			Group:       "intake",
+			Description: "公开渠道垃圾检测与限流策略",
+			IsActive:    true,
+			UpdatedBy:   &userID,
+		}
+		if err := config.SetValue(policy); err != nil {
+			return fmt.Errorf("failed to set policy value: %w", err)
+		}
+		if err := s.db.WithContext(ctx).Create(&config).Error; err != nil {
+			return fmt.Errorf("failed to create policy: %w", err)
+		}
+		return nil
+	}
+
+	if err := existing.SetValue(policy); err != nil {
+		return fmt.Errorf("failed to set policy value: %w", err)
+	}
+	existing.UpdatedBy = &userID
+	existing.Version++
+	if err := s.db.WithContext(ctx).Save(&existing).Error; err != nil {
+		return fmt.Errorf("failed to update policy: %w", err)
+	}
+	return nil
+}
+
+// Screen 对提交计算垃圾评分并检查渠道限流，策略关闭时返回零分
+func (s *IntakeSpamService) Screen(ctx context.Context, sub *IntakeSubmission) (*models.IntakeSpamVerdict, error) {
+	verdict := &models.IntakeSpamVerdict{Reasons: []string{}}
+	policy, err := s.GetPolicy(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !policy.Enabled {
+		return verdict, nil
+	}
+	sub.Sender = strings.ToLower(strings.TrimSpace(sub.Sender))
+
+	score, reasons := scoreIntakeContent(policy, sub.Subject, sub.Body)
+	reputation, reputationReasons, err := s.senderReputation(ctx, sub)
+	if err != nil {
+		return nil, err
+	}
+	score += reputation
+	reasons = append(reasons, reputationReasons...)
+	if score < 0 {
+		score = 0
+	}
+	if score > 100 {
+		score = 100
+	}
+	verdict.Score = score
+	verdict.Reasons = append(verdict.Reasons, reasons...)
+
+	if verdict.Throttled, err = s.throttled(ctx, policy, sub); err != nil {
+		return nil, err
+	}
+	if verdict.Throttled {
+		verdict.Reasons = append(verdict.Reasons, "超过渠道提交频率限制")
+	}
+	verdict.Quarantine = score >= policy.QuarantineScore
+	return verdict, nil
+}
+
+// scoreIntakeContent 按链接数量与密度、屏蔽短语和全大写标题计分
+func scoreIntakeContent(policy *models.IntakeSpamPolicy, subject, body string) (int, []string) {
+	score := 0
+	var reasons []string
+	text := subject + "\n" + body
+
+	links := intakeLinkPattern.FindAllString(text, -1)
+	if len(links) > policy.MaxLinks {
+		points := 15 + 5*(len(links)-policy.MaxLinks)
+		if points > 40 {
+			points = 40
+		}
+		score += points
+		reasons = append(reasons, fmt.Sprintf("包含 %d 个链接", len(links)))
+	}
+	if len(links) > 0 {
+		linkChars := 0
+		for _, link := range links {
+			linkChars += len(link)
+		}
+		if trimmed := len(strings.Join(strings.Fields(text), "")); trimmed > 0 && linkChars*2 > trimmed {
+			score += 20
+			reasons = append(reasons, "正文主要由链接组成")
+		}
+	}
+
+	lower := strings.ToLower(text)
+	phraseScore := 0
+	for _, phrase := range policy.BlockedPhrases {
+		if strings.Contains(lower, strings.ToLower(phrase)) {
+			phraseScore += 30
+			reasons = append(reasons, fmt.Sprintf("命中屏蔽短语「%s」", phrase))
+		}
+	}
+	if phraseScore > 60 {
+		phraseScore = 60
+	}
+	score += phraseScore
+
+	letters, upper := 0, 0
+	for _, r := range subject {
+		if unicode.IsLetter(r) && r < unicode.MaxASCII {
+			letters++
+			if unicode.IsUpper(r) {
+				upper++
+			}
+		}
+	}
+	if letters >= 10 && upper*10 >= letters*8 {
+		score += 10
+		reasons = append(reasons, "标题全部大写")
+	}
+	return score, reasons
+}
+
+// senderReputation 按黑名单训练计数、隔离区拒绝记录和正常往来历史调整评分
+func (s *IntakeSpamService) senderReputation(ctx context.Context, sub *IntakeSubmission) (int, []string, error) {
+	score := 0
+	var reasons []string
+
+	if sub.Sender != "" {
+		patterns := []string{sub.Sender}
+		if domain := models.EmailDomain(sub.Sender); domain != "" {
+			patterns = append(patterns, domain)
+		}
+		var entries []models.InboxBlocklistEntry
+		if err := s.db.WithContext(ctx).Where("pattern IN ?", patterns).Find(&entries).Error; err != nil {
+			return 0, nil, fmt.Errorf("failed to check sender reputation: %w", err)
+		}
+		for _, entry := range entries {
+			switch {
+			case entry.IsBlocked:
+				score += 100
+				reasons = append(reasons, fmt.Sprintf("%s 在黑名单中", entry.Pattern))
+			case entry.Kind == models.InboxBlocklistSender && entry.SpamCount > 0:
+				score += min(15*entry.SpamCount, 45)
+				reasons = append(reasons, fmt.Sprintf("发件人曾被标记垃圾 %d 次", entry.SpamCount))
+			case entry.SpamCount > 0:
+				score += min(10*entry.SpamCount, 30)
+				reasons = append(reasons, fmt.Sprintf("域名 %s 曾被标记垃圾 %d 次", entry.Pattern, entry.SpamCount))
+			}
+		}
+	}
+
+	var rejected int64
+	query := s.db.WithContext(ctx).Model(&models.IntakeQuarantineItem{}).
+		Where("status = ? AND created_at > ?", models.IntakeQuarantineRejected, time.Now().Add(-intakeReputationWindow))
+	if sub.UserID != nil {
+		query = query.Where("sender = ? OR sender_user_id = ?", sub.Sender, *sub.UserID)
+	} else {
+		query = query.Where("sender = ?", sub.Sender)
+	}
+	if err := query.Count(&rejected).Error; err != nil {
+		return 0, nil, fmt.Errorf("failed to count rejected submissions: %w", err)
+	}
+	if rejected > 0 {
+		score += min(20*int(rejected), 40)
+		reasons = append(reasons, fmt.Sprintf("近期 %d 次提交被确认为垃圾", rejected))
+	}
+
+	// 已有正常往来的发件人降低评分
+	var handled int64
+	tickets := s.db.WithContext(ctx).Model(&models.Ticket{}).
+		Where("deleted_at IS NULL AND status <> ? AND spam_score < ?", models.TicketStatusCancelled, 50)
+	if sub.UserID != nil {
+		tickets = tickets.Where("created_by_id = ?", *sub.UserID)
+	} else {
+		tickets = tickets.Where("lower(customer_email) = ?", sub.Sender)
+	}
+	if err := tickets.Count(&handled).Error; err != nil {
+		return 0, nil, fmt.Errorf("failed to count sender tickets: %w", err)
+	}
+	if handled > 0 {
+		score -= 20
+		reasons = append(reasons, "发件人有正常处理的工单")
+	}
+	return score, reasons, nil
+}
+
+// throttled 统计发件人在渠道限流窗口内的提交次数（含被隔离的提交）
+func (s *IntakeSpamService) throttled(ctx context.Context, policy *models.IntakeSpamPolicy, sub *IntakeSubmission) (bool, error) {
+	throttle, ok := policy.Throttles[string(sub.Channel)]
+	if !ok || throttle.Limit <= 0 {
+		return false, nil
+	}
+	since := time.Now().Add(-time.Duration(throttle.WindowMinutes) * time.Minute)
+
+	var count int64
+	if sub.Channel == models.TicketSourceEmail {
+		if err := s.db.WithContext(ctx).Model(&models.InboundEmail{}).
+			Where("from_address = ? AND created_at > ?", sub.Sender, since).
+			Count(&count).Error; err != nil {
+			return false, fmt.Errorf("failed to count recent emails: %w", err)
+		}
+		return count >= int64(throttle.Limit), nil
+	}
+	if sub.UserID == nil {
+		return false, nil
+	}
+
+	if err := s.db.WithContext(ctx).Model(&models.Ticket{}).
+		Where("created_by_id = ? AND source = ? AND created_at > ?", *sub.UserID, sub.Channel, since).
+		Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to count recent tickets: %w", err)
+	}
+	var quarantined int64
+	if err := s.db.WithContext(ctx).Model(&models.IntakeQuarantineItem{}).
+		Where("sender_user_id = ? AND channel = ? AND created_at > ?", *sub.UserID, sub.Channel, since).
+		Count(&quarantined).Error; err != nil {
+		return false, fmt.Errorf("failed to count recent quarantined submissions: %w", err)
+	}
+	return count+quarantined >= int64(throttle.Limit), nil
+}
+
+// QuarantineEmail 将收件放入隔离区，在入库事务中调用
+func (s *IntakeSpamService) QuarantineEmail(tx *gorm.DB, email *models.InboundEmail, verdict *models.IntakeSpamVerdict) error {
+	item := &models.IntakeQuarantineItem{
+		Channel:        models.TicketSourceEmail,
+		Sender:         email.FromAddress,
+		Subject:        email.Subject,
+		Body:           email.Body,
+		Score:          verdict.Score,
+		ReasonList:     verdict.Reasons,
+		Throttled:      verdict.Throttled,
+		InboundEmailID: &email.ID,
+		Status:         models.IntakeQuarantinePending,
+	}
+	if err := tx.Create(item).Error; err != nil {
+		return fmt.Errorf("failed to quarantine email: %w", err)
+	}
+	return nil
+}
+
+// SubmitPortalTicket 客户门户建单：超过限流返回 ErrIntakeThrottled；评分达到阈值时进入隔离区并返回隔离条目，
+// 否则带评分创建工单并执行 intake.screened 自动化规则
+func (s *IntakeSpamService) SubmitPortalTicket(ctx context.Context, req *models.TicketCreateRequest, userID uint) (*models.Ticket, *models.IntakeQuarantineItem, error) {
+	channel := req.Source
+	if channel == "" {
+		channel = models.TicketSourceWeb
+	}
+	senderEmail := req.CustomerEmail
+	var user models.User
+	if err := s.db.WithContext(ctx).Select("id", "email").First(&user, userID).Error; err == nil {
+		senderEmail = user.Email
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, fmt.Errorf("failed to get submitter: %w", err)
+	}
+	verdict, err := s.Screen(ctx, &IntakeSubmission{
+		Channel: channel,
+		Sender:  senderEmail,
+		UserID:  &userID,
+		Subject: req.Title,
+		Body:    req.Description,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if verdict.Throttled {
+		return nil, nil, ErrIntakeThrottled
+	}
+
+	if verdict.Quarantine {
+		payload, err := json.Marshal(req)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to encode ticket request: %w", err)
+		}
+		item := &models.IntakeQuarantineItem{
+			Channel:      channel,
+			Sender:       strings.ToLower(strings.TrimSpace(senderEmail)),
+			SenderUserID: &userID,
+			Subject:      req.Title,
+			Body:         req.Description,
+			Score:        verdict.Score,
+			ReasonList:   verdict.Reasons,
+			Payload:      string(payload),
+			Status:       models.IntakeQuarantinePending,
+		}
+		if err := s.db.WithContext(ctx).Create(item).Error; err != nil {
+			return nil, nil, fmt.Errorf("failed to quarantine submission: %w", err)
+		}
+		return nil, item, nil
+	}
+
+	req.SpamScore = verdict.Score
+	ticket, err := s.ticketService.CreateTicket(ctx, req, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.automationService.ExecuteRules(ctx, models.TriggerIntakeScreened, ticket); err != nil {
+		log.Printf("Failed to execute intake automation rules for ticket %d: %v", ticket.ID, err)
+	}
+	return ticket, nil, nil
+}
+
+// ListQuarantine 分页获取隔离区条目，默认只返回待审核的条目
+func (s *IntakeSpamService) ListQuarantine(ctx context.Context, status models.IntakeQuarantineStatus, channel string, page, pageSize int) ([]*models.IntakeQuarantineItem, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	if status == "" {
+		status = models.IntakeQuarantinePending
+	}
+
+	query := s.db.WithContext(ctx).Model(&models.IntakeQuarantineItem{}).Where("status = ?", status)
+	if channel != "" {
+		query = query.Where("channel = ?", channel)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count quarantine items: %w", err)
+	}
+	var items []*models.IntakeQuarantineItem
+	if err := query.Order("created_at DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&items).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to get quarantine items: %w", err)
+	}
+	return items, total, nil
+}
+
+// GetQuarantineItem 获取隔离区条目
+func (s *IntakeSpamService) GetQuarantineItem(ctx context.Context, id uint) (*models.IntakeQuarantineItem, error) {
+	var item models.IntakeQuarantineItem
+	if err := s.db.WithContext(ctx).First(&item, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrQuarantineItemNotFound
+		}
+		return nil, fmt.Errorf("failed to get quarantine item: %w", err)
+	}
+	return &item, nil
+}
+
+// Release 放行隔离区条目：邮件回到共享收件箱，门户提交以原请求创建工单；放行后垃圾评分清零
+func (s *IntakeSpamService) Release(ctx context.Context, id uint, req *models.IntakeQuarantineReviewRequest, reviewerID uint) (*models.IntakeQuarantineItem, error) {
+	item, err := s.GetQuarantineItem(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := s.claim(tx, item, models.IntakeQuarantineReleased, req.Note, reviewerID); err != nil {
+			return err
+		}
+		if item.InboundEmailID == nil {
+			return nil
+		}
+		return tx.Model(&models.InboundEmail{}).
+			Where("id = ? AND status = ?", *item.InboundEmailID, models.InboundEmailStatusQuarantined).
+			Updates(map[string]interface{}{"status": models.InboundEmailStatusPending, "spam_score": 0}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if item.Payload != "" && item.SenderUserID != nil {
+		var ticketReq models.TicketCreateRequest
+		if err := json.Unmarshal([]byte(item.Payload), &ticketReq); err != nil {
+			return nil, fmt.Errorf("failed to decode quarantined ticket request: %w", err)
+		}
+		ticket, err := s.ticketService.CreateTicket(ctx, &ticketReq, *item.SenderUserID)
+		if err != nil {
+			// 建单失败时放回隔离区
+			s.db.WithContext(ctx).Model(&models.IntakeQuarantineItem{}).Where("id = ?", item.ID).
+				Updates(map[string]interface{}{"status": models.IntakeQuarantinePending, "reviewed_by_id": nil, "reviewed_at": nil})
+			return nil, err
+		}
+		if err := s.db.WithContext(ctx).Model(&models.IntakeQuarantineItem{}).Where("id = ?", item.ID).
+			Update("ticket_id", ticket.ID).Error; err != nil {
+			return nil, fmt.Errorf("failed to link released ticket: %w", err)
+		}
+		item.TicketID = &ticket.ID
+	}
+	return item, nil
+}
+
+// Reject 确认隔离区条目为垃圾：邮件标记为垃圾邮件，可同时拉黑发件人
+func (s *IntakeSpamService) Reject(ctx context.Context, id uint, req *models.IntakeQuarantineReviewRequest, reviewerID uint) (*models.IntakeQuarantineItem, error) {
+	item, err := s.GetQuarantineItem(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := s.claim(tx, item, models.IntakeQuarantineRejected, req.Note, reviewerID); err != nil {
+			return err
+		}
+		if item.InboundEmailID != nil {
+			if err := tx.Model(&models.InboundEmail{}).
+				Where("id = ? AND status = ?", *item.InboundEmailID, models.InboundEmailStatusQuarantined).
+				Updates(map[string]interface{}{
+					"status":          models.InboundEmailStatusSpam,
+					"processed_by_id": reviewerID,
+					"processed_at":    time.Now(),
+				}).Error; err != nil {
+				return fmt.Errorf("failed to mark email as spam: %w", err)
+			}
+		}
+		if !req.BlockSender || models.EmailDomain(item.Sender) == "" {
+			return nil
+		}
+		return (&InboxService{db: tx}).trainSpam(tx, item.Sender, false, reviewerID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// claim 将待审核条目更新为审核结果，条目已被他人审核时返回 ErrQuarantineItemReviewed
+func (s *IntakeSpamService) claim(tx *gorm.DB, item *models.IntakeQuarantineItem, status models.IntakeQuarantineStatus, note string, reviewerID uint) error {
+	now := time.Now()
+	result := tx.Model(&models.IntakeQuarantineItem{}).
+		Where("id = ? AND status = ?", item.ID, models.IntakeQuarantinePending).
+		Updates(map[string]interface{}{
+			"status":         status,
+			"reviewed_by_id": reviewerID,
+			"reviewed_at":    now,
+			"review_note":    note,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to review quarantine item: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrQuarantineItemReviewed
+	}
+	item.Status = status
+	item.ReviewedByID = &reviewerID
+	item.ReviewedAt = &now
+	item.ReviewNote = note
+	return nil
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupIntakeSpamTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file:intake_spam_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+
+	if err := db.AutoMigrate(&models.User{}, &models.Team{}, &models.Ticket{}, &models.TicketComment{}, &models.TicketHistory{},
+		&models.SystemConfig{}, &models.AutomationRule{}, &models.AutomationLog{}, &models.InboundEmail{}, &models.InboxBlocklistEntry{},
+		&models.AssignmentDelegation{}, &models.IntakeQuarantineItem{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+
+	return db
+}
+
+func TestIntakeSpam_EmailQuarantineAndReview(t *testing.T) {
+	db := setupIntakeSpamTestDB(t)
+	ctx := context.Background()
+	inbox := NewInboxService(db)
+	svc := NewIntakeSpamService(db)
+
+	admin := models.User{Username: "intake-admin", Email: "intake-admin@example.com", PasswordHash: "x", Role: models.RoleAdmin}
+	if err := db.Create(&admin).Error; err != nil {
+		t.Fatalf("failed to seed user: %v", err)
+	}
+
+	clean, err := inbox.Ingest(ctx, &models.InboundEmailCreateRequest{MessageID: "<ok@customer.com>", From: "carol@customer.com", Subject: "Printer jam", Body: "tray 2 keeps jamming"})
+	if err != nil || clean.Status != models.InboundEmailStatusPending || clean.SpamScore != 0 {
+		t.Fatalf("expected clean email to reach the inbox, got %+v (%v)", clean, err)
+	}
+
+	spam, err := inbox.Ingest(ctx, &models.InboundEmailCreateRequest{
+		MessageID: "<promo@spam.example>",
+		From:      "promo@spam.example",
+		Subject:   "FREE CASINO BONUS NOW",
+		Body:      "casino https://a.example https://b.example https://c.example https://d.example https://e.example",
+	})
+	if err != nil {
+		t.Fatalf("ingest failed: %v", err)
+	}
+	if spam.Status != models.InboundEmailStatusQuarantined || spam.SpamScore < 60 {
+		t.Fatalf("expected spam to be quarantined, got status %s score %d", spam.Status, spam.SpamScore)
+	}
+	if _, total, _ := inbox.List(ctx, InboxListOptions{}); total != 1 {
+		t.Fatalf("expected quarantined email to be hidden from the inbox, total %d", total)
+	}
+
+	items, total, err := svc.ListQuarantine(ctx, "", "", 1, 20)
+	if err != nil || total != 1 || len(items[0].ReasonList) == 0 {
+		t.Fatalf("expected one quarantined item with reasons, got %d (%v)", total, err)
+	}
+
+	rejected, err := svc.Reject(ctx, items[0].ID, &models.IntakeQuarantineReviewRequest{BlockSender: true}, admin.ID)
+	if err != nil || rejected.Status != models.IntakeQuarantineRejected {
+		t.Fatalf("reject failed: %+v (%v)", rejected, err)
+	}
+	if _, err := svc.Release(ctx, items[0].ID, &models.IntakeQuarantineReviewRequest{}, admin.ID); !errors.Is(err, ErrQuarantineItemReviewed) {
+		t.Fatalf("expected reviewed item to be locked, got %v", err)
+	}
+
+	var stored models.InboundEmail
+	db.First(&stored, spam.ID)
+	if stored.Status != models.InboundEmailStatusSpam {
+		t.Fatalf("expected rejected email to be marked spam, got %s", stored.Status)
+	}
+	var blocked models.InboxBlocklistEntry
+	if err := db.Where("pattern = ? AND is_blocked = ?", "promo@spam.example", true).First(&blocked).Error; err != nil {
+		t.Fatalf("expected sender to be blocklisted: %v", err)
+	}
+}
+
+func TestIntakeSpam_PortalThrottleAndRelease(t *testing.T) {
+	db := setupIntakeSpamTestDB(t)
+	ctx := context.Background()
+	svc := NewIntakeSpamService(db)
+
+	customer := models.User{Username: "portal-customer", Email: "dave@customer.com", PasswordHash: "x", Role: models.RoleCustomer}
+	admin := models.User{Username: "portal-admin", Email: "portal-admin@example.com", PasswordHash: "x", Role: models.RoleAdmin}
+	if err := db.Create(&customer).Error; err != nil {
+		t.Fatalf("failed to seed customer: %v", err)
+	}
+	if err := db.Create(&admin).Error; err != nil {
+		t.Fatalf("failed to seed admin: %v", err)
+	}
+
+	policy := models.GetDefaultIntakeSpamPolicy()
+	policy.Throttles[string(models.TicketSourceWeb)] = models.IntakeThrottle{Limit: 2, WindowMinutes: 10}
+	if err := svc.SetPolicy(ctx, policy, admin.ID); err != nil {
+		t.Fatalf("set policy failed: %v", err)
+	}
+
+	_, quarantined, err := svc.SubmitPortalTicket(ctx, &models.TicketCreateRequest{Title: "加微信 免费领取", Description: "刷单 https://x.example", Type: models.TicketTypeIncident, Priority: models.TicketPriorityNormal}, customer.ID)
+	if err != nil || quarantined == nil {
+		t.Fatalf("expected spammy submission to be quarantined, got %v (%v)", quarantined, err)
+	}
+
+	ticket, held, err := svc.SubmitPortalTicket(ctx, &models.TicketCreateRequest{Title: "Cannot log in", Description: "password reset loops", Type: models.TicketTypeIncident, Priority: models.TicketPriorityNormal}, customer.ID)
+	if err != nil || ticket == nil || held != nil {
+		t.Fatalf("expected clean submission to create a ticket, got %v/%v (%v)", ticket, held, err)
+	}
+
+	if _, _, err := svc.SubmitPortalTicket(ctx, &models.TicketCreateRequest{Title: "Another", Description: "again", Type: models.TicketTypeIncident, Priority: models.TicketPriorityNormal}, customer.ID); !errors.Is(err, ErrIntakeThrottled) {
+		t.Fatalf("expected third submission to be throttled, got %v", err)
+	}
+
+	released, err := svc.Release(ctx, quarantined.ID, &models.IntakeQuarantineReviewRequest{Note: "false positive"}, admin.ID)
+	if err != nil || released.TicketID == nil {
+		t.Fatalf("expected release to create the ticket, got %+v (%v)", released, err)
+	}
+	var created models.Ticket
+	db.First(&created, *released.TicketID)
+	if created.CreatedByID != customer.ID || created.Title != "加微信 免费领取" {
+		t.Fatalf("unexpected released ticket: %+v", created)
+	}
+}
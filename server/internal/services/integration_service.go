@@ -0,0 +1,160 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// IntegrationEventTicketCreated 新建工单触发器事件
+	IntegrationEventTicketCreated = "ticket.created"
+	// IntegrationEventTicketUpdated 更新工单触发器事件
+	IntegrationEventTicketUpdated = "ticket.updated"
+
+	defaultIntegrationPageSize = 50
+	maxIntegrationPageSize     = 100
+)
+
+// ErrInvalidIntegrationCursor 游标格式不合法
+var ErrInvalidIntegrationCursor = errors.New("invalid cursor")
+
+// IntegrationService 面向 Zapier/Make 等无代码平台的轮询触发器服务
+type IntegrationService struct {
+	db *gorm.DB
+}
+
+// NewIntegrationService 创建集成服务
+func NewIntegrationService(db *gorm.DB) *IntegrationService {
+	return &IntegrationService{db: db}
+}
+
+// integrationCursor 游标记录上一页最后一条记录的时间戳和ID，保证同一时间戳下的稳定排序
+type integrationCursor struct {
+	At time.Time
+	ID uint
+}
+
+func encodeIntegrationCursor(at time.Time, id uint) string {
+	raw := fmt.Sprintf("%d:%d", at.UnixNano(), id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeIntegrationCursor(cursor string) (*integrationCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidIntegrationCursor
+	}
+	parts := strings.SplitN(string(raw), ":", 2)
+	if len(parts) != 2 {
+		return nil, ErrInvalidIntegrationCursor
+	}
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidIntegrationCursor
+	}
+	id, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return nil, ErrInvalidIntegrationCursor
+	}
+	return &integrationCursor{At: time.Unix(0, nanos), ID: uint(id)}, nil
+}
+
+// ListNewTickets 按创建时间升序返回 since 之后（或游标之后）新建的工单
+func (s *IntegrationService) ListNewTickets(ctx context.Context, since *time.Time, cursor string, limit int) (*models.IntegrationTriggerPage, error) {
+	return s.listTickets(ctx, "created_at", IntegrationEventTicketCreated, since, cursor, limit)
+}
+
+// ListUpdatedTickets 按更新时间升序返回 since 之后（或游标之后）更新过的工单
+func (s *IntegrationService) ListUpdatedTickets(ctx context.Context, since *time.Time, cursor string, limit int) (*models.IntegrationTriggerPage, error) {
+	return s.listTickets(ctx, "updated_at", IntegrationEventTicketUpdated, since, cursor, limit)
+}
+
+func (s *IntegrationService) listTickets(ctx context.Context, column, event string, since *time.Time, cursor string, limit int) (*models.IntegrationTriggerPage, error) {
+	if limit <= 0 {
+		limit = defaultIntegrationPageSize
+	}
+	if limit > maxIntegrationPageSize {
+		limit = maxIntegrationPageSize
+	}
+
+	query := s.db.WithContext(ctx).Model(&models.Ticket{}).
+		Where("deleted_at IS NULL").
+		Preload("CreatedBy").Preload("AssignedTo").Preload("AssignedTeam").Preload("Category")
+
+	// 游标优先于 since，二者都表示“从该位置之后开始”
+	if cursor != "" {
+		c, err := decodeIntegrationCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where(fmt.Sprintf("(%s > ?) OR (%s = ? AND id > ?)", column, column), c.At, c.At, c.ID)
+	} else if since != nil {
+		query = query.Where(fmt.Sprintf("%s > ?", column), *since)
+	}
+
+	var tickets []*models.Ticket
+	if err := query.Order(fmt.Sprintf("%s ASC, id ASC", column)).
+		Limit(limit + 1).
+		Find(&tickets).Error; err != nil {
+		return nil, fmt.Errorf("failed to list tickets: %w", err)
+	}
+
+	page := &models.IntegrationTriggerPage{Items: make([]*models.IntegrationTicketEvent, 0, len(tickets))}
+	if len(tickets) > limit {
+		page.HasMore = true
+		tickets = tickets[:limit]
+	}
+
+	for _, ticket := range tickets {
+		occurredAt := ticket.CreatedAt
+		id := strconv.FormatUint(uint64(ticket.ID), 10)
+		if event == IntegrationEventTicketUpdated {
+			occurredAt = ticket.UpdatedAt
+			id = fmt.Sprintf("%d-%d", ticket.ID, ticket.UpdatedAt.UnixNano())
+		}
+		page.Items = append(page.Items, &models.IntegrationTicketEvent{
+			ID:         id,
+			TicketID:   ticket.ID,
+			Event:      event,
+			OccurredAt: occurredAt,
+			Ticket:     ticket.ToResponse(),
+		})
+	}
+
+	if len(page.Items) > 0 {
+		last := page.Items[len(page.Items)-1]
+		page.NextCursor = encodeIntegrationCursor(last.OccurredAt, last.TicketID)
+	} else {
+		// 没有新数据时原样返回游标，调用方可继续用它轮询
+		page.NextCursor = cursor
+	}
+
+	return page, nil
+}
+
+// GetAuthInfo 返回连接测试所需的账户信息
+func (s *IntegrationService) GetAuthInfo(ctx context.Context, userID uint) (*models.IntegrationAuthInfo, error) {
+	var user models.User
+	if err := s.db.WithContext(ctx).First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("user not found")
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return &models.IntegrationAuthInfo{
+		UserID:   user.ID,
+		Username: user.Username,
+		Email:    user.Email,
+		Role:     string(user.Role),
+		Label:    fmt.Sprintf("%s (%s)", user.Username, user.Email),
+	}, nil
+}
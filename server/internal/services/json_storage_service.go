@@ -0,0 +1,287 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+// JSONColumn 以 JSON 保存、可切换为 jsonb 的列
+type JSONColumn struct {
+	Table  string `json:"table"`
+	Column string `json:"column"`
+	Index  string `json:"index"` // jsonb 存储时创建的 GIN 索引
+}
+
+// jsonColumns 受存储方式切换影响的列，模型中均为 models.JSONText 类型
+var jsonColumns = []JSONColumn{
+	{Table: "tickets", Column: "tags", Index: "idx_tickets_tags_gin"},
+	{Table: "tickets", Column: "custom_fields", Index: "idx_tickets_custom_fields_gin"},
+	{Table: "ticket_histories", Column: "metadata", Index: "idx_ticket_histories_metadata_gin"},
+	{Table: "notifications", Column: "metadata", Index: "idx_notifications_metadata_gin"},
+}
+
+// JSONColumnStatus JSON 列的实际类型与索引状态
+type JSONColumnStatus struct {
+	JSONColumn
+	DataType string `json:"data_type"`
+	Indexed  bool   `json:"indexed"`
+}
+
+// JSONStorageStatus JSON 列存储状态
+type JSONStorageStatus struct {
+	Mode      string             `json:"mode"`   // 配置的存储方式
+	Native    bool               `json:"native"` // 当前连接是否使用 jsonb 查询
+	Columns   []JSONColumnStatus `json:"columns"`
+	Pending   []string           `json:"pending"` // 切换到配置的存储方式尚需执行的语句
+	Supported bool               `json:"supported"`
+}
+
+// JSONStorageService JSON 列存储方式迁移服务
+type JSONStorageService struct {
+	db *gorm.DB
+}
+
+// NewJSONStorageService 创建 JSON 列存储迁移服务
+func NewJSONStorageService(db *gorm.DB) *JSONStorageService {
+	return &JSONStorageService{db: db}
+}
+
+// isPostgres jsonb 存储仅在 PostgreSQL 下可用
+func (s *JSONStorageService) isPostgres() bool {
+	return DialectOf(s.db).IsPostgres()
+}
+
+// columnTypes 获取 JSON 列当前的数据类型，尚未建表的列不返回
+func (s *JSONStorageService) columnTypes(ctx context.Context) (map[string]string, error) {
+	if !s.isPostgres() {
+		return nil, nil
+	}
+
+	var rows []struct {
+		TableName  string
+		ColumnName string
+		DataType   string
+	}
+	tables := make([]string, 0, len(jsonColumns))
+	for _, col := range jsonColumns {
+		tables = append(tables, col.Table)
+	}
+	if err := s.db.WithContext(ctx).
+		Raw("SELECT table_name, column_name, data_type FROM information_schema.columns WHERE table_schema = current_schema() AND table_name IN ?", uniqueStrings(tables)).
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to list JSON column types: %w", err)
+	}
+
+	types := make(map[string]string, len(rows))
+	for _, row := range rows {
+		types[row.TableName+"."+row.ColumnName] = row.DataType
+	}
+	return types, nil
+}
+
+// existingIndexes 获取已存在的 JSON 列 GIN 索引
+func (s *JSONStorageService) existingIndexes(ctx context.Context) (map[string]bool, error) {
+	if !s.isPostgres() {
+		return nil, nil
+	}
+
+	names := make([]string, 0, len(jsonColumns))
+	for _, col := range jsonColumns {
+		names = append(names, col.Index)
+	}
+	var existing []string
+	if err := s.db.WithContext(ctx).
+		Raw("SELECT indexname FROM pg_indexes WHERE schemaname = current_schema() AND indexname IN ?", names).
+		Scan(&existing).Error; err != nil {
+		return nil, fmt.Errorf("failed to list JSON column indexes: %w", err)
+	}
+
+	indexed := make(map[string]bool, len(existing))
+	for _, name := range existing {
+		indexed[name] = true
+	}
+	return indexed, nil
+}
+
+// Statements 生成切换到配置存储方式的迁移语句：jsonb 时转换列类型（空字符串转为 NULL）并创建 GIN 索引，
+// text 时删除索引并转回 TEXT；已符合配置的列不生成语句
+func (s *JSONStorageService) Statements(ctx context.Context) ([]string, error) {
+	types, err := s.columnTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	indexed, err := s.existingIndexes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	native := models.UsesNativeJSON(s.db)
+	var statements []string
+	for _, col := range jsonColumns {
+		dataType, ok := types[col.Table+"."+col.Column]
+		if !ok {
+			continue
+		}
+		if native {
+			if dataType != models.JSONStorageJSONB {
+				statements = append(statements, fmt.Sprintf(
+					"ALTER TABLE %s ALTER COLUMN %s TYPE jsonb USING NULLIF(btrim(%s), '')::jsonb", col.Table, col.Column, col.Column))
+			}
+			if !indexed[col.Index] {
+				statements = append(statements, fmt.Sprintf(
+					"CREATE INDEX IF NOT EXISTS %s ON %s USING gin(%s)", col.Index, col.Table, col.Column))
+			}
+			continue
+		}
+
+		if indexed[col.Index] {
+			statements = append(statements, fmt.Sprintf("DROP INDEX IF EXISTS %s", col.Index))
+		}
+		if dataType == models.JSONStorageJSONB {
+			statements = append(statements, fmt.Sprintf(
+				"ALTER TABLE %s ALTER COLUMN %s TYPE text USING %s::text", col.Table, col.Column, col.Column))
+		}
+	}
+	return statements, nil
+}
+
+// Migrate 在一个事务中将 JSON 列切换到配置的存储方式，返回已执行的语句；
+// 需在 AutoMigrate 之前执行，否则 GORM 会直接转换列类型且无法处理空字符串
+func (s *JSONStorageService) Migrate(ctx context.Context) ([]string, error) {
+	if !s.isPostgres() {
+		return nil, nil
+	}
+
+	statements, err := s.Statements(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(statements) == 0 {
+		return []string{}, nil
+	}
+
+	executed := make([]string, 0, len(statements))
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return fmt.Errorf("failed to execute %q: %w", statement, err)
+			}
+			executed = append(executed, statement)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	log.Printf("JSON column storage migrated (%d statements)", len(executed))
+	return executed, nil
+}
+
+// GetStatus 获取 JSON 列的存储方式、实际类型和待执行的迁移语句
+func (s *JSONStorageService) GetStatus(ctx context.Context) (*JSONStorageStatus, error) {
+	status := &JSONStorageStatus{
+		Mode:      models.JSONStorageText,
+		Native:    models.UsesNativeJSON(s.db),
+		Supported: s.isPostgres(),
+		Columns:   []JSONColumnStatus{},
+	}
+	if status.Native {
+		status.Mode = models.JSONStorageJSONB
+	}
+
+	types, err := s.columnTypes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	indexed, err := s.existingIndexes(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, col := range jsonColumns {
+		dataType, ok := types[col.Table+"."+col.Column]
+		if !ok && s.isPostgres() {
+			continue
+		}
+		if !ok {
+			dataType = "text"
+		}
+		status.Columns = append(status.Columns, JSONColumnStatus{
+			JSONColumn: col,
+			DataType:   dataType,
+			Indexed:    indexed[col.Index],
+		})
+	}
+
+	if status.Pending, err = s.Statements(ctx); err != nil {
+		return nil, err
+	}
+	if status.Pending == nil {
+		status.Pending = []string{}
+	}
+	return status, nil
+}
+
+// JSONContainsCondition 生成 JSON 列包含查询条件。jsonb 存储使用 @>，可命中 GIN 索引；
+// TEXT 存储（含 SQLite）退化为按序列化片段的字符串匹配：对象按键值逐项匹配，数组按元素逐项匹配
+func JSONContainsCondition(db *gorm.DB, column string, value interface{}) (string, []interface{}, error) {
+	if models.UsesNativeJSON(db) {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to encode %s filter: %w", column, err)
+		}
+		return column + " @> ?::jsonb", []interface{}{string(data)}, nil
+	}
+
+	var fragments []string
+	switch v := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			keyData, _ := json.Marshal(key)
+			valueData, err := json.Marshal(v[key])
+			if err != nil {
+				return "", nil, fmt.Errorf("failed to encode %s filter: %w", column, err)
+			}
+			fragments = append(fragments, string(keyData)+":"+string(valueData))
+		}
+	case []string:
+		for _, item := range v {
+			data, _ := json.Marshal(item)
+			fragments = append(fragments, string(data))
+		}
+	default:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to encode %s filter: %w", column, err)
+		}
+		fragments = append(fragments, string(data))
+	}
+	if len(fragments) == 0 {
+		return "1 = 1", nil, nil
+	}
+
+	conditions := make([]string, 0, len(fragments))
+	args := make([]interface{}, 0, len(fragments))
+	for _, fragment := range fragments {
+		conditions = append(conditions, column+` LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+escapeLikePattern(fragment)+"%")
+	}
+	return strings.Join(conditions, " AND "), args, nil
+}
+
+// escapeLikePattern 转义 LIKE 通配符
+func escapeLikePattern(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
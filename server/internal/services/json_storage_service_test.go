@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupJSONStorageTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file:json_storage_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+
+	if err := db.AutoMigrate(&models.User{}, &models.Team{}, &models.Ticket{}, &models.TicketComment{}, &models.TicketHistory{}, &models.SystemConfig{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+
+	return db
+}
+
+func TestJSONStorage_TextFallbackFilters(t *testing.T) {
+	db := setupJSONStorageTestDB(t)
+	ctx := context.Background()
+	svc := NewTicketService(db).(*TicketService)
+
+	// SQLite 始终使用 TEXT 存储，即使配置为 jsonb
+	if err := models.SetJSONStorage(models.JSONStorageJSONB); err != nil {
+		t.Fatalf("set storage failed: %v", err)
+	}
+	defer models.SetJSONStorage(models.JSONStorageText)
+	if models.UsesNativeJSON(db) {
+		t.Fatalf("expected sqlite to fall back to text storage")
+	}
+
+	user := models.User{Username: "json-user", Email: "json-user@example.com", PasswordHash: "x", Role: models.RoleAgent}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatalf("failed to seed user: %v", err)
+	}
+
+	seed := []*models.TicketCreateRequest{
+		{Title: "vip outage", Type: models.TicketTypeIncident, Priority: models.TicketPriorityHigh, Tags: models.StringList{"vip", "network"}, CustomFields: &models.JSONMap{"tier": "gold", "seats": 50}},
+		{Title: "vip question", Type: models.TicketTypeRequest, Priority: models.TicketPriorityNormal, Tags: models.StringList{"vip"}, CustomFields: &models.JSONMap{"tier": "silver", "seats": 50}},
+		{Title: "plain", Type: models.TicketTypeRequest, Priority: models.TicketPriorityLow},
+	}
+	for _, req := range seed {
+		if _, err := svc.CreateTicket(ctx, req, user.ID); err != nil {
+			t.Fatalf("failed to create ticket: %v", err)
+		}
+	}
+
+	tickets, total, err := svc.GetTickets(ctx, TicketFilters{Tags: []string{"vip"}})
+	if err != nil || total != 2 {
+		t.Fatalf("expected 2 vip tickets, got %d (%v)", total, err)
+	}
+
+	tickets, total, err = svc.GetTickets(ctx, TicketFilters{CustomFields: map[string]interface{}{"tier": "gold", "seats": 50}})
+	if err != nil || total != 1 || tickets[0].Title != "vip outage" {
+		t.Fatalf("expected custom field containment to match the gold ticket, got %d (%v)", total, err)
+	}
+
+	if _, total, _ = svc.GetTickets(ctx, TicketFilters{CustomFields: map[string]interface{}{"tier": "go%"}}); total != 0 {
+		t.Fatalf("expected LIKE wildcards in filter values to be escaped, got %d", total)
+	}
+
+	status, err := NewJSONStorageService(db).GetStatus(ctx)
+	if err != nil || status.Supported || status.Native || len(status.Pending) != 0 {
+		t.Fatalf("unexpected sqlite storage status: %+v (%v)", status, err)
+	}
+}
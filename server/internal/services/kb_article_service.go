@@ -0,0 +1,499 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// kbSuggestionLimit 默认推荐文章数量
+	kbSuggestionLimit = 5
+	// kbSuggestionMaxKeywords 推荐时最多使用的关键词数量
+	kbSuggestionMaxKeywords = 12
+	// kbSuggestionCandidates 推荐时参与打分的候选文章上限
+	kbSuggestionCandidates = 200
+)
+
+var (
+	// ErrKBArticleNotFound 文章不存在
+	ErrKBArticleNotFound = errors.New("knowledge base article not found")
+	// ErrKBAlreadyLinked 文章已关联到该工单
+	ErrKBAlreadyLinked = errors.New("article is already linked to this ticket")
+	// ErrKBTicketNotFound 工单不存在
+	ErrKBTicketNotFound = errors.New("ticket not found")
+	// ErrKBLinkNotFound 文章未关联到该工单
+	ErrKBLinkNotFound = errors.New("article is not linked to this ticket")
+)
+
+// KBArticleFilters 文章列表过滤条件
+type KBArticleFilters struct {
+	Query      string
+	CategoryID *uint
+	Status     models.KBArticleStatus
+	Visibility models.KBArticleVisibility
+	Page       int
+	PageSize   int
+}
+
+// KBArticleService 知识库文章服务
+type KBArticleService struct {
+	db            *gorm.DB
+	searchService *SearchConfigService
+}
+
+// NewKBArticleService 创建知识库文章服务
+func NewKBArticleService(db *gorm.DB) *KBArticleService {
+	return &KBArticleService{
+		db:            db,
+		searchService: NewSearchConfigService(db),
+	}
+}
+
+// Create 创建文章
+func (s *KBArticleService) Create(ctx context.Context, req *models.KBArticleCreateRequest, userID uint) (*models.KBArticle, error) {
+	article := &models.KBArticle{
+		Title:      strings.TrimSpace(req.Title),
+		Summary:    req.Summary,
+		Content:    req.Content,
+		CategoryID: req.CategoryID,
+		TagsObj:    normalizeKBTags(req.Tags),
+		Status:     req.Status,
+		Visibility: req.Visibility,
+		AuthorID:   userID,
+	}
+	if article.Status == "" {
+		article.Status = models.KBArticleStatusDraft
+	}
+	if article.Visibility == "" {
+		article.Visibility = models.KBVisibilityInternal
+	}
+	if article.Status == models.KBArticleStatusPublished {
+		now := time.Now()
+		article.PublishedAt = &now
+	}
+
+	if err := s.db.WithContext(ctx).Create(article).Error; err != nil {
+		return nil, fmt.Errorf("failed to create article: %w", err)
+	}
+	return article, nil
+}
+
+// Update 更新文章，首次发布时记录发布时间
+func (s *KBArticleService) Update(ctx context.Context, id uint, req *models.KBArticleUpdateRequest, userID uint) (*models.KBArticle, error) {
+	article, err := s.find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Title != nil {
+		article.Title = strings.TrimSpace(*req.Title)
+	}
+	if req.Summary != nil {
+		article.Summary = *req.Summary
+	}
+	if req.Content != nil {
+		article.Content = *req.Content
+	}
+	if req.CategoryID != nil {
+		if *req.CategoryID == 0 {
+			article.CategoryID = nil
+		} else {
+			article.CategoryID = req.CategoryID
+		}
+		article.Category = nil
+	}
+	if req.Tags != nil {
+		article.TagsObj = normalizeKBTags(req.Tags)
+	}
+	if req.Visibility != nil {
+		article.Visibility = *req.Visibility
+	}
+	if req.Status != nil {
+		if *req.Status == models.KBArticleStatusPublished && article.PublishedAt == nil {
+			now := time.Now()
+			article.PublishedAt = &now
+		}
+		article.Status = *req.Status
+	}
+	article.UpdatedByID = &userID
+
+	if err := s.db.WithContext(ctx).Omit("Category", "Author").Save(article).Error; err != nil {
+		return nil, fmt.Errorf("failed to update article: %w", err)
+	}
+	return article, nil
+}
+
+// Delete 删除文章及其工单关联
+func (s *KBArticleService) Delete(ctx context.Context, id uint) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&models.KBArticle{}, id)
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete article: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrKBArticleNotFound
+		}
+		if err := tx.Where("article_id = ?", id).Delete(&models.KBArticleTicketLink{}).Error; err != nil {
+			return fmt.Errorf("failed to delete article links: %w", err)
+		}
+		return nil
+	})
+}
+
+// Get 获取文章详情并累加阅读次数
+func (s *KBArticleService) Get(ctx context.Context, id uint, role string) (*models.KBArticle, error) {
+	article, err := s.find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !article.IsVisibleTo(role) {
+		// 对无权查看的用户隐藏文章存在性
+		return nil, ErrKBArticleNotFound
+	}
+
+	s.db.WithContext(ctx).Model(&models.KBArticle{}).Where("id = ?", id).
+		UpdateColumn("view_count", gorm.Expr("view_count + 1"))
+	article.ViewCount++
+	return article, nil
+}
+
+// List 按条件列出文章，q 使用与工单一致的全文检索规则
+func (s *KBArticleService) List(ctx context.Context, filters *KBArticleFilters, role string) ([]models.KBArticle, int64, error) {
+	query := s.visibleArticles(ctx, role)
+
+	if filters.Query != "" {
+		condition, args := s.searchService.BuildArticleSearchCondition(ctx, filters.Query)
+		query = query.Where(condition, args...)
+	}
+	if filters.CategoryID != nil {
+		query = query.Where("category_id = ?", *filters.CategoryID)
+	}
+	if filters.Status != "" {
+		query = query.Where("status = ?", filters.Status)
+	} else if filters.Query != "" {
+		// 搜索默认不返回已归档文章
+		query = query.Where("status <> ?", models.KBArticleStatusArchived)
+	}
+	if filters.Visibility != "" {
+		query = query.Where("visibility = ?", filters.Visibility)
+	}
+
+	var total int64
+	if err := query.Model(&models.KBArticle{}).Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count articles: %w", err)
+	}
+
+	var articles []models.KBArticle
+	err := query.Preload("Category").
+		Order("updated_at DESC").
+		Offset((filters.Page - 1) * filters.PageSize).
+		Limit(filters.PageSize).
+		Find(&articles).Error
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list articles: %w", err)
+	}
+	return articles, total, nil
+}
+
+// SuggestForTicket 根据工单标题和描述推荐相关文章
+func (s *KBArticleService) SuggestForTicket(ctx context.Context, ticketID uint, role string, limit int) ([]models.KBArticleSuggestion, error) {
+	var ticket models.Ticket
+	if err := s.db.WithContext(ctx).Select("id", "title", "description").First(&ticket, ticketID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrKBTicketNotFound
+		}
+		return nil, fmt.Errorf("failed to get ticket: %w", err)
+	}
+	return s.Suggest(ctx, ticket.Title+"\n"+ticket.Description, role, limit)
+}
+
+// Suggest 根据任意文本推荐已发布文章：提取关键词后按标题、标签、正文命中加权打分
+func (s *KBArticleService) Suggest(ctx context.Context, text string, role string, limit int) ([]models.KBArticleSuggestion, error) {
+	if limit <= 0 || limit > 20 {
+		limit = kbSuggestionLimit
+	}
+	keywords := extractKBKeywords(text, kbSuggestionMaxKeywords)
+	if len(keywords) == 0 {
+		return []models.KBArticleSuggestion{}, nil
+	}
+
+	conditions := make([]string, 0, len(keywords))
+	args := make([]interface{}, 0, len(keywords)*3)
+	for _, keyword := range keywords {
+		pattern := "%" + keyword + "%"
+		conditions = append(conditions, "LOWER(title) LIKE ? OR LOWER(tags) LIKE ? OR LOWER(content) LIKE ?")
+		args = append(args, pattern, pattern, pattern)
+	}
+
+	var candidates []models.KBArticle
+	err := s.visibleArticles(ctx, role).
+		Where("status = ?", models.KBArticleStatusPublished).
+		Where(strings.Join(conditions, " OR "), args...).
+		Order("resolution_count DESC").
+		Limit(kbSuggestionCandidates).
+		Find(&candidates).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to find candidate articles: %w", err)
+	}
+
+	suggestions := make([]models.KBArticleSuggestion, 0, len(candidates))
+	for _, article := range candidates {
+		title := strings.ToLower(article.Title)
+		content := strings.ToLower(article.Content)
+		tags := strings.ToLower(strings.Join(article.TagsObj, " "))
+
+		score := 0
+		var matched []string
+		for _, keyword := range keywords {
+			hit := 0
+			if strings.Contains(title, keyword) {
+				hit += 3
+			}
+			if strings.Contains(tags, keyword) {
+				hit += 2
+			}
+			if strings.Contains(content, keyword) {
+				hit++
+			}
+			if hit > 0 {
+				score += hit
+				matched = append(matched, keyword)
+			}
+		}
+		if score == 0 {
+			continue
+		}
+		suggestions = append(suggestions, models.KBArticleSuggestion{
+			ID:         article.ID,
+			Title:      article.Title,
+			Summary:    article.Summary,
+			Visibility: article.Visibility,
+			Score:      score,
+			Matched:    matched,
+		})
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].Score > suggestions[j].Score
+	})
+	if len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+	return suggestions, nil
+}
+
+// LinkToTicket 将文章关联到工单；作为解决方案关联时累加解决计数并写入工单历史
+func (s *KBArticleService) LinkToTicket(ctx context.Context, ticketID uint, req *models.KBLinkRequest, userID uint) (*models.KBArticleTicketLink, error) {
+	linkType := models.KBLinkReference
+	if req.AsResolution {
+		linkType = models.KBLinkResolution
+	}
+
+	link := &models.KBArticleTicketLink{
+		ArticleID:  req.ArticleID,
+		TicketID:   ticketID,
+		LinkType:   linkType,
+		LinkedByID: userID,
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var article models.KBArticle
+		if err := tx.Select("id", "title").First(&article, req.ArticleID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrKBArticleNotFound
+			}
+			return fmt.Errorf("failed to get article: %w", err)
+		}
+		var ticket models.Ticket
+		if err := tx.Select("id").First(&ticket, ticketID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrKBTicketNotFound
+			}
+			return fmt.Errorf("failed to get ticket: %w", err)
+		}
+
+		var count int64
+		tx.Model(&models.KBArticleTicketLink{}).
+			Where("article_id = ? AND ticket_id = ?", req.ArticleID, ticketID).
+			Count(&count)
+		if count > 0 {
+			return ErrKBAlreadyLinked
+		}
+
+		if err := tx.Create(link).Error; err != nil {
+			return fmt.Errorf("failed to link article: %w", err)
+		}
+
+		counters := map[string]interface{}{"link_count": gorm.Expr("link_count + 1")}
+		if req.AsResolution {
+			counters["resolution_count"] = gorm.Expr("resolution_count + 1")
+		}
+		if err := tx.Model(&models.KBArticle{}).Where("id = ?", req.ArticleID).UpdateColumns(counters).Error; err != nil {
+			return fmt.Errorf("failed to update article counters: %w", err)
+		}
+
+		description := fmt.Sprintf("关联知识库文章: %s", article.Title)
+		if req.AsResolution {
+			description = fmt.Sprintf("以知识库文章作为解决方案: %s", article.Title)
+		}
+		history := models.TicketHistory{
+			TicketID:    ticketID,
+			UserID:      &userID,
+			Action:      models.HistoryActionUpdate,
+			Description: description,
+			FieldName:   "kb_article",
+			NewValue:    fmt.Sprintf("%d", article.ID),
+			IsVisible:   true,
+		}
+		if err := tx.Create(&history).Error; err != nil {
+			return fmt.Errorf("failed to create ticket history: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return link, nil
+}
+
+// UnlinkFromTicket 取消文章与工单的关联并回退计数
+func (s *KBArticleService) UnlinkFromTicket(ctx context.Context, ticketID, articleID uint) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var link models.KBArticleTicketLink
+		if err := tx.Where("article_id = ? AND ticket_id = ?", articleID, ticketID).First(&link).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrKBLinkNotFound
+			}
+			return fmt.Errorf("failed to get article link: %w", err)
+		}
+		if err := tx.Delete(&link).Error; err != nil {
+			return fmt.Errorf("failed to delete article link: %w", err)
+		}
+
+		counters := map[string]interface{}{"link_count": gorm.Expr("link_count - 1")}
+		if link.LinkType == models.KBLinkResolution {
+			counters["resolution_count"] = gorm.Expr("resolution_count - 1")
+		}
+		return tx.Model(&models.KBArticle{}).Where("id = ?", articleID).UpdateColumns(counters).Error
+	})
+}
+
+// ListTicketArticles 列出工单关联的文章
+func (s *KBArticleService) ListTicketArticles(ctx context.Context, ticketID uint) ([]models.KBArticleTicketLink, error) {
+	var links []models.KBArticleTicketLink
+	err := s.db.WithContext(ctx).
+		Preload("Article").
+		Where("ticket_id = ?", ticketID).
+		Order("created_at ASC").
+		Find(&links).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ticket articles: %w", err)
+	}
+	return links, nil
+}
+
+// RecordDeflection 记录一次拦截：用户阅读文章后未再提交工单
+func (s *KBArticleService) RecordDeflection(ctx context.Context, id uint, role string) error {
+	article, err := s.find(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !article.IsVisibleTo(role) {
+		return ErrKBArticleNotFound
+	}
+	return s.db.WithContext(ctx).Model(&models.KBArticle{}).Where("id = ?", id).
+		UpdateColumn("deflection_count", gorm.Expr("deflection_count + 1")).Error
+}
+
+func (s *KBArticleService) find(ctx context.Context, id uint) (*models.KBArticle, error) {
+	var article models.KBArticle
+	if err := s.db.WithContext(ctx).Preload("Category").First(&article, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrKBArticleNotFound
+		}
+		return nil, fmt.Errorf("failed to get article: %w", err)
+	}
+	return &article, nil
+}
+
+// visibleArticles 按角色限定可见文章范围
+func (s *KBArticleService) visibleArticles(ctx context.Context, role string) *gorm.DB {
+	query := s.db.WithContext(ctx).Model(&models.KBArticle{})
+	if !models.IsKBStaffRole(role) {
+		query = query.Where("status = ? AND visibility = ?", models.KBArticleStatusPublished, models.KBVisibilityPublic)
+	}
+	return query
+}
+
+// normalizeKBTags 去除空白与重复标签
+func normalizeKBTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	result := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		result = append(result, tag)
+	}
+	return result
+}
+
+// extractKBKeywords 从文本中提取推荐关键词：拉丁词取长度≥3的单词，中文按相邻两字切分
+func extractKBKeywords(text string, max int) []string {
+	seen := make(map[string]bool)
+	keywords := make([]string, 0, max)
+	add := func(word string) bool {
+		if !seen[word] {
+			seen[word] = true
+			keywords = append(keywords, word)
+		}
+		return len(keywords) >= max
+	}
+
+	var latin []rune
+	var han []rune
+	flush := func() bool {
+		if len(latin) >= 3 && add(string(latin)) {
+			return true
+		}
+		latin = latin[:0]
+		for i := 0; i+1 < len(han); i++ {
+			if add(string(han[i : i+2])) {
+				return true
+			}
+		}
+		han = han[:0]
+		return false
+	}
+
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			if len(latin) > 0 && flush() {
+				return keywords
+			}
+			han = append(han, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if len(han) > 0 && flush() {
+				return keywords
+			}
+			latin = append(latin, r)
+		default:
+			if flush() {
+				return keywords
+			}
+		}
+	}
+	flush()
+	return keywords
+}
@@ -0,0 +1,388 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// liveQueueSafetyWindow 只推送早于该时长之前变更的工单，避免遗漏刚提交、时间戳略早的事务
+	liveQueueSafetyWindow = time.Second
+	liveQueueDefaultBatch = time.Second
+	liveQueueScanLimit    = 500
+	liveQueueDefaultLimit = 100
+	// liveQueueMaxKnown 每个订阅记录的已推送工单数上限，超出后不再为新工单发送 removed
+	liveQueueMaxKnown = 2000
+	// liveQueueMaxSubscriptions 每个连接的订阅数上限
+	liveQueueMaxSubscriptions = 10
+)
+
+var (
+	// ErrLiveQueueForbidden 仅在职客服可订阅工单列表
+	ErrLiveQueueForbidden = errors.New("live queue is only available to active staff")
+	// ErrLiveQueueSubscriptionLimit 连接的订阅数超过上限
+	ErrLiveQueueSubscriptionLimit = errors.New("too many live queue subscriptions")
+	// ErrInvalidLiveQueueCursor 列表变更游标无效
+	ErrInvalidLiveQueueCursor = errors.New("invalid live queue cursor")
+	// ErrLiveQueueCursorExpired 游标过期，需重新加载列表
+	ErrLiveQueueCursorExpired = errors.New("live queue cursor expired")
+)
+
+// liveQueueStaffRoles 可订阅工单列表的角色
+var liveQueueStaffRoles = []models.UserRole{models.RoleAgent, models.RoleSupervisor, models.RoleAdmin, "superuser"}
+
+// LiveQueueDeliver 向订阅方推送一批变更及推送后的游标
+type LiveQueueDeliver func(changes []*models.LiveQueueChange, cursor string)
+
+// liveQueueCursor 工单与删除记录的扫描位置，编码后作为不透明游标返回
+type liveQueueCursor struct {
+	IssuedAt   time.Time    `json:"i"`
+	Tickets    syncPosition `json:"t"`
+	Tombstones syncPosition `json:"d"`
+}
+
+func (c *liveQueueCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeLiveQueueCursor(value string) (*liveQueueCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidLiveQueueCursor
+	}
+	var cursor liveQueueCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.IssuedAt.IsZero() {
+		return nil, ErrInvalidLiveQueueCursor
+	}
+	return &cursor, nil
+}
+
+// liveQueueItem 扫描到的工单变更，Ticket 为空表示已删除
+type liveQueueItem struct {
+	TicketID uint
+	Ticket   *models.LiveQueueTicket
+	Event    models.LiveQueueEvent
+}
+
+// liveQueueSubscription 一个客户端列表的订阅
+type liveQueueSubscription struct {
+	userID  uint
+	filter  models.LiveQueueFilter
+	known   map[uint]bool // 该列表中已有的工单，离开筛选条件时推送 removed
+	deliver LiveQueueDeliver
+}
+
+// LiveQueueService 工单列表实时更新：按合并间隔扫描变更的工单，
+// 由服务端逐个判断各订阅的筛选条件并批量推送；同一游标也可通过 Changes 轮询
+type LiveQueueService struct {
+	db            *gorm.DB
+	configService *ConfigService
+	now           func() time.Time
+
+	mu            sync.Mutex
+	position      liveQueueCursor
+	subscriptions map[string]map[string]*liveQueueSubscription // 连接 -> 订阅ID -> 订阅
+
+	stop chan struct{}
+	done chan struct{}
+	once sync.Once
+}
+
+// NewLiveQueueService 创建工单列表实时更新服务
+func NewLiveQueueService(db *gorm.DB) *LiveQueueService {
+	service := &LiveQueueService{
+		db:            db,
+		configService: NewConfigService(db),
+		now:           time.Now,
+		subscriptions: make(map[string]map[string]*liveQueueSubscription),
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	service.position = service.startCursor()
+	return service
+}
+
+// startCursor 从当前时间开始的游标
+func (s *LiveQueueService) startCursor() liveQueueCursor {
+	now := s.now()
+	start := syncPosition{At: now.Add(-liveQueueSafetyWindow)}
+	return liveQueueCursor{IssuedAt: now, Tickets: start, Tombstones: start}
+}
+
+// Subscribe 为连接 owner 注册或替换订阅 id。ticketIDs 为客户端列表中已有的工单，
+// 它们离开筛选条件时会收到 removed。返回订阅生效时的游标，可在断线后用于轮询
+func (s *LiveQueueService) Subscribe(ctx context.Context, owner, id string, userID uint, filter *models.LiveQueueFilter, ticketIDs []uint, deliver LiveQueueDeliver) (string, error) {
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.User{}).
+		Where("id = ? AND role IN ? AND status = ?", userID, liveQueueStaffRoles, models.UserStatusActive).
+		Count(&count).Error; err != nil {
+		return "", fmt.Errorf("failed to check user role: %w", err)
+	}
+	if count == 0 {
+		return "", ErrLiveQueueForbidden
+	}
+
+	subscription := &liveQueueSubscription{
+		userID:  userID,
+		known:   make(map[uint]bool, len(ticketIDs)),
+		deliver: deliver,
+	}
+	if filter != nil {
+		subscription.filter = *filter
+	}
+	for _, ticketID := range ticketIDs {
+		if len(subscription.known) >= liveQueueMaxKnown {
+			break
+		}
+		subscription.known[ticketID] = true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	subscriptions := s.subscriptions[owner]
+	if subscriptions == nil {
+		subscriptions = make(map[string]*liveQueueSubscription)
+		s.subscriptions[owner] = subscriptions
+	}
+	if _, exists := subscriptions[id]; !exists && len(subscriptions) >= liveQueueMaxSubscriptions {
+		return "", ErrLiveQueueSubscriptionLimit
+	}
+	subscriptions[id] = subscription
+	return s.position.encode(), nil
+}
+
+// Unsubscribe 取消连接的某个订阅
+func (s *LiveQueueService) Unsubscribe(owner, id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if subscriptions := s.subscriptions[owner]; subscriptions != nil {
+		delete(subscriptions, id)
+		if len(subscriptions) == 0 {
+			delete(s.subscriptions, owner)
+		}
+	}
+}
+
+// UnsubscribeAll 连接断开时取消其全部订阅
+func (s *LiveQueueService) UnsubscribeAll(owner string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.subscriptions, owner)
+}
+
+// Start 按合并间隔在后台扫描并推送变更
+func (s *LiveQueueService) Start() {
+	go s.run()
+}
+
+// Stop 停止后台推送
+func (s *LiveQueueService) Stop() {
+	s.once.Do(func() {
+		close(s.stop)
+		<-s.done
+	})
+}
+
+func (s *LiveQueueService) run() {
+	defer close(s.done)
+
+	interval := liveQueueDefaultBatch
+	if value, err := s.configService.GetConfigInt(KeyLiveQueueBatchMillis); err == nil && value >= 200 {
+		interval = time.Duration(value) * time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			if err := s.dispatch(ctx); err != nil {
+				log.Printf("Failed to dispatch live queue changes: %v", err)
+			}
+			cancel()
+		}
+	}
+}
+
+// dispatch 扫描上次位置之后变更的工单，按订阅的筛选条件分别推送
+func (s *LiveQueueService) dispatch(ctx context.Context) error {
+	s.mu.Lock()
+	if len(s.subscriptions) == 0 {
+		// 无订阅时只推进位置
+		s.position = s.startCursor()
+		s.mu.Unlock()
+		return nil
+	}
+	cursor := s.position
+	s.mu.Unlock()
+
+	until := s.now().Add(-liveQueueSafetyWindow)
+	for {
+		items, next, more, err := s.scan(ctx, cursor, until, liveQueueScanLimit)
+		if err != nil {
+			return err
+		}
+		next.IssuedAt = s.now()
+		encoded := next.encode()
+
+		type delivery struct {
+			deliver LiveQueueDeliver
+			changes []*models.LiveQueueChange
+		}
+		var deliveries []delivery
+		s.mu.Lock()
+		s.position = next
+		for _, subscriptions := range s.subscriptions {
+			for _, subscription := range subscriptions {
+				if changes := subscription.apply(items); len(changes) > 0 {
+					deliveries = append(deliveries, delivery{subscription.deliver, changes})
+				}
+			}
+		}
+		s.mu.Unlock()
+
+		for _, d := range deliveries {
+			d.deliver(d.changes, encoded)
+		}
+		if !more {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// apply 生成该订阅需要的变更：符合筛选条件的工单，以及离开列表的已知工单
+func (sub *liveQueueSubscription) apply(items []*liveQueueItem) []*models.LiveQueueChange {
+	var changes []*models.LiveQueueChange
+	for _, item := range items {
+		if item.Ticket != nil && sub.filter.Matches(item.Ticket, sub.userID) {
+			if len(sub.known) < liveQueueMaxKnown {
+				sub.known[item.TicketID] = true
+			}
+			changes = append(changes, &models.LiveQueueChange{Event: item.Event, TicketID: item.TicketID, Ticket: item.Ticket})
+			continue
+		}
+		if sub.known[item.TicketID] {
+			delete(sub.known, item.TicketID)
+			changes = append(changes, &models.LiveQueueChange{Event: models.LiveQueueEventRemoved, TicketID: item.TicketID})
+		}
+	}
+	return changes
+}
+
+// Changes 游标之后符合筛选条件的变更，供 WebSocket 不可用时轮询。
+// 游标为空时不返回变更，仅返回从当前开始的游标；变更后不再符合条件的工单以 removed 返回
+func (s *LiveQueueService) Changes(ctx context.Context, userID uint, filter *models.LiveQueueFilter, cursorValue string, limit int) (*models.LiveQueueChanges, error) {
+	now := s.now()
+	if limit < 1 {
+		limit = liveQueueDefaultLimit
+	}
+	if limit > liveQueueScanLimit {
+		limit = liveQueueScanLimit
+	}
+	resp := &models.LiveQueueChanges{Changes: []*models.LiveQueueChange{}}
+	if cursorValue == "" {
+		cursor := s.startCursor()
+		resp.NextCursor = cursor.encode()
+		return resp, nil
+	}
+	cursor, err := decodeLiveQueueCursor(cursorValue)
+	if err != nil {
+		return nil, err
+	}
+	if cursor.IssuedAt.Before(now.Add(-syncTombstoneRetention)) {
+		return nil, ErrLiveQueueCursorExpired
+	}
+
+	items, next, more, err := s.scan(ctx, *cursor, now.Add(-liveQueueSafetyWindow), limit)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		if item.Ticket != nil && filter.Matches(item.Ticket, userID) {
+			resp.Changes = append(resp.Changes, &models.LiveQueueChange{Event: item.Event, TicketID: item.TicketID, Ticket: item.Ticket})
+		} else {
+			resp.Changes = append(resp.Changes, &models.LiveQueueChange{Event: models.LiveQueueEventRemoved, TicketID: item.TicketID})
+		}
+	}
+	next.IssuedAt = now
+	resp.NextCursor = next.encode()
+	resp.HasMore = more
+	return resp, nil
+}
+
+// scan 读取 cursor 之后、until 之前变更或删除的工单，并区分新建、分配与其他更新
+func (s *LiveQueueService) scan(ctx context.Context, cursor liveQueueCursor, until time.Time, limit int) ([]*liveQueueItem, liveQueueCursor, bool, error) {
+	db := s.db.WithContext(ctx)
+	next := cursor
+	more := false
+
+	var tickets []models.Ticket
+	ticketQuery := db.Model(&models.Ticket{}).Select("id", "ticket_number", "title", "status", "priority", "type",
+		"assigned_to_id", "assigned_team_id", "category_id", "created_by_id", "tags", "due_date", "sla_breached", "created_at", "updated_at")
+	if err := syncAfter(ticketQuery, "updated_at", cursor.Tickets, until).Limit(limit + 1).Find(&tickets).Error; err != nil {
+		return nil, next, false, fmt.Errorf("failed to scan ticket changes: %w", err)
+	}
+	if len(tickets) > limit {
+		tickets, more = tickets[:limit], true
+	}
+
+	items := make([]*liveQueueItem, 0, len(tickets))
+	if len(tickets) > 0 {
+		ticketIDs := make([]uint, 0, len(tickets))
+		for _, t := range tickets {
+			ticketIDs = append(ticketIDs, t.ID)
+		}
+		// 区间内有分配、转移、升级记录的工单
+		var reassigned []uint
+		if err := db.Model(&models.TicketHistory{}).
+			Where("ticket_id IN ? AND action IN ? AND created_at > ? AND created_at <= ?", ticketIDs, syncReassignActions, cursor.Tickets.At, until).
+			Distinct().Pluck("ticket_id", &reassigned).Error; err != nil {
+			return nil, next, false, fmt.Errorf("failed to scan ticket assignments: %w", err)
+		}
+		assigned := make(map[uint]bool, len(reassigned))
+		for _, id := range reassigned {
+			assigned[id] = true
+		}
+
+		for i := range tickets {
+			t := &tickets[i]
+			event := models.LiveQueueEventUpdated
+			switch {
+			case t.CreatedAt.After(cursor.Tickets.At):
+				event = models.LiveQueueEventCreated
+			case assigned[t.ID]:
+				event = models.LiveQueueEventAssigned
+			}
+			items = append(items, &liveQueueItem{TicketID: t.ID, Ticket: models.NewLiveQueueTicket(t), Event: event})
+			next.Tickets = syncPosition{At: t.UpdatedAt, ID: t.ID}
+		}
+	}
+
+	var tombstones []models.SyncTombstone
+	tombstoneQuery := db.Model(&models.SyncTombstone{}).Where("entity_type = ?", models.SyncEntityTicket)
+	if err := syncAfter(tombstoneQuery, "created_at", cursor.Tombstones, until).Limit(limit + 1).Find(&tombstones).Error; err != nil {
+		return nil, next, false, fmt.Errorf("failed to scan ticket deletions: %w", err)
+	}
+	if len(tombstones) > limit {
+		tombstones, more = tombstones[:limit], true
+	}
+	for _, t := range tombstones {
+		items = append(items, &liveQueueItem{TicketID: t.EntityID, Event: models.LiveQueueEventRemoved})
+		next.Tombstones = syncPosition{At: t.CreatedAt, ID: t.ID}
+	}
+	return items, next, more, nil
+}
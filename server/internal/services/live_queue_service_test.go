@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestLiveQueueService_FilteredBatchesAndCursor(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:live_queue_service_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Ticket{}, &models.TicketHistory{}, &models.SyncTombstone{}, &models.SystemConfig{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+
+	ctx := context.Background()
+	clock := time.Now().Add(-time.Hour)
+	svc := NewLiveQueueService(db)
+	svc.now = func() time.Time { return clock }
+	svc.position = svc.startCursor()
+
+	agent := models.User{Username: "lq-agent", Email: "lq-agent@example.com", PasswordHash: "x", Role: models.RoleAgent, Status: models.UserStatusActive}
+	customer := models.User{Username: "lq-customer", Email: "lq-customer@example.com", PasswordHash: "x", Role: models.RoleCustomer, Status: models.UserStatusActive}
+	for _, u := range []*models.User{&agent, &customer} {
+		if err := db.Create(u).Error; err != nil {
+			t.Fatalf("failed to seed user: %v", err)
+		}
+	}
+	newTicket := func(number string, status models.TicketStatus, at time.Time) *models.Ticket {
+		ticket := &models.Ticket{TicketNumber: number, Title: number, Status: status, Priority: models.TicketPriorityNormal,
+			Type: models.TicketTypeRequest, Source: models.TicketSourceWeb, CreatedByID: customer.ID, CreatedAt: at, UpdatedAt: at}
+		if err := db.Create(ticket).Error; err != nil {
+			t.Fatalf("failed to seed ticket: %v", err)
+		}
+		return ticket
+	}
+	existing := newTicket("LQ-1", models.TicketStatusOpen, clock.Add(-time.Hour))
+
+	var delivered [][]*models.LiveQueueChange
+	var lastCursor string
+	deliver := func(changes []*models.LiveQueueChange, cursor string) {
+		delivered = append(delivered, changes)
+		lastCursor = cursor
+	}
+	filter := &models.LiveQueueFilter{Status: []string{string(models.TicketStatusOpen)}}
+	if _, err := svc.Subscribe(ctx, "conn-2", "inbox", customer.ID, filter, nil, deliver); !errors.Is(err, ErrLiveQueueForbidden) {
+		t.Fatalf("expected customer subscription to be rejected, got %v", err)
+	}
+	startCursor, err := svc.Subscribe(ctx, "conn-1", "inbox", agent.ID, filter, []uint{existing.ID}, deliver)
+	if err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+
+	// 一个批次内：新建匹配工单、新建不匹配工单、已知工单离开筛选条件
+	clock = clock.Add(5 * time.Second)
+	created := newTicket("LQ-2", models.TicketStatusOpen, clock.Add(-3*time.Second))
+	newTicket("LQ-3", models.TicketStatusClosed, clock.Add(-3*time.Second))
+	db.Model(existing).UpdateColumns(map[string]interface{}{"status": models.TicketStatusResolved, "updated_at": clock.Add(-3 * time.Second)})
+	if err := svc.dispatch(ctx); err != nil {
+		t.Fatalf("dispatch failed: %v", err)
+	}
+	if len(delivered) != 1 || len(delivered[0]) != 2 {
+		t.Fatalf("expected one batch with two changes, got %+v", delivered)
+	}
+	if c := delivered[0][1]; c.Event != models.LiveQueueEventCreated || c.TicketID != created.ID || c.Ticket == nil || c.Ticket.TicketNumber != "LQ-2" {
+		t.Fatalf("unexpected created change: %+v", c)
+	}
+	if c := delivered[0][0]; c.Event != models.LiveQueueEventRemoved || c.TicketID != existing.ID || c.Ticket != nil {
+		t.Fatalf("unexpected removed change: %+v", c)
+	}
+	if lastCursor == "" {
+		t.Fatal("expected batch to carry a cursor")
+	}
+
+	// 分配记录使更新显示为 assigned
+	clock = clock.Add(5 * time.Second)
+	db.Model(created).UpdateColumns(map[string]interface{}{"assigned_to_id": agent.ID, "updated_at": clock.Add(-3 * time.Second)})
+	db.Create(&models.TicketHistory{TicketID: created.ID, UserID: &agent.ID, Action: models.HistoryActionAssign, Description: "assign",
+		CreatedAt: clock.Add(-3 * time.Second)})
+	if err := svc.dispatch(ctx); err != nil {
+		t.Fatalf("dispatch failed: %v", err)
+	}
+	if len(delivered) != 2 || len(delivered[1]) != 1 || delivered[1][0].Event != models.LiveQueueEventAssigned ||
+		delivered[1][0].Ticket.AssignedToID == nil || *delivered[1][0].Ticket.AssignedToID != agent.ID {
+		t.Fatalf("expected assigned change, got %+v", delivered)
+	}
+
+	// 删除的工单推送 removed；安全窗口内的变更留到下一批
+	clock = clock.Add(5 * time.Second)
+	db.Create(&models.SyncTombstone{EntityType: models.SyncEntityTicket, EntityID: created.ID, CreatedAt: clock.Add(-3 * time.Second)})
+	newTicket("LQ-4", models.TicketStatusOpen, clock)
+	if err := svc.dispatch(ctx); err != nil {
+		t.Fatalf("dispatch failed: %v", err)
+	}
+	if len(delivered) != 3 || len(delivered[2]) != 1 || delivered[2][0].Event != models.LiveQueueEventRemoved || delivered[2][0].TicketID != created.ID {
+		t.Fatalf("expected deletion to be pushed as removed, got %+v", delivered)
+	}
+
+	// 取消订阅后不再推送
+	svc.UnsubscribeAll("conn-1")
+	clock = clock.Add(5 * time.Second)
+	if err := svc.dispatch(ctx); err != nil {
+		t.Fatalf("dispatch failed: %v", err)
+	}
+	if len(delivered) != 3 {
+		t.Fatalf("expected no delivery after unsubscribe, got %d batches", len(delivered))
+	}
+
+	// 轮询：空游标只返回起点，旧游标分页返回全部变更
+	initial, err := svc.Changes(ctx, agent.ID, filter, "", 0)
+	if err != nil || len(initial.Changes) != 0 || initial.NextCursor == "" {
+		t.Fatalf("unexpected initial changes: %+v %v", initial, err)
+	}
+	if _, err := svc.Changes(ctx, agent.ID, filter, "not-a-cursor", 0); !errors.Is(err, ErrInvalidLiveQueueCursor) {
+		t.Fatalf("expected invalid cursor error, got %v", err)
+	}
+	page, err := svc.Changes(ctx, agent.ID, filter, startCursor, 1)
+	if err != nil || len(page.Changes) != 2 || !page.HasMore {
+		t.Fatalf("expected first page with one ticket and one deletion, got %+v %v", page, err)
+	}
+	var events []models.LiveQueueEvent
+	cursor := startCursor
+	for {
+		page, err := svc.Changes(ctx, agent.ID, filter, cursor, 2)
+		if err != nil {
+			t.Fatalf("changes failed: %v", err)
+		}
+		for _, c := range page.Changes {
+			events = append(events, c.Event)
+		}
+		cursor = page.NextCursor
+		if !page.HasMore {
+			break
+		}
+	}
+	// LQ-1 resolved、LQ-3 closed 不匹配及 LQ-2 删除返回 removed，LQ-2、LQ-4 返回最新状态
+	removed := 0
+	for _, e := range events {
+		if e == models.LiveQueueEventRemoved {
+			removed++
+		}
+	}
+	if len(events) != 5 || removed != 3 {
+		t.Fatalf("unexpected polled events: %v", events)
+	}
+
+	clock = clock.Add(syncTombstoneRetention + time.Hour)
+	if _, err := svc.Changes(ctx, agent.ID, filter, cursor, 0); !errors.Is(err, ErrLiveQueueCursorExpired) {
+		t.Fatalf("expected expired cursor error, got %v", err)
+	}
+}
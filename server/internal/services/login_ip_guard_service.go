@@ -0,0 +1,278 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+// KeyLoginIPProtection 按IP的登录暴力破解防护配置键
+const KeyLoginIPProtection = "security.login_ip_protection"
+
+var (
+	// ErrLoginIPBanned 该IP登录失败次数过多，暂时禁止登录
+	ErrLoginIPBanned = errors.New("too many failed login attempts from this IP address")
+	// ErrLoginIPBanNotFound 封禁记录不存在或已失效
+	ErrLoginIPBanNotFound = errors.New("login ip ban not found")
+)
+
+// LoginIPCounterStore 登录失败计数存储，database.ResilientRedis 满足该接口
+type LoginIPCounterStore interface {
+	Incr(ctx context.Context, key string) (int64, error)
+	Expire(ctx context.Context, key string, expiration time.Duration) error
+	Del(ctx context.Context, keys ...string) error
+}
+
+// LoginIPGuardService 按IP统计登录失败次数并临时封禁，防止攻击者轮换邮箱绕过按邮箱的限制。
+// 失败计数保存在 Redis 中由多个实例共享，Redis 不可用时降级为本实例内存计数；
+// 封禁记录保存在数据库中，供管理员查看和手动解封
+type LoginIPGuardService struct {
+	db    *gorm.DB
+	store LoginIPCounterStore
+	now   func() time.Time
+
+	mu       sync.Mutex
+	fallback map[string]*loginIPFailures
+}
+
+type loginIPFailures struct {
+	count   int64
+	expires time.Time
+}
+
+// NewLoginIPGuardService 创建IP登录防护服务，store 为 nil 时只使用内存计数
+func NewLoginIPGuardService(db *gorm.DB, store LoginIPCounterStore) *LoginIPGuardService {
+	return &LoginIPGuardService{
+		db:       db,
+		store:    store,
+		now:      time.Now,
+		fallback: make(map[string]*loginIPFailures),
+	}
+}
+
+// GetConfig 获取IP登录防护配置，未保存或无法解析时使用默认配置
+func (s *LoginIPGuardService) GetConfig(ctx context.Context) (*models.LoginIPProtectionConfig, error) {
+	var config models.SystemConfig
+	err := s.db.WithContext(ctx).
+		Where("key = ? AND is_active = ?", KeyLoginIPProtection, true).
+		First(&config).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.GetDefaultLoginIPProtectionConfig(), nil
+		}
+		return nil, fmt.Errorf("failed to get login ip protection config: %w", err)
+	}
+
+	cfg := models.GetDefaultLoginIPProtectionConfig()
+	if err := config.GetJSONValue(cfg); err != nil {
+		log.Printf("Warning: failed to parse login ip protection config, using defaults: %v", err)
+		return models.GetDefaultLoginIPProtectionConfig(), nil
+	}
+	if err := cfg.Validate(); err != nil {
+		log.Printf("Warning: invalid login ip protection config, using defaults: %v", err)
+		return models.GetDefaultLoginIPProtectionConfig(), nil
+	}
+	return cfg, nil
+}
+
+// SetConfig 保存IP登录防护配置，新的阈值从下一次登录失败开始生效
+func (s *LoginIPGuardService) SetConfig(ctx context.Context, cfg *models.LoginIPProtectionConfig, userID uint) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	var existing models.SystemConfig
+	err := s.db.WithContext(ctx).Where("key = ?", KeyLoginIPProtection).First(&existing).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to check existing config: %w", err)
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		config := models.SystemConfig{
+			Key:         KeyLoginIPProtection,
+			Category:    CategorySecurity,
+			Group:       "login",
+			Description: "按IP的登录暴力破解防护",
+			IsActive:    true,
+			UpdatedBy:   &userID,
+		}
+		if err := config.SetValue(cfg); err != nil {
+			return fmt.Errorf("failed to set config value: %w", err)
+		}
+		if err := s.db.WithContext(ctx).Create(&config).Error; err != nil {
+			return fmt.Errorf("failed to create config: %w", err)
+		}
+		return nil
+	}
+
+	if err := existing.SetValue(cfg); err != nil {
+		return fmt.Errorf("failed to set config value: %w", err)
+	}
+	existing.UpdatedBy = &userID
+	existing.Version++
+	if err := s.db.WithContext(ctx).Save(&existing).Error; err != nil {
+		return fmt.Errorf("failed to update config: %w", err)
+	}
+	return nil
+}
+
+// Check 检查IP是否被封禁，被封禁时返回 ErrLoginIPBanned；白名单中的IP不受限制
+func (s *LoginIPGuardService) Check(ctx context.Context, ip string) error {
+	if ip == "" {
+		return nil
+	}
+	cfg, err := s.GetConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if !cfg.Enabled || cfg.Allows(ip) {
+		return nil
+	}
+
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.LoginIPBan{}).
+		Where("ip_address = ? AND lifted_at IS NULL AND expires_at > ?", ip, s.now()).
+		Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check login ip ban: %w", err)
+	}
+	if count > 0 {
+		return ErrLoginIPBanned
+	}
+	return nil
+}
+
+// RecordFailure 记录一次登录失败，窗口内失败次数达到阈值时封禁该IP并返回封禁记录
+func (s *LoginIPGuardService) RecordFailure(ctx context.Context, ip string) (*models.LoginIPBan, error) {
+	if ip == "" {
+		return nil, nil
+	}
+	cfg, err := s.GetConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !cfg.Enabled || cfg.Allows(ip) {
+		return nil, nil
+	}
+
+	window := time.Duration(cfg.WindowMinutes) * time.Minute
+	count := s.incr(ctx, ip, window)
+	if count < int64(cfg.MaxFailures) {
+		return nil, nil
+	}
+
+	now := s.now()
+	ban := &models.LoginIPBan{
+		IPAddress:   ip,
+		FailedCount: int(count),
+		ExpiresAt:   now.Add(time.Duration(cfg.BanMinutes) * time.Minute),
+	}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 并发的失败请求可能同时达到阈值，已有生效中的封禁时不重复创建
+		var active int64
+		if err := tx.Model(&models.LoginIPBan{}).
+			Where("ip_address = ? AND lifted_at IS NULL AND expires_at > ?", ip, now).
+			Count(&active).Error; err != nil {
+			return err
+		}
+		if active > 0 {
+			ban = nil
+			return nil
+		}
+		return tx.Create(ban).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to ban login ip: %w", err)
+	}
+	s.reset(ctx, ip)
+	if ban != nil {
+		log.Printf("Login IP %s banned until %s after %d failed attempts", ip, ban.ExpiresAt.Format(time.RFC3339), ban.FailedCount)
+	}
+	return ban, nil
+}
+
+// ListActiveBans 获取当前生效的IP封禁，按到期时间排序
+func (s *LoginIPGuardService) ListActiveBans(ctx context.Context) ([]*models.LoginIPBan, error) {
+	var bans []*models.LoginIPBan
+	if err := s.db.WithContext(ctx).
+		Where("lifted_at IS NULL AND expires_at > ?", s.now()).
+		Order("expires_at ASC").
+		Find(&bans).Error; err != nil {
+		return nil, fmt.Errorf("failed to list login ip bans: %w", err)
+	}
+	return bans, nil
+}
+
+// Unban 手动解封IP，同时清空该IP的失败计数
+func (s *LoginIPGuardService) Unban(ctx context.Context, id uint, userID uint) (*models.LoginIPBan, error) {
+	var ban models.LoginIPBan
+	err := s.db.WithContext(ctx).
+		Where("id = ? AND lifted_at IS NULL AND expires_at > ?", id, s.now()).
+		First(&ban).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrLoginIPBanNotFound
+		}
+		return nil, fmt.Errorf("failed to get login ip ban: %w", err)
+	}
+
+	now := s.now()
+	if err := s.db.WithContext(ctx).Model(&ban).Updates(map[string]interface{}{
+		"lifted_at":    now,
+		"lifted_by_id": userID,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to lift login ip ban: %w", err)
+	}
+	ban.LiftedAt = &now
+	ban.LiftedByID = &userID
+	s.reset(ctx, ban.IPAddress)
+	return &ban, nil
+}
+
+// incr 累加失败计数，窗口从首次失败开始计算；Redis 不可用时使用内存计数
+func (s *LoginIPGuardService) incr(ctx context.Context, ip string, window time.Duration) int64 {
+	if s.store != nil {
+		key := loginIPFailureKey(ip)
+		if count, err := s.store.Incr(ctx, key); err == nil {
+			if count == 1 {
+				_ = s.store.Expire(ctx, key, window)
+			}
+			return count
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	entry, ok := s.fallback[ip]
+	if !ok || now.After(entry.expires) {
+		entry = &loginIPFailures{expires: now.Add(window)}
+		s.fallback[ip] = entry
+		// 顺带清理过期的内存计数
+		for key, e := range s.fallback {
+			if now.After(e.expires) {
+				delete(s.fallback, key)
+			}
+		}
+	}
+	entry.count++
+	return entry.count
+}
+
+func (s *LoginIPGuardService) reset(ctx context.Context, ip string) {
+	if s.store != nil {
+		_ = s.store.Del(ctx, loginIPFailureKey(ip))
+	}
+	s.mu.Lock()
+	delete(s.fallback, ip)
+	s.mu.Unlock()
+}
+
+func loginIPFailureKey(ip string) string {
+	return "login:ip_failures:" + ip
+}
@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+)
+
+// fakeLoginIPStore 内存实现的失败计数存储，down 为 true 时模拟 Redis 不可用
+type fakeLoginIPStore struct {
+	down   bool
+	counts map[string]int64
+}
+
+func (f *fakeLoginIPStore) Incr(ctx context.Context, key string) (int64, error) {
+	if f.down {
+		return 0, errors.New("connection refused")
+	}
+	f.counts[key]++
+	return f.counts[key], nil
+}
+
+func (f *fakeLoginIPStore) Expire(ctx context.Context, key string, expiration time.Duration) error {
+	return nil
+}
+
+func (f *fakeLoginIPStore) Del(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		delete(f.counts, key)
+	}
+	return nil
+}
+
+func TestLoginIPGuard_BansAfterThresholdAndUnbans(t *testing.T) {
+	db := newTestDB(t, &models.SystemConfig{}, &models.LoginIPBan{})
+	ctx := context.Background()
+	store := &fakeLoginIPStore{counts: map[string]int64{}}
+	guard := NewLoginIPGuardService(db, store)
+
+	cfg := models.GetDefaultLoginIPProtectionConfig()
+	cfg.MaxFailures = 3
+	cfg.Allowlist = []string{"10.0.0.0/8", " 192.168.1.5 "}
+	if err := guard.SetConfig(ctx, cfg, 1); err != nil {
+		t.Fatalf("set config failed: %v", err)
+	}
+	if err := guard.SetConfig(ctx, &models.LoginIPProtectionConfig{MaxFailures: 3, WindowMinutes: 1, BanMinutes: 1, Allowlist: []string{"office"}}, 1); err == nil {
+		t.Fatalf("expected invalid allowlist entry to be rejected")
+	}
+
+	for i := 0; i < 2; i++ {
+		if ban, err := guard.RecordFailure(ctx, "203.0.113.7"); err != nil || ban != nil {
+			t.Fatalf("expected no ban below threshold, got %+v, %v", ban, err)
+		}
+	}
+	ban, err := guard.RecordFailure(ctx, "203.0.113.7")
+	if err != nil || ban == nil || ban.FailedCount != 3 {
+		t.Fatalf("expected ban at threshold, got %+v, %v", ban, err)
+	}
+	if err := guard.Check(ctx, "203.0.113.7"); !errors.Is(err, ErrLoginIPBanned) {
+		t.Fatalf("expected banned ip to be rejected, got %v", err)
+	}
+	if err := guard.Check(ctx, "203.0.113.8"); err != nil {
+		t.Fatalf("expected other ip to be allowed, got %v", err)
+	}
+
+	// 白名单中的IP不计数也不封禁
+	for i := 0; i < 5; i++ {
+		guard.RecordFailure(ctx, "10.1.2.3")
+		guard.RecordFailure(ctx, "192.168.1.5")
+	}
+	if err := guard.Check(ctx, "10.1.2.3"); err != nil {
+		t.Fatalf("expected allowlisted range to be exempt, got %v", err)
+	}
+	bans, err := guard.ListActiveBans(ctx)
+	if err != nil || len(bans) != 1 || bans[0].IPAddress != "203.0.113.7" {
+		t.Fatalf("expected one active ban, got %+v, %v", bans, err)
+	}
+
+	// 手动解封后可立即登录，且失败计数重新开始
+	if _, err := guard.Unban(ctx, ban.ID, 1); err != nil {
+		t.Fatalf("unban failed: %v", err)
+	}
+	if _, err := guard.Unban(ctx, ban.ID, 1); !errors.Is(err, ErrLoginIPBanNotFound) {
+		t.Fatalf("expected lifted ban to be gone, got %v", err)
+	}
+	if err := guard.Check(ctx, "203.0.113.7"); err != nil {
+		t.Fatalf("expected unbanned ip to be allowed, got %v", err)
+	}
+	if ban, _ := guard.RecordFailure(ctx, "203.0.113.7"); ban != nil {
+		t.Fatalf("expected failure counter to be reset after unban")
+	}
+
+	// 封禁到期后自动失效
+	guard.RecordFailure(ctx, "198.51.100.1")
+	guard.RecordFailure(ctx, "198.51.100.1")
+	guard.RecordFailure(ctx, "198.51.100.1")
+	guard.now = func() time.Time { return time.Now().Add(31 * time.Minute) }
+	if err := guard.Check(ctx, "198.51.100.1"); err != nil {
+		t.Fatalf("expected expired ban to be ignored, got %v", err)
+	}
+}
+
+func TestLoginIPGuard_FallsBackToMemoryCounters(t *testing.T) {
+	db := newTestDB(t, &models.SystemConfig{}, &models.LoginIPBan{})
+	ctx := context.Background()
+	guard := NewLoginIPGuardService(db, &fakeLoginIPStore{down: true, counts: map[string]int64{}})
+
+	cfg := models.GetDefaultLoginIPProtectionConfig()
+	for i := 1; i < cfg.MaxFailures; i++ {
+		if ban, _ := guard.RecordFailure(ctx, "203.0.113.9"); ban != nil {
+			t.Fatalf("unexpected ban after %d failures", i)
+		}
+	}
+	if ban, err := guard.RecordFailure(ctx, "203.0.113.9"); err != nil || ban == nil {
+		t.Fatalf("expected memory counters to ban when redis is down, got %+v, %v", ban, err)
+	}
+
+	cfg.Enabled = false
+	guard.SetConfig(ctx, cfg, 1)
+	if err := guard.Check(ctx, "203.0.113.9"); err != nil {
+		t.Fatalf("expected disabled protection to allow all ips, got %v", err)
+	}
+}
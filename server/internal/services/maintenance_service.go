@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+// KeySystemMaintenanceMode 只读维护模式配置键
+const KeySystemMaintenanceMode = "system.maintenance_mode"
+
+// maintenanceCacheTTL 维护模式配置缓存时间，请求路径上只读缓存，避免每次访问数据库
+const maintenanceCacheTTL = 10 * time.Second
+
+// MaintenanceService 只读维护模式服务
+type MaintenanceService struct {
+	db *gorm.DB
+
+	mu       sync.RWMutex
+	cached   *models.MaintenanceMode
+	cachedAt time.Time
+}
+
+// NewMaintenanceService 创建维护模式服务
+func NewMaintenanceService(db *gorm.DB) *MaintenanceService {
+	return &MaintenanceService{db: db}
+}
+
+// GetMode 从配置存储读取维护模式配置
+func (s *MaintenanceService) GetMode(ctx context.Context) (*models.MaintenanceMode, error) {
+	var config models.SystemConfig
+	err := s.db.WithContext(ctx).
+		Where("key = ? AND is_active = ?", KeySystemMaintenanceMode, true).
+		First(&config).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.GetDefaultMaintenanceMode(), nil
+		}
+		return nil, fmt.Errorf("failed to get maintenance mode: %w", err)
+	}
+
+	mode := models.GetDefaultMaintenanceMode()
+	if err := config.GetJSONValue(mode); err != nil {
+		log.Printf("Warning: failed to parse maintenance mode config, using defaults: %v", err)
+		return models.GetDefaultMaintenanceMode(), nil
+	}
+
+	return mode, nil
+}
+
+// CurrentMode 获取缓存的维护模式配置；读取失败时沿用上一次的配置
+func (s *MaintenanceService) CurrentMode(ctx context.Context) *models.MaintenanceMode {
+	s.mu.RLock()
+	cached, cachedAt := s.cached, s.cachedAt
+	s.mu.RUnlock()
+
+	if cached != nil && time.Since(cachedAt) < maintenanceCacheTTL {
+		return cached
+	}
+
+	mode, err := s.GetMode(ctx)
+	if err != nil {
+		log.Printf("Warning: failed to refresh maintenance mode: %v", err)
+		if cached != nil {
+			return cached
+		}
+		return models.GetDefaultMaintenanceMode()
+	}
+
+	s.mu.Lock()
+	s.cached = mode
+	s.cachedAt = time.Now()
+	s.mu.Unlock()
+
+	return mode
+}
+
+// SetMode 保存维护模式配置并刷新缓存
+func (s *MaintenanceService) SetMode(ctx context.Context, mode *models.MaintenanceMode, userID uint) error {
+	if err := mode.Validate(); err != nil {
+		return err
+	}
+	if mode.Message == "" {
+		mode.Message = models.GetDefaultMaintenanceMode().Message
+	}
+	if mode.ExemptPrefixes == nil {
+		mode.ExemptPrefixes = models.GetDefaultMaintenanceMode().ExemptPrefixes
+	}
+
+	var existing models.SystemConfig
+	err := s.db.WithContext(ctx).Where("key = ?", KeySystemMaintenanceMode).First(&existing).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to check existing maintenance mode: %w", err)
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		config := models.SystemConfig{
+			Key:         KeySystemMaintenanceMode,
+			Category:    CategorySystem,
+			Group:       "maintenance",
+			Description: "只读维护模式",
+			IsActive:    true,
+			UpdatedBy:   &userID,
+		}
+		if err := config.SetValue(mode); err != nil {
+			return fmt.Errorf("failed to set maintenance mode value: %w", err)
+		}
+		if err := s.db.WithContext(ctx).Create(&config).Error; err != nil {
+			return fmt.Errorf("failed to create maintenance mode: %w", err)
+		}
+	} else {
+		if err := existing.SetValue(mode); err != nil {
+			return fmt.Errorf("failed to set maintenance mode value: %w", err)
+		}
+		existing.UpdatedBy = &userID
+		existing.Version++
+
+		if err := s.db.WithContext(ctx).Save(&existing).Error; err != nil {
+			return fmt.Errorf("failed to update maintenance mode: %w", err)
+		}
+	}
+
+	s.mu.Lock()
+	s.cached = mode
+	s.cachedAt = time.Now()
+	s.mu.Unlock()
+
+	return nil
+}
+
+// ExpireWindow 维护窗口结束后自动关闭维护模式，返回是否执行了关闭
+func (s *MaintenanceService) ExpireWindow(ctx context.Context) (bool, error) {
+	mode, err := s.GetMode(ctx)
+	if err != nil {
+		return false, err
+	}
+	if !mode.IsExpired(time.Now()) {
+		return false, nil
+	}
+
+	mode.Enabled = false
+	mode.StartAt = nil
+	mode.EndAt = nil
+	if err := s.SetMode(ctx, mode, 1); err != nil { // 系统用户
+		return false, err
+	}
+
+	log.Printf("Maintenance window ended, read-only mode disabled")
+	return true, nil
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+// notificationWebhookEvents Webhook 渠道通知对应的 Webhook 事件类型，未列出的通知类型不发送 Webhook
+var notificationWebhookEvents = map[models.NotificationType]models.WebhookEventType{
+	models.NotificationTypeTicketCreated:       models.WebhookEventTicketCreated,
+	models.NotificationTypeTicketAssigned:      models.WebhookEventTicketAssigned,
+	models.NotificationTypeTicketStatusChanged: models.WebhookEventTicketUpdated,
+	models.NotificationTypeTicketCommented:     models.WebhookEventTicketComment,
+	models.NotificationTypeTicketResolved:      models.WebhookEventTicketResolved,
+	models.NotificationTypeTicketClosed:        models.WebhookEventTicketClosed,
+	models.NotificationTypeSystemAlert:         models.WebhookEventSystemAlert,
+	models.NotificationTypeSystemMaintenance:   models.WebhookEventSystemAlert,
+}
+
+// recordNotificationDelivery 更新通知在某个渠道和目标上的投递记录，不存在时创建。
+// attempted 表示本次实际执行了发送，计入尝试次数；记录失败只写日志，不影响发送流程
+func recordNotificationDelivery(ctx context.Context, db *gorm.DB, notificationID uint, channel models.NotificationChannel,
+	target string, status models.NotificationDeliveryStatus, attempted bool, detail string) {
+	if notificationID == 0 {
+		return
+	}
+
+	delivery := models.NotificationDelivery{NotificationID: notificationID, Channel: channel, Target: target}
+	if err := db.WithContext(ctx).
+		Where("notification_id = ? AND channel = ? AND target = ?", notificationID, channel, target).
+		Attrs(models.NotificationDelivery{Status: status}).
+		FirstOrCreate(&delivery).Error; err != nil {
+		log.Printf("记录通知投递状态失败 (notification: %d, channel: %s): %v", notificationID, channel, err)
+		return
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":     status,
+		"last_error": detail,
+	}
+	if attempted {
+		updates["attempts"] = gorm.Expr("attempts + 1")
+		updates["last_attempt_at"] = now
+	}
+	if status == models.NotificationDeliveryDelivered {
+		updates["delivered_at"] = now
+	}
+	if err := db.WithContext(ctx).Model(&delivery).Updates(updates).Error; err != nil {
+		log.Printf("记录通知投递状态失败 (notification: %d, channel: %s): %v", notificationID, channel, err)
+	}
+}
+
+// trackEmailDelivery 按邮件通知的投递状态记录邮件渠道的投递结果，err 为发送流程返回的错误
+func trackEmailDelivery(ctx context.Context, db *gorm.DB, notification *models.Notification, err error) {
+	target := ""
+	if notification.Recipient != nil {
+		target = notification.Recipient.Email
+	}
+
+	status := notification.DeliveryStatus
+	switch {
+	case status == "delivered":
+		recordNotificationDelivery(ctx, db, notification.ID, models.NotificationChannelEmail, target,
+			models.NotificationDeliveryDelivered, true, "")
+	case status == deliveryStatusQueuedSMTP:
+		// 邮件通道不可用而排队，err 不为空表示本次实际尝试发送
+		recordNotificationDelivery(ctx, db, notification.ID, models.NotificationChannelEmail, target,
+			models.NotificationDeliveryPending, err != nil, notification.ErrorMessage)
+	case err != nil:
+		recordNotificationDelivery(ctx, db, notification.ID, models.NotificationChannelEmail, target,
+			models.NotificationDeliveryFailed, true, err.Error())
+	case strings.HasPrefix(status, "skipped_"):
+		recordNotificationDelivery(ctx, db, notification.ID, models.NotificationChannelEmail, target,
+			models.NotificationDeliverySkipped, false, strings.TrimPrefix(status, "skipped_"))
+	case status == deliveryStatusCoalescing:
+		recordNotificationDelivery(ctx, db, notification.ID, models.NotificationChannelEmail, target,
+			models.NotificationDeliveryPending, false, deliveryStatusCoalescing)
+	}
+}
+
+// trackWebhookDelivery 按 Webhook 日志的结果记录通知在该 Webhook 上的投递结果，目标为 Webhook 名称
+func trackWebhookDelivery(ctx context.Context, db *gorm.DB, notificationID uint, config *models.WebhookConfig, webhookLog *models.WebhookLog) {
+	status := models.NotificationDeliveryFailed
+	switch webhookLog.Status {
+	case "success":
+		status = models.NotificationDeliveryDelivered
+	case "retrying":
+		status = models.NotificationDeliveryPending
+	}
+	recordNotificationDelivery(ctx, db, notificationID, models.NotificationChannelWebhook, config.Name,
+		status, true, webhookLog.ErrorMessage)
+}
+
+// dispatchWebhookNotification 将 Webhook 渠道的通知转为对应事件发送到订阅了该事件的 Webhook
+func (ns *NotificationService) dispatchWebhookNotification(ctx context.Context, notification *models.Notification) error {
+	eventType, ok := notificationWebhookEvents[notification.Type]
+	if !ok {
+		recordNotificationDelivery(ctx, ns.db, notification.ID, models.NotificationChannelWebhook, "",
+			models.NotificationDeliverySkipped, false, fmt.Sprintf("no webhook event for notification type %s", notification.Type))
+		return nil
+	}
+
+	event := &NotificationEvent{
+		Type:         eventType,
+		ResourceID:   notification.ID,
+		ResourceType: "notification",
+		Title:        notification.Title,
+		Description:  notification.Content,
+		Data: map[string]interface{}{
+			"notification_id": notification.ID,
+			"recipient_id":    notification.RecipientID,
+			"priority":        notification.Priority,
+			"action_url":      notification.ActionURL,
+		},
+		Timestamp:      notification.CreatedAt,
+		UserID:         notification.SenderID,
+		NotificationID: &notification.ID,
+	}
+	if notification.RelatedTicketID != nil {
+		event.ResourceID = *notification.RelatedTicketID
+		event.ResourceType = "ticket"
+	}
+	return ns.SendNotification(ctx, event)
+}
+
+// GetNotificationDeliveries 获取通知在各渠道上的投递记录。接收者可查看自己的通知，管理员可查看任意通知
+func (ns *NotificationService) GetNotificationDeliveries(ctx context.Context, notificationID uint, userID uint, isAdmin bool) ([]*models.NotificationDelivery, error) {
+	var notification models.Notification
+	if err := ns.db.WithContext(ctx).Select("id", "recipient_id").First(&notification, notificationID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("通知不存在")
+		}
+		return nil, fmt.Errorf("查询通知失败: %w", err)
+	}
+	if !isAdmin && notification.RecipientID != userID {
+		return nil, fmt.Errorf("无权限操作此通知")
+	}
+
+	deliveries := make([]*models.NotificationDelivery, 0)
+	if err := ns.db.WithContext(ctx).
+		Where("notification_id = ?", notificationID).
+		Order("channel ASC, target ASC").
+		Find(&deliveries).Error; err != nil {
+		return nil, fmt.Errorf("获取通知投递记录失败: %w", err)
+	}
+	return deliveries, nil
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestNotificationDelivery_TracksEachChannel(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:notification_delivery_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Ticket{}, &models.Notification{}, &models.NotificationDelivery{}, &models.NotificationPreference{},
+		&models.SystemConfig{}, &models.EmailAddressStatus{}, &models.WebhookConfig{}, &models.WebhookLog{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+	ctx := context.Background()
+	ns := NewNotificationService(db)
+
+	user := models.User{Username: "nd-user", Email: "nd-user@example.com", PasswordHash: "x", Role: models.RoleAgent, Status: models.UserStatusActive}
+	other := models.User{Username: "nd-other", Email: "nd-other@example.com", PasswordHash: "x", Role: models.RoleAgent, Status: models.UserStatusActive}
+	db.Create(&user)
+	db.Create(&other)
+
+	deliveries := func(notificationID uint) map[models.NotificationChannel]*models.NotificationDelivery {
+		list, err := ns.GetNotificationDeliveries(ctx, notificationID, user.ID, false)
+		if err != nil {
+			t.Fatalf("get deliveries failed: %v", err)
+		}
+		byChannel := make(map[models.NotificationChannel]*models.NotificationDelivery)
+		for _, delivery := range list {
+			byChannel[delivery.Channel] = delivery
+		}
+		return byChannel
+	}
+
+	// 应用内通知写入收件箱即送达
+	inApp, err := ns.CreateNotification(ctx, &models.NotificationCreateRequest{Type: models.NotificationTypeSystemAlert, Title: "alert", Content: "alert", RecipientID: user.ID})
+	if err != nil {
+		t.Fatalf("create notification failed: %v", err)
+	}
+	if got := deliveries(inApp.ID)[models.NotificationChannelInApp]; got == nil || got.Status != models.NotificationDeliveryDelivered || got.DeliveredAt == nil {
+		t.Fatalf("unexpected in-app delivery %+v", got)
+	}
+
+	// 邮件发送失败后重试成功：同一条记录累计尝试次数
+	emails := NewEmailNotificationService(db, stubEmailConfigService{}, ns).(*EmailNotificationService)
+	failures := 1
+	emails.send = func(config *models.EmailConfig, to, subject, body string) error {
+		if failures > 0 {
+			failures--
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+	email := &models.Notification{Type: models.NotificationTypeSystemAlert, Title: "mail", Content: "mail", Channel: models.NotificationChannelEmail, RecipientID: user.ID}
+	db.Create(email)
+	if err := emails.SendEmailNotification(ctx, email); err == nil {
+		t.Fatal("expected first send to fail")
+	}
+	// 连接失败属于邮件通道错误，邮件排队等待重发
+	if got := deliveries(email.ID)[models.NotificationChannelEmail]; got == nil || got.Status != models.NotificationDeliveryPending || got.Attempts != 1 ||
+		got.Target != user.Email || got.LastError == "" {
+		t.Fatalf("unexpected queued email delivery %+v", got)
+	}
+	if err := emails.SendEmailNotification(ctx, email); err != nil {
+		t.Fatalf("retry failed: %v", err)
+	}
+	if got := deliveries(email.ID)[models.NotificationChannelEmail]; got.Status != models.NotificationDeliveryDelivered || got.Attempts != 2 || got.DeliveredAt == nil {
+		t.Fatalf("unexpected delivered email %+v", got)
+	}
+
+	// Webhook 通知按事件发送到订阅的 Webhook，目标为 Webhook 名称，失败待重试时为 pending；没有对应事件的通知类型记为跳过
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	hook := models.WebhookConfig{Name: "ops", Provider: models.WebhookProviderCustom, WebhookURL: server.URL, Status: models.WebhookStatusActive,
+		EnabledEventsObj: []models.WebhookEventType{models.WebhookEventSystemAlert}, CreatedBy: 1}
+	if err := db.Create(&hook).Error; err != nil {
+		t.Fatalf("failed to seed webhook: %v", err)
+	}
+	webhook := &models.Notification{Type: models.NotificationTypeSystemAlert, Title: "hook", Content: "hook", Channel: models.NotificationChannelWebhook, RecipientID: user.ID}
+	db.Create(webhook)
+	if err := ns.dispatchWebhookNotification(ctx, webhook); err != nil {
+		t.Fatalf("dispatch webhook failed: %v", err)
+	}
+	if got := deliveries(webhook.ID)[models.NotificationChannelWebhook]; got == nil || got.Status != models.NotificationDeliveryPending || got.Target != "ops" ||
+		got.Attempts != 1 || got.LastError == "" {
+		t.Fatalf("unexpected webhook delivery %+v", got)
+	}
+	mention := &models.Notification{Type: models.NotificationTypeUserMention, Title: "mention", Content: "mention", Channel: models.NotificationChannelWebhook, RecipientID: user.ID}
+	db.Create(mention)
+	ns.dispatchWebhookNotification(ctx, mention)
+	if got := deliveries(mention.ID)[models.NotificationChannelWebhook]; got == nil || got.Status != models.NotificationDeliverySkipped || got.Attempts != 0 {
+		t.Fatalf("unexpected skipped webhook delivery %+v", got)
+	}
+
+	// 其他用户不能查看，管理员可以
+	if _, err := ns.GetNotificationDeliveries(ctx, email.ID, other.ID, false); err == nil {
+		t.Fatal("expected other user to be forbidden")
+	}
+	if list, err := ns.GetNotificationDeliveries(ctx, email.ID, other.ID, true); err != nil || len(list) != 1 {
+		t.Fatalf("expected admin to see deliveries, got %d, %v", len(list), err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"crypto/sha256"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -28,7 +29,16 @@ type NotificationServiceInterface interface {
 	GetNotifications(ctx context.Context, filter *models.NotificationFilter) ([]*models.Notification, int64, error)
 	MarkAsRead(ctx context.Context, notificationID uint, userID uint) error
 	MarkAllAsRead(ctx context.Context, userID uint) error
+	ClearNotifications(ctx context.Context, userID uint, opts *models.NotificationClearOptions) (int64, error)
 	GetUnreadCount(ctx context.Context, userID uint) (int64, error)
+
+	// 通知分组
+	GetNotificationGroups(ctx context.Context, filter *models.NotificationFilter) ([]*models.NotificationGroup, int64, error)
+	GetGroupNotifications(ctx context.Context, groupID uint, userID uint) ([]*models.Notification, error)
+	MarkGroupAsRead(ctx context.Context, groupID uint, userID uint) (int64, error)
+
+	// 投递记录
+	GetNotificationDeliveries(ctx context.Context, notificationID uint, userID uint, isAdmin bool) ([]*models.NotificationDelivery, error)
 	
 	// 通知偏好设置
 	GetNotificationPreferences(ctx context.Context, userID uint) ([]*models.NotificationPreference, error)
@@ -42,6 +52,8 @@ type NotificationServiceInterface interface {
 	ProcessPendingEmailNotifications(ctx context.Context) error
 	RetryFailedEmailNotifications(ctx context.Context) error
 	SetEmailNotificationService(emailService EmailNotificationServiceInterface)
+	SetPushChannel(channel PushChannel)
+	SetScriptHooks(hooks *ScriptHookService)
 }
 
 // NotificationService 通知服务
@@ -49,6 +61,9 @@ type NotificationService struct {
 	db                      *gorm.DB
 	client                  *http.Client
 	emailNotificationService EmailNotificationServiceInterface
+	chatNotifier            *ChatNotifier
+	pushChannel             PushChannel
+	scriptHooks             *ScriptHookService
 }
 
 // NewNotificationService 创建通知服务实例
@@ -58,6 +73,7 @@ func NewNotificationService(db *gorm.DB) *NotificationService {
 		client: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		chatNotifier: NewChatNotifier(db),
 	}
 }
 
@@ -66,6 +82,16 @@ func (ns *NotificationService) SetEmailNotificationService(emailService EmailNot
 	ns.emailNotificationService = emailService
 }
 
+// SetPushChannel 设置浏览器推送渠道（依赖注入），未设置时不发送浏览器推送
+func (ns *NotificationService) SetPushChannel(channel PushChannel) {
+	ns.pushChannel = channel
+}
+
+// SetScriptHooks 设置脚本钩子服务（依赖注入），通知发送前执行通知钩子；未设置时不执行
+func (ns *NotificationService) SetScriptHooks(hooks *ScriptHookService) {
+	ns.scriptHooks = hooks
+}
+
 // NotificationEvent 通知事件
 type NotificationEvent struct {
 	Type       models.WebhookEventType `json:"type"`
@@ -77,6 +103,21 @@ type NotificationEvent struct {
 	Metadata   map[string]string       `json:"metadata"`
 	Timestamp  time.Time               `json:"timestamp"`
 	UserID     *uint                   `json:"user_id,omitempty"`
+
+	// Changes 工单字段变更列表（仅变更类事件），用于字段级订阅过滤，结构见 models.TicketFieldChange
+	Changes []models.TicketFieldChange `json:"changes,omitempty"`
+
+	// NotificationID 由 Webhook 渠道通知触发的事件关联的通知ID，发送结果记入该通知的投递记录
+	NotificationID *uint `json:"notification_id,omitempty"`
+}
+
+// ChangedFields 返回事件中发生变更的字段名
+func (e *NotificationEvent) ChangedFields() []string {
+	fields := make([]string, 0, len(e.Changes))
+	for _, change := range e.Changes {
+		fields = append(fields, change.Field)
+	}
+	return fields
 }
 
 // SendNotification 发送通知
@@ -87,10 +128,27 @@ func (ns *NotificationService) SendNotification(ctx context.Context, event *Noti
 		return fmt.Errorf("获取webhook配置失败: %w", err)
 	}
 
+	// 变更类事件按字段订阅过滤
+	if event.Changes != nil {
+		changedFields := event.ChangedFields()
+		matched := configs[:0]
+		for _, config := range configs {
+			if config.MatchesFieldChanges(changedFields) {
+				matched = append(matched, config)
+			}
+		}
+		configs = matched
+	}
+
 	if len(configs) == 0 {
 		// 没有配置的webhook，正常返回
+		if event.NotificationID != nil {
+			recordNotificationDelivery(ctx, ns.db, *event.NotificationID, models.NotificationChannelWebhook, "",
+				models.NotificationDeliverySkipped, false, fmt.Sprintf("no active webhook subscribed to %s", event.Type))
+		}
 		return nil
 	}
+	attachTicketExternalReferences(ctx, ns.db, event)
 
 	// 2. 并发发送通知
 	errChan := make(chan error, len(configs))
@@ -155,6 +213,9 @@ func (ns *NotificationService) sendWebhook(ctx context.Context, config *models.W
 		MaxRetries:   config.RetryCount,
 		Environment:  "development", // TODO: 从配置获取
 	}
+	if event.NotificationID != nil {
+		defer trackWebhookDelivery(ctx, ns.db, *event.NotificationID, config, log)
+	}
 
 	// 序列化事件数据
 	eventDataBytes, _ := json.Marshal(event)
@@ -170,7 +231,7 @@ func (ns *NotificationService) sendWebhook(ctx context.Context, config *models.W
 	}
 
 	// 构建请求
-	requestBody, err := ns.buildRequestBody(config, message)
+	requestBody, err := ns.buildEventRequestBody(config, message, event)
 	if err != nil {
 		log.Status = "failed"
 		log.ErrorMessage = fmt.Sprintf("构建请求失败: %v", err)
@@ -261,25 +322,9 @@ func (ns *NotificationService) generateMessage(config *models.WebhookConfig, eve
 	return ns.getDefaultMessage(config.Provider, event), nil
 }
 
-// renderTemplate 渲染消息模板
+// renderTemplate 渲染消息模板，语法见 RenderWebhookTemplate
 func (ns *NotificationService) renderTemplate(template string, event *NotificationEvent) (string, error) {
-	// 简单的模板变量替换
-	message := template
-	
-	// 替换基本变量
-	replacements := map[string]string{
-		"{{title}}":       event.Title,
-		"{{description}}": event.Description,
-		"{{type}}":        string(event.Type),
-		"{{resource_id}}": strconv.Itoa(int(event.ResourceID)),
-		"{{timestamp}}":   event.Timestamp.Format("2006-01-02 15:04:05"),
-	}
-
-	for placeholder, value := range replacements {
-		message = strings.ReplaceAll(message, placeholder, value)
-	}
-
-	return message, nil
+	return RenderWebhookTemplate(template, event)
 }
 
 // GetDefaultMessage 获取默认消息内容（公开方法用于测试）
@@ -384,6 +429,27 @@ func (ns *NotificationService) buildRequestBody(config *models.WebhookConfig, me
 	}
 }
 
+// buildEventRequestBody 构建携带事件数据的请求体
+// 自定义webhook额外附带事件类型、资源信息与字段变更（before/after），便于集成方按字段处理
+func (ns *NotificationService) buildEventRequestBody(config *models.WebhookConfig, message string, event *NotificationEvent) ([]byte, error) {
+	if config.Provider != models.WebhookProviderCustom {
+		return ns.buildRequestBody(config, message)
+	}
+
+	body := map[string]interface{}{
+		"text":          message,
+		"timestamp":     time.Now().Unix(),
+		"event":         event.Type,
+		"resource_id":   event.ResourceID,
+		"resource_type": event.ResourceType,
+		"data":          event.Data,
+	}
+	if event.Changes != nil {
+		body["changes"] = event.Changes
+	}
+	return json.Marshal(body)
+}
+
 // buildWeChatBody 构建企业微信请求体
 func (ns *NotificationService) buildWeChatBody(message string) ([]byte, error) {
 	return json.Marshal(map[string]interface{}{
@@ -516,6 +582,36 @@ func (ns *NotificationService) TestWebhook(ctx context.Context, configID uint) e
 	return ns.sendWebhook(ctx, &config, testEvent)
 }
 
+// WebhookRenderResult 模板试渲染结果
+type WebhookRenderResult struct {
+	Event   *NotificationEvent `json:"event"`   // 使用的示例事件
+	Message string             `json:"message"` // 模板渲染出的消息内容
+	Payload json.RawMessage    `json:"payload"` // 最终发送的请求体
+}
+
+// RenderTestWebhook 使用示例事件试渲染Webhook消息，不实际发送；template 非空时使用该模板代替已保存的模板
+func (ns *NotificationService) RenderTestWebhook(ctx context.Context, configID uint, event *NotificationEvent, template *string) (*WebhookRenderResult, error) {
+	var config models.WebhookConfig
+	if err := ns.db.WithContext(ctx).First(&config, configID).Error; err != nil {
+		return nil, fmt.Errorf("webhook配置不存在: %w", err)
+	}
+	if template != nil {
+		config.MessageTemplate = *template
+	}
+
+	message, err := ns.generateMessage(&config, event)
+	if err != nil {
+		return nil, fmt.Errorf("模板渲染失败: %w", err)
+	}
+
+	payload, err := ns.buildEventRequestBody(&config, message, event)
+	if err != nil {
+		return nil, fmt.Errorf("构建请求失败: %w", err)
+	}
+
+	return &WebhookRenderResult{Event: event, Message: message, Payload: payload}, nil
+}
+
 // RetryFailedWebhooks 重试失败的webhook
 func (ns *NotificationService) RetryFailedWebhooks(ctx context.Context) error {
 	var logs []models.WebhookLog
@@ -584,14 +680,39 @@ func (ns *NotificationService) CreateNotification(ctx context.Context, req *mode
 	if req.Metadata != nil {
 		metadataBytes, err := json.Marshal(req.Metadata)
 		if err == nil {
-			notification.Metadata = string(metadataBytes)
+			notification.Metadata = models.JSONText(metadataBytes)
 		}
 	}
 
+	// 脚本钩子可修改或跳过通知，跳过的通知不保存也不发送
+	if hooks := ns.scriptHooks; hooks != nil && hooks.DispatchNotification(ctx, notification) {
+		notification.DeliveryStatus = "skipped_hook"
+		return notification, nil
+	}
+
+	if err := ns.assignGroup(ctx, notification); err != nil {
+		return nil, err
+	}
+
 	if err := ns.db.Create(notification).Error; err != nil {
 		return nil, fmt.Errorf("创建通知失败: %w", err)
 	}
 
+	// 应用内通知写入收件箱即视为送达
+	if notification.Channel == models.NotificationChannelInApp || notification.Channel == models.NotificationChannelWebSocket {
+		recordNotificationDelivery(ctx, ns.db, notification.ID, models.NotificationChannelInApp, "",
+			models.NotificationDeliveryDelivered, true, "")
+	}
+
+	// Webhook 通知按通知类型对应的事件发送到订阅的 Webhook
+	if notification.Channel == models.NotificationChannelWebhook {
+		go func() {
+			if err := ns.dispatchWebhookNotification(context.Background(), notification); err != nil {
+				fmt.Printf("发送Webhook通知失败 (ID: %d): %v\n", notification.ID, err)
+			}
+		}()
+	}
+
 	// 如果是邮件通知，异步发送邮件
 	if notification.Channel == models.NotificationChannelEmail && ns.emailNotificationService != nil {
 		go func() {
@@ -604,6 +725,16 @@ func (ns *NotificationService) CreateNotification(ctx context.Context, req *mode
 		}()
 	}
 
+	// 应用内通知同时推送到用户已订阅的浏览器
+	if push := ns.pushChannel; push != nil &&
+		(notification.Channel == models.NotificationChannelInApp || notification.Channel == models.NotificationChannelPush) {
+		go func() {
+			if err := push.Deliver(context.Background(), notification); err != nil {
+				fmt.Printf("浏览器推送失败 (ID: %d): %v\n", notification.ID, err)
+			}
+		}()
+	}
+
 	// 性能优化：跳过预加载相关数据以提高创建速度
 	// 如果需要完整数据，调用方可以单独查询
 	// ns.db.Preload("Recipient").Preload("Sender").Preload("RelatedTicket").First(notification, notification.ID)
@@ -611,54 +742,16 @@ func (ns *NotificationService) CreateNotification(ctx context.Context, req *mode
 	return notification, nil
 }
 
+// notificationSortRegistry 通知列表允许排序的字段
+var notificationSortRegistry = NewSortRegistry(map[string]string{
+	"id": "id", "created_at": "created_at", "priority": "priority", "type": "type", "channel": "channel", "title": "title",
+	"recipient_id": "recipient_id", "sender_id": "sender_id", "related_ticket_id": "related_ticket_id",
+	"is_read": "is_read", "is_sent": "is_sent", "read_at": "read_at", "sent_at": "sent_at",
+}, models.SortField{Field: "created_at", Direction: models.SortDesc})
+
 // GetNotifications 获取通知列表
 func (ns *NotificationService) GetNotifications(ctx context.Context, filter *models.NotificationFilter) ([]*models.Notification, int64, error) {
-    baseQuery := ns.db.WithContext(ctx).Model(&models.Notification{})
-
-    // 应用过滤条件
-    if filter.RecipientID != nil {
-        baseQuery = baseQuery.Where("recipient_id = ?", *filter.RecipientID)
-    }
-    if filter.SenderID != nil {
-        baseQuery = baseQuery.Where("sender_id = ?", *filter.SenderID)
-    }
-    if len(filter.Types) > 0 {
-        baseQuery = baseQuery.Where("type IN ?", filter.Types)
-    }
-    if len(filter.Priorities) > 0 {
-        baseQuery = baseQuery.Where("priority IN ?", filter.Priorities)
-    }
-    if len(filter.Channels) > 0 {
-        baseQuery = baseQuery.Where("channel IN ?", filter.Channels)
-    }
-    if filter.IsRead != nil {
-        baseQuery = baseQuery.Where("is_read = ?", *filter.IsRead)
-    }
-    if filter.IsSent != nil {
-        baseQuery = baseQuery.Where("is_sent = ?", *filter.IsSent)
-    }
-    if filter.IsDelivered != nil {
-        baseQuery = baseQuery.Where("is_delivered = ?", *filter.IsDelivered)
-    }
-    if filter.RelatedType != "" {
-        baseQuery = baseQuery.Where("related_type = ?", filter.RelatedType)
-    }
-    if filter.RelatedID != nil {
-        baseQuery = baseQuery.Where("related_id = ?", *filter.RelatedID)
-    }
-    if filter.RelatedTicketID != nil {
-        baseQuery = baseQuery.Where("related_ticket_id = ?", *filter.RelatedTicketID)
-    }
-    if filter.CreatedAfter != nil {
-        baseQuery = baseQuery.Where("created_at >= ?", *filter.CreatedAfter)
-    }
-    if filter.CreatedBefore != nil {
-        baseQuery = baseQuery.Where("created_at <= ?", *filter.CreatedBefore)
-    }
-    if filter.Query != "" {
-        keyword := fmt.Sprintf("%%%s%%", filter.Query)
-        baseQuery = baseQuery.Where("title ILIKE ? OR content ILIKE ?", keyword, keyword)
-    }
+    baseQuery := ns.filterQuery(ctx, filter)
 
     // 统计总数
     var total int64
@@ -669,16 +762,19 @@ func (ns *NotificationService) GetNotifications(ctx context.Context, filter *mod
     // 构建数据查询
     dataQuery := baseQuery.Session(&gorm.Session{NewDB: true})
 
-    // 排序
-    orderBy := "created_at"
-    orderDir := "desc"
-    if filter.OrderBy != "" {
-        orderBy = filter.OrderBy
+    // 排序：只允许白名单字段
+    sort := filter.Sort
+    if len(sort) == 0 && filter.OrderBy != "" {
+        direction, err := ParseSortDirection(filter.OrderDir, models.SortDesc)
+        if err != nil {
+            return nil, 0, err
+        }
+        sort = []models.SortField{{Field: strings.ToLower(filter.OrderBy), Direction: direction}}
     }
-    if filter.OrderDir != "" {
-        orderDir = filter.OrderDir
+    dataQuery, err := notificationSortRegistry.Apply(dataQuery, sort)
+    if err != nil {
+        return nil, 0, err
     }
-    dataQuery = dataQuery.Order(fmt.Sprintf("%s %s", orderBy, orderDir))
 
     // 分页
     if filter.Limit > 0 {
@@ -699,6 +795,55 @@ func (ns *NotificationService) GetNotifications(ctx context.Context, filter *mod
     return notifications, total, nil
 }
 
+// filterQuery 按过滤条件构建通知查询
+func (ns *NotificationService) filterQuery(ctx context.Context, filter *models.NotificationFilter) *gorm.DB {
+	query := ns.db.WithContext(ctx).Model(&models.Notification{})
+	if filter.RecipientID != nil {
+		query = query.Where("recipient_id = ?", *filter.RecipientID)
+	}
+	if filter.SenderID != nil {
+		query = query.Where("sender_id = ?", *filter.SenderID)
+	}
+	if len(filter.Types) > 0 {
+		query = query.Where("type IN ?", filter.Types)
+	}
+	if len(filter.Priorities) > 0 {
+		query = query.Where("priority IN ?", filter.Priorities)
+	}
+	if len(filter.Channels) > 0 {
+		query = query.Where("channel IN ?", filter.Channels)
+	}
+	if filter.IsRead != nil {
+		query = query.Where("is_read = ?", *filter.IsRead)
+	}
+	if filter.IsSent != nil {
+		query = query.Where("is_sent = ?", *filter.IsSent)
+	}
+	if filter.IsDelivered != nil {
+		query = query.Where("is_delivered = ?", *filter.IsDelivered)
+	}
+	if filter.RelatedType != "" {
+		query = query.Where("related_type = ?", filter.RelatedType)
+	}
+	if filter.RelatedID != nil {
+		query = query.Where("related_id = ?", *filter.RelatedID)
+	}
+	if filter.RelatedTicketID != nil {
+		query = query.Where("related_ticket_id = ?", *filter.RelatedTicketID)
+	}
+	if filter.CreatedAfter != nil {
+		query = query.Where("created_at >= ?", *filter.CreatedAfter)
+	}
+	if filter.CreatedBefore != nil {
+		query = query.Where("created_at <= ?", *filter.CreatedBefore)
+	}
+	if filter.Query != "" {
+		keyword := fmt.Sprintf("%%%s%%", filter.Query)
+		query = query.Where(DialectOf(ns.db).AnyILike("title", "content"), keyword, keyword)
+	}
+	return query
+}
+
 // MarkAsRead 标记通知为已读
 func (ns *NotificationService) MarkAsRead(ctx context.Context, notificationID uint, userID uint) error {
 	var notification models.Notification
@@ -741,6 +886,42 @@ func (ns *NotificationService) MarkAllAsRead(ctx context.Context, userID uint) e
 	return nil
 }
 
+// ClearNotifications 按条件批量删除用户自己的通知
+func (ns *NotificationService) ClearNotifications(ctx context.Context, userID uint, opts *models.NotificationClearOptions) (int64, error) {
+	query := ns.db.WithContext(ctx).Where("recipient_id = ?", userID)
+	if opts != nil {
+		if opts.ReadOnly {
+			query = query.Where("is_read = ?", true)
+		}
+		if opts.OlderThan != nil {
+			query = query.Where("created_at < ?", *opts.OlderThan)
+		}
+	}
+
+	var ids []uint
+	if err := query.Model(&models.Notification{}).Pluck("id", &ids).Error; err != nil {
+		return 0, fmt.Errorf("清理通知失败: %w", err)
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	var deleted int64
+	err := ns.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id IN ?", ids).Delete(&models.Notification{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted = result.RowsAffected
+		return recordSyncTombstones(tx, models.SyncEntityNotification, &userID, ids)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("清理通知失败: %w", err)
+	}
+
+	return deleted, nil
+}
+
 // GetUnreadCount 获取未读通知数量
 func (ns *NotificationService) GetUnreadCount(ctx context.Context, userID uint) (int64, error) {
 	var count int64
@@ -752,6 +933,145 @@ func (ns *NotificationService) GetUnreadCount(ctx context.Context, userID uint)
 	return count, nil
 }
 
+// notificationGroupWindow 同一工单同类型通知与上一条间隔不超过该时间时归入同一分组
+const notificationGroupWindow = 30 * time.Minute
+
+// notificationGroupKey 分组标识，未分组的通知自成一组
+const notificationGroupKey = "COALESCE(group_id, id)"
+
+// assignGroup 为工单相关的站内通知查找同一接收者、同一类型、同一工单在时间窗口内的上一条通知，并加入其分组
+func (ns *NotificationService) assignGroup(ctx context.Context, notification *models.Notification) error {
+	if notification.RelatedTicketID == nil || notification.Channel == models.NotificationChannelEmail ||
+		notification.Channel == models.NotificationChannelWebhook {
+		return nil
+	}
+
+	var previous models.Notification
+	err := ns.db.WithContext(ctx).
+		Where("recipient_id = ? AND type = ? AND related_ticket_id = ? AND channel IN ? AND created_at >= ?",
+			notification.RecipientID, notification.Type, *notification.RelatedTicketID,
+			[]models.NotificationChannel{models.NotificationChannelInApp, models.NotificationChannelWebSocket},
+			time.Now().Add(-notificationGroupWindow)).
+		Order("id DESC").
+		First(&previous).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return fmt.Errorf("查询通知分组失败: %w", err)
+	}
+
+	groupID := previous.ID
+	if previous.GroupID != nil {
+		groupID = *previous.GroupID
+	} else if err := ns.db.WithContext(ctx).Model(&models.Notification{}).
+		Where("id = ?", previous.ID).UpdateColumn("group_id", previous.ID).Error; err != nil {
+		return fmt.Errorf("更新通知分组失败: %w", err)
+	}
+	notification.GroupID = &groupID
+	return nil
+}
+
+// notificationGroupRow 分组聚合结果
+type notificationGroupRow struct {
+	GroupKey    uint
+	Total       int64
+	UnreadCount int64
+	LatestID    uint
+}
+
+// GetNotificationGroups 按分组返回通知摘要，每组只返回最新一条通知及数量，按最新通知时间倒序
+func (ns *NotificationService) GetNotificationGroups(ctx context.Context, filter *models.NotificationFilter) ([]*models.NotificationGroup, int64, error) {
+	grouped := ns.filterQuery(ctx, filter).
+		Select(notificationGroupKey + " AS group_key, COUNT(*) AS total, " +
+			"SUM(CASE WHEN is_read THEN 0 ELSE 1 END) AS unread_count, MAX(id) AS latest_id").
+		Group(notificationGroupKey)
+
+	var total int64
+	if err := ns.db.WithContext(ctx).Table("(?) AS notification_groups", grouped).Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("统计通知分组失败: %w", err)
+	}
+
+	query := grouped.Order("latest_id DESC")
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	if filter.Offset > 0 {
+		query = query.Offset(filter.Offset)
+	}
+	var rows []notificationGroupRow
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, 0, fmt.Errorf("获取通知分组失败: %w", err)
+	}
+	if len(rows) == 0 {
+		return []*models.NotificationGroup{}, total, nil
+	}
+
+	latestIDs := make([]uint, 0, len(rows))
+	for _, row := range rows {
+		latestIDs = append(latestIDs, row.LatestID)
+	}
+	var latest []*models.Notification
+	if err := ns.db.WithContext(ctx).Preload("Sender").Preload("RelatedTicket").
+		Where("id IN ?", latestIDs).Find(&latest).Error; err != nil {
+		return nil, 0, fmt.Errorf("获取通知失败: %w", err)
+	}
+	latestByID := make(map[uint]*models.Notification, len(latest))
+	for _, notification := range latest {
+		latestByID[notification.ID] = notification
+	}
+
+	groups := make([]*models.NotificationGroup, 0, len(rows))
+	for _, row := range rows {
+		notification, ok := latestByID[row.LatestID]
+		if !ok {
+			continue
+		}
+		summary := notification.Title
+		if row.Total > 1 {
+			summary = fmt.Sprintf("%s（共 %d 条，%d 条未读）", notification.Title, row.Total, row.UnreadCount)
+		}
+		groups = append(groups, &models.NotificationGroup{
+			GroupID:         row.GroupKey,
+			Type:            notification.Type,
+			RelatedTicketID: notification.RelatedTicketID,
+			Count:           row.Total,
+			UnreadCount:     row.UnreadCount,
+			IsRead:          row.UnreadCount == 0,
+			Summary:         summary,
+			Latest:          notification.ToResponse(),
+		})
+	}
+	return groups, total, nil
+}
+
+// GetGroupNotifications 展开分组，返回用户在该组内的全部通知（新的在前）
+func (ns *NotificationService) GetGroupNotifications(ctx context.Context, groupID uint, userID uint) ([]*models.Notification, error) {
+	var notifications []*models.Notification
+	if err := ns.db.WithContext(ctx).Preload("Sender").Preload("RelatedTicket").
+		Where("recipient_id = ? AND "+notificationGroupKey+" = ?", userID, groupID).
+		Order("id DESC").
+		Find(&notifications).Error; err != nil {
+		return nil, fmt.Errorf("获取分组通知失败: %w", err)
+	}
+	if len(notifications) == 0 {
+		return nil, fmt.Errorf("通知不存在")
+	}
+	return notifications, nil
+}
+
+// MarkGroupAsRead 将用户在该组内的通知全部标记为已读，返回更新数量
+func (ns *NotificationService) MarkGroupAsRead(ctx context.Context, groupID uint, userID uint) (int64, error) {
+	now := time.Now()
+	result := ns.db.WithContext(ctx).Model(&models.Notification{}).
+		Where("recipient_id = ? AND is_read = ? AND "+notificationGroupKey+" = ?", userID, false, groupID).
+		Updates(map[string]interface{}{"is_read": true, "read_at": &now, "updated_at": now})
+	if result.Error != nil {
+		return 0, fmt.Errorf("分组标记已读失败: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
 // GetNotificationPreferences 获取用户通知偏好设置
 func (ns *NotificationService) GetNotificationPreferences(ctx context.Context, userID uint) ([]*models.NotificationPreference, error) {
 	var preferences []*models.NotificationPreference
@@ -825,6 +1145,11 @@ func (ns *NotificationService) NotifyTicketStatusChanged(ctx context.Context, ti
 		}
 	}
 
+	// 聊天渠道创建的工单，状态更新回复到来源会话
+	if err := ns.chatNotifier.NotifyStatusChanged(ctx, ticket, oldStatus); err != nil {
+		fmt.Printf("Failed to post chat status update: %v\n", err)
+	}
+
 	return nil
 }
 
@@ -0,0 +1,219 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+// KeySystemPaginationLimits 列表接口分页限制配置键
+const KeySystemPaginationLimits = "system.pagination_limits"
+
+// paginationPolicyCacheTTL 分页限制配置缓存时间，请求路径上只读缓存
+const paginationPolicyCacheTTL = 10 * time.Second
+
+// paginationAssumedPageSize 只传 page 未传每页条数时按各列表接口常用的默认值估算跳过的记录数
+const paginationAssumedPageSize = 20
+
+var (
+	// ErrPageSizeTooLarge 每页条数超过接口上限
+	ErrPageSizeTooLarge = errors.New("page size exceeds the maximum")
+	// ErrPaginationTooDeep 跳过的记录数超过上限，应改用游标分页或缩小筛选范围
+	ErrPaginationTooDeep = errors.New("pagination offset exceeds the maximum")
+)
+
+// PaginationGuard 列表接口分页守卫：拒绝超大的每页条数和过深的翻页，记录慢列表查询
+type PaginationGuard struct {
+	db *gorm.DB
+
+	mu       sync.RWMutex
+	cached   *models.PaginationPolicy
+	cachedAt time.Time
+}
+
+// paginationGuards 按数据库连接共享的分页守卫
+var paginationGuards sync.Map
+
+// NewPaginationGuard 获取分页守卫，同一数据库连接共用一个实例，
+// 中间件与服务层读取同一份缓存，修改配置后同时生效
+func NewPaginationGuard(db *gorm.DB) *PaginationGuard {
+	guard, _ := paginationGuards.LoadOrStore(db, &PaginationGuard{db: db})
+	return guard.(*PaginationGuard)
+}
+
+// GetPolicy 从配置存储读取分页限制
+func (g *PaginationGuard) GetPolicy(ctx context.Context) (*models.PaginationPolicy, error) {
+	var config models.SystemConfig
+	err := g.db.WithContext(ctx).
+		Where("key = ? AND is_active = ?", KeySystemPaginationLimits, true).
+		First(&config).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.GetDefaultPaginationPolicy(), nil
+		}
+		return nil, fmt.Errorf("failed to get pagination limits: %w", err)
+	}
+
+	policy := models.GetDefaultPaginationPolicy()
+	policy.MaxPageSizes = nil
+	if err := config.GetJSONValue(policy); err != nil {
+		log.Printf("Warning: failed to parse pagination limits, using defaults: %v", err)
+		return models.GetDefaultPaginationPolicy(), nil
+	}
+	if err := policy.Validate(); err != nil {
+		log.Printf("Warning: invalid pagination limits, using defaults: %v", err)
+		return models.GetDefaultPaginationPolicy(), nil
+	}
+	return policy, nil
+}
+
+// CurrentPolicy 获取缓存的分页限制；读取失败时沿用上一次的配置
+func (g *PaginationGuard) CurrentPolicy(ctx context.Context) *models.PaginationPolicy {
+	g.mu.RLock()
+	cached, cachedAt := g.cached, g.cachedAt
+	g.mu.RUnlock()
+
+	if cached != nil && time.Since(cachedAt) < paginationPolicyCacheTTL {
+		return cached
+	}
+
+	policy, err := g.GetPolicy(ctx)
+	if err != nil {
+		log.Printf("Warning: failed to refresh pagination limits: %v", err)
+		if cached != nil {
+			return cached
+		}
+		return models.GetDefaultPaginationPolicy()
+	}
+
+	g.mu.Lock()
+	g.cached = policy
+	g.cachedAt = time.Now()
+	g.mu.Unlock()
+
+	return policy
+}
+
+// SetPolicy 保存分页限制并刷新缓存，对后续请求立即生效
+func (g *PaginationGuard) SetPolicy(ctx context.Context, policy *models.PaginationPolicy, userID uint) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	var existing models.SystemConfig
+	err := g.db.WithContext(ctx).Where("key = ?", KeySystemPaginationLimits).First(&existing).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to check existing pagination limits: %w", err)
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		config := models.SystemConfig{
+			Key:         KeySystemPaginationLimits,
+			Category:    CategorySystem,
+			Group:       "performance",
+			Description: "列表接口分页限制",
+			IsActive:    true,
+			UpdatedBy:   &userID,
+		}
+		if err := config.SetValue(policy); err != nil {
+			return fmt.Errorf("failed to set pagination limits value: %w", err)
+		}
+		if err := g.db.WithContext(ctx).Create(&config).Error; err != nil {
+			return fmt.Errorf("failed to create pagination limits: %w", err)
+		}
+	} else {
+		if err := existing.SetValue(policy); err != nil {
+			return fmt.Errorf("failed to set pagination limits value: %w", err)
+		}
+		existing.UpdatedBy = &userID
+		existing.Version++
+
+		if err := g.db.WithContext(ctx).Save(&existing).Error; err != nil {
+			return fmt.Errorf("failed to update pagination limits: %w", err)
+		}
+	}
+
+	g.mu.Lock()
+	g.cached = policy
+	g.cachedAt = time.Now()
+	g.mu.Unlock()
+
+	return nil
+}
+
+// IsListRequest 请求是否带分页参数（page、page_size、limit、offset）
+func IsListRequest(query url.Values) bool {
+	for _, name := range []string{"page", "page_size", "limit", "offset"} {
+		if query.Has(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Check 校验列表请求的分页参数，endpoint 为 "GET /api/tickets" 形式的路由。
+// 每页条数取 page_size 或 limit，跳过的记录数取 offset 或 (page-1)×每页条数；无法解析的参数交由接口按默认值处理
+func (g *PaginationGuard) Check(ctx context.Context, endpoint string, query url.Values) error {
+	policy := g.CurrentPolicy(ctx)
+	if !policy.Enabled {
+		return nil
+	}
+
+	pageSize := 0
+	for _, name := range []string{"page_size", "limit"} {
+		if value, err := strconv.Atoi(query.Get(name)); err == nil && value > pageSize {
+			pageSize = value
+		}
+	}
+	if maxSize := policy.MaxPageSizeFor(endpoint); pageSize > maxSize {
+		return fmt.Errorf("%w: requested %d, maximum for %s is %d", ErrPageSizeTooLarge, pageSize, endpoint, maxSize)
+	}
+
+	if policy.MaxOffset == 0 {
+		return nil
+	}
+	offset := 0
+	if value, err := strconv.Atoi(query.Get("offset")); err == nil {
+		offset = value
+	} else if page, err := strconv.Atoi(query.Get("page")); err == nil && page > 1 {
+		size := pageSize
+		if size <= 0 {
+			size = paginationAssumedPageSize
+		}
+		offset = (page - 1) * size
+	}
+	if offset > policy.MaxOffset {
+		return fmt.Errorf("%w: skipping %d records, maximum is %d", ErrPaginationTooDeep, offset, policy.MaxOffset)
+	}
+	return nil
+}
+
+// CheckPage 校验分页参数在请求体中的列表接口（如 POST /api/tickets/query），规则与 Check 相同
+func (g *PaginationGuard) CheckPage(ctx context.Context, endpoint string, page, pageSize int) error {
+	query := url.Values{}
+	if page > 0 {
+		query.Set("page", strconv.Itoa(page))
+	}
+	if pageSize > 0 {
+		query.Set("page_size", strconv.Itoa(pageSize))
+	}
+	return g.Check(ctx, endpoint, query)
+}
+
+// ObserveList 记录列表请求耗时，超过慢查询阈值时写日志，返回是否为慢查询
+func (g *PaginationGuard) ObserveList(ctx context.Context, endpoint, rawQuery string, elapsed time.Duration) bool {
+	policy := g.CurrentPolicy(ctx)
+	if policy.SlowQueryMs <= 0 || elapsed < time.Duration(policy.SlowQueryMs)*time.Millisecond {
+		return false
+	}
+	log.Printf("Slow list query: %s?%s took %dms (threshold %dms)", endpoint, rawQuery, elapsed.Milliseconds(), policy.SlowQueryMs)
+	return true
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+)
+
+func TestPaginationGuard_PageSizeOffsetAndSlowQueries(t *testing.T) {
+	db := newTestDB(t, &models.SystemConfig{})
+	ctx := context.Background()
+	guard := NewPaginationGuard(db)
+	query := func(raw string) url.Values {
+		values, err := url.ParseQuery(raw)
+		if err != nil {
+			t.Fatalf("bad query %q: %v", raw, err)
+		}
+		return values
+	}
+
+	// 默认每页最多 100 条，单独配置的接口按各自上限；page_size 与 limit 取较大者
+	if err := guard.Check(ctx, "GET /api/tickets", query("page=1&page_size=100")); err != nil {
+		t.Fatalf("expected default maximum to be allowed, got %v", err)
+	}
+	if err := guard.Check(ctx, "GET /api/tickets", query("page_size=20&limit=500")); !errors.Is(err, ErrPageSizeTooLarge) {
+		t.Fatalf("expected oversized limit to be rejected, got %v", err)
+	}
+	if err := guard.Check(ctx, "GET /api/admin/audit-logs", query("limit=200")); err != nil {
+		t.Fatalf("expected endpoint override to allow 200, got %v", err)
+	}
+	if err := guard.Check(ctx, "GET /api/tickets", query("page_size=abc")); err != nil {
+		t.Fatalf("expected unparsable value to be left to the handler, got %v", err)
+	}
+
+	// 跳过的记录数：offset 优先，否则按 (page-1)×每页条数，未传每页条数时按 20 估算
+	if err := guard.Check(ctx, "GET /api/tickets", query("page=101&page_size=100")); err != nil {
+		t.Fatalf("expected offset 10000 to be allowed, got %v", err)
+	}
+	if err := guard.Check(ctx, "GET /api/tickets", query("page=102&page_size=100")); !errors.Is(err, ErrPaginationTooDeep) {
+		t.Fatalf("expected deep page to be rejected, got %v", err)
+	}
+	if err := guard.Check(ctx, "GET /api/tickets", query("page=600")); !errors.Is(err, ErrPaginationTooDeep) {
+		t.Fatalf("expected deep page with assumed page size to be rejected, got %v", err)
+	}
+	if err := guard.Check(ctx, "GET /api/notifications", query("offset=20000&limit=10")); !errors.Is(err, ErrPaginationTooDeep) {
+		t.Fatalf("expected deep offset to be rejected, got %v", err)
+	}
+
+	// 配置校验与生效
+	if err := guard.SetPolicy(ctx, &models.PaginationPolicy{Enabled: true, DefaultMaxPageSize: 50,
+		MaxPageSizes: map[string]int{"/api/tickets": 10}}, 1); err == nil {
+		t.Fatalf("expected endpoint without method to be rejected")
+	}
+	if err := guard.SetPolicy(ctx, &models.PaginationPolicy{Enabled: true, DefaultMaxPageSize: 50,
+		MaxPageSizes: map[string]int{"GET /api/tickets": 500}, MaxOffset: 0, SlowQueryMs: 200}, 1); err != nil {
+		t.Fatalf("set policy failed: %v", err)
+	}
+	if err := guard.Check(ctx, "GET /api/tickets", query("page=1000&page_size=500")); err != nil {
+		t.Fatalf("expected override and unlimited offset, got %v", err)
+	}
+	if err := guard.Check(ctx, "GET /api/users", query("page_size=51")); !errors.Is(err, ErrPageSizeTooLarge) {
+		t.Fatalf("expected new default maximum, got %v", err)
+	}
+	if stored, err := NewPaginationGuard(db).GetPolicy(ctx); err != nil || stored.DefaultMaxPageSize != 50 || stored.MaxPageSizes["GET /api/tickets"] != 500 {
+		t.Fatalf("unexpected stored policy %+v (%v)", stored, err)
+	}
+
+	if guard.ObserveList(ctx, "GET /api/tickets", "page=1", 150*time.Millisecond) {
+		t.Fatalf("expected fast request not to be logged")
+	}
+	if !guard.ObserveList(ctx, "GET /api/tickets", "page=1", 250*time.Millisecond) {
+		t.Fatalf("expected slow request to be logged")
+	}
+
+	guard.SetPolicy(ctx, &models.PaginationPolicy{Enabled: false, DefaultMaxPageSize: 10}, 1)
+	if err := guard.Check(ctx, "GET /api/tickets", query("page_size=1000&offset=99999999")); err != nil {
+		t.Fatalf("expected disabled policy to allow everything, got %v", err)
+	}
+	if !IsListRequest(query("limit=5")) || IsListRequest(query("status=open")) {
+		t.Fatalf("unexpected list request detection")
+	}
+}
@@ -0,0 +1,402 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+// KeyPIIScanConfig 工单内容敏感信息检测配置键
+const KeyPIIScanConfig = "security.pii_scan"
+
+// ErrPIIBlocked 内容包含敏感信息且处理方式为拒绝保存
+var ErrPIIBlocked = errors.New("content contains sensitive information")
+
+// PIIBlockedError 被拒绝保存的内容命中的敏感信息类型
+type PIIBlockedError struct {
+	Findings []models.PIIFinding
+}
+
+func (e *PIIBlockedError) Error() string {
+	types := make([]string, 0, len(e.Findings))
+	for _, finding := range e.Findings {
+		types = append(types, finding.Type)
+	}
+	return fmt.Sprintf("content contains sensitive information: %s", strings.Join(types, ", "))
+}
+
+// Is 使 errors.Is(err, ErrPIIBlocked) 成立
+func (e *PIIBlockedError) Is(target error) bool {
+	return target == ErrPIIBlocked
+}
+
+var (
+	piiCreditCardPattern = regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`)
+	piiNationalIDPattern = regexp.MustCompile(`\b\d{17}[\dXx]\b`)
+	// 只脱敏分隔符后的值，保留「密码:」等提示词
+	piiPasswordPattern = regexp.MustCompile(`(?i)(?:password|passwd|pwd|密码|口令)(?:\s*[:：=]|\s+is|\s*是|\s*为)\s*([^\s，。；、]+)`)
+)
+
+var piiDetectorLabels = map[string]string{
+	string(models.PIIDetectorCreditCard): "银行卡号",
+	string(models.PIIDetectorNationalID): "身份证号",
+	string(models.PIIDetectorPassword):   "密码",
+}
+
+// PIIScanService 工单内容敏感信息检测与脱敏
+type PIIScanService struct {
+	db *gorm.DB
+}
+
+// NewPIIScanService 创建敏感信息检测服务
+func NewPIIScanService(db *gorm.DB) *PIIScanService {
+	return &PIIScanService{db: db}
+}
+
+// GetConfig 获取检测配置
+func (s *PIIScanService) GetConfig(ctx context.Context) (*models.PIIScanConfig, error) {
+	var config models.SystemConfig
+	err := s.db.WithContext(ctx).
+		Where("key = ? AND is_active = ?", KeyPIIScanConfig, true).
+		First(&config).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.GetDefaultPIIScanConfig(), nil
+		}
+		return nil, fmt.Errorf("failed to get PII scan config: %w", err)
+	}
+
+	scanConfig := models.GetDefaultPIIScanConfig()
+	if err := config.GetJSONValue(scanConfig); err != nil {
+		log.Printf("Warning: failed to parse PII scan config, using defaults: %v", err)
+		return models.GetDefaultPIIScanConfig(), nil
+	}
+	if err := scanConfig.Validate(); err != nil {
+		log.Printf("Warning: invalid PII scan config, using defaults: %v", err)
+		return models.GetDefaultPIIScanConfig(), nil
+	}
+	return scanConfig, nil
+}
+
+// SetConfig 保存检测配置
+func (s *PIIScanService) SetConfig(ctx context.Context, scanConfig *models.PIIScanConfig, userID uint) error {
+	if err := scanConfig.Validate(); err != nil {
+		return err
+	}
+
+	var existing models.SystemConfig
+	err := s.db.WithContext(ctx).Where("key = ?", KeyPIIScanConfig).First(&existing).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to check existing config: %w", err)
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		config := models.SystemConfig{
+			Key:         KeyPIIScanConfig,
+			Category:    CategorySecurity,
+			Group:       "pii",
+			Description: "工单描述和评论的敏感信息检测与脱敏",
+			IsActive:    true,
+			UpdatedBy:   &userID,
+		}
+		if err := config.SetValue(scanConfig); err != nil {
+			return fmt.Errorf("failed to set config value: %w", err)
+		}
+		if err := s.db.WithContext(ctx).Create(&config).Error; err != nil {
+			return fmt.Errorf("failed to create config: %w", err)
+		}
+		return nil
+	}
+
+	if err := existing.SetValue(scanConfig); err != nil {
+		return fmt.Errorf("failed to set config value: %w", err)
+	}
+	existing.UpdatedBy = &userID
+	existing.Version++
+	if err := s.db.WithContext(ctx).Save(&existing).Error; err != nil {
+		return fmt.Errorf("failed to update config: %w", err)
+	}
+	return nil
+}
+
+// Scan 按工单分类的生效设置检测文本，未开启或未命中时返回 nil。
+// 处理方式为拒绝保存时返回 *PIIBlockedError
+func (s *PIIScanService) Scan(ctx context.Context, categoryID *uint, text string) (*models.PIIScanResult, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, nil
+	}
+	scanConfig, err := s.GetConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := ScanPII(scanConfig, categoryID, text)
+	if !result.Detected() {
+		return nil, nil
+	}
+	if result.Action == models.PIIActionBlock {
+		return nil, &PIIBlockedError{Findings: result.Findings}
+	}
+	return result, nil
+}
+
+// scanPIIContent 检测待保存的工单描述或评论，scanner 为空时不检测
+func scanPIIContent(ctx context.Context, scanner *PIIScanService, categoryID *uint, text string) (*models.PIIScanResult, error) {
+	if scanner != nil {
+		return scanner.Scan(ctx, categoryID, text)
+	}
+	return nil, nil
+}
+
+// recordPIIRedaction 记录检测结果，scanner 为空或未命中时不记录
+func recordPIIRedaction(ctx context.Context, tx *gorm.DB, scanner *PIIScanService, ticketID uint, commentID *uint, userID uint, result *models.PIIScanResult) error {
+	if scanner == nil || !result.Detected() {
+		return nil
+	}
+	return scanner.RecordRedaction(ctx, tx, ticketID, commentID, userID, result)
+}
+
+// piiSpan 命中的文本区间
+type piiSpan struct {
+	start, end int
+	kind       string
+}
+
+// ScanPII 按配置检测文本，结果中的 Masked 为脱敏后的文本。配置未开启或分类关闭检测时不检测
+func ScanPII(scanConfig *models.PIIScanConfig, categoryID *uint, text string) *models.PIIScanResult {
+	result := &models.PIIScanResult{Findings: []models.PIIFinding{}, Masked: text}
+	action, detectors, ok := scanConfig.ForCategory(categoryID)
+	if !ok {
+		return result
+	}
+
+	enabled := make(map[models.PIIDetector]bool, len(detectors))
+	for _, detector := range detectors {
+		enabled[detector] = true
+	}
+	// 身份证号先于银行卡号检测，同一串数字两者都通过校验时按身份证号处理
+	var spans []piiSpan
+	if enabled[models.PIIDetectorNationalID] {
+		for _, loc := range piiNationalIDPattern.FindAllStringIndex(text, -1) {
+			if isNationalIDValid(text[loc[0]:loc[1]]) {
+				spans = append(spans, piiSpan{loc[0], loc[1], string(models.PIIDetectorNationalID)})
+			}
+		}
+	}
+	if enabled[models.PIIDetectorCreditCard] {
+		for _, loc := range piiCreditCardPattern.FindAllStringIndex(text, -1) {
+			if isLuhnValid(text[loc[0]:loc[1]]) {
+				spans = append(spans, piiSpan{loc[0], loc[1], string(models.PIIDetectorCreditCard)})
+			}
+		}
+	}
+	if enabled[models.PIIDetectorPassword] {
+		for _, loc := range piiPasswordPattern.FindAllStringSubmatchIndex(text, -1) {
+			spans = append(spans, piiSpan{loc[2], loc[3], string(models.PIIDetectorPassword)})
+		}
+	}
+	for _, pattern := range scanConfig.CustomPatterns {
+		if !pattern.Enabled {
+			continue
+		}
+		re, err := regexp.Compile(pattern.Pattern)
+		if err != nil {
+			continue
+		}
+		for _, loc := range re.FindAllStringIndex(text, -1) {
+			if loc[1] > loc[0] {
+				spans = append(spans, piiSpan{loc[0], loc[1], pattern.Name})
+			}
+		}
+	}
+	if len(spans) == 0 {
+		return result
+	}
+
+	// 按位置合并重叠区间，先出现（或更长）的命中优先
+	sort.SliceStable(spans, func(i, j int) bool {
+		if spans[i].start != spans[j].start {
+			return spans[i].start < spans[j].start
+		}
+		return spans[i].end > spans[j].end
+	})
+	var masked strings.Builder
+	counts := make(map[string]int)
+	var order []string
+	last := 0
+	for _, span := range spans {
+		if span.start < last {
+			continue
+		}
+		masked.WriteString(text[last:span.start])
+		masked.WriteString(maskPIIValue(span.kind, text[span.start:span.end]))
+		last = span.end
+		if counts[span.kind] == 0 {
+			order = append(order, span.kind)
+		}
+		counts[span.kind]++
+	}
+	masked.WriteString(text[last:])
+
+	for _, kind := range order {
+		result.Findings = append(result.Findings, models.PIIFinding{Type: kind, Count: counts[kind]})
+	}
+	result.Action = action
+	result.Masked = masked.String()
+	return result
+}
+
+// maskPIIValue 卡号和身份证号保留末 4 位，密码固定替换为 8 个星号以免泄露长度，其他规则整体替换
+func maskPIIValue(kind, value string) string {
+	switch models.PIIDetector(kind) {
+	case models.PIIDetectorPassword:
+		return "********"
+	case models.PIIDetectorCreditCard, models.PIIDetectorNationalID:
+		keep := 4
+		var masked []byte
+		for i := len(value) - 1; i >= 0; i-- {
+			ch := value[i]
+			if ch == ' ' || ch == '-' {
+				masked = append(masked, ch)
+				continue
+			}
+			if keep > 0 {
+				masked = append(masked, ch)
+				keep--
+				continue
+			}
+			masked = append(masked, '*')
+		}
+		for i, j := 0, len(masked)-1; i < j; i, j = i+1, j-1 {
+			masked[i], masked[j] = masked[j], masked[i]
+		}
+		return string(masked)
+	}
+	return strings.Repeat("*", len([]rune(value)))
+}
+
+// isLuhnValid 银行卡号 Luhn 校验，忽略空格和连字符
+func isLuhnValid(value string) bool {
+	digits := strings.NewReplacer(" ", "", "-", "").Replace(value)
+	if len(digits) < 13 || len(digits) > 19 {
+		return false
+	}
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// isNationalIDValid 18 位居民身份证号校验码（GB 11643）
+func isNationalIDValid(value string) bool {
+	if len(value) != 18 {
+		return false
+	}
+	weights := []int{7, 9, 10, 5, 8, 4, 2, 1, 6, 3, 7, 9, 10, 5, 8, 4, 2}
+	sum := 0
+	for i, weight := range weights {
+		sum += int(value[i]-'0') * weight
+	}
+	check := "10X98765432"[sum%11]
+	return strings.ToUpper(value[17:]) == string(check)
+}
+
+// DescribePIIFindings 生成工单动态中的命中描述，如「银行卡号 2 处、密码 1 处」
+func DescribePIIFindings(findings []models.PIIFinding) string {
+	parts := make([]string, 0, len(findings))
+	for _, finding := range findings {
+		label := finding.Type
+		if builtin, ok := piiDetectorLabels[finding.Type]; ok {
+			label = builtin
+		}
+		parts = append(parts, fmt.Sprintf("%s %d 处", label, finding.Count))
+	}
+	return strings.Join(parts, "、")
+}
+
+// RecordRedaction 在工单动态中记录检测结果（仅客服可见），commentID 为空表示工单描述
+func (s *PIIScanService) RecordRedaction(ctx context.Context, tx *gorm.DB, ticketID uint, commentID *uint, userID uint, result *models.PIIScanResult) error {
+	field := "description"
+	subject := "工单描述"
+	if commentID != nil {
+		field = "comment"
+		subject = "评论"
+	}
+	outcome := "已脱敏保存"
+	if result.Action == models.PIIActionWarn {
+		outcome = "已按原文保存，请提醒提交人注意"
+	}
+	details, _ := json.Marshal(map[string]interface{}{
+		"action":   result.Action,
+		"findings": result.Findings,
+	})
+
+	history := &models.TicketHistory{
+		TicketID:    ticketID,
+		UserID:      &userID,
+		Action:      models.HistoryActionRedaction,
+		Description: fmt.Sprintf("%s中检测到敏感信息（%s），%s", subject, DescribePIIFindings(result.Findings), outcome),
+		Details:     string(details),
+		FieldName:   field,
+		NewValue:    string(result.Action),
+		CommentID:   commentID,
+		IsVisible:   false,
+		IsSystem:    true,
+		IsImportant: true,
+	}
+	if tx == nil {
+		tx = s.db
+	}
+	if err := tx.WithContext(ctx).Create(history).Error; err != nil {
+		return fmt.Errorf("failed to record redaction history: %w", err)
+	}
+	// is_visible 列默认为 true，插入时会忽略 false，单独更新为仅客服可见
+	if err := tx.WithContext(ctx).Model(history).UpdateColumn("is_visible", false).Error; err != nil {
+		return fmt.Errorf("failed to record redaction history: %w", err)
+	}
+	return nil
+}
+
+// ListRedactions 分页获取全部工单的敏感信息检测记录，按时间倒序
+func (s *PIIScanService) ListRedactions(ctx context.Context, since *time.Time, page, pageSize int) ([]*models.TicketHistory, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	query := s.db.WithContext(ctx).Model(&models.TicketHistory{}).Where("action = ?", models.HistoryActionRedaction)
+	if since != nil {
+		query = query.Where("created_at >= ?", *since)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count redactions: %w", err)
+	}
+	var histories []*models.TicketHistory
+	if err := query.Order("created_at DESC, id DESC").
+		Offset((page - 1) * pageSize).Limit(pageSize).
+		Find(&histories).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list redactions: %w", err)
+	}
+	return histories, total, nil
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestScanPII_DetectsAndMasksBuiltinAndCustomPatterns(t *testing.T) {
+	config := models.GetDefaultPIIScanConfig()
+	config.Enabled = true
+	config.CustomPatterns = []models.PIIPattern{{Name: "工号", Pattern: `EMP-\d{6}`, Enabled: true}}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+
+	text := "卡号 4111 1111 1111 1111，备用卡 4111111111111112，身份证 11010519491231002X，密码：Secr3t!，工号 EMP-123456，手机 13800138000"
+	result := ScanPII(config, nil, text)
+	want := "卡号 **** **** **** 1111，备用卡 4111111111111112，身份证 **************002X，密码：********，工号 **********，手机 13800138000"
+	if result.Masked != want {
+		t.Fatalf("unexpected masked text:\n got %s\nwant %s", result.Masked, want)
+	}
+	if len(result.Findings) != 4 || result.Findings[0].Type != "credit_card" || result.Findings[3].Type != "工号" || result.Action != models.PIIActionMask {
+		t.Fatalf("unexpected findings %+v", result)
+	}
+	if got := DescribePIIFindings(result.Findings); got != "银行卡号 1 处、身份证号 1 处、密码 1 处、工号 1 处" {
+		t.Fatalf("unexpected description %q", got)
+	}
+
+	// 「passwordisabled」等不含分隔符的文本不视为密码
+	if result := ScanPII(config, nil, "the passwordisabled flag"); result.Detected() {
+		t.Fatalf("expected no findings, got %+v", result.Findings)
+	}
+
+	for _, bad := range []models.PIIPattern{{Name: "坏规则", Pattern: `(`}, {Name: "空匹配", Pattern: `a*`}, {Name: "password", Pattern: `x`}} {
+		invalid := models.GetDefaultPIIScanConfig()
+		invalid.CustomPatterns = []models.PIIPattern{bad}
+		if err := invalid.Validate(); err == nil {
+			t.Fatalf("expected pattern %+v to be rejected", bad)
+		}
+	}
+}
+
+func TestPIIScan_TicketAndCommentActionsPerCategory(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:pii_scan_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Team{}, &models.Category{}, &models.Ticket{}, &models.TicketComment{}, &models.TicketHistory{},
+		&models.TicketAttachment{}, &models.SystemConfig{}, &models.QuotaAlert{}, &models.Notification{}, &models.NotificationPreference{},
+		&models.TicketCommentDraft{}, &models.TicketReplyLock{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+	ctx := context.Background()
+
+	agent := models.User{Username: "pii-agent", Email: "pii-agent@example.com", PasswordHash: "x", Role: models.RoleAgent, Status: models.UserStatusActive}
+	db.Create(&agent)
+	billing := models.Category{Name: "Billing", Slug: "pii-billing", Type: models.CategoryTypeBilling, Status: models.CategoryStatusActive}
+	internal := models.Category{Name: "Internal", Slug: "pii-internal", Type: models.CategoryTypeGeneral, Status: models.CategoryStatusActive}
+	hr := models.Category{Name: "HR", Slug: "pii-hr", Type: models.CategoryTypeGeneral, Status: models.CategoryStatusActive}
+	for _, category := range []*models.Category{&billing, &internal, &hr} {
+		db.Create(category)
+	}
+
+	svc := NewPIIScanService(db)
+
+	config := models.GetDefaultPIIScanConfig()
+	config.Enabled = true
+	config.Categories = []models.PIICategoryRule{
+		{CategoryID: internal.ID, Enabled: false},
+		{CategoryID: hr.ID, Enabled: true, Action: models.PIIActionBlock},
+		{CategoryID: billing.ID, Enabled: true, Action: models.PIIActionWarn, Detectors: []models.PIIDetector{models.PIIDetectorPassword}},
+	}
+	if err := svc.SetConfig(ctx, config, agent.ID); err != nil {
+		t.Fatalf("set config: %v", err)
+	}
+
+	tickets := NewTicketService(db).(*TicketService)
+	tickets.SetPIIScanner(svc)
+	create := func(categoryID *uint, description string) (*models.Ticket, error) {
+		return tickets.CreateTicket(ctx, &models.TicketCreateRequest{Title: "PII", Description: description, Priority: models.TicketPriorityNormal,
+			Type: models.TicketTypeIncident, Source: models.TicketSourceWeb, CategoryID: categoryID}, agent.ID)
+	}
+	redactions := func(ticketID uint) []models.TicketHistory {
+		var histories []models.TicketHistory
+		db.Where("ticket_id = ? AND action = ?", ticketID, models.HistoryActionRedaction).Order("id").Find(&histories)
+		return histories
+	}
+
+	// 全局设置：脱敏保存并记录动态
+	masked, err := create(nil, "我的卡号是 4111-1111-1111-1111")
+	if err != nil {
+		t.Fatalf("create masked ticket: %v", err)
+	}
+	if masked.Description != "我的卡号是 ****-****-****-1111" || masked.PIIScan == nil || masked.PIIScan.Action != models.PIIActionMask {
+		t.Fatalf("unexpected masked ticket %q %+v", masked.Description, masked.PIIScan)
+	}
+	if histories := redactions(masked.ID); len(histories) != 1 || histories[0].FieldName != "description" || histories[0].IsVisible {
+		t.Fatalf("unexpected redaction history %+v", histories)
+	}
+
+	// 分类关闭检测时原样保存；要求拒绝时不创建工单
+	if plain, err := create(&internal.ID, "卡号 4111111111111111"); err != nil || plain.Description != "卡号 4111111111111111" || plain.PIIScan != nil {
+		t.Fatalf("expected internal category to skip scanning, got %+v, %v", plain, err)
+	}
+	var blockedErr *PIIBlockedError
+	if _, err := create(&hr.ID, "身份证 11010519491231002X"); !errors.Is(err, ErrPIIBlocked) || !errors.As(err, &blockedErr) || blockedErr.Findings[0].Type != "national_id" {
+		t.Fatalf("expected HR category to block, got %v", err)
+	}
+
+	// 分类只检测密码且仅提示：卡号不计，密码原样保存并记录动态
+	warned, err := create(&billing.ID, "卡号 4111111111111111")
+	if err != nil || warned.PIIScan != nil {
+		t.Fatalf("expected billing detectors to ignore card numbers, got %+v, %v", warned, err)
+	}
+	comments := NewTicketCommentService(db, nil)
+	comments.SetPIIScanner(svc)
+	comment, err := comments.CreateComment(ctx, warned.ID, &models.TicketCommentCreateRequest{Content: "密码: hunter2", Type: models.CommentTypeInternal},
+		CommentViewer{UserID: agent.ID, Role: string(models.RoleAgent)})
+	if err != nil {
+		t.Fatalf("create comment: %v", err)
+	}
+	if comment.Content != "密码: hunter2" || comment.PIIScan == nil || comment.PIIScan.Action != models.PIIActionWarn {
+		t.Fatalf("unexpected warned comment %q %+v", comment.Content, comment.PIIScan)
+	}
+	histories := redactions(warned.ID)
+	if len(histories) != 1 || histories[0].CommentID == nil || *histories[0].CommentID != comment.ID || histories[0].NewValue != "warn" {
+		t.Fatalf("unexpected comment redaction history %+v", histories)
+	}
+
+	// 更新描述同样检测，动态中只出现脱敏后的内容
+	description := "新卡号 5500 0000 0000 0004"
+	updated, err := tickets.UpdateTicket(ctx, masked.ID, &models.TicketUpdateRequest{Description: &description}, agent.ID)
+	if err != nil || updated.Description != "新卡号 **** **** **** 0004" || updated.PIIScan == nil {
+		t.Fatalf("unexpected updated ticket %+v, %v", updated, err)
+	}
+	var change models.TicketHistory
+	db.Where("ticket_id = ? AND field_name = ? AND action = ?", masked.ID, "description", models.HistoryActionUpdate).First(&change)
+	if change.NewValue != "新卡号 **** **** **** 0004" {
+		t.Fatalf("expected history to store masked description, got %q", change.NewValue)
+	}
+
+	all, total, err := svc.ListRedactions(ctx, nil, 1, 20)
+	if err != nil || total != 3 || all[0].TicketID != masked.ID {
+		t.Fatalf("unexpected redaction list %d %+v, %v", total, all, err)
+	}
+}
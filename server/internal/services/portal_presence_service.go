@@ -0,0 +1,201 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// portalRealtimeTokenTTL 门户实时连接令牌的有效期，仅用于建立连接
+	portalRealtimeTokenTTL = 2 * time.Minute
+	// portalRealtimePath 门户实时连接地址
+	portalRealtimePath = "/api/portal/ws?token=%s"
+	// PortalTypingExpiry 客服输入提示的显示时长，客户端未收到后续事件时自动隐藏
+	PortalTypingExpiry = 6 * time.Second
+	// portalPresenceDefaultInterval 未配置时同一连接同一工单上事件的最小间隔
+	portalPresenceDefaultInterval = 2 * time.Second
+)
+
+var (
+	// ErrPortalTokenInvalid 门户实时连接令牌无效或已过期
+	ErrPortalTokenInvalid = errors.New("invalid or expired portal realtime token")
+	// ErrPresenceForbidden 无权查看或发送该工单的实时状态
+	ErrPresenceForbidden = errors.New("presence is not available for this ticket")
+	// ErrPresenceTicketNotFound 工单不存在
+	ErrPresenceTicketNotFound = errors.New("ticket not found")
+)
+
+// PortalPresenceClaims 门户实时连接令牌中的工单与客户
+type PortalPresenceClaims struct {
+	TicketID   uint
+	CustomerID uint
+}
+
+// PortalPresenceService 客户门户的输入/在线状态：为工单发起人签发实时连接令牌，
+// 校验客服的工单权限，按连接限制事件频率；是否向客户显示客服输入状态由管理员配置
+type PortalPresenceService struct {
+	db                 *gorm.DB
+	configService      *ConfigService
+	signer             *hmacSigner
+	categoryMembership *CategoryMembershipService
+	now                func() time.Time
+
+	mu        sync.Mutex
+	lastEvent map[string]map[string]time.Time // 连接 -> 工单:事件 -> 上次发送时间
+}
+
+// NewPortalPresenceService 创建客户门户实时状态服务
+func NewPortalPresenceService(db *gorm.DB) *PortalPresenceService {
+	configService := NewConfigService(db)
+	return &PortalPresenceService{
+		db:            db,
+		configService: configService,
+		signer:        newHMACSigner(configService, KeyPortalRealtimeSigningKey, "客户门户实时连接令牌的签名密钥", "portal_realtime"),
+		now:           time.Now,
+		lastEvent:     make(map[string]map[string]time.Time),
+	}
+}
+
+// SetCategoryMembershipService 开启分类权限时客服只能查看所属分类工单的客户状态
+func (s *PortalPresenceService) SetCategoryMembershipService(categoryMembership *CategoryMembershipService) {
+	s.categoryMembership = categoryMembership
+}
+
+// CustomerVisible 客户是否可以看到客服正在输入
+func (s *PortalPresenceService) CustomerVisible() bool {
+	visible, err := s.configService.GetConfigBool(KeyPortalPresenceVisible)
+	if err != nil {
+		return true
+	}
+	return visible
+}
+
+// IssueToken 为工单发起人签发门户实时连接令牌
+func (s *PortalPresenceService) IssueToken(ctx context.Context, ticketID, userID uint) (*models.PortalRealtimeToken, error) {
+	var ticket models.Ticket
+	if err := s.db.WithContext(ctx).Select("id", "created_by_id").First(&ticket, ticketID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPresenceTicketNotFound
+		}
+		return nil, fmt.Errorf("failed to get ticket: %w", err)
+	}
+	if ticket.CreatedByID != userID {
+		return nil, ErrPresenceForbidden
+	}
+
+	expiresAt := s.now().Add(portalRealtimeTokenTTL)
+	payload := fmt.Sprintf("%d.%d.%d", ticketID, userID, expiresAt.Unix())
+	signature, err := s.signer.Sign("portal-realtime:" + payload)
+	if err != nil {
+		return nil, err
+	}
+	token := payload + "." + signature
+	return &models.PortalRealtimeToken{
+		Token:     token,
+		TicketID:  ticketID,
+		ExpiresAt: expiresAt,
+		URL:       fmt.Sprintf(portalRealtimePath, url.QueryEscape(token)),
+	}, nil
+}
+
+// VerifyToken 校验门户实时连接令牌，并确认客户仍是工单发起人且账户有效
+func (s *PortalPresenceService) VerifyToken(ctx context.Context, token string) (*PortalPresenceClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 4 {
+		return nil, ErrPortalTokenInvalid
+	}
+	valid, err := s.signer.Verify("portal-realtime:"+strings.Join(parts[:3], "."), parts[3])
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		return nil, ErrPortalTokenInvalid
+	}
+	ticketID, err1 := strconv.ParseUint(parts[0], 10, 32)
+	userID, err2 := strconv.ParseUint(parts[1], 10, 32)
+	expires, err3 := strconv.ParseInt(parts[2], 10, 64)
+	if err1 != nil || err2 != nil || err3 != nil || s.now().Unix() > expires {
+		return nil, ErrPortalTokenInvalid
+	}
+
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.Ticket{}).
+		Joins("JOIN users ON users.id = tickets.created_by_id").
+		Where("tickets.id = ? AND tickets.created_by_id = ? AND users.status = ?", ticketID, userID, models.UserStatusActive).
+		Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to check ticket: %w", err)
+	}
+	if count == 0 {
+		return nil, ErrPortalTokenInvalid
+	}
+	return &PortalPresenceClaims{TicketID: uint(ticketID), CustomerID: uint(userID)}, nil
+}
+
+// AuthorizeAgent 检查客服是否可以查看工单的客户状态及向客户发送输入状态，返回工单发起人
+func (s *PortalPresenceService) AuthorizeAgent(ctx context.Context, ticketID, userID uint) (uint, error) {
+	var user models.User
+	if err := s.db.WithContext(ctx).Select("id", "role").
+		Where("id = ? AND role IN ? AND status = ?", userID, liveQueueStaffRoles, models.UserStatusActive).
+		First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, ErrPresenceForbidden
+		}
+		return 0, fmt.Errorf("failed to check user role: %w", err)
+	}
+
+	var ticket models.Ticket
+	if err := s.db.WithContext(ctx).Select("id", "created_by_id", "category_id", "assigned_to_id").First(&ticket, ticketID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return 0, ErrPresenceTicketNotFound
+		}
+		return 0, fmt.Errorf("failed to get ticket: %w", err)
+	}
+	if s.categoryMembership != nil {
+		if err := s.categoryMembership.CheckTicketAccess(ctx, &ticket, userID, string(user.Role)); err != nil {
+			if errors.Is(err, ErrCategoryAccessDenied) {
+				return 0, ErrPresenceForbidden
+			}
+			return 0, err
+		}
+	}
+	return ticket.CreatedByID, nil
+}
+
+// Allow 限制事件频率：同一连接在同一工单上的同类事件在最小间隔内只放行一次
+func (s *PortalPresenceService) Allow(conn string, ticketID uint, event string) bool {
+	interval := portalPresenceDefaultInterval
+	if v, err := s.configService.GetConfigInt(KeyPortalPresenceIntervalMs); err == nil && v > 0 {
+		interval = time.Duration(v) * time.Millisecond
+	}
+	now := s.now()
+	key := fmt.Sprintf("%d:%s", ticketID, event)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	events := s.lastEvent[conn]
+	if events == nil {
+		events = make(map[string]time.Time)
+		s.lastEvent[conn] = events
+	}
+	if last, ok := events[key]; ok && now.Sub(last) < interval {
+		return false
+	}
+	events[key] = now
+	return true
+}
+
+// Forget 连接断开时清除其频率记录
+func (s *PortalPresenceService) Forget(conn string) {
+	s.mu.Lock()
+	delete(s.lastEvent, conn)
+	s.mu.Unlock()
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+)
+
+func TestPortalPresence_TokensAccessAndRateLimit(t *testing.T) {
+	db := newTestDB(t, &models.User{}, &models.Category{}, &models.Ticket{}, &models.SystemConfig{}, &models.CategoryMembership{})
+	ctx := context.Background()
+	svc := NewPortalPresenceService(db)
+	now := time.Now()
+	svc.now = func() time.Time { return now }
+
+	customer := models.User{Username: "pp-customer", Email: "pp-customer@example.com", PasswordHash: "x", Role: models.RoleCustomer, Status: models.UserStatusActive}
+	stranger := models.User{Username: "pp-stranger", Email: "pp-stranger@example.com", PasswordHash: "x", Role: models.RoleCustomer, Status: models.UserStatusActive}
+	agent := models.User{Username: "pp-agent", Email: "pp-agent@example.com", PasswordHash: "x", Role: models.RoleAgent, Status: models.UserStatusActive}
+	for _, user := range []*models.User{&customer, &stranger, &agent} {
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("failed to seed user: %v", err)
+		}
+	}
+	category := models.Category{Name: "账务", Slug: "pp-billing", Type: models.CategoryTypeGeneral, Status: models.CategoryStatusActive, CreatedBy: agent.ID}
+	db.Create(&category)
+	ticket := models.Ticket{TicketNumber: "PP-1", Title: "VPN", Status: models.TicketStatusOpen, Priority: models.TicketPriorityNormal,
+		Type: models.TicketTypeIncident, Source: models.TicketSourceWeb, CreatedByID: customer.ID, CategoryID: &category.ID}
+	db.Create(&ticket)
+
+	// 令牌仅签发给工单发起人
+	if _, err := svc.IssueToken(ctx, ticket.ID, stranger.ID); !errors.Is(err, ErrPresenceForbidden) {
+		t.Fatalf("expected other customers to be rejected, got %v", err)
+	}
+	if _, err := svc.IssueToken(ctx, 9999, customer.ID); !errors.Is(err, ErrPresenceTicketNotFound) {
+		t.Fatalf("expected missing ticket, got %v", err)
+	}
+	token, err := svc.IssueToken(ctx, ticket.ID, customer.ID)
+	if err != nil || !strings.HasPrefix(token.URL, "/api/portal/ws?token=") {
+		t.Fatalf("unexpected token %+v (%v)", token, err)
+	}
+	claims, err := svc.VerifyToken(ctx, token.Token)
+	if err != nil || claims.TicketID != ticket.ID || claims.CustomerID != customer.ID {
+		t.Fatalf("expected token to verify, got %+v (%v)", claims, err)
+	}
+	if _, err := svc.VerifyToken(ctx, token.Token+"0"); !errors.Is(err, ErrPortalTokenInvalid) {
+		t.Fatalf("expected tampered token to be rejected, got %v", err)
+	}
+	now = now.Add(portalRealtimeTokenTTL + time.Second)
+	if _, err := svc.VerifyToken(ctx, token.Token); !errors.Is(err, ErrPortalTokenInvalid) {
+		t.Fatalf("expected expired token to be rejected, got %v", err)
+	}
+	token, _ = svc.IssueToken(ctx, ticket.ID, customer.ID)
+	db.Model(&customer).Update("status", models.UserStatusInactive)
+	if _, err := svc.VerifyToken(ctx, token.Token); !errors.Is(err, ErrPortalTokenInvalid) {
+		t.Fatalf("expected inactive customer to be rejected, got %v", err)
+	}
+
+	// 客服：仅在职客服，开启分类权限后需属于工单所在分类
+	if _, err := svc.AuthorizeAgent(ctx, ticket.ID, stranger.ID); !errors.Is(err, ErrPresenceForbidden) {
+		t.Fatalf("expected customers to be rejected, got %v", err)
+	}
+	if customerID, err := svc.AuthorizeAgent(ctx, ticket.ID, agent.ID); err != nil || customerID != customer.ID {
+		t.Fatalf("expected agent to be allowed, got %d (%v)", customerID, err)
+	}
+	svc.SetCategoryMembershipService(NewCategoryMembershipService(db))
+	if err := NewConfigService(db).SetConfig(KeyTicketCategoryScopeEnabled, "true", "bool", "", CategoryTicket, "workflow"); err != nil {
+		t.Fatalf("failed to enable category scope: %v", err)
+	}
+	if _, err := svc.AuthorizeAgent(ctx, ticket.ID, agent.ID); !errors.Is(err, ErrPresenceForbidden) {
+		t.Fatalf("expected agent outside the category to be rejected, got %v", err)
+	}
+
+	// 事件频率：同一连接同一工单同类事件在间隔内只放行一次
+	if !svc.Allow("1", ticket.ID, "typing") || svc.Allow("1", ticket.ID, "typing") {
+		t.Fatalf("expected repeated typing event to be throttled")
+	}
+	if !svc.Allow("1", ticket.ID, "typing_stop") || !svc.Allow("2", ticket.ID, "typing") {
+		t.Fatalf("expected other events and connections to be independent")
+	}
+	now = now.Add(portalPresenceDefaultInterval)
+	if !svc.Allow("1", ticket.ID, "typing") {
+		t.Fatalf("expected typing event after the interval")
+	}
+	svc.Forget("1")
+	if !svc.Allow("1", ticket.ID, "typing") {
+		t.Fatalf("expected forgotten connection to start over")
+	}
+
+	// 管理员可关闭客户可见的输入状态
+	if !svc.CustomerVisible() {
+		t.Fatalf("expected presence to be visible by default")
+	}
+	NewConfigService(db).SetConfig(KeyPortalPresenceVisible, "false", "bool", "", CategoryNotify, "portal_presence")
+	if svc.CustomerVisible() {
+		t.Fatalf("expected presence to be hidden")
+	}
+}
@@ -0,0 +1,332 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	prefillLinkPath           = "/tickets/new?prefill="
+	prefillMaxCustomFields    = 30
+	prefillMaxCustomFieldSize = 1000 // 单个字符串值的最大字符数
+)
+
+var (
+	// ErrPrefillLinkNotFound 预填链接不存在
+	ErrPrefillLinkNotFound = errors.New("prefill link not found")
+	// ErrInvalidPrefillLink 预填内容无效，如分类已停用、自定义字段不合法
+	ErrInvalidPrefillLink = errors.New("invalid prefill link")
+	// ErrInvalidPrefillToken 令牌格式错误、签名不匹配或链接已被修改
+	ErrInvalidPrefillToken = errors.New("invalid prefill token")
+	// ErrPrefillLinkExpired 链接已过期或已撤销
+	ErrPrefillLinkExpired = errors.New("prefill link expired or revoked")
+)
+
+var prefillFieldKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_]{1,64}$`)
+
+// prefillPayload 编码进链接的预填内容，键名尽量短以缩短链接
+type prefillPayload struct {
+	ID           uint                   `json:"id"`
+	Version      int                    `json:"v"`
+	CategoryID   *uint                  `json:"c,omitempty"`
+	TitlePrefix  string                 `json:"t,omitempty"`
+	Type         models.TicketType      `json:"y,omitempty"`
+	Priority     models.TicketPriority  `json:"p,omitempty"`
+	CustomFields map[string]interface{} `json:"f,omitempty"`
+}
+
+// PrefillLinkService 建单预填链接：管理员生成带签名的链接，用户打开时校验并展开为建单表单默认值
+type PrefillLinkService struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+// NewPrefillLinkService 创建预填链接服务
+func NewPrefillLinkService(db *gorm.DB) *PrefillLinkService {
+	return &PrefillLinkService{db: db, now: time.Now}
+}
+
+// List 获取预填链接及使用统计
+func (s *PrefillLinkService) List(ctx context.Context) ([]*models.PrefillLink, error) {
+	var links []*models.PrefillLink
+	if err := s.db.WithContext(ctx).Order("id DESC").Find(&links).Error; err != nil {
+		return nil, fmt.Errorf("failed to list prefill links: %w", err)
+	}
+	for _, link := range links {
+		s.attachToken(link)
+	}
+	return links, nil
+}
+
+// Get 获取预填链接
+func (s *PrefillLinkService) Get(ctx context.Context, id uint) (*models.PrefillLink, error) {
+	var link models.PrefillLink
+	if err := s.db.WithContext(ctx).First(&link, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPrefillLinkNotFound
+		}
+		return nil, fmt.Errorf("failed to get prefill link: %w", err)
+	}
+	s.attachToken(&link)
+	return &link, nil
+}
+
+// Create 校验预填内容并生成签名链接
+func (s *PrefillLinkService) Create(ctx context.Context, req *models.PrefillLinkRequest, userID uint) (*models.PrefillLink, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate prefill secret: %w", err)
+	}
+	link := &models.PrefillLink{Secret: hex.EncodeToString(secret), Version: 1, CreatedByID: userID}
+	if err := s.apply(ctx, link, req); err != nil {
+		return nil, err
+	}
+	if err := s.db.WithContext(ctx).Create(link).Error; err != nil {
+		return nil, fmt.Errorf("failed to create prefill link: %w", err)
+	}
+	s.attachToken(link)
+	return link, nil
+}
+
+// Update 更新预填链接；预填内容变化时递增版本，此前分发的链接失效。
+// 只修改名称、说明或有效期时原链接继续可用
+func (s *PrefillLinkService) Update(ctx context.Context, id uint, req *models.PrefillLinkRequest) (*models.PrefillLink, error) {
+	link, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	before := prefillPayloadOf(link)
+	if err := s.apply(ctx, link, req); err != nil {
+		return nil, err
+	}
+	if after := prefillPayloadOf(link); !reflect.DeepEqual(before, after) {
+		link.Version++
+	}
+	if err := s.db.WithContext(ctx).Save(link).Error; err != nil {
+		return nil, fmt.Errorf("failed to update prefill link: %w", err)
+	}
+	s.attachToken(link)
+	return link, nil
+}
+
+// Revoke 撤销预填链接，保留使用统计
+func (s *PrefillLinkService) Revoke(ctx context.Context, id uint) (*models.PrefillLink, error) {
+	link, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if link.RevokedAt == nil {
+		now := s.now()
+		link.RevokedAt = &now
+		if err := s.db.WithContext(ctx).Model(link).UpdateColumn("revoked_at", now).Error; err != nil {
+			return nil, fmt.Errorf("failed to revoke prefill link: %w", err)
+		}
+	}
+	return link, nil
+}
+
+// Delete 删除预填链接
+func (s *PrefillLinkService) Delete(ctx context.Context, id uint) error {
+	res := s.db.WithContext(ctx).Delete(&models.PrefillLink{}, id)
+	if res.Error != nil {
+		return fmt.Errorf("failed to delete prefill link: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return ErrPrefillLinkNotFound
+	}
+	return nil
+}
+
+func (s *PrefillLinkService) apply(ctx context.Context, link *models.PrefillLink, req *models.PrefillLinkRequest) error {
+	if req.CategoryID != nil {
+		if _, err := s.activeCategory(ctx, *req.CategoryID); err != nil {
+			return err
+		}
+	}
+	if err := validatePrefillCustomFields(req.CustomFields); err != nil {
+		return err
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(s.now()) {
+		return fmt.Errorf("%w: expires_at must be in the future", ErrInvalidPrefillLink)
+	}
+
+	link.Name = strings.TrimSpace(req.Name)
+	link.Description = req.Description
+	link.CategoryID = req.CategoryID
+	link.TitlePrefix = req.TitlePrefix
+	link.Type = req.Type
+	link.Priority = req.Priority
+	link.CustomFields = ""
+	if len(req.CustomFields) > 0 {
+		data, _ := json.Marshal(req.CustomFields)
+		link.CustomFields = models.JSONText(data)
+	}
+	link.ExpiresAt = req.ExpiresAt
+	return nil
+}
+
+func (s *PrefillLinkService) activeCategory(ctx context.Context, id uint) (*models.Category, error) {
+	var category models.Category
+	err := s.db.WithContext(ctx).Where("id = ? AND deleted_at IS NULL", id).First(&category).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && category.Status != models.CategoryStatusActive) {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPrefillLink, ErrCategoryNotActive)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get category: %w", err)
+	}
+	return &category, nil
+}
+
+// validatePrefillCustomFields 自定义字段只允许简单键名和标量值，避免链接过长或注入复杂结构
+func validatePrefillCustomFields(fields map[string]interface{}) error {
+	if len(fields) > prefillMaxCustomFields {
+		return fmt.Errorf("%w: at most %d custom fields", ErrInvalidPrefillLink, prefillMaxCustomFields)
+	}
+	for key, value := range fields {
+		if !prefillFieldKeyPattern.MatchString(key) {
+			return fmt.Errorf("%w: invalid custom field key %q", ErrInvalidPrefillLink, key)
+		}
+		switch v := value.(type) {
+		case string:
+			if utf8.RuneCountInString(v) > prefillMaxCustomFieldSize {
+				return fmt.Errorf("%w: custom field %s is too long", ErrInvalidPrefillLink, key)
+			}
+		case float64, bool:
+		default:
+			return fmt.Errorf("%w: custom field %s must be a string, number or boolean", ErrInvalidPrefillLink, key)
+		}
+	}
+	return nil
+}
+
+// ---- 令牌 ----
+
+func prefillPayloadOf(link *models.PrefillLink) prefillPayload {
+	payload := prefillPayload{
+		ID:          link.ID,
+		Version:     link.Version,
+		CategoryID:  link.CategoryID,
+		TitlePrefix: link.TitlePrefix,
+		Type:        link.Type,
+		Priority:    link.Priority,
+	}
+	if link.CustomFields != "" {
+		json.Unmarshal([]byte(link.CustomFields), &payload.CustomFields)
+	}
+	return payload
+}
+
+func prefillSignature(secret, encoded string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// attachToken 生成令牌：base64url(预填内容).base64url(HMAC-SHA256)
+func (s *PrefillLinkService) attachToken(link *models.PrefillLink) {
+	data, _ := json.Marshal(prefillPayloadOf(link))
+	encoded := base64.RawURLEncoding.EncodeToString(data)
+	link.Token = encoded + "." + prefillSignature(link.Secret, encoded)
+	link.URL = prefillLinkPath + link.Token
+}
+
+// verify 校验令牌签名和版本，返回链接和令牌中的预填内容
+func (s *PrefillLinkService) verify(ctx context.Context, token string) (*models.PrefillLink, *prefillPayload, error) {
+	encoded, signature, ok := strings.Cut(strings.TrimSpace(token), ".")
+	if !ok || encoded == "" || signature == "" {
+		return nil, nil, ErrInvalidPrefillToken
+	}
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, nil, ErrInvalidPrefillToken
+	}
+	var payload prefillPayload
+	if err := json.Unmarshal(data, &payload); err != nil || payload.ID == 0 {
+		return nil, nil, ErrInvalidPrefillToken
+	}
+
+	var link models.PrefillLink
+	if err := s.db.WithContext(ctx).First(&link, payload.ID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvalidPrefillToken
+		}
+		return nil, nil, fmt.Errorf("failed to get prefill link: %w", err)
+	}
+	if !hmac.Equal([]byte(signature), []byte(prefillSignature(link.Secret, encoded))) || payload.Version != link.Version {
+		return nil, nil, ErrInvalidPrefillToken
+	}
+	return &link, &payload, nil
+}
+
+// Expand 校验链接并展开为建单表单默认值，同时累计打开次数。
+// 链接创建后被停用的分类不再预填，并在 warnings 中说明
+func (s *PrefillLinkService) Expand(ctx context.Context, token string) (*models.PrefillExpansion, error) {
+	link, payload, err := s.verify(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	if link.RevokedAt != nil || (link.ExpiresAt != nil && !now.Before(*link.ExpiresAt)) {
+		return nil, ErrPrefillLinkExpired
+	}
+
+	expansion := &models.PrefillExpansion{
+		LinkID:       link.ID,
+		LinkName:     link.Name,
+		TitlePrefix:  payload.TitlePrefix,
+		Type:         payload.Type,
+		Priority:     payload.Priority,
+		CustomFields: payload.CustomFields,
+	}
+	if expansion.CustomFields == nil {
+		expansion.CustomFields = map[string]interface{}{}
+	}
+	if payload.CategoryID != nil {
+		category, err := s.activeCategory(ctx, *payload.CategoryID)
+		switch {
+		case errors.Is(err, ErrInvalidPrefillLink):
+			expansion.Warnings = append(expansion.Warnings, "预填的分类已停用或删除")
+		case err != nil:
+			return nil, err
+		default:
+			expansion.CategoryID = &category.ID
+			expansion.CategoryName = category.Name
+		}
+	}
+
+	if err := s.db.WithContext(ctx).Model(&models.PrefillLink{}).Where("id = ?", link.ID).UpdateColumns(map[string]interface{}{
+		"open_count":     gorm.Expr("open_count + 1"),
+		"last_opened_at": now,
+	}).Error; err != nil {
+		log.Printf("Failed to record prefill link %d open: %v", link.ID, err)
+	}
+	return expansion, nil
+}
+
+// RecordTicket 通过预填链接创建工单后累计工单数；令牌无效时忽略
+func (s *PrefillLinkService) RecordTicket(ctx context.Context, token string) {
+	link, _, err := s.verify(ctx, token)
+	if err != nil {
+		return
+	}
+	if err := s.db.WithContext(ctx).Model(&models.PrefillLink{}).Where("id = ?", link.ID).
+		UpdateColumn("ticket_count", gorm.Expr("ticket_count + 1")).Error; err != nil {
+		log.Printf("Failed to record prefill link %d ticket: %v", link.ID, err)
+	}
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestPrefillLinks_SignExpandAndCount(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:prefill_link_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Category{}, &models.PrefillLink{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+
+	ctx := context.Background()
+	svc := NewPrefillLinkService(db)
+	vpn := models.Category{Name: "VPN", Slug: "vpn", Type: models.CategoryType("technical"), Status: models.CategoryStatusActive}
+	archived := models.Category{Name: "Legacy", Slug: "legacy", Type: models.CategoryType("general"), Status: models.CategoryStatusArchived}
+	db.Create(&vpn)
+	db.Create(&archived)
+
+	// 预填内容校验
+	if _, err := svc.Create(ctx, &models.PrefillLinkRequest{Name: "old", CategoryID: &archived.ID}, 1); !errors.Is(err, ErrInvalidPrefillLink) {
+		t.Fatalf("expected archived category to be rejected, got %v", err)
+	}
+	nested := map[string]interface{}{"env": map[string]interface{}{"a": 1}}
+	if _, err := svc.Create(ctx, &models.PrefillLinkRequest{Name: "nested", CustomFields: nested}, 1); !errors.Is(err, ErrInvalidPrefillLink) {
+		t.Fatalf("expected nested custom field to be rejected, got %v", err)
+	}
+
+	link, err := svc.Create(ctx, &models.PrefillLinkRequest{
+		Name:         "Report VPN issue",
+		CategoryID:   &vpn.ID,
+		TitlePrefix:  "[VPN] ",
+		Priority:     models.TicketPriorityHigh,
+		CustomFields: map[string]interface{}{"tool": "vpn-portal", "build": float64(42)},
+	}, 1)
+	if err != nil {
+		t.Fatalf("create link: %v", err)
+	}
+	if !strings.HasPrefix(link.URL, "/tickets/new?prefill=") || !strings.HasSuffix(link.URL, link.Token) {
+		t.Fatalf("unexpected url %s", link.URL)
+	}
+
+	expansion, err := svc.Expand(ctx, link.Token)
+	if err != nil {
+		t.Fatalf("expand: %v", err)
+	}
+	if expansion.CategoryID == nil || *expansion.CategoryID != vpn.ID || expansion.CategoryName != "VPN" ||
+		expansion.TitlePrefix != "[VPN] " || expansion.Priority != models.TicketPriorityHigh ||
+		expansion.CustomFields["tool"] != "vpn-portal" || expansion.CustomFields["build"] != float64(42) {
+		t.Fatalf("unexpected expansion %+v", expansion)
+	}
+
+	// 篡改预填内容或签名都会被拒绝
+	encoded, signature, _ := strings.Cut(link.Token, ".")
+	other, _ := svc.Create(ctx, &models.PrefillLinkRequest{Name: "other", TitlePrefix: "[X] "}, 1)
+	otherEncoded, _, _ := strings.Cut(other.Token, ".")
+	for _, token := range []string{otherEncoded + "." + signature, encoded + ".AAAA", encoded, "garbage"} {
+		if _, err := svc.Expand(ctx, token); !errors.Is(err, ErrInvalidPrefillToken) {
+			t.Fatalf("expected tampered token %q to be rejected, got %v", token, err)
+		}
+	}
+
+	// 只改名称不影响已分发的链接，修改预填内容后旧链接失效
+	req := &models.PrefillLinkRequest{Name: "VPN", CategoryID: &vpn.ID, TitlePrefix: "[VPN] ", Priority: models.TicketPriorityHigh,
+		CustomFields: map[string]interface{}{"tool": "vpn-portal", "build": float64(42)}}
+	renamed, err := svc.Update(ctx, link.ID, req)
+	if err != nil || renamed.Token != link.Token {
+		t.Fatalf("rename should keep the token, err=%v", err)
+	}
+	req.TitlePrefix = "[VPN2] "
+	changed, err := svc.Update(ctx, link.ID, req)
+	if err != nil || changed.Token == link.Token || changed.Version != 2 {
+		t.Fatalf("changing the prefill should issue a new token, err=%v", err)
+	}
+	if _, err := svc.Expand(ctx, link.Token); !errors.Is(err, ErrInvalidPrefillToken) {
+		t.Fatalf("expected superseded token to be rejected, got %v", err)
+	}
+
+	// 分类停用后展开时不再预填并给出提示
+	db.Model(&vpn).Update("status", models.CategoryStatusInactive)
+	expansion, err = svc.Expand(ctx, changed.Token)
+	if err != nil || expansion.CategoryID != nil || len(expansion.Warnings) != 1 {
+		t.Fatalf("expected inactive category to be dropped with a warning, got %+v err=%v", expansion, err)
+	}
+
+	svc.RecordTicket(ctx, changed.Token)
+	svc.RecordTicket(ctx, "garbage")
+	stored, _ := svc.Get(ctx, link.ID)
+	if stored.OpenCount != 2 || stored.TicketCount != 1 || stored.LastOpenedAt == nil {
+		t.Fatalf("unexpected counters open=%d tickets=%d", stored.OpenCount, stored.TicketCount)
+	}
+
+	// 过期和撤销
+	db.Model(&vpn).Update("status", models.CategoryStatusActive)
+	svc.now = func() time.Time { return time.Now().Add(48 * time.Hour) }
+	expiry := time.Now().Add(24 * time.Hour)
+	req.ExpiresAt = &expiry
+	if _, err := NewPrefillLinkService(db).Update(ctx, link.ID, req); err != nil {
+		t.Fatalf("set expiry: %v", err)
+	}
+	if _, err := svc.Expand(ctx, changed.Token); !errors.Is(err, ErrPrefillLinkExpired) {
+		t.Fatalf("expected expired link, got %v", err)
+	}
+	svc.now = time.Now
+	if _, err := svc.Revoke(ctx, other.ID); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	if _, err := svc.Expand(ctx, other.Token); !errors.Is(err, ErrPrefillLinkExpired) {
+		t.Fatalf("expected revoked link, got %v", err)
+	}
+}
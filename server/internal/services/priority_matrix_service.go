@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+// KeyTicketPriorityMatrix 影响×紧急程度优先级矩阵配置键
+const KeyTicketPriorityMatrix = "ticket.priority_matrix"
+
+// ErrInvalidImpactUrgency 影响/紧急程度参数不合法
+var ErrInvalidImpactUrgency = errors.New("invalid impact/urgency")
+
+// PriorityMatrixService 优先级矩阵服务
+type PriorityMatrixService struct {
+	db *gorm.DB
+}
+
+// NewPriorityMatrixService 创建优先级矩阵服务
+func NewPriorityMatrixService(db *gorm.DB) *PriorityMatrixService {
+	return &PriorityMatrixService{db: db}
+}
+
+// GetMatrix 获取优先级矩阵
+func (s *PriorityMatrixService) GetMatrix(ctx context.Context) (*models.PriorityMatrix, error) {
+	var config models.SystemConfig
+	err := s.db.WithContext(ctx).
+		Where("key = ? AND is_active = ?", KeyTicketPriorityMatrix, true).
+		First(&config).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.GetDefaultPriorityMatrix(), nil
+		}
+		return nil, fmt.Errorf("failed to get priority matrix: %w", err)
+	}
+
+	var matrix models.PriorityMatrix
+	if err := config.GetJSONValue(&matrix); err != nil || matrix.Validate() != nil {
+		log.Printf("Warning: invalid priority matrix config, using defaults")
+		return models.GetDefaultPriorityMatrix(), nil
+	}
+
+	return &matrix, nil
+}
+
+// SetMatrix 保存优先级矩阵
+func (s *PriorityMatrixService) SetMatrix(ctx context.Context, matrix *models.PriorityMatrix, userID uint) error {
+	if err := matrix.Validate(); err != nil {
+		return err
+	}
+
+	var existing models.SystemConfig
+	err := s.db.WithContext(ctx).Where("key = ?", KeyTicketPriorityMatrix).First(&existing).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to check existing matrix: %w", err)
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		config := models.SystemConfig{
+			Key:         KeyTicketPriorityMatrix,
+			Category:    CategoryTicket,
+			Group:       "priority",
+			Description: "影响×紧急程度优先级矩阵",
+			IsActive:    true,
+			UpdatedBy:   &userID,
+		}
+		if err := config.SetValue(matrix); err != nil {
+			return fmt.Errorf("failed to set matrix value: %w", err)
+		}
+		if err := s.db.WithContext(ctx).Create(&config).Error; err != nil {
+			return fmt.Errorf("failed to create matrix: %w", err)
+		}
+		return nil
+	}
+
+	if err := existing.SetValue(matrix); err != nil {
+		return fmt.Errorf("failed to set matrix value: %w", err)
+	}
+	existing.UpdatedBy = &userID
+	existing.Version++
+
+	if err := s.db.WithContext(ctx).Save(&existing).Error; err != nil {
+		return fmt.Errorf("failed to update matrix: %w", err)
+	}
+
+	return nil
+}
+
+// ResolvePriority 校验影响/紧急程度并在矩阵启用时返回计算出的优先级
+// 返回的 ok 为 false 表示矩阵未启用，调用方应保留原优先级
+func (s *PriorityMatrixService) ResolvePriority(ctx context.Context, impact models.TicketImpact, urgency models.TicketUrgency) (models.TicketPriority, bool, error) {
+	if impact == "" && urgency == "" {
+		return "", false, nil
+	}
+	if impact == "" || urgency == "" {
+		return "", false, fmt.Errorf("%w: impact and urgency must be provided together", ErrInvalidImpactUrgency)
+	}
+	if !models.IsValidImpactUrgencyLevel(string(impact)) || !models.IsValidImpactUrgencyLevel(string(urgency)) {
+		return "", false, fmt.Errorf("%w: impact and urgency must be one of low, medium, high", ErrInvalidImpactUrgency)
+	}
+
+	matrix, err := s.GetMatrix(ctx)
+	if err != nil {
+		return "", false, err
+	}
+	if !matrix.Enabled {
+		return "", false, nil
+	}
+
+	priority, ok := matrix.Resolve(impact, urgency)
+	if !ok {
+		return "", false, fmt.Errorf("no priority configured for impact=%s urgency=%s", impact, urgency)
+	}
+	return priority, true, nil
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"strconv"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+// publicStatusCacheTTL 公开接口无需登录，统计结果缓存一分钟，避免被频繁请求时反复查询
+const publicStatusCacheTTL = time.Minute
+
+// ErrPublicStatusDisabled 管理员未开放公开状态接口
+var ErrPublicStatusDisabled = errors.New("public status is disabled")
+
+// PublicStatusService 公开的支持服务汇总指标，供嵌入 Wiki、看板的徽章和小组件使用
+type PublicStatusService struct {
+	db            *gorm.DB
+	configService *ConfigService
+
+	mu       sync.RWMutex
+	cached   *models.PublicSupportStats
+	cachedAt time.Time
+}
+
+// NewPublicStatusService 创建公开状态服务
+func NewPublicStatusService(db *gorm.DB) *PublicStatusService {
+	return &PublicStatusService{
+		db:            db,
+		configService: NewConfigService(db),
+	}
+}
+
+// Enabled 是否已开放公开状态接口
+func (s *PublicStatusService) Enabled() bool {
+	enabled, err := s.configService.GetConfigBool(KeyPublicStatusEnabled)
+	return err == nil && enabled
+}
+
+// Stats 获取汇总指标，缓存期内返回上一次的结果
+func (s *PublicStatusService) Stats(ctx context.Context, now time.Time) (*models.PublicSupportStats, error) {
+	if !s.Enabled() {
+		return nil, ErrPublicStatusDisabled
+	}
+
+	s.mu.RLock()
+	cached, cachedAt := s.cached, s.cachedAt
+	s.mu.RUnlock()
+	if cached != nil && now.Sub(cachedAt) >= 0 && now.Sub(cachedAt) < publicStatusCacheTTL {
+		return cached, nil
+	}
+
+	stats, err := s.compute(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.cached = stats
+	s.cachedAt = now
+	s.mu.Unlock()
+	return stats, nil
+}
+
+func (s *PublicStatusService) compute(ctx context.Context, now time.Time) (*models.PublicSupportStats, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	stats := &models.PublicSupportStats{
+		WeekStart:   today.AddDate(0, 0, -int(today.Weekday())),
+		GeneratedAt: now,
+	}
+
+	if err := s.db.WithContext(ctx).Model(&models.Ticket{}).
+		Where("deleted_at IS NULL AND status NOT IN ?", closedTicketStatuses).
+		Count(&stats.OpenTickets).Error; err != nil {
+		return nil, fmt.Errorf("failed to count open tickets: %w", err)
+	}
+
+	// 按本周内首次回复的工单计算，在 Go 中求差值以兼容不同数据库
+	var replies []struct {
+		CreatedAt    time.Time
+		FirstReplyAt time.Time
+	}
+	if err := s.db.WithContext(ctx).Model(&models.Ticket{}).
+		Select("created_at", "first_reply_at").
+		Where("deleted_at IS NULL AND first_reply_at >= ? AND first_reply_at <= ?", stats.WeekStart, now).
+		Scan(&replies).Error; err != nil {
+		return nil, fmt.Errorf("failed to load first replies: %w", err)
+	}
+	var total time.Duration
+	for _, reply := range replies {
+		if wait := reply.FirstReplyAt.Sub(reply.CreatedAt); wait > 0 {
+			total += wait
+		}
+	}
+	stats.RepliedThisWeek = int64(len(replies))
+	if len(replies) > 0 {
+		avg := float64(int(total.Minutes()/float64(len(replies))*10+0.5)) / 10
+		stats.AvgFirstResponseMinutes = &avg
+	}
+	return stats, nil
+}
+
+// Badge 徽章的默认标签、展示值和颜色
+func (s *PublicStatusService) Badge(stats *models.PublicSupportStats, metric models.PublicStatusMetric) (label, value, color string) {
+	if metric == models.PublicStatusMetricFirstResponse {
+		if stats.AvgFirstResponseMinutes == nil {
+			return "first response", "n/a", "#9f9f9f"
+		}
+		minutes := *stats.AvgFirstResponseMinutes
+		switch {
+		case minutes < 60:
+			color = "#4c1"
+		case minutes < 240:
+			color = "#dfb317"
+		default:
+			color = "#e05d44"
+		}
+		return "first response", formatBadgeMinutes(minutes), color
+	}
+	return "open tickets", strconv.FormatInt(stats.OpenTickets, 10), "#007ec6"
+}
+
+// formatBadgeMinutes 将分钟数格式化为 45m、3.5h、2d
+func formatBadgeMinutes(minutes float64) string {
+	switch {
+	case minutes < 60:
+		return fmt.Sprintf("%dm", int(minutes+0.5))
+	case minutes < 24*60:
+		return strconv.FormatFloat(float64(int(minutes/60*10+0.5))/10, 'f', -1, 64) + "h"
+	default:
+		return strconv.FormatFloat(float64(int(minutes/(24*60)*10+0.5))/10, 'f', -1, 64) + "d"
+	}
+}
+
+// RenderBadgeSVG 生成 shields 风格的扁平徽章，文字宽度按每字符 7px 估算
+func RenderBadgeSVG(label, value, color string) []byte {
+	labelWidth := 10 + 7*utf8.RuneCountInString(label)
+	valueWidth := 10 + 7*utf8.RuneCountInString(value)
+	width := labelWidth + valueWidth
+	label, value, color = html.EscapeString(label), html.EscapeString(value), html.EscapeString(color)
+
+	return []byte(fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[4]s: %[5]s">`+
+		`<title>%[4]s: %[5]s</title>`+
+		`<linearGradient id="s" x2="0" y2="100%%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>`+
+		`<clipPath id="r"><rect width="%[1]d" height="20" rx="3" fill="#fff"/></clipPath>`+
+		`<g clip-path="url(#r)"><rect width="%[2]d" height="20" fill="#555"/><rect x="%[2]d" width="%[3]d" height="20" fill="%[6]s"/>`+
+		`<rect width="%[1]d" height="20" fill="url(#s)"/></g>`+
+		`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">`+
+		`<text x="%[7]d" y="14">%[4]s</text><text x="%[8]d" y="14">%[5]s</text></g></svg>`,
+		width, labelWidth, valueWidth, label, value, color, labelWidth/2, labelWidth+valueWidth/2))
+}
@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestPublicStatus_StatsCacheAndBadge(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:public_status_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.Ticket{}, &models.SystemConfig{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+	ctx := context.Background()
+	svc := NewPublicStatusService(db)
+
+	// 周三中午，本周从周日开始
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.Local)
+	if _, err := svc.Stats(ctx, now); !errors.Is(err, ErrPublicStatusDisabled) {
+		t.Fatalf("expected public status to be disabled by default, got %v", err)
+	}
+	if err := svc.configService.SetConfig(KeyPublicStatusEnabled, "true", "bool", "", CategorySystem, "public_status"); err != nil {
+		t.Fatalf("failed to enable public status: %v", err)
+	}
+
+	seed := func(number string, status models.TicketStatus, created time.Time, replied *time.Time) {
+		ticket := models.Ticket{TicketNumber: number, Title: number, Description: number, Status: status, Priority: models.TicketPriorityNormal,
+			Type: models.TicketTypeRequest, Source: models.TicketSourceWeb, CreatedByID: 1, CreatedAt: created, FirstReplyAt: replied}
+		if err := db.Create(&ticket).Error; err != nil {
+			t.Fatalf("failed to seed ticket: %v", err)
+		}
+	}
+	at := func(d time.Duration) *time.Time { v := now.Add(d); return &v }
+	seed("PS-1", models.TicketStatusOpen, now.Add(-2*time.Hour), at(-90*time.Minute))     // 30 分钟
+	seed("PS-2", models.TicketStatusPending, now.Add(-5*time.Hour), at(-3*time.Hour))     // 120 分钟
+	seed("PS-3", models.TicketStatusResolved, now.AddDate(0, 0, -10), at(-240*time.Hour)) // 上周回复，不计入
+	seed("PS-4", models.TicketStatusClosed, now.Add(-time.Hour), nil)
+
+	stats, err := svc.Stats(ctx, now)
+	if err != nil {
+		t.Fatalf("stats failed: %v", err)
+	}
+	if stats.OpenTickets != 2 || stats.RepliedThisWeek != 2 || stats.AvgFirstResponseMinutes == nil || *stats.AvgFirstResponseMinutes != 75 ||
+		!stats.WeekStart.Equal(time.Date(2026, 10, 11, 0, 0, 0, 0, time.Local)) {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	// 缓存期内不重新统计
+	seed("PS-5", models.TicketStatusOpen, now, nil)
+	if cached, _ := svc.Stats(ctx, now.Add(30*time.Second)); cached.OpenTickets != 2 {
+		t.Fatalf("expected cached stats, got %+v", cached)
+	}
+	if fresh, _ := svc.Stats(ctx, now.Add(2*time.Minute)); fresh.OpenTickets != 3 {
+		t.Fatalf("expected refreshed stats, got %+v", fresh)
+	}
+
+	label, value, color := svc.Badge(stats, models.PublicStatusMetricFirstResponse)
+	if label != "first response" || value != "1.3h" || color != "#dfb317" {
+		t.Fatalf("unexpected first response badge %q %q %q", label, value, color)
+	}
+	if _, value, _ := svc.Badge(&models.PublicSupportStats{}, models.PublicStatusMetricFirstResponse); value != "n/a" {
+		t.Fatalf("expected n/a without replies, got %q", value)
+	}
+	svg := string(RenderBadgeSVG("<support>", "2", "#007ec6"))
+	if !strings.HasPrefix(svg, "<svg") || !strings.Contains(svg, "&lt;support&gt;: 2") || strings.Contains(svg, "<support>") {
+		t.Fatalf("unexpected badge svg %s", svg)
+	}
+}
@@ -0,0 +1,441 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+// pushBodyMaxRunes 推送正文最大字符数
+const pushBodyMaxRunes = 200
+
+var (
+	// ErrPushNotConfigured 浏览器推送未启用或未配置 VAPID 密钥
+	ErrPushNotConfigured = errors.New("web push is not configured")
+	// ErrPushSubscriptionNotFound 推送订阅不存在
+	ErrPushSubscriptionNotFound = errors.New("push subscription not found")
+	// ErrInvalidPushSubscription 推送订阅参数无效
+	ErrInvalidPushSubscription = errors.New("invalid push subscription")
+)
+
+// PushChannel 浏览器推送渠道，应用内通知创建后经它推送到用户已订阅的浏览器
+type PushChannel interface {
+	Deliver(ctx context.Context, notification *models.Notification) error
+}
+
+// pushConfig 生效中的 VAPID 配置
+type pushConfig struct {
+	PublicKey  string
+	PrivateKey string
+	Subject    string
+}
+
+// PushNotificationService 浏览器推送（Web Push）服务：管理订阅，按用户偏好与免打扰时段推送，并合并短时间内的多条通知
+type PushNotificationService struct {
+	db            *gorm.DB
+	configService *ConfigService
+	client        *http.Client
+	send          func(ctx context.Context, subscription *models.PushSubscription, message *models.PushMessage) (int, error)
+	now           func() time.Time
+
+	// 合并窗口内的接收者，值为窗口结束时需要合并推送的通知
+	batchMu      sync.Mutex
+	batchPending map[uint][]*models.Notification
+}
+
+// NewPushNotificationService 创建浏览器推送服务
+func NewPushNotificationService(db *gorm.DB) *PushNotificationService {
+	service := &PushNotificationService{
+		db:            db,
+		configService: NewConfigService(db),
+		client:        &http.Client{Timeout: 10 * time.Second},
+		now:           time.Now,
+		batchPending:  make(map[uint][]*models.Notification),
+	}
+	service.send = service.webPush
+	return service
+}
+
+// config 读取 VAPID 配置，未启用或密钥不完整时返回 ErrPushNotConfigured
+func (s *PushNotificationService) config() (*pushConfig, error) {
+	enabled, err := s.configService.GetConfigBool(KeyPushEnabled)
+	if err != nil || !enabled {
+		return nil, ErrPushNotConfigured
+	}
+	config := &pushConfig{
+		PublicKey:  s.configService.GetConfigWithDefault(KeyPushVAPIDPublicKey, ""),
+		PrivateKey: s.configService.GetConfigWithDefault(KeyPushVAPIDPrivateKey, ""),
+		Subject:    s.configService.GetConfigWithDefault(KeyPushVAPIDSubject, ""),
+	}
+	if config.PublicKey == "" || config.PrivateKey == "" || config.Subject == "" {
+		return nil, ErrPushNotConfigured
+	}
+	return config, nil
+}
+
+// PublicKey 浏览器订阅使用的 VAPID 公钥，未启用时返回 ErrPushNotConfigured
+func (s *PushNotificationService) PublicKey() (string, error) {
+	config, err := s.config()
+	if err != nil {
+		return "", err
+	}
+	return config.PublicKey, nil
+}
+
+// GenerateKeys 生成并保存新的 VAPID 密钥对。旧密钥下的订阅无法再推送，一并删除，浏览器需重新订阅
+func (s *PushNotificationService) GenerateKeys(ctx context.Context) (string, int64, error) {
+	publicKey, privateKey, err := GenerateVAPIDKeys()
+	if err != nil {
+		return "", 0, err
+	}
+	if err := s.configService.SetConfig(KeyPushVAPIDPublicKey, publicKey, "string", "VAPID公钥(base64url)，提供给浏览器订阅", CategoryNotify, "push"); err != nil {
+		return "", 0, fmt.Errorf("保存VAPID公钥失败: %w", err)
+	}
+	if err := s.configService.SetConfig(KeyPushVAPIDPrivateKey, privateKey, "string", "VAPID私钥(base64url)", CategoryNotify, "push"); err != nil {
+		return "", 0, fmt.Errorf("保存VAPID私钥失败: %w", err)
+	}
+
+	result := s.db.WithContext(ctx).Where("1 = 1").Delete(&models.PushSubscription{})
+	if result.Error != nil {
+		return "", 0, fmt.Errorf("清理推送订阅失败: %w", result.Error)
+	}
+	return publicKey, result.RowsAffected, nil
+}
+
+// Subscribe 注册浏览器订阅。同一 endpoint 重复注册时更新密钥，并归属到当前用户
+func (s *PushNotificationService) Subscribe(ctx context.Context, userID uint, req *models.PushSubscriptionRequest, userAgent string) (*models.PushSubscription, error) {
+	p256dh, err := decodeWebPushKey(req.Keys.P256dh)
+	if err != nil || len(p256dh) != 65 {
+		return nil, fmt.Errorf("%w: p256dh must be an uncompressed P-256 public key", ErrInvalidPushSubscription)
+	}
+	auth, err := decodeWebPushKey(req.Keys.Auth)
+	if err != nil || len(auth) != 16 {
+		return nil, fmt.Errorf("%w: auth must be 16 bytes", ErrInvalidPushSubscription)
+	}
+	if !strings.HasPrefix(req.Endpoint, "https://") {
+		return nil, fmt.Errorf("%w: endpoint must use https", ErrInvalidPushSubscription)
+	}
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+
+	hash := pushEndpointHash(req.Endpoint)
+	var subscription models.PushSubscription
+	err = s.db.WithContext(ctx).Where("endpoint_hash = ?", hash).First(&subscription).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("查询推送订阅失败: %w", err)
+	}
+
+	subscription.UserID = userID
+	subscription.Endpoint = req.Endpoint
+	subscription.EndpointHash = hash
+	subscription.P256dh = req.Keys.P256dh
+	subscription.Auth = req.Keys.Auth
+	subscription.UserAgent = userAgent
+	subscription.FailureCount = 0
+	if err := s.db.WithContext(ctx).Save(&subscription).Error; err != nil {
+		return nil, fmt.Errorf("保存推送订阅失败: %w", err)
+	}
+	return &subscription, nil
+}
+
+// Unsubscribe 取消当前用户的浏览器订阅
+func (s *PushNotificationService) Unsubscribe(ctx context.Context, userID uint, endpoint string) error {
+	result := s.db.WithContext(ctx).
+		Where("user_id = ? AND endpoint_hash = ?", userID, pushEndpointHash(endpoint)).
+		Delete(&models.PushSubscription{})
+	if result.Error != nil {
+		return fmt.Errorf("取消推送订阅失败: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrPushSubscriptionNotFound
+	}
+	return nil
+}
+
+// ListSubscriptions 当前用户已订阅的浏览器
+func (s *PushNotificationService) ListSubscriptions(ctx context.Context, userID uint) ([]*models.PushSubscription, error) {
+	var subscriptions []*models.PushSubscription
+	if err := s.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&subscriptions).Error; err != nil {
+		return nil, fmt.Errorf("获取推送订阅失败: %w", err)
+	}
+	return subscriptions, nil
+}
+
+// SendTest 向用户的全部浏览器发送测试推送，不受偏好、免打扰和合并窗口限制
+func (s *PushNotificationService) SendTest(ctx context.Context, userID uint) (int, error) {
+	if _, err := s.config(); err != nil {
+		return 0, err
+	}
+	return s.sendToUser(ctx, userID, &models.PushMessage{
+		Title: "测试推送",
+		Body:  "浏览器推送通知已开启",
+		URL:   "/notifications",
+		Tag:   "push-test",
+		Count: 1,
+	})
+}
+
+// Deliver 推送一条通知：遵循用户对该通知类型的推送开关和免打扰时段。
+// 合并窗口内第一条立即推送，其余在窗口结束时合并为一条推送
+func (s *PushNotificationService) Deliver(ctx context.Context, notification *models.Notification) error {
+	if _, err := s.config(); err != nil {
+		return nil
+	}
+	allowed, err := s.allowedForUser(ctx, notification.RecipientID, notification.Type)
+	if err != nil {
+		s.track(ctx, []*models.Notification{notification}, 0, err)
+		return err
+	}
+	if !allowed {
+		recordNotificationDelivery(ctx, s.db, notification.ID, models.NotificationChannelPush, "",
+			models.NotificationDeliverySkipped, false, "user_preference")
+		return nil
+	}
+
+	window := s.batchWindow()
+	if window == 0 {
+		sent, err := s.sendToUser(ctx, notification.RecipientID, pushMessageFor([]*models.Notification{notification}))
+		s.track(ctx, []*models.Notification{notification}, sent, err)
+		return err
+	}
+
+	recipientID := notification.RecipientID
+	s.batchMu.Lock()
+	if pending, open := s.batchPending[recipientID]; open {
+		s.batchPending[recipientID] = append(pending, notification)
+		s.batchMu.Unlock()
+		recordNotificationDelivery(ctx, s.db, notification.ID, models.NotificationChannelPush, "",
+			models.NotificationDeliveryPending, false, "batched")
+		return nil
+	}
+	s.batchPending[recipientID] = []*models.Notification{}
+	s.batchMu.Unlock()
+
+	time.AfterFunc(window, func() {
+		if err := s.flushBatch(context.Background(), recipientID); err != nil {
+			log.Printf("发送合并推送失败 (recipient: %d): %v", recipientID, err)
+		}
+	})
+	sent, err := s.sendToUser(ctx, recipientID, pushMessageFor([]*models.Notification{notification}))
+	s.track(ctx, []*models.Notification{notification}, sent, err)
+	return err
+}
+
+// flushBatch 关闭接收者的合并窗口，推送窗口内积累的通知
+func (s *PushNotificationService) flushBatch(ctx context.Context, recipientID uint) error {
+	s.batchMu.Lock()
+	pending := s.batchPending[recipientID]
+	delete(s.batchPending, recipientID)
+	s.batchMu.Unlock()
+
+	if len(pending) == 0 {
+		return nil
+	}
+	sent, err := s.sendToUser(ctx, recipientID, pushMessageFor(pending))
+	s.track(ctx, pending, sent, err)
+	return err
+}
+
+// track 记录通知的浏览器推送投递结果：至少推送到一个订阅即为送达，没有有效订阅时记为跳过
+func (s *PushNotificationService) track(ctx context.Context, notifications []*models.Notification, sent int, err error) {
+	status, attempted, detail := models.NotificationDeliveryDelivered, true, ""
+	switch {
+	case err != nil && sent == 0:
+		status, detail = models.NotificationDeliveryFailed, err.Error()
+	case err != nil:
+		detail = err.Error()
+	case sent == 0:
+		status, attempted, detail = models.NotificationDeliverySkipped, false, "no_active_subscription"
+	}
+	for _, notification := range notifications {
+		recordNotificationDelivery(ctx, s.db, notification.ID, models.NotificationChannelPush, "", status, attempted, detail)
+	}
+}
+
+// batchWindow 推送合并窗口，0 表示逐条推送
+func (s *PushNotificationService) batchWindow() time.Duration {
+	seconds, err := s.configService.GetConfigInt(KeyPushBatchSeconds)
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// allowedForUser 用户未设置偏好时默认推送；关闭推送或处于免打扰时段时不推送
+func (s *PushNotificationService) allowedForUser(ctx context.Context, userID uint, notificationType models.NotificationType) (bool, error) {
+	var preference models.NotificationPreference
+	err := s.db.WithContext(ctx).
+		Where("user_id = ? AND notification_type = ?", userID, notificationType).
+		First(&preference).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return true, nil
+		}
+		return false, fmt.Errorf("获取通知偏好失败: %w", err)
+	}
+	if preference.PushEnabled != nil && !*preference.PushEnabled {
+		return false, nil
+	}
+	return !inDoNotDisturb(preference.DoNotDisturbStart, preference.DoNotDisturbEnd, s.now()), nil
+}
+
+// inDoNotDisturb 按时分判断是否处于免打扰时段，支持跨零点（如 22:00-08:00）
+func inDoNotDisturb(start, end *time.Time, now time.Time) bool {
+	if start == nil || end == nil {
+		return false
+	}
+	minuteOfDay := func(t time.Time) int {
+		t = t.In(now.Location())
+		return t.Hour()*60 + t.Minute()
+	}
+	from, to, current := minuteOfDay(*start), minuteOfDay(*end), minuteOfDay(now)
+	switch {
+	case from == to:
+		return false
+	case from < to:
+		return current >= from && current < to
+	default:
+		return current >= from || current < to
+	}
+}
+
+// sendToUser 推送到用户的全部订阅，推送服务返回 404/410 的订阅已失效，直接删除
+func (s *PushNotificationService) sendToUser(ctx context.Context, userID uint, message *models.PushMessage) (int, error) {
+	subscriptions, err := s.ListSubscriptions(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+	if len(subscriptions) == 0 {
+		return 0, nil
+	}
+
+	sent, failed := 0, 0
+	var lastErr error
+	for _, subscription := range subscriptions {
+		status, err := s.send(ctx, subscription, message)
+		switch {
+		case status == http.StatusNotFound || status == http.StatusGone:
+			s.db.WithContext(ctx).Delete(subscription)
+		case err != nil:
+			failed++
+			lastErr = err
+			s.db.WithContext(ctx).Model(subscription).UpdateColumn("failure_count", gorm.Expr("failure_count + 1"))
+		default:
+			sent++
+			s.db.WithContext(ctx).Model(subscription).UpdateColumns(map[string]interface{}{
+				"last_used_at":  s.now(),
+				"failure_count": 0,
+			})
+		}
+	}
+	if failed > 0 {
+		return sent, fmt.Errorf("部分浏览器推送失败: 成功 %d, 失败 %d: %w", sent, failed, lastErr)
+	}
+	return sent, nil
+}
+
+// webPush 加密消息并发送到订阅的推送服务，返回推送服务的 HTTP 状态码
+func (s *PushNotificationService) webPush(ctx context.Context, subscription *models.PushSubscription, message *models.PushMessage) (int, error) {
+	config, err := s.config()
+	if err != nil {
+		return 0, err
+	}
+	payload, err := json.Marshal(message)
+	if err != nil {
+		return 0, err
+	}
+	body, err := encryptWebPush(payload, subscription.P256dh, subscription.Auth)
+	if err != nil {
+		return 0, err
+	}
+	authorization, err := vapidAuthorization(subscription.Endpoint, config.Subject, config.PublicKey, config.PrivateKey, s.now())
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", "86400")
+	req.Header.Set("Urgency", "normal")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("请求推送服务失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("推送服务返回状态码 %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// pushMessageFor 单条通知按原标题推送，多条合并为一条摘要推送
+func pushMessageFor(batch []*models.Notification) *models.PushMessage {
+	if len(batch) == 1 {
+		notification := batch[0]
+		tag := fmt.Sprintf("notification-%d", notification.ID)
+		if notification.RelatedTicketID != nil {
+			tag = fmt.Sprintf("ticket-%d", *notification.RelatedTicketID)
+		}
+		return &models.PushMessage{
+			Title:          notification.Title,
+			Body:           pushBody(notification.Content),
+			URL:            notification.ActionURL,
+			Tag:            tag,
+			NotificationID: notification.ID,
+			Count:          1,
+		}
+	}
+
+	titles := make([]string, 0, len(batch))
+	for i := len(batch) - 1; i >= 0 && len(titles) < 3; i-- {
+		titles = append(titles, batch[i].Title)
+	}
+	body := strings.Join(titles, "；")
+	if len(batch) > len(titles) {
+		body += " 等"
+	}
+	return &models.PushMessage{
+		Title: fmt.Sprintf("您有 %d 条新通知", len(batch)),
+		Body:  pushBody(body),
+		URL:   "/notifications",
+		Tag:   "notification-digest",
+		Count: len(batch),
+	}
+}
+
+// pushBody 推送正文按字符截断，浏览器通知只展示前几行
+func pushBody(text string) string {
+	text = strings.TrimSpace(text)
+	if utf8.RuneCountInString(text) <= pushBodyMaxRunes {
+		return text
+	}
+	return string([]rune(text)[:pushBodyMaxRunes]) + "…"
+}
+
+func pushEndpointHash(endpoint string) string {
+	sum := sha256.Sum256([]byte(endpoint))
+	return hex.EncodeToString(sum[:])
+}
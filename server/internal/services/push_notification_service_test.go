@@ -0,0 +1,197 @@
+package services
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestWebPush_EncryptionAndVAPID(t *testing.T) {
+	// 模拟浏览器的订阅密钥
+	uaKey, _ := ecdh.P256().GenerateKey(rand.Reader)
+	authSecret := make([]byte, 16)
+	rand.Read(authSecret)
+	p256dh := base64.RawURLEncoding.EncodeToString(uaKey.PublicKey().Bytes())
+	auth := base64.RawURLEncoding.EncodeToString(authSecret)
+
+	body, err := encryptWebPush([]byte(`{"title":"工单已分配"}`), p256dh, auth)
+	if err != nil {
+		t.Fatalf("encrypt failed: %v", err)
+	}
+
+	// 按 RFC 8291 解密
+	salt, keyID := body[:16], body[21:86]
+	if body[20] != 65 {
+		t.Fatalf("unexpected keyid length %d", body[20])
+	}
+	asPublic, err := ecdh.P256().NewPublicKey(keyID)
+	if err != nil {
+		t.Fatalf("invalid sender key: %v", err)
+	}
+	shared, _ := uaKey.ECDH(asPublic)
+	keyInfo := append(append([]byte("WebPush: info\x00"), uaKey.PublicKey().Bytes()...), keyID...)
+	ikm, _ := hkdfRead(shared, authSecret, keyInfo, 32)
+	cek, _ := hkdfRead(ikm, salt, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	nonce, _ := hkdfRead(ikm, salt, []byte("Content-Encoding: nonce\x00"), 12)
+	block, _ := aes.NewCipher(cek)
+	gcm, _ := cipher.NewGCM(block)
+	plaintext, err := gcm.Open(nil, nonce, body[86:], nil)
+	if err != nil {
+		t.Fatalf("decrypt failed: %v", err)
+	}
+	if string(plaintext) != "{\"title\":\"工单已分配\"}\x02" {
+		t.Fatalf("unexpected plaintext %q", plaintext)
+	}
+
+	publicKey, privateKey, err := GenerateVAPIDKeys()
+	if err != nil {
+		t.Fatalf("generate keys failed: %v", err)
+	}
+	header, err := vapidAuthorization("https://push.example.com/send/abc", "mailto:ops@example.com", publicKey, privateKey, time.Now())
+	if err != nil {
+		t.Fatalf("vapid failed: %v", err)
+	}
+	if !strings.HasPrefix(header, "vapid t=") || !strings.HasSuffix(header, ", k="+publicKey) {
+		t.Fatalf("unexpected authorization header %q", header)
+	}
+	token := strings.TrimSuffix(strings.TrimPrefix(header, "vapid t="), ", k="+publicKey)
+	parts := strings.Split(token, ".")
+	claimsJSON, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	var claims map[string]interface{}
+	json.Unmarshal(claimsJSON, &claims)
+	if claims["aud"] != "https://push.example.com" || claims["sub"] != "mailto:ops@example.com" {
+		t.Fatalf("unexpected claims %v", claims)
+	}
+	publicBytes, _ := base64.RawURLEncoding.DecodeString(publicKey)
+	verifier := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(publicBytes[1:33]), Y: new(big.Int).SetBytes(publicBytes[33:])}
+	signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if !ecdsa.Verify(verifier, digest[:], new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])) {
+		t.Fatal("vapid signature does not verify")
+	}
+
+	otherPublic, _, _ := GenerateVAPIDKeys()
+	if _, err := vapidAuthorization("https://push.example.com/x", "mailto:a@b.c", otherPublic, privateKey, time.Now()); err == nil {
+		t.Fatal("expected mismatched key pair to be rejected")
+	}
+}
+
+func TestPushNotification_PreferencesDNDAndBatching(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:push_notification_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.SystemConfig{}, &models.NotificationPreference{}, &models.PushSubscription{}, &models.NotificationDelivery{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+
+	ctx := context.Background()
+	svc := NewPushNotificationService(db)
+	var sent []*models.PushMessage
+	svc.send = func(ctx context.Context, subscription *models.PushSubscription, message *models.PushMessage) (int, error) {
+		if strings.Contains(subscription.Endpoint, "gone") {
+			return http.StatusGone, nil
+		}
+		sent = append(sent, message)
+		return http.StatusCreated, nil
+	}
+	now := time.Date(2024, 3, 1, 23, 30, 0, 0, time.Local)
+	svc.now = func() time.Time { return now }
+
+	uaKey, _ := ecdh.P256().GenerateKey(rand.Reader)
+	req := &models.PushSubscriptionRequest{Endpoint: "https://push.example.com/ok"}
+	req.Keys.P256dh = base64.RawURLEncoding.EncodeToString(uaKey.PublicKey().Bytes())
+	req.Keys.Auth = base64.RawURLEncoding.EncodeToString(make([]byte, 16))
+	if _, err := svc.Subscribe(ctx, 7, req, "test"); err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	req.Endpoint = "https://push.example.com/gone"
+	if _, err := svc.Subscribe(ctx, 7, req, "test"); err != nil {
+		t.Fatalf("subscribe failed: %v", err)
+	}
+	req.Keys.Auth = "short"
+	if _, err := svc.Subscribe(ctx, 7, req, "test"); err == nil {
+		t.Fatal("expected invalid auth secret to be rejected")
+	}
+
+	notify := func(id uint, notificationType models.NotificationType, title string) *models.Notification {
+		return &models.Notification{ID: id, Type: notificationType, Title: title, Content: title, RecipientID: 7}
+	}
+
+	// 未启用时不推送
+	if err := svc.Deliver(ctx, notify(1, models.NotificationTypeTicketAssigned, "a")); err != nil || len(sent) != 0 {
+		t.Fatalf("expected no push while disabled: %v %d", err, len(sent))
+	}
+
+	publicKey, _, err := svc.GenerateKeys(ctx)
+	if err != nil || publicKey == "" {
+		t.Fatalf("generate keys failed: %v", err)
+	}
+	var remaining int64
+	db.Model(&models.PushSubscription{}).Count(&remaining)
+	if remaining != 0 {
+		t.Fatalf("expected subscriptions under the old key to be removed, got %d", remaining)
+	}
+	req.Keys.Auth = base64.RawURLEncoding.EncodeToString(make([]byte, 16))
+	for _, endpoint := range []string{"https://push.example.com/ok", "https://push.example.com/gone"} {
+		req.Endpoint = endpoint
+		if _, err := svc.Subscribe(ctx, 7, req, "test"); err != nil {
+			t.Fatalf("subscribe failed: %v", err)
+		}
+	}
+	svc.configService.SetConfig(KeyPushEnabled, "true", "bool", "", CategoryNotify, "push")
+	svc.configService.SetConfig(KeyPushVAPIDSubject, "mailto:ops@example.com", "string", "", CategoryNotify, "push")
+	svc.configService.SetConfig(KeyPushBatchSeconds, "60", "int", "", CategoryNotify, "push")
+
+	// 评论通知关闭推送，状态通知处于 22:00-08:00 免打扰
+	off := false
+	dndStart := time.Date(2000, 1, 1, 22, 0, 0, 0, time.Local)
+	dndEnd := time.Date(2000, 1, 1, 8, 0, 0, 0, time.Local)
+	db.Create(&models.NotificationPreference{UserID: 7, NotificationType: models.NotificationTypeTicketCommented, PushEnabled: &off})
+	db.Create(&models.NotificationPreference{UserID: 7, NotificationType: models.NotificationTypeTicketStatusChanged,
+		DoNotDisturbStart: &dndStart, DoNotDisturbEnd: &dndEnd})
+
+	svc.Deliver(ctx, notify(2, models.NotificationTypeTicketCommented, "comment"))
+	svc.Deliver(ctx, notify(3, models.NotificationTypeTicketStatusChanged, "status"))
+	if len(sent) != 0 {
+		t.Fatalf("expected preference and DND to suppress pushes, got %d", len(sent))
+	}
+
+	now = time.Date(2024, 3, 2, 9, 0, 0, 0, time.Local)
+	for i, title := range []string{"first", "second", "third"} {
+		if err := svc.Deliver(ctx, notify(uint(10+i), models.NotificationTypeTicketStatusChanged, title)); err != nil {
+			t.Fatalf("deliver failed: %v", err)
+		}
+	}
+	if len(sent) != 1 || sent[0].Title != "first" || sent[0].Count != 1 {
+		t.Fatalf("expected only the first notification to be pushed immediately, got %+v", sent)
+	}
+	if err := svc.flushBatch(ctx, 7); err != nil {
+		t.Fatalf("flush failed: %v", err)
+	}
+	if len(sent) != 2 || sent[1].Count != 2 || sent[1].Body != "third；second" {
+		t.Fatalf("expected a digest push for the rest of the window, got %+v", sent[len(sent)-1])
+	}
+
+	// 推送服务返回 410 的订阅已被删除
+	subscriptions, _ := svc.ListSubscriptions(ctx, 7)
+	if len(subscriptions) != 1 || subscriptions[0].Endpoint != "https://push.example.com/ok" || subscriptions[0].LastUsedAt == nil {
+		t.Fatalf("expected gone subscription to be removed, got %+v", subscriptions)
+	}
+}
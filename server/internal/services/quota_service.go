@@ -0,0 +1,366 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+// KeyQuotaPolicy 套餐配额配置键
+const KeyQuotaPolicy = "system.quota_policy"
+
+// ErrQuotaExceeded 超出套餐配额（含宽限）
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// QuotaExceededError 新建资源会超出含宽限的配额上限
+type QuotaExceededError struct {
+	Usage *models.QuotaUsageItem
+}
+
+func (e *QuotaExceededError) Error() string {
+	return fmt.Sprintf("%s quota exceeded: used %d of %d", e.Usage.Resource, e.Usage.Used, e.Usage.Limit)
+}
+
+// Is 使 errors.Is(err, ErrQuotaExceeded) 成立
+func (e *QuotaExceededError) Is(target error) bool {
+	return target == ErrQuotaExceeded
+}
+
+// Message 面向用户的提示
+func (e *QuotaExceededError) Message() string {
+	switch e.Usage.Resource {
+	case models.QuotaResourceAgents:
+		return fmt.Sprintf("客服席位已达到套餐上限（%d 个），请联系管理员升级套餐", e.Usage.Limit)
+	case models.QuotaResourceMonthlyTickets:
+		return fmt.Sprintf("本月工单数已达到套餐上限（%d 张），请联系管理员升级套餐", e.Usage.Limit)
+	case models.QuotaResourceAttachmentStorage:
+		return fmt.Sprintf("附件存储已达到套餐上限（%.1f GB），请清理附件或联系管理员升级套餐", float64(e.Usage.Limit)/(1<<30))
+	}
+	return "已达到套餐配额上限，请联系管理员升级套餐"
+}
+
+// QuotaService 套餐配额：用量统计、新建前检查及阈值通知
+type QuotaService struct {
+	db *gorm.DB
+}
+
+// NewQuotaService 创建配额服务
+func NewQuotaService(db *gorm.DB) *QuotaService {
+	return &QuotaService{db: db}
+}
+
+// GetPolicy 获取配额定义，未配置时返回未启用的默认值
+func (s *QuotaService) GetPolicy(ctx context.Context) (*models.QuotaPolicy, error) {
+	var config models.SystemConfig
+	err := s.db.WithContext(ctx).
+		Where("key = ? AND is_active = ?", KeyQuotaPolicy, true).
+		First(&config).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.GetDefaultQuotaPolicy(), nil
+		}
+		return nil, fmt.Errorf("failed to get quota policy: %w", err)
+	}
+
+	policy := models.GetDefaultQuotaPolicy()
+	if err := config.GetJSONValue(policy); err != nil {
+		log.Printf("Warning: failed to parse quota policy, using defaults: %v", err)
+		return models.GetDefaultQuotaPolicy(), nil
+	}
+	if err := policy.Validate(); err != nil {
+		log.Printf("Warning: invalid quota policy, using defaults: %v", err)
+		return models.GetDefaultQuotaPolicy(), nil
+	}
+	return policy, nil
+}
+
+// SetPolicy 保存配额定义
+func (s *QuotaService) SetPolicy(ctx context.Context, policy *models.QuotaPolicy, userID uint) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	var existing models.SystemConfig
+	err := s.db.WithContext(ctx).Where("key = ?", KeyQuotaPolicy).First(&existing).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to check existing quota policy: %w", err)
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		config := models.SystemConfig{
+			Key:         KeyQuotaPolicy,
+			Category:    CategorySystem,
+			Group:       "quota",
+			Description: "套餐配额：客服席位、每月工单数与附件存储",
+			IsActive:    true,
+			UpdatedBy:   &userID,
+		}
+		if err := config.SetValue(policy); err != nil {
+			return fmt.Errorf("failed to set quota policy value: %w", err)
+		}
+		if err := s.db.WithContext(ctx).Create(&config).Error; err != nil {
+			return fmt.Errorf("failed to create quota policy: %w", err)
+		}
+		return nil
+	}
+
+	if err := existing.SetValue(policy); err != nil {
+		return fmt.Errorf("failed to set quota policy value: %w", err)
+	}
+	existing.UpdatedBy = &userID
+	existing.Version++
+	if err := s.db.WithContext(ctx).Save(&existing).Error; err != nil {
+		return fmt.Errorf("failed to update quota policy: %w", err)
+	}
+	return nil
+}
+
+// GetUsage 统计各项资源的配额使用情况
+func (s *QuotaService) GetUsage(ctx context.Context, now time.Time) (*models.QuotaUsage, error) {
+	policy, err := s.GetPolicy(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &models.QuotaUsage{Policy: policy, CheckedAt: now}
+	for _, resource := range []models.QuotaResource{
+		models.QuotaResourceAgents,
+		models.QuotaResourceMonthlyTickets,
+		models.QuotaResourceAttachmentStorage,
+	} {
+		item, err := s.usageItem(ctx, policy, resource, now)
+		if err != nil {
+			return nil, err
+		}
+		usage.Items = append(usage.Items, *item)
+	}
+	return usage, nil
+}
+
+// CheckTickets 新建工单前检查当月工单配额
+func (s *QuotaService) CheckTickets(ctx context.Context, count int64) error {
+	return s.check(ctx, models.QuotaResourceMonthlyTickets, count)
+}
+
+// CheckAgents 新增客服席位前检查，非客服角色直接通过
+func (s *QuotaService) CheckAgents(ctx context.Context, role models.UserRole) error {
+	if !isQuotaSeatRole(role) {
+		return nil
+	}
+	return s.check(ctx, models.QuotaResourceAgents, 1)
+}
+
+// CheckAttachmentStorage 写入附件前检查存储配额，size 为新增字节数
+func (s *QuotaService) CheckAttachmentStorage(ctx context.Context, size int64) error {
+	return s.check(ctx, models.QuotaResourceAttachmentStorage, size)
+}
+
+// check 新增 amount 后超出含宽限的上限时返回 QuotaExceededError。
+// 软配额：配额读取或统计失败时只记录日志，不阻止创建
+func (s *QuotaService) check(ctx context.Context, resource models.QuotaResource, amount int64) error {
+	policy, err := s.GetPolicy(ctx)
+	if err != nil {
+		log.Printf("Warning: skip %s quota check: %v", resource, err)
+		return nil
+	}
+	if !policy.Enabled || policy.Limit(resource) == 0 {
+		return nil
+	}
+	item, err := s.usageItem(ctx, policy, resource, time.Now())
+	if err != nil {
+		log.Printf("Warning: skip %s quota check: %v", resource, err)
+		return nil
+	}
+	if item.Used+amount > item.HardLimit {
+		return &QuotaExceededError{Usage: item}
+	}
+	return nil
+}
+
+// NotifyThresholds 用量达到 80%/100% 时通知管理员，同一周期每个阈值只通知一次；
+// 用量回落到阈值以下后清除记录，再次达到时重新通知
+func (s *QuotaService) NotifyThresholds(ctx context.Context, resource models.QuotaResource, now time.Time) error {
+	policy, err := s.GetPolicy(ctx)
+	if err != nil {
+		return err
+	}
+	if !policy.Enabled || policy.Limit(resource) == 0 {
+		return nil
+	}
+	item, err := s.usageItem(ctx, policy, resource, now)
+	if err != nil {
+		return err
+	}
+
+	period := ""
+	if resource == models.QuotaResourceMonthlyTickets {
+		period = now.Format("2006-01")
+	}
+
+	// 只通知达到的最高阈值，其余较低阈值一并记为已通知
+	var reached []int
+	for _, threshold := range models.QuotaWarningThresholds {
+		if item.Percent >= float64(threshold) {
+			reached = append(reached, threshold)
+		}
+	}
+	if err := s.db.WithContext(ctx).
+		Where("resource = ? AND period = ? AND threshold > ?", resource, period, item.Percent).
+		Delete(&models.QuotaAlert{}).Error; err != nil {
+		return fmt.Errorf("failed to reset quota alerts: %w", err)
+	}
+	if len(reached) == 0 {
+		return nil
+	}
+
+	var alerted []int
+	if err := s.db.WithContext(ctx).Model(&models.QuotaAlert{}).
+		Where("resource = ? AND period = ?", resource, period).
+		Pluck("threshold", &alerted).Error; err != nil {
+		return fmt.Errorf("failed to get quota alerts: %w", err)
+	}
+	sent := make(map[int]bool, len(alerted))
+	for _, threshold := range alerted {
+		sent[threshold] = true
+	}
+	highest := reached[len(reached)-1]
+	if sent[highest] {
+		return nil
+	}
+
+	for _, threshold := range reached {
+		if sent[threshold] {
+			continue
+		}
+		if err := s.db.WithContext(ctx).Create(&models.QuotaAlert{
+			Resource:  resource,
+			Period:    period,
+			Threshold: threshold,
+			Used:      item.Used,
+			Limit:     item.Limit,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to record quota alert: %w", err)
+		}
+	}
+	return s.notifyAdmins(ctx, item, highest)
+}
+
+func (s *QuotaService) notifyAdmins(ctx context.Context, item *models.QuotaUsageItem, threshold int) error {
+	var adminIDs []uint
+	if err := s.db.WithContext(ctx).Model(&models.User{}).
+		Where("role = ? AND status = ?", models.RoleAdmin, models.UserStatusActive).
+		Pluck("id", &adminIDs).Error; err != nil {
+		return fmt.Errorf("failed to get admins: %w", err)
+	}
+
+	name := quotaResourceName(item.Resource)
+	title := fmt.Sprintf("%s已使用 %d%%", name, threshold)
+	content := fmt.Sprintf("%s已使用 %s / %s（%.1f%%）", name, formatQuotaAmount(item.Resource, item.Used), formatQuotaAmount(item.Resource, item.Limit), item.Percent)
+	priority := models.NotificationPriorityNormal
+	if threshold >= 100 {
+		title = fmt.Sprintf("%s已达到套餐上限", name)
+		content += fmt.Sprintf("，超出 %s 后将无法继续新建，请及时升级套餐", formatQuotaAmount(item.Resource, item.HardLimit))
+		priority = models.NotificationPriorityHigh
+	}
+
+	notificationService := NewNotificationService(s.db)
+	for _, adminID := range adminIDs {
+		if _, err := notificationService.CreateNotification(ctx, &models.NotificationCreateRequest{
+			Type:        models.NotificationTypeSystemAlert,
+			Title:       title,
+			Content:     content,
+			Priority:    priority,
+			Channel:     models.NotificationChannelInApp,
+			RecipientID: adminID,
+			RelatedType: "quota",
+			ActionURL:   "/admin/quota",
+			Metadata: map[string]interface{}{
+				"resource":  item.Resource,
+				"threshold": threshold,
+				"used":      item.Used,
+				"limit":     item.Limit,
+			},
+		}); err != nil {
+			return fmt.Errorf("failed to notify admin %d: %w", adminID, err)
+		}
+	}
+	return nil
+}
+
+func (s *QuotaService) usageItem(ctx context.Context, policy *models.QuotaPolicy, resource models.QuotaResource, now time.Time) (*models.QuotaUsageItem, error) {
+	item := &models.QuotaUsageItem{
+		Resource:  resource,
+		Limit:     policy.Limit(resource),
+		HardLimit: policy.HardLimit(resource),
+		Status:    models.QuotaStatusOK,
+	}
+
+	db := s.db.WithContext(ctx)
+	switch resource {
+	case models.QuotaResourceAgents:
+		if err := db.Model(&models.User{}).
+			Where("role IN ? AND status <> ? AND deleted_at IS NULL", []models.UserRole{models.RoleAgent, models.RoleSupervisor}, models.UserStatusDeleted).
+			Count(&item.Used).Error; err != nil {
+			return nil, fmt.Errorf("failed to count agents: %w", err)
+		}
+	case models.QuotaResourceMonthlyTickets:
+		// 已删除的工单同样计入当月用量
+		periodStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		item.PeriodStart = &periodStart
+		if err := db.Model(&models.Ticket{}).
+			Where("created_at >= ?", periodStart).
+			Count(&item.Used).Error; err != nil {
+			return nil, fmt.Errorf("failed to count tickets: %w", err)
+		}
+	case models.QuotaResourceAttachmentStorage:
+		if err := db.Model(&models.TicketAttachment{}).
+			Where("deleted_at IS NULL").
+			Select("COALESCE(SUM(file_size), 0)").
+			Scan(&item.Used).Error; err != nil {
+			return nil, fmt.Errorf("failed to sum attachment storage: %w", err)
+		}
+	}
+
+	if item.Limit > 0 {
+		item.Percent = math.Round(float64(item.Used)/float64(item.Limit)*1000) / 10
+		switch {
+		case item.Used >= item.HardLimit:
+			item.Status = models.QuotaStatusBlocked
+		case item.Used >= item.Limit:
+			item.Status = models.QuotaStatusExceeded
+		case item.Percent >= 80:
+			item.Status = models.QuotaStatusWarning
+		}
+	}
+	return item, nil
+}
+
+// isQuotaSeatRole 占用客服席位的角色
+func isQuotaSeatRole(role models.UserRole) bool {
+	return role == models.RoleAgent || role == models.RoleSupervisor
+}
+
+func quotaResourceName(resource models.QuotaResource) string {
+	switch resource {
+	case models.QuotaResourceAgents:
+		return "客服席位"
+	case models.QuotaResourceMonthlyTickets:
+		return "本月工单数"
+	case models.QuotaResourceAttachmentStorage:
+		return "附件存储"
+	}
+	return string(resource)
+}
+
+func formatQuotaAmount(resource models.QuotaResource, amount int64) string {
+	if resource == models.QuotaResourceAttachmentStorage {
+		return fmt.Sprintf("%.2f GB", float64(amount)/(1<<30))
+	}
+	return fmt.Sprintf("%d", amount)
+}
@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupQuotaTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file:quota_service_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Team{}, &models.Ticket{}, &models.TicketComment{}, &models.TicketHistory{},
+		&models.TicketAttachment{}, &models.SystemConfig{}, &models.QuotaAlert{}, &models.Notification{}, &models.NotificationPreference{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+	return db
+}
+
+func TestQuota_MonthlyTicketsSoftLimitAndAlerts(t *testing.T) {
+	db := setupQuotaTestDB(t)
+	ctx := context.Background()
+	quota := NewQuotaService(db)
+	tickets := NewTicketService(db).(*TicketService)
+
+	admin := models.User{Username: "qt-admin", Email: "qt-admin@example.com", PasswordHash: "x", Role: models.RoleAdmin, Status: models.UserStatusActive}
+	if err := db.Create(&admin).Error; err != nil {
+		t.Fatalf("failed to seed admin: %v", err)
+	}
+
+	// 上月的工单不计入本月用量
+	lastMonth := time.Now().AddDate(0, -1, -1)
+	db.Create(&models.Ticket{TicketNumber: "QT-OLD", Title: "上月工单", Status: models.TicketStatusClosed, Priority: models.TicketPriorityNormal,
+		Type: models.TicketTypeRequest, Source: models.TicketSourceWeb, CreatedByID: admin.ID, CreatedAt: lastMonth})
+
+	if err := quota.SetPolicy(ctx, &models.QuotaPolicy{Enabled: true, MonthlyTickets: 5, GracePercent: 20}, admin.ID); err != nil {
+		t.Fatalf("set policy failed: %v", err)
+	}
+
+	create := func() error {
+		_, err := tickets.CreateTicket(ctx, &models.TicketCreateRequest{Title: "配额测试", Type: models.TicketTypeRequest, Priority: models.TicketPriorityNormal}, admin.ID)
+		return err
+	}
+	alerts := func() int64 {
+		var count int64
+		db.Model(&models.Notification{}).Where("recipient_id = ? AND related_type = ?", admin.ID, "quota").Count(&count)
+		return count
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := create(); err != nil {
+			t.Fatalf("create ticket %d failed: %v", i+1, err)
+		}
+	}
+	if n := alerts(); n != 0 {
+		t.Fatalf("expected no alert below 80%%, got %d", n)
+	}
+	if err := create(); err != nil {
+		t.Fatalf("create ticket 4 failed: %v", err)
+	}
+	if n := alerts(); n != 1 {
+		t.Fatalf("expected 80%% alert, got %d notifications", n)
+	}
+
+	// 第 5 张达到上限，第 6 张在 20% 宽限内仍可创建，且不重复通知
+	if err := create(); err != nil {
+		t.Fatalf("create ticket 5 failed: %v", err)
+	}
+	if err := create(); err != nil {
+		t.Fatalf("expected ticket within grace to be allowed: %v", err)
+	}
+	if n := alerts(); n != 2 {
+		t.Fatalf("expected 80%% and 100%% alerts only, got %d notifications", n)
+	}
+
+	err := create()
+	var quotaErr *QuotaExceededError
+	if !errors.Is(err, ErrQuotaExceeded) || !errors.As(err, &quotaErr) {
+		t.Fatalf("expected quota exceeded error, got %v", err)
+	}
+	if quotaErr.Usage.Used != 6 || quotaErr.Usage.Limit != 5 || quotaErr.Message() == "" {
+		t.Fatalf("unexpected quota error usage: %+v", quotaErr.Usage)
+	}
+
+	usage, err := quota.GetUsage(ctx, time.Now())
+	if err != nil {
+		t.Fatalf("get usage failed: %v", err)
+	}
+	for _, item := range usage.Items {
+		if item.Resource == models.QuotaResourceMonthlyTickets && (item.Used != 6 || item.Status != models.QuotaStatusBlocked || item.Percent != 120) {
+			t.Fatalf("unexpected monthly ticket usage: %+v", item)
+		}
+	}
+}
+
+func TestQuota_AgentSeatsAndAttachmentStorage(t *testing.T) {
+	db := setupQuotaTestDB(t)
+	ctx := context.Background()
+	quota := NewQuotaService(db)
+	users := NewAdminUserService(db)
+
+	if err := quota.SetPolicy(ctx, &models.QuotaPolicy{Enabled: true, MaxAgents: 1, AttachmentStorageGB: 1}, 1); err != nil {
+		t.Fatalf("set policy failed: %v", err)
+	}
+
+	agent, err := users.CreateUser(ctx, &models.UserCreateRequest{Username: "qt-agent", Email: "qt-agent@example.com", Password: "password123", Role: models.RoleAgent})
+	if err != nil {
+		t.Fatalf("create first agent failed: %v", err)
+	}
+	if _, err := users.CreateUser(ctx, &models.UserCreateRequest{Username: "qt-agent2", Email: "qt-agent2@example.com", Password: "password123", Role: models.RoleSupervisor}); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("expected seat quota error, got %v", err)
+	}
+	customer, err := users.CreateUser(ctx, &models.UserCreateRequest{Username: "qt-customer", Email: "qt-customer@example.com", Password: "password123", Role: models.RoleCustomer})
+	if err != nil {
+		t.Fatalf("customers should not use seats: %v", err)
+	}
+	role := models.RoleAgent
+	if _, err := users.UpdateUser(ctx, customer.ID, &models.UserUpdateRequest{Role: &role}); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("expected promotion to agent to hit seat quota, got %v", err)
+	}
+	if _, err := users.UpdateUser(ctx, agent.ID, &models.UserUpdateRequest{Role: &role}); err != nil {
+		t.Fatalf("existing agent update should not use a new seat: %v", err)
+	}
+
+	db.Create(&models.TicketAttachment{TicketID: 1, UploadedBy: agent.ID, FileName: "a.bin", OriginalName: "a.bin", StoragePath: "a.bin", FileSize: 900 << 20})
+	if err := quota.CheckAttachmentStorage(ctx, 100<<20); err != nil {
+		t.Fatalf("expected upload within limit to pass: %v", err)
+	}
+	if err := quota.CheckAttachmentStorage(ctx, 200<<20); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("expected storage quota error, got %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"time"
+)
+
+// CacheStore 缓存存储，database.ResilientRedis 满足该接口
+type CacheStore interface {
+	Get(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error
+	Del(ctx context.Context, keys ...string) error
+}
+
+// ReadThroughCacheStats 缓存命中统计
+type ReadThroughCacheStats struct {
+	Hits        int64 `json:"hits"`
+	Misses      int64 `json:"misses"`       // 未命中或 Redis 不可用，均回源数据库
+	StoreErrors int64 `json:"store_errors"` // 写回缓存失败次数
+}
+
+// ReadThroughCache 读穿缓存：优先读 Redis，未命中或 Redis 不可用时从数据库加载并写回。
+// Redis 故障只影响命中率，不影响请求结果
+type ReadThroughCache struct {
+	store  CacheStore
+	prefix string
+
+	hits        atomic.Int64
+	misses      atomic.Int64
+	storeErrors atomic.Int64
+}
+
+// NewReadThroughCache 创建读穿缓存，store 为 nil 时每次都从数据库加载
+func NewReadThroughCache(store CacheStore, prefix string) *ReadThroughCache {
+	return &ReadThroughCache{store: store, prefix: prefix}
+}
+
+// GetOrLoad 从缓存读取到 dest；未命中时调用 load 填充 dest，load 返回 cacheable 为 false 时不写回（如结果不完整）
+func (c *ReadThroughCache) GetOrLoad(ctx context.Context, key string, ttl time.Duration, dest interface{}, load func() (cacheable bool, err error)) error {
+	if c == nil || c.store == nil {
+		_, err := load()
+		return err
+	}
+
+	fullKey := c.prefix + ":" + key
+	if raw, err := c.store.Get(ctx, fullKey); err == nil && json.Unmarshal([]byte(raw), dest) == nil {
+		c.hits.Add(1)
+		return nil
+	}
+	c.misses.Add(1)
+
+	cacheable, err := load()
+	if err != nil || !cacheable {
+		return err
+	}
+	data, err := json.Marshal(dest)
+	if err != nil {
+		return nil
+	}
+	if err := c.store.Set(ctx, fullKey, string(data), ttl); err != nil {
+		c.storeErrors.Add(1)
+	}
+	return nil
+}
+
+// Invalidate 删除缓存项，Redis 不可用时忽略（缓存会随 TTL 过期）
+func (c *ReadThroughCache) Invalidate(ctx context.Context, keys ...string) {
+	if c == nil || c.store == nil || len(keys) == 0 {
+		return
+	}
+	fullKeys := make([]string, len(keys))
+	for i, key := range keys {
+		fullKeys[i] = c.prefix + ":" + key
+	}
+	if err := c.store.Del(ctx, fullKeys...); err != nil {
+		c.storeErrors.Add(1)
+	}
+}
+
+// Stats 返回命中统计
+func (c *ReadThroughCache) Stats() ReadThroughCacheStats {
+	return ReadThroughCacheStats{
+		Hits:        c.hits.Load(),
+		Misses:      c.misses.Load(),
+		StoreErrors: c.storeErrors.Load(),
+	}
+}
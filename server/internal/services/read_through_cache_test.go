@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type memoryCacheStore struct {
+	down bool
+	data map[string]string
+}
+
+func (m *memoryCacheStore) Get(ctx context.Context, key string) (string, error) {
+	if m.down {
+		return "", errors.New("redis unavailable")
+	}
+	value, ok := m.data[key]
+	if !ok {
+		return "", errors.New("key not found")
+	}
+	return value, nil
+}
+
+func (m *memoryCacheStore) Set(ctx context.Context, key string, value interface{}, expiration time.Duration) error {
+	if m.down {
+		return errors.New("redis unavailable")
+	}
+	m.data[key] = value.(string)
+	return nil
+}
+
+func (m *memoryCacheStore) Del(ctx context.Context, keys ...string) error {
+	for _, key := range keys {
+		delete(m.data, key)
+	}
+	return nil
+}
+
+func TestReadThroughCache_FallsBackToLoader(t *testing.T) {
+	ctx := context.Background()
+	store := &memoryCacheStore{data: map[string]string{}}
+	cache := NewReadThroughCache(store, "cache:test")
+
+	loads := 0
+	get := func(cacheable bool) int {
+		var value struct{ Count int }
+		if err := cache.GetOrLoad(ctx, "k", time.Minute, &value, func() (bool, error) {
+			loads++
+			value.Count = loads
+			return cacheable, nil
+		}); err != nil {
+			t.Fatalf("get failed: %v", err)
+		}
+		return value.Count
+	}
+
+	// 不完整的结果不写入缓存
+	if get(false) != 1 || get(true) != 2 || get(true) != 2 {
+		t.Fatalf("expected second complete load to be served from cache, loads=%d", loads)
+	}
+	if _, ok := store.data["cache:test:k"]; !ok {
+		t.Fatal("expected value to be stored with prefix")
+	}
+
+	// Redis 不可用时每次回源，不返回错误
+	store.down = true
+	if get(true) != 3 || get(true) != 4 {
+		t.Fatalf("expected loader to be used while redis is down, loads=%d", loads)
+	}
+
+	store.down = false
+	cache.Invalidate(ctx, "k")
+	if get(true) != 5 {
+		t.Fatalf("expected invalidated key to be reloaded, loads=%d", loads)
+	}
+
+	stats := cache.Stats()
+	if stats.Hits != 1 || stats.Misses != 5 || stats.StoreErrors != 2 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
@@ -0,0 +1,235 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+// KeyTicketResolutionCodes 解决代码列表配置键
+const KeyTicketResolutionCodes = "ticket.resolution_codes"
+
+var (
+	// ErrResolutionCodeRequired 开启必填后解决工单时未选择解决代码
+	ErrResolutionCodeRequired = errors.New("resolution code is required to resolve ticket")
+	// ErrInvalidResolutionCode 解决代码不存在或已停用
+	ErrInvalidResolutionCode = errors.New("invalid resolution code")
+)
+
+// ResolutionCodeService 解决代码配置与统计服务
+type ResolutionCodeService struct {
+	db            *gorm.DB
+	configService *ConfigService
+}
+
+// NewResolutionCodeService 创建解决代码服务
+func NewResolutionCodeService(db *gorm.DB) *ResolutionCodeService {
+	return &ResolutionCodeService{
+		db:            db,
+		configService: NewConfigService(db),
+	}
+}
+
+// GetConfig 获取解决代码列表，未配置时返回默认列表
+func (s *ResolutionCodeService) GetConfig(ctx context.Context) (*models.ResolutionCodeConfig, error) {
+	var config models.SystemConfig
+	err := s.db.WithContext(ctx).
+		Where("key = ? AND is_active = ?", KeyTicketResolutionCodes, true).
+		First(&config).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.GetDefaultResolutionCodeConfig(), nil
+		}
+		return nil, fmt.Errorf("failed to get resolution code config: %w", err)
+	}
+
+	codes := &models.ResolutionCodeConfig{}
+	if err := config.GetJSONValue(codes); err != nil {
+		log.Printf("Warning: failed to parse resolution code config, using defaults: %v", err)
+		return models.GetDefaultResolutionCodeConfig(), nil
+	}
+	return codes, nil
+}
+
+// SetConfig 保存解决代码列表
+func (s *ResolutionCodeService) SetConfig(ctx context.Context, codes *models.ResolutionCodeConfig, userID uint) error {
+	if err := codes.Validate(); err != nil {
+		return err
+	}
+
+	var existing models.SystemConfig
+	err := s.db.WithContext(ctx).Where("key = ?", KeyTicketResolutionCodes).First(&existing).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to check existing resolution code config: %w", err)
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		config := models.SystemConfig{
+			Key:         KeyTicketResolutionCodes,
+			Category:    CategoryTicket,
+			Group:       "workflow",
+			Description: "工单解决代码",
+			IsActive:    true,
+			UpdatedBy:   &userID,
+		}
+		if err := config.SetValue(codes); err != nil {
+			return fmt.Errorf("failed to set resolution code config value: %w", err)
+		}
+		if err := s.db.WithContext(ctx).Create(&config).Error; err != nil {
+			return fmt.Errorf("failed to create resolution code config: %w", err)
+		}
+		return nil
+	}
+
+	if err := existing.SetValue(codes); err != nil {
+		return fmt.Errorf("failed to set resolution code config value: %w", err)
+	}
+	existing.UpdatedBy = &userID
+	existing.Version++
+	if err := s.db.WithContext(ctx).Save(&existing).Error; err != nil {
+		return fmt.Errorf("failed to update resolution code config: %w", err)
+	}
+	return nil
+}
+
+// RequireCode 是否开启「解决工单时必须选择解决代码」
+func (s *ResolutionCodeService) RequireCode() bool {
+	enabled, err := s.configService.GetConfigBool(KeyTicketRequireResolutionCode)
+	return err == nil && enabled
+}
+
+// ValidateCode 校验解决工单时提交的解决代码，返回规范化后的代码。
+// 代码必须是启用状态；为空时仅在开启必填时报错
+func (s *ResolutionCodeService) ValidateCode(ctx context.Context, code string) (string, error) {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if code == "" {
+		if s.RequireCode() {
+			return "", ErrResolutionCodeRequired
+		}
+		return "", nil
+	}
+
+	codes, err := s.GetConfig(ctx)
+	if err != nil {
+		return "", err
+	}
+	found, ok := codes.Find(code)
+	if !ok || !found.Active {
+		return "", fmt.Errorf("%w: %s", ErrInvalidResolutionCode, code)
+	}
+	return code, nil
+}
+
+type resolutionCodeRecord struct {
+	ResolutionCode string
+	CategoryID     *uint
+	CreatedAt      time.Time
+	ResolvedAt     time.Time
+}
+
+// Breakdown 统计 [start, end) 内解决的工单按解决代码的分布，每个代码附带工单最多的前 5 个分类
+func (s *ResolutionCodeService) Breakdown(ctx context.Context, start, end time.Time) ([]*models.ResolutionCodeBreakdownRow, error) {
+	var records []resolutionCodeRecord
+	if err := s.db.WithContext(ctx).Model(&models.Ticket{}).
+		Select("resolution_code, category_id, created_at, resolved_at").
+		Where("resolved_at >= ? AND resolved_at < ?", start, end).
+		Scan(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to get resolution code breakdown: %w", err)
+	}
+
+	type aggregate struct {
+		row        *models.ResolutionCodeBreakdownRow
+		hours      float64
+		categories map[uint]int
+	}
+	aggregates := make(map[string]*aggregate)
+	categoryIDs := make(map[uint]bool)
+	for _, record := range records {
+		agg, ok := aggregates[record.ResolutionCode]
+		if !ok {
+			agg = &aggregate{
+				row:        &models.ResolutionCodeBreakdownRow{Code: record.ResolutionCode},
+				categories: make(map[uint]int),
+			}
+			aggregates[record.ResolutionCode] = agg
+		}
+		agg.row.Tickets++
+		agg.hours += record.ResolvedAt.Sub(record.CreatedAt).Hours()
+		var categoryID uint
+		if record.CategoryID != nil {
+			categoryID = *record.CategoryID
+			categoryIDs[categoryID] = true
+		}
+		agg.categories[categoryID]++
+	}
+
+	categoryNames := make(map[uint]string, len(categoryIDs))
+	if len(categoryIDs) > 0 {
+		ids := make([]uint, 0, len(categoryIDs))
+		for id := range categoryIDs {
+			ids = append(ids, id)
+		}
+		var categories []models.Category
+		if err := s.db.WithContext(ctx).Select("id, name").Where("id IN ?", ids).Find(&categories).Error; err != nil {
+			return nil, fmt.Errorf("failed to get categories: %w", err)
+		}
+		for _, category := range categories {
+			categoryNames[category.ID] = category.Name
+		}
+	}
+
+	codes, err := s.GetConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*models.ResolutionCodeBreakdownRow, 0, len(aggregates))
+	for code, agg := range aggregates {
+		row := agg.row
+		switch found, ok := codes.Find(code); {
+		case code == "":
+			row.Name = "未选择"
+		case ok:
+			row.Name = found.Name
+		default:
+			row.Name = code
+		}
+		row.Share = float64(row.Tickets) / float64(len(records))
+		row.AvgResolutionHours = agg.hours / float64(row.Tickets)
+
+		row.Categories = make([]models.ResolutionCodeCategoryCount, 0, len(agg.categories))
+		for categoryID, count := range agg.categories {
+			entry := models.ResolutionCodeCategoryCount{Name: "未分类", Tickets: count}
+			if categoryID != 0 {
+				id := categoryID
+				entry.CategoryID = &id
+				entry.Name = categoryNames[categoryID]
+			}
+			row.Categories = append(row.Categories, entry)
+		}
+		sort.Slice(row.Categories, func(i, j int) bool {
+			if row.Categories[i].Tickets != row.Categories[j].Tickets {
+				return row.Categories[i].Tickets > row.Categories[j].Tickets
+			}
+			return row.Categories[i].Name < row.Categories[j].Name
+		})
+		if len(row.Categories) > 5 {
+			row.Categories = row.Categories[:5]
+		}
+		result = append(result, row)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Tickets != result[j].Tickets {
+			return result[i].Tickets > result[j].Tickets
+		}
+		return result[i].Code < result[j].Code
+	})
+	return result, nil
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestResolutionCode_RequiredSelectionAutomationAndBreakdown(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:resolution_code_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Category{}, &models.Ticket{}, &models.TicketHistory{}, &models.TicketChecklistItem{},
+		&models.SystemConfig{}, &models.AutomationRule{}, &models.AutomationRuleRevision{}, &models.AutomationLog{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+	ctx := context.Background()
+
+	agent := models.User{Username: "rc-agent", Email: "rc-agent@example.com", PasswordHash: "x", Role: models.RoleAgent}
+	db.Create(&agent)
+	billing := models.Category{Name: "Billing", Slug: "billing", Type: models.CategoryTypeBilling, Status: models.CategoryStatusActive}
+	db.Create(&billing)
+	seed := func(number string) *models.Ticket {
+		ticket := &models.Ticket{TicketNumber: number, Title: number, Status: models.TicketStatusOpen, Priority: models.TicketPriorityNormal,
+			Type: models.TicketTypeIncident, Source: models.TicketSourceWeb, CreatedByID: agent.ID, CategoryID: &billing.ID}
+		if err := db.Create(ticket).Error; err != nil {
+			t.Fatalf("failed to seed ticket: %v", err)
+		}
+		db.Model(ticket).Update("created_at", time.Now().Add(-4*time.Hour))
+		return ticket
+	}
+
+	svc := NewResolutionCodeService(db)
+	codes := models.GetDefaultResolutionCodeConfig()
+	codes.Codes = append(codes.Codes, models.ResolutionCode{Code: "Legacy", Name: "旧代码"}, models.ResolutionCode{Code: "legacy", Name: "重复"})
+	if err := svc.SetConfig(ctx, codes, agent.ID); err == nil {
+		t.Fatal("expected duplicate code to be rejected")
+	}
+	codes.Codes = codes.Codes[:len(codes.Codes)-1]
+	if err := svc.SetConfig(ctx, codes, agent.ID); err != nil {
+		t.Fatalf("set codes: %v", err)
+	}
+
+	if _, err := NewAutomationService(db).CreateRule(ctx, &models.AutomationRuleRequest{
+		Name:         "客户操作问题降级",
+		RuleType:     "priority",
+		TriggerEvent: models.TriggerTicketResolved,
+		Conditions:   []models.RuleCondition{{Field: "resolution_code", Operator: "eq", Value: "customer_error"}},
+		Actions:      []models.RuleAction{{Type: "set_priority", Params: map[string]interface{}{"priority": "low"}}},
+	}, agent.ID); err != nil {
+		t.Fatalf("create rule failed: %v", err)
+	}
+
+	// 开启必填后未选择、已停用或不存在的代码都不能解决工单
+	if err := NewConfigService(db).SetConfig(KeyTicketRequireResolutionCode, "true", "bool", "", CategoryTicket, "workflow"); err != nil {
+		t.Fatalf("set config failed: %v", err)
+	}
+	tickets := NewTicketService(db).(*TicketService)
+	first := seed("RC-1")
+	if _, err := tickets.UpdateTicketStatus(first.ID, string(models.TicketStatusResolved), agent.ID, "", "", ""); !errors.Is(err, ErrResolutionCodeRequired) {
+		t.Fatalf("expected resolution code to be required, got %v", err)
+	}
+	for _, code := range []string{"legacy", "unknown"} {
+		if _, err := tickets.UpdateTicketStatus(first.ID, string(models.TicketStatusResolved), agent.ID, "", "", code); !errors.Is(err, ErrInvalidResolutionCode) {
+			t.Fatalf("expected %s to be rejected, got %v", code, err)
+		}
+	}
+
+	resolved, err := tickets.UpdateTicketStatus(first.ID, string(models.TicketStatusResolved), agent.ID, "", "", " Customer_Error ")
+	if err != nil || resolved.ResolutionCode != "customer_error" {
+		t.Fatalf("expected customer_error to be stored, got %+v, %v", resolved, err)
+	}
+	var reloaded models.Ticket
+	db.First(&reloaded, first.ID)
+	if reloaded.Priority != models.TicketPriorityLow {
+		t.Fatalf("expected ticket.resolved rule to lower priority, got %s", reloaded.Priority)
+	}
+
+	// 重新打开清空解决代码，再次解决时重新选择
+	reopen := models.TicketStatusOpen
+	if updated, err := tickets.UpdateTicket(ctx, first.ID, &models.TicketUpdateRequest{Status: &reopen}, agent.ID); err != nil || updated.ResolutionCode != "" {
+		t.Fatalf("expected reopen to clear resolution code, got %+v, %v", updated, err)
+	}
+	status, fixed := models.TicketStatusResolved, "fixed"
+	if updated, err := tickets.UpdateTicket(ctx, first.ID, &models.TicketUpdateRequest{Status: &status, ResolutionCode: &fixed}, agent.ID); err != nil || updated.ResolutionCode != "fixed" {
+		t.Fatalf("expected fixed resolution code, got %+v, %v", updated, err)
+	}
+	var histories int64
+	db.Model(&models.TicketHistory{}).Where("ticket_id = ? AND field_name = ?", first.ID, "resolution_code").Count(&histories)
+	if histories != 2 {
+		t.Fatalf("expected 2 resolution code history records, got %d", histories)
+	}
+
+	// 批量解决同样校验代码
+	second, third := seed("RC-2"), seed("RC-3")
+	resolvedStatus := string(models.TicketStatusResolved)
+	if err := tickets.BulkUpdateTickets(ctx, &BulkUpdateRequest{TicketIDs: []uint{second.ID, third.ID}, Status: &resolvedStatus}, agent.ID); !errors.Is(err, ErrResolutionCodeRequired) {
+		t.Fatalf("expected bulk resolve to require code, got %v", err)
+	}
+	duplicate := "duplicate"
+	if err := tickets.BulkUpdateTickets(ctx, &BulkUpdateRequest{TicketIDs: []uint{second.ID, third.ID}, Status: &resolvedStatus, ResolutionCode: &duplicate}, agent.ID); err != nil {
+		t.Fatalf("bulk resolve: %v", err)
+	}
+	db.Model(&models.Ticket{}).Where("id IN ?", []uint{second.ID, third.ID}).Update("resolved_at", time.Now())
+
+	rows, err := svc.Breakdown(ctx, time.Now().Add(-24*time.Hour), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("breakdown: %v", err)
+	}
+	if len(rows) != 2 || rows[0].Code != "duplicate" || rows[0].Tickets != 2 || rows[0].Name != "重复工单" {
+		t.Fatalf("unexpected breakdown %+v", rows)
+	}
+	if rows[0].AvgResolutionHours < 3.9 || len(rows[0].Categories) != 1 || rows[0].Categories[0].Name != "Billing" {
+		t.Fatalf("unexpected duplicate row %+v", rows[0])
+	}
+	if rows[1].Code != "fixed" || rows[1].Share < 0.33 || rows[1].Share > 0.34 {
+		t.Fatalf("unexpected fixed row %+v", rows[1])
+	}
+}
@@ -0,0 +1,461 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+// KeySchedulerJobSchedules 定时任务调度覆盖配置键，值为任务ID到调度覆盖的映射
+const KeySchedulerJobSchedules = "scheduler.job_schedules"
+
+const (
+	schedulerMinInterval     = time.Minute // 调度检查间隔为30秒，更密的调度没有意义
+	schedulerIntervalSamples = 10
+	schedulerDefaultPreview  = 5
+	schedulerMaxPreview      = 20
+)
+
+var (
+	// ErrSchedulerJobNotFound 定时任务不存在
+	ErrSchedulerJobNotFound = errors.New("scheduled job not found")
+	// ErrInvalidSchedule cron 表达式或超时时间无效
+	ErrInvalidSchedule = errors.New("invalid schedule")
+	// ErrSchedulerJobRunning 任务正在执行
+	ErrSchedulerJobRunning = errors.New("scheduled job is running")
+	// ErrInvalidJobParams 立即执行的参数无效
+	ErrInvalidJobParams = errors.New("invalid job params")
+)
+
+// schedulerCronParser 支持带秒（6段）或不带秒（5段）的表达式及 @hourly、@every 等描述符
+var schedulerCronParser = cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// 可覆盖的任务参数
+var (
+	asOfJobParam = models.SchedulerJobParam{
+		Name:        "as_of",
+		Type:        models.SchedulerJobParamTime,
+		Description: "按指定时间判断到期与保留期，不能晚于当前时间，用于补跑",
+	}
+	repairJobParam = models.SchedulerJobParam{
+		Name:        "repair",
+		Type:        models.SchedulerJobParamBool,
+		Description: "修正不一致的计数并删除孤立记录",
+	}
+)
+
+// parseSchedulerCron 解析 cron 表达式，相邻两次执行的间隔不能小于1分钟
+func parseSchedulerCron(expr string) (cron.Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return nil, fmt.Errorf("%w: cron expression is required", ErrInvalidSchedule)
+	}
+	schedule, err := schedulerCronParser.Parse(expr)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchedule, err)
+	}
+	prev := schedule.Next(time.Now())
+	if prev.IsZero() {
+		return nil, fmt.Errorf("%w: cron expression never fires", ErrInvalidSchedule)
+	}
+	for i := 0; i < schedulerIntervalSamples; i++ {
+		next := schedule.Next(prev)
+		if !next.IsZero() && next.Sub(prev) < schedulerMinInterval {
+			return nil, fmt.Errorf("%w: runs must be at least %s apart", ErrInvalidSchedule, schedulerMinInterval)
+		}
+		prev = next
+	}
+	return schedule, nil
+}
+
+// PreviewCron 预览 cron 表达式在 from 之后的执行时间，count 为 0 时返回5次
+func (s *SchedulerService) PreviewCron(expr string, count int, from time.Time) (*models.SchedulerCronPreview, error) {
+	schedule, err := parseSchedulerCron(expr)
+	if err != nil {
+		return nil, err
+	}
+	if count <= 0 {
+		count = schedulerDefaultPreview
+	}
+	if count > schedulerMaxPreview {
+		count = schedulerMaxPreview
+	}
+	return &models.SchedulerCronPreview{CronExpr: strings.TrimSpace(expr), Runs: upcomingRuns(schedule, from, count)}, nil
+}
+
+// ListJobs 获取全部定时任务的调度与执行状态，按任务ID排序
+func (s *SchedulerService) ListJobs() []models.SchedulerJobInfo {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	jobs := make([]models.SchedulerJobInfo, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, jobInfo(job, 0))
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID < jobs[j].ID })
+	return jobs
+}
+
+// GetJob 获取定时任务详情，附带接下来 preview 次执行时间
+func (s *SchedulerService) GetJob(jobID string, preview int) (*models.SchedulerJobInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	job, ok := s.jobs[jobID]
+	if !ok {
+		return nil, ErrSchedulerJobNotFound
+	}
+	info := jobInfo(job, preview)
+	return &info, nil
+}
+
+// UpdateJobSchedule 修改定时任务的调度并保存到系统配置，只修改请求中提供的字段；
+// 与默认值相同的字段不保存，其他实例在下次调度检查时生效
+func (s *SchedulerService) UpdateJobSchedule(ctx context.Context, jobID string, req *models.SchedulerJobUpdateRequest, userID uint) (*models.SchedulerJobInfo, error) {
+	s.mu.RLock()
+	job, ok := s.jobs[jobID]
+	var defaultCron string
+	var defaultActive bool
+	var defaultTimeout time.Duration
+	if ok {
+		defaultCron, defaultActive, defaultTimeout = job.defaultCronExpr, job.defaultActive, job.defaultTimeout
+	}
+	s.mu.RUnlock()
+	if !ok {
+		return nil, ErrSchedulerJobNotFound
+	}
+
+	if req.CronExpr != nil {
+		if _, err := parseSchedulerCron(*req.CronExpr); err != nil {
+			return nil, err
+		}
+	}
+	if req.TimeoutSeconds != nil && (*req.TimeoutSeconds < 1 || *req.TimeoutSeconds > models.SchedulerJobMaxTimeoutSeconds) {
+		return nil, fmt.Errorf("%w: timeout_seconds must be between 1 and %d", ErrInvalidSchedule, models.SchedulerJobMaxTimeoutSeconds)
+	}
+
+	err := s.saveOverrides(ctx, userID, func(overrides map[string]models.SchedulerJobOverride) {
+		override := overrides[jobID]
+		if req.CronExpr != nil {
+			override.CronExpr = strings.TrimSpace(*req.CronExpr)
+			if override.CronExpr == defaultCron {
+				override.CronExpr = ""
+			}
+		}
+		if req.IsActive != nil {
+			override.IsActive = req.IsActive
+			if *req.IsActive == defaultActive {
+				override.IsActive = nil
+			}
+		}
+		if req.TimeoutSeconds != nil {
+			override.TimeoutSeconds = *req.TimeoutSeconds
+			if time.Duration(override.TimeoutSeconds)*time.Second == defaultTimeout {
+				override.TimeoutSeconds = 0
+			}
+		}
+		if isEmptyJobOverride(override) {
+			delete(overrides, jobID)
+			return
+		}
+		overrides[jobID] = override
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.refreshOverrides(ctx)
+	return s.GetJob(jobID, 0)
+}
+
+// ResetJobSchedule 恢复定时任务的默认调度
+func (s *SchedulerService) ResetJobSchedule(ctx context.Context, jobID string, userID uint) (*models.SchedulerJobInfo, error) {
+	s.mu.RLock()
+	_, ok := s.jobs[jobID]
+	s.mu.RUnlock()
+	if !ok {
+		return nil, ErrSchedulerJobNotFound
+	}
+
+	if err := s.saveOverrides(ctx, userID, func(overrides map[string]models.SchedulerJobOverride) {
+		delete(overrides, jobID)
+	}); err != nil {
+		return nil, err
+	}
+	s.refreshOverrides(ctx)
+	return s.GetJob(jobID, 0)
+}
+
+// RunJob 立即在后台执行定时任务，可覆盖任务声明的参数及本次的超时时间；停用的任务也可立即执行。
+// 执行结束后按调度重新计算下次执行时间
+func (s *SchedulerService) RunJob(jobID string, req *models.SchedulerJobRunRequest, now time.Time) (*models.SchedulerJobInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[jobID]
+	if !ok {
+		return nil, ErrSchedulerJobNotFound
+	}
+	if job.running {
+		return nil, ErrSchedulerJobRunning
+	}
+	params, err := parseJobParams(job.Params, req.Params, now)
+	if err != nil {
+		return nil, err
+	}
+	timeout := job.Timeout
+	if req.TimeoutSeconds != 0 {
+		if req.TimeoutSeconds < 1 || req.TimeoutSeconds > models.SchedulerJobMaxTimeoutSeconds {
+			return nil, fmt.Errorf("%w: timeout_seconds must be between 1 and %d", ErrInvalidJobParams, models.SchedulerJobMaxTimeoutSeconds)
+		}
+		timeout = time.Duration(req.TimeoutSeconds) * time.Second
+	}
+
+	job.running = true
+	go s.executeJob(job, params, timeout)
+	log.Printf("Job %s triggered manually", job.ID)
+
+	info := jobInfo(job, 0)
+	return &info, nil
+}
+
+// refreshOverrides 加载调度覆盖配置，版本变化时重新应用到全部任务
+func (s *SchedulerService) refreshOverrides(ctx context.Context) {
+	overrides, version, err := s.loadOverrides(ctx)
+	if err != nil {
+		log.Printf("Failed to load scheduler job schedules: %v", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if version == s.overrideVersion {
+		return
+	}
+	s.overrideVersion = version
+	now := time.Now()
+	for id, job := range s.jobs {
+		applyJobOverride(job, overrides[id], now)
+	}
+}
+
+func (s *SchedulerService) loadOverrides(ctx context.Context) (map[string]models.SchedulerJobOverride, int, error) {
+	overrides := make(map[string]models.SchedulerJobOverride)
+	var config models.SystemConfig
+	err := s.db.WithContext(ctx).Where("key = ?", KeySchedulerJobSchedules).First(&config).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return overrides, 0, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get scheduler job schedules: %w", err)
+	}
+	if err := config.GetJSONValue(&overrides); err != nil {
+		return nil, 0, fmt.Errorf("failed to parse scheduler job schedules: %w", err)
+	}
+	if overrides == nil {
+		overrides = make(map[string]models.SchedulerJobOverride)
+	}
+	return overrides, config.Version, nil
+}
+
+// saveOverrides 在事务中读取、修改并保存调度覆盖配置
+func (s *SchedulerService) saveOverrides(ctx context.Context, userID uint, mutate func(map[string]models.SchedulerJobOverride)) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		overrides := make(map[string]models.SchedulerJobOverride)
+		var config models.SystemConfig
+		err := tx.Where("key = ?", KeySchedulerJobSchedules).First(&config).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to get scheduler job schedules: %w", err)
+		}
+		exists := err == nil
+		if exists {
+			if err := config.GetJSONValue(&overrides); err != nil || overrides == nil {
+				overrides = make(map[string]models.SchedulerJobOverride)
+			}
+		}
+
+		mutate(overrides)
+		if err := config.SetValue(overrides); err != nil {
+			return fmt.Errorf("failed to set scheduler job schedules: %w", err)
+		}
+		config.UpdatedBy = &userID
+
+		if !exists {
+			config.Key = KeySchedulerJobSchedules
+			config.Category = CategorySystem
+			config.Group = "scheduler"
+			config.Description = "定时任务的 cron 表达式、启用状态与超时时间覆盖"
+			config.IsActive = true
+			if err := tx.Create(&config).Error; err != nil {
+				return fmt.Errorf("failed to create scheduler job schedules: %w", err)
+			}
+			return nil
+		}
+		config.Version++
+		if err := tx.Save(&config).Error; err != nil {
+			return fmt.Errorf("failed to update scheduler job schedules: %w", err)
+		}
+		return nil
+	})
+}
+
+// applyJobOverride 以任务的默认调度为基础应用覆盖，调度变化或重新启用时重新计算下次执行时间。调用方须持有锁
+func applyJobOverride(job *ScheduledJob, override models.SchedulerJobOverride, now time.Time) {
+	cronExpr := job.defaultCronExpr
+	if override.CronExpr != "" {
+		if _, err := parseSchedulerCron(override.CronExpr); err != nil {
+			log.Printf("Ignoring invalid schedule for job %s: %v", job.ID, err)
+		} else {
+			cronExpr = override.CronExpr
+		}
+	}
+	active := job.defaultActive
+	if override.IsActive != nil {
+		active = *override.IsActive
+	}
+	timeout := job.defaultTimeout
+	if override.TimeoutSeconds > 0 {
+		timeout = time.Duration(override.TimeoutSeconds) * time.Second
+	}
+
+	if cronExpr != job.CronExpr || (active && !job.IsActive) {
+		if schedule, err := parseSchedulerCron(cronExpr); err == nil {
+			job.NextRun = schedule.Next(now)
+		}
+	}
+	job.CronExpr = cronExpr
+	job.IsActive = active
+	job.Timeout = timeout
+	job.overridden = !isEmptyJobOverride(override)
+}
+
+func isEmptyJobOverride(override models.SchedulerJobOverride) bool {
+	return override.CronExpr == "" && override.IsActive == nil && override.TimeoutSeconds == 0
+}
+
+// jobInfo 生成任务状态，preview 大于0时附带接下来的执行时间。调用方须持有锁
+func jobInfo(job *ScheduledJob, preview int) models.SchedulerJobInfo {
+	info := models.SchedulerJobInfo{
+		ID:              job.ID,
+		Name:            job.Name,
+		Description:     job.Description,
+		CronExpr:        job.CronExpr,
+		DefaultCronExpr: job.defaultCronExpr,
+		IsActive:        job.IsActive,
+		TimeoutSeconds:  int(job.Timeout / time.Second),
+		Overridden:      job.overridden,
+		Running:         job.running,
+		LastError:       job.lastError,
+		RunCount:        job.RunCount,
+		ErrorCount:      job.ErrorCount,
+		Params:          job.Params,
+	}
+	if !job.LastRun.IsZero() {
+		lastRun := job.LastRun
+		info.LastRun = &lastRun
+		info.LastDuration = job.lastDuration.String()
+	}
+	if job.IsActive {
+		nextRun := job.NextRun
+		info.NextRun = &nextRun
+	}
+	if preview > 0 && job.IsActive {
+		if preview > schedulerMaxPreview {
+			preview = schedulerMaxPreview
+		}
+		if schedule, err := parseSchedulerCron(job.CronExpr); err == nil {
+			info.UpcomingRuns = upcomingRuns(schedule, job.NextRun.Add(-time.Second), preview)
+		}
+	}
+	return info
+}
+
+func upcomingRuns(schedule cron.Schedule, from time.Time, count int) []time.Time {
+	runs := make([]time.Time, 0, count)
+	next := from
+	for i := 0; i < count; i++ {
+		next = schedule.Next(next)
+		if next.IsZero() {
+			break
+		}
+		runs = append(runs, next)
+	}
+	return runs
+}
+
+// jobParamsKey 立即执行时参数覆盖的上下文键
+type jobParamsKey struct{}
+
+// jobParams 立即执行时的参数覆盖，值已按参数类型转换
+type jobParams map[string]interface{}
+
+func jobParamsFrom(ctx context.Context) jobParams {
+	params, _ := ctx.Value(jobParamsKey{}).(jobParams)
+	return params
+}
+
+func (p jobParams) time(name string, fallback time.Time) time.Time {
+	if value, ok := p[name].(time.Time); ok {
+		return value
+	}
+	return fallback
+}
+
+func (p jobParams) bool(name string, fallback bool) bool {
+	if value, ok := p[name].(bool); ok {
+		return value
+	}
+	return fallback
+}
+
+// jobTime 任务判断到期使用的时间，立即执行时可通过 as_of 指定
+func jobTime(ctx context.Context) time.Time {
+	return jobParamsFrom(ctx).time(asOfJobParam.Name, time.Now())
+}
+
+// parseJobParams 按任务声明的参数校验并转换参数覆盖
+func parseJobParams(specs []models.SchedulerJobParam, values map[string]interface{}, now time.Time) (jobParams, error) {
+	params := make(jobParams, len(values))
+	for name, value := range values {
+		var spec *models.SchedulerJobParam
+		for i := range specs {
+			if specs[i].Name == name {
+				spec = &specs[i]
+				break
+			}
+		}
+		if spec == nil {
+			return nil, fmt.Errorf("%w: unknown parameter %q", ErrInvalidJobParams, name)
+		}
+
+		switch spec.Type {
+		case models.SchedulerJobParamTime:
+			str, ok := value.(string)
+			if !ok {
+				return nil, fmt.Errorf("%w: %s must be an RFC3339 time", ErrInvalidJobParams, name)
+			}
+			parsed, err := time.Parse(time.RFC3339, str)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s must be an RFC3339 time", ErrInvalidJobParams, name)
+			}
+			if parsed.After(now) {
+				return nil, fmt.Errorf("%w: %s must not be in the future", ErrInvalidJobParams, name)
+			}
+			params[name] = parsed
+		case models.SchedulerJobParamBool:
+			b, ok := value.(bool)
+			if !ok {
+				return nil, fmt.Errorf("%w: %s must be a boolean", ErrInvalidJobParams, name)
+			}
+			params[name] = b
+		}
+	}
+	return params, nil
+}
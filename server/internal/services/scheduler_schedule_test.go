@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestSchedulerService_CronSchedulesOverridesAndManualRun(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:scheduler_schedule_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.SystemConfig{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+	ctx := context.Background()
+	scheduler := NewSchedulerService(db)
+
+	// cron 表达式按实际时间计算，5段表达式省略秒
+	from := time.Date(2024, 1, 15, 10, 7, 0, 0, time.Local)
+	preview, err := scheduler.PreviewCron("30 4 * * 1-5", 3, from)
+	if err != nil || len(preview.Runs) != 3 || !preview.Runs[0].Equal(time.Date(2024, 1, 16, 4, 30, 0, 0, time.Local)) {
+		t.Fatalf("unexpected preview %+v, %v", preview, err)
+	}
+	for _, expr := range []string{"", "not a cron", "*/10 * * * * *"} {
+		if _, err := scheduler.PreviewCron(expr, 1, from); !errors.Is(err, ErrInvalidSchedule) {
+			t.Fatalf("expected %q to be rejected, got %v", expr, err)
+		}
+	}
+	job, err := scheduler.GetJob("orphan_upload_purge", 2)
+	if err != nil || job.NextRun == nil || job.NextRun.Minute() != 20 || len(job.UpcomingRuns) != 2 {
+		t.Fatalf("expected orphan purge to run at minute 20, got %+v, %v", job, err)
+	}
+
+	// 修改的调度保存到系统配置，新实例启动时加载
+	cronExpr, inactive := "0 30 4 * * *", false
+	updated, err := scheduler.UpdateJobSchedule(ctx, "webhook_log_purge", &models.SchedulerJobUpdateRequest{CronExpr: &cronExpr, IsActive: &inactive}, 1)
+	if err != nil || !updated.Overridden || updated.IsActive || updated.NextRun != nil || updated.DefaultCronExpr != "0 0 3 * * *" {
+		t.Fatalf("unexpected updated job %+v, %v", updated, err)
+	}
+	badTimeout := 0
+	if _, err := scheduler.UpdateJobSchedule(ctx, "webhook_log_purge", &models.SchedulerJobUpdateRequest{TimeoutSeconds: &badTimeout}, 1); !errors.Is(err, ErrInvalidSchedule) {
+		t.Fatalf("expected invalid timeout to be rejected, got %v", err)
+	}
+	if _, err := scheduler.UpdateJobSchedule(ctx, "missing", &models.SchedulerJobUpdateRequest{CronExpr: &cronExpr}, 1); !errors.Is(err, ErrSchedulerJobNotFound) {
+		t.Fatalf("expected unknown job, got %v", err)
+	}
+	reloaded, err := NewSchedulerService(db).GetJob("webhook_log_purge", 0)
+	if err != nil || reloaded.CronExpr != cronExpr || reloaded.IsActive {
+		t.Fatalf("expected override to be loaded by a new instance, got %+v, %v", reloaded, err)
+	}
+
+	// 启用状态改回默认值后只保留 cron 覆盖，恢复默认后不再视为覆盖
+	active := true
+	updated, err = scheduler.UpdateJobSchedule(ctx, "webhook_log_purge", &models.SchedulerJobUpdateRequest{IsActive: &active}, 1)
+	if err != nil || !updated.Overridden || !updated.IsActive || updated.NextRun == nil || updated.NextRun.Hour() != 4 {
+		t.Fatalf("expected re-enabled job with custom schedule, got %+v, %v", updated, err)
+	}
+	reset, err := scheduler.ResetJobSchedule(ctx, "webhook_log_purge", 1)
+	if err != nil || reset.Overridden || reset.CronExpr != "0 0 3 * * *" {
+		t.Fatalf("expected default schedule after reset, got %+v, %v", reset, err)
+	}
+
+	// 立即执行：参数按任务声明校验，执行中的任务不能重复触发
+	seen := make(chan time.Time)
+	release := make(chan struct{})
+	scheduler.AddJob(&ScheduledJob{
+		ID:       "test_purge",
+		Name:     "测试清理",
+		CronExpr: "0 0 * * * *",
+		IsActive: true,
+		Params:   []models.SchedulerJobParam{asOfJobParam},
+		Handler: func(ctx context.Context) error {
+			seen <- jobTime(ctx)
+			<-release
+			return errors.New("boom")
+		},
+	})
+	now := time.Now()
+	if _, err := scheduler.RunJob("test_purge", &models.SchedulerJobRunRequest{Params: map[string]interface{}{"repair": true}}, now); !errors.Is(err, ErrInvalidJobParams) {
+		t.Fatalf("expected undeclared param to be rejected, got %v", err)
+	}
+	future := now.Add(time.Hour).Format(time.RFC3339)
+	if _, err := scheduler.RunJob("test_purge", &models.SchedulerJobRunRequest{Params: map[string]interface{}{"as_of": future}}, now); !errors.Is(err, ErrInvalidJobParams) {
+		t.Fatalf("expected future as_of to be rejected, got %v", err)
+	}
+	asOf := "2024-01-15T00:00:00Z"
+	if _, err := scheduler.RunJob("test_purge", &models.SchedulerJobRunRequest{Params: map[string]interface{}{"as_of": asOf}}, now); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if got := <-seen; got.Format(time.RFC3339) != asOf {
+		t.Fatalf("expected handler to see as_of %s, got %s", asOf, got)
+	}
+	if _, err := scheduler.RunJob("test_purge", &models.SchedulerJobRunRequest{}, now); !errors.Is(err, ErrSchedulerJobRunning) {
+		t.Fatalf("expected running job to be rejected, got %v", err)
+	}
+	close(release)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		job, _ := scheduler.GetJob("test_purge", 0)
+		if !job.Running {
+			if job.RunCount != 1 || job.ErrorCount != 1 || job.LastError != "boom" || job.LastRun == nil {
+				t.Fatalf("unexpected job state after run %+v", job)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for job to finish")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
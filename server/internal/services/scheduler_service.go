@@ -14,13 +14,32 @@ import (
 
 // SchedulerService 调度服务
 type SchedulerService struct {
-	db                *gorm.DB
-	escalationService *EscalationService
-	automationService *AutomationService
-	jobs              map[string]*ScheduledJob
-	running           bool
-	stopChan          chan struct{}
-	mu                sync.RWMutex
+	db                 *gorm.DB
+	escalationService  *EscalationService
+	automationService  *AutomationService
+	autoCloseService   *AutoCloseService
+	agingService       *BacklogAgingService
+	cleanupService     *CleanupService
+	maintenanceService *MaintenanceService
+	delegationService  *DelegationService
+	draftService       *CommentDraftService
+	consistencyService *ConsistencyService
+	accessAuditService *TicketAccessAuditService
+	deletionService    *AccountDeletionService
+	uploadService      *UploadService
+	webhookLogService  *WebhookLogService
+	syncService        *SyncService
+	warRoomService     *WarRoomService
+	handoffService     *TicketHandoffService
+	slaWarningService  *SLAWarningService
+	reminderService    *TicketReminderService
+	emailService       EmailNotificationServiceInterface
+	downloadCenter     *DownloadCenterService
+	jobs               map[string]*ScheduledJob
+	overrideVersion    int // 已应用的调度覆盖配置版本，-1 表示尚未加载
+	running            bool
+	stopChan           chan struct{}
+	mu                 sync.RWMutex
 }
 
 // ScheduledJob 定时任务
@@ -36,6 +55,17 @@ type ScheduledJob struct {
 	RunCount    int64
 	ErrorCount  int64
 	Timeout     time.Duration
+	Params      []models.SchedulerJobParam // 立即执行时可覆盖的参数
+
+	// 注册时的默认调度，管理员修改调度后用于恢复
+	defaultCronExpr string
+	defaultActive   bool
+	defaultTimeout  time.Duration
+	overridden      bool
+
+	running      bool
+	lastError    string
+	lastDuration time.Duration
 }
 
 const defaultJobTimeout = 2 * time.Minute
@@ -53,16 +83,33 @@ type JobResult struct {
 // NewSchedulerService 创建调度服务
 func NewSchedulerService(db *gorm.DB) *SchedulerService {
 	service := &SchedulerService{
-		db:       db,
-		jobs:     make(map[string]*ScheduledJob),
-		stopChan: make(chan struct{}),
+		db:              db,
+		jobs:            make(map[string]*ScheduledJob),
+		overrideVersion: -1,
+		stopChan:        make(chan struct{}),
 	}
 
 	service.escalationService = NewEscalationService(db)
 	service.automationService = NewAutomationService(db)
-
-	// 注册默认任务
+	service.autoCloseService = NewAutoCloseService(db)
+	service.agingService = NewBacklogAgingService(db)
+	service.cleanupService = NewCleanupService(db)
+	service.maintenanceService = NewMaintenanceService(db)
+	service.delegationService = NewDelegationService(db)
+	service.draftService = NewCommentDraftService(db)
+	service.consistencyService = NewConsistencyService(db)
+	service.accessAuditService = NewTicketAccessAuditService(db)
+	service.deletionService = NewAccountDeletionService(db)
+	service.webhookLogService = NewWebhookLogService(db)
+	service.syncService = NewSyncService(db)
+	service.warRoomService = NewWarRoomService(db)
+	service.handoffService = NewTicketHandoffService(db)
+	service.slaWarningService = NewSLAWarningService(db)
+	service.reminderService = NewTicketReminderService(db)
+
+	// 注册默认任务，并应用管理员修改过的调度
 	service.registerDefaultJobs()
+	service.refreshOverrides(context.Background())
 
 	return service
 }
@@ -102,6 +149,216 @@ func (s *SchedulerService) registerDefaultJobs() {
 		Timeout:     5 * time.Minute,
 	})
 
+	// 通知归档任务 - 每天凌晨2点执行
+	s.AddJob(&ScheduledJob{
+		ID:          "notification_archive",
+		Name:        "通知归档",
+		Description: "按保留策略将过期的已读通知移入归档表或删除",
+		CronExpr:    "0 0 2 * * *", // 每天2点
+		Handler:     s.notificationArchiveHandler,
+		IsActive:    true,
+		Timeout:     10 * time.Minute,
+	})
+
+	// 数据一致性检查任务 - 每天凌晨2点执行，只报告不修复
+	s.AddJob(&ScheduledJob{
+		ID:          "consistency_check",
+		Name:        "数据一致性检查",
+		Description: "核对工单评论数、分类工单数、用户分配数等冗余计数及孤立的工单历史，结果写入日志",
+		CronExpr:    "0 0 2 * * *", // 每天2点
+		Handler:     s.consistencyCheckHandler,
+		IsActive:    true,
+		Timeout:     10 * time.Minute,
+		Params:      []models.SchedulerJobParam{repairJobParam},
+	})
+
+	// 保密工单访问日志清理任务 - 每天凌晨2点执行
+	s.AddJob(&ScheduledJob{
+		ID:          "ticket_access_log_purge",
+		Name:        "保密工单访问日志清理",
+		Description: "按访问审计策略的保留天数删除过期的保密工单访问记录",
+		CronExpr:    "0 0 2 * * *", // 每天2点
+		Handler:     s.ticketAccessLogPurgeHandler,
+		IsActive:    true,
+		Timeout:     5 * time.Minute,
+		Params:      []models.SchedulerJobParam{asOfJobParam},
+	})
+
+	// Webhook 日志清理任务 - 每天凌晨3点执行
+	s.AddJob(&ScheduledJob{
+		ID:          "webhook_log_purge",
+		Name:        "Webhook日志清理",
+		Description: "按各Webhook的保留天数（未设置时使用系统默认）分别删除过期的成功和失败日志",
+		CronExpr:    "0 0 3 * * *", // 每天3点
+		Handler:     s.webhookLogPurgeHandler,
+		IsActive:    true,
+		Timeout:     10 * time.Minute,
+		Params:      []models.SchedulerJobParam{asOfJobParam},
+	})
+
+	// 增量同步删除记录清理任务 - 每天凌晨3点30分执行
+	s.AddJob(&ScheduledJob{
+		ID:          "sync_tombstone_purge",
+		Name:        "同步删除记录清理",
+		Description: "删除超过30天的移动端增量同步删除记录，更早的同步游标需全量同步",
+		CronExpr:    "0 30 3 * * *", // 每天3点30分
+		Handler:     s.syncTombstonePurgeHandler,
+		IsActive:    true,
+		Timeout:     5 * time.Minute,
+		Params:      []models.SchedulerJobParam{asOfJobParam},
+	})
+
+	// 维护窗口到期检查任务 - 每5分钟执行一次
+	s.AddJob(&ScheduledJob{
+		ID:          "maintenance_window",
+		Name:        "维护窗口到期检查",
+		Description: "计划维护窗口结束后自动退出只读维护模式",
+		CronExpr:    "0 */5 * * * *", // 每5分钟
+		Handler:     s.maintenanceWindowHandler,
+		IsActive:    true,
+		Timeout:     time.Minute,
+	})
+
+	// 休假委托生效/到期处理任务 - 每5分钟执行一次
+	s.AddJob(&ScheduledJob{
+		ID:          "assignment_delegations",
+		Name:        "休假委托处理",
+		Description: "到达开始时间的委托生效并转移工单，到期的委托结束并转回工单",
+		CronExpr:    "0 */5 * * * *", // 每5分钟
+		Handler:     s.delegationHandler,
+		IsActive:    true,
+		Timeout:     2 * time.Minute,
+		Params:      []models.SchedulerJobParam{asOfJobParam},
+	})
+
+	// 回复锁过期清理任务 - 每分钟执行一次
+	s.AddJob(&ScheduledJob{
+		ID:          "reply_lock_expiry",
+		Name:        "回复锁过期清理",
+		Description: "清除超时未续期的工单回复锁并通知在线客服",
+		CronExpr:    "0 * * * * *", // 每分钟
+		Handler:     s.replyLockExpiryHandler,
+		IsActive:    true,
+		Timeout:     30 * time.Second,
+		Params:      []models.SchedulerJobParam{asOfJobParam},
+	})
+
+	// 升级作战室同步任务 - 每分钟执行一次
+	s.AddJob(&ScheduledJob{
+		ID:          "war_room_sync",
+		Name:        "升级作战室同步",
+		Description: "将严重工单的重要历史转发到作战室频道，工单解决或关闭后归档频道",
+		CronExpr:    "0 * * * * *", // 每分钟
+		Handler:     s.warRoomSyncHandler,
+		IsActive:    true,
+		Timeout:     time.Minute,
+	})
+
+	// 换班交接任务 - 每分钟执行一次
+	s.AddJob(&ScheduledJob{
+		ID:          "ticket_handoffs",
+		Name:        "换班交接",
+		Description: "到达换班时间的交接计划将未完成工单转交给接收团队，并写入交接摘要",
+		CronExpr:    "0 * * * * *", // 每分钟
+		Handler:     s.ticketHandoffHandler,
+		IsActive:    true,
+		Timeout:     2 * time.Minute,
+		Params:      []models.SchedulerJobParam{asOfJobParam},
+	})
+
+	// 工单提醒任务 - 每分钟执行一次
+	s.AddJob(&ScheduledJob{
+		ID:          "ticket_reminders",
+		Name:        "工单提醒",
+		Description: "发送到期的坐席工单提醒，周期提醒顺延到下一周期，工单完结后结束",
+		CronExpr:    "0 * * * * *", // 每分钟
+		Handler:     s.ticketReminderHandler,
+		IsActive:    true,
+		Timeout:     2 * time.Minute,
+		Params:      []models.SchedulerJobParam{asOfJobParam},
+	})
+
+	// SLA违约前预警任务 - 每5分钟执行一次
+	s.AddJob(&ScheduledJob{
+		ID:          "sla_warnings",
+		Name:        "SLA违约预警",
+		Description: "未完结工单的响应或解决时限达到预警阈值时提醒处理人及所属团队，每个阈值只提醒一次",
+		CronExpr:    "0 */5 * * * *", // 每5分钟
+		Handler:     s.slaWarningHandler,
+		IsActive:    true,
+		Timeout:     5 * time.Minute,
+		Params:      []models.SchedulerJobParam{asOfJobParam},
+	})
+
+	// 已解决工单自动关闭任务 - 每小时执行一次
+	s.AddJob(&ScheduledJob{
+		ID:          "auto_close_resolved",
+		Name:        "已解决工单自动关闭",
+		Description: "向长期无回复的已解决工单发送提醒，并在提醒后仍无回复时自动关闭",
+		CronExpr:    "0 0 * * * *", // 每小时
+		Handler:     s.autoCloseHandler,
+		IsActive:    true,
+		Timeout:     5 * time.Minute,
+	})
+
+	// 停滞工单提醒任务 - 每小时执行一次
+	s.AddJob(&ScheduledJob{
+		ID:          "stale_ticket_nudge",
+		Name:        "停滞工单提醒",
+		Description: "提醒处理人跟进长期未更新的工单，宽限期后仍无更新则升级至团队负责人",
+		CronExpr:    "0 0 * * * *", // 每小时
+		Handler:     s.staleTicketHandler,
+		IsActive:    true,
+		Timeout:     5 * time.Minute,
+	})
+
+	// 账户注销到期处理任务 - 每小时执行一次
+	s.AddJob(&ScheduledJob{
+		ID:          "account_deletion_finalize",
+		Name:        "账户注销处理",
+		Description: "宽限期结束的注销申请执行个人信息匿名化，工单内容保留并显示为 Deleted user",
+		CronExpr:    "0 0 * * * *", // 每小时
+		Handler:     s.accountDeletionHandler,
+		IsActive:    true,
+		Timeout:     5 * time.Minute,
+		Params:      []models.SchedulerJobParam{asOfJobParam},
+	})
+
+	// 孤立上传清理任务 - 每小时执行一次
+	s.AddJob(&ScheduledJob{
+		ID:          "orphan_upload_purge",
+		Name:        "孤立上传清理",
+		Description: "删除预签名上传后过期未随评论提交的文件及未传完的分片",
+		CronExpr:    "0 20 * * * *", // 每小时第20分钟
+		Handler:     s.orphanUploadPurgeHandler,
+		IsActive:    true,
+		Timeout:     5 * time.Minute,
+		Params:      []models.SchedulerJobParam{asOfJobParam},
+	})
+
+	// 邮件通道监控任务 - 每分钟执行一次
+	s.AddJob(&ScheduledJob{
+		ID:          "email_channel_monitor",
+		Name:        "邮件通道监控",
+		Description: "重发因 SMTP 不可用而排队的邮件，通道持续不可用时通知管理员，恢复后再通知一次",
+		CronExpr:    "30 * * * * *", // 每分钟第30秒
+		Handler:     s.emailChannelMonitorHandler,
+		IsActive:    true,
+		Timeout:     2 * time.Minute,
+	})
+
+	// 下载中心清理任务 - 每小时执行一次
+	s.AddJob(&ScheduledJob{
+		ID:          "download_cleanup",
+		Name:        "下载文件清理",
+		Description: "删除过期的导出文件及任务，下载记录标记为已过期，过期超过30天的下载记录一并删除",
+		CronExpr:    "0 40 * * * *", // 每小时第40分钟
+		Handler:     s.downloadCleanupHandler,
+		IsActive:    true,
+		Timeout:     5 * time.Minute,
+		Params:      []models.SchedulerJobParam{asOfJobParam},
+	})
+
 	// 统计数据更新任务 - 每小时执行一次
 	s.AddJob(&ScheduledJob{
 		ID:          "update_statistics",
@@ -114,6 +371,44 @@ func (s *SchedulerService) registerDefaultJobs() {
 	})
 }
 
+// SetAccountDeletionService 使用外部配置的账户注销服务（例如已设置头像存储）
+func (s *SchedulerService) SetAccountDeletionService(deletionService *AccountDeletionService) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deletionService = deletionService
+}
+
+// SetUploadService 设置预签名上传服务（使用与上传接口相同的文件存储），未设置时跳过孤立上传清理
+func (s *SchedulerService) SetUploadService(uploadService *UploadService) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.uploadService = uploadService
+}
+
+// SetEmailNotificationService 设置邮件通知服务，未设置时跳过邮件通道监控
+func (s *SchedulerService) SetEmailNotificationService(emailService EmailNotificationServiceInterface) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.emailService = emailService
+}
+
+// SetDownloadCenter 设置下载中心（使用与导出服务相同的导出目录），未设置时跳过下载文件清理
+func (s *SchedulerService) SetDownloadCenter(downloadCenter *DownloadCenterService) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.downloadCenter = downloadCenter
+}
+
+// SetHistoryWriter 设置异步历史写入器，自动关闭、账龄提醒及作战室的系统历史异步批量写入，须在 Start 前调用
+func (s *SchedulerService) SetHistoryWriter(writer *HistoryWriter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.autoCloseService.SetHistoryWriter(writer)
+	s.agingService.SetHistoryWriter(writer)
+	s.warRoomService.SetHistoryWriter(writer)
+	s.automationService.SetHistoryWriter(writer)
+}
+
 // AddJob 添加任务
 func (s *SchedulerService) AddJob(job *ScheduledJob) error {
 	s.mu.Lock()
@@ -128,12 +423,15 @@ func (s *SchedulerService) AddJob(job *ScheduledJob) error {
 	}
 
 	// 计算下次执行时间
-	nextRun, err := s.calculateNextRun(job.CronExpr)
+	nextRun, err := s.calculateNextRun(job.CronExpr, time.Now())
 	if err != nil {
 		return fmt.Errorf("invalid cron expression: %w", err)
 	}
 
 	job.NextRun = nextRun
+	job.defaultCronExpr = job.CronExpr
+	job.defaultActive = job.IsActive
+	job.defaultTimeout = job.Timeout
 	s.jobs[job.ID] = job
 
 	log.Printf("Added scheduled job: %s (%s)", job.Name, job.ID)
@@ -172,6 +470,8 @@ func (s *SchedulerService) Start() {
 	for {
 		select {
 		case <-ticker.C:
+			// 其他实例通过管理接口修改的调度在下次检查时生效
+			s.refreshOverrides(context.Background())
 			s.checkAndRunJobs()
 		case <-s.stopChan:
 			log.Println("Scheduler service stopped")
@@ -193,29 +493,33 @@ func (s *SchedulerService) Stop() {
 	close(s.stopChan)
 }
 
-// checkAndRunJobs 检查并执行到期的任务
+// checkAndRunJobs 检查并执行到期的任务，上次执行尚未结束的任务跳过本次
 func (s *SchedulerService) checkAndRunJobs() {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
 	now := time.Now()
 
 	for _, job := range s.jobs {
-		if !job.IsActive {
+		if !job.IsActive || job.running {
 			continue
 		}
 
 		if now.After(job.NextRun) {
-			go s.executeJob(job)
+			job.running = true
+			go s.executeJob(job, nil, job.Timeout)
 		}
 	}
 }
 
-// executeJob 执行任务
-func (s *SchedulerService) executeJob(job *ScheduledJob) {
+// executeJob 执行任务，params 为立即执行时的参数覆盖；调用方须已将任务标记为执行中
+func (s *SchedulerService) executeJob(job *ScheduledJob, params jobParams, timeout time.Duration) {
 	startTime := time.Now()
-	ctx, cancel := context.WithTimeout(context.Background(), job.Timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
+	if len(params) > 0 {
+		ctx = context.WithValue(ctx, jobParamsKey{}, params)
+	}
 
 	log.Printf("Executing job: %s (%s)", job.Name, job.ID)
 
@@ -230,18 +534,22 @@ func (s *SchedulerService) executeJob(job *ScheduledJob) {
 
 	// 更新任务统计
 	s.mu.Lock()
+	job.running = false
 	job.LastRun = startTime
+	job.lastDuration = duration
+	job.lastError = ""
 	job.RunCount++
 
 	if err != nil {
 		job.ErrorCount++
+		job.lastError = err.Error()
 		log.Printf("Job %s failed: %v", job.ID, err)
 	} else {
 		log.Printf("Job %s completed successfully in %v", job.ID, duration)
 	}
 
 	// 计算下次执行时间
-	nextRun, calcErr := s.calculateNextRun(job.CronExpr)
+	nextRun, calcErr := s.calculateNextRun(job.CronExpr, endTime)
 	if calcErr != nil {
 		log.Printf("Failed to calculate next run for job %s: %v", job.ID, calcErr)
 	} else {
@@ -266,29 +574,13 @@ func (s *SchedulerService) executeJob(job *ScheduledJob) {
 	s.logJobResult(result)
 }
 
-// calculateNextRun 计算下次执行时间（简化的cron实现）
-func (s *SchedulerService) calculateNextRun(cronExpr string) (time.Time, error) {
-	// 简化实现，支持基本的cron表达式格式
-	// "0 */15 * * * *" -> 每15分钟
-	// "0 0 2 * * *"    -> 每天2点
-	// "0 0 * * * *"    -> 每小时
-
-	now := time.Now()
-
-	switch cronExpr {
-	case "0 */15 * * * *": // 每15分钟
-		return now.Add(15 * time.Minute), nil
-	case "0 */5 * * * *": // 每5分钟
-		return now.Add(5 * time.Minute), nil
-	case "0 0 2 * * *": // 每天2点
-		tomorrow := now.AddDate(0, 0, 1)
-		return time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 2, 0, 0, 0, tomorrow.Location()), nil
-	case "0 0 * * * *": // 每小时
-		return now.Add(1 * time.Hour), nil
-	default:
-		// 默认每30分钟执行一次
-		return now.Add(30 * time.Minute), nil
+// calculateNextRun 按 cron 表达式计算 from 之后的下次执行时间
+func (s *SchedulerService) calculateNextRun(cronExpr string, from time.Time) (time.Time, error) {
+	schedule, err := parseSchedulerCron(cronExpr)
+	if err != nil {
+		return time.Time{}, err
 	}
+	return schedule.Next(from), nil
 }
 
 // logJobResult 记录任务执行结果
@@ -370,6 +662,159 @@ func (s *SchedulerService) automationRulesHandler(ctx context.Context) error {
 	return nil
 }
 
+// autoCloseHandler 自动关闭处理器
+func (s *SchedulerService) autoCloseHandler(ctx context.Context) error {
+	_, err := s.autoCloseService.ProcessStaleResolvedTickets(ctx)
+	return err
+}
+
+// staleTicketHandler 停滞工单提醒处理器
+func (s *SchedulerService) staleTicketHandler(ctx context.Context) error {
+	_, err := s.agingService.ProcessStaleTickets(ctx)
+	return err
+}
+
+// notificationArchiveHandler 通知归档处理器
+func (s *SchedulerService) notificationArchiveHandler(ctx context.Context) error {
+	return s.cleanupService.ExecuteCleanup(ctx, "notifications", "scheduled", nil)
+}
+
+// maintenanceWindowHandler 维护窗口到期处理器
+func (s *SchedulerService) maintenanceWindowHandler(ctx context.Context) error {
+	_, err := s.maintenanceService.ExpireWindow(ctx)
+	return err
+}
+
+// delegationHandler 休假委托处理器
+func (s *SchedulerService) delegationHandler(ctx context.Context) error {
+	_, err := s.delegationService.ProcessDue(ctx, jobTime(ctx))
+	return err
+}
+
+// ticketHandoffHandler 换班交接处理器
+func (s *SchedulerService) ticketHandoffHandler(ctx context.Context) error {
+	_, err := s.handoffService.ProcessDue(ctx, jobTime(ctx))
+	return err
+}
+
+// ticketReminderHandler 工单提醒处理器
+func (s *SchedulerService) ticketReminderHandler(ctx context.Context) error {
+	_, err := s.reminderService.ProcessDue(ctx, jobTime(ctx))
+	return err
+}
+
+// slaWarningHandler SLA违约预警处理器
+func (s *SchedulerService) slaWarningHandler(ctx context.Context) error {
+	_, err := s.slaWarningService.ProcessDue(ctx, jobTime(ctx))
+	return err
+}
+
+// replyLockExpiryHandler 回复锁过期处理器
+func (s *SchedulerService) replyLockExpiryHandler(ctx context.Context) error {
+	_, err := s.draftService.ExpireLocks(ctx, jobTime(ctx))
+	return err
+}
+
+// consistencyCheckHandler 数据一致性检查处理器，定时执行只报告不修复，立即执行时可指定 repair
+func (s *SchedulerService) consistencyCheckHandler(ctx context.Context) error {
+	report, err := s.consistencyService.Verify(ctx, jobParamsFrom(ctx).bool("repair", false))
+	if report != nil {
+		LogConsistencyReport(report)
+	}
+	return err
+}
+
+// ticketAccessLogPurgeHandler 保密工单访问日志清理处理器
+func (s *SchedulerService) ticketAccessLogPurgeHandler(ctx context.Context) error {
+	deleted, err := s.accessAuditService.PurgeExpired(ctx, jobTime(ctx))
+	if deleted > 0 {
+		log.Printf("Purged %d expired ticket access logs", deleted)
+	}
+	return err
+}
+
+// webhookLogPurgeHandler Webhook日志清理处理器
+func (s *SchedulerService) webhookLogPurgeHandler(ctx context.Context) error {
+	deleted, err := s.webhookLogService.PurgeExpired(ctx, jobTime(ctx))
+	if deleted > 0 {
+		log.Printf("Purged %d expired webhook logs", deleted)
+	}
+	return err
+}
+
+// syncTombstonePurgeHandler 同步删除记录清理处理器
+func (s *SchedulerService) syncTombstonePurgeHandler(ctx context.Context) error {
+	deleted, err := s.syncService.PurgeTombstones(ctx, jobTime(ctx))
+	if deleted > 0 {
+		log.Printf("Purged %d expired sync tombstones", deleted)
+	}
+	return err
+}
+
+// warRoomSyncHandler 升级作战室同步处理器
+func (s *SchedulerService) warRoomSyncHandler(ctx context.Context) error {
+	mirrored, archived, err := s.warRoomService.SyncActive(ctx)
+	if mirrored > 0 || archived > 0 {
+		log.Printf("War rooms: mirrored %d history entries, archived %d rooms", mirrored, archived)
+	}
+	return err
+}
+
+// accountDeletionHandler 账户注销到期处理器
+func (s *SchedulerService) accountDeletionHandler(ctx context.Context) error {
+	s.mu.RLock()
+	deletionService := s.deletionService
+	s.mu.RUnlock()
+
+	finalized, err := deletionService.FinalizeDue(ctx, jobTime(ctx))
+	if finalized > 0 {
+		log.Printf("Finalized %d account deletions", finalized)
+	}
+	return err
+}
+
+// orphanUploadPurgeHandler 孤立上传清理处理器
+func (s *SchedulerService) orphanUploadPurgeHandler(ctx context.Context) error {
+	s.mu.RLock()
+	uploadService := s.uploadService
+	s.mu.RUnlock()
+	if uploadService == nil {
+		return nil
+	}
+
+	purged, err := uploadService.PurgeOrphans(ctx, jobTime(ctx))
+	if purged > 0 {
+		log.Printf("Purged %d orphaned uploads", purged)
+	}
+	return err
+}
+
+// emailChannelMonitorHandler 邮件通道监控处理器
+func (s *SchedulerService) downloadCleanupHandler(ctx context.Context) error {
+	s.mu.RLock()
+	downloadCenter := s.downloadCenter
+	s.mu.RUnlock()
+	if downloadCenter == nil {
+		return nil
+	}
+
+	purged, err := downloadCenter.PurgeExpired(ctx, jobTime(ctx))
+	if purged > 0 {
+		log.Printf("Purged %d expired download files", purged)
+	}
+	return err
+}
+
+func (s *SchedulerService) emailChannelMonitorHandler(ctx context.Context) error {
+	s.mu.RLock()
+	emailService := s.emailService
+	s.mu.RUnlock()
+	if emailService == nil {
+		return nil
+	}
+	return emailService.MonitorChannel(ctx)
+}
+
 // cleanupHandler 清理处理器
 func (s *SchedulerService) cleanupHandler(ctx context.Context) error {
 	now := time.Now()
@@ -0,0 +1,1262 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"gongdan-system/internal/models"
+)
+
+// 脚本钩子使用的沙箱脚本语言：只能读取传入的变量、调用内置函数和当前挂载点允许的动作，
+// 没有文件、网络、进程或反射能力；执行步数、时间和累计分配的内存均有上限。
+//
+//	let tags = ticket.tags
+//	if ticket.priority == "low" && contains(lower(ticket.title), "outage") {
+//	    set_priority("urgent")
+//	    add_note("标题包含 outage，已自动提升优先级")
+//	}
+//	for change in changes { log(change.field, change.before, "->", change.after) }
+
+var (
+	errScriptStepLimit   = errors.New("script exceeded step limit")
+	errScriptMemoryLimit = errors.New("script exceeded memory limit")
+	errScriptTimeout     = errors.New("script exceeded time limit")
+)
+
+const (
+	scriptMaxSteps       = 100000 // 单次执行的最大求值步数
+	scriptMaxDepth       = 64     // 表达式和语句块的最大嵌套层数
+	scriptMaxOutputLines = 100
+	scriptMaxOutputLine  = 500
+	scriptMaxPattern     = 1000
+)
+
+// scriptError 带行号的语法或运行错误
+type scriptError struct {
+	line int
+	msg  string
+}
+
+func (e *scriptError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.line, e.msg)
+}
+
+func scriptErrorf(line int, format string, args ...interface{}) error {
+	return &scriptError{line: line, msg: fmt.Sprintf(format, args...)}
+}
+
+// ---- 词法分析 ----
+
+type scriptTokenKind int
+
+const (
+	scriptTokEOF scriptTokenKind = iota
+	scriptTokIdent
+	scriptTokNumber
+	scriptTokString
+	scriptTokOp
+)
+
+type scriptToken struct {
+	kind     scriptTokenKind
+	text     string
+	num      float64
+	line     int
+	nlBefore bool // 前面有换行，换行后的 ( 和 [ 不作为调用或下标
+}
+
+var scriptKeywords = map[string]bool{
+	"let": true, "if": true, "else": true, "for": true, "in": true, "return": true,
+	"true": true, "false": true, "nil": true,
+}
+
+func lexScript(src string) ([]scriptToken, error) {
+	var tokens []scriptToken
+	line, nl := 1, false
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			line++
+			nl = true
+			i++
+			continue
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+			continue
+		case c == '#' || (c == '/' && i+1 < len(src) && src[i+1] == '/'):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			continue
+		}
+
+		tok := scriptToken{line: line, nlBefore: nl}
+		nl = false
+		switch {
+		case c == '_' || (c|0x20 >= 'a' && c|0x20 <= 'z'):
+			start := i
+			for i < len(src) && (src[i] == '_' || (src[i]|0x20 >= 'a' && src[i]|0x20 <= 'z') || (src[i] >= '0' && src[i] <= '9')) {
+				i++
+			}
+			tok.kind, tok.text = scriptTokIdent, src[start:i]
+		case c >= '0' && c <= '9':
+			start := i
+			for i < len(src) && ((src[i] >= '0' && src[i] <= '9') || src[i] == '.') {
+				i++
+			}
+			n, err := strconv.ParseFloat(src[start:i], 64)
+			if err != nil {
+				return nil, scriptErrorf(line, "invalid number %q", src[start:i])
+			}
+			tok.kind, tok.text, tok.num = scriptTokNumber, src[start:i], n
+		case c == '"' || c == '\'':
+			var sb strings.Builder
+			i++
+			for {
+				if i >= len(src) || src[i] == '\n' {
+					return nil, scriptErrorf(line, "unterminated string")
+				}
+				if src[i] == c {
+					i++
+					break
+				}
+				if src[i] == '\\' && i+1 < len(src) {
+					switch src[i+1] {
+					case 'n':
+						sb.WriteByte('\n')
+					case 't':
+						sb.WriteByte('\t')
+					case '\\', '"', '\'':
+						sb.WriteByte(src[i+1])
+					default:
+						return nil, scriptErrorf(line, "invalid escape \\%c", src[i+1])
+					}
+					i += 2
+					continue
+				}
+				sb.WriteByte(src[i])
+				i++
+			}
+			tok.kind, tok.text = scriptTokString, sb.String()
+		default:
+			tok.kind = scriptTokOp
+			if i+1 < len(src) {
+				switch two := src[i : i+2]; two {
+				case "==", "!=", "<=", ">=", "&&", "||":
+					tok.text = two
+				}
+			}
+			if tok.text == "" {
+				if !strings.ContainsRune("()[]{},.;=<>+-*/%!", rune(c)) {
+					return nil, scriptErrorf(line, "unexpected character %q", c)
+				}
+				tok.text = string(c)
+			}
+			i += len(tok.text)
+		}
+		tokens = append(tokens, tok)
+	}
+	return append(tokens, scriptToken{kind: scriptTokEOF, line: line}), nil
+}
+
+// ---- 语法分析 ----
+
+type scriptNodeKind int
+
+const (
+	scriptLiteral scriptNodeKind = iota
+	scriptIdent
+	scriptList
+	scriptUnary
+	scriptBinary
+	scriptAnd
+	scriptOr
+	scriptMember
+	scriptIndex
+	scriptCall
+	scriptLet
+	scriptAssign
+	scriptIf
+	scriptFor
+	scriptReturn
+	scriptExprStmt
+)
+
+type scriptNode struct {
+	kind        scriptNodeKind
+	line        int
+	name        string // 变量名、字段名、函数名或运算符
+	value       interface{}
+	left, right *scriptNode
+	items       []*scriptNode // 列表元素或调用参数
+	body, alt   []*scriptNode
+}
+
+type scriptParser struct {
+	tokens []scriptToken
+	pos    int
+	depth  int
+}
+
+// parseScript 解析脚本，保存钩子时用于校验语法
+func parseScript(src string) ([]*scriptNode, error) {
+	tokens, err := lexScript(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &scriptParser{tokens: tokens}
+	return p.statements(false)
+}
+
+func (p *scriptParser) peek() scriptToken { return p.tokens[p.pos] }
+
+func (p *scriptParser) next() scriptToken {
+	t := p.tokens[p.pos]
+	if t.kind != scriptTokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *scriptParser) isOp(text string) bool {
+	t := p.peek()
+	return t.kind == scriptTokOp && t.text == text
+}
+
+func (p *scriptParser) isKeyword(word string) bool {
+	t := p.peek()
+	return t.kind == scriptTokIdent && t.text == word
+}
+
+func (p *scriptParser) expectOp(text string) error {
+	if !p.isOp(text) {
+		return p.unexpected(fmt.Sprintf("'%s'", text))
+	}
+	p.next()
+	return nil
+}
+
+func (p *scriptParser) unexpected(want string) error {
+	t := p.peek()
+	switch t.kind {
+	case scriptTokEOF:
+		return scriptErrorf(t.line, "expected %s, got end of script", want)
+	case scriptTokString:
+		return scriptErrorf(t.line, "expected %s, got string", want)
+	}
+	return scriptErrorf(t.line, "expected %s, got %q", want, t.text)
+}
+
+func (p *scriptParser) enter() error {
+	p.depth++
+	if p.depth > scriptMaxDepth {
+		return scriptErrorf(p.peek().line, "nesting too deep")
+	}
+	return nil
+}
+
+func (p *scriptParser) statements(inBlock bool) ([]*scriptNode, error) {
+	var stmts []*scriptNode
+	for {
+		for p.isOp(";") {
+			p.next()
+		}
+		if p.peek().kind == scriptTokEOF {
+			if inBlock {
+				return nil, p.unexpected("'}'")
+			}
+			return stmts, nil
+		}
+		if inBlock && p.isOp("}") {
+			return stmts, nil
+		}
+		stmt, err := p.statement()
+		if err != nil {
+			return nil, err
+		}
+		stmts = append(stmts, stmt)
+	}
+}
+
+func (p *scriptParser) block() ([]*scriptNode, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer func() { p.depth-- }()
+	if err := p.expectOp("{"); err != nil {
+		return nil, err
+	}
+	stmts, err := p.statements(true)
+	if err != nil {
+		return nil, err
+	}
+	return stmts, p.expectOp("}")
+}
+
+func (p *scriptParser) variableName() (scriptToken, error) {
+	t := p.peek()
+	if t.kind != scriptTokIdent || scriptKeywords[t.text] {
+		return t, p.unexpected("variable name")
+	}
+	return p.next(), nil
+}
+
+func (p *scriptParser) statement() (*scriptNode, error) {
+	t := p.peek()
+	switch {
+	case p.isKeyword("let"):
+		p.next()
+		name, err := p.variableName()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectOp("="); err != nil {
+			return nil, err
+		}
+		value, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		return &scriptNode{kind: scriptLet, line: t.line, name: name.text, left: value}, nil
+	case p.isKeyword("if"):
+		return p.ifStatement()
+	case p.isKeyword("for"):
+		p.next()
+		name, err := p.variableName()
+		if err != nil {
+			return nil, err
+		}
+		if !p.isKeyword("in") {
+			return nil, p.unexpected("'in'")
+		}
+		p.next()
+		iter, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		body, err := p.block()
+		if err != nil {
+			return nil, err
+		}
+		return &scriptNode{kind: scriptFor, line: t.line, name: name.text, left: iter, body: body}, nil
+	case p.isKeyword("return"):
+		p.next()
+		return &scriptNode{kind: scriptReturn, line: t.line}, nil
+	case t.kind == scriptTokIdent && !scriptKeywords[t.text] &&
+		p.tokens[p.pos+1].kind == scriptTokOp && p.tokens[p.pos+1].text == "=":
+		p.pos += 2
+		value, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		return &scriptNode{kind: scriptAssign, line: t.line, name: t.text, left: value}, nil
+	}
+
+	expr, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	return &scriptNode{kind: scriptExprStmt, line: t.line, left: expr}, nil
+}
+
+func (p *scriptParser) ifStatement() (*scriptNode, error) {
+	line := p.next().line
+	cond, err := p.expression()
+	if err != nil {
+		return nil, err
+	}
+	body, err := p.block()
+	if err != nil {
+		return nil, err
+	}
+	node := &scriptNode{kind: scriptIf, line: line, left: cond, body: body}
+	if p.isKeyword("else") {
+		p.next()
+		if p.isKeyword("if") {
+			elseIf, err := p.ifStatement()
+			if err != nil {
+				return nil, err
+			}
+			node.alt = []*scriptNode{elseIf}
+		} else if node.alt, err = p.block(); err != nil {
+			return nil, err
+		}
+	}
+	return node, nil
+}
+
+func (p *scriptParser) expression() (*scriptNode, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer func() { p.depth-- }()
+	return p.binary(0)
+}
+
+// scriptPrecedence 二元运算符按优先级从低到高排列
+var scriptPrecedence = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!="},
+	{"<", "<=", ">", ">=", "in"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+func (p *scriptParser) binaryOp(level int) (string, bool) {
+	t := p.peek()
+	if t.kind != scriptTokOp && !(t.kind == scriptTokIdent && t.text == "in") {
+		return "", false
+	}
+	for _, op := range scriptPrecedence[level] {
+		if t.text == op {
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *scriptParser) binary(level int) (*scriptNode, error) {
+	if level == len(scriptPrecedence) {
+		return p.unary()
+	}
+	left, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.binaryOp(level)
+		if !ok {
+			return left, nil
+		}
+		line := p.next().line
+		right, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		kind := scriptBinary
+		switch op {
+		case "&&":
+			kind = scriptAnd
+		case "||":
+			kind = scriptOr
+		}
+		left = &scriptNode{kind: kind, line: line, name: op, left: left, right: right}
+	}
+}
+
+func (p *scriptParser) unary() (*scriptNode, error) {
+	if p.isOp("!") || p.isOp("-") {
+		if err := p.enter(); err != nil {
+			return nil, err
+		}
+		defer func() { p.depth-- }()
+		t := p.next()
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &scriptNode{kind: scriptUnary, line: t.line, name: t.text, left: operand}, nil
+	}
+	return p.postfix()
+}
+
+func (p *scriptParser) postfix() (*scriptNode, error) {
+	node, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		t := p.peek()
+		switch {
+		case p.isOp("(") && !t.nlBefore:
+			if node.kind != scriptIdent {
+				return nil, scriptErrorf(t.line, "only functions can be called")
+			}
+			p.next()
+			args, err := p.expressionList(")")
+			if err != nil {
+				return nil, err
+			}
+			node = &scriptNode{kind: scriptCall, line: node.line, name: node.name, items: args}
+		case p.isOp("[") && !t.nlBefore:
+			p.next()
+			index, err := p.expression()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectOp("]"); err != nil {
+				return nil, err
+			}
+			node = &scriptNode{kind: scriptIndex, line: t.line, left: node, right: index}
+		case p.isOp("."):
+			p.next()
+			field := p.next()
+			if field.kind != scriptTokIdent {
+				return nil, scriptErrorf(field.line, "expected field name after '.'")
+			}
+			node = &scriptNode{kind: scriptMember, line: t.line, name: field.text, left: node}
+		default:
+			return node, nil
+		}
+	}
+}
+
+func (p *scriptParser) expressionList(closing string) ([]*scriptNode, error) {
+	var items []*scriptNode
+	for !p.isOp(closing) {
+		item, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		if !p.isOp(",") {
+			break
+		}
+		p.next()
+	}
+	return items, p.expectOp(closing)
+}
+
+func (p *scriptParser) primary() (*scriptNode, error) {
+	t := p.peek()
+	switch t.kind {
+	case scriptTokNumber:
+		p.next()
+		return &scriptNode{kind: scriptLiteral, line: t.line, value: t.num}, nil
+	case scriptTokString:
+		p.next()
+		return &scriptNode{kind: scriptLiteral, line: t.line, value: t.text}, nil
+	case scriptTokIdent:
+		switch t.text {
+		case "true", "false":
+			p.next()
+			return &scriptNode{kind: scriptLiteral, line: t.line, value: t.text == "true"}, nil
+		case "nil":
+			p.next()
+			return &scriptNode{kind: scriptLiteral, line: t.line}, nil
+		}
+		if scriptKeywords[t.text] {
+			return nil, p.unexpected("expression")
+		}
+		p.next()
+		return &scriptNode{kind: scriptIdent, line: t.line, name: t.text}, nil
+	}
+	switch {
+	case p.isOp("("):
+		p.next()
+		expr, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		return expr, p.expectOp(")")
+	case p.isOp("["):
+		p.next()
+		items, err := p.expressionList("]")
+		if err != nil {
+			return nil, err
+		}
+		return &scriptNode{kind: scriptList, line: t.line, items: items}, nil
+	}
+	return nil, p.unexpected("expression")
+}
+
+// ---- 执行 ----
+
+// scriptLimits 单次执行的资源上限
+type scriptLimits struct {
+	maxSteps    int
+	memoryBytes int
+}
+
+// scriptActionFunc 校验并规范化动作参数，返回要记录的参数
+type scriptActionFunc func(args []interface{}) ([]interface{}, error)
+
+type scriptBuiltin func(in *scriptInterp, line int, args []interface{}) (interface{}, error)
+
+// scriptResult 执行结果；Err 为空表示成功
+type scriptResult struct {
+	Actions []models.ScriptHookAction
+	Output  []string
+	Steps   int
+	Err     error
+}
+
+type scriptInterp struct {
+	ctx       context.Context
+	limits    scriptLimits
+	steps     int
+	memory    int
+	scopes    []map[string]interface{}
+	actions   map[string]scriptActionFunc
+	regexps   map[string]*regexp.Regexp
+	result    *scriptResult
+	returning bool
+}
+
+// runScript 执行已解析的脚本。vars 经 JSON 规范化后作为只读输入（数字均为 float64），
+// actions 为当前挂载点允许调用的动作；超时由 ctx 控制
+func runScript(ctx context.Context, program []*scriptNode, vars map[string]interface{}, actions map[string]scriptActionFunc, limits scriptLimits) (result *scriptResult) {
+	result = &scriptResult{Actions: []models.ScriptHookAction{}, Output: []string{}}
+	globals := map[string]interface{}{}
+	if len(vars) > 0 {
+		data, err := json.Marshal(vars)
+		if err != nil {
+			result.Err = fmt.Errorf("invalid script input: %w", err)
+			return result
+		}
+		if err := json.Unmarshal(data, &globals); err != nil {
+			result.Err = fmt.Errorf("invalid script input: %w", err)
+			return result
+		}
+	}
+	if limits.maxSteps <= 0 {
+		limits.maxSteps = scriptMaxSteps
+	}
+
+	in := &scriptInterp{
+		ctx:     ctx,
+		limits:  limits,
+		scopes:  []map[string]interface{}{globals, {}},
+		actions: actions,
+		regexps: map[string]*regexp.Regexp{},
+		result:  result,
+	}
+	defer func() {
+		// 解释器内部错误不影响调用方
+		if r := recover(); r != nil {
+			result.Err = fmt.Errorf("script panicked: %v", r)
+		}
+		result.Steps = in.steps
+	}()
+	result.Err = in.execBlock(program)
+	return result
+}
+
+func (in *scriptInterp) step() error {
+	in.steps++
+	if in.steps > in.limits.maxSteps {
+		return errScriptStepLimit
+	}
+	if in.steps%64 == 0 && in.ctx.Err() != nil {
+		return errScriptTimeout
+	}
+	return nil
+}
+
+// alloc 累计脚本分配的内存，超出上限时终止执行
+func (in *scriptInterp) alloc(bytes int) error {
+	in.memory += bytes
+	if in.limits.memoryBytes > 0 && in.memory > in.limits.memoryBytes {
+		return errScriptMemoryLimit
+	}
+	return nil
+}
+
+func (in *scriptInterp) lookup(name string) (interface{}, bool) {
+	for i := len(in.scopes) - 1; i >= 0; i-- {
+		if v, ok := in.scopes[i][name]; ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+func (in *scriptInterp) execBlock(stmts []*scriptNode) error {
+	in.scopes = append(in.scopes, map[string]interface{}{})
+	defer func() { in.scopes = in.scopes[:len(in.scopes)-1] }()
+	for _, stmt := range stmts {
+		if err := in.exec(stmt); err != nil {
+			return err
+		}
+		if in.returning {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (in *scriptInterp) exec(node *scriptNode) error {
+	if err := in.step(); err != nil {
+		return err
+	}
+	switch node.kind {
+	case scriptLet:
+		value, err := in.eval(node.left)
+		if err != nil {
+			return err
+		}
+		in.scopes[len(in.scopes)-1][node.name] = value
+	case scriptAssign:
+		value, err := in.eval(node.left)
+		if err != nil {
+			return err
+		}
+		// 输入变量（最外层作用域）只读
+		for i := len(in.scopes) - 1; i >= 1; i-- {
+			if _, ok := in.scopes[i][node.name]; ok {
+				in.scopes[i][node.name] = value
+				return nil
+			}
+		}
+		if _, ok := in.scopes[0][node.name]; ok {
+			return scriptErrorf(node.line, "cannot assign to input variable %s", node.name)
+		}
+		return scriptErrorf(node.line, "undefined variable %s (use let to declare)", node.name)
+	case scriptIf:
+		cond, err := in.eval(node.left)
+		if err != nil {
+			return err
+		}
+		if scriptTruthy(cond) {
+			return in.execBlock(node.body)
+		}
+		if node.alt != nil {
+			return in.execBlock(node.alt)
+		}
+	case scriptFor:
+		iter, err := in.eval(node.left)
+		if err != nil {
+			return err
+		}
+		var items []interface{}
+		switch v := iter.(type) {
+		case []interface{}:
+			items = v
+		case map[string]interface{}:
+			for key := range v {
+				items = append(items, key)
+			}
+			sort.Slice(items, func(i, j int) bool { return items[i].(string) < items[j].(string) })
+		case nil:
+		default:
+			return scriptErrorf(node.line, "cannot iterate over %s", scriptTypeName(iter))
+		}
+		for _, item := range items {
+			if err := in.step(); err != nil {
+				return err
+			}
+			in.scopes = append(in.scopes, map[string]interface{}{node.name: item})
+			err := in.execBlock(node.body)
+			in.scopes = in.scopes[:len(in.scopes)-1]
+			if err != nil {
+				return err
+			}
+			if in.returning {
+				return nil
+			}
+		}
+	case scriptReturn:
+		in.returning = true
+	case scriptExprStmt:
+		_, err := in.eval(node.left)
+		return err
+	}
+	return nil
+}
+
+func (in *scriptInterp) eval(node *scriptNode) (interface{}, error) {
+	if err := in.step(); err != nil {
+		return nil, err
+	}
+	switch node.kind {
+	case scriptLiteral:
+		return node.value, nil
+	case scriptIdent:
+		value, ok := in.lookup(node.name)
+		if !ok {
+			return nil, scriptErrorf(node.line, "undefined variable %s", node.name)
+		}
+		return value, nil
+	case scriptList:
+		if err := in.alloc(16*len(node.items) + 24); err != nil {
+			return nil, err
+		}
+		list := make([]interface{}, 0, len(node.items))
+		for _, item := range node.items {
+			value, err := in.eval(item)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		return list, nil
+	case scriptUnary:
+		value, err := in.eval(node.left)
+		if err != nil {
+			return nil, err
+		}
+		if node.name == "!" {
+			return !scriptTruthy(value), nil
+		}
+		n, ok := value.(float64)
+		if !ok {
+			return nil, scriptErrorf(node.line, "cannot negate %s", scriptTypeName(value))
+		}
+		return -n, nil
+	case scriptAnd, scriptOr:
+		left, err := in.eval(node.left)
+		if err != nil {
+			return nil, err
+		}
+		if scriptTruthy(left) == (node.kind == scriptOr) {
+			return scriptTruthy(left), nil
+		}
+		right, err := in.eval(node.right)
+		if err != nil {
+			return nil, err
+		}
+		return scriptTruthy(right), nil
+	case scriptBinary:
+		left, err := in.eval(node.left)
+		if err != nil {
+			return nil, err
+		}
+		right, err := in.eval(node.right)
+		if err != nil {
+			return nil, err
+		}
+		return in.binary(node, left, right)
+	case scriptMember:
+		target, err := in.eval(node.left)
+		if err != nil {
+			return nil, err
+		}
+		switch v := target.(type) {
+		case map[string]interface{}:
+			return v[node.name], nil
+		case nil:
+			return nil, nil
+		}
+		return nil, scriptErrorf(node.line, "cannot read field %s of %s", node.name, scriptTypeName(target))
+	case scriptIndex:
+		target, err := in.eval(node.left)
+		if err != nil {
+			return nil, err
+		}
+		index, err := in.eval(node.right)
+		if err != nil {
+			return nil, err
+		}
+		switch v := target.(type) {
+		case []interface{}:
+			n, ok := index.(float64)
+			if !ok || n != math.Trunc(n) {
+				return nil, scriptErrorf(node.line, "list index must be an integer")
+			}
+			i := int(n)
+			if i < 0 {
+				i += len(v)
+			}
+			if i < 0 || i >= len(v) {
+				return nil, scriptErrorf(node.line, "list index %d out of range", int(n))
+			}
+			return v[i], nil
+		case map[string]interface{}:
+			key, ok := index.(string)
+			if !ok {
+				return nil, scriptErrorf(node.line, "map key must be a string")
+			}
+			return v[key], nil
+		case nil:
+			return nil, nil
+		}
+		return nil, scriptErrorf(node.line, "cannot index %s", scriptTypeName(target))
+	case scriptCall:
+		args := make([]interface{}, len(node.items))
+		for i, item := range node.items {
+			value, err := in.eval(item)
+			if err != nil {
+				return nil, err
+			}
+			args[i] = value
+		}
+		if action, ok := in.actions[node.name]; ok {
+			normalized, err := action(args)
+			if err != nil {
+				return nil, scriptErrorf(node.line, "%s: %v", node.name, err)
+			}
+			in.result.Actions = append(in.result.Actions, models.ScriptHookAction{Name: node.name, Args: normalized})
+			return nil, nil
+		}
+		builtin, ok := scriptBuiltins[node.name]
+		if !ok {
+			return nil, scriptErrorf(node.line, "unknown function %s", node.name)
+		}
+		value, err := builtin(in, node.line, args)
+		if err != nil {
+			var se *scriptError
+			if errors.As(err, &se) || errors.Is(err, errScriptMemoryLimit) {
+				return nil, err
+			}
+			return nil, scriptErrorf(node.line, "%s: %v", node.name, err)
+		}
+		return value, nil
+	}
+	return nil, scriptErrorf(node.line, "invalid expression")
+}
+
+func (in *scriptInterp) binary(node *scriptNode, left, right interface{}) (interface{}, error) {
+	switch node.name {
+	case "==":
+		return scriptEqual(left, right), nil
+	case "!=":
+		return !scriptEqual(left, right), nil
+	case "in":
+		return scriptContains(right, left), nil
+	case "<", "<=", ">", ">=":
+		var cmp int
+		ln, lok := left.(float64)
+		rn, rok := right.(float64)
+		ls, lsok := left.(string)
+		rs, rsok := right.(string)
+		switch {
+		case lok && rok:
+			cmp = compareScriptNumbers(ln, rn)
+		case lsok && rsok:
+			cmp = strings.Compare(ls, rs)
+		default:
+			return nil, scriptErrorf(node.line, "cannot compare %s and %s", scriptTypeName(left), scriptTypeName(right))
+		}
+		switch node.name {
+		case "<":
+			return cmp < 0, nil
+		case "<=":
+			return cmp <= 0, nil
+		case ">":
+			return cmp > 0, nil
+		}
+		return cmp >= 0, nil
+	case "+":
+		if ll, ok := left.([]interface{}); ok {
+			if rl, ok := right.([]interface{}); ok {
+				if err := in.alloc(16*(len(ll)+len(rl)) + 24); err != nil {
+					return nil, err
+				}
+				return append(append(make([]interface{}, 0, len(ll)+len(rl)), ll...), rl...), nil
+			}
+		}
+		_, lsok := left.(string)
+		_, rsok := right.(string)
+		if lsok || rsok {
+			s := scriptString(left) + scriptString(right)
+			if err := in.alloc(len(s)); err != nil {
+				return nil, err
+			}
+			return s, nil
+		}
+	}
+
+	ln, lok := left.(float64)
+	rn, rok := right.(float64)
+	if !lok || !rok {
+		return nil, scriptErrorf(node.line, "invalid operands for %s: %s and %s", node.name, scriptTypeName(left), scriptTypeName(right))
+	}
+	switch node.name {
+	case "+":
+		return ln + rn, nil
+	case "-":
+		return ln - rn, nil
+	case "*":
+		return ln * rn, nil
+	case "/", "%":
+		if rn == 0 {
+			return nil, scriptErrorf(node.line, "division by zero")
+		}
+		if node.name == "/" {
+			return ln / rn, nil
+		}
+		return math.Mod(ln, rn), nil
+	}
+	return nil, scriptErrorf(node.line, "unknown operator %s", node.name)
+}
+
+func compareScriptNumbers(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func scriptTruthy(v interface{}) bool {
+	switch x := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return x
+	case float64:
+		return x != 0
+	case string:
+		return x != ""
+	case []interface{}:
+		return len(x) > 0
+	case map[string]interface{}:
+		return len(x) > 0
+	}
+	return true
+}
+
+func scriptTypeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "nil"
+	case bool:
+		return "bool"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "map"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// scriptString 转为字符串：整数不带小数点，列表和映射输出 JSON
+func scriptString(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case bool:
+		return strconv.FormatBool(x)
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+func scriptEqual(a, b interface{}) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+func scriptContains(container, item interface{}) bool {
+	switch c := container.(type) {
+	case string:
+		return strings.Contains(c, scriptString(item))
+	case []interface{}:
+		for _, v := range c {
+			if scriptEqual(v, item) {
+				return true
+			}
+		}
+	case map[string]interface{}:
+		key, ok := item.(string)
+		if ok {
+			_, found := c[key]
+			return found
+		}
+	}
+	return false
+}
+
+// ---- 内置函数 ----
+
+func scriptArgs(args []interface{}, min, max int) error {
+	if len(args) < min || (max >= 0 && len(args) > max) {
+		if min == max {
+			return fmt.Errorf("expected %d argument(s), got %d", min, len(args))
+		}
+		return fmt.Errorf("expected %d-%d arguments, got %d", min, max, len(args))
+	}
+	return nil
+}
+
+func scriptStringArg(args []interface{}, i int) (string, error) {
+	s, ok := args[i].(string)
+	if !ok {
+		return "", fmt.Errorf("argument %d must be a string, got %s", i+1, scriptTypeName(args[i]))
+	}
+	return s, nil
+}
+
+// scriptStringFunc 包装单个字符串参数的内置函数
+func scriptStringFunc(fn func(string) string) scriptBuiltin {
+	return func(in *scriptInterp, line int, args []interface{}) (interface{}, error) {
+		if err := scriptArgs(args, 1, 1); err != nil {
+			return nil, err
+		}
+		s, err := scriptStringArg(args, 0)
+		if err != nil {
+			return nil, err
+		}
+		result := fn(s)
+		return result, in.alloc(len(result))
+	}
+}
+
+// scriptStringPredicate 包装两个字符串参数、返回布尔值的内置函数
+func scriptStringPredicate(fn func(s, substr string) bool) scriptBuiltin {
+	return func(in *scriptInterp, line int, args []interface{}) (interface{}, error) {
+		if err := scriptArgs(args, 2, 2); err != nil {
+			return nil, err
+		}
+		s, err := scriptStringArg(args, 0)
+		if err != nil {
+			return nil, err
+		}
+		substr, err := scriptStringArg(args, 1)
+		if err != nil {
+			return nil, err
+		}
+		return fn(s, substr), nil
+	}
+}
+
+var scriptBuiltins map[string]scriptBuiltin
+
+func init() {
+	scriptBuiltins = map[string]scriptBuiltin{
+		"len": func(in *scriptInterp, line int, args []interface{}) (interface{}, error) {
+			if err := scriptArgs(args, 1, 1); err != nil {
+				return nil, err
+			}
+			switch v := args[0].(type) {
+			case string:
+				return float64(utf8.RuneCountInString(v)), nil
+			case []interface{}:
+				return float64(len(v)), nil
+			case map[string]interface{}:
+				return float64(len(v)), nil
+			case nil:
+				return float64(0), nil
+			}
+			return nil, fmt.Errorf("cannot get length of %s", scriptTypeName(args[0]))
+		},
+		"lower":       scriptStringFunc(strings.ToLower),
+		"upper":       scriptStringFunc(strings.ToUpper),
+		"trim":        scriptStringFunc(strings.TrimSpace),
+		"starts_with": scriptStringPredicate(strings.HasPrefix),
+		"ends_with":   scriptStringPredicate(strings.HasSuffix),
+		"contains": func(in *scriptInterp, line int, args []interface{}) (interface{}, error) {
+			if err := scriptArgs(args, 2, 2); err != nil {
+				return nil, err
+			}
+			return scriptContains(args[0], args[1]), nil
+		},
+		"matches": func(in *scriptInterp, line int, args []interface{}) (interface{}, error) {
+			if err := scriptArgs(args, 2, 2); err != nil {
+				return nil, err
+			}
+			s, err := scriptStringArg(args, 0)
+			if err != nil {
+				return nil, err
+			}
+			pattern, err := scriptStringArg(args, 1)
+			if err != nil {
+				return nil, err
+			}
+			re, ok := in.regexps[pattern]
+			if !ok {
+				if len(pattern) > scriptMaxPattern {
+					return nil, fmt.Errorf("pattern too long")
+				}
+				if re, err = regexp.Compile(pattern); err != nil {
+					return nil, fmt.Errorf("invalid pattern: %v", err)
+				}
+				if err := in.alloc(len(pattern) * 64); err != nil {
+					return nil, err
+				}
+				in.regexps[pattern] = re
+			}
+			return re.MatchString(s), nil
+		},
+		"str": func(in *scriptInterp, line int, args []interface{}) (interface{}, error) {
+			if err := scriptArgs(args, 1, 1); err != nil {
+				return nil, err
+			}
+			s := scriptString(args[0])
+			return s, in.alloc(len(s))
+		},
+		"num": func(in *scriptInterp, line int, args []interface{}) (interface{}, error) {
+			if err := scriptArgs(args, 1, 1); err != nil {
+				return nil, err
+			}
+			switch v := args[0].(type) {
+			case float64:
+				return v, nil
+			case bool:
+				if v {
+					return float64(1), nil
+				}
+				return float64(0), nil
+			case string:
+				n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+				if err != nil {
+					return nil, fmt.Errorf("cannot convert %q to number", v)
+				}
+				return n, nil
+			}
+			return nil, fmt.Errorf("cannot convert %s to number", scriptTypeName(args[0]))
+		},
+		"join": func(in *scriptInterp, line int, args []interface{}) (interface{}, error) {
+			if err := scriptArgs(args, 2, 2); err != nil {
+				return nil, err
+			}
+			list, ok := args[0].([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("argument 1 must be a list, got %s", scriptTypeName(args[0]))
+			}
+			sep, err := scriptStringArg(args, 1)
+			if err != nil {
+				return nil, err
+			}
+			parts := make([]string, len(list))
+			for i, item := range list {
+				parts[i] = scriptString(item)
+			}
+			s := strings.Join(parts, sep)
+			return s, in.alloc(len(s))
+		},
+		"split": func(in *scriptInterp, line int, args []interface{}) (interface{}, error) {
+			if err := scriptArgs(args, 2, 2); err != nil {
+				return nil, err
+			}
+			s, err := scriptStringArg(args, 0)
+			if err != nil {
+				return nil, err
+			}
+			sep, err := scriptStringArg(args, 1)
+			if err != nil {
+				return nil, err
+			}
+			parts := strings.Split(s, sep)
+			if err := in.alloc(len(s) + 16*len(parts) + 24); err != nil {
+				return nil, err
+			}
+			list := make([]interface{}, len(parts))
+			for i, part := range parts {
+				list[i] = part
+			}
+			return list, nil
+		},
+		"log": func(in *scriptInterp, line int, args []interface{}) (interface{}, error) {
+			if len(in.result.Output) >= scriptMaxOutputLines {
+				return nil, nil
+			}
+			parts := make([]string, len(args))
+			for i, arg := range args {
+				parts[i] = scriptString(arg)
+			}
+			in.result.Output = append(in.result.Output, truncateString(strings.Join(parts, " "), scriptMaxOutputLine))
+			return nil, nil
+		},
+	}
+}
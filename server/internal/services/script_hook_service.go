@@ -0,0 +1,593 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	scriptHookDefaultTimeoutMillis    = 200
+	scriptHookDefaultMemoryLimitKB    = 1024
+	scriptHookDefaultFailureThreshold = 5
+	scriptHookLogKeep                 = 1000 // 每个钩子保留的最近执行记录数
+	scriptHookMaxTags                 = 20
+)
+
+var (
+	// ErrScriptHookNotFound 脚本钩子不存在
+	ErrScriptHookNotFound = errors.New("script hook not found")
+	// ErrInvalidScriptHook 挂载点不支持或脚本语法错误
+	ErrInvalidScriptHook = errors.New("invalid script hook")
+)
+
+// ScriptHookService 脚本钩子：管理员安装的脚本在工单创建/更新、评论创建和通知发送前执行，
+// 脚本只能通过挂载点允许的动作影响系统，动作在脚本成功结束后统一应用
+type ScriptHookService struct {
+	db            *gorm.DB
+	configService *ConfigService
+	now           func() time.Time
+}
+
+// NewScriptHookService 创建脚本钩子服务
+func NewScriptHookService(db *gorm.DB) *ScriptHookService {
+	return &ScriptHookService{
+		db:            db,
+		configService: NewConfigService(db),
+		now:           time.Now,
+	}
+}
+
+// ---- 管理 ----
+
+// List 获取脚本钩子，event 为空时返回全部
+func (s *ScriptHookService) List(ctx context.Context, event string) ([]*models.ScriptHook, error) {
+	query := s.db.WithContext(ctx).Order("event ASC, position ASC, id ASC")
+	if event != "" {
+		query = query.Where("event = ?", event)
+	}
+	var hooks []*models.ScriptHook
+	if err := query.Find(&hooks).Error; err != nil {
+		return nil, fmt.Errorf("failed to list script hooks: %w", err)
+	}
+	return hooks, nil
+}
+
+// Get 获取脚本钩子
+func (s *ScriptHookService) Get(ctx context.Context, id uint) (*models.ScriptHook, error) {
+	var hook models.ScriptHook
+	if err := s.db.WithContext(ctx).First(&hook, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrScriptHookNotFound
+		}
+		return nil, fmt.Errorf("failed to get script hook: %w", err)
+	}
+	return &hook, nil
+}
+
+// Create 安装脚本钩子，保存前校验挂载点和脚本语法
+func (s *ScriptHookService) Create(ctx context.Context, req *models.ScriptHookRequest, userID uint) (*models.ScriptHook, error) {
+	hook := &models.ScriptHook{Enabled: true, CreatedByID: userID}
+	if err := applyScriptHookRequest(hook, req); err != nil {
+		return nil, err
+	}
+	if err := s.db.WithContext(ctx).Create(hook).Error; err != nil {
+		return nil, fmt.Errorf("failed to create script hook: %w", err)
+	}
+	return hook, nil
+}
+
+// Update 更新脚本钩子；重新启用时清除连续失败计数和自动停用原因
+func (s *ScriptHookService) Update(ctx context.Context, id uint, req *models.ScriptHookRequest, userID uint) (*models.ScriptHook, error) {
+	hook, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	wasEnabled := hook.Enabled
+	if err := applyScriptHookRequest(hook, req); err != nil {
+		return nil, err
+	}
+	if hook.Enabled && !wasEnabled {
+		hook.ConsecutiveFailures = 0
+		hook.DisabledReason = ""
+	}
+	hook.UpdatedByID = &userID
+	if err := s.db.WithContext(ctx).Save(hook).Error; err != nil {
+		return nil, fmt.Errorf("failed to update script hook: %w", err)
+	}
+	return hook, nil
+}
+
+// Delete 卸载脚本钩子及其执行记录
+func (s *ScriptHookService) Delete(ctx context.Context, id uint) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		res := tx.Delete(&models.ScriptHook{}, id)
+		if res.Error != nil {
+			return fmt.Errorf("failed to delete script hook: %w", res.Error)
+		}
+		if res.RowsAffected == 0 {
+			return ErrScriptHookNotFound
+		}
+		return tx.Where("hook_id = ?", id).Delete(&models.ScriptHookLog{}).Error
+	})
+}
+
+func applyScriptHookRequest(hook *models.ScriptHook, req *models.ScriptHookRequest) error {
+	if !req.Event.Valid() {
+		return fmt.Errorf("%w: unsupported event %s", ErrInvalidScriptHook, req.Event)
+	}
+	if _, err := parseScript(req.Script); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidScriptHook, err)
+	}
+	hook.Name = strings.TrimSpace(req.Name)
+	hook.Description = req.Description
+	hook.Event = req.Event
+	hook.Script = req.Script
+	hook.Position = req.Position
+	if req.Enabled != nil {
+		hook.Enabled = *req.Enabled
+	}
+	hook.TimeoutMillis = req.TimeoutMillis
+	if hook.TimeoutMillis == 0 {
+		hook.TimeoutMillis = scriptHookDefaultTimeoutMillis
+	}
+	hook.MemoryLimitKB = req.MemoryLimitKB
+	if hook.MemoryLimitKB == 0 {
+		hook.MemoryLimitKB = scriptHookDefaultMemoryLimitKB
+	}
+	return nil
+}
+
+// ListLogs 获取钩子的执行记录，status 为空时返回全部
+func (s *ScriptHookService) ListLogs(ctx context.Context, hookID uint, status string, page, pageSize int) ([]*models.ScriptHookLog, int64, error) {
+	if _, err := s.Get(ctx, hookID); err != nil {
+		return nil, 0, err
+	}
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	query := s.db.WithContext(ctx).Model(&models.ScriptHookLog{}).Where("hook_id = ?", hookID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count script hook logs: %w", err)
+	}
+	var logs []*models.ScriptHookLog
+	if err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&logs).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list script hook logs: %w", err)
+	}
+	return logs, total, nil
+}
+
+// Test 用示例数据试运行脚本，只返回脚本调用的动作，不应用也不记录
+func (s *ScriptHookService) Test(ctx context.Context, req *models.ScriptHookTestRequest) (*models.ScriptHookTestResult, error) {
+	if !req.Event.Valid() {
+		return nil, fmt.Errorf("%w: unsupported event %s", ErrInvalidScriptHook, req.Event)
+	}
+	hook := &models.ScriptHook{Event: req.Event, Script: req.Script, TimeoutMillis: req.TimeoutMillis, MemoryLimitKB: req.MemoryLimitKB}
+	if hook.TimeoutMillis == 0 {
+		hook.TimeoutMillis = scriptHookDefaultTimeoutMillis
+	}
+	if hook.MemoryLimitKB == 0 {
+		hook.MemoryLimitKB = scriptHookDefaultMemoryLimitKB
+	}
+
+	started := s.now()
+	result := s.execute(ctx, hook, req.Payload)
+	testResult := &models.ScriptHookTestResult{
+		Status:         scriptHookStatus(result.Err),
+		Actions:        result.Actions,
+		Output:         result.Output,
+		Steps:          result.Steps,
+		DurationMicros: s.now().Sub(started).Microseconds(),
+	}
+	if result.Err != nil {
+		testResult.Error = result.Err.Error()
+	}
+	return testResult, nil
+}
+
+// ---- 执行 ----
+
+// enabledHooks 获取挂载点上启用的钩子；全局开关关闭时返回空
+func (s *ScriptHookService) enabledHooks(ctx context.Context, event models.ScriptHookEvent) []models.ScriptHook {
+	if enabled, err := s.configService.GetConfigBool(KeyScriptHooksEnabled); err == nil && !enabled {
+		return nil
+	}
+	var hooks []models.ScriptHook
+	if err := s.db.WithContext(ctx).Where("event = ? AND enabled = ?", event, true).
+		Order("position ASC, id ASC").Find(&hooks).Error; err != nil {
+		log.Printf("Failed to load script hooks for %s: %v", event, err)
+		return nil
+	}
+	return hooks
+}
+
+// execute 在超时和内存限制下执行单个钩子
+func (s *ScriptHookService) execute(ctx context.Context, hook *models.ScriptHook, vars map[string]interface{}) *scriptResult {
+	program, err := parseScript(hook.Script)
+	if err != nil {
+		return &scriptResult{Actions: []models.ScriptHookAction{}, Output: []string{}, Err: err}
+	}
+	runCtx, cancel := context.WithTimeout(ctx, time.Duration(hook.TimeoutMillis)*time.Millisecond)
+	defer cancel()
+	return runScript(runCtx, program, vars, scriptHookActions(hook.Event), scriptLimits{memoryBytes: hook.MemoryLimitKB * 1024})
+}
+
+func scriptHookStatus(err error) models.ScriptHookStatus {
+	switch {
+	case err == nil:
+		return models.ScriptHookStatusSuccess
+	case errors.Is(err, errScriptTimeout):
+		return models.ScriptHookStatusTimeout
+	case errors.Is(err, errScriptStepLimit), errors.Is(err, errScriptMemoryLimit):
+		return models.ScriptHookStatusLimitExceeded
+	}
+	return models.ScriptHookStatusError
+}
+
+// record 写入执行记录并更新钩子状态，连续失败达到阈值时自动停用
+func (s *ScriptHookService) record(ctx context.Context, hook *models.ScriptHook, resourceType string, resourceID uint,
+	result *scriptResult, runErr error, duration time.Duration) {
+	status := scriptHookStatus(runErr)
+	actions, _ := json.Marshal(result.Actions)
+	entry := &models.ScriptHookLog{
+		HookID:         hook.ID,
+		Event:          hook.Event,
+		ResourceType:   resourceType,
+		ResourceID:     resourceID,
+		Status:         status,
+		DurationMicros: duration.Microseconds(),
+		Steps:          result.Steps,
+		Actions:        models.JSONText(actions),
+		Output:         strings.Join(result.Output, "\n"),
+	}
+	if runErr != nil {
+		entry.Actions = "[]"
+		entry.Error = truncateString(runErr.Error(), 1000)
+	}
+	db := s.db.WithContext(context.WithoutCancel(ctx))
+	if err := db.Create(entry).Error; err != nil {
+		log.Printf("Failed to record script hook %d run: %v", hook.ID, err)
+	}
+
+	now := s.now()
+	updates := map[string]interface{}{"last_run_at": now, "last_status": status, "consecutive_failures": 0}
+	if runErr != nil {
+		failures := hook.ConsecutiveFailures + 1
+		updates["consecutive_failures"] = failures
+		threshold, err := s.configService.GetConfigInt(KeyScriptHookFailureThreshold)
+		if err != nil {
+			threshold = scriptHookDefaultFailureThreshold
+		}
+		if threshold > 0 && failures >= threshold {
+			updates["enabled"] = false
+			updates["disabled_reason"] = fmt.Sprintf("连续 %d 次执行失败，已自动停用", failures)
+			log.Printf("Script hook %d disabled after %d consecutive failures", hook.ID, failures)
+		}
+	}
+	if err := db.Model(&models.ScriptHook{}).Where("id = ?", hook.ID).UpdateColumns(updates).Error; err != nil {
+		log.Printf("Failed to update script hook %d status: %v", hook.ID, err)
+	}
+
+	if entry.ID > 0 && entry.ID%100 == 0 {
+		var ids []uint
+		db.Model(&models.ScriptHookLog{}).Where("hook_id = ?", hook.ID).
+			Order("id DESC").Offset(scriptHookLogKeep).Limit(1).Pluck("id", &ids)
+		if len(ids) > 0 {
+			db.Where("hook_id = ? AND id <= ?", hook.ID, ids[0]).Delete(&models.ScriptHookLog{})
+		}
+	}
+}
+
+// RunTicketHooks 工单创建/更新后执行钩子，返回是否修改了工单。
+// 动作直接写入数据库，不会再次触发钩子；钩子失败不影响工单操作
+func (s *ScriptHookService) RunTicketHooks(ctx context.Context, event models.ScriptHookEvent, ticket *models.Ticket, changes []models.TicketFieldChange) bool {
+	vars := map[string]interface{}{"event": string(event)}
+	if event == models.ScriptHookTicketUpdated {
+		if changes == nil {
+			changes = []models.TicketFieldChange{}
+		}
+		vars["changes"] = changes
+	}
+	return s.runTicketScoped(ctx, event, ticket, vars, "ticket", ticket.ID)
+}
+
+// RunCommentHooks 评论创建后执行钩子，动作作用于评论所属工单
+func (s *ScriptHookService) RunCommentHooks(ctx context.Context, ticket *models.Ticket, comment *models.TicketComment, byCustomer bool) bool {
+	vars := map[string]interface{}{
+		"event": string(models.ScriptHookCommentCreated),
+		"comment": map[string]interface{}{
+			"id":          comment.ID,
+			"content":     comment.Content,
+			"type":        comment.Type,
+			"visibility":  comment.Visibility,
+			"user_id":     comment.UserID,
+			"by_customer": byCustomer,
+		},
+	}
+	return s.runTicketScoped(ctx, models.ScriptHookCommentCreated, ticket, vars, "comment", comment.ID)
+}
+
+func (s *ScriptHookService) runTicketScoped(ctx context.Context, event models.ScriptHookEvent, ticket *models.Ticket,
+	vars map[string]interface{}, resourceType string, resourceID uint) bool {
+	hooks := s.enabledHooks(ctx, event)
+	if len(hooks) == 0 {
+		return false
+	}
+
+	// 后续钩子看到前面钩子修改后的工单
+	current := *ticket
+	changed := false
+	for i := range hooks {
+		hook := &hooks[i]
+		vars["ticket"] = scriptTicketVars(&current)
+		started := s.now()
+		result := s.execute(ctx, hook, vars)
+		runErr := result.Err
+		if runErr == nil && len(result.Actions) > 0 {
+			applied, err := s.applyTicketActions(ctx, hook, &current, result.Actions)
+			if err != nil {
+				runErr = fmt.Errorf("failed to apply actions: %w", err)
+			}
+			changed = changed || applied
+		}
+		s.record(ctx, hook, resourceType, resourceID, result, runErr, s.now().Sub(started))
+	}
+	return changed
+}
+
+// scriptTicketVars 脚本可读取的工单字段
+func scriptTicketVars(ticket *models.Ticket) map[string]interface{} {
+	tags := []string{}
+	if ticket.Tags != "" {
+		json.Unmarshal([]byte(ticket.Tags), &tags)
+	}
+	var customFields map[string]interface{}
+	if ticket.CustomFields != "" {
+		json.Unmarshal([]byte(ticket.CustomFields), &customFields)
+	}
+	return map[string]interface{}{
+		"id":               ticket.ID,
+		"number":           ticket.TicketNumber,
+		"title":            ticket.Title,
+		"description":      ticket.Description,
+		"status":           ticket.Status,
+		"priority":         ticket.Priority,
+		"type":             ticket.Type,
+		"source":           ticket.Source,
+		"tags":             tags,
+		"custom_fields":    customFields,
+		"category_id":      ticket.CategoryID,
+		"assigned_to_id":   ticket.AssignedToID,
+		"assigned_team_id": ticket.AssignedTeamID,
+		"created_by_id":    ticket.CreatedByID,
+		"customer_email":   ticket.CustomerEmail,
+		"customer_name":    ticket.CustomerName,
+		"is_confidential":  ticket.IsConfidential,
+		"created_at":       ticket.CreatedAt,
+	}
+}
+
+// applyTicketActions 在一个事务中应用工单动作并记录自动化历史
+func (s *ScriptHookService) applyTicketActions(ctx context.Context, hook *models.ScriptHook, ticket *models.Ticket, actions []models.ScriptHookAction) (bool, error) {
+	priority := ticket.Priority
+	tags := []string{}
+	if ticket.Tags != "" {
+		json.Unmarshal([]byte(ticket.Tags), &tags)
+	}
+	originalTags := strings.Join(tags, ",")
+	var notes []string
+	for _, action := range actions {
+		arg := action.Args[0].(string)
+		switch action.Name {
+		case "set_priority":
+			priority = models.TicketPriority(arg)
+		case "add_tag":
+			if !containsString(tags, arg) {
+				tags = append(tags, arg)
+			}
+		case "remove_tag":
+			kept := tags[:0:0]
+			for _, tag := range tags {
+				if tag != arg {
+					kept = append(kept, tag)
+				}
+			}
+			tags = kept
+		case "add_note":
+			notes = append(notes, arg)
+		}
+	}
+	if len(tags) > scriptHookMaxTags {
+		return false, fmt.Errorf("ticket cannot have more than %d tags", scriptHookMaxTags)
+	}
+
+	updates := map[string]interface{}{}
+	var histories []*models.TicketHistory
+	automated := func(action models.HistoryAction, field, oldValue, newValue string) *models.TicketHistory {
+		return &models.TicketHistory{
+			TicketID:    ticket.ID,
+			Action:      action,
+			Description: fmt.Sprintf("脚本钩子「%s」将%s从「%s」变更为「%s」", hook.Name, scriptHookFieldLabel(field), oldValue, newValue),
+			FieldName:   field,
+			OldValue:    oldValue,
+			NewValue:    newValue,
+			IsVisible:   true,
+			IsSystem:    true,
+			IsAutomated: true,
+		}
+	}
+	if priority != ticket.Priority {
+		updates["priority"] = priority
+		histories = append(histories, automated(models.HistoryActionPriorityChange, "priority", string(ticket.Priority), string(priority)))
+	}
+	if newTags := strings.Join(tags, ","); newTags != originalTags {
+		data, _ := json.Marshal(tags)
+		updates["tags"] = models.JSONText(data)
+		histories = append(histories, automated(models.HistoryActionUpdate, "tags", originalTags, newTags))
+	}
+	if len(updates) == 0 && len(notes) == 0 {
+		return false, nil
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(updates) > 0 {
+			updates["updated_at"] = s.now()
+			if err := tx.Model(&models.Ticket{}).Where("id = ?", ticket.ID).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		for _, history := range histories {
+			if err := tx.Create(history).Error; err != nil {
+				return err
+			}
+		}
+		for _, note := range notes {
+			comment := &models.TicketComment{
+				TicketID: ticket.ID,
+				UserID:   1, // 系统用户
+				Content:  note,
+				Type:     models.CommentTypeSystem,
+			}
+			if err := tx.Create(comment).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	ticket.Priority = priority
+	if tagsJSON, ok := updates["tags"]; ok {
+		ticket.Tags = tagsJSON.(models.JSONText)
+	}
+	return true, nil
+}
+
+func scriptHookFieldLabel(field string) string {
+	if field == "priority" {
+		return "优先级"
+	}
+	return "标签"
+}
+
+// DispatchNotification 通知保存和发送前执行钩子，可修改标题、内容、优先级；返回 true 表示跳过该通知
+func (s *ScriptHookService) DispatchNotification(ctx context.Context, notification *models.Notification) bool {
+	hooks := s.enabledHooks(ctx, models.ScriptHookNotificationDispatch)
+	for i := range hooks {
+		hook := &hooks[i]
+		vars := map[string]interface{}{
+			"event": string(models.ScriptHookNotificationDispatch),
+			"notification": map[string]interface{}{
+				"type":              notification.Type,
+				"title":             notification.Title,
+				"content":           notification.Content,
+				"priority":          notification.Priority,
+				"channel":           notification.Channel,
+				"recipient_id":      notification.RecipientID,
+				"sender_id":         notification.SenderID,
+				"related_type":      notification.RelatedType,
+				"related_ticket_id": notification.RelatedTicketID,
+			},
+		}
+		started := s.now()
+		result := s.execute(ctx, hook, vars)
+		skip := false
+		if result.Err == nil {
+			for _, action := range result.Actions {
+				switch action.Name {
+				case "skip":
+					skip = true
+				case "set_title":
+					notification.Title = action.Args[0].(string)
+				case "set_content":
+					notification.Content = action.Args[0].(string)
+				case "set_priority":
+					notification.Priority = models.NotificationPriority(action.Args[0].(string))
+				}
+			}
+		}
+		s.record(ctx, hook, "notification", notification.RecipientID, result, result.Err, s.now().Sub(started))
+		if skip {
+			return true
+		}
+	}
+	return false
+}
+
+// ---- 挂载点动作 ----
+
+// scriptHookActions 挂载点允许调用的动作
+func scriptHookActions(event models.ScriptHookEvent) map[string]scriptActionFunc {
+	if event == models.ScriptHookNotificationDispatch {
+		return map[string]scriptActionFunc{
+			"skip": func(args []interface{}) ([]interface{}, error) {
+				if err := scriptArgs(args, 0, 1); err != nil {
+					return nil, err
+				}
+				if len(args) == 1 {
+					return []interface{}{scriptString(args[0])}, nil
+				}
+				return []interface{}{}, nil
+			},
+			"set_title":   scriptStringAction(1, 255),
+			"set_content": scriptStringAction(1, 10000),
+			"set_priority": scriptStringAction(1, 20, string(models.NotificationPriorityLow), string(models.NotificationPriorityNormal),
+				string(models.NotificationPriorityHigh), string(models.NotificationPriorityUrgent)),
+		}
+	}
+	return map[string]scriptActionFunc{
+		"set_priority": scriptStringAction(1, 20, string(models.TicketPriorityLow), string(models.TicketPriorityNormal),
+			string(models.TicketPriorityHigh), string(models.TicketPriorityUrgent), string(models.TicketPriorityCritical)),
+		"add_tag":    scriptStringAction(1, 50),
+		"remove_tag": scriptStringAction(1, 50),
+		"add_note":   scriptStringAction(1, 5000),
+	}
+}
+
+// scriptStringAction 单个字符串参数的动作，限制长度（按字符计）并可限定取值
+func scriptStringAction(min, max int, allowed ...string) scriptActionFunc {
+	return func(args []interface{}) ([]interface{}, error) {
+		if err := scriptArgs(args, 1, 1); err != nil {
+			return nil, err
+		}
+		value, err := scriptStringArg(args, 0)
+		if err != nil {
+			return nil, err
+		}
+		value = strings.TrimSpace(value)
+		if n := utf8.RuneCountInString(value); n < min || n > max {
+			return nil, fmt.Errorf("length must be between %d and %d", min, max)
+		}
+		if len(allowed) > 0 && !containsString(allowed, value) {
+			return nil, fmt.Errorf("must be one of %s", strings.Join(allowed, ", "))
+		}
+		return []interface{}{value}, nil
+	}
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestScriptHooks_TicketActionsLimitsAndNotificationDispatch(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:script_hook_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Ticket{}, &models.TicketComment{}, &models.TicketHistory{}, &models.SystemConfig{},
+		&models.ScriptHook{}, &models.ScriptHookLog{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+
+	ctx := context.Background()
+	svc := NewScriptHookService(db)
+	admin := models.User{Username: "sh-admin", Email: "sh-admin@example.com", PasswordHash: "x", Role: models.RoleAdmin, Status: models.UserStatusActive}
+	db.Create(&admin)
+
+	// 保存时校验语法，错误信息带行号
+	_, err = svc.Create(ctx, &models.ScriptHookRequest{Name: "broken", Event: models.ScriptHookTicketCreated, Script: "let x = 1\nif x {"}, admin.ID)
+	if !errors.Is(err, ErrInvalidScriptHook) || !strings.Contains(err.Error(), "line 2") {
+		t.Fatalf("expected syntax error with line number, got %v", err)
+	}
+	if _, err := svc.Create(ctx, &models.ScriptHookRequest{Name: "bad event", Event: "ticket.deleted", Script: "log(1)"}, admin.ID); !errors.Is(err, ErrInvalidScriptHook) {
+		t.Fatalf("expected unsupported event to be rejected, got %v", err)
+	}
+
+	escalate, err := svc.Create(ctx, &models.ScriptHookRequest{Name: "outage", Event: models.ScriptHookTicketCreated, Script: `
+		# 标题包含 outage 的工单提升为紧急
+		if contains(lower(ticket.title), "outage") && ticket.priority != "urgent" {
+			set_priority("urgent")
+			add_tag("outage")
+			add_note("已自动提升为紧急：" + ticket.number)
+		}
+		log("checked", ticket.id, len(ticket.tags))
+	`}, admin.ID)
+	if err != nil {
+		t.Fatalf("create hook failed: %v", err)
+	}
+	// 后执行的钩子看到前一个钩子修改后的工单
+	if _, err := svc.Create(ctx, &models.ScriptHookRequest{Name: "follow", Event: models.ScriptHookTicketCreated, Position: 1,
+		Script: `if "outage" in ticket.tags { remove_tag("triage") }`}, admin.ID); err != nil {
+		t.Fatalf("create hook failed: %v", err)
+	}
+
+	ticket := models.Ticket{TicketNumber: "SH-1", Title: "Database OUTAGE", Status: models.TicketStatusOpen, Priority: models.TicketPriorityNormal,
+		Type: models.TicketTypeIncident, Source: models.TicketSourceWeb, CreatedByID: admin.ID, Tags: `["triage"]`}
+	db.Create(&ticket)
+	if !svc.RunTicketHooks(ctx, models.ScriptHookTicketCreated, &ticket, nil) {
+		t.Fatalf("expected hooks to modify the ticket")
+	}
+	var stored models.Ticket
+	db.First(&stored, ticket.ID)
+	if stored.Priority != models.TicketPriorityUrgent || stored.Tags != `["outage"]` {
+		t.Fatalf("unexpected ticket after hooks: priority=%s tags=%s", stored.Priority, stored.Tags)
+	}
+	var note models.TicketComment
+	if err := db.Where("ticket_id = ? AND type = ?", ticket.ID, models.CommentTypeSystem).First(&note).Error; err != nil || note.Content != "已自动提升为紧急：SH-1" {
+		t.Fatalf("expected system note, got %+v %v", note, err)
+	}
+	var histories int64
+	db.Model(&models.TicketHistory{}).Where("ticket_id = ? AND is_automated = ?", ticket.ID, true).Count(&histories)
+	if histories != 3 {
+		t.Fatalf("expected priority and two tag histories, got %d", histories)
+	}
+	logs, total, err := svc.ListLogs(ctx, escalate.ID, "", 1, 20)
+	if err != nil || total != 1 || logs[0].Status != models.ScriptHookStatusSuccess || logs[0].Output != "checked 1 1" ||
+		!strings.Contains(string(logs[0].Actions), "set_priority") {
+		t.Fatalf("unexpected hook logs: %+v %v", logs, err)
+	}
+
+	// 步数与内存上限
+	limits := []struct {
+		script string
+		memory int
+	}{
+		{`let l = [1,1,1,1,1,1,1,1,1,1]
+		  for a in l { for b in l { for c in l { for d in l { for e in l { let x = a } } } } }`, 1024},
+		{`let s = "0123456789"
+		  for i in [1,1,1,1,1,1,1,1,1,1,1,1,1,1,1,1,1,1,1,1] { s = s + s }`, 64},
+	}
+	for _, tc := range limits {
+		result, err := svc.Test(ctx, &models.ScriptHookTestRequest{Event: models.ScriptHookTicketCreated, Script: tc.script, MemoryLimitKB: tc.memory})
+		if err != nil || result.Status != models.ScriptHookStatusLimitExceeded {
+			t.Fatalf("expected limit to be exceeded, got %+v %v", result, err)
+		}
+	}
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	program, _ := parseScript(`let l = [1,1,1,1,1,1,1,1] for a in l { for b in l { let x = a } }`)
+	if result := runScript(cancelled, program, nil, nil, scriptLimits{}); !errors.Is(result.Err, errScriptTimeout) {
+		t.Fatalf("expected timeout, got %v", result.Err)
+	}
+
+	// 试运行不应用动作；输入变量只读
+	result, _ := svc.Test(ctx, &models.ScriptHookTestRequest{Event: models.ScriptHookTicketUpdated,
+		Script:  `for c in changes { if c.field == "status" && c.after == "resolved" { add_tag("done") } }`,
+		Payload: map[string]interface{}{"changes": []interface{}{map[string]interface{}{"field": "status", "before": "open", "after": "resolved"}}}})
+	if result.Status != models.ScriptHookStatusSuccess || len(result.Actions) != 1 || result.Actions[0].Args[0] != "done" {
+		t.Fatalf("unexpected dry run result: %+v", result)
+	}
+	if result, _ := svc.Test(ctx, &models.ScriptHookTestRequest{Event: models.ScriptHookTicketCreated, Script: `ticket = nil`,
+		Payload: map[string]interface{}{"ticket": map[string]interface{}{}}}); result.Status != models.ScriptHookStatusError ||
+		!strings.Contains(result.Error, "input variable") {
+		t.Fatalf("expected assignment to input to fail, got %+v", result)
+	}
+
+	// 连续失败达到阈值后自动停用
+	svc.configService.SetConfig(KeyScriptHookFailureThreshold, "2", "int", "", CategorySystem, "script_hooks")
+	failing, _ := svc.Create(ctx, &models.ScriptHookRequest{Name: "failing", Event: models.ScriptHookCommentCreated, Script: `set_priority("whenever")`}, admin.ID)
+	comment := models.TicketComment{TicketID: ticket.ID, UserID: admin.ID, Content: "hi", Type: models.CommentTypePublic}
+	db.Create(&comment)
+	for i := 0; i < 3; i++ {
+		svc.RunCommentHooks(ctx, &stored, &comment, false)
+	}
+	failing, _ = svc.Get(ctx, failing.ID)
+	if failing.Enabled || failing.ConsecutiveFailures != 2 || failing.LastStatus != models.ScriptHookStatusError || failing.DisabledReason == "" {
+		t.Fatalf("expected failing hook to be disabled, got %+v", failing)
+	}
+	enabled := true
+	failing, err = svc.Update(ctx, failing.ID, &models.ScriptHookRequest{Name: "failing", Event: models.ScriptHookCommentCreated,
+		Script: `set_priority("high")`, Enabled: &enabled}, admin.ID)
+	if err != nil || !failing.Enabled || failing.ConsecutiveFailures != 0 || failing.DisabledReason != "" {
+		t.Fatalf("expected re-enable to reset failures, got %+v %v", failing, err)
+	}
+
+	// 通知发送前可修改或跳过
+	svc.Create(ctx, &models.ScriptHookRequest{Name: "quiet", Event: models.ScriptHookNotificationDispatch, Script: `
+		if notification.priority == "low" { skip("低优先级不发送") return }
+		set_title("[工单] " + notification.title)
+	`}, admin.ID)
+	low := &models.Notification{Title: "digest", Content: "x", Priority: models.NotificationPriorityLow, RecipientID: admin.ID}
+	if !svc.DispatchNotification(ctx, low) {
+		t.Fatalf("expected low priority notification to be skipped")
+	}
+	normal := &models.Notification{Title: "assigned", Content: "x", Priority: models.NotificationPriorityNormal, RecipientID: admin.ID}
+	if svc.DispatchNotification(ctx, normal) || normal.Title != "[工单] assigned" {
+		t.Fatalf("expected title to be rewritten, got %+v", normal)
+	}
+
+	// 全局开关关闭后不执行
+	svc.configService.SetConfig(KeyScriptHooksEnabled, "false", "bool", "", CategorySystem, "script_hooks")
+	if svc.DispatchNotification(ctx, low) {
+		t.Fatalf("expected hooks to be paused")
+	}
+	if err := svc.Delete(ctx, escalate.ID); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	if _, _, err := svc.ListLogs(ctx, escalate.ID, "", 1, 20); !errors.Is(err, ErrScriptHookNotFound) {
+		t.Fatalf("expected deleted hook to be gone, got %v", err)
+	}
+}
@@ -0,0 +1,433 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+// KeySearchLanguageConfig 全文搜索语言配置键
+const KeySearchLanguageConfig = "search.language_config"
+
+// searchConfigCacheTTL 搜索配置及数据库可用文本搜索配置的缓存时间
+const searchConfigCacheTTL = time.Minute
+
+// 旧版本使用 'english' 配置创建的全文索引，迁移时删除
+var legacySearchIndexes = []string{
+	"idx_tickets_search",
+	"idx_tickets_title_gin",
+	"idx_tickets_description_gin",
+	"idx_ticket_comments_content_gin",
+}
+
+// ResolvedSearchConfigs 按数据库实际可用的文本搜索配置解析后的结果
+type ResolvedSearchConfigs struct {
+	Language string `json:"language"`
+	Chinese  string `json:"chinese"`
+	English  string `json:"english"`
+}
+
+// SearchConfigStatus 全文搜索配置状态
+type SearchConfigStatus struct {
+	Config     *models.SearchLanguageConfig `json:"config"`
+	Resolved   *ResolvedSearchConfigs       `json:"resolved"`
+	Available  []string                     `json:"available_configs"`
+	Extensions []string                     `json:"extensions"`
+	Indexes    []string                     `json:"indexes"`
+}
+
+// SearchConfigService 全文搜索语言配置服务
+type SearchConfigService struct {
+	db *gorm.DB
+
+	mu         sync.RWMutex
+	resolved   *ResolvedSearchConfigs
+	resolvedAt time.Time
+}
+
+// NewSearchConfigService 创建全文搜索语言配置服务
+func NewSearchConfigService(db *gorm.DB) *SearchConfigService {
+	return &SearchConfigService{db: db}
+}
+
+// isPostgres 全文搜索仅在 PostgreSQL 下可用
+func (s *SearchConfigService) isPostgres() bool {
+	return DialectOf(s.db).IsPostgres()
+}
+
+// GetConfig 获取全文搜索语言配置
+func (s *SearchConfigService) GetConfig(ctx context.Context) (*models.SearchLanguageConfig, error) {
+	var config models.SystemConfig
+	err := s.db.WithContext(ctx).
+		Where("key = ? AND is_active = ?", KeySearchLanguageConfig, true).
+		First(&config).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.GetDefaultSearchLanguageConfig(), nil
+		}
+		return nil, fmt.Errorf("failed to get search language config: %w", err)
+	}
+
+	searchConfig := models.GetDefaultSearchLanguageConfig()
+	if err := config.GetJSONValue(searchConfig); err != nil {
+		log.Printf("Warning: failed to parse search language config, using defaults: %v", err)
+		return models.GetDefaultSearchLanguageConfig(), nil
+	}
+
+	return searchConfig, nil
+}
+
+// SetConfig 保存全文搜索语言配置；修改后需执行索引迁移才能使用新的分词配置建立索引
+func (s *SearchConfigService) SetConfig(ctx context.Context, searchConfig *models.SearchLanguageConfig, userID uint) error {
+	defaults := models.GetDefaultSearchLanguageConfig()
+	if searchConfig.Language == "" {
+		searchConfig.Language = defaults.Language
+	}
+	if searchConfig.ChineseConfigs == nil {
+		searchConfig.ChineseConfigs = defaults.ChineseConfigs
+	}
+	if searchConfig.EnglishConfig == "" {
+		searchConfig.EnglishConfig = defaults.EnglishConfig
+	}
+	if searchConfig.FallbackConfig == "" {
+		searchConfig.FallbackConfig = defaults.FallbackConfig
+	}
+	if err := searchConfig.Validate(); err != nil {
+		return err
+	}
+
+	var existing models.SystemConfig
+	err := s.db.WithContext(ctx).Where("key = ?", KeySearchLanguageConfig).First(&existing).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to check existing search language config: %w", err)
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		config := models.SystemConfig{
+			Key:         KeySearchLanguageConfig,
+			Category:    CategorySystem,
+			Group:       "search",
+			Description: "全文搜索语言配置",
+			IsActive:    true,
+			UpdatedBy:   &userID,
+		}
+		if err := config.SetValue(searchConfig); err != nil {
+			return fmt.Errorf("failed to set search language config value: %w", err)
+		}
+		if err := s.db.WithContext(ctx).Create(&config).Error; err != nil {
+			return fmt.Errorf("failed to create search language config: %w", err)
+		}
+	} else {
+		if err := existing.SetValue(searchConfig); err != nil {
+			return fmt.Errorf("failed to set search language config value: %w", err)
+		}
+		existing.UpdatedBy = &userID
+		existing.Version++
+
+		if err := s.db.WithContext(ctx).Save(&existing).Error; err != nil {
+			return fmt.Errorf("failed to update search language config: %w", err)
+		}
+	}
+
+	s.mu.Lock()
+	s.resolved = nil
+	s.mu.Unlock()
+
+	return nil
+}
+
+// AvailableConfigs 获取数据库中已安装的文本搜索配置
+func (s *SearchConfigService) AvailableConfigs(ctx context.Context) ([]string, error) {
+	if !s.isPostgres() {
+		return nil, nil
+	}
+
+	var names []string
+	if err := s.db.WithContext(ctx).Raw("SELECT cfgname FROM pg_ts_config ORDER BY cfgname").Scan(&names).Error; err != nil {
+		return nil, fmt.Errorf("failed to list text search configs: %w", err)
+	}
+	return names, nil
+}
+
+// Resolve 按数据库可用配置解析中英文分词配置；中文候选均不可用时回退到 fallback 配置
+func (s *SearchConfigService) Resolve(ctx context.Context) (*ResolvedSearchConfigs, error) {
+	s.mu.RLock()
+	cached, cachedAt := s.resolved, s.resolvedAt
+	s.mu.RUnlock()
+	if cached != nil && time.Since(cachedAt) < searchConfigCacheTTL {
+		return cached, nil
+	}
+
+	searchConfig, err := s.GetConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	available, err := s.AvailableConfigs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	installed := make(map[string]bool, len(available))
+	for _, name := range available {
+		installed[name] = true
+	}
+
+	resolved := &ResolvedSearchConfigs{
+		Language: searchConfig.Language,
+		Chinese:  searchConfig.FallbackConfig,
+		English:  searchConfig.FallbackConfig,
+	}
+	for _, candidate := range searchConfig.ChineseConfigs {
+		if installed[candidate] {
+			resolved.Chinese = candidate
+			break
+		}
+	}
+	if installed[searchConfig.EnglishConfig] {
+		resolved.English = searchConfig.EnglishConfig
+	}
+
+	s.mu.Lock()
+	s.resolved = resolved
+	s.resolvedAt = time.Now()
+	s.mu.Unlock()
+
+	return resolved, nil
+}
+
+// indexedConfigs 需要建立索引的文本搜索配置
+func (r *ResolvedSearchConfigs) indexedConfigs() []string {
+	switch r.Language {
+	case models.SearchLanguageChinese:
+		return []string{r.Chinese}
+	case models.SearchLanguageEnglish:
+		return []string{r.English}
+	default:
+		return uniqueStrings([]string{r.Chinese, r.English})
+	}
+}
+
+// QueryConfigs 根据配置语言及查询内容选择查询使用的文本搜索配置
+func (r *ResolvedSearchConfigs) QueryConfigs(query string) []string {
+	if r.Language != models.SearchLanguageAuto {
+		return r.indexedConfigs()
+	}
+
+	switch models.DetectTextLanguage(query) {
+	case models.SearchLanguageChinese:
+		return []string{r.Chinese}
+	case models.SearchLanguageEnglish:
+		return []string{r.English}
+	default:
+		return uniqueStrings([]string{r.Chinese, r.English})
+	}
+}
+
+// ticketSearchVector 工单全文检索表达式，需与索引表达式保持一致才能命中索引
+func ticketSearchVector(config string) string {
+	return fmt.Sprintf("to_tsvector('%s', coalesce(title, '') || ' ' || coalesce(description, ''))", config)
+}
+
+// articleSearchVector 知识库文章全文检索表达式
+func articleSearchVector(config string) string {
+	return fmt.Sprintf("to_tsvector('%s', coalesce(title, '') || ' ' || coalesce(content, ''))", config)
+}
+
+// commentSearchVector 评论全文检索表达式
+func commentSearchVector(config string) string {
+	return fmt.Sprintf("to_tsvector('%s', coalesce(content, ''))", config)
+}
+
+// BuildTicketSearchCondition 构建工单搜索条件；非 PostgreSQL 或使用回退配置时附加 ILIKE 子串匹配
+func (s *SearchConfigService) BuildTicketSearchCondition(ctx context.Context, search string) (string, []interface{}) {
+	return s.buildSearchCondition(ctx, search, ticketSearchVector, "title", "description")
+}
+
+// BuildArticleSearchCondition 构建知识库文章搜索条件，规则与工单搜索一致
+func (s *SearchConfigService) BuildArticleSearchCondition(ctx context.Context, search string) (string, []interface{}) {
+	return s.buildSearchCondition(ctx, search, articleSearchVector, "title", "content")
+}
+
+func (s *SearchConfigService) buildSearchCondition(ctx context.Context, search string, vector func(config string) string, columns ...string) (string, []interface{}) {
+	pattern := "%" + search + "%"
+	like := func(op string) (string, []interface{}) {
+		parts := make([]string, 0, len(columns))
+		args := make([]interface{}, 0, len(columns))
+		for _, column := range columns {
+			parts = append(parts, column+" "+op+" ?")
+			args = append(args, pattern)
+		}
+		return strings.Join(parts, " OR "), args
+	}
+
+	if !s.isPostgres() {
+		return like("LIKE")
+	}
+
+	resolved, err := s.Resolve(ctx)
+	if err != nil {
+		log.Printf("Warning: failed to resolve search configs, using ILIKE search: %v", err)
+		return like("ILIKE")
+	}
+	searchConfig, err := s.GetConfig(ctx)
+	if err != nil {
+		return like("ILIKE")
+	}
+
+	var conditions []string
+	var args []interface{}
+	needsLike := false
+	for _, config := range resolved.QueryConfigs(search) {
+		conditions = append(conditions, fmt.Sprintf("%s @@ plainto_tsquery('%s', ?)", vector(config), config))
+		args = append(args, search)
+		// simple 等回退配置不做中文分词，仍需子串匹配保证可搜到
+		if config == searchConfig.FallbackConfig {
+			needsLike = true
+		}
+	}
+	if needsLike {
+		likeCondition, likeArgs := like("ILIKE")
+		conditions = append(conditions, likeCondition)
+		args = append(args, likeArgs...)
+	}
+
+	return strings.Join(conditions, " OR "), args
+}
+
+// IndexStatements 生成全文索引迁移语句：删除旧的 english 索引及不再使用的配置索引，按当前配置创建索引
+func (s *SearchConfigService) IndexStatements(ctx context.Context) ([]string, error) {
+	resolved, err := s.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := s.existingSearchIndexes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[string]bool)
+	var statements []string
+	for _, config := range resolved.indexedConfigs() {
+		ticketIndex := "idx_tickets_fts_" + config
+		commentIndex := "idx_ticket_comments_fts_" + config
+		articleIndex := "idx_kb_articles_fts_" + config
+		wanted[ticketIndex] = true
+		wanted[commentIndex] = true
+		wanted[articleIndex] = true
+		statements = append(statements,
+			fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON tickets USING gin(%s)", ticketIndex, ticketSearchVector(config)),
+			fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON ticket_comments USING gin(%s)", commentIndex, commentSearchVector(config)),
+			fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON kb_articles USING gin(%s)", articleIndex, articleSearchVector(config)),
+		)
+	}
+
+	var drops []string
+	for _, name := range legacySearchIndexes {
+		drops = append(drops, fmt.Sprintf("DROP INDEX IF EXISTS %s", name))
+	}
+	for _, name := range existing {
+		if !wanted[name] {
+			drops = append(drops, fmt.Sprintf("DROP INDEX IF EXISTS %s", name))
+		}
+	}
+
+	return append(drops, statements...), nil
+}
+
+// existingSearchIndexes 获取已存在的按配置命名的全文索引
+func (s *SearchConfigService) existingSearchIndexes(ctx context.Context) ([]string, error) {
+	if !s.isPostgres() {
+		return nil, nil
+	}
+
+	var names []string
+	if err := s.db.WithContext(ctx).
+		Raw("SELECT indexname FROM pg_indexes WHERE indexname LIKE 'idx_tickets_fts_%' OR indexname LIKE 'idx_ticket_comments_fts_%' OR indexname LIKE 'idx_kb_articles_fts_%' ORDER BY indexname").
+		Scan(&names).Error; err != nil {
+		return nil, fmt.Errorf("failed to list search indexes: %w", err)
+	}
+	return names, nil
+}
+
+// MigrateIndexes 按当前配置重建全文索引，返回已执行的语句
+func (s *SearchConfigService) MigrateIndexes(ctx context.Context) ([]string, error) {
+	if !s.isPostgres() {
+		return nil, fmt.Errorf("full-text search indexes require PostgreSQL")
+	}
+
+	s.mu.Lock()
+	s.resolved = nil
+	s.mu.Unlock()
+
+	statements, err := s.IndexStatements(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	executed := make([]string, 0, len(statements))
+	for _, statement := range statements {
+		if err := s.db.WithContext(ctx).Exec(statement).Error; err != nil {
+			return executed, fmt.Errorf("failed to execute %q: %w", statement, err)
+		}
+		executed = append(executed, statement)
+	}
+
+	log.Printf("Full-text search indexes migrated (%d statements)", len(executed))
+	return executed, nil
+}
+
+// GetStatus 获取全文搜索配置、可用分词扩展及现有索引
+func (s *SearchConfigService) GetStatus(ctx context.Context) (*SearchConfigStatus, error) {
+	searchConfig, err := s.GetConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resolved, err := s.Resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	available, err := s.AvailableConfigs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	indexes, err := s.existingSearchIndexes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &SearchConfigStatus{
+		Config:    searchConfig,
+		Resolved:  resolved,
+		Available: available,
+		Indexes:   indexes,
+	}
+	if s.isPostgres() {
+		if err := s.db.WithContext(ctx).
+			Raw("SELECT extname FROM pg_extension WHERE extname IN ('zhparser', 'pg_jieba') ORDER BY extname").
+			Scan(&status.Extensions).Error; err != nil {
+			return nil, fmt.Errorf("failed to list search extensions: %w", err)
+		}
+	}
+
+	return status, nil
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if value == "" || seen[value] {
+			continue
+		}
+		seen[value] = true
+		result = append(result, value)
+	}
+	return result
+}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+
+	"gongdan-system/internal/models"
+)
+
+func TestResolvedSearchConfigs_QueryConfigs(t *testing.T) {
+	resolved := &ResolvedSearchConfigs{Language: models.SearchLanguageAuto, Chinese: "chinese", English: "english"}
+
+	cases := []struct {
+		query string
+		want  []string
+	}{
+		{"登录失败", []string{"chinese"}},
+		{"login failed", []string{"english"}},
+		{"VPN 连接失败", []string{"chinese", "english"}},
+	}
+	for _, tc := range cases {
+		if got := resolved.QueryConfigs(tc.query); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("QueryConfigs(%q) = %v, want %v", tc.query, got, tc.want)
+		}
+	}
+
+	// 中文配置不可用时回退到 simple，与英文配置去重后只保留一份
+	fallback := &ResolvedSearchConfigs{Language: models.SearchLanguageChinese, Chinese: "simple", English: "simple"}
+	if got := fallback.QueryConfigs("login 登录"); !reflect.DeepEqual(got, []string{"simple"}) {
+		t.Errorf("unexpected fallback configs: %v", got)
+	}
+
+	invalid := models.GetDefaultSearchLanguageConfig()
+	invalid.ChineseConfigs = []string{"chinese'); DROP TABLE tickets; --"}
+	if err := invalid.Validate(); err == nil {
+		t.Fatal("expected invalid config name to be rejected")
+	}
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+// KeySessionPolicy 按角色的会话与可信设备策略配置键
+const KeySessionPolicy = "security.session_policy"
+
+// SessionPolicyService 按角色的会话策略：刷新令牌有效期、可信设备、第二因子要求及空闲超时
+type SessionPolicyService struct {
+	db *gorm.DB
+}
+
+// NewSessionPolicyService 创建会话策略服务
+func NewSessionPolicyService(db *gorm.DB) *SessionPolicyService {
+	return &SessionPolicyService{db: db}
+}
+
+// GetPolicy 获取会话策略，未保存或无法解析时所有角色沿用全局设置
+func (s *SessionPolicyService) GetPolicy(ctx context.Context) (*models.SessionPolicyConfig, error) {
+	var config models.SystemConfig
+	err := s.db.WithContext(ctx).
+		Where("key = ? AND is_active = ?", KeySessionPolicy, true).
+		First(&config).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.GetDefaultSessionPolicyConfig(), nil
+		}
+		return nil, fmt.Errorf("failed to get session policy: %w", err)
+	}
+
+	policy := models.GetDefaultSessionPolicyConfig()
+	if err := config.GetJSONValue(policy); err != nil {
+		log.Printf("Warning: failed to parse session policy, using defaults: %v", err)
+		return models.GetDefaultSessionPolicyConfig(), nil
+	}
+	if err := policy.Validate(); err != nil {
+		log.Printf("Warning: invalid session policy, using defaults: %v", err)
+		return models.GetDefaultSessionPolicyConfig(), nil
+	}
+	return policy, nil
+}
+
+// SetPolicy 保存会话策略，新的刷新令牌有效期和可信设备设置在下次登录或刷新时生效
+func (s *SessionPolicyService) SetPolicy(ctx context.Context, policy *models.SessionPolicyConfig, userID uint) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	var existing models.SystemConfig
+	err := s.db.WithContext(ctx).Where("key = ?", KeySessionPolicy).First(&existing).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to check existing policy: %w", err)
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		config := models.SystemConfig{
+			Key:         KeySessionPolicy,
+			Category:    CategorySecurity,
+			Group:       "session",
+			Description: "按角色的会话与可信设备策略",
+			IsActive:    true,
+			UpdatedBy:   &userID,
+		}
+		if err := config.SetValue(policy); err != nil {
+			return fmt.Errorf("failed to set policy value: %w", err)
+		}
+		if err := s.db.WithContext(ctx).Create(&config).Error; err != nil {
+			return fmt.Errorf("failed to create policy: %w", err)
+		}
+		return nil
+	}
+
+	if err := existing.SetValue(policy); err != nil {
+		return fmt.Errorf("failed to set policy value: %w", err)
+	}
+	existing.UpdatedBy = &userID
+	existing.Version++
+	if err := s.db.WithContext(ctx).Save(&existing).Error; err != nil {
+		return fmt.Errorf("failed to update policy: %w", err)
+	}
+	return nil
+}
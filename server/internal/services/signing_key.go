@@ -0,0 +1,91 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// hmacSigner 使用系统配置中保存的密钥计算 HMAC-SHA256 签名。
+// 密钥在首次签名时生成，多个实例同时生成时以先写入数据库的为准
+type hmacSigner struct {
+	configService *ConfigService
+	key           string // 系统配置键
+	description   string
+	group         string
+}
+
+// newHMACSigner 创建签名器，key 为保存密钥的系统配置键
+func newHMACSigner(configService *ConfigService, key, description, group string) *hmacSigner {
+	return &hmacSigner{configService: configService, key: key, description: description, group: group}
+}
+
+// Sign 对 payload 签名，密钥不存在时生成
+func (s *hmacSigner) Sign(payload string) (string, error) {
+	key, err := s.signingKey(true)
+	if err != nil {
+		return "", err
+	}
+	return hmacHex(key, payload), nil
+}
+
+// Verify 校验签名，密钥尚未生成时视为无效
+func (s *hmacSigner) Verify(payload, signature string) (bool, error) {
+	key, err := s.signingKey(false)
+	if err != nil || key == nil || signature == "" {
+		return false, err
+	}
+	return hmac.Equal([]byte(signature), []byte(hmacHex(key, payload))), nil
+}
+
+// SignLink 对资源ID和链接过期时间签名，domain 区分链接用途
+func (s *hmacSigner) SignLink(domain string, id uint, expires time.Time) (string, error) {
+	return s.Sign(signedLinkPayload(domain, id, expires.Unix()))
+}
+
+// VerifyLink 校验签名链接：签名有效且未到过期时间（expires 为 Unix 秒）
+func (s *hmacSigner) VerifyLink(domain string, id uint, expires, signature string, now time.Time) (bool, error) {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return false, nil
+	}
+	valid, err := s.Verify(signedLinkPayload(domain, id, expiresAt), signature)
+	if err != nil || !valid {
+		return false, err
+	}
+	return now.Unix() <= expiresAt, nil
+}
+
+// signingKey 读取签名密钥，create 为 true 时密钥不存在则生成；不生成且不存在时返回 nil
+func (s *hmacSigner) signingKey(create bool) ([]byte, error) {
+	if key := s.configService.GetConfigWithDefault(s.key, ""); key != "" {
+		return []byte(key), nil
+	}
+	if !create {
+		return nil, nil
+	}
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+	// 只在密钥为空时写入，并发生成时返回已保存的密钥
+	key, err := s.configService.SetConfigIfEmpty(s.key, hex.EncodeToString(buf), "string", s.description, CategorySecurity, s.group)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store signing key: %w", err)
+	}
+	return []byte(key), nil
+}
+
+func signedLinkPayload(domain string, id uint, expires int64) string {
+	return fmt.Sprintf("%s:%d:%d", domain, id, expires)
+}
+
+func hmacHex(key []byte, payload string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+)
+
+func TestHMACSigner_SharedKeyAndLinks(t *testing.T) {
+	db := newTestDB(t, &models.SystemConfig{})
+	// 默认配置中密钥为空
+	if err := NewConfigService(db).InitDefaultConfigs(); err != nil {
+		t.Fatalf("init configs failed: %v", err)
+	}
+
+	// 多个实例同时首次签名，最终使用同一个密钥
+	signers := make([]*hmacSigner, 4)
+	signatures := make([]string, len(signers))
+	errs := make([]error, len(signers))
+	var wg sync.WaitGroup
+	for i := range signers {
+		signers[i] = newHMACSigner(NewConfigService(db), KeyDownloadSigningKey, "", "downloads")
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			signatures[i], errs[i] = signers[i].Sign("payload")
+		}(i)
+	}
+	wg.Wait()
+	var stored models.SystemConfig
+	db.Where("key = ?", KeyDownloadSigningKey).First(&stored)
+	if stored.Value == "" {
+		t.Fatal("expected signing key to be stored")
+	}
+	for i := range signers {
+		if errs[i] != nil {
+			t.Fatalf("sign failed: %v", errs[i])
+		}
+		if valid, err := signers[0].Verify("payload", signatures[i]); err != nil || !valid {
+			t.Fatalf("expected signature %d to verify with the stored key, got %v (%v)", i, valid, err)
+		}
+	}
+
+	// 未生成密钥时校验失败而不是生成密钥
+	unset := newHMACSigner(NewConfigService(db), KeyPortalRealtimeSigningKey, "", "portal_realtime")
+	if valid, err := unset.Verify("payload", signatures[0]); err != nil || valid {
+		t.Fatalf("expected missing key to fail verification, got %v (%v)", valid, err)
+	}
+
+	now := time.Now()
+	signature, err := signers[0].SignLink("download", 7, now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("sign link failed: %v", err)
+	}
+	expires := now.Add(time.Minute).Unix()
+	cases := []struct {
+		name    string
+		domain  string
+		id      uint
+		expires string
+		now     time.Time
+		want    bool
+	}{
+		{"valid", "download", 7, strconv.FormatInt(expires, 10), now, true},
+		{"other resource", "download", 8, strconv.FormatInt(expires, 10), now, false},
+		{"other purpose", "ticket-archive", 7, strconv.FormatInt(expires, 10), now, false},
+		{"extended expiry", "download", 7, strconv.FormatInt(expires+3600, 10), now, false},
+		{"malformed expiry", "download", 7, "soon", now, false},
+		{"expired", "download", 7, strconv.FormatInt(expires, 10), now.Add(time.Minute + time.Second), false},
+	}
+	for _, tc := range cases {
+		if valid, err := signers[1].VerifyLink(tc.domain, tc.id, tc.expires, signature, tc.now); err != nil || valid != tc.want {
+			t.Errorf("%s: expected %v, got %v (%v)", tc.name, tc.want, valid, err)
+		}
+	}
+}
@@ -0,0 +1,330 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrSLAContractNotFound SLA合同不存在
+	ErrSLAContractNotFound = errors.New("sla contract not found")
+	// ErrInvalidSLAContract SLA合同参数无效
+	ErrInvalidSLAContract = errors.New("invalid sla contract")
+)
+
+// SLAContractFilter SLA合同查询条件
+type SLAContractFilter struct {
+	Company  string
+	IsActive *bool
+	Page     int
+	PageSize int
+}
+
+// SLAContractCompliance 合同在统计区间内的达成情况
+type SLAContractCompliance struct {
+	ContractID         uint    `json:"contract_id"`
+	Name               string  `json:"name"`
+	Company            string  `json:"company"`
+	Tier               string  `json:"tier"`
+	Tickets            int     `json:"tickets"`
+	ResponseMet        int     `json:"response_met"`
+	ResponseBreached   int     `json:"response_breached"`
+	ResolutionMet      int     `json:"resolution_met"`
+	ResolutionBreached int     `json:"resolution_breached"`
+	ResponseRate       float64 `json:"response_rate"`   // 已判定工单中响应达标的百分比
+	ResolutionRate     float64 `json:"resolution_rate"` // 已判定工单中解决达标的百分比
+}
+
+// SLAContractService 客户SLA合同管理及合同达成率统计
+type SLAContractService struct {
+	db                *gorm.DB
+	automationService *AutomationService
+}
+
+// NewSLAContractService 创建SLA合同服务
+func NewSLAContractService(db *gorm.DB) *SLAContractService {
+	return &SLAContractService{
+		db:                db,
+		automationService: NewAutomationService(db),
+	}
+}
+
+// Create 创建SLA合同
+func (s *SLAContractService) Create(ctx context.Context, req *models.SLAContractRequest, userID uint) (*models.SLAContract, error) {
+	contract := &models.SLAContract{IsActive: true, ExcludeWeekends: true, CreatedByID: userID}
+	if err := applySLAContractRequest(contract, req); err != nil {
+		return nil, err
+	}
+	if err := s.db.WithContext(ctx).Create(contract).Error; err != nil {
+		return nil, fmt.Errorf("failed to create sla contract: %w", err)
+	}
+	return contract, nil
+}
+
+// Update 更新SLA合同
+func (s *SLAContractService) Update(ctx context.Context, id uint, req *models.SLAContractRequest) (*models.SLAContract, error) {
+	contract, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applySLAContractRequest(contract, req); err != nil {
+		return nil, err
+	}
+	if err := s.db.WithContext(ctx).Save(contract).Error; err != nil {
+		return nil, fmt.Errorf("failed to update sla contract: %w", err)
+	}
+	return contract, nil
+}
+
+// Delete 删除SLA合同，已计算的工单截止时间不受影响
+func (s *SLAContractService) Delete(ctx context.Context, id uint) error {
+	result := s.db.WithContext(ctx).Delete(&models.SLAContract{}, id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete sla contract: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrSLAContractNotFound
+	}
+	return nil
+}
+
+// Get 获取SLA合同
+func (s *SLAContractService) Get(ctx context.Context, id uint) (*models.SLAContract, error) {
+	var contract models.SLAContract
+	if err := s.db.WithContext(ctx).First(&contract, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSLAContractNotFound
+		}
+		return nil, fmt.Errorf("failed to get sla contract: %w", err)
+	}
+	return &contract, nil
+}
+
+// List 分页查询SLA合同
+func (s *SLAContractService) List(ctx context.Context, filter *SLAContractFilter) ([]*models.SLAContract, int64, error) {
+	query := s.db.WithContext(ctx).Model(&models.SLAContract{})
+	if filter.Company != "" {
+		query = query.Where("company = ?", filter.Company)
+	}
+	if filter.IsActive != nil {
+		query = query.Where("is_active = ?", *filter.IsActive)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count sla contracts: %w", err)
+	}
+
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 || filter.PageSize > 100 {
+		filter.PageSize = 20
+	}
+	var contracts []*models.SLAContract
+	if err := query.Order("company ASC, valid_from DESC").
+		Offset((filter.Page - 1) * filter.PageSize).
+		Limit(filter.PageSize).
+		Find(&contracts).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list sla contracts: %w", err)
+	}
+	return contracts, total, nil
+}
+
+// ComplianceReport 统计 [start, end) 内创建、归属各合同的工单的响应与解决达成情况。
+// 尚未到期且未完成的工单不计入达标或违约
+func (s *SLAContractService) ComplianceReport(ctx context.Context, start, end, now time.Time) ([]*SLAContractCompliance, error) {
+	var contracts []models.SLAContract
+	if err := s.db.WithContext(ctx).
+		Where("valid_from < ? AND (valid_until IS NULL OR valid_until > ?)", end, start).
+		Find(&contracts).Error; err != nil {
+		return nil, fmt.Errorf("failed to get sla contracts: %w", err)
+	}
+	if len(contracts) == 0 {
+		return []*SLAContractCompliance{}, nil
+	}
+
+	var tickets []models.Ticket
+	if err := s.db.WithContext(ctx).
+		Preload("CreatedBy").
+		Where("created_at >= ? AND created_at < ? AND deleted_at IS NULL", start, end).
+		Find(&tickets).Error; err != nil {
+		return nil, fmt.Errorf("failed to get tickets: %w", err)
+	}
+
+	rows := make(map[uint]*SLAContractCompliance, len(contracts))
+	for i := range contracts {
+		c := &contracts[i]
+		rows[c.ID] = &SLAContractCompliance{ContractID: c.ID, Name: c.Name, Company: c.Company, Tier: c.Tier}
+	}
+
+	for i := range tickets {
+		ticket := &tickets[i]
+		email := ticket.CustomerEmail
+		if email == "" && ticket.CreatedBy != nil {
+			email = ticket.CreatedBy.Email
+		}
+		contract := selectSLAContract(contracts, email, string(ticket.Priority), ticket.CreatedAt)
+		if contract == nil {
+			continue
+		}
+
+		config, err := s.automationService.GetSLAConfigForTicket(ctx, ticket)
+		if err != nil {
+			return nil, err
+		}
+		responseDue, resolutionDue, err := s.automationService.CalculateSLADeadlines(ctx, ticket, config)
+		if err != nil {
+			return nil, err
+		}
+
+		row := rows[contract.ID]
+		row.Tickets++
+
+		firstResponse, err := s.firstResponseAt(ctx, ticket)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case firstResponse != nil && !firstResponse.After(responseDue):
+			row.ResponseMet++
+		case firstResponse != nil || now.After(responseDue):
+			row.ResponseBreached++
+		}
+
+		switch {
+		case ticket.ResolvedAt != nil && !ticket.ResolvedAt.After(resolutionDue):
+			row.ResolutionMet++
+		case ticket.ResolvedAt != nil || now.After(resolutionDue):
+			row.ResolutionBreached++
+		}
+	}
+
+	result := make([]*SLAContractCompliance, 0, len(rows))
+	for _, row := range rows {
+		row.ResponseRate = complianceRate(row.ResponseMet, row.ResponseBreached)
+		row.ResolutionRate = complianceRate(row.ResolutionMet, row.ResolutionBreached)
+		result = append(result, row)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Tickets != result[j].Tickets {
+			return result[i].Tickets > result[j].Tickets
+		}
+		return result[i].ContractID < result[j].ContractID
+	})
+	return result, nil
+}
+
+// firstResponseAt 工单首次由非提交人发表的非系统评论时间
+func (s *SLAContractService) firstResponseAt(ctx context.Context, ticket *models.Ticket) (*time.Time, error) {
+	var comment models.TicketComment
+	err := s.db.WithContext(ctx).
+		Where("ticket_id = ? AND type <> ? AND user_id <> ?", ticket.ID, models.CommentTypeSystem, ticket.CreatedByID).
+		Order("created_at ASC").
+		First(&comment).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get first response: %w", err)
+	}
+	return &comment.CreatedAt, nil
+}
+
+// selectSLAContract 在有效合同中选出适用于客户的一份：指定邮箱优先于域名，
+// 限定优先级的优先于通用合同，其余按生效时间取最新
+func selectSLAContract(contracts []models.SLAContract, email, priority string, at time.Time) *models.SLAContract {
+	var best *models.SLAContract
+	score := func(c *models.SLAContract) int {
+		n := 0
+		if c.MatchType == models.SLAContractMatchEmail {
+			n += 2
+		}
+		if c.Priority != nil {
+			n++
+		}
+		return n
+	}
+	for i := range contracts {
+		c := &contracts[i]
+		if !c.CoversTime(at) || !c.MatchesCustomer(email) {
+			continue
+		}
+		if c.Priority != nil && *c.Priority != priority {
+			continue
+		}
+		if best == nil || score(c) > score(best) || (score(c) == score(best) && c.ValidFrom.After(best.ValidFrom)) {
+			best = c
+		}
+	}
+	return best
+}
+
+func applySLAContractRequest(contract *models.SLAContract, req *models.SLAContractRequest) error {
+	pattern := strings.ToLower(strings.TrimSpace(req.Pattern))
+	switch req.MatchType {
+	case models.SLAContractMatchEmail:
+		if !strings.Contains(pattern, "@") {
+			return fmt.Errorf("%w: pattern must be an email address", ErrInvalidSLAContract)
+		}
+	case models.SLAContractMatchDomain:
+		pattern = strings.TrimPrefix(pattern, "@")
+		if pattern == "" || strings.Contains(pattern, "@") {
+			return fmt.Errorf("%w: pattern must be a domain", ErrInvalidSLAContract)
+		}
+	default:
+		return fmt.Errorf("%w: match_type must be email or domain", ErrInvalidSLAContract)
+	}
+	if req.Priority != nil {
+		switch models.TicketPriority(*req.Priority) {
+		case models.TicketPriorityLow, models.TicketPriorityNormal, models.TicketPriorityHigh,
+			models.TicketPriorityUrgent, models.TicketPriorityCritical:
+		default:
+			return fmt.Errorf("%w: unknown priority %q", ErrInvalidSLAContract, *req.Priority)
+		}
+	}
+	if req.ResponseTime <= 0 || req.ResolutionTime <= 0 {
+		return fmt.Errorf("%w: response and resolution targets must be positive", ErrInvalidSLAContract)
+	}
+	if req.ResponseTime > req.ResolutionTime {
+		return fmt.Errorf("%w: response target cannot exceed resolution target", ErrInvalidSLAContract)
+	}
+	if req.ValidFrom.IsZero() {
+		return fmt.Errorf("%w: valid_from is required", ErrInvalidSLAContract)
+	}
+	if req.ValidUntil != nil && !req.ValidUntil.After(req.ValidFrom) {
+		return fmt.Errorf("%w: valid_until must be after valid_from", ErrInvalidSLAContract)
+	}
+
+	contract.Name = strings.TrimSpace(req.Name)
+	contract.Company = strings.TrimSpace(req.Company)
+	contract.Tier = strings.TrimSpace(req.Tier)
+	contract.MatchType = req.MatchType
+	contract.Pattern = pattern
+	contract.Priority = req.Priority
+	contract.ResponseTime = req.ResponseTime
+	contract.ResolutionTime = req.ResolutionTime
+	contract.ValidFrom = req.ValidFrom
+	contract.ValidUntil = req.ValidUntil
+	if req.ExcludeWeekends != nil {
+		contract.ExcludeWeekends = *req.ExcludeWeekends
+	}
+	if req.IsActive != nil {
+		contract.IsActive = *req.IsActive
+	}
+	return nil
+}
+
+func complianceRate(met, breached int) float64 {
+	if met+breached == 0 {
+		return 0
+	}
+	return float64(met) / float64(met+breached) * 100
+}
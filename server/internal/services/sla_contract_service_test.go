@@ -0,0 +1,108 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestSLAContract_PrecedenceAndCompliance(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:sla_contract_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Ticket{}, &models.TicketComment{}, &models.SLAConfig{}, &models.SLAContract{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+
+	ctx := context.Background()
+	svc := NewSLAContractService(db)
+	automation := NewAutomationService(db)
+
+	if _, err := automation.CreateSLAConfig(ctx, &models.SLAConfigRequest{Name: "默认", ResponseTime: 240, ResolutionTime: 2880,
+		IsDefault: boolPtr(true), ExcludeWeekends: boolPtr(false), ExcludeHolidays: boolPtr(false)}); err != nil {
+		t.Fatalf("create sla config failed: %v", err)
+	}
+
+	customer := models.User{Username: "sc-customer", Email: "alice@acme.com", PasswordHash: "x", Role: models.RoleCustomer}
+	agent := models.User{Username: "sc-agent", Email: "agent@example.com", PasswordHash: "x", Role: models.RoleAgent}
+	db.Create(&customer)
+	db.Create(&agent)
+
+	validFrom := time.Now().AddDate(0, -1, 0)
+	if _, err := svc.Create(ctx, &models.SLAContractRequest{Name: "ACME", Company: "ACME", MatchType: models.SLAContractMatchEmail,
+		Pattern: "not-an-email", ResponseTime: 30, ResolutionTime: 240, ValidFrom: validFrom}, agent.ID); !errors.Is(err, ErrInvalidSLAContract) {
+		t.Fatalf("expected invalid pattern to be rejected, got %v", err)
+	}
+	company, err := svc.Create(ctx, &models.SLAContractRequest{Name: "ACME 金牌", Company: "ACME", Tier: "gold", MatchType: models.SLAContractMatchDomain,
+		Pattern: "@ACME.com", ResponseTime: 60, ResolutionTime: 480, ExcludeWeekends: boolPtr(false), ValidFrom: validFrom}, agent.ID)
+	if err != nil {
+		t.Fatalf("create company contract failed: %v", err)
+	}
+	urgent := "urgent"
+	explicit, err := svc.Create(ctx, &models.SLAContractRequest{Name: "Alice 紧急", Company: "ACME", MatchType: models.SLAContractMatchEmail,
+		Pattern: "alice@acme.com", Priority: &urgent, ResponseTime: 15, ResolutionTime: 120, ExcludeWeekends: boolPtr(false), ValidFrom: validFrom}, agent.ID)
+	if err != nil {
+		t.Fatalf("create explicit contract failed: %v", err)
+	}
+
+	created := time.Now().Add(-10 * time.Hour)
+	tickets := []models.Ticket{
+		// 合同内响应和解决
+		{TicketNumber: "SC-1", Title: "met", Status: models.TicketStatusResolved, Priority: models.TicketPriorityNormal, Type: models.TicketTypeRequest,
+			Source: models.TicketSourceWeb, CreatedByID: customer.ID},
+		// 按邮件域名匹配客户邮箱，超时未解决
+		{TicketNumber: "SC-2", Title: "breached", Status: models.TicketStatusOpen, Priority: models.TicketPriorityNormal, Type: models.TicketTypeRequest,
+			Source: models.TicketSourceEmail, CreatedByID: agent.ID, CustomerEmail: "bob@acme.com"},
+		// 紧急工单命中显式合同
+		{TicketNumber: "SC-3", Title: "urgent", Status: models.TicketStatusOpen, Priority: models.TicketPriorityUrgent, Type: models.TicketTypeRequest,
+			Source: models.TicketSourceWeb, CreatedByID: customer.ID},
+		// 非合同客户
+		{TicketNumber: "SC-4", Title: "other", Status: models.TicketStatusOpen, Priority: models.TicketPriorityNormal, Type: models.TicketTypeRequest,
+			Source: models.TicketSourceWeb, CreatedByID: agent.ID},
+	}
+	for i := range tickets {
+		if err := db.Create(&tickets[i]).Error; err != nil {
+			t.Fatalf("failed to seed ticket: %v", err)
+		}
+		db.Model(&tickets[i]).Update("created_at", created)
+		tickets[i].CreatedAt = created
+	}
+	resolved := created.Add(2 * time.Hour)
+	db.Model(&tickets[0]).Update("resolved_at", resolved)
+	db.Create(&models.TicketComment{TicketID: tickets[0].ID, UserID: agent.ID, Content: "处理中", Type: models.CommentTypePublic, CreatedAt: created.Add(30 * time.Minute)})
+
+	config, err := automation.GetSLAConfigForTicket(ctx, &tickets[1])
+	if err != nil || config.ContractID == nil || *config.ContractID != company.ID || config.ResolutionTime != 480 {
+		t.Fatalf("expected company contract to override default config, got %+v (%v)", config, err)
+	}
+	config, err = automation.GetSLAConfigForTicket(ctx, &tickets[2])
+	if err != nil || config.ContractID == nil || *config.ContractID != explicit.ID {
+		t.Fatalf("expected explicit contract to take precedence, got %+v (%v)", config, err)
+	}
+	config, err = automation.GetSLAConfigForTicket(ctx, &tickets[3])
+	if err != nil || config.ContractID != nil || config.ResolutionTime != 2880 {
+		t.Fatalf("expected default config for non-contract customer, got %+v (%v)", config, err)
+	}
+
+	report, err := svc.ComplianceReport(ctx, created.Add(-time.Hour), time.Now(), time.Now())
+	if err != nil {
+		t.Fatalf("compliance report failed: %v", err)
+	}
+	rows := map[uint]*SLAContractCompliance{}
+	for _, row := range report {
+		rows[row.ContractID] = row
+	}
+	if row := rows[company.ID]; row == nil || row.Tickets != 2 || row.ResolutionMet != 1 || row.ResolutionBreached != 1 ||
+		row.ResponseMet != 1 || row.ResponseBreached != 1 || row.ResolutionRate != 50 {
+		t.Fatalf("unexpected company contract compliance: %+v", row)
+	}
+	if row := rows[explicit.ID]; row == nil || row.Tickets != 1 || row.ResolutionBreached != 1 {
+		t.Fatalf("unexpected explicit contract compliance: %+v", row)
+	}
+}
@@ -0,0 +1,398 @@
+package services
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+// slaReportPriorityOrder 报表中优先级的展示顺序
+var slaReportPriorityOrder = []models.TicketPriority{
+	models.TicketPriorityCritical, models.TicketPriorityUrgent, models.TicketPriorityHigh,
+	models.TicketPriorityNormal, models.TicketPriorityLow,
+}
+
+// SLAReportService SLA达成率报表：按分类、优先级、团队和月份统计响应与解决的达标/违约，
+// 设置了排除周末或节假日的SLA按营业日历的工作时间计时
+type SLAReportService struct {
+	db              *gorm.DB
+	calendarService *BusinessCalendarService
+}
+
+// NewSLAReportService 创建SLA达成率报表服务
+func NewSLAReportService(db *gorm.DB) *SLAReportService {
+	return &SLAReportService{
+		db:              db,
+		calendarService: NewBusinessCalendarService(db),
+	}
+}
+
+// slaReportTicket 报表所需的工单字段，首次响应与分类、团队名称在同一查询中取得
+type slaReportTicket struct {
+	ID              uint
+	CreatedAt       time.Time
+	ResolvedAt      *time.Time
+	Priority        string
+	Type            string
+	AssignedToID    *uint
+	CreatedByID     uint
+	CustomerEmail   string
+	CategoryID      *uint
+	CategoryName    *string
+	AssignedTeamID  *uint
+	TeamName        *string
+	FirstResponseAt *time.Time
+}
+
+// slaTargets 一次加载的SLA配置与客户合同，按 AutomationService.GetSLAConfigForTicket 的规则为工单选择时限
+type slaTargets struct {
+	configs       []models.SLAConfig // 按匹配优先顺序排列
+	defaultConfig *models.SLAConfig
+	contracts     []models.SLAContract
+}
+
+// ComplianceReport 统计 [start, end) 内创建的工单的SLA达成率，并与上一等长区间对比
+func (s *SLAReportService) ComplianceReport(ctx context.Context, start, end, now time.Time) (*models.SLAComplianceReport, error) {
+	calendar, err := s.calendarService.GetCalendar(ctx)
+	if err != nil {
+		return nil, err
+	}
+	clock := newBusinessClock(calendar)
+	targets, err := loadSLATargets(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.SLAComplianceReport{
+		StartDate:         start,
+		EndDate:           end,
+		PreviousStartDate: start.Add(-end.Sub(start)),
+		PreviousEndDate:   start,
+		Timezone:          clock.loc.String(),
+	}
+	current, err := s.aggregate(ctx, start, end, now, targets, clock)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := s.aggregate(ctx, report.PreviousStartDate, start, now, targets, clock)
+	if err != nil {
+		return nil, err
+	}
+
+	report.Untracked = current.untracked
+	report.Overall = current.overall
+	compareSLARow(report.Overall, previous.overall)
+	report.ByCategory = current.rows("category", previous)
+	report.ByPriority = current.rows("priority", previous)
+	report.ByTeam = current.rows("team", previous)
+	report.ByMonth = current.rows("month", nil)
+
+	// 优先级按固定顺序，月份按时间顺序
+	rank := make(map[string]int, len(slaReportPriorityOrder))
+	for i, p := range slaReportPriorityOrder {
+		rank[string(p)] = i
+	}
+	sort.SliceStable(report.ByPriority, func(i, j int) bool {
+		ri, ok := rank[report.ByPriority[i].Key]
+		if !ok {
+			ri = len(rank)
+		}
+		rj, ok := rank[report.ByPriority[j].Key]
+		if !ok {
+			rj = len(rank)
+		}
+		return ri < rj
+	})
+	sort.Slice(report.ByMonth, func(i, j int) bool { return report.ByMonth[i].Key < report.ByMonth[j].Key })
+	return report, nil
+}
+
+// slaAggregation 一个区间内按维度分组的统计
+type slaAggregation struct {
+	overall   *models.SLAComplianceRow
+	groups    map[string]map[string]*models.SLAComplianceRow // 维度 -> key -> 行
+	untracked int
+}
+
+func (a *slaAggregation) row(dimension, key, label string) *models.SLAComplianceRow {
+	rows := a.groups[dimension]
+	if rows == nil {
+		rows = make(map[string]*models.SLAComplianceRow)
+		a.groups[dimension] = rows
+	}
+	row := rows[key]
+	if row == nil {
+		row = &models.SLAComplianceRow{Key: key, Label: label}
+		rows[key] = row
+	}
+	return row
+}
+
+// rows 返回维度下的各行（按工单数降序），previous 不为空时附加上一区间的对比
+func (a *slaAggregation) rows(dimension string, previous *slaAggregation) []*models.SLAComplianceRow {
+	result := make([]*models.SLAComplianceRow, 0, len(a.groups[dimension]))
+	for key, row := range a.groups[dimension] {
+		if previous != nil {
+			compareSLARow(row, previous.groups[dimension][key])
+		}
+		result = append(result, row)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Tickets != result[j].Tickets {
+			return result[i].Tickets > result[j].Tickets
+		}
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
+
+// compareSLARow 填充上一区间的达成率及变化（百分点）
+func compareSLARow(row, previous *models.SLAComplianceRow) {
+	if previous == nil {
+		return
+	}
+	if previous.ResponseMet+previous.ResponseBreached > 0 {
+		rate := previous.ResponseRate
+		row.PreviousResponseRate = &rate
+		if row.ResponseMet+row.ResponseBreached > 0 {
+			change := row.ResponseRate - rate
+			row.ResponseRateChange = &change
+		}
+	}
+	if previous.ResolutionMet+previous.ResolutionBreached > 0 {
+		rate := previous.ResolutionRate
+		row.PreviousResolutionRate = &rate
+		if row.ResolutionMet+row.ResolutionBreached > 0 {
+			change := row.ResolutionRate - rate
+			row.ResolutionRateChange = &change
+		}
+	}
+}
+
+// aggregate 查询区间内的工单并逐个判定响应与解决是否达标
+func (s *SLAReportService) aggregate(ctx context.Context, start, end, now time.Time, targets *slaTargets, clock *businessClock) (*slaAggregation, error) {
+	var tickets []slaReportTicket
+	err := s.db.WithContext(ctx).Table("tickets").
+		Select(`tickets.id, tickets.created_at, tickets.resolved_at, tickets.priority, tickets.type, tickets.assigned_to_id,
+			tickets.created_by_id, COALESCE(NULLIF(tickets.customer_email, ''), users.email) AS customer_email,
+			tickets.category_id, categories.name AS category_name, tickets.assigned_team_id, teams.name AS team_name,
+			(SELECT c.created_at FROM ticket_comments c
+				WHERE c.ticket_id = tickets.id AND c.type <> ? AND c.user_id <> tickets.created_by_id
+				ORDER BY c.created_at ASC LIMIT 1) AS first_response_at`, models.CommentTypeSystem).
+		Joins("LEFT JOIN users ON users.id = tickets.created_by_id").
+		Joins("LEFT JOIN categories ON categories.id = tickets.category_id").
+		Joins("LEFT JOIN teams ON teams.id = tickets.assigned_team_id").
+		Where("tickets.created_at >= ? AND tickets.created_at < ? AND tickets.deleted_at IS NULL", start, end).
+		Scan(&tickets).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tickets for sla report: %w", err)
+	}
+
+	agg := &slaAggregation{
+		overall: &models.SLAComplianceRow{Key: "all", Label: "all"},
+		groups:  make(map[string]map[string]*models.SLAComplianceRow),
+	}
+	for i := range tickets {
+		ticket := &tickets[i]
+		config := targets.resolve(ticket)
+		if config == nil {
+			agg.untracked++
+			continue
+		}
+
+		// 排除周末或节假日的SLA按营业时间计时，否则按自然时间
+		elapsed := func(to time.Time) float64 {
+			if config.ExcludeWeekends || config.ExcludeHolidays {
+				return clock.businessHoursBetween(ticket.CreatedAt, to) * 60
+			}
+			return to.Sub(ticket.CreatedAt).Minutes()
+		}
+		responseMet, responseDecided := slaOutcome(ticket.FirstResponseAt, now, float64(config.ResponseTime), elapsed)
+		resolutionMet, resolutionDecided := slaOutcome(ticket.ResolvedAt, now, float64(config.ResolutionTime), elapsed)
+
+		categoryKey, categoryLabel := "none", "未分类"
+		if ticket.CategoryID != nil {
+			categoryKey = strconv.FormatUint(uint64(*ticket.CategoryID), 10)
+			if ticket.CategoryName != nil {
+				categoryLabel = *ticket.CategoryName
+			}
+		}
+		teamKey, teamLabel := "none", "未分配团队"
+		if ticket.AssignedTeamID != nil {
+			teamKey = strconv.FormatUint(uint64(*ticket.AssignedTeamID), 10)
+			if ticket.TeamName != nil {
+				teamLabel = *ticket.TeamName
+			}
+		}
+		month := ticket.CreatedAt.In(clock.loc).Format("2006-01")
+
+		for _, row := range []*models.SLAComplianceRow{
+			agg.overall,
+			agg.row("category", categoryKey, categoryLabel),
+			agg.row("priority", ticket.Priority, ticket.Priority),
+			agg.row("team", teamKey, teamLabel),
+			agg.row("month", month, month),
+		} {
+			row.Tickets++
+			if responseDecided {
+				if responseMet {
+					row.ResponseMet++
+				} else {
+					row.ResponseBreached++
+				}
+			}
+			if resolutionDecided {
+				if resolutionMet {
+					row.ResolutionMet++
+				} else {
+					row.ResolutionBreached++
+				}
+			}
+		}
+	}
+
+	agg.overall.ResponseRate = complianceRate(agg.overall.ResponseMet, agg.overall.ResponseBreached)
+	agg.overall.ResolutionRate = complianceRate(agg.overall.ResolutionMet, agg.overall.ResolutionBreached)
+	for _, rows := range agg.groups {
+		for _, row := range rows {
+			row.ResponseRate = complianceRate(row.ResponseMet, row.ResponseBreached)
+			row.ResolutionRate = complianceRate(row.ResolutionMet, row.ResolutionBreached)
+		}
+	}
+	return agg, nil
+}
+
+// slaOutcome 已完成时按用时判定；未完成且已超时判为违约；未完成未超时不计入
+func slaOutcome(doneAt *time.Time, now time.Time, targetMinutes float64, elapsed func(time.Time) float64) (met, decided bool) {
+	if doneAt != nil {
+		return elapsed(*doneAt) <= targetMinutes, true
+	}
+	if elapsed(now) > targetMinutes {
+		return false, true
+	}
+	return false, false
+}
+
+// loadSLATargets 加载启用的SLA配置与客户合同
+func loadSLATargets(ctx context.Context, db *gorm.DB) (*slaTargets, error) {
+	targets := &slaTargets{}
+	if err := db.WithContext(ctx).Where("is_active = ?", true).Order("id ASC").Find(&targets.configs).Error; err != nil {
+		return nil, fmt.Errorf("failed to get sla configs: %w", err)
+	}
+	// 与 matchSLAConfig 一致：类型、优先级、处理人限定越多越优先
+	specificity := func(c *models.SLAConfig) int {
+		n := 0
+		if c.TicketType != nil {
+			n += 4
+		}
+		if c.Priority != nil {
+			n += 2
+		}
+		if c.AssignedUserID != nil {
+			n++
+		}
+		return n
+	}
+	sort.SliceStable(targets.configs, func(i, j int) bool {
+		return specificity(&targets.configs[i]) > specificity(&targets.configs[j])
+	})
+	for i := range targets.configs {
+		if targets.configs[i].IsDefault {
+			targets.defaultConfig = &targets.configs[i]
+			break
+		}
+	}
+	if err := db.WithContext(ctx).Where("is_active = ?", true).Find(&targets.contracts).Error; err != nil {
+		return nil, fmt.Errorf("failed to get sla contracts: %w", err)
+	}
+	return targets, nil
+}
+
+// resolve 返回工单适用的SLA配置，命中客户合同时由合同覆盖时限；没有适用配置时返回 nil
+func (t *slaTargets) resolve(ticket *slaReportTicket) *models.SLAConfig {
+	var config *models.SLAConfig
+	for i := range t.configs {
+		c := &t.configs[i]
+		if (c.TicketType == nil || *c.TicketType == ticket.Type) &&
+			(c.Priority == nil || *c.Priority == ticket.Priority) &&
+			(c.AssignedUserID == nil || (ticket.AssignedToID != nil && *c.AssignedUserID == *ticket.AssignedToID)) {
+			config = c
+			break
+		}
+	}
+	if config == nil {
+		config = t.defaultConfig
+	}
+
+	contract := selectSLAContract(t.contracts, strings.ToLower(strings.TrimSpace(ticket.CustomerEmail)), ticket.Priority, ticket.CreatedAt)
+	if contract == nil {
+		return config
+	}
+	merged := models.SLAConfig{ExcludeHolidays: true}
+	if config != nil {
+		merged = *config
+	}
+	merged.ResponseTime = contract.ResponseTime
+	merged.ResolutionTime = contract.ResolutionTime
+	merged.ExcludeWeekends = contract.ExcludeWeekends
+	merged.ContractID = &contract.ID
+	return &merged
+}
+
+// WriteComplianceCSV 将报表按维度逐行导出为 CSV
+func (s *SLAReportService) WriteComplianceCSV(report *models.SLAComplianceReport, w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{
+		"dimension", "key", "label", "tickets", "response_met", "response_breached", "response_rate",
+		"resolution_met", "resolution_breached", "resolution_rate",
+		"previous_response_rate", "response_rate_change", "previous_resolution_rate", "resolution_rate_change",
+	}); err != nil {
+		return err
+	}
+
+	formatRate := func(v float64) string { return strconv.FormatFloat(v, 'f', 2, 64) }
+	formatOptional := func(v *float64) string {
+		if v == nil {
+			return ""
+		}
+		return formatRate(*v)
+	}
+	write := func(dimension string, rows []*models.SLAComplianceRow) error {
+		for _, row := range rows {
+			if err := writer.Write([]string{
+				dimension, row.Key, row.Label, strconv.Itoa(row.Tickets),
+				strconv.Itoa(row.ResponseMet), strconv.Itoa(row.ResponseBreached), formatRate(row.ResponseRate),
+				strconv.Itoa(row.ResolutionMet), strconv.Itoa(row.ResolutionBreached), formatRate(row.ResolutionRate),
+				formatOptional(row.PreviousResponseRate), formatOptional(row.ResponseRateChange),
+				formatOptional(row.PreviousResolutionRate), formatOptional(row.ResolutionRateChange),
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, section := range []struct {
+		dimension string
+		rows      []*models.SLAComplianceRow
+	}{
+		{"overall", []*models.SLAComplianceRow{report.Overall}},
+		{"category", report.ByCategory},
+		{"priority", report.ByPriority},
+		{"team", report.ByTeam},
+		{"month", report.ByMonth},
+	} {
+		if err := write(section.dimension, section.rows); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
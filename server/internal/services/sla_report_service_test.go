@@ -0,0 +1,142 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestSLAReport_GroupsBusinessHoursAndPreviousPeriod(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:sla_report_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Ticket{}, &models.TicketComment{}, &models.Category{}, &models.Team{},
+		&models.SLAConfig{}, &models.SLAContract{}, &models.SystemConfig{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+
+	ctx := context.Background()
+	svc := NewSLAReportService(db)
+	automation := NewAutomationService(db)
+
+	// 默认SLA按自然时间计时；高优先级按营业日历（默认 Asia/Shanghai 工作日 9:00-18:00）计时
+	if _, err := automation.CreateSLAConfig(ctx, &models.SLAConfigRequest{Name: "默认", ResponseTime: 60, ResolutionTime: 480,
+		IsDefault: boolPtr(true), ExcludeWeekends: boolPtr(false), ExcludeHolidays: boolPtr(false)}); err != nil {
+		t.Fatalf("create sla config failed: %v", err)
+	}
+	high := string(models.TicketPriorityHigh)
+	if _, err := automation.CreateSLAConfig(ctx, &models.SLAConfigRequest{Name: "高优先级", Priority: &high, ResponseTime: 60, ResolutionTime: 480,
+		ExcludeWeekends: boolPtr(true), ExcludeHolidays: boolPtr(true)}); err != nil {
+		t.Fatalf("create sla config failed: %v", err)
+	}
+
+	customer := models.User{Username: "sr-customer", Email: "sr-customer@example.com", PasswordHash: "x", Role: models.RoleCustomer}
+	agent := models.User{Username: "sr-agent", Email: "sr-agent@example.com", PasswordHash: "x", Role: models.RoleAgent}
+	db.Create(&customer)
+	db.Create(&agent)
+	hardware := models.Category{Name: "硬件", Slug: "hardware"}
+	db.Create(&hardware)
+	team := models.Team{Name: "一线", Slug: "l1"}
+	db.Create(&team)
+
+	shanghai, _ := time.LoadLocation("Asia/Shanghai")
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2026, month, day, hour, minute, 0, 0, shanghai).UTC()
+	}
+	type seed struct {
+		priority  models.TicketPriority
+		category  *uint
+		team      *uint
+		created   time.Time
+		responded *time.Time
+		resolved  *time.Time
+	}
+	ptr := func(t time.Time) *time.Time { return &t }
+	seeds := []seed{
+		// 周五 17:30 创建，周一 9:20 响应、12:00 解决：营业时间内达标，按自然时间则违约
+		{models.TicketPriorityHigh, &hardware.ID, &team.ID, at(3, 6, 17, 30), ptr(at(3, 9, 9, 20)), ptr(at(3, 9, 12, 0))},
+		// 响应超时、解决达标
+		{models.TicketPriorityNormal, &hardware.ID, nil, at(2, 2, 10, 0), ptr(at(2, 2, 12, 0)), ptr(at(2, 2, 14, 0))},
+		// 无分类、未响应未解决且已超时
+		{models.TicketPriorityNormal, nil, &team.ID, at(3, 10, 10, 0), nil, nil},
+		// 刚创建、尚未超时，不计入达标或违约
+		{models.TicketPriorityNormal, nil, nil, at(3, 31, 11, 50), nil, nil},
+		// 上一区间：硬件分类全部达标
+		{models.TicketPriorityNormal, &hardware.ID, nil, at(1, 15, 10, 0), ptr(at(1, 15, 10, 30)), ptr(at(1, 15, 12, 0))},
+	}
+	for i, s := range seeds {
+		ticket := models.Ticket{TicketNumber: "SR-" + string(rune('A'+i)), Title: "t", Status: models.TicketStatusOpen, Priority: s.priority,
+			Type: models.TicketTypeRequest, Source: models.TicketSourceWeb, CreatedByID: customer.ID, CategoryID: s.category, AssignedTeamID: s.team}
+		if err := db.Create(&ticket).Error; err != nil {
+			t.Fatalf("failed to seed ticket: %v", err)
+		}
+		db.Model(&ticket).Updates(map[string]interface{}{"created_at": s.created, "resolved_at": s.resolved})
+		if s.responded != nil {
+			db.Create(&models.TicketComment{TicketID: ticket.ID, UserID: agent.ID, Content: "处理中", Type: models.CommentTypePublic, CreatedAt: *s.responded})
+		}
+		// 提交人自己的评论不算响应
+		db.Create(&models.TicketComment{TicketID: ticket.ID, UserID: customer.ID, Content: "补充", Type: models.CommentTypePublic, CreatedAt: s.created})
+	}
+
+	start, end, now := at(2, 1, 0, 0), at(4, 1, 0, 0), at(3, 31, 12, 0)
+	report, err := svc.ComplianceReport(ctx, start, end, now)
+	if err != nil {
+		t.Fatalf("compliance report failed: %v", err)
+	}
+	if !report.PreviousEndDate.Equal(start) || !report.PreviousStartDate.Equal(start.Add(-end.Sub(start))) || report.Timezone != "Asia/Shanghai" {
+		t.Fatalf("unexpected report period: %+v", report)
+	}
+
+	overall := report.Overall
+	if overall.Tickets != 4 || overall.ResponseMet != 1 || overall.ResponseBreached != 2 || overall.ResolutionMet != 2 || overall.ResolutionBreached != 1 {
+		t.Fatalf("unexpected overall counts: %+v", overall)
+	}
+	if overall.PreviousResponseRate == nil || *overall.PreviousResponseRate != 100 || overall.ResponseRateChange == nil ||
+		*overall.ResponseRateChange > -66 || *overall.ResponseRateChange < -67 {
+		t.Fatalf("unexpected overall comparison: %+v", overall)
+	}
+
+	findRow := func(rows []*models.SLAComplianceRow, key string) *models.SLAComplianceRow {
+		for _, row := range rows {
+			if row.Key == key {
+				return row
+			}
+		}
+		t.Fatalf("row %s not found", key)
+		return nil
+	}
+	category := findRow(report.ByCategory, "1")
+	if category.Label != "硬件" || category.Tickets != 2 || category.ResponseRate != 50 || category.ResolutionRate != 100 ||
+		category.ResolutionRateChange == nil || *category.ResolutionRateChange != 0 {
+		t.Fatalf("unexpected category row: %+v", category)
+	}
+	if none := findRow(report.ByCategory, "none"); none.Label != "未分类" || none.Tickets != 2 || none.ResponseBreached != 1 || none.PreviousResponseRate != nil {
+		t.Fatalf("unexpected uncategorized row: %+v", none)
+	}
+	if len(report.ByPriority) != 2 || report.ByPriority[0].Key != "high" || report.ByPriority[0].ResponseRate != 100 {
+		t.Fatalf("expected high priority first and met on business hours: %+v", report.ByPriority)
+	}
+	if row := findRow(report.ByTeam, "1"); row.Label != "一线" || row.Tickets != 2 {
+		t.Fatalf("unexpected team row: %+v", row)
+	}
+	if len(report.ByMonth) != 2 || report.ByMonth[0].Key != "2026-02" || report.ByMonth[1].Key != "2026-03" || report.ByMonth[1].Tickets != 3 {
+		t.Fatalf("unexpected month rows: %+v", report.ByMonth)
+	}
+
+	var buf bytes.Buffer
+	if err := svc.WriteComplianceCSV(report, &buf); err != nil {
+		t.Fatalf("write csv failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 1+1+len(report.ByCategory)+len(report.ByPriority)+len(report.ByTeam)+len(report.ByMonth) ||
+		!strings.HasPrefix(lines[1], "overall,all,all,4,1,2,33.33,2,1,66.67,100.00,-66.67,100.00,-33.33") {
+		t.Fatalf("unexpected csv output:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,356 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxSLAWarningThresholds 每个SLA配置最多设置的预警阈值数
+const maxSLAWarningThresholds = 5
+
+// ErrInvalidSLAWarningThresholds 预警阈值不合法
+var ErrInvalidSLAWarningThresholds = errors.New("invalid SLA warning thresholds")
+
+// encodeSLAWarningThresholds 校验预警阈值（1-99，最多 5 个）并去重排序后编码，为空时返回空字符串表示使用默认阈值
+func encodeSLAWarningThresholds(thresholds []int) (string, error) {
+	if len(thresholds) == 0 {
+		return "", nil
+	}
+	seen := make(map[int]bool, len(thresholds))
+	normalized := make([]int, 0, len(thresholds))
+	for _, threshold := range thresholds {
+		if threshold < 1 || threshold > 99 {
+			return "", fmt.Errorf("%w: %d is not between 1 and 99", ErrInvalidSLAWarningThresholds, threshold)
+		}
+		if !seen[threshold] {
+			seen[threshold] = true
+			normalized = append(normalized, threshold)
+		}
+	}
+	if len(normalized) > maxSLAWarningThresholds {
+		return "", fmt.Errorf("%w: at most %d thresholds", ErrInvalidSLAWarningThresholds, maxSLAWarningThresholds)
+	}
+	sort.Ints(normalized)
+	data, _ := json.Marshal(normalized)
+	return string(data), nil
+}
+
+// SLAWarningService SLA违约前预警：工单已用时限达到SLA配置的预警阈值时提醒处理人及所属团队，
+// 每个工单的每项时限在每个阈值只提醒一次。计时规则与SLA达成率报表一致
+type SLAWarningService struct {
+	db                  *gorm.DB
+	calendarService     *BusinessCalendarService
+	notificationService NotificationServiceInterface
+}
+
+// NewSLAWarningService 创建SLA预警服务
+func NewSLAWarningService(db *gorm.DB) *SLAWarningService {
+	return &SLAWarningService{
+		db:                  db,
+		calendarService:     NewBusinessCalendarService(db),
+		notificationService: NewNotificationService(db),
+	}
+}
+
+// slaWarningTicket 预警计算所需的工单字段
+type slaWarningTicket struct {
+	ID              uint
+	TicketNumber    string
+	Title           string
+	Status          string
+	CreatedAt       time.Time
+	ResolvedAt      *time.Time
+	SLADueDate      *time.Time
+	Priority        string
+	Type            string
+	AssignedToID    *uint
+	AssignedTeamID  *uint
+	CreatedByID     uint
+	CustomerEmail   string
+	FirstResponseAt *time.Time
+}
+
+// reportTicket 转换为SLA报表的工单字段，用于匹配SLA配置与客户合同
+func (t *slaWarningTicket) reportTicket() *slaReportTicket {
+	return &slaReportTicket{
+		ID:              t.ID,
+		CreatedAt:       t.CreatedAt,
+		ResolvedAt:      t.ResolvedAt,
+		Priority:        t.Priority,
+		Type:            t.Type,
+		AssignedToID:    t.AssignedToID,
+		CreatedByID:     t.CreatedByID,
+		CustomerEmail:   t.CustomerEmail,
+		AssignedTeamID:  t.AssignedTeamID,
+		FirstResponseAt: t.FirstResponseAt,
+	}
+}
+
+// AtRiskTickets 返回已越过预警阈值、尚未违约的工单时限，按违约时间从近到远排列
+func (s *SLAWarningService) AtRiskTickets(ctx context.Context, filter models.SLAAtRiskFilter, now time.Time) ([]models.SLAAtRiskTicket, error) {
+	items, err := s.evaluate(ctx, filter, now)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].BreachAt.Before(items[j].BreachAt) })
+	return items, nil
+}
+
+// ProcessDue 为新越过预警阈值的工单时限发送预警，返回发出预警的时限数。
+// 一次越过多个阈值（如任务暂停期间）时只按最高阈值提醒
+func (s *SLAWarningService) ProcessDue(ctx context.Context, now time.Time) (int, error) {
+	items, err := s.evaluate(ctx, models.SLAAtRiskFilter{}, now)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for i := range items {
+		if ctx.Err() != nil {
+			return sent, ctx.Err()
+		}
+		item := &items[i]
+		warning := &models.SLAWarning{
+			TicketID:       item.TicketID,
+			Target:         item.Target,
+			Threshold:      item.Threshold,
+			TargetMinutes:  item.TargetMinutes,
+			SLAConfigID:    item.SLAConfigID,
+			ContractID:     item.ContractID,
+			ElapsedPercent: item.ElapsedPercent,
+			BreachAt:       item.BreachAt,
+		}
+		// 唯一索引去重，多实例同时执行时只有一个实例发送
+		result := s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(warning)
+		if result.Error != nil {
+			log.Printf("Failed to record SLA warning for ticket %d: %v", item.TicketID, result.Error)
+			continue
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+
+		recipients, err := s.recipients(ctx, item)
+		if err != nil {
+			log.Printf("Failed to find SLA warning recipients for ticket %d: %v", item.TicketID, err)
+		}
+		notified := 0
+		for _, recipientID := range recipients {
+			if err := s.notify(ctx, item, recipientID); err != nil {
+				log.Printf("Failed to send SLA warning for ticket %d to user %d: %v", item.TicketID, recipientID, err)
+				continue
+			}
+			notified++
+		}
+		s.db.WithContext(ctx).Model(warning).UpdateColumn("recipient_count", notified)
+		sent++
+	}
+
+	if sent > 0 {
+		log.Printf("SLA warning check sent %d warnings", sent)
+	}
+	return sent, nil
+}
+
+// evaluate 计算未完结工单各项时限的已用比例，返回已越过预警阈值且尚未违约的时限
+func (s *SLAWarningService) evaluate(ctx context.Context, filter models.SLAAtRiskFilter, now time.Time) ([]models.SLAAtRiskTicket, error) {
+	calendar, err := s.calendarService.GetCalendar(ctx)
+	if err != nil {
+		return nil, err
+	}
+	clock := newBusinessClock(calendar)
+	targets, err := loadSLATargets(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	query := s.db.WithContext(ctx).Table("tickets").
+		Select(`tickets.id, tickets.ticket_number, tickets.title, tickets.status, tickets.created_at, tickets.resolved_at,
+			tickets.sla_due_date, tickets.priority, tickets.type, tickets.assigned_to_id, tickets.assigned_team_id, tickets.created_by_id,
+			COALESCE(NULLIF(tickets.customer_email, ''), users.email) AS customer_email,
+			(SELECT c.created_at FROM ticket_comments c
+				WHERE c.ticket_id = tickets.id AND c.type <> ? AND c.user_id <> tickets.created_by_id
+				ORDER BY c.created_at ASC LIMIT 1) AS first_response_at`, models.CommentTypeSystem).
+		Joins("LEFT JOIN users ON users.id = tickets.created_by_id").
+		Where("tickets.deleted_at IS NULL AND tickets.sla_breached = ? AND tickets.status NOT IN ?", false, closedTicketStatuses)
+	if filter.AssignedToID != nil {
+		query = query.Where("tickets.assigned_to_id = ?", *filter.AssignedToID)
+	}
+	if filter.TeamID != nil {
+		query = query.Where("tickets.assigned_team_id = ?", *filter.TeamID)
+	}
+	var tickets []slaWarningTicket
+	if err := query.Order("tickets.id ASC").Scan(&tickets).Error; err != nil {
+		return nil, fmt.Errorf("failed to get tickets for sla warnings: %w", err)
+	}
+
+	var items []models.SLAAtRiskTicket
+	for i := range tickets {
+		ticket := &tickets[i]
+		config := targets.resolve(ticket.reportTicket())
+		for _, target := range []models.SLAWarningTarget{models.SLAWarningTargetResponse, models.SLAWarningTargetResolution} {
+			if filter.Target != "" && filter.Target != target {
+				continue
+			}
+			if item, ok := slaWarningFor(ticket, config, target, clock, now); ok {
+				items = append(items, item)
+			}
+		}
+	}
+	return items, nil
+}
+
+// slaWarningFor 计算工单某项时限的预警状态。已完成、已违约、没有适用时限或未达到最低阈值时 ok 为 false。
+// 手动设置的 SLA 截止时间覆盖解决时限，按自然时间计时
+func slaWarningFor(ticket *slaWarningTicket, config *models.SLAConfig, target models.SLAWarningTarget, clock *businessClock, now time.Time) (models.SLAAtRiskTicket, bool) {
+	var targetMinutes float64
+	var elapsed float64
+	var breachAt time.Time
+	businessTimed := config != nil && (config.ExcludeWeekends || config.ExcludeHolidays)
+
+	switch {
+	case target == models.SLAWarningTargetResponse:
+		if ticket.FirstResponseAt != nil || config == nil {
+			return models.SLAAtRiskTicket{}, false
+		}
+		targetMinutes = float64(config.ResponseTime)
+	case ticket.ResolvedAt != nil:
+		return models.SLAAtRiskTicket{}, false
+	case ticket.SLADueDate != nil:
+		targetMinutes = math.Floor(ticket.SLADueDate.Sub(ticket.CreatedAt).Minutes())
+		businessTimed = false
+	case config != nil:
+		targetMinutes = float64(config.ResolutionTime)
+	}
+	if targetMinutes <= 0 {
+		return models.SLAAtRiskTicket{}, false
+	}
+
+	if businessTimed {
+		elapsed = clock.businessHoursBetween(ticket.CreatedAt, now) * 60
+		breachAt, _ = clock.addBusinessMinutes(ticket.CreatedAt, targetMinutes)
+	} else {
+		elapsed = now.Sub(ticket.CreatedAt).Minutes()
+		breachAt = ticket.CreatedAt.Add(time.Duration(targetMinutes) * time.Minute)
+	}
+	if elapsed >= targetMinutes {
+		return models.SLAAtRiskTicket{}, false
+	}
+
+	percent := elapsed / targetMinutes * 100
+	thresholds := models.DefaultSLAWarningThresholds
+	if config != nil {
+		thresholds = config.GetWarningThresholds()
+	}
+	crossed := 0
+	for _, threshold := range thresholds {
+		if percent >= float64(threshold) {
+			crossed = threshold
+		}
+	}
+	if crossed == 0 {
+		return models.SLAAtRiskTicket{}, false
+	}
+
+	item := models.SLAAtRiskTicket{
+		TicketID:         ticket.ID,
+		TicketNumber:     ticket.TicketNumber,
+		Title:            ticket.Title,
+		Status:           ticket.Status,
+		Priority:         ticket.Priority,
+		AssignedToID:     ticket.AssignedToID,
+		AssignedTeamID:   ticket.AssignedTeamID,
+		Target:           target,
+		TargetMinutes:    int(targetMinutes),
+		ElapsedPercent:   math.Round(percent*10) / 10,
+		Threshold:        crossed,
+		RemainingMinutes: math.Round((targetMinutes-elapsed)*10) / 10,
+		BreachAt:         breachAt,
+	}
+	if config != nil {
+		if config.ID != 0 {
+			item.SLAConfigID = &config.ID
+		}
+		item.ContractID = config.ContractID
+	}
+	return item, true
+}
+
+// recipients 预警接收人：处理人及所属团队负责人；工单未分配处理人时提醒团队全体成员
+func (s *SLAWarningService) recipients(ctx context.Context, item *models.SLAAtRiskTicket) ([]uint, error) {
+	var ids []uint
+	if item.AssignedToID != nil {
+		ids = append(ids, *item.AssignedToID)
+	}
+	if item.AssignedTeamID != nil {
+		var leadIDs []uint
+		if err := s.db.WithContext(ctx).Model(&models.Team{}).
+			Where("id = ? AND is_active = ? AND lead_id IS NOT NULL", *item.AssignedTeamID, true).
+			Pluck("lead_id", &leadIDs).Error; err != nil {
+			return ids, err
+		}
+		ids = append(ids, leadIDs...)
+		if item.AssignedToID == nil {
+			var memberIDs []uint
+			if err := s.db.WithContext(ctx).Table("team_members").
+				Where("team_id = ?", *item.AssignedTeamID).
+				Pluck("user_id", &memberIDs).Error; err != nil {
+				return ids, err
+			}
+			ids = append(ids, memberIDs...)
+		}
+	}
+
+	seen := make(map[uint]bool, len(ids))
+	unique := ids[:0]
+	for _, id := range ids {
+		if id != 0 && !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique, nil
+}
+
+func (s *SLAWarningService) notify(ctx context.Context, item *models.SLAAtRiskTicket, recipientID uint) error {
+	targetLabel := "解决"
+	if item.Target == models.SLAWarningTargetResponse {
+		targetLabel = "首次响应"
+	}
+	priority := models.NotificationPriorityNormal
+	if item.Threshold >= 90 {
+		priority = models.NotificationPriorityHigh
+	}
+	_, err := s.notificationService.CreateNotification(ctx, &models.NotificationCreateRequest{
+		Type:  models.NotificationTypeTicketOverdue,
+		Title: fmt.Sprintf("SLA即将违约 - %s", item.Title),
+		Content: fmt.Sprintf("工单 #%s 的%s时限已用 %.0f%%，约 %.0f 分钟后违约（%s）",
+			item.TicketNumber, targetLabel, item.ElapsedPercent, math.Ceil(item.RemainingMinutes), item.BreachAt.Format("2006-01-02 15:04")),
+		Priority:        priority,
+		Channel:         models.NotificationChannelInApp,
+		RecipientID:     recipientID,
+		RelatedType:     "ticket",
+		RelatedID:       &item.TicketID,
+		RelatedTicketID: &item.TicketID,
+		ActionURL:       fmt.Sprintf("/tickets/%d", item.TicketID),
+		Metadata: map[string]interface{}{
+			"ticket_number":     item.TicketNumber,
+			"sla_target":        item.Target,
+			"threshold":         item.Threshold,
+			"elapsed_percent":   item.ElapsedPercent,
+			"remaining_minutes": item.RemainingMinutes,
+			"breach_at":         item.BreachAt,
+		},
+	})
+	return err
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestSLAWarning_ThresholdsDedupeAndAtRisk(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:sla_warning_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Team{}, &models.Ticket{}, &models.TicketComment{}, &models.SLAConfig{},
+		&models.SLAContract{}, &models.SLAWarning{}, &models.Notification{}, &models.SystemConfig{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+	ctx := context.Background()
+	svc := NewSLAWarningService(db)
+
+	agent := models.User{Username: "warn-agent", Email: "warn-agent@example.com", PasswordHash: "x", Role: models.RoleAgent}
+	member := models.User{Username: "warn-member", Email: "warn-member@example.com", PasswordHash: "x", Role: models.RoleAgent}
+	lead := models.User{Username: "warn-lead", Email: "warn-lead@example.com", PasswordHash: "x", Role: models.RoleSupervisor}
+	customer := models.User{Username: "warn-customer", Email: "warn-customer@example.com", PasswordHash: "x", Role: models.RoleCustomer}
+	for _, u := range []*models.User{&agent, &member, &lead, &customer} {
+		if err := db.Create(u).Error; err != nil {
+			t.Fatalf("failed to seed user: %v", err)
+		}
+	}
+	team, err := NewTeamService(db).CreateTeam(ctx, &models.TeamCreateRequest{Name: "Warn L1", Slug: "warn-l1", MemberIDs: []uint{agent.ID, member.ID}, LeadID: &lead.ID})
+	if err != nil {
+		t.Fatalf("failed to create team: %v", err)
+	}
+
+	// 阈值校验
+	automation := NewAutomationService(db)
+	if _, err := automation.CreateSLAConfig(ctx, &models.SLAConfigRequest{Name: "bad", ResponseTime: 60, ResolutionTime: 600, WarningThresholds: []int{50, 100}}); !errors.Is(err, ErrInvalidSLAWarningThresholds) {
+		t.Fatalf("expected invalid thresholds to be rejected, got %v", err)
+	}
+	isDefault := true
+	config, err := automation.CreateSLAConfig(ctx, &models.SLAConfigRequest{Name: "standard", ResponseTime: 100, ResolutionTime: 1000,
+		IsDefault: &isDefault, WarningThresholds: []int{80, 50, 80}})
+	if err != nil {
+		t.Fatalf("create sla config failed: %v", err)
+	}
+	// 按自然时间计时，结果与运行时刻无关
+	db.Model(config).UpdateColumns(map[string]interface{}{"exclude_weekends": false, "exclude_holidays": false})
+	if got := config.GetWarningThresholds(); len(got) != 2 || got[0] != 50 || got[1] != 80 {
+		t.Fatalf("unexpected normalized thresholds %v", got)
+	}
+
+	now := time.Now()
+	seed := func(number string, createdAt time.Time, assignee *uint) models.Ticket {
+		t.Helper()
+		ticket := models.Ticket{TicketNumber: number, Title: "ticket " + number, Status: models.TicketStatusOpen, Priority: models.TicketPriorityNormal,
+			Type: models.TicketTypeRequest, Source: models.TicketSourceWeb, CreatedByID: customer.ID, AssignedToID: assignee, AssignedTeamID: &team.ID}
+		if err := db.Create(&ticket).Error; err != nil {
+			t.Fatalf("failed to seed ticket: %v", err)
+		}
+		db.Model(&ticket).UpdateColumn("created_at", createdAt)
+		ticket.CreatedAt = createdAt
+		return ticket
+	}
+	// 响应时限已用 60%，解决时限 6%
+	assigned := seed("SW-1", now.Add(-60*time.Minute), &agent.ID)
+	// 响应时限已用 90%（一次越过两个阈值）；未分配处理人
+	unassigned := seed("SW-2", now.Add(-90*time.Minute), nil)
+	// 已违约的响应时限不再预警
+	seed("SW-3", now.Add(-120*time.Minute), &agent.ID)
+
+	items, err := svc.AtRiskTickets(ctx, models.SLAAtRiskFilter{}, now)
+	if err != nil {
+		t.Fatalf("at-risk query failed: %v", err)
+	}
+	if len(items) != 2 || items[0].TicketID != unassigned.ID || items[1].TicketID != assigned.ID {
+		t.Fatalf("expected at-risk tickets ordered by breach time, got %+v", items)
+	}
+	if items[0].Threshold != 80 || items[0].Target != models.SLAWarningTargetResponse || items[0].RemainingMinutes < 9 || items[0].RemainingMinutes > 10 {
+		t.Fatalf("unexpected at-risk item %+v", items[0])
+	}
+	if !items[1].BreachAt.Equal(assigned.CreatedAt.Add(100 * time.Minute)) {
+		t.Fatalf("unexpected breach time %v", items[1].BreachAt)
+	}
+	if mine, _ := svc.AtRiskTickets(ctx, models.SLAAtRiskFilter{AssignedToID: &agent.ID}, now); len(mine) != 1 || mine[0].TicketID != assigned.ID {
+		t.Fatalf("unexpected assignee filter result %+v", mine)
+	}
+	if resolution, _ := svc.AtRiskTickets(ctx, models.SLAAtRiskFilter{Target: models.SLAWarningTargetResolution}, now); len(resolution) != 0 {
+		t.Fatalf("expected no resolution warnings, got %+v", resolution)
+	}
+
+	// 每个阈值只提醒一次；处理人与团队负责人收到提醒，未分配时提醒团队全体成员
+	sent, err := svc.ProcessDue(ctx, now)
+	if err != nil || sent != 2 {
+		t.Fatalf("expected 2 warnings, got %d, %v", sent, err)
+	}
+	if sent, _ := svc.ProcessDue(ctx, now.Add(time.Minute)); sent != 0 {
+		t.Fatalf("expected warnings to be deduplicated, got %d", sent)
+	}
+	countFor := func(ticketID, userID uint) int64 {
+		var count int64
+		db.Model(&models.Notification{}).Where("related_ticket_id = ? AND recipient_id = ?", ticketID, userID).Count(&count)
+		return count
+	}
+	if countFor(assigned.ID, agent.ID) != 1 || countFor(assigned.ID, lead.ID) != 1 || countFor(assigned.ID, member.ID) != 0 {
+		t.Fatalf("unexpected recipients for assigned ticket")
+	}
+	if countFor(unassigned.ID, agent.ID) != 1 || countFor(unassigned.ID, member.ID) != 1 || countFor(unassigned.ID, lead.ID) != 1 {
+		t.Fatalf("unexpected recipients for unassigned ticket")
+	}
+	var warning models.SLAWarning
+	db.Where("ticket_id = ?", unassigned.ID).First(&warning)
+	if warning.Threshold != 80 || warning.RecipientCount != 3 {
+		t.Fatalf("unexpected warning record %+v", warning)
+	}
+
+	// 越过下一个阈值时再次提醒；已首次响应的工单不再提醒响应时限
+	db.Create(&models.TicketComment{TicketID: unassigned.ID, UserID: agent.ID, Content: "looking", Type: models.CommentTypePublic})
+	if sent, _ := svc.ProcessDue(ctx, now.Add(25*time.Minute)); sent != 1 {
+		t.Fatalf("expected only the next threshold of ticket SW-1, got %d", sent)
+	}
+	var thresholds []int
+	db.Model(&models.SLAWarning{}).Where("ticket_id = ?", assigned.ID).Order("threshold").Pluck("threshold", &thresholds)
+	if len(thresholds) != 2 || thresholds[0] != 50 || thresholds[1] != 80 {
+		t.Fatalf("unexpected warning thresholds %v", thresholds)
+	}
+}
@@ -0,0 +1,410 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+
+	"gongdan-system/internal/models"
+)
+
+const (
+	smsCodeLength      = 6
+	smsCodeMaxAttempts = 5
+)
+
+var (
+	// ErrSMSNotConfigured 未启用短信或服务商配置不完整
+	ErrSMSNotConfigured = errors.New("sms is not configured")
+	// ErrSMSSendFailed 短信服务商请求失败
+	ErrSMSSendFailed = errors.New("sms provider request failed")
+	// ErrSMSRateLimited 发送过于频繁
+	ErrSMSRateLimited = errors.New("too many sms requests")
+	// ErrSMSCodeInvalid 验证码错误、已使用或不存在
+	ErrSMSCodeInvalid = errors.New("invalid sms code")
+	// ErrSMSCodeExpired 验证码已过期
+	ErrSMSCodeExpired = errors.New("sms code expired")
+	// ErrInvalidPhoneNumber 手机号不是 E.164 格式
+	ErrInvalidPhoneNumber = errors.New("phone number must be in E.164 format")
+)
+
+// e164Pattern E.164 手机号，如 +8613800000000
+var e164Pattern = regexp.MustCompile(`^\+[1-9]\d{6,14}$`)
+
+// SMSCodeRequest 发送短信验证码请求
+type SMSCodeRequest struct {
+	UserID    uint
+	Purpose   models.OTPType
+	Phone     string
+	IPAddress string
+	UserAgent string
+}
+
+// SMSOTPService 短信验证码服务：发送频率限制、验证码校验及费用与送达统计。
+// 验证码保存在 otp_codes（仅保存哈希），每次发送记录在 sms_messages
+type SMSOTPService struct {
+	db            *gorm.DB
+	configService *ConfigService
+	client        *http.Client
+	newProvider   func(config *SMSProviderConfig) (SMSProvider, error)
+	now           func() time.Time
+}
+
+// NewSMSOTPService 创建短信验证码服务
+func NewSMSOTPService(db *gorm.DB) *SMSOTPService {
+	service := &SMSOTPService{
+		db:            db,
+		configService: NewConfigService(db),
+		client:        &http.Client{Timeout: 10 * time.Second},
+		now:           time.Now,
+	}
+	service.newProvider = func(config *SMSProviderConfig) (SMSProvider, error) {
+		return NewSMSProvider(config, service.client)
+	}
+	return service
+}
+
+// ValidatePhoneNumber 校验 E.164 手机号
+func ValidatePhoneNumber(phone string) error {
+	if !e164Pattern.MatchString(phone) {
+		return ErrInvalidPhoneNumber
+	}
+	return nil
+}
+
+// Enabled 是否已启用短信验证码
+func (s *SMSOTPService) Enabled() bool {
+	enabled, err := s.configService.GetConfigBool(KeySMSEnabled)
+	return err == nil && enabled
+}
+
+// provider 按系统配置创建短信客户端，未启用或缺少配置时返回 ErrSMSNotConfigured
+func (s *SMSOTPService) provider() (SMSProvider, error) {
+	if !s.Enabled() {
+		return nil, ErrSMSNotConfigured
+	}
+	return s.newProvider(&SMSProviderConfig{
+		Provider:     models.SMSProviderName(s.configService.GetConfigWithDefault(KeySMSProvider, string(models.SMSProviderTwilio))),
+		AccessKeyID:  s.configService.GetConfigWithDefault(KeySMSAccessKeyID, ""),
+		AccessSecret: s.configService.GetConfigWithDefault(KeySMSAccessSecret, ""),
+		Sender:       s.configService.GetConfigWithDefault(KeySMSSender, ""),
+		TemplateCode: s.configService.GetConfigWithDefault(KeySMSTemplateCode, ""),
+		Endpoint:     s.configService.GetConfigWithDefault(KeySMSEndpoint, ""),
+	})
+}
+
+// CodeTTL 验证码有效期
+func (s *SMSOTPService) CodeTTL() time.Duration {
+	return time.Duration(s.configInt(KeySMSCodeTTLMinutes, 5)) * time.Minute
+}
+
+func (s *SMSOTPService) configInt(key string, defaultValue int) int {
+	if value, err := s.configService.GetConfigInt(key); err == nil && value >= 0 {
+		return value
+	}
+	return defaultValue
+}
+
+// SendCode 生成验证码并通过短信发送，同一用户同一用途之前未使用的验证码随之作废。
+// 超过重发间隔或每小时发送上限（按账户、手机号、IP 分别计算）时返回 ErrSMSRateLimited
+func (s *SMSOTPService) SendCode(ctx context.Context, req *SMSCodeRequest) (*models.OTPCode, error) {
+	if err := ValidatePhoneNumber(req.Phone); err != nil {
+		return nil, err
+	}
+	provider, err := s.provider()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkRateLimit(ctx, req); err != nil {
+		return nil, err
+	}
+
+	code, err := generateSMSCode()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate sms code: %w", err)
+	}
+	now := s.now()
+	ttl := s.CodeTTL()
+	otp := &models.OTPCode{
+		UserID:          req.UserID,
+		Code:            hashSMSCode(code),
+		Type:            req.Purpose,
+		Status:          models.OTPStatusPending,
+		ExpiresAt:       now.Add(ttl),
+		DeliveryMethod:  models.OTPDeliverySMS,
+		Recipient:       req.Phone,
+		MaxAttempts:     smsCodeMaxAttempts,
+		SourceIP:        req.IPAddress,
+		UserAgent:       truncateString(req.UserAgent, 490),
+		Length:          smsCodeLength,
+		IsNumeric:       true,
+		ValidityMinutes: int(ttl.Minutes()),
+	}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.OTPCode{}).
+			Where("user_id = ? AND type = ? AND delivery_method = ? AND status = ?", req.UserID, req.Purpose, models.OTPDeliverySMS, models.OTPStatusPending).
+			Updates(map[string]interface{}{"status": models.OTPStatusRevoked, "revoked_at": now, "revoke_reason": "superseded"}).Error; err != nil {
+			return err
+		}
+		return tx.Create(otp).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sms code: %w", err)
+	}
+
+	result, sendErr := provider.SendCode(ctx, req.Phone, code, ttl)
+	userID := req.UserID
+	message := &models.SMSMessage{
+		CreatedAt: now,
+		UserID:    &userID,
+		Purpose:   req.Purpose,
+		Provider:  provider.Provider(),
+		Recipient: req.Phone,
+		IPAddress: req.IPAddress,
+		Status:    models.SMSMessageSent,
+		Segments:  1,
+	}
+	if sendErr != nil {
+		message.Status = models.SMSMessageFailed
+		message.Error = truncateString(sendErr.Error(), 490)
+		otp.Status = models.OTPStatusFailed
+		otp.FailedAt = &now
+		otp.FailureReason = "delivery failed"
+	} else {
+		message.ProviderMessageID = result.MessageID
+		message.Segments = result.Segments
+		message.Cost = s.costPerMessage() * float64(result.Segments)
+		otp.SentAt = &now
+	}
+	if err := s.db.WithContext(ctx).Create(message).Error; err != nil {
+		return nil, fmt.Errorf("failed to record sms message: %w", err)
+	}
+	if err := s.db.WithContext(ctx).Model(otp).Select("status", "sent_at", "failed_at", "failure_reason").Updates(otp).Error; err != nil {
+		return nil, fmt.Errorf("failed to update sms code: %w", err)
+	}
+	if sendErr != nil {
+		return nil, sendErr
+	}
+	return otp, nil
+}
+
+// checkRateLimit 重发间隔及每小时发送上限，失败的发送同样计入
+func (s *SMSOTPService) checkRateLimit(ctx context.Context, req *SMSCodeRequest) error {
+	now := s.now()
+	if interval := s.configInt(KeySMSResendSeconds, 60); interval > 0 {
+		var count int64
+		if err := s.db.WithContext(ctx).Model(&models.SMSMessage{}).
+			Where("user_id = ? AND purpose = ? AND created_at > ?", req.UserID, req.Purpose, now.Add(-time.Duration(interval)*time.Second)).
+			Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to count sms messages: %w", err)
+		}
+		if count > 0 {
+			return fmt.Errorf("%w: please wait %d seconds before requesting another code", ErrSMSRateLimited, interval)
+		}
+	}
+
+	since := now.Add(-time.Hour)
+	limit := int64(s.configInt(KeySMSMaxPerHour, 5))
+	type hourlyLimit struct {
+		column string
+		value  interface{}
+		limit  int64
+	}
+	limits := []hourlyLimit{
+		{"user_id", req.UserID, limit},
+		{"recipient", req.Phone, limit},
+	}
+	if req.IPAddress != "" {
+		limits = append(limits, hourlyLimit{"ip_address", req.IPAddress, int64(s.configInt(KeySMSMaxPerIPHour, 20))})
+	}
+	for _, check := range limits {
+		var count int64
+		if err := s.db.WithContext(ctx).Model(&models.SMSMessage{}).
+			Where(check.column+" = ? AND created_at > ?", check.value, since).
+			Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to count sms messages: %w", err)
+		}
+		if count >= check.limit {
+			return fmt.Errorf("%w: hourly limit reached", ErrSMSRateLimited)
+		}
+	}
+	return nil
+}
+
+func (s *SMSOTPService) costPerMessage() float64 {
+	cost, err := strconv.ParseFloat(s.configService.GetConfigWithDefault(KeySMSCostPerMessage, "0"), 64)
+	if err != nil || cost < 0 {
+		return 0
+	}
+	return cost
+}
+
+// VerifyCode 校验用户最近一次发送到 phone 的验证码，成功后验证码失效。
+// 错误次数达到上限后验证码作废，需重新发送
+func (s *SMSOTPService) VerifyCode(ctx context.Context, userID uint, purpose models.OTPType, phone, code string) error {
+	var otp models.OTPCode
+	err := s.db.WithContext(ctx).
+		Where("user_id = ? AND type = ? AND delivery_method = ? AND recipient = ? AND status = ?",
+			userID, purpose, models.OTPDeliverySMS, phone, models.OTPStatusPending).
+		Order("id DESC").First(&otp).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrSMSCodeInvalid
+		}
+		return fmt.Errorf("failed to get sms code: %w", err)
+	}
+
+	now := s.now()
+	if now.After(otp.ExpiresAt) {
+		s.db.WithContext(ctx).Model(&otp).Update("status", models.OTPStatusExpired)
+		return ErrSMSCodeExpired
+	}
+	if subtle.ConstantTimeCompare([]byte(otp.Code), []byte(hashSMSCode(code))) != 1 {
+		otp.IncrementAttempts()
+		if err := s.db.WithContext(ctx).Model(&otp).
+			Select("attempts", "last_attempt_at", "status", "failed_at", "failure_reason").Updates(&otp).Error; err != nil {
+			return fmt.Errorf("failed to update sms code: %w", err)
+		}
+		return ErrSMSCodeInvalid
+	}
+
+	// 原子地标记为已使用，并发请求只有一个能成功
+	result := s.db.WithContext(ctx).Model(&models.OTPCode{}).
+		Where("id = ? AND status = ?", otp.ID, models.OTPStatusPending).
+		Updates(map[string]interface{}{"status": models.OTPStatusUsed, "used_at": now, "verified_at": now})
+	if result.Error != nil {
+		return fmt.Errorf("failed to use sms code: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrSMSCodeInvalid
+	}
+	return nil
+}
+
+// Metrics 统计区间内的短信发送量、送达率、验证码使用率及估算费用
+func (s *SMSOTPService) Metrics(ctx context.Context, start, end time.Time) (*models.SMSMetrics, error) {
+	var messages []models.SMSMessage
+	if err := s.db.WithContext(ctx).
+		Select("created_at", "purpose", "provider", "status", "cost").
+		Where("created_at >= ? AND created_at < ?", start, end).
+		Find(&messages).Error; err != nil {
+		return nil, fmt.Errorf("failed to get sms messages: %w", err)
+	}
+
+	metrics := &models.SMSMetrics{
+		StartDate: start,
+		EndDate:   end,
+		Currency:  s.configService.GetConfigWithDefault(KeySMSCostCurrency, "CNY"),
+	}
+	byProvider := make(map[string]*models.SMSMetricsRow)
+	byPurpose := make(map[string]*models.SMSMetricsRow)
+	daily := make(map[string]*models.SMSDailyMetrics)
+	for _, message := range messages {
+		provider := smsMetricsRow(byProvider, string(message.Provider))
+		purpose := smsMetricsRow(byPurpose, string(message.Purpose))
+		date := message.CreatedAt.In(start.Location()).Format("2006-01-02")
+		day, ok := daily[date]
+		if !ok {
+			day = &models.SMSDailyMetrics{Date: date}
+			daily[date] = day
+		}
+		if message.Status == models.SMSMessageSent {
+			metrics.Sent++
+			provider.Sent++
+			purpose.Sent++
+			day.Sent++
+		} else {
+			metrics.Failed++
+			provider.Failed++
+			purpose.Failed++
+			day.Failed++
+		}
+		metrics.Cost += message.Cost
+		provider.Cost += message.Cost
+		purpose.Cost += message.Cost
+		day.Cost += message.Cost
+	}
+	if total := metrics.Sent + metrics.Failed; total > 0 {
+		metrics.DeliveryRate = roundRate(float64(metrics.Sent) / float64(total))
+	}
+
+	if err := s.db.WithContext(ctx).Model(&models.OTPCode{}).
+		Where("delivery_method = ? AND sent_at >= ? AND sent_at < ? AND used_at IS NOT NULL", models.OTPDeliverySMS, start, end).
+		Count(&metrics.CodesVerified).Error; err != nil {
+		return nil, fmt.Errorf("failed to count verified sms codes: %w", err)
+	}
+	if metrics.Sent > 0 {
+		metrics.VerificationRate = roundRate(float64(metrics.CodesVerified) / float64(metrics.Sent))
+	}
+
+	metrics.ByProvider = sortedSMSMetricsRows(byProvider)
+	metrics.ByPurpose = sortedSMSMetricsRows(byPurpose)
+	metrics.Daily = make([]*models.SMSDailyMetrics, 0, len(daily))
+	for _, day := range daily {
+		metrics.Daily = append(metrics.Daily, day)
+	}
+	sort.Slice(metrics.Daily, func(i, j int) bool { return metrics.Daily[i].Date < metrics.Daily[j].Date })
+	return metrics, nil
+}
+
+func smsMetricsRow(rows map[string]*models.SMSMetricsRow, key string) *models.SMSMetricsRow {
+	row, ok := rows[key]
+	if !ok {
+		row = &models.SMSMetricsRow{Key: key}
+		rows[key] = row
+	}
+	return row
+}
+
+func sortedSMSMetricsRows(rows map[string]*models.SMSMetricsRow) []*models.SMSMetricsRow {
+	result := make([]*models.SMSMetricsRow, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, row)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Sent+result[i].Failed != result[j].Sent+result[j].Failed {
+			return result[i].Sent+result[i].Failed > result[j].Sent+result[j].Failed
+		}
+		return result[i].Key < result[j].Key
+	})
+	return result
+}
+
+// roundRate 保留四位小数
+func roundRate(rate float64) float64 {
+	return float64(int64(rate*10000+0.5)) / 10000
+}
+
+// MaskPhoneNumber 隐藏手机号中间部分，如 +86138****0000
+func MaskPhoneNumber(phone string) string {
+	if len(phone) <= 7 {
+		return phone
+	}
+	return phone[:len(phone)-8] + "****" + phone[len(phone)-4:]
+}
+
+// generateSMSCode 生成6位数字验证码
+func generateSMSCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+func hashSMSCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
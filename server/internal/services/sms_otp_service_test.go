@@ -0,0 +1,239 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// fakeTwilioAPI 模拟 Twilio Messages 接口，记录每个号码最近收到的验证码
+type fakeTwilioAPI struct {
+	mu    sync.Mutex
+	codes map[string]string
+}
+
+var smsCodePattern = regexp.MustCompile(`\d{6}`)
+
+func (f *fakeTwilioAPI) handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		user, pass, _ := r.BasicAuth()
+		to := r.Form.Get("To")
+		if user != "AC123" || pass != "twilio-token" || r.URL.Path != "/2010-04-01/Accounts/AC123/Messages.json" || to == "+15550009999" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"code":21211,"message":"Invalid 'To' Phone Number"}`))
+			return
+		}
+		f.mu.Lock()
+		f.codes[to] = smsCodePattern.FindString(r.Form.Get("Body"))
+		f.mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"sid":"SM` + to[1:] + `","num_segments":"2"}`))
+	})
+}
+
+func (f *fakeTwilioAPI) code(phone string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.codes[phone]
+}
+
+func TestSMSOTP_SendVerifyRateLimitAndMetrics(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:sms_otp_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.SystemConfig{}, &models.OTPCode{}, &models.SMSMessage{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+
+	api := &fakeTwilioAPI{codes: map[string]string{}}
+	server := httptest.NewServer(api.handler())
+	defer server.Close()
+
+	ctx := context.Background()
+	now := time.Date(2024, 6, 3, 9, 0, 0, 0, time.UTC)
+	svc := NewSMSOTPService(db)
+	svc.now = func() time.Time { return now }
+
+	users := make([]models.User, 3)
+	for i := range users {
+		users[i] = models.User{Username: "sms-user" + string(rune('a'+i)), Email: "sms-user" + string(rune('a'+i)) + "@example.com", PasswordHash: "x", Role: models.RoleCustomer, Status: models.UserStatusActive}
+		if err := db.Create(&users[i]).Error; err != nil {
+			t.Fatalf("failed to seed user: %v", err)
+		}
+	}
+	phone := "+8613800000000"
+	send := func(userID uint, phone string) error {
+		_, err := svc.SendCode(ctx, &SMSCodeRequest{UserID: userID, Purpose: models.OTPTypeTwoFactor, Phone: phone, IPAddress: "10.0.0.1"})
+		return err
+	}
+
+	// 未启用短信时不发送
+	if err := send(users[0].ID, phone); !errors.Is(err, ErrSMSNotConfigured) {
+		t.Fatalf("expected sms to be disabled by default, got %v", err)
+	}
+	for key, value := range map[string]string{
+		KeySMSEnabled:        "true",
+		KeySMSProvider:       "twilio",
+		KeySMSAccessKeyID:    "AC123",
+		KeySMSAccessSecret:   "twilio-token",
+		KeySMSSender:         "+15550000000",
+		KeySMSEndpoint:       server.URL,
+		KeySMSMaxPerHour:     "3",
+		KeySMSCostPerMessage: "0.05",
+	} {
+		if err := svc.configService.SetConfig(key, value, "string", "", CategorySecurity, "sms"); err != nil {
+			t.Fatalf("failed to set %s: %v", key, err)
+		}
+	}
+	if err := send(users[0].ID, "13800000000"); !errors.Is(err, ErrInvalidPhoneNumber) {
+		t.Fatalf("expected non E.164 number to be rejected, got %v", err)
+	}
+
+	if err := send(users[0].ID, phone); err != nil {
+		t.Fatalf("send code failed: %v", err)
+	}
+	first := api.code(phone)
+	var stored models.OTPCode
+	if err := db.Where("user_id = ?", users[0].ID).First(&stored).Error; err != nil {
+		t.Fatalf("failed to load otp: %v", err)
+	}
+	if first == "" || stored.Code == first || stored.Code != hashSMSCode(first) || stored.SentAt == nil {
+		t.Fatalf("expected hashed code to be stored, got %+v (code %q)", stored, first)
+	}
+
+	// 重发间隔内拒绝
+	if err := send(users[0].ID, phone); !errors.Is(err, ErrSMSRateLimited) {
+		t.Fatalf("expected resend cooldown, got %v", err)
+	}
+
+	// 重发后旧验证码作废
+	now = now.Add(61 * time.Second)
+	if err := send(users[0].ID, phone); err != nil {
+		t.Fatalf("resend failed: %v", err)
+	}
+	second := api.code(phone)
+	if first != second {
+		if err := svc.VerifyCode(ctx, users[0].ID, models.OTPTypeTwoFactor, phone, first); !errors.Is(err, ErrSMSCodeInvalid) {
+			t.Fatalf("expected superseded code to be rejected, got %v", err)
+		}
+	}
+	if err := svc.VerifyCode(ctx, users[0].ID, models.OTPTypeTwoFactor, "+8613900000000", second); !errors.Is(err, ErrSMSCodeInvalid) {
+		t.Fatalf("expected code for another phone to be rejected, got %v", err)
+	}
+	if err := svc.VerifyCode(ctx, users[0].ID, models.OTPTypeTwoFactor, phone, second); err != nil {
+		t.Fatalf("verify code failed: %v", err)
+	}
+	if err := svc.VerifyCode(ctx, users[0].ID, models.OTPTypeTwoFactor, phone, second); !errors.Is(err, ErrSMSCodeInvalid) {
+		t.Fatalf("expected used code to be rejected, got %v", err)
+	}
+
+	// 每小时上限（按手机号计算，换账户也不能绕过）
+	now = now.Add(61 * time.Second)
+	if err := send(users[0].ID, phone); err != nil {
+		t.Fatalf("third send failed: %v", err)
+	}
+	now = now.Add(61 * time.Second)
+	if err := send(users[1].ID, phone); !errors.Is(err, ErrSMSRateLimited) {
+		t.Fatalf("expected hourly limit per phone, got %v", err)
+	}
+
+	// 错误次数达到上限后验证码作废
+	other := "+8613700000000"
+	if err := send(users[1].ID, other); err != nil {
+		t.Fatalf("send to second user failed: %v", err)
+	}
+	for i := 0; i < smsCodeMaxAttempts; i++ {
+		if err := svc.VerifyCode(ctx, users[1].ID, models.OTPTypeTwoFactor, other, "000000x"); !errors.Is(err, ErrSMSCodeInvalid) {
+			t.Fatalf("expected wrong code to be rejected, got %v", err)
+		}
+	}
+	if err := svc.VerifyCode(ctx, users[1].ID, models.OTPTypeTwoFactor, other, api.code(other)); !errors.Is(err, ErrSMSCodeInvalid) {
+		t.Fatalf("expected code to be locked after max attempts, got %v", err)
+	}
+
+	// 过期
+	third := "+8613600000000"
+	if err := send(users[2].ID, third); err != nil {
+		t.Fatalf("send to third user failed: %v", err)
+	}
+	now = now.Add(6 * time.Minute)
+	if err := svc.VerifyCode(ctx, users[2].ID, models.OTPTypeTwoFactor, third, api.code(third)); !errors.Is(err, ErrSMSCodeExpired) {
+		t.Fatalf("expected expired code, got %v", err)
+	}
+
+	// 服务商拒绝时记录失败并返回错误
+	now = now.Add(61 * time.Second)
+	if err := send(users[2].ID, "+15550009999"); !errors.Is(err, ErrSMSSendFailed) {
+		t.Fatalf("expected provider failure, got %v", err)
+	}
+
+	metrics, err := svc.Metrics(ctx, now.Add(-24*time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("metrics failed: %v", err)
+	}
+	if metrics.Sent != 5 || metrics.Failed != 1 || metrics.DeliveryRate != 0.8333 || metrics.CodesVerified != 1 || metrics.VerificationRate != 0.2 {
+		t.Fatalf("unexpected sms metrics: %+v", metrics)
+	}
+	if metrics.Cost < 0.4999 || metrics.Cost > 0.5001 || metrics.Currency != "CNY" {
+		t.Fatalf("expected cost of 5 messages x 2 segments x 0.05, got %v %s", metrics.Cost, metrics.Currency)
+	}
+	if len(metrics.ByProvider) != 1 || metrics.ByProvider[0].Key != "twilio" || len(metrics.Daily) != 1 || metrics.Daily[0].Date != "2024-06-03" {
+		t.Fatalf("unexpected sms metric breakdown: %+v %+v", metrics.ByProvider, metrics.Daily)
+	}
+}
+
+func TestSMSProvider_AliyunSignsSendSmsRequest(t *testing.T) {
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query = r.URL.Query()
+		w.Write([]byte(`{"Code":"OK","Message":"OK","BizId":"biz-1"}`))
+	}))
+	defer server.Close()
+
+	provider, err := NewSMSProvider(&SMSProviderConfig{
+		Provider:     models.SMSProviderAliyun,
+		AccessKeyID:  "ak",
+		AccessSecret: "secret",
+		Sender:       "工单系统",
+		TemplateCode: "SMS_123",
+		Endpoint:     server.URL + "/",
+	}, server.Client())
+	if err != nil {
+		t.Fatalf("create provider failed: %v", err)
+	}
+	result, err := provider.SendCode(context.Background(), "+8613800000000", "123456", 5*time.Minute)
+	if err != nil || result.MessageID != "biz-1" {
+		t.Fatalf("send failed: %+v %v", result, err)
+	}
+	if query.Get("PhoneNumbers") != "13800000000" || query.Get("TemplateParam") != `{"code":"123456"}` || query.Get("SignName") != "工单系统" {
+		t.Fatalf("unexpected aliyun params: %v", query)
+	}
+
+	// 去掉签名后按相同规则重新计算应一致
+	signature := query.Get("Signature")
+	params := map[string]string{}
+	for key := range query {
+		if key != "Signature" {
+			params[key] = query.Get(key)
+		}
+	}
+	if expected := aliyunSignature("secret", http.MethodGet, aliyunCanonicalQuery(params)); signature != expected {
+		t.Fatalf("signature mismatch: got %s want %s", signature, expected)
+	}
+
+	if _, err := NewSMSProvider(&SMSProviderConfig{Provider: models.SMSProviderAliyun, AccessKeyID: "ak", AccessSecret: "secret", Sender: "工单系统"}, nil); !errors.Is(err, ErrSMSNotConfigured) {
+		t.Fatalf("expected missing template code to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gongdan-system/internal/models"
+)
+
+// SMSProvider 短信服务提供方
+type SMSProvider interface {
+	Provider() models.SMSProviderName
+	// SendCode 向 phone（E.164 格式）发送验证码
+	SendCode(ctx context.Context, phone, code string, ttl time.Duration) (*SMSSendResult, error)
+}
+
+// SMSSendResult 服务商受理结果
+type SMSSendResult struct {
+	MessageID string
+	Segments  int
+}
+
+// SMSProviderConfig 短信服务配置
+type SMSProviderConfig struct {
+	Provider     models.SMSProviderName
+	AccessKeyID  string // Twilio Account SID / 阿里云 AccessKey ID
+	AccessSecret string // Twilio Auth Token / 阿里云 AccessKey Secret
+	Sender       string // Twilio 发送号码或 Messaging Service SID / 阿里云短信签名
+	TemplateCode string // 阿里云短信模板，模板变量为 ${code}
+	Endpoint     string // 为空时使用服务商默认地址
+}
+
+// NewSMSProvider 按配置创建短信服务客户端
+func NewSMSProvider(config *SMSProviderConfig, client *http.Client) (SMSProvider, error) {
+	if config.AccessKeyID == "" || config.AccessSecret == "" || config.Sender == "" {
+		return nil, ErrSMSNotConfigured
+	}
+	switch config.Provider {
+	case models.SMSProviderTwilio:
+		endpoint := config.Endpoint
+		if endpoint == "" {
+			endpoint = "https://api.twilio.com"
+		}
+		return &twilioSMSProvider{config: config, endpoint: strings.TrimRight(endpoint, "/"), client: client}, nil
+	case models.SMSProviderAliyun:
+		if config.TemplateCode == "" {
+			return nil, fmt.Errorf("%w: aliyun template code is required", ErrSMSNotConfigured)
+		}
+		endpoint := config.Endpoint
+		if endpoint == "" {
+			endpoint = "https://dysmsapi.aliyuncs.com/"
+		}
+		return &aliyunSMSProvider{config: config, endpoint: endpoint, client: client}, nil
+	}
+	return nil, fmt.Errorf("%w: unknown provider %q", ErrSMSNotConfigured, config.Provider)
+}
+
+// twilioSMSProvider Twilio Programmable Messaging
+type twilioSMSProvider struct {
+	config   *SMSProviderConfig
+	endpoint string
+	client   *http.Client
+}
+
+func (p *twilioSMSProvider) Provider() models.SMSProviderName {
+	return models.SMSProviderTwilio
+}
+
+func (p *twilioSMSProvider) SendCode(ctx context.Context, phone, code string, ttl time.Duration) (*SMSSendResult, error) {
+	form := url.Values{}
+	form.Set("To", phone)
+	form.Set("Body", fmt.Sprintf("您的验证码为 %s，%d 分钟内有效。如非本人操作请忽略。", code, int(ttl.Minutes())))
+	// MG 开头的是 Messaging Service SID，由 Twilio 选择发送号码
+	if strings.HasPrefix(p.config.Sender, "MG") {
+		form.Set("MessagingServiceSid", p.config.Sender)
+	} else {
+		form.Set("From", p.config.Sender)
+	}
+
+	endpoint := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json", p.endpoint, url.PathEscape(p.config.AccessKeyID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(p.config.AccessKeyID, p.config.AccessSecret)
+
+	var resp struct {
+		SID         string `json:"sid"`
+		NumSegments string `json:"num_segments"`
+		Code        int    `json:"code"`
+		Message     string `json:"message"`
+	}
+	status, err := doSMSRequest(p.client, req, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if status < 200 || status >= 300 || resp.SID == "" {
+		return nil, fmt.Errorf("%w: twilio returned %d: %d %s", ErrSMSSendFailed, status, resp.Code, resp.Message)
+	}
+	segments, _ := strconv.Atoi(resp.NumSegments)
+	if segments < 1 {
+		segments = 1
+	}
+	return &SMSSendResult{MessageID: resp.SID, Segments: segments}, nil
+}
+
+// aliyunSMSProvider 阿里云短信服务 SendSms（RPC 签名 v1）
+type aliyunSMSProvider struct {
+	config   *SMSProviderConfig
+	endpoint string
+	client   *http.Client
+}
+
+func (p *aliyunSMSProvider) Provider() models.SMSProviderName {
+	return models.SMSProviderAliyun
+}
+
+func (p *aliyunSMSProvider) SendCode(ctx context.Context, phone, code string, ttl time.Duration) (*SMSSendResult, error) {
+	templateParam, _ := json.Marshal(map[string]string{"code": code})
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	params := map[string]string{
+		"AccessKeyId":      p.config.AccessKeyID,
+		"Action":           "SendSms",
+		"Format":           "JSON",
+		"PhoneNumbers":     aliyunPhoneNumber(phone),
+		"RegionId":         "cn-hangzhou",
+		"SignName":         p.config.Sender,
+		"SignatureMethod":  "HMAC-SHA1",
+		"SignatureNonce":   hex.EncodeToString(nonce),
+		"SignatureVersion": "1.0",
+		"TemplateCode":     p.config.TemplateCode,
+		"TemplateParam":    string(templateParam),
+		"Timestamp":        time.Now().UTC().Format("2006-01-02T15:04:05Z"),
+		"Version":          "2017-05-25",
+	}
+	query := aliyunCanonicalQuery(params)
+	query += "&Signature=" + aliyunPercentEncode(aliyunSignature(p.config.AccessSecret, http.MethodGet, query))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.endpoint+"?"+query, nil)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Code    string `json:"Code"`
+		Message string `json:"Message"`
+		BizID   string `json:"BizId"`
+	}
+	status, err := doSMSRequest(p.client, req, &resp)
+	if err != nil {
+		return nil, err
+	}
+	if status < 200 || status >= 300 || resp.Code != "OK" {
+		return nil, fmt.Errorf("%w: aliyun returned %d: %s %s", ErrSMSSendFailed, status, resp.Code, resp.Message)
+	}
+	return &SMSSendResult{MessageID: resp.BizID, Segments: 1}, nil
+}
+
+// aliyunPhoneNumber 中国大陆号码去掉 +86，其他地区去掉 + 保留国家码
+func aliyunPhoneNumber(phone string) string {
+	if strings.HasPrefix(phone, "+86") {
+		return strings.TrimPrefix(phone, "+86")
+	}
+	return strings.TrimPrefix(phone, "+")
+}
+
+// aliyunCanonicalQuery 按参数名排序并编码的查询串
+func aliyunCanonicalQuery(params map[string]string) string {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, aliyunPercentEncode(key)+"="+aliyunPercentEncode(params[key]))
+	}
+	return strings.Join(parts, "&")
+}
+
+// aliyunSignature HMAC-SHA1(AccessKeySecret&, Method&%2F&编码后的查询串)
+func aliyunSignature(secret, method, canonicalQuery string) string {
+	stringToSign := method + "&" + aliyunPercentEncode("/") + "&" + aliyunPercentEncode(canonicalQuery)
+	mac := hmac.New(sha1.New, []byte(secret+"&"))
+	mac.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// aliyunPercentEncode RFC 3986 编码，空格为 %20，保留 ~
+func aliyunPercentEncode(value string) string {
+	encoded := url.QueryEscape(value)
+	encoded = strings.ReplaceAll(encoded, "+", "%20")
+	encoded = strings.ReplaceAll(encoded, "*", "%2A")
+	return strings.ReplaceAll(encoded, "%7E", "~")
+}
+
+// doSMSRequest 发送请求并解析 JSON 响应，返回状态码；网络错误返回 ErrSMSSendFailed
+func doSMSRequest(client *http.Client, req *http.Request, out interface{}) (int, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrSMSSendFailed, err)
+	}
+	defer resp.Body.Close()
+
+	payload, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return resp.StatusCode, fmt.Errorf("%w: %v", ErrSMSSendFailed, err)
+	}
+	if err := json.Unmarshal(payload, out); err != nil {
+		return resp.StatusCode, fmt.Errorf("%w: provider returned %d: %s", ErrSMSSendFailed, resp.StatusCode, truncateString(string(payload), 200))
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxSortFields 一次排序最多的字段数
+const maxSortFields = 5
+
+// ErrInvalidSort 排序参数无效或字段不允许排序
+var ErrInvalidSort = errors.New("invalid sort")
+
+// SortRegistry 列表接口允许排序的字段：对外字段名 -> 数据库列。
+// 排序只能使用登记的列，列名由 GORM 引用后写入 ORDER BY，不拼接用户输入
+type SortRegistry struct {
+	columns  map[string]string
+	defaults []models.SortField
+}
+
+// NewSortRegistry 创建排序字段白名单，defaults 为未指定排序时的默认排序
+func NewSortRegistry(columns map[string]string, defaults ...models.SortField) *SortRegistry {
+	return &SortRegistry{columns: columns, defaults: defaults}
+}
+
+// Allows 字段是否允许排序
+func (r *SortRegistry) Allows(field string) bool {
+	_, ok := r.columns[field]
+	return ok
+}
+
+// Apply 按排序条件添加 ORDER BY；未指定时使用默认排序，字段不在白名单内返回 ErrInvalidSort
+func (r *SortRegistry) Apply(query *gorm.DB, fields []models.SortField) (*gorm.DB, error) {
+	if len(fields) == 0 {
+		fields = r.defaults
+	}
+	if len(fields) == 0 {
+		return query, nil
+	}
+	if len(fields) > maxSortFields {
+		return nil, fmt.Errorf("%w: at most %d sort fields", ErrInvalidSort, maxSortFields)
+	}
+
+	columns := make([]clause.OrderByColumn, 0, len(fields))
+	for _, field := range fields {
+		column, ok := r.columns[field.Field]
+		if !ok {
+			return nil, fmt.Errorf("%w: cannot sort by %q", ErrInvalidSort, field.Field)
+		}
+		switch field.Direction {
+		case models.SortAsc, models.SortDesc:
+		default:
+			return nil, fmt.Errorf("%w: invalid direction %q for %q", ErrInvalidSort, field.Direction, field.Field)
+		}
+		columns = append(columns, clause.OrderByColumn{
+			Column: clause.Column{Name: column},
+			Desc:   field.Direction == models.SortDesc,
+		})
+	}
+	return query.Order(clause.OrderBy{Columns: columns}), nil
+}
+
+// ParseSortDirection 解析排序方向（asc/desc，不区分大小写），为空时返回 fallback
+func ParseSortDirection(value string, fallback models.SortDirection) (models.SortDirection, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "":
+		return fallback, nil
+	case "asc":
+		return models.SortAsc, nil
+	case "desc":
+		return models.SortDesc, nil
+	default:
+		return "", fmt.Errorf("%w: direction must be asc or desc", ErrInvalidSort)
+	}
+}
+
+// ParseSort 解析多字段排序参数，如 "priority:desc,created_at:asc"，省略方向时为 desc；
+// 兼容旧的 ["created_at","DESC"] 格式。只检查格式，字段是否允许由各接口的 SortRegistry 判断
+func ParseSort(spec string) ([]models.SortField, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return nil, nil
+	}
+	if strings.HasPrefix(spec, "[") {
+		var pair []string
+		if err := json.Unmarshal([]byte(spec), &pair); err != nil || len(pair) != 2 {
+			return nil, fmt.Errorf("%w: malformed sort", ErrInvalidSort)
+		}
+		field, err := parseSortField(pair[0], pair[1])
+		if err != nil {
+			return nil, err
+		}
+		return []models.SortField{field}, nil
+	}
+
+	parts := strings.Split(spec, ",")
+	if len(parts) > maxSortFields {
+		return nil, fmt.Errorf("%w: at most %d sort fields", ErrInvalidSort, maxSortFields)
+	}
+	fields := make([]models.SortField, 0, len(parts))
+	seen := make(map[string]bool, len(parts))
+	for _, part := range parts {
+		name, direction, _ := strings.Cut(part, ":")
+		field, err := parseSortField(name, direction)
+		if err != nil {
+			return nil, err
+		}
+		if seen[field.Field] {
+			return nil, fmt.Errorf("%w: duplicate sort field %q", ErrInvalidSort, field.Field)
+		}
+		seen[field.Field] = true
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+// parseSortField 解析单个排序字段及方向
+func parseSortField(name, direction string) (models.SortField, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return models.SortField{}, fmt.Errorf("%w: empty sort field", ErrInvalidSort)
+	}
+	dir, err := ParseSortDirection(direction, models.SortDesc)
+	if err != nil {
+		return models.SortField{}, err
+	}
+	return models.SortField{Field: name, Direction: dir}, nil
+}
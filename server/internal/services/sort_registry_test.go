@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+func TestParseSort_MultiColumnLegacyAndMalicious(t *testing.T) {
+	fields, err := ParseSort(" priority:DESC , created_at:asc,id ")
+	want := []models.SortField{
+		{Field: "priority", Direction: models.SortDesc},
+		{Field: "created_at", Direction: models.SortAsc},
+		{Field: "id", Direction: models.SortDesc},
+	}
+	if err != nil || !reflect.DeepEqual(fields, want) {
+		t.Fatalf("unexpected sort %+v (%v)", fields, err)
+	}
+	if fields, err := ParseSort(`["created_at","ASC"]`); err != nil || len(fields) != 1 || fields[0].Direction != models.SortAsc {
+		t.Fatalf("expected legacy sort to parse, got %+v (%v)", fields, err)
+	}
+	if fields, err := ParseSort(""); err != nil || fields != nil {
+		t.Fatalf("expected empty sort, got %+v (%v)", fields, err)
+	}
+
+	for _, spec := range []string{
+		"created_at:desc; DROP TABLE tickets",
+		"created_at:desc--",
+		"created_at:(CASE WHEN 1=1 THEN 1 END)",
+		"created_at:asc:desc",
+		"priority,priority",
+		":asc",
+		"a,b,c,d,e,f",
+		`["created_at"]`,
+		`["created_at","desc; DROP TABLE tickets"]`,
+	} {
+		if _, err := ParseSort(spec); !errors.Is(err, ErrInvalidSort) {
+			t.Fatalf("expected %q to be rejected, got %v", spec, err)
+		}
+	}
+}
+
+func TestSortRegistry_OnlyWhitelistedColumns(t *testing.T) {
+	db := newTestDB(t, &models.User{}, &models.Ticket{}, &models.TicketComment{}, &models.Notification{}, &models.LoginHistory{})
+	ctx := context.Background()
+
+	// 字段名只作为白名单的键，写入 SQL 的是登记的列名并加引号
+	dry := db.Session(&gorm.Session{DryRun: true}).Model(&models.Ticket{})
+	query, err := ticketSortRegistry.Apply(dry, []models.SortField{{Field: "priority", Direction: models.SortDesc}, {Field: "created_at", Direction: models.SortAsc}})
+	if err != nil {
+		t.Fatalf("apply failed: %v", err)
+	}
+	sql := query.Find(&[]models.Ticket{}).Statement.SQL.String()
+	if !strings.Contains(sql, "ORDER BY `priority` DESC,`created_at`") {
+		t.Fatalf("unexpected order clause: %s", sql)
+	}
+
+	agent := models.User{Username: "sort-agent", Email: "sort-agent@example.com", PasswordHash: "x", Role: models.RoleAgent, Status: models.UserStatusActive}
+	db.Create(&agent)
+	base := time.Now().Add(-time.Hour)
+	for i, fixture := range []struct {
+		number   string
+		priority models.TicketPriority
+	}{{"SORT-1", models.TicketPriorityHigh}, {"SORT-2", models.TicketPriorityLow}, {"SORT-3", models.TicketPriorityHigh}} {
+		ticket := models.Ticket{TicketNumber: fixture.number, Title: fixture.number, Status: models.TicketStatusOpen, Priority: fixture.priority,
+			Type: models.TicketTypeIncident, Source: models.TicketSourceWeb, CreatedByID: agent.ID}
+		db.Create(&ticket)
+		db.Model(&ticket).UpdateColumn("created_at", base.Add(time.Duration(i)*time.Minute))
+	}
+
+	// 多字段排序：优先级升序，同优先级按创建时间倒序
+	svc := NewTicketService(db)
+	tickets, _, err := svc.GetTickets(ctx, TicketFilters{Page: 1, Limit: 10, Sort: []models.SortField{
+		{Field: "priority", Direction: models.SortAsc}, {Field: "created_at", Direction: models.SortDesc}}})
+	if err != nil {
+		t.Fatalf("get tickets failed: %v", err)
+	}
+	var numbers []string
+	for _, ticket := range tickets {
+		numbers = append(numbers, ticket.TicketNumber)
+	}
+	if strings.Join(numbers, ",") != "SORT-3,SORT-1,SORT-2" {
+		t.Fatalf("unexpected order %v", numbers)
+	}
+
+	malicious := []models.SortField{
+		{Field: "created_at; DROP TABLE tickets", Direction: models.SortDesc},
+		{Field: "password_hash", Direction: models.SortAsc},
+		{Field: "(SELECT 1)", Direction: models.SortAsc},
+	}
+	for _, field := range malicious {
+		if _, _, err := svc.GetTickets(ctx, TicketFilters{Sort: []models.SortField{field}}); !errors.Is(err, ErrInvalidSort) {
+			t.Fatalf("expected ticket sort %q to be rejected, got %v", field.Field, err)
+		}
+	}
+	if _, _, err := svc.GetTickets(ctx, TicketFilters{Sort: []models.SortField{{Field: "created_at", Direction: "desc; DROP TABLE tickets"}}}); !errors.Is(err, ErrInvalidSort) {
+		t.Fatalf("expected malicious direction to be rejected, got %v", err)
+	}
+
+	// 通知与登录历史的旧参数同样经过白名单
+	notifications := NewNotificationService(db)
+	if _, _, err := notifications.GetNotifications(ctx, &models.NotificationFilter{OrderBy: "created_at desc; DROP TABLE notifications"}); !errors.Is(err, ErrInvalidSort) {
+		t.Fatalf("expected notification sort to be rejected, got %v", err)
+	}
+	if _, _, err := notifications.GetNotifications(ctx, &models.NotificationFilter{OrderBy: "created_at", OrderDir: "desc, (SELECT 1)"}); !errors.Is(err, ErrInvalidSort) {
+		t.Fatalf("expected notification direction to be rejected, got %v", err)
+	}
+	if _, _, err := notifications.GetNotifications(ctx, &models.NotificationFilter{Sort: []models.SortField{
+		{Field: "is_read", Direction: models.SortAsc}, {Field: "created_at", Direction: models.SortDesc}}}); err != nil {
+		t.Fatalf("expected multi-column notification sort, got %v", err)
+	}
+	users := NewUserService(db)
+	if _, _, err := users.GetLoginHistory(ctx, agent.ID, &models.LoginHistoryRequest{OrderBy: "login_time; DELETE FROM users"}); !errors.Is(err, ErrInvalidSort) {
+		t.Fatalf("expected login history sort to be rejected, got %v", err)
+	}
+	if _, _, err := users.GetLoginHistory(ctx, agent.ID, &models.LoginHistoryRequest{Order: "asc", Page: 1, PageSize: 20}); err != nil {
+		t.Fatalf("expected default login history sort, got %v", err)
+	}
+
+	var count int64
+	if err := db.Model(&models.Ticket{}).Count(&count).Error; err != nil || count != 3 {
+		t.Fatalf("expected tickets table intact, got %d (%v)", count, err)
+	}
+}
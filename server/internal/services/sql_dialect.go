@@ -0,0 +1,116 @@
+package services
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 支持的数据库方言，取值与 gorm Dialector.Name() 一致
+const (
+	DialectPostgres = "postgres"
+	DialectSQLite   = "sqlite"
+)
+
+// SQLDialect 生成不同数据库下写法不同的 SQL 片段。
+// 生产环境使用 PostgreSQL，本地开发及测试可使用 SQLite；
+// 服务中需要方言相关语法（ILIKE、时间运算、日期截取）时统一通过此处生成
+type SQLDialect struct {
+	name string
+}
+
+// DialectOf 获取数据库连接对应的方言
+func DialectOf(db *gorm.DB) SQLDialect {
+	return SQLDialect{name: db.Dialector.Name()}
+}
+
+// Name 方言名称
+func (d SQLDialect) Name() string {
+	return d.name
+}
+
+// IsPostgres 是否为 PostgreSQL
+func (d SQLDialect) IsPostgres() bool {
+	return d.name == DialectPostgres
+}
+
+// ILike 不区分大小写的模糊匹配条件，参数为一个 ? 占位符
+func (d SQLDialect) ILike(column string) string {
+	if d.IsPostgres() {
+		return column + " ILIKE ?"
+	}
+	return "LOWER(" + column + ") LIKE LOWER(?)"
+}
+
+// AnyILike 多列任一匹配的条件，每列占用一个 ? 占位符
+func (d SQLDialect) AnyILike(columns ...string) string {
+	conds := make([]string, len(columns))
+	for i, column := range columns {
+		conds[i] = d.ILike(column)
+	}
+	return strings.Join(conds, " OR ")
+}
+
+// EpochSeconds 时间表达式对应的 Unix 秒数（浮点）
+func (d SQLDialect) EpochSeconds(expr string) string {
+	if d.IsPostgres() {
+		return "EXTRACT(EPOCH FROM " + expr + ")"
+	}
+	return fmt.Sprintf("((julianday(%s) - 2440587.5) * 86400.0)", expr)
+}
+
+// HoursBetween 两个时间表达式相差的小时数（浮点）
+func (d SQLDialect) HoursBetween(start, end string) string {
+	return fmt.Sprintf("((%s - %s) / 3600.0)", d.EpochSeconds(end), d.EpochSeconds(start))
+}
+
+// Date 截取日期，按日分组时使用；结果应扫描到 SQLDate
+func (d SQLDialect) Date(expr string) string {
+	if d.IsPostgres() {
+		return "DATE(" + expr + ")"
+	}
+	// SQLite 的 DATE() 按 UTC 截取，换算为本地时区与 PostgreSQL 会话时区保持一致
+	return "DATE(" + expr + ", 'localtime')"
+}
+
+// SQLDate 按日分组结果中的日期：PostgreSQL 返回 time.Time，SQLite 返回 YYYY-MM-DD 字符串
+type SQLDate struct {
+	time.Time
+}
+
+// Scan 实现 sql.Scanner
+func (d *SQLDate) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		d.Time = time.Time{}
+		return nil
+	case time.Time:
+		d.Time = v
+		return nil
+	case []byte:
+		return d.parse(string(v))
+	case string:
+		return d.parse(v)
+	}
+	return fmt.Errorf("unsupported date value %T", value)
+}
+
+// Value 实现 driver.Valuer
+func (d SQLDate) Value() (driver.Value, error) {
+	return d.Time, nil
+}
+
+func (d *SQLDate) parse(value string) error {
+	if len(value) > len("2006-01-02") {
+		value = value[:len("2006-01-02")]
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return fmt.Errorf("invalid date value %q: %w", value, err)
+	}
+	d.Time = t
+	return nil
+}
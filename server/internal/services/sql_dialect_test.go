@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+)
+
+func TestSQLDialect_Fragments(t *testing.T) {
+	pg := SQLDialect{name: DialectPostgres}
+	if got := pg.AnyILike("title", "content"); got != "title ILIKE ? OR content ILIKE ?" {
+		t.Fatalf("unexpected postgres ilike %q", got)
+	}
+	if got := pg.HoursBetween("created_at", "updated_at"); got != "((EXTRACT(EPOCH FROM updated_at) - EXTRACT(EPOCH FROM created_at)) / 3600.0)" {
+		t.Fatalf("unexpected postgres hours %q", got)
+	}
+	lite := SQLDialect{name: DialectSQLite}
+	if got := lite.ILike("title"); got != "LOWER(title) LIKE LOWER(?)" {
+		t.Fatalf("unexpected sqlite ilike %q", got)
+	}
+
+	var d SQLDate
+	if err := d.Scan("2026-03-04"); err != nil || d.Format("2006-01-02") != "2026-03-04" {
+		t.Fatalf("unexpected date %v, %v", d, err)
+	}
+	if err := d.Scan(42); err == nil {
+		t.Fatalf("expected unsupported value to be rejected")
+	}
+}
+
+func TestSQLDialect_QueriesRunOnSQLite(t *testing.T) {
+	db := newTestDB(t, &models.User{}, &models.Category{}, &models.Ticket{}, &models.TicketComment{}, &models.LoginHistory{},
+		&models.Notification{}, &models.AdminAuditLog{})
+	ctx := context.Background()
+
+	user := models.User{Username: "dialect", Email: "dialect@example.com", PasswordHash: "x", Role: models.RoleAgent, Status: models.UserStatusActive}
+	db.Create(&user)
+
+	// 不区分大小写的关键字过滤
+	db.Create(&models.Notification{Type: models.NotificationTypeSystemAlert, Title: "Disk FULL", Content: "x", RecipientID: user.ID})
+	notifications, total, err := NewNotificationService(db).GetNotifications(ctx, &models.NotificationFilter{RecipientID: &user.ID, Query: "disk full", Limit: 10})
+	if err != nil || total != 1 || len(notifications) != 1 {
+		t.Fatalf("expected case-insensitive notification match, got %d, %v", total, err)
+	}
+	db.Create(&models.AdminAuditLog{UserID: &user.ID, Username: "Dialect", Method: "POST", Path: "/api/admin/users", Action: "create"})
+	logs, total, err := NewAdminAuditService(db).List(ctx, &AdminAuditFilter{Keyword: "DIALECT", Page: 1, Limit: 10})
+	if err != nil || total != 1 || len(logs) != 1 {
+		t.Fatalf("expected case-insensitive audit match, got %d, %v", total, err)
+	}
+
+	// SLA 违约：创建时间加分类 SLA 时长早于当前时间且未解决
+	sla := 4
+	category := models.Category{Name: "网络", SLAHours: &sla}
+	db.Create(&category)
+	now := time.Now()
+	seed := func(number string, age time.Duration, status models.TicketStatus) {
+		db.Create(&models.Ticket{TicketNumber: number, Title: number, Status: status, Priority: models.TicketPriorityNormal,
+			Type: models.TicketTypeIncident, Source: models.TicketSourceWeb, CreatedByID: user.ID, CategoryID: &category.ID,
+			CreatedAt: now.Add(-age), UpdatedAt: now.Add(-age).Add(3 * time.Hour)})
+	}
+	seed("SLA-1", 5*time.Hour, models.TicketStatusOpen)
+	seed("SLA-2", 3*time.Hour, models.TicketStatusOpen)
+	seed("SLA-3", 6*time.Hour, models.TicketStatusResolved)
+	tickets, total, err := NewTicketService(db).GetSLABreachedTickets(user.ID, "admin")
+	if err != nil || total != 1 || len(tickets) != 1 || tickets[0].TicketNumber != "SLA-1" {
+		t.Fatalf("expected only SLA-1 to breach, got %d, %v", total, err)
+	}
+
+	// 统计：平均处理时长及按日趋势
+	analytics := NewAnalyticsService(db)
+	stats, err := analytics.GetBusinessStats(ctx)
+	if err != nil || math.Abs(stats.TicketStats.AvgResolutionTime-3) > 0.01 {
+		t.Fatalf("expected 3 hour resolution time, got %+v, %v", stats, err)
+	}
+	rangeStats, err := analytics.GetTimeRangeStats(ctx, now.Add(-24*time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("time range stats failed: %v", err)
+	}
+	var counted int64
+	for _, day := range rangeStats.TicketTrend {
+		if day.Date.IsZero() {
+			t.Fatalf("expected trend dates to be parsed, got %+v", rangeStats.TicketTrend)
+		}
+		counted += day.Count
+	}
+	if counted != 3 {
+		t.Fatalf("expected 3 tickets in trend, got %+v", rangeStats.TicketTrend)
+	}
+}
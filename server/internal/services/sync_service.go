@@ -0,0 +1,345 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// syncSafetyWindow 只返回早于该时长之前变更的记录，避免遗漏刚提交、时间戳略早的事务
+	syncSafetyWindow = 2 * time.Second
+	// syncTombstoneRetention 删除记录保留时长，游标早于该时长时需要全量同步
+	syncTombstoneRetention = 30 * 24 * time.Hour
+	syncDefaultLimit       = 100
+	syncMaxLimit           = 500
+)
+
+// 同步范围
+const (
+	SyncScopeMine = "mine" // 指派给我、我创建或我所在团队队列中未认领的工单
+	SyncScopeAll  = "all"  // 全部工单
+)
+
+var (
+	// ErrInvalidSyncCursor 同步游标无效
+	ErrInvalidSyncCursor = errors.New("invalid sync cursor")
+	// ErrSyncCursorExpired 同步游标过期，删除记录可能已被清理
+	ErrSyncCursorExpired = errors.New("sync cursor expired")
+)
+
+// syncReassignActions 可能使工单离开“我的”同步范围的操作
+var syncReassignActions = []models.HistoryAction{
+	models.HistoryActionAssign,
+	models.HistoryActionUnassign,
+	models.HistoryActionTransfer,
+	models.HistoryActionEscalate,
+}
+
+// SyncRequest 增量同步请求
+type SyncRequest struct {
+	Cursor string     // 上次同步返回的 next_cursor，优先于 Since
+	Since  *time.Time // 未提供游标时从该时间开始；两者均为空时全量同步
+	Scope  string
+	Limit  int // 每类实体的最大条数
+}
+
+// syncPosition 按 (时间, ID) 排序的同步位置
+type syncPosition struct {
+	At time.Time `json:"at"`
+	ID uint      `json:"id"`
+}
+
+// syncCursor 各类实体的同步位置，编码后作为不透明游标返回
+type syncCursor struct {
+	Scope         string       `json:"s"`
+	IssuedAt      time.Time    `json:"i"`
+	Tickets       syncPosition `json:"t"`
+	Comments      syncPosition `json:"c"`
+	Notifications syncPosition `json:"n"`
+	Tombstones    syncPosition `json:"d"`
+	Reassignments syncPosition `json:"r"` // 工单改派历史
+}
+
+func (c *syncCursor) encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeSyncCursor(value string) (*syncCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidSyncCursor
+	}
+	var cursor syncCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, ErrInvalidSyncCursor
+	}
+	return &cursor, nil
+}
+
+// SyncService 移动端增量同步：返回自上次同步以来变更的工单、评论、通知及需要删除的记录
+type SyncService struct {
+	db  *gorm.DB
+	now func() time.Time
+}
+
+// NewSyncService 创建增量同步服务
+func NewSyncService(db *gorm.DB) *SyncService {
+	return &SyncService{db: db, now: time.Now}
+}
+
+// Sync 返回游标之后的变更，每类实体最多 limit 条，has_more 时应继续用 next_cursor 拉取
+func (s *SyncService) Sync(ctx context.Context, viewer CommentViewer, req *SyncRequest) (*models.SyncResponse, error) {
+	now := s.now()
+	limit := req.Limit
+	if limit < 1 {
+		limit = syncDefaultLimit
+	}
+	if limit > syncMaxLimit {
+		limit = syncMaxLimit
+	}
+
+	scope := req.Scope
+	if scope == "" {
+		scope = SyncScopeMine
+	}
+	if scope != SyncScopeMine && scope != SyncScopeAll {
+		return nil, fmt.Errorf("%w: scope must be mine or all", ErrInvalidSyncCursor)
+	}
+
+	cursor := &syncCursor{Scope: scope}
+	switch {
+	case req.Cursor != "":
+		decoded, err := decodeSyncCursor(req.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if decoded.Scope != scope {
+			return nil, fmt.Errorf("%w: cursor was issued for scope %q", ErrInvalidSyncCursor, decoded.Scope)
+		}
+		if decoded.IssuedAt.Before(now.Add(-syncTombstoneRetention)) {
+			return nil, ErrSyncCursorExpired
+		}
+		cursor = decoded
+	case req.Since != nil:
+		if req.Since.Before(now.Add(-syncTombstoneRetention)) {
+			return nil, ErrSyncCursorExpired
+		}
+		position := syncPosition{At: *req.Since}
+		cursor.Tickets, cursor.Comments, cursor.Notifications = position, position, position
+		cursor.Tombstones, cursor.Reassignments = position, position
+	default:
+		// 全量同步：本地没有数据，无需此前的删除与改派记录
+		start := syncPosition{At: now.Add(-syncSafetyWindow)}
+		cursor.Tombstones, cursor.Reassignments = start, start
+	}
+	incremental := req.Cursor != "" || req.Since != nil
+	until := now.Add(-syncSafetyWindow)
+
+	teamIDs, err := s.teamIDs(ctx, viewer.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &models.SyncResponse{
+		Tickets:       []models.SyncTicket{},
+		Comments:      []models.SyncComment{},
+		Notifications: []models.SyncNotification{},
+		Deleted:       []models.SyncDeletion{},
+		ServerTime:    now,
+	}
+	db := s.db.WithContext(ctx)
+	inScope := func() *gorm.DB {
+		query := db.Model(&models.Ticket{})
+		if scope == SyncScopeMine {
+			query = query.Where("(assigned_to_id = ? OR created_by_id = ? OR (assigned_to_id IS NULL AND assigned_team_id IN ?))",
+				viewer.UserID, viewer.UserID, append(teamIDs, 0))
+		}
+		return query
+	}
+
+	// 工单
+	var tickets []models.Ticket
+	if err := syncAfter(inScope(), "updated_at", cursor.Tickets, until).Limit(limit + 1).Find(&tickets).Error; err != nil {
+		return nil, fmt.Errorf("failed to sync tickets: %w", err)
+	}
+	if len(tickets) > limit {
+		tickets, resp.HasMore = tickets[:limit], true
+	}
+	for _, t := range tickets {
+		resp.Tickets = append(resp.Tickets, models.SyncTicket{
+			ID: t.ID, TicketNumber: t.TicketNumber, Title: t.Title, Status: t.Status, Priority: t.Priority,
+			AssignedToID: t.AssignedToID, AssignedTeamID: t.AssignedTeamID, CreatedByID: t.CreatedByID,
+			CustomerName: t.CustomerName, DueDate: t.DueDate, SLABreached: t.SLABreached,
+			CreatedAt: t.CreatedAt, UpdatedAt: t.UpdatedAt,
+		})
+		cursor.Tickets = syncPosition{At: t.UpdatedAt, ID: t.ID}
+	}
+
+	// 改派后不再属于“我的”范围的工单
+	if scope == SyncScopeMine {
+		var histories []models.TicketHistory
+		historyQuery := db.Model(&models.TicketHistory{}).Select("id", "ticket_id", "created_at").Where("action IN ?", syncReassignActions)
+		if err := syncAfter(historyQuery, "created_at", cursor.Reassignments, until).Limit(limit + 1).Find(&histories).Error; err != nil {
+			return nil, fmt.Errorf("failed to sync reassigned tickets: %w", err)
+		}
+		if len(histories) > limit {
+			histories, resp.HasMore = histories[:limit], true
+		}
+		if len(histories) > 0 {
+			cursor.Reassignments = syncPosition{At: histories[len(histories)-1].CreatedAt, ID: histories[len(histories)-1].ID}
+		}
+		if incremental && len(histories) > 0 {
+			ticketIDs := make([]uint, 0, len(histories))
+			for _, h := range histories {
+				ticketIDs = append(ticketIDs, h.TicketID)
+			}
+			var stillMine []uint
+			if err := inScope().Where("id IN ?", ticketIDs).Pluck("id", &stillMine).Error; err != nil {
+				return nil, fmt.Errorf("failed to sync reassigned tickets: %w", err)
+			}
+			mine := make(map[uint]bool, len(stillMine))
+			for _, id := range stillMine {
+				mine[id] = true
+			}
+			for _, id := range ticketIDs {
+				if !mine[id] {
+					mine[id] = true // 同一工单只返回一次
+					resp.Deleted = append(resp.Deleted, models.SyncDeletion{Type: models.SyncEntityTicket, ID: id, Reason: "out_of_scope"})
+				}
+			}
+		}
+	}
+
+	// 评论：已删除或对当前用户不可见的评论通知客户端删除
+	commentQuery := db.Model(&models.TicketComment{})
+	if scope == SyncScopeMine {
+		commentQuery = commentQuery.Where("ticket_id IN (?)", inScope().Select("id"))
+	}
+	var comments []models.TicketComment
+	if err := syncAfter(commentQuery, "updated_at", cursor.Comments, until).Limit(limit + 1).Find(&comments).Error; err != nil {
+		return nil, fmt.Errorf("failed to sync comments: %w", err)
+	}
+	if len(comments) > limit {
+		comments, resp.HasMore = comments[:limit], true
+	}
+	for _, c := range comments {
+		cursor.Comments = syncPosition{At: c.UpdatedAt, ID: c.ID}
+		switch {
+		case c.IsDeleted || c.DeletedAt != nil:
+			resp.Deleted = append(resp.Deleted, models.SyncDeletion{Type: models.SyncEntityComment, ID: c.ID, Reason: "deleted"})
+		case !syncCommentVisible(&c, viewer, teamIDs):
+			resp.Deleted = append(resp.Deleted, models.SyncDeletion{Type: models.SyncEntityComment, ID: c.ID, Reason: "hidden"})
+		default:
+			resp.Comments = append(resp.Comments, models.SyncComment{
+				ID: c.ID, TicketID: c.TicketID, UserID: c.UserID, Content: c.Content, ContentType: c.ContentType,
+				Visibility: c.Visibility, CreatedAt: c.CreatedAt, UpdatedAt: c.UpdatedAt,
+			})
+		}
+	}
+
+	// 站内通知
+	var notifications []models.Notification
+	notificationQuery := db.Model(&models.Notification{}).
+		Where("recipient_id = ? AND channel = ?", viewer.UserID, models.NotificationChannelInApp)
+	if err := syncAfter(notificationQuery, "updated_at", cursor.Notifications, until).Limit(limit + 1).Find(&notifications).Error; err != nil {
+		return nil, fmt.Errorf("failed to sync notifications: %w", err)
+	}
+	if len(notifications) > limit {
+		notifications, resp.HasMore = notifications[:limit], true
+	}
+	for _, n := range notifications {
+		resp.Notifications = append(resp.Notifications, models.SyncNotification{
+			ID: n.ID, Type: n.Type, Title: n.Title, Content: n.Content, Priority: n.Priority, IsRead: n.IsRead,
+			RelatedTicketID: n.RelatedTicketID, ActionURL: n.ActionURL, CreatedAt: n.CreatedAt, UpdatedAt: n.UpdatedAt,
+		})
+		cursor.Notifications = syncPosition{At: n.UpdatedAt, ID: n.ID}
+	}
+
+	// 物理删除的工单与通知
+	var tombstones []models.SyncTombstone
+	tombstoneQuery := db.Model(&models.SyncTombstone{}).Where("user_id IS NULL OR user_id = ?", viewer.UserID)
+	if err := syncAfter(tombstoneQuery, "created_at", cursor.Tombstones, until).Limit(limit + 1).Find(&tombstones).Error; err != nil {
+		return nil, fmt.Errorf("failed to sync deletions: %w", err)
+	}
+	if len(tombstones) > limit {
+		tombstones, resp.HasMore = tombstones[:limit], true
+	}
+	for _, t := range tombstones {
+		resp.Deleted = append(resp.Deleted, models.SyncDeletion{Type: t.EntityType, ID: t.EntityID, Reason: "deleted"})
+		cursor.Tombstones = syncPosition{At: t.CreatedAt, ID: t.ID}
+	}
+
+	cursor.IssuedAt = now
+	resp.NextCursor = cursor.encode()
+	return resp, nil
+}
+
+// PurgeTombstones 删除超过保留时长的删除记录，返回删除条数
+func (s *SyncService) PurgeTombstones(ctx context.Context, now time.Time) (int64, error) {
+	result := s.db.WithContext(ctx).Where("created_at < ?", now.Add(-syncTombstoneRetention)).Delete(&models.SyncTombstone{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to purge sync tombstones: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+func (s *SyncService) teamIDs(ctx context.Context, userID uint) ([]uint, error) {
+	var teamIDs []uint
+	if err := s.db.WithContext(ctx).Table("team_members").Where("user_id = ?", userID).Pluck("team_id", &teamIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to get user teams: %w", err)
+	}
+	return teamIDs, nil
+}
+
+// syncAfter 取 position 之后、until 之前变更的记录，按 (column, id) 升序
+func syncAfter(query *gorm.DB, column string, position syncPosition, until time.Time) *gorm.DB {
+	if !position.At.IsZero() {
+		query = query.Where(fmt.Sprintf("(%[1]s > ? OR (%[1]s = ? AND id > ?))", column), position.At, position.At, position.ID)
+	}
+	return query.Where(column+" <= ?", until).Order(column + " ASC, id ASC")
+}
+
+// syncCommentVisible 与 commentVisibleToUser 规则一致，使用预先查询的团队避免逐条查询
+func syncCommentVisible(comment *models.TicketComment, viewer CommentViewer, teamIDs []uint) bool {
+	if comment.IsCustomerVisible() {
+		return true
+	}
+	if viewer.IsCustomer() {
+		return false
+	}
+	if comment.Visibility != models.CommentVisibilityTeam || viewer.seesAllTeams() || comment.UserID == viewer.UserID {
+		return true
+	}
+	if comment.VisibleTeamID == nil {
+		return false
+	}
+	for _, id := range teamIDs {
+		if id == *comment.VisibleTeamID {
+			return true
+		}
+	}
+	return false
+}
+
+// recordSyncTombstones 记录物理删除，userID 为空时对所有用户同步
+func recordSyncTombstones(tx *gorm.DB, entityType models.SyncEntity, userID *uint, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	tombstones := make([]models.SyncTombstone, 0, len(ids))
+	for _, id := range ids {
+		tombstones = append(tombstones, models.SyncTombstone{EntityType: entityType, EntityID: id, UserID: userID})
+	}
+	if err := tx.CreateInBatches(&tombstones, 500).Error; err != nil {
+		return fmt.Errorf("failed to record sync tombstones: %w", err)
+	}
+	return nil
+}
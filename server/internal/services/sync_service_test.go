@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestSyncService_DeltasAndDeletions(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:sync_service_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Team{}, &models.Ticket{}, &models.TicketComment{}, &models.TicketHistory{},
+		&models.Notification{}, &models.SyncTombstone{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+
+	ctx := context.Background()
+	svc := NewSyncService(db)
+	earlier := time.Now().Add(-time.Hour)
+
+	me := models.User{Username: "sync-me", Email: "sync-me@example.com", PasswordHash: "x", Role: models.RoleAgent, Status: models.UserStatusActive}
+	other := models.User{Username: "sync-other", Email: "sync-other@example.com", PasswordHash: "x", Role: models.RoleAgent, Status: models.UserStatusActive}
+	for _, u := range []*models.User{&me, &other} {
+		if err := db.Create(u).Error; err != nil {
+			t.Fatalf("failed to seed user: %v", err)
+		}
+	}
+	myTeam := models.Team{Name: "Sync L1", Slug: "sync-l1", IsActive: true, Members: []models.User{me}}
+	otherTeam := models.Team{Name: "Sync L2", Slug: "sync-l2", IsActive: true, Members: []models.User{other}}
+	db.Create(&myTeam)
+	db.Create(&otherTeam)
+
+	newTicket := func(number string, assignee, team *uint) *models.Ticket {
+		ticket := &models.Ticket{TicketNumber: number, Title: number, Status: models.TicketStatusOpen, Priority: models.TicketPriorityNormal,
+			Type: models.TicketTypeRequest, Source: models.TicketSourceWeb, CreatedByID: other.ID, AssignedToID: assignee, AssignedTeamID: team,
+			CreatedAt: earlier, UpdatedAt: earlier}
+		if err := db.Create(ticket).Error; err != nil {
+			t.Fatalf("failed to seed ticket: %v", err)
+		}
+		return ticket
+	}
+	mine := newTicket("SY-1", &me.ID, nil)
+	queued := newTicket("SY-2", nil, &myTeam.ID)
+	newTicket("SY-3", &other.ID, nil)
+	newTicket("SY-4", nil, &otherTeam.ID)
+
+	newComment := func(visibility models.CommentVisibility, teamID *uint) *models.TicketComment {
+		comment := &models.TicketComment{TicketID: mine.ID, UserID: other.ID, Content: "c", Type: models.CommentTypePublic,
+			Visibility: visibility, VisibleTeamID: teamID, CreatedAt: earlier, UpdatedAt: earlier}
+		if visibility != models.CommentVisibilityPublic {
+			comment.Type = models.CommentTypeInternal
+		}
+		db.Create(comment)
+		return comment
+	}
+	public := newComment(models.CommentVisibilityPublic, nil)
+	newComment(models.CommentVisibilityInternal, nil)
+	hidden := newComment(models.CommentVisibilityTeam, &otherTeam.ID)
+
+	inApp := models.Notification{Type: models.NotificationTypeTicketAssigned, Title: "分配", Content: "x", Channel: models.NotificationChannelInApp,
+		RecipientID: me.ID, CreatedAt: earlier, UpdatedAt: earlier}
+	email := models.Notification{Type: models.NotificationTypeTicketAssigned, Title: "分配", Content: "x", Channel: models.NotificationChannelEmail,
+		RecipientID: me.ID, CreatedAt: earlier, UpdatedAt: earlier}
+	db.Create(&inApp)
+	db.Create(&email)
+
+	viewer := CommentViewer{UserID: me.ID, Role: string(models.RoleAgent)}
+
+	// 全量同步分页拉取（时钟回拨到变更发生之前）
+	svc.now = func() time.Time { return earlier.Add(30 * time.Minute) }
+	var cursor string
+	tickets := map[uint]bool{}
+	var comments, notifications int
+	var deleted []models.SyncDeletion
+	for page := 0; page < 10; page++ {
+		resp, err := svc.Sync(ctx, viewer, &SyncRequest{Cursor: cursor, Limit: 1})
+		if err != nil {
+			t.Fatalf("full sync failed: %v", err)
+		}
+		for _, ticket := range resp.Tickets {
+			tickets[ticket.ID] = true
+		}
+		comments += len(resp.Comments)
+		notifications += len(resp.Notifications)
+		deleted = append(deleted, resp.Deleted...)
+		cursor = resp.NextCursor
+		if !resp.HasMore {
+			break
+		}
+	}
+	if len(tickets) != 2 || !tickets[mine.ID] || !tickets[queued.ID] {
+		t.Fatalf("expected my ticket and team queue ticket, got %v", tickets)
+	}
+	if comments != 2 || notifications != 1 {
+		t.Fatalf("expected 2 visible comments and 1 in-app notification, got %d/%d", comments, notifications)
+	}
+	if len(deleted) != 1 || deleted[0] != (models.SyncDeletion{Type: models.SyncEntityComment, ID: hidden.ID, Reason: "hidden"}) {
+		t.Fatalf("expected other team's comment to be hidden, got %+v", deleted)
+	}
+
+	// 无变化时返回空增量
+	resp, err := svc.Sync(ctx, viewer, &SyncRequest{Cursor: cursor})
+	if err != nil || len(resp.Tickets)+len(resp.Comments)+len(resp.Notifications)+len(resp.Deleted) != 0 {
+		t.Fatalf("expected empty delta, got %+v (%v)", resp, err)
+	}
+
+	svc.now = time.Now
+
+	// 变更：更新我的工单、队列工单改派给他人、删除评论、清理通知
+	changed := time.Now().Add(-time.Minute)
+	db.Model(&models.Ticket{}).Where("id = ?", mine.ID).Updates(map[string]interface{}{"title": "updated", "updated_at": changed})
+	db.Model(&models.Ticket{}).Where("id = ?", queued.ID).Updates(map[string]interface{}{"assigned_to_id": other.ID, "updated_at": changed})
+	db.Create(&models.TicketHistory{TicketID: queued.ID, Action: models.HistoryActionAssign, CreatedAt: changed})
+	db.Model(&models.TicketComment{}).Where("id = ?", public.ID).Updates(map[string]interface{}{"is_deleted": true, "updated_at": changed})
+	if _, err := NewNotificationService(db).ClearNotifications(ctx, me.ID, nil); err != nil {
+		t.Fatalf("clear notifications failed: %v", err)
+	}
+	db.Model(&models.SyncTombstone{}).Where("1 = 1").Update("created_at", changed)
+
+	resp, err = svc.Sync(ctx, viewer, &SyncRequest{Cursor: cursor})
+	if err != nil {
+		t.Fatalf("incremental sync failed: %v", err)
+	}
+	if len(resp.Tickets) != 1 || resp.Tickets[0].Title != "updated" {
+		t.Fatalf("expected updated ticket, got %+v", resp.Tickets)
+	}
+	want := map[models.SyncDeletion]bool{
+		{Type: models.SyncEntityTicket, ID: queued.ID, Reason: "out_of_scope"}: true,
+		{Type: models.SyncEntityComment, ID: public.ID, Reason: "deleted"}:     true,
+		{Type: models.SyncEntityNotification, ID: inApp.ID, Reason: "deleted"}: true,
+		{Type: models.SyncEntityNotification, ID: email.ID, Reason: "deleted"}: true,
+	}
+	for _, d := range resp.Deleted {
+		if !want[d] {
+			t.Fatalf("unexpected deletion %+v", d)
+		}
+		delete(want, d)
+	}
+	if len(want) != 0 {
+		t.Fatalf("missing deletions %+v", want)
+	}
+
+	// 同一游标重复拉取结果一致
+	again, err := svc.Sync(ctx, viewer, &SyncRequest{Cursor: cursor})
+	if err != nil || len(again.Deleted) != len(resp.Deleted) || len(again.Tickets) != 1 {
+		t.Fatalf("expected repeatable delta for the same cursor, got %+v (%v)", again, err)
+	}
+
+	if _, err := svc.Sync(ctx, viewer, &SyncRequest{Cursor: "not-a-cursor"}); !errors.Is(err, ErrInvalidSyncCursor) {
+		t.Fatalf("expected invalid cursor error, got %v", err)
+	}
+	if _, err := svc.Sync(ctx, viewer, &SyncRequest{Cursor: cursor, Scope: SyncScopeAll}); !errors.Is(err, ErrInvalidSyncCursor) {
+		t.Fatalf("expected scope mismatch error, got %v", err)
+	}
+	old := time.Now().AddDate(0, 0, -40)
+	if _, err := svc.Sync(ctx, viewer, &SyncRequest{Since: &old}); !errors.Is(err, ErrSyncCursorExpired) {
+		t.Fatalf("expected expired cursor error, got %v", err)
+	}
+}
@@ -0,0 +1,310 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrTeamNotFound 团队不存在或已停用
+	ErrTeamNotFound = errors.New("team not found")
+	// ErrInvalidTeamSlug 团队标识不合法
+	ErrInvalidTeamSlug = errors.New("invalid team slug: use lowercase letters, digits, '-' or '_'")
+	// ErrNotTeamMember 调用者不是工单所属团队的成员
+	ErrNotTeamMember = errors.New("user is not a member of the ticket's team")
+	// ErrTicketAlreadyClaimed 工单已被分配，无法认领
+	ErrTicketAlreadyClaimed = errors.New("ticket is already assigned")
+)
+
+// closedTicketStatuses 不计入队列的已完结状态
+var closedTicketStatuses = []models.TicketStatus{
+	models.TicketStatusResolved,
+	models.TicketStatusClosed,
+	models.TicketStatusCancelled,
+}
+
+// isClosedTicketStatus 判断工单状态是否为已完结状态
+func isClosedTicketStatus(status models.TicketStatus) bool {
+	for _, closed := range closedTicketStatuses {
+		if status == closed {
+			return true
+		}
+	}
+	return false
+}
+
+// TeamService 团队及团队队列服务
+type TeamService struct {
+	db                  *gorm.DB
+	notificationService NotificationServiceInterface
+}
+
+// NewTeamService 创建团队服务
+func NewTeamService(db *gorm.DB) *TeamService {
+	return &TeamService{
+		db:                  db,
+		notificationService: NewNotificationService(db),
+	}
+}
+
+// ListTeams 获取团队列表
+func (s *TeamService) ListTeams(ctx context.Context, includeInactive bool) ([]*models.Team, error) {
+	var teams []*models.Team
+	query := s.db.WithContext(ctx).Preload("Members").Order("name ASC")
+	if !includeInactive {
+		query = query.Where("is_active = ?", true)
+	}
+	if err := query.Find(&teams).Error; err != nil {
+		return nil, fmt.Errorf("failed to list teams: %w", err)
+	}
+	return teams, nil
+}
+
+// GetTeam 获取团队详情
+func (s *TeamService) GetTeam(ctx context.Context, id uint) (*models.Team, error) {
+	var team models.Team
+	if err := s.db.WithContext(ctx).Preload("Members").First(&team, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTeamNotFound
+		}
+		return nil, fmt.Errorf("failed to get team: %w", err)
+	}
+	return &team, nil
+}
+
+// CreateTeam 创建团队
+func (s *TeamService) CreateTeam(ctx context.Context, req *models.TeamCreateRequest) (*models.Team, error) {
+	if !models.IsValidTeamSlug(req.Slug) {
+		return nil, ErrInvalidTeamSlug
+	}
+
+	team := &models.Team{
+		Name:        req.Name,
+		Slug:        req.Slug,
+		Description: req.Description,
+		IsActive:    true,
+		LeadID:      req.LeadID,
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(team).Error; err != nil {
+			return fmt.Errorf("failed to create team: %w", err)
+		}
+		if len(req.MemberIDs) > 0 {
+			var members []models.User
+			if err := tx.Where("id IN ?", req.MemberIDs).Find(&members).Error; err != nil {
+				return fmt.Errorf("failed to load members: %w", err)
+			}
+			if err := tx.Model(team).Association("Members").Append(&members); err != nil {
+				return fmt.Errorf("failed to add members: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetTeam(ctx, team.ID)
+}
+
+// UpdateTeam 更新团队
+func (s *TeamService) UpdateTeam(ctx context.Context, id uint, req *models.TeamUpdateRequest) (*models.Team, error) {
+	team, err := s.GetTeam(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := map[string]interface{}{}
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+	if req.Slug != nil {
+		if !models.IsValidTeamSlug(*req.Slug) {
+			return nil, ErrInvalidTeamSlug
+		}
+		updates["slug"] = *req.Slug
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.IsActive != nil {
+		updates["is_active"] = *req.IsActive
+	}
+	if req.LeadID != nil {
+		if *req.LeadID == 0 {
+			updates["lead_id"] = nil
+		} else {
+			updates["lead_id"] = *req.LeadID
+		}
+	}
+
+	if len(updates) > 0 {
+		if err := s.db.WithContext(ctx).Model(team).Updates(updates).Error; err != nil {
+			return nil, fmt.Errorf("failed to update team: %w", err)
+		}
+	}
+
+	return s.GetTeam(ctx, id)
+}
+
+// DeleteTeam 删除团队，队列中的工单移出团队队列
+func (s *TeamService) DeleteTeam(ctx context.Context, id uint) error {
+	team, err := s.GetTeam(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Ticket{}).Where("assigned_team_id = ?", id).
+			Update("assigned_team_id", nil).Error; err != nil {
+			return fmt.Errorf("failed to detach tickets: %w", err)
+		}
+		if err := tx.Model(team).Association("Members").Clear(); err != nil {
+			return fmt.Errorf("failed to clear members: %w", err)
+		}
+		if err := tx.Delete(team).Error; err != nil {
+			return fmt.Errorf("failed to delete team: %w", err)
+		}
+		return nil
+	})
+}
+
+// AddMember 添加团队成员
+func (s *TeamService) AddMember(ctx context.Context, teamID, userID uint) error {
+	team, err := s.GetTeam(ctx, teamID)
+	if err != nil {
+		return err
+	}
+
+	var user models.User
+	if err := s.db.WithContext(ctx).First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("user not found")
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if err := s.db.WithContext(ctx).Model(team).Association("Members").Append(&user); err != nil {
+		return fmt.Errorf("failed to add member: %w", err)
+	}
+	return nil
+}
+
+// RemoveMember 移除团队成员
+func (s *TeamService) RemoveMember(ctx context.Context, teamID, userID uint) error {
+	team, err := s.GetTeam(ctx, teamID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.db.WithContext(ctx).Model(team).Association("Members").Delete(&models.User{ID: userID}); err != nil {
+		return fmt.Errorf("failed to remove member: %w", err)
+	}
+	return nil
+}
+
+// GetQueueSummaries 获取各团队队列的未认领及未完结工单数；memberID 非空时仅返回该用户所在的团队
+func (s *TeamService) GetQueueSummaries(ctx context.Context, memberID *uint) ([]*models.TeamQueueSummary, error) {
+	var teams []models.Team
+	query := s.db.WithContext(ctx).Model(&models.Team{}).Where("is_active = ?", true).Order("name ASC")
+	if memberID != nil {
+		query = query.Where("id IN (?)", s.db.Table("team_members").Select("team_id").Where("user_id = ?", *memberID))
+	}
+	if err := query.Find(&teams).Error; err != nil {
+		return nil, fmt.Errorf("failed to list teams: %w", err)
+	}
+
+	type queueCount struct {
+		AssignedTeamID  uint
+		OpenCount       int64
+		UnassignedCount int64
+	}
+	var counts []queueCount
+	if err := s.db.WithContext(ctx).Model(&models.Ticket{}).
+		Select("assigned_team_id, COUNT(*) AS open_count, SUM(CASE WHEN assigned_to_id IS NULL THEN 1 ELSE 0 END) AS unassigned_count").
+		Where("assigned_team_id IS NOT NULL AND deleted_at IS NULL AND status NOT IN ?", closedTicketStatuses).
+		Group("assigned_team_id").
+		Scan(&counts).Error; err != nil {
+		return nil, fmt.Errorf("failed to count team queues: %w", err)
+	}
+
+	countByTeam := make(map[uint]queueCount, len(counts))
+	for _, count := range counts {
+		countByTeam[count.AssignedTeamID] = count
+	}
+
+	summaries := make([]*models.TeamQueueSummary, 0, len(teams))
+	for _, team := range teams {
+		count := countByTeam[team.ID]
+		summaries = append(summaries, &models.TeamQueueSummary{
+			TeamID:          team.ID,
+			Name:            team.Name,
+			Slug:            team.Slug,
+			UnassignedCount: count.UnassignedCount,
+			OpenCount:       count.OpenCount,
+		})
+	}
+	return summaries, nil
+}
+
+// NotifyTeamMentions 通知评论中被@提及团队的成员，返回被提及的团队标识
+func (s *TeamService) NotifyTeamMentions(ctx context.Context, ticket *models.Ticket, comment *models.TicketComment) ([]string, error) {
+	slugs := models.ExtractTeamMentions(comment.Content)
+	if len(slugs) == 0 {
+		return nil, nil
+	}
+
+	var teams []models.Team
+	if err := s.db.WithContext(ctx).Preload("Members").
+		Where("slug IN ? AND is_active = ?", slugs, true).
+		Find(&teams).Error; err != nil {
+		return nil, fmt.Errorf("failed to load mentioned teams: %w", err)
+	}
+
+	notified := make(map[uint]bool)
+	mentioned := make([]string, 0, len(teams))
+	for _, team := range teams {
+		mentioned = append(mentioned, team.Slug)
+		for _, member := range team.Members {
+			if member.ID == comment.UserID || notified[member.ID] {
+				continue
+			}
+			// 团队限定评论不向看不到它的成员发送通知
+			if !commentVisibleToUser(s.db.WithContext(ctx), comment, &member) {
+				continue
+			}
+			notified[member.ID] = true
+
+			req := &models.NotificationCreateRequest{
+				Type:            models.NotificationTypeUserMention,
+				Title:           fmt.Sprintf("团队 @%s 在工单中被提及 - %s", team.Slug, ticket.Title),
+				Content:         truncateString(comment.Content, 200),
+				Priority:        models.NotificationPriorityNormal,
+				Channel:         models.NotificationChannelInApp,
+				RecipientID:     member.ID,
+				SenderID:        &comment.UserID,
+				RelatedType:     "ticket",
+				RelatedID:       &ticket.ID,
+				RelatedTicketID: &ticket.ID,
+				ActionURL:       fmt.Sprintf("/tickets/%d", ticket.ID),
+				Metadata: map[string]interface{}{
+					"ticket_number": ticket.TicketNumber,
+					"team_id":       team.ID,
+					"team_slug":     team.Slug,
+					"comment_id":    comment.ID,
+				},
+			}
+			if _, err := s.notificationService.CreateNotification(ctx, req); err != nil {
+				log.Printf("Failed to notify team member %d of mention: %v", member.ID, err)
+			}
+		}
+	}
+
+	return mentioned, nil
+}
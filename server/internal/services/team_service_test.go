@@ -0,0 +1,90 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupTeamTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file:team_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+
+	if err := db.AutoMigrate(&models.User{}, &models.Team{}, &models.Ticket{}, &models.TicketHistory{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+
+	return db
+}
+
+func TestTeamQueue_ClaimAndCounts(t *testing.T) {
+	db := setupTeamTestDB(t)
+	ctx := context.Background()
+
+	member := models.User{Username: "l2-agent", Email: "l2@example.com", PasswordHash: "x", Role: models.RoleAgent}
+	outsider := models.User{Username: "l1-agent", Email: "l1@example.com", PasswordHash: "x", Role: models.RoleAgent}
+	if err := db.Create(&member).Error; err != nil {
+		t.Fatalf("failed to seed user: %v", err)
+	}
+	if err := db.Create(&outsider).Error; err != nil {
+		t.Fatalf("failed to seed user: %v", err)
+	}
+
+	teamService := NewTeamService(db)
+	team, err := teamService.CreateTeam(ctx, &models.TeamCreateRequest{Name: "Support L2", Slug: "support-l2", MemberIDs: []uint{member.ID}})
+	if err != nil {
+		t.Fatalf("failed to create team: %v", err)
+	}
+
+	for i, number := range []string{"TQ-001", "TQ-002"} {
+		ticket := models.Ticket{TicketNumber: number, Title: "queue ticket", Status: models.TicketStatusOpen, Priority: models.TicketPriorityNormal, Type: models.TicketTypeRequest, Source: models.TicketSourceWeb, CreatedByID: outsider.ID, AssignedTeamID: &team.ID}
+		if i == 1 {
+			ticket.AssignedToID = &member.ID
+		}
+		if err := db.Create(&ticket).Error; err != nil {
+			t.Fatalf("failed to seed ticket: %v", err)
+		}
+	}
+
+	summaries, err := teamService.GetQueueSummaries(ctx, nil)
+	if err != nil {
+		t.Fatalf("failed to get queue summaries: %v", err)
+	}
+	if len(summaries) != 1 || summaries[0].OpenCount != 2 || summaries[0].UnassignedCount != 1 {
+		t.Fatalf("unexpected queue summaries: %+v", summaries)
+	}
+
+	var unclaimed models.Ticket
+	if err := db.Where("ticket_number = ?", "TQ-001").First(&unclaimed).Error; err != nil {
+		t.Fatalf("failed to load ticket: %v", err)
+	}
+
+	ticketService := &TicketService{db: db}
+	if _, err := ticketService.ClaimTicket(ctx, unclaimed.ID, outsider.ID, "agent"); !errors.Is(err, ErrNotTeamMember) {
+		t.Fatalf("expected ErrNotTeamMember, got %v", err)
+	}
+
+	claimed, err := ticketService.ClaimTicket(ctx, unclaimed.ID, member.ID, "agent")
+	if err != nil {
+		t.Fatalf("claim failed: %v", err)
+	}
+	if claimed.AssignedToID == nil || *claimed.AssignedToID != member.ID {
+		t.Fatalf("expected ticket to be assigned to member, got %+v", claimed.AssignedToID)
+	}
+
+	if _, err := ticketService.ClaimTicket(ctx, unclaimed.ID, member.ID, "admin"); !errors.Is(err, ErrTicketAlreadyClaimed) {
+		t.Fatalf("expected ErrTicketAlreadyClaimed, got %v", err)
+	}
+
+	if got := models.ExtractTeamMentions("ping @Support-L2 and @support-l2, mail a@support-l2.com"); len(got) != 1 || got[0] != "support-l2" {
+		t.Fatalf("unexpected mentions: %v", got)
+	}
+}
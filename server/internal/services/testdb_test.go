@@ -0,0 +1,41 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// testDBSeq 区分同一测试的多次运行（-count>1），上一次运行遗留的后台写入不会让旧库延续到下一次
+var testDBSeq atomic.Int64
+
+// newTestDB 为当前测试创建独立的 SQLite 内存库并迁移给定模型，测试结束时关闭。
+// 库名取自测试名，子测试及并行测试互不影响
+func newTestDB(t *testing.T, models ...interface{}) *gorm.DB {
+	t.Helper()
+
+	name := fmt.Sprintf("%s_%d", strings.NewReplacer("/", "_", " ", "_").Replace(t.Name()), testDBSeq.Add(1))
+	db, err := gorm.Open(sqlite.Open("file:"+name+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("failed to get sqlite connection: %v", err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if len(models) > 0 {
+		if err := db.AutoMigrate(models...); err != nil {
+			t.Fatalf("failed to migrate schemas: %v", err)
+		}
+	}
+	return db
+}
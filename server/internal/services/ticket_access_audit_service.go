@@ -0,0 +1,292 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+// KeyTicketAccessAuditPolicy 保密工单访问审计策略配置键
+const KeyTicketAccessAuditPolicy = "security.ticket_access_audit"
+
+// TicketAccessEntry 一次工单读取
+type TicketAccessEntry struct {
+	UserID    uint
+	Role      string
+	Action    models.TicketAccessAction
+	ClientIP  string
+	UserAgent string
+}
+
+// TicketAccessLogFilter 访问日志查询条件
+type TicketAccessLogFilter struct {
+	TicketID    *uint
+	UserID      *uint
+	Action      models.TicketAccessAction
+	NonAssignee bool // 只看非处理人的读取
+	AlertedOnly bool // 只看触发告警的读取
+	StartTime   *time.Time
+	EndTime     *time.Time
+	Page        int
+	PageSize    int
+}
+
+// TicketAccessAuditService 保密工单读取审计、保留期清理及重复访问告警
+type TicketAccessAuditService struct {
+	db *gorm.DB
+}
+
+// NewTicketAccessAuditService 创建保密工单访问审计服务
+func NewTicketAccessAuditService(db *gorm.DB) *TicketAccessAuditService {
+	return &TicketAccessAuditService{db: db}
+}
+
+// GetPolicy 获取访问审计策略
+func (s *TicketAccessAuditService) GetPolicy(ctx context.Context) (*models.TicketAccessAuditPolicy, error) {
+	var config models.SystemConfig
+	err := s.db.WithContext(ctx).
+		Where("key = ? AND is_active = ?", KeyTicketAccessAuditPolicy, true).
+		First(&config).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.GetDefaultTicketAccessAuditPolicy(), nil
+		}
+		return nil, fmt.Errorf("failed to get ticket access audit policy: %w", err)
+	}
+
+	policy := models.GetDefaultTicketAccessAuditPolicy()
+	if err := config.GetJSONValue(policy); err != nil {
+		log.Printf("Warning: failed to parse ticket access audit policy, using defaults: %v", err)
+		return models.GetDefaultTicketAccessAuditPolicy(), nil
+	}
+	if err := policy.Validate(); err != nil {
+		log.Printf("Warning: invalid ticket access audit policy, using defaults: %v", err)
+		return models.GetDefaultTicketAccessAuditPolicy(), nil
+	}
+	return policy, nil
+}
+
+// SetPolicy 保存访问审计策略
+func (s *TicketAccessAuditService) SetPolicy(ctx context.Context, policy *models.TicketAccessAuditPolicy, userID uint) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	var existing models.SystemConfig
+	err := s.db.WithContext(ctx).Where("key = ?", KeyTicketAccessAuditPolicy).First(&existing).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to check existing policy: %w", err)
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		config := models.SystemConfig{
+			Key:         KeyTicketAccessAuditPolicy,
+			Category:    CategorySecurity,
+			Group:       "audit",
+			Description: "保密工单访问审计：保留天数与重复访问告警",
+			IsActive:    true,
+			UpdatedBy:   &userID,
+		}
+		if err := config.SetValue(policy); err != nil {
+			return fmt.Errorf("failed to set policy value: %w", err)
+		}
+		if err := s.db.WithContext(ctx).Create(&config).Error; err != nil {
+			return fmt.Errorf("failed to create policy: %w", err)
+		}
+		return nil
+	}
+
+	if err := existing.SetValue(policy); err != nil {
+		return fmt.Errorf("failed to set policy value: %w", err)
+	}
+	existing.UpdatedBy = &userID
+	existing.Version++
+	if err := s.db.WithContext(ctx).Save(&existing).Error; err != nil {
+		return fmt.Errorf("failed to update policy: %w", err)
+	}
+	return nil
+}
+
+// RecordAccess 记录对保密工单的读取，非保密工单直接忽略。
+// 返回写入的记录，未记录时为 nil
+func (s *TicketAccessAuditService) RecordAccess(ctx context.Context, ticketID uint, entry *TicketAccessEntry) (*models.TicketAccessLog, error) {
+	var ticket models.Ticket
+	err := s.db.WithContext(ctx).
+		Select("id", "ticket_number", "is_confidential", "assigned_to_id", "created_by_id").
+		First(&ticket, ticketID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get ticket: %w", err)
+	}
+	if !ticket.IsConfidential {
+		return nil, nil
+	}
+
+	var user models.User
+	if err := s.db.WithContext(ctx).Select("id", "username").First(&user, entry.UserID).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	accessLog := &models.TicketAccessLog{
+		TicketID:     ticket.ID,
+		TicketNumber: ticket.TicketNumber,
+		UserID:       entry.UserID,
+		Username:     user.Username,
+		Role:         entry.Role,
+		Action:       entry.Action,
+		ClientIP:     entry.ClientIP,
+		UserAgent:    truncateString(entry.UserAgent, 252),
+		IsAssignee:   ticket.AssignedToID != nil && *ticket.AssignedToID == entry.UserID,
+	}
+	if err := s.db.WithContext(ctx).Create(accessLog).Error; err != nil {
+		return nil, fmt.Errorf("failed to record ticket access: %w", err)
+	}
+
+	// 处理人和提交人查看自己的工单不参与告警
+	if !accessLog.IsAssignee && ticket.CreatedByID != entry.UserID {
+		if err := s.checkRepeatedAccess(ctx, &ticket, accessLog); err != nil {
+			log.Printf("Failed to check repeated access on ticket %d: %v", ticket.ID, err)
+		}
+	}
+	return accessLog, nil
+}
+
+// checkRepeatedAccess 非处理人在时间窗口内重复读取同一保密工单达到阈值时通知管理员，窗口内只告警一次
+func (s *TicketAccessAuditService) checkRepeatedAccess(ctx context.Context, ticket *models.Ticket, accessLog *models.TicketAccessLog) error {
+	policy, err := s.GetPolicy(ctx)
+	if err != nil {
+		return err
+	}
+	if !policy.AlertEnabled {
+		return nil
+	}
+
+	since := accessLog.CreatedAt.Add(-time.Duration(policy.AlertWindowMinutes) * time.Minute)
+	scope := func() *gorm.DB {
+		return s.db.WithContext(ctx).Model(&models.TicketAccessLog{}).
+			Where("ticket_id = ? AND user_id = ? AND is_assignee = ? AND created_at >= ?", ticket.ID, accessLog.UserID, false, since)
+	}
+
+	var count int64
+	if err := scope().Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to count ticket access: %w", err)
+	}
+	if count < int64(policy.AlertThreshold) {
+		return nil
+	}
+	var alerted int64
+	if err := scope().Where("alerted_at IS NOT NULL").Count(&alerted).Error; err != nil {
+		return fmt.Errorf("failed to check previous alerts: %w", err)
+	}
+	if alerted > 0 {
+		return nil
+	}
+
+	now := time.Now()
+	if err := s.db.WithContext(ctx).Model(accessLog).Update("alerted_at", now).Error; err != nil {
+		return fmt.Errorf("failed to mark access alert: %w", err)
+	}
+	accessLog.AlertedAt = &now
+
+	var adminIDs []uint
+	if err := s.db.WithContext(ctx).Model(&models.User{}).
+		Where("role = ? AND status = ?", models.RoleAdmin, models.UserStatusActive).
+		Pluck("id", &adminIDs).Error; err != nil {
+		return fmt.Errorf("failed to get admins: %w", err)
+	}
+
+	who := accessLog.Username
+	if who == "" {
+		who = fmt.Sprintf("用户 %d", accessLog.UserID)
+	}
+	notificationService := NewNotificationService(s.db)
+	for _, adminID := range adminIDs {
+		if _, err := notificationService.CreateNotification(ctx, &models.NotificationCreateRequest{
+			Type:            models.NotificationTypeSystemAlert,
+			Title:           fmt.Sprintf("保密工单被重复访问 - #%s", ticket.TicketNumber),
+			Content:         fmt.Sprintf("%s（非处理人）在 %d 分钟内读取保密工单 #%s %d 次，最近一次来自 %s", who, policy.AlertWindowMinutes, ticket.TicketNumber, count, accessLog.ClientIP),
+			Priority:        models.NotificationPriorityHigh,
+			Channel:         models.NotificationChannelInApp,
+			RecipientID:     adminID,
+			RelatedType:     "ticket",
+			RelatedID:       &ticket.ID,
+			RelatedTicketID: &ticket.ID,
+			ActionURL:       fmt.Sprintf("/admin/ticket-access-logs?ticket_id=%d&user_id=%d", ticket.ID, accessLog.UserID),
+			Metadata: map[string]interface{}{
+				"ticket_number": ticket.TicketNumber,
+				"user_id":       accessLog.UserID,
+				"access_count":  count,
+			},
+		}); err != nil {
+			return fmt.Errorf("failed to notify admin %d: %w", adminID, err)
+		}
+	}
+	return nil
+}
+
+// ListLogs 分页查询访问日志，按时间倒序
+func (s *TicketAccessAuditService) ListLogs(ctx context.Context, filter *TicketAccessLogFilter) ([]*models.TicketAccessLog, int64, error) {
+	query := s.db.WithContext(ctx).Model(&models.TicketAccessLog{})
+	if filter.TicketID != nil {
+		query = query.Where("ticket_id = ?", *filter.TicketID)
+	}
+	if filter.UserID != nil {
+		query = query.Where("user_id = ?", *filter.UserID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.NonAssignee {
+		query = query.Where("is_assignee = ?", false)
+	}
+	if filter.AlertedOnly {
+		query = query.Where("alerted_at IS NOT NULL")
+	}
+	if filter.StartTime != nil {
+		query = query.Where("created_at >= ?", *filter.StartTime)
+	}
+	if filter.EndTime != nil {
+		query = query.Where("created_at <= ?", *filter.EndTime)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count ticket access logs: %w", err)
+	}
+
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 || filter.PageSize > 200 {
+		filter.PageSize = 50
+	}
+	var logs []*models.TicketAccessLog
+	if err := query.Order("created_at DESC, id DESC").
+		Offset((filter.Page - 1) * filter.PageSize).
+		Limit(filter.PageSize).
+		Find(&logs).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list ticket access logs: %w", err)
+	}
+	return logs, total, nil
+}
+
+// PurgeExpired 删除超过保留天数的访问日志
+func (s *TicketAccessAuditService) PurgeExpired(ctx context.Context, now time.Time) (int64, error) {
+	policy, err := s.GetPolicy(ctx)
+	if err != nil {
+		return 0, err
+	}
+	cutoff := now.AddDate(0, 0, -policy.RetentionDays)
+	result := s.db.WithContext(ctx).Where("created_at < ?", cutoff).Delete(&models.TicketAccessLog{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to purge ticket access logs: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestTicketAccessAudit_RecordsConfidentialReadsAndAlertsOnce(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:ticket_access_audit_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Ticket{}, &models.SystemConfig{}, &models.TicketAccessLog{},
+		&models.Notification{}, &models.NotificationPreference{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+
+	ctx := context.Background()
+	svc := NewTicketAccessAuditService(db)
+
+	admin := models.User{Username: "ta-admin", Email: "ta-admin@example.com", PasswordHash: "x", Role: models.RoleAdmin, Status: models.UserStatusActive}
+	assignee := models.User{Username: "ta-assignee", Email: "ta-assignee@example.com", PasswordHash: "x", Role: models.RoleAgent, Status: models.UserStatusActive}
+	other := models.User{Username: "ta-other", Email: "ta-other@example.com", PasswordHash: "x", Role: models.RoleAgent, Status: models.UserStatusActive}
+	for _, u := range []*models.User{&admin, &assignee, &other} {
+		if err := db.Create(u).Error; err != nil {
+			t.Fatalf("failed to seed user: %v", err)
+		}
+	}
+
+	public := models.Ticket{TicketNumber: "TA-001", Title: "普通工单", Status: models.TicketStatusOpen, Priority: models.TicketPriorityNormal,
+		Type: models.TicketTypeRequest, Source: models.TicketSourceWeb, CreatedByID: admin.ID}
+	secret := models.Ticket{TicketNumber: "TA-002", Title: "薪资调整", Status: models.TicketStatusOpen, Priority: models.TicketPriorityNormal,
+		Type: models.TicketTypeRequest, Source: models.TicketSourceWeb, CreatedByID: admin.ID, AssignedToID: &assignee.ID, IsConfidential: true}
+	db.Create(&public)
+	db.Create(&secret)
+
+	if log, err := svc.RecordAccess(ctx, public.ID, &TicketAccessEntry{UserID: other.ID, Action: models.TicketAccessView}); err != nil || log != nil {
+		t.Fatalf("expected non-confidential ticket to be ignored, got %+v (%v)", log, err)
+	}
+
+	policy := models.GetDefaultTicketAccessAuditPolicy()
+	policy.AlertEnabled = true
+	policy.AlertThreshold = 3
+	if err := svc.SetPolicy(ctx, policy, admin.ID); err != nil {
+		t.Fatalf("set policy failed: %v", err)
+	}
+
+	for i := 0; i < 4; i++ {
+		if _, err := svc.RecordAccess(ctx, secret.ID, &TicketAccessEntry{UserID: assignee.ID, Role: "agent", Action: models.TicketAccessView}); err != nil {
+			t.Fatalf("record assignee access failed: %v", err)
+		}
+	}
+	var alerts []*models.TicketAccessLog
+	for i := 0; i < 4; i++ {
+		log, err := svc.RecordAccess(ctx, secret.ID, &TicketAccessEntry{UserID: other.ID, Role: "agent", Action: models.TicketAccessComments, ClientIP: "10.0.0.8"})
+		if err != nil {
+			t.Fatalf("record access failed: %v", err)
+		}
+		if log.IsAssignee || log.Username != "ta-other" {
+			t.Fatalf("unexpected access log: %+v", log)
+		}
+		if log.AlertedAt != nil {
+			alerts = append(alerts, log)
+		}
+	}
+	if len(alerts) != 1 {
+		t.Fatalf("expected exactly one alert for repeated access, got %d", len(alerts))
+	}
+	var notifications int64
+	db.Model(&models.Notification{}).Where("recipient_id = ? AND type = ?", admin.ID, models.NotificationTypeSystemAlert).Count(&notifications)
+	if notifications != 1 {
+		t.Fatalf("expected admin to be notified once, got %d", notifications)
+	}
+
+	logs, total, err := svc.ListLogs(ctx, &TicketAccessLogFilter{TicketID: &secret.ID, NonAssignee: true})
+	if err != nil || total != 4 || len(logs) != 4 {
+		t.Fatalf("expected 4 non-assignee reads, got %d (%v)", total, err)
+	}
+	if _, total, _ = svc.ListLogs(ctx, &TicketAccessLogFilter{AlertedOnly: true}); total != 1 {
+		t.Fatalf("expected 1 alerted read, got %d", total)
+	}
+
+	deleted, err := svc.PurgeExpired(ctx, time.Now().AddDate(0, 0, policy.RetentionDays+1))
+	if err != nil || deleted != 8 {
+		t.Fatalf("expected all 8 logs past retention to be purged, got %d (%v)", deleted, err)
+	}
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+const (
+	pdfPageWidth  = 595.0 // A4，单位为磅
+	pdfPageHeight = 842.0
+	pdfMargin     = 50.0
+)
+
+var pdfHTMLTagPattern = regexp.MustCompile(`(?s)<[^>]*>`)
+
+// pdfDocument 纯文本PDF生成器：A4纵向，自动换行分页。
+// 使用阅读器内置的 STSong-Light（Adobe-GB1）字体显示中英文，不嵌入字体文件
+type pdfDocument struct {
+	pages []*bytes.Buffer
+	y     float64
+}
+
+func newPDFDocument() *pdfDocument {
+	return &pdfDocument{}
+}
+
+// Text 写入一段文本，按页宽换行，size 为字号
+func (d *pdfDocument) Text(text string, size float64) {
+	for _, paragraph := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		for _, line := range pdfWrap(paragraph, size, pdfPageWidth-2*pdfMargin) {
+			d.line(line, size)
+		}
+	}
+}
+
+// Gap 插入空白
+func (d *pdfDocument) Gap(height float64) {
+	if len(d.pages) > 0 {
+		d.y -= height
+	}
+}
+
+func (d *pdfDocument) line(text string, size float64) {
+	lineHeight := size * 1.5
+	if len(d.pages) == 0 || d.y-lineHeight < pdfMargin {
+		d.pages = append(d.pages, &bytes.Buffer{})
+		d.y = pdfPageHeight - pdfMargin
+	}
+	d.y -= lineHeight
+	if text == "" {
+		return
+	}
+	fmt.Fprintf(d.pages[len(d.pages)-1], "BT /F1 %.1f Tf %.2f %.2f Td <%s> Tj ET\n", size, pdfMargin, d.y, pdfHexString(text))
+}
+
+// Bytes 生成PDF文件
+func (d *pdfDocument) Bytes() []byte {
+	if len(d.pages) == 0 {
+		d.line("", 10)
+	}
+
+	objects := []string{
+		"", // 1: Catalog，页面对象编号确定后填充
+		"", // 2: Pages
+		"<< /Type /Font /Subtype /Type0 /BaseFont /STSong-Light /Encoding /UniGB-UCS2-H /DescendantFonts [4 0 R] >>",
+		"<< /Type /Font /Subtype /CIDFontType0 /BaseFont /STSong-Light " +
+			"/CIDSystemInfo << /Registry (Adobe) /Ordering (GB1) /Supplement 2 >> /FontDescriptor 5 0 R /DW 1000 /W [1 95 500] >>",
+		"<< /Type /FontDescriptor /FontName /STSong-Light /Flags 6 /FontBBox [-25 -254 1000 880] " +
+			"/ItalicAngle 0 /Ascent 880 /Descent -120 /CapHeight 880 /StemV 93 >>",
+	}
+	kids := make([]string, 0, len(d.pages))
+	for _, page := range d.pages {
+		pageObj := len(objects) + 1
+		kids = append(kids, fmt.Sprintf("%d 0 R", pageObj))
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+				pdfPageWidth, pdfPageHeight, pageObj+1),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.Len(), page.String()))
+	}
+	objects[0] = "<< /Type /Catalog /Pages 2 0 R >>"
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages))
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return out.Bytes()
+}
+
+// pdfWrap 按估算宽度换行：ASCII 字符按半角计，其余按全角计；英文尽量在空格处断行
+func pdfWrap(text string, size, maxWidth float64) []string {
+	runes := []rune(strings.ReplaceAll(text, "\t", "    "))
+	if len(runes) == 0 {
+		return []string{""}
+	}
+	var lines []string
+	start, lastSpace := 0, -1
+	width := 0.0
+	for i := 0; i < len(runes); i++ {
+		w := size
+		if runes[i] < 0x80 {
+			w = size / 2
+		}
+		if width+w > maxWidth && i > start {
+			end := i
+			if lastSpace > start {
+				end = lastSpace + 1
+			}
+			lines = append(lines, strings.TrimRight(string(runes[start:end]), " "))
+			start, lastSpace, width = end, -1, 0
+			i = end - 1
+			continue
+		}
+		if runes[i] == ' ' {
+			lastSpace = i
+		}
+		width += w
+	}
+	return append(lines, string(runes[start:]))
+}
+
+// pdfHexString 将文本编码为 UCS-2 大端十六进制串，基本平面以外的字符及控制字符以 ? 代替
+func pdfHexString(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		if r > 0xFFFF || unicode.IsControl(r) {
+			r = '?'
+		}
+		fmt.Fprintf(&b, "%04X", r)
+	}
+	return b.String()
+}
+
+// pdfPlainText 将 HTML 内容转换为纯文本
+func pdfPlainText(content, contentType string) string {
+	if contentType != "html" {
+		return content
+	}
+	content = strings.NewReplacer("<br>", "\n", "<br/>", "\n", "<br />", "\n", "</p>", "\n").Replace(content)
+	return strings.TrimSpace(html.UnescapeString(pdfHTMLTagPattern.ReplaceAllString(content, "")))
+}
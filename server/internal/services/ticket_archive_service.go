@@ -0,0 +1,541 @@
+package services
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// ticketArchiveRetention 归档文件的保留时间，包含客户数据，不宜长期保存
+	ticketArchiveRetention = 24 * time.Hour
+	// ticketArchiveDownloadPath 签名下载地址，无需登录
+	ticketArchiveDownloadPath = "/api/ticket-archives/%d/download?expires=%d&signature=%s"
+)
+
+var (
+	// ErrTicketArchiveTicketNotFound 工单不存在
+	ErrTicketArchiveTicketNotFound = errors.New("ticket not found")
+	// ErrTicketArchiveNotFound 归档任务不存在
+	ErrTicketArchiveNotFound = errors.New("ticket archive job not found")
+	// ErrTicketArchiveNotReady 归档尚未生成
+	ErrTicketArchiveNotReady = errors.New("ticket archive not ready")
+	// ErrTicketArchiveExpired 归档文件已过期
+	ErrTicketArchiveExpired = errors.New("ticket archive expired")
+	// ErrInvalidTicketArchiveLink 下载链接签名无效或已过期
+	ErrInvalidTicketArchiveLink = errors.New("invalid or expired ticket archive link")
+)
+
+// TicketArchiveService 在后台将单个工单的详情、PDF、评论、历史及附件打包为 ZIP，
+// 完成后通过限时签名链接下载
+type TicketArchiveService struct {
+	db            *gorm.DB
+	attachments   *TicketAttachmentService
+	configService *ConfigService
+	signer        *hmacSigner
+	dir           string // 导出文件目录，不应位于静态文件路由下
+	downloads     *DownloadCenterService
+	now           func() time.Time
+}
+
+// NewTicketArchiveService 创建工单归档服务
+func NewTicketArchiveService(db *gorm.DB, attachments *TicketAttachmentService, dir string) *TicketArchiveService {
+	configService := NewConfigService(db)
+	return &TicketArchiveService{
+		db:            db,
+		attachments:   attachments,
+		configService: configService,
+		signer:        newHMACSigner(configService, KeyTicketArchiveSigningKey, "工单归档下载链接的签名密钥", "ticket_archive"),
+		dir:           dir,
+		now:           time.Now,
+	}
+}
+
+// SetDownloadCenter 设置下载中心，归档生成后通知发起人并记入其下载列表
+func (s *TicketArchiveService) SetDownloadCenter(downloads *DownloadCenterService) {
+	s.downloads = downloads
+	downloads.RegisterSource(models.DownloadKindTicketArchive, s)
+}
+
+// ticketArchiveDownloadParams 重新生成归档所需的参数
+type ticketArchiveDownloadParams struct {
+	TicketID        uint `json:"ticket_id"`
+	IncludeInternal bool `json:"include_internal"`
+}
+
+// RegenerateDownload 按下载记录的参数重新创建归档任务
+func (s *TicketArchiveService) RegenerateDownload(ctx context.Context, params string, userID uint) (uint, error) {
+	var p ticketArchiveDownloadParams
+	if err := json.Unmarshal([]byte(params), &p); err != nil {
+		return 0, fmt.Errorf("invalid ticket archive download params: %w", err)
+	}
+	job, err := s.CreateJob(ctx, p.TicketID, &models.TicketArchiveRequest{IncludeInternal: p.IncludeInternal}, userID)
+	if err != nil {
+		return 0, err
+	}
+	return job.ID, nil
+}
+
+// CreateJob 创建归档任务。同一工单已有相同范围的任务在生成中时直接返回该任务
+func (s *TicketArchiveService) CreateJob(ctx context.Context, ticketID uint, req *models.TicketArchiveRequest, actorID uint) (*models.TicketArchiveJob, error) {
+	var ticket models.Ticket
+	if err := s.db.WithContext(ctx).Select("id", "ticket_number").
+		Where("id = ? AND deleted_at IS NULL", ticketID).First(&ticket).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTicketArchiveTicketNotFound
+		}
+		return nil, fmt.Errorf("failed to get ticket: %w", err)
+	}
+
+	if _, err := s.PurgeExpired(ctx, s.now()); err != nil {
+		log.Printf("Failed to purge expired ticket archives: %v", err)
+	}
+
+	var active models.TicketArchiveJob
+	err := s.db.WithContext(ctx).
+		Where("ticket_id = ? AND include_internal = ? AND status IN ?", ticketID, req.IncludeInternal,
+			[]models.TicketArchiveStatus{models.TicketArchivePending, models.TicketArchiveRunning}).
+		Order("id DESC").First(&active).Error
+	if err == nil {
+		return &active, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to check ticket archive jobs: %w", err)
+	}
+
+	job := &models.TicketArchiveJob{
+		TicketID:        ticketID,
+		TicketNumber:    ticket.TicketNumber,
+		IncludeInternal: req.IncludeInternal,
+		Status:          models.TicketArchivePending,
+		CreatedByID:     actorID,
+	}
+	if err := s.db.WithContext(ctx).Create(job).Error; err != nil {
+		return nil, fmt.Errorf("failed to create ticket archive job: %w", err)
+	}
+	go s.runJob(context.Background(), job.ID)
+	return job, nil
+}
+
+// GetJob 获取工单的归档任务，已完成且未过期的任务附带签名下载链接
+func (s *TicketArchiveService) GetJob(ctx context.Context, ticketID, jobID uint) (*models.TicketArchiveJob, error) {
+	job, err := s.getJob(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job.TicketID != ticketID {
+		return nil, ErrTicketArchiveNotFound
+	}
+	now := s.now()
+	if job.Status == models.TicketArchiveCompleted && job.ExpiresAt != nil && now.Before(*job.ExpiresAt) {
+		if err := s.attachDownloadLink(job, now); err != nil {
+			return nil, err
+		}
+	}
+	return job, nil
+}
+
+// SignedFile 校验签名下载链接，返回归档任务及文件路径
+func (s *TicketArchiveService) SignedFile(ctx context.Context, jobID uint, expires, signature string) (*models.TicketArchiveJob, string, error) {
+	now := s.now()
+	valid, err := s.signer.VerifyLink("ticket-archive", jobID, expires, signature, now)
+	if err != nil {
+		return nil, "", err
+	}
+	if !valid {
+		return nil, "", ErrInvalidTicketArchiveLink
+	}
+
+	job, err := s.getJob(ctx, jobID)
+	if err != nil {
+		return nil, "", err
+	}
+	if job.Status != models.TicketArchiveCompleted {
+		return nil, "", ErrTicketArchiveNotReady
+	}
+	if job.ExpiresAt != nil && now.After(*job.ExpiresAt) {
+		return nil, "", ErrTicketArchiveExpired
+	}
+	return job, filepath.Join(s.dir, filepath.FromSlash(job.FilePath)), nil
+}
+
+// PurgeExpired 删除过期的归档文件及任务记录
+func (s *TicketArchiveService) PurgeExpired(ctx context.Context, now time.Time) (int, error) {
+	var jobs []models.TicketArchiveJob
+	if err := s.db.WithContext(ctx).Where("expires_at IS NOT NULL AND expires_at < ?", now).Find(&jobs).Error; err != nil {
+		return 0, fmt.Errorf("failed to find expired ticket archives: %w", err)
+	}
+	purged := 0
+	for _, job := range jobs {
+		if job.FilePath != "" {
+			if err := os.Remove(filepath.Join(s.dir, filepath.FromSlash(job.FilePath))); err != nil && !errors.Is(err, os.ErrNotExist) {
+				log.Printf("Failed to remove ticket archive file for job %d: %v", job.ID, err)
+				continue
+			}
+		}
+		if err := s.db.WithContext(ctx).Delete(&models.TicketArchiveJob{}, job.ID).Error; err != nil {
+			return purged, fmt.Errorf("failed to delete ticket archive job: %w", err)
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+func (s *TicketArchiveService) getJob(ctx context.Context, jobID uint) (*models.TicketArchiveJob, error) {
+	var job models.TicketArchiveJob
+	if err := s.db.WithContext(ctx).First(&job, jobID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTicketArchiveNotFound
+		}
+		return nil, fmt.Errorf("failed to get ticket archive job: %w", err)
+	}
+	return &job, nil
+}
+
+// attachDownloadLink 生成签名下载链接，有效期不超过文件保留期限
+func (s *TicketArchiveService) attachDownloadLink(job *models.TicketArchiveJob, now time.Time) error {
+	ttl := 60
+	if value, err := s.configService.GetConfigInt(KeyTicketArchiveLinkTTL); err == nil && value > 0 {
+		ttl = value
+	}
+	expires := now.Add(time.Duration(ttl) * time.Minute)
+	if expires.After(*job.ExpiresAt) {
+		expires = *job.ExpiresAt
+	}
+	signature, err := s.signer.SignLink("ticket-archive", job.ID, expires)
+	if err != nil {
+		return err
+	}
+	job.DownloadURL = fmt.Sprintf(ticketArchiveDownloadPath, job.ID, expires.Unix(), signature)
+	job.DownloadExpiresAt = &expires
+	return nil
+}
+
+// runJob 生成归档并写入导出目录，各阶段更新进度
+func (s *TicketArchiveService) runJob(ctx context.Context, jobID uint) {
+	job, err := s.getJob(ctx, jobID)
+	if err != nil {
+		log.Printf("Failed to load ticket archive job %d: %v", jobID, err)
+		return
+	}
+
+	jobs := s.db.WithContext(ctx).Model(&models.TicketArchiveJob{}).Where("id = ?", jobID).Session(&gorm.Session{})
+	fail := func(err error) {
+		log.Printf("Ticket archive job %d failed: %v", jobID, err)
+		finished := s.now()
+		jobs.Updates(map[string]interface{}{
+			"status":      models.TicketArchiveFailed,
+			"error":       truncateString(err.Error(), 497),
+			"finished_at": &finished,
+		})
+	}
+
+	started := s.now()
+	if err := jobs.Updates(map[string]interface{}{
+		"status":     models.TicketArchiveRunning,
+		"stage":      models.TicketArchiveStageCollecting,
+		"started_at": &started,
+	}).Error; err != nil {
+		log.Printf("Failed to start ticket archive job %d: %v", jobID, err)
+		return
+	}
+
+	relPath := fmt.Sprintf("ticket_archives/job_%d.zip", job.ID)
+	fullPath := filepath.Join(s.dir, filepath.FromSlash(relPath))
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+		fail(fmt.Errorf("failed to create export directory: %w", err))
+		return
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(fullPath), ".archive-*")
+	if err != nil {
+		fail(fmt.Errorf("failed to create archive file: %w", err))
+		return
+	}
+	defer os.Remove(tmp.Name())
+
+	counter := &attachmentCounter{hash: sha256.New()}
+	manifest, err := s.buildArchive(ctx, job, io.MultiWriter(tmp, counter), func(stage string, progress int) {
+		jobs.Updates(map[string]interface{}{"stage": stage, "progress": progress})
+	})
+	if closeErr := tmp.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to close archive file: %w", closeErr)
+	}
+	if err != nil {
+		fail(err)
+		return
+	}
+	if err := os.Rename(tmp.Name(), fullPath); err != nil {
+		fail(fmt.Errorf("failed to store archive file: %w", err))
+		return
+	}
+
+	finished := s.now()
+	expires := finished.Add(ticketArchiveRetention)
+	fileName := TicketArchiveFileName(job.TicketNumber, finished)
+	if err := jobs.Updates(map[string]interface{}{
+		"status":              models.TicketArchiveCompleted,
+		"stage":               models.TicketArchiveStageDone,
+		"progress":            100,
+		"file_name":           fileName,
+		"file_path":           relPath,
+		"file_size":           counter.size,
+		"checksum":            hex.EncodeToString(counter.hash.Sum(nil)),
+		"skipped_attachments": len(manifest.Skipped),
+		"finished_at":         &finished,
+		"expires_at":          &expires,
+	}).Error; err != nil {
+		log.Printf("Failed to finish ticket archive job %d: %v", jobID, err)
+		return
+	}
+
+	if s.downloads != nil {
+		params, _ := json.Marshal(ticketArchiveDownloadParams{TicketID: job.TicketID, IncludeInternal: job.IncludeInternal})
+		if err := s.downloads.Publish(ctx, &models.DownloadArtifact{
+			UserID:    job.CreatedByID,
+			Kind:      models.DownloadKindTicketArchive,
+			SourceID:  job.ID,
+			Title:     fmt.Sprintf("工单 #%s 完整归档", job.TicketNumber),
+			FileName:  fileName,
+			FilePath:  relPath,
+			FileSize:  counter.size,
+			Params:    string(params),
+			ExpiresAt: expires,
+		}); err != nil {
+			log.Printf("Failed to publish ticket archive job %d to download center: %v", jobID, err)
+		}
+	}
+}
+
+// buildArchive 写入 ZIP：ticket.json、ticket.pdf、comments.json、history.json、attachments/ 及 manifest.json。
+// 无法读取或被判定为感染的附件跳过并记入清单
+func (s *TicketArchiveService) buildArchive(ctx context.Context, job *models.TicketArchiveJob, w io.Writer, progress func(stage string, percent int)) (*models.TicketArchiveManifest, error) {
+	var ticket models.Ticket
+	if err := s.db.WithContext(ctx).
+		Preload("CreatedBy").Preload("AssignedTo").Preload("AssignedTeam").Preload("Category").Preload("Subcategory").
+		Where("id = ? AND deleted_at IS NULL", job.TicketID).First(&ticket).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTicketArchiveTicketNotFound
+		}
+		return nil, fmt.Errorf("failed to load ticket: %w", err)
+	}
+
+	comments := s.db.WithContext(ctx).Preload("User").
+		Where("ticket_id = ? AND deleted_at IS NULL AND is_deleted = ?", ticket.ID, false)
+	if !job.IncludeInternal {
+		comments = comments.Where("visibility = ? AND type <> ?", models.CommentVisibilityPublic, models.CommentTypeInternal)
+	}
+	var commentList []models.TicketComment
+	if err := comments.Order("created_at ASC, id ASC").Find(&commentList).Error; err != nil {
+		return nil, fmt.Errorf("failed to load comments: %w", err)
+	}
+
+	history := s.db.WithContext(ctx).Preload("User").Where("ticket_id = ?", ticket.ID)
+	if !job.IncludeInternal {
+		history = history.Where("is_visible = ?", true)
+	}
+	var historyList []models.TicketHistory
+	if err := history.Order("created_at ASC, id ASC").Find(&historyList).Error; err != nil {
+		return nil, fmt.Errorf("failed to load history: %w", err)
+	}
+
+	attachments := s.db.WithContext(ctx).Where("ticket_id = ? AND deleted_at IS NULL", ticket.ID)
+	if !job.IncludeInternal {
+		// 只包含工单本身及可见评论的附件
+		visible := make([]uint, 0, len(commentList))
+		for _, comment := range commentList {
+			visible = append(visible, comment.ID)
+		}
+		if len(visible) > 0 {
+			attachments = attachments.Where("comment_id IS NULL OR comment_id IN ?", visible)
+		} else {
+			attachments = attachments.Where("comment_id IS NULL")
+		}
+	}
+	var attachmentList []models.TicketAttachment
+	if err := attachments.Order("created_at ASC, id ASC").Find(&attachmentList).Error; err != nil {
+		return nil, fmt.Errorf("failed to load attachments: %w", err)
+	}
+
+	manifest := &models.TicketArchiveManifest{
+		TicketID:        ticket.ID,
+		TicketNumber:    ticket.TicketNumber,
+		GeneratedAt:     s.now(),
+		GeneratedByID:   job.CreatedByID,
+		IncludeInternal: job.IncludeInternal,
+		Files:           []models.TicketArchiveManifestFile{},
+		Skipped:         []models.TicketArchiveSkippedFile{},
+	}
+	zw := zip.NewWriter(w)
+	add := func(name string, write func(io.Writer) error) error {
+		entry, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: manifest.GeneratedAt})
+		if err != nil {
+			return fmt.Errorf("failed to add %s: %w", name, err)
+		}
+		counter := &attachmentCounter{hash: sha256.New()}
+		if err := write(io.MultiWriter(entry, counter)); err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, models.TicketArchiveManifestFile{Name: name, Size: counter.size, SHA256: hex.EncodeToString(counter.hash.Sum(nil))})
+		return nil
+	}
+	addJSON := func(name string, v interface{}) error {
+		return add(name, func(w io.Writer) error {
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			return enc.Encode(v)
+		})
+	}
+
+	if err := addJSON("ticket.json", &ticket); err != nil {
+		return nil, err
+	}
+	if err := addJSON("comments.json", commentList); err != nil {
+		return nil, err
+	}
+	if err := addJSON("history.json", historyList); err != nil {
+		return nil, err
+	}
+
+	progress(models.TicketArchiveStageRendering, 10)
+	pdf := renderTicketArchivePDF(&ticket, commentList, historyList, attachmentList, manifest.GeneratedAt)
+	if err := add("ticket.pdf", func(w io.Writer) error {
+		_, err := w.Write(pdf)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	progress(models.TicketArchiveStageAttachments, 20)
+	for i := range attachmentList {
+		attachment := &attachmentList[i]
+		name := fmt.Sprintf("attachments/%d_%s", attachment.ID, ticketArchiveFileName(attachment.OriginalName))
+		if attachment.VirusScan == "infected" {
+			manifest.Skipped = append(manifest.Skipped, models.TicketArchiveSkippedFile{AttachmentID: attachment.ID, Name: attachment.OriginalName, Reason: "infected"})
+			continue
+		}
+		_, reader, err := s.attachments.Open(ctx, ticket.ID, attachment.ID)
+		if err != nil {
+			manifest.Skipped = append(manifest.Skipped, models.TicketArchiveSkippedFile{AttachmentID: attachment.ID, Name: attachment.OriginalName, Reason: err.Error()})
+			continue
+		}
+		err = add(name, func(w io.Writer) error {
+			_, err := io.Copy(w, reader)
+			return err
+		})
+		reader.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to archive attachment %d: %w", attachment.ID, err)
+		}
+		progress(models.TicketArchiveStageAttachments, 20+75*(i+1)/len(attachmentList))
+	}
+
+	// 清单最后写入，不包含自身的摘要
+	if err := addJSON("manifest.json", manifest); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish archive: %w", err)
+	}
+	return manifest, nil
+}
+
+// renderTicketArchivePDF 生成工单的可读版本：基本信息、描述、评论、附件列表及历史
+func renderTicketArchivePDF(ticket *models.Ticket, comments []models.TicketComment, history []models.TicketHistory, attachments []models.TicketAttachment, generatedAt time.Time) []byte {
+	const timeLayout = "2006-01-02 15:04:05"
+	userName := func(u *models.User) string {
+		if u == nil {
+			return "-"
+		}
+		return u.GetFullName()
+	}
+	optionalTime := func(t *time.Time) string {
+		if t == nil {
+			return "-"
+		}
+		return t.Format(timeLayout)
+	}
+
+	doc := newPDFDocument()
+	doc.Text(fmt.Sprintf("工单 %s：%s", ticket.TicketNumber, ticket.Title), 16)
+	doc.Text("生成时间："+generatedAt.Format(timeLayout), 9)
+	doc.Gap(8)
+
+	category := "-"
+	if ticket.Category != nil {
+		category = ticket.Category.Name
+		if ticket.Subcategory != nil {
+			category += " / " + ticket.Subcategory.Name
+		}
+	}
+	for _, field := range [][2]string{
+		{"状态", string(ticket.Status)},
+		{"优先级", string(ticket.Priority)},
+		{"类型", string(ticket.Type)},
+		{"来源", string(ticket.Source)},
+		{"分类", category},
+		{"创建人", userName(ticket.CreatedBy)},
+		{"处理人", userName(ticket.AssignedTo)},
+		{"客户", strings.TrimSpace(ticket.CustomerName + " " + ticket.CustomerEmail + " " + ticket.CustomerPhone)},
+		{"创建时间", ticket.CreatedAt.Format(timeLayout)},
+		{"解决时间", optionalTime(ticket.ResolvedAt)},
+		{"关闭时间", optionalTime(ticket.ClosedAt)},
+	} {
+		value := field[1]
+		if value == "" {
+			value = "-"
+		}
+		doc.Text(field[0]+"："+value, 10)
+	}
+
+	doc.Gap(10)
+	doc.Text("描述", 13)
+	doc.Text(ticket.Description, 10)
+
+	doc.Gap(10)
+	doc.Text(fmt.Sprintf("评论（%d）", len(comments)), 13)
+	for _, comment := range comments {
+		doc.Gap(4)
+		doc.Text(fmt.Sprintf("[%s] %s（%s）", comment.CreatedAt.Format(timeLayout), userName(comment.User), comment.Visibility), 9)
+		doc.Text(pdfPlainText(comment.Content, comment.ContentType), 10)
+	}
+
+	doc.Gap(10)
+	doc.Text(fmt.Sprintf("附件（%d）", len(attachments)), 13)
+	for _, attachment := range attachments {
+		doc.Text(fmt.Sprintf("%d_%s（%d 字节）", attachment.ID, attachment.OriginalName, attachment.FileSize), 10)
+	}
+
+	doc.Gap(10)
+	doc.Text(fmt.Sprintf("处理历史（%d）", len(history)), 13)
+	for _, entry := range history {
+		doc.Text(fmt.Sprintf("[%s] %s %s：%s", entry.CreatedAt.Format(timeLayout), userName(entry.User), entry.Action, entry.Description), 9)
+	}
+	return doc.Bytes()
+}
+
+// ticketArchiveFileName 归档内的附件文件名，去除路径分隔符
+func ticketArchiveFileName(name string) string {
+	name = strings.NewReplacer("/", "_", "\\", "_", "..", "_").Replace(strings.TrimSpace(name))
+	if name == "" {
+		return "file"
+	}
+	return name
+}
+
+// TicketArchiveFileName 归档下载文件名
+func TicketArchiveFileName(ticketNumber string, generatedAt time.Time) string {
+	return fmt.Sprintf("ticket_%s_archive_%s.zip", ticketArchiveFileName(ticketNumber), generatedAt.Format("20060102150405"))
+}
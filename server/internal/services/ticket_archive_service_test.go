@@ -0,0 +1,157 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestTicketArchive_BuildsZipAndSignsDownloadLink(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:ticket_archive_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Team{}, &models.Category{}, &models.Ticket{}, &models.TicketComment{},
+		&models.TicketHistory{}, &models.TicketAttachment{}, &models.SystemConfig{}, &models.TicketArchiveJob{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+	ctx := context.Background()
+
+	agent := models.User{Username: "archive-agent", Email: "archive-agent@example.com", PasswordHash: "x", Role: models.RoleAgent, Status: models.UserStatusActive}
+	db.Create(&agent)
+	ticket := models.Ticket{TicketNumber: "ARC-1", Title: "打印机无法连接", Description: "Printer offline since Monday", Status: models.TicketStatusOpen,
+		Priority: models.TicketPriorityHigh, Type: models.TicketTypeIncident, Source: models.TicketSourceWeb, CreatedByID: agent.ID}
+	if err := db.Create(&ticket).Error; err != nil {
+		t.Fatalf("failed to seed ticket: %v", err)
+	}
+	public := models.TicketComment{TicketID: ticket.ID, UserID: agent.ID, Content: "<p>请重启打印机</p>", ContentType: "html",
+		Type: models.CommentTypePublic, Visibility: models.CommentVisibilityPublic}
+	internal := models.TicketComment{TicketID: ticket.ID, UserID: agent.ID, Content: "driver bug", Type: models.CommentTypeInternal,
+		Visibility: models.CommentVisibilityInternal}
+	db.Create(&public)
+	db.Create(&internal)
+	db.Create(&models.TicketHistory{TicketID: ticket.ID, UserID: &agent.ID, Action: models.HistoryActionCreate, Description: "created", IsVisible: true})
+
+	storage := NewLocalFileStorage(t.TempDir(), "/uploads")
+	attachments := NewTicketAttachmentService(db, storage)
+	seedAttachment := func(name, content string, commentID *uint, scan string) {
+		key := "tickets/archive/" + name
+		if err := storage.Put(ctx, key, strings.NewReader(content), "text/plain"); err != nil {
+			t.Fatalf("failed to store attachment: %v", err)
+		}
+		db.Create(&models.TicketAttachment{TicketID: ticket.ID, CommentID: commentID, UploadedBy: agent.ID, FileName: name, OriginalName: name,
+			FileSize: int64(len(content)), StoragePath: key, VirusScan: scan})
+	}
+	seedAttachment("log.txt", "printer log", nil, "clean")
+	seedAttachment("internal.txt", "internal notes", &internal.ID, "clean")
+	seedAttachment("virus.exe", "bad", nil, "infected")
+
+	svc := NewTicketArchiveService(db, attachments, t.TempDir())
+	if _, err := svc.CreateJob(ctx, 9999, &models.TicketArchiveRequest{}, agent.ID); !errors.Is(err, ErrTicketArchiveTicketNotFound) {
+		t.Fatalf("expected missing ticket to be rejected, got %v", err)
+	}
+	job, err := svc.CreateJob(ctx, ticket.ID, &models.TicketArchiveRequest{}, agent.ID)
+	if err != nil {
+		t.Fatalf("create archive job failed: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for job.Status != models.TicketArchiveCompleted && job.Status != models.TicketArchiveFailed && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+		if job, err = svc.GetJob(ctx, ticket.ID, job.ID); err != nil {
+			t.Fatalf("get archive job failed: %v", err)
+		}
+	}
+	if job.Status != models.TicketArchiveCompleted || job.Progress != 100 || job.SkippedAttachments != 1 || job.DownloadURL == "" || len(job.Checksum) != 64 {
+		t.Fatalf("unexpected archive job %+v", job)
+	}
+	if _, err := svc.GetJob(ctx, ticket.ID+1, job.ID); !errors.Is(err, ErrTicketArchiveNotFound) {
+		t.Fatalf("expected job of another ticket to be hidden, got %v", err)
+	}
+
+	// 签名链接：篡改签名或过期时间均被拒绝
+	link, _ := url.Parse(job.DownloadURL)
+	query := link.Query()
+	tampered := []byte(query.Get("signature"))
+	tampered[0] ^= 1
+	if _, _, err := svc.SignedFile(ctx, job.ID, query.Get("expires"), string(tampered)); !errors.Is(err, ErrInvalidTicketArchiveLink) {
+		t.Fatalf("expected tampered signature to be rejected, got %v", err)
+	}
+	if _, _, err := svc.SignedFile(ctx, job.ID, "9999999999", query.Get("signature")); !errors.Is(err, ErrInvalidTicketArchiveLink) {
+		t.Fatalf("expected modified expiry to be rejected, got %v", err)
+	}
+	_, path, err := svc.SignedFile(ctx, job.ID, query.Get("expires"), query.Get("signature"))
+	if err != nil {
+		t.Fatalf("signed download failed: %v", err)
+	}
+
+	// 默认只包含客户可见的评论及附件，清单记录跳过的感染附件
+	reader, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatalf("failed to open archive: %v", err)
+	}
+	defer reader.Close()
+	files := map[string][]byte{}
+	for _, f := range reader.File {
+		rc, _ := f.Open()
+		files[f.Name], _ = io.ReadAll(rc)
+		rc.Close()
+	}
+	for _, name := range []string{"ticket.json", "ticket.pdf", "comments.json", "history.json", "manifest.json"} {
+		if files[name] == nil {
+			t.Fatalf("archive is missing %s, has %v", name, len(files))
+		}
+	}
+	if string(files["attachments/1_log.txt"]) != "printer log" || files["attachments/2_internal.txt"] != nil || files["attachments/3_virus.exe"] != nil {
+		t.Fatalf("unexpected attachments in archive")
+	}
+	var comments []models.TicketComment
+	json.Unmarshal(files["comments.json"], &comments)
+	if len(comments) != 1 || comments[0].ID != public.ID {
+		t.Fatalf("expected only the public comment, got %+v", comments)
+	}
+	if !bytes.HasPrefix(files["ticket.pdf"], []byte("%PDF-1.4")) || !bytes.Contains(files["ticket.pdf"], []byte(pdfHexString("请重启打印机"))) {
+		t.Fatalf("unexpected pdf content")
+	}
+	var manifest models.TicketArchiveManifest
+	json.Unmarshal(files["manifest.json"], &manifest)
+	if len(manifest.Files) != 5 || len(manifest.Skipped) != 1 || manifest.Skipped[0].Reason != "infected" {
+		t.Fatalf("unexpected manifest %+v", manifest)
+	}
+
+	// 链接到期及文件过期
+	svc.now = func() time.Time { return time.Now().Add(2 * time.Hour) }
+	if _, _, err := svc.SignedFile(ctx, job.ID, query.Get("expires"), query.Get("signature")); !errors.Is(err, ErrInvalidTicketArchiveLink) {
+		t.Fatalf("expected expired link to be rejected, got %v", err)
+	}
+	if purged, err := svc.PurgeExpired(ctx, time.Now().Add(25*time.Hour)); err != nil || purged != 1 {
+		t.Fatalf("expected expired archive to be purged, got %d, %v", purged, err)
+	}
+}
+
+func TestPDFDocument_WrapsAndPaginates(t *testing.T) {
+	if lines := pdfWrap("hello brave new world", 10, 60); len(lines) != 2 || lines[0] != "hello brave" || lines[1] != "new world" {
+		t.Fatalf("expected english text to wrap at spaces, got %q", lines)
+	}
+	if lines := pdfWrap("工单归档导出", 10, 30); len(lines) != 2 || lines[0] != "工单归" {
+		t.Fatalf("expected CJK text to wrap per character, got %q", lines)
+	}
+
+	doc := newPDFDocument()
+	for i := 0; i < 80; i++ {
+		doc.Text("line", 10)
+	}
+	if out := doc.Bytes(); !bytes.Contains(out, []byte("/Count 2")) || !bytes.HasSuffix(out, []byte("%%EOF\n")) {
+		t.Fatalf("expected two pages, got %s", out[len(out)-200:])
+	}
+}
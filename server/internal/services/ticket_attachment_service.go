@@ -0,0 +1,584 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+// KeyTicketAttachmentPolicy 工单附件策略配置键
+const KeyTicketAttachmentPolicy = "ticket.attachment_policy"
+
+// 附件被拒绝的原因
+const (
+	AttachmentRejectTooLarge       = "file_too_large"
+	AttachmentRejectMimeType       = "mime_type_not_allowed"
+	AttachmentRejectTooManyFiles   = "too_many_attachments"
+	AttachmentRejectInfected       = "virus_detected"
+	attachmentSniffLen             = 512
+	attachmentStorageKeyRandomSize = 8
+)
+
+var (
+	// ErrAttachmentRejected 附件不符合分类的附件策略
+	ErrAttachmentRejected = errors.New("attachment rejected by category policy")
+	// ErrAttachmentTicketNotFound 工单不存在
+	ErrAttachmentTicketNotFound = errors.New("ticket not found")
+	// ErrAttachmentNotFound 附件不存在
+	ErrAttachmentNotFound = errors.New("attachment not found")
+)
+
+// AttachmentRejectedError 附件违反策略的详细原因，errors.Is(err, ErrAttachmentRejected) 为 true
+type AttachmentRejectedError struct {
+	Reason   string                  `json:"reason"`
+	MimeType string                  `json:"mime_type,omitempty"`
+	Size     int64                   `json:"size,omitempty"`
+	Schema   models.AttachmentSchema `json:"policy"`
+}
+
+func (e *AttachmentRejectedError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrAttachmentRejected.Error(), e.Reason)
+}
+
+// Is 支持 errors.Is(err, ErrAttachmentRejected)
+func (e *AttachmentRejectedError) Is(target error) bool {
+	return target == ErrAttachmentRejected
+}
+
+// Message 面向用户的错误说明
+func (e *AttachmentRejectedError) Message() string {
+	switch e.Reason {
+	case AttachmentRejectTooLarge:
+		return fmt.Sprintf("文件过大，该分类最大支持%dMB", e.Schema.MaxFileSizeMB)
+	case AttachmentRejectMimeType:
+		return fmt.Sprintf("该分类不允许上传 %s 类型的文件，允许的类型：%s", e.MimeType, strings.Join(e.Schema.AllowedMimeTypes, ", "))
+	case AttachmentRejectTooManyFiles:
+		return fmt.Sprintf("附件数量已达上限，该分类每个工单最多%d个附件", e.Schema.MaxCount)
+	case AttachmentRejectInfected:
+		return "附件未通过病毒扫描，不能添加到评论"
+	}
+	return "附件不符合分类的附件策略"
+}
+
+// TicketAttachmentService 工单附件服务：按工单分类的附件策略校验并保存上传的文件
+type TicketAttachmentService struct {
+	db           *gorm.DB
+	storage      FileStorage
+	quotaService *QuotaService
+	cipher       *AttachmentCipher
+}
+
+// NewTicketAttachmentService 创建工单附件服务
+func NewTicketAttachmentService(db *gorm.DB, storage FileStorage) *TicketAttachmentService {
+	return &TicketAttachmentService{
+		db:           db,
+		storage:      storage,
+		quotaService: NewQuotaService(db),
+	}
+}
+
+// SetCipher 设置附件加解密器，设置后新上传的附件加密存储，下载时透明解密
+func (s *TicketAttachmentService) SetCipher(cipher *AttachmentCipher) {
+	s.cipher = cipher
+}
+
+// GetPolicy 获取附件策略
+func (s *TicketAttachmentService) GetPolicy(ctx context.Context) (*models.AttachmentPolicy, error) {
+	var config models.SystemConfig
+	err := s.db.WithContext(ctx).
+		Where("key = ? AND is_active = ?", KeyTicketAttachmentPolicy, true).
+		First(&config).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.GetDefaultAttachmentPolicy(), nil
+		}
+		return nil, fmt.Errorf("failed to get attachment policy: %w", err)
+	}
+
+	policy := &models.AttachmentPolicy{}
+	if err := config.GetJSONValue(policy); err != nil {
+		log.Printf("Warning: failed to parse attachment policy, using defaults: %v", err)
+		return models.GetDefaultAttachmentPolicy(), nil
+	}
+	if err := policy.Validate(); err != nil {
+		log.Printf("Warning: invalid attachment policy, using defaults: %v", err)
+		return models.GetDefaultAttachmentPolicy(), nil
+	}
+	if policy.Categories == nil {
+		policy.Categories = map[uint]models.AttachmentRule{}
+	}
+	return policy, nil
+}
+
+// SetPolicy 保存附件策略
+func (s *TicketAttachmentService) SetPolicy(ctx context.Context, policy *models.AttachmentPolicy, userID uint) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	var existing models.SystemConfig
+	err := s.db.WithContext(ctx).Where("key = ?", KeyTicketAttachmentPolicy).First(&existing).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to check existing policy: %w", err)
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		config := models.SystemConfig{
+			Key:         KeyTicketAttachmentPolicy,
+			Category:    CategoryTicket,
+			Group:       "attachments",
+			Description: "按工单分类的附件大小、类型与数量限制",
+			IsActive:    true,
+			UpdatedBy:   &userID,
+		}
+		if err := config.SetValue(policy); err != nil {
+			return fmt.Errorf("failed to set policy value: %w", err)
+		}
+		if err := s.db.WithContext(ctx).Create(&config).Error; err != nil {
+			return fmt.Errorf("failed to create policy: %w", err)
+		}
+		return nil
+	}
+
+	if err := existing.SetValue(policy); err != nil {
+		return fmt.Errorf("failed to set policy value: %w", err)
+	}
+	existing.UpdatedBy = &userID
+	existing.Version++
+
+	if err := s.db.WithContext(ctx).Save(&existing).Error; err != nil {
+		return fmt.Errorf("failed to update policy: %w", err)
+	}
+	return nil
+}
+
+// FormSchema 获取分类的建单表单约束，客户端据此在上传前校验附件
+func (s *TicketAttachmentService) FormSchema(ctx context.Context, categoryID, subcategoryID *uint) (*models.TicketFormSchema, error) {
+	schema := &models.TicketFormSchema{CategoryID: categoryID, SubcategoryID: subcategoryID}
+	for _, id := range []*uint{categoryID, subcategoryID} {
+		if id == nil {
+			continue
+		}
+		var category models.Category
+		err := s.db.WithContext(ctx).Where("id = ? AND deleted_at IS NULL", *id).First(&category).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCategoryNotActive
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get category: %w", err)
+		}
+		// 子分类的模板与审批要求覆盖父分类
+		if category.Template != "" {
+			schema.Template = category.Template
+		}
+		schema.RequireApproval = schema.RequireApproval || category.RequireApproval
+	}
+
+	policy, err := s.GetPolicy(ctx)
+	if err != nil {
+		return nil, err
+	}
+	schema.Attachments = attachmentSchema(policy, categoryID, subcategoryID)
+	return schema, nil
+}
+
+// Upload 按工单分类的附件策略校验并保存附件。
+// declaredSize 为客户端声明的大小（如 multipart 头部），用于提前拒绝；实际大小以读取到的内容为准
+func (s *TicketAttachmentService) Upload(ctx context.Context, ticketID, userID uint, fileName string, declaredSize int64, r io.Reader) (*models.TicketAttachment, error) {
+	schema, err := s.ticketSchema(ctx, ticketID)
+	if err != nil {
+		return nil, err
+	}
+	reject := func(reason, mimeType string, size int64) error {
+		return &AttachmentRejectedError{Reason: reason, MimeType: mimeType, Size: size, Schema: schema}
+	}
+
+	if schema.MaxCount > 0 {
+		count, err := countTicketAttachments(s.db.WithContext(ctx), ticketID)
+		if err != nil {
+			return nil, err
+		}
+		if count >= int64(schema.MaxCount) {
+			return nil, reject(AttachmentRejectTooManyFiles, "", 0)
+		}
+	}
+	if declaredSize > schema.MaxFileSizeBytes {
+		return nil, reject(AttachmentRejectTooLarge, "", declaredSize)
+	}
+	if declaredSize > 0 {
+		if err := s.quotaService.CheckAttachmentStorage(ctx, declaredSize); err != nil {
+			return nil, err
+		}
+	}
+
+	// 按文件内容识别类型，不信任扩展名与客户端声明的 Content-Type
+	buffered := bufio.NewReaderSize(r, attachmentSniffLen)
+	head, err := buffered.Peek(attachmentSniffLen)
+	if err != nil && err != io.EOF && !errors.Is(err, bufio.ErrBufferFull) {
+		return nil, fmt.Errorf("failed to read attachment: %w", err)
+	}
+	originalName, ext := attachmentOriginalName(fileName)
+	mimeType := attachmentMimeType(head, ext)
+	if !(&models.AttachmentRule{AllowedMimeTypes: schema.AllowedMimeTypes}).AllowsMimeType(mimeType) {
+		return nil, reject(AttachmentRejectMimeType, mimeType, 0)
+	}
+	key, err := attachmentKey(ticketID, ext)
+	if err != nil {
+		return nil, err
+	}
+
+	// 边读边计数，超过上限的文件写入后立即删除
+	counter := &attachmentCounter{hash: sha256.New()}
+	limited := io.LimitReader(buffered, schema.MaxFileSizeBytes+1)
+	encryption, err := s.putFile(ctx, key, io.TeeReader(limited, counter), mimeType)
+	if err != nil {
+		return nil, err
+	}
+	if counter.size > schema.MaxFileSizeBytes {
+		s.deleteStored(ctx, key)
+		return nil, reject(AttachmentRejectTooLarge, mimeType, counter.size)
+	}
+	if declaredSize <= 0 {
+		if err := s.quotaService.CheckAttachmentStorage(ctx, counter.size); err != nil {
+			s.deleteStored(ctx, key)
+			return nil, err
+		}
+	}
+
+	attachment := &models.TicketAttachment{
+		TicketID:     ticketID,
+		UploadedBy:   userID,
+		FileName:     filepath.Base(key),
+		OriginalName: originalName,
+		FileSize:     counter.size,
+		MimeType:     mimeType,
+		FileType:     attachmentFileType(mimeType, ext),
+		Extension:    ext,
+		StoragePath:  key,
+		StorageType:  s.storage.Type(),
+		Encryption:   encryption,
+		Hash:         hex.EncodeToString(counter.hash.Sum(nil)),
+		VirusScan:    "pending",
+	}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(attachment).Error; err != nil {
+			return fmt.Errorf("failed to create attachment: %w", err)
+		}
+		return recordHistory(tx, nil, &models.TicketHistory{
+			TicketID:     ticketID,
+			UserID:       &userID,
+			Action:       models.HistoryActionAttachment,
+			Description:  "上传附件 " + originalName,
+			NewValue:     originalName,
+			AttachmentID: &attachment.ID,
+			IsVisible:    true,
+		})
+	})
+	if err != nil {
+		s.deleteStored(ctx, key)
+		return nil, err
+	}
+
+	if err := s.quotaService.NotifyThresholds(ctx, models.QuotaResourceAttachmentStorage, time.Now()); err != nil {
+		log.Printf("Failed to check attachment storage quota thresholds: %v", err)
+	}
+	return attachment, nil
+}
+
+// List 获取工单的附件
+func (s *TicketAttachmentService) List(ctx context.Context, ticketID uint) ([]models.TicketAttachment, error) {
+	var attachments []models.TicketAttachment
+	if err := s.db.WithContext(ctx).
+		Where("ticket_id = ? AND deleted_at IS NULL", ticketID).
+		Order("created_at ASC, id ASC").
+		Find(&attachments).Error; err != nil {
+		return nil, fmt.Errorf("failed to list attachments: %w", err)
+	}
+	return attachments, nil
+}
+
+// Open 打开附件内容，加密存储的附件返回解密后的内容，调用方负责关闭
+func (s *TicketAttachmentService) Open(ctx context.Context, ticketID, attachmentID uint) (*models.TicketAttachment, io.ReadCloser, error) {
+	var attachment models.TicketAttachment
+	err := s.db.WithContext(ctx).
+		Where("id = ? AND ticket_id = ? AND deleted_at IS NULL", attachmentID, ticketID).
+		First(&attachment).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil, ErrAttachmentNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get attachment: %w", err)
+	}
+	reader, err := s.storage.Open(ctx, attachment.StoragePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	if attachment.Encryption.Encrypted() {
+		if s.cipher == nil {
+			reader.Close()
+			return nil, nil, ErrAttachmentEncryptionDisabled
+		}
+		decrypted, err := s.cipher.Decrypt(ctx, attachment.Encryption, reader)
+		if err != nil {
+			reader.Close()
+			return nil, nil, err
+		}
+		reader = decrypted
+	}
+	s.db.WithContext(ctx).Model(&models.TicketAttachment{}).Where("id = ?", attachment.ID).
+		UpdateColumn("download_count", gorm.Expr("download_count + 1"))
+	return &attachment, reader, nil
+}
+
+// ResolveCommentAttachments 校验评论引用的附件：须是发表者上传到该工单、尚未关联评论的附件。
+// 附件策略可能在上传后调整，按工单当前适用的策略重新检查类型与大小，未通过病毒扫描的附件不能引用
+func (s *TicketAttachmentService) ResolveCommentAttachments(ctx context.Context, ticketID, userID uint, attachmentIDs []uint) ([]*models.TicketAttachment, error) {
+	ids := make([]uint, 0, len(attachmentIDs))
+	seen := make(map[uint]bool, len(attachmentIDs))
+	for _, id := range attachmentIDs {
+		if id == 0 || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	if len(ids) > maxUploadsPerSubmit {
+		return nil, fmt.Errorf("%w: at most %d attachments per comment", ErrInvalidUpload, maxUploadsPerSubmit)
+	}
+
+	var found []*models.TicketAttachment
+	if err := s.db.WithContext(ctx).
+		Where("id IN ? AND ticket_id = ? AND uploaded_by = ? AND comment_id IS NULL AND deleted_at IS NULL", ids, ticketID, userID).
+		Find(&found).Error; err != nil {
+		return nil, fmt.Errorf("failed to get attachments: %w", err)
+	}
+	byID := make(map[uint]*models.TicketAttachment, len(found))
+	for _, attachment := range found {
+		byID[attachment.ID] = attachment
+	}
+
+	schema, err := s.ticketSchema(ctx, ticketID)
+	if err != nil {
+		return nil, err
+	}
+	rule := &models.AttachmentRule{AllowedMimeTypes: schema.AllowedMimeTypes}
+	attachments := make([]*models.TicketAttachment, 0, len(ids))
+	for _, id := range ids {
+		attachment, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("%w: %d", ErrAttachmentNotFound, id)
+		}
+		if attachment.VirusScan == "infected" {
+			return nil, &AttachmentRejectedError{Reason: AttachmentRejectInfected, MimeType: attachment.MimeType, Schema: schema}
+		}
+		if !rule.AllowsMimeType(attachment.MimeType) {
+			return nil, &AttachmentRejectedError{Reason: AttachmentRejectMimeType, MimeType: attachment.MimeType, Schema: schema}
+		}
+		if attachment.FileSize > schema.MaxFileSizeBytes {
+			return nil, &AttachmentRejectedError{Reason: AttachmentRejectTooLarge, MimeType: attachment.MimeType, Size: attachment.FileSize, Schema: schema}
+		}
+		attachments = append(attachments, attachment)
+	}
+	return attachments, nil
+}
+
+// linkCommentAttachments 在评论事务中将附件关联到评论，并把下载地址追加到评论的附件列表。
+// 只关联仍未关联评论的附件，同一附件被并发引用时只有一个评论成功
+func linkCommentAttachments(tx *gorm.DB, comment *models.TicketComment, attachments []*models.TicketAttachment) error {
+	if len(attachments) == 0 {
+		return nil
+	}
+
+	var links []string
+	if comment.Attachments != "" {
+		if err := json.Unmarshal([]byte(comment.Attachments), &links); err != nil {
+			links = nil
+		}
+	}
+	for _, attachment := range attachments {
+		result := tx.Model(&models.TicketAttachment{}).
+			Where("id = ? AND comment_id IS NULL", attachment.ID).
+			Update("comment_id", comment.ID)
+		if result.Error != nil {
+			return fmt.Errorf("failed to link attachment: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: %d", ErrAttachmentNotFound, attachment.ID)
+		}
+		attachment.CommentID = &comment.ID
+		links = append(links, fmt.Sprintf(ticketAttachmentDownloadPath, comment.TicketID, attachment.ID))
+	}
+
+	data, _ := json.Marshal(links)
+	if err := tx.Model(&models.TicketComment{}).Where("id = ?", comment.ID).
+		Update("attachments", string(data)).Error; err != nil {
+		return fmt.Errorf("failed to update comment attachments: %w", err)
+	}
+	comment.Attachments = string(data)
+	return nil
+}
+
+// putFile 写入附件文件，配置了加解密器时加密后写入并返回加密元数据
+func (s *TicketAttachmentService) putFile(ctx context.Context, key string, r io.Reader, mimeType string) (models.AttachmentEncryption, error) {
+	if s.cipher == nil {
+		return models.AttachmentEncryption{}, s.storage.Put(ctx, key, r, mimeType)
+	}
+	encrypted, encryption, err := s.cipher.Encrypt(ctx, r, time.Now())
+	if err != nil {
+		return models.AttachmentEncryption{}, err
+	}
+	if err := s.storage.Put(ctx, key, encrypted, "application/octet-stream"); err != nil {
+		return models.AttachmentEncryption{}, err
+	}
+	return *encryption, nil
+}
+
+func (s *TicketAttachmentService) deleteStored(ctx context.Context, key string) {
+	if err := s.storage.Delete(ctx, key); err != nil {
+		log.Printf("Warning: failed to delete rejected attachment %s: %v", key, err)
+	}
+}
+
+// ticketSchema 获取工单分类适用的附件约束
+func (s *TicketAttachmentService) ticketSchema(ctx context.Context, ticketID uint) (models.AttachmentSchema, error) {
+	var ticket models.Ticket
+	err := s.db.WithContext(ctx).Select("id", "category_id", "subcategory_id").
+		Where("id = ? AND deleted_at IS NULL", ticketID).First(&ticket).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return models.AttachmentSchema{}, ErrAttachmentTicketNotFound
+	}
+	if err != nil {
+		return models.AttachmentSchema{}, fmt.Errorf("failed to get ticket: %w", err)
+	}
+
+	policy, err := s.GetPolicy(ctx)
+	if err != nil {
+		return models.AttachmentSchema{}, err
+	}
+	return attachmentSchema(policy, ticket.CategoryID, ticket.SubcategoryID), nil
+}
+
+// countTicketAttachments 统计工单现有的附件数
+func countTicketAttachments(db *gorm.DB, ticketID uint) (int64, error) {
+	var count int64
+	if err := db.Model(&models.TicketAttachment{}).
+		Where("ticket_id = ? AND deleted_at IS NULL", ticketID).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count attachments: %w", err)
+	}
+	return count, nil
+}
+
+// attachmentOriginalName 取上传文件名的基本名及小写扩展名，过长的扩展名视为没有扩展名
+func attachmentOriginalName(fileName string) (string, string) {
+	originalName := filepath.Base(strings.ReplaceAll(fileName, "\\", "/"))
+	if originalName == "." || originalName == "/" {
+		originalName = "attachment"
+	}
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(originalName), "."))
+	if len(ext) > 10 {
+		ext = ""
+	}
+	return originalName, ext
+}
+
+// attachmentSchema 计算分类适用的附件约束
+func attachmentSchema(policy *models.AttachmentPolicy, categoryID, subcategoryID *uint) models.AttachmentSchema {
+	rule, source := policy.RuleFor(categoryID, subcategoryID)
+	maxBytes := rule.MaxFileSizeBytes()
+	return models.AttachmentSchema{
+		MaxFileSizeMB:    int(maxBytes >> 20),
+		MaxFileSizeBytes: maxBytes,
+		AllowedMimeTypes: rule.AllowedMimeTypes,
+		MaxCount:         rule.MaxCount,
+		SourceCategoryID: source,
+	}
+}
+
+// attachmentKey 生成附件存储键，随机文件名避免猜测与覆盖
+func attachmentKey(ticketID uint, ext string) (string, error) {
+	random := make([]byte, attachmentStorageKeyRandomSize)
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("failed to generate attachment name: %w", err)
+	}
+	name := hex.EncodeToString(random)
+	if ext != "" {
+		name += "." + ext
+	}
+	return fmt.Sprintf("attachments/%d/%s", ticketID, name), nil
+}
+
+// officeMimeTypes Office 文档的 MIME 类型，内容识别只能得到容器格式（zip 或 OLE）
+var officeMimeTypes = map[string]string{
+	"doc":  "application/msword",
+	"xls":  "application/vnd.ms-excel",
+	"ppt":  "application/vnd.ms-powerpoint",
+	"docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	"xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	"pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+}
+
+// oleMagic OLE 复合文档（doc/xls/ppt）的文件头
+var oleMagic = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+// attachmentMimeType 按文件内容识别 MIME 类型；Office 文档仅在容器格式与扩展名一致时按扩展名细化
+func attachmentMimeType(head []byte, ext string) string {
+	mimeType := strings.SplitN(http.DetectContentType(head), ";", 2)[0]
+	office, ok := officeMimeTypes[ext]
+	if !ok {
+		return mimeType
+	}
+	switch {
+	case mimeType == "application/zip" && strings.HasSuffix(ext, "x"):
+		return office
+	case bytes.HasPrefix(head, oleMagic) && !strings.HasSuffix(ext, "x"):
+		return office
+	}
+	return mimeType
+}
+
+// attachmentFileType 按 MIME 类型归类附件
+func attachmentFileType(mimeType, ext string) models.AttachmentType {
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		return models.AttachmentTypeImage
+	case strings.HasPrefix(mimeType, "video/"):
+		return models.AttachmentTypeVideo
+	case strings.HasPrefix(mimeType, "audio/"):
+		return models.AttachmentTypeAudio
+	case mimeType == "application/zip", mimeType == "application/x-gzip", mimeType == "application/x-rar-compressed":
+		return models.AttachmentTypeArchive
+	case mimeType == "application/pdf", strings.HasPrefix(mimeType, "text/"), officeMimeTypes[ext] == mimeType:
+		return models.AttachmentTypeDocument
+	}
+	return models.AttachmentTypeOther
+}
+
+// attachmentCounter 统计写入的字节数并计算哈希
+type attachmentCounter struct {
+	size int64
+	hash hash.Hash
+}
+
+func (c *attachmentCounter) Write(p []byte) (int, error) {
+	c.size += int64(len(p))
+	return c.hash.Write(p)
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestTicketAttachment_CategoryPolicyEnforcedAtUpload(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:ticket_attachment_service_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Category{}, &models.Ticket{}, &models.TicketHistory{}, &models.TicketAttachment{},
+		&models.SystemConfig{}, &models.QuotaAlert{}, &models.Notification{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+
+	ctx := context.Background()
+	svc := NewTicketAttachmentService(db, NewLocalFileStorage(t.TempDir(), "/uploads"))
+
+	user := models.User{Username: "att-user", Email: "att-user@example.com", PasswordHash: "x", Role: models.RoleAgent, Status: models.UserStatusActive}
+	db.Create(&user)
+	billing := models.Category{Name: "账单", Slug: "att-billing", Type: models.CategoryTypeBilling, Status: models.CategoryStatusActive, CreatedBy: user.ID, Template: "请提供发票号"}
+	invoices := models.Category{Name: "发票", Slug: "att-invoices", Type: models.CategoryTypeBilling, Status: models.CategoryStatusActive, CreatedBy: user.ID}
+	db.Create(&billing)
+	invoices.ParentID = &billing.ID
+	db.Create(&invoices)
+
+	policy := models.GetDefaultAttachmentPolicy()
+	policy.Categories[billing.ID] = models.AttachmentRule{MaxFileSizeMB: 1, AllowedMimeTypes: []string{"application/pdf"}, MaxCount: 2}
+	policy.Categories[invoices.ID] = models.AttachmentRule{MaxFileSizeMB: 2, AllowedMimeTypes: []string{"image/*"}}
+	if err := svc.SetPolicy(ctx, policy, user.ID); err != nil {
+		t.Fatalf("set policy failed: %v", err)
+	}
+	if err := svc.SetPolicy(ctx, &models.AttachmentPolicy{Default: models.AttachmentRule{AllowedMimeTypes: []string{"pdf"}}}, user.ID); err == nil {
+		t.Fatalf("expected invalid mime type to be rejected")
+	}
+
+	// 表单约束：子分类规则优先，模板继承父分类
+	schema, err := svc.FormSchema(ctx, &billing.ID, &invoices.ID)
+	if err != nil {
+		t.Fatalf("form schema failed: %v", err)
+	}
+	if schema.Template != "请提供发票号" || schema.Attachments.MaxFileSizeBytes != 2<<20 ||
+		schema.Attachments.SourceCategoryID == nil || *schema.Attachments.SourceCategoryID != invoices.ID {
+		t.Fatalf("unexpected form schema: %+v", schema)
+	}
+	if schema, _ := svc.FormSchema(ctx, nil, nil); schema.Attachments.MaxCount != 20 || schema.Attachments.SourceCategoryID != nil {
+		t.Fatalf("expected default attachment rule, got %+v", schema.Attachments)
+	}
+	missing := uint(9999)
+	if _, err := svc.FormSchema(ctx, &missing, nil); !errors.Is(err, ErrCategoryNotActive) {
+		t.Fatalf("expected unknown category error, got %v", err)
+	}
+
+	ticket := models.Ticket{TicketNumber: "ATT-1", Title: "发票问题", Status: models.TicketStatusOpen, Priority: models.TicketPriorityNormal,
+		Type: models.TicketTypeRequest, Source: models.TicketSourceWeb, CreatedByID: user.ID, CategoryID: &billing.ID}
+	db.Create(&ticket)
+
+	pdf := []byte("%PDF-1.4\n" + strings.Repeat("x", 1024))
+	png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 64)...)
+	upload := func(name string, data []byte, declared int64) (*models.TicketAttachment, error) {
+		return svc.Upload(ctx, ticket.ID, user.ID, name, declared, bytes.NewReader(data))
+	}
+	rejectedReason := func(err error) string {
+		var rejected *AttachmentRejectedError
+		if !errors.As(err, &rejected) || !errors.Is(err, ErrAttachmentRejected) || rejected.Message() == "" {
+			t.Fatalf("expected attachment rejection, got %v", err)
+		}
+		return rejected.Reason
+	}
+
+	attachment, err := upload("../../发票.pdf", pdf, int64(len(pdf)))
+	if err != nil {
+		t.Fatalf("upload pdf failed: %v", err)
+	}
+	if attachment.OriginalName != "发票.pdf" || attachment.MimeType != "application/pdf" || attachment.FileSize != int64(len(pdf)) ||
+		attachment.FileType != models.AttachmentTypeDocument || !strings.HasPrefix(attachment.StoragePath, "attachments/") {
+		t.Fatalf("unexpected attachment: %+v", attachment)
+	}
+
+	// 按内容识别类型：改扩展名的图片仍被拒绝
+	if reason := rejectedReason(func() error { _, err := upload("fake.pdf", png, int64(len(png))); return err }()); reason != AttachmentRejectMimeType {
+		t.Fatalf("expected mime rejection, got %s", reason)
+	}
+	// 声明大小超限时直接拒绝；未声明大小时按实际读取的内容拒绝
+	if reason := rejectedReason(func() error { _, err := upload("big.pdf", pdf, 2<<20); return err }()); reason != AttachmentRejectTooLarge {
+		t.Fatalf("expected size rejection, got %s", reason)
+	}
+	big := append([]byte("%PDF-1.4\n"), make([]byte, 1<<20)...)
+	if reason := rejectedReason(func() error { _, err := upload("big.pdf", big, 0); return err }()); reason != AttachmentRejectTooLarge {
+		t.Fatalf("expected size rejection for streamed file, got %s", reason)
+	}
+
+	if _, err := upload("second.pdf", pdf, int64(len(pdf))); err != nil {
+		t.Fatalf("upload second pdf failed: %v", err)
+	}
+	if reason := rejectedReason(func() error { _, err := upload("third.pdf", pdf, int64(len(pdf))); return err }()); reason != AttachmentRejectTooManyFiles {
+		t.Fatalf("expected count rejection, got %s", reason)
+	}
+
+	attachments, err := svc.List(ctx, ticket.ID)
+	if err != nil || len(attachments) != 2 {
+		t.Fatalf("expected only accepted attachments to be stored, got %d (%v)", len(attachments), err)
+	}
+	var history int64
+	db.Model(&models.TicketHistory{}).Where("ticket_id = ? AND action = ?", ticket.ID, models.HistoryActionAttachment).Count(&history)
+	if history != 2 {
+		t.Fatalf("expected attachment history for each upload, got %d", history)
+	}
+
+	stored, reader, err := svc.Open(ctx, ticket.ID, attachment.ID)
+	if err != nil {
+		t.Fatalf("open attachment failed: %v", err)
+	}
+	data, _ := io.ReadAll(reader)
+	reader.Close()
+	if !bytes.Equal(data, pdf) || stored.OriginalName != "发票.pdf" {
+		t.Fatalf("unexpected attachment content")
+	}
+	if _, _, err := svc.Open(ctx, ticket.ID+1, attachment.ID); !errors.Is(err, ErrAttachmentNotFound) {
+		t.Fatalf("expected attachment of another ticket to be hidden, got %v", err)
+	}
+}
+
+func TestAttachmentMimeType_OfficeDocuments(t *testing.T) {
+	zipHead := []byte("PK\x03\x04" + strings.Repeat("\x00", 30))
+	ole := append([]byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}, make([]byte, 32)...)
+
+	cases := []struct {
+		head []byte
+		ext  string
+		want string
+	}{
+		{zipHead, "docx", "application/vnd.openxmlformats-officedocument.wordprocessingml.document"},
+		{zipHead, "zip", "application/zip"},
+		{zipHead, "doc", "application/zip"},
+		{ole, "xls", "application/vnd.ms-excel"},
+		{ole, "xlsx", "application/octet-stream"},
+		{[]byte("plain text"), "docx", "text/plain"},
+	}
+	for _, tc := range cases {
+		if got := attachmentMimeType(tc.head, tc.ext); got != tc.want {
+			t.Errorf("attachmentMimeType(%q) = %s, want %s", tc.ext, got, tc.want)
+		}
+	}
+}
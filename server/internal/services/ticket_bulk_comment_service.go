@@ -0,0 +1,340 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrTicketBulkCommentNotFound 批量评论任务不存在
+	ErrTicketBulkCommentNotFound = errors.New("ticket bulk comment job not found")
+	// ErrInvalidTicketBulkComment 批量评论请求无效
+	ErrInvalidTicketBulkComment = errors.New("invalid ticket bulk comment")
+)
+
+// bulkCommentNotifyTicketLimit 汇总通知中最多列出的工单数
+const bulkCommentNotifyTicketLimit = 10
+
+// TicketBulkCommentService 批量评论服务：故障期间向大量工单发布同一进展，
+// 每个工单按评论可见范围规则单独发表，结束后按接收人汇总通知
+type TicketBulkCommentService struct {
+	db                  *gorm.DB
+	commentService      *TicketCommentService
+	notificationService NotificationServiceInterface
+}
+
+// NewTicketBulkCommentService 创建批量评论服务
+func NewTicketBulkCommentService(db *gorm.DB, commentService *TicketCommentService) *TicketBulkCommentService {
+	if commentService == nil {
+		commentService = NewTicketCommentService(db, nil)
+	}
+	return &TicketBulkCommentService{
+		db:                  db,
+		commentService:      commentService,
+		notificationService: NewNotificationService(db),
+	}
+}
+
+// CreateJob 创建批量评论任务并在后台执行，可见范围在创建时校验，逐个工单记录执行结果
+func (s *TicketBulkCommentService) CreateJob(ctx context.Context, req *models.TicketBulkCommentRequest, viewer CommentViewer) (*models.TicketBulkCommentJob, error) {
+	job, err := s.createJob(ctx, req, viewer)
+	if err != nil {
+		return nil, err
+	}
+	go s.runJob(context.Background(), job.ID, viewer)
+	return job, nil
+}
+
+func (s *TicketBulkCommentService) createJob(ctx context.Context, req *models.TicketBulkCommentRequest, viewer CommentViewer) (*models.TicketBulkCommentJob, error) {
+	if strings.TrimSpace(req.Content) == "" {
+		return nil, fmt.Errorf("%w: content is required", ErrInvalidTicketBulkComment)
+	}
+	if viewer.IsCustomer() {
+		return nil, ErrCommentVisibilityForbidden
+	}
+
+	seen := make(map[uint]bool, len(req.TicketIDs))
+	ticketIDs := make([]uint, 0, len(req.TicketIDs))
+	for _, id := range req.TicketIDs {
+		if id != 0 && !seen[id] {
+			seen[id] = true
+			ticketIDs = append(ticketIDs, id)
+		}
+	}
+	if len(ticketIDs) == 0 {
+		return nil, fmt.Errorf("%w: ticket_ids is empty", ErrInvalidTicketBulkComment)
+	}
+
+	commentType := req.Type
+	if commentType == "" {
+		commentType = models.CommentTypePublic
+	}
+	if commentType != models.CommentTypePublic && commentType != models.CommentTypeInternal {
+		return nil, fmt.Errorf("%w: invalid comment type %s", ErrInvalidTicketBulkComment, commentType)
+	}
+	// 可见范围在创建时确定，执行期间角色默认值变更不影响已创建的任务
+	createReq := &models.TicketCommentCreateRequest{Type: req.Type, Visibility: req.Visibility, TeamID: req.TeamID}
+	visibility, err := s.commentService.resolveCreateVisibility(ctx, createReq, commentType, viewer)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.commentService.checkVisibilityAllowed(ctx, viewer, visibility, req.TeamID); err != nil {
+		return nil, err
+	}
+
+	contentType := req.ContentType
+	if contentType == "" {
+		contentType = "text"
+	}
+	job := &models.TicketBulkCommentJob{
+		Content:         req.Content,
+		ContentType:     contentType,
+		Type:            commentType,
+		Visibility:      visibility,
+		IgnoreReplyLock: req.IgnoreReplyLock,
+		TicketIDList:    ticketIDs,
+		Status:          models.TicketBulkCommentPending,
+		Total:           len(ticketIDs),
+		CreatedByID:     viewer.UserID,
+	}
+	if visibility == models.CommentVisibilityTeam {
+		job.VisibleTeamID = req.TeamID
+	}
+	if err := s.db.WithContext(ctx).Create(job).Error; err != nil {
+		return nil, fmt.Errorf("failed to create bulk comment job: %w", err)
+	}
+	return job, nil
+}
+
+// GetJob 获取批量评论任务及逐个工单的结果，仅创建者、管理员和主管可查看
+func (s *TicketBulkCommentService) GetJob(ctx context.Context, id uint, viewer CommentViewer) (*models.TicketBulkCommentJob, error) {
+	var job models.TicketBulkCommentJob
+	if err := s.db.WithContext(ctx).First(&job, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTicketBulkCommentNotFound
+		}
+		return nil, fmt.Errorf("failed to get bulk comment job: %w", err)
+	}
+	if job.CreatedByID != viewer.UserID && !viewer.seesAllTeams() {
+		return nil, ErrTicketBulkCommentNotFound
+	}
+	return &job, nil
+}
+
+// ListJobs 分页获取当前用户创建的批量评论任务，按创建时间倒序
+func (s *TicketBulkCommentService) ListJobs(ctx context.Context, userID uint, page, pageSize int) ([]*models.TicketBulkCommentJob, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	query := s.db.WithContext(ctx).Model(&models.TicketBulkCommentJob{}).Where("created_by_id = ?", userID)
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count bulk comment jobs: %w", err)
+	}
+	var jobs []*models.TicketBulkCommentJob
+	if err := query.Order("created_at DESC").
+		Offset((page - 1) * pageSize).Limit(pageSize).Find(&jobs).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to get bulk comment jobs: %w", err)
+	}
+	return jobs, total, nil
+}
+
+// runJob 逐个工单发表评论，每处理一个工单保存一次进度，全部完成后发送汇总通知
+func (s *TicketBulkCommentService) runJob(ctx context.Context, jobID uint, viewer CommentViewer) {
+	var job models.TicketBulkCommentJob
+	if err := s.db.WithContext(ctx).First(&job, jobID).Error; err != nil {
+		log.Printf("Failed to load bulk comment job %d: %v", jobID, err)
+		return
+	}
+
+	now := time.Now()
+	job.Status = models.TicketBulkCommentRunning
+	job.StartedAt = &now
+	if err := s.db.WithContext(ctx).Save(&job).Error; err != nil {
+		log.Printf("Failed to start bulk comment job %d: %v", jobID, err)
+		return
+	}
+
+	// 接收人 -> 该接收人可见的已评论工单
+	pending := make(map[uint][]*models.Ticket)
+	for _, ticketID := range job.TicketIDList {
+		result := models.TicketBulkCommentResult{TicketID: ticketID, Success: true}
+		ticket, comment, err := s.commentTicket(ctx, &job, ticketID, viewer)
+		if ticket != nil {
+			result.TicketNumber = ticket.TicketNumber
+		}
+		if err != nil {
+			result.Success = false
+			result.Error = err.Error()
+			job.Failed++
+		} else {
+			result.CommentID = comment.ID
+			job.Succeeded++
+			for _, recipientID := range s.recipients(ctx, ticket, comment) {
+				pending[recipientID] = append(pending[recipientID], ticket)
+			}
+		}
+		job.ResultList = append(job.ResultList, result)
+		if err := s.db.WithContext(ctx).Save(&job).Error; err != nil {
+			log.Printf("Failed to save progress of bulk comment job %d: %v", jobID, err)
+			s.db.WithContext(ctx).Model(&models.TicketBulkCommentJob{}).Where("id = ?", jobID).
+				Updates(map[string]interface{}{"status": models.TicketBulkCommentFailed, "error": err.Error()})
+			return
+		}
+	}
+
+	job.Notified = s.notifyRecipients(ctx, &job, pending)
+	finished := time.Now()
+	job.Status = models.TicketBulkCommentCompleted
+	job.FinishedAt = &finished
+	if err := s.db.WithContext(ctx).Save(&job).Error; err != nil {
+		log.Printf("Failed to finish bulk comment job %d: %v", jobID, err)
+	}
+}
+
+// commentTicket 按工单渲染模板变量后发表评论
+func (s *TicketBulkCommentService) commentTicket(ctx context.Context, job *models.TicketBulkCommentJob, ticketID uint, viewer CommentViewer) (*models.Ticket, *models.TicketComment, error) {
+	var ticket models.Ticket
+	if err := s.db.WithContext(ctx).First(&ticket, ticketID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, fmt.Errorf("ticket not found")
+		}
+		return nil, nil, fmt.Errorf("failed to get ticket: %w", err)
+	}
+
+	req := &models.TicketCommentCreateRequest{
+		TicketID:        ticketID,
+		Content:         renderTicketVariables(job.Content, &ticket),
+		ContentType:     job.ContentType,
+		Type:            job.Type,
+		Visibility:      job.Visibility,
+		TeamID:          job.VisibleTeamID,
+		IgnoreReplyLock: job.IgnoreReplyLock,
+		Metadata:        map[string]interface{}{"bulk_comment_job_id": job.ID},
+	}
+	comment, err := s.commentService.createComment(ctx, ticketID, req, viewer, true)
+	if err != nil {
+		return &ticket, nil, err
+	}
+	return &ticket, comment, nil
+}
+
+// recipients 工单处理人、创建人及评论中@提及团队的成员，不含发表者和看不到该评论的用户
+func (s *TicketBulkCommentService) recipients(ctx context.Context, ticket *models.Ticket, comment *models.TicketComment) []uint {
+	candidates := []uint{ticket.CreatedByID}
+	if ticket.AssignedToID != nil {
+		candidates = append(candidates, *ticket.AssignedToID)
+	}
+	if slugs := models.ExtractTeamMentions(comment.Content); len(slugs) > 0 {
+		var memberIDs []uint
+		if err := s.db.WithContext(ctx).Table("team_members").
+			Joins("JOIN teams ON teams.id = team_members.team_id").
+			Where("teams.slug IN ? AND teams.is_active = ?", slugs, true).
+			Pluck("team_members.user_id", &memberIDs).Error; err != nil {
+			log.Printf("Failed to load mentioned team members for ticket %d: %v", ticket.ID, err)
+		}
+		candidates = append(candidates, memberIDs...)
+	}
+
+	seen := map[uint]bool{comment.UserID: true}
+	ids := make([]uint, 0, len(candidates))
+	for _, id := range candidates {
+		if id != 0 && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var users []models.User
+	if err := s.db.WithContext(ctx).Where("id IN ?", ids).Find(&users).Error; err != nil {
+		log.Printf("Failed to load notification recipients for ticket %d: %v", ticket.ID, err)
+		return nil
+	}
+	visible := make([]uint, 0, len(users))
+	for i := range users {
+		if commentVisibleToUser(s.db.WithContext(ctx), comment, &users[i]) {
+			visible = append(visible, users[i].ID)
+		}
+	}
+	return visible
+}
+
+// notifyRecipients 每个接收人只发送一条通知，涉及多个工单时汇总列出，返回发出的通知数
+func (s *TicketBulkCommentService) notifyRecipients(ctx context.Context, job *models.TicketBulkCommentJob, pending map[uint][]*models.Ticket) int {
+	recipientIDs := make([]uint, 0, len(pending))
+	for id := range pending {
+		recipientIDs = append(recipientIDs, id)
+	}
+	sort.Slice(recipientIDs, func(i, j int) bool { return recipientIDs[i] < recipientIDs[j] })
+
+	sent := 0
+	for _, recipientID := range recipientIDs {
+		tickets := pending[recipientID]
+		numbers := make([]string, 0, len(tickets))
+		for _, ticket := range tickets {
+			numbers = append(numbers, ticket.TicketNumber)
+		}
+
+		req := &models.NotificationCreateRequest{
+			Type:        models.NotificationTypeTicketCommented,
+			Priority:    models.NotificationPriorityNormal,
+			Channel:     models.NotificationChannelInApp,
+			RecipientID: recipientID,
+			SenderID:    &job.CreatedByID,
+			Metadata: map[string]interface{}{
+				"bulk_comment_job_id": job.ID,
+				"ticket_numbers":      numbers,
+			},
+		}
+		if len(tickets) == 1 {
+			ticket := tickets[0]
+			req.Title = fmt.Sprintf("工单有新回复 - %s", ticket.Title)
+			req.Content = truncateString(renderTicketVariables(job.Content, ticket), 200)
+			req.RelatedType = "ticket"
+			req.RelatedID = &ticket.ID
+			req.RelatedTicketID = &ticket.ID
+			req.ActionURL = fmt.Sprintf("/tickets/%d", ticket.ID)
+		} else {
+			listed := numbers
+			if len(listed) > bulkCommentNotifyTicketLimit {
+				listed = listed[:bulkCommentNotifyTicketLimit]
+			}
+			req.Title = fmt.Sprintf("%d 个工单有新回复", len(tickets))
+			req.Content = fmt.Sprintf("工单 #%s", strings.Join(listed, "、#"))
+			if len(numbers) > len(listed) {
+				req.Content += fmt.Sprintf(" 等 %d 个", len(numbers))
+			}
+			// 内容含工单变量时各工单不同，只列出工单
+			if strings.Contains(job.Content, "{{ticket.") {
+				req.Content += " 收到批量回复"
+			} else {
+				req.Content += " 收到批量回复：" + truncateString(job.Content, 200)
+			}
+			req.RelatedType = "ticket_bulk_comment"
+			req.RelatedID = &job.ID
+			req.ActionURL = "/tickets"
+		}
+
+		if _, err := s.notificationService.CreateNotification(ctx, req); err != nil {
+			log.Printf("Failed to notify user %d of bulk comment job %d: %v", recipientID, job.ID, err)
+			continue
+		}
+		sent++
+	}
+	return sent
+}
@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestTicketBulkComment_TemplatesLocksAndBatchedNotifications(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:ticket_bulk_comment?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Team{}, &models.Ticket{}, &models.TicketComment{}, &models.SystemConfig{},
+		&models.TicketCommentDraft{}, &models.TicketReplyLock{}, &models.Notification{},
+		&models.TicketBulkCommentJob{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+
+	alice := models.User{Username: "bulk-alice", Email: "bulk-alice@example.com", PasswordHash: "x", Role: models.RoleAgent, Status: models.UserStatusActive}
+	bob := models.User{Username: "bulk-bob", Email: "bulk-bob@example.com", PasswordHash: "x", Role: models.RoleAgent, Status: models.UserStatusActive}
+	carol := models.User{Username: "bulk-carol", Email: "bulk-carol@example.com", PasswordHash: "x", Role: models.RoleCustomer, Status: models.UserStatusActive}
+	admin := models.User{Username: "bulk-admin", Email: "bulk-admin@example.com", PasswordHash: "x", Role: models.RoleAdmin, Status: models.UserStatusActive}
+	for _, user := range []*models.User{&alice, &bob, &carol, &admin} {
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("failed to seed user: %v", err)
+		}
+	}
+	first := models.Ticket{TicketNumber: "T-BULK-1", Title: "VPN 无法连接", CreatedByID: carol.ID, AssignedToID: &bob.ID}
+	second := models.Ticket{TicketNumber: "T-BULK-2", Title: "邮件延迟", CreatedByID: alice.ID, AssignedToID: &bob.ID}
+	for _, ticket := range []*models.Ticket{&first, &second} {
+		if err := db.Create(ticket).Error; err != nil {
+			t.Fatalf("failed to seed ticket: %v", err)
+		}
+	}
+
+	ctx := context.Background()
+	comments := NewTicketCommentService(db, nil)
+	svc := NewTicketBulkCommentService(db, comments)
+	aliceViewer := CommentViewer{UserID: alice.ID, Role: string(models.RoleAgent)}
+
+	// bob 正在回复第二个工单
+	if _, err := comments.DraftService().SaveDraft(ctx, second.ID, &models.CommentDraftSaveRequest{Content: "稍等", Lock: true},
+		CommentViewer{UserID: bob.ID, Role: string(models.RoleAgent)}); err != nil {
+		t.Fatalf("save draft failed: %v", err)
+	}
+
+	job, err := svc.createJob(ctx, &models.TicketBulkCommentRequest{
+		TicketIDs: []uint{first.ID, second.ID, first.ID, 999999},
+		Content:   "故障处理中（{{ticket.number}}）",
+		Type:      models.CommentTypePublic,
+	}, aliceViewer)
+	if err != nil {
+		t.Fatalf("create job failed: %v", err)
+	}
+	if job.Total != 3 || job.Status != models.TicketBulkCommentPending {
+		t.Fatalf("expected 3 deduplicated tickets, got %+v", job)
+	}
+	svc.runJob(ctx, job.ID, aliceViewer)
+
+	job, err = svc.GetJob(ctx, job.ID, aliceViewer)
+	if err != nil {
+		t.Fatalf("get job failed: %v", err)
+	}
+	if job.Status != models.TicketBulkCommentCompleted || job.Succeeded != 1 || job.Failed != 2 || len(job.ResultList) != 3 {
+		t.Fatalf("unexpected job result: %+v", job)
+	}
+	if !job.ResultList[0].Success || job.ResultList[1].Success || !strings.Contains(job.ResultList[1].Error, "is replying") ||
+		job.ResultList[2].Success || job.ResultList[2].Error != "ticket not found" {
+		t.Fatalf("expected locked and missing tickets to fail, got %+v", job.ResultList)
+	}
+	var comment models.TicketComment
+	db.First(&comment, job.ResultList[0].CommentID)
+	if comment.Content != "故障处理中（T-BULK-1）" || comment.Visibility != models.CommentVisibilityPublic {
+		t.Fatalf("expected rendered public comment, got %+v", comment)
+	}
+	// 处理人和客户各收到一条通知
+	if job.Notified != 2 {
+		t.Fatalf("expected 2 notifications, got %d", job.Notified)
+	}
+
+	// 内部评论忽略回复锁：客户不会收到通知，bob 两个工单只收到一条汇总
+	internal, err := svc.createJob(ctx, &models.TicketBulkCommentRequest{
+		TicketIDs:       []uint{first.ID, second.ID},
+		Content:         "内部跟进：上游已恢复",
+		Type:            models.CommentTypeInternal,
+		IgnoreReplyLock: true,
+	}, aliceViewer)
+	if err != nil {
+		t.Fatalf("create internal job failed: %v", err)
+	}
+	if internal.Visibility != models.CommentVisibilityInternal {
+		t.Fatalf("expected internal visibility, got %s", internal.Visibility)
+	}
+	svc.runJob(ctx, internal.ID, aliceViewer)
+	internal, _ = svc.GetJob(ctx, internal.ID, aliceViewer)
+	if internal.Succeeded != 2 || internal.Notified != 1 {
+		t.Fatalf("expected 2 comments and 1 summary notification, got %+v", internal)
+	}
+	var summary models.Notification
+	if err := db.Where("recipient_id = ?", bob.ID).Order("id DESC").First(&summary).Error; err != nil {
+		t.Fatalf("expected summary notification for bob: %v", err)
+	}
+	if summary.Title != "2 个工单有新回复" || !strings.Contains(summary.Content, "T-BULK-2") {
+		t.Fatalf("unexpected summary notification: %+v", summary)
+	}
+	var customerNotifications int64
+	db.Model(&models.Notification{}).Where("recipient_id = ?", carol.ID).Count(&customerNotifications)
+	if customerNotifications != 1 {
+		t.Fatalf("expected customer to be notified of the public comment only, got %d", customerNotifications)
+	}
+
+	if _, err := svc.createJob(ctx, &models.TicketBulkCommentRequest{TicketIDs: []uint{first.ID}, Content: "hi"},
+		CommentViewer{UserID: carol.ID, Role: string(models.RoleCustomer)}); !errors.Is(err, ErrCommentVisibilityForbidden) {
+		t.Fatalf("expected customers to be rejected, got %v", err)
+	}
+	if _, err := svc.GetJob(ctx, job.ID, CommentViewer{UserID: bob.ID, Role: string(models.RoleAgent)}); !errors.Is(err, ErrTicketBulkCommentNotFound) {
+		t.Fatalf("expected other agents not to see the job, got %v", err)
+	}
+	if _, err := svc.GetJob(ctx, job.ID, CommentViewer{UserID: admin.ID, Role: string(models.RoleAdmin)}); err != nil {
+		t.Fatalf("expected admin to see the job, got %v", err)
+	}
+	if jobs, total, err := svc.ListJobs(ctx, alice.ID, 1, 20); err != nil || total != 2 || len(jobs) != 2 {
+		t.Fatalf("expected 2 jobs for alice, got %d (%v)", total, err)
+	}
+}
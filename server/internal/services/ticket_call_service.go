@@ -0,0 +1,325 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"gongdan-system/internal/models"
+)
+
+var (
+	// ErrCallTicketNotFound 关联的工单不存在
+	ErrCallTicketNotFound = errors.New("ticket not found")
+	// ErrInvalidCallLog 通话记录参数无效
+	ErrInvalidCallLog = errors.New("invalid call log")
+)
+
+// TicketCallService 电话渠道通话记录服务
+type TicketCallService struct {
+	db            *gorm.DB
+	ticketService TicketServiceInterface
+	now           func() time.Time
+}
+
+// NewTicketCallService 创建通话记录服务
+func NewTicketCallService(db *gorm.DB) *TicketCallService {
+	return &TicketCallService{
+		db:            db,
+		ticketService: NewTicketService(db),
+		now:           time.Now,
+	}
+}
+
+// LogCall 记录一次通话：指定工单时关联到该工单，否则新建来源为电话的工单。
+// 通话同时写入工单动态。携带呼叫中心通话ID且已记录过时返回已有记录，duplicate 为 true
+func (s *TicketCallService) LogCall(ctx context.Context, req *models.TicketCallRequest, userID uint) (*models.TicketCallLog, bool, error) {
+	if req.RecordingURL != "" {
+		parsed, err := url.Parse(req.RecordingURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return nil, false, fmt.Errorf("%w: recording_url must be an http(s) url", ErrInvalidCallLog)
+		}
+	}
+	if req.ExternalCallID != "" {
+		var existing models.TicketCallLog
+		err := s.db.WithContext(ctx).Where("external_call_id = ?", req.ExternalCallID).First(&existing).Error
+		if err == nil {
+			return &existing, true, nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, false, fmt.Errorf("failed to check call log: %w", err)
+		}
+	}
+
+	agentID := userID
+	if req.AgentID != nil {
+		agentID = *req.AgentID
+	}
+	var agent models.User
+	if err := s.db.WithContext(ctx).First(&agent, agentID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, false, fmt.Errorf("%w: agent %d not found", ErrInvalidCallLog, agentID)
+		}
+		return nil, false, fmt.Errorf("failed to get agent: %w", err)
+	}
+	if agent.Role == models.RoleCustomer {
+		return nil, false, fmt.Errorf("%w: user %d is not an agent", ErrInvalidCallLog, agentID)
+	}
+
+	startedAt := s.now().Add(-time.Duration(req.DurationSeconds) * time.Second)
+	if req.StartedAt != nil {
+		startedAt = *req.StartedAt
+	}
+
+	call := &models.TicketCallLog{
+		AgentID:         agentID,
+		Direction:       req.Direction,
+		PhoneNumber:     strings.TrimSpace(req.PhoneNumber),
+		StartedAt:       startedAt,
+		DurationSeconds: req.DurationSeconds,
+		RecordingURL:    req.RecordingURL,
+		ExternalCallID:  req.ExternalCallID,
+		Notes:           req.Notes,
+		LoggedByID:      userID,
+	}
+
+	if req.TicketID != nil {
+		var count int64
+		if err := s.db.WithContext(ctx).Model(&models.Ticket{}).
+			Where("id = ? AND deleted_at IS NULL", *req.TicketID).Count(&count).Error; err != nil {
+			return nil, false, fmt.Errorf("failed to check ticket: %w", err)
+		}
+		if count == 0 {
+			return nil, false, ErrCallTicketNotFound
+		}
+		call.TicketID = *req.TicketID
+	} else {
+		ticket, err := s.ticketService.CreateTicket(ctx, callTicketRequest(req, call.PhoneNumber), userID)
+		if err != nil {
+			return nil, false, err
+		}
+		call.TicketID = ticket.ID
+		call.CreatedTicket = true
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(call).Error; err != nil {
+			return fmt.Errorf("failed to create call log: %w", err)
+		}
+		return recordHistory(tx, nil, callHistory(call, userID))
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	call.Agent = &agent
+	return call, false, nil
+}
+
+// callTicketRequest 由通话新建工单，未填写标题时按通话方向和号码生成
+func callTicketRequest(req *models.TicketCallRequest, phone string) *models.TicketCreateRequest {
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		title = callDirectionLabel(req.Direction)
+		if phone != "" {
+			title += " " + phone
+		}
+	}
+	description := strings.TrimSpace(req.Description)
+	if description == "" {
+		description = req.Notes
+	}
+	if description == "" {
+		description = title
+	}
+	ticketType := req.Type
+	if ticketType == "" {
+		ticketType = models.TicketTypeRequest
+	}
+	priority := req.Priority
+	if priority == "" {
+		priority = models.TicketPriorityNormal
+	}
+	return &models.TicketCreateRequest{
+		Title:         title,
+		Description:   description,
+		Type:          ticketType,
+		Priority:      priority,
+		Source:        models.TicketSourcePhone,
+		CategoryID:    req.CategoryID,
+		CustomerName:  req.CustomerName,
+		CustomerEmail: req.CustomerEmail,
+		CustomerPhone: phone,
+	}
+}
+
+// callHistory 通话在工单动态中的记录。动态对客户可见，备注和录音地址只保存在通话记录中
+func callHistory(call *models.TicketCallLog, userID uint) *models.TicketHistory {
+	description := fmt.Sprintf("%s，通话 %s", callDirectionLabel(call.Direction), formatCallDuration(call.DurationSeconds))
+	if call.PhoneNumber != "" {
+		description = fmt.Sprintf("%s %s，通话 %s", callDirectionLabel(call.Direction), call.PhoneNumber, formatCallDuration(call.DurationSeconds))
+	}
+	metadata, _ := json.Marshal(map[string]interface{}{
+		"call_id":   call.ID,
+		"direction": call.Direction,
+		"agent_id":  call.AgentID,
+	})
+	duration := call.DurationSeconds
+	startedAt := call.StartedAt
+	return &models.TicketHistory{
+		TicketID:    call.TicketID,
+		UserID:      &userID,
+		Action:      models.HistoryActionCall,
+		Description: description,
+		Metadata:    models.JSONText(metadata),
+		Duration:    &duration,
+		ScheduledAt: &startedAt,
+	}
+}
+
+func callDirectionLabel(direction models.CallDirection) string {
+	if direction == models.CallDirectionOutbound {
+		return "外呼"
+	}
+	return "来电"
+}
+
+// formatCallDuration 通话时长，如 3分20秒
+func formatCallDuration(seconds int) string {
+	if seconds < 60 {
+		return fmt.Sprintf("%d秒", seconds)
+	}
+	if seconds < 3600 {
+		return fmt.Sprintf("%d分%d秒", seconds/60, seconds%60)
+	}
+	return fmt.Sprintf("%d小时%d分", seconds/3600, seconds%3600/60)
+}
+
+// ListCalls 工单的通话记录，按通话时间倒序
+func (s *TicketCallService) ListCalls(ctx context.Context, ticketID uint) ([]*models.TicketCallLog, error) {
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.Ticket{}).
+		Where("id = ? AND deleted_at IS NULL", ticketID).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to check ticket: %w", err)
+	}
+	if count == 0 {
+		return nil, ErrCallTicketNotFound
+	}
+
+	var calls []*models.TicketCallLog
+	if err := s.db.WithContext(ctx).Preload("Agent").
+		Where("ticket_id = ?", ticketID).
+		Order("started_at DESC, id DESC").
+		Find(&calls).Error; err != nil {
+		return nil, fmt.Errorf("failed to list calls: %w", err)
+	}
+	return calls, nil
+}
+
+// callVolumeRecord 统计用的通话及所属工单分类
+type callVolumeRecord struct {
+	AgentID         uint
+	Direction       models.CallDirection
+	DurationSeconds int
+	CreatedTicket   bool
+	CategoryID      *uint
+}
+
+// CallVolume 统计区间内的通话量，groupBy 为 agent（按客服）或 category（按工单分类），按通话数倒序
+func (s *TicketCallService) CallVolume(ctx context.Context, groupBy string, start, end time.Time) ([]*models.CallVolumeRow, error) {
+	if groupBy != "agent" && groupBy != "category" {
+		return nil, fmt.Errorf("%w: group_by must be agent or category", ErrInvalidCallLog)
+	}
+
+	var records []callVolumeRecord
+	if err := s.db.WithContext(ctx).Table("ticket_call_logs").
+		Select("ticket_call_logs.agent_id, ticket_call_logs.direction, ticket_call_logs.duration_seconds, ticket_call_logs.created_ticket, tickets.category_id").
+		Joins("JOIN tickets ON tickets.id = ticket_call_logs.ticket_id").
+		Where("ticket_call_logs.started_at >= ? AND ticket_call_logs.started_at < ?", start, end).
+		Scan(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to get call volume: %w", err)
+	}
+
+	rows := make(map[uint]*models.CallVolumeRow)
+	for _, record := range records {
+		key := record.AgentID
+		if groupBy == "category" {
+			key = 0
+			if record.CategoryID != nil {
+				key = *record.CategoryID
+			}
+		}
+		row, ok := rows[key]
+		if !ok {
+			row = &models.CallVolumeRow{Key: key}
+			rows[key] = row
+		}
+		row.Calls++
+		if record.Direction == models.CallDirectionOutbound {
+			row.Outbound++
+		} else {
+			row.Inbound++
+		}
+		row.TotalSeconds += record.DurationSeconds
+		if record.CreatedTicket {
+			row.NewTickets++
+		}
+	}
+
+	keys := make([]uint, 0, len(rows))
+	for key := range rows {
+		keys = append(keys, key)
+	}
+	names, err := s.callVolumeNames(ctx, groupBy, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*models.CallVolumeRow, 0, len(rows))
+	for key, row := range rows {
+		row.Name = names[key]
+		if row.Name == "" && key == 0 {
+			row.Name = "未分类"
+		}
+		row.AvgSeconds = row.TotalSeconds / row.Calls
+		result = append(result, row)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Calls != result[j].Calls {
+			return result[i].Calls > result[j].Calls
+		}
+		return result[i].Key < result[j].Key
+	})
+	return result, nil
+}
+
+func (s *TicketCallService) callVolumeNames(ctx context.Context, groupBy string, keys []uint) (map[uint]string, error) {
+	names := make(map[uint]string, len(keys))
+	if len(keys) == 0 {
+		return names, nil
+	}
+	if groupBy == "agent" {
+		var users []models.User
+		if err := s.db.WithContext(ctx).Where("id IN ?", keys).Find(&users).Error; err != nil {
+			return nil, fmt.Errorf("failed to get agents: %w", err)
+		}
+		for i := range users {
+			names[users[i].ID] = users[i].GetFullName()
+		}
+		return names, nil
+	}
+	var categories []models.Category
+	if err := s.db.WithContext(ctx).Where("id IN ?", keys).Find(&categories).Error; err != nil {
+		return nil, fmt.Errorf("failed to get categories: %w", err)
+	}
+	for i := range categories {
+		names[categories[i].ID] = categories[i].Name
+	}
+	return names, nil
+}
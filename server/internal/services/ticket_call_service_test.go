@@ -0,0 +1,144 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestTicketCall_LogCallCreatesOrAttachesTicket(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:ticket_call_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Team{}, &models.Ticket{}, &models.TicketHistory{}, &models.SystemConfig{},
+		&models.Category{}, &models.TicketCallLog{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+
+	ctx := context.Background()
+	now := time.Date(2024, 5, 6, 10, 0, 0, 0, time.UTC)
+	svc := NewTicketCallService(db)
+	svc.now = func() time.Time { return now }
+
+	agent := models.User{Username: "call-agent", Email: "call-agent@example.com", PasswordHash: "x", Role: models.RoleAgent, Status: models.UserStatusActive}
+	other := models.User{Username: "call-agent2", Email: "call-agent2@example.com", PasswordHash: "x", Role: models.RoleAgent, Status: models.UserStatusActive}
+	customer := models.User{Username: "call-customer", Email: "call-customer@example.com", PasswordHash: "x", Role: models.RoleCustomer, Status: models.UserStatusActive}
+	for _, user := range []*models.User{&agent, &other, &customer} {
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("failed to seed user: %v", err)
+		}
+	}
+	category := models.Category{Name: "网络故障", Slug: "call-network"}
+	if err := db.Create(&category).Error; err != nil {
+		t.Fatalf("failed to seed category: %v", err)
+	}
+
+	// 未指定工单时新建来源为电话的工单
+	created, duplicate, err := svc.LogCall(ctx, &models.TicketCallRequest{
+		Direction:       models.CallDirectionInbound,
+		PhoneNumber:     " 13800000000 ",
+		DurationSeconds: 200,
+		RecordingURL:    "https://pbx.example.com/rec/1.mp3",
+		ExternalCallID:  "pbx-1",
+		Notes:           "客户反映无法上网",
+		CategoryID:      &category.ID,
+	}, agent.ID)
+	if err != nil || duplicate {
+		t.Fatalf("log call failed: %v duplicate=%v", err, duplicate)
+	}
+	if !created.CreatedTicket || created.TicketID == 0 || created.PhoneNumber != "13800000000" {
+		t.Fatalf("unexpected call log: %+v", created)
+	}
+	if !created.StartedAt.Equal(now.Add(-200 * time.Second)) {
+		t.Fatalf("expected started_at to be derived from duration, got %v", created.StartedAt)
+	}
+	var ticket models.Ticket
+	if err := db.First(&ticket, created.TicketID).Error; err != nil {
+		t.Fatalf("failed to load ticket: %v", err)
+	}
+	if ticket.Source != models.TicketSourcePhone || ticket.Title != "来电 13800000000" || ticket.Description != "客户反映无法上网" {
+		t.Fatalf("unexpected ticket from call: source=%s title=%q description=%q", ticket.Source, ticket.Title, ticket.Description)
+	}
+
+	var history models.TicketHistory
+	if err := db.Where("ticket_id = ? AND action = ?", ticket.ID, models.HistoryActionCall).First(&history).Error; err != nil {
+		t.Fatalf("expected call entry in ticket history: %v", err)
+	}
+	if history.Description != "来电 13800000000，通话 3分20秒" || history.Details != "" || history.Duration == nil || *history.Duration != 200 {
+		t.Fatalf("unexpected call history: %+v", history)
+	}
+
+	// 呼叫中心重复上报同一通话时返回已有记录
+	again, duplicate, err := svc.LogCall(ctx, &models.TicketCallRequest{Direction: models.CallDirectionInbound, ExternalCallID: "pbx-1"}, agent.ID)
+	if err != nil || !duplicate || again.ID != created.ID {
+		t.Fatalf("expected duplicate call to return existing log, got %+v duplicate=%v err=%v", again, duplicate, err)
+	}
+
+	// 关联到已有工单，由其他客服外呼
+	attached, _, err := svc.LogCall(ctx, &models.TicketCallRequest{
+		TicketID:        &ticket.ID,
+		Direction:       models.CallDirectionOutbound,
+		DurationSeconds: 40,
+		AgentID:         &other.ID,
+	}, agent.ID)
+	if err != nil {
+		t.Fatalf("attach call failed: %v", err)
+	}
+	if attached.CreatedTicket || attached.TicketID != ticket.ID || attached.AgentID != other.ID || attached.LoggedByID != agent.ID {
+		t.Fatalf("unexpected attached call: %+v", attached)
+	}
+
+	missing := uint(9999)
+	if _, _, err := svc.LogCall(ctx, &models.TicketCallRequest{TicketID: &missing, Direction: models.CallDirectionInbound}, agent.ID); !errors.Is(err, ErrCallTicketNotFound) {
+		t.Fatalf("expected missing ticket to be rejected, got %v", err)
+	}
+	if _, _, err := svc.LogCall(ctx, &models.TicketCallRequest{TicketID: &ticket.ID, Direction: models.CallDirectionInbound, RecordingURL: "ftp://pbx/1.mp3"}, agent.ID); !errors.Is(err, ErrInvalidCallLog) {
+		t.Fatalf("expected non-http recording url to be rejected, got %v", err)
+	}
+	if _, _, err := svc.LogCall(ctx, &models.TicketCallRequest{TicketID: &ticket.ID, Direction: models.CallDirectionInbound, AgentID: &customer.ID}, agent.ID); !errors.Is(err, ErrInvalidCallLog) {
+		t.Fatalf("expected customer to be rejected as call agent, got %v", err)
+	}
+
+	calls, err := svc.ListCalls(ctx, ticket.ID)
+	if err != nil {
+		t.Fatalf("list calls failed: %v", err)
+	}
+	if len(calls) != 2 || calls[0].ID != attached.ID || calls[0].Agent == nil {
+		t.Fatalf("expected calls newest first with agent loaded, got %+v", calls)
+	}
+
+	// 未分类工单上的来电
+	if _, _, err := svc.LogCall(ctx, &models.TicketCallRequest{Direction: models.CallDirectionInbound, DurationSeconds: 60, Title: "咨询账单"}, agent.ID); err != nil {
+		t.Fatalf("log uncategorized call failed: %v", err)
+	}
+
+	start, end := now.Add(-24*time.Hour), now.Add(time.Hour)
+	byAgent, err := svc.CallVolume(ctx, "agent", start, end)
+	if err != nil {
+		t.Fatalf("call volume by agent failed: %v", err)
+	}
+	if len(byAgent) != 2 || byAgent[0].Key != agent.ID || byAgent[0].Calls != 2 || byAgent[0].Inbound != 2 || byAgent[0].NewTickets != 2 || byAgent[0].AvgSeconds != 130 {
+		t.Fatalf("unexpected call volume by agent: %+v", byAgent[0])
+	}
+	if byAgent[1].Key != other.ID || byAgent[1].Outbound != 1 || byAgent[1].TotalSeconds != 40 {
+		t.Fatalf("unexpected call volume for second agent: %+v", byAgent[1])
+	}
+
+	byCategory, err := svc.CallVolume(ctx, "category", start, end)
+	if err != nil {
+		t.Fatalf("call volume by category failed: %v", err)
+	}
+	if len(byCategory) != 2 || byCategory[0].Name != "网络故障" || byCategory[0].Calls != 2 || byCategory[1].Name != "未分类" || byCategory[1].Calls != 1 {
+		t.Fatalf("unexpected call volume by category: %+v %+v", byCategory[0], byCategory[1])
+	}
+
+	if _, err := svc.CallVolume(ctx, "team", start, end); !errors.Is(err, ErrInvalidCallLog) {
+		t.Fatalf("expected unsupported group_by to be rejected, got %v", err)
+	}
+}
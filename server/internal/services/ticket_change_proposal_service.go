@@ -0,0 +1,443 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+// KeyTicketChangeProposalPolicy 工单变更提案策略配置键
+const KeyTicketChangeProposalPolicy = "ticket.change_proposal_policy"
+
+var (
+	// ErrChangeProposalNotFound 提案不存在
+	ErrChangeProposalNotFound = errors.New("change proposal not found")
+	// ErrChangeProposalReviewed 提案已审核，不能重复处理
+	ErrChangeProposalReviewed = errors.New("change proposal has already been reviewed")
+	// ErrChangeProposalForbidden 当前角色无权审核或查看提案
+	ErrChangeProposalForbidden = errors.New("not allowed to review this change proposal")
+	// ErrChangeProposalSelfReview 不能审核自己提交的提案
+	ErrChangeProposalSelfReview = errors.New("cannot review your own change proposal")
+	// ErrChangeProposalEmpty 提案与工单当前值相比没有任何变更
+	ErrChangeProposalEmpty = errors.New("change proposal contains no changes")
+)
+
+// TicketChangeProposalService 工单变更提案服务：指定角色的编辑先生成提案，审核通过后原子地应用到工单
+type TicketChangeProposalService struct {
+	db            *gorm.DB
+	ticketService *TicketService
+}
+
+// NewTicketChangeProposalService 创建变更提案服务
+func NewTicketChangeProposalService(db *gorm.DB) *TicketChangeProposalService {
+	return &TicketChangeProposalService{
+		db:            db,
+		ticketService: NewTicketService(db).(*TicketService),
+	}
+}
+
+// GetPolicy 获取变更提案策略
+func (s *TicketChangeProposalService) GetPolicy(ctx context.Context) (*models.ChangeProposalPolicy, error) {
+	var config models.SystemConfig
+	err := s.db.WithContext(ctx).
+		Where("key = ? AND is_active = ?", KeyTicketChangeProposalPolicy, true).
+		First(&config).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.GetDefaultChangeProposalPolicy(), nil
+		}
+		return nil, fmt.Errorf("failed to get change proposal policy: %w", err)
+	}
+
+	policy := &models.ChangeProposalPolicy{}
+	if err := config.GetJSONValue(policy); err != nil {
+		log.Printf("Warning: failed to parse change proposal policy, using defaults: %v", err)
+		return models.GetDefaultChangeProposalPolicy(), nil
+	}
+	if err := policy.Validate(); err != nil {
+		log.Printf("Warning: invalid change proposal policy, using defaults: %v", err)
+		return models.GetDefaultChangeProposalPolicy(), nil
+	}
+	return policy, nil
+}
+
+// SetPolicy 保存变更提案策略
+func (s *TicketChangeProposalService) SetPolicy(ctx context.Context, policy *models.ChangeProposalPolicy, userID uint) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	var existing models.SystemConfig
+	err := s.db.WithContext(ctx).Where("key = ?", KeyTicketChangeProposalPolicy).First(&existing).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to check existing policy: %w", err)
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		config := models.SystemConfig{
+			Key:         KeyTicketChangeProposalPolicy,
+			Category:    CategoryTicket,
+			Group:       "workflow",
+			Description: "工单变更提案（编辑需审核）策略",
+			IsActive:    true,
+			UpdatedBy:   &userID,
+		}
+		if err := config.SetValue(policy); err != nil {
+			return fmt.Errorf("failed to set policy value: %w", err)
+		}
+		if err := s.db.WithContext(ctx).Create(&config).Error; err != nil {
+			return fmt.Errorf("failed to create policy: %w", err)
+		}
+		return nil
+	}
+
+	if err := existing.SetValue(policy); err != nil {
+		return fmt.Errorf("failed to set policy value: %w", err)
+	}
+	existing.UpdatedBy = &userID
+	existing.Version++
+
+	if err := s.db.WithContext(ctx).Save(&existing).Error; err != nil {
+		return fmt.Errorf("failed to update policy: %w", err)
+	}
+	return nil
+}
+
+// RequiresReview 判断该角色的工单编辑是否需要提交审核
+func (s *TicketChangeProposalService) RequiresReview(ctx context.Context, role string) bool {
+	policy, err := s.GetPolicy(ctx)
+	if err != nil {
+		log.Printf("Warning: failed to load change proposal policy: %v", err)
+		return false
+	}
+	return policy.RequiresReview(role)
+}
+
+// Propose 提交变更提案，工单保持不变直到审核通过
+func (s *TicketChangeProposalService) Propose(ctx context.Context, ticketID uint, req *models.TicketUpdateRequest, proposerID uint) (*models.ChangeProposalResponse, error) {
+	ticket, err := s.ticketService.GetTicket(ctx, ticketID)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := previewTicketChanges(ticket, req)
+	if len(diff) == 0 {
+		return nil, ErrChangeProposalEmpty
+	}
+
+	changes, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode changes: %w", err)
+	}
+
+	proposal := &models.TicketChangeProposal{
+		TicketID:   ticketID,
+		ProposerID: proposerID,
+		Status:     models.ChangeProposalPending,
+		Changes:    string(changes),
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(proposal).Error; err != nil {
+			return fmt.Errorf("failed to create change proposal: %w", err)
+		}
+		return tx.Create(&models.TicketHistory{
+			TicketID:    ticketID,
+			UserID:      &proposerID,
+			Action:      models.HistoryActionUpdate,
+			Description: fmt.Sprintf("提交变更提案 #%d（待审核）：%s", proposal.ID, diffFieldNames(diff)),
+			FieldName:   "change_proposal",
+			NewValue:    string(models.ChangeProposalPending),
+			IsVisible:   true,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ChangeProposalResponse{TicketChangeProposal: proposal, RequestedChanges: req, Diff: diff}, nil
+}
+
+// ChangeProposalFilters 提案列表过滤条件
+type ChangeProposalFilters struct {
+	Status     models.ChangeProposalStatus
+	TicketID   uint
+	ProposerID uint // 非审核角色只能查看自己提交的提案
+	Page       int
+	PageSize   int
+}
+
+// List 获取变更提案列表
+func (s *TicketChangeProposalService) List(ctx context.Context, filters ChangeProposalFilters) ([]*models.TicketChangeProposal, int64, error) {
+	query := s.db.WithContext(ctx).Model(&models.TicketChangeProposal{})
+	if filters.Status != "" {
+		query = query.Where("status = ?", filters.Status)
+	}
+	if filters.TicketID != 0 {
+		query = query.Where("ticket_id = ?", filters.TicketID)
+	}
+	if filters.ProposerID != 0 {
+		query = query.Where("proposer_id = ?", filters.ProposerID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count change proposals: %w", err)
+	}
+
+	if filters.Page < 1 {
+		filters.Page = 1
+	}
+	if filters.PageSize < 1 || filters.PageSize > 100 {
+		filters.PageSize = 20
+	}
+
+	var proposals []*models.TicketChangeProposal
+	if err := query.Preload("Proposer").Preload("Reviewer").
+		Order("created_at DESC").
+		Offset((filters.Page - 1) * filters.PageSize).Limit(filters.PageSize).
+		Find(&proposals).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list change proposals: %w", err)
+	}
+	return proposals, total, nil
+}
+
+// Get 获取提案详情及与工单当前值的差异；非审核角色只能查看自己的提案
+func (s *TicketChangeProposalService) Get(ctx context.Context, id uint, userID uint, role string) (*models.ChangeProposalResponse, error) {
+	proposal, err := s.load(ctx, s.db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	policy, err := s.GetPolicy(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if proposal.ProposerID != userID && !policy.CanReview(role) {
+		return nil, ErrChangeProposalForbidden
+	}
+
+	req, err := proposal.UpdateRequest()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode changes: %w", err)
+	}
+	resp := &models.ChangeProposalResponse{TicketChangeProposal: proposal, RequestedChanges: req}
+	if proposal.Status == models.ChangeProposalPending {
+		ticket, err := s.ticketService.GetTicket(ctx, proposal.TicketID)
+		if err != nil {
+			return nil, err
+		}
+		resp.Diff = previewTicketChanges(ticket, req)
+	}
+	return resp, nil
+}
+
+// Approve 批准提案：在同一事务内应用变更、更新提案状态并记录审核历史。
+// 字段变更历史归属提交人，批准记录归属审核人
+func (s *TicketChangeProposalService) Approve(ctx context.Context, id uint, reviewerID uint, role string, comment string) (*models.Ticket, error) {
+	proposal, err := s.checkReviewable(ctx, id, reviewerID, role)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := proposal.UpdateRequest()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode changes: %w", err)
+	}
+
+	ticket, err := s.ticketService.GetTicket(ctx, proposal.TicketID)
+	if err != nil {
+		return nil, err
+	}
+	fields := diffFieldNames(previewTicketChanges(ticket, req))
+
+	return s.ticketService.updateTicket(ctx, proposal.TicketID, req, proposal.ProposerID, func(tx *gorm.DB, _ *models.Ticket) error {
+		if err := s.markReviewed(tx, proposal, models.ChangeProposalApproved, reviewerID, comment); err != nil {
+			return err
+		}
+		description := fmt.Sprintf("批准用户 ID: %d 提交的变更提案 #%d", proposal.ProposerID, proposal.ID)
+		if fields != "" {
+			description += "：" + fields
+		}
+		return tx.Create(&models.TicketHistory{
+			TicketID:    proposal.TicketID,
+			UserID:      &reviewerID,
+			Action:      models.HistoryActionApprove,
+			Description: description,
+			FieldName:   "change_proposal",
+			OldValue:    string(models.ChangeProposalPending),
+			NewValue:    string(models.ChangeProposalApproved),
+			IsVisible:   true,
+			IsImportant: true,
+		}).Error
+	})
+}
+
+// Reject 驳回提案，工单保持不变
+func (s *TicketChangeProposalService) Reject(ctx context.Context, id uint, reviewerID uint, role string, comment string) (*models.TicketChangeProposal, error) {
+	proposal, err := s.checkReviewable(ctx, id, reviewerID, role)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := s.markReviewed(tx, proposal, models.ChangeProposalRejected, reviewerID, comment); err != nil {
+			return err
+		}
+		description := fmt.Sprintf("驳回用户 ID: %d 提交的变更提案 #%d", proposal.ProposerID, proposal.ID)
+		if comment != "" {
+			description += "：" + comment
+		}
+		return tx.Create(&models.TicketHistory{
+			TicketID:    proposal.TicketID,
+			UserID:      &reviewerID,
+			Action:      models.HistoryActionReject,
+			Description: description,
+			FieldName:   "change_proposal",
+			OldValue:    string(models.ChangeProposalPending),
+			NewValue:    string(models.ChangeProposalRejected),
+			IsVisible:   true,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.load(ctx, s.db, id)
+}
+
+func (s *TicketChangeProposalService) checkReviewable(ctx context.Context, id uint, reviewerID uint, role string) (*models.TicketChangeProposal, error) {
+	policy, err := s.GetPolicy(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !policy.CanReview(role) {
+		return nil, ErrChangeProposalForbidden
+	}
+
+	proposal, err := s.load(ctx, s.db, id)
+	if err != nil {
+		return nil, err
+	}
+	if proposal.ProposerID == reviewerID {
+		return nil, ErrChangeProposalSelfReview
+	}
+	if proposal.Status != models.ChangeProposalPending {
+		return nil, ErrChangeProposalReviewed
+	}
+	return proposal, nil
+}
+
+// markReviewed 以状态为条件更新提案，防止并发审核重复处理
+func (s *TicketChangeProposalService) markReviewed(tx *gorm.DB, proposal *models.TicketChangeProposal, status models.ChangeProposalStatus, reviewerID uint, comment string) error {
+	now := time.Now()
+	result := tx.Model(&models.TicketChangeProposal{}).
+		Where("id = ? AND status = ?", proposal.ID, models.ChangeProposalPending).
+		Updates(map[string]interface{}{
+			"status":         status,
+			"reviewer_id":    reviewerID,
+			"review_comment": comment,
+			"reviewed_at":    now,
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update change proposal: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrChangeProposalReviewed
+	}
+	return nil
+}
+
+func (s *TicketChangeProposalService) load(ctx context.Context, db *gorm.DB, id uint) (*models.TicketChangeProposal, error) {
+	var proposal models.TicketChangeProposal
+	if err := db.WithContext(ctx).Preload("Proposer").Preload("Reviewer").First(&proposal, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrChangeProposalNotFound
+		}
+		return nil, fmt.Errorf("failed to get change proposal: %w", err)
+	}
+	return &proposal, nil
+}
+
+// previewTicketChanges 计算变更请求相对工单当前值的差异（不含优先级矩阵推导的优先级）
+func previewTicketChanges(ticket *models.Ticket, req *models.TicketUpdateRequest) []models.TicketFieldChange {
+	after := *ticket
+	if req.Title != nil {
+		after.Title = *req.Title
+	}
+	if req.Description != nil {
+		after.Description = *req.Description
+	}
+	if req.Status != nil {
+		after.Status = *req.Status
+	}
+	if req.Priority != nil {
+		after.Priority = *req.Priority
+	}
+	if req.Type != nil {
+		after.Type = *req.Type
+	}
+	if req.Source != nil {
+		after.Source = *req.Source
+	}
+	if req.Impact != nil {
+		after.Impact = *req.Impact
+	}
+	if req.Urgency != nil {
+		after.Urgency = *req.Urgency
+	}
+	if req.AssignedToID != nil {
+		after.AssignedToID = req.AssignedToID
+	}
+	if req.AssignedTeamID != nil {
+		after.AssignedTeamID = req.AssignedTeamID
+		if *req.AssignedTeamID == 0 {
+			after.AssignedTeamID = nil
+		}
+	}
+	if req.DueDate != nil {
+		after.DueDate = req.DueDate
+	}
+	if req.SLADueDate != nil {
+		after.SLADueDate = req.SLADueDate
+	}
+	if req.CustomerEmail != nil {
+		after.CustomerEmail = *req.CustomerEmail
+	}
+	if req.CustomerPhone != nil {
+		after.CustomerPhone = *req.CustomerPhone
+	}
+	if req.CustomerName != nil {
+		after.CustomerName = *req.CustomerName
+	}
+	if req.IsConfidential != nil {
+		after.IsConfidential = *req.IsConfidential
+	}
+	if req.ResolutionCode != nil {
+		after.ResolutionCode = *req.ResolutionCode
+	}
+	if req.Tags != nil {
+		tagsBytes, _ := json.Marshal(req.Tags)
+		after.Tags = models.JSONText(tagsBytes)
+	}
+	if req.CustomFields != nil {
+		customFieldsBytes, _ := json.Marshal(req.CustomFields)
+		after.CustomFields = models.JSONText(customFieldsBytes)
+	}
+	return models.DiffTicketFields(ticket, &after)
+}
+
+func diffFieldNames(diff []models.TicketFieldChange) string {
+	fields := make([]string, 0, len(diff))
+	for _, change := range diff {
+		fields = append(fields, change.Field)
+	}
+	return strings.Join(fields, ", ")
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestChangeProposal_ApproveAppliesWithAttribution(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:change_proposal_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Team{}, &models.Ticket{}, &models.TicketHistory{},
+		&models.SystemConfig{}, &models.TicketChangeProposal{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+
+	ctx := context.Background()
+	svc := NewTicketChangeProposalService(db)
+
+	junior := models.User{Username: "cp-junior", Email: "cp-junior@example.com", PasswordHash: "x", Role: models.RoleAgent}
+	lead := models.User{Username: "cp-lead", Email: "cp-lead@example.com", PasswordHash: "x", Role: models.RoleSupervisor}
+	db.Create(&junior)
+	db.Create(&lead)
+
+	ticket := models.Ticket{TicketNumber: "CP-001", Title: "old title", Status: models.TicketStatusOpen, Priority: models.TicketPriorityNormal, Type: models.TicketTypeIncident, Source: models.TicketSourceWeb, CreatedByID: junior.ID}
+	if err := db.Create(&ticket).Error; err != nil {
+		t.Fatalf("failed to seed ticket: %v", err)
+	}
+
+	if svc.RequiresReview(ctx, string(models.RoleAgent)) {
+		t.Fatalf("expected change proposals to be disabled by default")
+	}
+	policy := models.GetDefaultChangeProposalPolicy()
+	policy.Enabled = true
+	if err := svc.SetPolicy(ctx, policy, lead.ID); err != nil {
+		t.Fatalf("set policy failed: %v", err)
+	}
+	if !svc.RequiresReview(ctx, string(models.RoleAgent)) || svc.RequiresReview(ctx, string(models.RoleSupervisor)) {
+		t.Fatalf("expected only agents to require review")
+	}
+
+	title := "new title"
+	priority := models.TicketPriorityHigh
+	proposal, err := svc.Propose(ctx, ticket.ID, &models.TicketUpdateRequest{Title: &title, Priority: &priority}, junior.ID)
+	if err != nil {
+		t.Fatalf("propose failed: %v", err)
+	}
+	if len(proposal.Diff) != 2 {
+		t.Fatalf("expected 2 changed fields, got %+v", proposal.Diff)
+	}
+
+	var unchanged models.Ticket
+	db.First(&unchanged, ticket.ID)
+	if unchanged.Title != "old title" {
+		t.Fatalf("ticket must not change before approval, got %q", unchanged.Title)
+	}
+
+	if _, err := svc.Approve(ctx, proposal.ID, junior.ID, string(models.RoleAgent), ""); !errors.Is(err, ErrChangeProposalForbidden) {
+		t.Fatalf("expected agent to be forbidden from reviewing, got %v", err)
+	}
+
+	updated, err := svc.Approve(ctx, proposal.ID, lead.ID, string(models.RoleSupervisor), "looks good")
+	if err != nil {
+		t.Fatalf("approve failed: %v", err)
+	}
+	if updated.Title != title || updated.Priority != priority {
+		t.Fatalf("expected changes to be applied, got %q/%s", updated.Title, updated.Priority)
+	}
+	if _, err := svc.Reject(ctx, proposal.ID, lead.ID, string(models.RoleSupervisor), ""); !errors.Is(err, ErrChangeProposalReviewed) {
+		t.Fatalf("expected reviewed proposal to be rejected, got %v", err)
+	}
+
+	// 字段变更归属提交人，批准记录归属审核人
+	var fieldHistory, approveHistory models.TicketHistory
+	db.Where("ticket_id = ? AND field_name = ?", ticket.ID, "title").First(&fieldHistory)
+	db.Where("ticket_id = ? AND action = ?", ticket.ID, models.HistoryActionApprove).First(&approveHistory)
+	if fieldHistory.UserID == nil || *fieldHistory.UserID != junior.ID {
+		t.Fatalf("expected field change attributed to proposer, got %+v", fieldHistory.UserID)
+	}
+	if approveHistory.UserID == nil || *approveHistory.UserID != lead.ID {
+		t.Fatalf("expected approval attributed to reviewer, got %+v", approveHistory.UserID)
+	}
+
+	if _, err := svc.Propose(ctx, ticket.ID, &models.TicketUpdateRequest{Title: &title}, junior.ID); !errors.Is(err, ErrChangeProposalEmpty) {
+		t.Fatalf("expected empty proposal error, got %v", err)
+	}
+}
@@ -0,0 +1,373 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrChecklistItemNotFound 检查项不存在
+	ErrChecklistItemNotFound = errors.New("checklist item not found")
+	// ErrChecklistTicketNotFound 工单不存在
+	ErrChecklistTicketNotFound = errors.New("ticket not found")
+	// ErrChecklistTemplateNotFound 工单模板不存在
+	ErrChecklistTemplateNotFound = errors.New("ticket template not found")
+	// ErrInvalidChecklistItem 检查项参数无效
+	ErrInvalidChecklistItem = errors.New("invalid checklist item")
+	// ErrChecklistIncomplete 必填检查项未完成，不能解决工单
+	ErrChecklistIncomplete = errors.New("required checklist items are not done")
+)
+
+// TicketChecklistService 工单检查清单服务
+type TicketChecklistService struct {
+	db            *gorm.DB
+	configService *ConfigService
+}
+
+// NewTicketChecklistService 创建工单检查清单服务
+func NewTicketChecklistService(db *gorm.DB) *TicketChecklistService {
+	return &TicketChecklistService{
+		db:            db,
+		configService: NewConfigService(db),
+	}
+}
+
+// List 获取工单的检查项及完成进度
+func (s *TicketChecklistService) List(ctx context.Context, ticketID uint) ([]*models.TicketChecklistItem, *models.ChecklistProgress, error) {
+	if err := s.ensureTicket(s.db.WithContext(ctx), ticketID); err != nil {
+		return nil, nil, err
+	}
+
+	var items []*models.TicketChecklistItem
+	if err := s.db.WithContext(ctx).
+		Preload("Assignee").
+		Where("ticket_id = ?", ticketID).
+		Order("position ASC, id ASC").
+		Find(&items).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to list checklist items: %w", err)
+	}
+
+	progress := &models.ChecklistProgress{}
+	for _, item := range items {
+		progress.Add(item.IsDone, item.IsRequired)
+	}
+	return items, progress, nil
+}
+
+// Add 向工单追加检查项，排在末尾
+func (s *TicketChecklistService) Add(ctx context.Context, ticketID uint, req *models.TicketChecklistItemRequest, userID uint) (*models.TicketChecklistItem, error) {
+	text := strings.TrimSpace(req.Text)
+	if text == "" {
+		return nil, fmt.Errorf("%w: text is required", ErrInvalidChecklistItem)
+	}
+
+	item := &models.TicketChecklistItem{
+		TicketID:    ticketID,
+		Text:        text,
+		IsRequired:  req.IsRequired,
+		AssigneeID:  req.AssigneeID,
+		DueDate:     req.DueDate,
+		CreatedByID: userID,
+	}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := s.ensureTicket(tx, ticketID); err != nil {
+			return err
+		}
+		position, err := nextChecklistPosition(tx, ticketID)
+		if err != nil {
+			return err
+		}
+		item.Position = position
+		if err := tx.Create(item).Error; err != nil {
+			return fmt.Errorf("failed to create checklist item: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return item, nil
+}
+
+// Update 更新检查项，勾选或取消勾选时记录工单历史
+func (s *TicketChecklistService) Update(ctx context.Context, ticketID, itemID uint, req *models.TicketChecklistItemUpdateRequest, userID uint) (*models.TicketChecklistItem, error) {
+	var item models.TicketChecklistItem
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("ticket_id = ?", ticketID).First(&item, itemID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrChecklistItemNotFound
+			}
+			return fmt.Errorf("failed to get checklist item: %w", err)
+		}
+
+		if req.Text != nil {
+			text := strings.TrimSpace(*req.Text)
+			if text == "" {
+				return fmt.Errorf("%w: text is required", ErrInvalidChecklistItem)
+			}
+			item.Text = text
+		}
+		if req.IsRequired != nil {
+			item.IsRequired = *req.IsRequired
+		}
+		if req.AssigneeID != nil {
+			item.AssigneeID = req.AssigneeID
+			if *req.AssigneeID == 0 {
+				item.AssigneeID = nil
+			}
+		}
+		if req.ClearDueDate {
+			item.DueDate = nil
+		} else if req.DueDate != nil {
+			item.DueDate = req.DueDate
+		}
+
+		var history *models.TicketHistory
+		if req.IsDone != nil && *req.IsDone != item.IsDone {
+			item.IsDone = *req.IsDone
+			description := fmt.Sprintf("检查项「%s」已完成", item.Text)
+			if item.IsDone {
+				now := time.Now()
+				item.CompletedAt = &now
+				item.CompletedByID = &userID
+			} else {
+				item.CompletedAt = nil
+				item.CompletedByID = nil
+				description = fmt.Sprintf("检查项「%s」已重新打开", item.Text)
+			}
+			history = &models.TicketHistory{
+				TicketID:    ticketID,
+				UserID:      &userID,
+				Action:      models.HistoryActionUpdate,
+				Description: description,
+				FieldName:   "checklist",
+				NewValue:    fmt.Sprintf("%d", item.ID),
+				IsVisible:   true,
+			}
+		}
+
+		if err := tx.Omit("Assignee").Save(&item).Error; err != nil {
+			return fmt.Errorf("failed to update checklist item: %w", err)
+		}
+		if history != nil {
+			if err := tx.Create(history).Error; err != nil {
+				return fmt.Errorf("failed to record checklist history: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// Delete 删除检查项
+func (s *TicketChecklistService) Delete(ctx context.Context, ticketID, itemID uint) error {
+	result := s.db.WithContext(ctx).
+		Where("ticket_id = ?", ticketID).
+		Delete(&models.TicketChecklistItem{}, itemID)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete checklist item: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrChecklistItemNotFound
+	}
+	return nil
+}
+
+// Reorder 按给定顺序重排检查项，必须包含工单的全部检查项
+func (s *TicketChecklistService) Reorder(ctx context.Context, ticketID uint, itemIDs []uint) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing []uint
+		if err := tx.Model(&models.TicketChecklistItem{}).
+			Where("ticket_id = ?", ticketID).
+			Pluck("id", &existing).Error; err != nil {
+			return fmt.Errorf("failed to list checklist items: %w", err)
+		}
+
+		known := make(map[uint]bool, len(existing))
+		for _, id := range existing {
+			known[id] = true
+		}
+		if len(itemIDs) != len(existing) {
+			return fmt.Errorf("%w: item_ids must list every item of the ticket", ErrInvalidChecklistItem)
+		}
+		for _, id := range itemIDs {
+			if !known[id] {
+				return fmt.Errorf("%w: item %d does not belong to the ticket or is repeated", ErrInvalidChecklistItem, id)
+			}
+			delete(known, id)
+		}
+
+		for position, id := range itemIDs {
+			if err := tx.Model(&models.TicketChecklistItem{}).
+				Where("id = ?", id).
+				UpdateColumn("position", position).Error; err != nil {
+				return fmt.Errorf("failed to reorder checklist items: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// ApplyTemplate 将工单模板中的检查项追加到工单
+func (s *TicketChecklistService) ApplyTemplate(ctx context.Context, ticketID, templateID, userID uint) ([]*models.TicketChecklistItem, error) {
+	var template models.TicketTemplate
+	if err := s.db.WithContext(ctx).First(&template, templateID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrChecklistTemplateNotFound
+		}
+		return nil, fmt.Errorf("failed to get template: %w", err)
+	}
+	specs, err := template.GetChecklistItems()
+	if err != nil {
+		return nil, fmt.Errorf("template %d has invalid checklist items: %w", template.ID, err)
+	}
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("%w: template has no checklist items", ErrInvalidChecklistItem)
+	}
+
+	var items []*models.TicketChecklistItem
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := s.ensureTicket(tx, ticketID); err != nil {
+			return err
+		}
+		items, err = createChecklistFromTemplate(tx, ticketID, &template, specs, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// Progress 单个工单的检查项进度，没有检查项时返回 nil
+func (s *TicketChecklistService) Progress(ctx context.Context, ticketID uint) (*models.ChecklistProgress, error) {
+	progress, err := s.ProgressByTickets(ctx, []uint{ticketID})
+	if err != nil {
+		return nil, err
+	}
+	return progress[ticketID], nil
+}
+
+// ProgressByTickets 批量统计工单的检查项进度，没有检查项的工单不在结果中
+func (s *TicketChecklistService) ProgressByTickets(ctx context.Context, ticketIDs []uint) (map[uint]*models.ChecklistProgress, error) {
+	result := make(map[uint]*models.ChecklistProgress)
+	if len(ticketIDs) == 0 {
+		return result, nil
+	}
+
+	var items []models.TicketChecklistItem
+	if err := s.db.WithContext(ctx).
+		Select("ticket_id", "is_done", "is_required").
+		Where("ticket_id IN ?", ticketIDs).
+		Find(&items).Error; err != nil {
+		return nil, fmt.Errorf("failed to get checklist progress: %w", err)
+	}
+	for _, item := range items {
+		progress, ok := result[item.TicketID]
+		if !ok {
+			progress = &models.ChecklistProgress{}
+			result[item.TicketID] = progress
+		}
+		progress.Add(item.IsDone, item.IsRequired)
+	}
+	return result, nil
+}
+
+// blocksResolve 是否开启「必填检查项未完成时阻止解决工单」
+func (s *TicketChecklistService) blocksResolve() bool {
+	enabled, err := s.configService.GetConfigBool(KeyTicketChecklistBlockResolve)
+	return err == nil && enabled
+}
+
+// EnsureResolvable 开启阻止解决时，检查工单的必填检查项是否都已完成
+func (s *TicketChecklistService) EnsureResolvable(ctx context.Context, ticketIDs ...uint) error {
+	if len(ticketIDs) == 0 || !s.blocksResolve() {
+		return nil
+	}
+
+	var blocked []uint
+	if err := s.db.WithContext(ctx).Model(&models.TicketChecklistItem{}).
+		Distinct("ticket_id").
+		Where("ticket_id IN ? AND is_required = ? AND is_done = ?", ticketIDs, true, false).
+		Order("ticket_id ASC").
+		Pluck("ticket_id", &blocked).Error; err != nil {
+		return fmt.Errorf("failed to check checklist items: %w", err)
+	}
+	if len(blocked) > 0 {
+		return fmt.Errorf("%w: tickets %v", ErrChecklistIncomplete, blocked)
+	}
+	return nil
+}
+
+func (s *TicketChecklistService) ensureTicket(db *gorm.DB, ticketID uint) error {
+	var ticket models.Ticket
+	if err := db.Select("id").First(&ticket, ticketID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrChecklistTicketNotFound
+		}
+		return fmt.Errorf("failed to get ticket: %w", err)
+	}
+	return nil
+}
+
+// createChecklistFromTemplate 按模板定义在工单末尾创建检查项，截止时间相对当前时间计算
+func createChecklistFromTemplate(tx *gorm.DB, ticketID uint, template *models.TicketTemplate, specs []models.ChecklistTemplateItem, userID uint) ([]*models.TicketChecklistItem, error) {
+	position, err := nextChecklistPosition(tx, ticketID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	templateID := template.ID
+	items := make([]*models.TicketChecklistItem, 0, len(specs))
+	for _, spec := range specs {
+		text := strings.TrimSpace(spec.Text)
+		if text == "" {
+			continue
+		}
+		item := &models.TicketChecklistItem{
+			TicketID:    ticketID,
+			Text:        text,
+			IsRequired:  spec.IsRequired,
+			Position:    position,
+			CreatedByID: userID,
+			TemplateID:  &templateID,
+		}
+		if spec.DueInHours > 0 {
+			due := now.Add(time.Duration(spec.DueInHours) * time.Hour)
+			item.DueDate = &due
+		}
+		items = append(items, item)
+		position++
+	}
+	if len(items) == 0 {
+		return items, nil
+	}
+	if err := tx.Create(&items).Error; err != nil {
+		return nil, fmt.Errorf("failed to create checklist items: %w", err)
+	}
+	return items, nil
+}
+
+func nextChecklistPosition(tx *gorm.DB, ticketID uint) (int, error) {
+	var maxPosition *int
+	if err := tx.Model(&models.TicketChecklistItem{}).
+		Where("ticket_id = ?", ticketID).
+		Select("MAX(position)").
+		Scan(&maxPosition).Error; err != nil {
+		return 0, fmt.Errorf("failed to get checklist position: %w", err)
+	}
+	if maxPosition == nil {
+		return 0, nil
+	}
+	return *maxPosition + 1, nil
+}
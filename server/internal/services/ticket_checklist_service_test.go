@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestTicketChecklist_TemplateProgressAndResolveBlocking(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:ticket_checklist_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Team{}, &models.Ticket{}, &models.TicketComment{}, &models.TicketHistory{},
+		&models.SystemConfig{}, &models.TicketTemplate{}, &models.TicketChecklistItem{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+
+	ctx := context.Background()
+	agent := models.User{Username: "cl-agent", Email: "cl-agent@example.com", PasswordHash: "x", Role: models.RoleAgent}
+	db.Create(&agent)
+	ticket := models.Ticket{TicketNumber: "CL-1", Title: "onboarding", Status: models.TicketStatusOpen, Priority: models.TicketPriorityNormal,
+		Type: models.TicketTypeRequest, Source: models.TicketSourceWeb, CreatedByID: agent.ID}
+	db.Create(&ticket)
+
+	template, err := NewAutomationService(db).CreateTemplate(ctx, &models.TicketTemplateRequest{Name: "入职", Category: "hr",
+		ChecklistItems: []models.ChecklistTemplateItem{
+			{Text: "创建账号", IsRequired: true, DueInHours: 24},
+			{Text: "发放电脑", IsRequired: true},
+			{Text: "欢迎邮件"},
+		}}, agent.ID)
+	if err != nil {
+		t.Fatalf("create template failed: %v", err)
+	}
+
+	svc := NewTicketChecklistService(db)
+	items, err := svc.ApplyTemplate(ctx, ticket.ID, template.ID, agent.ID)
+	if err != nil {
+		t.Fatalf("apply template failed: %v", err)
+	}
+	if len(items) != 3 || items[0].DueDate == nil || items[1].DueDate != nil || items[2].Position != 2 {
+		t.Fatalf("unexpected items from template: %+v", items)
+	}
+	extra, err := svc.Add(ctx, ticket.ID, &models.TicketChecklistItemRequest{Text: "  归档合同 "}, agent.ID)
+	if err != nil || extra.Position != 3 || extra.Text != "归档合同" {
+		t.Fatalf("add item failed: %+v %v", extra, err)
+	}
+
+	done := true
+	if _, err := svc.Update(ctx, ticket.ID, items[2].ID, &models.TicketChecklistItemUpdateRequest{IsDone: &done}, agent.ID); err != nil {
+		t.Fatalf("complete item failed: %v", err)
+	}
+	progress, err := svc.Progress(ctx, ticket.ID)
+	if err != nil {
+		t.Fatalf("progress failed: %v", err)
+	}
+	if progress.Total != 4 || progress.Done != 1 || progress.Required != 2 || progress.RequiredDone != 0 || progress.Percent != 25 {
+		t.Fatalf("unexpected progress: %+v", progress)
+	}
+
+	if err := svc.Reorder(ctx, ticket.ID, []uint{extra.ID, items[0].ID}); !errors.Is(err, ErrInvalidChecklistItem) {
+		t.Fatalf("expected partial reorder to be rejected, got %v", err)
+	}
+	if err := svc.Reorder(ctx, ticket.ID, []uint{extra.ID, items[2].ID, items[1].ID, items[0].ID}); err != nil {
+		t.Fatalf("reorder failed: %v", err)
+	}
+	listed, _, err := svc.List(ctx, ticket.ID)
+	if err != nil || listed[0].ID != extra.ID || listed[3].ID != items[0].ID {
+		t.Fatalf("unexpected order after reorder: %v", err)
+	}
+
+	tickets := NewTicketService(db).(*TicketService)
+
+	// 开启阻止解决后，必填项未完成时不能解决工单
+	if err := NewConfigService(db).SetConfig(KeyTicketChecklistBlockResolve, "true", "bool", "", CategoryTicket, "workflow"); err != nil {
+		t.Fatalf("set config failed: %v", err)
+	}
+	if _, err := tickets.UpdateTicketStatus(ticket.ID, string(models.TicketStatusResolved), agent.ID, "", "", ""); !errors.Is(err, ErrChecklistIncomplete) {
+		t.Fatalf("expected resolve to be blocked, got %v", err)
+	}
+	status := models.TicketStatusResolved
+	if _, err := tickets.UpdateTicket(ctx, ticket.ID, &models.TicketUpdateRequest{Status: &status}, agent.ID); !errors.Is(err, ErrChecklistIncomplete) {
+		t.Fatalf("expected update to resolved to be blocked, got %v", err)
+	}
+
+	for _, item := range items[:2] {
+		if _, err := svc.Update(ctx, ticket.ID, item.ID, &models.TicketChecklistItemUpdateRequest{IsDone: &done}, agent.ID); err != nil {
+			t.Fatalf("complete item failed: %v", err)
+		}
+	}
+	resolved, err := tickets.UpdateTicketStatus(ticket.ID, string(models.TicketStatusResolved), agent.ID, "", "", "")
+	if err != nil || resolved.Status != models.TicketStatusResolved {
+		t.Fatalf("expected resolve to succeed once required items are done: %v", err)
+	}
+
+	var histories int64
+	db.Model(&models.TicketHistory{}).Where("ticket_id = ? AND field_name = ?", ticket.ID, "checklist").Count(&histories)
+	if histories != 3 {
+		t.Fatalf("expected 3 checklist history records, got %d", histories)
+	}
+}
@@ -0,0 +1,547 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+// KeyTicketClassification 工单自动分类配置键
+const KeyTicketClassification = "ticket.classification"
+
+var (
+	// ErrClassificationNotConfigured 未启用自动分类或服务商配置无效
+	ErrClassificationNotConfigured = errors.New("ticket classification is not configured")
+	// ErrClassificationFailed 分类服务请求失败
+	ErrClassificationFailed = errors.New("ticket classification provider request failed")
+	// ErrClassificationTicketNotFound 工单不存在
+	ErrClassificationTicketNotFound = errors.New("ticket not found")
+	// ErrTicketClassificationNotFound 工单没有分类记录
+	ErrTicketClassificationNotFound = errors.New("ticket classification not found")
+	// ErrClassificationNoSuggestion 分类记录没有待确认的建议
+	ErrClassificationNoSuggestion = errors.New("ticket classification has no pending suggestion")
+)
+
+// TicketClassificationService 工单自动分类：调用管理员配置的分类服务，
+// 高置信度的字段直接写入工单，其余作为建议等待坐席确认，所有结果留痕用于评估准确率
+type TicketClassificationService struct {
+	db            *gorm.DB
+	now           func() time.Time
+	newClassifier func(config *models.TicketClassificationConfig) (TicketClassifier, error)
+	historyWriter *HistoryWriter
+}
+
+// NewTicketClassificationService 创建工单自动分类服务
+func NewTicketClassificationService(db *gorm.DB) *TicketClassificationService {
+	return &TicketClassificationService{
+		db:  db,
+		now: time.Now,
+		newClassifier: func(config *models.TicketClassificationConfig) (TicketClassifier, error) {
+			return NewTicketClassifier(config, &http.Client{Timeout: time.Duration(config.TimeoutSeconds) * time.Second})
+		},
+	}
+}
+
+// SetHistoryWriter 设置异步历史写入器，自动应用分类产生的系统历史异步批量写入
+func (s *TicketClassificationService) SetHistoryWriter(writer *HistoryWriter) {
+	s.historyWriter = writer
+}
+
+// GetConfig 获取自动分类配置，未保存或无法解析时返回默认配置
+func (s *TicketClassificationService) GetConfig(ctx context.Context) (*models.TicketClassificationConfig, error) {
+	var config models.SystemConfig
+	err := s.db.WithContext(ctx).
+		Where("key = ? AND is_active = ?", KeyTicketClassification, true).
+		First(&config).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.GetDefaultTicketClassificationConfig(), nil
+		}
+		return nil, fmt.Errorf("failed to get ticket classification config: %w", err)
+	}
+
+	classification := models.GetDefaultTicketClassificationConfig()
+	if err := config.GetJSONValue(classification); err != nil {
+		log.Printf("Warning: failed to parse ticket classification config, using defaults: %v", err)
+		return models.GetDefaultTicketClassificationConfig(), nil
+	}
+	return classification, nil
+}
+
+// SetConfig 保存自动分类配置，api_key 留空时保留原值
+func (s *TicketClassificationService) SetConfig(ctx context.Context, classification *models.TicketClassificationConfig, userID uint) error {
+	var existing models.SystemConfig
+	err := s.db.WithContext(ctx).Where("key = ?", KeyTicketClassification).First(&existing).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to check existing ticket classification config: %w", err)
+	}
+
+	if err == nil && classification.APIKey == "" {
+		previous := models.GetDefaultTicketClassificationConfig()
+		if err := existing.GetJSONValue(previous); err == nil {
+			classification.APIKey = previous.APIKey
+		}
+	}
+	if err := classification.Validate(); err != nil {
+		return err
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		config := models.SystemConfig{
+			Key:         KeyTicketClassification,
+			Category:    CategoryTicket,
+			Group:       "classification",
+			Description: "工单自动分类",
+			IsActive:    true,
+			UpdatedBy:   &userID,
+		}
+		if err := config.SetValue(classification); err != nil {
+			return fmt.Errorf("failed to set ticket classification config value: %w", err)
+		}
+		if err := s.db.WithContext(ctx).Create(&config).Error; err != nil {
+			return fmt.Errorf("failed to create ticket classification config: %w", err)
+		}
+		return nil
+	}
+
+	if err := existing.SetValue(classification); err != nil {
+		return fmt.Errorf("failed to set ticket classification config value: %w", err)
+	}
+	existing.UpdatedBy = &userID
+	existing.Version++
+	if err := s.db.WithContext(ctx).Save(&existing).Error; err != nil {
+		return fmt.Errorf("failed to update ticket classification config: %w", err)
+	}
+	return nil
+}
+
+// ClassifyAsync 建单后在后台分类，未启用时不做任何事
+func (s *TicketClassificationService) ClassifyAsync(ticketID uint) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 90*time.Second)
+		defer cancel()
+		if _, err := s.Classify(ctx, ticketID); err != nil && !errors.Is(err, ErrClassificationNotConfigured) {
+			log.Printf("Failed to classify ticket %d: %v", ticketID, err)
+		}
+	}()
+}
+
+// Classify 对工单分类并按阈值自动应用或保存为建议，返回本次分类记录。
+// 分类服务调用失败时同样记录，状态为 failed
+func (s *TicketClassificationService) Classify(ctx context.Context, ticketID uint) (*models.TicketClassification, error) {
+	config, err := s.GetConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !config.Enabled {
+		return nil, ErrClassificationNotConfigured
+	}
+
+	var ticket models.Ticket
+	if err := s.db.WithContext(ctx).Select("id", "title", "description", "type", "priority", "category_id").
+		First(&ticket, ticketID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrClassificationTicketNotFound
+		}
+		return nil, fmt.Errorf("failed to get ticket: %w", err)
+	}
+
+	record := &models.TicketClassification{TicketID: ticket.ID, Provider: config.Provider}
+	result, err := s.run(ctx, config, ticket.Title, ticket.Description, record)
+	if err != nil {
+		record.Status = models.ClassificationStatusFailed
+		record.Error = truncateString(err.Error(), 500)
+		if createErr := s.db.WithContext(ctx).Create(record).Error; createErr != nil {
+			return nil, fmt.Errorf("failed to save ticket classification: %w", createErr)
+		}
+		return record, err
+	}
+	s.fillSuggestion(ctx, record, result, config)
+
+	// 达到自动应用阈值的字段直接写入工单，与当前值相同的视为已应用
+	updates := map[string]interface{}{}
+	var histories []*models.TicketHistory
+	var applied []string
+	if record.SuggestedType != "" && record.TypeConfidence >= config.AutoApplyThreshold && config.AppliesField(models.ClassificationFieldType) {
+		applied = append(applied, models.ClassificationFieldType)
+		if record.SuggestedType != ticket.Type {
+			updates["type"] = record.SuggestedType
+			histories = append(histories, classificationHistory(ticket.ID, nil, models.HistoryActionUpdate, "type",
+				string(ticket.Type), string(record.SuggestedType), record.TypeConfidence))
+		}
+	}
+	if record.SuggestedPriority != "" && record.PriorityConfidence >= config.AutoApplyThreshold && config.AppliesField(models.ClassificationFieldPriority) {
+		applied = append(applied, models.ClassificationFieldPriority)
+		if record.SuggestedPriority != ticket.Priority {
+			updates["priority"] = record.SuggestedPriority
+			histories = append(histories, classificationHistory(ticket.ID, nil, models.HistoryActionPriorityChange, "priority",
+				string(ticket.Priority), string(record.SuggestedPriority), record.PriorityConfidence))
+		}
+	}
+	if record.SuggestedCategoryID != nil && record.CategoryConfidence >= config.AutoApplyThreshold && config.AppliesField(models.ClassificationFieldCategory) {
+		applied = append(applied, models.ClassificationFieldCategory)
+		if !sameUintPtr(record.SuggestedCategoryID, ticket.CategoryID) {
+			updates["category_id"] = *record.SuggestedCategoryID
+			histories = append(histories, classificationHistory(ticket.ID, nil, models.HistoryActionUpdate, "category_id",
+				formatOptionalID(ticket.CategoryID), formatOptionalID(record.SuggestedCategoryID), record.CategoryConfidence))
+		}
+	}
+	record.AppliedFields = strings.Join(applied, ",")
+
+	switch {
+	case len(applied) > 0:
+		record.Status = models.ClassificationStatusApplied
+	case record.HasSuggestion():
+		record.Status = models.ClassificationStatusSuggested
+	default:
+		record.Status = models.ClassificationStatusNoMatch
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(updates) > 0 {
+			updates["updated_at"] = s.now()
+			if err := tx.Model(&models.Ticket{}).Where("id = ?", ticket.ID).Updates(updates).Error; err != nil {
+				return fmt.Errorf("failed to apply classification: %w", err)
+			}
+			if err := recordHistory(tx, s.historyWriter, histories...); err != nil {
+				return err
+			}
+		}
+		if err := tx.Create(record).Error; err != nil {
+			return fmt.Errorf("failed to save ticket classification: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// Test 用示例内容试运行分类，只返回结果不保存。请求中的配置 api_key 留空时使用已保存的密钥
+func (s *TicketClassificationService) Test(ctx context.Context, req *models.TicketClassificationTestRequest) (*models.TicketClassification, error) {
+	stored, err := s.GetConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	config := stored
+	if req.Config != nil {
+		config = req.Config
+		if config.APIKey == "" {
+			config.APIKey = stored.APIKey
+		}
+		if err := config.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	record := &models.TicketClassification{Provider: config.Provider}
+	result, err := s.run(ctx, config, req.Title, req.Description, record)
+	if err != nil {
+		return nil, err
+	}
+	s.fillSuggestion(ctx, record, result, config)
+
+	// AppliedFields 为达到自动应用阈值的字段
+	var applied []string
+	for _, field := range []struct {
+		name       string
+		confidence float64
+	}{
+		{models.ClassificationFieldType, record.TypeConfidence},
+		{models.ClassificationFieldPriority, record.PriorityConfidence},
+		{models.ClassificationFieldCategory, record.CategoryConfidence},
+	} {
+		if field.confidence > 0 && field.confidence >= config.AutoApplyThreshold && config.AppliesField(field.name) {
+			applied = append(applied, field.name)
+		}
+	}
+	record.AppliedFields = strings.Join(applied, ",")
+	switch {
+	case len(applied) > 0:
+		record.Status = models.ClassificationStatusApplied
+	case record.HasSuggestion():
+		record.Status = models.ClassificationStatusSuggested
+	default:
+		record.Status = models.ClassificationStatusNoMatch
+	}
+	return record, nil
+}
+
+// run 调用分类服务并记录耗时
+func (s *TicketClassificationService) run(ctx context.Context, config *models.TicketClassificationConfig, title, description string, record *models.TicketClassification) (*models.TicketClassificationResult, error) {
+	classifier, err := s.newClassifier(config)
+	if err != nil {
+		return nil, err
+	}
+
+	input := &ClassifierInput{
+		Title:       title,
+		Description: truncateString(description, 4000),
+		Types:       classifierTypes,
+		Priorities:  classifierPriorities,
+	}
+	var categories []models.Category
+	if err := s.db.WithContext(ctx).Select("id", "name", "slug").
+		Where("status = ? AND deleted_at IS NULL", models.CategoryStatusActive).
+		Order("sort_order ASC, id ASC").Limit(500).Find(&categories).Error; err != nil {
+		return nil, fmt.Errorf("failed to list categories: %w", err)
+	}
+	for _, category := range categories {
+		input.Categories = append(input.Categories, ClassifierCategory{ID: category.ID, Name: category.Name, Slug: category.Slug})
+	}
+
+	timeoutCtx, cancel := context.WithTimeout(ctx, time.Duration(config.TimeoutSeconds)*time.Second)
+	defer cancel()
+	started := s.now()
+	result, err := classifier.Classify(timeoutCtx, input)
+	record.LatencyMs = s.now().Sub(started).Milliseconds()
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		result = &models.TicketClassificationResult{}
+	}
+	return result, nil
+}
+
+// fillSuggestion 保存达到建议阈值且取值合法的字段，分类按ID、名称或 slug 匹配启用的分类
+func (s *TicketClassificationService) fillSuggestion(ctx context.Context, record *models.TicketClassification, result *models.TicketClassificationResult, config *models.TicketClassificationConfig) {
+	accept := func(value *models.ClassifiedValue) bool {
+		return value != nil && value.Value != "" && value.Confidence >= config.SuggestThreshold && value.Confidence <= 1
+	}
+	if accept(result.Type) {
+		if t := models.TicketType(strings.ToLower(result.Type.Value)); t.IsValid() {
+			record.SuggestedType, record.TypeConfidence = t, result.Type.Confidence
+		}
+	}
+	if accept(result.Priority) {
+		if p := models.TicketPriority(strings.ToLower(result.Priority.Value)); p.IsValid() {
+			record.SuggestedPriority, record.PriorityConfidence = p, result.Priority.Confidence
+		}
+	}
+	if accept(result.Category) {
+		if categoryID, ok := s.resolveCategory(ctx, result.Category.Value); ok {
+			record.SuggestedCategoryID, record.CategoryConfidence = &categoryID, result.Category.Confidence
+		}
+	}
+	if result.Language != nil && result.Language.Value != "" {
+		if language, err := normalizeTranslationLanguage(result.Language.Value); err == nil {
+			record.Language, record.LanguageConfidence = language, result.Language.Confidence
+		}
+	}
+}
+
+func (s *TicketClassificationService) resolveCategory(ctx context.Context, value string) (uint, bool) {
+	query := s.db.WithContext(ctx).Model(&models.Category{}).
+		Where("status = ? AND deleted_at IS NULL", models.CategoryStatusActive)
+	if id, err := strconv.ParseUint(value, 10, 32); err == nil {
+		query = query.Where("id = ?", id)
+	} else {
+		query = query.Where("LOWER(name) = ? OR slug = ?", strings.ToLower(value), strings.ToLower(value))
+	}
+	var category models.Category
+	if err := query.Select("id").First(&category).Error; err != nil {
+		return 0, false
+	}
+	return category.ID, true
+}
+
+// GetForTicket 获取工单最近一次分类记录
+func (s *TicketClassificationService) GetForTicket(ctx context.Context, ticketID uint) (*models.TicketClassification, error) {
+	var record models.TicketClassification
+	if err := s.db.WithContext(ctx).Where("ticket_id = ?", ticketID).Order("id DESC").First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTicketClassificationNotFound
+		}
+		return nil, fmt.Errorf("failed to get ticket classification: %w", err)
+	}
+	return &record, nil
+}
+
+// Review 坐席确认工单最近一次分类的建议：采纳时把未自动应用的建议字段写入工单，拒绝时只记录结果
+func (s *TicketClassificationService) Review(ctx context.Context, ticketID uint, accept bool, userID uint) (*models.TicketClassification, error) {
+	record, err := s.GetForTicket(ctx, ticketID)
+	if err != nil {
+		return nil, err
+	}
+	if record.ReviewedAt != nil || !record.HasSuggestion() {
+		return nil, ErrClassificationNoSuggestion
+	}
+
+	now := s.now()
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if accept {
+			var ticket models.Ticket
+			if err := tx.Select("id", "type", "priority", "category_id").First(&ticket, ticketID).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return ErrClassificationTicketNotFound
+				}
+				return fmt.Errorf("failed to get ticket: %w", err)
+			}
+
+			updates := map[string]interface{}{}
+			var histories []*models.TicketHistory
+			if record.SuggestedType != "" && !record.IsApplied(models.ClassificationFieldType) && record.SuggestedType != ticket.Type {
+				updates["type"] = record.SuggestedType
+				histories = append(histories, classificationHistory(ticketID, &userID, models.HistoryActionUpdate, "type",
+					string(ticket.Type), string(record.SuggestedType), record.TypeConfidence))
+			}
+			if record.SuggestedPriority != "" && !record.IsApplied(models.ClassificationFieldPriority) && record.SuggestedPriority != ticket.Priority {
+				updates["priority"] = record.SuggestedPriority
+				histories = append(histories, classificationHistory(ticketID, &userID, models.HistoryActionPriorityChange, "priority",
+					string(ticket.Priority), string(record.SuggestedPriority), record.PriorityConfidence))
+			}
+			if record.SuggestedCategoryID != nil && !record.IsApplied(models.ClassificationFieldCategory) && !sameUintPtr(record.SuggestedCategoryID, ticket.CategoryID) {
+				updates["category_id"] = *record.SuggestedCategoryID
+				histories = append(histories, classificationHistory(ticketID, &userID, models.HistoryActionUpdate, "category_id",
+					formatOptionalID(ticket.CategoryID), formatOptionalID(record.SuggestedCategoryID), record.CategoryConfidence))
+			}
+			if len(updates) > 0 {
+				updates["updated_at"] = now
+				if err := tx.Model(&models.Ticket{}).Where("id = ?", ticketID).Updates(updates).Error; err != nil {
+					return fmt.Errorf("failed to apply classification: %w", err)
+				}
+				if err := recordHistory(tx, s.historyWriter, histories...); err != nil {
+					return err
+				}
+			}
+			record.Status = models.ClassificationStatusAccepted
+		} else {
+			record.Status = models.ClassificationStatusRejected
+		}
+		record.ReviewedByID = &userID
+		record.ReviewedAt = &now
+		return tx.Save(record).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// ListLogs 分页获取分类记录，可按状态和工单过滤
+func (s *TicketClassificationService) ListLogs(ctx context.Context, status string, ticketID uint, page, pageSize int) ([]*models.TicketClassification, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	query := s.db.WithContext(ctx).Model(&models.TicketClassification{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if ticketID > 0 {
+		query = query.Where("ticket_id = ?", ticketID)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count ticket classifications: %w", err)
+	}
+	var records []*models.TicketClassification
+	if err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&records).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list ticket classifications: %w", err)
+	}
+	return records, total, nil
+}
+
+// Stats 统计最近 days 天的分类结果，各字段的准确率以工单当前值为准
+func (s *TicketClassificationService) Stats(ctx context.Context, days int) (*models.TicketClassificationStats, error) {
+	if days < 1 || days > 365 {
+		days = 30
+	}
+	stats := &models.TicketClassificationStats{
+		Since:    s.now().AddDate(0, 0, -days),
+		ByStatus: map[models.TicketClassificationStatus]int{},
+		Fields: map[string]*models.ClassificationFieldAccuracy{
+			models.ClassificationFieldType:     {},
+			models.ClassificationFieldPriority: {},
+			models.ClassificationFieldCategory: {},
+		},
+	}
+
+	var records []models.TicketClassification
+	if err := s.db.WithContext(ctx).Where("created_at >= ?", stats.Since).Find(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to list ticket classifications: %w", err)
+	}
+	ticketIDs := make([]uint, 0, len(records))
+	for _, record := range records {
+		ticketIDs = append(ticketIDs, record.TicketID)
+	}
+	tickets := map[uint]models.Ticket{}
+	for start := 0; start < len(ticketIDs); start += 500 {
+		end := start + 500
+		if end > len(ticketIDs) {
+			end = len(ticketIDs)
+		}
+		var batch []models.Ticket
+		if err := s.db.WithContext(ctx).Select("id", "type", "priority", "category_id").
+			Where("id IN ?", ticketIDs[start:end]).Find(&batch).Error; err != nil {
+			return nil, fmt.Errorf("failed to load tickets: %w", err)
+		}
+		for _, ticket := range batch {
+			tickets[ticket.ID] = ticket
+		}
+	}
+
+	var latency int64
+	for _, record := range records {
+		stats.Total++
+		stats.ByStatus[record.Status]++
+		latency += record.LatencyMs
+		ticket, ok := tickets[record.TicketID]
+		if !ok {
+			continue
+		}
+		count := func(field string, suggested, correct bool) {
+			if suggested {
+				stats.Fields[field].Suggested++
+				if correct {
+					stats.Fields[field].Correct++
+				}
+			}
+		}
+		count(models.ClassificationFieldType, record.SuggestedType != "", record.SuggestedType == ticket.Type)
+		count(models.ClassificationFieldPriority, record.SuggestedPriority != "", record.SuggestedPriority == ticket.Priority)
+		count(models.ClassificationFieldCategory, record.SuggestedCategoryID != nil, sameUintPtr(record.SuggestedCategoryID, ticket.CategoryID))
+	}
+	for _, field := range stats.Fields {
+		if field.Suggested > 0 {
+			field.Accuracy = float64(field.Correct) / float64(field.Suggested)
+		}
+	}
+	if stats.Total > 0 {
+		stats.AvgLatencyMs = float64(latency) / float64(stats.Total)
+	}
+	return stats, nil
+}
+
+// classificationHistory 分类写入工单字段的动态，userID 为空时为自动应用
+func classificationHistory(ticketID uint, userID *uint, action models.HistoryAction, field, oldValue, newValue string, confidence float64) *models.TicketHistory {
+	description := fmt.Sprintf("自动分类（置信度 %.0f%%）", confidence*100)
+	if userID != nil {
+		description = fmt.Sprintf("采纳自动分类建议（置信度 %.0f%%）", confidence*100)
+	}
+	return &models.TicketHistory{
+		TicketID:    ticketID,
+		UserID:      userID,
+		Action:      action,
+		Description: description,
+		FieldName:   field,
+		OldValue:    oldValue,
+		NewValue:    newValue,
+		IsVisible:   true,
+		IsSystem:    userID == nil,
+		IsAutomated: userID == nil,
+	}
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestTicketClassification_ProvidersThresholdsAndReview(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:ticket_classification_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Category{}, &models.Ticket{}, &models.TicketHistory{},
+		&models.SystemConfig{}, &models.TicketClassification{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+	ctx := context.Background()
+
+	agent := models.User{Username: "classify-agent", Email: "agent@example.com", PasswordHash: "x", Role: models.RoleAgent, Status: models.UserStatusActive}
+	if err := db.Create(&agent).Error; err != nil {
+		t.Fatalf("failed to seed user: %v", err)
+	}
+	billing := models.Category{Name: "Billing", Slug: "billing", Type: models.CategoryTypeBilling, Status: models.CategoryStatusActive}
+	if err := db.Create(&billing).Error; err != nil {
+		t.Fatalf("failed to seed category: %v", err)
+	}
+	seed := func(number, title string) *models.Ticket {
+		ticket := &models.Ticket{TicketNumber: number, Title: title, Description: title, Status: models.TicketStatusOpen,
+			Priority: models.TicketPriorityNormal, Type: models.TicketTypeRequest, Source: models.TicketSourceWeb, CreatedByID: agent.ID}
+		if err := db.Create(ticket).Error; err != nil {
+			t.Fatalf("failed to seed ticket: %v", err)
+		}
+		return ticket
+	}
+
+	svc := NewTicketClassificationService(db)
+	first := seed("CL-001", "App crash error on login")
+	if _, err := svc.Classify(ctx, first.ID); !errors.Is(err, ErrClassificationNotConfigured) {
+		t.Fatalf("expected disabled classification, got %v", err)
+	}
+
+	// 内置关键词规则：命中 2 个关键词置信度 0.67，超过自动应用阈值
+	config := models.GetDefaultTicketClassificationConfig()
+	config.Enabled = true
+	config.AutoApplyThreshold = 0.6
+	if err := svc.SetConfig(ctx, config, agent.ID); err != nil {
+		t.Fatalf("set keyword config: %v", err)
+	}
+	record, err := svc.Classify(ctx, first.ID)
+	if err != nil {
+		t.Fatalf("keyword classify: %v", err)
+	}
+	if record.Status != models.ClassificationStatusApplied || record.SuggestedType != models.TicketTypeIncident || record.Language != "en" {
+		t.Fatalf("unexpected keyword classification %+v", record)
+	}
+	var ticket models.Ticket
+	db.First(&ticket, first.ID)
+	if ticket.Type != models.TicketTypeIncident {
+		t.Fatalf("expected type to be auto applied, got %s", ticket.Type)
+	}
+
+	// 外部模型接口：类型自动应用，优先级和分类作为建议
+	var authHeader string
+	failing := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authHeader = r.Header.Get("Authorization")
+		if failing {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		var input ClassifierInput
+		json.NewDecoder(r.Body).Decode(&input)
+		if len(input.Categories) != 1 || input.Categories[0].Name != "Billing" {
+			t.Errorf("expected categories in classifier input, got %+v", input.Categories)
+		}
+		json.NewEncoder(w).Encode(models.TicketClassificationResult{
+			Type:     &models.ClassifiedValue{Value: "problem", Confidence: 0.95},
+			Priority: &models.ClassifiedValue{Value: "urgent", Confidence: 0.5},
+			Category: &models.ClassifiedValue{Value: "billing", Confidence: 0.9},
+			Language: &models.ClassifiedValue{Value: "de", Confidence: 0.99},
+		})
+	}))
+	defer server.Close()
+
+	config = models.GetDefaultTicketClassificationConfig()
+	config.Enabled = true
+	config.Provider = models.ClassifierProviderHTTP
+	config.Endpoint = server.URL
+	config.APIKey = "model-secret"
+	if err := svc.SetConfig(ctx, config, agent.ID); err != nil {
+		t.Fatalf("set http config: %v", err)
+	}
+	config.APIKey = ""
+	if err := svc.SetConfig(ctx, config, agent.ID); err != nil {
+		t.Fatalf("update http config: %v", err)
+	}
+
+	second := seed("CL-002", "Invoice charged twice")
+	record, err = svc.Classify(ctx, second.ID)
+	if err != nil {
+		t.Fatalf("http classify: %v", err)
+	}
+	if authHeader != "Bearer model-secret" {
+		t.Fatalf("expected api key to be kept, got %q", authHeader)
+	}
+	if record.AppliedFields != "type" || !record.HasSuggestion() || record.SuggestedCategoryID == nil || *record.SuggestedCategoryID != billing.ID {
+		t.Fatalf("unexpected http classification %+v", record)
+	}
+	if current, err := svc.GetForTicket(ctx, second.ID); err != nil || current.ID != record.ID {
+		t.Fatalf("expected latest classification, got %+v, %v", current, err)
+	}
+
+	// 采纳建议后写入优先级和分类，不能重复处理
+	if _, err := svc.Review(ctx, second.ID, true, agent.ID); err != nil {
+		t.Fatalf("accept suggestion: %v", err)
+	}
+	var reviewed models.Ticket
+	db.First(&reviewed, second.ID)
+	if reviewed.Type != models.TicketTypeProblem || reviewed.Priority != models.TicketPriorityUrgent || reviewed.CategoryID == nil || *reviewed.CategoryID != billing.ID {
+		t.Fatalf("expected suggestion to be applied, got %+v", reviewed)
+	}
+	if _, err := svc.Review(ctx, second.ID, false, agent.ID); !errors.Is(err, ErrClassificationNoSuggestion) {
+		t.Fatalf("expected no pending suggestion, got %v", err)
+	}
+	var histories int64
+	db.Model(&models.TicketHistory{}).Where("ticket_id = ?", second.ID).Count(&histories)
+	if histories != 3 {
+		t.Fatalf("expected 3 history records, got %d", histories)
+	}
+
+	// 服务失败也留痕
+	failing = true
+	if record, err := svc.Classify(ctx, first.ID); !errors.Is(err, ErrClassificationFailed) || record == nil || record.Status != models.ClassificationStatusFailed {
+		t.Fatalf("expected failed classification record, got %+v, %v", record, err)
+	}
+
+	// 人工把第一张工单改回 request 后，关键词建议的类型计为错误
+	db.Model(&models.Ticket{}).Where("id = ?", first.ID).Update("type", models.TicketTypeRequest)
+	stats, err := svc.Stats(ctx, 30)
+	if err != nil {
+		t.Fatalf("stats: %v", err)
+	}
+	typeAccuracy := stats.Fields[models.ClassificationFieldType]
+	if stats.Total != 3 || stats.ByStatus[models.ClassificationStatusFailed] != 1 || typeAccuracy.Suggested != 2 || typeAccuracy.Correct != 1 {
+		t.Fatalf("unexpected stats %+v, type %+v", stats, typeAccuracy)
+	}
+
+	// 试运行不保存记录
+	failing = false
+	result, err := svc.Test(ctx, &models.TicketClassificationTestRequest{Title: "Refund", Config: config})
+	if err != nil || result.SuggestedType != models.TicketTypeProblem || result.ID != 0 {
+		t.Fatalf("unexpected test result %+v, %v", result, err)
+	}
+}
@@ -0,0 +1,266 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gongdan-system/internal/models"
+)
+
+// TicketClassifier 工单自动分类服务提供方
+type TicketClassifier interface {
+	Provider() models.ClassifierProvider
+	// Classify 根据工单标题和描述给出类型、优先级、分类及语言的建议值和置信度
+	Classify(ctx context.Context, input *ClassifierInput) (*models.TicketClassificationResult, error)
+}
+
+// ClassifierInput 分类服务的输入
+type ClassifierInput struct {
+	Title       string               `json:"title"`
+	Description string               `json:"description"`
+	Types       []models.TicketType  `json:"types"`
+	Priorities  []string             `json:"priorities"`
+	Categories  []ClassifierCategory `json:"categories"`
+}
+
+// ClassifierCategory 可供选择的工单分类
+type ClassifierCategory struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+var (
+	classifierTypes = []models.TicketType{
+		models.TicketTypeIncident, models.TicketTypeRequest, models.TicketTypeProblem,
+		models.TicketTypeChange, models.TicketTypeComplaint, models.TicketTypeConsultation,
+	}
+	classifierPriorities = []string{
+		string(models.TicketPriorityLow), string(models.TicketPriorityNormal), string(models.TicketPriorityHigh),
+		string(models.TicketPriorityUrgent), string(models.TicketPriorityCritical),
+	}
+)
+
+// NewTicketClassifier 按配置创建分类服务客户端
+func NewTicketClassifier(config *models.TicketClassificationConfig, client *http.Client) (TicketClassifier, error) {
+	switch config.Provider {
+	case models.ClassifierProviderKeyword:
+		return &keywordClassifier{rules: config.Rules}, nil
+	case models.ClassifierProviderHTTP:
+		return &httpClassifier{endpoint: config.Endpoint, apiKey: config.APIKey, client: client}, nil
+	case models.ClassifierProviderOpenAI:
+		endpoint := strings.TrimSuffix(config.Endpoint, "/")
+		if endpoint == "" {
+			endpoint = "https://api.openai.com/v1"
+		}
+		return &openAIClassifier{endpoint: endpoint + "/chat/completions", apiKey: config.APIKey, model: config.Model, client: client}, nil
+	}
+	return nil, fmt.Errorf("%w: unknown provider %q", ErrClassificationNotConfigured, config.Provider)
+}
+
+// keywordClassifier 内置关键词规则：命中关键词越多置信度越高（1 个 0.5，2 个 0.67，3 个 0.75 …）
+type keywordClassifier struct {
+	rules []models.TicketClassificationRule
+}
+
+func (c *keywordClassifier) Provider() models.ClassifierProvider {
+	return models.ClassifierProviderKeyword
+}
+
+func (c *keywordClassifier) Classify(ctx context.Context, input *ClassifierInput) (*models.TicketClassificationResult, error) {
+	content := strings.ToLower(input.Title + " " + input.Description)
+	result := &models.TicketClassificationResult{Language: detectScriptLanguage(input.Title + " " + input.Description)}
+
+	// 每个字段取命中关键词最多的规则，相同时取靠前的规则
+	best := map[string]int{}
+	for _, rule := range c.rules {
+		hits := 0
+		for _, keyword := range rule.Keywords {
+			if strings.Contains(content, keyword) {
+				hits++
+			}
+		}
+		if hits == 0 {
+			continue
+		}
+		confidence := float64(hits) / float64(hits+1)
+		if rule.Type != "" && hits > best[models.ClassificationFieldType] {
+			best[models.ClassificationFieldType] = hits
+			result.Type = &models.ClassifiedValue{Value: string(rule.Type), Confidence: confidence}
+		}
+		if rule.Priority != "" && hits > best[models.ClassificationFieldPriority] {
+			best[models.ClassificationFieldPriority] = hits
+			result.Priority = &models.ClassifiedValue{Value: string(rule.Priority), Confidence: confidence}
+		}
+		if rule.CategoryID != nil && hits > best[models.ClassificationFieldCategory] {
+			best[models.ClassificationFieldCategory] = hits
+			result.Category = &models.ClassifiedValue{Value: strconv.FormatUint(uint64(*rule.CategoryID), 10), Confidence: confidence}
+		}
+	}
+	return result, nil
+}
+
+// detectScriptLanguage 按文字书写系统粗略识别语言，拉丁字母无法区分具体语言，按英语给出较低置信度
+func detectScriptLanguage(text string) *models.ClassifiedValue {
+	counts := map[string]int{}
+	total := 0
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Hiragana, r) || unicode.Is(unicode.Katakana, r):
+			counts["ja"]++
+		case unicode.Is(unicode.Hangul, r):
+			counts["ko"]++
+		case unicode.Is(unicode.Han, r):
+			counts["zh"]++
+		case unicode.Is(unicode.Cyrillic, r):
+			counts["ru"]++
+		case unicode.Is(unicode.Arabic, r):
+			counts["ar"]++
+		case unicode.Is(unicode.Latin, r):
+			counts["en"]++
+		default:
+			continue
+		}
+		total++
+	}
+	if total == 0 {
+		return nil
+	}
+	// 日文混用汉字，出现假名即视为日文
+	if counts["ja"] > 0 {
+		counts["ja"] += counts["zh"]
+		counts["zh"] = 0
+	}
+	language, max := "", 0
+	for _, candidate := range []string{"zh", "ja", "ko", "ru", "ar", "en"} {
+		if counts[candidate] > max {
+			language, max = candidate, counts[candidate]
+		}
+	}
+	confidence := float64(max) / float64(total)
+	if language == "en" {
+		confidence *= 0.5
+	}
+	return &models.ClassifiedValue{Value: language, Confidence: confidence}
+}
+
+// httpClassifier 自建模型接口：POST ClassifierInput，返回 TicketClassificationResult 格式的 JSON
+type httpClassifier struct {
+	endpoint string
+	apiKey   string
+	client   *http.Client
+}
+
+func (c *httpClassifier) Provider() models.ClassifierProvider {
+	return models.ClassifierProviderHTTP
+}
+
+func (c *httpClassifier) Classify(ctx context.Context, input *ClassifierInput) (*models.TicketClassificationResult, error) {
+	var result models.TicketClassificationResult
+	if err := postClassifierJSON(ctx, c.client, c.endpoint, c.apiKey, input, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// openAIClassifier OpenAI 兼容的 Chat Completions 接口，要求模型按 JSON 格式输出
+type openAIClassifier struct {
+	endpoint string
+	apiKey   string
+	model    string
+	client   *http.Client
+}
+
+func (c *openAIClassifier) Provider() models.ClassifierProvider {
+	return models.ClassifierProviderOpenAI
+}
+
+func (c *openAIClassifier) Classify(ctx context.Context, input *ClassifierInput) (*models.TicketClassificationResult, error) {
+	categories := make([]string, 0, len(input.Categories))
+	for _, category := range input.Categories {
+		categories = append(categories, fmt.Sprintf("%d=%s", category.ID, category.Name))
+	}
+	types := make([]string, 0, len(input.Types))
+	for _, t := range input.Types {
+		types = append(types, string(t))
+	}
+	prompt := "You classify helpdesk tickets. Reply with a JSON object only, in the form " +
+		`{"type":{"value":"","confidence":0},"priority":{"value":"","confidence":0},"category":{"value":"","confidence":0},"language":{"value":"","confidence":0}}. ` +
+		"Confidence is between 0 and 1. Omit a field when unsure. " +
+		"type is one of: " + strings.Join(types, ", ") + ". " +
+		"priority is one of: " + strings.Join(input.Priorities, ", ") + ". " +
+		"category value is the id of one of: " + strings.Join(categories, "; ") + ". " +
+		"language is a BCP 47 code of the ticket text."
+
+	body := map[string]interface{}{
+		"model":           c.model,
+		"temperature":     0,
+		"response_format": map[string]string{"type": "json_object"},
+		"messages": []map[string]string{
+			{"role": "system", "content": prompt},
+			{"role": "user", "content": "Title: " + input.Title + "\n\n" + input.Description},
+		},
+	}
+	var resp struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := postClassifierJSON(ctx, c.client, c.endpoint, c.apiKey, body, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("%w: empty response", ErrClassificationFailed)
+	}
+
+	var result models.TicketClassificationResult
+	content := strings.TrimSpace(resp.Choices[0].Message.Content)
+	content = strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(content, "```json"), "```"), "```")
+	if err := json.Unmarshal([]byte(strings.TrimSpace(content)), &result); err != nil {
+		return nil, fmt.Errorf("%w: model returned invalid json: %v", ErrClassificationFailed, err)
+	}
+	return &result, nil
+}
+
+// postClassifierJSON 发送 JSON 请求并解析响应，非 2xx 状态码返回 ErrClassificationFailed
+func postClassifierJSON(ctx context.Context, client *http.Client, endpoint, apiKey string, body, out interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrClassificationFailed, err)
+	}
+	defer resp.Body.Close()
+
+	payload, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrClassificationFailed, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%w: provider returned %d: %s", ErrClassificationFailed, resp.StatusCode, truncateString(string(payload), 200))
+	}
+	if err := json.Unmarshal(payload, out); err != nil {
+		return fmt.Errorf("%w: invalid response: %v", ErrClassificationFailed, err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestTicketComment_AttachmentReferencesAndQuotes(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:ticket_comment_attachment_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Team{}, &models.Ticket{}, &models.TicketComment{}, &models.TicketHistory{},
+		&models.TicketAttachment{}, &models.SystemConfig{}, &models.TicketCommentDraft{}, &models.TicketReplyLock{},
+		&models.QuotaAlert{}, &models.Notification{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+	ctx := context.Background()
+	attachments := NewTicketAttachmentService(db, NewLocalFileStorage(t.TempDir(), "/uploads"))
+	comments := NewTicketCommentService(db, nil)
+
+	customer := models.User{Username: "quote-customer", Email: "quote-customer@example.com", PasswordHash: "x", Role: models.RoleCustomer, DisplayName: "Alice"}
+	agent := models.User{Username: "quote-agent", Email: "quote-agent@example.com", PasswordHash: "x", Role: models.RoleAgent}
+	for _, u := range []*models.User{&customer, &agent} {
+		if err := db.Create(u).Error; err != nil {
+			t.Fatalf("failed to seed user: %v", err)
+		}
+	}
+	ticket := models.Ticket{TicketNumber: "CQ-1", Title: "Invoice", Status: models.TicketStatusOpen, Priority: models.TicketPriorityNormal,
+		Type: models.TicketTypeRequest, Source: models.TicketSourceWeb, CreatedByID: customer.ID, AssignedToID: &agent.ID}
+	db.Create(&ticket)
+	customerViewer := CommentViewer{UserID: customer.ID, Role: string(models.RoleCustomer)}
+	agentViewer := CommentViewer{UserID: agent.ID, Role: string(models.RoleAgent)}
+
+	pdf := []byte("%PDF-1.4\n" + strings.Repeat("x", 256))
+	invoice, err := attachments.Upload(ctx, ticket.ID, agent.ID, "invoice.pdf", int64(len(pdf)), bytes.NewReader(pdf))
+	if err != nil {
+		t.Fatalf("upload failed: %v", err)
+	}
+
+	// 只能引用自己上传的附件
+	if _, err := comments.CreateComment(ctx, ticket.ID, &models.TicketCommentCreateRequest{Content: "mine", AttachmentIDs: []uint{invoice.ID}}, customerViewer); !errors.Is(err, ErrAttachmentNotFound) {
+		t.Fatalf("expected another user's attachment to be rejected, got %v", err)
+	}
+	withFile, err := comments.CreateComment(ctx, ticket.ID, &models.TicketCommentCreateRequest{Content: "Invoice attached", AttachmentIDs: []uint{invoice.ID}}, agentViewer)
+	if err != nil {
+		t.Fatalf("create comment with attachment failed: %v", err)
+	}
+	link := fmt.Sprintf(ticketAttachmentDownloadPath, ticket.ID, invoice.ID)
+	if response := withFile.ToResponse(); len(response.Attachments) != 1 || response.Attachments[0] != link {
+		t.Fatalf("unexpected comment attachments %+v", response.Attachments)
+	}
+	var linked models.TicketAttachment
+	db.First(&linked, invoice.ID)
+	if linked.CommentID == nil || *linked.CommentID != withFile.ID {
+		t.Fatalf("expected attachment linked to comment, got %v", linked.CommentID)
+	}
+	if _, err := comments.CreateComment(ctx, ticket.ID, &models.TicketCommentCreateRequest{Content: "again", AttachmentIDs: []uint{invoice.ID}}, agentViewer); !errors.Is(err, ErrAttachmentNotFound) {
+		t.Fatalf("expected linked attachment not to be reused, got %v", err)
+	}
+
+	// 上传后收紧的附件策略在引用时生效
+	draft, _ := attachments.Upload(ctx, ticket.ID, agent.ID, "draft.pdf", int64(len(pdf)), bytes.NewReader(pdf))
+	policy := models.GetDefaultAttachmentPolicy()
+	policy.Default.AllowedMimeTypes = []string{"image/*"}
+	if err := attachments.SetPolicy(ctx, policy, agent.ID); err != nil {
+		t.Fatalf("set policy failed: %v", err)
+	}
+	var rejected *AttachmentRejectedError
+	if _, err := comments.CreateComment(ctx, ticket.ID, &models.TicketCommentCreateRequest{Content: "draft", AttachmentIDs: []uint{draft.ID}}, agentViewer); !errors.As(err, &rejected) || rejected.Reason != AttachmentRejectMimeType {
+		t.Fatalf("expected policy rejection, got %v", err)
+	}
+
+	// 评论相关的邮件列出附件
+	mailer := &EmailNotificationService{db: db}
+	htmlList, textList := mailer.commentAttachmentsData(&models.Notification{Metadata: models.JSONText(fmt.Sprintf(`{"comment_id": %d}`, withFile.ID))})
+	if !strings.Contains(htmlList, "invoice.pdf") || !strings.Contains(htmlList, "{{.BrandPortalURL}}"+link) || !strings.Contains(textList, "invoice.pdf") {
+		t.Fatalf("unexpected attachment list %q / %q", htmlList, textList)
+	}
+	if htmlList, _ := mailer.commentAttachmentsData(&models.Notification{Metadata: `{"ticket_number": "CQ-1"}`}); htmlList != "" {
+		t.Fatalf("expected no attachment list without comment, got %q", htmlList)
+	}
+
+	// 引用回复：引用标记保存在内容中，引用信息存入元数据
+	question, _ := comments.CreateComment(ctx, ticket.ID, &models.TicketCommentCreateRequest{Content: "Line one\nLine two"}, customerViewer)
+	note, _ := comments.CreateComment(ctx, ticket.ID, &models.TicketCommentCreateRequest{Content: "Customer is on legacy plan", Type: models.CommentTypeInternal}, agentViewer)
+	reply, err := comments.CreateComment(ctx, ticket.ID, &models.TicketCommentCreateRequest{Content: "Thanks!", QuoteCommentID: &question.ID}, agentViewer)
+	if err != nil {
+		t.Fatalf("create quoted reply failed: %v", err)
+	}
+	attribution := fmt.Sprintf("> Alice 于 %s 写道：\n", question.CreatedAt.Format("2006-01-02 15:04"))
+	if reply.Content != attribution+"> Line one\n> Line two\n\nThanks!" {
+		t.Fatalf("unexpected quoted content %q", reply.Content)
+	}
+	if quote := reply.ToResponse().Quote; quote == nil || quote.CommentID != question.ID || quote.AuthorID != customer.ID || quote.Text != "Line one\nLine two" {
+		t.Fatalf("unexpected quote metadata %+v", quote)
+	}
+
+	// 引用引用回复时只引用其回复部分；指定原文时须为原评论中的内容
+	nested, err := comments.CreateComment(ctx, ticket.ID, &models.TicketCommentCreateRequest{Content: "You're welcome", QuoteCommentID: &reply.ID}, customerViewer)
+	if err != nil || nested.GetQuote().Text != "Thanks!" || strings.Contains(nested.Content, "Line one") {
+		t.Fatalf("unexpected nested quote %q, %v", nested.Content, err)
+	}
+	partial, err := comments.CreateComment(ctx, ticket.ID, &models.TicketCommentCreateRequest{Content: "About this", QuoteCommentID: &question.ID, QuoteText: "Line two"}, agentViewer)
+	if err != nil || !strings.Contains(partial.Content, "> Line two\n") || strings.Contains(partial.Content, "Line one") {
+		t.Fatalf("unexpected partial quote %q, %v", partial.Content, err)
+	}
+	if _, err := comments.CreateComment(ctx, ticket.ID, &models.TicketCommentCreateRequest{Content: "x", QuoteCommentID: &question.ID, QuoteText: "Line three"}, agentViewer); !errors.Is(err, ErrInvalidCommentQuote) {
+		t.Fatalf("expected unknown quote text to be rejected, got %v", err)
+	}
+
+	// 内部评论不能被引用到公开回复中，客户也看不到内部评论
+	if _, err := comments.CreateComment(ctx, ticket.ID, &models.TicketCommentCreateRequest{Content: "x", QuoteCommentID: &note.ID}, agentViewer); !errors.Is(err, ErrInvalidCommentQuote) {
+		t.Fatalf("expected internal quote in public reply to be rejected, got %v", err)
+	}
+	if _, err := comments.CreateComment(ctx, ticket.ID, &models.TicketCommentCreateRequest{Content: "x", QuoteCommentID: &note.ID, Type: models.CommentTypeInternal}, agentViewer); err != nil {
+		t.Fatalf("expected internal quote in internal note to be allowed, got %v", err)
+	}
+	if _, err := comments.CreateComment(ctx, ticket.ID, &models.TicketCommentCreateRequest{Content: "x", QuoteCommentID: &note.ID}, customerViewer); !errors.Is(err, ErrCommentNotFound) {
+		t.Fatalf("expected customer not to see internal comment, got %v", err)
+	}
+}
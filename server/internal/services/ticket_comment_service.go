@@ -0,0 +1,644 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"log"
+	"strings"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+// KeyCommentVisibilityDefaults 各角色评论默认可见范围配置键
+const KeyCommentVisibilityDefaults = "ticket.comment_visibility_defaults"
+
+var (
+	// ErrCommentNotFound 评论不存在
+	ErrCommentNotFound = errors.New("comment not found")
+	// ErrCommentVisibilityForbidden 调用者无权设置该可见范围
+	ErrCommentVisibilityForbidden = errors.New("not allowed to use this comment visibility")
+	// ErrInvalidCommentVisibility 可见范围不合法
+	ErrInvalidCommentVisibility = errors.New("invalid comment visibility")
+	// ErrInvalidCommentQuote 引用的原文不合法，或原评论的可见范围比新评论更窄
+	ErrInvalidCommentQuote = errors.New("invalid comment quote")
+)
+
+// maxCommentQuoteRunes 引用原文的最大长度，引用全文时超出部分截断
+const maxCommentQuoteRunes = 2000
+
+// CommentViewer 评论的查看或发表者
+type CommentViewer struct {
+	UserID uint
+	Role   string
+}
+
+// IsCustomer 客户及普通用户只能查看和发表公开评论
+func (v CommentViewer) IsCustomer() bool {
+	return v.Role == "" || v.Role == string(models.RoleCustomer) || v.Role == "user"
+}
+
+// seesAllTeams 管理员及主管可查看所有团队的限定评论
+func (v CommentViewer) seesAllTeams() bool {
+	switch v.Role {
+	case string(models.RoleAdmin), string(models.RoleSupervisor), "superuser":
+		return true
+	}
+	return false
+}
+
+// scopeVisibleComments 按查看者过滤评论，includeInternal 为 false 时只返回公开评论
+func scopeVisibleComments(db, query *gorm.DB, viewer CommentViewer, includeInternal bool) *gorm.DB {
+	if viewer.IsCustomer() || !includeInternal {
+		return query.Where("visibility = ? AND type <> ?", models.CommentVisibilityPublic, models.CommentTypeInternal)
+	}
+	if viewer.seesAllTeams() {
+		return query
+	}
+	return query.Where("visibility <> ? OR user_id = ? OR visible_team_id IN (?)",
+		models.CommentVisibilityTeam, viewer.UserID,
+		db.Table("team_members").Select("team_id").Where("user_id = ?", viewer.UserID))
+}
+
+// commentVisibleToUser 判断用户能否查看评论，用于通知、邮件等服务端发送前的检查
+func commentVisibleToUser(db *gorm.DB, comment *models.TicketComment, user *models.User) bool {
+	if comment.IsCustomerVisible() {
+		return true
+	}
+	viewer := CommentViewer{UserID: user.ID, Role: string(user.Role)}
+	if viewer.IsCustomer() {
+		return false
+	}
+	if comment.Visibility != models.CommentVisibilityTeam || viewer.seesAllTeams() || comment.UserID == user.ID {
+		return true
+	}
+	if comment.VisibleTeamID == nil {
+		return false
+	}
+	var count int64
+	db.Table("team_members").Where("team_id = ? AND user_id = ?", *comment.VisibleTeamID, user.ID).Count(&count)
+	return count > 0
+}
+
+// TicketCommentService 工单评论服务
+type TicketCommentService struct {
+	db                 *gorm.DB
+	teamService        *TeamService
+	draftService       *CommentDraftService
+	translationService *CommentTranslationService
+	uploadService      *UploadService
+	attachmentService  *TicketAttachmentService
+	scriptHooks        *ScriptHookService
+	piiScanner         *PIIScanService
+}
+
+// NewTicketCommentService 创建工单评论服务
+func NewTicketCommentService(db *gorm.DB, teamService *TeamService) *TicketCommentService {
+	if teamService == nil {
+		teamService = NewTeamService(db)
+	}
+	return &TicketCommentService{
+		db:                 db,
+		teamService:        teamService,
+		draftService:       NewCommentDraftService(db),
+		translationService: NewCommentTranslationService(db),
+		attachmentService:  NewTicketAttachmentService(db, nil),
+	}
+}
+
+// SetUploadService 设置预签名上传服务，评论提交时将 upload_ids 引用的上传转为评论附件
+func (s *TicketCommentService) SetUploadService(uploadService *UploadService) {
+	s.uploadService = uploadService
+}
+
+// SetScriptHooks 设置脚本钩子服务，评论创建后执行评论钩子；未设置时不执行
+func (s *TicketCommentService) SetScriptHooks(hooks *ScriptHookService) {
+	s.scriptHooks = hooks
+}
+
+// SetPIIScanner 设置敏感信息检测服务，评论保存前检测；未设置时不检测
+func (s *TicketCommentService) SetPIIScanner(scanner *PIIScanService) {
+	s.piiScanner = scanner
+}
+
+// ListComments 分页获取工单评论，查看者不可见的评论在服务端过滤
+func (s *TicketCommentService) ListComments(ctx context.Context, ticketID uint, viewer CommentViewer, includeInternal bool, page, pageSize int) ([]*models.TicketComment, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	query := s.db.WithContext(ctx).Model(&models.TicketComment{}).
+		Where("ticket_id = ? AND is_deleted = ?", ticketID, false)
+	query = scopeVisibleComments(s.db, query, viewer, includeInternal)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count comments: %w", err)
+	}
+
+	var comments []*models.TicketComment
+	if err := query.Preload("User").
+		Order("created_at ASC").
+		Offset((page - 1) * pageSize).
+		Limit(pageSize).
+		Find(&comments).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list comments: %w", err)
+	}
+	s.translationService.AttachTranslations(ctx, viewer, comments)
+
+	return comments, total, nil
+}
+
+// CreateComment 添加工单评论，并通知评论中@提及的团队成员
+// 未指定类型和可见范围时使用发表者角色的默认可见范围
+// 其他客服持有回复锁时拒绝客服的公开回复，除非请求显式忽略回复锁
+func (s *TicketCommentService) CreateComment(ctx context.Context, ticketID uint, req *models.TicketCommentCreateRequest, viewer CommentViewer) (*models.TicketComment, error) {
+	return s.createComment(ctx, ticketID, req, viewer, false)
+}
+
+// createComment 添加工单评论；bulk 为 true 时（批量评论）不清除草稿，也不单独发送@提及通知，由批量任务汇总通知
+func (s *TicketCommentService) createComment(ctx context.Context, ticketID uint, req *models.TicketCommentCreateRequest, viewer CommentViewer, bulk bool) (*models.TicketComment, error) {
+	userID := viewer.UserID
+	if strings.TrimSpace(req.Content) == "" {
+		return nil, fmt.Errorf("content is required")
+	}
+
+	var ticket models.Ticket
+	if err := s.db.WithContext(ctx).First(&ticket, ticketID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("ticket not found")
+		}
+		return nil, fmt.Errorf("failed to get ticket: %w", err)
+	}
+
+	commentType := req.Type
+	if commentType == "" {
+		commentType = models.CommentTypePublic
+	}
+	if commentType != models.CommentTypePublic && commentType != models.CommentTypeInternal {
+		return nil, fmt.Errorf("invalid comment type: %s", commentType)
+	}
+	visibility, err := s.resolveCreateVisibility(ctx, req, commentType, viewer)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.checkVisibilityAllowed(ctx, viewer, visibility, req.TeamID); err != nil {
+		return nil, err
+	}
+	if visibility == models.CommentVisibilityPublic && !viewer.IsCustomer() && !req.IgnoreReplyLock {
+		if err := s.draftService.CheckReplyAllowed(ctx, ticketID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	contentType := req.ContentType
+	if contentType == "" {
+		contentType = "text"
+	}
+
+	// 引用回复：引用的原文以引用标记保存在评论内容中，引用信息同时存入元数据
+	content := req.Content
+	metadata := req.Metadata
+	if req.QuoteCommentID != nil {
+		quote, err := s.resolveQuote(ctx, ticketID, *req.QuoteCommentID, req.QuoteText, visibility, req.TeamID, viewer)
+		if err != nil {
+			return nil, err
+		}
+		content = renderQuotedContent(quote, contentType, content)
+		metadata = make(map[string]interface{}, len(req.Metadata)+1)
+		for key, value := range req.Metadata {
+			metadata[key] = value
+		}
+		metadata["quote"] = quote
+	}
+
+	// 敏感信息检测：拒绝保存时直接返回，脱敏时只保存脱敏后的内容
+	piiResult, err := scanPIIContent(ctx, s.piiScanner, ticket.CategoryID, content)
+	if err != nil {
+		return nil, err
+	}
+	if piiResult != nil && piiResult.Action == models.PIIActionMask {
+		content = piiResult.Masked
+	}
+
+	var uploads []*models.PendingUpload
+	if len(req.UploadIDs) > 0 {
+		if s.uploadService == nil {
+			return nil, fmt.Errorf("%w: uploads are not enabled", ErrInvalidUpload)
+		}
+		if uploads, err = s.uploadService.ResolveUploads(ctx, ticketID, userID, req.UploadIDs, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+	attachments, err := s.attachmentService.ResolveCommentAttachments(ctx, ticketID, userID, req.AttachmentIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	comment := &models.TicketComment{
+		TicketID:      ticketID,
+		UserID:        userID,
+		Content:       content,
+		ContentType:   contentType,
+		Type:          commentType,
+		Visibility:    visibility,
+		VisibleTeamID: req.TeamID,
+		ParentID:      req.ParentID,
+		TimeSpent:     req.TimeSpent,
+		BillableTime:  req.BillableTime,
+		WorkType:      req.WorkType,
+	}
+	if len(req.Attachments) > 0 {
+		data, _ := json.Marshal(req.Attachments)
+		comment.Attachments = string(data)
+	}
+	if len(metadata) > 0 {
+		data, _ := json.Marshal(metadata)
+		comment.Metadata = string(data)
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(comment).Error; err != nil {
+			return fmt.Errorf("failed to create comment: %w", err)
+		}
+		if err := tx.Model(&models.Ticket{}).Where("id = ?", ticketID).
+			UpdateColumn("comment_count", gorm.Expr("comment_count + 1")).Error; err != nil {
+			return fmt.Errorf("failed to update comment count: %w", err)
+		}
+		if comment.ParentID != nil {
+			if err := tx.Model(&models.TicketComment{}).Where("id = ?", *comment.ParentID).
+				UpdateColumn("reply_count", gorm.Expr("reply_count + 1")).Error; err != nil {
+				return fmt.Errorf("failed to update reply count: %w", err)
+			}
+		}
+		if err := linkCommentAttachments(tx, comment, attachments); err != nil {
+			return err
+		}
+		if err := s.uploadService.attachUploads(tx, comment, uploads); err != nil {
+			return err
+		}
+		return recordPIIRedaction(ctx, tx, s.piiScanner, ticketID, &comment.ID, userID, piiResult)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if hooks := s.scriptHooks; hooks != nil {
+		hooks.RunCommentHooks(ctx, &ticket, comment, viewer.IsCustomer())
+	}
+
+	if !bulk {
+		if err := s.draftService.CompleteDraft(ctx, ticketID, userID); err != nil {
+			log.Printf("Failed to clear comment draft for ticket %d: %v", ticketID, err)
+		}
+
+		// 后面会重新加载评论，通知使用副本
+		snapshot := *comment
+		go func() {
+			if _, err := s.teamService.NotifyTeamMentions(context.Background(), &ticket, &snapshot); err != nil {
+				log.Printf("Failed to notify team mentions: %v", err)
+			}
+		}()
+	}
+
+	s.db.WithContext(ctx).Preload("User").First(comment, comment.ID)
+	comment.PIIScan = piiResult.Notice()
+	if viewer.IsCustomer() && !bulk {
+		// 客户的评论按客服的自动翻译偏好预先翻译
+		snapshot := *comment
+		s.translationService.autoTranslateInBackground(&snapshot)
+	}
+	return comment, nil
+}
+
+// resolveQuote 获取被引用的评论并确定引用原文。原评论须属于同一工单且发表者可见，
+// 其可见范围不能比新评论更窄，避免内部内容通过引用出现在客户可见的回复中
+func (s *TicketCommentService) resolveQuote(ctx context.Context, ticketID, quoteCommentID uint, quoteText string, visibility models.CommentVisibility, teamID *uint, viewer CommentViewer) (*models.CommentQuote, error) {
+	query := s.db.WithContext(ctx).Model(&models.TicketComment{}).
+		Where("id = ? AND ticket_id = ? AND is_deleted = ?", quoteCommentID, ticketID, false)
+	var original models.TicketComment
+	err := scopeVisibleComments(s.db, query, viewer, true).Preload("User").First(&original).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrCommentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get quoted comment: %w", err)
+	}
+
+	switch {
+	case original.IsCustomerVisible():
+	case visibility == models.CommentVisibilityPublic:
+		return nil, fmt.Errorf("%w: cannot quote a non-public comment in a public reply", ErrInvalidCommentQuote)
+	case original.Visibility == models.CommentVisibilityTeam &&
+		(visibility != models.CommentVisibilityTeam || teamID == nil || original.VisibleTeamID == nil || *teamID != *original.VisibleTeamID):
+		return nil, fmt.Errorf("%w: team-restricted comments can only be quoted within the same team", ErrInvalidCommentQuote)
+	}
+
+	body := original.Content
+	if original.GetQuote() != nil {
+		body = stripQuotedContent(body)
+	}
+	text := strings.TrimSpace(quoteText)
+	if text == "" {
+		text = strings.TrimSpace(body)
+		if runes := []rune(text); len(runes) > maxCommentQuoteRunes {
+			text = string(runes[:maxCommentQuoteRunes]) + "…"
+		}
+	} else {
+		if !strings.Contains(original.Content, text) {
+			return nil, fmt.Errorf("%w: quote_text must be part of the quoted comment", ErrInvalidCommentQuote)
+		}
+		if len([]rune(text)) > maxCommentQuoteRunes {
+			return nil, fmt.Errorf("%w: quote_text exceeds %d characters", ErrInvalidCommentQuote, maxCommentQuoteRunes)
+		}
+	}
+
+	quote := &models.CommentQuote{
+		CommentID: original.ID,
+		AuthorID:  original.UserID,
+		QuotedAt:  original.CreatedAt,
+		Text:      text,
+	}
+	if original.User != nil {
+		quote.AuthorName = original.User.GetFullName()
+	}
+	return quote, nil
+}
+
+// renderQuotedContent 将引用原文放在回复内容之前：文本与 Markdown 以 "> " 标记每一行，HTML 使用 blockquote
+func renderQuotedContent(quote *models.CommentQuote, contentType, reply string) string {
+	attribution := fmt.Sprintf("%s 于 %s 写道：", quote.AuthorName, quote.QuotedAt.Format("2006-01-02 15:04"))
+	if contentType == "html" {
+		lines := strings.Split(html.EscapeString(quote.Text), "\n")
+		return fmt.Sprintf(`<blockquote data-quote-comment-id="%d"><p>%s</p><p>%s</p></blockquote>`,
+			quote.CommentID, html.EscapeString(attribution), strings.Join(lines, "<br>")) + "\n" + reply
+	}
+
+	var b strings.Builder
+	b.WriteString("> " + attribution + "\n")
+	for _, line := range strings.Split(quote.Text, "\n") {
+		b.WriteString(strings.TrimRight("> "+line, " ") + "\n")
+	}
+	b.WriteString("\n")
+	b.WriteString(reply)
+	return b.String()
+}
+
+// stripQuotedContent 去掉评论开头的引用，引用一条引用回复时只引用其回复部分，避免引用层层嵌套
+func stripQuotedContent(content string) string {
+	if strings.HasPrefix(content, "<blockquote data-quote-comment-id=") {
+		if end := strings.Index(content, "</blockquote>"); end >= 0 {
+			return strings.TrimLeft(content[end+len("</blockquote>"):], "\n")
+		}
+		return content
+	}
+	lines := strings.Split(content, "\n")
+	i := 0
+	for i < len(lines) && strings.HasPrefix(lines[i], ">") {
+		i++
+	}
+	return strings.TrimLeft(strings.Join(lines[i:], "\n"), "\n")
+}
+
+// resolveCreateVisibility 确定新评论的可见范围：显式可见范围 > 内部类型 > 角色默认值
+func (s *TicketCommentService) resolveCreateVisibility(ctx context.Context, req *models.TicketCommentCreateRequest, commentType models.CommentType, viewer CommentViewer) (models.CommentVisibility, error) {
+	if req.Visibility != "" {
+		if !models.IsValidCommentVisibility(req.Visibility) {
+			return "", ErrInvalidCommentVisibility
+		}
+		return req.Visibility, nil
+	}
+	if commentType == models.CommentTypeInternal {
+		return models.CommentVisibilityInternal, nil
+	}
+	if req.Type != "" || viewer.IsCustomer() {
+		return models.CommentVisibilityPublic, nil
+	}
+
+	defaults, err := s.GetVisibilityDefaults(ctx)
+	if err != nil {
+		return models.CommentVisibilityPublic, nil
+	}
+	return defaults.For(viewer.Role), nil
+}
+
+// checkVisibilityAllowed 客户只能使用公开可见范围；团队可见时团队必须有效，且非管理员须为该团队成员
+func (s *TicketCommentService) checkVisibilityAllowed(ctx context.Context, viewer CommentViewer, visibility models.CommentVisibility, teamID *uint) error {
+	if visibility == models.CommentVisibilityPublic {
+		return nil
+	}
+	if viewer.IsCustomer() {
+		return ErrCommentVisibilityForbidden
+	}
+	if visibility != models.CommentVisibilityTeam {
+		return nil
+	}
+	if teamID == nil {
+		return fmt.Errorf("%w: visible_team_id is required for team visibility", ErrInvalidCommentVisibility)
+	}
+
+	var team models.Team
+	if err := s.db.WithContext(ctx).Where("id = ? AND is_active = ?", *teamID, true).First(&team).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrTeamNotFound
+		}
+		return fmt.Errorf("failed to get team: %w", err)
+	}
+	if viewer.seesAllTeams() {
+		return nil
+	}
+
+	var count int64
+	if err := s.db.WithContext(ctx).Table("team_members").
+		Where("team_id = ? AND user_id = ?", *teamID, viewer.UserID).
+		Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check team membership: %w", err)
+	}
+	if count == 0 {
+		return ErrNotTeamMember
+	}
+	return nil
+}
+
+// UpdateVisibility 变更评论可见范围并记录工单历史
+func (s *TicketCommentService) UpdateVisibility(ctx context.Context, ticketID, commentID uint, req *models.CommentVisibilityUpdateRequest, viewer CommentViewer) (*models.TicketComment, error) {
+	if !models.IsValidCommentVisibility(req.Visibility) {
+		return nil, ErrInvalidCommentVisibility
+	}
+	if viewer.IsCustomer() {
+		return nil, ErrCommentVisibilityForbidden
+	}
+
+	var comment models.TicketComment
+	if err := s.db.WithContext(ctx).
+		Where("id = ? AND ticket_id = ? AND is_deleted = ?", commentID, ticketID, false).
+		First(&comment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCommentNotFound
+		}
+		return nil, fmt.Errorf("failed to get comment: %w", err)
+	}
+	if comment.Type == models.CommentTypeSystem {
+		return nil, fmt.Errorf("%w: system comments cannot be changed", ErrCommentVisibilityForbidden)
+	}
+	// 团队限定评论只有能看到它的人才能修改
+	if !commentVisibleToUser(s.db.WithContext(ctx), &comment, &models.User{ID: viewer.UserID, Role: models.UserRole(viewer.Role)}) {
+		return nil, ErrCommentNotFound
+	}
+	if err := s.checkVisibilityAllowed(ctx, viewer, req.Visibility, req.TeamID); err != nil {
+		return nil, err
+	}
+
+	oldValue := describeCommentVisibility(comment.Visibility, comment.VisibleTeamID)
+	comment.Visibility = req.Visibility
+	comment.VisibleTeamID = req.TeamID
+	comment.SyncVisibility()
+	newValue := describeCommentVisibility(comment.Visibility, comment.VisibleTeamID)
+	if oldValue == newValue {
+		return &comment, nil
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.TicketComment{}).Where("id = ?", comment.ID).Updates(map[string]interface{}{
+			"visibility":      comment.Visibility,
+			"visible_team_id": comment.VisibleTeamID,
+			"type":            comment.Type,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to update comment visibility: %w", err)
+		}
+
+		history := &models.TicketHistory{
+			TicketID:    ticketID,
+			UserID:      &viewer.UserID,
+			Action:      models.HistoryActionComment,
+			Description: fmt.Sprintf("评论 #%d 可见范围由 %s 变更为 %s", comment.ID, oldValue, newValue),
+			FieldName:   "comment_visibility",
+			OldValue:    oldValue,
+			NewValue:    newValue,
+			CommentID:   &comment.ID,
+			IsVisible:   true,
+		}
+		if err := tx.Create(history).Error; err != nil {
+			return fmt.Errorf("failed to record visibility history: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.db.WithContext(ctx).Preload("User").First(&comment, comment.ID)
+	return &comment, nil
+}
+
+func describeCommentVisibility(visibility models.CommentVisibility, teamID *uint) string {
+	if visibility == models.CommentVisibilityTeam && teamID != nil {
+		return fmt.Sprintf("team:%d", *teamID)
+	}
+	return string(visibility)
+}
+
+// DraftService 返回评论草稿服务
+func (s *TicketCommentService) DraftService() *CommentDraftService {
+	return s.draftService
+}
+
+// TranslationService 返回评论翻译服务
+func (s *TicketCommentService) TranslationService() *CommentTranslationService {
+	return s.translationService
+}
+
+// GetVisibilityDefaults 获取各角色评论默认可见范围
+func (s *TicketCommentService) GetVisibilityDefaults(ctx context.Context) (*models.CommentVisibilityDefaults, error) {
+	var config models.SystemConfig
+	err := s.db.WithContext(ctx).
+		Where("key = ? AND is_active = ?", KeyCommentVisibilityDefaults, true).
+		First(&config).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.GetDefaultCommentVisibilityDefaults(), nil
+		}
+		return nil, fmt.Errorf("failed to get comment visibility defaults: %w", err)
+	}
+
+	defaults := models.GetDefaultCommentVisibilityDefaults()
+	if err := config.GetJSONValue(defaults); err != nil {
+		log.Printf("Warning: failed to parse comment visibility defaults, using defaults: %v", err)
+		return models.GetDefaultCommentVisibilityDefaults(), nil
+	}
+	return defaults, nil
+}
+
+// SetVisibilityDefaults 保存各角色评论默认可见范围；客户角色始终为 public
+func (s *TicketCommentService) SetVisibilityDefaults(ctx context.Context, defaults *models.CommentVisibilityDefaults, userID uint) error {
+	for role, visibility := range defaults.Roles {
+		if !models.IsValidCommentVisibility(visibility) {
+			return fmt.Errorf("%w: %s", ErrInvalidCommentVisibility, visibility)
+		}
+		// 团队可见需要在发表时指定团队，不能作为默认值
+		if visibility == models.CommentVisibilityTeam {
+			return fmt.Errorf("%w: team visibility cannot be a default", ErrInvalidCommentVisibility)
+		}
+		if (CommentViewer{Role: role}).IsCustomer() && visibility != models.CommentVisibilityPublic {
+			return fmt.Errorf("%w: customers can only post public comments", ErrInvalidCommentVisibility)
+		}
+	}
+
+	var existing models.SystemConfig
+	err := s.db.WithContext(ctx).Where("key = ?", KeyCommentVisibilityDefaults).First(&existing).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to check existing comment visibility defaults: %w", err)
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		config := models.SystemConfig{
+			Key:         KeyCommentVisibilityDefaults,
+			Category:    CategoryTicket,
+			Group:       "comments",
+			Description: "各角色评论默认可见范围",
+			IsActive:    true,
+			UpdatedBy:   &userID,
+		}
+		if err := config.SetValue(defaults); err != nil {
+			return fmt.Errorf("failed to set comment visibility defaults value: %w", err)
+		}
+		if err := s.db.WithContext(ctx).Create(&config).Error; err != nil {
+			return fmt.Errorf("failed to create comment visibility defaults: %w", err)
+		}
+		return nil
+	}
+
+	if err := existing.SetValue(defaults); err != nil {
+		return fmt.Errorf("failed to set comment visibility defaults value: %w", err)
+	}
+	existing.UpdatedBy = &userID
+	existing.Version++
+	if err := s.db.WithContext(ctx).Save(&existing).Error; err != nil {
+		return fmt.Errorf("failed to update comment visibility defaults: %w", err)
+	}
+	return nil
+}
+
+// ComposerOptions 评论编辑器的默认可见范围及可选范围
+func (s *TicketCommentService) ComposerOptions(ctx context.Context, viewer CommentViewer) (models.CommentVisibility, []models.CommentVisibility) {
+	if viewer.IsCustomer() {
+		return models.CommentVisibilityPublic, []models.CommentVisibility{models.CommentVisibilityPublic}
+	}
+
+	defaultVisibility := models.CommentVisibilityPublic
+	if defaults, err := s.GetVisibilityDefaults(ctx); err == nil {
+		defaultVisibility = defaults.For(viewer.Role)
+	}
+	return defaultVisibility, []models.CommentVisibility{
+		models.CommentVisibilityPublic,
+		models.CommentVisibilityInternal,
+		models.CommentVisibilityTeam,
+	}
+}
@@ -0,0 +1,127 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+// ticketCountsCacheTTL 导航角标数字的缓存时间，前端每 30 秒轮询一次，每个用户最多 15 秒查询一次数据库
+const ticketCountsCacheTTL = 15 * time.Second
+
+// ErrTicketCountsUserInactive 用户不存在或已停用，不再推送角标数字
+var ErrTicketCountsUserInactive = errors.New("user not found or inactive")
+
+// TicketCounts 导航角标数字，工单数字按仪表板相同的可见范围统计
+type TicketCounts struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	Scope       string    `json:"scope"`       // all / assigned / own
+	MyOpen      int64     `json:"my_open"`     // 分配给我（客户为我提交）的未完结工单
+	Unassigned  int64     `json:"unassigned"`  // 范围内未分配给个人的未完结工单，客户为 0
+	Overdue     int64     `json:"overdue"`     // 范围内已逾期的未完结工单
+	SLAAtRisk   int64     `json:"sla_at_risk"` // 范围内已违约或即将到期的未完结工单，客户为 0
+	Mentions    int64     `json:"mentions"`    // 未读的提及通知，不缓存
+}
+
+// SameNumbers 两次统计的数字是否相同，用于只在变化时推送
+func (c *TicketCounts) SameNumbers(other *TicketCounts) bool {
+	if c == nil || other == nil {
+		return c == other
+	}
+	return c.Scope == other.Scope && c.MyOpen == other.MyOpen && c.Unassigned == other.Unassigned &&
+		c.Overdue == other.Overdue && c.SLAAtRisk == other.SLAAtRisk && c.Mentions == other.Mentions
+}
+
+// TicketCountsService 导航角标统计服务，只返回数字，供轮询或 WebSocket 推送
+type TicketCountsService struct {
+	db    *gorm.DB
+	cache *ReadThroughCache
+}
+
+// NewTicketCountsService 创建角标统计服务
+func NewTicketCountsService(db *gorm.DB) *TicketCountsService {
+	return &TicketCountsService{db: db}
+}
+
+// SetCache 设置结果缓存，未设置或 Redis 不可用时直接查询数据库
+func (s *TicketCountsService) SetCache(cache *ReadThroughCache) {
+	s.cache = cache
+}
+
+// GetCounts 获取当前用户的角标数字：工单数字按用户缓存，未读提及数每次查询，读完通知后角标立即更新
+func (s *TicketCountsService) GetCounts(ctx context.Context, userID uint, role string) (*TicketCounts, error) {
+	counts := &TicketCounts{}
+	load := func() (bool, error) {
+		if err := s.loadTicketCounts(ctx, newDashboardScope(userID, role), counts); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+
+	if err := s.cache.GetOrLoad(ctx, fmt.Sprintf("counts:%d:%s", userID, role), ticketCountsCacheTTL, counts, load); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.WithContext(ctx).Model(&models.Notification{}).
+		Where("recipient_id = ? AND type = ? AND is_read = ?", userID, models.NotificationTypeUserMention, false).
+		Count(&counts.Mentions).Error; err != nil {
+		return nil, fmt.Errorf("failed to count unread mentions: %w", err)
+	}
+	return counts, nil
+}
+
+// GetCountsForUser 按用户当前角色获取角标数字，供没有请求上下文的 WebSocket 推送使用
+func (s *TicketCountsService) GetCountsForUser(ctx context.Context, userID uint) (*TicketCounts, error) {
+	var user models.User
+	if err := s.db.WithContext(ctx).Select("id", "role", "status").First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTicketCountsUserInactive
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.Status != models.UserStatusActive {
+		return nil, ErrTicketCountsUserInactive
+	}
+	return s.GetCounts(ctx, userID, string(user.Role))
+}
+
+// loadTicketCounts 一次聚合查询得到全部工单数字
+func (s *TicketCountsService) loadTicketCounts(ctx context.Context, scope dashboardScope, counts *TicketCounts) error {
+	now := time.Now()
+	mine := "tickets.assigned_to_id = ?"
+	if scope.customer {
+		mine = "tickets.created_by_id = ?"
+	}
+
+	var row struct {
+		MyOpen     int64
+		Unassigned int64
+		Overdue    int64
+		SLAAtRisk  int64
+	}
+	query := s.db.WithContext(ctx).Model(&models.Ticket{}).
+		Where("tickets.deleted_at IS NULL AND tickets.status NOT IN ?", closedTicketStatuses)
+	if err := scope.apply(s.db, query).
+		Select(`COALESCE(SUM(CASE WHEN `+mine+` THEN 1 ELSE 0 END), 0) AS my_open,
+			COALESCE(SUM(CASE WHEN tickets.assigned_to_id IS NULL THEN 1 ELSE 0 END), 0) AS unassigned,
+			COALESCE(SUM(CASE WHEN tickets.due_date < ? THEN 1 ELSE 0 END), 0) AS overdue,
+			COALESCE(SUM(CASE WHEN tickets.sla_breached = ? OR tickets.sla_due_date < ? THEN 1 ELSE 0 END), 0) AS sla_at_risk`,
+			scope.userID, now, true, now.Add(dashboardSLARiskWindow)).
+		Scan(&row).Error; err != nil {
+		return fmt.Errorf("failed to count tickets: %w", err)
+	}
+
+	counts.GeneratedAt = now
+	counts.Scope = scope.name
+	counts.MyOpen = row.MyOpen
+	counts.Overdue = row.Overdue
+	if !scope.customer {
+		counts.Unassigned = row.Unassigned
+		counts.SLAAtRisk = row.SLAAtRisk
+	}
+	return nil
+}
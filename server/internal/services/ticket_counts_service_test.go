@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestTicketCounts_ScopesAndCache(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:ticket_counts_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Team{}, &models.Ticket{}, &models.Notification{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+	ctx := context.Background()
+
+	agent := models.User{Username: "counts-agent", Email: "agent@example.com", PasswordHash: "x", Role: models.RoleAgent, Status: models.UserStatusActive}
+	customer := models.User{Username: "counts-customer", Email: "customer@example.com", PasswordHash: "x", Role: models.RoleCustomer, Status: models.UserStatusActive}
+	suspended := models.User{Username: "counts-suspended", Email: "suspended@example.com", PasswordHash: "x", Role: models.RoleAgent, Status: models.UserStatusSuspended}
+	for _, user := range []*models.User{&agent, &customer, &suspended} {
+		if err := db.Create(user).Error; err != nil {
+			t.Fatalf("failed to seed user: %v", err)
+		}
+	}
+	teamService := NewTeamService(db)
+	mine, err := teamService.CreateTeam(ctx, &models.TeamCreateRequest{Name: "Counts L1", Slug: "counts-l1", MemberIDs: []uint{agent.ID}})
+	if err != nil {
+		t.Fatalf("failed to create team: %v", err)
+	}
+	other, err := teamService.CreateTeam(ctx, &models.TeamCreateRequest{Name: "Counts L2", Slug: "counts-l2"})
+	if err != nil {
+		t.Fatalf("failed to create team: %v", err)
+	}
+
+	yesterday := time.Now().Add(-24 * time.Hour)
+	soon := time.Now().Add(time.Hour)
+	seed := func(number string, status models.TicketStatus, teamID uint, assignee *uint, due, slaDue *time.Time) {
+		ticket := models.Ticket{TicketNumber: number, Title: "counts ticket", Status: status, Priority: models.TicketPriorityNormal,
+			Type: models.TicketTypeRequest, Source: models.TicketSourceWeb, CreatedByID: customer.ID,
+			AssignedTeamID: &teamID, AssignedToID: assignee, DueDate: due, SLADueDate: slaDue}
+		if err := db.Create(&ticket).Error; err != nil {
+			t.Fatalf("failed to seed ticket: %v", err)
+		}
+	}
+	seed("TC-001", models.TicketStatusOpen, mine.ID, nil, &yesterday, nil)           // 团队未分配、已逾期
+	seed("TC-002", models.TicketStatusInProgress, mine.ID, &agent.ID, nil, &soon)    // 我的、SLA 即将到期
+	seed("TC-003", models.TicketStatusOpen, other.ID, nil, nil, nil)                 // 其他团队未分配
+	seed("TC-004", models.TicketStatusResolved, mine.ID, &agent.ID, &yesterday, nil) // 已解决，不计入
+
+	for _, read := range []bool{false, true} {
+		if err := db.Create(&models.Notification{Type: models.NotificationTypeUserMention, Title: "@you", Content: "mention",
+			Priority: models.NotificationPriorityNormal, Channel: models.NotificationChannelInApp, RecipientID: agent.ID, IsRead: read}).Error; err != nil {
+			t.Fatalf("failed to seed notification: %v", err)
+		}
+	}
+
+	svc := NewTicketCountsService(db)
+	cases := []struct {
+		name   string
+		userID uint
+		role   string
+		want   TicketCounts
+	}{
+		{"agent", agent.ID, "agent", TicketCounts{Scope: "assigned", MyOpen: 1, Unassigned: 1, Overdue: 1, SLAAtRisk: 1, Mentions: 1}},
+		{"admin", 9999, "admin", TicketCounts{Scope: "all", MyOpen: 0, Unassigned: 2, Overdue: 1, SLAAtRisk: 1}},
+		{"customer", customer.ID, "customer", TicketCounts{Scope: "own", MyOpen: 3, Overdue: 1}},
+	}
+	for _, tc := range cases {
+		counts, err := svc.GetCounts(ctx, tc.userID, tc.role)
+		if err != nil {
+			t.Fatalf("%s: get counts: %v", tc.name, err)
+		}
+		if !counts.SameNumbers(&tc.want) {
+			t.Fatalf("%s: unexpected counts %+v, want %+v", tc.name, counts, tc.want)
+		}
+	}
+
+	// 工单数字走缓存，未读提及数每次查询
+	svc.SetCache(NewReadThroughCache(&memoryCacheStore{data: map[string]string{}}, "cache:test"))
+	if _, err := svc.GetCounts(ctx, agent.ID, "agent"); err != nil {
+		t.Fatalf("warm cache: %v", err)
+	}
+	seed("TC-005", models.TicketStatusOpen, mine.ID, &agent.ID, nil, nil)
+	db.Model(&models.Notification{}).Where("recipient_id = ?", agent.ID).Update("is_read", true)
+	counts, err := svc.GetCounts(ctx, agent.ID, "agent")
+	if err != nil {
+		t.Fatalf("cached counts: %v", err)
+	}
+	if counts.MyOpen != 1 || counts.Mentions != 0 {
+		t.Fatalf("expected cached ticket counts with fresh mentions, got %+v", counts)
+	}
+
+	// WebSocket 推送按用户当前角色统计，停用用户不再推送
+	if counts, err := svc.GetCountsForUser(ctx, customer.ID); err != nil || counts.Scope != "own" {
+		t.Fatalf("expected customer scope, got %+v, %v", counts, err)
+	}
+	if _, err := svc.GetCountsForUser(ctx, suspended.ID); !errors.Is(err, ErrTicketCountsUserInactive) {
+		t.Fatalf("expected inactive user error, got %v", err)
+	}
+}
@@ -0,0 +1,551 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	ticketPurgePlaceholder     = "[已删除]"
+	ticketPurgedDescription    = "[客户数据已按删除请求清除]"
+	ticketDeletionCertAlgo     = "HMAC-SHA256"
+	ticketPurgeMinNameRunes    = 2
+	ticketPurgeAnonymousSender = "ticket_%d@deleted.invalid"
+)
+
+var (
+	// ErrTicketPurgeNotFound 工单不存在
+	ErrTicketPurgeNotFound = errors.New("ticket not found")
+	// ErrTicketLegalHold 工单处于法律保留，不能清除客户数据
+	ErrTicketLegalHold = errors.New("ticket is under legal hold")
+	// ErrTicketPurgeInvalid 请求参数无效
+	ErrTicketPurgeInvalid = errors.New("invalid ticket purge request")
+	// ErrDeletionCertificateInvalid 删除证书签名不匹配
+	ErrDeletionCertificateInvalid = errors.New("deletion certificate signature mismatch")
+)
+
+// TicketDataPurgeService 按数据主体删除请求清除或匿名化单个工单中的客户数据，
+// 法律保留期间拒绝处理，完成后生成签名的删除证书写入审计日志
+type TicketDataPurgeService struct {
+	db           *gorm.DB
+	storage      FileStorage
+	signer       *hmacSigner
+	auditService *AdminAuditService
+}
+
+// NewTicketDataPurgeService 创建工单客户数据删除服务
+func NewTicketDataPurgeService(db *gorm.DB, storage FileStorage, auditService *AdminAuditService) *TicketDataPurgeService {
+	return &TicketDataPurgeService{
+		db:           db,
+		storage:      storage,
+		signer:       newHMACSigner(NewConfigService(db), KeyDataDeletionSigningKey, "工单客户数据删除证书的签名密钥", "account_deletion"),
+		auditService: auditService,
+	}
+}
+
+// SetLegalHold 设置或解除工单法律保留，设置时必须填写原因
+func (s *TicketDataPurgeService) SetLegalHold(ctx context.Context, ticketID uint, req *models.TicketLegalHoldRequest, actorID uint) (*models.Ticket, error) {
+	reason := strings.TrimSpace(req.Reason)
+	if req.Hold && reason == "" {
+		return nil, fmt.Errorf("%w: reason is required when placing a legal hold", ErrTicketPurgeInvalid)
+	}
+
+	var ticket models.Ticket
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&ticket, ticketID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTicketPurgeNotFound
+			}
+			return fmt.Errorf("failed to get ticket: %w", err)
+		}
+		if ticket.LegalHold == req.Hold && (!req.Hold || ticket.LegalHoldReason == reason) {
+			return nil
+		}
+
+		updates := map[string]interface{}{"legal_hold": req.Hold}
+		description := "解除法律保留"
+		if req.Hold {
+			now := time.Now()
+			updates["legal_hold_reason"] = reason
+			updates["legal_hold_by_id"] = actorID
+			updates["legal_hold_at"] = now
+			description = "设置法律保留：" + reason
+		} else {
+			updates["legal_hold_reason"] = ""
+			updates["legal_hold_by_id"] = nil
+			updates["legal_hold_at"] = nil
+		}
+		if err := tx.Model(&models.Ticket{}).Where("id = ?", ticket.ID).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to update legal hold: %w", err)
+		}
+		if err := recordHistory(tx, nil, &models.TicketHistory{
+			TicketID:    ticket.ID,
+			UserID:      &actorID,
+			Action:      models.HistoryActionSystem,
+			Description: description,
+			FieldName:   "legal_hold",
+			OldValue:    fmt.Sprintf("%t", ticket.LegalHold),
+			NewValue:    fmt.Sprintf("%t", req.Hold),
+			IsImportant: true,
+		}); err != nil {
+			return err
+		}
+		return tx.First(&ticket, ticket.ID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &ticket, nil
+}
+
+// ListLegalHolds 列出处于法律保留的工单
+func (s *TicketDataPurgeService) ListLegalHolds(ctx context.Context) ([]models.Ticket, error) {
+	var tickets []models.Ticket
+	if err := s.db.WithContext(ctx).
+		Select("id", "ticket_number", "title", "status", "legal_hold", "legal_hold_reason", "legal_hold_by_id", "legal_hold_at", "created_at").
+		Where("legal_hold = ?", true).Order("legal_hold_at DESC").Find(&tickets).Error; err != nil {
+		return nil, fmt.Errorf("failed to list legal holds: %w", err)
+	}
+	return tickets, nil
+}
+
+// Purge 清除或匿名化工单中的客户数据，返回已签名并写入审计日志的删除证书；
+// 已软删除的工单同样可以处理
+func (s *TicketDataPurgeService) Purge(ctx context.Context, ticketID uint, req *models.TicketPurgeRequest, actorID uint) (*models.TicketDeletionCertificate, error) {
+	reason := strings.TrimSpace(req.Reason)
+	if reason == "" {
+		return nil, fmt.Errorf("%w: reason is required", ErrTicketPurgeInvalid)
+	}
+	if req.Mode != models.TicketPurgeModeAnonymize && req.Mode != models.TicketPurgeModePurge {
+		return nil, fmt.Errorf("%w: unsupported mode %q", ErrTicketPurgeInvalid, req.Mode)
+	}
+
+	var (
+		ticket models.Ticket
+		counts models.TicketPurgeCounts
+		files  []string
+	)
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&ticket, ticketID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTicketPurgeNotFound
+			}
+			return fmt.Errorf("failed to get ticket: %w", err)
+		}
+		if ticket.LegalHold {
+			return ErrTicketLegalHold
+		}
+
+		scrub, err := s.buildScrubber(tx, &ticket)
+		if err != nil {
+			return err
+		}
+		customerIDs := tx.Model(&models.User{}).Select("id").Where("role = ?", models.RoleCustomer)
+
+		if files, err = s.purgeAttachments(tx, &ticket, req.Mode, customerIDs, &counts); err != nil {
+			return err
+		}
+		if err := s.purgeComments(tx, &ticket, req.Mode, customerIDs, scrub, &counts); err != nil {
+			return err
+		}
+		if err := s.purgeEmails(tx, &ticket, req.Mode, scrub, &counts); err != nil {
+			return err
+		}
+		if err := s.purgeCallLogs(tx, &ticket, req.Mode, scrub, &counts); err != nil {
+			return err
+		}
+		if err := s.scrubHistory(tx, &ticket, scrub, &counts); err != nil {
+			return err
+		}
+		if err := s.purgeTicketFields(tx, &ticket, req.Mode, scrub, &counts); err != nil {
+			return err
+		}
+
+		return recordHistory(tx, nil, &models.TicketHistory{
+			TicketID:    ticket.ID,
+			UserID:      &actorID,
+			Action:      models.HistoryActionRedaction,
+			Description: fmt.Sprintf("按删除请求%s客户数据", purgeModeLabel(req.Mode)),
+			FieldName:   "pii_purged_at",
+			IsImportant: true,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// 事务提交后再删除文件，失败只记录日志，数据库中已不再引用
+	for _, key := range files {
+		if err := s.storage.Delete(ctx, key); err != nil {
+			log.Printf("failed to delete purged attachment %s: %v", key, err)
+		}
+	}
+
+	cert := &models.TicketDeletionCertificate{
+		CertificateID: newCertificateID(),
+		TicketID:      ticket.ID,
+		TicketNumber:  ticket.TicketNumber,
+		Mode:          req.Mode,
+		Reason:        reason,
+		Counts:        counts,
+		PerformedByID: actorID,
+		PerformedAt:   time.Now().UTC().Truncate(time.Microsecond),
+		Algorithm:     ticketDeletionCertAlgo,
+	}
+	if email := strings.ToLower(strings.TrimSpace(ticket.CustomerEmail)); email != "" {
+		sum := sha256.Sum256([]byte(email))
+		cert.SubjectHash = hex.EncodeToString(sum[:])
+	}
+	if err := s.sign(cert); err != nil {
+		return nil, err
+	}
+	if err := s.recordCertificate(ctx, cert); err != nil {
+		return nil, err
+	}
+	return cert, nil
+}
+
+// VerifyCertificate 重新计算签名，校验删除证书未被篡改
+func (s *TicketDataPurgeService) VerifyCertificate(ctx context.Context, cert *models.TicketDeletionCertificate) error {
+	if cert == nil || cert.Signature == "" {
+		return ErrDeletionCertificateInvalid
+	}
+	payload, err := certificatePayload(cert)
+	if err != nil {
+		return err
+	}
+	valid, err := s.signer.Verify(payload, cert.Signature)
+	if err != nil {
+		return err
+	}
+	if !valid {
+		return ErrDeletionCertificateInvalid
+	}
+	return nil
+}
+
+// purgeAttachments 删除客户上传的附件（清除模式下删除全部附件），返回需要删除的存储文件
+func (s *TicketDataPurgeService) purgeAttachments(tx *gorm.DB, ticket *models.Ticket, mode models.TicketPurgeMode, customerIDs *gorm.DB, counts *models.TicketPurgeCounts) ([]string, error) {
+	query := tx.Model(&models.TicketAttachment{}).Where("ticket_id = ?", ticket.ID)
+	if mode == models.TicketPurgeModeAnonymize {
+		query = query.Where("uploaded_by IN (?)", customerIDs)
+	}
+	var attachments []models.TicketAttachment
+	if err := query.Find(&attachments).Error; err != nil {
+		return nil, fmt.Errorf("failed to load attachments: %w", err)
+	}
+	if len(attachments) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]uint, 0, len(attachments))
+	files := make([]string, 0, len(attachments))
+	for _, attachment := range attachments {
+		ids = append(ids, attachment.ID)
+		if attachment.StoragePath != "" {
+			files = append(files, attachment.StoragePath)
+		}
+	}
+	if err := tx.Where("id IN ?", ids).Delete(&models.TicketAttachment{}).Error; err != nil {
+		return nil, fmt.Errorf("failed to delete attachments: %w", err)
+	}
+	counts.AttachmentsDeleted = len(ids)
+	return files, nil
+}
+
+// purgeComments 替换评论中的客户信息并删除评论译文；清除模式下直接删除客户评论
+func (s *TicketDataPurgeService) purgeComments(tx *gorm.DB, ticket *models.Ticket, mode models.TicketPurgeMode, customerIDs *gorm.DB, scrub *piiScrubber, counts *models.TicketPurgeCounts) error {
+	commentIDs := tx.Model(&models.TicketComment{}).Select("id").Where("ticket_id = ?", ticket.ID)
+	if err := tx.Where("comment_id IN (?)", commentIDs).Delete(&models.CommentTranslation{}).Error; err != nil {
+		return fmt.Errorf("failed to delete comment translations: %w", err)
+	}
+
+	if mode == models.TicketPurgeModePurge {
+		result := tx.Where("ticket_id = ? AND user_id IN (?)", ticket.ID, customerIDs).Delete(&models.TicketComment{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete customer comments: %w", result.Error)
+		}
+		counts.CommentsDeleted = int(result.RowsAffected)
+	}
+
+	var comments []models.TicketComment
+	if err := tx.Select("id", "content", "metadata").Where("ticket_id = ?", ticket.ID).Find(&comments).Error; err != nil {
+		return fmt.Errorf("failed to load comments: %w", err)
+	}
+	for _, comment := range comments {
+		content, metadata := scrub.Replace(comment.Content), scrub.Replace(comment.Metadata)
+		if content == comment.Content && metadata == comment.Metadata {
+			continue
+		}
+		if err := tx.Model(&models.TicketComment{}).Where("id = ?", comment.ID).
+			UpdateColumns(map[string]interface{}{"content": content, "metadata": metadata}).Error; err != nil {
+			return fmt.Errorf("failed to redact comment: %w", err)
+		}
+		counts.CommentsRedacted++
+	}
+
+	if counts.CommentsDeleted > 0 {
+		var remaining int64
+		if err := tx.Model(&models.TicketComment{}).Where("ticket_id = ? AND deleted_at IS NULL", ticket.ID).Count(&remaining).Error; err != nil {
+			return fmt.Errorf("failed to count comments: %w", err)
+		}
+		ticket.CommentCount = int(remaining)
+	}
+	return nil
+}
+
+// purgeEmails 匿名化来信（清除模式下删除来信，会话消息保留但不再关联原始邮件）
+func (s *TicketDataPurgeService) purgeEmails(tx *gorm.DB, ticket *models.Ticket, mode models.TicketPurgeMode, scrub *piiScrubber, counts *models.TicketPurgeCounts) error {
+	if mode == models.TicketPurgeModePurge {
+		emailIDs := tx.Model(&models.InboundEmail{}).Select("id").Where("ticket_id = ?", ticket.ID)
+		if err := tx.Model(&models.EmailThreadMessage{}).Where("inbound_email_id IN (?)", emailIDs).
+			UpdateColumn("inbound_email_id", nil).Error; err != nil {
+			return fmt.Errorf("failed to detach thread messages: %w", err)
+		}
+		result := tx.Where("ticket_id = ?", ticket.ID).Delete(&models.InboundEmail{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete inbound emails: %w", result.Error)
+		}
+		counts.EmailsDeleted = int(result.RowsAffected)
+		return nil
+	}
+
+	var emails []models.InboundEmail
+	if err := tx.Select("id", "subject", "body").Where("ticket_id = ?", ticket.ID).Find(&emails).Error; err != nil {
+		return fmt.Errorf("failed to load inbound emails: %w", err)
+	}
+	for _, email := range emails {
+		if err := tx.Model(&models.InboundEmail{}).Where("id = ?", email.ID).UpdateColumns(map[string]interface{}{
+			"from_address": fmt.Sprintf(ticketPurgeAnonymousSender, ticket.ID),
+			"from_name":    "",
+			"subject":      scrub.Replace(email.Subject),
+			"body":         scrub.Replace(email.Body),
+		}).Error; err != nil {
+			return fmt.Errorf("failed to anonymize inbound email: %w", err)
+		}
+	}
+	counts.EmailsRedacted = len(emails)
+	return nil
+}
+
+// purgeCallLogs 清除通话记录中的号码（清除模式下删除通话记录）
+func (s *TicketDataPurgeService) purgeCallLogs(tx *gorm.DB, ticket *models.Ticket, mode models.TicketPurgeMode, scrub *piiScrubber, counts *models.TicketPurgeCounts) error {
+	if mode == models.TicketPurgeModePurge {
+		result := tx.Where("ticket_id = ?", ticket.ID).Delete(&models.TicketCallLog{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete call logs: %w", result.Error)
+		}
+		counts.CallLogs = int(result.RowsAffected)
+		return nil
+	}
+
+	var calls []models.TicketCallLog
+	if err := tx.Select("id", "notes").Where("ticket_id = ?", ticket.ID).Find(&calls).Error; err != nil {
+		return fmt.Errorf("failed to load call logs: %w", err)
+	}
+	for _, call := range calls {
+		if err := tx.Model(&models.TicketCallLog{}).Where("id = ?", call.ID).UpdateColumns(map[string]interface{}{
+			"phone_number": "",
+			"notes":        scrub.Replace(call.Notes),
+		}).Error; err != nil {
+			return fmt.Errorf("failed to anonymize call log: %w", err)
+		}
+	}
+	counts.CallLogs = len(calls)
+	return nil
+}
+
+// scrubHistory 替换工单历史中的客户信息
+func (s *TicketDataPurgeService) scrubHistory(tx *gorm.DB, ticket *models.Ticket, scrub *piiScrubber, counts *models.TicketPurgeCounts) error {
+	var histories []models.TicketHistory
+	if err := tx.Select("id", "description", "old_value", "new_value", "metadata").
+		Where("ticket_id = ?", ticket.ID).Find(&histories).Error; err != nil {
+		return fmt.Errorf("failed to load ticket history: %w", err)
+	}
+	for _, h := range histories {
+		description, oldValue, newValue := scrub.Replace(h.Description), scrub.Replace(h.OldValue), scrub.Replace(h.NewValue)
+		metadata := models.JSONText(scrub.Replace(string(h.Metadata)))
+		if description == h.Description && oldValue == h.OldValue && newValue == h.NewValue && metadata == h.Metadata {
+			continue
+		}
+		if err := tx.Model(&models.TicketHistory{}).Where("id = ?", h.ID).UpdateColumns(map[string]interface{}{
+			"description": description,
+			"old_value":   oldValue,
+			"new_value":   newValue,
+			"metadata":    metadata,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to redact ticket history: %w", err)
+		}
+		counts.HistoryRedacted++
+	}
+	return nil
+}
+
+// purgeTicketFields 清空客户联系信息并替换工单文本中的客户信息
+func (s *TicketDataPurgeService) purgeTicketFields(tx *gorm.DB, ticket *models.Ticket, mode models.TicketPurgeMode, scrub *piiScrubber, counts *models.TicketPurgeCounts) error {
+	for _, value := range []string{ticket.CustomerEmail, ticket.CustomerName, ticket.CustomerPhone} {
+		if value != "" {
+			counts.CustomerFields++
+		}
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"customer_email": "",
+		"customer_name":  "",
+		"customer_phone": "",
+		"title":          scrub.Replace(ticket.Title),
+		"internal_notes": scrub.Replace(ticket.InternalNotes),
+		"comment_count":  ticket.CommentCount,
+		"pii_purged_at":  now,
+	}
+	if mode == models.TicketPurgeModePurge {
+		updates["description"] = ticketPurgedDescription
+		updates["rating_comment"] = ""
+		updates["attachments"] = ""
+		updates["custom_fields"] = models.JSONText("{}")
+	} else {
+		updates["description"] = scrub.Replace(ticket.Description)
+		updates["rating_comment"] = scrub.Replace(ticket.RatingComment)
+		updates["custom_fields"] = models.JSONText(scrub.Replace(string(ticket.CustomFields)))
+	}
+	if err := tx.Model(&models.Ticket{}).Where("id = ?", ticket.ID).UpdateColumns(updates).Error; err != nil {
+		return fmt.Errorf("failed to purge ticket fields: %w", err)
+	}
+	ticket.PIIPurgedAt = &now
+	return nil
+}
+
+// buildScrubber 收集工单关联的客户邮箱、姓名和电话：工单上的客户信息、客户角色的提交人及来信发件人
+func (s *TicketDataPurgeService) buildScrubber(tx *gorm.DB, ticket *models.Ticket) (*piiScrubber, error) {
+	terms := []string{ticket.CustomerEmail, ticket.CustomerName, ticket.CustomerPhone}
+
+	var creator models.User
+	if err := tx.Select("id", "role", "email", "username", "first_name", "last_name", "display_name", "phone").
+		First(&creator, ticket.CreatedByID).Error; err == nil && creator.Role == models.RoleCustomer {
+		terms = append(terms, creator.Email, creator.DisplayName, creator.Phone,
+			strings.TrimSpace(creator.FirstName+" "+creator.LastName))
+	} else if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to get ticket creator: %w", err)
+	}
+
+	var senders []models.InboundEmail
+	if err := tx.Select("from_address", "from_name").Where("ticket_id = ?", ticket.ID).Find(&senders).Error; err != nil {
+		return nil, fmt.Errorf("failed to load inbound email senders: %w", err)
+	}
+	for _, sender := range senders {
+		terms = append(terms, sender.FromAddress, sender.FromName)
+	}
+	return newPIIScrubber(terms), nil
+}
+
+// recordCertificate 将删除证书写入审计日志
+func (s *TicketDataPurgeService) recordCertificate(ctx context.Context, cert *models.TicketDeletionCertificate) error {
+	body, err := json.Marshal(cert)
+	if err != nil {
+		return fmt.Errorf("failed to encode deletion certificate: %w", err)
+	}
+	actorID := cert.PerformedByID
+	record := &AdminAuditRecord{
+		UserID:     &actorID,
+		Action:     models.AuditActionTicketPIIPurge,
+		Method:     "POST",
+		Path:       fmt.Sprintf("/api/admin/tickets/%d/purge-pii", cert.TicketID),
+		StatusCode: 200,
+		Result:     "success",
+		Notes:      string(body),
+	}
+	_, record.ClientIP, record.UserAgent = AuditActorFrom(ctx)
+	if err := s.auditService.Record(ctx, record); err != nil {
+		return fmt.Errorf("failed to record deletion certificate: %w", err)
+	}
+	return nil
+}
+
+func (s *TicketDataPurgeService) sign(cert *models.TicketDeletionCertificate) error {
+	payload, err := certificatePayload(cert)
+	if err != nil {
+		return err
+	}
+	signature, err := s.signer.Sign(payload)
+	if err != nil {
+		return err
+	}
+	cert.Signature = signature
+	return nil
+}
+
+// certificatePayload 签名内容为除 signature 外的证书 JSON
+func certificatePayload(cert *models.TicketDeletionCertificate) (string, error) {
+	unsigned := *cert
+	unsigned.Signature = ""
+	payload, err := json.Marshal(unsigned)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode deletion certificate: %w", err)
+	}
+	return string(payload), nil
+}
+
+func newCertificateID() string {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(buf)
+}
+
+func purgeModeLabel(mode models.TicketPurgeMode) string {
+	if mode == models.TicketPurgeModePurge {
+		return "清除"
+	}
+	return "匿名化"
+}
+
+// piiScrubber 不区分大小写地将已知的客户邮箱、姓名、电话替换为占位文本
+type piiScrubber struct {
+	pattern *regexp.Regexp
+}
+
+func newPIIScrubber(terms []string) *piiScrubber {
+	seen := make(map[string]bool)
+	var quoted []string
+	for _, term := range terms {
+		term = strings.TrimSpace(term)
+		if utf8.RuneCountInString(term) < ticketPurgeMinNameRunes || seen[strings.ToLower(term)] {
+			continue
+		}
+		seen[strings.ToLower(term)] = true
+		quoted = append(quoted, term)
+	}
+	if len(quoted) == 0 {
+		return &piiScrubber{}
+	}
+	// 长的先匹配，避免姓名先于包含它的邮箱被替换
+	sort.Slice(quoted, func(i, j int) bool { return len(quoted[i]) > len(quoted[j]) })
+	for i, term := range quoted {
+		quoted[i] = regexp.QuoteMeta(term)
+	}
+	return &piiScrubber{pattern: regexp.MustCompile(`(?i)` + strings.Join(quoted, "|"))}
+}
+
+// Replace 返回替换后的文本
+func (p *piiScrubber) Replace(text string) string {
+	if p.pattern == nil || text == "" {
+		return text
+	}
+	return p.pattern.ReplaceAllString(text, ticketPurgePlaceholder)
+}
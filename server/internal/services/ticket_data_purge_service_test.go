@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestTicketDataPurge_LegalHoldAnonymizeAndPurge(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:ticket_data_purge_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Ticket{}, &models.TicketComment{}, &models.TicketHistory{},
+		&models.TicketAttachment{}, &models.InboundEmail{}, &models.EmailThreadMessage{}, &models.TicketCallLog{},
+		&models.CommentTranslation{}, &models.AdminAuditLog{}, &models.SystemConfig{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+	ctx := context.Background()
+	storage := NewLocalFileStorage(t.TempDir(), "/uploads")
+	svc := NewTicketDataPurgeService(db, storage, NewAdminAuditService(db))
+
+	admin := models.User{Username: "purge-admin", Email: "purge-admin@example.com", PasswordHash: "x", Role: models.RoleAdmin}
+	agent := models.User{Username: "purge-agent", Email: "purge-agent@example.com", PasswordHash: "x", Role: models.RoleAgent}
+	customer := models.User{Username: "purge-customer", Email: "Jane.Doe@Example.com", PasswordHash: "x", Role: models.RoleCustomer,
+		DisplayName: "Jane Doe", Phone: "+8613800000000"}
+	for _, u := range []*models.User{&admin, &agent, &customer} {
+		if err := db.Create(u).Error; err != nil {
+			t.Fatalf("failed to seed user: %v", err)
+		}
+	}
+
+	seed := func(number string) models.Ticket {
+		ticket := models.Ticket{TicketNumber: number, Title: "Refund for Jane Doe", Description: "Please call Jane Doe at +8613800000000 or jane.doe@example.com",
+			Status: models.TicketStatusOpen, Priority: models.TicketPriorityNormal, Type: models.TicketTypeRequest, Source: models.TicketSourceEmail,
+			CreatedByID: customer.ID, CustomerEmail: "jane.doe@example.com", CustomerName: "Jane Doe", CustomerPhone: "+8613800000000", CommentCount: 2}
+		if err := db.Create(&ticket).Error; err != nil {
+			t.Fatalf("failed to seed ticket: %v", err)
+		}
+		customerComment := models.TicketComment{TicketID: ticket.ID, UserID: customer.ID, Content: "My phone is +8613800000000", Type: models.CommentTypePublic}
+		agentComment := models.TicketComment{TicketID: ticket.ID, UserID: agent.ID, Content: "Hi Jane Doe, we will refund you", Type: models.CommentTypePublic}
+		db.Create(&customerComment)
+		db.Create(&agentComment)
+		db.Create(&models.CommentTranslation{CommentID: agentComment.ID, TargetLanguage: "zh", Content: "你好 Jane Doe"})
+		db.Create(&models.TicketHistory{TicketID: ticket.ID, Action: models.HistoryActionUpdate, Description: "customer_email changed",
+			FieldName: "customer_email", NewValue: "jane.doe@example.com"})
+
+		for i, uploader := range []uint{customer.ID, agent.ID} {
+			key := number + "/file" + string(rune('a'+i)) + ".txt"
+			if err := storage.Put(ctx, key, strings.NewReader("data"), "text/plain"); err != nil {
+				t.Fatalf("failed to store attachment: %v", err)
+			}
+			db.Create(&models.TicketAttachment{TicketID: ticket.ID, UploadedBy: uploader, FileName: key, OriginalName: key, FileSize: 4, StoragePath: key})
+		}
+		email := models.InboundEmail{FromAddress: "jane.doe@example.com", FromName: "Jane Doe", Subject: "Refund", Body: "Regards, Jane Doe", TicketID: &ticket.ID}
+		db.Create(&email)
+		db.Create(&models.EmailThreadMessage{MessageID: "<" + number + "@example.com>", ThreadID: number, TicketID: ticket.ID, InboundEmailID: &email.ID})
+		db.Create(&models.TicketCallLog{TicketID: ticket.ID, AgentID: agent.ID, Direction: models.CallDirectionInbound, PhoneNumber: "+8613800000000",
+			Notes: "Jane Doe asked about refund"})
+		return ticket
+	}
+
+	// 法律保留期间拒绝删除，设置保留必须填写原因
+	held := seed("PURGE-1")
+	if _, err := svc.SetLegalHold(ctx, held.ID, &models.TicketLegalHoldRequest{Hold: true}, admin.ID); !errors.Is(err, ErrTicketPurgeInvalid) {
+		t.Fatalf("expected hold without reason to be rejected, got %v", err)
+	}
+	if ticket, err := svc.SetLegalHold(ctx, held.ID, &models.TicketLegalHoldRequest{Hold: true, Reason: "Litigation 2026-17"}, admin.ID); err != nil ||
+		!ticket.LegalHold || ticket.LegalHoldByID == nil || *ticket.LegalHoldByID != admin.ID {
+		t.Fatalf("unexpected hold result %+v, %v", ticket, err)
+	}
+	if holds, _ := svc.ListLegalHolds(ctx); len(holds) != 1 || holds[0].ID != held.ID {
+		t.Fatalf("unexpected legal holds %+v", holds)
+	}
+	req := &models.TicketPurgeRequest{Mode: models.TicketPurgeModeAnonymize, Reason: "DSR-1"}
+	if _, err := svc.Purge(ctx, held.ID, req, admin.ID); !errors.Is(err, ErrTicketLegalHold) {
+		t.Fatalf("expected legal hold to block purge, got %v", err)
+	}
+
+	// 匿名化：清空联系信息、替换文本，只删除客户上传的附件
+	svc.SetLegalHold(ctx, held.ID, &models.TicketLegalHoldRequest{Hold: false}, admin.ID)
+	cert, err := svc.Purge(ctx, held.ID, req, admin.ID)
+	if err != nil {
+		t.Fatalf("anonymize failed: %v", err)
+	}
+	var ticket models.Ticket
+	db.First(&ticket, held.ID)
+	if ticket.CustomerEmail != "" || ticket.CustomerName != "" || ticket.CustomerPhone != "" || ticket.PIIPurgedAt == nil ||
+		strings.Contains(ticket.Description, "Jane") || strings.Contains(ticket.Description, "+8613800000000") || strings.Contains(ticket.Title, "Jane") {
+		t.Fatalf("ticket not anonymized: %+v", ticket)
+	}
+	var comments []models.TicketComment
+	db.Where("ticket_id = ?", held.ID).Order("id").Find(&comments)
+	if len(comments) != 2 || comments[0].Content != "My phone is [已删除]" || comments[1].Content != "Hi [已删除], we will refund you" {
+		t.Fatalf("unexpected anonymized comments %+v", comments)
+	}
+	var attachments []models.TicketAttachment
+	db.Where("ticket_id = ?", held.ID).Find(&attachments)
+	if len(attachments) != 1 || attachments[0].UploadedBy != agent.ID {
+		t.Fatalf("expected only agent attachment to remain, got %+v", attachments)
+	}
+	if rc, err := storage.Open(ctx, "PURGE-1/filea.txt"); err == nil {
+		rc.Close()
+		t.Fatalf("expected customer attachment file to be deleted")
+	}
+	var email models.InboundEmail
+	db.Where("ticket_id = ?", held.ID).First(&email)
+	if email.FromAddress == "jane.doe@example.com" || email.FromName != "" || email.Body != "Regards, [已删除]" {
+		t.Fatalf("inbound email not anonymized: %+v", email)
+	}
+	var call models.TicketCallLog
+	db.Where("ticket_id = ?", held.ID).First(&call)
+	if call.PhoneNumber != "" || strings.Contains(call.Notes, "Jane") {
+		t.Fatalf("call log not anonymized: %+v", call)
+	}
+	var leaked int64
+	db.Model(&models.TicketHistory{}).Where("ticket_id = ? AND new_value LIKE ?", held.ID, "%jane%").Count(&leaked)
+	if leaked != 0 {
+		t.Fatalf("expected history to be redacted")
+	}
+	if cert.Counts.CustomerFields != 3 || cert.Counts.CommentsRedacted != 2 || cert.Counts.AttachmentsDeleted != 1 ||
+		cert.Counts.EmailsRedacted != 1 || cert.Counts.CallLogs != 1 || cert.Counts.HistoryRedacted != 1 || cert.SubjectHash == "" {
+		t.Fatalf("unexpected certificate %+v", cert)
+	}
+
+	// 删除证书写入审计日志且签名可校验，篡改后校验失败
+	var audit models.AdminAuditLog
+	if err := db.Where("action = ?", models.AuditActionTicketPIIPurge).First(&audit).Error; err != nil {
+		t.Fatalf("expected certificate audit entry: %v", err)
+	}
+	var recorded models.TicketDeletionCertificate
+	if err := json.Unmarshal([]byte(audit.Notes), &recorded); err != nil || recorded.CertificateID != cert.CertificateID {
+		t.Fatalf("unexpected audit notes %q, %v", audit.Notes, err)
+	}
+	if err := svc.VerifyCertificate(ctx, &recorded); err != nil {
+		t.Fatalf("expected recorded certificate to verify, got %v", err)
+	}
+	recorded.Counts.CommentsDeleted = 5
+	if err := svc.VerifyCertificate(ctx, &recorded); !errors.Is(err, ErrDeletionCertificateInvalid) {
+		t.Fatalf("expected tampered certificate to fail, got %v", err)
+	}
+
+	// 清除：删除客户评论、全部附件、原始邮件和通话记录
+	purged := seed("PURGE-2")
+	cert, err = svc.Purge(ctx, purged.ID, &models.TicketPurgeRequest{Mode: models.TicketPurgeModePurge, Reason: "DSR-2"}, admin.ID)
+	if err != nil {
+		t.Fatalf("purge failed: %v", err)
+	}
+	ticket = models.Ticket{}
+	db.First(&ticket, purged.ID)
+	if ticket.Description != ticketPurgedDescription || ticket.CommentCount != 1 {
+		t.Fatalf("unexpected purged ticket %+v", ticket)
+	}
+	var count int64
+	db.Model(&models.TicketAttachment{}).Where("ticket_id = ?", purged.ID).Count(&count)
+	if count != 0 {
+		t.Fatalf("expected all attachments to be deleted, got %d", count)
+	}
+	db.Model(&models.InboundEmail{}).Where("ticket_id = ?", purged.ID).Count(&count)
+	if count != 0 {
+		t.Fatalf("expected inbound emails to be deleted, got %d", count)
+	}
+	var message models.EmailThreadMessage
+	db.Where("ticket_id = ?", purged.ID).First(&message)
+	if message.InboundEmailID != nil {
+		t.Fatalf("expected thread message to be detached from deleted email")
+	}
+	if cert.Counts.CommentsDeleted != 1 || cert.Counts.AttachmentsDeleted != 2 || cert.Counts.EmailsDeleted != 1 || cert.Counts.CallLogs != 1 {
+		t.Fatalf("unexpected purge certificate %+v", cert.Counts)
+	}
+}
@@ -0,0 +1,177 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+// KeyTicketFieldPermissionPolicy 工单字段编辑权限策略配置键
+const KeyTicketFieldPermissionPolicy = "ticket.field_permission_policy"
+
+// ErrTicketFieldForbidden 当前角色无权修改部分工单字段
+var ErrTicketFieldForbidden = errors.New("not allowed to edit ticket fields")
+
+// TicketFieldPermissionError 被拒绝修改的字段及可修改这些字段的角色
+type TicketFieldPermissionError struct {
+	Fields []models.TicketFieldAccess
+}
+
+func (e *TicketFieldPermissionError) Error() string {
+	fields := make([]string, 0, len(e.Fields))
+	for _, field := range e.Fields {
+		fields = append(fields, field.Field)
+	}
+	return fmt.Sprintf("%s: %s", ErrTicketFieldForbidden.Error(), strings.Join(fields, ", "))
+}
+
+// Is 支持 errors.Is(err, ErrTicketFieldForbidden)
+func (e *TicketFieldPermissionError) Is(target error) bool {
+	return target == ErrTicketFieldForbidden
+}
+
+// TicketFieldPermissionService 工单字段编辑权限服务：按角色限制优先级、截止时间、SLA、客户信息等字段的修改
+type TicketFieldPermissionService struct {
+	db *gorm.DB
+}
+
+// NewTicketFieldPermissionService 创建字段编辑权限服务
+func NewTicketFieldPermissionService(db *gorm.DB) *TicketFieldPermissionService {
+	return &TicketFieldPermissionService{db: db}
+}
+
+// GetPolicy 获取字段编辑权限策略
+func (s *TicketFieldPermissionService) GetPolicy(ctx context.Context) (*models.TicketFieldPermissionPolicy, error) {
+	var config models.SystemConfig
+	err := s.db.WithContext(ctx).
+		Where("key = ? AND is_active = ?", KeyTicketFieldPermissionPolicy, true).
+		First(&config).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.GetDefaultTicketFieldPermissionPolicy(), nil
+		}
+		return nil, fmt.Errorf("failed to get field permission policy: %w", err)
+	}
+
+	policy := &models.TicketFieldPermissionPolicy{}
+	if err := config.GetJSONValue(policy); err != nil {
+		log.Printf("Warning: failed to parse field permission policy, using defaults: %v", err)
+		return models.GetDefaultTicketFieldPermissionPolicy(), nil
+	}
+	if err := policy.Validate(); err != nil {
+		log.Printf("Warning: invalid field permission policy, using defaults: %v", err)
+		return models.GetDefaultTicketFieldPermissionPolicy(), nil
+	}
+	return policy, nil
+}
+
+// SetPolicy 保存字段编辑权限策略
+func (s *TicketFieldPermissionService) SetPolicy(ctx context.Context, policy *models.TicketFieldPermissionPolicy, userID uint) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	var existing models.SystemConfig
+	err := s.db.WithContext(ctx).Where("key = ?", KeyTicketFieldPermissionPolicy).First(&existing).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to check existing policy: %w", err)
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		config := models.SystemConfig{
+			Key:         KeyTicketFieldPermissionPolicy,
+			Category:    CategoryTicket,
+			Group:       "workflow",
+			Description: "工单字段编辑权限（按角色）",
+			IsActive:    true,
+			UpdatedBy:   &userID,
+		}
+		if err := config.SetValue(policy); err != nil {
+			return fmt.Errorf("failed to set policy value: %w", err)
+		}
+		if err := s.db.WithContext(ctx).Create(&config).Error; err != nil {
+			return fmt.Errorf("failed to create policy: %w", err)
+		}
+		return nil
+	}
+
+	if err := existing.SetValue(policy); err != nil {
+		return fmt.Errorf("failed to set policy value: %w", err)
+	}
+	existing.UpdatedBy = &userID
+	existing.Version++
+
+	if err := s.db.WithContext(ctx).Save(&existing).Error; err != nil {
+		return fmt.Errorf("failed to update policy: %w", err)
+	}
+	return nil
+}
+
+// CheckUpdate 检查角色能否应用更新请求。只检查相对工单当前值实际变更的字段，
+// 表单原样提交未修改的受限字段不会被拒绝；被拒绝时返回 *TicketFieldPermissionError
+func (s *TicketFieldPermissionService) CheckUpdate(ctx context.Context, ticketID uint, role string, req *models.TicketUpdateRequest) error {
+	policy, err := s.GetPolicy(ctx)
+	if err != nil {
+		return err
+	}
+	if !policy.Enabled {
+		return nil
+	}
+	ticket, err := s.loadTicket(ctx, ticketID)
+	if err != nil {
+		return err
+	}
+
+	var denied []models.TicketFieldAccess
+	for _, change := range previewTicketChanges(ticket, req) {
+		if !policy.CanEdit(role, change.Field) {
+			denied = append(denied, models.TicketFieldAccess{Field: change.Field, AllowedRoles: policy.AllowedRoles(change.Field)})
+		}
+	}
+	if len(denied) > 0 {
+		return &TicketFieldPermissionError{Fields: denied}
+	}
+	return nil
+}
+
+// EditableFields 返回角色对工单各字段的编辑权限，供前端禁用不可编辑的控件
+func (s *TicketFieldPermissionService) EditableFields(ctx context.Context, ticketID uint, role string) (*models.TicketEditableFields, error) {
+	if _, err := s.loadTicket(ctx, ticketID); err != nil {
+		return nil, err
+	}
+	policy, err := s.GetPolicy(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &models.TicketEditableFields{
+		TicketID:   ticketID,
+		Role:       role,
+		Restricted: policy.Enabled,
+		Fields:     make([]models.TicketFieldAccess, 0, len(models.TicketRestrictableFields)),
+	}
+	for _, field := range models.TicketRestrictableFields {
+		result.Fields = append(result.Fields, models.TicketFieldAccess{
+			Field:        field,
+			Editable:     policy.CanEdit(role, field),
+			AllowedRoles: policy.AllowedRoles(field),
+		})
+	}
+	return result, nil
+}
+
+func (s *TicketFieldPermissionService) loadTicket(ctx context.Context, ticketID uint) (*models.Ticket, error) {
+	var ticket models.Ticket
+	if err := s.db.WithContext(ctx).First(&ticket, ticketID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("ticket not found")
+		}
+		return nil, fmt.Errorf("failed to get ticket: %w", err)
+	}
+	return &ticket, nil
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestTicketFieldPermission_RestrictsChangedFieldsByRole(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:ticket_field_permission_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Category{}, &models.Ticket{}, &models.TicketHistory{}, &models.TicketChecklistItem{},
+		&models.SystemConfig{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+	ctx := context.Background()
+
+	agent := models.User{Username: "fp-agent", Email: "fp-agent@example.com", PasswordHash: "x", Role: models.RoleAgent}
+	db.Create(&agent)
+	ticket := models.Ticket{TicketNumber: "FP-1", Title: "field permissions", Status: models.TicketStatusOpen, Priority: models.TicketPriorityNormal,
+		Type: models.TicketTypeIncident, Source: models.TicketSourceWeb, CreatedByID: agent.ID, CustomerName: "Alice"}
+	db.Create(&ticket)
+
+	svc := NewTicketFieldPermissionService(db)
+	high, normal, title, customer := models.TicketPriorityHigh, models.TicketPriorityNormal, "renamed", "Bob"
+
+	// 默认关闭，不限制任何字段
+	if err := svc.CheckUpdate(ctx, ticket.ID, string(models.RoleAgent), &models.TicketUpdateRequest{Priority: &high}); err != nil {
+		t.Fatalf("expected disabled policy to allow edits, got %v", err)
+	}
+
+	if err := svc.SetPolicy(ctx, &models.TicketFieldPermissionPolicy{Enabled: true, Fields: map[string][]string{"created_by_id": {"admin"}}}, 1); err == nil {
+		t.Fatal("expected unknown field to be rejected")
+	}
+	if err := svc.SetPolicy(ctx, &models.TicketFieldPermissionPolicy{Enabled: true, Fields: map[string][]string{"priority": {"owner"}}}, 1); err == nil {
+		t.Fatal("expected unknown role to be rejected")
+	}
+	policy := models.GetDefaultTicketFieldPermissionPolicy()
+	policy.Enabled = true
+	if err := svc.SetPolicy(ctx, policy, 1); err != nil {
+		t.Fatalf("set policy failed: %v", err)
+	}
+
+	// 客服修改受限字段被拒绝，返回每个字段及可编辑的角色
+	err = svc.CheckUpdate(ctx, ticket.ID, string(models.RoleAgent), &models.TicketUpdateRequest{Priority: &high, Title: &title, CustomerName: &customer})
+	var permErr *TicketFieldPermissionError
+	if !errors.Is(err, ErrTicketFieldForbidden) || !errors.As(err, &permErr) || len(permErr.Fields) != 2 ||
+		permErr.Fields[0].Field != "priority" || permErr.Fields[1].Field != "customer_name" || len(permErr.Fields[0].AllowedRoles) != 2 {
+		t.Fatalf("unexpected permission error %+v", err)
+	}
+
+	// 原样提交未修改的受限字段不受影响；主管与管理员可以修改
+	if err := svc.CheckUpdate(ctx, ticket.ID, string(models.RoleAgent), &models.TicketUpdateRequest{Priority: &normal, Title: &title}); err != nil {
+		t.Fatalf("expected unchanged restricted field to pass, got %v", err)
+	}
+	for _, role := range []models.UserRole{models.RoleSupervisor, models.RoleAdmin} {
+		if err := svc.CheckUpdate(ctx, ticket.ID, string(role), &models.TicketUpdateRequest{Priority: &high, CustomerName: &customer}); err != nil {
+			t.Fatalf("expected %s to edit restricted fields, got %v", role, err)
+		}
+	}
+
+	fields, err := svc.EditableFields(ctx, ticket.ID, string(models.RoleAgent))
+	if err != nil || !fields.Restricted {
+		t.Fatalf("unexpected editable fields %+v, %v", fields, err)
+	}
+	editable := make(map[string]bool)
+	for _, field := range fields.Fields {
+		editable[field.Field] = field.Editable
+	}
+	if editable["priority"] || editable["sla_due_date"] || !editable["title"] || !editable["status"] {
+		t.Fatalf("unexpected editable map %v", editable)
+	}
+	if _, err := svc.EditableFields(ctx, 9999, string(models.RoleAgent)); err == nil {
+		t.Fatal("expected missing ticket to fail")
+	}
+
+	// 客户信息与 SLA 截止时间的修改会写入工单并记录历史
+	slaDue := time.Now().Add(48 * time.Hour).Truncate(time.Second)
+	updated, err := NewTicketService(db).(*TicketService).UpdateTicket(ctx, ticket.ID, &models.TicketUpdateRequest{CustomerName: &customer, SLADueDate: &slaDue}, agent.ID)
+	if err != nil || updated.CustomerName != "Bob" || updated.SLADueDate == nil || !updated.SLADueDate.Equal(slaDue) {
+		t.Fatalf("unexpected updated ticket %+v, %v", updated, err)
+	}
+	var histories int64
+	db.Model(&models.TicketHistory{}).Where("ticket_id = ? AND field_name IN ?", ticket.ID, []string{"customer_name", "sla_due_date"}).Count(&histories)
+	if histories != 2 {
+		t.Fatalf("expected 2 history records, got %d", histories)
+	}
+}
@@ -0,0 +1,635 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// ticketHandoffMaxTickets 单次交接最多转交的工单数，超出部分留待下次交接
+	ticketHandoffMaxTickets = 500
+	// ticketHandoffPreviewLimit 预览最多列出的工单数
+	ticketHandoffPreviewLimit = 50
+	// ticketHandoffExcerptRunes 摘要中引用客户回复的最大长度
+	ticketHandoffExcerptRunes = 120
+)
+
+var (
+	// ErrTicketHandoffScheduleNotFound 交接计划不存在
+	ErrTicketHandoffScheduleNotFound = errors.New("handoff schedule not found")
+	// ErrTicketHandoffNotFound 交接记录不存在
+	ErrTicketHandoffNotFound = errors.New("handoff not found")
+	// ErrInvalidTicketHandoff 交接参数无效
+	ErrInvalidTicketHandoff = errors.New("invalid handoff")
+)
+
+// CanManageHandoffs 判断角色是否可以执行交接和维护交接计划
+func CanManageHandoffs(role string) bool {
+	return role == string(models.RoleAdmin) || role == string(models.RoleSupervisor) || role == "superuser"
+}
+
+// ticketHandoffPlan 一次交接的参数，来自手动请求或交接计划
+type ticketHandoffPlan struct {
+	scheduleID    *uint
+	fromTeamID    uint
+	toTeamID      uint
+	filter        *models.TicketQueryNode
+	noteTemplate  string
+	openQuestions string
+	nextSteps     string
+	trigger       models.TicketHandoffTrigger
+	executedByID  *uint
+}
+
+// TicketHandoffService 跨时区交接服务：换班时将一个团队的未完成工单按条件转交给另一个团队，
+// 为每个工单写入交接摘要内部评论并通知接收团队；交接计划由定时任务按 cron 表达式执行
+type TicketHandoffService struct {
+	db                  *gorm.DB
+	notificationService NotificationServiceInterface
+	summaryService      *TicketSummaryService
+}
+
+// NewTicketHandoffService 创建交接服务
+func NewTicketHandoffService(db *gorm.DB) *TicketHandoffService {
+	return &TicketHandoffService{
+		db:                  db,
+		notificationService: NewNotificationService(db),
+		summaryService:      NewTicketSummaryService(db),
+	}
+}
+
+// ListSchedules 获取全部交接计划
+func (s *TicketHandoffService) ListSchedules(ctx context.Context) ([]models.TicketHandoffSchedule, error) {
+	schedules := []models.TicketHandoffSchedule{}
+	if err := s.db.WithContext(ctx).Preload("FromTeam").Preload("ToTeam").
+		Order("id ASC").Find(&schedules).Error; err != nil {
+		return nil, fmt.Errorf("failed to list handoff schedules: %w", err)
+	}
+	return schedules, nil
+}
+
+// GetSchedule 获取交接计划
+func (s *TicketHandoffService) GetSchedule(ctx context.Context, id uint) (*models.TicketHandoffSchedule, error) {
+	var schedule models.TicketHandoffSchedule
+	if err := s.db.WithContext(ctx).Preload("FromTeam").Preload("ToTeam").First(&schedule, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTicketHandoffScheduleNotFound
+		}
+		return nil, fmt.Errorf("failed to get handoff schedule: %w", err)
+	}
+	return &schedule, nil
+}
+
+// CreateSchedule 创建交接计划，下次执行时间按 cron 表达式计算
+func (s *TicketHandoffService) CreateSchedule(ctx context.Context, req *models.TicketHandoffScheduleRequest, userID uint) (*models.TicketHandoffSchedule, error) {
+	schedule := &models.TicketHandoffSchedule{IsActive: true, CreatedByID: userID}
+	if err := s.applyScheduleRequest(ctx, schedule, req, time.Now()); err != nil {
+		return nil, err
+	}
+	if err := s.db.WithContext(ctx).Create(schedule).Error; err != nil {
+		return nil, fmt.Errorf("failed to create handoff schedule: %w", err)
+	}
+	return s.GetSchedule(ctx, schedule.ID)
+}
+
+// UpdateSchedule 更新交接计划
+func (s *TicketHandoffService) UpdateSchedule(ctx context.Context, id uint, req *models.TicketHandoffScheduleRequest) (*models.TicketHandoffSchedule, error) {
+	schedule, err := s.GetSchedule(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyScheduleRequest(ctx, schedule, req, time.Now()); err != nil {
+		return nil, err
+	}
+	schedule.FromTeam, schedule.ToTeam = nil, nil
+	if err := s.db.WithContext(ctx).Save(schedule).Error; err != nil {
+		return nil, fmt.Errorf("failed to update handoff schedule: %w", err)
+	}
+	return s.GetSchedule(ctx, id)
+}
+
+// DeleteSchedule 删除交接计划，已执行的交接记录保留
+func (s *TicketHandoffService) DeleteSchedule(ctx context.Context, id uint) error {
+	result := s.db.WithContext(ctx).Delete(&models.TicketHandoffSchedule{}, id)
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete handoff schedule: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrTicketHandoffScheduleNotFound
+	}
+	return nil
+}
+
+func (s *TicketHandoffService) applyScheduleRequest(ctx context.Context, schedule *models.TicketHandoffSchedule, req *models.TicketHandoffScheduleRequest, now time.Time) error {
+	if strings.TrimSpace(req.Name) == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidTicketHandoff)
+	}
+	if err := s.validatePlan(ctx, req.FromTeamID, req.ToTeamID, req.Filter); err != nil {
+		return err
+	}
+	cronSchedule, err := parseSchedulerCron(req.CronExpr)
+	if err != nil {
+		return err
+	}
+
+	schedule.Name = strings.TrimSpace(req.Name)
+	schedule.FromTeamID = req.FromTeamID
+	schedule.ToTeamID = req.ToTeamID
+	schedule.FilterNode = req.Filter
+	schedule.CronExpr = strings.TrimSpace(req.CronExpr)
+	schedule.NoteTemplate = req.NoteTemplate
+	schedule.OpenQuestions = req.OpenQuestions
+	schedule.NextSteps = req.NextSteps
+	if req.IsActive != nil {
+		schedule.IsActive = *req.IsActive
+	}
+	next := cronSchedule.Next(now)
+	schedule.NextRunAt = &next
+	return nil
+}
+
+// validatePlan 校验交出与接收团队及筛选条件
+func (s *TicketHandoffService) validatePlan(ctx context.Context, fromTeamID, toTeamID uint, filter *models.TicketQueryNode) error {
+	if fromTeamID == 0 || toTeamID == 0 {
+		return fmt.Errorf("%w: from_team_id and to_team_id are required", ErrInvalidTicketHandoff)
+	}
+	if fromTeamID == toTeamID {
+		return fmt.Errorf("%w: from_team_id and to_team_id must differ", ErrInvalidTicketHandoff)
+	}
+	if err := s.ensureTeam(ctx, fromTeamID, "from_team_id", false); err != nil {
+		return err
+	}
+	if err := s.ensureTeam(ctx, toTeamID, "to_team_id", true); err != nil {
+		return err
+	}
+	if filter != nil {
+		if _, _, err := CompileTicketQuery(s.db, filter, time.Now()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *TicketHandoffService) ensureTeam(ctx context.Context, teamID uint, field string, requireActive bool) error {
+	var team models.Team
+	if err := s.db.WithContext(ctx).Select("id", "is_active").First(&team, teamID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("%w: %s team not found", ErrInvalidTicketHandoff, field)
+		}
+		return fmt.Errorf("failed to get team: %w", err)
+	}
+	if requireActive && !team.IsActive {
+		return fmt.Errorf("%w: %s team is inactive", ErrInvalidTicketHandoff, field)
+	}
+	return nil
+}
+
+// ticketsQuery 交出团队中符合条件的未完成工单
+func (s *TicketHandoffService) ticketsQuery(ctx context.Context, fromTeamID uint, filter *models.TicketQueryNode, now time.Time) (*gorm.DB, error) {
+	query := s.db.WithContext(ctx).Model(&models.Ticket{}).
+		Where("deleted_at IS NULL AND assigned_team_id = ? AND status NOT IN ?", fromTeamID, closedTicketStatuses)
+	if filter != nil {
+		condition, args, err := CompileTicketQuery(s.db, filter, now)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where("("+condition+")", args...)
+	}
+	return query, nil
+}
+
+// Preview 预览将被转交的工单
+func (s *TicketHandoffService) Preview(ctx context.Context, req *models.TicketHandoffRequest) (*models.TicketHandoffPreview, error) {
+	if err := s.ensureTeam(ctx, req.FromTeamID, "from_team_id", false); err != nil {
+		return nil, err
+	}
+	query, err := s.ticketsQuery(ctx, req.FromTeamID, req.Filter, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &models.TicketHandoffPreview{Tickets: []models.TicketHandoffPreviewItem{}}
+	if err := query.Count(&preview.Total).Error; err != nil {
+		return nil, fmt.Errorf("failed to count handoff tickets: %w", err)
+	}
+	if err := query.Select("id", "ticket_number", "title", "status", "priority", "assigned_to_id").
+		Order("id ASC").Limit(ticketHandoffPreviewLimit).Scan(&preview.Tickets).Error; err != nil {
+		return nil, fmt.Errorf("failed to get handoff tickets: %w", err)
+	}
+	return preview, nil
+}
+
+// Execute 立即执行一次交接
+func (s *TicketHandoffService) Execute(ctx context.Context, req *models.TicketHandoffRequest, userID uint) (*models.TicketHandoff, error) {
+	if err := s.validatePlan(ctx, req.FromTeamID, req.ToTeamID, req.Filter); err != nil {
+		return nil, err
+	}
+	return s.run(ctx, &ticketHandoffPlan{
+		fromTeamID:    req.FromTeamID,
+		toTeamID:      req.ToTeamID,
+		filter:        req.Filter,
+		noteTemplate:  req.NoteTemplate,
+		openQuestions: req.OpenQuestions,
+		nextSteps:     req.NextSteps,
+		trigger:       models.TicketHandoffManual,
+		executedByID:  &userID,
+	}, time.Now())
+}
+
+// RunSchedule 立即执行交接计划，不影响计划的下次执行时间
+func (s *TicketHandoffService) RunSchedule(ctx context.Context, id uint, userID uint) (*models.TicketHandoff, error) {
+	schedule, err := s.GetSchedule(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	plan := schedulePlan(schedule)
+	plan.trigger = models.TicketHandoffManual
+	plan.executedByID = &userID
+	return s.run(ctx, plan, time.Now())
+}
+
+// ProcessDue 执行到达换班时间的交接计划并计算下次执行时间，由定时任务调用
+func (s *TicketHandoffService) ProcessDue(ctx context.Context, now time.Time) (int, error) {
+	var due []models.TicketHandoffSchedule
+	if err := s.db.WithContext(ctx).
+		Where("is_active = ? AND next_run_at IS NOT NULL AND next_run_at <= ?", true, now).
+		Order("next_run_at ASC").Find(&due).Error; err != nil {
+		return 0, fmt.Errorf("failed to find due handoff schedules: %w", err)
+	}
+
+	executed := 0
+	for i := range due {
+		schedule := &due[i]
+		cronSchedule, err := parseSchedulerCron(schedule.CronExpr)
+		if err != nil {
+			log.Printf("Skipping handoff schedule %d with invalid cron expression: %v", schedule.ID, err)
+			continue
+		}
+		// 先推进下次执行时间，避免多个实例重复交接
+		next := cronSchedule.Next(now)
+		claim := s.db.WithContext(ctx).Model(&models.TicketHandoffSchedule{}).
+			Where("id = ? AND next_run_at = ?", schedule.ID, schedule.NextRunAt).
+			Updates(map[string]interface{}{"next_run_at": next, "last_run_at": now})
+		if claim.Error != nil {
+			return executed, fmt.Errorf("failed to claim handoff schedule: %w", claim.Error)
+		}
+		if claim.RowsAffected == 0 {
+			continue
+		}
+
+		plan := schedulePlan(schedule)
+		plan.trigger = models.TicketHandoffScheduled
+		if _, err := s.run(ctx, plan, now); err != nil {
+			log.Printf("Failed to run handoff schedule %d: %v", schedule.ID, err)
+			continue
+		}
+		executed++
+	}
+	return executed, nil
+}
+
+func schedulePlan(schedule *models.TicketHandoffSchedule) *ticketHandoffPlan {
+	return &ticketHandoffPlan{
+		scheduleID:    &schedule.ID,
+		fromTeamID:    schedule.FromTeamID,
+		toTeamID:      schedule.ToTeamID,
+		filter:        schedule.FilterNode,
+		noteTemplate:  schedule.NoteTemplate,
+		openQuestions: schedule.OpenQuestions,
+		nextSteps:     schedule.NextSteps,
+	}
+}
+
+// run 转交工单到接收团队队列，为每个工单写入交接摘要及历史，完成后通知接收团队
+func (s *TicketHandoffService) run(ctx context.Context, plan *ticketHandoffPlan, now time.Time) (*models.TicketHandoff, error) {
+	query, err := s.ticketsQuery(ctx, plan.fromTeamID, plan.filter, now)
+	if err != nil {
+		return nil, err
+	}
+	var tickets []models.Ticket
+	if err := query.Order("id ASC").Limit(ticketHandoffMaxTickets).Find(&tickets).Error; err != nil {
+		return nil, fmt.Errorf("failed to get handoff tickets: %w", err)
+	}
+
+	summary, err := s.loadSummaryContext(ctx, plan, tickets)
+	if err != nil {
+		return nil, err
+	}
+
+	handoff := &models.TicketHandoff{
+		ScheduleID:   plan.scheduleID,
+		FromTeamID:   plan.fromTeamID,
+		ToTeamID:     plan.toTeamID,
+		Trigger:      plan.trigger,
+		ExecutedByID: plan.executedByID,
+	}
+	authorID := uint(1) // 定时交接记在系统用户名下
+	if plan.executedByID != nil {
+		authorID = *plan.executedByID
+	}
+	automated := plan.trigger == models.TicketHandoffScheduled
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(handoff).Error; err != nil {
+			return fmt.Errorf("failed to create handoff: %w", err)
+		}
+		for i := range tickets {
+			ticket := &tickets[i]
+			// 期间已被改派的工单跳过
+			result := tx.Model(&models.Ticket{}).
+				Where("id = ? AND assigned_team_id = ?", ticket.ID, plan.fromTeamID).
+				Updates(map[string]interface{}{"assigned_team_id": plan.toTeamID, "assigned_to_id": nil, "updated_at": now})
+			if result.Error != nil {
+				return fmt.Errorf("failed to hand off ticket %d: %w", ticket.ID, result.Error)
+			}
+			if result.RowsAffected == 0 {
+				continue
+			}
+
+			comment := &models.TicketComment{
+				TicketID:   ticket.ID,
+				UserID:     authorID,
+				Content:    summary.render(ticket),
+				Type:       models.CommentTypeInternal,
+				Visibility: models.CommentVisibilityInternal,
+			}
+			if err := tx.Create(comment).Error; err != nil {
+				return fmt.Errorf("failed to create handoff note: %w", err)
+			}
+			history := &models.TicketHistory{
+				TicketID:    ticket.ID,
+				UserID:      plan.executedByID,
+				Action:      models.HistoryActionTransfer,
+				Description: fmt.Sprintf("换班交接：从团队 %s 转交给团队 %s", summary.fromTeam, summary.toTeam),
+				FieldName:   "assigned_team_id",
+				OldValue:    fmt.Sprintf("%d", plan.fromTeamID),
+				NewValue:    fmt.Sprintf("%d", plan.toTeamID),
+				CommentID:   &comment.ID,
+				IsVisible:   false,
+				IsSystem:    automated,
+				IsAutomated: automated,
+				IsImportant: true,
+			}
+			if err := tx.Create(history).Error; err != nil {
+				return fmt.Errorf("failed to record handoff history: %w", err)
+			}
+			item := &models.TicketHandoffItem{
+				HandoffID:          handoff.ID,
+				TicketID:           ticket.ID,
+				TicketNumber:       ticket.TicketNumber,
+				PreviousAssigneeID: ticket.AssignedToID,
+				CommentID:          comment.ID,
+			}
+			if err := tx.Create(item).Error; err != nil {
+				return fmt.Errorf("failed to record handoff item: %w", err)
+			}
+			handoff.Items = append(handoff.Items, *item)
+			handoff.TicketCount++
+		}
+		return tx.Model(handoff).Update("ticket_count", handoff.TicketCount).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if handoff.TicketCount > 0 {
+		s.notifyReceivingTeam(ctx, handoff, summary)
+		ticketIDs := make([]uint, 0, len(handoff.Items))
+		for _, item := range handoff.Items {
+			ticketIDs = append(ticketIDs, item.TicketID)
+		}
+		s.summaryService.SummarizeAfterHandoff(ticketIDs)
+	}
+	return handoff, nil
+}
+
+// handoffSummaryContext 生成交接摘要所需的团队名称、原处理人、未完成检查项及客户最新回复
+type handoffSummaryContext struct {
+	plan          *ticketHandoffPlan
+	fromTeam      string
+	toTeam        string
+	assignees     map[uint]string
+	checklists    map[uint][]string
+	customerAsks  map[uint]string
+	toTeamLeadID  *uint
+	toTeamMembers []uint
+}
+
+func (s *TicketHandoffService) loadSummaryContext(ctx context.Context, plan *ticketHandoffPlan, tickets []models.Ticket) (*handoffSummaryContext, error) {
+	summary := &handoffSummaryContext{
+		plan:         plan,
+		assignees:    map[uint]string{},
+		checklists:   map[uint][]string{},
+		customerAsks: map[uint]string{},
+	}
+
+	var teams []models.Team
+	if err := s.db.WithContext(ctx).Select("id", "name", "lead_id").
+		Where("id IN ?", []uint{plan.fromTeamID, plan.toTeamID}).Find(&teams).Error; err != nil {
+		return nil, fmt.Errorf("failed to get teams: %w", err)
+	}
+	for _, team := range teams {
+		if team.ID == plan.fromTeamID {
+			summary.fromTeam = team.Name
+		} else {
+			summary.toTeam = team.Name
+			summary.toTeamLeadID = team.LeadID
+		}
+	}
+	if err := s.db.WithContext(ctx).Table("team_members").
+		Where("team_id = ?", plan.toTeamID).Pluck("user_id", &summary.toTeamMembers).Error; err != nil {
+		return nil, fmt.Errorf("failed to get team members: %w", err)
+	}
+	if len(tickets) == 0 {
+		return summary, nil
+	}
+
+	ticketIDs := make([]uint, 0, len(tickets))
+	var assigneeIDs []uint
+	creators := make(map[uint]uint, len(tickets))
+	for _, ticket := range tickets {
+		ticketIDs = append(ticketIDs, ticket.ID)
+		creators[ticket.ID] = ticket.CreatedByID
+		if ticket.AssignedToID != nil {
+			assigneeIDs = append(assigneeIDs, *ticket.AssignedToID)
+		}
+	}
+
+	if len(assigneeIDs) > 0 {
+		var users []models.User
+		if err := s.db.WithContext(ctx).Select("id", "username", "first_name", "last_name", "display_name").
+			Where("id IN ?", assigneeIDs).Find(&users).Error; err != nil {
+			return nil, fmt.Errorf("failed to get assignees: %w", err)
+		}
+		for _, user := range users {
+			summary.assignees[user.ID] = user.GetFullName()
+		}
+	}
+
+	var items []models.TicketChecklistItem
+	if err := s.db.WithContext(ctx).Select("ticket_id", "text").
+		Where("ticket_id IN ? AND is_done = ?", ticketIDs, false).
+		Order("ticket_id ASC, position ASC, id ASC").Find(&items).Error; err != nil {
+		return nil, fmt.Errorf("failed to get checklist items: %w", err)
+	}
+	for _, item := range items {
+		summary.checklists[item.TicketID] = append(summary.checklists[item.TicketID], item.Text)
+	}
+
+	// 最新的公开评论来自客户（工单创建人）时视为待答复的问题
+	var comments []models.TicketComment
+	if err := s.db.WithContext(ctx).Select("id", "ticket_id", "user_id", "content").
+		Where("ticket_id IN ? AND type = ? AND deleted_at IS NULL", ticketIDs, models.CommentTypePublic).
+		Order("ticket_id ASC, id DESC").Find(&comments).Error; err != nil {
+		return nil, fmt.Errorf("failed to get comments: %w", err)
+	}
+	seen := make(map[uint]bool, len(ticketIDs))
+	for _, comment := range comments {
+		if seen[comment.TicketID] {
+			continue
+		}
+		seen[comment.TicketID] = true
+		if comment.UserID == creators[comment.TicketID] {
+			summary.customerAsks[comment.TicketID] = handoffExcerpt(comment.Content)
+		}
+	}
+	return summary, nil
+}
+
+// render 按模板生成工单的交接摘要
+func (c *handoffSummaryContext) render(ticket *models.Ticket) string {
+	template := c.plan.noteTemplate
+	if strings.TrimSpace(template) == "" {
+		template = models.DefaultTicketHandoffTemplate
+	}
+
+	previous := "未分配"
+	if ticket.AssignedToID != nil {
+		previous = fmt.Sprintf("用户 ID: %d", *ticket.AssignedToID)
+		if name := c.assignees[*ticket.AssignedToID]; name != "" {
+			previous = name
+		}
+	}
+
+	var questions []string
+	if text := strings.TrimSpace(c.plan.openQuestions); text != "" {
+		questions = append(questions, text)
+	}
+	if ask := c.customerAsks[ticket.ID]; ask != "" {
+		questions = append(questions, "- 客户最新回复待答复："+ask)
+	}
+	var steps []string
+	if text := strings.TrimSpace(c.plan.nextSteps); text != "" {
+		steps = append(steps, text)
+	}
+	for _, item := range c.checklists[ticket.ID] {
+		steps = append(steps, "- [ ] "+item)
+	}
+
+	return strings.NewReplacer(
+		"{{ticket_number}}", ticket.TicketNumber,
+		"{{title}}", ticket.Title,
+		"{{status}}", string(ticket.Status),
+		"{{priority}}", string(ticket.Priority),
+		"{{from_team}}", c.fromTeam,
+		"{{to_team}}", c.toTeam,
+		"{{previous_assignee}}", previous,
+		"{{last_activity}}", ticket.UpdatedAt.Format("2006-01-02 15:04 MST"),
+		"{{open_questions}}", handoffList(questions),
+		"{{next_steps}}", handoffList(steps),
+	).Replace(template)
+}
+
+func handoffList(lines []string) string {
+	if len(lines) == 0 {
+		return "（无）"
+	}
+	return strings.Join(lines, "\n")
+}
+
+func handoffExcerpt(content string) string {
+	content = strings.Join(strings.Fields(content), " ")
+	if utf8.RuneCountInString(content) <= ticketHandoffExcerptRunes {
+		return content
+	}
+	return string([]rune(content)[:ticketHandoffExcerptRunes]) + "…"
+}
+
+// notifyReceivingTeam 通知接收团队成员及负责人，每人一条汇总通知
+func (s *TicketHandoffService) notifyReceivingTeam(ctx context.Context, handoff *models.TicketHandoff, summary *handoffSummaryContext) {
+	recipients := append([]uint{}, summary.toTeamMembers...)
+	if summary.toTeamLeadID != nil {
+		recipients = append(recipients, *summary.toTeamLeadID)
+	}
+	seen := make(map[uint]bool, len(recipients))
+	for _, recipientID := range recipients {
+		if seen[recipientID] {
+			continue
+		}
+		seen[recipientID] = true
+		_, err := s.notificationService.CreateNotification(ctx, &models.NotificationCreateRequest{
+			Type:        models.NotificationTypeTicketAssigned,
+			Title:       "换班交接",
+			Content:     fmt.Sprintf("团队 %s 已将 %d 个未完成工单交接给团队 %s，请查看各工单的交接摘要", summary.fromTeam, handoff.TicketCount, summary.toTeam),
+			Priority:    models.NotificationPriorityNormal,
+			Channel:     models.NotificationChannelInApp,
+			RecipientID: recipientID,
+			RelatedType: "ticket_handoff",
+			RelatedID:   &handoff.ID,
+			Metadata: map[string]interface{}{
+				"handoff_id":   handoff.ID,
+				"from_team_id": handoff.FromTeamID,
+				"to_team_id":   handoff.ToTeamID,
+				"ticket_count": handoff.TicketCount,
+			},
+		})
+		if err != nil {
+			log.Printf("Failed to send handoff notification to user %d: %v", recipientID, err)
+		}
+	}
+}
+
+// ListHandoffs 分页获取交接记录，teamID 不为 0 时只返回交出或接收团队为该团队的记录
+func (s *TicketHandoffService) ListHandoffs(ctx context.Context, teamID uint, page, pageSize int) ([]models.TicketHandoff, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+	query := s.db.WithContext(ctx).Model(&models.TicketHandoff{})
+	if teamID != 0 {
+		query = query.Where("from_team_id = ? OR to_team_id = ?", teamID, teamID)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count handoffs: %w", err)
+	}
+	handoffs := []models.TicketHandoff{}
+	if err := query.Order("id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&handoffs).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list handoffs: %w", err)
+	}
+	return handoffs, total, nil
+}
+
+// GetHandoff 获取交接记录及转交的工单
+func (s *TicketHandoffService) GetHandoff(ctx context.Context, id uint) (*models.TicketHandoff, error) {
+	var handoff models.TicketHandoff
+	if err := s.db.WithContext(ctx).Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return db.Order("id ASC")
+	}).First(&handoff, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTicketHandoffNotFound
+		}
+		return nil, fmt.Errorf("failed to get handoff: %w", err)
+	}
+	return &handoff, nil
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestTicketHandoff_ExecutesAndSchedules(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:ticket_handoff_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Team{}, &models.Ticket{}, &models.TicketComment{}, &models.TicketHistory{},
+		&models.TicketChecklistItem{}, &models.Notification{}, &models.NotificationDelivery{}, &models.SystemConfig{},
+		&models.TicketHandoffSchedule{}, &models.TicketHandoff{}, &models.TicketHandoffItem{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+	ctx := context.Background()
+	svc := NewTicketHandoffService(db)
+
+	customer := models.User{Username: "handoff-customer", Email: "handoff-customer@example.com", PasswordHash: "x", Role: models.RoleCustomer}
+	apacAgent := models.User{Username: "handoff-apac", Email: "handoff-apac@example.com", PasswordHash: "x", Role: models.RoleAgent, FirstName: "Mei", LastName: "Lin"}
+	emeaAgent := models.User{Username: "handoff-emea", Email: "handoff-emea@example.com", PasswordHash: "x", Role: models.RoleAgent}
+	emeaLead := models.User{Username: "handoff-lead", Email: "handoff-lead@example.com", PasswordHash: "x", Role: models.RoleSupervisor}
+	for _, u := range []*models.User{&customer, &apacAgent, &emeaAgent, &emeaLead} {
+		if err := db.Create(u).Error; err != nil {
+			t.Fatalf("failed to seed user: %v", err)
+		}
+	}
+	teams := NewTeamService(db)
+	apac, err := teams.CreateTeam(ctx, &models.TeamCreateRequest{Name: "APAC", Slug: "handoff-apac", MemberIDs: []uint{apacAgent.ID}})
+	if err != nil {
+		t.Fatalf("failed to create team: %v", err)
+	}
+	emea, err := teams.CreateTeam(ctx, &models.TeamCreateRequest{Name: "EMEA", Slug: "handoff-emea", MemberIDs: []uint{emeaAgent.ID}, LeadID: &emeaLead.ID})
+	if err != nil {
+		t.Fatalf("failed to create team: %v", err)
+	}
+
+	seed := func(number string, status models.TicketStatus, priority models.TicketPriority) models.Ticket {
+		ticket := models.Ticket{TicketNumber: number, Title: "ticket " + number, Status: status, Priority: priority, Type: models.TicketTypeIncident,
+			Source: models.TicketSourceWeb, CreatedByID: customer.ID, AssignedToID: &apacAgent.ID, AssignedTeamID: &apac.ID}
+		if err := db.Create(&ticket).Error; err != nil {
+			t.Fatalf("failed to seed ticket: %v", err)
+		}
+		return ticket
+	}
+	urgent := seed("HO-001", models.TicketStatusInProgress, models.TicketPriorityUrgent)
+	low := seed("HO-002", models.TicketStatusOpen, models.TicketPriorityLow)
+	seed("HO-003", models.TicketStatusResolved, models.TicketPriorityUrgent)
+	db.Create(&models.TicketComment{TicketID: urgent.ID, UserID: customer.ID, Content: "Still seeing 502 errors after the restart", Type: models.CommentTypePublic})
+	db.Create(&models.TicketChecklistItem{TicketID: urgent.ID, Text: "Collect gateway logs", CreatedByID: apacAgent.ID})
+	db.Create(&models.TicketChecklistItem{TicketID: urgent.ID, Text: "Restart gateway", IsDone: true, CreatedByID: apacAgent.ID})
+
+	if _, err := svc.Execute(ctx, &models.TicketHandoffRequest{FromTeamID: apac.ID, ToTeamID: apac.ID}, emeaLead.ID); !errors.Is(err, ErrInvalidTicketHandoff) {
+		t.Fatalf("expected same-team handoff to be rejected, got %v", err)
+	}
+	badFilter := &models.TicketQueryNode{Field: "nonexistent", Operator: "eq", Value: "x"}
+	if _, err := svc.Execute(ctx, &models.TicketHandoffRequest{FromTeamID: apac.ID, ToTeamID: emea.ID, Filter: badFilter}, emeaLead.ID); !errors.Is(err, ErrInvalidTicketQuery) {
+		t.Fatalf("expected invalid filter to be rejected, got %v", err)
+	}
+
+	// 只转交符合筛选条件的未完成工单
+	filter := &models.TicketQueryNode{Field: "priority", Operator: "in", Value: []interface{}{"urgent", "high"}}
+	preview, err := svc.Preview(ctx, &models.TicketHandoffRequest{FromTeamID: apac.ID, Filter: filter})
+	if err != nil || preview.Total != 1 || preview.Tickets[0].ID != urgent.ID {
+		t.Fatalf("unexpected preview %+v, %v", preview, err)
+	}
+	handoff, err := svc.Execute(ctx, &models.TicketHandoffRequest{FromTeamID: apac.ID, ToTeamID: emea.ID, Filter: filter,
+		OpenQuestions: "- Is the customer on the legacy plan?"}, emeaLead.ID)
+	if err != nil {
+		t.Fatalf("execute failed: %v", err)
+	}
+	if handoff.TicketCount != 1 || handoff.Trigger != models.TicketHandoffManual || len(handoff.Items) != 1 || *handoff.Items[0].PreviousAssigneeID != apacAgent.ID {
+		t.Fatalf("unexpected handoff %+v", handoff)
+	}
+
+	var moved models.Ticket
+	db.First(&moved, urgent.ID)
+	if moved.AssignedTeamID == nil || *moved.AssignedTeamID != emea.ID || moved.AssignedToID != nil {
+		t.Fatalf("expected ticket in receiving team queue, got team=%v assignee=%v", moved.AssignedTeamID, moved.AssignedToID)
+	}
+	var note models.TicketComment
+	db.First(&note, handoff.Items[0].CommentID)
+	for _, want := range []string{"APAC → EMEA", "原处理人：Mei Lin", "Is the customer on the legacy plan?", "客户最新回复待答复：Still seeing 502 errors",
+		"- [ ] Collect gateway logs"} {
+		if !strings.Contains(note.Content, want) {
+			t.Fatalf("expected handoff note to contain %q, got:\n%s", want, note.Content)
+		}
+	}
+	if note.Type != models.CommentTypeInternal || strings.Contains(note.Content, "Restart gateway") {
+		t.Fatalf("unexpected handoff note %s:\n%s", note.Type, note.Content)
+	}
+	var history models.TicketHistory
+	if err := db.Where("ticket_id = ? AND action = ?", urgent.ID, models.HistoryActionTransfer).First(&history).Error; err != nil || history.CommentID == nil {
+		t.Fatalf("expected transfer history linked to note, got %+v, %v", history, err)
+	}
+	var notified int64
+	db.Model(&models.Notification{}).Where("related_type = ? AND related_id = ?", "ticket_handoff", handoff.ID).Count(&notified)
+	if notified != 2 {
+		t.Fatalf("expected receiving team member and lead to be notified, got %d", notified)
+	}
+
+	// 交接计划：到达换班时间后转交剩余工单，并推进下次执行时间
+	if _, err := svc.CreateSchedule(ctx, &models.TicketHandoffScheduleRequest{Name: "bad", FromTeamID: apac.ID, ToTeamID: emea.ID, CronExpr: "* * * * * *"}, emeaLead.ID); !errors.Is(err, ErrInvalidSchedule) {
+		t.Fatalf("expected sub-minute cron to be rejected, got %v", err)
+	}
+	schedule, err := svc.CreateSchedule(ctx, &models.TicketHandoffScheduleRequest{Name: "APAC to EMEA", FromTeamID: apac.ID, ToTeamID: emea.ID,
+		CronExpr: "0 0 8 * * *", NoteTemplate: "{{ticket_number}} from {{from_team}}: {{next_steps}}", NextSteps: "Call the customer"}, emeaLead.ID)
+	if err != nil {
+		t.Fatalf("create schedule failed: %v", err)
+	}
+	if schedule.NextRunAt == nil || schedule.NextRunAt.Hour() != 8 {
+		t.Fatalf("unexpected next run %v", schedule.NextRunAt)
+	}
+	if ran, err := svc.ProcessDue(ctx, schedule.NextRunAt.Add(-time.Minute)); err != nil || ran != 0 {
+		t.Fatalf("expected no due schedules, got %d, %v", ran, err)
+	}
+	due := schedule.NextRunAt.Add(time.Second)
+	if ran, err := svc.ProcessDue(ctx, due); err != nil || ran != 1 {
+		t.Fatalf("expected schedule to run, got %d, %v", ran, err)
+	}
+	if ran, _ := svc.ProcessDue(ctx, due); ran != 0 {
+		t.Fatal("expected schedule not to run twice for the same shift")
+	}
+	schedule, _ = svc.GetSchedule(ctx, schedule.ID)
+	if schedule.LastRunAt == nil || !schedule.NextRunAt.After(due) {
+		t.Fatalf("expected next run to advance, got %+v", schedule)
+	}
+
+	handoffs, total, err := svc.ListHandoffs(ctx, emea.ID, 1, 20)
+	if err != nil || total != 2 || handoffs[0].Trigger != models.TicketHandoffScheduled || handoffs[0].TicketCount != 1 {
+		t.Fatalf("unexpected handoff history %+v, %v", handoffs, err)
+	}
+	detail, err := svc.GetHandoff(ctx, handoffs[0].ID)
+	if err != nil || detail.Items[0].TicketID != low.ID {
+		t.Fatalf("unexpected scheduled handoff %+v, %v", detail, err)
+	}
+	var scheduledNote models.TicketComment
+	db.First(&scheduledNote, detail.Items[0].CommentID)
+	if scheduledNote.Content != "HO-002 from APAC: Call the customer" {
+		t.Fatalf("unexpected templated note %q", scheduledNote.Content)
+	}
+}
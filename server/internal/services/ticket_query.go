@@ -0,0 +1,539 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidTicketQuery 高级查询条件无效
+var ErrInvalidTicketQuery = errors.New("invalid ticket query")
+
+// TicketQueryError 指明出错条件位置的查询校验错误，Path 形如 filter.conditions[1].conditions[0]
+type TicketQueryError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (e *TicketQueryError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+// Is 使 errors.Is(err, ErrInvalidTicketQuery) 成立
+func (e *TicketQueryError) Is(target error) bool {
+	return target == ErrInvalidTicketQuery
+}
+
+const (
+	ticketQueryMaxDepth      = 5
+	ticketQueryMaxConditions = 50
+	ticketQueryMaxValues     = 500
+	ticketQueryCustomPrefix  = "custom_fields."
+	// ticketQueryEndpoint 高级查询接口在分页限制中的路由名
+	ticketQueryEndpoint = "POST /api/tickets/query"
+)
+
+// ticketQueryFieldKind 可查询字段的类型，决定允许的运算符和取值
+type ticketQueryFieldKind int
+
+const (
+	ticketQueryEnum ticketQueryFieldKind = iota
+	ticketQueryText
+	ticketQueryID
+	ticketQueryNumber
+	ticketQueryBool
+	ticketQueryDate
+	ticketQueryTags
+	ticketQueryCustomField
+)
+
+// ticketQueryFields 可查询字段白名单，字段名即列名，不会把请求中的字段名拼入 SQL
+var ticketQueryFields = map[string]ticketQueryFieldKind{
+	"status":          ticketQueryEnum,
+	"priority":        ticketQueryEnum,
+	"type":            ticketQueryEnum,
+	"source":          ticketQueryEnum,
+	"impact":          ticketQueryEnum,
+	"urgency":         ticketQueryEnum,
+	"resolution_code": ticketQueryEnum,
+
+	"title":          ticketQueryText,
+	"description":    ticketQueryText,
+	"ticket_number":  ticketQueryText,
+	"customer_email": ticketQueryText,
+	"customer_name":  ticketQueryText,
+	"customer_phone": ticketQueryText,
+
+	"created_by_id":    ticketQueryID,
+	"assigned_to_id":   ticketQueryID,
+	"assigned_team_id": ticketQueryID,
+	"category_id":      ticketQueryID,
+	"subcategory_id":   ticketQueryID,
+	"parent_ticket_id": ticketQueryID,
+
+	"view_count":      ticketQueryNumber,
+	"comment_count":   ticketQueryNumber,
+	"rating":          ticketQueryNumber,
+	"spam_score":      ticketQueryNumber,
+	"response_time":   ticketQueryNumber,
+	"resolution_time": ticketQueryNumber,
+
+	"sla_breached":    ticketQueryBool,
+	"is_confidential": ticketQueryBool,
+	"is_escalated":    ticketQueryBool,
+
+	"created_at":     ticketQueryDate,
+	"updated_at":     ticketQueryDate,
+	"due_date":       ticketQueryDate,
+	"resolved_at":    ticketQueryDate,
+	"closed_at":      ticketQueryDate,
+	"first_reply_at": ticketQueryDate,
+	"sla_due_date":   ticketQueryDate,
+
+	"tags": ticketQueryTags,
+}
+
+// ticketQueryOperators 各类型字段允许的运算符
+var ticketQueryOperators = map[ticketQueryFieldKind][]string{
+	ticketQueryEnum:        {"eq", "ne", "in", "not_in", "is_null", "is_not_null"},
+	ticketQueryText:        {"eq", "ne", "in", "contains", "not_contains", "starts_with", "is_null", "is_not_null"},
+	ticketQueryID:          {"eq", "ne", "in", "not_in", "is_null", "is_not_null"},
+	ticketQueryNumber:      {"eq", "ne", "gt", "gte", "lt", "lte", "between", "in", "is_null", "is_not_null"},
+	ticketQueryBool:        {"eq", "ne"},
+	ticketQueryDate:        {"gt", "gte", "lt", "lte", "between", "is_null", "is_not_null"},
+	ticketQueryTags:        {"contains", "not_contains"},
+	ticketQueryCustomField: {"eq", "ne", "in", "exists", "not_exists"},
+}
+
+// ticketSortRegistry 工单列表与高级查询允许排序的字段
+var ticketSortRegistry = NewSortRegistry(map[string]string{
+	"id": "id", "created_at": "created_at", "updated_at": "updated_at", "due_date": "due_date", "sla_due_date": "sla_due_date",
+	"resolved_at": "resolved_at", "closed_at": "closed_at", "priority": "priority", "status": "status", "ticket_number": "ticket_number",
+	"title": "title", "view_count": "view_count", "comment_count": "comment_count", "rating": "rating",
+	"type": "type", "source": "source", "assigned_to_id": "assigned_to_id", "created_by_id": "created_by_id", "category_id": "category_id",
+	"customer_name": "customer_name",
+}, models.SortField{Field: "created_at", Direction: models.SortDesc})
+
+var (
+	ticketQueryCustomKeyPattern    = regexp.MustCompile(`^[A-Za-z0-9_]{1,64}$`)
+	ticketQueryRelativeTimePattern = regexp.MustCompile(`^(now|today)(?:\s*([+-])\s*(\d{1,4})([mhdw]))?$`)
+)
+
+// ticketQueryCompiler 把条件树编译为参数化 SQL 条件
+type ticketQueryCompiler struct {
+	db         *gorm.DB
+	now        time.Time
+	conditions int
+}
+
+// CompileTicketQuery 校验并编译查询条件树，返回可直接用于 Where 的条件和参数。
+// 相对时间以 now 为基准；空的根条件组匹配全部工单
+func CompileTicketQuery(db *gorm.DB, filter *models.TicketQueryNode, now time.Time) (string, []interface{}, error) {
+	compiler := &ticketQueryCompiler{db: db, now: now}
+	if filter.IsGroup() && len(filter.Conditions) == 0 {
+		if filter.Operator != "" || filter.Value != nil {
+			return "", nil, &TicketQueryError{Path: "filter", Message: "field is required"}
+		}
+		return "1 = 1", nil, nil
+	}
+	return compiler.compile(filter, "filter", 1)
+}
+
+func (c *ticketQueryCompiler) compile(node *models.TicketQueryNode, path string, depth int) (string, []interface{}, error) {
+	if !node.IsGroup() {
+		if len(node.Conditions) > 0 || node.Logic != "" {
+			return "", nil, &TicketQueryError{Path: path, Message: "a condition cannot also have logic or nested conditions"}
+		}
+		c.conditions++
+		if c.conditions > ticketQueryMaxConditions {
+			return "", nil, &TicketQueryError{Path: path, Message: fmt.Sprintf("at most %d conditions are allowed", ticketQueryMaxConditions)}
+		}
+		return c.compileCondition(node, path)
+	}
+
+	if depth > ticketQueryMaxDepth {
+		return "", nil, &TicketQueryError{Path: path, Message: fmt.Sprintf("groups can be nested at most %d levels deep", ticketQueryMaxDepth)}
+	}
+	if node.Operator != "" || node.Value != nil {
+		return "", nil, &TicketQueryError{Path: path, Message: "field is required"}
+	}
+	joiner := " AND "
+	switch node.Logic {
+	case "", models.TicketQueryAnd:
+	case models.TicketQueryOr:
+		joiner = " OR "
+	default:
+		return "", nil, &TicketQueryError{Path: path, Message: fmt.Sprintf("invalid logic %q, expected and or or", node.Logic)}
+	}
+	if len(node.Conditions) == 0 {
+		return "", nil, &TicketQueryError{Path: path, Message: "group must contain at least one condition"}
+	}
+
+	parts := make([]string, 0, len(node.Conditions))
+	var args []interface{}
+	for i := range node.Conditions {
+		sql, childArgs, err := c.compile(&node.Conditions[i], fmt.Sprintf("%s.conditions[%d]", path, i), depth+1)
+		if err != nil {
+			return "", nil, err
+		}
+		parts = append(parts, "("+sql+")")
+		args = append(args, childArgs...)
+	}
+	return strings.Join(parts, joiner), args, nil
+}
+
+func (c *ticketQueryCompiler) compileCondition(node *models.TicketQueryNode, path string) (string, []interface{}, error) {
+	fail := func(format string, a ...interface{}) (string, []interface{}, error) {
+		return "", nil, &TicketQueryError{Path: path, Message: fmt.Sprintf(format, a...)}
+	}
+
+	column := node.Field
+	kind, ok := ticketQueryFields[column]
+	customKey := ""
+	if strings.HasPrefix(column, ticketQueryCustomPrefix) {
+		customKey = strings.TrimPrefix(column, ticketQueryCustomPrefix)
+		if !ticketQueryCustomKeyPattern.MatchString(customKey) {
+			return fail("invalid custom field key %q", customKey)
+		}
+		kind, ok = ticketQueryCustomField, true
+	}
+	if !ok {
+		return fail("unknown field %q", column)
+	}
+
+	operator := strings.ToLower(strings.TrimSpace(node.Operator))
+	if !containsString(ticketQueryOperators[kind], operator) {
+		return fail("operator %q is not supported for field %q, expected one of %s",
+			node.Operator, column, strings.Join(ticketQueryOperators[kind], ", "))
+	}
+
+	switch operator {
+	case "is_null", "is_not_null", "exists", "not_exists":
+		if node.Value != nil {
+			return fail("operator %q does not take a value", operator)
+		}
+	default:
+		if node.Value == nil {
+			return fail("value is required for operator %q", operator)
+		}
+	}
+
+	if kind == ticketQueryCustomField {
+		return c.compileCustomField(customKey, operator, node.Value, fail)
+	}
+	if kind == ticketQueryTags {
+		tags, err := ticketQueryStrings(node.Value)
+		if err != nil {
+			return fail("%v", err)
+		}
+		condition, args, err := JSONContainsCondition(c.db, "tags", tags)
+		if err != nil {
+			return "", nil, err
+		}
+		if operator == "not_contains" {
+			return "tags IS NULL OR NOT (" + condition + ")", args, nil
+		}
+		return condition, args, nil
+	}
+
+	switch operator {
+	case "is_null":
+		return column + " IS NULL", nil, nil
+	case "is_not_null":
+		return column + " IS NOT NULL", nil, nil
+	}
+
+	switch operator {
+	case "in", "not_in":
+		items, ok := node.Value.([]interface{})
+		if !ok || len(items) == 0 {
+			return fail("value must be a non-empty array for operator %q", operator)
+		}
+		if len(items) > ticketQueryMaxValues {
+			return fail("at most %d values are allowed", ticketQueryMaxValues)
+		}
+		values := make([]interface{}, 0, len(items))
+		for i, item := range items {
+			value, err := c.scalar(kind, item)
+			if err != nil {
+				return fail("value[%d]: %v", i, err)
+			}
+			values = append(values, value)
+		}
+		if operator == "not_in" {
+			return column + " IS NULL OR " + column + " NOT IN ?", []interface{}{values}, nil
+		}
+		return column + " IN ?", []interface{}{values}, nil
+	case "between":
+		items, ok := node.Value.([]interface{})
+		if !ok || len(items) != 2 {
+			return fail("value must be an array of two bounds for operator %q", operator)
+		}
+		low, err := c.scalar(kind, items[0])
+		if err != nil {
+			return fail("value[0]: %v", err)
+		}
+		high, err := c.scalar(kind, items[1])
+		if err != nil {
+			return fail("value[1]: %v", err)
+		}
+		return column + " BETWEEN ? AND ?", []interface{}{low, high}, nil
+	}
+
+	value, err := c.scalar(kind, node.Value)
+	if err != nil {
+		return fail("%v", err)
+	}
+	switch operator {
+	case "eq":
+		return column + " = ?", []interface{}{value}, nil
+	case "ne":
+		return column + " IS NULL OR " + column + " <> ?", []interface{}{value}, nil
+	case "gt":
+		return column + " > ?", []interface{}{value}, nil
+	case "gte":
+		return column + " >= ?", []interface{}{value}, nil
+	case "lt":
+		return column + " < ?", []interface{}{value}, nil
+	case "lte":
+		return column + " <= ?", []interface{}{value}, nil
+	case "contains":
+		return "LOWER(" + column + `) LIKE ? ESCAPE '\'`, []interface{}{"%" + escapeLikePattern(strings.ToLower(value.(string))) + "%"}, nil
+	case "not_contains":
+		return column + " IS NULL OR LOWER(" + column + `) NOT LIKE ? ESCAPE '\'`, []interface{}{"%" + escapeLikePattern(strings.ToLower(value.(string))) + "%"}, nil
+	case "starts_with":
+		return "LOWER(" + column + `) LIKE ? ESCAPE '\'`, []interface{}{escapeLikePattern(strings.ToLower(value.(string))) + "%"}, nil
+	}
+	return fail("unsupported operator %q", operator)
+}
+
+// compileCustomField 自定义字段按 JSON 包含匹配，与列表接口的 custom_fields 过滤一致
+func (c *ticketQueryCompiler) compileCustomField(key, operator string, value interface{}, fail func(string, ...interface{}) (string, []interface{}, error)) (string, []interface{}, error) {
+	switch operator {
+	case "exists", "not_exists":
+		var condition string
+		var args []interface{}
+		if models.UsesNativeJSON(c.db) {
+			condition, args = "jsonb_exists(custom_fields, ?)", []interface{}{key}
+		} else {
+			keyData, _ := json.Marshal(key)
+			condition, args = `custom_fields LIKE ? ESCAPE '\'`, []interface{}{"%" + escapeLikePattern(string(keyData)+":") + "%"}
+		}
+		if operator == "not_exists" {
+			return "custom_fields IS NULL OR NOT (" + condition + ")", args, nil
+		}
+		return condition, args, nil
+	}
+
+	values := []interface{}{value}
+	if operator == "in" {
+		items, ok := value.([]interface{})
+		if !ok || len(items) == 0 {
+			return fail("value must be a non-empty array for operator %q", operator)
+		}
+		if len(items) > ticketQueryMaxValues {
+			return fail("at most %d values are allowed", ticketQueryMaxValues)
+		}
+		values = items
+	}
+
+	parts := make([]string, 0, len(values))
+	var args []interface{}
+	for i, item := range values {
+		switch item.(type) {
+		case string, float64, bool, json.Number:
+		default:
+			if operator == "in" {
+				return fail("value[%d]: custom field values must be strings, numbers or booleans", i)
+			}
+			return fail("custom field values must be strings, numbers or booleans")
+		}
+		condition, itemArgs, err := JSONContainsCondition(c.db, "custom_fields", map[string]interface{}{key: item})
+		if err != nil {
+			return "", nil, err
+		}
+		parts = append(parts, "("+condition+")")
+		args = append(args, itemArgs...)
+	}
+	condition := strings.Join(parts, " OR ")
+	if operator == "ne" {
+		return "custom_fields IS NULL OR NOT (" + condition + ")", args, nil
+	}
+	return condition, args, nil
+}
+
+// scalar 按字段类型校验并转换单个取值
+func (c *ticketQueryCompiler) scalar(kind ticketQueryFieldKind, value interface{}) (interface{}, error) {
+	switch kind {
+	case ticketQueryEnum, ticketQueryText:
+		s, ok := value.(string)
+		if !ok || strings.TrimSpace(s) == "" {
+			return nil, fmt.Errorf("value must be a non-empty string")
+		}
+		return s, nil
+	case ticketQueryID:
+		n, ok := ticketQueryNumberValue(value)
+		if !ok || n < 1 || n != math.Trunc(n) || n > math.MaxUint32 {
+			return nil, fmt.Errorf("value must be a positive integer id")
+		}
+		return uint(n), nil
+	case ticketQueryNumber:
+		n, ok := ticketQueryNumberValue(value)
+		if !ok {
+			return nil, fmt.Errorf("value must be a number")
+		}
+		return n, nil
+	case ticketQueryBool:
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("value must be a boolean")
+		}
+		return b, nil
+	case ticketQueryDate:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("value must be a date string")
+		}
+		return parseTicketQueryTime(s, c.now)
+	}
+	return nil, fmt.Errorf("unsupported value")
+}
+
+// parseTicketQueryTime 解析日期取值：RFC3339、YYYY-MM-DD（本地零点），
+// 或相对时间 now、today 加减 m/h/d/w，如 now-7d、today+1d
+func parseTicketQueryTime(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if match := ticketQueryRelativeTimePattern.FindStringSubmatch(strings.ToLower(value)); match != nil {
+		base := now
+		if match[1] == "today" {
+			base = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+		}
+		if match[2] == "" {
+			return base, nil
+		}
+		amount, _ := strconv.Atoi(match[3])
+		if match[2] == "-" {
+			amount = -amount
+		}
+		switch match[4] {
+		case "m":
+			return base.Add(time.Duration(amount) * time.Minute), nil
+		case "h":
+			return base.Add(time.Duration(amount) * time.Hour), nil
+		case "d":
+			return base.AddDate(0, 0, amount), nil
+		default:
+			return base.AddDate(0, 0, amount*7), nil
+		}
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02", value, now.Location()); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q, expected RFC3339, YYYY-MM-DD or a relative time such as now-7d", value)
+}
+
+func ticketQueryNumberValue(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case json.Number:
+		n, err := v.Float64()
+		return n, err == nil
+	}
+	return 0, false
+}
+
+func ticketQueryStrings(value interface{}) ([]string, error) {
+	switch v := value.(type) {
+	case string:
+		if strings.TrimSpace(v) != "" {
+			return []string{strings.TrimSpace(v)}, nil
+		}
+	case []interface{}:
+		if len(v) > ticketQueryMaxValues {
+			return nil, fmt.Errorf("at most %d values are allowed", ticketQueryMaxValues)
+		}
+		result := make([]string, 0, len(v))
+		for i, item := range v {
+			s, ok := item.(string)
+			if !ok || strings.TrimSpace(s) == "" {
+				return nil, fmt.Errorf("value[%d] must be a non-empty string", i)
+			}
+			result = append(result, strings.TrimSpace(s))
+		}
+		if len(result) > 0 {
+			return result, nil
+		}
+	}
+	return nil, fmt.Errorf("value must be a tag or a non-empty array of tags")
+}
+
+// QueryTickets 按高级查询条件分页获取工单，条件无效时返回 *TicketQueryError
+func (s *TicketService) QueryTickets(ctx context.Context, req *models.TicketQueryRequest) ([]*models.Ticket, int64, error) {
+	condition, args, err := CompileTicketQuery(s.db, &req.Filter, time.Now())
+	if err != nil {
+		return nil, 0, err
+	}
+
+	sortBy := strings.ToLower(strings.TrimSpace(req.SortBy))
+	if sortBy == "" {
+		sortBy = "created_at"
+	}
+	if !ticketSortRegistry.Allows(sortBy) {
+		return nil, 0, &TicketQueryError{Path: "sort_by", Message: fmt.Sprintf("cannot sort by %q", req.SortBy)}
+	}
+	sortOrder, err := ParseSortDirection(req.SortOrder, models.SortDesc)
+	if err != nil {
+		return nil, 0, &TicketQueryError{Path: "sort_order", Message: "sort_order must be asc or desc"}
+	}
+
+	// 分页参数在请求体中，不经过分页守卫中间件，在此按同样的限制校验
+	if err := s.paginationGuard.CheckPage(ctx, ticketQueryEndpoint, req.Page, req.PageSize); err != nil {
+		return nil, 0, err
+	}
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if maxSize := s.paginationGuard.CurrentPolicy(ctx).MaxPageSizeFor(ticketQueryEndpoint); req.PageSize < 1 {
+		req.PageSize = 20
+	} else if req.PageSize > maxSize {
+		req.PageSize = maxSize
+	}
+
+	query := CategoryScopeFrom(ctx).Apply(s.db.WithContext(ctx).Model(&models.Ticket{}).Where("("+condition+")", args...))
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count tickets: %w", err)
+	}
+
+	sort := []models.SortField{{Field: sortBy, Direction: sortOrder}}
+	if sortBy != "id" {
+		sort = append(sort, models.SortField{Field: "id", Direction: sortOrder})
+	}
+	query, err = ticketSortRegistry.Apply(query, sort)
+	if err != nil {
+		return nil, 0, err
+	}
+	var tickets []*models.Ticket
+	if err := query.
+		Offset((req.Page - 1) * req.PageSize).Limit(req.PageSize).
+		Preload("CreatedBy").Preload("AssignedTo").Preload("AssignedTeam").Preload("Comments").
+		Find(&tickets).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to query tickets: %w", err)
+	}
+	return tickets, total, nil
+}
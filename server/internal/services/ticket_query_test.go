@@ -0,0 +1,165 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestQueryTickets_NestedGroupsCustomFieldsAndRelativeDates(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:ticket_query_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Team{}, &models.Ticket{}, &models.TicketComment{}, &models.TicketHistory{}, &models.SystemConfig{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+	ctx := context.Background()
+	svc := NewTicketService(db).(*TicketService)
+
+	agent := models.User{Username: "query-agent", Email: "query-agent@example.com", PasswordHash: "x", Role: models.RoleAgent}
+	db.Create(&agent)
+
+	seed := []struct {
+		req *models.TicketCreateRequest
+		age time.Duration
+	}{
+		{&models.TicketCreateRequest{Title: "VPN outage 100%", Type: models.TicketTypeIncident, Priority: models.TicketPriorityUrgent,
+			Tags: models.StringList{"vip", "network"}, CustomFields: &models.JSONMap{"tier": "gold", "seats": 50}}, time.Hour},
+		{&models.TicketCreateRequest{Title: "Invoice question", Type: models.TicketTypeRequest, Priority: models.TicketPriorityHigh,
+			Tags: models.StringList{"billing"}, CustomFields: &models.JSONMap{"tier": "silver"}}, 3 * 24 * time.Hour},
+		{&models.TicketCreateRequest{Title: "Old printer issue", Type: models.TicketTypeIncident, Priority: models.TicketPriorityLow}, 30 * 24 * time.Hour},
+	}
+	ids := make([]uint, len(seed))
+	for i, item := range seed {
+		ticket, err := svc.CreateTicket(ctx, item.req, agent.ID)
+		if err != nil {
+			t.Fatalf("failed to create ticket: %v", err)
+		}
+		ids[i] = ticket.ID
+		db.Model(ticket).UpdateColumn("created_at", time.Now().Add(-item.age))
+	}
+	db.Model(&models.Ticket{}).Where("id = ?", ids[1]).UpdateColumn("assigned_to_id", agent.ID)
+
+	query := func(filter string) ([]*models.Ticket, int64, error) {
+		req := &models.TicketQueryRequest{SortBy: "id", SortOrder: "asc"}
+		if err := json.Unmarshal([]byte(filter), &req.Filter); err != nil {
+			t.Fatalf("invalid filter json: %v", err)
+		}
+		return svc.QueryTickets(ctx, req)
+	}
+	expect := func(filter string, want ...uint) {
+		t.Helper()
+		tickets, total, err := query(filter)
+		if err != nil {
+			t.Fatalf("query %s: %v", filter, err)
+		}
+		if int(total) != len(want) || len(tickets) != len(want) {
+			t.Fatalf("query %s: expected %v, got %d tickets", filter, want, total)
+		}
+		for i, ticket := range tickets {
+			if ticket.ID != want[i] {
+				t.Fatalf("query %s: expected %v, got ticket %d at %d", filter, want, ticket.ID, i)
+			}
+		}
+	}
+
+	expect(`{}`, ids...)
+	// 近 7 天内的紧急工单，或已分配的高优先级工单
+	expect(`{"logic":"or","conditions":[
+		{"conditions":[{"field":"created_at","operator":"gte","value":"now-7d"},{"field":"priority","operator":"eq","value":"urgent"}]},
+		{"conditions":[{"field":"assigned_to_id","operator":"is_not_null"},{"field":"priority","operator":"in","value":["high","urgent"]}]}
+	]}`, ids[0], ids[1])
+	expect(`{"field":"created_at","operator":"lt","value":"today-7d"}`, ids[2])
+	expect(`{"field":"custom_fields.tier","operator":"in","value":["gold","platinum"]}`, ids[0])
+	expect(`{"field":"custom_fields.tier","operator":"ne","value":"gold"}`, ids[1], ids[2])
+	expect(`{"field":"custom_fields.seats","operator":"exists"}`, ids[0])
+	expect(`{"field":"tags","operator":"not_contains","value":"vip"}`, ids[1], ids[2])
+	// LIKE 通配符按字面匹配
+	expect(`{"field":"title","operator":"contains","value":"100%"}`, ids[0])
+	expect(`{"field":"title","operator":"contains","value":"_"}`)
+
+	invalid := []struct {
+		filter, path string
+	}{
+		{`{"conditions":[{"field":"priority","operator":"eq","value":"low"},{"field":"password_hash","operator":"eq","value":"x"}]}`, "filter.conditions[1]"},
+		{`{"logic":"or","conditions":[{"conditions":[{"field":"created_at","operator":"gte","value":"last week"}]}]}`, "filter.conditions[0].conditions[0]"},
+		{`{"field":"title","operator":"gt","value":"a"}`, "filter"},
+		{`{"field":"custom_fields.tier'); DROP TABLE tickets; --","operator":"eq","value":"x"}`, "filter"},
+		{`{"field":"rating","operator":"between","value":[1]}`, "filter"},
+		{`{"logic":"xor","conditions":[{"field":"status","operator":"eq","value":"open"}]}`, "filter"},
+	}
+	for _, tc := range invalid {
+		_, _, err := query(tc.filter)
+		var queryErr *TicketQueryError
+		if !errors.Is(err, ErrInvalidTicketQuery) || !errors.As(err, &queryErr) || queryErr.Path != tc.path {
+			t.Fatalf("query %s: expected error at %s, got %v", tc.filter, tc.path, err)
+		}
+	}
+	if _, _, err := svc.QueryTickets(ctx, &models.TicketQueryRequest{SortBy: "password_hash"}); !errors.Is(err, ErrInvalidTicketQuery) {
+		t.Fatalf("expected unsortable field to be rejected, got %v", err)
+	}
+}
+
+func TestQueryTickets_EnforcesPaginationLimits(t *testing.T) {
+	db := newTestDB(t, &models.User{}, &models.Team{}, &models.Ticket{}, &models.TicketComment{}, &models.SystemConfig{})
+	ctx := context.Background()
+	match := models.TicketQueryNode{Field: "status", Operator: "eq", Value: "open"}
+
+	// 分页参数在请求体中，同样受分页限制
+	svc := NewTicketService(db).(*TicketService)
+	if _, _, err := svc.QueryTickets(ctx, &models.TicketQueryRequest{Filter: match, PageSize: 500}); !errors.Is(err, ErrPageSizeTooLarge) {
+		t.Fatalf("expected oversized page to be rejected, got %v", err)
+	}
+	if _, _, err := svc.QueryTickets(ctx, &models.TicketQueryRequest{Filter: match, Page: 1000, PageSize: 100}); !errors.Is(err, ErrPaginationTooDeep) {
+		t.Fatalf("expected deep page to be rejected, got %v", err)
+	}
+	req := &models.TicketQueryRequest{Filter: match, PageSize: 100}
+	if _, _, err := svc.QueryTickets(ctx, req); err != nil || req.PageSize != 100 {
+		t.Fatalf("expected default maximum to be allowed, got %d (%v)", req.PageSize, err)
+	}
+
+	// 按接口单独配置的上限；关闭限制时超出部分截断而不是报错
+	if err := NewPaginationGuard(db).SetPolicy(ctx, &models.PaginationPolicy{Enabled: true, DefaultMaxPageSize: 100,
+		MaxPageSizes: map[string]int{ticketQueryEndpoint: 10}}, 1); err != nil {
+		t.Fatalf("set policy failed: %v", err)
+	}
+	if _, _, err := svc.QueryTickets(ctx, &models.TicketQueryRequest{Filter: match, PageSize: 20}); !errors.Is(err, ErrPageSizeTooLarge) {
+		t.Fatalf("expected endpoint limit to apply, got %v", err)
+	}
+	if err := NewPaginationGuard(db).SetPolicy(ctx, &models.PaginationPolicy{Enabled: false, DefaultMaxPageSize: 100,
+		MaxPageSizes: map[string]int{ticketQueryEndpoint: 10}}, 1); err != nil {
+		t.Fatalf("set policy failed: %v", err)
+	}
+	req = &models.TicketQueryRequest{Filter: match, PageSize: 20}
+	if _, _, err := svc.QueryTickets(ctx, req); err != nil || req.PageSize != 10 {
+		t.Fatalf("expected page size to be capped, got %d (%v)", req.PageSize, err)
+	}
+}
+
+func TestParseTicketQueryTime_RelativeAndAbsolute(t *testing.T) {
+	now := time.Date(2024, 5, 15, 14, 30, 0, 0, time.UTC)
+	cases := map[string]time.Time{
+		"now":                  now,
+		"now-7d":               now.AddDate(0, 0, -7),
+		"now + 3h":             now.Add(3 * time.Hour),
+		"today+1w":             time.Date(2024, 5, 22, 0, 0, 0, 0, time.UTC),
+		"2024-05-01":           time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		"2024-05-01T08:00:00Z": time.Date(2024, 5, 1, 8, 0, 0, 0, time.UTC),
+	}
+	for value, want := range cases {
+		got, err := parseTicketQueryTime(value, now)
+		if err != nil || !got.Equal(want) {
+			t.Fatalf("parse %q: expected %v, got %v (%v)", value, want, got, err)
+		}
+	}
+	if _, err := parseTicketQueryTime("now-7y", now); err == nil {
+		t.Fatalf("expected unsupported unit to be rejected")
+	}
+}
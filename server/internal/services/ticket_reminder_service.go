@@ -0,0 +1,320 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxReminderRepeatDays 周期提醒的最大间隔天数
+	maxReminderRepeatDays = 365
+	// maxPendingRemindersPerTicket 每个坐席在同一工单上最多保留的待提醒数
+	maxPendingRemindersPerTicket = 20
+)
+
+var (
+	// ErrReminderNotFound 提醒不存在
+	ErrReminderNotFound = errors.New("reminder not found")
+	// ErrReminderTicketNotFound 工单不存在
+	ErrReminderTicketNotFound = errors.New("ticket not found")
+	// ErrReminderForbidden 无权管理该提醒
+	ErrReminderForbidden = errors.New("not allowed to manage this reminder")
+	// ErrReminderInvalid 提醒参数无效
+	ErrReminderInvalid = errors.New("invalid reminder")
+	// ErrReminderFinished 提醒已发送、结束或已取消
+	ErrReminderFinished = errors.New("reminder is no longer pending")
+)
+
+// TicketReminderRunResult 定时处理结果
+type TicketReminderRunResult struct {
+	Sent      int `json:"sent"`
+	Completed int `json:"completed"` // 因工单已完结而结束的周期提醒
+}
+
+// TicketReminderService 工单提醒服务
+type TicketReminderService struct {
+	db                  *gorm.DB
+	notificationService NotificationServiceInterface
+}
+
+// NewTicketReminderService 创建工单提醒服务
+func NewTicketReminderService(db *gorm.DB) *TicketReminderService {
+	return &TicketReminderService{
+		db:                  db,
+		notificationService: NewNotificationService(db),
+	}
+}
+
+// Create 为自己或同事设置工单提醒
+func (s *TicketReminderService) Create(ctx context.Context, ticketID uint, req *models.TicketReminderRequest, actorID uint) (*models.TicketReminder, error) {
+	userID := actorID
+	if req.UserID != nil {
+		userID = *req.UserID
+	}
+	if !req.RemindAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: remind_at must be in the future", ErrReminderInvalid)
+	}
+	if req.RepeatDays < 0 || req.RepeatDays > maxReminderRepeatDays {
+		return nil, fmt.Errorf("%w: repeat_days must be between 0 and %d", ErrReminderInvalid, maxReminderRepeatDays)
+	}
+
+	var ticket models.Ticket
+	if err := s.db.WithContext(ctx).Select("id", "status").
+		Where("deleted_at IS NULL").First(&ticket, ticketID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrReminderTicketNotFound
+		}
+		return nil, fmt.Errorf("failed to get ticket: %w", err)
+	}
+	if req.RepeatDays > 0 && isClosedTicketStatus(ticket.Status) {
+		return nil, fmt.Errorf("%w: recurring reminders require an open ticket", ErrReminderInvalid)
+	}
+
+	if userID != actorID {
+		var user models.User
+		if err := s.db.WithContext(ctx).Select("id", "role", "status").First(&user, userID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, fmt.Errorf("%w: user not found", ErrReminderInvalid)
+			}
+			return nil, fmt.Errorf("failed to get user: %w", err)
+		}
+		if user.IsCustomer() || user.Status == models.UserStatusSuspended {
+			return nil, fmt.Errorf("%w: reminders can only be set for active agents", ErrReminderInvalid)
+		}
+	}
+
+	var pending int64
+	if err := s.db.WithContext(ctx).Model(&models.TicketReminder{}).
+		Where("ticket_id = ? AND user_id = ? AND status = ?", ticketID, userID, models.TicketReminderStatusPending).
+		Count(&pending).Error; err != nil {
+		return nil, fmt.Errorf("failed to count reminders: %w", err)
+	}
+	if pending >= maxPendingRemindersPerTicket {
+		return nil, fmt.Errorf("%w: at most %d pending reminders per ticket", ErrReminderInvalid, maxPendingRemindersPerTicket)
+	}
+
+	reminder := &models.TicketReminder{
+		TicketID:    ticketID,
+		UserID:      userID,
+		CreatedByID: actorID,
+		Note:        strings.TrimSpace(req.Note),
+		RemindAt:    req.RemindAt,
+		RepeatDays:  req.RepeatDays,
+		Status:      models.TicketReminderStatusPending,
+	}
+	if err := s.db.WithContext(ctx).Create(reminder).Error; err != nil {
+		return nil, fmt.Errorf("failed to create reminder: %w", err)
+	}
+	return reminder, nil
+}
+
+// ListForTicket 获取工单上与当前用户相关（为其设置或由其设置）的提醒，管理员/主管可查看全部
+func (s *TicketReminderService) ListForTicket(ctx context.Context, ticketID, userID uint, role string) ([]models.TicketReminder, error) {
+	query := s.db.WithContext(ctx).Where("ticket_id = ?", ticketID)
+	if !CanManageDelegations(role) {
+		query = query.Where("user_id = ? OR created_by_id = ?", userID, userID)
+	}
+	var reminders []models.TicketReminder
+	if err := query.Preload("User").Order("remind_at ASC").Find(&reminders).Error; err != nil {
+		return nil, fmt.Errorf("failed to list reminders: %w", err)
+	}
+	return reminders, nil
+}
+
+// ListForUser 获取发给指定用户的提醒，status 为空时返回待提醒的，按提醒时间排序
+func (s *TicketReminderService) ListForUser(ctx context.Context, userID uint, status models.TicketReminderStatus, limit int) ([]models.TicketReminder, error) {
+	if status == "" {
+		status = models.TicketReminderStatusPending
+	}
+	query := s.db.WithContext(ctx).
+		Preload("Ticket", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id", "ticket_number", "title", "status", "priority")
+		}).
+		Where("user_id = ? AND status = ?", userID, status)
+	if status == models.TicketReminderStatusPending {
+		query = query.Order("remind_at ASC")
+	} else {
+		query = query.Order("updated_at DESC")
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	var reminders []models.TicketReminder
+	if err := query.Find(&reminders).Error; err != nil {
+		return nil, fmt.Errorf("failed to list reminders: %w", err)
+	}
+	return reminders, nil
+}
+
+// Cancel 取消待提醒的提醒，接收人、设置人或管理员/主管可以取消
+func (s *TicketReminderService) Cancel(ctx context.Context, ticketID, reminderID, actorID uint, actorRole string) (*models.TicketReminder, error) {
+	var reminder models.TicketReminder
+	if err := s.db.WithContext(ctx).Where("ticket_id = ?", ticketID).First(&reminder, reminderID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrReminderNotFound
+		}
+		return nil, fmt.Errorf("failed to get reminder: %w", err)
+	}
+	if reminder.UserID != actorID && reminder.CreatedByID != actorID && !CanManageDelegations(actorRole) {
+		return nil, ErrReminderForbidden
+	}
+
+	now := time.Now()
+	result := s.db.WithContext(ctx).Model(&models.TicketReminder{}).
+		Where("id = ? AND status = ?", reminder.ID, models.TicketReminderStatusPending).
+		Updates(map[string]interface{}{"status": models.TicketReminderStatusCancelled, "cancelled_at": now})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to cancel reminder: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrReminderFinished
+	}
+	reminder.Status = models.TicketReminderStatusCancelled
+	reminder.CancelledAt = &now
+	return &reminder, nil
+}
+
+// ProcessDue 发送到期的提醒，由定时任务调用。周期提醒发送后顺延到下一个未到期的周期；
+// 工单已完结时结束周期提醒，不再发送
+func (s *TicketReminderService) ProcessDue(ctx context.Context, now time.Time) (*TicketReminderRunResult, error) {
+	result := &TicketReminderRunResult{}
+
+	var due []models.TicketReminder
+	if err := s.db.WithContext(ctx).
+		Preload("Ticket", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id", "ticket_number", "title", "status", "deleted_at")
+		}).
+		Where("status = ? AND remind_at <= ?", models.TicketReminderStatusPending, now).
+		Order("remind_at ASC").
+		Find(&due).Error; err != nil {
+		return nil, fmt.Errorf("failed to find due reminders: %w", err)
+	}
+
+	for i := range due {
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+		reminder := &due[i]
+		ticket := reminder.Ticket
+
+		updates := map[string]interface{}{}
+		finished := ticket == nil || ticket.DeletedAt != nil
+		if reminder.RepeatDays > 0 {
+			finished = finished || isClosedTicketStatus(ticket.Status)
+		}
+		switch {
+		case finished:
+			updates["status"] = models.TicketReminderStatusCompleted
+		case reminder.RepeatDays > 0:
+			next := reminder.RemindAt
+			for !next.After(now) {
+				next = next.AddDate(0, 0, reminder.RepeatDays)
+			}
+			updates["remind_at"] = next
+		default:
+			updates["status"] = models.TicketReminderStatusSent
+		}
+		if !finished {
+			updates["sent_count"] = gorm.Expr("sent_count + 1")
+			updates["last_sent_at"] = now
+		}
+
+		// 先按原提醒时间认领，多实例同时执行时只有一个实例发送
+		claim := s.db.WithContext(ctx).Model(&models.TicketReminder{}).
+			Where("id = ? AND status = ? AND remind_at = ?", reminder.ID, models.TicketReminderStatusPending, reminder.RemindAt).
+			Updates(updates)
+		if claim.Error != nil {
+			log.Printf("Failed to update reminder %d: %v", reminder.ID, claim.Error)
+			continue
+		}
+		if claim.RowsAffected == 0 {
+			continue
+		}
+		if finished {
+			result.Completed++
+			continue
+		}
+
+		if err := s.deliver(ctx, reminder); err != nil {
+			log.Printf("Failed to deliver reminder %d to user %d: %v", reminder.ID, reminder.UserID, err)
+			continue
+		}
+		result.Sent++
+	}
+
+	if result.Sent > 0 || result.Completed > 0 {
+		log.Printf("Ticket reminders processed: %d sent, %d completed", result.Sent, result.Completed)
+	}
+	return result, nil
+}
+
+// reminderChannels 按接收人对工单提醒的通知偏好选择渠道，未设置偏好时只发送应用内通知（同时推送到已订阅的浏览器）
+func (s *TicketReminderService) reminderChannels(ctx context.Context, userID uint) ([]models.NotificationChannel, error) {
+	var preference models.NotificationPreference
+	err := s.db.WithContext(ctx).
+		Where("user_id = ? AND notification_type = ?", userID, models.NotificationTypeTicketReminder).
+		First(&preference).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return []models.NotificationChannel{models.NotificationChannelInApp}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification preference: %w", err)
+	}
+
+	var channels []models.NotificationChannel
+	if preference.InAppEnabled {
+		channels = append(channels, models.NotificationChannelInApp)
+	}
+	if preference.EmailEnabled {
+		channels = append(channels, models.NotificationChannelEmail)
+	}
+	return channels, nil
+}
+
+func (s *TicketReminderService) deliver(ctx context.Context, reminder *models.TicketReminder) error {
+	channels, err := s.reminderChannels(ctx, reminder.UserID)
+	if err != nil {
+		return err
+	}
+
+	content := fmt.Sprintf("工单 #%s 的提醒时间已到", reminder.Ticket.TicketNumber)
+	if reminder.Note != "" {
+		content = fmt.Sprintf("工单 #%s：%s", reminder.Ticket.TicketNumber, reminder.Note)
+	}
+	var sender *uint
+	if reminder.CreatedByID != reminder.UserID {
+		sender = &reminder.CreatedByID
+	}
+	for _, channel := range channels {
+		_, err := s.notificationService.CreateNotification(ctx, &models.NotificationCreateRequest{
+			Type:            models.NotificationTypeTicketReminder,
+			Title:           fmt.Sprintf("工单提醒 - %s", reminder.Ticket.Title),
+			Content:         content,
+			Priority:        models.NotificationPriorityNormal,
+			Channel:         channel,
+			RecipientID:     reminder.UserID,
+			SenderID:        sender,
+			RelatedType:     "ticket",
+			RelatedID:       &reminder.TicketID,
+			RelatedTicketID: &reminder.TicketID,
+			ActionURL:       fmt.Sprintf("/tickets/%d", reminder.TicketID),
+			Metadata: map[string]interface{}{
+				"reminder_id":   reminder.ID,
+				"ticket_number": reminder.Ticket.TicketNumber,
+				"note":          reminder.Note,
+				"repeat_days":   reminder.RepeatDays,
+			},
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestTicketReminder_DeliveryRecurrenceAndCancel(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:ticket_reminder_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Team{}, &models.Ticket{}, &models.TicketReminder{},
+		&models.Notification{}, &models.NotificationPreference{}, &models.SystemConfig{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+	ctx := context.Background()
+	svc := NewTicketReminderService(db)
+
+	agent := models.User{Username: "remind-agent", Email: "remind-agent@example.com", PasswordHash: "x", Role: models.RoleAgent}
+	teammate := models.User{Username: "remind-mate", Email: "remind-mate@example.com", PasswordHash: "x", Role: models.RoleAgent}
+	customer := models.User{Username: "remind-customer", Email: "remind-customer@example.com", PasswordHash: "x", Role: models.RoleCustomer}
+	for _, u := range []*models.User{&agent, &teammate, &customer} {
+		if err := db.Create(u).Error; err != nil {
+			t.Fatalf("failed to seed user: %v", err)
+		}
+	}
+	ticket := models.Ticket{TicketNumber: "RM-1", Title: "Follow up", Status: models.TicketStatusOpen, Priority: models.TicketPriorityNormal,
+		Type: models.TicketTypeRequest, Source: models.TicketSourceWeb, CreatedByID: customer.ID, AssignedToID: &agent.ID}
+	db.Create(&ticket)
+
+	now := time.Now()
+	if _, err := svc.Create(ctx, ticket.ID, &models.TicketReminderRequest{RemindAt: now.Add(-time.Minute)}, agent.ID); !errors.Is(err, ErrReminderInvalid) {
+		t.Fatalf("expected past reminder to be rejected, got %v", err)
+	}
+	if _, err := svc.Create(ctx, ticket.ID, &models.TicketReminderRequest{RemindAt: now.Add(time.Hour), UserID: &customer.ID}, agent.ID); !errors.Is(err, ErrReminderInvalid) {
+		t.Fatalf("expected reminder for customer to be rejected, got %v", err)
+	}
+
+	once, err := svc.Create(ctx, ticket.ID, &models.TicketReminderRequest{RemindAt: now.Add(time.Hour), Note: " Call back "}, agent.ID)
+	if err != nil || once.UserID != agent.ID || once.Note != "Call back" {
+		t.Fatalf("unexpected reminder %+v, %v", once, err)
+	}
+	recurring, err := svc.Create(ctx, ticket.ID, &models.TicketReminderRequest{RemindAt: now.Add(2 * time.Hour), UserID: &teammate.ID, RepeatDays: 2}, agent.ID)
+	if err != nil {
+		t.Fatalf("create recurring reminder failed: %v", err)
+	}
+	// 同事只接收邮件
+	db.Create(&models.NotificationPreference{UserID: teammate.ID, NotificationType: models.NotificationTypeTicketReminder, EmailEnabled: true})
+	db.Model(&models.NotificationPreference{}).Where("user_id = ?", teammate.ID).Update("in_app_enabled", false)
+
+	if upcoming, _ := svc.ListForUser(ctx, agent.ID, "", 10); len(upcoming) != 1 || upcoming[0].Ticket == nil || upcoming[0].Ticket.TicketNumber != "RM-1" {
+		t.Fatalf("unexpected upcoming reminders %+v", upcoming)
+	}
+	if listed, _ := svc.ListForTicket(ctx, ticket.ID, teammate.ID, string(models.RoleAgent)); len(listed) != 1 || listed[0].ID != recurring.ID {
+		t.Fatalf("expected teammate to see only their reminder, got %+v", listed)
+	}
+
+	// 到期发送：单次提醒发送后结束，周期提醒顺延
+	result, err := svc.ProcessDue(ctx, now.Add(3*time.Hour))
+	if err != nil || result.Sent != 2 {
+		t.Fatalf("expected 2 reminders sent, got %+v, %v", result, err)
+	}
+	if again, _ := svc.ProcessDue(ctx, now.Add(3*time.Hour)); again.Sent != 0 {
+		t.Fatalf("expected no duplicate delivery, got %+v", again)
+	}
+	var notifications []models.Notification
+	db.Where("type = ?", models.NotificationTypeTicketReminder).Order("id").Find(&notifications)
+	if len(notifications) != 2 || notifications[0].RecipientID != agent.ID || notifications[0].Channel != models.NotificationChannelInApp ||
+		notifications[1].RecipientID != teammate.ID || notifications[1].Channel != models.NotificationChannelEmail ||
+		notifications[1].SenderID == nil || *notifications[1].SenderID != agent.ID {
+		t.Fatalf("unexpected reminder notifications %+v", notifications)
+	}
+	db.First(&once, once.ID)
+	db.First(&recurring, recurring.ID)
+	if once.Status != models.TicketReminderStatusSent || once.SentCount != 1 {
+		t.Fatalf("unexpected one-time reminder state %+v", once)
+	}
+	if recurring.Status != models.TicketReminderStatusPending || recurring.SentCount != 1 ||
+		!recurring.RemindAt.Equal(now.Add(2*time.Hour).AddDate(0, 0, 2)) {
+		t.Fatalf("unexpected recurring reminder state %+v", recurring)
+	}
+
+	// 工单完结后周期提醒结束
+	db.Model(&ticket).Update("status", models.TicketStatusResolved)
+	result, _ = svc.ProcessDue(ctx, now.AddDate(0, 0, 3))
+	db.First(&recurring, recurring.ID)
+	if result.Sent != 0 || result.Completed != 1 || recurring.Status != models.TicketReminderStatusCompleted {
+		t.Fatalf("expected recurring reminder to complete, got %+v %+v", result, recurring)
+	}
+
+	// 只有接收人、设置人或管理员可以取消
+	db.Model(&ticket).Update("status", models.TicketStatusOpen)
+	pending, _ := svc.Create(ctx, ticket.ID, &models.TicketReminderRequest{RemindAt: now.Add(time.Hour)}, agent.ID)
+	if _, err := svc.Cancel(ctx, ticket.ID, pending.ID, teammate.ID, string(models.RoleAgent)); !errors.Is(err, ErrReminderForbidden) {
+		t.Fatalf("expected teammate cancel to be forbidden, got %v", err)
+	}
+	if cancelled, err := svc.Cancel(ctx, ticket.ID, pending.ID, agent.ID, string(models.RoleAgent)); err != nil || cancelled.Status != models.TicketReminderStatusCancelled {
+		t.Fatalf("cancel failed: %+v, %v", cancelled, err)
+	}
+	if _, err := svc.Cancel(ctx, ticket.ID, pending.ID, agent.ID, string(models.RoleAgent)); !errors.Is(err, ErrReminderFinished) {
+		t.Fatalf("expected second cancel to conflict, got %v", err)
+	}
+}
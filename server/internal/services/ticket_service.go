@@ -14,9 +14,13 @@ import (
 	"gorm.io/gorm"
 )
 
+// ErrTicketNotFound is returned when the requested ticket does not exist
+var ErrTicketNotFound = errors.New("ticket not found")
+
 // TicketServiceInterface defines the interface for ticket service
 type TicketServiceInterface interface {
 	GetTickets(ctx context.Context, filters TicketFilters) ([]*models.Ticket, int64, error)
+	QueryTickets(ctx context.Context, req *models.TicketQueryRequest) ([]*models.Ticket, int64, error)
 	GetTicket(ctx context.Context, id uint) (*models.Ticket, error)
 	CreateTicket(ctx context.Context, req *models.TicketCreateRequest, userID uint) (*models.Ticket, error)
 	UpdateTicket(ctx context.Context, id uint, req *models.TicketUpdateRequest, userID uint) (*models.Ticket, error)
@@ -24,24 +28,38 @@ type TicketServiceInterface interface {
 	AssignTicket(ticketID uint, assigneeID uint, userID uint, comment string) (*models.Ticket, error)
 	TransferTicket(ticketID uint, assigneeID uint, userID uint, comment string, transferReason string) (*models.Ticket, error)
 	EscalateTicket(ticketID uint, escalateToID uint, userID uint, reason string, comment string) (*models.Ticket, error)
-	UpdateTicketStatus(ticketID uint, status string, userID uint, comment string, resolutionNotes string) (*models.Ticket, error)
+	UpdateTicketStatus(ticketID uint, status string, userID uint, comment string, resolutionNotes string, resolutionCode string) (*models.Ticket, error)
 	GetTicketStatistics(userID uint, role string) (*TicketStatisticsResponse, error)
 	GetUserTickets(userID uint, status string, priority string, limit int) ([]*models.Ticket, int64, error)
-	GetUnassignedTickets(priority string, categoryID string, limit int) ([]*models.Ticket, int64, error)
+	GetUnassignedTickets(ctx context.Context, priority string, categoryID string, limit int) ([]*models.Ticket, int64, error)
 	GetOverdueTickets(userID uint, role string) ([]*models.Ticket, int64, error)
 	GetSLABreachedTickets(userID uint, role string) ([]*models.Ticket, int64, error)
 	BulkAssignTickets(ticketIDs []uint, assigneeID uint, userID uint, comment string) (*BulkOperationResult, error)
-	BulkUpdateStatus(ticketIDs []uint, status string, userID uint, comment string) (*BulkOperationResult, error)
+	BulkUpdateStatus(ticketIDs []uint, status string, userID uint, comment string, resolutionCode string) (*BulkOperationResult, error)
 	GetTicketStats(ctx context.Context, userID uint) (*TicketStats, error)
 	BulkUpdateTickets(ctx context.Context, req *BulkUpdateRequest, userID uint) error
 	GetTicketHistory(ticketID uint) ([]*models.TicketHistory, int64, error)
 	CreateTicketHistory(ctx context.Context, req *models.TicketHistoryCreateRequest, userID *uint) error
+	ClaimTicket(ctx context.Context, ticketID uint, userID uint, userRole string) (*models.Ticket, error)
+	SetPushChannel(channel PushChannel)
+	SetScriptHooks(hooks *ScriptHookService)
+	SetClassifier(classifier *TicketClassificationService)
+	SetPIIScanner(scanner *PIIScanService)
 }
 
 // TicketService implements TicketServiceInterface
 type TicketService struct {
 	db                  *gorm.DB
 	notificationService NotificationServiceInterface
+	searchService       *SearchConfigService
+	delegationService   *DelegationService
+	checklistService    *TicketChecklistService
+	quotaService        *QuotaService
+	resolutionCodes     *ResolutionCodeService
+	paginationGuard     *PaginationGuard
+	scriptHooks         *ScriptHookService
+	classifier          *TicketClassificationService
+	piiScanner          *PIIScanService
 }
 
 // NewTicketService creates a new ticket service
@@ -49,22 +67,51 @@ func NewTicketService(db *gorm.DB) TicketServiceInterface {
 	return &TicketService{
 		db:                  db,
 		notificationService: NewNotificationService(db),
+		searchService:       NewSearchConfigService(db),
+		delegationService:   NewDelegationService(db),
+		checklistService:    NewTicketChecklistService(db),
+		quotaService:        NewQuotaService(db),
+		resolutionCodes:     NewResolutionCodeService(db),
+		paginationGuard:     NewPaginationGuard(db),
 	}
 }
 
+// SetPushChannel sets the browser push channel used for ticket notifications
+func (s *TicketService) SetPushChannel(channel PushChannel) {
+	s.notificationService.SetPushChannel(channel)
+}
+
+// SetScriptHooks sets the script hooks run after tickets are created or updated and before their notifications are sent
+func (s *TicketService) SetScriptHooks(hooks *ScriptHookService) {
+	s.scriptHooks = hooks
+	s.notificationService.SetScriptHooks(hooks)
+}
+
+// SetClassifier sets the classifier that classifies newly created tickets in the background
+func (s *TicketService) SetClassifier(classifier *TicketClassificationService) {
+	s.classifier = classifier
+}
+
+// SetPIIScanner sets the scanner that checks ticket descriptions for sensitive data before they are saved
+func (s *TicketService) SetPIIScanner(scanner *PIIScanService) {
+	s.piiScanner = scanner
+}
+
 // TicketFilters represents filters for ticket queries
 type TicketFilters struct {
-	Status     string
-	Priority   string
-	Type       string
-	Tags       []string
-	AssigneeID *uint
-	CreatorID  *uint
-	Search     string
-	Page       int
-	Limit      int
-	SortBy     string
-	SortOrder  string
+	Status       string
+	Priority     string
+	Type         string
+	Tags         []string
+	CustomFields map[string]interface{} // 自定义字段包含过滤，工单的 custom_fields 需包含全部键值
+	AssigneeID   *uint
+	CreatorID    *uint
+	TeamID       *uint
+	Unassigned   bool
+	Search       string
+	Page         int
+	Limit        int
+	Sort         []models.SortField // 为空时按创建时间倒序
 }
 
 // TicketStats represents ticket statistics
@@ -107,12 +154,13 @@ type BulkOperationResult struct {
 
 // BulkUpdateRequest represents bulk update request
 type BulkUpdateRequest struct {
-	TicketIDs    []uint                 `json:"ticket_ids"`
-	Status       *string                `json:"status,omitempty"`
-	Priority     *string                `json:"priority,omitempty"`
-	AssignedToID *uint                  `json:"assigned_to_id,omitempty"`
-	Tags         []string               `json:"tags,omitempty"`
-	CustomFields map[string]interface{} `json:"custom_fields,omitempty"`
+	TicketIDs      []uint                 `json:"ticket_ids"`
+	Status         *string                `json:"status,omitempty"`
+	Priority       *string                `json:"priority,omitempty"`
+	AssignedToID   *uint                  `json:"assigned_to_id,omitempty"`
+	Tags           []string               `json:"tags,omitempty"`
+	CustomFields   map[string]interface{} `json:"custom_fields,omitempty"`
+	ResolutionCode *string                `json:"resolution_code,omitempty"`
 }
 
 // GetTickets retrieves tickets with filters
@@ -120,7 +168,7 @@ func (s *TicketService) GetTickets(ctx context.Context, filters TicketFilters) (
 	var tickets []*models.Ticket
 	var total int64
 
-	query := s.db.WithContext(ctx).Model(&models.Ticket{})
+	query := CategoryScopeFrom(ctx).Apply(s.db.WithContext(ctx).Model(&models.Ticket{}))
 
 	// Apply filters
 	if filters.Status != "" {
@@ -148,17 +196,41 @@ func (s *TicketService) GetTickets(ctx context.Context, filters TicketFilters) (
 	if filters.CreatorID != nil {
 		query = query.Where("created_by_id = ?", *filters.CreatorID)
 	}
+	if filters.TeamID != nil {
+		query = query.Where("assigned_team_id = ?", *filters.TeamID)
+	}
+	if filters.Unassigned {
+		query = query.Where("assigned_to_id IS NULL")
+	}
 	if filters.Search != "" {
-		query = query.Where("title ILIKE ? OR description ILIKE ?", "%"+filters.Search+"%", "%"+filters.Search+"%")
+		searchService := s.searchService
+		if searchService == nil {
+			searchService = NewSearchConfigService(s.db)
+		}
+		condition, args := searchService.BuildTicketSearchCondition(ctx, filters.Search)
+		query = query.Where(condition, args...)
 	}
 	if len(filters.Tags) > 0 {
+		tags := make([]string, 0, len(filters.Tags))
 		for _, tag := range filters.Tags {
-			trimmed := strings.TrimSpace(tag)
-			if trimmed == "" {
-				continue
+			if trimmed := strings.TrimSpace(tag); trimmed != "" {
+				tags = append(tags, trimmed)
+			}
+		}
+		if len(tags) > 0 {
+			condition, args, err := JSONContainsCondition(s.db, "tags", tags)
+			if err != nil {
+				return nil, 0, err
 			}
-			query = query.Where("tags::jsonb ? ?", trimmed)
+			query = query.Where(condition, args...)
+		}
+	}
+	if len(filters.CustomFields) > 0 {
+		condition, args, err := JSONContainsCondition(s.db, "custom_fields", filters.CustomFields)
+		if err != nil {
+			return nil, 0, err
 		}
+		query = query.Where(condition, args...)
 	}
 
 	// Count total
@@ -172,19 +244,14 @@ func (s *TicketService) GetTickets(ctx context.Context, filters TicketFilters) (
 		query = query.Offset(offset).Limit(filters.Limit)
 	}
 
-	// Apply sorting
-	sortBy := "created_at"
-	sortOrder := "DESC"
-	if filters.SortBy != "" {
-		sortBy = filters.SortBy
-	}
-	if filters.SortOrder != "" {
-		sortOrder = filters.SortOrder
+	// Apply sorting, only whitelisted fields
+	query, err := ticketSortRegistry.Apply(query, filters.Sort)
+	if err != nil {
+		return nil, 0, err
 	}
-	query = query.Order(fmt.Sprintf("%s %s", sortBy, sortOrder))
 
 	// Preload associations
-	query = query.Preload("CreatedBy").Preload("AssignedTo").Preload("Comments")
+	query = query.Preload("CreatedBy").Preload("AssignedTo").Preload("AssignedTeam").Preload("Comments")
 
 	if err := query.Find(&tickets).Error; err != nil {
 		return nil, 0, fmt.Errorf("failed to get tickets: %w", err)
@@ -212,13 +279,14 @@ func (s *TicketService) GetTicket(ctx context.Context, id uint) (*models.Ticket,
 	err := s.db.WithContext(ctx).
 		Preload("CreatedBy").
 		Preload("AssignedTo").
+		Preload("AssignedTeam").
 		Preload("Comments").
 		Preload("Comments.User").
 		First(&ticket, id).Error
 
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, fmt.Errorf("ticket not found")
+			return nil, ErrTicketNotFound
 		}
 		return nil, fmt.Errorf("failed to get ticket: %w", err)
 	}
@@ -228,18 +296,22 @@ func (s *TicketService) GetTicket(ctx context.Context, id uint) (*models.Ticket,
 
 // CreateTicket creates a new ticket
 func (s *TicketService) CreateTicket(ctx context.Context, req *models.TicketCreateRequest, userID uint) (*models.Ticket, error) {
+	if err := s.quotaService.CheckTickets(ctx, 1); err != nil {
+		return nil, err
+	}
+
 	// Convert tags to JSON string
-	tagsJSON := ""
+	var tagsJSON models.JSONText
 	if len(req.Tags) > 0 {
 		tagsBytes, _ := json.Marshal(req.Tags)
-		tagsJSON = string(tagsBytes)
+		tagsJSON = models.JSONText(tagsBytes)
 	}
 
 	// Convert custom fields to JSON string
-	customFieldsJSON := ""
+	var customFieldsJSON models.JSONText
 	if req.CustomFields != nil {
 		customFieldsBytes, _ := json.Marshal(req.CustomFields)
-		customFieldsJSON = string(customFieldsBytes)
+		customFieldsJSON = models.JSONText(customFieldsBytes)
 	}
 
 	// Generate unique ticket number
@@ -253,21 +325,23 @@ func (s *TicketService) CreateTicket(ctx context.Context, req *models.TicketCrea
 	now := time.Now()
 
 	ticket := &models.Ticket{
-		TicketNumber:  ticketNumber,
-		Title:         req.Title,
-		Description:   req.Description,
-		Status:        status,
-		Priority:      req.Priority,
-		Type:          req.Type,
-		Source:        req.Source,
-		CreatedByID:   userID,
-		Tags:          tagsJSON,
-		CustomFields:  customFieldsJSON,
-		CustomerEmail: req.CustomerEmail,
-		CustomerPhone: req.CustomerPhone,
-		CustomerName:  req.CustomerName,
-		CreatedAt:     now,
-		UpdatedAt:     now,
+		TicketNumber:   ticketNumber,
+		Title:          req.Title,
+		Description:    req.Description,
+		Status:         status,
+		Priority:       req.Priority,
+		Type:           req.Type,
+		Source:         req.Source,
+		CreatedByID:    userID,
+		Tags:           tagsJSON,
+		CustomFields:   customFieldsJSON,
+		CustomerEmail:  req.CustomerEmail,
+		CustomerPhone:  req.CustomerPhone,
+		CustomerName:   req.CustomerName,
+		SpamScore:      req.SpamScore,
+		IsConfidential: req.IsConfidential,
+		CreatedAt:      now,
+		UpdatedAt:      now,
 	}
 
 	if status == models.TicketStatusResolved && ticket.ResolvedAt == nil {
@@ -282,6 +356,32 @@ func (s *TicketService) CreateTicket(ctx context.Context, req *models.TicketCrea
 		ticket.AssignedToID = req.AssignedToID
 	}
 
+	// Set team queue if provided
+	if req.AssignedTeamID != nil {
+		if err := s.ensureActiveTeam(ctx, *req.AssignedTeamID); err != nil {
+			return nil, err
+		}
+		ticket.AssignedTeamID = req.AssignedTeamID
+	}
+
+	// 处理人休假委托生效时改派给代理人或团队队列
+	var delegationRoute *DelegationRoute
+	var delegatedFromID uint
+	if ticket.AssignedToID != nil {
+		route, err := s.delegationService.Route(ctx, *ticket.AssignedToID, now)
+		if err != nil {
+			return nil, err
+		}
+		if route != nil {
+			delegatedFromID = *ticket.AssignedToID
+			ticket.AssignedToID = route.UserID
+			if route.TeamID != nil {
+				ticket.AssignedTeamID = route.TeamID
+			}
+			delegationRoute = route
+		}
+	}
+
 	// Set category if provided
 	if req.CategoryID != nil {
 		ticket.CategoryID = req.CategoryID
@@ -297,16 +397,81 @@ func (s *TicketService) CreateTicket(ctx context.Context, req *models.TicketCrea
 		ticket.DueDate = req.DueDate
 	}
 
+	// 敏感信息检测：拒绝保存时直接返回，脱敏时只保存脱敏后的描述
+	piiResult, err := scanPIIContent(ctx, s.piiScanner, ticket.CategoryID, ticket.Description)
+	if err != nil {
+		return nil, err
+	}
+	if piiResult != nil && piiResult.Action == models.PIIActionMask {
+		ticket.Description = piiResult.Masked
+	}
+
+	// 影响×紧急程度：矩阵启用时由矩阵决定优先级
+	if req.Impact != nil || req.Urgency != nil {
+		if req.Impact != nil {
+			ticket.Impact = *req.Impact
+		}
+		if req.Urgency != nil {
+			ticket.Urgency = *req.Urgency
+		}
+		priority, ok, err := NewPriorityMatrixService(s.db).ResolvePriority(ctx, ticket.Impact, ticket.Urgency)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			ticket.Priority = priority
+		}
+	}
+
 	if err := s.db.WithContext(ctx).Create(ticket).Error; err != nil {
 		return nil, fmt.Errorf("failed to create ticket: %w", err)
 	}
 
+	if delegationRoute != nil {
+		history := delegationHistory(ticket.ID, models.HistoryActionAssign, delegationRoute.Description(delegatedFromID),
+			getAssigneeValue(&delegatedFromID), getAssigneeValue(delegationRoute.UserID))
+		if err := s.db.WithContext(ctx).Create(history).Error; err != nil {
+			fmt.Printf("Failed to record delegation history for ticket %d: %v\n", ticket.ID, err)
+		}
+	}
+
+	if err := s.quotaService.NotifyThresholds(ctx, models.QuotaResourceMonthlyTickets, now); err != nil {
+		fmt.Printf("Failed to check ticket quota thresholds: %v\n", err)
+	}
+
+	if err := recordPIIRedaction(ctx, nil, s.piiScanner, ticket.ID, nil, userID, piiResult); err != nil {
+		fmt.Printf("Failed to record redaction history for ticket %d: %v\n", ticket.ID, err)
+	}
+
+	if req.PrefillToken != "" {
+		NewPrefillLinkService(s.db).RecordTicket(ctx, req.PrefillToken)
+	}
+
+	// 脚本钩子的动作直接写入数据库，重新加载后返回
+	if hooks := s.scriptHooks; hooks != nil {
+		hooks.RunTicketHooks(ctx, models.ScriptHookTicketCreated, ticket, nil)
+	}
+
+	if classifier := s.classifier; classifier != nil {
+		classifier.ClassifyAsync(ticket.ID)
+	}
+
 	// Reload with associations
-	return s.GetTicket(ctx, ticket.ID)
+	created, err := s.GetTicket(ctx, ticket.ID)
+	if err != nil {
+		return nil, err
+	}
+	created.PIIScan = piiResult.Notice()
+	return created, nil
 }
 
 // UpdateTicket updates an existing ticket
 func (s *TicketService) UpdateTicket(ctx context.Context, id uint, req *models.TicketUpdateRequest, userID uint) (*models.Ticket, error) {
+	return s.updateTicket(ctx, id, req, userID, nil)
+}
+
+// updateTicket 应用工单变更；inTx 非空时在同一事务内执行，用于审核通过的变更提案等需原子提交的场景
+func (s *TicketService) updateTicket(ctx context.Context, id uint, req *models.TicketUpdateRequest, userID uint, inTx func(tx *gorm.DB, ticket *models.Ticket) error) (*models.Ticket, error) {
 	// 获取原工单信息用于比较
 	originalTicket, err := s.GetTicket(ctx, id)
 	if err != nil {
@@ -330,19 +495,52 @@ func (s *TicketService) UpdateTicket(ctx context.Context, id uint, req *models.T
 		ticket.Title = *req.Title
 	}
 
+	var piiResult *models.PIIScanResult
 	if req.Description != nil && *req.Description != ticket.Description {
+		description := *req.Description
+		if piiResult, err = scanPIIContent(ctx, s.piiScanner, ticket.CategoryID, description); err != nil {
+			return nil, err
+		}
+		if piiResult != nil && piiResult.Action == models.PIIActionMask {
+			description = piiResult.Masked
+		}
 		historyRecords = append(historyRecords, &models.TicketHistoryCreateRequest{
 			TicketID:    id,
 			Action:      models.HistoryActionUpdate,
 			Description: "描述已更新",
 			FieldName:   "description",
 			OldValue:    truncateString(ticket.Description, 50),
-			NewValue:    truncateString(*req.Description, 50),
+			NewValue:    truncateString(description, 50),
+		})
+		ticket.Description = description
+	}
+
+	newStatus := ticket.Status
+	if req.Status != nil {
+		newStatus = *req.Status
+	}
+	resolutionCode, err := s.nextResolutionCode(ctx, &ticket, newStatus, req.ResolutionCode)
+	if err != nil {
+		return nil, err
+	}
+	if resolutionCode != ticket.ResolutionCode {
+		historyRecords = append(historyRecords, &models.TicketHistoryCreateRequest{
+			TicketID:    id,
+			Action:      models.HistoryActionUpdate,
+			Description: fmt.Sprintf("解决代码从「%s」变更为「%s」", ticket.ResolutionCode, resolutionCode),
+			FieldName:   "resolution_code",
+			OldValue:    ticket.ResolutionCode,
+			NewValue:    resolutionCode,
 		})
-		ticket.Description = *req.Description
+		ticket.ResolutionCode = resolutionCode
 	}
 
 	if req.Status != nil && models.TicketStatus(*req.Status) != ticket.Status {
+		if *req.Status == models.TicketStatusResolved {
+			if err := s.checklistService.EnsureResolvable(ctx, id); err != nil {
+				return nil, err
+			}
+		}
 		oldStatus := string(ticket.Status)
 		newStatus := string(*req.Status)
 		historyRecords = append(historyRecords, &models.TicketHistoryCreateRequest{
@@ -358,12 +556,45 @@ func (s *TicketService) UpdateTicket(ctx context.Context, id uint, req *models.T
 
 		// 设置特殊时间戳
 		now := time.Now()
-		if *req.Status == "resolved" && ticket.ResolvedAt == nil {
+		// 每次进入已解决都刷新解决时间，重新打开后再次解决时自动关闭重新计时
+		if *req.Status == "resolved" {
 			ticket.ResolvedAt = &now
 		}
 		if *req.Status == "closed" && ticket.ClosedAt == nil {
 			ticket.ClosedAt = &now
 		}
+		// 状态变更后重新计算自动关闭提醒
+		ticket.AutoCloseReminderAt = nil
+	}
+
+	if req.Impact != nil || req.Urgency != nil {
+		newImpact, newUrgency := ticket.Impact, ticket.Urgency
+		if req.Impact != nil {
+			newImpact = *req.Impact
+		}
+		if req.Urgency != nil {
+			newUrgency = *req.Urgency
+		}
+		priority, ok, err := NewPriorityMatrixService(s.db).ResolvePriority(ctx, newImpact, newUrgency)
+		if err != nil {
+			return nil, err
+		}
+		if newImpact != ticket.Impact || newUrgency != ticket.Urgency {
+			historyRecords = append(historyRecords, &models.TicketHistoryCreateRequest{
+				TicketID:    id,
+				Action:      models.HistoryActionUpdate,
+				Description: fmt.Sprintf("影响/紧急程度从「%s/%s」变更为「%s/%s」", ticket.Impact, ticket.Urgency, newImpact, newUrgency),
+				FieldName:   "impact_urgency",
+				OldValue:    fmt.Sprintf("%s/%s", ticket.Impact, ticket.Urgency),
+				NewValue:    fmt.Sprintf("%s/%s", newImpact, newUrgency),
+			})
+			ticket.Impact = newImpact
+			ticket.Urgency = newUrgency
+		}
+		// 矩阵启用时，优先级由矩阵计算结果决定
+		if ok {
+			req.Priority = &priority
+		}
 	}
 
 	if req.Priority != nil && models.TicketPriority(*req.Priority) != ticket.Priority {
@@ -409,6 +640,36 @@ func (s *TicketService) UpdateTicket(ctx context.Context, id uint, req *models.T
 		ticket.Source = models.TicketSource(*req.Source)
 	}
 
+	// 处理人休假委托生效时改派给代理人或团队队列
+	if req.AssignedToID != nil && getAssigneeValue(ticket.AssignedToID) != getAssigneeValue(req.AssignedToID) {
+		route, err := s.delegationService.Route(ctx, *req.AssignedToID, time.Now())
+		if err != nil {
+			return nil, err
+		}
+		if route != nil {
+			historyRecords = append(historyRecords, &models.TicketHistoryCreateRequest{
+				TicketID:    id,
+				Action:      models.HistoryActionAssign,
+				Description: route.Description(*req.AssignedToID),
+				FieldName:   "assigned_to_id",
+				OldValue:    getAssigneeValue(req.AssignedToID),
+				NewValue:    getAssigneeValue(route.UserID),
+				IsImportant: getBoolPtr(true),
+			})
+			rerouted := *req
+			rerouted.AssignedToID = route.UserID
+			if route.TeamID != nil {
+				rerouted.AssignedTeamID = route.TeamID
+			}
+			req = &rerouted
+			// 改派到团队队列时清除原处理人，由团队成员认领
+			if route.UserID == nil {
+				ticket.AssignedToID = nil
+				ticket.AssignedTo = nil
+			}
+		}
+	}
+
 	// 处理分配变更
 	if req.AssignedToID != nil {
 		oldAssigneeID := ticket.AssignedToID
@@ -452,16 +713,86 @@ func (s *TicketService) UpdateTicket(ctx context.Context, id uint, req *models.T
 		ticket.AssignedToID = req.AssignedToID
 	}
 
+	// 团队队列变更，传 0 表示移出团队队列
+	if req.AssignedTeamID != nil {
+		var newTeamID *uint
+		if *req.AssignedTeamID != 0 {
+			if err := s.ensureActiveTeam(ctx, *req.AssignedTeamID); err != nil {
+				return nil, err
+			}
+			newTeamID = req.AssignedTeamID
+		}
+		if getAssigneeValue(ticket.AssignedTeamID) != getAssigneeValue(newTeamID) {
+			historyRecords = append(historyRecords, &models.TicketHistoryCreateRequest{
+				TicketID:    id,
+				Action:      models.HistoryActionAssign,
+				Description: fmt.Sprintf("团队队列从「%s」变更为「%s」", getAssigneeValue(ticket.AssignedTeamID), getAssigneeValue(newTeamID)),
+				FieldName:   "assigned_team_id",
+				OldValue:    getAssigneeValue(ticket.AssignedTeamID),
+				NewValue:    getAssigneeValue(newTeamID),
+			})
+			ticket.AssignedTeamID = newTeamID
+			ticket.AssignedTeam = nil
+		}
+	}
+
 	if req.DueDate != nil {
 		ticket.DueDate = req.DueDate
 	}
+	if req.SLADueDate != nil && getTimeValue(ticket.SLADueDate) != getTimeValue(req.SLADueDate) {
+		historyRecords = append(historyRecords, &models.TicketHistoryCreateRequest{
+			TicketID:    id,
+			Action:      models.HistoryActionUpdate,
+			Description: "SLA截止时间已手动调整",
+			FieldName:   "sla_due_date",
+			OldValue:    getTimeValue(ticket.SLADueDate),
+			NewValue:    getTimeValue(req.SLADueDate),
+			IsImportant: getBoolPtr(true),
+		})
+		ticket.SLADueDate = req.SLADueDate
+	}
+	for _, field := range []struct {
+		name    string
+		label   string
+		current *string
+		value   *string
+	}{
+		{"customer_email", "客户邮箱", &ticket.CustomerEmail, req.CustomerEmail},
+		{"customer_phone", "客户电话", &ticket.CustomerPhone, req.CustomerPhone},
+		{"customer_name", "客户姓名", &ticket.CustomerName, req.CustomerName},
+	} {
+		if field.value == nil || *field.value == *field.current {
+			continue
+		}
+		historyRecords = append(historyRecords, &models.TicketHistoryCreateRequest{
+			TicketID:    id,
+			Action:      models.HistoryActionUpdate,
+			Description: field.label + "已更新",
+			FieldName:   field.name,
+			OldValue:    *field.current,
+			NewValue:    *field.value,
+		})
+		*field.current = *field.value
+	}
 	if req.Tags != nil {
 		tagsBytes, _ := json.Marshal(req.Tags)
-		ticket.Tags = string(tagsBytes)
+		ticket.Tags = models.JSONText(tagsBytes)
 	}
 	if req.CustomFields != nil {
 		customFieldsBytes, _ := json.Marshal(req.CustomFields)
-		ticket.CustomFields = string(customFieldsBytes)
+		ticket.CustomFields = models.JSONText(customFieldsBytes)
+	}
+	if req.IsConfidential != nil && *req.IsConfidential != ticket.IsConfidential {
+		historyRecords = append(historyRecords, &models.TicketHistoryCreateRequest{
+			TicketID:    id,
+			Action:      models.HistoryActionUpdate,
+			Description: fmt.Sprintf("保密标记从「%t」变更为「%t」", ticket.IsConfidential, *req.IsConfidential),
+			FieldName:   "is_confidential",
+			OldValue:    fmt.Sprintf("%t", ticket.IsConfidential),
+			NewValue:    fmt.Sprintf("%t", *req.IsConfidential),
+			IsImportant: getBoolPtr(true),
+		})
+		ticket.IsConfidential = *req.IsConfidential
 	}
 
 	ticket.UpdatedAt = time.Now()
@@ -473,9 +804,10 @@ func (s *TicketService) UpdateTicket(ctx context.Context, id uint, req *models.T
 			return fmt.Errorf("failed to update ticket: %w", err)
 		}
 
-		// 创建历史记录
+		// 创建历史记录（多行 INSERT 一次写入）
+		batch := NewHistoryBatch()
 		for _, historyReq := range historyRecords {
-			history := &models.TicketHistory{
+			batch.Add(&models.TicketHistory{
 				TicketID:    historyReq.TicketID,
 				UserID:      &userID,
 				Action:      historyReq.Action,
@@ -487,13 +819,18 @@ func (s *TicketService) UpdateTicket(ctx context.Context, id uint, req *models.T
 				IsSystem:    false,
 				IsAutomated: false,
 				IsImportant: historyReq.IsImportant != nil && *historyReq.IsImportant,
-			}
-
-			if err := tx.Create(history).Error; err != nil {
-				return fmt.Errorf("failed to create history record: %w", err)
-			}
+			})
+		}
+		if err := batch.Flush(tx); err != nil {
+			return err
+		}
+		if err := recordPIIRedaction(ctx, tx, s.piiScanner, id, nil, userID, piiResult); err != nil {
+			return err
 		}
 
+		if inTx != nil {
+			return inTx(tx, &ticket)
+		}
 		return nil
 	})
 
@@ -501,8 +838,15 @@ func (s *TicketService) UpdateTicket(ctx context.Context, id uint, req *models.T
 		return nil, err
 	}
 
+	// 计算字段变更，供字段级Webhook订阅使用
+	changes := models.DiffTicketFields(originalTicket, &ticket)
+
 	// 发送通知
 	go func() {
+		if len(changes) > 0 {
+			s.sendTicketUpdatedWebhook(&ticket, changes, userID)
+		}
+
 		// 检查是否有状态变更需要发送通知
 		if req.Status != nil && models.TicketStatus(*req.Status) != originalTicket.Status {
 			if err := s.notificationService.NotifyTicketStatusChanged(ctx, &ticket, originalTicket.Status, userID); err != nil {
@@ -522,9 +866,135 @@ func (s *TicketService) UpdateTicket(ctx context.Context, id uint, req *models.T
 		}
 	}()
 
+	if ticket.Status == models.TicketStatusResolved && originalTicket.Status != models.TicketStatusResolved {
+		s.runResolvedRules(ctx, &ticket)
+	}
+
+	// 脚本钩子修改了工单时返回最新数据
+	ticket.PIIScan = piiResult.Notice()
+	if hooks := s.scriptHooks; hooks != nil && len(changes) > 0 {
+		if hooks.RunTicketHooks(ctx, models.ScriptHookTicketUpdated, &ticket, changes) {
+			if updated, err := s.GetTicket(ctx, id); err == nil {
+				updated.PIIScan = ticket.PIIScan
+				return updated, nil
+			}
+		}
+	}
+
 	return &ticket, nil
 }
 
+// sendTicketUpdatedWebhook 发送携带字段变更（before/after）的 ticket.updated Webhook 事件
+func (s *TicketService) sendTicketUpdatedWebhook(ticket *models.Ticket, changes []models.TicketFieldChange, userID uint) {
+	fields := make([]string, 0, len(changes))
+	for _, change := range changes {
+		fields = append(fields, change.Field)
+	}
+
+	event := &NotificationEvent{
+		Type:         models.WebhookEventTicketUpdated,
+		ResourceID:   ticket.ID,
+		ResourceType: "ticket",
+		Title:        fmt.Sprintf("工单更新: %s", ticket.Title),
+		Description:  fmt.Sprintf("工单字段已更新: %s", strings.Join(fields, ", ")),
+		Data: map[string]interface{}{
+			"ticket_number": ticket.TicketNumber,
+			"title":         ticket.Title,
+			"status":        ticket.Status,
+			"priority":      ticket.Priority,
+			"changes":       changes,
+		},
+		Metadata: map[string]string{
+			"action": "update",
+		},
+		Timestamp: time.Now(),
+		UserID:    &userID,
+		Changes:   changes,
+	}
+
+	if err := s.notificationService.SendNotification(context.Background(), event); err != nil {
+		fmt.Printf("Failed to send ticket updated webhook: %v\n", err)
+	}
+}
+
+// ClaimTicket 认领团队队列中的工单：仅当工单尚未分配时原子地分配给调用者
+func (s *TicketService) ClaimTicket(ctx context.Context, ticketID uint, userID uint, userRole string) (*models.Ticket, error) {
+	ticket, err := s.GetTicket(ctx, ticketID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 团队队列中的工单只能由团队成员（或管理员/主管）认领
+	if ticket.AssignedTeamID != nil && userRole != "admin" && userRole != "superuser" && userRole != "supervisor" {
+		var count int64
+		if err := s.db.WithContext(ctx).Table("team_members").
+			Where("team_id = ? AND user_id = ?", *ticket.AssignedTeamID, userID).
+			Count(&count).Error; err != nil {
+			return nil, fmt.Errorf("failed to check team membership: %w", err)
+		}
+		if count == 0 {
+			return nil, ErrNotTeamMember
+		}
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.Ticket{}).
+			Where("id = ? AND assigned_to_id IS NULL", ticketID).
+			Updates(map[string]interface{}{
+				"assigned_to_id": userID,
+				"updated_at":     time.Now(),
+			})
+		if result.Error != nil {
+			return fmt.Errorf("failed to claim ticket: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrTicketAlreadyClaimed
+		}
+
+		history := &models.TicketHistory{
+			TicketID:    ticketID,
+			UserID:      &userID,
+			Action:      models.HistoryActionAssign,
+			Description: fmt.Sprintf("用户 ID: %d 从团队队列认领了工单", userID),
+			FieldName:   "assigned_to_id",
+			OldValue:    "未分配",
+			NewValue:    fmt.Sprintf("%d", userID),
+			IsVisible:   true,
+			IsImportant: true,
+		}
+		return tx.Create(history).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetTicket(ctx, ticketID)
+}
+
+// applyDelegationRoute 按休假委托改派工单处理人或团队队列
+func (s *TicketService) applyDelegationRoute(ticket *models.Ticket, route *DelegationRoute) {
+	ticket.AssignedToID = route.UserID
+	ticket.AssignedTo = nil
+	if route.TeamID != nil {
+		ticket.AssignedTeamID = route.TeamID
+		ticket.AssignedTeam = nil
+	}
+}
+
+// ensureActiveTeam 检查团队存在且处于启用状态
+func (s *TicketService) ensureActiveTeam(ctx context.Context, teamID uint) error {
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.Team{}).
+		Where("id = ? AND is_active = ?", teamID, true).
+		Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check team: %w", err)
+	}
+	if count == 0 {
+		return ErrTeamNotFound
+	}
+	return nil
+}
+
 // AssignTicket assigns a ticket to a user with workflow support
 func (s *TicketService) AssignTicket(ticketID uint, assigneeID uint, userID uint, comment string) (*models.Ticket, error) {
 	ticket, err := s.GetTicket(context.Background(), ticketID)
@@ -532,8 +1002,16 @@ func (s *TicketService) AssignTicket(ticketID uint, assigneeID uint, userID uint
 		return nil, err
 	}
 
+	route, err := s.delegationService.Route(context.Background(), assigneeID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
 	oldAssigneeID := ticket.AssignedToID
 	ticket.AssignedToID = &assigneeID
+	if route != nil {
+		s.applyDelegationRoute(ticket, route)
+	}
 	ticket.UpdatedAt = time.Now()
 
 	err = s.db.Transaction(func(tx *gorm.DB) error {
@@ -551,6 +1029,10 @@ func (s *TicketService) AssignTicket(ticketID uint, assigneeID uint, userID uint
 			IsImportant: getBoolPtr(true),
 		}
 
+		if route != nil {
+			historyReq.Description = route.Description(assigneeID)
+			historyReq.NewValue = getAssigneeValue(route.UserID)
+		}
 		if comment != "" {
 			historyReq.Description += fmt.Sprintf(" - %s", comment)
 		}
@@ -592,8 +1074,16 @@ func (s *TicketService) TransferTicket(ticketID uint, assigneeID uint, userID ui
 		return nil, err
 	}
 
+	route, err := s.delegationService.Route(context.Background(), assigneeID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
 	oldAssigneeID := ticket.AssignedToID
 	ticket.AssignedToID = &assigneeID
+	if route != nil {
+		s.applyDelegationRoute(ticket, route)
+	}
 	ticket.UpdatedAt = time.Now()
 
 	err = s.db.Transaction(func(tx *gorm.DB) error {
@@ -602,6 +1092,9 @@ func (s *TicketService) TransferTicket(ticketID uint, assigneeID uint, userID ui
 		}
 
 		description := fmt.Sprintf("工单从用户 ID: %s 转移给用户 ID: %d", getAssigneeValue(oldAssigneeID), assigneeID)
+		if route != nil {
+			description += "；" + route.Description(assigneeID)
+		}
 		if transferReason != "" {
 			description += fmt.Sprintf(" (原因: %s)", transferReason)
 		}
@@ -689,23 +1182,43 @@ func (s *TicketService) EscalateTicket(ticketID uint, escalateToID uint, userID
 }
 
 // UpdateTicketStatus updates ticket status with workflow support
-func (s *TicketService) UpdateTicketStatus(ticketID uint, status string, userID uint, comment string, resolutionNotes string) (*models.Ticket, error) {
+func (s *TicketService) UpdateTicketStatus(ticketID uint, status string, userID uint, comment string, resolutionNotes string, resolutionCode string) (*models.Ticket, error) {
 	ticket, err := s.GetTicket(context.Background(), ticketID)
 	if err != nil {
 		return nil, err
 	}
 
+	var code *string
+	if resolutionCode != "" {
+		code = &resolutionCode
+	}
+	nextCode, err := s.nextResolutionCode(context.Background(), ticket, models.TicketStatus(status), code)
+	if err != nil {
+		return nil, err
+	}
+
+	if status == string(models.TicketStatusResolved) && ticket.Status != models.TicketStatusResolved {
+		if err := s.checklistService.EnsureResolvable(context.Background(), ticketID); err != nil {
+			return nil, err
+		}
+	}
+
 	oldStatus := ticket.Status
+	oldCode := ticket.ResolutionCode
 	ticket.Status = models.TicketStatus(status)
+	ticket.ResolutionCode = nextCode
 	ticket.UpdatedAt = time.Now()
 
 	now := time.Now()
-	if status == "resolved" && ticket.ResolvedAt == nil {
+	if status == "resolved" && oldStatus != models.TicketStatusResolved {
 		ticket.ResolvedAt = &now
 	}
 	if status == "closed" && ticket.ClosedAt == nil {
 		ticket.ClosedAt = &now
 	}
+	if oldStatus != ticket.Status {
+		ticket.AutoCloseReminderAt = nil
+	}
 
 	err = s.db.Transaction(func(tx *gorm.DB) error {
 		if err := tx.Save(ticket).Error; err != nil {
@@ -719,6 +1232,9 @@ func (s *TicketService) UpdateTicketStatus(ticketID uint, status string, userID
 		if resolutionNotes != "" && status == "resolved" {
 			description += fmt.Sprintf(" (解决方案: %s)", resolutionNotes)
 		}
+		if nextCode != "" && nextCode != oldCode {
+			description += fmt.Sprintf(" [解决代码: %s]", nextCode)
+		}
 
 		history := &models.TicketHistory{
 			TicketID:    ticketID,
@@ -747,9 +1263,38 @@ func (s *TicketService) UpdateTicketStatus(ticketID uint, status string, userID
 		}
 	}()
 
+	if ticket.Status == models.TicketStatusResolved && oldStatus != models.TicketStatusResolved {
+		s.runResolvedRules(context.Background(), ticket)
+	}
+
 	return ticket, nil
 }
 
+// nextResolutionCode 计算状态变更后工单的解决代码：
+// 变为已解决时校验提交的代码（按配置必填），已解决/已关闭工单可单独修改代码，重新打开时清空
+func (s *TicketService) nextResolutionCode(ctx context.Context, ticket *models.Ticket, status models.TicketStatus, code *string) (string, error) {
+	switch {
+	case status == models.TicketStatusResolved && ticket.Status != models.TicketStatusResolved:
+		submitted := ""
+		if code != nil {
+			submitted = *code
+		}
+		return s.resolutionCodes.ValidateCode(ctx, submitted)
+	case status == models.TicketStatusOpen || status == models.TicketStatusInProgress || status == models.TicketStatusPending:
+		return "", nil
+	case code != nil && (status == models.TicketStatusResolved || status == models.TicketStatusClosed):
+		return s.resolutionCodes.ValidateCode(ctx, *code)
+	}
+	return ticket.ResolutionCode, nil
+}
+
+// runResolvedRules 触发 ticket.resolved 自动化规则，失败只记录日志
+func (s *TicketService) runResolvedRules(ctx context.Context, ticket *models.Ticket) {
+	if err := NewAutomationService(s.db).ExecuteRules(ctx, models.TriggerTicketResolved, ticket); err != nil {
+		fmt.Printf("Failed to run ticket.resolved rules for ticket %d: %v\n", ticket.ID, err)
+	}
+}
+
 // GetTicketStatistics returns enhanced statistics for dashboard
 func (s *TicketService) GetTicketStatistics(userID uint, role string) (*TicketStatisticsResponse, error) {
 	stats := &TicketStatisticsResponse{
@@ -871,12 +1416,12 @@ func (s *TicketService) GetUserTickets(userID uint, status string, priority stri
 	return tickets, total, nil
 }
 
-// GetUnassignedTickets gets unassigned tickets
-func (s *TicketService) GetUnassignedTickets(priority string, categoryID string, limit int) ([]*models.Ticket, int64, error) {
+// GetUnassignedTickets gets unassigned tickets, limited to the category scope carried by ctx
+func (s *TicketService) GetUnassignedTickets(ctx context.Context, priority string, categoryID string, limit int) ([]*models.Ticket, int64, error) {
 	var tickets []*models.Ticket
 	var total int64
 
-	query := s.db.Model(&models.Ticket{}).Where("assigned_to_id IS NULL")
+	query := CategoryScopeFrom(ctx).Apply(s.db.WithContext(ctx).Model(&models.Ticket{}).Where("assigned_to_id IS NULL"))
 
 	if priority != "" {
 		priorities := parseCommaSeparated(priority)
@@ -938,10 +1483,12 @@ func (s *TicketService) GetSLABreachedTickets(userID uint, role string) ([]*mode
 	var tickets []*models.Ticket
 	var total int64
 
+	// 以 Unix 秒比较创建时间加 SLA 时长与当前时间，各数据库写法一致
+	deadline := DialectOf(s.db).EpochSeconds("tickets.created_at") + " + c.sla_hours * 3600"
 	query := s.db.Model(&models.Ticket{}).
 		Joins("JOIN categories c ON tickets.category_id = c.id").
-		Where("tickets.created_at + INTERVAL c.sla_hours HOUR < NOW() AND tickets.status NOT IN (?, ?)",
-			models.TicketStatusResolved, models.TicketStatusClosed)
+		Where("c.sla_hours > 0 AND "+deadline+" < ? AND tickets.status NOT IN (?, ?)",
+			time.Now().Unix(), models.TicketStatusResolved, models.TicketStatusClosed)
 
 	if role == "agent" {
 		query = query.Where("tickets.assigned_to_id = ?", userID)
@@ -982,14 +1529,14 @@ func (s *TicketService) BulkAssignTickets(ticketIDs []uint, assigneeID uint, use
 }
 
 // BulkUpdateStatus updates status for multiple tickets
-func (s *TicketService) BulkUpdateStatus(ticketIDs []uint, status string, userID uint, comment string) (*BulkOperationResult, error) {
+func (s *TicketService) BulkUpdateStatus(ticketIDs []uint, status string, userID uint, comment string, resolutionCode string) (*BulkOperationResult, error) {
 	result := &BulkOperationResult{
 		UpdatedTickets: []uint{},
 		FailedTickets:  []uint{},
 	}
 
 	for _, ticketID := range ticketIDs {
-		if _, err := s.UpdateTicketStatus(ticketID, status, userID, comment, ""); err != nil {
+		if _, err := s.UpdateTicketStatus(ticketID, status, userID, comment, "", resolutionCode); err != nil {
 			result.FailedTickets = append(result.FailedTickets, ticketID)
 			result.FailedCount++
 		} else {
@@ -1029,6 +1576,13 @@ func getAssigneeValue(assigneeID *uint) string {
 	return fmt.Sprintf("%d", *assigneeID)
 }
 
+func getTimeValue(t *time.Time) string {
+	if t == nil {
+		return "未设置"
+	}
+	return t.Format(time.RFC3339)
+}
+
 func parseCommaSeparated(value string) []string {
 	if value == "" {
 		return []string{}
@@ -1056,11 +1610,16 @@ func (s *TicketService) DeleteTicket(ctx context.Context, id uint, userID uint,
 		}
 	}
 
-	if err := s.db.WithContext(ctx).Delete(ticket).Error; err != nil {
-		return fmt.Errorf("failed to delete ticket: %w", err)
-	}
-
-	return nil
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(ticket).Error; err != nil {
+			return fmt.Errorf("failed to delete ticket: %w", err)
+		}
+		// 释放外部编号，以便关联到其他工单
+		if err := tx.Where("ticket_id = ?", ticket.ID).Delete(&models.ExternalReference{}).Error; err != nil {
+			return fmt.Errorf("failed to delete external references: %w", err)
+		}
+		return recordSyncTombstones(tx, models.SyncEntityTicket, nil, []uint{ticket.ID})
+	})
 }
 
 func isElevatedRole(role string) bool {
@@ -1114,11 +1673,33 @@ func (s *TicketService) BulkUpdateTickets(ctx context.Context, req *BulkUpdateRe
 	if len(req.TicketIDs) == 0 {
 		return fmt.Errorf("no ticket IDs provided")
 	}
+	var resolutionCode string
+	if req.Status != nil && *req.Status == string(models.TicketStatusResolved) {
+		if err := s.checklistService.EnsureResolvable(ctx, req.TicketIDs...); err != nil {
+			return err
+		}
+		submitted := ""
+		if req.ResolutionCode != nil {
+			submitted = *req.ResolutionCode
+		}
+		code, err := s.resolutionCodes.ValidateCode(ctx, submitted)
+		if err != nil {
+			return err
+		}
+		resolutionCode = code
+		req.ResolutionCode = &resolutionCode
+	}
 
 	updates := make(map[string]interface{})
 
 	if req.Status != nil {
 		updates["status"] = *req.Status
+		switch models.TicketStatus(*req.Status) {
+		case models.TicketStatusResolved:
+			updates["resolution_code"] = resolutionCode
+		case models.TicketStatusOpen, models.TicketStatusInProgress, models.TicketStatusPending:
+			updates["resolution_code"] = ""
+		}
 	}
 	if req.Priority != nil {
 		updates["priority"] = *req.Priority
@@ -1127,24 +1708,93 @@ func (s *TicketService) BulkUpdateTickets(ctx context.Context, req *BulkUpdateRe
 		updates["assigned_to_id"] = *req.AssignedToID
 	}
 	if req.Tags != nil {
-		updates["tags"] = req.Tags
+		tagsBytes, _ := json.Marshal(req.Tags)
+		updates["tags"] = models.JSONText(tagsBytes)
 	}
 	if req.CustomFields != nil {
 		customFieldsBytes, _ := json.Marshal(req.CustomFields)
-		updates["custom_fields"] = string(customFieldsBytes)
+		updates["custom_fields"] = models.JSONText(customFieldsBytes)
 	}
 
 	updates["updated_at"] = time.Now()
 
-	if err := s.db.WithContext(ctx).Model(&models.Ticket{}).
-		Where("id IN ?", req.TicketIDs).
-		Updates(updates).Error; err != nil {
-		return fmt.Errorf("failed to bulk update tickets: %w", err)
+	var tickets []models.Ticket
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Select("id", "status", "priority", "assigned_to_id", "resolution_code").
+			Where("id IN ?", req.TicketIDs).Find(&tickets).Error; err != nil {
+			return fmt.Errorf("failed to load tickets: %w", err)
+		}
+
+		if err := tx.Model(&models.Ticket{}).
+			Where("id IN ?", req.TicketIDs).
+			Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to bulk update tickets: %w", err)
+		}
+
+		// 所有工单的变更历史合并为多行 INSERT 写入
+		batch := NewHistoryBatch()
+		for _, ticket := range tickets {
+			for _, history := range bulkUpdateHistory(&ticket, req, userID) {
+				batch.Add(history)
+			}
+		}
+		return batch.Flush(tx)
+	})
+	if err != nil {
+		return err
 	}
 
+	if req.Status != nil && *req.Status == string(models.TicketStatusResolved) {
+		for _, ticket := range tickets {
+			if ticket.Status == models.TicketStatusResolved {
+				continue
+			}
+			var resolved models.Ticket
+			if err := s.db.WithContext(ctx).First(&resolved, ticket.ID).Error; err == nil {
+				s.runResolvedRules(ctx, &resolved)
+			}
+		}
+	}
 	return nil
 }
 
+// bulkUpdateHistory 生成批量更新中单个工单的字段变更历史
+func bulkUpdateHistory(ticket *models.Ticket, req *BulkUpdateRequest, userID uint) []*models.TicketHistory {
+	var histories []*models.TicketHistory
+	add := func(action models.HistoryAction, field, description, oldValue, newValue string) {
+		histories = append(histories, &models.TicketHistory{
+			TicketID:    ticket.ID,
+			UserID:      &userID,
+			Action:      action,
+			Description: "批量更新：" + description,
+			FieldName:   field,
+			OldValue:    oldValue,
+			NewValue:    newValue,
+			IsVisible:   true,
+		})
+	}
+	if req.Status != nil && string(ticket.Status) != *req.Status {
+		add(models.HistoryActionStatusChange, "status", fmt.Sprintf("状态从 %s 变更为 %s", ticket.Status, *req.Status), string(ticket.Status), *req.Status)
+	}
+	if req.Status != nil && *req.Status == string(models.TicketStatusResolved) && req.ResolutionCode != nil && ticket.ResolutionCode != *req.ResolutionCode {
+		add(models.HistoryActionUpdate, "resolution_code", fmt.Sprintf("解决代码从 %s 变更为 %s", ticket.ResolutionCode, *req.ResolutionCode), ticket.ResolutionCode, *req.ResolutionCode)
+	}
+	if req.Priority != nil && string(ticket.Priority) != *req.Priority {
+		add(models.HistoryActionPriorityChange, "priority", fmt.Sprintf("优先级从 %s 变更为 %s", ticket.Priority, *req.Priority), string(ticket.Priority), *req.Priority)
+	}
+	if req.AssignedToID != nil && getAssigneeValue(ticket.AssignedToID) != getAssigneeValue(req.AssignedToID) {
+		add(models.HistoryActionAssign, "assigned_to_id", fmt.Sprintf("处理人从 %s 变更为 %s", getAssigneeValue(ticket.AssignedToID), getAssigneeValue(req.AssignedToID)),
+			getAssigneeValue(ticket.AssignedToID), getAssigneeValue(req.AssignedToID))
+	}
+	if req.Tags != nil {
+		add(models.HistoryActionUpdate, "tags", "标签设置为 "+strings.Join(req.Tags, ","), "", strings.Join(req.Tags, ","))
+	}
+	if req.CustomFields != nil {
+		add(models.HistoryActionUpdate, "custom_fields", "更新自定义字段", "", "")
+	}
+	return histories
+}
+
 // CreateTicketHistory creates a new ticket history record
 func (s *TicketService) CreateTicketHistory(ctx context.Context, req *models.TicketHistoryCreateRequest, userID *uint) error {
 	history := &models.TicketHistory{
@@ -1178,7 +1828,7 @@ func (s *TicketService) CreateTicketHistory(ctx context.Context, req *models.Tic
 	}
 	if req.Metadata != nil {
 		metadataJSON, _ := json.Marshal(req.Metadata)
-		history.Metadata = string(metadataJSON)
+		history.Metadata = models.JSONText(metadataJSON)
 	}
 
 	// 如果没有用户ID，设置为系统操作
@@ -1259,6 +1909,11 @@ func getSourceLabel(source string) string {
 
 // generateTicketNumber generates a unique ticket number
 func (s *TicketService) generateTicketNumber() string {
+	return newTicketNumber()
+}
+
+// newTicketNumber generates a ticket number for callers outside TicketService
+func newTicketNumber() string {
 	now := time.Now()
 	// Format: TK-YYYYMMDD-HHMMSS-RRR (RRR is random 3-digit number)
 	randomNum := rand.Intn(1000)
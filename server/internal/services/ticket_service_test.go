@@ -91,12 +91,11 @@ func TestGetTicketsSupportsMultiValueFilters(t *testing.T) {
 	svc := &TicketService{db: db}
 
 	filters := TicketFilters{
-		Status:    "open,in_progress",
-		Priority:  "urgent,critical",
-		Page:      1,
-		Limit:     10,
-		SortBy:    "created_at",
-		SortOrder: "desc",
+		Status:   "open,in_progress",
+		Priority: "urgent,critical",
+		Page:     1,
+		Limit:    10,
+		Sort:     []models.SortField{{Field: "created_at", Direction: models.SortDesc}},
 	}
 
 	tickets, total, err := svc.GetTickets(context.Background(), filters)
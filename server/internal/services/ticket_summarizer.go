@@ -0,0 +1,241 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"gongdan-system/internal/models"
+)
+
+// ticketSummaryMaxTokens 单次摘要允许模型输出的最大 token 数
+const ticketSummaryMaxTokens = 800
+
+// TicketSummarizer 工单摘要生成服务提供方
+type TicketSummarizer interface {
+	Provider() models.TicketSummaryProvider
+	// Summarize 根据系统提示词和工单内容生成摘要
+	Summarize(ctx context.Context, instructions, transcript string) (*TicketSummaryResult, error)
+}
+
+// TicketSummaryResult 模型返回的摘要结果
+type TicketSummaryResult struct {
+	Summary      string
+	NextActions  []string
+	Model        string // 服务商返回的实际模型版本，未返回时为请求的模型名称
+	InputTokens  int
+	OutputTokens int
+}
+
+// TicketSummarizerConfig 摘要服务配置
+type TicketSummarizerConfig struct {
+	Provider models.TicketSummaryProvider
+	APIKey   string
+	Endpoint string // 为空时使用服务商默认地址
+	Model    string
+}
+
+// NewTicketSummarizer 按配置创建摘要服务客户端。
+// OpenAI 兼容接口配置了自建服务地址时可以不填密钥
+func NewTicketSummarizer(config *TicketSummarizerConfig, client *http.Client) (TicketSummarizer, error) {
+	if config.Model == "" {
+		return nil, fmt.Errorf("%w: model is required", ErrTicketSummaryNotConfigured)
+	}
+	switch config.Provider {
+	case models.TicketSummaryProviderOpenAI:
+		endpoint := config.Endpoint
+		if endpoint == "" {
+			if config.APIKey == "" {
+				return nil, ErrTicketSummaryNotConfigured
+			}
+			endpoint = "https://api.openai.com/v1/chat/completions"
+		}
+		return &openAISummarizer{apiKey: config.APIKey, endpoint: endpoint, model: config.Model, client: client}, nil
+	case models.TicketSummaryProviderAnthropic:
+		if config.APIKey == "" {
+			return nil, ErrTicketSummaryNotConfigured
+		}
+		endpoint := config.Endpoint
+		if endpoint == "" {
+			endpoint = "https://api.anthropic.com/v1/messages"
+		}
+		return &anthropicSummarizer{apiKey: config.APIKey, endpoint: endpoint, model: config.Model, client: client}, nil
+	}
+	return nil, fmt.Errorf("%w: unknown provider %q", ErrTicketSummaryNotConfigured, config.Provider)
+}
+
+// openAISummarizer OpenAI Chat Completions 接口及兼容该接口的自建服务
+type openAISummarizer struct {
+	apiKey   string
+	endpoint string
+	model    string
+	client   *http.Client
+}
+
+func (s *openAISummarizer) Provider() models.TicketSummaryProvider {
+	return models.TicketSummaryProviderOpenAI
+}
+
+func (s *openAISummarizer) Summarize(ctx context.Context, instructions, transcript string) (*TicketSummaryResult, error) {
+	body := map[string]interface{}{
+		"model": s.model,
+		"messages": []map[string]string{
+			{"role": "system", "content": instructions},
+			{"role": "user", "content": transcript},
+		},
+		"max_tokens":  ticketSummaryMaxTokens,
+		"temperature": 0.2,
+	}
+	headers := map[string]string{}
+	if s.apiKey != "" {
+		headers["Authorization"] = "Bearer " + s.apiKey
+	}
+
+	var resp struct {
+		Model   string `json:"model"`
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+		Usage struct {
+			PromptTokens     int `json:"prompt_tokens"`
+			CompletionTokens int `json:"completion_tokens"`
+		} `json:"usage"`
+	}
+	if err := postSummaryJSON(ctx, s.client, s.endpoint, headers, body, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("%w: empty response", ErrTicketSummaryFailed)
+	}
+	result := parseTicketSummaryOutput(resp.Choices[0].Message.Content)
+	result.Model = firstNonEmpty(resp.Model, s.model)
+	result.InputTokens = resp.Usage.PromptTokens
+	result.OutputTokens = resp.Usage.CompletionTokens
+	return result, nil
+}
+
+// anthropicSummarizer Anthropic Messages 接口
+type anthropicSummarizer struct {
+	apiKey   string
+	endpoint string
+	model    string
+	client   *http.Client
+}
+
+func (s *anthropicSummarizer) Provider() models.TicketSummaryProvider {
+	return models.TicketSummaryProviderAnthropic
+}
+
+func (s *anthropicSummarizer) Summarize(ctx context.Context, instructions, transcript string) (*TicketSummaryResult, error) {
+	body := map[string]interface{}{
+		"model":      s.model,
+		"system":     instructions,
+		"messages":   []map[string]string{{"role": "user", "content": transcript}},
+		"max_tokens": ticketSummaryMaxTokens,
+	}
+	headers := map[string]string{
+		"x-api-key":         s.apiKey,
+		"anthropic-version": "2023-06-01",
+	}
+
+	var resp struct {
+		Model   string `json:"model"`
+		Content []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		} `json:"content"`
+		Usage struct {
+			InputTokens  int `json:"input_tokens"`
+			OutputTokens int `json:"output_tokens"`
+		} `json:"usage"`
+	}
+	if err := postSummaryJSON(ctx, s.client, s.endpoint, headers, body, &resp); err != nil {
+		return nil, err
+	}
+	var text strings.Builder
+	for _, block := range resp.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	if text.Len() == 0 {
+		return nil, fmt.Errorf("%w: empty response", ErrTicketSummaryFailed)
+	}
+	result := parseTicketSummaryOutput(text.String())
+	result.Model = firstNonEmpty(resp.Model, s.model)
+	result.InputTokens = resp.Usage.InputTokens
+	result.OutputTokens = resp.Usage.OutputTokens
+	return result, nil
+}
+
+// parseTicketSummaryOutput 解析模型输出的 JSON（{"summary": "...", "next_actions": [...]}），
+// 允许外层包裹 Markdown 代码块；无法解析时整段文本作为摘要
+func parseTicketSummaryOutput(output string) *TicketSummaryResult {
+	text := strings.TrimSpace(output)
+	if start, end := strings.Index(text, "{"), strings.LastIndex(text, "}"); start >= 0 && end > start {
+		var parsed struct {
+			Summary     string   `json:"summary"`
+			NextActions []string `json:"next_actions"`
+		}
+		if err := json.Unmarshal([]byte(text[start:end+1]), &parsed); err == nil && strings.TrimSpace(parsed.Summary) != "" {
+			actions := make([]string, 0, len(parsed.NextActions))
+			for _, action := range parsed.NextActions {
+				if action = strings.TrimSpace(action); action != "" {
+					actions = append(actions, action)
+				}
+			}
+			return &TicketSummaryResult{Summary: strings.TrimSpace(parsed.Summary), NextActions: actions}
+		}
+	}
+	return &TicketSummaryResult{Summary: text, NextActions: []string{}}
+}
+
+// firstNonEmpty 返回第一个非空字符串
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// postSummaryJSON 发送 JSON 请求并解析响应，非 2xx 状态码返回 ErrTicketSummaryFailed
+func postSummaryJSON(ctx context.Context, client *http.Client, endpoint string, headers map[string]string, body, out interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrTicketSummaryFailed, err)
+	}
+	defer resp.Body.Close()
+
+	payload, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrTicketSummaryFailed, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%w: provider returned %d: %s", ErrTicketSummaryFailed, resp.StatusCode, truncateString(string(payload), 200))
+	}
+	if err := json.Unmarshal(payload, out); err != nil {
+		return fmt.Errorf("%w: invalid response: %v", ErrTicketSummaryFailed, err)
+	}
+	return nil
+}
@@ -0,0 +1,261 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// ticketSummaryPromptVersion 提示词版本，调整 ticketSummaryInstructions 时递增
+	ticketSummaryPromptVersion = "v1"
+	// ticketSummaryMaxRunes 发送给模型的工单内容上限，超出时省略较早的评论
+	ticketSummaryMaxRunes = 24000
+	// ticketSummaryDescriptionRunes 工单描述的最大长度
+	ticketSummaryDescriptionRunes = 4000
+	// ticketSummaryTimeout 交接后自动生成摘要的超时时间
+	ticketSummaryTimeout = 60 * time.Second
+	// ticketSummaryHistoryLimit 摘要历史最多返回的条数
+	ticketSummaryHistoryLimit = 20
+)
+
+// ticketSummaryInstructions 系统提示词，要求模型只输出 JSON
+const ticketSummaryInstructions = `你是客服工单助手，帮助接手的客服快速了解工单。阅读用户消息中的工单内容，使用工单主要使用的语言，只输出如下 JSON，不要输出其他内容：
+{"summary": "不超过200字的摘要：客户的问题、已经采取的措施、当前进展及尚未解决的问题", "next_actions": ["建议客服接下来执行的具体操作，最多5条"]}
+只依据工单中的信息，不要编造；内部备注仅供客服参考。`
+
+var (
+	// ErrTicketSummaryNotConfigured 未启用工单摘要或模型服务配置不完整
+	ErrTicketSummaryNotConfigured = errors.New("ticket summarization is not configured")
+	// ErrTicketSummaryFailed 模型服务请求失败
+	ErrTicketSummaryFailed = errors.New("summarization provider request failed")
+	// ErrTicketSummaryTicketNotFound 工单不存在
+	ErrTicketSummaryTicketNotFound = errors.New("ticket not found")
+)
+
+// TicketSummaryService 工单摘要服务：将工单描述及评论发送给配置的模型服务，生成摘要和建议的下一步操作。
+// 结果连同模型版本保存，工单内容未变化时直接返回上次的结果
+type TicketSummaryService struct {
+	db            *gorm.DB
+	configService *ConfigService
+	client        *http.Client
+	newSummarizer func(config *TicketSummarizerConfig) (TicketSummarizer, error)
+
+	// 正在后台生成摘要的工单，避免重复请求模型服务
+	inflight sync.Map
+}
+
+// NewTicketSummaryService 创建工单摘要服务
+func NewTicketSummaryService(db *gorm.DB) *TicketSummaryService {
+	service := &TicketSummaryService{
+		db:            db,
+		configService: NewConfigService(db),
+		client:        &http.Client{Timeout: 60 * time.Second},
+	}
+	service.newSummarizer = func(config *TicketSummarizerConfig) (TicketSummarizer, error) {
+		return NewTicketSummarizer(config, service.client)
+	}
+	return service
+}
+
+// summarizer 按系统配置创建摘要服务客户端，未启用或配置不完整时返回 ErrTicketSummaryNotConfigured
+func (s *TicketSummaryService) summarizer() (TicketSummarizer, error) {
+	enabled, err := s.configService.GetConfigBool(KeySummaryEnabled)
+	if err != nil || !enabled {
+		return nil, ErrTicketSummaryNotConfigured
+	}
+	return s.newSummarizer(&TicketSummarizerConfig{
+		Provider: models.TicketSummaryProvider(s.configService.GetConfigWithDefault(KeySummaryProvider, string(models.TicketSummaryProviderOpenAI))),
+		APIKey:   s.configService.GetConfigWithDefault(KeySummaryAPIKey, ""),
+		Endpoint: s.configService.GetConfigWithDefault(KeySummaryEndpoint, ""),
+		Model:    s.configService.GetConfigWithDefault(KeySummaryModel, "gpt-4o-mini"),
+	})
+}
+
+// Summarize 为工单生成摘要。工单内容未变化且未要求强制重新生成时返回上次的摘要，
+// 返回的 bool 表示摘要是否为复用的结果
+func (s *TicketSummaryService) Summarize(ctx context.Context, ticketID uint, req *models.TicketSummarizeRequest, userID uint) (*models.TicketSummary, bool, error) {
+	summarizer, err := s.summarizer()
+	if err != nil {
+		return nil, false, err
+	}
+	return s.generate(ctx, summarizer, ticketID, models.TicketSummaryManual, req.Force, &userID)
+}
+
+// List 获取工单的摘要历史，最新的在前
+func (s *TicketSummaryService) List(ctx context.Context, ticketID uint) ([]*models.TicketSummary, error) {
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.Ticket{}).Where("id = ?", ticketID).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to get ticket: %w", err)
+	}
+	if count == 0 {
+		return nil, ErrTicketSummaryTicketNotFound
+	}
+
+	summaries := []*models.TicketSummary{}
+	if err := s.db.WithContext(ctx).Where("ticket_id = ?", ticketID).
+		Order("created_at DESC, id DESC").Limit(ticketSummaryHistoryLimit).
+		Find(&summaries).Error; err != nil {
+		return nil, fmt.Errorf("failed to list ticket summaries: %w", err)
+	}
+	return summaries, nil
+}
+
+// SummarizeAfterHandoff 交接后在后台为评论较多的工单生成摘要，未开启交接自动摘要时不处理
+func (s *TicketSummaryService) SummarizeAfterHandoff(ticketIDs []uint) {
+	if onHandoff, err := s.configService.GetConfigBool(KeySummaryOnHandoff); err != nil || !onHandoff {
+		return
+	}
+	summarizer, err := s.summarizer()
+	if err != nil {
+		return
+	}
+	minComments, err := s.configService.GetConfigInt(KeySummaryMinComments)
+	if err != nil {
+		minComments = 5
+	}
+
+	for _, ticketID := range ticketIDs {
+		ticketID := ticketID
+		if _, loaded := s.inflight.LoadOrStore(ticketID, true); loaded {
+			continue
+		}
+		go func() {
+			defer s.inflight.Delete(ticketID)
+			ctx, cancel := context.WithTimeout(context.Background(), ticketSummaryTimeout)
+			defer cancel()
+
+			var comments int64
+			if err := s.db.WithContext(ctx).Model(&models.TicketComment{}).
+				Where("ticket_id = ? AND is_deleted = ? AND type <> ?", ticketID, false, models.CommentTypeSystem).
+				Count(&comments).Error; err != nil || comments < int64(minComments) {
+				return
+			}
+			if _, _, err := s.generate(ctx, summarizer, ticketID, models.TicketSummaryHandoff, false, nil); err != nil {
+				log.Printf("Failed to summarize ticket %d after handoff: %v", ticketID, err)
+			}
+		}()
+	}
+}
+
+func (s *TicketSummaryService) generate(ctx context.Context, summarizer TicketSummarizer, ticketID uint, trigger models.TicketSummaryTrigger, force bool, createdByID *uint) (*models.TicketSummary, bool, error) {
+	var ticket models.Ticket
+	if err := s.db.WithContext(ctx).First(&ticket, ticketID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, false, ErrTicketSummaryTicketNotFound
+		}
+		return nil, false, fmt.Errorf("failed to get ticket: %w", err)
+	}
+	var comments []models.TicketComment
+	if err := s.db.WithContext(ctx).Preload("User").
+		Where("ticket_id = ? AND is_deleted = ? AND type <> ?", ticketID, false, models.CommentTypeSystem).
+		Order("created_at ASC, id ASC").Find(&comments).Error; err != nil {
+		return nil, false, fmt.Errorf("failed to get ticket comments: %w", err)
+	}
+
+	transcript, included := buildTicketSummaryTranscript(&ticket, comments)
+	sum := sha256.Sum256([]byte(ticketSummaryPromptVersion + "\x00" + string(summarizer.Provider()) + "\x00" +
+		s.configService.GetConfigWithDefault(KeySummaryModel, "") + "\x00" + transcript))
+	sourceHash := hex.EncodeToString(sum[:])
+
+	if !force {
+		var latest models.TicketSummary
+		err := s.db.WithContext(ctx).Where("ticket_id = ?", ticketID).Order("id DESC").First(&latest).Error
+		if err == nil && latest.SourceHash == sourceHash {
+			return &latest, true, nil
+		}
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, false, fmt.Errorf("failed to get latest summary: %w", err)
+		}
+	}
+
+	result, err := summarizer.Summarize(ctx, ticketSummaryInstructions, transcript)
+	if err != nil {
+		return nil, false, err
+	}
+	summary := &models.TicketSummary{
+		TicketID:       ticketID,
+		Trigger:        trigger,
+		Summary:        result.Summary,
+		NextActionList: result.NextActions,
+		Provider:       summarizer.Provider(),
+		Model:          truncateString(result.Model, 100),
+		PromptVersion:  ticketSummaryPromptVersion,
+		CommentCount:   included,
+		InputTokens:    result.InputTokens,
+		OutputTokens:   result.OutputTokens,
+		SourceHash:     sourceHash,
+		CreatedByID:    createdByID,
+	}
+	if err := s.db.WithContext(ctx).Create(summary).Error; err != nil {
+		return nil, false, fmt.Errorf("failed to save ticket summary: %w", err)
+	}
+	return summary, false, nil
+}
+
+// buildTicketSummaryTranscript 将工单及评论整理为纯文本，超出长度上限时省略较早的评论；返回纳入的评论数
+func buildTicketSummaryTranscript(ticket *models.Ticket, comments []models.TicketComment) (string, int) {
+	var header strings.Builder
+	fmt.Fprintf(&header, "工单 %s：%s\n", ticket.TicketNumber, ticket.Title)
+	fmt.Fprintf(&header, "状态：%s，优先级：%s，类型：%s，创建时间：%s\n", ticket.Status, ticket.Priority, ticket.Type,
+		ticket.CreatedAt.Format("2006-01-02 15:04"))
+	// 描述可能来自富文本编辑器，统一去除标签
+	fmt.Fprintf(&header, "\n问题描述：\n%s\n", truncateRunes(pdfPlainText(ticket.Description, "html"), ticketSummaryDescriptionRunes))
+
+	budget := ticketSummaryMaxRunes - utf8.RuneCountInString(header.String())
+	entries := make([]string, 0, len(comments))
+	for i := len(comments) - 1; i >= 0; i-- {
+		comment := &comments[i]
+		author := "客服"
+		if comment.User != nil && (CommentViewer{Role: string(comment.User.Role)}).IsCustomer() {
+			author = "客户"
+		}
+		if comment.User != nil {
+			author += " " + comment.User.GetFullName()
+		}
+		if comment.Type == models.CommentTypeInternal {
+			author += "（内部备注）"
+		}
+		entry := fmt.Sprintf("[%s %s]\n%s\n", comment.CreatedAt.Format("2006-01-02 15:04"), author,
+			strings.TrimSpace(pdfPlainText(comment.Content, comment.ContentType)))
+		length := utf8.RuneCountInString(entry)
+		if length > budget {
+			break
+		}
+		budget -= length
+		entries = append(entries, entry)
+	}
+
+	var transcript strings.Builder
+	transcript.WriteString(header.String())
+	if len(entries) > 0 {
+		transcript.WriteString("\n对话记录（按时间顺序）：\n")
+	}
+	if omitted := len(comments) - len(entries); omitted > 0 {
+		fmt.Fprintf(&transcript, "（较早的 %d 条评论已省略）\n", omitted)
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		transcript.WriteString(entries[i])
+	}
+	return transcript.String(), len(entries)
+}
+
+// truncateRunes 按字符截断文本
+func truncateRunes(text string, max int) string {
+	runes := []rune(text)
+	if len(runes) <= max {
+		return text
+	}
+	return string(runes[:max]) + "…"
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+)
+
+func TestTicketSummary_SelfHostedEndpointCachesAndSummarizesAfterHandoff(t *testing.T) {
+	db := newTestDB(t, &models.User{}, &models.Ticket{}, &models.TicketComment{}, &models.SystemConfig{}, &models.TicketSummary{})
+
+	var calls int32
+	var lastTranscript string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("expected no authorization header for self-hosted endpoint")
+		}
+		var body struct {
+			Model    string `json:"model"`
+			Messages []struct {
+				Role    string `json:"role"`
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		lastTranscript = body.Messages[len(body.Messages)-1].Content
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"model":"llama-3.1-8b-2024","choices":[{"message":{"content":"` +
+			"```json\\n{\\\"summary\\\":\\\"客户无法登录\\\",\\\"next_actions\\\":[\\\"重置密码\\\",\\\" \\\"]}\\n```" +
+			`"}}],"usage":{"prompt_tokens":120,"completion_tokens":30}}`))
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	svc := NewTicketSummaryService(db)
+	agent := models.User{Username: "sum-agent", Email: "sum-agent@example.com", PasswordHash: "x", Role: models.RoleAgent, Status: models.UserStatusActive}
+	customer := models.User{Username: "sum-customer", Email: "sum-customer@example.com", PasswordHash: "x", Role: models.RoleCustomer, Status: models.UserStatusActive}
+	db.Create(&agent)
+	db.Create(&customer)
+	ticket := models.Ticket{TicketNumber: "SUM-1", Title: "Login issue", Description: "<p>I cannot log in</p>", Status: models.TicketStatusOpen,
+		Priority: models.TicketPriorityNormal, Type: models.TicketTypeRequest, Source: models.TicketSourceWeb, CreatedByID: customer.ID}
+	db.Create(&ticket)
+	db.Create(&models.TicketComment{TicketID: ticket.ID, UserID: customer.ID, Content: "Still broken", Type: models.CommentTypePublic})
+	db.Create(&models.TicketComment{TicketID: ticket.ID, UserID: agent.ID, Content: "Checked the auth logs", Type: models.CommentTypeInternal})
+
+	// 默认关闭
+	if _, _, err := svc.Summarize(ctx, ticket.ID, &models.TicketSummarizeRequest{}, agent.ID); !errors.Is(err, ErrTicketSummaryNotConfigured) {
+		t.Fatalf("expected summarization not configured, got %v", err)
+	}
+	svc.configService.SetConfig(KeySummaryEnabled, "true", "bool", "", CategoryTicket, "summary")
+	// OpenAI 接口未配置密钥及自建服务地址时不可用
+	if _, _, err := svc.Summarize(ctx, ticket.ID, &models.TicketSummarizeRequest{}, agent.ID); !errors.Is(err, ErrTicketSummaryNotConfigured) {
+		t.Fatalf("expected missing api key to be rejected, got %v", err)
+	}
+	svc.configService.SetConfig(KeySummaryEndpoint, server.URL, "string", "", CategoryTicket, "summary")
+
+	summary, cached, err := svc.Summarize(ctx, ticket.ID, &models.TicketSummarizeRequest{}, agent.ID)
+	if err != nil || cached {
+		t.Fatalf("summarize failed: %+v cached=%v err=%v", summary, cached, err)
+	}
+	if summary.Summary != "客户无法登录" || len(summary.NextActionList) != 1 || summary.NextActionList[0] != "重置密码" ||
+		summary.Model != "llama-3.1-8b-2024" || summary.PromptVersion != ticketSummaryPromptVersion || summary.CommentCount != 2 ||
+		summary.InputTokens != 120 || summary.Trigger != models.TicketSummaryManual || summary.CreatedByID == nil {
+		t.Fatalf("unexpected summary %+v", summary)
+	}
+	if !strings.Contains(lastTranscript, "I cannot log in") || strings.Contains(lastTranscript, "<p>") ||
+		!strings.Contains(lastTranscript, "（内部备注）") {
+		t.Fatalf("unexpected transcript %q", lastTranscript)
+	}
+
+	// 内容未变化时复用，强制时重新生成
+	if again, cached, _ := svc.Summarize(ctx, ticket.ID, &models.TicketSummarizeRequest{}, agent.ID); !cached || again.ID != summary.ID {
+		t.Fatalf("expected cached summary, got %+v", again)
+	}
+	if _, cached, _ := svc.Summarize(ctx, ticket.ID, &models.TicketSummarizeRequest{Force: true}, agent.ID); cached || atomic.LoadInt32(&calls) != 2 {
+		t.Fatalf("expected forced regeneration, calls=%d", calls)
+	}
+	db.Create(&models.TicketComment{TicketID: ticket.ID, UserID: customer.ID, Content: "Any update?", Type: models.CommentTypePublic})
+	if _, cached, _ := svc.Summarize(ctx, ticket.ID, &models.TicketSummarizeRequest{}, agent.ID); cached {
+		t.Fatalf("expected new comment to invalidate cached summary")
+	}
+
+	summaries, err := svc.List(ctx, ticket.ID)
+	if err != nil || len(summaries) != 3 || summaries[0].CommentCount != 3 {
+		t.Fatalf("unexpected summary history %+v (%v)", summaries, err)
+	}
+	if _, err := svc.List(ctx, 9999); !errors.Is(err, ErrTicketSummaryTicketNotFound) {
+		t.Fatalf("expected ticket not found, got %v", err)
+	}
+
+	// 交接自动摘要：未开启时不处理，评论数不足时跳过
+	svc.SummarizeAfterHandoff([]uint{ticket.ID})
+	svc.configService.SetConfig(KeySummaryOnHandoff, "true", "bool", "", CategoryTicket, "summary")
+	svc.configService.SetConfig(KeySummaryMinComments, "5", "int", "", CategoryTicket, "summary")
+	svc.SummarizeAfterHandoff([]uint{ticket.ID})
+	waitForTicketSummaryIdle(t, svc, ticket.ID)
+	svc.configService.SetConfig(KeySummaryMinComments, "3", "int", "", CategoryTicket, "summary")
+	db.Create(&models.TicketComment{TicketID: ticket.ID, UserID: agent.ID, Content: "Handing over", Type: models.CommentTypePublic})
+	svc.SummarizeAfterHandoff([]uint{ticket.ID})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		var handoffSummaries int64
+		db.Model(&models.TicketSummary{}).Where(&models.TicketSummary{TicketID: ticket.ID, Trigger: models.TicketSummaryHandoff}).Count(&handoffSummaries)
+		if handoffSummaries == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected handoff summary to be generated, got %d", handoffSummaries)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if atomic.LoadInt32(&calls) != 4 {
+		t.Fatalf("expected skipped handoff summaries not to call the provider, calls=%d", calls)
+	}
+}
+
+// waitForTicketSummaryIdle 等待后台摘要任务结束
+func waitForTicketSummaryIdle(t *testing.T, svc *TicketSummaryService, ticketID uint) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, running := svc.inflight.Load(ticketID); !running {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("background summary for ticket %d did not finish", ticketID)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestParseTicketSummaryOutput_FallsBackToRawText(t *testing.T) {
+	result := parseTicketSummaryOutput("  The customer cannot log in.  ")
+	if result.Summary != "The customer cannot log in." || len(result.NextActions) != 0 {
+		t.Fatalf("unexpected fallback result %+v", result)
+	}
+	result = parseTicketSummaryOutput(`{"summary": "", "next_actions": ["a"]}`)
+	if result.Summary != `{"summary": "", "next_actions": ["a"]}` {
+		t.Fatalf("expected empty json summary to fall back to raw text, got %+v", result)
+	}
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+// KeyTicketSurveyPolicy 满意度调查策略配置键
+const KeyTicketSurveyPolicy = "ticket.survey_policy"
+
+var (
+	// ErrSurveyNotAvailable 工单尚未解决或关闭，不能评分
+	ErrSurveyNotAvailable = errors.New("survey is only available for resolved or closed tickets")
+	// ErrSurveyAlreadySubmitted 工单已评分且策略不允许修改
+	ErrSurveyAlreadySubmitted = errors.New("survey already submitted")
+	// ErrSurveyForbidden 只有工单提交人可以评分
+	ErrSurveyForbidden = errors.New("only the ticket requester can submit the survey")
+)
+
+// TicketSurveyService 满意度调查服务，提交评分后触发 survey.* 自动化规则
+type TicketSurveyService struct {
+	db                *gorm.DB
+	automationService *AutomationService
+}
+
+// NewTicketSurveyService 创建满意度调查服务
+func NewTicketSurveyService(db *gorm.DB) *TicketSurveyService {
+	return &TicketSurveyService{
+		db:                db,
+		automationService: NewAutomationService(db),
+	}
+}
+
+// GetPolicy 获取满意度调查策略
+func (s *TicketSurveyService) GetPolicy(ctx context.Context) (*models.SurveyPolicy, error) {
+	var config models.SystemConfig
+	err := s.db.WithContext(ctx).
+		Where("key = ? AND is_active = ?", KeyTicketSurveyPolicy, true).
+		First(&config).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.GetDefaultSurveyPolicy(), nil
+		}
+		return nil, fmt.Errorf("failed to get survey policy: %w", err)
+	}
+
+	policy := models.GetDefaultSurveyPolicy()
+	if err := config.GetJSONValue(policy); err != nil {
+		log.Printf("Warning: failed to parse survey policy, using defaults: %v", err)
+		return models.GetDefaultSurveyPolicy(), nil
+	}
+	if err := policy.Validate(); err != nil {
+		log.Printf("Warning: invalid survey policy, using defaults: %v", err)
+		return models.GetDefaultSurveyPolicy(), nil
+	}
+	return policy, nil
+}
+
+// SetPolicy 保存满意度调查策略
+func (s *TicketSurveyService) SetPolicy(ctx context.Context, policy *models.SurveyPolicy, userID uint) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	var existing models.SystemConfig
+	err := s.db.WithContext(ctx).Where("key = ?", KeyTicketSurveyPolicy).First(&existing).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to check existing policy: %w", err)
+	}
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		config := models.SystemConfig{
+			Key:         KeyTicketSurveyPolicy,
+			Category:    CategoryTicket,
+			Group:       "survey",
+			Description: "满意度调查策略",
+			IsActive:    true,
+			UpdatedBy:   &userID,
+		}
+		if err := config.SetValue(policy); err != nil {
+			return fmt.Errorf("failed to set policy value: %w", err)
+		}
+		if err := s.db.WithContext(ctx).Create(&config).Error; err != nil {
+			return fmt.Errorf("failed to create policy: %w", err)
+		}
+		return nil
+	}
+
+	if err := existing.SetValue(policy); err != nil {
+		return fmt.Errorf("failed to set policy value: %w", err)
+	}
+	existing.UpdatedBy = &userID
+	existing.Version++
+	if err := s.db.WithContext(ctx).Save(&existing).Error; err != nil {
+		return fmt.Errorf("failed to update policy: %w", err)
+	}
+	return nil
+}
+
+// SubmitSurvey 提交满意度评分，并依次触发 survey.submitted 与 survey.low_score 规则。
+// 管理员和主管可以代客户录入（如电话回访）
+func (s *TicketSurveyService) SubmitSurvey(ctx context.Context, ticketID, userID uint, role string, req *models.TicketSurveyRequest) (*models.Ticket, error) {
+	policy, err := s.GetPolicy(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var ticket models.Ticket
+	if err := s.db.WithContext(ctx).Where("deleted_at IS NULL").First(&ticket, ticketID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: %d", ErrTicketNotFound, ticketID)
+		}
+		return nil, fmt.Errorf("failed to get ticket: %w", err)
+	}
+
+	viewer := CommentViewer{UserID: userID, Role: role}
+	if ticket.CreatedByID != userID && !viewer.seesAllTeams() {
+		return nil, ErrSurveyForbidden
+	}
+	if ticket.Status != models.TicketStatusResolved && ticket.Status != models.TicketStatusClosed {
+		return nil, ErrSurveyNotAvailable
+	}
+	if ticket.Rating != nil && !policy.AllowResubmit {
+		return nil, ErrSurveyAlreadySubmitted
+	}
+
+	oldValue := ""
+	if ticket.Rating != nil {
+		oldValue = strconv.Itoa(*ticket.Rating)
+	}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Ticket{}).Where("id = ?", ticket.ID).
+			Updates(map[string]interface{}{
+				"rating":         req.Rating,
+				"rating_comment": req.Comment,
+				"updated_at":     time.Now(),
+			}).Error; err != nil {
+			return fmt.Errorf("failed to save rating: %w", err)
+		}
+
+		history := &models.TicketHistory{
+			TicketID:    ticket.ID,
+			UserID:      &userID,
+			Action:      models.HistoryActionUpdate,
+			Description: fmt.Sprintf("提交满意度评分：%d 分", req.Rating),
+			FieldName:   "rating",
+			OldValue:    oldValue,
+			NewValue:    strconv.Itoa(req.Rating),
+			IsVisible:   true,
+		}
+		return tx.Create(history).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rating := req.Rating
+	ticket.Rating = &rating
+	ticket.RatingComment = req.Comment
+
+	if err := s.automationService.ExecuteRules(ctx, models.TriggerSurveySubmitted, &ticket); err != nil {
+		log.Printf("Failed to run survey.submitted rules for ticket %d: %v", ticket.ID, err)
+	}
+	if rating <= policy.LowScoreThreshold {
+		if err := s.automationService.ExecuteRules(ctx, models.TriggerSurveyLowScore, &ticket); err != nil {
+			log.Printf("Failed to run survey.low_score rules for ticket %d: %v", ticket.ID, err)
+		}
+	}
+
+	// 规则可能已重新打开或改派工单，返回最新状态
+	if err := s.db.WithContext(ctx).First(&ticket, ticket.ID).Error; err != nil {
+		return nil, fmt.Errorf("failed to reload ticket: %w", err)
+	}
+	return &ticket, nil
+}
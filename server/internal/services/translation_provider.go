@@ -0,0 +1,242 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"gongdan-system/internal/models"
+)
+
+// Translator 翻译服务提供方
+type Translator interface {
+	Provider() models.TranslationProvider
+	// Translate 将 text 翻译为 targetLanguage，返回译文和识别出的原文语言
+	Translate(ctx context.Context, text, targetLanguage string) (*TranslationResult, error)
+}
+
+// TranslationResult 翻译结果
+type TranslationResult struct {
+	Text           string
+	SourceLanguage string
+}
+
+// TranslatorConfig 翻译服务配置
+type TranslatorConfig struct {
+	Provider models.TranslationProvider
+	APIKey   string
+	Region   string // Azure 资源所在区域
+	Endpoint string // 为空时使用服务商默认地址
+}
+
+// NewTranslator 按配置创建翻译服务客户端
+func NewTranslator(config *TranslatorConfig, client *http.Client) (Translator, error) {
+	if config.APIKey == "" {
+		return nil, ErrTranslationNotConfigured
+	}
+	switch config.Provider {
+	case models.TranslationProviderDeepL:
+		endpoint := config.Endpoint
+		if endpoint == "" {
+			// DeepL 免费版密钥以 :fx 结尾，使用单独的域名
+			endpoint = "https://api.deepl.com/v2/translate"
+			if strings.HasSuffix(config.APIKey, ":fx") {
+				endpoint = "https://api-free.deepl.com/v2/translate"
+			}
+		}
+		return &deepLTranslator{apiKey: config.APIKey, endpoint: endpoint, client: client}, nil
+	case models.TranslationProviderGoogle:
+		endpoint := config.Endpoint
+		if endpoint == "" {
+			endpoint = "https://translation.googleapis.com/language/translate/v2"
+		}
+		return &googleTranslator{apiKey: config.APIKey, endpoint: endpoint, client: client}, nil
+	case models.TranslationProviderAzure:
+		endpoint := config.Endpoint
+		if endpoint == "" {
+			endpoint = "https://api.cognitive.microsofttranslator.com/translate"
+		}
+		return &azureTranslator{apiKey: config.APIKey, region: config.Region, endpoint: endpoint, client: client}, nil
+	}
+	return nil, fmt.Errorf("%w: unknown provider %q", ErrTranslationNotConfigured, config.Provider)
+}
+
+// deepLTranslator DeepL API v2
+type deepLTranslator struct {
+	apiKey   string
+	endpoint string
+	client   *http.Client
+}
+
+func (t *deepLTranslator) Provider() models.TranslationProvider {
+	return models.TranslationProviderDeepL
+}
+
+func (t *deepLTranslator) Translate(ctx context.Context, text, targetLanguage string) (*TranslationResult, error) {
+	body := map[string]interface{}{
+		"text":        []string{text},
+		"target_lang": deepLLanguage(targetLanguage),
+	}
+	headers := map[string]string{"Authorization": "DeepL-Auth-Key " + t.apiKey}
+
+	var resp struct {
+		Translations []struct {
+			DetectedSourceLanguage string `json:"detected_source_language"`
+			Text                   string `json:"text"`
+		} `json:"translations"`
+	}
+	if err := postTranslationJSON(ctx, t.client, t.endpoint, headers, body, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Translations) == 0 {
+		return nil, fmt.Errorf("%w: empty response", ErrTranslationFailed)
+	}
+	return &TranslationResult{
+		Text:           resp.Translations[0].Text,
+		SourceLanguage: strings.ToLower(resp.Translations[0].DetectedSourceLanguage),
+	}, nil
+}
+
+// googleTranslator Google Cloud Translation API v2
+type googleTranslator struct {
+	apiKey   string
+	endpoint string
+	client   *http.Client
+}
+
+func (t *googleTranslator) Provider() models.TranslationProvider {
+	return models.TranslationProviderGoogle
+}
+
+func (t *googleTranslator) Translate(ctx context.Context, text, targetLanguage string) (*TranslationResult, error) {
+	body := map[string]interface{}{
+		"q":      []string{text},
+		"target": targetLanguage,
+		"format": "text",
+	}
+
+	var resp struct {
+		Data struct {
+			Translations []struct {
+				TranslatedText         string `json:"translatedText"`
+				DetectedSourceLanguage string `json:"detectedSourceLanguage"`
+			} `json:"translations"`
+		} `json:"data"`
+	}
+	endpoint := t.endpoint + "?key=" + url.QueryEscape(t.apiKey)
+	if err := postTranslationJSON(ctx, t.client, endpoint, nil, body, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Data.Translations) == 0 {
+		return nil, fmt.Errorf("%w: empty response", ErrTranslationFailed)
+	}
+	return &TranslationResult{
+		Text:           resp.Data.Translations[0].TranslatedText,
+		SourceLanguage: strings.ToLower(resp.Data.Translations[0].DetectedSourceLanguage),
+	}, nil
+}
+
+// azureTranslator Azure AI Translator v3
+type azureTranslator struct {
+	apiKey   string
+	region   string
+	endpoint string
+	client   *http.Client
+}
+
+func (t *azureTranslator) Provider() models.TranslationProvider {
+	return models.TranslationProviderAzure
+}
+
+func (t *azureTranslator) Translate(ctx context.Context, text, targetLanguage string) (*TranslationResult, error) {
+	body := []map[string]string{{"Text": text}}
+	headers := map[string]string{"Ocp-Apim-Subscription-Key": t.apiKey}
+	if t.region != "" {
+		headers["Ocp-Apim-Subscription-Region"] = t.region
+	}
+
+	var resp []struct {
+		DetectedLanguage struct {
+			Language string `json:"language"`
+		} `json:"detectedLanguage"`
+		Translations []struct {
+			Text string `json:"text"`
+		} `json:"translations"`
+	}
+	endpoint := t.endpoint + "?api-version=3.0&to=" + url.QueryEscape(azureLanguage(targetLanguage))
+	if err := postTranslationJSON(ctx, t.client, endpoint, headers, body, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp) == 0 || len(resp[0].Translations) == 0 {
+		return nil, fmt.Errorf("%w: empty response", ErrTranslationFailed)
+	}
+	return &TranslationResult{
+		Text:           resp[0].Translations[0].Text,
+		SourceLanguage: strings.ToLower(resp[0].DetectedLanguage.Language),
+	}, nil
+}
+
+// postTranslationJSON 发送 JSON 请求并解析响应，非 2xx 状态码返回 ErrTranslationFailed
+func postTranslationJSON(ctx context.Context, client *http.Client, endpoint string, headers map[string]string, body, out interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrTranslationFailed, err)
+	}
+	defer resp.Body.Close()
+
+	payload, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrTranslationFailed, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%w: provider returned %d: %s", ErrTranslationFailed, resp.StatusCode, truncateString(string(payload), 200))
+	}
+	if err := json.Unmarshal(payload, out); err != nil {
+		return fmt.Errorf("%w: invalid response: %v", ErrTranslationFailed, err)
+	}
+	return nil
+}
+
+// deepLLanguage 转换为 DeepL 目标语言代码，如 zh-CN -> ZH-HANS，en -> EN-US
+func deepLLanguage(language string) string {
+	switch lower := strings.ToLower(language); {
+	case lower == "zh-tw" || lower == "zh-hk" || lower == "zh-hant":
+		return "ZH-HANT"
+	case strings.HasPrefix(lower, "zh"):
+		return "ZH-HANS"
+	case lower == "en":
+		return "EN-US"
+	case lower == "pt":
+		return "PT-BR"
+	}
+	return strings.ToUpper(language)
+}
+
+// azureLanguage 转换为 Azure 目标语言代码，如 zh-CN -> zh-Hans
+func azureLanguage(language string) string {
+	switch lower := strings.ToLower(language); {
+	case lower == "zh-tw" || lower == "zh-hk" || lower == "zh-hant":
+		return "zh-Hant"
+	case strings.HasPrefix(lower, "zh"):
+		return "zh-Hans"
+	}
+	return language
+}
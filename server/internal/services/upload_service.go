@@ -0,0 +1,548 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+// 预签名上传参数
+const (
+	// UploadChunkSize 分片上传的分片大小，最后一个分片可以更小
+	UploadChunkSize = 5 << 20
+
+	uploadURLTTL        = 15 * time.Minute // 上传地址有效期
+	uploadRetention     = 24 * time.Hour   // 上传地址过期后仍可随评论提交的时间，过后视为孤立文件
+	uploadPurgeBatch    = 500
+	maxUploadsPerSubmit = 20
+	uploadIDRandomSize  = 16
+
+	// uploadInlineScheme 评论内容中以 upload://<upload_id> 引用上传的文件（如粘贴的图片），提交时替换为附件下载地址
+	uploadInlineScheme           = "upload://"
+	ticketAttachmentDownloadPath = "/api/tickets/%d/attachments/%d/download"
+)
+
+var (
+	// ErrUploadNotFound 上传不存在、不属于当前用户或已提交
+	ErrUploadNotFound = errors.New("upload not found")
+	// ErrUploadExpired 上传地址已过期
+	ErrUploadExpired = errors.New("upload expired")
+	// ErrInvalidUpload 上传参数或分片无效
+	ErrInvalidUpload = errors.New("invalid upload")
+	// ErrUploadIncomplete 文件尚未上传完成
+	ErrUploadIncomplete = errors.New("upload not completed")
+)
+
+// PresignedFileStorage 支持预签名直传的存储后端（如 S3）。
+// 本地存储不实现该接口，改为分片上传到本服务
+type PresignedFileStorage interface {
+	FileStorage
+	// PresignPut 签发直传地址，返回上传地址及客户端上传时须携带的请求头
+	PresignPut(ctx context.Context, key, contentType string, size int64, expires time.Duration) (string, map[string]string, error)
+}
+
+// UploadService 预签名上传服务：签发地址时按工单分类的附件策略校验大小与类型，
+// 文件随评论提交时转为工单附件，过期未提交的由清理任务删除
+type UploadService struct {
+	db          *gorm.DB
+	storage     FileStorage
+	attachments *TicketAttachmentService
+	urlPrefix   string
+}
+
+// NewUploadService 创建预签名上传服务，urlPrefix 为分片上传接口的路径前缀，如 /api/uploads
+func NewUploadService(db *gorm.DB, storage FileStorage, urlPrefix string) *UploadService {
+	return &UploadService{
+		db:          db,
+		storage:     storage,
+		attachments: NewTicketAttachmentService(db, storage),
+		urlPrefix:   strings.TrimRight(urlPrefix, "/"),
+	}
+}
+
+// SetCipher 设置附件加解密器，分片上传合并后的文件加密存储；直传对象存储的文件由存储端加密
+func (s *UploadService) SetCipher(cipher *AttachmentCipher) {
+	s.attachments.SetCipher(cipher)
+}
+
+// Presign 签发上传地址。按声明的大小与类型校验附件策略，工单的附件数（含未提交的上传）不能超过上限
+func (s *UploadService) Presign(ctx context.Context, userID uint, req *models.UploadPresignRequest, now time.Time) (*models.UploadPresignResponse, error) {
+	schema, err := s.attachments.ticketSchema(ctx, req.TicketID)
+	if err != nil {
+		return nil, err
+	}
+	reject := func(reason, mimeType string, size int64) error {
+		return &AttachmentRejectedError{Reason: reason, MimeType: mimeType, Size: size, Schema: schema}
+	}
+
+	contentType := strings.ToLower(strings.TrimSpace(strings.SplitN(req.ContentType, ";", 2)[0]))
+	if req.Size <= 0 {
+		return nil, fmt.Errorf("%w: size must be positive", ErrInvalidUpload)
+	}
+	if req.Size > schema.MaxFileSizeBytes {
+		return nil, reject(AttachmentRejectTooLarge, "", req.Size)
+	}
+	if !(&models.AttachmentRule{AllowedMimeTypes: schema.AllowedMimeTypes}).AllowsMimeType(contentType) {
+		return nil, reject(AttachmentRejectMimeType, contentType, 0)
+	}
+	if schema.MaxCount > 0 {
+		count, err := countTicketAttachments(s.db.WithContext(ctx), req.TicketID)
+		if err != nil {
+			return nil, err
+		}
+		var pending int64
+		if err := liveUploads(s.db.WithContext(ctx), now).Model(&models.PendingUpload{}).
+			Where("ticket_id = ?", req.TicketID).Count(&pending).Error; err != nil {
+			return nil, fmt.Errorf("failed to count uploads: %w", err)
+		}
+		if count+pending >= int64(schema.MaxCount) {
+			return nil, reject(AttachmentRejectTooManyFiles, "", 0)
+		}
+	}
+	if err := s.attachments.quotaService.CheckAttachmentStorage(ctx, req.Size); err != nil {
+		return nil, err
+	}
+
+	originalName, ext := attachmentOriginalName(req.FileName)
+	key, err := attachmentKey(req.TicketID, ext)
+	if err != nil {
+		return nil, err
+	}
+	uploadID, err := newUploadID()
+	if err != nil {
+		return nil, err
+	}
+	upload := &models.PendingUpload{
+		UploadID:     uploadID,
+		UserID:       userID,
+		TicketID:     req.TicketID,
+		Status:       models.PendingUploadStatusPending,
+		OriginalName: originalName,
+		Extension:    ext,
+		DeclaredSize: req.Size,
+		StoragePath:  key,
+		StorageType:  s.storage.Type(),
+		ExpiresAt:    now.Add(uploadURLTTL),
+	}
+	resp := &models.UploadPresignResponse{
+		UploadID:  uploadID,
+		Method:    http.MethodPut,
+		MaxSize:   schema.MaxFileSizeBytes,
+		ExpiresAt: upload.ExpiresAt,
+	}
+	if presigner, ok := s.storage.(PresignedFileStorage); ok {
+		uploadURL, headers, err := presigner.PresignPut(ctx, key, contentType, req.Size, uploadURLTTL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to presign upload: %w", err)
+		}
+		upload.Mode = models.UploadModeDirect
+		resp.UploadURL = uploadURL
+		resp.Headers = headers
+	} else {
+		upload.Mode = models.UploadModeChunked
+		resp.UploadURL = s.urlPrefix + "/" + uploadID
+		resp.ChunkSize = UploadChunkSize
+	}
+	resp.Mode = upload.Mode
+
+	if err := s.db.WithContext(ctx).Create(upload).Error; err != nil {
+		return nil, fmt.Errorf("failed to create upload: %w", err)
+	}
+	return resp, nil
+}
+
+// PutChunk 接收分片上传的一个分片。分片须按顺序上传，start 为分片起始偏移，total 为文件总大小；
+// 最后一个分片到达后合并分片、按内容识别类型并校验附件策略
+func (s *UploadService) PutChunk(ctx context.Context, userID uint, uploadID string, start, total int64, r io.Reader, now time.Time) (*models.UploadChunkResponse, error) {
+	upload, err := s.get(ctx, userID, uploadID)
+	if err != nil {
+		return nil, err
+	}
+	if upload.Mode != models.UploadModeChunked {
+		return nil, fmt.Errorf("%w: upload does not accept chunks", ErrInvalidUpload)
+	}
+	if upload.Status != models.PendingUploadStatusPending {
+		return nil, fmt.Errorf("%w: upload already completed", ErrInvalidUpload)
+	}
+	if now.After(upload.ExpiresAt) {
+		return nil, ErrUploadExpired
+	}
+	if total != upload.DeclaredSize {
+		return nil, fmt.Errorf("%w: total size %d does not match declared size %d", ErrInvalidUpload, total, upload.DeclaredSize)
+	}
+	if start != upload.ReceivedSize {
+		return nil, fmt.Errorf("%w: expected chunk starting at byte %d", ErrInvalidUpload, upload.ReceivedSize)
+	}
+
+	limit := upload.DeclaredSize - upload.ReceivedSize
+	if limit > UploadChunkSize {
+		limit = UploadChunkSize
+	}
+	chunkKey := uploadChunkKey(upload.UploadID, upload.ChunkCount)
+	counter := &attachmentCounter{hash: sha256.New()}
+	if err := s.storage.Put(ctx, chunkKey, io.TeeReader(io.LimitReader(r, limit+1), counter), "application/octet-stream"); err != nil {
+		return nil, err
+	}
+	if counter.size == 0 || counter.size > limit {
+		s.deleteStored(ctx, chunkKey)
+		return nil, fmt.Errorf("%w: chunk must be 1-%d bytes", ErrInvalidUpload, limit)
+	}
+
+	// 以已接收大小作为版本，并发提交同一分片时只有一个生效
+	result := s.db.WithContext(ctx).Model(&models.PendingUpload{}).
+		Where("id = ? AND status = ? AND received_size = ?", upload.ID, models.PendingUploadStatusPending, start).
+		Updates(map[string]interface{}{
+			"received_size": start + counter.size,
+			"chunk_count":   upload.ChunkCount + 1,
+		})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to update upload: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, fmt.Errorf("%w: chunk was uploaded concurrently", ErrInvalidUpload)
+	}
+	upload.ReceivedSize = start + counter.size
+	upload.ChunkCount++
+
+	if upload.ReceivedSize == upload.DeclaredSize {
+		if err := s.assemble(ctx, upload); err != nil {
+			return nil, err
+		}
+	}
+	return &models.UploadChunkResponse{
+		UploadID:     upload.UploadID,
+		Status:       upload.Status,
+		ReceivedSize: upload.ReceivedSize,
+		TotalSize:    upload.DeclaredSize,
+		MimeType:     upload.MimeType,
+	}, nil
+}
+
+// ResolveUploads 检查随评论提交的上传：必须由当前用户针对该工单上传且已上传完成，
+// 直传的文件在此时按内容校验。提交后工单的附件数不能超过上限
+func (s *UploadService) ResolveUploads(ctx context.Context, ticketID, userID uint, uploadIDs []string, now time.Time) ([]*models.PendingUpload, error) {
+	ids := make([]string, 0, len(uploadIDs))
+	seen := make(map[string]bool, len(uploadIDs))
+	for _, id := range uploadIDs {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	if len(ids) > maxUploadsPerSubmit {
+		return nil, fmt.Errorf("%w: at most %d uploads per submit", ErrInvalidUpload, maxUploadsPerSubmit)
+	}
+
+	var found []*models.PendingUpload
+	if err := s.db.WithContext(ctx).
+		Where("upload_id IN ? AND user_id = ? AND ticket_id = ?", ids, userID, ticketID).
+		Find(&found).Error; err != nil {
+		return nil, fmt.Errorf("failed to get uploads: %w", err)
+	}
+	byID := make(map[string]*models.PendingUpload, len(found))
+	for _, upload := range found {
+		byID[upload.UploadID] = upload
+	}
+
+	uploads := make([]*models.PendingUpload, 0, len(ids))
+	for _, id := range ids {
+		upload, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUploadNotFound, id)
+		}
+		if now.After(upload.ExpiresAt.Add(uploadRetention)) {
+			return nil, fmt.Errorf("%w: %s", ErrUploadExpired, id)
+		}
+		if upload.Status == models.PendingUploadStatusPending {
+			if upload.Mode != models.UploadModeDirect {
+				return nil, fmt.Errorf("%w: %s", ErrUploadIncomplete, id)
+			}
+			if err := s.verifyDirect(ctx, upload); err != nil {
+				return nil, err
+			}
+		}
+		uploads = append(uploads, upload)
+	}
+
+	schema, err := s.attachments.ticketSchema(ctx, ticketID)
+	if err != nil {
+		return nil, err
+	}
+	if schema.MaxCount > 0 {
+		count, err := countTicketAttachments(s.db.WithContext(ctx), ticketID)
+		if err != nil {
+			return nil, err
+		}
+		if count+int64(len(uploads)) > int64(schema.MaxCount) {
+			return nil, &AttachmentRejectedError{Reason: AttachmentRejectTooManyFiles, Schema: schema}
+		}
+	}
+	return uploads, nil
+}
+
+// attachUploads 在评论的事务中将上传转为评论的附件，评论内容中的 upload://<upload_id> 替换为附件下载地址
+func (s *UploadService) attachUploads(tx *gorm.DB, comment *models.TicketComment, uploads []*models.PendingUpload) error {
+	if len(uploads) == 0 {
+		return nil
+	}
+
+	var links []string
+	if comment.Attachments != "" {
+		if err := json.Unmarshal([]byte(comment.Attachments), &links); err != nil {
+			links = nil
+		}
+	}
+	content := comment.Content
+	for _, upload := range uploads {
+		attachment := &models.TicketAttachment{
+			TicketID:     comment.TicketID,
+			CommentID:    &comment.ID,
+			UploadedBy:   upload.UserID,
+			FileName:     path.Base(upload.StoragePath),
+			OriginalName: upload.OriginalName,
+			FileSize:     upload.DeclaredSize,
+			MimeType:     upload.MimeType,
+			FileType:     attachmentFileType(upload.MimeType, upload.Extension),
+			Extension:    upload.Extension,
+			StoragePath:  upload.StoragePath,
+			StorageType:  upload.StorageType,
+			Encryption:   upload.Encryption,
+			Hash:         upload.Hash,
+			VirusScan:    "pending",
+		}
+		if err := tx.Create(attachment).Error; err != nil {
+			return fmt.Errorf("failed to create attachment: %w", err)
+		}
+		// 只删除已上传完成的记录，同一上传被并发提交时只有一个成功
+		result := tx.Where("id = ? AND status = ?", upload.ID, models.PendingUploadStatusUploaded).Delete(&models.PendingUpload{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to delete upload: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: %s", ErrUploadNotFound, upload.UploadID)
+		}
+		userID := upload.UserID
+		if err := recordHistory(tx, nil, &models.TicketHistory{
+			TicketID:     comment.TicketID,
+			UserID:       &userID,
+			Action:       models.HistoryActionAttachment,
+			Description:  "上传附件 " + upload.OriginalName,
+			NewValue:     upload.OriginalName,
+			AttachmentID: &attachment.ID,
+			IsVisible:    true,
+		}); err != nil {
+			return err
+		}
+
+		link := fmt.Sprintf(ticketAttachmentDownloadPath, comment.TicketID, attachment.ID)
+		content = strings.ReplaceAll(content, uploadInlineScheme+upload.UploadID, link)
+		links = append(links, link)
+	}
+
+	data, _ := json.Marshal(links)
+	if err := tx.Model(&models.TicketComment{}).Where("id = ?", comment.ID).
+		Updates(map[string]interface{}{"content": content, "attachments": string(data)}).Error; err != nil {
+		return fmt.Errorf("failed to update comment attachments: %w", err)
+	}
+	comment.Content = content
+	comment.Attachments = string(data)
+	return nil
+}
+
+// PurgeOrphans 删除过期未提交的上传及其文件：分片上传未传完的在地址过期后删除，
+// 其余在地址过期且超过提交保留时间后删除
+func (s *UploadService) PurgeOrphans(ctx context.Context, now time.Time) (int, error) {
+	purged := 0
+	for {
+		var uploads []*models.PendingUpload
+		if err := s.db.WithContext(ctx).
+			Where("(mode = ? AND status = ? AND expires_at < ?) OR expires_at < ?",
+				models.UploadModeChunked, models.PendingUploadStatusPending, now, now.Add(-uploadRetention)).
+			Order("id ASC").
+			Limit(uploadPurgeBatch).
+			Find(&uploads).Error; err != nil {
+			return purged, fmt.Errorf("failed to find orphaned uploads: %w", err)
+		}
+		for _, upload := range uploads {
+			if err := s.discard(ctx, upload); err != nil {
+				return purged, err
+			}
+			purged++
+		}
+		if len(uploads) < uploadPurgeBatch {
+			return purged, nil
+		}
+	}
+}
+
+func (s *UploadService) get(ctx context.Context, userID uint, uploadID string) (*models.PendingUpload, error) {
+	var upload models.PendingUpload
+	err := s.db.WithContext(ctx).Where("upload_id = ? AND user_id = ?", uploadID, userID).First(&upload).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrUploadNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get upload: %w", err)
+	}
+	return &upload, nil
+}
+
+// assemble 合并分片为最终文件。失败时删除上传，客户端需重新申请上传地址
+func (s *UploadService) assemble(ctx context.Context, upload *models.PendingUpload) error {
+	readers := make([]io.Reader, 0, upload.ChunkCount)
+	closers := make([]io.Closer, 0, upload.ChunkCount)
+	defer func() {
+		for _, closer := range closers {
+			closer.Close()
+		}
+	}()
+	var err error
+	for i := 0; i < upload.ChunkCount && err == nil; i++ {
+		var chunk io.ReadCloser
+		if chunk, err = s.storage.Open(ctx, uploadChunkKey(upload.UploadID, i)); err == nil {
+			readers = append(readers, chunk)
+			closers = append(closers, chunk)
+		}
+	}
+	if err == nil {
+		err = s.complete(ctx, upload, io.MultiReader(readers...), true)
+	}
+	for i := 0; i < upload.ChunkCount; i++ {
+		s.deleteStored(ctx, uploadChunkKey(upload.UploadID, i))
+	}
+	if err != nil {
+		upload.ChunkCount = 0
+		if discardErr := s.discard(ctx, upload); discardErr != nil {
+			log.Printf("Warning: failed to discard upload %s: %v", upload.UploadID, discardErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// verifyDirect 校验直传到对象存储的文件，大小不足时视为尚未上传完成，违反附件策略时删除上传
+func (s *UploadService) verifyDirect(ctx context.Context, upload *models.PendingUpload) error {
+	reader, err := s.storage.Open(ctx, upload.StoragePath)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrUploadIncomplete, upload.UploadID)
+	}
+	defer reader.Close()
+
+	err = s.complete(ctx, upload, reader, false)
+	if errors.Is(err, ErrAttachmentRejected) {
+		if discardErr := s.discard(ctx, upload); discardErr != nil {
+			log.Printf("Warning: failed to discard upload %s: %v", upload.UploadID, discardErr)
+		}
+	}
+	return err
+}
+
+// complete 按内容识别类型并校验附件策略，大小须与声明一致；store 为 true 时写入最终存储键
+func (s *UploadService) complete(ctx context.Context, upload *models.PendingUpload, r io.Reader, store bool) error {
+	schema, err := s.attachments.ticketSchema(ctx, upload.TicketID)
+	if err != nil {
+		return err
+	}
+
+	buffered := bufio.NewReaderSize(r, attachmentSniffLen)
+	head, err := buffered.Peek(attachmentSniffLen)
+	if err != nil && err != io.EOF && !errors.Is(err, bufio.ErrBufferFull) {
+		return fmt.Errorf("failed to read upload: %w", err)
+	}
+	mimeType := attachmentMimeType(head, upload.Extension)
+	if !(&models.AttachmentRule{AllowedMimeTypes: schema.AllowedMimeTypes}).AllowsMimeType(mimeType) {
+		return &AttachmentRejectedError{Reason: AttachmentRejectMimeType, MimeType: mimeType, Schema: schema}
+	}
+
+	counter := &attachmentCounter{hash: sha256.New()}
+	limited := io.LimitReader(buffered, upload.DeclaredSize+1)
+	if store {
+		upload.Encryption, err = s.attachments.putFile(ctx, upload.StoragePath, io.TeeReader(limited, counter), mimeType)
+	} else {
+		_, err = io.Copy(counter, limited)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to store upload: %w", err)
+	}
+	if counter.size > upload.DeclaredSize {
+		return &AttachmentRejectedError{Reason: AttachmentRejectTooLarge, MimeType: mimeType, Size: counter.size, Schema: schema}
+	}
+	if counter.size < upload.DeclaredSize {
+		return fmt.Errorf("%w: %s", ErrUploadIncomplete, upload.UploadID)
+	}
+
+	upload.Status = models.PendingUploadStatusUploaded
+	upload.MimeType = mimeType
+	upload.Hash = hex.EncodeToString(counter.hash.Sum(nil))
+	if err := s.db.WithContext(ctx).Model(&models.PendingUpload{}).Where("id = ?", upload.ID).
+		Updates(map[string]interface{}{
+			"status":                  upload.Status,
+			"mime_type":               upload.MimeType,
+			"hash":                    upload.Hash,
+			"encryption_algorithm":    upload.Encryption.Algorithm,
+			"encryption_key_id":       upload.Encryption.KeyID,
+			"encryption_wrapped_key":  upload.Encryption.WrappedKey,
+			"encryption_nonce_prefix": upload.Encryption.NoncePrefix,
+			"encryption_wrapped_at":   upload.Encryption.WrappedAt,
+		}).Error; err != nil {
+		return fmt.Errorf("failed to update upload: %w", err)
+	}
+	return nil
+}
+
+// discard 删除上传记录及已写入的分片和文件
+func (s *UploadService) discard(ctx context.Context, upload *models.PendingUpload) error {
+	for i := 0; i < upload.ChunkCount; i++ {
+		s.deleteStored(ctx, uploadChunkKey(upload.UploadID, i))
+	}
+	s.deleteStored(ctx, upload.StoragePath)
+	if err := s.db.WithContext(ctx).Delete(&models.PendingUpload{}, upload.ID).Error; err != nil {
+		return fmt.Errorf("failed to delete upload: %w", err)
+	}
+	return nil
+}
+
+func (s *UploadService) deleteStored(ctx context.Context, key string) {
+	if err := s.storage.Delete(ctx, key); err != nil {
+		log.Printf("Warning: failed to delete upload file %s: %v", key, err)
+	}
+}
+
+// liveUploads 未过期的上传：上传中的在地址过期前有效，已上传完成的在提交保留时间内有效
+func liveUploads(db *gorm.DB, now time.Time) *gorm.DB {
+	return db.Where("((status = ? AND expires_at > ?) OR (status = ? AND expires_at > ?))",
+		models.PendingUploadStatusPending, now, models.PendingUploadStatusUploaded, now.Add(-uploadRetention))
+}
+
+// uploadChunkKey 分片的存储键
+func uploadChunkKey(uploadID string, index int) string {
+	return fmt.Sprintf("uploads/%s/%d", uploadID, index)
+}
+
+// newUploadID 生成上传ID，作为分片上传地址的一部分，不可猜测
+func newUploadID() (string, error) {
+	random := make([]byte, uploadIDRandomSize)
+	if _, err := rand.Read(random); err != nil {
+		return "", fmt.Errorf("failed to generate upload id: %w", err)
+	}
+	return hex.EncodeToString(random), nil
+}
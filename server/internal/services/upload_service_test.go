@@ -0,0 +1,126 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestUploadService_PresignChunkedUploadAndCommentSubmit(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:upload_service_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Team{}, &models.Ticket{}, &models.TicketComment{}, &models.TicketHistory{},
+		&models.SystemConfig{}, &models.TicketCommentDraft{}, &models.TicketReplyLock{}, &models.TicketAttachment{}, &models.PendingUpload{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+	ctx := context.Background()
+	now := time.Now()
+
+	agent := models.User{Username: "upload-agent", Email: "upload-agent@example.com", PasswordHash: "x", Role: models.RoleAgent}
+	db.Create(&agent)
+	ticket := models.Ticket{TicketNumber: "T-UPLOAD-1", Title: "screenshot", Status: models.TicketStatusOpen, CreatedByID: agent.ID}
+	db.Create(&ticket)
+
+	baseDir := t.TempDir()
+	uploads := NewUploadService(db, NewLocalFileStorage(baseDir, "/uploads"), "/api/uploads")
+	comments := NewTicketCommentService(db, nil)
+	comments.SetUploadService(uploads)
+
+	// 签发时按声明的大小与类型校验附件策略
+	var rejected *AttachmentRejectedError
+	if _, err := uploads.Presign(ctx, agent.ID, &models.UploadPresignRequest{TicketID: ticket.ID, FileName: "big.png", Size: 11 << 20, ContentType: "image/png"}, now); !errors.As(err, &rejected) || rejected.Reason != AttachmentRejectTooLarge {
+		t.Fatalf("expected oversized upload to be rejected, got %v", err)
+	}
+	if _, err := uploads.Presign(ctx, agent.ID, &models.UploadPresignRequest{TicketID: ticket.ID, FileName: "a.exe", Size: 10, ContentType: "application/x-msdownload"}, now); !errors.As(err, &rejected) || rejected.Reason != AttachmentRejectMimeType {
+		t.Fatalf("expected disallowed type to be rejected, got %v", err)
+	}
+
+	// 本地存储不支持直传，按分片上传
+	content := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, UploadChunkSize)...)
+	size := int64(len(content))
+	presigned, err := uploads.Presign(ctx, agent.ID, &models.UploadPresignRequest{TicketID: ticket.ID, FileName: "paste.png", Size: size, ContentType: "image/png"}, now)
+	if err != nil {
+		t.Fatalf("presign failed: %v", err)
+	}
+	if presigned.Mode != models.UploadModeChunked || presigned.UploadURL != "/api/uploads/"+presigned.UploadID || presigned.ChunkSize != UploadChunkSize {
+		t.Fatalf("unexpected presign response %+v", presigned)
+	}
+	if _, err := uploads.PutChunk(ctx, agent.ID, presigned.UploadID, 100, size, bytes.NewReader(content[100:]), now); !errors.Is(err, ErrInvalidUpload) {
+		t.Fatalf("expected out-of-order chunk to be rejected, got %v", err)
+	}
+	progress, err := uploads.PutChunk(ctx, agent.ID, presigned.UploadID, 0, size, bytes.NewReader(content[:UploadChunkSize]), now)
+	if err != nil || progress.Status != models.PendingUploadStatusPending || progress.ReceivedSize != UploadChunkSize {
+		t.Fatalf("first chunk failed: %+v, %v", progress, err)
+	}
+
+	// 未传完的上传不能随评论提交
+	submit := &models.TicketCommentCreateRequest{Content: "see ![](upload://" + presigned.UploadID + ")", Type: models.CommentTypeInternal, UploadIDs: []string{presigned.UploadID}}
+	viewer := CommentViewer{UserID: agent.ID, Role: string(models.RoleAgent)}
+	if _, err := comments.CreateComment(ctx, ticket.ID, submit, viewer); !errors.Is(err, ErrUploadIncomplete) {
+		t.Fatalf("expected incomplete upload to block submit, got %v", err)
+	}
+
+	progress, err = uploads.PutChunk(ctx, agent.ID, presigned.UploadID, UploadChunkSize, size, bytes.NewReader(content[UploadChunkSize:]), now)
+	if err != nil || progress.Status != models.PendingUploadStatusUploaded || progress.MimeType != "image/png" {
+		t.Fatalf("last chunk failed: %+v, %v", progress, err)
+	}
+	if _, err := os.Stat(filepath.Join(baseDir, "uploads", presigned.UploadID, "0")); !os.IsNotExist(err) {
+		t.Fatalf("expected chunks to be removed after assembly, got %v", err)
+	}
+
+	comment, err := comments.CreateComment(ctx, ticket.ID, submit, viewer)
+	if err != nil {
+		t.Fatalf("submit failed: %v", err)
+	}
+	var attachment models.TicketAttachment
+	if err := db.Where("comment_id = ?", comment.ID).First(&attachment).Error; err != nil || attachment.FileSize != size || attachment.FileType != models.AttachmentTypeImage {
+		t.Fatalf("expected comment attachment, got %+v, %v", attachment, err)
+	}
+	if !strings.Contains(comment.Content, "/api/tickets/") || strings.Contains(comment.Content, "upload://") {
+		t.Fatalf("expected inline reference to be rewritten, got %q", comment.Content)
+	}
+	if _, err := comments.CreateComment(ctx, ticket.ID, submit, viewer); !errors.Is(err, ErrUploadNotFound) {
+		t.Fatalf("expected submitted upload to be consumed, got %v", err)
+	}
+
+	// 内容与声明类型不符时在合并后拒绝
+	fake, err := uploads.Presign(ctx, agent.ID, &models.UploadPresignRequest{TicketID: ticket.ID, FileName: "fake.png", Size: 4, ContentType: "image/png"}, now)
+	if err != nil {
+		t.Fatalf("presign failed: %v", err)
+	}
+	if _, err := uploads.PutChunk(ctx, agent.ID, fake.UploadID, 0, 4, strings.NewReader("MZ\x90\x00"), now); !errors.As(err, &rejected) || rejected.Reason != AttachmentRejectMimeType {
+		t.Fatalf("expected sniffed type to be rejected, got %v", err)
+	}
+
+	// 过期未传完的上传由清理任务删除
+	orphan, err := uploads.Presign(ctx, agent.ID, &models.UploadPresignRequest{TicketID: ticket.ID, FileName: "orphan.txt", Size: 10, ContentType: "text/plain"}, now)
+	if err != nil {
+		t.Fatalf("presign failed: %v", err)
+	}
+	if _, err := uploads.PutChunk(ctx, agent.ID, orphan.UploadID, 0, 10, strings.NewReader("hello"), now); err != nil {
+		t.Fatalf("chunk failed: %v", err)
+	}
+	if purged, err := uploads.PurgeOrphans(ctx, now); err != nil || purged != 0 {
+		t.Fatalf("expected nothing to purge before expiry, got %d, %v", purged, err)
+	}
+	if purged, err := uploads.PurgeOrphans(ctx, now.Add(time.Hour)); err != nil || purged != 1 {
+		t.Fatalf("expected orphaned upload to be purged, got %d, %v", purged, err)
+	}
+	if _, err := os.Stat(filepath.Join(baseDir, "uploads", orphan.UploadID, "0")); !os.IsNotExist(err) {
+		t.Fatalf("expected orphaned chunk to be deleted, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(baseDir, filepath.FromSlash(attachment.StoragePath))); err != nil {
+		t.Fatalf("expected submitted file to be kept, got %v", err)
+	}
+}
@@ -0,0 +1,631 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	maxUserFieldTextLength = 1000
+	maxUserFieldOptions    = 100
+	maxUserSkills          = 50
+	maxUserSkillLevel      = 5
+)
+
+var (
+	// ErrUserFieldNotFound 用户自定义字段不存在
+	ErrUserFieldNotFound = errors.New("user field not found")
+	// ErrUserFieldKeyExists 字段标识已存在
+	ErrUserFieldKeyExists = errors.New("user field key already exists")
+	// ErrUserFieldInvalid 字段定义或字段值无效
+	ErrUserFieldInvalid = errors.New("invalid user field")
+	// ErrUserSkillInvalid 技能标签无效
+	ErrUserSkillInvalid = errors.New("invalid user skill")
+	// ErrUserAttributesUserNotFound 用户不存在
+	ErrUserAttributesUserNotFound = errors.New("user not found")
+)
+
+var (
+	userFieldKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,63}$`)
+	userSkillPattern    = regexp.MustCompile(`^[a-z0-9][a-z0-9_.+-]{0,49}$`)
+)
+
+// UserAttributeService 用户自定义字段与坐席技能标签
+type UserAttributeService struct {
+	db *gorm.DB
+}
+
+// NewUserAttributeService 创建用户属性服务
+func NewUserAttributeService(db *gorm.DB) *UserAttributeService {
+	return &UserAttributeService{db: db}
+}
+
+// ListFieldDefinitions 获取字段定义，按排序值及ID排列
+func (s *UserAttributeService) ListFieldDefinitions(ctx context.Context, includeInactive bool) ([]models.UserFieldDefinition, error) {
+	query := s.db.WithContext(ctx).Order("sort_order ASC, id ASC")
+	if !includeInactive {
+		query = query.Where("is_active = ?", true)
+	}
+	var defs []models.UserFieldDefinition
+	if err := query.Find(&defs).Error; err != nil {
+		return nil, fmt.Errorf("failed to list user fields: %w", err)
+	}
+	return defs, nil
+}
+
+// CreateFieldDefinition 创建字段定义
+func (s *UserAttributeService) CreateFieldDefinition(ctx context.Context, req *models.UserFieldDefinitionRequest) (*models.UserFieldDefinition, error) {
+	def := &models.UserFieldDefinition{IsActive: true}
+	if err := applyUserFieldDefinition(def, req); err != nil {
+		return nil, err
+	}
+	if err := s.ensureKeyAvailable(ctx, def.Key, 0); err != nil {
+		return nil, err
+	}
+	if err := s.db.WithContext(ctx).Create(def).Error; err != nil {
+		return nil, fmt.Errorf("failed to create user field: %w", err)
+	}
+	// default:true 标签会忽略显式的 false
+	if !def.IsActive {
+		if err := s.db.WithContext(ctx).Model(def).Update("is_active", false).Error; err != nil {
+			return nil, fmt.Errorf("failed to create user field: %w", err)
+		}
+	}
+	return def, nil
+}
+
+// UpdateFieldDefinition 更新字段定义；已有值的字段不能修改类型，删除的选项不能仍被使用
+func (s *UserAttributeService) UpdateFieldDefinition(ctx context.Context, id uint, req *models.UserFieldDefinitionRequest) (*models.UserFieldDefinition, error) {
+	def, err := s.getDefinition(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	oldType, oldOptions := def.Type, def.OptionList
+	if err := applyUserFieldDefinition(def, req); err != nil {
+		return nil, err
+	}
+	if err := s.ensureKeyAvailable(ctx, def.Key, def.ID); err != nil {
+		return nil, err
+	}
+
+	var values []models.UserFieldValue
+	if err := s.db.WithContext(ctx).Where("field_id = ?", def.ID).Find(&values).Error; err != nil {
+		return nil, fmt.Errorf("failed to load user field values: %w", err)
+	}
+	if len(values) > 0 && oldType != def.Type {
+		return nil, fmt.Errorf("%w: cannot change the type of a field that has values", ErrUserFieldInvalid)
+	}
+	if len(values) > 0 && (def.Type == models.UserFieldTypeSelect || def.Type == models.UserFieldTypeMultiSelect) {
+		removed := make(map[string]bool)
+		for _, option := range oldOptions {
+			removed[option] = true
+		}
+		for _, option := range def.OptionList {
+			delete(removed, option)
+		}
+		for _, value := range values {
+			for _, selected := range decodeUserFieldOptions(def.Type, value.Value) {
+				if removed[selected] {
+					return nil, fmt.Errorf("%w: option %q is still in use", ErrUserFieldInvalid, selected)
+				}
+			}
+		}
+	}
+
+	if err := s.db.WithContext(ctx).Select("*").Omit("created_at").Save(def).Error; err != nil {
+		return nil, fmt.Errorf("failed to update user field: %w", err)
+	}
+	return def, nil
+}
+
+// DeleteFieldDefinition 删除字段定义及所有用户的值
+func (s *UserAttributeService) DeleteFieldDefinition(ctx context.Context, id uint) error {
+	if _, err := s.getDefinition(ctx, id); err != nil {
+		return err
+	}
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("field_id = ?", id).Delete(&models.UserFieldValue{}).Error; err != nil {
+			return fmt.Errorf("failed to delete user field values: %w", err)
+		}
+		if err := tx.Delete(&models.UserFieldDefinition{}, id).Error; err != nil {
+			return fmt.Errorf("failed to delete user field: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetUserAttributes 获取用户的字段值（仅启用的字段）及技能
+func (s *UserAttributeService) GetUserAttributes(ctx context.Context, userID uint) (*models.UserAttributes, error) {
+	if _, err := s.getUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	return s.loadAttributes(ctx, userID)
+}
+
+// SetFieldValues 设置用户的字段值，值为 null 或空字符串时删除该字段的值
+func (s *UserAttributeService) SetFieldValues(ctx context.Context, userID uint, values map[string]interface{}) (*models.UserAttributes, error) {
+	if _, err := s.getUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	defs, err := s.ListFieldDefinitions(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	byKey := make(map[string]*models.UserFieldDefinition, len(defs))
+	for i := range defs {
+		byKey[defs[i].Key] = &defs[i]
+	}
+
+	upserts := make([]models.UserFieldValue, 0, len(values))
+	var removals []uint
+	for key, raw := range values {
+		def, ok := byKey[key]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown field %q", ErrUserFieldInvalid, key)
+		}
+		value, err := normalizeUserFieldValue(def, raw)
+		if err != nil {
+			return nil, err
+		}
+		if value == "" {
+			removals = append(removals, def.ID)
+			continue
+		}
+		upserts = append(upserts, models.UserFieldValue{UserID: userID, FieldID: def.ID, Value: value})
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(removals) > 0 {
+			if err := tx.Where("user_id = ? AND field_id IN ?", userID, removals).Delete(&models.UserFieldValue{}).Error; err != nil {
+				return fmt.Errorf("failed to clear user field values: %w", err)
+			}
+		}
+		if len(upserts) == 0 {
+			return nil
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "field_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"value", "updated_at"}),
+		}).Create(&upserts).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.loadAttributes(ctx, userID)
+}
+
+// SetSkills 替换坐席的技能标签，客户账户不能设置技能
+func (s *UserAttributeService) SetSkills(ctx context.Context, userID uint, inputs []models.UserSkillInput) (*models.UserAttributes, error) {
+	user, err := s.getUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.Role == models.RoleCustomer && len(inputs) > 0 {
+		return nil, fmt.Errorf("%w: skills can only be assigned to agents", ErrUserSkillInvalid)
+	}
+	if len(inputs) > maxUserSkills {
+		return nil, fmt.Errorf("%w: at most %d skills", ErrUserSkillInvalid, maxUserSkills)
+	}
+
+	skills := make([]models.UserSkill, 0, len(inputs))
+	seen := make(map[string]bool, len(inputs))
+	for _, input := range inputs {
+		skill, err := NormalizeSkill(input.Skill)
+		if err != nil {
+			return nil, err
+		}
+		level := input.Level
+		if level == 0 {
+			level = 1
+		}
+		if level < 1 || level > maxUserSkillLevel {
+			return nil, fmt.Errorf("%w: level must be between 1 and %d", ErrUserSkillInvalid, maxUserSkillLevel)
+		}
+		if seen[skill] {
+			return nil, fmt.Errorf("%w: duplicate skill %q", ErrUserSkillInvalid, skill)
+		}
+		seen[skill] = true
+		skills = append(skills, models.UserSkill{UserID: userID, Skill: skill, Level: level})
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.UserSkill{}).Error; err != nil {
+			return fmt.Errorf("failed to clear user skills: %w", err)
+		}
+		if len(skills) == 0 {
+			return nil
+		}
+		if err := tx.Create(&skills).Error; err != nil {
+			return fmt.Errorf("failed to save user skills: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.loadAttributes(ctx, userID)
+}
+
+// ListSkills 获取已使用的技能标签及拥有该技能的坐席数
+func (s *UserAttributeService) ListSkills(ctx context.Context) ([]models.SkillSummary, error) {
+	var summaries []models.SkillSummary
+	if err := s.db.WithContext(ctx).Model(&models.UserSkill{}).
+		Select("skill, COUNT(*) AS agents").Group("skill").Order("skill ASC").
+		Scan(&summaries).Error; err != nil {
+		return nil, fmt.Errorf("failed to list skills: %w", err)
+	}
+	return summaries, nil
+}
+
+// SkillAssignmentCandidate 按技能分配的候选坐席
+type SkillAssignmentCandidate struct {
+	UserID    uint
+	OpenCount int64
+}
+
+// FindAgentsBySkills 查找具备全部技能（熟练度不低于 minLevel）的在职坐席，按未完结工单数升序；
+// teamID 不为空时只在该团队成员中查找
+func (s *UserAttributeService) FindAgentsBySkills(ctx context.Context, skills []string, minLevel int, teamID *uint) ([]SkillAssignmentCandidate, error) {
+	normalized := make([]string, 0, len(skills))
+	for _, skill := range skills {
+		value, err := NormalizeSkill(skill)
+		if err != nil {
+			return nil, err
+		}
+		normalized = append(normalized, value)
+	}
+	if len(normalized) == 0 {
+		return nil, fmt.Errorf("%w: at least one skill is required", ErrUserSkillInvalid)
+	}
+	if minLevel < 1 {
+		minLevel = 1
+	}
+
+	query := s.db.WithContext(ctx).Model(&models.User{}).
+		Where("role IN ? AND status = ?", []models.UserRole{models.RoleAgent, models.RoleSupervisor, models.RoleAdmin}, models.UserStatusActive).
+		Where("id IN (?)", s.db.Model(&models.UserSkill{}).Select("user_id").
+			Where("skill IN ? AND level >= ?", normalized, minLevel).
+			Group("user_id").Having("COUNT(DISTINCT skill) = ?", len(normalized)))
+	if teamID != nil {
+		query = query.Where("id IN (?)", s.db.Table("team_members").Select("user_id").Where("team_id = ?", *teamID))
+	}
+	var userIDs []uint
+	if err := query.Order("id ASC").Pluck("id", &userIDs).Error; err != nil {
+		return nil, fmt.Errorf("failed to find agents by skills: %w", err)
+	}
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+
+	var counts []struct {
+		AssignedToID uint
+		OpenCount    int64
+	}
+	if err := s.db.WithContext(ctx).Model(&models.Ticket{}).
+		Select("assigned_to_id, COUNT(*) AS open_count").
+		Where("assigned_to_id IN ? AND deleted_at IS NULL AND status NOT IN ?", userIDs, closedTicketStatuses).
+		Group("assigned_to_id").Scan(&counts).Error; err != nil {
+		return nil, fmt.Errorf("failed to count open tickets: %w", err)
+	}
+	open := make(map[uint]int64, len(counts))
+	for _, count := range counts {
+		open[count.AssignedToID] = count.OpenCount
+	}
+
+	candidates := make([]SkillAssignmentCandidate, 0, len(userIDs))
+	for _, id := range userIDs {
+		candidates = append(candidates, SkillAssignmentCandidate{UserID: id, OpenCount: open[id]})
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].OpenCount < candidates[j].OpenCount })
+	return candidates, nil
+}
+
+// applyUserAttributeFilters 按技能（需全部具备）及字段值过滤用户列表，多选字段匹配包含该选项的用户
+func applyUserAttributeFilters(db *gorm.DB, query *gorm.DB, skills []string, fields map[string]string) (*gorm.DB, error) {
+	for _, skill := range skills {
+		value, err := NormalizeSkill(skill)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidUserListFilter, err)
+		}
+		query = query.Where("id IN (?)", db.Model(&models.UserSkill{}).Select("user_id").Where("skill = ?", value))
+	}
+	if len(fields) == 0 {
+		return query, nil
+	}
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	var defs []models.UserFieldDefinition
+	if err := db.Where("key IN ?", keys).Find(&defs).Error; err != nil {
+		return nil, fmt.Errorf("failed to load user fields: %w", err)
+	}
+	byKey := make(map[string]*models.UserFieldDefinition, len(defs))
+	for i := range defs {
+		byKey[defs[i].Key] = &defs[i]
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		def, ok := byKey[key]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown field %q", ErrInvalidUserListFilter, key)
+		}
+		values := db.Model(&models.UserFieldValue{}).Select("user_id").Where("field_id = ?", def.ID)
+		if def.Type == models.UserFieldTypeMultiSelect {
+			encoded, _ := json.Marshal(fields[key])
+			values = values.Where(`value LIKE ? ESCAPE '\'`, "%"+escapeLikePattern(string(encoded))+"%")
+		} else {
+			value, err := normalizeUserFieldValue(def, fields[key])
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidUserListFilter, err)
+			}
+			values = values.Where("value = ?", value)
+		}
+		query = query.Where("id IN (?)", values)
+	}
+	return query, nil
+}
+
+// NormalizeSkill 规范化技能标签：去除首尾空白、转小写、空格替换为连字符
+func NormalizeSkill(skill string) (string, error) {
+	value := strings.Join(strings.Fields(strings.ToLower(skill)), "-")
+	if !userSkillPattern.MatchString(value) {
+		return "", fmt.Errorf("%w: %q", ErrUserSkillInvalid, skill)
+	}
+	return value, nil
+}
+
+func (s *UserAttributeService) loadAttributes(ctx context.Context, userID uint) (*models.UserAttributes, error) {
+	attrs := &models.UserAttributes{UserID: userID, Fields: map[string]interface{}{}, Skills: []models.UserSkill{}}
+
+	var rows []struct {
+		Key   string
+		Type  models.UserFieldType
+		Value string
+	}
+	if err := s.db.WithContext(ctx).Table("user_field_values AS v").
+		Select("d.key AS key, d.type AS type, v.value AS value").
+		Joins("JOIN user_field_definitions d ON d.id = v.field_id").
+		Where("v.user_id = ? AND d.is_active = ?", userID, true).
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to load user field values: %w", err)
+	}
+	for _, row := range rows {
+		attrs.Fields[row.Key] = decodeUserFieldValue(row.Type, row.Value)
+	}
+
+	if err := s.db.WithContext(ctx).Where("user_id = ?", userID).
+		Order("level DESC, skill ASC").Find(&attrs.Skills).Error; err != nil {
+		return nil, fmt.Errorf("failed to load user skills: %w", err)
+	}
+	return attrs, nil
+}
+
+func (s *UserAttributeService) getDefinition(ctx context.Context, id uint) (*models.UserFieldDefinition, error) {
+	var def models.UserFieldDefinition
+	if err := s.db.WithContext(ctx).First(&def, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserFieldNotFound
+		}
+		return nil, fmt.Errorf("failed to get user field: %w", err)
+	}
+	return &def, nil
+}
+
+func (s *UserAttributeService) getUser(ctx context.Context, userID uint) (*models.User, error) {
+	var user models.User
+	if err := s.db.WithContext(ctx).Select("id", "role").First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrUserAttributesUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return &user, nil
+}
+
+func (s *UserAttributeService) ensureKeyAvailable(ctx context.Context, key string, excludeID uint) error {
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&models.UserFieldDefinition{}).
+		Where("key = ? AND id <> ?", key, excludeID).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check user field key: %w", err)
+	}
+	if count > 0 {
+		return ErrUserFieldKeyExists
+	}
+	return nil
+}
+
+// applyUserFieldDefinition 校验请求并写入字段定义
+func applyUserFieldDefinition(def *models.UserFieldDefinition, req *models.UserFieldDefinitionRequest) error {
+	key := strings.TrimSpace(req.Key)
+	if !userFieldKeyPattern.MatchString(key) {
+		return fmt.Errorf("%w: key must start with a lowercase letter and contain only lowercase letters, digits and underscores", ErrUserFieldInvalid)
+	}
+	label := strings.TrimSpace(req.Label)
+	if label == "" {
+		return fmt.Errorf("%w: label is required", ErrUserFieldInvalid)
+	}
+
+	var options []string
+	if req.Type == models.UserFieldTypeSelect || req.Type == models.UserFieldTypeMultiSelect {
+		seen := make(map[string]bool, len(req.Options))
+		for _, option := range req.Options {
+			option = strings.TrimSpace(option)
+			if option == "" || seen[option] {
+				continue
+			}
+			if utf8.RuneCountInString(option) > 100 {
+				return fmt.Errorf("%w: option %q is too long", ErrUserFieldInvalid, option)
+			}
+			seen[option] = true
+			options = append(options, option)
+		}
+		if len(options) == 0 {
+			return fmt.Errorf("%w: options are required for %s fields", ErrUserFieldInvalid, req.Type)
+		}
+		if len(options) > maxUserFieldOptions {
+			return fmt.Errorf("%w: at most %d options", ErrUserFieldInvalid, maxUserFieldOptions)
+		}
+	}
+
+	def.Key = key
+	def.Label = label
+	def.Type = req.Type
+	def.OptionList = options
+	def.Description = strings.TrimSpace(req.Description)
+	def.SortOrder = req.SortOrder
+	if req.IsActive != nil {
+		def.IsActive = *req.IsActive
+	}
+	return nil
+}
+
+// normalizeUserFieldValue 按字段类型校验并转换为保存的文本，空值返回空字符串
+func normalizeUserFieldValue(def *models.UserFieldDefinition, raw interface{}) (string, error) {
+	if raw == nil {
+		return "", nil
+	}
+	invalid := func(reason string) error {
+		return fmt.Errorf("%w: %s %s", ErrUserFieldInvalid, def.Key, reason)
+	}
+	text, isText := raw.(string)
+	if isText {
+		text = strings.TrimSpace(text)
+		if text == "" {
+			return "", nil
+		}
+	}
+
+	switch def.Type {
+	case models.UserFieldTypeText:
+		if !isText {
+			return "", invalid("must be a string")
+		}
+		if utf8.RuneCountInString(text) > maxUserFieldTextLength {
+			return "", invalid(fmt.Sprintf("must be at most %d characters", maxUserFieldTextLength))
+		}
+		return text, nil
+	case models.UserFieldTypeNumber:
+		var number float64
+		switch v := raw.(type) {
+		case float64:
+			number = v
+		case int:
+			number = float64(v)
+		case string:
+			parsed, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return "", invalid("must be a number")
+			}
+			number = parsed
+		default:
+			return "", invalid("must be a number")
+		}
+		return strconv.FormatFloat(number, 'f', -1, 64), nil
+	case models.UserFieldTypeBool:
+		switch v := raw.(type) {
+		case bool:
+			return strconv.FormatBool(v), nil
+		case string:
+			parsed, err := strconv.ParseBool(text)
+			if err != nil {
+				return "", invalid("must be true or false")
+			}
+			return strconv.FormatBool(parsed), nil
+		default:
+			return "", invalid("must be true or false")
+		}
+	case models.UserFieldTypeDate:
+		if !isText {
+			return "", invalid("must be a date")
+		}
+		if _, err := time.Parse("2006-01-02", text); err != nil {
+			return "", invalid("must be a date in YYYY-MM-DD format")
+		}
+		return text, nil
+	case models.UserFieldTypeSelect:
+		if !isText || !containsString(def.OptionList, text) {
+			return "", invalid("must be one of the options")
+		}
+		return text, nil
+	case models.UserFieldTypeMultiSelect:
+		var selected []string
+		switch v := raw.(type) {
+		case string:
+			selected = []string{text}
+		case []string:
+			selected = v
+		case []interface{}:
+			for _, item := range v {
+				option, ok := item.(string)
+				if !ok {
+					return "", invalid("must be a list of options")
+				}
+				selected = append(selected, option)
+			}
+		default:
+			return "", invalid("must be a list of options")
+		}
+		chosen := make(map[string]bool, len(selected))
+		for _, option := range selected {
+			option = strings.TrimSpace(option)
+			if !containsString(def.OptionList, option) {
+				return "", invalid(fmt.Sprintf("option %q is not allowed", option))
+			}
+			chosen[option] = true
+		}
+		if len(chosen) == 0 {
+			return "", nil
+		}
+		// 按定义中的选项顺序保存
+		ordered := make([]string, 0, len(chosen))
+		for _, option := range def.OptionList {
+			if chosen[option] {
+				ordered = append(ordered, option)
+			}
+		}
+		data, _ := json.Marshal(ordered)
+		return string(data), nil
+	default:
+		return "", invalid("has an unsupported type")
+	}
+}
+
+// decodeUserFieldValue 将保存的文本转换为字段类型对应的值
+func decodeUserFieldValue(fieldType models.UserFieldType, value string) interface{} {
+	switch fieldType {
+	case models.UserFieldTypeNumber:
+		if number, err := strconv.ParseFloat(value, 64); err == nil {
+			return number
+		}
+	case models.UserFieldTypeBool:
+		return value == "true"
+	case models.UserFieldTypeMultiSelect:
+		return decodeUserFieldOptions(fieldType, value)
+	}
+	return value
+}
+
+func decodeUserFieldOptions(fieldType models.UserFieldType, value string) []string {
+	if fieldType != models.UserFieldTypeMultiSelect {
+		return []string{value}
+	}
+	var options []string
+	if err := json.Unmarshal([]byte(value), &options); err != nil {
+		return []string{}
+	}
+	return options
+}
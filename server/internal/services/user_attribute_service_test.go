@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestUserAttributes_FieldsSkillsFiltersAndAutomation(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:user_attribute_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Team{}, &models.Ticket{}, &models.TicketHistory{}, &models.SystemConfig{},
+		&models.UserFieldDefinition{}, &models.UserFieldValue{}, &models.UserSkill{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+	ctx := context.Background()
+	svc := NewUserAttributeService(db)
+
+	alice := models.User{Username: "attr-alice", Email: "attr-alice@example.com", PasswordHash: "x", Role: models.RoleAgent, Status: models.UserStatusActive}
+	bob := models.User{Username: "attr-bob", Email: "attr-bob@example.com", PasswordHash: "x", Role: models.RoleAgent, Status: models.UserStatusActive}
+	customer := models.User{Username: "attr-customer", Email: "attr-customer@example.com", PasswordHash: "x", Role: models.RoleCustomer, Status: models.UserStatusActive}
+	for _, u := range []*models.User{&alice, &bob, &customer} {
+		if err := db.Create(u).Error; err != nil {
+			t.Fatalf("failed to seed user: %v", err)
+		}
+	}
+
+	// 字段定义：选项必填、标识唯一
+	if _, err := svc.CreateFieldDefinition(ctx, &models.UserFieldDefinitionRequest{Key: "shift", Label: "Shift", Type: models.UserFieldTypeSelect}); !errors.Is(err, ErrUserFieldInvalid) {
+		t.Fatalf("expected select without options to be rejected, got %v", err)
+	}
+	shift, err := svc.CreateFieldDefinition(ctx, &models.UserFieldDefinitionRequest{Key: "shift", Label: "Shift", Type: models.UserFieldTypeSelect, Options: []string{"day", "night"}})
+	if err != nil {
+		t.Fatalf("create shift field failed: %v", err)
+	}
+	if _, err := svc.CreateFieldDefinition(ctx, &models.UserFieldDefinitionRequest{Key: "shift", Label: "Dup", Type: models.UserFieldTypeText}); !errors.Is(err, ErrUserFieldKeyExists) {
+		t.Fatalf("expected duplicate key to conflict, got %v", err)
+	}
+	svc.CreateFieldDefinition(ctx, &models.UserFieldDefinitionRequest{Key: "cost_center", Label: "Cost center", Type: models.UserFieldTypeText})
+	svc.CreateFieldDefinition(ctx, &models.UserFieldDefinitionRequest{Key: "certifications", Label: "Certifications", Type: models.UserFieldTypeMultiSelect,
+		Options: []string{"itil", "ccna", "aws"}})
+
+	// 字段值按类型校验，多选按定义顺序保存
+	if _, err := svc.SetFieldValues(ctx, alice.ID, map[string]interface{}{"shift": "evening"}); !errors.Is(err, ErrUserFieldInvalid) {
+		t.Fatalf("expected unknown option to be rejected, got %v", err)
+	}
+	attrs, err := svc.SetFieldValues(ctx, alice.ID, map[string]interface{}{
+		"shift": "night", "cost_center": " CC-100 ", "certifications": []interface{}{"aws", "itil"},
+	})
+	if err != nil {
+		t.Fatalf("set field values failed: %v", err)
+	}
+	certs, _ := attrs.Fields["certifications"].([]string)
+	if attrs.Fields["shift"] != "night" || attrs.Fields["cost_center"] != "CC-100" || len(certs) != 2 || certs[0] != "itil" {
+		t.Fatalf("unexpected attributes %+v", attrs.Fields)
+	}
+	svc.SetFieldValues(ctx, bob.ID, map[string]interface{}{"shift": "day", "certifications": []interface{}{"ccna"}})
+	if attrs, _ = svc.SetFieldValues(ctx, bob.ID, map[string]interface{}{"shift": nil}); attrs.Fields["shift"] != nil {
+		t.Fatalf("expected null to clear the value, got %+v", attrs.Fields)
+	}
+
+	// 已使用的选项不能删除，已有值的字段不能改类型
+	if _, err := svc.UpdateFieldDefinition(ctx, shift.ID, &models.UserFieldDefinitionRequest{Key: "shift", Label: "Shift", Type: models.UserFieldTypeSelect, Options: []string{"day"}}); !errors.Is(err, ErrUserFieldInvalid) {
+		t.Fatalf("expected removing used option to be rejected, got %v", err)
+	}
+	if _, err := svc.UpdateFieldDefinition(ctx, shift.ID, &models.UserFieldDefinitionRequest{Key: "shift", Label: "Shift", Type: models.UserFieldTypeText}); !errors.Is(err, ErrUserFieldInvalid) {
+		t.Fatalf("expected type change to be rejected, got %v", err)
+	}
+
+	// 技能：规范化，客户不能设置
+	if _, err := svc.SetSkills(ctx, customer.ID, []models.UserSkillInput{{Skill: "billing"}}); !errors.Is(err, ErrUserSkillInvalid) {
+		t.Fatalf("expected customer skills to be rejected, got %v", err)
+	}
+	attrs, err = svc.SetSkills(ctx, alice.ID, []models.UserSkillInput{{Skill: " Billing ", Level: 4}, {Skill: "Lang FR", Level: 2}})
+	if err != nil || len(attrs.Skills) != 2 || attrs.Skills[0].Skill != "billing" || attrs.Skills[1].Skill != "lang-fr" {
+		t.Fatalf("unexpected skills %+v, %v", attrs, err)
+	}
+	svc.SetSkills(ctx, bob.ID, []models.UserSkillInput{{Skill: "billing", Level: 2}})
+	if skills, _ := svc.ListSkills(ctx); len(skills) != 2 || skills[0].Skill != "billing" || skills[0].Agents != 2 {
+		t.Fatalf("unexpected skill summary %+v", skills)
+	}
+
+	// 管理员用户列表按技能及字段过滤
+	adminUsers := NewAdminUserService(db)
+	list, err := adminUsers.GetUserList(ctx, &UserListRequest{Skills: "billing, lang-fr"})
+	if err != nil || list.Total != 1 || list.Items[0].ID != alice.ID {
+		t.Fatalf("unexpected skill filter result %+v, %v", list, err)
+	}
+	list, err = adminUsers.GetUserList(ctx, &UserListRequest{Fields: map[string]string{"certifications": "ccna"}})
+	if err != nil || list.Total != 1 || list.Items[0].ID != bob.ID {
+		t.Fatalf("unexpected multi-select filter result %+v, %v", list, err)
+	}
+	if _, err := adminUsers.GetUserList(ctx, &UserListRequest{Fields: map[string]string{"unknown": "x"}}); !errors.Is(err, ErrInvalidUserListFilter) {
+		t.Fatalf("expected unknown field filter to be rejected, got %v", err)
+	}
+
+	// 按技能分配：选择具备技能且未完结工单最少的坐席
+	for i := 0; i < 2; i++ {
+		db.Create(&models.Ticket{TicketNumber: "ATTR-OPEN-" + string(rune('A'+i)), Title: "open", Status: models.TicketStatusOpen,
+			Priority: models.TicketPriorityNormal, Type: models.TicketTypeRequest, Source: models.TicketSourceWeb, CreatedByID: customer.ID, AssignedToID: &alice.ID})
+	}
+	ticket := models.Ticket{TicketNumber: "ATTR-1", Title: "Invoice question", Status: models.TicketStatusOpen, Priority: models.TicketPriorityNormal,
+		Type: models.TicketTypeRequest, Source: models.TicketSourceWeb, CreatedByID: customer.ID}
+	db.Create(&ticket)
+
+	automation := NewAutomationService(db)
+	action := &models.RuleAction{Type: "assign_by_skill", Params: map[string]interface{}{"skills": []interface{}{"billing"}}}
+	if err := automation.executeAction(ctx, action, &ticket); err != nil {
+		t.Fatalf("assign_by_skill failed: %v", err)
+	}
+	if ticket.AssignedToID == nil || *ticket.AssignedToID != bob.ID {
+		t.Fatalf("expected least loaded skilled agent bob, got %v", ticket.AssignedToID)
+	}
+	action.Params["min_level"] = float64(3)
+	if err := automation.executeAction(ctx, action, &ticket); err != nil || *ticket.AssignedToID != alice.ID {
+		t.Fatalf("expected min_level to select alice, got %v, %v", ticket.AssignedToID, err)
+	}
+
+	// 自动化条件读取处理人的技能及自定义字段
+	conditions := []models.RuleCondition{
+		{Field: "assignee_skills", Operator: "contains", Value: "lang-fr"},
+		{Field: "assignee_field.certifications", Operator: "in", Value: []interface{}{"aws", "ccna"}},
+		{Field: "assignee_field.shift", Operator: "eq", Value: "night"},
+	}
+	if !automation.evaluateConditions(ctx, conditions, &ticket) {
+		t.Fatalf("expected assignee attribute conditions to match")
+	}
+	if automation.evaluateConditions(ctx, []models.RuleCondition{{Field: "assignee_skills", Operator: "not_in", Value: []interface{}{"billing"}}}, &ticket) {
+		t.Fatalf("expected not_in to fail for assignee with billing skill")
+	}
+}
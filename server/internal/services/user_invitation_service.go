@@ -0,0 +1,387 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+const defaultUserInvitationTTLHours = 72
+
+var (
+	// ErrInvitationNotFound 邀请不存在，或链接已失效（已接受、已撤销、已过期）
+	ErrInvitationNotFound = errors.New("invitation not found")
+	// ErrInvitationNotPending 只有待接受的邀请可以重发或撤销
+	ErrInvitationNotPending = errors.New("invitation is not pending")
+	// ErrInvitationConflict 邮箱已注册或已有待接受的邀请，或接受时用户名已被占用
+	ErrInvitationConflict = errors.New("invitation conflicts with an existing user or invitation")
+	// ErrInvalidInvitationStatus 不支持的邀请状态过滤条件
+	ErrInvalidInvitationStatus = errors.New("invalid invitation status")
+)
+
+// InvitationAcceptance 接受邀请时提交的账户信息，密码由认证模块校验并哈希
+type InvitationAcceptance struct {
+	Username     string
+	PasswordHash string
+	FirstName    string
+	LastName     string
+}
+
+// UserInvitationService 批量邀请用户服务
+type UserInvitationService struct {
+	db            *gorm.DB
+	configService *ConfigService
+	quotaService  *QuotaService
+}
+
+// NewUserInvitationService 创建用户邀请服务
+func NewUserInvitationService(db *gorm.DB) *UserInvitationService {
+	return &UserInvitationService{
+		db:            db,
+		configService: NewConfigService(db),
+		quotaService:  NewQuotaService(db),
+	}
+}
+
+// TTL 邀请链接默认有效期
+func (s *UserInvitationService) TTL() time.Duration {
+	if hours, err := s.configService.GetConfigInt(KeyUserInvitationTTLHours); err == nil && hours > 0 {
+		return time.Duration(hours) * time.Hour
+	}
+	return defaultUserInvitationTTLHours * time.Hour
+}
+
+// Invite 批量创建邀请，逐个返回结果及写入邮件的令牌。
+// 邮箱已注册、已有未过期的待接受邀请或超出席位配额的条目标记为失败，不影响其他条目
+func (s *UserInvitationService) Invite(ctx context.Context, req *models.UserInviteRequest, invitedByID uint) ([]*models.UserInviteResult, error) {
+	ttl := s.TTL()
+	if req.ExpiresInHours > 0 {
+		ttl = time.Duration(req.ExpiresInHours) * time.Hour
+	}
+
+	results := make([]*models.UserInviteResult, 0, len(req.Invitations))
+	seen := make(map[string]bool, len(req.Invitations))
+	for _, entry := range req.Invitations {
+		email := strings.ToLower(strings.TrimSpace(entry.Email))
+		result := &models.UserInviteResult{Email: email, Role: entry.Role}
+		results = append(results, result)
+
+		if seen[email] {
+			result.Error = "duplicate email in request"
+			continue
+		}
+		seen[email] = true
+
+		invitation, token, err := s.create(ctx, email, entry.Role, ttl, invitedByID)
+		if err != nil {
+			if !errors.Is(err, ErrInvitationConflict) && !errors.Is(err, ErrQuotaExceeded) {
+				return nil, err
+			}
+			result.Error = err.Error()
+			continue
+		}
+		result.Success = true
+		result.InvitationID = invitation.ID
+		result.ExpiresAt = &invitation.ExpiresAt
+		result.Token = token
+	}
+	return results, nil
+}
+
+func (s *UserInvitationService) create(ctx context.Context, email string, role models.UserRole, ttl time.Duration, invitedByID uint) (*models.UserInvitation, string, error) {
+	var users int64
+	if err := s.db.WithContext(ctx).Model(&models.User{}).Where("LOWER(email) = ?", email).Count(&users).Error; err != nil {
+		return nil, "", fmt.Errorf("failed to check existing user: %w", err)
+	}
+	if users > 0 {
+		return nil, "", fmt.Errorf("%w: email already registered", ErrInvitationConflict)
+	}
+
+	now := time.Now()
+	var pending int64
+	if err := s.db.WithContext(ctx).Model(&models.UserInvitation{}).
+		Where("email = ? AND status = ? AND expires_at > ?", email, models.UserInvitationPending, now).
+		Count(&pending).Error; err != nil {
+		return nil, "", fmt.Errorf("failed to check existing invitation: %w", err)
+	}
+	if pending > 0 {
+		return nil, "", fmt.Errorf("%w: a pending invitation already exists", ErrInvitationConflict)
+	}
+	if err := s.quotaService.CheckAgents(ctx, role); err != nil {
+		return nil, "", err
+	}
+
+	token, err := newInvitationToken()
+	if err != nil {
+		return nil, "", err
+	}
+	invitation := &models.UserInvitation{
+		Email:       email,
+		Role:        role,
+		Status:      models.UserInvitationPending,
+		TokenHash:   hashInvitationToken(token),
+		ExpiresAt:   now.Add(ttl),
+		InvitedByID: invitedByID,
+		SendCount:   1,
+		LastSentAt:  &now,
+	}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 过期未接受的旧邀请作废，邮箱只保留一条待接受邀请
+		if err := tx.Model(&models.UserInvitation{}).
+			Where("email = ? AND status = ?", email, models.UserInvitationPending).
+			Updates(map[string]interface{}{"status": models.UserInvitationRevoked, "revoked_at": now, "token_hash": nil}).Error; err != nil {
+			return err
+		}
+		return tx.Create(invitation).Error
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create invitation: %w", err)
+	}
+	return invitation, token, nil
+}
+
+// Resend 轮换令牌并重新计算有效期，旧链接立即失效；已过期的待接受邀请也可重发
+func (s *UserInvitationService) Resend(ctx context.Context, id uint) (*models.UserInvitation, string, error) {
+	invitation, err := s.get(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+	if invitation.Status != models.UserInvitationPending {
+		return nil, "", ErrInvitationNotPending
+	}
+
+	token, err := newInvitationToken()
+	if err != nil {
+		return nil, "", err
+	}
+	now := time.Now()
+	updates := map[string]interface{}{
+		"token_hash":   hashInvitationToken(token),
+		"expires_at":   now.Add(s.TTL()),
+		"send_count":   gorm.Expr("send_count + 1"),
+		"last_sent_at": now,
+	}
+	result := s.db.WithContext(ctx).Model(&models.UserInvitation{}).
+		Where("id = ? AND status = ?", id, models.UserInvitationPending).
+		Updates(updates)
+	if result.Error != nil {
+		return nil, "", fmt.Errorf("failed to resend invitation: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return nil, "", ErrInvitationNotPending
+	}
+	invitation, err = s.get(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+	return invitation, token, nil
+}
+
+// Revoke 撤销待接受的邀请
+func (s *UserInvitationService) Revoke(ctx context.Context, id uint) error {
+	if _, err := s.get(ctx, id); err != nil {
+		return err
+	}
+	result := s.db.WithContext(ctx).Model(&models.UserInvitation{}).
+		Where("id = ? AND status = ?", id, models.UserInvitationPending).
+		Updates(map[string]interface{}{"status": models.UserInvitationRevoked, "revoked_at": time.Now(), "token_hash": nil})
+	if result.Error != nil {
+		return fmt.Errorf("failed to revoke invitation: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvitationNotPending
+	}
+	return nil
+}
+
+// Lookup 按邮件令牌获取仍可接受的邀请
+func (s *UserInvitationService) Lookup(ctx context.Context, token string) (*models.UserInvitation, error) {
+	if token == "" {
+		return nil, ErrInvitationNotFound
+	}
+	var invitation models.UserInvitation
+	err := s.db.WithContext(ctx).
+		Where("token_hash = ? AND status = ? AND expires_at > ?", hashInvitationToken(token), models.UserInvitationPending, time.Now()).
+		First(&invitation).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvitationNotFound
+		}
+		return nil, fmt.Errorf("failed to get invitation: %w", err)
+	}
+	return &invitation, nil
+}
+
+// Accept 接受邀请：创建已验证邮箱的激活账户并原子地消耗邀请，并发请求只有一个能成功
+func (s *UserInvitationService) Accept(ctx context.Context, token string, acceptance *InvitationAcceptance) (*models.User, error) {
+	invitation, err := s.Lookup(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	username := strings.TrimSpace(acceptance.Username)
+	if username == "" {
+		username = strings.SplitN(invitation.Email, "@", 2)[0]
+	}
+	if err := s.quotaService.CheckAgents(ctx, invitation.Role); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	user := &models.User{
+		Username:        username,
+		Email:           invitation.Email,
+		PasswordHash:    acceptance.PasswordHash,
+		FirstName:       strings.TrimSpace(acceptance.FirstName),
+		LastName:        strings.TrimSpace(acceptance.LastName),
+		Role:            invitation.Role,
+		Status:          models.UserStatusActive,
+		EmailVerified:   true, // 通过邮件链接接受，视为已验证邮箱
+		EmailVerifiedAt: &now,
+		Timezone:        "Asia/Shanghai",
+		Language:        "zh-CN",
+	}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing int64
+		if err := tx.Model(&models.User{}).
+			Where("username = ? OR LOWER(email) = ?", username, invitation.Email).
+			Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return fmt.Errorf("%w: username or email already registered", ErrInvitationConflict)
+		}
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		result := tx.Model(&models.UserInvitation{}).
+			Where("id = ? AND status = ?", invitation.ID, models.UserInvitationPending).
+			Updates(map[string]interface{}{
+				"status":           models.UserInvitationAccepted,
+				"accepted_at":      now,
+				"accepted_user_id": user.ID,
+				"token_hash":       nil,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInvitationNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, ErrInvitationNotFound) || errors.Is(err, ErrInvitationConflict) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to accept invitation: %w", err)
+	}
+
+	if isQuotaSeatRole(user.Role) {
+		if err := s.quotaService.NotifyThresholds(ctx, models.QuotaResourceAgents, now); err != nil {
+			log.Printf("Warning: failed to check agent quota thresholds: %v", err)
+		}
+	}
+	return user, nil
+}
+
+// List 分页获取邀请，status 可为 pending、accepted、expired、revoked，为空时返回全部
+func (s *UserInvitationService) List(ctx context.Context, status string, page, pageSize int) ([]*models.UserInvitation, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	now := time.Now()
+	query := s.db.WithContext(ctx).Model(&models.UserInvitation{})
+	switch models.UserInvitationStatus(status) {
+	case "":
+	case models.UserInvitationPending:
+		query = query.Where("status = ? AND expires_at > ?", models.UserInvitationPending, now)
+	case models.UserInvitationExpired:
+		query = query.Where("status = ? AND expires_at <= ?", models.UserInvitationPending, now)
+	case models.UserInvitationAccepted, models.UserInvitationRevoked:
+		query = query.Where("status = ?", status)
+	default:
+		return nil, 0, fmt.Errorf("%w: %s", ErrInvalidInvitationStatus, status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count invitations: %w", err)
+	}
+	var invitations []*models.UserInvitation
+	if err := query.Order("created_at DESC, id DESC").
+		Offset((page - 1) * pageSize).Limit(pageSize).
+		Find(&invitations).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list invitations: %w", err)
+	}
+	for _, invitation := range invitations {
+		invitation.Status = invitation.DisplayStatus(now)
+	}
+	return invitations, total, nil
+}
+
+// Summary 统计各状态的邀请数量
+func (s *UserInvitationService) Summary(ctx context.Context) (*models.UserInvitationSummary, error) {
+	var rows []struct {
+		Status  models.UserInvitationStatus
+		Expired bool
+		Count   int64
+	}
+	if err := s.db.WithContext(ctx).Model(&models.UserInvitation{}).
+		Select("status, expires_at <= ? AS expired, COUNT(*) AS count", time.Now()).
+		Group("status, expired").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to summarize invitations: %w", err)
+	}
+
+	summary := &models.UserInvitationSummary{}
+	for _, row := range rows {
+		switch {
+		case row.Status == models.UserInvitationPending && row.Expired:
+			summary.Expired += row.Count
+		case row.Status == models.UserInvitationPending:
+			summary.Pending += row.Count
+		case row.Status == models.UserInvitationAccepted:
+			summary.Accepted += row.Count
+		case row.Status == models.UserInvitationRevoked:
+			summary.Revoked += row.Count
+		}
+	}
+	return summary, nil
+}
+
+func (s *UserInvitationService) get(ctx context.Context, id uint) (*models.UserInvitation, error) {
+	var invitation models.UserInvitation
+	if err := s.db.WithContext(ctx).First(&invitation, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvitationNotFound
+		}
+		return nil, fmt.Errorf("failed to get invitation: %w", err)
+	}
+	return &invitation, nil
+}
+
+func newInvitationToken() (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", fmt.Errorf("failed to generate invitation token: %w", err)
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+func hashInvitationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
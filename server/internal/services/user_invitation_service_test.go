@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestUserInvitation_InviteResendAcceptAndReport(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:user_invitation_test?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.SystemConfig{}, &models.UserInvitation{}, &models.QuotaAlert{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+	ctx := context.Background()
+
+	admin := models.User{Username: "inv-admin", Email: "inv-admin@example.com", PasswordHash: "x", Role: models.RoleAdmin, Status: models.UserStatusActive}
+	db.Create(&admin)
+
+	svc := NewUserInvitationService(db)
+	results, err := svc.Invite(ctx, &models.UserInviteRequest{Invitations: []models.UserInviteEntry{
+		{Email: " Alice@Example.com ", Role: models.RoleAgent},
+		{Email: "bob@example.com", Role: models.RoleSupervisor},
+		{Email: "alice@example.com", Role: models.RoleAgent},
+		{Email: "INV-ADMIN@example.com", Role: models.RoleAgent},
+	}}, admin.ID)
+	if err != nil {
+		t.Fatalf("invite: %v", err)
+	}
+	if len(results) != 4 || !results[0].Success || !results[1].Success || results[0].Email != "alice@example.com" || results[0].Token == "" {
+		t.Fatalf("unexpected invite results %+v %+v", results[0], results[1])
+	}
+	if results[2].Success || results[3].Success {
+		t.Fatalf("expected duplicate and registered emails to fail, got %+v %+v", results[2], results[3])
+	}
+
+	// 已有待接受邀请的邮箱不能重复邀请
+	again, err := svc.Invite(ctx, &models.UserInviteRequest{Invitations: []models.UserInviteEntry{{Email: "bob@example.com", Role: models.RoleAgent}}}, admin.ID)
+	if err != nil || again[0].Success {
+		t.Fatalf("expected pending invitation conflict, got %+v, %v", again[0], err)
+	}
+
+	// 重发轮换令牌，旧链接失效
+	aliceID, oldToken := results[0].InvitationID, results[0].Token
+	resent, newToken, err := svc.Resend(ctx, aliceID)
+	if err != nil || resent.SendCount != 2 || newToken == oldToken {
+		t.Fatalf("unexpected resend %+v, %v", resent, err)
+	}
+	if _, err := svc.Lookup(ctx, oldToken); !errors.Is(err, ErrInvitationNotFound) {
+		t.Fatalf("expected old token to be invalid, got %v", err)
+	}
+
+	user, err := svc.Accept(ctx, newToken, &InvitationAcceptance{PasswordHash: "hashed", FirstName: "Alice"})
+	if err != nil {
+		t.Fatalf("accept: %v", err)
+	}
+	if user.Username != "alice" || user.Role != models.RoleAgent || user.Status != models.UserStatusActive || !user.EmailVerified {
+		t.Fatalf("unexpected accepted user %+v", user)
+	}
+	if _, err := svc.Accept(ctx, newToken, &InvitationAcceptance{PasswordHash: "hashed"}); !errors.Is(err, ErrInvitationNotFound) {
+		t.Fatalf("expected token to be consumed, got %v", err)
+	}
+	if _, _, err := svc.Resend(ctx, aliceID); !errors.Is(err, ErrInvitationNotPending) {
+		t.Fatalf("expected accepted invitation not to be resent, got %v", err)
+	}
+
+	// 过期的邀请不能接受，但可以重发
+	bobID := results[1].InvitationID
+	db.Model(&models.UserInvitation{}).Where("id = ?", bobID).Update("expires_at", time.Now().Add(-time.Hour))
+	if _, err := svc.Lookup(ctx, results[1].Token); !errors.Is(err, ErrInvitationNotFound) {
+		t.Fatalf("expected expired token to be invalid, got %v", err)
+	}
+	expired, total, err := svc.List(ctx, "expired", 1, 20)
+	if err != nil || total != 1 || expired[0].ID != bobID || expired[0].Status != models.UserInvitationExpired {
+		t.Fatalf("unexpected expired list %+v, %d, %v", expired, total, err)
+	}
+
+	carol, err := svc.Invite(ctx, &models.UserInviteRequest{Invitations: []models.UserInviteEntry{{Email: "carol@example.com", Role: models.RoleCustomer}}, ExpiresInHours: 1}, admin.ID)
+	if err != nil || !carol[0].Success || time.Until(*carol[0].ExpiresAt) > time.Hour {
+		t.Fatalf("unexpected custom expiry invite %+v, %v", carol[0], err)
+	}
+	if err := svc.Revoke(ctx, carol[0].InvitationID); err != nil {
+		t.Fatalf("revoke: %v", err)
+	}
+	if err := svc.Revoke(ctx, carol[0].InvitationID); !errors.Is(err, ErrInvitationNotPending) {
+		t.Fatalf("expected second revoke to fail, got %v", err)
+	}
+
+	summary, err := svc.Summary(ctx)
+	if err != nil {
+		t.Fatalf("summary: %v", err)
+	}
+	if summary.Pending != 0 || summary.Accepted != 1 || summary.Expired != 1 || summary.Revoked != 1 {
+		t.Fatalf("unexpected summary %+v", summary)
+	}
+	if _, _, err := svc.List(ctx, "unknown", 1, 20); !errors.Is(err, ErrInvalidInvitationStatus) {
+		t.Fatal("expected unknown status filter to be rejected")
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"fmt"
 	"mime/multipart"
-	"path/filepath"
 	"strings"
 	"time"
 
@@ -15,7 +14,9 @@ import (
 
 // UserService 用户服务
 type UserService struct {
-	db *gorm.DB
+	db            *gorm.DB
+	avatarService *AvatarService
+	auditLog      *AdminAuditService
 }
 
 // NewUserService 创建用户服务
@@ -25,6 +26,11 @@ func NewUserService(db *gorm.DB) *UserService {
 	}
 }
 
+// SetAvatarService 设置头像处理服务
+func (s *UserService) SetAvatarService(avatarService *AvatarService) {
+	s.avatarService = avatarService
+}
+
 // GetUserProfile 获取用户详细信息
 func (s *UserService) GetUserProfile(ctx context.Context, userID uint) (*models.User, error) {
 	var user models.User
@@ -62,6 +68,7 @@ func (s *UserService) UpdateUserProfile(ctx context.Context, userID uint, req *m
 	if req.Phone != nil {
 		updates["phone"] = *req.Phone
 		updates["phone_verified"] = false // 手机变更需要重新验证
+		updates["sms_otp_enabled"] = false
 	}
 
 	if req.FirstName != nil {
@@ -135,10 +142,21 @@ func (s *UserService) ChangePassword(ctx context.Context, userID uint, currentPa
 
 	// 记录密码修改历史（可选）
 	s.recordPasswordChange(ctx, userID)
+	s.auditLog.RecordSecurityEvent(ctx, &AdminAuditRecord{
+		UserID:   &user.ID,
+		Username: user.Username,
+		Role:     string(user.Role),
+		Action:   "password_change",
+	})
 
 	return nil
 }
 
+// loginHistorySortRegistry 登录历史允许排序的字段
+var loginHistorySortRegistry = NewSortRegistry(map[string]string{
+	"login_time": "login_time", "created_at": "created_at",
+}, models.SortField{Field: "login_time", Direction: models.SortDesc})
+
 // GetLoginHistory 获取用户登录历史
 func (s *UserService) GetLoginHistory(ctx context.Context, userID uint, req *models.LoginHistoryRequest) ([]*models.LoginHistoryResponse, int64, error) {
 	query := s.db.WithContext(ctx).Model(&models.LoginHistory{})
@@ -184,19 +202,24 @@ func (s *UserService) GetLoginHistory(ctx context.Context, userID uint, req *mod
 		return nil, 0, fmt.Errorf("failed to count login history: %w", err)
 	}
 
-	// 排序
-	orderBy := "login_time"
-	if req.OrderBy != "" {
-		orderBy = req.OrderBy
+	// 排序：只允许白名单字段
+	var sort []models.SortField
+	if req.OrderBy != "" || req.Order != "" {
+		direction, err := ParseSortDirection(req.Order, models.SortDesc)
+		if err != nil {
+			return nil, 0, err
+		}
+		orderBy := strings.ToLower(strings.TrimSpace(req.OrderBy))
+		if orderBy == "" {
+			orderBy = "login_time"
+		}
+		sort = []models.SortField{{Field: orderBy, Direction: direction}}
 	}
-
-	order := "DESC"
-	if req.Order != "" {
-		order = strings.ToUpper(req.Order)
+	query, err := loginHistorySortRegistry.Apply(query, sort)
+	if err != nil {
+		return nil, 0, err
 	}
 
-	query = query.Order(fmt.Sprintf("%s %s", orderBy, order))
-
 	// 分页
 	page := 1
 	pageSize := 20
@@ -389,44 +412,28 @@ func (s *UserService) GetUserStats(ctx context.Context, userID uint) (*models.Us
 	return stats, nil
 }
 
-// UploadAvatar 上传用户头像
-func (s *UserService) UploadAvatar(ctx context.Context, userID uint, file multipart.File, header *multipart.FileHeader) (string, error) {
-	// 验证文件类型
-	allowedTypes := []string{".jpg", ".jpeg", ".png", ".gif"}
-	ext := strings.ToLower(filepath.Ext(header.Filename))
-
-	isAllowed := false
-	for _, allowedType := range allowedTypes {
-		if ext == allowedType {
-			isAllowed = true
-			break
-		}
+// UploadAvatar 上传用户头像，按内容校验格式和尺寸，生成标准尺寸并替换旧头像
+func (s *UserService) UploadAvatar(ctx context.Context, userID uint, file multipart.File, header *multipart.FileHeader) (*AvatarUploadResult, error) {
+	if s.avatarService == nil {
+		return nil, fmt.Errorf("avatar storage is not configured")
 	}
-
-	if !isAllowed {
-		return "", fmt.Errorf("unsupported file type: %s", ext)
-	}
-
-	// 验证文件大小（2MB限制）
-	if header.Size > 2*1024*1024 {
-		return "", fmt.Errorf("file too large: maximum 2MB allowed")
+	if header.Size > MaxAvatarFileSize {
+		return nil, ErrAvatarTooLarge
 	}
+	return s.avatarService.Upload(ctx, userID, file)
+}
 
-	// 生成唯一文件名
-	filename := fmt.Sprintf("avatar_%d_%d%s", userID, time.Now().Unix(), ext)
-
-	// TODO: 实现文件保存逻辑（本地存储或云存储）
-	// 这里暂时返回模拟的URL
-	avatarURL := fmt.Sprintf("/uploads/avatars/%s", filename)
-
-	// 更新用户头像URL
-	if err := s.db.WithContext(ctx).Model(&models.User{}).
-		Where("id = ?", userID).
-		Update("avatar", avatarURL).Error; err != nil {
-		return "", fmt.Errorf("failed to update avatar: %w", err)
+// DeleteAvatar 删除用户头像，恢复为默认头像
+func (s *UserService) DeleteAvatar(ctx context.Context, userID uint) error {
+	if s.avatarService == nil {
+		return fmt.Errorf("avatar storage is not configured")
 	}
+	return s.avatarService.Remove(ctx, userID)
+}
 
-	return avatarURL, nil
+// SetAuditLog 设置审计日志服务，修改密码记录为安全事件
+func (s *UserService) SetAuditLog(auditLog *AdminAuditService) {
+	s.auditLog = auditLog
 }
 
 // recordPasswordChange 记录密码修改（内部方法）
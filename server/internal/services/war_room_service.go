@@ -0,0 +1,520 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+const (
+	// warRoomMirrorBatch 每次同步每个作战室最多转发的历史条数
+	warRoomMirrorBatch = 50
+	// warRoomDescriptionMaxRunes 作战室首条消息中工单描述的最大长度
+	warRoomDescriptionMaxRunes  = 500
+	defaultWarRoomChannelPrefix = "war"
+)
+
+var (
+	// ErrWarRoomExists 工单已有作战室
+	ErrWarRoomExists = errors.New("war room already exists for ticket")
+	// ErrWarRoomNotCritical 只有严重优先级的工单可以创建作战室
+	ErrWarRoomNotCritical = errors.New("war room is only available for critical tickets")
+)
+
+// warRoomChannelInvalid 频道名中不允许的字符
+var warRoomChannelInvalid = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+// WarRoomOptions 创建作战室的参数
+type WarRoomOptions struct {
+	Platform      models.ChatPlatform // slack 或 teams，默认 slack
+	ChannelID     string              // 非空时不新建频道，在该频道中发起线程
+	ChannelPrefix string              // 新建频道名前缀，默认 war
+	Invite        []string            // 邀请对象：assignee、team、on_call，默认全部
+	OnCallUserIDs []uint              // 值班人员
+}
+
+// warRoomMember 作战室成员
+type warRoomMember struct {
+	UserID uint
+	Email  string
+	Name   string
+}
+
+// warRoomClient 作战室所在的聊天平台
+type warRoomClient interface {
+	// CreateChannel 新建频道并邀请成员，返回频道ID和成功邀请的人数
+	CreateChannel(ctx context.Context, name string, members []warRoomMember) (string, int, error)
+	// Invite 邀请成员加入已有频道
+	Invite(ctx context.Context, channelID string, members []warRoomMember) (int, error)
+	// Post 发送消息，threadID 非空时回复到线程，返回消息ID
+	Post(ctx context.Context, channelID, threadID, text string) (string, error)
+	// Archive 归档频道
+	Archive(ctx context.Context, channelID string) error
+}
+
+// WarRoomService 紧急工单升级作战室：创建聊天频道并邀请相关人员，
+// 同步工单的重要历史，工单解决后归档
+type WarRoomService struct {
+	db             *gorm.DB
+	notifier       *ChatNotifier
+	graphAPIBase   string
+	teamsLoginBase string
+	now            func() time.Time
+	historyWriter  *HistoryWriter
+}
+
+// NewWarRoomService 创建作战室服务
+func NewWarRoomService(db *gorm.DB) *WarRoomService {
+	return &WarRoomService{
+		db:             db,
+		notifier:       NewChatNotifier(db),
+		graphAPIBase:   "https://graph.microsoft.com/v1.0",
+		teamsLoginBase: "https://login.microsoftonline.com",
+		now:            time.Now,
+	}
+}
+
+// SetHistoryWriter 设置异步历史写入器，创建作战室的系统历史异步批量写入
+func (s *WarRoomService) SetHistoryWriter(writer *HistoryWriter) {
+	s.historyWriter = writer
+}
+
+// client 按平台创建作战室客户端，平台未启用时返回 ErrChatPlatformDisabled
+func (s *WarRoomService) client(config *models.ChatIntegrationConfig, platform models.ChatPlatform) (warRoomClient, error) {
+	switch platform {
+	case models.ChatPlatformSlack:
+		if !config.SlackEnabled {
+			return nil, ErrChatPlatformDisabled
+		}
+		return &slackWarRoomClient{db: s.db, notifier: s.notifier, token: config.SlackBotToken}, nil
+	case models.ChatPlatformTeams:
+		if !config.TeamsEnabled {
+			return nil, ErrChatPlatformDisabled
+		}
+		return &teamsWarRoomClient{
+			notifier:  s.notifier,
+			apiBase:   s.graphAPIBase,
+			loginBase: s.teamsLoginBase,
+			config:    config,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported war room platform: %s", platform)
+	}
+}
+
+// Open 为严重优先级工单创建作战室，并发送工单概况；工单已有作战室时返回 ErrWarRoomExists
+func (s *WarRoomService) Open(ctx context.Context, ticket *models.Ticket, opts *WarRoomOptions) (*models.TicketWarRoom, error) {
+	if ticket.Priority != models.TicketPriorityCritical {
+		return nil, ErrWarRoomNotCritical
+	}
+	var existing int64
+	if err := s.db.WithContext(ctx).Model(&models.TicketWarRoom{}).Where("ticket_id = ?", ticket.ID).Count(&existing).Error; err != nil {
+		return nil, fmt.Errorf("failed to check war room: %w", err)
+	}
+	if existing > 0 {
+		return nil, ErrWarRoomExists
+	}
+
+	platform := opts.Platform
+	if platform == "" {
+		platform = models.ChatPlatformSlack
+	}
+	config, err := loadChatIntegrationConfig(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+	client, err := s.client(config, platform)
+	if err != nil {
+		return nil, err
+	}
+	members, err := s.resolveMembers(ctx, ticket, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	room := &models.TicketWarRoom{
+		TicketID: ticket.ID,
+		Platform: platform,
+		Status:   models.WarRoomStatusActive,
+	}
+	if opts.ChannelID == "" {
+		room.ChannelName = warRoomChannelName(opts.ChannelPrefix, ticket.TicketNumber)
+		room.ChannelID, room.Invited, err = client.CreateChannel(ctx, room.ChannelName, members)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create war room channel: %w", err)
+		}
+	} else {
+		// 线程模式：邀请失败不影响发起线程
+		room.ChannelID = opts.ChannelID
+		if room.Invited, err = client.Invite(ctx, room.ChannelID, members); err != nil {
+			room.LastError = err.Error()
+		}
+	}
+
+	messageID, err := client.Post(ctx, room.ChannelID, "", warRoomIntro(ticket, members))
+	if err != nil {
+		return nil, fmt.Errorf("failed to post war room context: %w", err)
+	}
+	if opts.ChannelID != "" {
+		room.ThreadID = messageID
+	}
+
+	// 只同步创建之后的历史
+	if err := s.db.WithContext(ctx).Model(&models.TicketHistory{}).Where("ticket_id = ?", ticket.ID).
+		Select("COALESCE(MAX(id), 0)").Scan(&room.LastHistoryID).Error; err != nil {
+		return nil, fmt.Errorf("failed to get latest ticket history: %w", err)
+	}
+
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(room).Error; err != nil {
+			return fmt.Errorf("failed to save war room: %w", err)
+		}
+		where := room.ChannelName
+		if where == "" {
+			where = room.ChannelID
+		}
+		return recordHistory(tx, s.historyWriter, &models.TicketHistory{
+			TicketID:    ticket.ID,
+			Action:      models.HistoryActionEscalate,
+			Description: fmt.Sprintf("已创建升级作战室（%s #%s），邀请 %d 人", platform, where, room.Invited),
+			FieldName:   "war_room",
+			NewValue:    room.ChannelID,
+			IsVisible:   true,
+			IsSystem:    true,
+			IsAutomated: true,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return room, nil
+}
+
+// resolveMembers 解析要邀请的处理人、处理团队成员及值班人员，只保留启用的账户
+func (s *WarRoomService) resolveMembers(ctx context.Context, ticket *models.Ticket, opts *WarRoomOptions) ([]warRoomMember, error) {
+	kinds := opts.Invite
+	if len(kinds) == 0 {
+		kinds = []string{"assignee", "team", "on_call"}
+	}
+
+	var ids []uint
+	for _, kind := range kinds {
+		switch kind {
+		case "assignee":
+			if ticket.AssignedToID != nil {
+				ids = append(ids, *ticket.AssignedToID)
+			}
+		case "team":
+			if ticket.AssignedTeamID == nil {
+				continue
+			}
+			var team models.Team
+			if err := s.db.WithContext(ctx).Preload("Members").First(&team, *ticket.AssignedTeamID).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					continue
+				}
+				return nil, fmt.Errorf("failed to get ticket team: %w", err)
+			}
+			if team.LeadID != nil {
+				ids = append(ids, *team.LeadID)
+			}
+			for _, member := range team.Members {
+				ids = append(ids, member.ID)
+			}
+		case "on_call":
+			ids = append(ids, opts.OnCallUserIDs...)
+		default:
+			return nil, fmt.Errorf("invalid war room invitee: %s", kind)
+		}
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	var users []models.User
+	if err := s.db.WithContext(ctx).
+		Where("id IN ? AND status = ?", ids, models.UserStatusActive).
+		Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("failed to get war room members: %w", err)
+	}
+	byID := make(map[uint]*models.User, len(users))
+	for i := range users {
+		byID[users[i].ID] = &users[i]
+	}
+
+	// 按邀请对象的顺序排列，处理人在最前（Teams 私有频道以第一位成员为所有者）
+	members := make([]warRoomMember, 0, len(users))
+	for _, id := range ids {
+		user, ok := byID[id]
+		if !ok {
+			continue
+		}
+		delete(byID, id)
+		name := user.DisplayName
+		if name == "" {
+			name = user.Username
+		}
+		members = append(members, warRoomMember{UserID: user.ID, Email: strings.ToLower(user.Email), Name: name})
+	}
+	return members, nil
+}
+
+// SyncActive 同步所有进行中的作战室：转发新的重要历史，工单解决、关闭或删除后发送结束消息并归档。
+// 单个作战室失败时记录错误并继续处理其他作战室
+func (s *WarRoomService) SyncActive(ctx context.Context) (mirrored, archived int, err error) {
+	var rooms []*models.TicketWarRoom
+	if err := s.db.WithContext(ctx).Where("status = ?", models.WarRoomStatusActive).Order("id").Find(&rooms).Error; err != nil {
+		return 0, 0, fmt.Errorf("failed to get active war rooms: %w", err)
+	}
+	if len(rooms) == 0 {
+		return 0, 0, nil
+	}
+
+	config, err := loadChatIntegrationConfig(ctx, s.db)
+	if err != nil {
+		return 0, 0, err
+	}
+	clients := make(map[models.ChatPlatform]warRoomClient)
+	var failures []string
+	for _, room := range rooms {
+		client, ok := clients[room.Platform]
+		if !ok {
+			if client, err = s.client(config, room.Platform); err != nil {
+				failures = append(failures, fmt.Sprintf("war room %d: %v", room.ID, err))
+				continue
+			}
+			clients[room.Platform] = client
+		}
+
+		count, done, err := s.syncRoom(ctx, client, room)
+		mirrored += count
+		if done {
+			archived++
+		}
+		if err != nil {
+			failures = append(failures, fmt.Sprintf("war room %d: %v", room.ID, err))
+			s.db.WithContext(ctx).Model(room).Update("last_error", err.Error())
+		}
+	}
+	if len(failures) > 0 {
+		return mirrored, archived, fmt.Errorf("failed to sync war rooms: %s", strings.Join(failures, "; "))
+	}
+	return mirrored, archived, nil
+}
+
+// syncRoom 转发一个作战室的新历史，工单已结束时归档
+func (s *WarRoomService) syncRoom(ctx context.Context, client warRoomClient, room *models.TicketWarRoom) (int, bool, error) {
+	var ticket models.Ticket
+	if err := s.db.WithContext(ctx).First(&ticket, room.TicketID).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, false, fmt.Errorf("failed to get ticket: %w", err)
+	}
+
+	var histories []models.TicketHistory
+	if err := s.db.WithContext(ctx).Preload("User").
+		Where("ticket_id = ? AND id > ? AND is_important = ?", room.TicketID, room.LastHistoryID, true).
+		Order("id").Limit(warRoomMirrorBatch).Find(&histories).Error; err != nil {
+		return 0, false, fmt.Errorf("failed to get ticket history: %w", err)
+	}
+
+	mirrored := 0
+	for _, history := range histories {
+		if _, err := client.Post(ctx, room.ChannelID, room.ThreadID, warRoomHistoryText(&history)); err != nil {
+			return mirrored, false, err
+		}
+		mirrored++
+		room.LastHistoryID = history.ID
+		if err := s.db.WithContext(ctx).Model(room).Updates(map[string]interface{}{
+			"last_history_id": history.ID,
+			"last_error":      "",
+		}).Error; err != nil {
+			return mirrored, false, fmt.Errorf("failed to update war room cursor: %w", err)
+		}
+	}
+	if len(histories) == warRoomMirrorBatch {
+		// 还有未转发的历史，下次继续，全部转发后再归档
+		return mirrored, false, nil
+	}
+
+	finished := ticket.ID == 0 || ticket.DeletedAt != nil ||
+		ticket.Status == models.TicketStatusResolved || ticket.Status == models.TicketStatusClosed ||
+		ticket.Status == models.TicketStatusCancelled
+	if !finished {
+		return mirrored, false, nil
+	}
+
+	text := "工单已删除，作战室即将归档"
+	if ticket.ID != 0 && ticket.DeletedAt == nil {
+		text = fmt.Sprintf("工单 #%s 已%s，作战室即将归档", ticket.TicketNumber, getStatusLabel(string(ticket.Status)))
+	}
+	if _, err := client.Post(ctx, room.ChannelID, room.ThreadID, text); err != nil {
+		return mirrored, false, err
+	}
+	// 线程模式不归档所在的共享频道
+	if room.ThreadID == "" {
+		if err := client.Archive(ctx, room.ChannelID); err != nil {
+			return mirrored, false, fmt.Errorf("failed to archive channel: %w", err)
+		}
+	}
+	now := s.now()
+	if err := s.db.WithContext(ctx).Model(room).Updates(map[string]interface{}{
+		"status":      models.WarRoomStatusArchived,
+		"archived_at": now,
+		"last_error":  "",
+	}).Error; err != nil {
+		return mirrored, false, fmt.Errorf("failed to archive war room: %w", err)
+	}
+	return mirrored, true, nil
+}
+
+// warRoomChannelName 生成频道名，如 war-tk-20240115-0001（小写，最长 80 字符）
+func warRoomChannelName(prefix, ticketNumber string) string {
+	if prefix == "" {
+		prefix = defaultWarRoomChannelPrefix
+	}
+	name := warRoomChannelInvalid.ReplaceAllString(strings.ToLower(prefix+"-"+ticketNumber), "-")
+	name = strings.Trim(name, "-")
+	if len(name) > 80 {
+		name = name[:80]
+	}
+	return name
+}
+
+// warRoomIntro 作战室首条消息：工单概况及邀请的成员
+func warRoomIntro(ticket *models.Ticket, members []warRoomMember) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "🚨 严重工单 #%s：%s\n", ticket.TicketNumber, ticket.Title)
+	fmt.Fprintf(&b, "状态：%s　优先级：%s　类型：%s\n", getStatusLabel(string(ticket.Status)),
+		getPriorityLabel(string(ticket.Priority)), ticket.Type)
+	if ticket.CustomerName != "" || ticket.CustomerEmail != "" {
+		fmt.Fprintf(&b, "客户：%s %s\n", ticket.CustomerName, ticket.CustomerEmail)
+	}
+	if description := strings.TrimSpace(ticket.Description); description != "" {
+		if utf8.RuneCountInString(description) > warRoomDescriptionMaxRunes {
+			description = string([]rune(description)[:warRoomDescriptionMaxRunes]) + "…"
+		}
+		fmt.Fprintf(&b, "\n%s\n", description)
+	}
+	if len(members) > 0 {
+		names := make([]string, len(members))
+		for i, member := range members {
+			names[i] = member.Name
+		}
+		fmt.Fprintf(&b, "\n成员：%s\n", strings.Join(names, "、"))
+	}
+	b.WriteString("\n工单的重要动态将同步到这里，工单解决后作战室自动归档。")
+	return b.String()
+}
+
+// warRoomHistoryText 转发到作战室的历史消息
+func warRoomHistoryText(history *models.TicketHistory) string {
+	actor := "系统"
+	if history.User != nil {
+		actor = history.User.Username
+		if history.User.DisplayName != "" {
+			actor = history.User.DisplayName
+		}
+	}
+	return fmt.Sprintf("[%s] %s：%s", history.CreatedAt.Format("01-02 15:04"), actor, history.Description)
+}
+
+// slackWarRoomClient Slack 作战室，机器人需要 channels:manage、chat:write 权限；
+// 成员按聊天账号映射查找 Slack 用户，未映射的用户不会被邀请
+type slackWarRoomClient struct {
+	db       *gorm.DB
+	notifier *ChatNotifier
+	token    string
+}
+
+type slackConversationResult struct {
+	OK      bool   `json:"ok"`
+	Error   string `json:"error"`
+	Channel struct {
+		ID string `json:"id"`
+	} `json:"channel"`
+}
+
+func (c *slackWarRoomClient) call(ctx context.Context, method string, payload map[string]interface{}) (*slackConversationResult, error) {
+	var result slackConversationResult
+	if err := c.notifier.postJSON(ctx, c.notifier.slackAPIBase+"/"+method, c.token, payload, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func (c *slackWarRoomClient) CreateChannel(ctx context.Context, name string, members []warRoomMember) (string, int, error) {
+	result, err := c.call(ctx, "conversations.create", map[string]interface{}{"name": name, "is_private": false})
+	if err == nil && !result.OK && result.Error == "name_taken" {
+		// 同名频道已存在（如之前归档过），加时间后缀重试
+		suffix := fmt.Sprintf("-%d", time.Now().Unix())
+		if len(name)+len(suffix) > 80 {
+			name = name[:80-len(suffix)]
+		}
+		result, err = c.call(ctx, "conversations.create", map[string]interface{}{"name": name + suffix, "is_private": false})
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	if !result.OK {
+		return "", 0, fmt.Errorf("slack conversations.create failed: %s", result.Error)
+	}
+
+	invited, err := c.Invite(ctx, result.Channel.ID, members)
+	if err != nil {
+		log.Printf("Failed to invite war room members to slack channel %s: %v", result.Channel.ID, err)
+	}
+	return result.Channel.ID, invited, nil
+}
+
+func (c *slackWarRoomClient) Invite(ctx context.Context, channelID string, members []warRoomMember) (int, error) {
+	if len(members) == 0 {
+		return 0, nil
+	}
+	emails := make([]string, len(members))
+	for i, member := range members {
+		emails[i] = member.Email
+	}
+	var slackIDs []string
+	if err := c.db.WithContext(ctx).Model(&models.ChatUserMapping{}).
+		Where("platform = ? AND email IN ?", models.ChatPlatformSlack, emails).
+		Distinct().Pluck("external_user_id", &slackIDs).Error; err != nil {
+		return 0, fmt.Errorf("failed to get slack user mappings: %w", err)
+	}
+	if len(slackIDs) == 0 {
+		return 0, nil
+	}
+
+	result, err := c.call(ctx, "conversations.invite", map[string]interface{}{
+		"channel": channelID,
+		"users":   strings.Join(slackIDs, ","),
+	})
+	if err != nil {
+		return 0, err
+	}
+	if !result.OK && result.Error != "already_in_channel" {
+		return 0, fmt.Errorf("slack conversations.invite failed: %s", result.Error)
+	}
+	return len(slackIDs), nil
+}
+
+func (c *slackWarRoomClient) Post(ctx context.Context, channelID, threadID, text string) (string, error) {
+	return c.notifier.postSlack(ctx, c.token, channelID, threadID, text)
+}
+
+func (c *slackWarRoomClient) Archive(ctx context.Context, channelID string) error {
+	result, err := c.call(ctx, "conversations.archive", map[string]interface{}{"channel": channelID})
+	if err != nil {
+		return err
+	}
+	if !result.OK && result.Error != "already_archived" {
+		return fmt.Errorf("slack conversations.archive failed: %s", result.Error)
+	}
+	return nil
+}
@@ -0,0 +1,298 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// fakeWarRoomAPI 模拟 Slack Web API 与 Microsoft Graph，记录收到的请求
+type fakeWarRoomAPI struct {
+	mu       sync.Mutex
+	requests []map[string]interface{}
+	tokens   int
+}
+
+func (f *fakeWarRoomAPI) handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/oauth2/v2.0/token") {
+			r.ParseForm()
+			f.mu.Lock()
+			f.tokens++
+			f.mu.Unlock()
+			if r.Form.Get("grant_type") != "client_credentials" || r.Form.Get("client_secret") != "teams-secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				json.NewEncoder(w).Encode(map[string]string{"error_description": "invalid client"})
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"access_token": "graph-token"})
+			return
+		}
+
+		payload := map[string]interface{}{}
+		json.NewDecoder(r.Body).Decode(&payload)
+		payload["path"] = r.URL.Path
+		payload["auth"] = r.Header.Get("Authorization")
+		f.mu.Lock()
+		f.requests = append(f.requests, payload)
+		n := len(f.requests)
+		f.mu.Unlock()
+
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/slack/"):
+			json.NewEncoder(w).Encode(map[string]interface{}{"ok": true, "ts": "1700000000." + strconv.Itoa(n), "channel": map[string]string{"id": "CWAR"}})
+		case strings.HasSuffix(r.URL.Path, "/archive"):
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			w.WriteHeader(http.StatusCreated)
+			json.NewEncoder(w).Encode(map[string]string{"id": "graph-" + strconv.Itoa(n)})
+		}
+	})
+}
+
+func (f *fakeWarRoomAPI) sent() []map[string]interface{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]map[string]interface{}(nil), f.requests...)
+}
+
+func (f *fakeWarRoomAPI) reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = nil
+}
+
+func setupWarRoomTestDB(t *testing.T, name string) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file:"+name+"?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.Team{}, &models.Ticket{}, &models.TicketHistory{}, &models.SystemConfig{},
+		&models.ChatUserMapping{}, &models.TicketWarRoom{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+	return db
+}
+
+func TestWarRoom_SlackChannelMirrorsImportantHistoryAndArchives(t *testing.T) {
+	db := setupWarRoomTestDB(t, "war_room_slack_test")
+	api := &fakeWarRoomAPI{}
+	server := httptest.NewServer(api.handler())
+	defer server.Close()
+
+	ctx := context.Background()
+	automation := NewAutomationService(db)
+	rooms := automation.warRoomService
+	rooms.notifier.slackAPIBase = server.URL + "/slack"
+
+	newUser := func(name string, status models.UserStatus, slackID string) models.User {
+		user := models.User{Username: name, Email: name + "@example.com", PasswordHash: "x", Role: models.RoleAgent, Status: status}
+		db.Create(&user)
+		if slackID != "" {
+			db.Create(&models.ChatUserMapping{Platform: models.ChatPlatformSlack, ExternalUserID: slackID, Email: user.Email})
+		}
+		return user
+	}
+	assignee := newUser("wr-assignee", models.UserStatusActive, "U1")
+	member := newUser("wr-member", models.UserStatusActive, "U2")
+	lead := newUser("wr-lead", models.UserStatusActive, "")
+	onCall := newUser("wr-oncall", models.UserStatusActive, "U3")
+	inactive := newUser("wr-inactive", models.UserStatusInactive, "U4")
+	team := models.Team{Name: "War Room L2", Slug: "wr-l2", IsActive: true, LeadID: &lead.ID, Members: []models.User{member}}
+	db.Create(&team)
+
+	config := models.GetDefaultChatIntegrationConfig()
+	config.SlackEnabled = true
+	config.SlackSigningSecret = "signing-secret"
+	config.SlackBotToken = "xoxb-test"
+	if err := NewChatIntakeService(db).SetConfig(ctx, config, assignee.ID); err != nil {
+		t.Fatalf("set chat config failed: %v", err)
+	}
+
+	action := &models.RuleAction{Type: "create_war_room", Params: map[string]interface{}{
+		"on_call_user_ids": []interface{}{float64(onCall.ID), float64(inactive.ID)},
+	}}
+
+	// 非严重工单不创建作战室
+	normal := models.Ticket{TicketNumber: "WR-000", Title: "minor", Status: models.TicketStatusOpen, Priority: models.TicketPriorityHigh,
+		Type: models.TicketTypeIncident, Source: models.TicketSourceWeb, CreatedByID: assignee.ID}
+	db.Create(&normal)
+	if err := automation.executeAction(ctx, action, &normal); err != nil || len(api.sent()) != 0 {
+		t.Fatalf("expected non-critical ticket to be skipped, err=%v requests=%d", err, len(api.sent()))
+	}
+
+	ticket := models.Ticket{TicketNumber: "WR-001", Title: "支付中断", Description: "所有支付请求失败", Status: models.TicketStatusInProgress,
+		Priority: models.TicketPriorityCritical, Type: models.TicketTypeIncident, Source: models.TicketSourceWeb,
+		CreatedByID: assignee.ID, AssignedToID: &assignee.ID, AssignedTeamID: &team.ID}
+	db.Create(&ticket)
+	db.Create(&models.TicketHistory{TicketID: ticket.ID, Action: models.HistoryActionCreate, Description: "创建工单", IsImportant: true})
+
+	// 重复执行只创建一个作战室
+	for i := 0; i < 2; i++ {
+		if err := automation.executeAction(ctx, action, &ticket); err != nil {
+			t.Fatalf("create_war_room action failed: %v", err)
+		}
+	}
+	var room models.TicketWarRoom
+	if err := db.Where("ticket_id = ?", ticket.ID).First(&room).Error; err != nil {
+		t.Fatalf("expected war room to be created: %v", err)
+	}
+	if room.ChannelID != "CWAR" || room.ChannelName != "war-wr-001" || room.Invited != 3 || room.Status != models.WarRoomStatusActive {
+		t.Fatalf("unexpected war room: %+v", room)
+	}
+
+	sent := api.sent()
+	if len(sent) != 3 {
+		t.Fatalf("expected create, invite and intro requests, got %d", len(sent))
+	}
+	if sent[0]["path"] != "/slack/conversations.create" || sent[0]["name"] != "war-wr-001" || sent[0]["auth"] != "Bearer xoxb-test" {
+		t.Fatalf("unexpected create request: %v", sent[0])
+	}
+	invited := strings.Split(sent[1]["users"].(string), ",")
+	sort.Strings(invited)
+	if sent[1]["path"] != "/slack/conversations.invite" || strings.Join(invited, ",") != "U1,U2,U3" {
+		t.Fatalf("expected mapped active assignee, team and on-call users to be invited, got %v", sent[1])
+	}
+	intro, _ := sent[2]["text"].(string)
+	if sent[2]["channel"] != "CWAR" || !strings.Contains(intro, "WR-001") || !strings.Contains(intro, "所有支付请求失败") {
+		t.Fatalf("unexpected intro message: %v", sent[2])
+	}
+
+	// 只转发创建之后的重要历史
+	api.reset()
+	db.Create(&models.TicketHistory{TicketID: ticket.ID, UserID: &assignee.ID, Action: models.HistoryActionComment, Description: "内部讨论"})
+	db.Create(&models.TicketHistory{TicketID: ticket.ID, UserID: &assignee.ID, Action: models.HistoryActionPriorityChange, Description: "已回滚发布", IsImportant: true})
+	mirrored, archived, err := rooms.SyncActive(ctx)
+	if err != nil || mirrored != 1 || archived != 0 {
+		t.Fatalf("expected one mirrored entry, got mirrored=%d archived=%d err=%v", mirrored, archived, err)
+	}
+	if sent := api.sent(); len(sent) != 1 || !strings.Contains(sent[0]["text"].(string), "wr-assignee：已回滚发布") {
+		t.Fatalf("unexpected mirrored message: %v", sent)
+	}
+
+	// 工单解决后转发剩余历史并归档频道
+	api.reset()
+	db.Model(&ticket).Update("status", models.TicketStatusResolved)
+	db.Create(&models.TicketHistory{TicketID: ticket.ID, Action: models.HistoryActionResolve, Description: "工单已解决", IsImportant: true})
+	if mirrored, archived, err = rooms.SyncActive(ctx); err != nil || mirrored != 1 || archived != 1 {
+		t.Fatalf("expected war room to be archived, got mirrored=%d archived=%d err=%v", mirrored, archived, err)
+	}
+	sent = api.sent()
+	if len(sent) != 3 || sent[2]["path"] != "/slack/conversations.archive" || sent[2]["channel"] != "CWAR" {
+		t.Fatalf("unexpected archive requests: %v", sent)
+	}
+	db.First(&room, room.ID)
+	if room.Status != models.WarRoomStatusArchived || room.ArchivedAt == nil {
+		t.Fatalf("expected war room to be archived, got %+v", room)
+	}
+	if mirrored, archived, _ := rooms.SyncActive(ctx); mirrored != 0 || archived != 0 {
+		t.Fatalf("archived war rooms should not be synced again")
+	}
+
+	// 线程模式：在已有频道中发起线程，后续消息回复到线程，结束时不归档频道
+	api.reset()
+	thread := models.Ticket{TicketNumber: "WR-002", Title: "数据库故障", Status: models.TicketStatusOpen, Priority: models.TicketPriorityCritical,
+		Type: models.TicketTypeIncident, Source: models.TicketSourceWeb, CreatedByID: assignee.ID, AssignedToID: &assignee.ID}
+	db.Create(&thread)
+	threadAction := &models.RuleAction{Type: "create_war_room", Params: map[string]interface{}{"channel_id": "COPS", "invite": "assignee"}}
+	if err := automation.executeAction(ctx, threadAction, &thread); err != nil {
+		t.Fatalf("create_war_room thread action failed: %v", err)
+	}
+	var threadRoom models.TicketWarRoom
+	db.Where("ticket_id = ?", thread.ID).First(&threadRoom)
+	if threadRoom.ChannelID != "COPS" || threadRoom.ThreadID == "" || threadRoom.Invited != 1 {
+		t.Fatalf("unexpected thread war room: %+v", threadRoom)
+	}
+	db.Model(&thread).Update("status", models.TicketStatusClosed)
+	if _, archived, err := rooms.SyncActive(ctx); err != nil || archived != 1 {
+		t.Fatalf("expected thread war room to be closed, archived=%d err=%v", archived, err)
+	}
+	for _, req := range api.sent() {
+		if req["path"] == "/slack/conversations.create" || req["path"] == "/slack/conversations.archive" {
+			t.Fatalf("thread mode should not create or archive channels: %v", req)
+		}
+	}
+	if last := api.sent()[len(api.sent())-1]; last["thread_ts"] != threadRoom.ThreadID {
+		t.Fatalf("expected closing message in thread, got %v", last)
+	}
+}
+
+func TestWarRoom_TeamsPrivateChannel(t *testing.T) {
+	db := setupWarRoomTestDB(t, "war_room_teams_test")
+	api := &fakeWarRoomAPI{}
+	server := httptest.NewServer(api.handler())
+	defer server.Close()
+
+	ctx := context.Background()
+	rooms := NewWarRoomService(db)
+	rooms.graphAPIBase = server.URL + "/graph"
+	rooms.teamsLoginBase = server.URL + "/login"
+
+	owner := models.User{Username: "teams-owner", Email: "Owner@Example.com", PasswordHash: "x", Role: models.RoleAgent, Status: models.UserStatusActive}
+	onCall := models.User{Username: "teams-oncall", Email: "oncall@example.com", PasswordHash: "x", Role: models.RoleAgent, Status: models.UserStatusActive}
+	db.Create(&owner)
+	db.Create(&onCall)
+	ticket := models.Ticket{TicketNumber: "WR-100", Title: "机房断电", Status: models.TicketStatusOpen, Priority: models.TicketPriorityCritical,
+		Type: models.TicketTypeIncident, Source: models.TicketSourceWeb, CreatedByID: owner.ID, AssignedToID: &owner.ID}
+	db.Create(&ticket)
+
+	opts := &WarRoomOptions{Platform: models.ChatPlatformTeams, OnCallUserIDs: []uint{onCall.ID}}
+	if _, err := rooms.Open(ctx, &ticket, opts); err != ErrChatPlatformDisabled {
+		t.Fatalf("expected disabled teams to be rejected, got %v", err)
+	}
+
+	config := models.GetDefaultChatIntegrationConfig()
+	config.TeamsEnabled = true
+	config.TeamsTenantID = "tenant"
+	config.TeamsClientID = "client"
+	config.TeamsTeamID = "team-1"
+	if err := config.Validate(); err == nil {
+		t.Fatalf("expected teams without client secret to be invalid")
+	}
+	config.TeamsClientSecret = "teams-secret"
+	if err := NewChatIntakeService(db).SetConfig(ctx, config, owner.ID); err != nil {
+		t.Fatalf("set chat config failed: %v", err)
+	}
+
+	room, err := rooms.Open(ctx, &ticket, opts)
+	if err != nil {
+		t.Fatalf("open teams war room failed: %v", err)
+	}
+	if room.Platform != models.ChatPlatformTeams || room.ChannelID != "graph-1" || room.Invited != 2 {
+		t.Fatalf("unexpected teams war room: %+v", room)
+	}
+
+	sent := api.sent()
+	if len(sent) != 3 || api.tokens != 1 {
+		t.Fatalf("expected channel, member and message requests with one token, got %d requests, %d tokens", len(sent), api.tokens)
+	}
+	create := sent[0]
+	members, _ := create["members"].([]interface{})
+	if create["path"] != "/graph/teams/team-1/channels" || create["membershipType"] != "private" || create["auth"] != "Bearer graph-token" ||
+		len(members) != 1 || !strings.Contains(members[0].(map[string]interface{})["user@odata.bind"].(string), "users('owner@example.com')") {
+		t.Fatalf("unexpected create channel request: %v", create)
+	}
+	if sent[1]["path"] != "/graph/teams/team-1/channels/graph-1/members" || sent[2]["path"] != "/graph/teams/team-1/channels/graph-1/messages" {
+		t.Fatalf("unexpected teams requests: %v", sent)
+	}
+
+	api.reset()
+	db.Model(&ticket).Update("status", models.TicketStatusResolved)
+	if _, archived, err := rooms.SyncActive(ctx); err != nil || archived != 1 {
+		t.Fatalf("expected teams war room to be archived, archived=%d err=%v", archived, err)
+	}
+	if sent := api.sent(); len(sent) != 2 || sent[1]["path"] != "/graph/teams/team-1/channels/graph-1/archive" {
+		t.Fatalf("unexpected teams archive requests: %v", sent)
+	}
+}
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"gongdan-system/internal/models"
+)
+
+// teamsChannelNameMaxLen Teams 频道显示名最大长度
+const teamsChannelNameMaxLen = 50
+
+// teamsWarRoomClient Microsoft Teams 作战室，通过 Microsoft Graph 客户端凭据调用，
+// 应用需要 Channel.Create、ChannelMember.ReadWrite.All、ChannelMessage.Send 权限。
+// 有成员时新建私有频道，成员按邮箱（UPN）添加，第一位成员为频道所有者
+type teamsWarRoomClient struct {
+	notifier  *ChatNotifier
+	apiBase   string
+	loginBase string
+	config    *models.ChatIntegrationConfig
+	token     string
+}
+
+// accessToken 获取 Graph 访问令牌，同一客户端内复用
+func (c *teamsWarRoomClient) accessToken(ctx context.Context) (string, error) {
+	if c.token != "" {
+		return c.token, nil
+	}
+	form := url.Values{
+		"client_id":     {c.config.TeamsClientID},
+		"client_secret": {c.config.TeamsClientSecret},
+		"scope":         {"https://graph.microsoft.com/.default"},
+		"grant_type":    {"client_credentials"},
+	}
+	endpoint := fmt.Sprintf("%s/%s/oauth2/v2.0/token", c.loginBase, url.PathEscape(c.config.TeamsTenantID))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.notifier.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	var result struct {
+		AccessToken      string `json:"access_token"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("unexpected token response (HTTP %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK || result.AccessToken == "" {
+		return "", fmt.Errorf("teams token request failed (HTTP %d): %s", resp.StatusCode, result.ErrorDescription)
+	}
+	c.token = result.AccessToken
+	return c.token, nil
+}
+
+// graph 调用 Graph 接口，非 2xx 状态码视为失败；out 为空或响应无内容时不解析
+func (c *teamsWarRoomClient) graph(ctx context.Context, path string, payload, out interface{}) error {
+	token, err := c.accessToken(ctx)
+	if err != nil {
+		return err
+	}
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.apiBase+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := c.notifier.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("graph %s failed (HTTP %d): %s", path, resp.StatusCode, truncateString(string(data), 200))
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+func (c *teamsWarRoomClient) teamPath() string {
+	return "/teams/" + url.PathEscape(c.config.TeamsTeamID)
+}
+
+// teamsMember Graph 频道成员
+func teamsMember(member warRoomMember, owner bool) map[string]interface{} {
+	roles := []string{}
+	if owner {
+		roles = []string{"owner"}
+	}
+	return map[string]interface{}{
+		"@odata.type":     "#microsoft.graph.aadUserConversationMember",
+		"roles":           roles,
+		"user@odata.bind": fmt.Sprintf("https://graph.microsoft.com/v1.0/users('%s')", member.Email),
+	}
+}
+
+func (c *teamsWarRoomClient) CreateChannel(ctx context.Context, name string, members []warRoomMember) (string, int, error) {
+	if len(name) > teamsChannelNameMaxLen {
+		name = name[:teamsChannelNameMaxLen]
+	}
+	payload := map[string]interface{}{
+		"displayName":    name,
+		"description":    "工单升级作战室",
+		"membershipType": "standard",
+	}
+	// 私有频道创建时必须指定所有者，其余成员创建后逐个添加
+	if len(members) > 0 {
+		payload["membershipType"] = "private"
+		payload["members"] = []interface{}{teamsMember(members[0], true)}
+	}
+	var channel struct {
+		ID string `json:"id"`
+	}
+	if err := c.graph(ctx, c.teamPath()+"/channels", payload, &channel); err != nil {
+		return "", 0, err
+	}
+	if len(members) == 0 {
+		return channel.ID, 0, nil
+	}
+
+	invited, err := c.Invite(ctx, channel.ID, members[1:])
+	if err != nil {
+		log.Printf("Failed to invite war room members to teams channel %s: %v", channel.ID, err)
+	}
+	return channel.ID, invited + 1, nil
+}
+
+func (c *teamsWarRoomClient) Invite(ctx context.Context, channelID string, members []warRoomMember) (int, error) {
+	invited := 0
+	var failures []string
+	for _, member := range members {
+		path := c.teamPath() + "/channels/" + url.PathEscape(channelID) + "/members"
+		if err := c.graph(ctx, path, teamsMember(member, false), nil); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", member.Email, err))
+			continue
+		}
+		invited++
+	}
+	if len(failures) > 0 {
+		return invited, fmt.Errorf("failed to add teams channel members: %s", strings.Join(failures, "; "))
+	}
+	return invited, nil
+}
+
+func (c *teamsWarRoomClient) Post(ctx context.Context, channelID, threadID, text string) (string, error) {
+	path := c.teamPath() + "/channels/" + url.PathEscape(channelID) + "/messages"
+	if threadID != "" {
+		path += "/" + url.PathEscape(threadID) + "/replies"
+	}
+	payload := map[string]interface{}{
+		"body": map[string]string{"contentType": "text", "content": text},
+	}
+	var message struct {
+		ID string `json:"id"`
+	}
+	if err := c.graph(ctx, path, payload, &message); err != nil {
+		return "", err
+	}
+	return message.ID, nil
+}
+
+func (c *teamsWarRoomClient) Archive(ctx context.Context, channelID string) error {
+	return c.graph(ctx, c.teamPath()+"/channels/"+url.PathEscape(channelID)+"/archive", nil, nil)
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// Web Push 消息加密（RFC 8291，aes128gcm）与 VAPID 认证（RFC 8292）
+
+// webPushRecordSize aes128gcm 记录大小，消息只占一条记录
+const webPushRecordSize = 4096
+
+// webPushMaxPayload 加密前消息上限：推送服务限制请求体 4096 字节，需扣除头部(86)、GCM 标签(16)与记录分隔符(1)
+const webPushMaxPayload = webPushRecordSize - 86 - 16 - 1
+
+// ErrInvalidVAPIDKey VAPID 密钥格式错误
+var ErrInvalidVAPIDKey = errors.New("invalid vapid key")
+
+// decodeWebPushKey 解码 base64url 密钥，兼容部分浏览器带填充的格式
+func decodeWebPushKey(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+}
+
+// GenerateVAPIDKeys 生成 VAPID 密钥对，返回 base64url 编码的公钥（65字节未压缩点）和私钥（32字节）
+func GenerateVAPIDKeys() (publicKey, privateKey string, err error) {
+	key, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", fmt.Errorf("生成VAPID密钥失败: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(key.PublicKey().Bytes()),
+		base64.RawURLEncoding.EncodeToString(key.Bytes()), nil
+}
+
+// encryptWebPush 使用浏览器订阅中的 p256dh 与 auth 加密消息，返回可直接作为请求体的 aes128gcm 数据
+func encryptWebPush(payload []byte, p256dh, authSecret string) ([]byte, error) {
+	if len(payload) > webPushMaxPayload {
+		return nil, fmt.Errorf("推送内容过长: %d 字节", len(payload))
+	}
+	uaPublicBytes, err := decodeWebPushKey(p256dh)
+	if err != nil {
+		return nil, fmt.Errorf("订阅公钥格式错误: %w", err)
+	}
+	auth, err := decodeWebPushKey(authSecret)
+	if err != nil {
+		return nil, fmt.Errorf("订阅认证密钥格式错误: %w", err)
+	}
+	uaPublic, err := ecdh.P256().NewPublicKey(uaPublicBytes)
+	if err != nil {
+		return nil, fmt.Errorf("订阅公钥无效: %w", err)
+	}
+
+	asPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	asPublic := asPrivate.PublicKey().Bytes()
+	shared, err := asPrivate.ECDH(uaPublic)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	keyInfo := append([]byte("WebPush: info\x00"), uaPublicBytes...)
+	keyInfo = append(keyInfo, asPublic...)
+	ikm, err := hkdfRead(shared, auth, keyInfo, 32)
+	if err != nil {
+		return nil, err
+	}
+	cek, err := hkdfRead(ikm, salt, []byte("Content-Encoding: aes128gcm\x00"), 16)
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := hkdfRead(ikm, salt, []byte("Content-Encoding: nonce\x00"), 12)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// 头部: salt(16) | rs(4) | idlen(1) | keyid(发送方公钥)
+	header := make([]byte, 0, 21+len(asPublic))
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, webPushRecordSize)
+	header = append(header, byte(len(asPublic)))
+	header = append(header, asPublic...)
+
+	// 单条记录以 0x02 作为最后一条记录的分隔符
+	plaintext := append(append([]byte{}, payload...), 0x02)
+	return gcm.Seal(header, nonce, plaintext, nil), nil
+}
+
+func hkdfRead(secret, salt, info []byte, size int) ([]byte, error) {
+	out := make([]byte, size)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, salt, info), out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// vapidAuthorization 生成推送请求的 Authorization 头，JWT 的 aud 为推送服务的源
+func vapidAuthorization(endpoint, subject, publicKey, privateKey string, now time.Time) (string, error) {
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil || endpointURL.Scheme == "" || endpointURL.Host == "" {
+		return "", fmt.Errorf("推送地址无效: %s", endpoint)
+	}
+
+	signer, err := vapidSigner(publicKey, privateKey)
+	if err != nil {
+		return "", err
+	}
+
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"typ":"JWT","alg":"ES256"}`))
+	claims, err := json.Marshal(map[string]interface{}{
+		"aud": endpointURL.Scheme + "://" + endpointURL.Host,
+		"exp": now.Add(12 * time.Hour).Unix(),
+		"sub": subject,
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := header + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(unsigned))
+	r, s, err := ecdsa.Sign(rand.Reader, signer, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("VAPID签名失败: %w", err)
+	}
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+
+	token := unsigned + "." + base64.RawURLEncoding.EncodeToString(signature)
+	return fmt.Sprintf("vapid t=%s, k=%s", token, strings.TrimRight(publicKey, "=")), nil
+}
+
+// vapidSigner 由 base64url 编码的密钥对构造 ES256 签名私钥，并校验公私钥匹配
+func vapidSigner(publicKey, privateKey string) (*ecdsa.PrivateKey, error) {
+	privateBytes, err := decodeWebPushKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidVAPIDKey, err)
+	}
+	key, err := ecdh.P256().NewPrivateKey(privateBytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidVAPIDKey, err)
+	}
+	publicBytes := key.PublicKey().Bytes()
+	if configured, err := decodeWebPushKey(publicKey); err != nil || string(configured) != string(publicBytes) {
+		return nil, fmt.Errorf("%w: public key does not match private key", ErrInvalidVAPIDKey)
+	}
+
+	return &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(publicBytes[1:33]),
+			Y:     new(big.Int).SetBytes(publicBytes[33:]),
+		},
+		D: new(big.Int).SetBytes(privateBytes),
+	}, nil
+}
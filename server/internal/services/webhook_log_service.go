@@ -0,0 +1,226 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidWebhookLogFilter 日志查询条件无效
+var ErrInvalidWebhookLogFilter = errors.New("invalid webhook log filter")
+
+// webhookFailedStatuses 计入失败的日志状态，重试中的投递同样计为失败
+var webhookFailedStatuses = []string{"failed", "retrying"}
+
+// WebhookLogService Webhook日志查询、统计与按保留策略清理
+type WebhookLogService struct {
+	db            *gorm.DB
+	configService *ConfigService
+}
+
+// NewWebhookLogService 创建Webhook日志服务
+func NewWebhookLogService(db *gorm.DB) *WebhookLogService {
+	return &WebhookLogService{
+		db:            db,
+		configService: NewConfigService(db),
+	}
+}
+
+// ListLogs 按条件分页查询Webhook日志，按创建时间倒序
+func (s *WebhookLogService) ListLogs(ctx context.Context, configID uint, filter *models.WebhookLogFilter) ([]models.WebhookLog, int64, error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PageSize < 1 || filter.PageSize > 100 {
+		filter.PageSize = 20
+	}
+
+	query, err := s.filterQuery(ctx, configID, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count webhook logs: %w", err)
+	}
+	logs := []models.WebhookLog{}
+	if err := query.Order("created_at DESC, id DESC").
+		Offset((filter.Page - 1) * filter.PageSize).Limit(filter.PageSize).
+		Find(&logs).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to get webhook logs: %w", err)
+	}
+	return logs, total, nil
+}
+
+func (s *WebhookLogService) filterQuery(ctx context.Context, configID uint, filter *models.WebhookLogFilter) (*gorm.DB, error) {
+	query := s.db.WithContext(ctx).Model(&models.WebhookLog{}).Where("config_id = ?", configID)
+
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.EventType != "" {
+		query = query.Where("event_type = ?", filter.EventType)
+	}
+	if code := strings.ToLower(strings.TrimSpace(filter.HTTPStatus)); code != "" {
+		// 2xx/4xx/5xx 按状态类过滤，其余按精确状态码过滤
+		if len(code) == 3 && strings.HasSuffix(code, "xx") && code[0] >= '1' && code[0] <= '5' {
+			base := int(code[0]-'0') * 100
+			query = query.Where("response_status >= ? AND response_status < ?", base, base+100)
+		} else {
+			status, err := strconv.Atoi(code)
+			if err != nil || status < 0 || status > 599 {
+				return nil, fmt.Errorf("%w: http_status %q", ErrInvalidWebhookLogFilter, filter.HTTPStatus)
+			}
+			query = query.Where("response_status = ?", status)
+		}
+	}
+	if filter.StartDate != nil {
+		query = query.Where("created_at >= ?", *filter.StartDate)
+	}
+	if filter.EndDate != nil {
+		query = query.Where("created_at < ?", *filter.EndDate)
+	}
+	if filter.MinResponseTime != nil {
+		query = query.Where("response_time >= ?", *filter.MinResponseTime)
+	}
+	if q := strings.TrimSpace(filter.Query); q != "" {
+		pattern := "%" + strings.ToLower(q) + "%"
+		query = query.Where("LOWER(request_body) LIKE ? OR LOWER(response_body) LIKE ? OR LOWER(event_data) LIKE ? OR LOWER(error_message) LIKE ?",
+			pattern, pattern, pattern, pattern)
+	}
+	return query, nil
+}
+
+// EndpointStats 统计区间内按请求地址汇总的投递量、失败率和响应时间，失败率高的排在前面
+func (s *WebhookLogService) EndpointStats(ctx context.Context, configID uint, since time.Time) ([]*models.WebhookEndpointStats, error) {
+	var rows []struct {
+		RequestURL      string
+		Sent            int64
+		Success         int64
+		Failed          int64
+		AvgResponseTime float64
+		MaxResponseTime int64
+	}
+	if err := s.db.WithContext(ctx).Model(&models.WebhookLog{}).
+		Select("request_url, COUNT(*) AS sent, "+
+			"SUM(CASE WHEN status = 'success' THEN 1 ELSE 0 END) AS success, "+
+			"SUM(CASE WHEN status IN ('failed', 'retrying') THEN 1 ELSE 0 END) AS failed, "+
+			"AVG(response_time) AS avg_response_time, MAX(response_time) AS max_response_time").
+		Where("config_id = ? AND created_at >= ?", configID, since).
+		Group("request_url").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to aggregate webhook logs: %w", err)
+	}
+
+	stats := make([]*models.WebhookEndpointStats, 0, len(rows))
+	for _, row := range rows {
+		item := &models.WebhookEndpointStats{
+			RequestURL:      row.RequestURL,
+			Sent:            row.Sent,
+			Success:         row.Success,
+			Failed:          row.Failed,
+			AvgResponseTime: math.Round(row.AvgResponseTime*10) / 10,
+			MaxResponseTime: row.MaxResponseTime,
+		}
+		if row.Sent > 0 {
+			item.FailureRate = math.Round(float64(row.Failed)/float64(row.Sent)*10000) / 100
+		}
+		if row.Failed > 0 {
+			var last models.WebhookLog
+			if err := s.db.WithContext(ctx).Select("created_at").
+				Where("config_id = ? AND request_url = ? AND status IN ? AND created_at >= ?", configID, row.RequestURL, webhookFailedStatuses, since).
+				Order("created_at DESC").First(&last).Error; err == nil {
+				item.LastFailureAt = &last.CreatedAt
+			}
+		}
+		stats = append(stats, item)
+	}
+
+	// 失败率降序，相同时按投递量降序
+	sort.SliceStable(stats, func(i, j int) bool {
+		if stats[i].FailureRate != stats[j].FailureRate {
+			return stats[i].FailureRate > stats[j].FailureRate
+		}
+		return stats[i].Sent > stats[j].Sent
+	})
+	return stats, nil
+}
+
+// Retention 获取Webhook生效的日志保留天数：单独设置优先，否则使用系统默认
+func (s *WebhookLogService) Retention(config *models.WebhookConfig) models.WebhookLogRetention {
+	retention := models.WebhookLogRetention{SuccessDays: 30, FailedDays: 90}
+	if days, err := s.configService.GetConfigInt(KeyWebhookLogRetentionDays); err == nil && days >= 0 {
+		retention.SuccessDays = days
+	}
+	if days, err := s.configService.GetConfigInt(KeyWebhookFailedLogRetentionDays); err == nil && days >= 0 {
+		retention.FailedDays = days
+	}
+	if config.LogRetentionDays > 0 {
+		retention.SuccessDays = config.LogRetentionDays
+	}
+	if config.FailedLogRetentionDays > 0 {
+		retention.FailedDays = config.FailedLogRetentionDays
+	}
+	return retention
+}
+
+// PurgeExpired 按各Webhook的保留策略删除过期日志，已删除Webhook的日志按系统默认清理，返回删除条数
+func (s *WebhookLogService) PurgeExpired(ctx context.Context, now time.Time) (int64, error) {
+	var configs []models.WebhookConfig
+	if err := s.db.WithContext(ctx).Select("id", "log_retention_days", "failed_log_retention_days").
+		Find(&configs).Error; err != nil {
+		return 0, fmt.Errorf("failed to get webhook configs: %w", err)
+	}
+
+	var deleted int64
+	configIDs := make([]uint, 0, len(configs))
+	for i := range configs {
+		configIDs = append(configIDs, configs[i].ID)
+		retention := s.Retention(&configs[i])
+		n, err := s.purge(s.db.WithContext(ctx).Where("config_id = ?", configs[i].ID), retention, now)
+		deleted += n
+		if err != nil {
+			return deleted, err
+		}
+	}
+
+	orphans := s.db.WithContext(ctx)
+	if len(configIDs) > 0 {
+		orphans = orphans.Where("config_id NOT IN ?", configIDs)
+	}
+	n, err := s.purge(orphans, s.Retention(&models.WebhookConfig{}), now)
+	return deleted + n, err
+}
+
+// purge 删除 scope 范围内超过保留天数的日志，成功与失败日志分别计算
+func (s *WebhookLogService) purge(scope *gorm.DB, retention models.WebhookLogRetention, now time.Time) (int64, error) {
+	var deleted int64
+	if retention.SuccessDays > 0 {
+		result := scope.Session(&gorm.Session{}).
+			Where("status NOT IN ? AND created_at < ?", webhookFailedStatuses, now.AddDate(0, 0, -retention.SuccessDays)).
+			Delete(&models.WebhookLog{})
+		if result.Error != nil {
+			return deleted, fmt.Errorf("failed to purge webhook logs: %w", result.Error)
+		}
+		deleted += result.RowsAffected
+	}
+	if retention.FailedDays > 0 {
+		result := scope.Session(&gorm.Session{}).
+			Where("status IN ? AND created_at < ?", webhookFailedStatuses, now.AddDate(0, 0, -retention.FailedDays)).
+			Delete(&models.WebhookLog{})
+		if result.Error != nil {
+			return deleted, fmt.Errorf("failed to purge webhook logs: %w", result.Error)
+		}
+		deleted += result.RowsAffected
+	}
+	return deleted, nil
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gongdan-system/internal/models"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestWebhookLog_FilterStatsAndRetention(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:webhook_logs?mode=memory&cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to open sqlite memory db: %v", err)
+	}
+	if err := db.AutoMigrate(&models.User{}, &models.SystemConfig{}, &models.WebhookConfig{}, &models.WebhookLog{}); err != nil {
+		t.Fatalf("failed to migrate schemas: %v", err)
+	}
+
+	ctx := context.Background()
+	svc := NewWebhookLogService(db)
+	now := time.Now()
+
+	shortLived := models.WebhookConfig{Name: "ops", Provider: models.WebhookProviderCustom, WebhookURL: "https://ops.example.com/hook", CreatedBy: 1, LogRetentionDays: 7}
+	defaults := models.WebhookConfig{Name: "chat", Provider: models.WebhookProviderSlack, WebhookURL: "https://chat.example.com/hook", CreatedBy: 1}
+	for _, config := range []*models.WebhookConfig{&shortLived, &defaults} {
+		if err := db.Create(config).Error; err != nil {
+			t.Fatalf("failed to seed webhook: %v", err)
+		}
+	}
+
+	seed := func(config *models.WebhookConfig, url, status string, code int, ms int64, age time.Duration, body string) {
+		entry := &models.WebhookLog{ConfigID: config.ID, EventType: models.WebhookEventTicketCreated, RequestURL: url, Status: status,
+			ResponseStatus: code, ResponseTime: ms, RequestBody: body}
+		if err := db.Create(entry).Error; err != nil {
+			t.Fatalf("failed to seed log: %v", err)
+		}
+		db.Model(entry).UpdateColumn("created_at", now.Add(-age))
+	}
+	day := 24 * time.Hour
+	seed(&shortLived, "https://ops.example.com/hook", "success", 200, 120, time.Hour, `{"ticket":"T-100"}`)
+	seed(&shortLived, "https://ops.example.com/hook", "failed", 502, 2400, 2*time.Hour, `{"ticket":"T-101"}`)
+	seed(&shortLived, "https://ops.example.com/hook", "retrying", 503, 1800, 3*time.Hour, `{"ticket":"T-102"}`)
+	seed(&shortLived, "https://old.example.com/hook", "success", 200, 80, 2*day, `{"ticket":"T-103"}`)
+	seed(&shortLived, "https://ops.example.com/hook", "success", 200, 90, 10*day, `{"ticket":"T-050"}`)
+	seed(&shortLived, "https://ops.example.com/hook", "failed", 500, 300, 40*day, `{"ticket":"T-040"}`)
+	seed(&defaults, "https://chat.example.com/hook", "success", 200, 100, 10*day, `{"ticket":"T-060"}`)
+	seed(&defaults, "https://chat.example.com/hook", "success", 200, 100, 40*day, `{"ticket":"T-030"}`)
+	seed(&models.WebhookConfig{ID: 999}, "https://gone.example.com/hook", "success", 200, 100, 40*day, `{}`)
+
+	list := func(filter models.WebhookLogFilter) int64 {
+		t.Helper()
+		_, total, err := svc.ListLogs(ctx, shortLived.ID, &filter)
+		if err != nil {
+			t.Fatalf("list logs failed: %v", err)
+		}
+		return total
+	}
+	minMs := int64(1000)
+	since := now.Add(-day)
+	if total := list(models.WebhookLogFilter{HTTPStatus: "5xx"}); total != 3 {
+		t.Fatalf("expected 3 5xx logs, got %d", total)
+	}
+	if total := list(models.WebhookLogFilter{HTTPStatus: "502"}); total != 1 {
+		t.Fatalf("expected 1 502 log, got %d", total)
+	}
+	if total := list(models.WebhookLogFilter{MinResponseTime: &minMs, StartDate: &since}); total != 2 {
+		t.Fatalf("expected 2 slow recent logs, got %d", total)
+	}
+	if total := list(models.WebhookLogFilter{Query: "t-101"}); total != 1 {
+		t.Fatalf("expected payload search to match one log, got %d", total)
+	}
+	if total := list(models.WebhookLogFilter{Status: "success", EventType: models.WebhookEventTicketCreated}); total != 3 {
+		t.Fatalf("expected 3 successful logs, got %d", total)
+	}
+	if _, _, err := svc.ListLogs(ctx, shortLived.ID, &models.WebhookLogFilter{HTTPStatus: "abc"}); !errors.Is(err, ErrInvalidWebhookLogFilter) {
+		t.Fatalf("expected ErrInvalidWebhookLogFilter, got %v", err)
+	}
+
+	endpoints, err := svc.EndpointStats(ctx, shortLived.ID, now.Add(-7*day))
+	if err != nil || len(endpoints) != 2 {
+		t.Fatalf("expected 2 endpoints, got %+v (%v)", endpoints, err)
+	}
+	ops := endpoints[0]
+	if ops.RequestURL != "https://ops.example.com/hook" || ops.Sent != 3 || ops.Failed != 2 || ops.FailureRate != 66.67 ||
+		ops.MaxResponseTime != 2400 || ops.LastFailureAt == nil {
+		t.Fatalf("unexpected endpoint stats: %+v", ops)
+	}
+
+	// 成功日志：ops 保留7天，chat 使用默认30天；失败日志均为默认90天；已删除 Webhook 的日志按默认清理
+	deleted, err := svc.PurgeExpired(ctx, now)
+	if err != nil || deleted != 3 {
+		t.Fatalf("expected 3 purged logs, got %d (%v)", deleted, err)
+	}
+	var remaining int64
+	db.Model(&models.WebhookLog{}).Where("status = ? AND created_at < ?", "failed", now.Add(-30*day)).Count(&remaining)
+	if remaining != 1 {
+		t.Fatalf("expected old failed log to be kept, got %d", remaining)
+	}
+	if retention := svc.Retention(&shortLived); retention.SuccessDays != 7 || retention.FailedDays != 90 {
+		t.Fatalf("unexpected retention: %+v", retention)
+	}
+}
@@ -0,0 +1,235 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"gongdan-system/internal/models"
+)
+
+const (
+	// maxWebhookTemplateSize 消息模板最大长度
+	maxWebhookTemplateSize = 20000
+	// maxWebhookRenderedSize 渲染结果最大长度，防止 range 等导致输出膨胀
+	maxWebhookRenderedSize = 64 * 1024
+)
+
+// ErrWebhookTemplateOutputTooLarge 模板渲染结果超出长度限制
+var ErrWebhookTemplateOutputTooLarge = errors.New("rendered webhook template exceeds size limit")
+
+// legacyWebhookPlaceholders 旧版 {{name}} 占位符到 text/template 表达式的映射，保证已保存的模板继续可用
+var legacyWebhookPlaceholders = strings.NewReplacer(
+	"{{title}}", "{{.Title}}",
+	"{{description}}", "{{.Description}}",
+	"{{type}}", "{{.Type}}",
+	"{{resource_id}}", "{{.ResourceID}}",
+	"{{timestamp}}", `{{date .Timestamp "2006-01-02 15:04:05"}}`,
+)
+
+// webhookTemplateFuncs 模板可用函数，仅包含无副作用的格式化与取值函数
+var webhookTemplateFuncs = template.FuncMap{
+	"date":     templateFormatDate,
+	"truncate": templateTruncate,
+	"json":     templateJSON,
+	"get":      templateGet,
+	"default":  templateDefault,
+	"upper":    strings.ToUpper,
+	"lower":    strings.ToLower,
+	"trim":     strings.TrimSpace,
+	"replace":  strings.ReplaceAll,
+	"join":     templateJoin,
+}
+
+// ParseWebhookTemplate 解析Webhook消息模板（Go text/template 语法，兼容旧版占位符）
+func ParseWebhookTemplate(text string) (*template.Template, error) {
+	if len(text) > maxWebhookTemplateSize {
+		return nil, fmt.Errorf("template exceeds %d characters", maxWebhookTemplateSize)
+	}
+	return template.New("webhook").Funcs(webhookTemplateFuncs).Parse(legacyWebhookPlaceholders.Replace(text))
+}
+
+// RenderWebhookTemplate 使用事件渲染消息模板，模板数据即 NotificationEvent（.Title、.Data 等）
+func RenderWebhookTemplate(text string, event *NotificationEvent) (string, error) {
+	tmpl, err := ParseWebhookTemplate(text)
+	if err != nil {
+		return "", err
+	}
+
+	out := &limitedBuffer{limit: maxWebhookRenderedSize}
+	if err := tmpl.Execute(out, event); err != nil {
+		if errors.Is(err, ErrWebhookTemplateOutputTooLarge) {
+			return "", ErrWebhookTemplateOutputTooLarge
+		}
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// ValidateWebhookTemplate 校验模板语法，并用示例事件试渲染以发现函数参数等运行期错误
+func ValidateWebhookTemplate(text string) error {
+	if text == "" {
+		return nil
+	}
+	if _, err := RenderWebhookTemplate(text, SampleNotificationEvent(models.WebhookEventTicketUpdated)); err != nil {
+		return fmt.Errorf("invalid message template: %w", err)
+	}
+	return nil
+}
+
+// SampleNotificationEvent 生成用于模板试渲染的示例事件
+func SampleNotificationEvent(eventType models.WebhookEventType) *NotificationEvent {
+	if eventType == "" {
+		eventType = models.WebhookEventTicketCreated
+	}
+	userID := uint(1)
+	return &NotificationEvent{
+		Type:         eventType,
+		ResourceID:   1001,
+		ResourceType: "ticket",
+		Title:        "示例工单: 打印机无法连接",
+		Description:  "三楼办公区打印机无法连接，影响部门日常打印。",
+		Data: map[string]interface{}{
+			"ticket_number": "TK-20240115-0001",
+			"title":         "打印机无法连接",
+			"status":        string(models.TicketStatusOpen),
+			"priority":      string(models.TicketPriorityHigh),
+			"customer": map[string]interface{}{
+				"name":  "张三",
+				"email": "zhangsan@example.com",
+			},
+			"tags": []interface{}{"printer", "office"},
+			"external_references": []map[string]interface{}{
+				{"system": "crm", "key": "ACC-1001", "url": "https://crm.example.com/accounts/1001"},
+			},
+		},
+		Metadata: map[string]string{
+			"action": "sample",
+		},
+		Timestamp: time.Now(),
+		UserID:    &userID,
+		Changes: []models.TicketFieldChange{
+			{Field: "priority", Before: string(models.TicketPriorityNormal), After: string(models.TicketPriorityHigh)},
+		},
+	}
+}
+
+// limitedBuffer 超出上限即报错的输出缓冲
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > b.limit {
+		return 0, ErrWebhookTemplateOutputTooLarge
+	}
+	return b.Buffer.Write(p)
+}
+
+// templateFormatDate 格式化时间，layout 省略时使用 2006-01-02 15:04:05
+func templateFormatDate(value interface{}, layout ...string) (string, error) {
+	format := "2006-01-02 15:04:05"
+	if len(layout) > 0 && layout[0] != "" {
+		format = layout[0]
+	}
+
+	switch v := value.(type) {
+	case time.Time:
+		return v.Format(format), nil
+	case *time.Time:
+		if v == nil {
+			return "", nil
+		}
+		return v.Format(format), nil
+	case string:
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return v, nil
+		}
+		return t.Format(format), nil
+	case nil:
+		return "", nil
+	default:
+		return "", fmt.Errorf("date: unsupported value type %T", value)
+	}
+}
+
+// templateTruncate 按字符截断，超出部分以省略号结尾
+func templateTruncate(length int, value interface{}) string {
+	s := fmt.Sprint(value)
+	if value == nil {
+		s = ""
+	}
+	if length <= 0 || utf8.RuneCountInString(s) <= length {
+		return s
+	}
+	runes := []rune(s)
+	return string(runes[:length]) + "..."
+}
+
+// templateJSON 序列化为JSON，用于在JSON负载中安全地嵌入字符串或对象
+func templateJSON(value interface{}) (string, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// templateGet 按点分路径读取嵌套数据，如 get .Data "customer.email"；路径不存在时返回 nil
+func templateGet(value interface{}, path string) interface{} {
+	current := value
+	for _, key := range strings.Split(path, ".") {
+		switch v := current.(type) {
+		case map[string]interface{}:
+			current = v[key]
+		case map[string]string:
+			current = v[key]
+		case []interface{}:
+			index, err := strconv.Atoi(key)
+			if err != nil || index < 0 || index >= len(v) {
+				return nil
+			}
+			current = v[index]
+		default:
+			return nil
+		}
+	}
+	return current
+}
+
+// templateDefault 值为空时返回默认值，如 default "未分配" (get .Data "assignee")
+func templateDefault(def interface{}, value interface{}) interface{} {
+	if value == nil {
+		return def
+	}
+	if s, ok := value.(string); ok && s == "" {
+		return def
+	}
+	return value
+}
+
+// templateJoin 用分隔符连接列表
+func templateJoin(sep string, value interface{}) string {
+	switch v := value.(type) {
+	case []string:
+		return strings.Join(v, sep)
+	case []interface{}:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			parts = append(parts, fmt.Sprint(item))
+		}
+		return strings.Join(parts, sep)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"gongdan-system/internal/models"
+)
+
+func TestRenderWebhookTemplate(t *testing.T) {
+	event := SampleNotificationEvent(models.WebhookEventTicketUpdated)
+
+	cases := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{"legacy placeholders", "{{title}} #{{resource_id}}", "示例工单: 打印机无法连接 #1001"},
+		{"nested data", `{{get .Data "customer.email"}}`, "zhangsan@example.com"},
+		{"default for missing key", `{{default "未分配" (get .Data "assignee")}}`, "未分配"},
+		{"truncate runes", `{{truncate 3 .Description}}`, "三楼办..."},
+		{"json escaping", `{"text": {{json .Title}}}`, `{"text": "示例工单: 打印机无法连接"}`},
+		{"date layout", `{{date .Timestamp "2006"}}`, event.Timestamp.Format("2006")},
+		{"join list", `{{join "," (get .Data "tags")}}`, "printer,office"},
+		{"range changes", `{{range .Changes}}{{.Field}}:{{.Before}}->{{.After}}{{end}}`, "priority:normal->high"},
+	}
+	for _, tc := range cases {
+		got, err := RenderWebhookTemplate(tc.template, event)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", tc.name, err)
+		}
+		if got != tc.want {
+			t.Fatalf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+
+	if err := ValidateWebhookTemplate("{{if .Title}}"); err == nil {
+		t.Fatalf("expected unclosed action to fail validation")
+	}
+	if err := ValidateWebhookTemplate(`{{truncate "x" .Title}}`); err == nil {
+		t.Fatalf("expected wrong argument type to fail validation")
+	}
+
+	// 5×5 次嵌套循环，每次输出 3000 字符，超过渲染上限
+	huge := `{{range .Data}}{{range $.Data}}` + strings.Repeat("x", 3000) + `{{end}}{{end}}`
+	if _, err := RenderWebhookTemplate(huge, event); !errors.Is(err, ErrWebhookTemplateOutputTooLarge) {
+		t.Fatalf("expected oversized output to be rejected, got %v", err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -20,10 +21,13 @@ const (
 	// Send pings to peer with this period. Must be less than pongWait.
 	pingPeriod = (pongWait * 9) / 10
 
-	// Maximum message size allowed from peer.
-	maxMessageSize = 512
+	// Maximum message size allowed from peer (queue_subscribe may carry the ids of the tickets shown).
+	maxMessageSize = 16 * 1024
 )
 
+// clientSeq assigns connection ids
+var clientSeq uint64
+
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
@@ -39,12 +43,27 @@ type Client struct {
 	// The websocket connection.
 	conn *websocket.Conn
 
-	// Buffered channel of outbound messages.
-	send chan []byte
+	// Bounded queue of outbound messages, oldest dropped when full.
+	queue *sendQueue
+
+	// When the connection was accepted
+	connectedAt time.Time
+
+	// Connection id, unique within the process
+	id uint64
 
 	// User ID associated with this connection
 	UserID uint
 
+	// Nav badge counts subscription
+	counts countsSubscription
+
+	// Ticket of a portal connection, 0 for regular connections
+	portalTicketID uint
+
+	// Portal visibility and customer presence subscriptions
+	presence presenceState
+
 	// Hub reference
 	hub *Hub
 }
@@ -52,10 +71,12 @@ type Client struct {
 // NewClient creates a new WebSocket client
 func NewClient(hub *Hub, conn *websocket.Conn, userID uint) *Client {
 	return &Client{
-		hub:    hub,
-		conn:   conn,
-		send:   make(chan []byte, 256),
-		UserID: userID,
+		id:          atomic.AddUint64(&clientSeq, 1),
+		hub:         hub,
+		conn:        conn,
+		queue:       newSendQueue(hub.limits.QueueSize),
+		connectedAt: time.Now(),
+		UserID:      userID,
 	}
 }
 
@@ -105,33 +126,37 @@ func (c *Client) writePump() {
 	
 	for {
 		select {
-		case message, ok := <-c.send:
-			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
-			if !ok {
-				// The hub closed the channel.
+		case <-c.queue.ready:
+			messages, closed := c.queue.drain()
+			c.conn.SetWriteDeadline(time.Now().Add(c.hub.limits.WriteTimeout))
+			if closed {
+				// The hub closed the queue.
 				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
 				return
 			}
+			if len(messages) == 0 {
+				continue
+			}
 
+			// Send everything queued as one frame.
 			w, err := c.conn.NextWriter(websocket.TextMessage)
 			if err != nil {
 				return
 			}
-			w.Write(message)
-
-			// Add queued messages to the current message.
-			n := len(c.send)
-			for i := 0; i < n; i++ {
-				w.Write([]byte{'\n'})
-				w.Write(<-c.send)
+			for i, message := range messages {
+				if i > 0 {
+					w.Write([]byte{'\n'})
+				}
+				w.Write(message)
 			}
 
 			if err := w.Close(); err != nil {
 				return
 			}
+			c.queue.markSent(len(messages), time.Now())
 			
 		case <-ticker.C:
-			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			c.conn.SetWriteDeadline(time.Now().Add(c.hub.limits.WriteTimeout))
 			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
 				return
 			}
@@ -153,6 +178,11 @@ func (c *Client) handleMessage(message []byte) {
 		return
 	}
 
+	if c.portalTicketID != 0 {
+		c.handlePortalMessage(msgType, message)
+		return
+	}
+
 	switch msgType {
 	case "ping":
 		// Respond to client ping
@@ -160,6 +190,20 @@ func (c *Client) handleMessage(message []byte) {
 	case "mark_read":
 		// Handle mark notification as read
 		c.handleMarkRead(msg)
+	case "queue_subscribe":
+		c.handleQueueSubscribe(message)
+	case "queue_unsubscribe":
+		c.handleQueueUnsubscribe(msg)
+	case "counts_subscribe":
+		c.handleCountsSubscribe()
+	case "counts_unsubscribe":
+		c.handleCountsUnsubscribe()
+	case "ticket_typing":
+		c.handleTicketTyping(message)
+	case "presence_subscribe":
+		c.handlePresenceSubscribe(message)
+	case "presence_unsubscribe":
+		c.handlePresenceUnsubscribe(message)
 	default:
 		log.Printf("Unknown message type: %s from client %d", msgType, c.UserID)
 	}
@@ -173,11 +217,9 @@ func (c *Client) sendPong() {
 	}
 	
 	responseBytes, _ := json.Marshal(response)
-	select {
-	case c.send <- responseBytes:
-	default:
-		close(c.send)
-	}
+	c.hub.mu.RLock()
+	c.hub.enqueue(c, responseBytes)
+	c.hub.mu.RUnlock()
 }
 
 // handleMarkRead handles marking notifications as read via WebSocket
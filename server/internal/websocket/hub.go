@@ -3,8 +3,12 @@ package websocket
 import (
 	"encoding/json"
 	"log"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"gongdan-system/internal/services"
 )
 
 // Hub maintains the set of active clients and broadcasts messages to the clients.
@@ -23,6 +27,23 @@ type Hub struct {
 
 	// Mutex for thread-safe operations
 	mu sync.RWMutex
+
+	// Live ticket list subscriptions, nil when disabled
+	liveQueue *services.LiveQueueService
+
+	// Per-connection send queue limits
+	limits Limits
+
+	// Nav badge counts pushed to subscribed clients, nil when disabled
+	ticketCounts  *services.TicketCountsService
+	countsPushing int32
+
+	// Portal typing/presence, nil when disabled
+	presence *services.PortalPresenceService
+
+	// Counters kept across connections, updated atomically
+	slowDisconnects uint64
+	departedDropped uint64
 }
 
 // NewHub creates a new WebSocket hub
@@ -32,11 +53,50 @@ func NewHub() *Hub {
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
 		clients:    make(map[*Client]bool),
+		limits:     DefaultLimits(),
+	}
+}
+
+// SetLimits configures the per-connection send queues; call before Run.
+// Connections opened earlier keep the queue size they were created with.
+func (h *Hub) SetLimits(limits Limits) {
+	defaults := DefaultLimits()
+	if limits.QueueSize <= 0 {
+		limits.QueueSize = defaults.QueueSize
+	}
+	if limits.WriteTimeout <= 0 {
+		limits.WriteTimeout = defaults.WriteTimeout
+	}
+	h.mu.Lock()
+	h.limits = limits
+	h.mu.Unlock()
+}
+
+// enqueue queues a message for a client without blocking. When the client has
+// fallen too far behind its queue is closed, which makes writePump send a close
+// frame; the connection is then unregistered by readPump as usual.
+// Callers must hold h.mu.
+func (h *Hub) enqueue(client *Client, message []byte) {
+	now := time.Now()
+	if !client.queue.push(message, now) {
+		return
+	}
+	if reason := client.queue.slowReason(h.limits, now); reason != "" {
+		atomic.AddUint64(&h.slowDisconnects, 1)
+		log.Printf("Disconnecting slow WebSocket client %d (user %d): %s", client.id, client.UserID, reason)
+		client.queue.close()
 	}
 }
 
 // Run starts the hub
 func (h *Hub) Run() {
+	var countsTick <-chan time.Time
+	if h.ticketCounts != nil {
+		ticker := time.NewTicker(ticketCountsPushInterval)
+		defer ticker.Stop()
+		countsTick = ticker.C
+	}
+
 	for {
 		select {
 		case client := <-h.register:
@@ -44,27 +104,38 @@ func (h *Hub) Run() {
 			h.clients[client] = true
 			h.mu.Unlock()
 			log.Printf("WebSocket client connected, user: %d, total: %d", client.UserID, len(h.clients))
+			if client.portalTicketID != 0 {
+				go h.pushCustomerPresence(client.portalTicketID, client.UserID)
+			}
 
 		case client := <-h.unregister:
 			h.mu.Lock()
 			if _, ok := h.clients[client]; ok {
 				delete(h.clients, client)
-				close(client.send)
+				client.queue.close()
+				atomic.AddUint64(&h.departedDropped, client.queue.stats().Dropped)
+				if h.liveQueue != nil {
+					h.liveQueue.UnsubscribeAll(client.key())
+				}
+				if h.presence != nil {
+					h.presence.Forget(client.key())
+				}
 				log.Printf("WebSocket client disconnected, user: %d, total: %d", client.UserID, len(h.clients))
 			}
 			h.mu.Unlock()
+			if client.portalTicketID != 0 {
+				go h.pushCustomerPresence(client.portalTicketID, client.UserID)
+			}
 
 		case message := <-h.broadcast:
 			h.mu.RLock()
 			for client := range h.clients {
-				select {
-				case client.send <- message:
-				default:
-					close(client.send)
-					delete(h.clients, client)
-				}
+				h.enqueue(client, message)
 			}
 			h.mu.RUnlock()
+
+		case <-countsTick:
+			go h.pushTicketCounts()
 		}
 	}
 }
@@ -88,12 +159,7 @@ func (h *Hub) BroadcastToUser(userID uint, messageType string, data interface{})
 	
 	for client := range h.clients {
 		if client.UserID == userID {
-			select {
-			case client.send <- messageBytes:
-			default:
-				close(client.send)
-				delete(h.clients, client)
-			}
+			h.enqueue(client, messageBytes)
 		}
 	}
 }
@@ -156,4 +222,61 @@ func (h *Hub) GetClientCount() int {
 
 func getTimestamp() int64 {
 	return time.Now().Unix()
-}
\ No newline at end of file
+}
+
+// ClientStats describes one live connection
+type ClientStats struct {
+	ID             uint64    `json:"id"`
+	UserID         uint      `json:"user_id"`
+	PortalTicketID uint      `json:"portal_ticket_id,omitempty"` // portal connections only
+	ConnectedAt    time.Time `json:"connected_at"`
+	QueueStats
+}
+
+// HubStats is a snapshot of the hub and its connections' send queues
+type HubStats struct {
+	Clients           int           `json:"clients"`
+	QueueSize         int           `json:"queue_size"`
+	WriteTimeoutSec   int           `json:"write_timeout_seconds"`
+	SlowClientTimeout int           `json:"slow_client_timeout_seconds"`
+	MaxDrops          int           `json:"max_drops"`
+	TotalDropped      uint64        `json:"total_dropped"` // includes connections already closed
+	SlowDisconnects   uint64        `json:"slow_disconnects"`
+	Connections       []ClientStats `json:"connections"`
+}
+
+// Stats returns per-connection queue depth and drop counters, deepest queues first
+func (h *Hub) Stats() *HubStats {
+	h.mu.RLock()
+	stats := &HubStats{
+		Clients:           len(h.clients),
+		QueueSize:         h.limits.QueueSize,
+		WriteTimeoutSec:   int(h.limits.WriteTimeout / time.Second),
+		SlowClientTimeout: int(h.limits.SlowClientTimeout / time.Second),
+		MaxDrops:          h.limits.MaxDrops,
+		TotalDropped:      atomic.LoadUint64(&h.departedDropped),
+		SlowDisconnects:   atomic.LoadUint64(&h.slowDisconnects),
+		Connections:       make([]ClientStats, 0, len(h.clients)),
+	}
+	for client := range h.clients {
+		queueStats := client.queue.stats()
+		stats.TotalDropped += queueStats.Dropped
+		stats.Connections = append(stats.Connections, ClientStats{
+			ID:             client.id,
+			UserID:         client.UserID,
+			PortalTicketID: client.portalTicketID,
+			ConnectedAt:    client.connectedAt,
+			QueueStats:     queueStats,
+		})
+	}
+	h.mu.RUnlock()
+
+	sort.Slice(stats.Connections, func(i, j int) bool {
+		a, b := stats.Connections[i], stats.Connections[j]
+		if a.Depth != b.Depth {
+			return a.Depth > b.Depth
+		}
+		return a.ID < b.ID
+	})
+	return stats
+}
@@ -52,6 +52,15 @@ func NotificationAllMarkedAsReadHook(ctx context.Context, userID uint) {
 	GlobalNotificationService.PushUnreadCount(ctx, userID, 0)
 }
 
+// NotificationsClearedHook is called when a user bulk-clears notifications
+func NotificationsClearedHook(ctx context.Context, userID uint, unreadCount int64) {
+	if GlobalNotificationService == nil {
+		return
+	}
+
+	GlobalNotificationService.PushUnreadCount(ctx, userID, unreadCount)
+}
+
 // TicketUpdatedHook is called when a ticket is updated
 func TicketUpdatedHook(ctx context.Context, ticket *models.Ticket, updateType string) {
 	if GlobalNotificationService == nil {
@@ -62,4 +71,15 @@ func TicketUpdatedHook(ctx context.Context, ticket *models.Ticket, updateType st
 	if err != nil {
 		log.Printf("Failed to push ticket update via WebSocket: %v", err)
 	}
-}
\ No newline at end of file
+}
+// TicketReplyLockHook is called when a ticket reply lock is acquired or released
+func TicketReplyLockHook(ctx context.Context, event *models.TicketReplyLockEvent) {
+	if GlobalNotificationService == nil {
+		return
+	}
+
+	err := GlobalNotificationService.PushTicketReplyLock(ctx, event)
+	if err != nil {
+		log.Printf("Failed to push ticket reply lock via WebSocket: %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+// queueSubscribeMessage subscribes a ticket list to live updates:
+// {"type":"queue_subscribe","subscription_id":"inbox","filter":{...},"ticket_ids":[...]}
+type queueSubscribeMessage struct {
+	SubscriptionID string                 `json:"subscription_id"`
+	Filter         models.LiveQueueFilter `json:"filter"`
+	TicketIDs      []uint                 `json:"ticket_ids"` // tickets currently shown, so the server can send "removed" for them
+}
+
+// SetLiveQueue enables queue_subscribe / queue_unsubscribe messages
+func (h *Hub) SetLiveQueue(liveQueue *services.LiveQueueService) {
+	h.liveQueue = liveQueue
+}
+
+// sendToClient queues a message for a single connection, dropping it if the client is gone
+func (h *Hub) sendToClient(client *Client, messageType string, data interface{}) {
+	messageBytes, err := json.Marshal(map[string]interface{}{
+		"type":      messageType,
+		"data":      data,
+		"timestamp": getTimestamp(),
+	})
+	if err != nil {
+		log.Printf("Error marshaling message: %v", err)
+		return
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if !h.clients[client] {
+		return
+	}
+	h.enqueue(client, messageBytes)
+}
+
+// key identifies the connection for live queue subscriptions
+func (c *Client) key() string {
+	return strconv.FormatUint(c.id, 10)
+}
+
+// handleQueueSubscribe registers a live queue subscription for this connection
+func (c *Client) handleQueueSubscribe(message []byte) {
+	var msg queueSubscribeMessage
+	if err := json.Unmarshal(message, &msg); err != nil || msg.SubscriptionID == "" || len(msg.SubscriptionID) > 64 {
+		c.hub.sendToClient(c, "queue_error", map[string]interface{}{
+			"subscription_id": msg.SubscriptionID,
+			"error":           "invalid subscription",
+		})
+		return
+	}
+	if c.hub.liveQueue == nil {
+		c.hub.sendToClient(c, "queue_error", map[string]interface{}{
+			"subscription_id": msg.SubscriptionID,
+			"error":           "live queue is not available",
+		})
+		return
+	}
+
+	subscriptionID := msg.SubscriptionID
+	cursor, err := c.hub.liveQueue.Subscribe(context.Background(), c.key(), subscriptionID, c.UserID, &msg.Filter, msg.TicketIDs,
+		func(changes []*models.LiveQueueChange, cursor string) {
+			c.hub.sendToClient(c, "queue_changes", map[string]interface{}{
+				"subscription_id": subscriptionID,
+				"changes":         changes,
+				"cursor":          cursor,
+			})
+		})
+	if err != nil {
+		reason := "failed to subscribe"
+		switch {
+		case errors.Is(err, services.ErrLiveQueueForbidden):
+			reason = "live queue is only available to staff"
+		case errors.Is(err, services.ErrLiveQueueSubscriptionLimit):
+			reason = "too many subscriptions"
+		default:
+			log.Printf("Failed to subscribe user %d to live queue: %v", c.UserID, err)
+		}
+		c.hub.sendToClient(c, "queue_error", map[string]interface{}{
+			"subscription_id": subscriptionID,
+			"error":           reason,
+		})
+		return
+	}
+
+	c.hub.sendToClient(c, "queue_subscribed", map[string]interface{}{
+		"subscription_id": subscriptionID,
+		"cursor":          cursor,
+	})
+}
+
+// handleQueueUnsubscribe removes a live queue subscription
+func (c *Client) handleQueueUnsubscribe(msg map[string]interface{}) {
+	subscriptionID, _ := msg["subscription_id"].(string)
+	if c.hub.liveQueue != nil && subscriptionID != "" {
+		c.hub.liveQueue.Unsubscribe(c.key(), subscriptionID)
+	}
+}
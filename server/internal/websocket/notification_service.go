@@ -113,6 +113,21 @@ func (s *NotificationWebSocketService) PushTicketUpdate(ctx context.Context, tic
 	return nil
 }
 
+// PushTicketReplyLock sends a reply lock change ("X is replying") to online staff users
+func (s *NotificationWebSocketService) PushTicketReplyLock(ctx context.Context, event *models.TicketReplyLockEvent) error {
+	pushed := 0
+	for _, userID := range event.Recipients {
+		if userID == event.UserID || !s.hub.IsUserOnline(userID) {
+			continue
+		}
+		s.hub.BroadcastToUser(userID, "ticket_reply_lock", event)
+		pushed++
+	}
+
+	log.Printf("Pushed reply lock change for ticket %d to %d users", event.TicketID, pushed)
+	return nil
+}
+
 // GetOnlineUsers returns the list of currently online users
 func (s *NotificationWebSocketService) GetOnlineUsers() []uint {
 	return s.hub.GetConnectedUsers()
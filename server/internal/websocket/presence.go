@@ -0,0 +1,246 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gongdan-system/internal/models"
+	"gongdan-system/internal/services"
+)
+
+const (
+	// presenceCheckTimeout bounds the permission check for a single presence message
+	presenceCheckTimeout = 5 * time.Second
+	// maxPresenceSubscriptions is the number of tickets one connection may watch at once
+	maxPresenceSubscriptions = 20
+)
+
+// presenceMessage is sent by clients:
+// portal  {"type":"visibility","visible":false}
+// staff   {"type":"ticket_typing","ticket_id":1,"typing":true}
+//
+//	{"type":"presence_subscribe","ticket_id":1} / {"type":"presence_unsubscribe","ticket_id":1}
+type presenceMessage struct {
+	TicketID uint `json:"ticket_id"`
+	Typing   bool `json:"typing"`
+	Visible  bool `json:"visible"`
+}
+
+// presenceState tracks a connection's portal visibility and the tickets whose customer presence it watches
+type presenceState struct {
+	mu         sync.Mutex
+	viewing    bool          // portal connections: the ticket page is in the foreground
+	subscribed map[uint]uint // staff connections: ticket id -> customer id
+}
+
+// SetPortalPresence enables portal connections and typing/presence messages; call before Run.
+func (h *Hub) SetPortalPresence(presence *services.PortalPresenceService) {
+	h.presence = presence
+}
+
+// handlePortalMessage processes messages from a portal connection, which is limited to its own ticket
+func (c *Client) handlePortalMessage(msgType string, message []byte) {
+	switch msgType {
+	case "ping":
+		c.sendPong()
+	case "visibility":
+		var msg presenceMessage
+		if err := json.Unmarshal(message, &msg); err != nil {
+			return
+		}
+		c.presence.mu.Lock()
+		changed := c.presence.viewing != msg.Visible
+		c.presence.viewing = msg.Visible
+		c.presence.mu.Unlock()
+		// The state is always kept; only the push to staff is rate limited.
+		if changed && c.hub.presence.Allow(c.key(), c.portalTicketID, "visibility") {
+			c.hub.pushCustomerPresence(c.portalTicketID, c.UserID)
+		}
+	default:
+		log.Printf("Unknown portal message type: %s from client %d", msgType, c.UserID)
+	}
+}
+
+// handleTicketTyping forwards "agent is typing" to the customer's portal connections for the ticket
+func (c *Client) handleTicketTyping(message []byte) {
+	var msg presenceMessage
+	if err := json.Unmarshal(message, &msg); err != nil || msg.TicketID == 0 || c.hub.presence == nil {
+		return
+	}
+	event := "typing_stop"
+	if msg.Typing {
+		event = "typing"
+	}
+	if !c.hub.presence.Allow(c.key(), msg.TicketID, event) || !c.hub.presence.CustomerVisible() {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), presenceCheckTimeout)
+	defer cancel()
+	if _, err := c.hub.presence.AuthorizeAgent(ctx, msg.TicketID, c.UserID); err != nil {
+		c.sendPresenceError(msg.TicketID, err)
+		return
+	}
+
+	typing := &models.AgentTypingEvent{TicketID: msg.TicketID, Typing: msg.Typing}
+	if msg.Typing {
+		typing.ExpiresIn = int(services.PortalTypingExpiry / time.Second)
+	}
+	for _, client := range c.hub.portalClients(msg.TicketID) {
+		c.hub.sendToClient(client, "agent_typing", typing)
+	}
+}
+
+// handlePresenceSubscribe sends the customer's current presence and keeps pushing changes
+func (c *Client) handlePresenceSubscribe(message []byte) {
+	var msg presenceMessage
+	if err := json.Unmarshal(message, &msg); err != nil || msg.TicketID == 0 {
+		return
+	}
+	if c.hub.presence == nil {
+		c.hub.sendToClient(c, "presence_error", map[string]interface{}{"ticket_id": msg.TicketID, "error": "presence is not available"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), presenceCheckTimeout)
+	defer cancel()
+	customerID, err := c.hub.presence.AuthorizeAgent(ctx, msg.TicketID, c.UserID)
+	if err != nil {
+		c.sendPresenceError(msg.TicketID, err)
+		return
+	}
+
+	c.presence.mu.Lock()
+	if c.presence.subscribed == nil {
+		c.presence.subscribed = make(map[uint]uint)
+	}
+	_, exists := c.presence.subscribed[msg.TicketID]
+	if !exists && len(c.presence.subscribed) >= maxPresenceSubscriptions {
+		c.presence.mu.Unlock()
+		c.hub.sendToClient(c, "presence_error", map[string]interface{}{"ticket_id": msg.TicketID, "error": "too many subscriptions"})
+		return
+	}
+	c.presence.subscribed[msg.TicketID] = customerID
+	c.presence.mu.Unlock()
+
+	c.hub.sendToClient(c, "customer_presence", c.hub.customerPresence(msg.TicketID, customerID))
+}
+
+// handlePresenceUnsubscribe stops presence pushes for a ticket
+func (c *Client) handlePresenceUnsubscribe(message []byte) {
+	var msg presenceMessage
+	if err := json.Unmarshal(message, &msg); err != nil {
+		return
+	}
+	c.presence.mu.Lock()
+	delete(c.presence.subscribed, msg.TicketID)
+	c.presence.mu.Unlock()
+}
+
+// sendPresenceError reports a rejected presence message
+func (c *Client) sendPresenceError(ticketID uint, err error) {
+	reason := "failed to check ticket"
+	switch {
+	case errors.Is(err, services.ErrPresenceForbidden):
+		reason = "presence is not available for this ticket"
+	case errors.Is(err, services.ErrPresenceTicketNotFound):
+		reason = "ticket not found"
+	default:
+		log.Printf("Failed to check presence access for user %d on ticket %d: %v", c.UserID, ticketID, err)
+	}
+	c.hub.sendToClient(c, "presence_error", map[string]interface{}{"ticket_id": ticketID, "error": reason})
+}
+
+// portalClients returns the portal connections opened for a ticket
+func (h *Hub) portalClients(ticketID uint) []*Client {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	var clients []*Client
+	for client := range h.clients {
+		if client.portalTicketID == ticketID {
+			clients = append(clients, client)
+		}
+	}
+	return clients
+}
+
+// customerPresence computes whether the customer is connected and viewing the ticket in the portal
+func (h *Hub) customerPresence(ticketID, customerID uint) *models.CustomerPresence {
+	presence := &models.CustomerPresence{TicketID: ticketID, CustomerID: customerID, UpdatedAt: time.Now()}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for client := range h.clients {
+		if client.UserID != customerID {
+			continue
+		}
+		presence.Online = true
+		if client.portalTicketID == ticketID {
+			client.presence.mu.Lock()
+			if client.presence.viewing {
+				presence.Viewing = true
+			}
+			client.presence.mu.Unlock()
+		}
+	}
+	return presence
+}
+
+// pushCustomerPresence sends the customer's presence to staff connections watching the ticket
+func (h *Hub) pushCustomerPresence(ticketID, customerID uint) {
+	var watchers []*Client
+	h.mu.RLock()
+	for client := range h.clients {
+		client.presence.mu.Lock()
+		if watched, ok := client.presence.subscribed[ticketID]; ok && watched == customerID {
+			watchers = append(watchers, client)
+		}
+		client.presence.mu.Unlock()
+	}
+	h.mu.RUnlock()
+	if len(watchers) == 0 {
+		return
+	}
+
+	presence := h.customerPresence(ticketID, customerID)
+	for _, client := range watchers {
+		h.sendToClient(client, "customer_presence", presence)
+	}
+}
+
+// ServePortalWS opens a portal connection for one ticket, authenticated by a portal realtime token
+// passed as ?token= since browsers cannot set headers on WebSocket requests.
+func ServePortalWS(hub *Hub, c *gin.Context) {
+	if hub.presence == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Portal realtime is not available"})
+		return
+	}
+
+	claims, err := hub.presence.VerifyToken(c.Request.Context(), c.Query("token"))
+	if err != nil {
+		if !errors.Is(err, services.ErrPortalTokenInvalid) {
+			log.Printf("Failed to verify portal realtime token: %v", err)
+		}
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+		return
+	}
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("WebSocket upgrade failed: %v", err)
+		return
+	}
+
+	client := NewClient(hub, conn, claims.CustomerID)
+	client.portalTicketID = claims.TicketID
+	client.presence.viewing = true
+	client.hub.register <- client
+
+	go client.writePump()
+	go client.readPump()
+}
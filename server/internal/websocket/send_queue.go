@@ -0,0 +1,196 @@
+package websocket
+
+import (
+	"sync"
+	"time"
+
+	"gongdan-system/internal/services"
+)
+
+// Limits bounds how much a single connection may buffer, so one stuck browser
+// cannot hold up delivery to everyone else.
+type Limits struct {
+	// QueueSize is the number of messages buffered per connection; when full the oldest is dropped.
+	QueueSize int
+	// WriteTimeout is the time allowed for a single write to the peer.
+	WriteTimeout time.Duration
+	// SlowClientTimeout disconnects a client whose oldest queued message has waited longer than this.
+	SlowClientTimeout time.Duration
+	// MaxDrops disconnects a client after this many drops since its last successful write; 0 disables.
+	MaxDrops int
+}
+
+// DefaultLimits returns the limits used when nothing is configured
+func DefaultLimits() Limits {
+	return Limits{
+		QueueSize:         256,
+		WriteTimeout:      writeWait,
+		SlowClientTimeout: 30 * time.Second,
+		MaxDrops:          500,
+	}
+}
+
+// LoadLimits reads the limits from system config, falling back to the defaults
+func LoadLimits(configService *services.ConfigService) Limits {
+	limits := DefaultLimits()
+	if v, err := configService.GetConfigInt(services.KeyWSSendQueueSize); err == nil && v > 0 {
+		limits.QueueSize = v
+	}
+	if v, err := configService.GetConfigInt(services.KeyWSWriteTimeoutSec); err == nil && v > 0 {
+		limits.WriteTimeout = time.Duration(v) * time.Second
+	}
+	if v, err := configService.GetConfigInt(services.KeyWSSlowClientTimeoutSec); err == nil && v > 0 {
+		limits.SlowClientTimeout = time.Duration(v) * time.Second
+	}
+	if v, err := configService.GetConfigInt(services.KeyWSMaxDrops); err == nil && v >= 0 {
+		limits.MaxDrops = v
+	}
+	return limits
+}
+
+type queuedMessage struct {
+	data     []byte
+	queuedAt time.Time
+}
+
+// sendQueue is a bounded per-connection outbound queue with a drop-oldest policy.
+// Producers never block; the connection's writePump drains it.
+type sendQueue struct {
+	mu       sync.Mutex
+	items    []queuedMessage
+	capacity int
+	closed   bool
+
+	// ready is signalled when messages are queued or the queue is closed
+	ready chan struct{}
+
+	enqueued        uint64
+	sent            uint64
+	dropped         uint64
+	dropsSinceWrite int
+	maxDepth        int
+	lastWriteAt     time.Time
+}
+
+func newSendQueue(capacity int) *sendQueue {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &sendQueue{
+		items:    make([]queuedMessage, 0, capacity),
+		capacity: capacity,
+		ready:    make(chan struct{}, 1),
+	}
+}
+
+func (q *sendQueue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// push queues a message, dropping the oldest one when full. It returns false once the queue is closed.
+func (q *sendQueue) push(data []byte, now time.Time) bool {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return false
+	}
+	if len(q.items) >= q.capacity {
+		copy(q.items, q.items[1:])
+		q.items = q.items[:len(q.items)-1]
+		q.dropped++
+		q.dropsSinceWrite++
+	}
+	q.items = append(q.items, queuedMessage{data: data, queuedAt: now})
+	q.enqueued++
+	if len(q.items) > q.maxDepth {
+		q.maxDepth = len(q.items)
+	}
+	q.mu.Unlock()
+	q.signal()
+	return true
+}
+
+// drain takes every queued message, and reports whether the queue has been closed
+func (q *sendQueue) drain() ([][]byte, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return nil, true
+	}
+	messages := make([][]byte, len(q.items))
+	for i, item := range q.items {
+		messages[i] = item.data
+	}
+	q.items = q.items[:0]
+	return messages, false
+}
+
+// markSent records a successful write of n messages
+func (q *sendQueue) markSent(n int, now time.Time) {
+	q.mu.Lock()
+	q.sent += uint64(n)
+	q.dropsSinceWrite = 0
+	q.lastWriteAt = now
+	q.mu.Unlock()
+}
+
+// close discards pending messages and wakes the writer; safe to call more than once
+func (q *sendQueue) close() {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return
+	}
+	q.closed = true
+	q.items = nil
+	q.mu.Unlock()
+	q.signal()
+}
+
+// slowReason reports why the consumer is considered too slow to keep, or "" if it is keeping up
+func (q *sendQueue) slowReason(limits Limits, now time.Time) string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ""
+	}
+	if limits.MaxDrops > 0 && q.dropsSinceWrite >= limits.MaxDrops {
+		return "too many dropped messages"
+	}
+	if limits.SlowClientTimeout > 0 && len(q.items) > 0 && now.Sub(q.items[0].queuedAt) > limits.SlowClientTimeout {
+		return "send queue stalled"
+	}
+	return ""
+}
+
+// QueueStats is a snapshot of one connection's send queue
+type QueueStats struct {
+	Depth       int        `json:"queue_depth"`
+	Capacity    int        `json:"queue_capacity"`
+	MaxDepth    int        `json:"max_queue_depth"`
+	Enqueued    uint64     `json:"enqueued"`
+	Sent        uint64     `json:"sent"`
+	Dropped     uint64     `json:"dropped"`
+	LastWriteAt *time.Time `json:"last_write_at,omitempty"`
+}
+
+func (q *sendQueue) stats() QueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	stats := QueueStats{
+		Depth:    len(q.items),
+		Capacity: q.capacity,
+		MaxDepth: q.maxDepth,
+		Enqueued: q.enqueued,
+		Sent:     q.sent,
+		Dropped:  q.dropped,
+	}
+	if !q.lastWriteAt.IsZero() {
+		lastWriteAt := q.lastWriteAt
+		stats.LastWriteAt = &lastWriteAt
+	}
+	return stats
+}
@@ -0,0 +1,63 @@
+package websocket
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSendQueue_DropsOldestWhenFull(t *testing.T) {
+	q := newSendQueue(2)
+	now := time.Now()
+	for _, m := range []string{"a", "b", "c"} {
+		if !q.push([]byte(m), now) {
+			t.Fatalf("push %s rejected", m)
+		}
+	}
+
+	messages, closed := q.drain()
+	if closed || len(messages) != 2 || string(messages[0]) != "b" || string(messages[1]) != "c" {
+		t.Fatalf("expected [b c], got %q closed=%v", messages, closed)
+	}
+	q.markSent(len(messages), now)
+
+	stats := q.stats()
+	if stats.Dropped != 1 || stats.Enqueued != 3 || stats.Sent != 2 || stats.MaxDepth != 2 || stats.Depth != 0 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestSendQueue_SlowClientDetection(t *testing.T) {
+	limits := Limits{QueueSize: 2, SlowClientTimeout: 30 * time.Second, MaxDrops: 3}
+	now := time.Now()
+
+	q := newSendQueue(limits.QueueSize)
+	for i := 0; i < 4; i++ {
+		q.push([]byte("x"), now)
+	}
+	if reason := q.slowReason(limits, now); reason != "" {
+		t.Fatalf("2 drops should be tolerated, got %q", reason)
+	}
+	q.push([]byte("x"), now)
+	if reason := q.slowReason(limits, now); reason == "" {
+		t.Fatal("expected disconnect after 3 drops")
+	}
+	q.drain()
+	q.markSent(2, now)
+	if reason := q.slowReason(limits, now); reason != "" {
+		t.Fatalf("successful write should reset drops, got %q", reason)
+	}
+
+	q.push([]byte("x"), now)
+	if reason := q.slowReason(limits, now.Add(31*time.Second)); reason == "" {
+		t.Fatal("expected disconnect when the oldest message is stale")
+	}
+
+	q.close()
+	q.close()
+	if q.push([]byte("x"), now) {
+		t.Fatal("push after close should be rejected")
+	}
+	if _, closed := q.drain(); !closed {
+		t.Fatal("drain should report closed")
+	}
+}
@@ -0,0 +1,111 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gongdan-system/internal/services"
+)
+
+const (
+	// ticketCountsPushInterval is how often subscribed clients get their nav badge counts refreshed
+	ticketCountsPushInterval = 30 * time.Second
+	// ticketCountsTimeout bounds the queries for a single user's counts
+	ticketCountsTimeout = 5 * time.Second
+)
+
+// countsSubscription tracks a connection's nav badge subscription and the numbers it last received
+type countsSubscription struct {
+	mu         sync.Mutex
+	subscribed bool
+	last       *services.TicketCounts
+}
+
+// SetTicketCounts enables counts_subscribe / counts_unsubscribe messages; call before Run.
+func (h *Hub) SetTicketCounts(counts *services.TicketCountsService) {
+	h.ticketCounts = counts
+}
+
+// handleCountsSubscribe sends the current counts right away and keeps pushing them when they change
+func (c *Client) handleCountsSubscribe() {
+	if c.hub.ticketCounts == nil {
+		c.hub.sendToClient(c, "counts_error", map[string]interface{}{"error": "ticket counts are not available"})
+		return
+	}
+
+	c.counts.mu.Lock()
+	c.counts.subscribed = true
+	c.counts.last = nil
+	c.counts.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), ticketCountsTimeout)
+	defer cancel()
+	counts, err := c.hub.ticketCounts.GetCountsForUser(ctx, c.UserID)
+	if err != nil {
+		log.Printf("Failed to get ticket counts for user %d: %v", c.UserID, err)
+		c.hub.sendToClient(c, "counts_error", map[string]interface{}{"error": "failed to get ticket counts"})
+		return
+	}
+	c.deliverCounts(counts)
+}
+
+// handleCountsUnsubscribe stops the periodic pushes for this connection
+func (c *Client) handleCountsUnsubscribe() {
+	c.counts.mu.Lock()
+	c.counts.subscribed = false
+	c.counts.last = nil
+	c.counts.mu.Unlock()
+}
+
+// deliverCounts sends the counts unless the connection has unsubscribed or already has the same numbers
+func (c *Client) deliverCounts(counts *services.TicketCounts) {
+	c.counts.mu.Lock()
+	if !c.counts.subscribed || counts.SameNumbers(c.counts.last) {
+		c.counts.mu.Unlock()
+		return
+	}
+	c.counts.last = counts
+	c.counts.mu.Unlock()
+
+	c.hub.sendToClient(c, "ticket_counts", counts)
+}
+
+// pushTicketCounts refreshes the counts of every subscribed connection, querying once per user.
+// A run still in progress when the next tick fires is not overlapped.
+func (h *Hub) pushTicketCounts() {
+	if !atomic.CompareAndSwapInt32(&h.countsPushing, 0, 1) {
+		return
+	}
+	defer atomic.StoreInt32(&h.countsPushing, 0)
+
+	byUser := make(map[uint][]*Client)
+	h.mu.RLock()
+	for client := range h.clients {
+		client.counts.mu.Lock()
+		subscribed := client.counts.subscribed
+		client.counts.mu.Unlock()
+		if subscribed {
+			byUser[client.UserID] = append(byUser[client.UserID], client)
+		}
+	}
+	h.mu.RUnlock()
+
+	for userID, clients := range byUser {
+		ctx, cancel := context.WithTimeout(context.Background(), ticketCountsTimeout)
+		counts, err := h.ticketCounts.GetCountsForUser(ctx, userID)
+		cancel()
+		if err != nil {
+			if !errors.Is(err, services.ErrTicketCountsUserInactive) {
+				log.Printf("Failed to push ticket counts for user %d: %v", userID, err)
+			}
+			continue
+		}
+		for _, client := range clients {
+			client.deliverCounts(counts)
+		}
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -19,6 +20,7 @@ import (
 	"gongdan-system/internal/database"
 	"gongdan-system/internal/handlers"
 	"gongdan-system/internal/middleware"
+	"gongdan-system/internal/models"
 	"gongdan-system/internal/services"
 	websocketPkg "gongdan-system/internal/websocket"
 )
@@ -51,6 +53,33 @@ func ginAdapter(handler func(auth.HTTPContext)) gin.HandlerFunc {
 	}
 }
 
+// httpSecurityDefaults 根据环境变量构建默认HTTP安全策略
+func httpSecurityDefaults(cfg *config.Config) *models.HTTPSecurityPolicy {
+	policy := models.GetDefaultHTTPSecurityPolicy()
+	if len(cfg.CORS.AllowedOrigins) > 0 {
+		policy.AllowedOrigins = cfg.CORS.AllowedOrigins
+	}
+	if len(cfg.CORS.AllowedMethods) > 0 {
+		policy.AllowedMethods = cfg.CORS.AllowedMethods
+	}
+	for _, header := range cfg.CORS.AllowedHeaders {
+		found := false
+		for _, existing := range policy.AllowedHeaders {
+			if strings.EqualFold(existing, header) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			policy.AllowedHeaders = append(policy.AllowedHeaders, header)
+		}
+	}
+	if cfg.Server.Environment != "production" {
+		policy.HSTSMaxAge = 0
+	}
+	return policy
+}
+
 func testRedis() {
 	// 加载环境变量
 	if err := godotenv.Load(); err != nil {
@@ -239,6 +268,11 @@ func main() {
 		gin.SetMode(gin.DebugMode)
 	}
 
+	// JSON 列存储方式影响模型列类型与查询方式，需在连接数据库前设置
+	if err := models.SetJSONStorage(cfg.Database.JSONStorage); err != nil {
+		log.Fatal("Invalid JSON storage config:", err)
+	}
+
 	// 初始化数据库
 	db, err := database.New(cfg)
 	if err != nil {
@@ -246,9 +280,23 @@ func main() {
 	}
 	defer db.Close()
 
+	// Redis 熔断器健康检查：探测故障与恢复，启动时未连接则后台重连
+	redisCtx, stopRedisHealthCheck := context.WithCancel(context.Background())
+	defer stopRedisHealthCheck()
+	db.Redis.StartHealthCheck(redisCtx, 10*time.Second)
+
+	// 系统配置读穿缓存：所有 ConfigService 共享，配置写入后通过 Redis 发布订阅通知其他实例失效
+	configCache := services.NewConfigCache(db.Redis, db.Redis, "cache:config")
+	configCache.Start(redisCtx)
+	services.SetConfigCache(configCache)
+
 	// 可选的数据库迁移（通过环境变量控制）
 	if os.Getenv("AUTO_MIGRATE") == "true" {
 		log.Println("Starting database migration...")
+		// JSON 列存储方式需在模型迁移前切换
+		if _, err := services.NewJSONStorageService(db.DB).Migrate(context.Background()); err != nil {
+			log.Fatal("Failed to migrate JSON column storage:", err)
+		}
 		if err := database.RunMigrations(db.DB); err != nil {
 			log.Fatal("Failed to run database migrations:", err)
 		}
@@ -263,9 +311,15 @@ func main() {
 		log.Fatal("Failed to initialize auth module:", err)
 	}
 
+	// 非关键工单历史（提醒、系统记录）异步批量写入
+	historyWriter := services.NewHistoryWriter(db.DB)
+	historyWriter.Start()
+	defer historyWriter.Stop()
+
 	// 初始化清理服务和调度器
 	log.Println("Initializing cleanup service and scheduler...")
 	schedulerService := services.NewSchedulerService(db.DB)
+	schedulerService.SetHistoryWriter(historyWriter)
 
 	// 启动调度器（在后台运行）
 	go func() {
@@ -283,8 +337,24 @@ func main() {
 		schedulerService.Stop()
 	}()
 
+	// 工单列表实时更新：按批次窗口扫描工单变更，推送给筛选条件匹配的订阅
+	liveQueueService := services.NewLiveQueueService(db.DB)
+	liveQueueService.Start()
+	defer liveQueueService.Stop()
+	liveQueueHandler := handlers.NewLiveQueueHandler(liveQueueService)
+
+	// 审计事件转发（SIEM）
+	auditForwarder := services.NewAuditForwarder(db.DB)
+	auditForwarder.Start()
+	defer auditForwarder.Stop()
+	authModule.AuthService.SetAuditForwarder(auditForwarder)
+
 	// 创建 Gin 路由器
 	r := gin.New()
+	// 客户端IP（登录IP封禁、限流、审计）只信任配置的代理转发的 X-Forwarded-For
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
 
 	// 设置中间件配置
 	var middlewareConfig *middleware.MiddlewareConfig
@@ -303,22 +373,26 @@ func main() {
 	r.Use(gin.Logger())
 	r.Use(gin.Recovery())
 
-	// 添加CORS中间件
-	r.Use(func(c *gin.Context) {
-		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, HEAD, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Length, Content-Type, Authorization, Accept, Accept-Encoding, Accept-Language, X-Requested-With, X-CSRF-Token, X-Request-ID")
-		c.Header("Access-Control-Allow-Credentials", "true")
-		c.Header("Access-Control-Expose-Headers", "Content-Length, X-Request-ID, X-Response-Time")
-		c.Header("Access-Control-Max-Age", "86400")
-
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(http.StatusNoContent)
-			return
-		}
-
-		c.Next()
-	})
+	// CORS及安全响应头中间件（策略可由管理员配置并热更新）
+	httpSecurityPolicy := httpSecurityDefaults(cfg)
+	if err := httpSecurityPolicy.Validate(); err != nil {
+		log.Fatalf("Invalid CORS/HTTP security configuration: %v", err)
+	}
+	httpSecurityService := services.NewHTTPSecurityService(db.DB, httpSecurityPolicy)
+	httpSecurityManager := middleware.NewHTTPSecurityManager(httpSecurityService)
+	httpSecurityCtx, stopHTTPSecurityReload := context.WithCancel(context.Background())
+	defer stopHTTPSecurityReload()
+	httpSecurityManager.StartAutoReload(httpSecurityCtx, time.Minute)
+	r.Use(httpSecurityManager.SecurityHeadersHandler())
+	r.Use(httpSecurityManager.CORSHandler())
+
+	// 响应压缩（gzip/deflate），小于最小字节数的响应不压缩
+	if cfg.Server.CompressionEnabled {
+		r.Use(middleware.Compression(middleware.CompressionConfig{
+			MinSize: cfg.Server.CompressionMinSize,
+			Level:   cfg.Server.CompressionLevel,
+		}))
+	}
 
 	// 健康检查端点
 	r.GET("/healthz", func(c *gin.Context) {
@@ -333,11 +407,156 @@ func main() {
 			"message":  "Ticket System API is running",
 			"version":  "1.0.0",
 			"database": dbStatus,
+			"redis":    db.Redis.Stats().State,
 		})
 	})
 
+	// 本地文件存储；头像文件名包含内容哈希，可长期缓存
+	fileStorage := services.NewLocalFileStorage(cfg.Upload.Dir, cfg.Upload.URLPrefix)
+	avatarFiles := r.Group(cfg.Upload.URLPrefix + "/avatars")
+	avatarFiles.Use(func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=31536000, immutable")
+		c.Next()
+	})
+	avatarFiles.Static("", filepath.Join(cfg.Upload.Dir, "avatars"))
+	// 品牌资源文件名同样包含内容哈希
+	brandingFiles := r.Group(cfg.Upload.URLPrefix + "/branding")
+	brandingFiles.Use(func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=31536000, immutable")
+		c.Next()
+	})
+	brandingFiles.Static("", filepath.Join(cfg.Upload.Dir, "branding"))
+	// 工单附件不提供静态访问，经鉴权的下载接口读取；配置主密钥文件后加密存储，下载时透明解密
+	attachmentService := services.NewTicketAttachmentService(db.DB, fileStorage)
+	var attachmentCipher *services.AttachmentCipher
+	if cfg.Upload.KeyFile != "" {
+		keys, err := services.LoadLocalKeyProvider(cfg.Upload.KeyFile)
+		if err != nil {
+			log.Fatal("Failed to load attachment key file:", err)
+		}
+		attachmentCipher = services.NewAttachmentCipher(keys)
+		attachmentService.SetCipher(attachmentCipher)
+	}
+	attachmentHandler := handlers.NewTicketAttachmentHandler(attachmentService)
+	// 工单完整归档（ZIP）与分析报表导出共用导出目录，通过限时签名链接下载
+	// 下载中心：导出完成后通知发起人，保留下载历史并可重新生成，过期文件由调度任务清理
+	exportsDir := filepath.Join(cfg.Upload.Dir, "exports")
+	downloadCenter := services.NewDownloadCenterService(db.DB, exportsDir)
+	downloadCenterHandler := handlers.NewDownloadCenterHandler(downloadCenter)
+	schedulerService.SetDownloadCenter(downloadCenter)
+	ticketArchiveService := services.NewTicketArchiveService(db.DB, attachmentService, exportsDir)
+	ticketArchiveService.SetDownloadCenter(downloadCenter)
+	ticketArchiveHandler := handlers.NewTicketArchiveHandler(ticketArchiveService)
+	// 工单摘要：由管理员配置的模型服务生成，默认关闭
+	ticketSummaryHandler := handlers.NewTicketSummaryHandler(services.NewTicketSummaryService(db.DB))
+	// 工时计费：计费工时标记、工单计费参考、月度报表及开票锁定
+	billingHandler := handlers.NewBillingHandler(services.NewBillingService(db.DB))
+	prefillLinkHandler := handlers.NewPrefillLinkHandler(services.NewPrefillLinkService(db.DB))
+
+	// 自助注销：宽限期内登录被拦截并可恢复，到期后由调度任务匿名化
+	accountDeletionService := services.NewAccountDeletionService(db.DB)
+	accountDeletionService.SetAvatarService(services.NewAvatarService(db.DB, fileStorage))
+	authModule.AuthService.SetAccountDeletionService(accountDeletionService)
+	schedulerService.SetAccountDeletionService(accountDeletionService)
+
+	// 短信验证码：手机号验证及短信登录第二因子
+	smsOTPService := services.NewSMSOTPService(db.DB)
+	authModule.AuthService.SetSMSOTPService(smsOTPService)
+
+	// 按角色的会话策略：刷新令牌有效期、可信设备、第二因子要求及空闲超时
+	sessionPolicyService := services.NewSessionPolicyService(db.DB)
+	authModule.AuthService.SetSessionPolicyService(sessionPolicyService)
+
+	// 按IP的登录防护：同一IP登录失败次数过多时临时封禁，失败计数由各实例通过 Redis 共享
+	loginIPGuardService := services.NewLoginIPGuardService(db.DB, db.Redis)
+	authModule.AuthService.SetLoginIPGuard(loginIPGuardService)
+
+	// 批量邀请用户：邀请邮件中的链接设置密码（可同时启用OTP）后创建账户
+	authModule.AuthService.SetUserInvitationService(services.NewUserInvitationService(db.DB))
+
+	// 会话模式：cookie 模式下令牌写入 httpOnly Cookie，通过 Cookie 认证的写请求需携带双提交的CSRF令牌
+	if cfg.Security.SessionMode == auth.SessionModeCookie {
+		sameSite, err := auth.ParseSameSite(cfg.Security.CookieSameSite)
+		if err != nil {
+			log.Fatalf("Invalid auth cookie configuration: %v", err)
+		}
+		authModule.Handler.SetCookieSession(&auth.CookieSessionConfig{
+			Domain:     cfg.Security.CookieDomain,
+			Secure:     cfg.Security.CookieSecure,
+			SameSite:   sameSite,
+			CSRFSecret: []byte(cfg.Security.CSRFSecret),
+		})
+	}
+
+	// 邮件退信与投诉：永久退信和投诉的地址停止发送（通知邮件及认证邮件）
+	emailSuppressionService := services.NewEmailSuppressionService(db.DB)
+	authModule.EmailService.SetEmailSuppression(emailSuppressionService)
+	emailSuppressionHandler := handlers.NewEmailSuppressionHandler(emailSuppressionService)
+
+	// 品牌与白标：邮件模板与门户使用的产品名称、Logo、配色、发件域名及门户地址，多租户模式下按门户域名覆盖
+	brandingService := services.NewBrandingService(db.DB, fileStorage)
+	brandingService.SetWebURL(cfg.App.WebURL)
+	authModule.EmailService.SetBranding(brandingService)
+	brandingHandler := handlers.NewBrandingHandler(brandingService)
+
+	// 脚本钩子：管理员安装的脚本在工单创建/更新、评论创建和通知发送前沙箱执行
+	scriptHookService := services.NewScriptHookService(db.DB)
+
+	// 工单自动分类：建单后调用配置的分类服务，高置信度字段自动应用，其余作为建议
+	ticketClassificationService := services.NewTicketClassificationService(db.DB)
+	ticketClassificationService.SetHistoryWriter(historyWriter)
+	classificationHandler := handlers.NewTicketClassificationHandler(ticketClassificationService)
+
+	// 解决代码：解决工单时选择（可配置为必填），用于按处理结果统计
+	resolutionCodeHandler := handlers.NewResolutionCodeHandler(services.NewResolutionCodeService(db.DB))
+
+	// 敏感信息检测：工单描述和评论保存前按分类配置提示、脱敏或拒绝
+	piiScanService := services.NewPIIScanService(db.DB)
+
+	// 只读维护模式（写操作返回503，认证及管理员接口除外）
+	maintenanceService := services.NewMaintenanceService(db.DB)
+
+	// 导出、搜索、统计等高开销接口的并发限流
+	concurrencyLimiter := services.NewConcurrencyLimiter(db.DB)
+	exportLimit := middleware.ConcurrencyLimit(concurrencyLimiter, models.ConcurrencyGroupExport)
+	analyticsLimit := middleware.ConcurrencyLimit(concurrencyLimiter, models.ConcurrencyGroupAnalytics)
+	searchLimit := middleware.ConcurrencyLimitIf(concurrencyLimiter, models.ConcurrencyGroupSearch, func(c *gin.Context) bool {
+		return c.Query("search") != ""
+	})
+
+	// 列表接口分页限制：拒绝超大的每页条数和过深的翻页，记录慢列表查询
+	paginationGuard := services.NewPaginationGuard(db.DB)
+
+	// 依赖 Redis 的功能在 Redis 不可用时降级：认证接口限流退回本实例内存计数，仪表板缓存退回直接查询数据库
+	authRateLimiter := middleware.NewDistributedRateLimiter(db.Redis, "ratelimit:auth", cfg.RateLimit.Requests, cfg.RateLimit.Window)
+	authRateLimit := middleware.WrapGinMiddleware(middleware.RateLimit(&middleware.RateLimitConfig{
+		Limiter: authRateLimiter,
+		KeyFunc: middleware.GinClientIPKeyFunc,
+		Headers: true,
+	}))
+	dashboardCache := services.NewReadThroughCache(db.Redis, "cache:dashboard")
+
+	// 导航角标数字（与仪表板共用缓存），支持 HTTP 轮询和 WebSocket 推送
+	ticketCountsService := services.NewTicketCountsService(db.DB)
+	ticketCountsService.SetCache(dashboardCache)
+
+	// 工单变更提案（指定角色的编辑需审核后生效）
+	changeProposalService := services.NewTicketChangeProposalService(db.DB)
+
+	// 组织营业日历（工作时间、节假日及截止时间建议）
+	businessCalendarService := services.NewBusinessCalendarService(db.DB)
+
+	// 可缓存接口的 ETag 及缓存策略：管理配置每次向服务端确认，表单约束可短时缓存，导出文件不缓存
+	adminETag := middleware.ETag("private, no-cache")
+	formSchemaETag := middleware.ETag("private, max-age=60")
+	noStore := middleware.CacheControl("no-store")
+
 	// API 路由组
 	api := r.Group("/api")
+	api.Use(middleware.MaintenanceMode(maintenanceService))
+	api.Use(middleware.PaginationGuard(paginationGuard))
+	api.Use(middleware.ForwardPermissionDenials(auditForwarder))
+	api.Use(ginAdapter(authModule.Handler.CSRFProtect))
 	{
 		api.GET("/ping", func(c *gin.Context) {
 			c.JSON(http.StatusOK, gin.H{
@@ -352,14 +571,24 @@ func main() {
 		// 认证路由
 		authGroup := api.Group("/auth")
 		{
-			authGroup.POST("/register", ginAdapter(authModule.Handler.Register))
-			authGroup.POST("/login", ginAdapter(authModule.Handler.Login))
+			authGroup.GET("/session-mode", ginAdapter(authModule.Handler.SessionMode))
+			authGroup.POST("/register", authRateLimit, ginAdapter(authModule.Handler.Register))
+			authGroup.POST("/login", authRateLimit, ginAdapter(authModule.Handler.Login))
+			authGroup.POST("/login/sms-code", authRateLimit, ginAdapter(authModule.Handler.RequestLoginSMSCode))
 			authGroup.POST("/logout", ginAdapter(authModule.Handler.Logout))
 			authGroup.POST("/refresh", ginAdapter(authModule.Handler.RefreshToken))
-			authGroup.POST("/forgot-password", ginAdapter(authModule.Handler.ForgotPassword))
-			authGroup.POST("/reset-password", ginAdapter(authModule.Handler.ResetPassword))
+			authGroup.POST("/forgot-password", authRateLimit, ginAdapter(authModule.Handler.ForgotPassword))
+			authGroup.POST("/reset-password", authRateLimit, ginAdapter(authModule.Handler.ResetPassword))
+			authGroup.POST("/magic-link", authRateLimit, ginAdapter(authModule.Handler.RequestMagicLink))
+			authGroup.GET("/magic-link/verify", ginAdapter(authModule.Handler.VerifyMagicLink))
 			authGroup.POST("/verify-email", ginAdapter(authModule.Handler.VerifyEmail))
-			authGroup.POST("/resend-verification", ginAdapter(authModule.Handler.ResendVerification))
+			authGroup.POST("/resend-verification", authRateLimit, ginAdapter(authModule.Handler.ResendVerification))
+			authGroup.POST("/delete-account/confirm", ginAdapter(authModule.Handler.ConfirmAccountDeletion))
+			authGroup.POST("/restore-account", ginAdapter(authModule.Handler.RestoreAccount))
+			authGroup.GET("/invitations/:token", ginAdapter(authModule.Handler.GetInvitation))
+			authGroup.POST("/invitations/:token/accept", authRateLimit, ginAdapter(authModule.Handler.AcceptInvitation))
+			// 会话空闲状态查询不计入会话活动
+			authGroup.GET("/session/idle", ginAdapter(authModule.Handler.PassiveSession), ginAdapter(authModule.Handler.RequireAuth), ginAdapter(authModule.Handler.GetSessionIdle))
 
 			// 需要认证的路由
 			authenticated := authGroup.Group("/")
@@ -367,39 +596,204 @@ func main() {
 			{
 				authenticated.GET("/me", ginAdapter(authModule.Handler.GetProfile))
 				authenticated.GET("/profile", ginAdapter(authModule.Handler.GetProfile))
+				authenticated.GET("/policy", ginAdapter(authModule.Handler.GetSessionPolicy))
+				authenticated.POST("/session/keepalive", ginAdapter(authModule.Handler.KeepSessionAlive))
 				authenticated.PUT("/profile", ginAdapter(authModule.Handler.UpdateProfile))
 				authenticated.POST("/change-password", ginAdapter(authModule.Handler.ChangePassword))
 				authenticated.POST("/enable-otp", ginAdapter(authModule.Handler.EnableOTP))
 				authenticated.POST("/disable-otp", ginAdapter(authModule.Handler.DisableOTP))
 				authenticated.POST("/verify-otp", ginAdapter(authModule.Handler.VerifyOTP))
 				authenticated.POST("/otp/backup-codes", ginAdapter(authModule.Handler.GenerateBackupCodes))
+				authenticated.POST("/phone/send-code", authRateLimit, ginAdapter(authModule.Handler.SendPhoneCode))
+				authenticated.POST("/phone/verify", ginAdapter(authModule.Handler.VerifyPhone))
+				authenticated.POST("/sms-otp/enable", ginAdapter(authModule.Handler.EnableSMSOTP))
+				authenticated.POST("/sms-otp/disable", ginAdapter(authModule.Handler.DisableSMSOTP))
 			}
 		}
 
+		// 团队服务（团队队列、@团队提及）
+		teamService := services.NewTeamService(db.DB)
+		intakeSpamService := services.NewIntakeSpamService(db.DB)
+		commentService := services.NewTicketCommentService(db.DB, teamService)
+		commentService.SetScriptHooks(scriptHookService)
+		commentService.SetPIIScanner(piiScanService)
+
+		// 预签名上传：评论中粘贴的图片和文件先上传，随评论提交转为附件，过期未提交的由调度任务清理
+		uploadService := services.NewUploadService(db.DB, fileStorage, "/api/uploads")
+		uploadService.SetCipher(attachmentCipher)
+		commentService.SetUploadService(uploadService)
+		schedulerService.SetUploadService(uploadService)
+		uploadHandler := handlers.NewUploadHandler(uploadService)
+		uploads := api.Group("/uploads")
+		uploads.Use(ginAdapter(authModule.Handler.RequireAuth))
+		{
+			uploads.POST("/presign", uploadHandler.Presign)
+			uploads.PUT("/:upload_id", uploadHandler.PutChunk) // 分片上传（存储不支持直传时）
+		}
+
+		// 邮件收件箱：同一邮件会话的回复按 Message-ID 追加到已有工单
+		inboxService := services.NewInboxService(db.DB)
+		inboxHandler := handlers.NewInboxHandler(inboxService)
+
+		// 保密工单访问审计：详情、历史、评论的读取写入访问日志
+		accessAuditService := services.NewTicketAccessAuditService(db.DB)
+		auditView := middleware.AuditTicketAccess(accessAuditService, models.TicketAccessView)
+		auditHistory := middleware.AuditTicketAccess(accessAuditService, models.TicketAccessHistory)
+		auditComments := middleware.AuditTicketAccess(accessAuditService, models.TicketAccessComments)
+		auditArchive := middleware.AuditTicketAccess(accessAuditService, models.TicketAccessArchive)
+
+		// 知识库（文章检索、推荐及与工单的关联）
+		kbHandler := handlers.NewKBHandler(services.NewKBArticleService(db.DB))
+		requireAgent := ginAdapter(authModule.Handler.RequireRole(auth.RoleAgent))
+
+		// 浏览器推送：应用内通知同时推送到用户订阅的浏览器
+		pushService := services.NewPushNotificationService(db.DB)
+
 		// 工单路由
+		// 坐席分类成员：开启分类权限后坐席只能查看和处理所属分类的工单
+		categoryMembershipService := services.NewCategoryMembershipService(db.DB)
+		categoryMembershipHandler := handlers.NewCategoryMembershipHandler(categoryMembershipService)
+
 		tickets := api.Group("/tickets")
 		{
 			// 创建工单服务和处理器
 			ticketService := services.NewTicketService(db.DB)
+			ticketService.SetPushChannel(pushService)
+			ticketService.SetScriptHooks(scriptHookService)
+			ticketService.SetClassifier(ticketClassificationService)
+			ticketService.SetPIIScanner(piiScanService)
 			ticketHandler := handlers.NewTicketHandler(ticketService)
+			ticketHandler.SetChangeProposalService(changeProposalService)
+			ticketHandler.SetFieldPermissionService(services.NewTicketFieldPermissionService(db.DB))
+			ticketHandler.SetBusinessCalendarService(businessCalendarService)
+			ticketHandler.SetIntakeSpamService(intakeSpamService)
+			checklistService := services.NewTicketChecklistService(db.DB)
+			ticketHandler.SetChecklistService(checklistService)
+			ticketHandler.SetInboxService(inboxService)
+			externalReferenceService := services.NewExternalReferenceService(db.DB)
+			ticketHandler.SetExternalReferenceService(externalReferenceService)
+			ticketHandler.SetCategoryMembershipService(categoryMembershipService)
+			externalReferenceHandler := handlers.NewExternalReferenceHandler(externalReferenceService)
+			checklistHandler := handlers.NewTicketChecklistHandler(checklistService)
 			workflowHandler := handlers.NewTicketWorkflowHandler(ticketService)
+			workflowHandler.SetSurveyService(services.NewTicketSurveyService(db.DB))
+			workflowHandler.SetCategoryTransferService(services.NewCategoryTransferService(db.DB))
+			workflowHandler.SetCategoryMembershipService(categoryMembershipService)
+			teamHandler := handlers.NewTeamHandler(teamService)
+			commentHandler := handlers.NewTicketCommentHandler(commentService)
+			bulkCommentHandler := handlers.NewTicketBulkCommentHandler(services.NewTicketBulkCommentService(db.DB, commentService))
+			callHandler := handlers.NewTicketCallHandler(services.NewTicketCallService(db.DB))
 
 			// 所有工单路由都需要认证
 			tickets.Use(ginAdapter(authModule.Handler.RequireAuth))
+			// 评论、附件、历史、归档等工单子资源按工单分类检查访问权限
+			ticketAccess := categoryMembershipHandler.RequireTicketAccess
 
 			// 基础工单CRUD路由
-			tickets.GET("", ticketHandler.GetTickets)          // 获取工单列表
-			tickets.GET("/:id", ticketHandler.GetTicket)       // 获取单个工单
-			tickets.POST("", ticketHandler.CreateTicket)       // 创建工单
-			tickets.PUT("/:id", ticketHandler.UpdateTicket)    // 更新工单
-			tickets.DELETE("/:id", ticketHandler.DeleteTicket) // 删除工单
+			tickets.GET("", searchLimit, ticketHandler.GetTickets)  // 获取工单列表（带搜索关键字时限流）
+			tickets.GET("/:id", auditView, ticketHandler.GetTicket) // 获取单个工单
+			tickets.POST("", ticketHandler.CreateTicket)            // 创建工单
+			tickets.PUT("/:id", ticketHandler.UpdateTicket)         // 更新工单
+			tickets.DELETE("/:id", ticketHandler.DeleteTicket)      // 删除工单
+
+			// 字段编辑权限：当前角色可修改的字段，供前端禁用受限控件
+			tickets.GET("/:id/editable-fields", ticketHandler.GetEditableFields)
+
+			// 分配处理人选择：开启分类权限时只返回工单所在分类的坐席及管理员、主管
+			tickets.GET("/:id/assignees", requireAgent, categoryMembershipHandler.ListAssignees)
+
+			// 高级条件查询：字段/运算符/取值条件组，支持自定义字段和相对时间
+			tickets.POST("/query", requireAgent, middleware.ConcurrencyLimit(concurrencyLimiter, models.ConcurrencyGroupSearch), ticketHandler.QueryTickets)
 
 			// 工作流相关路由
-			tickets.POST("/:id/assign", workflowHandler.AssignTicket)       // 分配工单
-			tickets.POST("/:id/transfer", workflowHandler.TransferTicket)   // 转移工单
-			tickets.POST("/:id/escalate", workflowHandler.EscalateTicket)   // 升级工单
-			tickets.POST("/:id/status", workflowHandler.UpdateTicketStatus) // 更新状态
-			tickets.GET("/:id/history", workflowHandler.GetTicketHistory)   // 获取工单历史
+			tickets.POST("/:id/assign", workflowHandler.AssignTicket)                                 // 分配工单
+			tickets.POST("/:id/transfer", workflowHandler.TransferTicket)                             // 转移工单
+			tickets.POST("/:id/escalate", workflowHandler.EscalateTicket)                             // 升级工单
+			tickets.POST("/:id/status", workflowHandler.UpdateTicketStatus)                           // 更新状态
+			tickets.GET("/:id/history", ticketAccess, auditHistory, workflowHandler.GetTicketHistory) // 获取工单历史
+			tickets.POST("/:id/claim", workflowHandler.ClaimTicket)                                   // 认领未分配工单
+			tickets.POST("/:id/survey", ticketAccess, workflowHandler.SubmitSurvey)                   // 提交满意度评分
+
+			// 转移分类：按新分类重算SLA、执行分类自动分配并触发 category.changed 规则
+			tickets.POST("/:id/transfer-category", requireAgent, workflowHandler.TransferCategory)
+
+			// 附件（按工单分类的附件策略校验大小、类型与数量）
+			tickets.GET("/form-schema", formSchemaETag, attachmentHandler.GetFormSchema) // 分类的建单表单约束，供上传前预校验
+			tickets.GET("/prefill", prefillLinkHandler.ExpandLink)                       // 展开建单预填链接
+			tickets.GET("/:id/attachments", ticketAccess, attachmentHandler.ListAttachments)
+			tickets.POST("/:id/attachments", ticketAccess, attachmentHandler.UploadAttachment)
+			tickets.GET("/:id/attachments/:attachment_id/download", ticketAccess, attachmentHandler.DownloadAttachment)
+
+			// 完整归档：详情JSON、PDF、评论、历史及附件打包为 ZIP，后台生成，完成后返回签名下载链接
+			tickets.POST("/:id/archive", requireAgent, ticketAccess, auditArchive, ticketArchiveHandler.CreateArchive)
+			tickets.GET("/:id/archive/jobs/:job_id", requireAgent, ticketAccess, ticketArchiveHandler.GetArchiveJob)
+			tickets.POST("/:id/summarize", requireAgent, ticketAccess, auditComments, ticketSummaryHandler.Summarize)
+			tickets.GET("/:id/summaries", requireAgent, ticketAccess, ticketSummaryHandler.ListSummaries)
+			tickets.GET("/:id/worklogs", requireAgent, ticketAccess, billingHandler.ListWorklogs)
+			tickets.PATCH("/:id/worklogs/:comment_id/billable", requireAgent, ticketAccess, billingHandler.SetWorklogBillable)
+			tickets.GET("/:id/billing-reference", requireAgent, ticketAccess, billingHandler.GetReference)
+			tickets.PUT("/:id/billing-reference", requireAgent, ticketAccess, billingHandler.SetReference)
+			tickets.DELETE("/:id/billing-reference", requireAgent, ticketAccess, billingHandler.DeleteReference)
+
+			// 评论路由（内容中的 @团队标识 会通知团队成员）
+			tickets.GET("/:id/comments", ticketAccess, auditComments, commentHandler.GetComments)
+			tickets.POST("/:id/comments", ticketAccess, commentHandler.CreateComment)
+			tickets.PATCH("/:id/comments/:comment_id/visibility", ticketAccess, commentHandler.UpdateVisibility) // 切换评论可见范围
+			tickets.GET("/:id/comment-draft", ticketAccess, commentHandler.GetDraft)                             // 当前用户的评论草稿及回复锁
+			tickets.PUT("/:id/comment-draft", ticketAccess, commentHandler.SaveDraft)                            // 自动保存草稿，可获取回复锁
+			tickets.DELETE("/:id/comment-draft", ticketAccess, commentHandler.DiscardDraft)                      // 放弃草稿并释放回复锁
+			tickets.GET("/comments/composer-defaults", commentHandler.GetComposerDefaults)                       // 评论编辑器默认可见范围
+
+			// 知识库文章关联（作为解决方案关联时计入文章解决次数）
+			tickets.GET("/:id/kb-articles", requireAgent, ticketAccess, kbHandler.ListTicketArticles)
+			tickets.POST("/:id/kb-articles", requireAgent, ticketAccess, kbHandler.LinkTicketArticle)
+			tickets.DELETE("/:id/kb-articles/:article_id", requireAgent, ticketAccess, kbHandler.UnlinkTicketArticle)
+
+			// 检查清单（开启 ticket.checklist_block_resolve 后必填项未完成不能解决工单）
+			tickets.GET("/:id/checklist", requireAgent, ticketAccess, checklistHandler.ListItems)
+			tickets.POST("/:id/checklist", requireAgent, ticketAccess, checklistHandler.AddItem)
+			tickets.PUT("/:id/checklist/:item_id", requireAgent, ticketAccess, checklistHandler.UpdateItem)
+			tickets.DELETE("/:id/checklist/:item_id", requireAgent, ticketAccess, checklistHandler.DeleteItem)
+			tickets.POST("/:id/checklist/reorder", requireAgent, ticketAccess, checklistHandler.ReorderItems)
+			tickets.POST("/:id/checklist/apply-template", requireAgent, ticketAccess, checklistHandler.ApplyTemplate)
+
+			// 工单提醒（到时按通知偏好提醒自己或同事，可每隔 N 天重复直到工单完结）
+			reminderHandler := handlers.NewTicketReminderHandler(services.NewTicketReminderService(db.DB))
+			tickets.GET("/reminders", requireAgent, reminderHandler.ListMyReminders)
+			tickets.GET("/:id/reminders", requireAgent, ticketAccess, reminderHandler.ListTicketReminders)
+			tickets.POST("/:id/reminders", requireAgent, ticketAccess, reminderHandler.CreateReminder)
+			tickets.DELETE("/:id/reminders/:reminder_id", requireAgent, ticketAccess, reminderHandler.CancelReminder)
+
+			// 外部系统关联（CRM 客户 ID、订单号等），同一系统的同一编号只能关联一个工单
+			tickets.GET("/by-reference", requireAgent, ticketHandler.GetTicketByReference)
+			tickets.GET("/:id/references", requireAgent, ticketAccess, externalReferenceHandler.ListReferences)
+			tickets.POST("/:id/references", requireAgent, ticketAccess, externalReferenceHandler.AttachReference)
+			tickets.DELETE("/:id/references/:ref_id", requireAgent, ticketAccess, externalReferenceHandler.DetachReference)
+
+			// 电话渠道通话记录（未指定工单时新建来源为电话的工单）
+			tickets.POST("/calls", requireAgent, callHandler.LogCall)
+			tickets.GET("/:id/calls", requireAgent, ticketAccess, callHandler.ListCalls)
+			tickets.POST("/:id/calls", requireAgent, ticketAccess, callHandler.LogTicketCall)
+
+			// 自动分类结果及建议确认
+			tickets.GET("/:id/classification", requireAgent, ticketAccess, classificationHandler.GetTicketClassification)
+			tickets.POST("/:id/classification/accept", requireAgent, ticketAccess, classificationHandler.AcceptSuggestion)
+			tickets.POST("/:id/classification/reject", requireAgent, ticketAccess, classificationHandler.RejectSuggestion)
+
+			// 解决工单时可选的解决代码（开启 ticket.require_resolution_code 后解决时必填）
+			tickets.GET("/resolution-codes", requireAgent, resolutionCodeHandler.ListActiveCodes)
+
+			// 原始邮件往来（邮件渠道工单）
+			tickets.GET("/:id/email-thread", requireAgent, ticketAccess, auditView, inboxHandler.GetTicketEmailThread)
+
+			// 团队队列
+			tickets.GET("/team-queues", teamHandler.GetTeamQueues) // 团队队列及未认领数
+
+			// 工单列表实时更新（WebSocket 不可用时轮询）
+			tickets.GET("/changes", requireAgent, liveQueueHandler.GetChanges)
+
+			// 导航角标数字（我的未完结、未分配、逾期、SLA风险、未读提及）
+			tickets.GET("/counts", handlers.NewTicketCountsHandler(ticketCountsService).GetCounts)
 
 			// 统计和特殊查询路由
 			tickets.GET("/stats", workflowHandler.GetTicketStats)             // 获取工单统计
@@ -408,10 +802,18 @@ func main() {
 			tickets.GET("/overdue", workflowHandler.GetOverdueTickets)        // 获取逾期工单
 			tickets.GET("/sla-breach", workflowHandler.GetSLABreachedTickets) // 获取SLA违约工单
 
+			// 即将违约工单（已越过SLA预警阈值）
+			tickets.GET("/sla-at-risk", requireAgent, handlers.NewSLAWarningHandler(services.NewSLAWarningService(db.DB)).GetAtRiskTickets)
+
 			// 批量操作路由
 			tickets.POST("/bulk-assign", workflowHandler.BulkAssignTickets) // 批量分配
 			tickets.POST("/bulk-status", workflowHandler.BulkUpdateStatus)  // 批量状态更新
 			tickets.POST("/bulk-update", ticketHandler.BulkUpdateTickets)   // 原有批量更新
+
+			// 批量评论：后台逐个工单发表（支持 {{ticket.number}} 等变量），结束后按接收人汇总通知
+			tickets.POST("/bulk-comment", requireAgent, bulkCommentHandler.CreateJob)
+			tickets.GET("/bulk-comment", requireAgent, bulkCommentHandler.ListJobs)
+			tickets.GET("/bulk-comment/:job_id", requireAgent, bulkCommentHandler.GetJob)
 		}
 
 		// 邮箱配置路由
@@ -423,11 +825,19 @@ func main() {
 
 		// 用户个人中心路由（需要认证）
 		userService := services.NewUserService(db.DB)
+		userService.SetAvatarService(services.NewAvatarService(db.DB, fileStorage))
 		trustedDeviceService := services.NewTrustedDeviceService(db.DB)
 		userHandler := handlers.NewUserHandler(userService, trustedDeviceService)
 		adminAuditService := services.NewAdminAuditService(db.DB)
+		adminAuditService.SetForwarder(auditForwarder)
+		// 账户安全事件（修改密码、两步验证、角色变更）写入审计日志
+		userService.SetAuditLog(adminAuditService)
+		authModule.AuthService.SetSecurityAuditLog(adminAuditService)
 		adminAuditHandler := handlers.NewAdminAuditHandler(adminAuditService)
 
+		// 默认头像（公开，内容只由种子决定）
+		api.GET("/avatars/identicon/:seed", userHandler.GetIdenticon)
+
 		user := api.Group("/user")
 		user.Use(ginAdapter(authModule.Handler.RequireAuth))
 		{
@@ -437,9 +847,13 @@ func main() {
 			user.GET("/login-history", userHandler.GetLoginHistory)
 			user.GET("/stats", userHandler.GetStats)
 			user.POST("/avatar", userHandler.UploadAvatar)
+			user.DELETE("/avatar", userHandler.DeleteAvatar)
 			user.DELETE("/login-history/:id", userHandler.DeleteLoginSession)
+			user.GET("/downloads", downloadCenterHandler.ListDownloads)                      // 下载中心：导出文件列表
+			user.POST("/downloads/:id/regenerate", downloadCenterHandler.RegenerateDownload) // 按原参数重新生成
 			user.GET("/trusted-devices", userHandler.GetTrustedDevices)
 			user.DELETE("/trusted-devices/:id", userHandler.RevokeTrustedDevice)
+			user.POST("/delete-account", ginAdapter(authModule.Handler.RequestAccountDeletion))
 		}
 
 		// 管理员路由（需要认证和管理员权限）
@@ -455,11 +869,16 @@ func main() {
 
 			// 管理员用户管理路由
 			adminUserService := services.NewAdminUserService(db.DB)
+			adminUserService.SetAuditLog(adminAuditService)
 			adminUserHandler := handlers.NewAdminUserHandler(adminUserService)
 
 			// 用户管理路由
 			admin.GET("/users", adminUserHandler.GetUserList)
 			admin.GET("/users/stats", adminUserHandler.GetUserStats)
+			admin.GET("/users/export", noStore, exportLimit, adminUserHandler.ExportUsers)
+			admin.GET("/users/bulk-jobs", adminUserHandler.ListBulkJobs)
+			admin.GET("/users/bulk-jobs/:id", adminUserHandler.GetBulkJob)
+			admin.POST("/users/bulk-jobs", adminUserHandler.CreateBulkJob)
 			admin.GET("/users/:id", adminUserHandler.GetUser)
 			admin.POST("/users", adminUserHandler.CreateUser)
 			admin.PUT("/users/:id", adminUserHandler.UpdateUser)
@@ -467,40 +886,139 @@ func main() {
 			admin.POST("/users/:id/reset-password", adminUserHandler.ResetUserPassword)
 			admin.POST("/users/:id/toggle-status", adminUserHandler.ToggleUserStatus)
 			admin.POST("/users/batch-delete", adminUserHandler.BatchDeleteUsers)
+
+			// 批量邀请用户
+			admin.POST("/users/invite", ginAdapter(authModule.Handler.InviteUsers))
+			admin.GET("/users/invitations", ginAdapter(authModule.Handler.ListInvitations))
+			admin.POST("/users/invitations/:id/resend", ginAdapter(authModule.Handler.ResendInvitation))
+			admin.DELETE("/users/invitations/:id", ginAdapter(authModule.Handler.RevokeInvitation))
+
+			// 坐席分类成员管理
+			admin.GET("/category-memberships", categoryMembershipHandler.ListMemberships)
+			admin.POST("/category-memberships/bulk", categoryMembershipHandler.BulkUpdateMemberships)
+			admin.PUT("/category-memberships/users/:user_id", categoryMembershipHandler.ReplaceUserMemberships)
+
 			admin.GET("/audit-logs", adminAuditHandler.GetAuditLogs)
+			admin.GET("/audit-logs/verify", adminAuditHandler.VerifyAuditLogs) // 校验审计日志哈希链
 
 			// 系统配置和清理管理路由
 			systemHandler := handlers.NewSystemHandler(db.DB)
+			systemHandler.SetHTTPSecurity(httpSecurityService, httpSecurityManager.Reload)
+			systemHandler.SetMaintenanceService(maintenanceService)
+			systemHandler.SetConcurrencyLimiter(concurrencyLimiter)
+			systemHandler.SetPaginationGuard(paginationGuard)
+			systemHandler.SetAuditForwarder(auditForwarder)
+			systemHandler.SetRedis(db.Redis, authRateLimiter, dashboardCache)
 			systemHandler.RegisterRoutes(admin)
 
+			// 团队管理路由
+			handlers.NewTeamHandler(teamService).RegisterAdminRoutes(admin)
+
+			// 公开渠道垃圾检测策略与隔离区审核
+			handlers.NewIntakeSpamHandler(intakeSpamService).RegisterAdminRoutes(admin)
+
+			// 收件 SPF/DKIM/DMARC 认证策略
+			inboxHandler.RegisterAdminRoutes(admin)
+
+			// 邮件退信/投诉地址状态及手动恢复
+			emailSuppressionHandler.RegisterAdminRoutes(admin)
+			brandingHandler.RegisterAdminRoutes(admin)
+			handlers.NewChatIntakeHandler(services.NewChatIntakeService(db.DB)).RegisterAdminRoutes(admin)
+
+			// 脚本钩子管理、试运行及执行记录
+			handlers.NewScriptHookHandler(scriptHookService).RegisterAdminRoutes(admin)
+
+			// 工单自动分类配置、试运行、分类记录及准确率
+			classificationHandler.RegisterAdminRoutes(admin)
+
+			// 解决代码列表维护
+			resolutionCodeHandler.RegisterAdminRoutes(admin)
+
+			// 敏感信息检测配置、试运行及脱敏记录
+			handlers.NewPIIScanHandler(piiScanService).RegisterAdminRoutes(admin)
+
+			// 计费费率、月度计费工时报表及开票锁定
+			billingHandler.RegisterAdminRoutes(admin)
+
+			// 建单预填链接及使用统计
+			prefillLinkHandler.RegisterAdminRoutes(admin)
+
+			// 按角色的会话与可信设备策略
+			handlers.NewSessionPolicyHandler(sessionPolicyService).RegisterAdminRoutes(admin)
+
+			// 按IP的登录防护配置、封禁列表及手动解封
+			handlers.NewLoginIPGuardHandler(loginIPGuardService).RegisterAdminRoutes(admin)
+
+			// 保密工单访问日志与审计策略
+			handlers.NewTicketAccessAuditHandler(accessAuditService).RegisterAdminRoutes(admin)
+
+			// 套餐配额定义与用量
+			handlers.NewQuotaHandler(services.NewQuotaService(db.DB)).RegisterAdminRoutes(admin)
+
+			// 按分类的工单附件策略
+			attachmentHandler.RegisterAdminRoutes(admin)
+
+			// 定时任务调度查看、修改及立即执行
+			handlers.NewSchedulerHandler(schedulerService).RegisterAdminRoutes(admin)
+
+			// 待注销账户查看及取消
+			handlers.NewAccountDeletionHandler(accountDeletionService).RegisterAdminRoutes(admin)
+
+			// 评论默认可见范围
+			handlers.NewTicketCommentHandler(commentService).RegisterAdminRoutes(admin)
+
+			// 用户自定义字段（成本中心、班次、认证等）及坐席技能标签
+			handlers.NewUserAttributeHandler(services.NewUserAttributeService(db.DB)).RegisterAdminRoutes(admin)
+
+			// 工单法律保留及按删除请求清除客户数据（生成签名删除证书）
+			handlers.NewTicketDataPurgeHandler(services.NewTicketDataPurgeService(db.DB, fileStorage, adminAuditService)).RegisterAdminRoutes(admin)
+
 			// 系统全局配置管理路由
 			configHandler := handlers.NewConfigHandler(db.DB)
 			configs := admin.Group("/configs")
 			{
-				configs.GET("", configHandler.GetAllConfigs)                     // 获取所有配置
-				configs.GET("/:key", configHandler.GetConfig)                    // 获取单个配置
-				configs.POST("", configHandler.CreateConfig)                     // 创建配置
-				configs.PUT("/:key", configHandler.UpdateConfig)                 // 更新配置
-				configs.DELETE("/:key", configHandler.DeleteConfig)              // 删除配置
-				configs.PUT("/batch", configHandler.BatchUpdateConfigs)          // 批量更新配置
-				configs.GET("/security-policy", configHandler.GetSecurityPolicy) // 获取安全策略
-				configs.GET("/export", configHandler.ExportConfigs)              // 导出配置
-				configs.POST("/import", configHandler.ImportConfigs)             // 导入配置
-				configs.POST("/cache/clear", configHandler.ClearCache)           // 清空缓存
-				configs.GET("/cache/stats", configHandler.GetCacheStats)         // 缓存统计
-				configs.POST("/init", configHandler.InitDefaultConfigs)          // 初始化默认配置
+				configs.GET("", adminETag, configHandler.GetAllConfigs)                     // 获取所有配置
+				configs.GET("/schema", adminETag, configHandler.GetSchema)                  // 配置定义及孤立配置检查
+				configs.GET("/:key", adminETag, configHandler.GetConfig)                    // 获取单个配置
+				configs.POST("", configHandler.CreateConfig)                                // 创建配置
+				configs.PUT("/:key", configHandler.UpdateConfig)                            // 更新配置
+				configs.DELETE("/:key", configHandler.DeleteConfig)                         // 删除配置
+				configs.PUT("/batch", configHandler.BatchUpdateConfigs)                     // 批量更新配置
+				configs.GET("/security-policy", adminETag, configHandler.GetSecurityPolicy) // 获取安全策略
+				configs.GET("/export", noStore, exportLimit, configHandler.ExportConfigs)   // 导出配置
+				configs.POST("/import", configHandler.ImportConfigs)                        // 导入配置
+				configs.POST("/cache/clear", configHandler.ClearCache)                      // 清空缓存
+				configs.GET("/cache/stats", configHandler.GetCacheStats)                    // 缓存统计
+				configs.POST("/init", configHandler.InitDefaultConfigs)                     // 初始化默认配置
 			}
 
 			// 系统监控统计管理路由
 			analyticsHandler := handlers.NewAnalyticsHandler(db.DB)
+			analyticsExportService := services.NewAnalyticsExportService(db.DB, exportsDir)
+			analyticsExportService.SetDownloadCenter(downloadCenter)
+			analyticsHandler.SetExportService(analyticsExportService)
+			// 客户SLA合同（有效期内优先于分类、优先级SLA配置）及合同达成率
+			slaContractHandler := handlers.NewSLAContractHandler(services.NewSLAContractService(db.DB))
+			slaContractHandler.RegisterAdminRoutes(admin)
+			callAnalyticsHandler := handlers.NewTicketCallHandler(services.NewTicketCallService(db.DB))
+			smsHandler := handlers.NewSMSHandler(smsOTPService)
+			slaReportHandler := handlers.NewSLAReportHandler(services.NewSLAReportService(db.DB))
 			analytics := admin.Group("/analytics")
 			{
-				analytics.GET("/system", analyticsHandler.GetSystemStats)       // 获取系统运行状态
-				analytics.GET("/business", analyticsHandler.GetBusinessStats)   // 获取业务数据统计
-				analytics.GET("/dashboard", analyticsHandler.GetDashboardStats) // 获取仪表板综合统计
-				analytics.GET("/timerange", analyticsHandler.GetTimeRangeStats) // 获取指定时间范围统计
-				analytics.GET("/export", analyticsHandler.ExportStats)          // 导出统计数据
-				analytics.GET("/realtime", analyticsHandler.GetRealtimeMetrics) // 获取实时指标
+				analytics.GET("/system", analyticsLimit, analyticsHandler.GetSystemStats)               // 获取系统运行状态
+				analytics.GET("/business", analyticsLimit, analyticsHandler.GetBusinessStats)           // 获取业务数据统计
+				analytics.GET("/dashboard", analyticsLimit, analyticsHandler.GetDashboardStats)         // 获取仪表板综合统计
+				analytics.GET("/timerange", analyticsLimit, analyticsHandler.GetTimeRangeStats)         // 获取指定时间范围统计
+				analytics.GET("/export", noStore, exportLimit, analyticsHandler.ExportStats)            // 导出统计数据
+				analytics.GET("/export/jobs/:id", analyticsHandler.GetExportJob)                        // 获取报表导出任务
+				analytics.GET("/export/jobs/:id/download", analyticsHandler.DownloadExportJob)          // 下载报表导出文件
+				analytics.GET("/realtime", analyticsHandler.GetRealtimeMetrics)                         // 获取实时指标
+				analytics.GET("/aging", analyticsLimit, analyticsHandler.GetAgingReport)                // 获取积压账龄报表
+				analytics.GET("/sla-contracts", analyticsLimit, slaContractHandler.GetComplianceReport) // 获取客户SLA合同达成率
+				analytics.GET("/calls", analyticsLimit, callAnalyticsHandler.GetCallVolume)             // 获取按客服或分类的通话量
+				analytics.GET("/resolution-codes", analyticsLimit, resolutionCodeHandler.GetBreakdown)  // 获取按解决代码的工单分布
+				analytics.GET("/sms", analyticsLimit, smsHandler.GetMetrics)                            // 获取短信发送量、送达率及费用
+				analytics.GET("/sla", analyticsLimit, slaReportHandler.GetComplianceReport)             // 获取SLA达成率报表（支持CSV导出）
 			}
 
 			// FE008 自动化流程管理路由
@@ -510,17 +1028,29 @@ func main() {
 				// 自动化规则管理
 				rules := automation.Group("/rules")
 				{
-					rules.POST("", automationHandler.CreateRule)            // 创建自动化规则
-					rules.GET("", automationHandler.GetRules)               // 获取规则列表
-					rules.GET("/:id", automationHandler.GetRule)            // 获取规则详情
-					rules.PUT("/:id", automationHandler.UpdateRule)         // 更新规则
-					rules.DELETE("/:id", automationHandler.DeleteRule)      // 删除规则
-					rules.GET("/:id/stats", automationHandler.GetRuleStats) // 获取规则统计
+					rules.POST("", automationHandler.CreateRule)                                   // 创建自动化规则
+					rules.GET("", automationHandler.GetRules)                                      // 获取规则列表
+					rules.GET("/:id", automationHandler.GetRule)                                   // 获取规则详情
+					rules.PUT("/:id", automationHandler.UpdateRule)                                // 更新规则
+					rules.DELETE("/:id", automationHandler.DeleteRule)                             // 删除规则
+					rules.GET("/:id/stats", automationHandler.GetRuleStats)                        // 获取规则统计
+					rules.GET("/:id/revisions", automationHandler.GetRuleRevisions)                // 获取规则修订历史
+					rules.POST("/:id/revisions/:version/rollback", automationHandler.RollbackRule) // 回滚到指定版本
+					rules.POST("/simulate", automationHandler.SimulateRule)                        // 用历史工单模拟规则
+					rules.GET("/simulations/:id", automationHandler.GetSimulationJob)              // 获取后台模拟任务
 				}
 
 				// 执行日志查询
 				automation.GET("/logs", automationHandler.GetExecutionLogs) // 获取执行日志
 
+				// 配置包导入导出（跨环境复制规则、SLA配置和模板）
+				bundle := automation.Group("/bundle")
+				{
+					bundle.GET("/export", automationHandler.ExportBundle)  // 导出配置包
+					bundle.POST("/import", automationHandler.ImportBundle) // 导入配置包
+					bundle.GET("/checksum", automationHandler.GetChecksum) // 配置校验和
+				}
+
 				// SLA配置管理
 				sla := automation.Group("/sla")
 				{
@@ -561,24 +1091,65 @@ func main() {
 
 		// 邮件通知服务
 		emailNotificationService := services.NewEmailNotificationService(db.DB, emailConfigService, notificationService)
+		emailNotificationService.SetBranding(brandingService)
 
 		// 将邮件通知服务注入到通知服务中
 		notificationService.SetEmailNotificationService(emailNotificationService)
+		// SMTP 不可用时排队重发的邮件及管理员告警由调度任务处理
+		schedulerService.SetEmailNotificationService(emailNotificationService)
 
 		notificationHandler := handlers.NewNotificationHandler(notificationService)
 
 		// 初始化 WebSocket Hub 和 WebSocket 通知服务
 		wsHub := websocketPkg.NewHub()
 		wsNotificationService := websocketPkg.NewNotificationWebSocketService(wsHub)
+		wsHub.SetLiveQueue(liveQueueService)
+		wsHub.SetTicketCounts(ticketCountsService)
+		wsHub.SetLimits(websocketPkg.LoadLimits(services.NewConfigService(db.DB)))
+
+		// 客户门户实时状态：客户看到客服正在输入，客服看到客户在线/正在查看
+		portalPresenceService := services.NewPortalPresenceService(db.DB)
+		portalPresenceService.SetCategoryMembershipService(categoryMembershipService)
+		wsHub.SetPortalPresence(portalPresenceService)
+		portalPresenceHandler := handlers.NewPortalPresenceHandler(portalPresenceService)
 
 		// 启动 WebSocket Hub（在后台运行）
 		go wsHub.Run()
 
 		// 设置全局WebSocket通知服务以供hook使用
 		websocketPkg.SetGlobalNotificationService(wsNotificationService)
+		services.TicketReplyLockHook = websocketPkg.TicketReplyLockHook
+		services.InAppNotificationHook = websocketPkg.NotificationCreatedHook
+
+		notificationService.SetPushChannel(pushService)
+		notificationService.SetScriptHooks(scriptHookService)
+		pushHandler := handlers.NewPushHandler(pushService)
 
 		// 管理员通知管理路由
-		admin.POST("/notifications", notificationHandler.CreateNotification) // 创建通知（管理员）
+		admin.POST("/notifications", notificationHandler.CreateNotification)                 // 创建通知（管理员）
+		pushHandler.RegisterAdminRoutes(admin)                                               // 生成 VAPID 密钥
+		handlers.NewWebSocketStatsHandler(wsHub).RegisterAdminRoutes(admin)                  // WebSocket 连接队列监控
+		handlers.NewEmailChannelHandler(emailNotificationService).RegisterAdminRoutes(admin) // 邮件通道健康监控
+
+		// 首页仪表板（聚合统计、我的工单、SLA风险、最近动态和未读通知）
+		dashboardService := services.NewDashboardService(db.DB)
+		dashboardService.SetCache(dashboardCache)
+		dashboardHandler := handlers.NewDashboardHandler(dashboardService)
+		api.GET("/dashboard", ginAdapter(authModule.Handler.RequireAuth), dashboardHandler.GetDashboard)
+
+		// 营业日历（工作时间、近期节假日、当前营业状态）
+		calendarHandler := handlers.NewCalendarHandler(businessCalendarService)
+		api.GET("/calendar", ginAdapter(authModule.Handler.RequireAuth), calendarHandler.GetCalendar)
+
+		// 移动端增量同步（精简字段、删除记录、ETag/gzip）
+		syncHandler := handlers.NewSyncHandler(services.NewSyncService(db.DB))
+		api.GET("/sync", ginAdapter(authModule.Handler.RequireAuth), requireAgent, syncHandler.Sync)
+
+		// 评论翻译：译文按目标语言缓存；开启自动翻译的客服查看客户评论时附带译文
+		translationHandler := handlers.NewTicketCommentHandler(commentService)
+		api.POST("/comments/:id/translate", ginAdapter(authModule.Handler.RequireAuth), translationHandler.TranslateComment)
+		api.GET("/user/translation-preference", ginAdapter(authModule.Handler.RequireAuth), translationHandler.GetTranslationPreference)
+		api.PUT("/user/translation-preference", ginAdapter(authModule.Handler.RequireAuth), translationHandler.UpdateTranslationPreference)
 
 		// 通知系统路由（需要认证）
 		notifications := api.Group("/notifications")
@@ -586,10 +1157,15 @@ func main() {
 		{
 			notifications.GET("", notificationHandler.GetNotifications)                          // 获取通知列表
 			notifications.PUT("/:id/read", notificationHandler.MarkAsRead)                       // 标记单个通知为已读
+			notifications.GET("/:id/deliveries", notificationHandler.GetNotificationDeliveries)  // 各渠道投递记录
 			notifications.PUT("/read-all", notificationHandler.MarkAllAsRead)                    // 标记所有通知为已读
+			notifications.GET("/groups/:group_id", notificationHandler.GetGroupNotifications)    // 展开通知分组
+			notifications.PUT("/groups/:group_id/read", notificationHandler.MarkGroupAsRead)     // 分组标记已读
+			notifications.DELETE("/clear", notificationHandler.ClearNotifications)               // 批量清理通知
 			notifications.GET("/unread-count", notificationHandler.GetUnreadCount)               // 获取未读通知数量
 			notifications.GET("/preferences", notificationHandler.GetNotificationPreferences)    // 获取通知偏好设置
 			notifications.PUT("/preferences", notificationHandler.UpdateNotificationPreferences) // 更新通知偏好设置
+			pushHandler.RegisterRoutes(notifications)                                            // 浏览器推送订阅
 		}
 
 		// WebSocket 连接端点 (需要认证)
@@ -608,6 +1184,12 @@ func main() {
 			websocketPkg.ServeWS(wsHub, c)
 		})
 
+		// 客户门户实时连接：先用登录态为自己的工单签发短期令牌，再以令牌建立 WebSocket 连接
+		api.POST("/portal/tickets/:id/realtime-token", ginAdapter(authModule.Handler.RequireAuth), portalPresenceHandler.IssueToken)
+		api.GET("/portal/ws", func(c *gin.Context) {
+			websocketPkg.ServePortalWS(wsHub, c)
+		})
+
 		// Webhook管理路由（需要管理员权限）
 		webhooks := api.Group("/webhooks")
 		webhooks.Use(ginAdapter(authModule.Handler.RequireAuth))
@@ -618,16 +1200,84 @@ func main() {
 			webhookHandler := handlers.NewWebhookHandler(db.DB)
 
 			// Webhook配置管理路由
-			webhooks.GET("", webhookHandler.ListWebhooks)              // 获取webhook列表
-			webhooks.POST("", webhookHandler.CreateWebhook)            // 创建webhook
-			webhooks.GET("/:id", webhookHandler.GetWebhook)            // 获取webhook详情
-			webhooks.PUT("/:id", webhookHandler.UpdateWebhook)         // 更新webhook
-			webhooks.DELETE("/:id", webhookHandler.DeleteWebhook)      // 删除webhook
-			webhooks.POST("/:id/test", webhookHandler.TestWebhook)     // 测试webhook
-			webhooks.GET("/:id/logs", webhookHandler.GetWebhookLogs)   // 获取webhook日志
-			webhooks.GET("/:id/stats", webhookHandler.GetWebhookStats) // 获取webhook统计
+			webhooks.GET("", webhookHandler.ListWebhooks)                       // 获取webhook列表
+			webhooks.POST("", webhookHandler.CreateWebhook)                     // 创建webhook
+			webhooks.GET("/:id", webhookHandler.GetWebhook)                     // 获取webhook详情
+			webhooks.PUT("/:id", webhookHandler.UpdateWebhook)                  // 更新webhook
+			webhooks.DELETE("/:id", webhookHandler.DeleteWebhook)               // 删除webhook
+			webhooks.POST("/:id/test", webhookHandler.TestWebhook)              // 测试webhook
+			webhooks.POST("/:id/render-test", webhookHandler.RenderTestWebhook) // 试渲染消息模板
+			webhooks.GET("/:id/logs", webhookHandler.GetWebhookLogs)            // 获取webhook日志
+			webhooks.GET("/:id/stats", webhookHandler.GetWebhookStats)          // 获取webhook统计
 		}
 
+		// 集成触发器路由（Zapier/Make 轮询触发器，需要客服及以上权限）
+		integrations := api.Group("/integrations")
+		integrations.Use(ginAdapter(authModule.Handler.RequireAuth))
+		integrations.Use(ginAdapter(authModule.Handler.RequireRole(auth.RoleAgent)))
+		handlers.NewIntegrationHandler(services.NewIntegrationService(db.DB)).RegisterRoutes(integrations)
+
+		// 聊天平台建单回调（Slack 斜杠命令 / Telegram 机器人，按平台签名校验，无需登录）
+		chatIntakeService := services.NewChatIntakeService(db.DB)
+		chatIntakeService.SetWebURL(cfg.App.WebURL)
+		chatIntakeHandler := handlers.NewChatIntakeHandler(chatIntakeService)
+		chatIntakeHandler.RegisterPublicRoutes(api.Group("/chat-intake"))
+
+		// 邮件服务商退信/投诉回调（按回调令牌校验，无需登录）
+		emailSuppressionHandler.RegisterPublicRoutes(api.Group("/email-events"))
+
+		// 门户品牌信息（按请求域名匹配租户覆盖，无需登录）
+		brandingHandler.RegisterPublicRoutes(api.Group("/branding"))
+
+		// 工单归档签名下载（链接由归档任务接口生成，限时有效，无需登录）
+		api.GET("/ticket-archives/:id/download", ticketArchiveHandler.DownloadArchive)
+
+		// 下载中心签名下载（链接随完成通知及下载列表下发，文件过期前有效，无需登录）
+		api.GET("/downloads/:id/file", downloadCenterHandler.DownloadFile)
+
+		// 公开支持状态及 SVG 徽章（管理员开启 system.public_status_enabled 后可用，无需登录，结果缓存一分钟）
+		handlers.NewPublicStatusHandler(services.NewPublicStatusService(db.DB)).
+			RegisterPublicRoutes(api.Group("/public-status", middleware.ETag("public, max-age=60")))
+
+		// 邮件渠道共享收件箱（待分拣邮件及新建邮件工单，需要客服及以上权限）
+		inbox := api.Group("/inbox")
+		inbox.Use(ginAdapter(authModule.Handler.RequireAuth))
+		inbox.Use(ginAdapter(authModule.Handler.RequireRole(auth.RoleAgent)))
+		inboxHandler.RegisterRoutes(inbox)
+
+		// 知识库文章（客户只能查看已发布的公开文章，编辑需要客服及以上权限）
+		kb := api.Group("/kb")
+		kb.Use(ginAdapter(authModule.Handler.RequireAuth))
+		{
+			kb.GET("/articles", kbHandler.ListArticles)                       // 文章列表及全文检索
+			kb.GET("/articles/:id", kbHandler.GetArticle)                     // 文章详情
+			kb.POST("/articles/:id/deflect", kbHandler.RecordDeflection)      // 记录阅读后未提交工单
+			kb.GET("/suggestions", kbHandler.SuggestArticles)                 // 按工单或文本推荐文章
+			kb.POST("/articles", requireAgent, kbHandler.CreateArticle)       // 创建文章
+			kb.PUT("/articles/:id", requireAgent, kbHandler.UpdateArticle)    // 更新文章
+			kb.DELETE("/articles/:id", requireAgent, kbHandler.DeleteArticle) // 删除文章
+		}
+
+		// 工单变更提案审核（审核角色处理全部提案，其他客服只能查看自己提交的提案）
+		changeProposals := api.Group("/change-proposals")
+		changeProposals.Use(ginAdapter(authModule.Handler.RequireAuth))
+		changeProposals.Use(ginAdapter(authModule.Handler.RequireRole(auth.RoleAgent)))
+		handlers.NewChangeProposalHandler(changeProposalService).RegisterRoutes(changeProposals)
+
+		// 休假委托（坐席管理自己的委托，管理员/主管可为他人创建）
+		delegations := api.Group("/delegations")
+		delegations.Use(ginAdapter(authModule.Handler.RequireAuth))
+		delegations.Use(ginAdapter(authModule.Handler.RequireRole(auth.RoleAgent)))
+		delegations.Use(middleware.LogAdminOperation(adminAuditService))
+		handlers.NewDelegationHandler(services.NewDelegationService(db.DB)).RegisterRoutes(delegations)
+
+		// 换班交接（坐席可查看和预览，管理员/主管执行交接和维护交接计划）
+		handoffs := api.Group("/handoffs")
+		handoffs.Use(ginAdapter(authModule.Handler.RequireAuth))
+		handoffs.Use(ginAdapter(authModule.Handler.RequireRole(auth.RoleAgent)))
+		handoffs.Use(middleware.LogAdminOperation(adminAuditService))
+		handlers.NewTicketHandoffHandler(services.NewTicketHandoffService(db.DB)).RegisterRoutes(handoffs)
+
 		// Redis 连接测试端点
 		api.GET("/redis/test", func(c *gin.Context) {
 			if db.Redis == nil {